@@ -0,0 +1,86 @@
+package toglacier
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Approval decides if a backup bigger than the confirmation threshold can be
+// uploaded, for example asking the user or checking an approval given
+// previously.
+type Approval interface {
+	Approved(size int64) (bool, error)
+}
+
+// ApprovalResult is implemented by the Approval that needs to know if the
+// approved upload succeeded, so an approval given for a single backup is only
+// discarded after the backup is sent.
+type ApprovalResult interface {
+	// OnUpload is called after the upload of an approved archive, with the
+	// error of the upload (nil when it was sent).
+	OnUpload(size int64, err error)
+}
+
+// ApprovalError stores the archive size of the backup that was deferred
+// because it is bigger than the confirmation threshold. It is the low level
+// error of the ErrorCodeApprovalRequired.
+type ApprovalError struct {
+	Size      int64
+	Threshold int64
+}
+
+// Error returns the sizes in a human readable format.
+func (a ApprovalError) Error() string {
+	return fmt.Sprintf("archive of %d bytes above the threshold of %d bytes", a.Size, a.Threshold)
+}
+
+// confirmUpload checks if the archive can be uploaded. Archives bigger than the
+// confirmation threshold are only uploaded when approved, otherwise the backup
+// is deferred. The archive is built again in the next backup, so the approval
+// is checked against the current size of the data. The size of the approved
+// archive is returned, or zero when the approval wasn't needed.
+func (t ToGlacier) confirmUpload(backupPaths []string, filename string) (int64, error) {
+	if t.ConfirmAbove <= 0 {
+		return 0, nil
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	if info.Size() <= t.ConfirmAbove {
+		return 0, nil
+	}
+
+	if t.Approval != nil {
+		approved, err := t.Approval.Approved(info.Size())
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+
+		if approved {
+			t.Logger.Infof("toglacier: upload of %d bytes approved", info.Size())
+			return info.Size(), nil
+		}
+	}
+
+	return 0, errors.WithStack(newError(backupPaths, ErrorCodeApprovalRequired, ApprovalError{
+		Size:      info.Size(),
+		Threshold: t.ConfirmAbove,
+	}))
+}
+
+// approvedUpload informs the Approval about the result of the upload of an
+// approved archive, when it keeps track of them.
+func (t ToGlacier) approvedUpload(size int64, err error) {
+	if size == 0 {
+		return
+	}
+
+	if result, ok := t.Approval.(ApprovalResult); ok {
+		result.OnUpload(size, err)
+	}
+}
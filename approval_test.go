@@ -0,0 +1,152 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_ConfirmAbove(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		confirmAbove  int64
+		approval      toglacier.Approval
+		expectedError error
+	}{
+		{
+			description:   "it should send the backup when there's no threshold",
+			expectedError: errors.New("upload attempted"),
+		},
+		{
+			description:   "it should send the backup smaller than the threshold",
+			confirmAbove:  10,
+			expectedError: errors.New("upload attempted"),
+		},
+		{
+			description:  "it should defer the backup bigger than the threshold without approval",
+			confirmAbove: 5,
+			expectedError: &toglacier.Error{
+				Paths: []string{"data"},
+				Code:  toglacier.ErrorCodeApprovalRequired,
+				Err:   toglacier.ApprovalError{Size: 10, Threshold: 5},
+			},
+		},
+		{
+			description:  "it should defer the backup that wasn't approved",
+			confirmAbove: 5,
+			approval: mockApproval{
+				mockApproved: func(size int64) (bool, error) {
+					return false, nil
+				},
+			},
+			expectedError: &toglacier.Error{
+				Paths: []string{"data"},
+				Code:  toglacier.ErrorCodeApprovalRequired,
+				Err:   toglacier.ApprovalError{Size: 10, Threshold: 5},
+			},
+		},
+		{
+			description:  "it should send the approved backup",
+			confirmAbove: 5,
+			approval: mockApproval{
+				mockApproved: func(size int64) (bool, error) {
+					if size != 10 {
+						t.Errorf("unexpected size %d", size)
+					}
+					return true, nil
+				},
+				mockOnUpload: func(size int64, err error) {
+					// the approval is only discarded when the backup is sent
+					if size != 10 || err == nil || err.Error() != "upload attempted" {
+						t.Errorf("unexpected upload result %d, %v", size, err)
+					}
+				},
+			},
+			expectedError: errors.New("upload attempted"),
+		},
+		{
+			description:  "it should detect an error while checking the approval",
+			confirmAbove: 5,
+			approval: mockApproval{
+				mockApproved: func(size int64) (bool, error) {
+					return false, errors.New("approval failure")
+				},
+			},
+			expectedError: errors.New("approval failure"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Archive: mockArchive{
+					mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+						f, err := ioutil.TempFile("", "toglacier-test")
+						if err != nil {
+							t.Fatalf("error creating temporary file. details: %s", err)
+						}
+						defer f.Close()
+
+						if _, err := f.WriteString("0123456789"); err != nil {
+							t.Fatalf("error writing temporary file. details: %s", err)
+						}
+
+						return f.Name(), archive.Info{
+							"data": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusNew,
+								Checksum: "84d89877f0d4041efb6bf91a16f0248f2fd573e6af05c19f96bedb9f882f7882",
+							},
+						}, nil
+					},
+				},
+				// the upload is interrupted as soon as it is attempted
+				Cloud: mockCloud{
+					mockSend: func(filename, comment string) (cloud.Backup, error) {
+						return cloud.Backup{}, errors.New("upload attempted")
+					},
+				},
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return nil, nil
+					},
+				},
+				Logger: mockLogger{
+					mockDebug:    func(args ...interface{}) {},
+					mockDebugf:   func(format string, args ...interface{}) {},
+					mockInfo:     func(args ...interface{}) {},
+					mockInfof:    func(format string, args ...interface{}) {},
+					mockWarning:  func(args ...interface{}) {},
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+				ConfirmAbove: scenario.confirmAbove,
+				Approval:     scenario.approval,
+			}
+
+			err := toGlacier.Backup([]string{"data"}, "", 0, nil, "")
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+type mockApproval struct {
+	mockApproved func(size int64) (bool, error)
+	mockOnUpload func(size int64, err error)
+}
+
+func (m mockApproval) Approved(size int64) (bool, error) {
+	return m.mockApproved(size)
+}
+
+func (m mockApproval) OnUpload(size int64, err error) {
+	m.mockOnUpload(size, err)
+}
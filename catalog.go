@@ -0,0 +1,313 @@
+package toglacier
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// catalog is the small archive sent after each backup with the information
+// needed to rebuild the local storage without downloading the backups. Each
+// catalog is complete, storing the backup and all the previous backups of the
+// route, so the local storage is rebuilt retrieving only the newest catalog,
+// and no catalog depends on another one. Older versions chained the catalogs,
+// where each catalog stored only its backup and referenced the previous
+// catalogs.
+type catalog struct {
+	Backup   storage.Backup  `json:"backup"`
+	Backups  storage.Backups `json:"backups,omitempty"`
+	Catalogs []string        `json:"catalogs,omitempty"`
+}
+
+// sendCatalog uploads the catalog of the backup, returning the catalog
+// identifier in the cloud. The other backups of the route are stored together,
+// so the catalog alone rebuilds the local storage. As the backup was already
+// sent, a failure here only means that the local storage can't be rebuilt from
+// this catalog, so it doesn't stop the backup.
+func (t ToGlacier) sendCatalog(backup storage.Backup, backups storage.Backups, backupSecret string) string {
+	c := catalog{Backup: backup}
+	for _, previousBackup := range backups {
+		if previousBackup.Backup.ID != backup.Backup.ID {
+			c.Backups = append(c.Backups, previousBackup)
+		}
+	}
+
+	filename, err := t.writeCatalog(c, backupSecret)
+	if err != nil {
+		t.Logger.Warningf("toglacier: failed to build the catalog of backup “%s”. details: %s", backup.Backup.ID, err)
+		return ""
+	}
+	defer os.Remove(filename)
+
+	catalogBackup, err := t.Cloud.SendCatalog(t.Context, filename, backup.Backup.ID)
+	if err != nil {
+		t.Logger.Warningf("toglacier: failed to send the catalog of backup “%s”. details: %s", backup.Backup.ID, err)
+		return ""
+	}
+
+	return catalogBackup.ID
+}
+
+func (t ToGlacier) writeCatalog(c catalog, backupSecret string) (string, error) {
+	return t.writeJSON(c, "toglacier-catalog-", backupSecret, ErrorCodeEncodingCatalog)
+}
+
+// writeJSON encodes the value in a temporary file, encrypting it if the
+// backupSecret is informed. Encoding problems are reported with the given
+// error code.
+func (t ToGlacier) writeJSON(v interface{}, pattern, backupSecret string, code ErrorCode) (string, error) {
+	file, err := ioutil.TempFile("", pattern)
+	if err != nil {
+		return "", errors.WithStack(newError(nil, code, err))
+	}
+	defer file.Close()
+
+	if err = json.NewEncoder(file).Encode(v); err != nil {
+		os.Remove(file.Name())
+		return "", errors.WithStack(newError(nil, code, err))
+	}
+
+	if backupSecret == "" {
+		return file.Name(), nil
+	}
+
+	encryptedFilename, err := t.Envelop.Encrypt(file.Name(), backupSecret)
+	if err != nil {
+		os.Remove(file.Name())
+		return "", errors.WithStack(err)
+	}
+
+	if err = os.Rename(encryptedFilename, file.Name()); err != nil {
+		os.Remove(file.Name())
+		return "", errors.WithStack(err)
+	}
+
+	return file.Name(), nil
+}
+
+// BootstrapCatalog rebuilds the local storage from the catalogs sent after each
+// backup, retrieving the backups records and archive information without
+// downloading the backups. Only the catalog identified by id is retrieved, as
+// it stores all the previous backups (catalogs of older versions are chained,
+// retrieving the previous catalogs at once). If id is empty the remote backups
+// are listed, the newest catalog is used and the backups removed after it was
+// sent are ignored. Each route has its own chain of catalogs, so an informed id
+// must be stored in the default cloud, and without id the newest catalog of
+// each route is used. If the catalogs are encrypted they can be decrypted if
+// the backupSecret is informed. The restored backups are recorded in the audit
+// log of the destructive operations. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) BootstrapCatalog(id, backupSecret string) (err error) {
+	var restored []string
+	defer func() {
+		if len(restored) > 0 || err != nil {
+			t.recordOperation("bootstrap catalog", restored, err)
+		}
+	}()
+
+	if id != "" {
+		restored, err = t.bootstrapCatalog(id, backupSecret, nil)
+		return errors.WithStack(err)
+	}
+
+	backups, err := t.listBackups(true, 0, false)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// backups and catalogs can be removed after the newest catalog was sent, so
+	// only the archives found remotely should be restored
+	remoteArchives := make(map[string]bool)
+	newestCatalogs := make(map[*Route]string)
+	var routes []*Route
+
+	for _, backup := range backups {
+		remoteArchives[backup.Backup.ID] = true
+		if backup.Backup.CatalogID == "" {
+			continue
+		}
+		remoteArchives[backup.Backup.CatalogID] = true
+
+		// the newest backup is always in the first position
+		route := t.vaultRoute(backup.Backup.VaultName)
+		if _, ok := newestCatalogs[route]; !ok {
+			newestCatalogs[route] = backup.Backup.CatalogID
+			routes = append(routes, route)
+		}
+	}
+
+	if len(routes) == 0 {
+		return errors.WithStack(newError(nil, ErrorCodeCatalogNotFound, nil))
+	}
+
+	for _, route := range routes {
+		routed := t
+		if route != nil {
+			routed.Cloud = route.Cloud
+		}
+
+		routeRestored, err := routed.bootstrapCatalog(newestCatalogs[route], backupSecret, remoteArchives)
+		restored = append(restored, routeRestored...)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+// bootstrapCatalog rebuilds the local storage from the catalog identified by
+// id, returning the identifiers of the restored backups. When remoteArchives is
+// informed, only the backups and the chained catalogs on it are restored.
+func (t ToGlacier) bootstrapCatalog(id, backupSecret string, remoteArchives map[string]bool) ([]string, error) {
+	filenames, err := t.Cloud.Get(t.Context, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	newest, err := t.readCatalog(filenames[id], backupSecret)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	newest.Backup.Backup.CatalogID = id
+	backups := append(storage.Backups{newest.Backup}, newest.Backups...)
+
+	chained, err := t.chainedCatalogs(id, newest, backupSecret, remoteArchives)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	backups = append(backups, chained...)
+
+	if remoteArchives != nil {
+		// the kept backups could reference the archives of the removed backups,
+		// that are replaced like in the backup removal
+		var removedIDs []string
+		for _, backup := range backups {
+			if !remoteArchives[backup.Backup.ID] {
+				removedIDs = append(removedIDs, backup.Backup.ID)
+			}
+		}
+
+		for _, removedID := range removedIDs {
+			t.Logger.Debugf("toglacier: backup “%s” of catalog “%s” was removed, ignoring it", removedID, id)
+			t.rearrangeBackups(backups, removedID)
+		}
+	}
+
+	var restored []string
+	for _, backup := range backups {
+		if remoteArchives != nil && !remoteArchives[backup.Backup.ID] {
+			continue
+		}
+
+		if err = t.Storage.Save(t.Context, backup); err != nil {
+			return restored, errors.WithStack(err)
+		}
+
+		restored = append(restored, backup.Backup.ID)
+		t.Logger.Debugf("toglacier: backup “%s” restored from catalog “%s”", backup.Backup.ID, id)
+	}
+
+	return restored, nil
+}
+
+// chainedCatalogs retrieves the previous catalogs referenced by a catalog sent
+// by older versions, returning their backups. When remoteArchives is informed,
+// only the catalogs on it are retrieved.
+func (t ToGlacier) chainedCatalogs(id string, newest catalog, backupSecret string, remoteArchives map[string]bool) (storage.Backups, error) {
+	var previousIDs []string
+	for _, previousID := range newest.Catalogs {
+		if previousID == id || (remoteArchives != nil && !remoteArchives[previousID]) {
+			continue
+		}
+
+		previousIDs = append(previousIDs, previousID)
+	}
+
+	if len(previousIDs) == 0 {
+		return nil, nil
+	}
+
+	filenames, err := t.Cloud.Get(t.Context, previousIDs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var backups storage.Backups
+	for catalogID, filename := range filenames {
+		c, err := t.readCatalog(filename, backupSecret)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		c.Backup.Backup.CatalogID = catalogID
+		backups = append(backups, c.Backup)
+	}
+
+	return backups, nil
+}
+
+func (t ToGlacier) readCatalog(filename, backupSecret string) (catalog, error) {
+	var c catalog
+	if err := t.readJSON(filename, backupSecret, &c, ErrorCodeDecodingCatalog); err != nil {
+		return catalog{}, errors.WithStack(err)
+	}
+
+	return c, nil
+}
+
+// readJSON decodes the file in the value, decrypting it first if the
+// backupSecret is informed. The file is always removed. Decoding problems are
+// reported with the given error code.
+func (t ToGlacier) readJSON(filename, backupSecret string, v interface{}, code ErrorCode) error {
+	// after reading the content we don't need the file anymore, but if there's
+	// some error removing it we don't want to stop the process
+	defer func() {
+		if err := os.Remove(filename); err != nil {
+			t.Logger.Warningf("toglacier: failed to remove file “%s”. details: %s", filename, err)
+		}
+	}()
+
+	if backupSecret != "" {
+		decryptedFilename, err := t.Envelop.Decrypt(filename, backupSecret)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if err = os.Rename(decryptedFilename, filename); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return errors.WithStack(newError(nil, code, err))
+	}
+
+	if err = json.Unmarshal(content, v); err != nil {
+		return errors.WithStack(newError(nil, code, err))
+	}
+
+	return nil
+}
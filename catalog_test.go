@@ -0,0 +1,489 @@
+package toglacier_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/log"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_BootstrapCatalog(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		id            string
+		backupSecret  string
+		cloud         cloud.Cloud
+		storage       storage.Storage
+		envelop       archive.Envelop
+		logger        log.Logger
+		expected      map[string]string
+		expectedError error
+	}{
+		{
+			description: "it should restore all backups from the chained catalogs",
+			id:          "CATALOG3",
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (map[string]string, error) {
+					sort.Strings(ids)
+
+					switch fmt.Sprintf("%v", ids) {
+					case "[CATALOG3]":
+						return map[string]string{
+							"CATALOG3": writeCatalogTestFile(t, `{"backup":{"Backup":{"ID":"123458"}},"catalogs":["CATALOG2","CATALOG1"]}`),
+						}, nil
+
+					case "[CATALOG1 CATALOG2]":
+						return map[string]string{
+							"CATALOG1": writeCatalogTestFile(t, `{"backup":{"Backup":{"ID":"123456"}}}`),
+							"CATALOG2": writeCatalogTestFile(t, `{"backup":{"Backup":{"ID":"123457"}},"catalogs":["CATALOG1"]}`),
+						}, nil
+					}
+
+					return nil, fmt.Errorf("unexpected ids %v", ids)
+				},
+			},
+			storage: &mockStorageRecorder{},
+			logger: mockLogger{
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expected: map[string]string{
+				"123456": "CATALOG1",
+				"123457": "CATALOG2",
+				"123458": "CATALOG3",
+			},
+		},
+		{
+			description: "it should restore from the newest remote catalog ignoring removed catalogs",
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{
+							ID:        "123457",
+							CreatedAt: time.Date(2017, 9, 20, 0, 0, 0, 0, time.UTC),
+							CatalogID: "CATALOG2",
+						},
+						{
+							ID:        "123458",
+							CreatedAt: time.Date(2017, 9, 21, 0, 0, 0, 0, time.UTC),
+							CatalogID: "CATALOG3",
+						},
+					}, nil
+				},
+				mockGet: func(ids ...string) (map[string]string, error) {
+					switch fmt.Sprintf("%v", ids) {
+					case "[CATALOG3]":
+						return map[string]string{
+							"CATALOG3": writeCatalogTestFile(t, `{"backup":{"Backup":{"ID":"123458"}},"catalogs":["CATALOG2","CATALOG1"]}`),
+						}, nil
+
+					case "[CATALOG2]":
+						return map[string]string{
+							"CATALOG2": writeCatalogTestFile(t, `{"backup":{"Backup":{"ID":"123457"}},"catalogs":["CATALOG1"]}`),
+						}, nil
+					}
+
+					return nil, fmt.Errorf("unexpected ids %v", ids)
+				},
+			},
+			storage: &mockStorageRecorder{},
+			logger: mockLogger{
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expected: map[string]string{
+				"123457": "CATALOG2",
+				"123458": "CATALOG3",
+			},
+		},
+		{
+			description: "it should decrypt the catalogs when there's a backup secret",
+			id:          "CATALOG1",
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (map[string]string, error) {
+					return map[string]string{
+						"CATALOG1": writeCatalogTestFile(t, "encrypted content"),
+					}, nil
+				},
+			},
+			backupSecret: "abc123",
+			envelop: mockEnvelop{
+				mockDecrypt: func(encryptedFilename, secret string) (string, error) {
+					if secret != "abc123" {
+						return "", fmt.Errorf("unexpected secret “%s”", secret)
+					}
+
+					return writeCatalogTestFile(t, `{"backup":{"Backup":{"ID":"123456"}}}`), nil
+				},
+			},
+			storage: &mockStorageRecorder{},
+			logger: mockLogger{
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expected: map[string]string{
+				"123456": "CATALOG1",
+			},
+		},
+		{
+			description: "it should detect when there's no catalog in the cloud",
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{
+							ID:        "123456",
+							CreatedAt: time.Date(2017, 9, 20, 0, 0, 0, 0, time.UTC),
+						},
+					}, nil
+				},
+			},
+			storage: &mockStorageRecorder{},
+			logger: mockLogger{
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeCatalogNotFound,
+			},
+		},
+		{
+			description: "it should detect an error retrieving the catalog",
+			id:          "CATALOG1",
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (map[string]string, error) {
+					return nil, errors.New("error retrieving catalog")
+				},
+			},
+			storage: &mockStorageRecorder{},
+			logger: mockLogger{
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("error retrieving catalog"),
+		},
+		{
+			description: "it should detect an invalid catalog",
+			id:          "CATALOG1",
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (map[string]string, error) {
+					return map[string]string{
+						"CATALOG1": writeCatalogTestFile(t, "{{{"),
+					}, nil
+				},
+			},
+			storage: &mockStorageRecorder{},
+			logger: mockLogger{
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeDecodingCatalog,
+				Err:  errors.New("invalid character '{' looking for beginning of object key string"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud,
+				Storage: scenario.storage,
+				Envelop: scenario.envelop,
+				Logger:  scenario.logger,
+			}
+
+			err := toGlacier.BootstrapCatalog(scenario.id, scenario.backupSecret)
+
+			if recorder, ok := scenario.storage.(*mockStorageRecorder); ok && scenario.expected != nil {
+				if !reflect.DeepEqual(scenario.expected, recorder.saved) {
+					t.Errorf("saved backups don't match. expected “%v” and got “%v”", scenario.expected, recorder.saved)
+				}
+			}
+
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_BootstrapCatalogAfterRemoval(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarning:  func(args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	scenarios := []struct {
+		description      string
+		remove           string
+		expectedBackups  []string
+		expectedCatalogs map[string]string
+		expectedFileB    string
+	}{
+		{
+			description:     "it should restore the backups after removing an older backup",
+			remove:          "ARCHIVE2",
+			expectedBackups: []string{"ARCHIVE1", "ARCHIVE3"},
+			expectedCatalogs: map[string]string{
+				"CATALOG1": "ARCHIVE1",
+				"CATALOG3": "ARCHIVE3",
+			},
+			// the reference to the removed backup is replaced by the older version
+			// of the file
+			expectedFileB: "ARCHIVE1",
+		},
+		{
+			description:     "it should restore the backups after removing the newest backup",
+			remove:          "ARCHIVE3",
+			expectedBackups: []string{"ARCHIVE1", "ARCHIVE2"},
+			expectedCatalogs: map[string]string{
+				"CATALOG1": "ARCHIVE1",
+				"CATALOG2": "ARCHIVE2",
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			files := fstest.MapFS{
+				"data/a.txt": &fstest.MapFile{Data: []byte("content of a"), Mode: 0600},
+				"data/b.txt": &fstest.MapFile{Data: []byte("content of b"), Mode: 0600},
+			}
+
+			builder := archive.NewZIPBuilder(logger)
+			builder.FileSystem = archive.NewIOFileSystem(files)
+
+			memory := newMemoryCloud()
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Archive: builder,
+				Cloud:   memory,
+				Storage: storage.NewAuditFile(logger, path.Join(dir, "audit.log")),
+				Logger:  logger,
+			}
+
+			for i, change := range []func(){
+				func() {},
+				func() { files["data/b.txt"] = &fstest.MapFile{Data: []byte("new content of b"), Mode: 0600} },
+				func() { files["data/c.txt"] = &fstest.MapFile{Data: []byte("content of c"), Mode: 0600} },
+			} {
+				change()
+				if err = toGlacier.Backup([]string{"data"}, "", 0, nil, ""); err != nil {
+					t.Fatalf("unexpected error sending backup %d. details: %s", i+1, err)
+				}
+			}
+
+			if err = toGlacier.RemoveBackups(scenario.remove); err != nil {
+				t.Fatalf("unexpected error removing the backup. details: %s", err)
+			}
+
+			// the local storage is lost and rebuilt from the catalogs
+			toGlacier.Storage = storage.NewAuditFile(logger, path.Join(dir, "rebuilt.log"))
+			memory.retrievals = 0
+
+			if err = toGlacier.BootstrapCatalog("", ""); err != nil {
+				t.Fatalf("unexpected error rebuilding the local storage. details: %s", err)
+			}
+
+			// only the newest catalog is retrieved
+			if memory.retrievals != 1 {
+				t.Errorf("unexpected number of retrievals “%d”", memory.retrievals)
+			}
+
+			backups, err := toGlacier.Storage.List(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error listing the backups. details: %s", err)
+			}
+
+			var ids []string
+			for _, backup := range backups {
+				ids = append(ids, backup.Backup.ID)
+				if backup.Info == nil {
+					t.Errorf("backup “%s” without archive information", backup.Backup.ID)
+				}
+
+				for filename, itemInfo := range backup.Info {
+					if itemInfo.ID == scenario.remove {
+						t.Errorf("file “%s” of backup “%s” references the removed backup", filename, backup.Backup.ID)
+					}
+
+					if scenario.expectedFileB != "" && filename == "data/b.txt" && itemInfo.ID != scenario.expectedFileB {
+						t.Errorf("file “%s” of backup “%s” references “%s”", filename, backup.Backup.ID, itemInfo.ID)
+					}
+				}
+			}
+			sort.Strings(ids)
+
+			if !reflect.DeepEqual(scenario.expectedBackups, ids) {
+				t.Errorf("backups don't match. expected “%v” and got “%v”", scenario.expectedBackups, ids)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedCatalogs, memory.catalogs) {
+				t.Errorf("catalogs don't match. expected “%v” and got “%v”", scenario.expectedCatalogs, memory.catalogs)
+			}
+		})
+	}
+}
+
+// memoryCloud keeps the archives sent in memory, identifying the backups and
+// the catalogs in the order that they were sent.
+type memoryCloud struct {
+	mockCloud
+
+	backups    map[string]cloud.Backup
+	catalogs   map[string]string
+	contents   map[string][]byte
+	retrievals int
+}
+
+func newMemoryCloud() *memoryCloud {
+	return &memoryCloud{
+		backups:  make(map[string]cloud.Backup),
+		catalogs: make(map[string]string),
+		contents: make(map[string][]byte),
+	}
+}
+
+func (m *memoryCloud) Send(ctx context.Context, filename, comment string) (cloud.Backup, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return cloud.Backup{}, err
+	}
+
+	backup := cloud.Backup{
+		ID:        fmt.Sprintf("ARCHIVE%d", len(m.backups)+1),
+		CreatedAt: time.Date(2017, 9, 20, len(m.backups), 0, 0, 0, time.UTC),
+		Size:      int64(len(content)),
+		VaultName: "test",
+		Location:  cloud.LocationAWS,
+	}
+
+	m.backups[backup.ID] = backup
+	m.contents[backup.ID] = content
+	return backup, nil
+}
+
+func (m *memoryCloud) SendCatalog(ctx context.Context, filename, backupID string) (cloud.Backup, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return cloud.Backup{}, err
+	}
+
+	id := strings.Replace(backupID, "ARCHIVE", "CATALOG", 1)
+	m.catalogs[id] = backupID
+	m.contents[id] = content
+
+	backup := m.backups[backupID]
+	backup.CatalogID = id
+	m.backups[backupID] = backup
+
+	return cloud.Backup{ID: id}, nil
+}
+
+func (m *memoryCloud) List(ctx context.Context) ([]cloud.Backup, error) {
+	var backups []cloud.Backup
+	for _, backup := range m.backups {
+		backups = append(backups, backup)
+	}
+	return backups, nil
+}
+
+func (m *memoryCloud) Get(ctx context.Context, ids ...string) (map[string]string, error) {
+	m.retrievals++
+
+	filenames := make(map[string]string)
+	for _, id := range ids {
+		content, ok := m.contents[id]
+		if !ok {
+			return nil, fmt.Errorf("archive “%s” not found", id)
+		}
+
+		f, err := ioutil.TempFile("", "toglacier-test")
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = f.Write(content)
+		f.Close()
+
+		if err != nil {
+			return nil, err
+		}
+		filenames[id] = f.Name()
+	}
+
+	return filenames, nil
+}
+
+func (m *memoryCloud) Remove(ctx context.Context, id string) error {
+	if _, ok := m.contents[id]; !ok {
+		return fmt.Errorf("archive “%s” not found", id)
+	}
+
+	delete(m.backups, id)
+	delete(m.catalogs, id)
+	delete(m.contents, id)
+	return nil
+}
+
+// mockStorageRecorder keeps track of the catalog of each saved backup,
+// answering the other storage operations with empty results.
+type mockStorageRecorder struct {
+	mockStorage
+	saved map[string]string
+}
+
+func (m *mockStorageRecorder) Save(ctx context.Context, b storage.Backup) error {
+	if m.saved == nil {
+		m.saved = make(map[string]string)
+	}
+	m.saved[b.Backup.ID] = b.Backup.CatalogID
+	return nil
+}
+
+func (m *mockStorageRecorder) List(ctx context.Context) (storage.Backups, error) {
+	return nil, nil
+}
+
+func (m *mockStorageRecorder) SaveInventoryDate(ctx context.Context, date time.Time) error {
+	return nil
+}
+
+func writeCatalogTestFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary file. details: %s", err)
+	}
+	defer f.Close()
+
+	if _, err = f.WriteString(content); err != nil {
+		t.Fatalf("error writing temporary file. details: %s", err)
+	}
+
+	return f.Name()
+}
@@ -0,0 +1,29 @@
+package toglacier
+
+import "time"
+
+// clearChanges forgets the changes detected before the backup started, as they
+// are stored in the backups. While there are archives in the spool the changes
+// are kept, because the next backup is compared with the backups already sent
+// and the spooled files must be analyzed again. The copies waiting for the
+// replica don't hold the changes, as their backups were already sent.
+func (t ToGlacier) clearChanges(startedAt time.Time) {
+	if t.Changes == nil {
+		return
+	}
+
+	if t.Spool != nil {
+		spooledArchives, err := t.Spool.List(t.Context)
+		if err != nil {
+			return
+		}
+
+		for _, spooled := range spooledArchives {
+			if spooled.ReplicaOf == "" {
+				return
+			}
+		}
+	}
+
+	t.Changes.Clear(startedAt)
+}
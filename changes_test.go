@@ -0,0 +1,91 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_Backup_Changes(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		buildErr      error
+		spooled       []storage.SpooledArchive
+		expectedClear bool
+	}{
+		{
+			description:   "it should clear the changes after the backup",
+			expectedClear: true,
+		},
+		{
+			description: "it should keep the changes when the backup fails",
+			buildErr:    errors.New("disk failure"),
+		},
+		{
+			description: "it should keep the changes while there are spooled archives",
+			spooled:     []storage.SpooledArchive{{ID: "1", Comment: "old dump"}},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			startedAt := time.Now()
+
+			var cleared bool
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Archive: mockArchive{
+					mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+						return "", nil, scenario.buildErr
+					},
+				},
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return nil, nil
+					},
+				},
+				Spool: mockSpool{
+					mockList: func() ([]storage.SpooledArchive, error) {
+						return scenario.spooled, nil
+					},
+				},
+				Changes: mockChanges{
+					mockClear: func(until time.Time) {
+						if until.Before(startedAt) || until.After(time.Now()) {
+							t.Errorf("unexpected clear moment %s", until)
+						}
+						cleared = true
+					},
+				},
+				Logger: mockLogger{
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			toGlacier.Backup([]string{"/data"}, "", 0, nil, "")
+
+			if cleared != scenario.expectedClear {
+				t.Errorf("unexpected clear of the changes: %t", cleared)
+			}
+		})
+	}
+}
+
+type mockChanges struct {
+	mockModified func(path string) bool
+	mockClear    func(until time.Time)
+}
+
+func (m mockChanges) Modified(path string) bool {
+	return m.mockModified(path)
+}
+
+func (m mockChanges) Clear(until time.Time) {
+	m.mockClear(until)
+}
@@ -0,0 +1,108 @@
+package toglacier
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+// BackupCheck is the result of comparing the files of a backup with the local
+// filesystem.
+type BackupCheck struct {
+	// Unmodified is the number of files with the same content of the backup.
+	Unmodified int
+
+	// Modified files with a content different from the backup, sorted by name.
+	Modified []string
+
+	// Missing files that aren't in the local filesystem anymore, sorted by
+	// name.
+	Missing []string
+}
+
+// Drifted informs if the local filesystem doesn't match the backup anymore.
+func (b BackupCheck) Drifted() bool {
+	return len(b.Modified) > 0 || len(b.Missing) > 0
+}
+
+// CheckBackup compares the checksums of the local files with the ones stored
+// in the local storage for the backup. When the local storage doesn't have the
+// archive information of the backup, it is retrieved from the cloud (catalog or
+// archive), decrypted with the backupSecret. The files that were already
+// removed when the backup was built are ignored. When the backup doesn't exist,
+// is a backup of a stream or its archive information can't be found it will
+// return an Error type encapsulated in a traceable error, and when a file can't
+// be read it will return an archive.PathError type encapsulated in a traceable
+// error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *archive.PathError:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) CheckBackup(id, backupSecret string) (BackupCheck, error) {
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		return BackupCheck{}, errors.WithStack(err)
+	}
+
+	selectedBackup, ok := backups.Search(id)
+	if !ok {
+		return BackupCheck{}, errors.WithStack(newError(nil, ErrorCodeBackupNotFound, fmt.Errorf("backup “%s” isn't in the local storage", id)))
+	}
+
+	if selectedBackup.Backup.Stream != "" {
+		return BackupCheck{}, errors.WithStack(newError([]string{selectedBackup.Backup.Stream}, ErrorCodeStreamBackup, nil))
+	}
+
+	archiveInfo, err := t.backupInfo(selectedBackup, backups, backupSecret)
+	if err != nil {
+		return BackupCheck{}, errors.WithStack(err)
+	}
+
+	var check BackupCheck
+	for path, itemInfo := range archiveInfo {
+		if itemInfo.Status == archive.ItemInfoStatusDeleted {
+			continue
+		}
+
+		checksum, err := t.Archive.FileChecksum(path)
+		if errors.Is(err, os.ErrNotExist) {
+			check.Missing = append(check.Missing, path)
+			continue
+
+		} else if err != nil {
+			return BackupCheck{}, errors.WithStack(err)
+		}
+
+		if checksum != itemInfo.Checksum {
+			check.Modified = append(check.Modified, path)
+		} else {
+			check.Unmodified++
+		}
+	}
+
+	sort.Strings(check.Modified)
+	sort.Strings(check.Missing)
+
+	t.Logger.Infof("toglacier: backup “%s” checked against the local files: %d unmodified, %d modified and %d missing",
+		id, check.Unmodified, len(check.Modified), len(check.Missing))
+
+	return check, nil
+}
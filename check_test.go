@@ -0,0 +1,214 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_CheckBackup(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	backups := storage.Backups{
+		{
+			Backup: cloud.Backup{ID: "AWSID123", CreatedAt: now},
+			Info: archive.Info{
+				"/data/a.txt": archive.ItemInfo{ID: "AWSID123", Status: archive.ItemInfoStatusModified, Checksum: "checksum-a"},
+				"/data/b.txt": archive.ItemInfo{ID: "AWSID122", Status: archive.ItemInfoStatusUnmodified, Checksum: "checksum-b"},
+				"/data/c.txt": archive.ItemInfo{ID: "AWSID123", Status: archive.ItemInfoStatusNew, Checksum: "checksum-c"},
+				"/data/d.txt": archive.ItemInfo{ID: "AWSID123", Status: archive.ItemInfoStatusNew, Checksum: "checksum-d"},
+				"/data/e.txt": archive.ItemInfo{ID: "AWSID121", Status: archive.ItemInfoStatusDeleted, Checksum: "checksum-e"},
+			},
+		},
+		{
+			Backup: cloud.Backup{ID: "AWSID124", CreatedAt: now, Stream: "db.sql"},
+			Info: archive.Info{
+				"db.sql": archive.ItemInfo{ID: "AWSID124", Status: archive.ItemInfoStatusStream},
+			},
+		},
+		{
+			// synchronized from the cloud inventory, without archive information
+			Backup: cloud.Backup{ID: "AWSID125", CreatedAt: now, CatalogID: "CATALOG125"},
+		},
+		{
+			Backup: cloud.Backup{ID: "AWSID126", CreatedAt: now},
+		},
+	}
+
+	notFound := func(filename string) error {
+		return &archive.PathError{
+			Path: filename,
+			Code: archive.PathErrorCodeOpeningFile,
+			Err:  &os.PathError{Op: "open", Path: filename, Err: syscall.ENOENT},
+		}
+	}
+
+	scenarios := []struct {
+		description   string
+		id            string
+		listError     error
+		checksums     map[string]string
+		checksumError error
+		get           func(ids ...string) (map[string]string, error)
+		expectedSaved []string
+		expected      toglacier.BackupCheck
+		expectedError error
+	}{
+		{
+			description: "it should detect the modified and missing files",
+			id:          "AWSID123",
+			checksums: map[string]string{
+				"/data/a.txt": "checksum-a",
+				"/data/b.txt": "checksum-b2",
+				"/data/c.txt": "checksum-c",
+			},
+			expected: toglacier.BackupCheck{
+				Unmodified: 2,
+				Modified:   []string{"/data/b.txt"},
+				Missing:    []string{"/data/d.txt"},
+			},
+		},
+		{
+			description: "it should detect when the local files match the backup",
+			id:          "AWSID123",
+			checksums: map[string]string{
+				"/data/a.txt": "checksum-a",
+				"/data/b.txt": "checksum-b",
+				"/data/c.txt": "checksum-c",
+				"/data/d.txt": "checksum-d",
+			},
+			expected: toglacier.BackupCheck{
+				Unmodified: 4,
+			},
+		},
+		{
+			description: "it should detect when the backup doesn't exist",
+			id:          "AWSID999",
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeBackupNotFound,
+				Err:  errors.New("backup “AWSID999” isn't in the local storage"),
+			},
+		},
+		{
+			description: "it should refuse to check a backup of a stream",
+			id:          "AWSID124",
+			expectedError: &toglacier.Error{
+				Paths: []string{"db.sql"},
+				Code:  toglacier.ErrorCodeStreamBackup,
+			},
+		},
+		{
+			description: "it should retrieve the missing archive information from the catalog",
+			id:          "AWSID125",
+			checksums: map[string]string{
+				"/data/a.txt": "checksum-a",
+			},
+			get: func(ids ...string) (map[string]string, error) {
+				if len(ids) != 1 || ids[0] != "CATALOG125" {
+					return nil, fmt.Errorf("unexpected archives “%v”", ids)
+				}
+
+				return map[string]string{
+					"CATALOG125": writeCatalogTestFile(t, `{"backup":{"Backup":{"ID":"AWSID125"},"Info":{"/data/a.txt":{"ID":"AWSID125","Status":"new","Checksum":"checksum-a"},"/data/b.txt":{"ID":"AWSID125","Status":"new","Checksum":"checksum-b"}}}}`),
+				}, nil
+			},
+			expectedSaved: []string{"AWSID125"},
+			expected: toglacier.BackupCheck{
+				Unmodified: 1,
+				Missing:    []string{"/data/b.txt"},
+			},
+		},
+		{
+			description: "it should not report success when the archive information can't be retrieved",
+			id:          "AWSID126",
+			get: func(ids ...string) (map[string]string, error) {
+				return nil, errors.New("connection error")
+			},
+			expectedError: errors.New("connection error"),
+		},
+		{
+			description:   "it should detect an error listing the backups",
+			id:            "AWSID123",
+			listError:     errors.New("database corrupted"),
+			expectedError: errors.New("database corrupted"),
+		},
+		{
+			description:   "it should detect an error reading a local file",
+			id:            "AWSID123",
+			checksumError: errors.New("permission denied"),
+			expectedError: errors.New("permission denied"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var saved []string
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud: mockCloud{
+					mockGet: scenario.get,
+				},
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return backups, scenario.listError
+					},
+					mockSave: func(backup storage.Backup) error {
+						saved = append(saved, backup.Backup.ID)
+						return nil
+					},
+					mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+						return storage.RestoreProgress{}, nil
+					},
+					mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+						return nil
+					},
+				},
+				Archive: mockArchive{
+					mockFileChecksum: func(filename string) (string, error) {
+						if scenario.checksumError != nil {
+							return "", scenario.checksumError
+						}
+
+						checksum, ok := scenario.checksums[filename]
+						if !ok {
+							return "", notFound(filename)
+						}
+						return checksum, nil
+					},
+				},
+				Logger: mockLogger{
+					mockInfof:    func(format string, args ...interface{}) {},
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			check, err := toGlacier.CheckBackup(scenario.id, "")
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, check) {
+				t.Errorf("checks don't match.\n%s", Diff(scenario.expected, check))
+			}
+
+			if !reflect.DeepEqual(scenario.expectedSaved, saved) {
+				t.Errorf("saved backups don't match.\n%s", Diff(scenario.expectedSaved, saved))
+			}
+
+			if drifted := len(scenario.expected.Modified)+len(scenario.expected.Missing) > 0; drifted != check.Drifted() {
+				t.Errorf("drift doesn't match. expected “%t” and got “%t”", drifted, check.Drifted())
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -70,6 +71,7 @@ func commandConvert(c *cli.Context) error {
 		return nil
 	}
 
+	ctx := context.Background()
 	var fromStorage, toStorage storage.Storage
 
 	switch from.value {
@@ -81,7 +83,7 @@ func commandConvert(c *cli.Context) error {
 		fmt.Printf("unknown “from” storage “%s”\n", from.value)
 	}
 
-	backups, err := fromStorage.List()
+	backups, err := fromStorage.List(ctx)
 	if err != nil {
 		fmt.Printf("error reading backups. details: %s", err)
 		return nil
@@ -102,7 +104,7 @@ func commandConvert(c *cli.Context) error {
 	}
 
 	for _, backup := range backups {
-		if err := toStorage.Save(backup); err != nil {
+		if err := toStorage.Save(ctx, backup); err != nil {
 			fmt.Printf("error saving backup “%s”. details: %s", backup.Backup.ID, err)
 			return nil
 		}
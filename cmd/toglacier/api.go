@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/config"
+)
+
+// selfSignedValidity is the period that a generated self-signed certificate is
+// valid. An expired certificate is generated again when the listener starts.
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// apiListener describes an HTTP endpoint of the scheduler. The requests are
+// authenticated with the bearer tokens of the configuration, so the endpoint
+// uses TLS unless it only accepts connections from the local machine.
+type apiListener struct {
+	// name identifies the endpoint in the logs and in the default names of the
+	// self-signed certificate files.
+	name string
+
+	address    string
+	cert       string
+	key        string
+	selfSigned bool
+}
+
+// tls returns the certificate and the private key files used by the
+// listener, or empty names when the requests are received in plain HTTP. With
+// the self-signed option the certificate is generated when it doesn't exist
+// yet (or expired), by default next to the local storage. As the tokens would
+// be sent in plain text, the plain HTTP is only accepted in the loopback
+// interface.
+//
+// On error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a apiListener) tls() (cert, key string, err error) {
+	cert, key = a.cert, a.key
+	if a.selfSigned && cert == "" && key == "" {
+		prefix := fmt.Sprintf("%s.%s", cfg.Database.File, a.name)
+		cert, key = prefix+".crt", prefix+".key"
+	}
+
+	if (cert == "") != (key == "") {
+		return "", "", errors.WithStack(newError(a.address, ErrorCodeTLSPair, nil))
+	}
+
+	if cert == "" {
+		if !loopbackAddress(a.address) {
+			return "", "", errors.WithStack(newError(a.address, ErrorCodePlainText, nil))
+		}
+		return "", "", nil
+	}
+
+	if a.selfSigned {
+		if err = a.selfSignedCertificate(cert, key); err != nil {
+			return "", "", errors.WithStack(newError(a.address, ErrorCodeCertificate, err))
+		}
+	}
+
+	return cert, key, nil
+}
+
+// selfSignedCertificate generates a self-signed certificate for the listener
+// address, the hostname and the loopback interface, unless the files already
+// have a valid certificate. The fingerprint is logged so the clients can pin
+// the certificate.
+func (a apiListener) selfSignedCertificate(certFile, keyFile string) error {
+	if pair, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if cert, err := x509.ParseCertificate(pair.Certificate[0]); err == nil && time.Now().Before(cert.NotAfter) {
+			return nil
+		}
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: "toglacier " + a.name},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+
+	if host, _, err := net.SplitHostPort(a.address); err == nil && host != "" {
+		if ip := net.ParseIP(host); ip == nil {
+			template.DNSNames = append(template.DNSNames, host)
+		} else if !ip.IsUnspecified() {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		}
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0644); err != nil {
+		return errors.WithStack(err)
+	}
+
+	fingerprint := sha256.Sum256(certDER)
+	logger.Infof("toglacier: self-signed certificate of the %s generated in “%s” with SHA-256 fingerprint %s", a.name, certFile, hex.EncodeToString(fingerprint[:]))
+	return nil
+}
+
+// loopbackAddress checks if the address only accepts connections from the
+// local machine.
+func loopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// authorizeRequest checks if the request uses the POST method and a bearer
+// token that grants the scope, answering the request when it doesn't.
+func authorizeRequest(response http.ResponseWriter, request *http.Request, scope config.Scope) (config.Token, bool) {
+	if request.Method != http.MethodPost {
+		response.Header().Set("Allow", http.MethodPost)
+		http.Error(response, "only the POST method is allowed", http.StatusMethodNotAllowed)
+		return config.Token{}, false
+	}
+
+	bearer := request.Header.Get("Authorization")
+	if !strings.HasPrefix(bearer, "Bearer ") {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(response, "missing bearer token", http.StatusUnauthorized)
+		return config.Token{}, false
+	}
+
+	token, ok := cfg.FindToken(strings.TrimPrefix(bearer, "Bearer "))
+	if !ok {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(response, "invalid bearer token", http.StatusUnauthorized)
+		return config.Token{}, false
+	}
+
+	if !token.Allows(scope) {
+		http.Error(response, fmt.Sprintf("token “%s” requires the scope “%s”", token.Name, scope), http.StatusForbidden)
+		return config.Token{}, false
+	}
+
+	return token, true
+}
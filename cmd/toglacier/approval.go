@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/urfave/cli"
+)
+
+// approvalValidity limits the time that an approval waits for the next backup,
+// so a forgotten approval doesn't allow a big upload days later.
+const approvalValidity = 24 * time.Hour
+
+// approvalCommand is the command informed in the notification to approve a
+// deferred backup.
+const approvalCommand = "toglacier approve"
+
+// approvalFilename returns the file where the approval of the next big backup
+// is stored.
+func approvalFilename() string {
+	return cfg.Database.File + ".approve"
+}
+
+// deferralFilename returns the file that remembers the size of the backup
+// deferred until approved.
+func deferralFilename() string {
+	return cfg.Database.File + ".deferred"
+}
+
+// uploadApproval approves the backups above the confirmation threshold. When
+// interactive the user is asked, otherwise the approval left by the approve
+// command (or the webhook) is used, and each approval sends a single backup.
+type uploadApproval struct {
+	filename    string
+	interactive bool
+}
+
+// claimedFilename returns where the approval is kept while the approved backup
+// is sent.
+func (u uploadApproval) claimedFilename() string {
+	return u.filename + ".claimed"
+}
+
+// Approved checks if the upload of the archive can happen.
+func (u uploadApproval) Approved(size int64) (bool, error) {
+	if u.interactive {
+		fmt.Printf("send a backup of %d bytes, above the confirmation threshold? [y/N] ", size)
+
+		answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes", nil
+	}
+
+	// the file is claimed before the approval is used, so concurrent backups
+	// (routes) can't use the same approval
+	if err := os.Rename(u.filename, u.claimedFilename()); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	content, err := ioutil.ReadFile(u.claimedFilename())
+	if err != nil {
+		return false, err
+	}
+
+	until, err := time.Parse(time.RFC3339, strings.TrimSpace(string(content)))
+	if err != nil || time.Now().After(until) {
+		os.Remove(u.claimedFilename())
+		return false, err
+	}
+
+	return true, nil
+}
+
+// OnUpload discards the approval and the pending deferral after the approved
+// backup is sent. When the upload fails the approval is given back, so the
+// next backup can use it.
+func (u uploadApproval) OnUpload(size int64, err error) {
+	if err != nil {
+		if !u.interactive {
+			if err := os.Rename(u.claimedFilename(), u.filename); err != nil {
+				logger.Warningf("toglacier: failed to keep the approval for the next backup. details: %s", err)
+			}
+		}
+		return
+	}
+
+	if !u.interactive {
+		if err := os.Remove(u.claimedFilename()); err != nil && !os.IsNotExist(err) {
+			logger.Warningf("toglacier: failed to remove the used approval. details: %s", err)
+		}
+	}
+
+	if err := os.Remove(deferralFilename()); err != nil && !os.IsNotExist(err) {
+		logger.Warningf("toglacier: failed to remove the deferred backup. details: %s", err)
+	}
+}
+
+// pendingDeferral returns the size of the backup deferred until approved, when
+// it wasn't approved yet. The archive isn't built again, and the alert isn't
+// sent again, until the approval is given.
+func pendingDeferral() (int64, bool) {
+	content, err := ioutil.ReadFile(deferralFilename())
+	if err != nil {
+		return 0, false
+	}
+
+	if _, err = os.Stat(approvalFilename()); err == nil {
+		return 0, false
+	}
+
+	size, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return size, true
+}
+
+// waitingApproval checks if the backup is still deferred, logging it.
+func waitingApproval() bool {
+	size, ok := pendingDeferral()
+	if ok {
+		logger.Infof("toglacier: backup of %d bytes still waiting for the approval with “%s”", size, approvalCommand)
+	}
+
+	return ok
+}
+
+// approveNextBackup stores the approval for the next backup above the
+// confirmation threshold.
+func approveNextBackup() (time.Time, error) {
+	until := time.Now().Add(approvalValidity)
+	if err := ioutil.WriteFile(approvalFilename(), []byte(until.Format(time.RFC3339)+"\n"), 0600); err != nil {
+		return time.Time{}, err
+	}
+
+	return until, nil
+}
+
+// interactive checks if the standard input is a terminal, so the user can be
+// asked to confirm the upload.
+func interactive() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// notifyApproval sends the notification asking to approve the deferred backup,
+// remembering it so the next backups wait for the approval. It returns false
+// when the error isn't a deferred backup.
+func notifyApproval(err error) bool {
+	var approvalErr toglacier.ApprovalError
+	if !errors.Is(err, toglacier.ErrApprovalRequired) || !errors.As(err, &approvalErr) {
+		return false
+	}
+
+	logger.Warningf("toglacier: backup of %d bytes deferred until approved with “%s”", approvalErr.Size, approvalCommand)
+
+	// the next backups wait for the approval without building the archive
+	if err := ioutil.WriteFile(deferralFilename(), []byte(strconv.FormatInt(approvalErr.Size, 10)+"\n"), 0600); err != nil {
+		logger.Warningf("toglacier: failed to remember the deferred backup. details: %s", err)
+	}
+
+	approval := report.NewApproval(approvalErr.Size, approvalErr.Threshold, approvalCommand)
+	if err := toGlacier.SendAlert(currentEmailInfo(), approval); err != nil {
+		logger.Error(err)
+	}
+
+	return true
+}
+
+func commandApprove(c *cli.Context) error {
+	until, err := approveNextBackup()
+	if err != nil {
+		logger.Error(err)
+		exitCode = exitCodeFailure
+		return nil
+	}
+
+	fmt.Printf("the next backup above the confirmation threshold will be sent, approval valid until %s\n", until.Format("2006-01-02 15:04:05"))
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// maxBlackout limits the merged blackout windows, as windows that always
+// overlap (misconfiguration) would never close.
+const maxBlackout = 31 * 24 * time.Hour
+
+// blackoutUntil returns when the blackout windows open at the given moment
+// close. Windows that open before the others close are merged, so the
+// scheduled actions aren't released inside a window. The windows follow the
+// timezone of the schedulers.
+func blackoutUntil(now time.Time) (until time.Time, ok bool) {
+	now = now.In(cfg.Scheduler.Timezone.Location())
+	until = now
+	for until.Sub(now) < maxBlackout {
+		extended := false
+		for _, blackout := range cfg.Blackouts {
+			if windowUntil, open := blackout.Until(until); open && windowUntil.After(until) {
+				until = windowUntil
+				extended = true
+			}
+		}
+
+		if !extended {
+			break
+		}
+	}
+
+	return until, until.After(now)
+}
+
+// blackoutJob defers the scheduled action while a blackout window is open,
+// running it once when the window closes. Activations while the action is
+// deferred are dropped, as the deferred run covers them.
+type blackoutJob struct {
+	action   string
+	job      cron.Job
+	deferred int32
+}
+
+// deferInBlackout wraps the scheduled action so it respects the blackout
+// windows of the configuration.
+func deferInBlackout(action string, job cron.Job) cron.Job {
+	if len(cfg.Blackouts) == 0 {
+		return job
+	}
+
+	return &blackoutJob{action: action, job: job}
+}
+
+func (b *blackoutJob) Run() {
+	if !atomic.CompareAndSwapInt32(&b.deferred, 0, 1) {
+		logger.Infof("toglacier: action “%s” already deferred by a blackout window", b.action)
+		return
+	}
+	defer atomic.StoreInt32(&b.deferred, 0)
+
+	for {
+		until, ok := blackoutUntil(time.Now())
+		if !ok {
+			break
+		}
+
+		logger.Infof("toglacier: action “%s” deferred until %s by a blackout window", b.action, until.Format("2006-01-02 15:04:05"))
+
+		select {
+		case <-time.After(until.Sub(time.Now())):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	b.job.Run()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+)
+
+// controlInterval is how often the running operations are published and the
+// cancellation requests are checked.
+const controlInterval = 2 * time.Second
+
+// runningFilename returns the file where the operations in progress are
+// stored, so the cancel command (another process) can list them.
+func runningFilename() string {
+	return cfg.Database.File + ".running"
+}
+
+// cancelFilename returns the file where the cancel command requests the
+// cancellation of the operations, one identifier per line.
+func cancelFilename() string {
+	return cfg.Database.File + ".cancel"
+}
+
+// controlOperations publishes the running operations and cancels the ones
+// requested by the cancel command until the returned function is called.
+// Failures are only logged, as they don't affect the operations.
+func controlOperations() (stop func()) {
+	if toGlacier == nil || toGlacier.Running == nil {
+		return func() {}
+	}
+
+	// requests left by a previous process could match the identifiers of the
+	// new operations
+	os.Remove(cancelFilename())
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(controlInterval)
+		defer ticker.Stop()
+
+		for {
+			cancelRequested()
+			writeRunning(toGlacier.Running.List())
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+
+		os.Remove(runningFilename())
+		os.Remove(cancelFilename())
+	}
+}
+
+// cancelRequested cancels the operations listed in the cancel file, removing
+// it afterwards.
+func cancelRequested() {
+	content, err := ioutil.ReadFile(cancelFilename())
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		logger.Warningf("toglacier: failed to read the cancellation requests. details: %s", err)
+		return
+	}
+
+	if err = os.Remove(cancelFilename()); err != nil {
+		logger.Warningf("toglacier: failed to remove the cancellation requests. details: %s", err)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		id := strings.TrimSpace(scanner.Text())
+		if id == "" {
+			continue
+		}
+
+		if toGlacier.Running.Cancel(id) {
+			logger.Infof("toglacier: operation “%s” cancelled", id)
+		} else {
+			logger.Warningf("toglacier: operation “%s” not running, cancellation ignored", id)
+		}
+	}
+}
+
+// writeRunning stores the running operations, removing the file when there's
+// nothing running.
+func writeRunning(operations []toglacier.RunningOperation) {
+	if len(operations) == 0 {
+		os.Remove(runningFilename())
+		return
+	}
+
+	content, err := json.Marshal(operations)
+	if err != nil {
+		logger.Warningf("toglacier: failed to encode the running operations. details: %s", err)
+		return
+	}
+
+	if err = ioutil.WriteFile(runningFilename(), content, 0600); err != nil {
+		logger.Warningf("toglacier: failed to store the running operations. details: %s", err)
+	}
+}
+
+// readRunning returns the operations in progress in the other process.
+func readRunning() ([]toglacier.RunningOperation, error) {
+	content, err := ioutil.ReadFile(runningFilename())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var operations []toglacier.RunningOperation
+	if err = json.Unmarshal(content, &operations); err != nil {
+		return nil, err
+	}
+
+	return operations, nil
+}
+
+// requestCancel asks the process running the operation to cancel it.
+func requestCancel(id string) error {
+	f, err := os.OpenFile(cancelFilename(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.WriteString(id + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
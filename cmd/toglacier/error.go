@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrorCodeTLSPair the certificate or the private key is missing to use
+	// TLS.
+	ErrorCodeTLSPair ErrorCode = "tls-pair"
+
+	// ErrorCodePlainText plain HTTP requested outside the loopback interface.
+	ErrorCodePlainText ErrorCode = "plain-text"
+
+	// ErrorCodeCertificate error generating the self-signed certificate.
+	ErrorCodeCertificate ErrorCode = "certificate"
+
+	// ErrorCodeWebhookTokens webhook enabled without tokens to authenticate the
+	// requests.
+	ErrorCodeWebhookTokens ErrorCode = "webhook-tokens"
+
+	// ErrorCodeListen error listening in the address.
+	ErrorCodeListen ErrorCode = "listen"
+)
+
+// ErrorCode stores the error type that occurred while serving the HTTP API.
+type ErrorCode string
+
+// String translate the error code to a human readable text.
+func (e ErrorCode) String() string {
+	switch e {
+	case ErrorCodeTLSPair:
+		return "both the certificate and the private key are required to use TLS"
+	case ErrorCodePlainText:
+		return "plain HTTP is only allowed in the loopback interface, as the tokens would be sent in plain text"
+	case ErrorCodeCertificate:
+		return "error generating the self-signed certificate"
+	case ErrorCodeWebhookTokens:
+		return "webhook requires tokens to authenticate the requests"
+	case ErrorCodeListen:
+		return "error listening in the address"
+	}
+
+	return "unknown error code"
+}
+
+// Error stores error details from a problem occurred while serving the HTTP
+// API.
+type Error struct {
+	Address string
+	Code    ErrorCode
+	Err     error
+}
+
+func newError(address string, code ErrorCode, err error) *Error {
+	return &Error{
+		Address: address,
+		Code:    code,
+		Err:     errors.WithStack(err),
+	}
+}
+
+// Error returns the error in a human readable format.
+func (e Error) Error() string {
+	return e.String()
+}
+
+// Unwrap returns the low level error, so it can be inspected with errors.Is and
+// errors.As.
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// String translate the error to a human readable text.
+func (e Error) String() string {
+	var address string
+	if e.Address != "" {
+		address = fmt.Sprintf("address “%s”, ", e.Address)
+	}
+
+	var err string
+	if e.Err != nil {
+		err = fmt.Sprintf(". details: %s", e.Err)
+	}
+
+	return fmt.Sprintf("api: %s%s%s", address, e.Code, err)
+}
+
+// ErrorEqual compares two Error objects. This is useful to compare down to the
+// low level errors.
+func ErrorEqual(first, second error) bool {
+	if first == nil || second == nil {
+		return first == second
+	}
+
+	var err1, err2 *Error
+	if !errors.As(first, &err1) || !errors.As(second, &err2) {
+		return false
+	}
+
+	if err1.Address != err2.Address || err1.Code != err2.Code {
+		return false
+	}
+
+	errCause1 := errors.Cause(err1.Err)
+	errCause2 := errors.Cause(err2.Err)
+
+	if errCause1 == nil || errCause2 == nil {
+		return errCause1 == errCause2
+	}
+
+	return errCause1.Error() == errCause2.Error()
+}
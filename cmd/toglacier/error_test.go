@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestError_Error(t *testing.T) {
+	scenarios := []struct {
+		description string
+		err         *Error
+		expected    string
+	}{
+		{
+			description: "it should show the message with the address and the low level error",
+			err: &Error{
+				Address: "0.0.0.0:8443",
+				Code:    ErrorCodeCertificate,
+				Err:     errors.New("low level error"),
+			},
+			expected: "api: address “0.0.0.0:8443”, error generating the self-signed certificate. details: low level error",
+		},
+		{
+			description: "it should show the correct error message for a missing certificate or private key",
+			err:         &Error{Code: ErrorCodeTLSPair},
+			expected:    "api: both the certificate and the private key are required to use TLS",
+		},
+		{
+			description: "it should show the correct error message for plain HTTP outside the loopback interface",
+			err:         &Error{Code: ErrorCodePlainText},
+			expected:    "api: plain HTTP is only allowed in the loopback interface, as the tokens would be sent in plain text",
+		},
+		{
+			description: "it should show the correct error message for a self-signed certificate problem",
+			err:         &Error{Code: ErrorCodeCertificate},
+			expected:    "api: error generating the self-signed certificate",
+		},
+		{
+			description: "it should show the correct error message for a webhook without tokens",
+			err:         &Error{Code: ErrorCodeWebhookTokens},
+			expected:    "api: webhook requires tokens to authenticate the requests",
+		},
+		{
+			description: "it should show the correct error message for a listening problem",
+			err:         &Error{Code: ErrorCodeListen},
+			expected:    "api: error listening in the address",
+		},
+		{
+			description: "it should detect when the code doesn't exist",
+			err:         &Error{Code: ErrorCode("i-dont-exist")},
+			expected:    "api: unknown error code",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if msg := scenario.err.Error(); msg != scenario.expected {
+				t.Errorf("errors don't match. expected “%s” and got “%s”", scenario.expected, msg)
+			}
+		})
+	}
+}
+
+func TestErrorEqual(t *testing.T) {
+	scenarios := []struct {
+		description string
+		err1        error
+		err2        error
+		expected    bool
+	}{
+		{
+			description: "it should detect equal Error instances",
+			err1: &Error{
+				Address: "0.0.0.0:8443",
+				Code:    ErrorCodeCertificate,
+				Err:     errors.New("low level error"),
+			},
+			err2: &Error{
+				Address: "0.0.0.0:8443",
+				Code:    ErrorCodeCertificate,
+				Err:     errors.New("low level error"),
+			},
+			expected: true,
+		},
+		{
+			description: "it should detect when the address is different",
+			err1: &Error{
+				Address: "0.0.0.0:8443",
+				Code:    ErrorCodeCertificate,
+			},
+			err2: &Error{
+				Address: "127.0.0.1:8443",
+				Code:    ErrorCodeCertificate,
+			},
+			expected: false,
+		},
+		{
+			description: "it should detect when the code is different",
+			err1: &Error{
+				Code: ErrorCodeCertificate,
+				Err:  errors.New("low level error"),
+			},
+			err2: &Error{
+				Code: ErrorCode("unknown-error"),
+				Err:  errors.New("low level error"),
+			},
+			expected: false,
+		},
+		{
+			description: "it should detect when the low level error is different",
+			err1: &Error{
+				Code: ErrorCodeCertificate,
+				Err:  errors.New("low level error 1"),
+			},
+			err2: &Error{
+				Code: ErrorCodeCertificate,
+				Err:  errors.New("low level error 2"),
+			},
+			expected: false,
+		},
+		{
+			description: "it should detect when both errors are undefined",
+			expected:    true,
+		},
+		{
+			description: "it should detect when only one error is undefined",
+			err1: &Error{
+				Code: ErrorCodeCertificate,
+				Err:  errors.New("low level error"),
+			},
+			expected: false,
+		},
+		{
+			description: "it should detect when both causes of the error are undefined",
+			err1:        &Error{Code: ErrorCodeTLSPair},
+			err2:        &Error{Code: ErrorCodeTLSPair},
+			expected:    true,
+		},
+		{
+			description: "it should detect when only one causes of the error is undefined",
+			err1: &Error{
+				Code: ErrorCodeCertificate,
+				Err:  errors.New("low level error"),
+			},
+			err2: &Error{
+				Code: ErrorCodeCertificate,
+			},
+			expected: false,
+		},
+		{
+			description: "it should detect when one the error isn't Error type",
+			err1: &Error{
+				Code: ErrorCodeCertificate,
+				Err:  errors.New("low level error"),
+			},
+			err2:     errors.New("low level error"),
+			expected: false,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if equal := ErrorEqual(scenario.err1, scenario.err2); equal != scenario.expected {
+				t.Errorf("results don't match. expected “%t” and got “%t”", scenario.expected, equal)
+			}
+		})
+	}
+}
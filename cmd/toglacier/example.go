@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"github.com/rafaeljusto/toglacier/internal/config"
+	"github.com/urfave/cli"
+)
+
+// servicePlatform describes how the scheduler (start command) is registered
+// as a service in each platform.
+type servicePlatform struct {
+	description string
+	executable  string
+	config      string
+	template    *template.Template
+}
+
+var servicePlatforms = map[string]servicePlatform{
+	"linux": {
+		description: "systemd unit, save it as /etc/systemd/system/toglacier.service and run “systemctl enable --now toglacier”",
+		executable:  "/usr/local/bin/toglacier",
+		config:      "/etc/toglacier.yml",
+		template: template.Must(template.New("linux").Parse(`[Unit]
+Description=toglacier - Periodic send backups to the cloud
+Requires=network.target
+After=network.target
+
+[Service]
+Type=simple
+ExecStart={{.Executable}} -c {{.Config}} start
+Restart=on-failure
+RestartSec=10
+StartLimitInterval=10m
+StartLimitBurst=5
+User=toglacier
+
+[Install]
+WantedBy=multi-user.target
+`)),
+	},
+	"windows": {
+		description: "Task Scheduler task starting with the system, run it in an administrator prompt",
+		executable:  `C:\Program Files\toglacier\toglacier.exe`,
+		config:      `C:\ProgramData\toglacier\toglacier.yml`,
+		template: template.Must(template.New("windows").Parse(
+			`schtasks /Create /TN toglacier /SC ONSTART /RU SYSTEM /RL HIGHEST /F /TR "\"{{.Executable}}\" -c \"{{.Config}}\" start"
+`)),
+	},
+}
+
+// commandConfigExample shows the configuration file with the default values,
+// followed by the service definition of the platform in comments, so the
+// output can be saved directly as the configuration file.
+func commandConfigExample(c *cli.Context) error {
+	platformName := c.String("platform")
+	if platformName == "" {
+		platformName = runtime.GOOS
+	}
+
+	platform, ok := servicePlatforms[platformName]
+	if !ok {
+		fmt.Printf("unsupported platform “%s”, it should be linux or windows\n", platformName)
+		exitCode = exitCodeConfigError
+		return nil
+	}
+
+	data := struct {
+		Executable string
+		Config     string
+	}{
+		Executable: platform.executable,
+		Config:     platform.config,
+	}
+
+	// on the target platform the current installation is used
+	if platformName == runtime.GOOS {
+		if executable, err := os.Executable(); err == nil {
+			data.Executable = executable
+		}
+	}
+
+	if configFile := c.GlobalString("config"); configFile != "" {
+		data.Config = configFile
+	}
+
+	var service bytes.Buffer
+	if err := platform.template.Execute(&service, data); err != nil {
+		logger.Errorf("error generating the service definition. details: %s", err)
+		return nil
+	}
+
+	fmt.Print(string(config.Example()))
+	fmt.Println()
+	fmt.Printf("# %s:\n", platform.description)
+	fmt.Println("#")
+	for _, line := range strings.Split(strings.TrimSuffix(service.String(), "\n"), "\n") {
+		fmt.Println(strings.TrimRight("#   "+line, " "))
+	}
+
+	return nil
+}
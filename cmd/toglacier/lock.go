@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// lockPollInterval is the time between the attempts to acquire the lock when
+// waiting for other process.
+const lockPollInterval = time.Second
+
+// errLocked is returned when other process is holding the lock.
+var errLocked = errors.New("other toglacier process is using the same local storage")
+
+// lockFilename returns the file used to allow only one toglacier process per
+// local storage. BoltDB already uses the ".lock" suffix on Windows, so we need
+// a different one.
+func lockFilename() string {
+	return cfg.Database.File + ".instance"
+}
+
+// acquireLock locks the file at the OS level, so it is released even when the
+// process crashes. With wait it will block until the other process releases the
+// lock or the context is cancelled. The returned function releases the lock.
+func acquireLock(filename string, wait bool) (release func(), err error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	for waiting := false; ; waiting = true {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		if locked {
+			return func() { file.Close() }, nil
+		}
+
+		if !wait {
+			file.Close()
+			return nil, errLocked
+		}
+
+		if !waiting {
+			logger.Infof("toglacier: %s, waiting for it to finish", errLocked)
+		}
+
+		select {
+		case <-time.After(lockPollInterval):
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// exclusive runs the action only when there's no other toglacier process using
+// the same local storage, as mixing the scheduler with external schedulers
+// (cron) could corrupt the database. In stateless mode the local storage is
+// also synchronized with the cloud around the action.
+func exclusive(action func(*cli.Context) error) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		if c.GlobalBool("force") {
+			logger.Warningf("toglacier: running without the lock, other toglacier process could be using the same local storage")
+			return stateful(action)(c)
+		}
+
+		release, err := acquireLock(lockFilename(), c.GlobalBool("wait"))
+		if err != nil {
+			fmt.Printf("error locking the local storage. details: %s\n", err)
+			exitCode = exitCodeFailure
+			return nil
+		}
+		defer release()
+
+		// only the process holding the lock publishes its operations, so the
+		// control files aren't shared
+		stop := controlOperations()
+		defer stop()
+
+		return stateful(action)(c)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/config"
+)
+
+// logModules links the modules of the configuration with the modules of the
+// library.
+var logModules = map[config.LogModule]toglacier.Module{
+	config.LogModuleArchive: toglacier.ModuleArchive,
+	config.LogModuleCloud:   toglacier.ModuleCloud,
+	config.LogModuleStorage: toglacier.ModuleStorage,
+}
+
+// logLevel converts the log level of the configuration to the logrus level.
+func logLevel(level config.LogLevel) logrus.Level {
+	switch level {
+	case config.LogLevelDebug:
+		return logrus.DebugLevel
+	case config.LogLevelInfo:
+		return logrus.InfoLevel
+	case config.LogLevelWarning:
+		return logrus.WarnLevel
+	case config.LogLevelFatal:
+		return logrus.FatalLevel
+	case config.LogLevelPanic:
+		return logrus.PanicLevel
+	}
+
+	return logrus.ErrorLevel
+}
+
+// moduleLogger creates a logger with its own level for a module, writing to
+// the output and hooks of the main logger.
+func moduleLogger(level config.LogLevel) *logrus.Logger {
+	return &logrus.Logger{
+		Out:       loggerOutput{},
+		Hooks:     logger.Hooks,
+		Formatter: logger.Formatter,
+		Level:     logLevel(level),
+	}
+}
+
+// loggerOutput writes to the current output of the main logger, that is
+// replaced by some commands to keep their output clean.
+type loggerOutput struct{}
+
+func (loggerOutput) Write(p []byte) (int, error) {
+	return logger.Out.Write(p)
+}
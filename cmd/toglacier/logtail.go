@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// logTail keeps the last log lines of the running action in a ring buffer, so
+// they can be attached to the alert e-mail when the action fails.
+type logTail struct {
+	lock  sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+// newLogTail creates a log hook that keeps up to size log lines. With a size
+// of zero no lines are kept.
+func newLogTail(size int) *logTail {
+	if size < 0 {
+		size = 0
+	}
+
+	return &logTail{
+		lines: make([]string, size),
+	}
+}
+
+// Levels returns all log levels, as the logger already filters the entries
+// with the configured level.
+func (l *logTail) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire stores the log entry, overwriting the oldest line when the buffer is
+// full.
+func (l *logTail) Fire(entry *logrus.Entry) error {
+	if len(l.lines) == 0 {
+		return nil
+	}
+
+	line := fmt.Sprintf("%s [%s] %s",
+		entry.Time.Format("2006-01-02 15:04:05"),
+		entry.Level,
+		strings.TrimSpace(entry.Message),
+	)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.lines[l.next] = line
+	l.next = (l.next + 1) % len(l.lines)
+	if l.next == 0 {
+		l.full = true
+	}
+
+	return nil
+}
+
+// Lines returns the stored log lines, from the oldest to the newest.
+func (l *logTail) Lines() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !l.full {
+		return append([]string(nil), l.lines[:l.next]...)
+	}
+
+	lines := make([]string, 0, len(l.lines))
+	lines = append(lines, l.lines[l.next:]...)
+	return append(lines, l.lines[:l.next]...)
+}
+
+// Reset forgets the stored log lines when a new action starts.
+func (l *logTail) Reset() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.next = 0
+	l.full = false
+}
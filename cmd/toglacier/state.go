@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/urfave/cli"
+)
+
+// stateLock serializes the saves of the state, as the scheduled actions and
+// the webhook can change the local storage at the same time.
+var stateLock sync.Mutex
+
+// stateful loads the local storage from the cloud before the action and saves
+// it back after it, when the stateless mode is enabled. This allows running
+// toglacier without a persistent disk (e.g. Kubernetes CronJob). The errors
+// are logged, as the standard output can be the content of a backup (get
+// --stdout).
+func stateful(action func(*cli.Context) error) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		if !cfg.Database.Stateless {
+			return action(c)
+		}
+
+		if !loadState() {
+			exitCode = exitCodeFailure
+			return nil
+		}
+
+		err := action(c)
+
+		if !saveState() {
+			exitCode = exitCodeFailure
+		}
+
+		return err
+	}
+}
+
+// stateLoaded loads the local storage from the cloud before the action when
+// the stateless mode is enabled, without saving it back, as the action only
+// reads the local storage.
+func stateLoaded(action func(*cli.Context) error) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		if cfg.Database.Stateless && !loadState() {
+			exitCode = exitCodeFailure
+			return nil
+		}
+
+		return action(c)
+	}
+}
+
+// loadState replaces the local storage with the state stored in the cloud. It
+// returns false when the state couldn't be loaded.
+func loadState() bool {
+	if err := toGlacier.LoadState(cfg.BackupSecret.Value); err != nil {
+		logger.Errorf("toglacier: error loading the local storage from the cloud. details: %s", err)
+		return false
+	}
+
+	return true
+}
+
+// saveState stores the local storage in the cloud when the stateless mode is
+// enabled. The scheduler saves it after each action, as a container can be
+// killed without stopping the scheduler gracefully. It returns false when the
+// state couldn't be saved.
+func saveState() bool {
+	if !cfg.Database.Stateless {
+		return true
+	}
+
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	if err := toGlacier.SaveState(cfg.BackupSecret.Value); err != nil {
+		logger.Errorf("toglacier: error saving the local storage in the cloud. details: %s", err)
+		return false
+	}
+
+	return true
+}
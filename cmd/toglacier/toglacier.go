@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -8,8 +9,12 @@ import (
 	"io/ioutil"
 	"net/smtp"
 	"os"
+	"os/user"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Sirupsen/logrus"
@@ -17,23 +22,47 @@ import (
 	"github.com/rafaeljusto/toglacier/internal/archive"
 	"github.com/rafaeljusto/toglacier/internal/cloud"
 	"github.com/rafaeljusto/toglacier/internal/config"
+	"github.com/rafaeljusto/toglacier/internal/proxy"
 	"github.com/rafaeljusto/toglacier/internal/report"
-	"github.com/rafaeljusto/toglacier/internal/storage"
 	"github.com/robfig/cron"
 	"github.com/urfave/cli"
 )
 
 var (
-	toGlacier  toglacier.ToGlacier
+	cfg        *config.Config
+	toGlacier  *toglacier.ToGlacier
 	logger     *logrus.Logger
+	runLog     *logTail
 	logFile    *os.File
 	ctx        context.Context
 	cancel     context.CancelFunc
 	cancelFunc func()
+	exitCode   int
+	backupLock sync.Mutex
+)
+
+// exit codes of the program, so external schedulers (cron, systemd timers) can
+// react to the result of each run.
+const (
+	exitCodeSuccess     = 0
+	exitCodePartial     = 1
+	exitCodeFailure     = 2
+	exitCodeConfigError = 3
+	exitCodeNotApproved = 4
 )
 
 func main() {
-	defer logFile.Close()
+	os.Exit(run())
+}
+
+// run executes the command, returning the exit code only after the deferred
+// cleanup (log file, lock and control files) is done, as os.Exit doesn't run
+// the deferred functions.
+func run() int {
+	// the log file is opened later, by the initialize function
+	defer func() {
+		logFile.Close()
+	}()
 
 	// ctx is used to abort long transactions, such as big files uploads or
 	// inventories
@@ -55,6 +84,35 @@ func main() {
 			Name:  "config, c",
 			Usage: "tool configuration file (YAML)",
 		},
+		cli.StringFlag{
+			Name:  "data-dir",
+			Usage: "directory of the local storage, instead of the per-user data directory",
+		},
+		cli.BoolFlag{
+			Name:  "strict",
+			Usage: "reject unknown attributes in the configuration file, instead of ignoring them",
+		},
+		cli.BoolFlag{
+			Name:  "wait",
+			Usage: "wait for other toglacier process using the same local storage to finish",
+		},
+		cli.BoolFlag{
+			Name:  "force",
+			Usage: "run even when other toglacier process is using the same local storage",
+		},
+		cli.BoolFlag{
+			Name:  "trace",
+			Usage: "log the requests and responses of the cloud, without the credentials",
+		},
+		cli.StringFlag{
+			Name:   "token",
+			Usage:  "token granting the commands on a shared machine, when the configuration has tokens",
+			EnvVar: "TOGLACIER_TOKEN",
+		},
+		cli.BoolFlag{
+			Name:  "read-only",
+			Usage: "disable the commands that change the backups in the cloud, only browsing and restoring",
+		},
 	}
 	app.Before = initialize
 	app.Commands = []cli.Command{
@@ -62,41 +120,184 @@ func main() {
 			Name:  "sync",
 			Usage: "backup now the desired paths to AWS Glacier",
 			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "comment,m",
+					Usage: "note stored with the backup to identify it later",
+				},
+				cli.StringFlag{
+					Name:  "stdin",
+					Usage: "backup the data read from the standard input with the given name, instead of the paths",
+				},
 				cli.BoolFlag{
 					Name:  "verbose,v",
 					Usage: "show what is happening behind the scenes",
 				},
 			},
-			Action: commandSync,
+			Action: scoped(config.ScopeBackup, exclusive(commandSync)),
 		},
 		{
 			Name:  "get",
-			Usage: "retrieve a specific backup from AWS Glacier",
+			Usage: "retrieve a specific backup (or the latest one) from AWS Glacier",
 			Flags: []cli.Flag{
 				cli.BoolFlag{
 					Name:  "skip-unmodified,s",
 					Usage: "ignore files unmodified in disk since the backup",
 				},
+				cli.StringFlag{
+					Name:  "path,p",
+					Usage: "restore only the files matching the glob pattern (e.g. /home/user/documents/*.odt)",
+				},
+				cli.BoolFlag{
+					Name:  "stdout",
+					Usage: "write the retrieved backup to the standard output, instead of extracting it",
+				},
+				cli.StringFlag{
+					Name:  "conflict",
+					Value: archive.OverwriteReplace.String(),
+					Usage: "what happens with the existing files: overwrite, skip, rename (keeping a copy with the " + archive.ExistingBackupSuffix + " suffix) or newer-only",
+				},
+				cli.BoolFlag{
+					Name:  "no-overwrite",
+					Usage: "keep the existing files, restoring only the missing ones (same as --conflict skip)",
+				},
+				cli.BoolFlag{
+					Name:  "backup-existing",
+					Usage: "keep a copy of the replaced files with the " + archive.ExistingBackupSuffix + " suffix (same as --conflict rename)",
+				},
+				cli.BoolFlag{
+					Name:  "all-or-nothing",
+					Usage: "keep all existing files of an archive when any retrieved file doesn't match its checksum",
+				},
 				cli.BoolFlag{
 					Name:  "verbose,v",
 					Usage: "show what is happening behind the scenes",
 				},
 			},
-			ArgsUsage: "<archiveID>",
-			Action:    commandGet,
+			ArgsUsage: "<archiveID|latest>",
+			Action:    scoped(config.ScopeRestore, exclusive(commandGet)),
+		},
+		{
+			Name:  "check",
+			Usage: "compare the local files with a backup, without retrieving it from AWS Glacier",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "verbose,v",
+					Usage: "show what is happening behind the scenes",
+				},
+			},
+			ArgsUsage: "<archiveID|latest>",
+			Action:    scoped(config.ScopeList, stateLoaded(commandCheck)),
+		},
+		{
+			Name:  "mount",
+			Usage: "expose the files of a backup in a read-only filesystem, without extracting them",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "verbose,v",
+					Usage: "show what is happening behind the scenes",
+				},
+			},
+			ArgsUsage: "<archiveID|latest> <directory>",
+			Action:    scoped(config.ScopeRestore, stateLoaded(commandMount)),
+		},
+		{
+			Name:  "jobs",
+			Usage: "list the retrieval jobs in AWS Glacier, with their age and status",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "wait,w",
+					Usage: "keep checking the jobs in progress until all of them complete",
+				},
+				cli.DurationFlag{
+					Name:  "interval,i",
+					Value: 5 * time.Minute,
+					Usage: "time between the checks of the jobs in progress when waiting",
+				},
+				cli.BoolFlag{
+					Name:  "verbose,v",
+					Usage: "show what is happening behind the scenes",
+				},
+			},
+			Action: scoped(config.ScopeList, commandJobs),
+		},
+		{
+			Name:  "bootstrap",
+			Usage: "rebuild the local storage from the newest backup catalog",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "verbose,v",
+					Usage: "show what is happening behind the scenes",
+				},
+			},
+			ArgsUsage: "[catalogID]",
+			Action:    scoped(config.ScopeAdmin, exclusive(commandBootstrap)),
 		},
 		{
 			Name:    "remove",
 			Aliases: []string{"rm"},
 			Usage:   "remove backups from AWS Glacier",
 			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "ids-file,f",
+					Usage: "read the archive ids from a file, one per line (- for the standard input)",
+				},
 				cli.BoolFlag{
 					Name:  "verbose,v",
 					Usage: "show what is happening behind the scenes",
 				},
 			},
-			ArgsUsage: "<archiveID> [archiveID ...]",
-			Action:    commandRemove,
+			ArgsUsage: "[archiveID ...]",
+			Action:    scoped(config.ScopeRemove, exclusive(commandRemove)),
+		},
+		{
+			Name:      "restore-trash",
+			Usage:     "undo the removal of a backup that is still in the trash",
+			ArgsUsage: "<archiveID>",
+			Action:    scoped(config.ScopeRemove, exclusive(commandRestoreTrash)),
+		},
+		{
+			Name:  "pin",
+			Usage: "protect a backup from the old backups removal (lists the pinned backups without id)",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "reason,r",
+					Usage: "why the backup is protected, recorded in the audit trail",
+				},
+				cli.StringFlag{
+					Name:  "until,u",
+					Usage: "date when the protection expires (2006-01-02 or RFC 3339)",
+				},
+				cli.BoolFlag{
+					Name:  "history",
+					Usage: "show the audit trail of the pinned and unpinned backups",
+				},
+			},
+			ArgsUsage: "[archiveID]",
+			Action:    scoped(config.ScopeAdmin, stateful(commandPin)),
+		},
+		{
+			Name:  "unpin",
+			Usage: "allow a pinned backup to be removed with the old backups",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "reason,r",
+					Usage: "why the protection was removed, recorded in the audit trail",
+				},
+			},
+			ArgsUsage: "<archiveID>",
+			Action:    scoped(config.ScopeAdmin, stateful(commandUnpin)),
+		},
+		{
+			Name:      "tag",
+			Usage:     "name a backup, so the name can be used instead of the archive id (lists the tags without arguments)",
+			ArgsUsage: "[archiveID name]",
+			Action:    scoped(config.ScopeAdmin, stateful(commandTag)),
+		},
+		{
+			Name:      "untag",
+			Usage:     "remove the name of a backup",
+			ArgsUsage: "<name>",
+			Action:    scoped(config.ScopeAdmin, stateful(commandUntag)),
 		},
 		{
 			Name:    "list",
@@ -107,23 +308,182 @@ func main() {
 					Name:  "remote,r",
 					Usage: "retrieve the list from AWS Glacier (long wait)",
 				},
+				cli.DurationFlag{
+					Name:  "max-age,m",
+					Usage: "accept a remote list synchronized within this period (e.g. 24h)",
+				},
+				cli.BoolFlag{
+					Name:  "all-machines",
+					Usage: "list the backups of all machines sharing the vault",
+				},
+				cli.BoolFlag{
+					Name:  "group-by-day,g",
+					Usage: "show only the newest backup of each day (default from the group by day setting)",
+				},
 				cli.BoolFlag{
 					Name:  "verbose,v",
 					Usage: "show what is happening behind the scenes",
 				},
 			},
 			ArgsUsage: "[pattern]",
-			Action:    commandList,
+			Action:    scoped(config.ScopeList, exclusive(commandList)),
+		},
+		{
+			Name:  "gc",
+			Usage: "list the archives in AWS Glacier not referenced by the local storage",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "remove",
+					Usage: "remove the orphan archives after confirmation",
+				},
+				cli.BoolFlag{
+					Name:  "yes,y",
+					Usage: "don't ask for confirmation before removing",
+				},
+				cli.BoolFlag{
+					Name:  "verbose,v",
+					Usage: "show what is happening behind the scenes",
+				},
+			},
+			Action: scoped(config.ScopeList, exclusive(commandGC)),
+		},
+		{
+			Name:  "vault",
+			Usage: "manage the retention policy of the backups vault",
+			Subcommands: []cli.Command{
+				{
+					Name:      "lock",
+					Usage:     "initiate the vault lock with a policy (must be completed within 24 hours)",
+					ArgsUsage: "<policy-file>",
+					Action:    scoped(config.ScopeAdmin, commandVaultLock),
+				},
+				{
+					Name:      "complete",
+					Usage:     "complete the vault lock, making the policy immutable",
+					ArgsUsage: "<lockID>",
+					Action:    scoped(config.ScopeAdmin, commandVaultComplete),
+				},
+				{
+					Name:   "abort",
+					Usage:  "abort a vault lock in progress",
+					Action: scoped(config.ScopeAdmin, commandVaultAbort),
+				},
+				{
+					Name:      "policy",
+					Usage:     "replace the vault access policy",
+					ArgsUsage: "<policy-file>",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "remove",
+							Usage: "remove the current access policy instead",
+						},
+					},
+					Action: scoped(config.ScopeAdmin, commandVaultPolicy),
+				},
+				{
+					Name:      "tags",
+					Usage:     "replace the vault tags (tags not informed are removed)",
+					ArgsUsage: "[key=value ...]",
+					Action:    scoped(config.ScopeAdmin, commandVaultTags),
+				},
+				{
+					Name:   "apply",
+					Usage:  "make the vault access policy and tags match the configuration",
+					Action: scoped(config.ScopeAdmin, commandVaultApply),
+				},
+			},
+		},
+		{
+			Name:  "db",
+			Usage: "manage the local storage",
+			Subcommands: []cli.Command{
+				{
+					Name:  "repair",
+					Usage: "drop obsolete and corrupted records from the local storage",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "verbose,v",
+							Usage: "show what is happening behind the scenes",
+						},
+					},
+					Action: scoped(config.ScopeAdmin, exclusive(commandDBRepair)),
+				},
+			},
+		},
+		{
+			Name:      "pause",
+			Usage:     "suspend the scheduled actions for a period (e.g. 2h) or until resumed",
+			ArgsUsage: "[duration]",
+			Action:    scoped(config.ScopeAdmin, stateful(commandPause)),
+		},
+		{
+			Name:   "resume",
+			Usage:  "restart the suspended scheduled actions",
+			Action: scoped(config.ScopeAdmin, stateful(commandResume)),
+		},
+		{
+			Name:   "approve",
+			Usage:  "allow the next backup above the confirmation threshold to be sent",
+			Action: scoped(config.ScopeBackup, stateful(commandApprove)),
+		},
+		{
+			Name:      "cancel",
+			Usage:     "cancel a running backup or retrieval, listing the running operations when no identifier is informed",
+			ArgsUsage: "[operationID]",
+			Action:    scoped(config.ScopeAdmin, stateful(commandCancel)),
+		},
+		{
+			Name:   "status",
+			Usage:  "show if the scheduled actions are suspended",
+			Action: scoped(config.ScopeList, stateLoaded(commandStatus)),
+		},
+		{
+			Name:  "audit",
+			Usage: "list the destructive operations (backups removal and local storage changes)",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "since,s",
+					Usage: "only operations recorded after the date (2006-01-02 or RFC 3339)",
+				},
+				cli.StringFlag{
+					Name:  "action,a",
+					Usage: "only operations of the action (e.g. \"remove old backups\")",
+				},
+			},
+			Action: scoped(config.ScopeList, stateLoaded(commandAudit)),
+		},
+		{
+			Name:  "start",
+			Usage: "run the scheduler (will block forever)",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "all-machines",
+					Usage: "list and report the backups of all machines sharing the vault",
+				},
+			},
+			Action: scoped(config.ScopeAdmin, exclusive(commandStart)),
 		},
 		{
-			Name:   "start",
-			Usage:  "run the scheduler (will block forever)",
-			Action: commandStart,
+			Name:  "run",
+			Usage: "run an action without the scheduler (for cron or systemd timers)",
+			Subcommands: []cli.Command{
+				{
+					Name:  "backup",
+					Usage: "backup the desired paths and send the report",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "once",
+							Usage: "run a single time and exit with the result (0 success, 1 partial, 2 failure, 3 config error)",
+						},
+					},
+					Action: scoped(config.ScopeBackup, exclusive(commandRunBackup)),
+				},
+			},
 		},
 		{
 			Name:   "report",
 			Usage:  "test report notification",
-			Action: commandReport,
+			Action: scoped(config.ScopeAdmin, commandReport),
 		},
 		{
 			Name:      "encrypt",
@@ -132,6 +492,33 @@ func main() {
 			ArgsUsage: "<password>",
 			Action:    commandEncrypt,
 		},
+		{
+			Name:   "token",
+			Usage:  "generate a token and the hash to add it to the configuration",
+			Action: commandToken,
+		},
+		{
+			Name:  "config",
+			Usage: "help writing the configuration file",
+			Subcommands: []cli.Command{
+				{
+					Name:   "schema",
+					Usage:  "show the JSON schema of the configuration file, for editors",
+					Action: commandConfigSchema,
+				},
+				{
+					Name:  "example",
+					Usage: "show the configuration file with the default values and the service definition",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "platform,p",
+							Usage: "platform of the service definition (linux or windows), by default the current one",
+						},
+					},
+					Action: commandConfigExample,
+				},
+			},
+		},
 	}
 
 	manageSignals(cancel, func() {
@@ -140,105 +527,255 @@ func main() {
 		}
 	})
 
-	app.Run(os.Args)
+	defer func() {
+		if toGlacier != nil && toGlacier.Cloud != nil {
+			toGlacier.Cloud.Close()
+		}
+	}()
 
-	if toGlacier.Cloud != nil {
-		toGlacier.Cloud.Close()
-	}
+	app.Run(os.Args)
+	return exitCode
 }
 
 func initialize(c *cli.Context) error {
-	config.Default()
+	load := config.Load
+	if c.Bool("strict") {
+		load = config.LoadStrict
+	}
 
 	var err error
+	if cfg, err = load(c.String("config")); err != nil {
+		fmt.Printf("error loading configuration. details: %s\n", err)
+		exitCode = exitCodeConfigError
+		return err
+	}
 
-	if c.String("config") != "" {
-		if err = config.LoadFromFile(c.String("config")); err != nil {
-			fmt.Printf("error loading configuration file. details: %s\n", err)
-			return err
-		}
+	if dataDir := c.String("data-dir"); dataDir != "" {
+		cfg.DataDir = dataDir
+	}
+
+	// the data directory (by default of the user) is created on the first run,
+	// so unprivileged installations work out of the box
+	if err = os.MkdirAll(cfg.DataDirectory(), 0700); err != nil {
+		fmt.Printf("error creating the data directory. details: %s\n", err)
+		exitCode = exitCodeConfigError
+		return err
 	}
 
-	if err = config.LoadFromEnvironment(); err != nil {
-		fmt.Printf("error loading configuration from environment variables. details: %s\n", err)
+	if cfg.Database.File == "" {
+		cfg.Database.File = cfg.DatabaseFile()
+	}
+
+	// the archives are built and retrieved in the data directory, as the system
+	// temporary directory can be too small for them (e.g. tmpfs)
+	if err = useTempDir(filepath.Join(cfg.DataDirectory(), "tmp")); err != nil {
+		fmt.Printf("error creating the temporary directory. details: %s\n", err)
+		exitCode = exitCodeConfigError
 		return err
 	}
 
 	logger = logrus.New()
 	logger.Out = os.Stdout
 
-	// optionally set logger output file defined in configuration. if not
-	// defined stdout will be used
-	if config.Current().Log.File != "" {
-		if logFile, err = os.OpenFile(config.Current().Log.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.ModePerm); err != nil {
-			fmt.Printf("error opening log file “%s”. details: %s\n", config.Current().Log.File, err)
-			return err
-		}
+	// keeps the last log lines of the scheduled backup to attach them to the
+	// alert e-mail
+	runLog = newLogTail(cfg.Failure.LogLines)
+	logger.Hooks.Add(runLog)
 
-		// writes to the stdout and to the log file
-		logger.Out = io.MultiWriter(os.Stdout, logFile)
+	// reports and e-mail subjects are written in the configured language
+	report.SetLocale(report.Locale(cfg.Email.Locale))
+
+	// the log file defined in configuration, by default in the data directory
+	cfg.Log.File = cfg.LogFile()
+	if logFile, err = os.OpenFile(cfg.Log.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.ModePerm); err != nil {
+		fmt.Printf("error opening log file “%s”. details: %s\n", cfg.Log.File, err)
+		exitCode = exitCodeConfigError
+		return err
+	}
+
+	// writes to the stdout and to the log file
+	logger.Out = io.MultiWriter(os.Stdout, logFile)
+
+	logger.Level = logLevel(cfg.Log.Level)
+
+	// modules with their own log level, so debugging the uploads doesn't bring
+	// all the messages of the files scan
+	moduleLoggers := make(map[config.LogModule]*logrus.Logger)
+	for module, level := range cfg.Log.Levels {
+		moduleLoggers[module] = moduleLogger(level)
+	}
+
+	// the requests and responses of the cloud are logged as debug messages
+	if c.Bool("trace") {
+		moduleLoggers[config.LogModuleCloud] = moduleLogger(config.LogLevelDebug)
 	}
 
-	switch config.Current().Log.Level {
-	case config.LogLevelDebug:
-		logger.Level = logrus.DebugLevel
-	case config.LogLevelInfo:
-		logger.Level = logrus.InfoLevel
-	case config.LogLevelWarning:
-		logger.Level = logrus.WarnLevel
-	case config.LogLevelError:
-		logger.Level = logrus.ErrorLevel
-	case config.LogLevelFatal:
-		logger.Level = logrus.FatalLevel
-	case config.LogLevelPanic:
-		logger.Level = logrus.PanicLevel
+	// hashing, compression and encryption use all available cores, competing
+	// with the other workloads of the server
+	if cfg.MaxCPUs > 0 {
+		runtime.GOMAXPROCS(cfg.MaxCPUs)
 	}
 
-	var chosenCloud cloud.Cloud
+	options := []toglacier.Option{
+		toglacier.WithContext(ctx),
+		toglacier.WithLogger(logger),
+		toglacier.WithMachineID(cfg.MachineID),
+		toglacier.WithCommand(c.Args().First()),
+		toglacier.WithArchiveFormat(cfg.Archive.Format),
+		toglacier.WithEnvelop(cfg.Archive.Envelop),
+		toglacier.WithAllowUnencrypted(cfg.Archive.AllowUnencrypted),
+		toglacier.WithRedundancy(int(cfg.Archive.Redundancy)),
+		toglacier.WithNormalization(archive.Normalization(cfg.Archive.Normalization)),
+		toglacier.WithSpecialFiles(archive.SpecialFiles(cfg.Archive.SpecialFiles)),
+		toglacier.WithTrace(c.Bool("trace")),
+		toglacier.WithReadOnly(cfg.ReadOnly || c.Bool("read-only")),
+	}
+
+	for module, moduleLogger := range moduleLoggers {
+		options = append(options, toglacier.WithModuleLogger(logModules[module], moduleLogger))
+	}
+
+	if cfg.Proxy.Value != nil {
+		options = append(options, toglacier.WithProxy(cfg.Proxy.Value))
+	}
+
+	if cfg.Tracing.Endpoint != "" {
+		options = append(options, toglacier.WithOTLPTracing(cfg.Tracing.Endpoint))
+	}
 
-	switch config.Current().Cloud {
+	switch cfg.Cloud {
 	case config.CloudTypeAWS:
-		awsConfig := cloud.AWSConfig{
-			AccountID:       config.Current().AWS.AccountID.Value,
-			AccessKeyID:     config.Current().AWS.AccessKeyID.Value,
-			SecretAccessKey: config.Current().AWS.SecretAccessKey.Value,
-			Region:          config.Current().AWS.Region,
-			VaultName:       config.Current().AWS.VaultName,
+		options = append(options, toglacier.WithRequestsPerSecond(cfg.AWS.RequestsPerSecond))
+		options = append(options, toglacier.WithAWSCloud(
+			cfg.AWS.AccountID.Value,
+			cfg.AWS.AccessKeyID.Value,
+			cfg.AWS.SecretAccessKey.Value,
+			cfg.AWS.Region,
+			cfg.AWS.VaultName,
+		))
+
+	case config.CloudTypeGCS:
+		options = append(options, toglacier.WithGCSCloud(
+			cfg.GCS.Project,
+			cfg.GCS.Bucket,
+			cfg.GCS.AccountFile,
+		))
+
+	case config.CloudTypeS3:
+		options = append(options, toglacier.WithS3Cloud(
+			cfg.S3.Endpoint,
+			cfg.S3.Region,
+			cfg.S3.Bucket,
+			cfg.S3.AccessKeyID.Value,
+			cfg.S3.SecretAccessKey.Value,
+			cfg.S3.PathStyle,
+		))
+
+	case config.CloudTypeRclone:
+		options = append(options, toglacier.WithRcloneCloud(
+			cfg.Rclone.Remote,
+			cfg.Rclone.Binary,
+			cfg.Rclone.ConfigFile,
+		))
+	}
+
+	options = append(options, toglacier.WithConcurrency(cfg.Concurrency))
+	options = append(options, toglacier.WithRemoveConcurrency(cfg.RemoveConcurrency))
+	options = append(options, toglacier.WithRebaseAfter(time.Duration(cfg.RebaseAfter)))
+	options = append(options, toglacier.WithFullBackupEvery(time.Duration(cfg.FullBackupEvery)))
+	options = append(options, toglacier.WithMaxUnreadable(cfg.MaxUnreadable))
+	options = append(options, toglacier.WithBackupMaxDuration(time.Duration(cfg.BackupMaxDuration)))
+	options = append(options, toglacier.WithSnapshot(cfg.Snapshot))
+	options = append(options, toglacier.WithChangeRetries(cfg.ChangeRetries))
+	if cfg.GroupByDay {
+		options = append(options, toglacier.WithGroupByDay(cfg.Scheduler.Timezone.Location()))
+	}
+	options = append(options, toglacier.WithPriority(cfg.Priority.Nice, toglacier.IOClass(cfg.Priority.IOClass), cfg.Priority.IOLevel))
+	for _, route := range cfg.Routes {
+		options = append(options, toglacier.WithRoute(route.Prefix, route.VaultName, route.Region))
+
+		if route.Email.Defined() {
+			options = append(options, toglacier.WithRouteNotification(route.Prefix, toglacier.Notification{
+				To:         route.Email.To,
+				Format:     report.Format(route.Email.Format),
+				ErrorsOnly: route.Email.ErrorsOnly,
+			}))
+		}
+	}
+	if cfg.Replica.VaultName != "" {
+		options = append(options, toglacier.WithReplica(cfg.Replica.VaultName, cfg.Replica.Region))
+	}
+	if len(cfg.Sources) > 0 {
+		// the outputs of the sources stay in the data directory, instead of the
+		// shared temporary directory
+		options = append(options, toglacier.WithSourcesDir(filepath.Join(cfg.DataDirectory(), "sources")))
+	}
+	for _, source := range cfg.Sources {
+		if source.DockerVolume != "" {
+			options = append(options, toglacier.WithDockerVolumeSource(source.Name, source.DockerVolume, source.Include, source.Exclude, time.Duration(source.Timeout)))
+		} else {
+			options = append(options, toglacier.WithSource(source.Name, source.Command, time.Duration(source.Timeout)))
 		}
+	}
+	options = append(options, toglacier.WithDockerImage(cfg.DockerImage))
+	if cfg.Telegram.BotToken.Value != "" {
+		options = append(options, toglacier.WithTelegram(cfg.Telegram.BotToken.Value, cfg.Telegram.ChatID))
+	}
+	if events := notificationEvents(); len(events) > 0 {
+		options = append(options, toglacier.WithEvents(events))
+	}
 
-		if chosenCloud, err = cloud.NewAWSCloud(logger, awsConfig, false); err != nil {
-			fmt.Printf("error initializing aws cloud. details: %s\n", err)
-			return err
+	switch cfg.Database.Type {
+	case config.DatabaseTypeAuditFile:
+		options = append(options, toglacier.WithAuditFileStorage(cfg.Database.File))
+	case config.DatabaseTypeBoltDB:
+		options = append(options, toglacier.WithBoltDBStorage(cfg.Database.File))
+		options = append(options, toglacier.WithBoltDBOptions(cfg.Database.NoSync, cfg.Database.AllocSize))
+	}
+	options = append(options, toglacier.WithJournal(cfg.Database.File+".journal"))
+	options = append(options, toglacier.WithUploadProgress(uploadStatusFile{filename: uploadStatusFilename()}))
+	if cfg.ConfirmAbove > 0 {
+		options = append(options, toglacier.WithConfirmAbove(int64(cfg.ConfirmAbove), uploadApproval{filename: approvalFilename()}))
+	}
+	if cfg.Spool.Dir != "" {
+		options = append(options, toglacier.WithSpool(cfg.Spool.Dir, cfg.Spool.MaxSize))
+	}
+	if cfg.TrashPeriod > 0 {
+		options = append(options, toglacier.WithTrash(cfg.Database.File+".trash", time.Duration(cfg.TrashPeriod)))
+	}
+
+	// only the scheduler lives long enough to use the detected changes
+	if cfg.Watch && c.Args().First() == "start" {
+		var ignorePatterns []*regexp.Regexp
+		for _, pattern := range cfg.IgnorePatterns {
+			ignorePatterns = append(ignorePatterns, pattern.Value)
 		}
 
-	case config.CloudTypeGCS:
-		gcsConfig := cloud.GCSConfig{
-			Project:     config.Current().GCS.Project,
-			Bucket:      config.Current().GCS.Bucket,
-			AccountFile: config.Current().GCS.AccountFile,
+		watcherLogger := logger
+		if archiveLogger, ok := moduleLoggers[config.LogModuleArchive]; ok {
+			watcherLogger = archiveLogger
 		}
 
-		if chosenCloud, err = cloud.NewGCS(ctx, logger, gcsConfig); err != nil {
-			fmt.Printf("error initializing google cloud. details: %s\n", err)
-			return err
+		if watcher, err := archive.NewWatcher(watcherLogger, ignorePatterns, cfg.Paths...); err != nil {
+			logger.Warningf("toglacier: failed to watch the backup paths, all files will be analyzed on each backup. details: %s", err)
+		} else {
+			options = append(options, toglacier.WithChanges(watcher))
 		}
 	}
 
-	var localStorage storage.Storage
-	switch config.Current().Database.Type {
-	case config.DatabaseTypeAuditFile:
-		localStorage = storage.NewAuditFile(logger, config.Current().Database.File)
-	case config.DatabaseTypeBoltDB:
-		localStorage = storage.NewBoltDB(logger, config.Current().Database.File)
+	if toGlacier, err = toglacier.New(options...); err != nil {
+		fmt.Printf("error initializing toglacier. details: %s\n", err)
+		exitCode = exitCodeConfigError
+		return err
 	}
 
-	toGlacier = toglacier.ToGlacier{
-		Context: ctx,
-		Archive: archive.NewTARBuilder(logger),
-		Envelop: archive.NewOFBEnvelop(logger),
-		Cloud:   chosenCloud,
-		Storage: localStorage,
-		Logger:  logger,
+	// the state is a single object replaced on each save, so the clouds where
+	// the archives can't be replaced (AWS Glacier) can't keep it
+	if _, ok := toGlacier.Cloud.(cloud.State); cfg.Database.Stateless && !ok {
+		err = errors.New("the stateless mode isn't supported by the configured cloud")
+		logger.Error(err)
+		exitCode = exitCodeConfigError
+		return err
 	}
 
 	return nil
@@ -249,19 +786,44 @@ func commandSync(c *cli.Context) error {
 		logger.Out = ioutil.Discard
 	}
 
+	if name := c.String("stdin"); name != "" {
+		if err := toGlacier.BackupStream(os.Stdin, name, cfg.BackupSecret.Value, c.String("comment")); err != nil {
+			logger.Error(err)
+		}
+
+		return nil
+	}
+
 	var ignorePatterns []*regexp.Regexp
-	for _, pattern := range config.Current().IgnorePatterns {
+	for _, pattern := range cfg.IgnorePatterns {
 		ignorePatterns = append(ignorePatterns, pattern.Value)
 	}
 
+	// in a terminal the user confirms the backups above the confirmation
+	// threshold, otherwise a deferred backup waits for the approval
+	if interactive() {
+		toGlacier.Approval = uploadApproval{interactive: true}
+	} else if waitingApproval() {
+		exitCode = exitCodeNotApproved
+		return nil
+	}
+
 	err := toGlacier.Backup(
-		config.Current().Paths,
-		config.Current().BackupSecret.Value,
-		float64(config.Current().ModifyTolerance),
+		cfg.Paths,
+		cfg.BackupSecret.Value,
+		float64(cfg.ModifyTolerance),
 		ignorePatterns,
+		c.String("comment"),
 	)
 
-	if err != nil {
+	switch {
+	case err == nil:
+	case errors.Is(err, toglacier.ErrApprovalRequired) && interactive():
+		fmt.Println("backup not sent")
+		exitCode = exitCodeNotApproved
+	case notifyApproval(err):
+		exitCode = exitCodeNotApproved
+	default:
 		logger.Error(err)
 	}
 
@@ -273,126 +835,1027 @@ func commandGet(c *cli.Context) error {
 		logger.Out = ioutil.Discard
 	}
 
-	if err := toGlacier.RetrieveBackup(c.Args().First(), config.Current().BackupSecret.Value, c.Bool("skip-unmodified")); err != nil {
+	if c.Bool("stdout") {
+		// the standard output is reserved for the backup content
+		if c.Bool("verbose") {
+			logger.Out = os.Stderr
+		}
+
+		id, err := toGlacier.ResolveID(c.Args().First())
+		if err != nil {
+			logger.Error(err)
+			return nil
+		}
+
+		if err = toGlacier.RetrieveBackupStream(id, cfg.BackupSecret.Value, os.Stdout); err != nil {
+			logger.Error(err)
+		}
+
+		return nil
+	}
+
+	overwrite, err := archive.ParseOverwritePolicy(c.String("conflict"))
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	switch {
+	case c.Bool("no-overwrite") && c.Bool("backup-existing"):
+		fmt.Println("--no-overwrite and --backup-existing can't be used together")
+		return nil
+	case c.Bool("no-overwrite"):
+		overwrite = archive.OverwriteSkip
+	case c.Bool("backup-existing"):
+		overwrite = archive.OverwriteBackup
+	}
+
+	toGlacier.AllOrNothing = c.Bool("all-or-nothing")
+
+	id := c.Args().First()
+	if id == "latest" {
+		latest, err := toGlacier.LatestBackup(c.String("path"), cfg.BackupSecret.Value)
+		if err != nil {
+			logger.Error(err)
+			return nil
+		}
+
+		id = latest.Backup.ID
+		fmt.Printf("retrieving backup “%s” created at %s\n", id, latest.Backup.CreatedAt.Format("2006-01-02 15:04"))
+
+	} else if id, err = toGlacier.ResolveID(id); err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	if err = toGlacier.RetrieveBackupPaths(id, c.String("path"), cfg.BackupSecret.Value, c.Bool("skip-unmodified"), overwrite); err != nil {
 		logger.Error(err)
 	} else {
 		fmt.Println("backup recovered successfully")
 	}
 
-	return nil
-}
-
-func commandRemove(c *cli.Context) error {
-	if !c.Bool("verbose") {
-		logger.Out = ioutil.Discard
+	if cfg.Email.Server == "" {
+		logger.Info("no e-mail server configured, report not sent")
+		return nil
 	}
 
-	ids := []string{c.Args().First()}
-	ids = append(ids, c.Args().Tail()...)
-	if err := toGlacier.RemoveBackups(ids...); err != nil {
+	// large retrievals can take hours, so the administrator is notified with
+	// the files that were restored
+	if err = toGlacier.SendReport(currentEmailInfo()); err != nil {
 		logger.Error(err)
 	}
 
 	return nil
 }
 
-func commandList(c *cli.Context) error {
+func commandMount(c *cli.Context) error {
 	if !c.Bool("verbose") {
 		logger.Out = ioutil.Discard
 	}
 
-	backups, err := toGlacier.ListBackups(c.Bool("remote"))
-	if err != nil {
-		logger.Error(err)
-
-	} else if len(backups) == 0 {
+	if c.NArg() < 2 {
+		fmt.Println("archive id and mount directory must be informed")
 		return nil
 	}
 
-	var filenameMatch *regexp.Regexp
+	id := c.Args().Get(0)
+	if id == "latest" {
+		latest, err := toGlacier.LatestBackup("", cfg.BackupSecret.Value)
+		if err != nil {
+			logger.Error(err)
+			return nil
+		}
+
+		id = latest.Backup.ID
+		fmt.Printf("mounting backup “%s” created at %s\n", id, latest.Backup.CreatedAt.Format("2006-01-02 15:04"))
+
+	} else {
+		var err error
+		if id, err = toGlacier.ResolveID(id); err != nil {
+			logger.Error(err)
+			return nil
+		}
+	}
+
+	dir := c.Args().Get(1)
+	fmt.Printf("backup will be available in “%s” after the retrieval, unmount it (fusermount -u or umount) or press Ctrl+C to finish\n", dir)
+
+	if err := toGlacier.MountBackup(id, cfg.BackupSecret.Value, dir); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("backup unmounted")
+	}
+
+	return nil
+}
+
+func commandCheck(c *cli.Context) error {
+	if !c.Bool("verbose") {
+		logger.Out = ioutil.Discard
+	}
+
+	if c.NArg() == 0 {
+		fmt.Println("no archive id informed")
+		return nil
+	}
+
+	id := c.Args().First()
+	if id == "latest" {
+		latest, err := toGlacier.LatestBackup("", cfg.BackupSecret.Value)
+		if err != nil {
+			logger.Error(err)
+			exitCode = exitCodeFailure
+			return nil
+		}
+
+		id = latest.Backup.ID
+		fmt.Printf("checking backup “%s” created at %s\n", id, latest.Backup.CreatedAt.Format("2006-01-02 15:04"))
+
+	} else {
+		var err error
+		if id, err = toGlacier.ResolveID(id); err != nil {
+			logger.Error(err)
+			exitCode = exitCodeFailure
+			return nil
+		}
+	}
+
+	check, err := toGlacier.CheckBackup(id, cfg.BackupSecret.Value)
+	if err != nil {
+		logger.Error(err)
+		exitCode = exitCodeFailure
+		return nil
+	}
+
+	for _, path := range check.Modified {
+		fmt.Printf("modified | %s\n", path)
+	}
+
+	for _, path := range check.Missing {
+		fmt.Printf("missing  | %s\n", path)
+	}
+
+	fmt.Printf("%d unmodified, %d modified and %d missing files\n", check.Unmodified, len(check.Modified), len(check.Missing))
+
+	// scripts auditing the files can detect the drift without parsing the
+	// output
+	if check.Drifted() {
+		exitCode = exitCodeFailure
+	}
+
+	return nil
+}
+
+func commandJobs(c *cli.Context) error {
+	if !c.Bool("verbose") {
+		logger.Out = ioutil.Discard
+	}
+
+	jobs, err := toGlacier.ListJobs()
+	if err != nil {
+		logger.Error(err)
+		exitCode = exitCodeFailure
+		return nil
+	}
+
+	printJobs := func(jobs []cloud.Job) {
+		now := time.Now()
+		for _, job := range jobs {
+			status := string(job.Status)
+			if job.StatusMessage != "" && job.StatusMessage != status {
+				status += " (" + job.StatusMessage + ")"
+			}
+
+			fmt.Printf("%-16s | %-18s | %-10s | %-10s | %s | %s\n", job.CreatedAt.Format("2006-01-02 15:04"), job.Action,
+				job.Tier, job.Age(now).Truncate(time.Second), job.ID, status)
+		}
+	}
+
+	if len(jobs) == 0 {
+		fmt.Println("no jobs found")
+		return nil
+	}
+
+	printJobs(jobs)
+
+	if !c.Bool("wait") {
+		return nil
+	}
+
+	// jobs that already failed before waiting are history, only the ones
+	// followed now define the exit code
+	waited := make(map[string]bool)
+	for _, job := range jobs {
+		if !job.Completed() {
+			waited[job.ID] = true
+		}
+	}
+
+	jobs, err = toGlacier.WaitJobs(jobs, c.Duration("interval"), func(jobs []cloud.Job) {
+		fmt.Printf("\n[%s]\n", time.Now().Format("2006-01-02 15:04:05"))
+		printJobs(jobs)
+	})
+
+	if err != nil {
+		logger.Error(err)
+		exitCode = exitCodeFailure
+		return nil
+	}
+
+	// a failed retrieval will need to be requested again
+	for _, job := range jobs {
+		if waited[job.ID] && job.Status == cloud.JobStatusFailed {
+			exitCode = exitCodeFailure
+		}
+	}
+
+	return nil
+}
+
+func commandBootstrap(c *cli.Context) error {
+	if !c.Bool("verbose") {
+		logger.Out = ioutil.Discard
+	}
+
+	if err := toGlacier.BootstrapCatalog(c.Args().First(), cfg.BackupSecret.Value); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("local storage rebuilt successfully")
+	}
+
+	return nil
+}
+
+func commandRemove(c *cli.Context) error {
+	if !c.Bool("verbose") {
+		logger.Out = ioutil.Discard
+	}
+
+	ids := append([]string{}, c.Args()...)
+
+	if filename := c.String("ids-file"); filename != "" {
+		fileIDs, err := readIDs(filename)
+		if err != nil {
+			logger.Errorf("error reading ids file. details: %s", err)
+			return nil
+		}
+		ids = append(ids, fileIDs...)
+	}
+
+	if len(ids) == 0 {
+		fmt.Println("no archive ids informed")
+		return nil
+	}
+
+	for i := range ids {
+		var err error
+		if ids[i], err = toGlacier.ResolveID(ids[i]); err != nil {
+			logger.Error(err)
+			return nil
+		}
+	}
+
+	if err := toGlacier.RemoveBackups(ids...); err != nil {
+		logger.Error(err)
+	} else if toGlacier.Trash != nil {
+		fmt.Printf("backups moved to the trash, they will be removed in %s\n", toGlacier.TrashPeriod)
+	}
+
+	return nil
+}
+
+func commandRestoreTrash(c *cli.Context) error {
+	if c.NArg() == 0 {
+		fmt.Println("no archive id informed")
+		return nil
+	}
+
+	id, err := toGlacier.ResolveID(c.Args().First())
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	if err = toGlacier.RestoreTrash(id); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("backup restored from the trash")
+	}
+
+	return nil
+}
+
+// readIDs reads the archive ids from a file (or from the standard input when
+// the filename is “-”), one per line. Empty lines and lines starting with “#”
+// are ignored, so the output of other tools can be easily adapted.
+func readIDs(filename string) ([]string, error) {
+	input := io.Reader(os.Stdin)
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	var ids []string
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		id := strings.TrimSpace(scanner.Text())
+		if id == "" || strings.HasPrefix(id, "#") {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, scanner.Err()
+}
+
+func commandPin(c *cli.Context) error {
+	if c.Bool("history") {
+		events, err := toGlacier.PinEvents()
+		if err != nil {
+			logger.Error(err)
+			return nil
+		}
+
+		for _, event := range events {
+			fmt.Printf("%s | %-5s | %s | %s | %s\n",
+				event.CreatedAt.Format("2006-01-02 15:04:05"), event.Action, event.ID, event.User, event.Reason)
+		}
+		return nil
+	}
+
+	if c.NArg() == 0 {
+		pins, err := toGlacier.Pins()
+		if err != nil {
+			logger.Error(err)
+			return nil
+		}
+
+		now := toGlacier.Clock.Now()
+		for _, pin := range pins {
+			until := "unpinned"
+			if !pin.Until.IsZero() {
+				until = pin.Until.Format("2006-01-02 15:04:05")
+				if !pin.Active(now) {
+					until += " (expired)"
+				}
+			}
+
+			fmt.Printf("%s | %s | %s | %s\n", pin.ID, pin.User, until, pin.Reason)
+		}
+		return nil
+	}
+
+	var until time.Time
+	if c.String("until") != "" {
+		var err error
+		if until, err = parseDate(c.String("until")); err != nil {
+			logger.Errorf("invalid pin expiration date “%s”", c.String("until"))
+			return nil
+		}
+	}
+
+	id, err := toGlacier.ResolveID(c.Args().First())
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	if err = toGlacier.Pin(id, currentUser(), c.String("reason"), until); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("backup pinned")
+	}
+
+	return nil
+}
+
+func commandUnpin(c *cli.Context) error {
+	if c.NArg() == 0 {
+		fmt.Println("no archive id informed")
+		return nil
+	}
+
+	id, err := toGlacier.ResolveID(c.Args().First())
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	if err = toGlacier.Unpin(id, currentUser(), c.String("reason")); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("backup unpinned")
+	}
+
+	return nil
+}
+
+func commandTag(c *cli.Context) error {
+	if c.NArg() == 0 {
+		tags, err := toGlacier.Tags()
+		if err != nil {
+			logger.Error(err)
+			return nil
+		}
+
+		for _, tag := range tags {
+			fmt.Printf("%-16s | %s | %s\n", tag.Name, tag.CreatedAt.Format("2006-01-02 15:04:05"), tag.ID)
+		}
+		return nil
+	}
+
+	if c.NArg() != 2 {
+		fmt.Println("archive id and name must be informed")
+		return nil
+	}
+
+	// a backup can also be referenced by one of its other names
+	id, err := toGlacier.ResolveID(c.Args().Get(0))
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	if err = toGlacier.TagBackup(id, c.Args().Get(1)); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("backup tagged")
+	}
+
+	return nil
+}
+
+func commandUntag(c *cli.Context) error {
+	if c.NArg() == 0 {
+		fmt.Println("no name informed")
+		return nil
+	}
+
+	if err := toGlacier.UntagBackup(c.Args().First()); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("backup untagged")
+	}
+
+	return nil
+}
+
+// parseDate accepts a day (local time) or a full RFC 3339 timestamp.
+func parseDate(value string) (time.Time, error) {
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// currentUser identifies who is running the command, so it can be recorded in
+// the audit trails.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func commandList(c *cli.Context) error {
+	if !c.Bool("verbose") {
+		logger.Out = ioutil.Discard
+	}
+
+	toGlacier.AllMachines = c.Bool("all-machines")
+
+	backups, err := toGlacier.ListBackups(c.Bool("remote"), c.Duration("max-age"))
+	if err != nil {
+		logger.Error(err)
+
+	} else if len(backups) == 0 {
+		return nil
+	}
+
+	var filenameMatch *regexp.Regexp
+	if c.NArg() > 0 {
+		fmt.Printf("backups containing pattern “%s”\n\n", c.Args().First())
+
+		if filenameMatch, err = regexp.Compile(c.Args().First()); err != nil {
+			logger.Errorf("invalid pattern. details: %s", err)
+		}
+	}
+
+	// the other runs of the day are counted in the newest backup of the day.
+	// When looking for a file all backups are listed, as an older run of the
+	// day can be the only one containing it
+	sameDayRuns := make(map[string]int)
+	if (c.Bool("group-by-day") || cfg.GroupByDay) && c.NArg() == 0 {
+		days := toglacier.GroupBackupsByDay(backups, cfg.Scheduler.Timezone.Location())
+
+		backups = backups[:0]
+		for _, day := range days {
+			backups = append(backups, day.Backups[0])
+			sameDayRuns[day.Backups[0].Backup.ID] = len(day.Backups) - 1
+		}
+	}
+
+	tags, err := toGlacier.Tags()
+	if err != nil {
+		logger.Error(err)
+	}
+
+	backupTags := make(map[string][]string)
+	for _, tag := range tags {
+		backupTags[tag.ID] = append(backupTags[tag.ID], tag.Name)
+	}
+
+	fmt.Printf("Date             | Vault Name       | Machine          | %-138s | Comment\n", "Archive ID")
+	fmt.Printf("%s-+-%s-+-%s-+-%s-+-%s\n", strings.Repeat("-", 16), strings.Repeat("-", 16), strings.Repeat("-", 16), strings.Repeat("-", 138), strings.Repeat("-", 16))
+
+	for _, backup := range backups {
+		show := false
+		if c.NArg() > 0 {
+			for filename, itemInfo := range backup.Info {
+				if itemInfo.Status.Useful() && (filenameMatch != nil && filenameMatch.MatchString(filename)) {
+					show = true
+				}
+			}
+		}
+
+		if show || c.NArg() == 0 {
+			comment := backup.Backup.Comment
+			if runs := sameDayRuns[backup.Backup.ID]; runs > 0 {
+				comment = strings.TrimSpace(fmt.Sprintf("%s (+%d runs on the same day)", comment, runs))
+			}
+			if backup.Backup.Partial {
+				comment = strings.TrimSpace(comment + " (partial)")
+			}
+			if names := backupTags[backup.Backup.ID]; len(names) > 0 {
+				comment = strings.TrimSpace(fmt.Sprintf("%s [%s]", comment, strings.Join(names, ", ")))
+			}
+
+			fmt.Printf("%-16s | %-16s | %-16s | %-138s | %s\n", backup.Backup.CreatedAt.Format("2006-01-02 15:04"), backup.Backup.VaultName, backup.Backup.MachineID, backup.Backup.ID, comment)
+		}
+	}
+
+	return nil
+}
+
+func commandGC(c *cli.Context) error {
+	// listing the orphan archives only requires the list scope
+	if c.Bool("remove") && !authorized(c, config.ScopeRemove) {
+		return nil
+	}
+
+	if !c.Bool("verbose") {
+		logger.Out = ioutil.Discard
+	}
+
+	orphans, err := toGlacier.OrphanBackups()
+	if err != nil {
+		logger.Error(err)
+		return nil
+
+	} else if len(orphans) == 0 {
+		fmt.Println("no orphan archives found")
+		return nil
+	}
+
+	fmt.Printf("Date             | Vault Name       | Machine          | %-138s | Comment\n", "Archive ID")
+	fmt.Printf("%s-+-%s-+-%s-+-%s-+-%s\n", strings.Repeat("-", 16), strings.Repeat("-", 16), strings.Repeat("-", 16), strings.Repeat("-", 138), strings.Repeat("-", 16))
+
+	for _, orphan := range orphans {
+		fmt.Printf("%-16s | %-16s | %-16s | %-138s | %s\n", orphan.CreatedAt.Format("2006-01-02 15:04"), orphan.VaultName, orphan.MachineID, orphan.ID, orphan.Comment)
+	}
+
+	if !c.Bool("remove") {
+		return nil
+	}
+
+	if !c.Bool("yes") {
+		fmt.Printf("\nremove %d orphan archive(s)? [y/N] ", len(orphans))
+
+		answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			logger.Errorf("error reading confirmation. details: %s", err)
+			return nil
+		}
+
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			return nil
+		}
+	}
+
+	if err := toGlacier.RemoveOrphanBackups(orphans...); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("orphan archives removed successfully")
+	}
+
+	return nil
+}
+
+func commandVaultLock(c *cli.Context) error {
+	policy, err := ioutil.ReadFile(c.Args().First())
+	if err != nil {
+		logger.Errorf("error reading policy file. details: %s", err)
+		return nil
+	}
+
+	lockID, err := toGlacier.LockVault(string(policy))
+	if err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Printf("vault lock initiated with id “%s”, complete it within 24 hours\n", lockID)
+	}
+
+	return nil
+}
+
+func commandVaultComplete(c *cli.Context) error {
+	if err := toGlacier.CompleteVaultLock(c.Args().First()); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("vault locked successfully")
+	}
+
+	return nil
+}
+
+func commandVaultAbort(c *cli.Context) error {
+	if err := toGlacier.AbortVaultLock(); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("vault lock aborted")
+	}
+
+	return nil
+}
+
+func commandVaultPolicy(c *cli.Context) error {
+	var policy []byte
+	if !c.Bool("remove") {
+		var err error
+		if policy, err = ioutil.ReadFile(c.Args().First()); err != nil {
+			logger.Errorf("error reading policy file. details: %s", err)
+			return nil
+		}
+	}
+
+	if err := toGlacier.SetVaultAccessPolicy(string(policy)); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("vault access policy changed successfully")
+	}
+
+	return nil
+}
+
+func commandVaultTags(c *cli.Context) error {
+	tags := make(map[string]string)
+	for _, arg := range c.Args() {
+		keyValue := strings.SplitN(arg, "=", 2)
+		if len(keyValue) != 2 || keyValue[0] == "" {
+			logger.Errorf("invalid tag “%s”, expected format key=value", arg)
+			return nil
+		}
+		tags[keyValue[0]] = keyValue[1]
+	}
+
+	if err := toGlacier.SetVaultTags(tags); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("vault tags changed successfully")
+	}
+
+	return nil
+}
+
+func commandVaultApply(c *cli.Context) error {
+	// only the settings present in the configuration are managed, so an
+	// unrelated vault setting will not be removed by accident
+	if cfg.AWS.VaultAccessPolicy != "" {
+		policy, err := ioutil.ReadFile(cfg.AWS.VaultAccessPolicy)
+		if err != nil {
+			logger.Errorf("error reading policy file. details: %s", err)
+			return nil
+		}
+
+		if err = toGlacier.SetVaultAccessPolicy(string(policy)); err != nil {
+			logger.Error(err)
+			return nil
+		}
+	}
+
+	if cfg.AWS.VaultTags != nil {
+		if err := toGlacier.SetVaultTags(cfg.AWS.VaultTags); err != nil {
+			logger.Error(err)
+			return nil
+		}
+	}
+
+	fmt.Println("vault settings applied successfully")
+	return nil
+}
+
+func commandDBRepair(c *cli.Context) error {
+	if !c.Bool("verbose") {
+		logger.Out = ioutil.Discard
+	}
+
+	dropped, err := toGlacier.CompactStorage()
+	if err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Printf("local storage repaired, %d records dropped\n", dropped)
+	}
+
+	return nil
+}
+
+func commandPause(c *cli.Context) error {
+	var duration time.Duration
 	if c.NArg() > 0 {
-		fmt.Printf("backups containing pattern “%s”\n\n", c.Args().First())
+		var err error
+		if duration, err = time.ParseDuration(c.Args().First()); err != nil || duration <= 0 {
+			logger.Errorf("invalid pause duration “%s”", c.Args().First())
+			return nil
+		}
+	}
 
-		if filenameMatch, err = regexp.Compile(c.Args().First()); err != nil {
-			logger.Errorf("invalid pattern. details: %s", err)
+	if err := toGlacier.Pause(duration); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("scheduled actions paused")
+	}
+
+	return nil
+}
+
+func commandResume(c *cli.Context) error {
+	if err := toGlacier.Resume(); err != nil {
+		logger.Error(err)
+	} else {
+		fmt.Println("scheduled actions resumed")
+	}
+
+	return nil
+}
+
+func commandCancel(c *cli.Context) error {
+	// the operations are executed by other process, that publishes them
+	// periodically
+	operations, err := readRunning()
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	if c.NArg() == 0 {
+		if len(operations) == 0 {
+			fmt.Println("no running operations")
+		}
+
+		for _, operation := range operations {
+			fmt.Printf("%-25s %-10s started at %s\n", operation.ID, operation.Action, operation.StartedAt.Format("2006-01-02 15:04:05"))
 		}
+		return nil
 	}
 
-	fmt.Println("Date             | Vault Name       | Archive ID")
-	fmt.Printf("%s-+-%s-+-%s\n", strings.Repeat("-", 16), strings.Repeat("-", 16), strings.Repeat("-", 138))
+	id := c.Args().First()
 
-	for _, backup := range backups {
-		show := false
-		if c.NArg() > 0 {
-			for filename, itemInfo := range backup.Info {
-				if itemInfo.Status.Useful() && (filenameMatch != nil && filenameMatch.MatchString(filename)) {
-					show = true
-				}
-			}
+	var found bool
+	for _, operation := range operations {
+		if operation.ID == id {
+			found = true
+			break
 		}
+	}
 
-		if show || c.NArg() == 0 {
-			fmt.Printf("%-16s | %-16s | %-138s\n", backup.Backup.CreatedAt.Format("2006-01-02 15:04"), backup.Backup.VaultName, backup.Backup.ID)
+	if !found {
+		logger.Errorf("operation “%s” isn't running", id)
+		exitCode = exitCodeFailure
+		return nil
+	}
+
+	if err := requestCancel(id); err != nil {
+		logger.Error(err)
+		exitCode = exitCodeFailure
+		return nil
+	}
+
+	fmt.Printf("cancellation of operation “%s” requested\n", id)
+	return nil
+}
+
+func commandStatus(c *cli.Context) error {
+	paused, until, err := toGlacier.Paused()
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	switch {
+	case !paused:
+		fmt.Println("scheduled actions running")
+	case until.IsZero():
+		fmt.Println("scheduled actions paused until resumed")
+	default:
+		fmt.Printf("scheduled actions paused until %s\n", until.Format("2006-01-02 15:04:05"))
+	}
+
+	if until, ok := blackoutUntil(time.Now()); ok {
+		fmt.Printf("scheduled actions deferred by a blackout window until %s\n", until.Format("2006-01-02 15:04:05"))
+	}
+
+	// the same information of the reports, so the administrator can compare them
+	schedule := scheduleReport()
+	fmt.Printf("configuration fingerprint: %s\n", schedule.ConfigFingerprint)
+	for _, nextRun := range schedule.NextRuns {
+		fmt.Printf("next %s: %s\n", nextRun.Action, nextRun.Next.Format("2006-01-02 15:04:05"))
+	}
+
+	if toGlacier.Spool != nil {
+		spooledArchives, err := toGlacier.Spool.List(ctx)
+		if err != nil {
+			logger.Error(err)
+			return nil
+		}
+
+		var size int64
+		for _, spooled := range spooledArchives {
+			size += spooled.Size
+		}
+		fmt.Printf("spooled archives: %d (%d bytes)\n", len(spooledArchives), size)
+	}
+
+	trashedBackups, err := toGlacier.ListTrash()
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	for _, trashed := range trashedBackups {
+		fmt.Printf("trashed backup %s: removed at %s\n", trashed.ID,
+			trashed.TrashedAt.Add(toGlacier.TrashPeriod).Format("2006-01-02 15:04:05"))
+	}
+
+	// the upload is performed by other process, that updates the progress after
+	// each part
+	upload, ok, err := readUploadStatus(uploadStatusFilename())
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	if ok {
+		fmt.Printf("upload in progress: %.1f%% (%d of %d bytes) at %.0f bytes/s, ETA %s (updated at %s)\n",
+			upload.Percentage(), upload.Sent, upload.Total, upload.Throughput,
+			upload.ETA.Round(time.Second), upload.UpdatedAt.Format("2006-01-02 15:04:05"))
+	}
+
+	return nil
+}
+
+func commandAudit(c *cli.Context) error {
+	var since time.Time
+	if c.String("since") != "" {
+		var err error
+		if since, err = parseDate(c.String("since")); err != nil {
+			logger.Errorf("invalid audit date “%s”", c.String("since"))
+			return nil
 		}
 	}
 
+	operations, err := toGlacier.Operations()
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+
+	for _, operation := range operations {
+		if operation.CreatedAt.Before(since) || (c.String("action") != "" && operation.Action != c.String("action")) {
+			continue
+		}
+
+		result := "success"
+		if operation.Error != "" {
+			result = "failure: " + operation.Error
+		}
+
+		fmt.Printf("%s | %-10s | %-21s | %s | %s\n", operation.CreatedAt.Format("2006-01-02 15:04:05"),
+			operation.Command, operation.Action, strings.Join(operation.Backups, ","), result)
+	}
+
 	return nil
 }
 
 func commandStart(c *cli.Context) error {
+	// the scheduled actions send and remove backups
+	if toGlacier.ReadOnly {
+		fmt.Println("the scheduler can't run in the read-only mode")
+		return nil
+	}
+
+	toGlacier.AllMachines = c.Bool("all-machines")
+
 	var ignorePatterns []*regexp.Regexp
-	for _, pattern := range config.Current().IgnorePatterns {
+	for _, pattern := range cfg.IgnorePatterns {
 		ignorePatterns = append(ignorePatterns, pattern.Value)
 	}
 
-	scheduler := cron.New()
+	// the schedulers follow the wall clock of the configured timezone, handling
+	// the daylight saving time transitions
+	scheduler := cron.NewWithLocation(cfg.Scheduler.Timezone.Location())
 
-	scheduler.Schedule(config.Current().Scheduler.Backup.Value, jobFunc(func() {
-		err := toGlacier.Backup(
-			config.Current().Paths,
-			config.Current().BackupSecret.Value,
-			float64(config.Current().ModifyTolerance),
-			ignorePatterns,
-		)
+	backupFailurePolicy := newBackupFailurePolicy()
 
-		if err != nil {
+	// the reports are still sent while paused, so the skipped actions are
+	// visible to the administrator. Inside a blackout window the actions are
+	// deferred until the window closes
+	scheduler.Schedule(cfg.Scheduler.Backup.Value, deferInBlackout("backup", jobFunc(func() {
+		if toGlacier.SkipPaused("backup") {
+			return
+		}
+
+		if _, err := runBackup(backupFailurePolicy, ignorePatterns, ""); err != nil {
+			logger.Error(err)
+		}
+	})))
+
+	scheduler.Schedule(cfg.Scheduler.RemoveOldBackups.Value, deferInBlackout("remove old backups", jobFunc(func() {
+		if toGlacier.SkipPaused("remove old backups") {
+			return
+		}
+
+		if err := toGlacier.RemoveOldBackups(cfg.KeepBackups, cfg.BackupSecret.Value); err != nil {
 			logger.Error(err)
 		}
-	}))
 
-	scheduler.Schedule(config.Current().Scheduler.RemoveOldBackups.Value, jobFunc(func() {
-		if err := toGlacier.RemoveOldBackups(config.Current().KeepBackups); err != nil {
+		// updated backups are appended to the audit file, so the obsolete records
+		// are dropped periodically
+		if _, err := toGlacier.CompactStorage(); err != nil {
 			logger.Error(err)
 		}
-	}))
+	})))
+
+	scheduler.Schedule(cfg.Scheduler.ListRemoteBackups.Value, deferInBlackout("list remote backups", jobFunc(func() {
+		if toGlacier.SkipPaused("list remote backups") {
+			return
+		}
 
-	scheduler.Schedule(config.Current().Scheduler.ListRemoteBackups.Value, jobFunc(func() {
-		if _, err := toGlacier.ListBackups(true); err != nil {
+		if _, err := toGlacier.ListBackups(true, 0); err != nil {
 			logger.Error(err)
 		}
-	}))
+	})))
 
-	scheduler.Schedule(config.Current().Scheduler.SendReport.Value, jobFunc(func() {
-		emailInfo := toglacier.EmailInfo{
-			Sender:   toglacier.EmailSenderFunc(smtp.SendMail),
-			Server:   config.Current().Email.Server,
-			Port:     config.Current().Email.Port,
-			Username: config.Current().Email.Username,
-			Password: config.Current().Email.Password.Value,
-			From:     config.Current().Email.From,
-			To:       config.Current().Email.To,
-			Format:   report.Format(config.Current().Email.Format),
+	scheduler.Schedule(cfg.Scheduler.SendReport.Value, deferInBlackout("send report", jobFunc(func() {
+		toGlacier.Reports.Add(scheduleReport())
+		if pins := pinsReport(); len(pins.Pinned) > 0 || len(pins.Errors) > 0 {
+			toGlacier.Reports.Add(pins)
 		}
 
-		if err := toGlacier.SendReport(emailInfo); err != nil {
+		if err := toGlacier.SendReport(currentEmailInfo()); err != nil {
 			logger.Error(err)
 		}
-	}))
+	})))
+
+	if toGlacier.Spool != nil {
+		scheduler.Schedule(cfg.Scheduler.SendSpooled.Value, deferInBlackout("send spooled", jobFunc(func() {
+			if toGlacier.SkipPaused("send spooled") {
+				return
+			}
+
+			if err := toGlacier.SendSpooled(cfg.BackupSecret.Value); err != nil {
+				logger.Error(err)
+			}
+		})))
+	}
+
+	if toGlacier.Trash != nil {
+		scheduler.Schedule(cfg.Scheduler.EmptyTrash.Value, deferInBlackout("empty trash", jobFunc(func() {
+			if toGlacier.SkipPaused("empty trash") {
+				return
+			}
+
+			if _, err := toGlacier.EmptyTrash(); err != nil {
+				logger.Error(err)
+			}
+		})))
+	}
+
+	stopWebhook, err := startWebhook(backupFailurePolicy, ignorePatterns)
+	if err != nil {
+		logger.Error(err)
+		exitCode = exitCodeConfigError
+		return nil
+	}
 
 	scheduler.Start()
 
 	stopped := make(chan bool)
 	cancelFunc = func() {
+		stopWebhook()
 		scheduler.Stop()
 		stopped <- true
 	}
@@ -406,29 +1869,78 @@ func commandStart(c *cli.Context) error {
 	return nil
 }
 
+func commandRunBackup(c *cli.Context) error {
+	if !c.Bool("once") {
+		fmt.Println("only the --once mode is supported, use the start command to run the scheduler")
+		exitCode = exitCodeConfigError
+		return nil
+	}
+
+	var ignorePatterns []*regexp.Regexp
+	for _, pattern := range cfg.IgnorePatterns {
+		ignorePatterns = append(ignorePatterns, pattern.Value)
+	}
+
+	// a paused backup isn't a failure, the skipped action is only listed in the
+	// report. A backup with problems (e.g. unreadable files left out, failed
+	// copy to the replica or an archive kept in the spool) is partial, and a
+	// backup deferred until approved wasn't sent
+	exitCode = exitCodeSuccess
+	if !toGlacier.SkipPaused("backup") {
+		if deferred, err := runBackup(newBackupFailurePolicy(), ignorePatterns, ""); err != nil {
+			logger.Error(err)
+			exitCode = exitCodeFailure
+		} else if deferred {
+			exitCode = exitCodeNotApproved
+		} else if toGlacier.Reports.Incomplete() {
+			exitCode = exitCodePartial
+		}
+	}
+
+	if cfg.Email.Server == "" {
+		logger.Info("no e-mail server configured, report not sent")
+		return nil
+	}
+
+	// there's no schedule when running once, only the configuration is
+	// identified
+	toGlacier.Reports.Add(report.NewSchedule(cfg.Fingerprint()))
+
+	if err := toGlacier.SendReport(currentEmailInfo()); err != nil {
+		logger.Error(err)
+
+		// the backup was stored, but the administrator wasn't notified
+		if exitCode == exitCodeSuccess {
+			exitCode = exitCodePartial
+		}
+	}
+
+	return nil
+}
+
 func commandReport(c *cli.Context) error {
 	test := report.NewTest()
 	test.Errors = append(test.Errors, errors.New("simulated error 1"))
 	test.Errors = append(test.Errors, errors.New("simulated error 2"))
 	test.Errors = append(test.Errors, errors.New("simulated error 3"))
 
-	report.Add(test)
+	toGlacier.Reports.Add(test)
 
-	emailInfo := toglacier.EmailInfo{
-		Sender:   toglacier.EmailSenderFunc(smtp.SendMail),
-		Server:   config.Current().Email.Server,
-		Port:     config.Current().Email.Port,
-		Username: config.Current().Email.Username,
-		Password: config.Current().Email.Password.Value,
-		From:     config.Current().Email.From,
-		To:       config.Current().Email.To,
-		Format:   report.Format(config.Current().Email.Format),
+	if err := toGlacier.SendReport(currentEmailInfo()); err != nil {
+		logger.Error(err)
 	}
 
-	if err := toGlacier.SendReport(emailInfo); err != nil {
-		logger.Error(err)
+	return nil
+}
+
+func commandConfigSchema(c *cli.Context) error {
+	schema, err := config.Schema()
+	if err != nil {
+		logger.Errorf("error generating the configuration schema. details: %s", err)
+		return nil
 	}
 
+	fmt.Print(string(schema))
 	return nil
 }
 
@@ -442,9 +1954,193 @@ func commandEncrypt(c *cli.Context) error {
 	return nil
 }
 
-// jobFunc is used only to implement inline functions in the scheduler.
+// newBackupFailurePolicy retries the failed backups and sends an alert after
+// the configured number of consecutive failures.
+func newBackupFailurePolicy() *toglacier.FailurePolicy {
+	return &toglacier.FailurePolicy{
+		RetryDelay:    cfg.Failure.RetryDelay,
+		EscalateAfter: cfg.Failure.EscalateAfter,
+		Logger:        logger,
+		Escalate: func(failures int, err error) {
+			escalation := report.NewEscalation("backup", failures)
+			escalation.Errors = append(escalation.Errors, err)
+			escalation.Log = runLog.Lines()
+
+			if err := toGlacier.SendAlert(currentEmailInfo(), escalation); err != nil {
+				logger.Error(err)
+			}
+		},
+	}
+}
+
+// runBackup sends the backup of the configured paths following the failure
+// policy. The log lines of previous runs are discarded, so only the lines of
+// this run are attached to the alert e-mail. Backups triggered at the same
+// time (scheduler and webhook) run one after the other. It informs when the
+// backup is deferred until approved, as it isn't a failure, but it also wasn't
+// sent.
+func runBackup(failurePolicy *toglacier.FailurePolicy, ignorePatterns []*regexp.Regexp, comment string) (deferred bool, err error) {
+	backupLock.Lock()
+	defer backupLock.Unlock()
+
+	runLog.Reset()
+
+	// a deferred backup isn't built again before the approval
+	if waitingApproval() {
+		return true, nil
+	}
+
+	err = failurePolicy.Run(ctx, func() error {
+		return toGlacier.Backup(
+			cfg.Paths,
+			cfg.BackupSecret.Value,
+			float64(cfg.ModifyTolerance),
+			ignorePatterns,
+			comment,
+		)
+	})
+
+	// a backup above the confirmation threshold waits for the approval
+	if notifyApproval(err) {
+		return true, nil
+	}
+
+	return false, err
+}
+
+// scheduleReport lists when each scheduled action will run again and the
+// fingerprint of the active configuration.
+func scheduleReport() report.Schedule {
+	schedule := report.NewSchedule(cfg.Fingerprint())
+
+	schedulers := []struct {
+		action    string
+		scheduler config.Scheduler
+		disabled  bool
+	}{
+		{action: "backup", scheduler: cfg.Scheduler.Backup},
+		{action: "remove old backups", scheduler: cfg.Scheduler.RemoveOldBackups},
+		{action: "list remote backups", scheduler: cfg.Scheduler.ListRemoteBackups},
+		{action: "send report", scheduler: cfg.Scheduler.SendReport},
+		{action: "send spooled", scheduler: cfg.Scheduler.SendSpooled, disabled: toGlacier.Spool == nil},
+		{action: "empty trash", scheduler: cfg.Scheduler.EmptyTrash, disabled: toGlacier.Trash == nil},
+	}
+
+	now := toGlacier.Clock.Now().In(cfg.Scheduler.Timezone.Location())
+	for _, s := range schedulers {
+		if s.scheduler.Value == nil || s.disabled {
+			continue
+		}
+
+		// the actions activated inside a blackout window run when it closes
+		next := s.scheduler.Value.Next(now)
+		if until, ok := blackoutUntil(next); ok {
+			next = until
+		}
+
+		schedule.NextRuns = append(schedule.NextRuns, report.ScheduledAction{
+			Action: s.action,
+			Next:   next,
+		})
+	}
+
+	return schedule
+}
+
+// pinsReport lists the backups with an active hold, so the administrators are
+// periodically reminded of what is protected from the old backups removal.
+func pinsReport() report.Pins {
+	pinsReport := report.NewPins()
+
+	pins, err := toGlacier.Pins()
+	if err != nil {
+		pinsReport.Errors = append(pinsReport.Errors, err)
+		return pinsReport
+	}
+
+	now := toGlacier.Clock.Now()
+	for _, pin := range pins {
+		if !pin.Active(now) {
+			continue
+		}
+
+		pinsReport.Pinned = append(pinsReport.Pinned, report.PinnedBackup{
+			ID:       pin.ID,
+			User:     pin.User,
+			Reason:   pin.Reason,
+			PinnedAt: pin.CreatedAt,
+			Until:    pin.Until,
+		})
+	}
+
+	return pinsReport
+}
+
+// notificationEvents builds the receivers of the actions events from the
+// configured notification channels.
+func notificationEvents() toglacier.MultiEvents {
+	var events toglacier.MultiEvents
+
+	if cfg.Desktop.Enabled {
+		events = append(events, toglacier.DesktopNotifier{
+			ErrorsOnly: cfg.Desktop.ErrorsOnly,
+			Logger:     logger,
+		})
+	}
+
+	if nagios := cfg.Notifications.Nagios; nagios.CommandFile != "" {
+		host := nagios.Host
+		if host == "" {
+			host = cfg.MachineID
+		}
+
+		events = append(events, toglacier.NagiosCheck{
+			CommandFile: nagios.CommandFile,
+			Host:        host,
+			Service:     nagios.Service,
+			Logger:      logger,
+		})
+	}
+
+	return events
+}
+
+// currentEmailInfo builds the e-mail parameters from the current
+// configuration.
+func currentEmailInfo() toglacier.EmailInfo {
+	return toglacier.EmailInfo{
+		Sender:   emailSender(),
+		Server:   cfg.Email.Server,
+		Port:     cfg.Email.Port,
+		Username: cfg.Email.Username,
+		Password: cfg.Email.Password.Value,
+		From:     cfg.Email.From,
+		To:       cfg.Email.To,
+		Format:   report.Format(cfg.Email.Format),
+	}
+}
+
+// emailSender connects to the SMTP server through the proxy when configured.
+func emailSender() toglacier.EmailSender {
+	if cfg.Proxy.Value == nil {
+		return toglacier.EmailSenderFunc(smtp.SendMail)
+	}
+
+	return toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		dialer, err := proxy.NewDialer(cfg.Proxy.Value)
+		if err != nil {
+			return err
+		}
+
+		return proxy.SendMail(dialer, addr, a, from, to, msg)
+	})
+}
+
+// jobFunc is used only to implement inline functions in the scheduler. In the
+// stateless mode the local storage is saved in the cloud after each action.
 type jobFunc func()
 
 func (j jobFunc) Run() {
 	j()
+	saveState()
 }
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/config"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+	"github.com/urfave/cli"
+)
+
+func TestCommandRunBackup(t *testing.T) {
+	logger = logrus.New()
+	logger.Out = ioutil.Discard
+	runLog = newLogTail(10)
+	ctx = context.Background()
+
+	scenarios := []struct {
+		description      string
+		once             bool
+		init             func(dir string)
+		expectedExitCode int
+		expectedDeferred bool
+	}{
+		{
+			description:      "it should exit with the not approved code when the backup is deferred until approved",
+			once:             true,
+			expectedExitCode: exitCodeNotApproved,
+			expectedDeferred: true,
+		},
+		{
+			description: "it should exit with the not approved code while the deferred backup waits for the approval",
+			once:        true,
+			init: func(dir string) {
+				if err := ioutil.WriteFile(path.Join(dir, "toglacier.db.deferred"), []byte("1024\n"), 0600); err != nil {
+					t.Fatalf("error creating the deferred backup. details: %s", err)
+				}
+			},
+			expectedExitCode: exitCodeNotApproved,
+			expectedDeferred: true,
+		},
+		{
+			description:      "it should exit with the configuration error code without the once flag",
+			expectedExitCode: exitCodeConfigError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-")
+			if err != nil {
+				t.Fatalf("error creating a temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			dataDir := path.Join(dir, "data")
+			if err = os.Mkdir(dataDir, 0700); err != nil {
+				t.Fatalf("error creating the data directory. details: %s", err)
+			}
+
+			if err = ioutil.WriteFile(path.Join(dataDir, "file1.txt"), []byte("content of file1"), 0600); err != nil {
+				t.Fatalf("error creating file. details: %s", err)
+			}
+
+			if scenario.init != nil {
+				scenario.init(dir)
+			}
+
+			cfg = new(config.Config)
+			cfg.Database.File = path.Join(dir, "toglacier.db")
+			cfg.Paths = []string{dataDir}
+
+			// without the approval the backups above the confirmation threshold are
+			// deferred, before reaching the cloud
+			toGlacier = &toglacier.ToGlacier{
+				Context:      context.Background(),
+				Archive:      archive.NewTARBuilder(logger),
+				Envelop:      archive.NewOFBEnvelop(logger),
+				Storage:      storage.NewAuditFile(logger, cfg.Database.File),
+				Logger:       logger,
+				Reports:      report.NewCollector(),
+				ConfirmAbove: 1,
+			}
+
+			set := flag.NewFlagSet("backup", flag.ContinueOnError)
+			set.Bool("once", scenario.once, "")
+
+			exitCode = exitCodeSuccess
+			if err := commandRunBackup(cli.NewContext(nil, set, nil)); err != nil {
+				t.Fatalf("unexpected error “%v”", err)
+			}
+
+			if exitCode != scenario.expectedExitCode {
+				t.Errorf("exit codes don't match. expected “%d” and got “%d”", scenario.expectedExitCode, exitCode)
+			}
+
+			if _, deferred := pendingDeferral(); deferred != scenario.expectedDeferred {
+				t.Errorf("deferred backup don't match. expected “%t” and got “%t”", scenario.expectedDeferred, deferred)
+			}
+		})
+	}
+}
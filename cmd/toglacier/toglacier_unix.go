@@ -24,3 +24,24 @@ func manageSignals(cancel context.CancelFunc, cancelFunc func()) {
 		cancel()
 	}()
 }
+
+// tryLockFile acquires an exclusive advisory lock on the file without
+// blocking, returning false when other process is holding it.
+func tryLockFile(file *os.File) (bool, error) {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// useTempDir creates the temporary files of the process (and of the commands
+// it runs) in the directory, instead of the system temporary directory.
+func useTempDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	return os.Setenv("TMPDIR", dir)
+}
@@ -7,6 +7,21 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"unsafe"
+)
+
+var (
+	modkernel32    = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx = modkernel32.NewProc("LockFileEx")
+)
+
+const (
+	// see https://msdn.microsoft.com/en-us/library/windows/desktop/aa365203(v=vs.85).aspx
+	flagLockExclusive       = 2
+	flagLockFailImmediately = 1
+
+	// see https://msdn.microsoft.com/en-us/library/windows/desktop/ms681382(v=vs.85).aspx
+	errLockViolation syscall.Errno = 0x21
 )
 
 func manageSignals(cancel context.CancelFunc, cancelFunc func()) {
@@ -24,3 +39,33 @@ func manageSignals(cancel context.CancelFunc, cancelFunc func()) {
 		cancel()
 	}()
 }
+
+// tryLockFile acquires an exclusive lock on the first byte of the file without
+// blocking, returning false when other process is holding it.
+func tryLockFile(file *os.File) (bool, error) {
+	var overlapped syscall.Overlapped
+	r, _, err := procLockFileEx.Call(file.Fd(), flagLockExclusive|flagLockFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r != 0 {
+		return true, nil
+	}
+
+	if err == errLockViolation {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// useTempDir creates the temporary files of the process (and of the commands
+// it runs) in the directory, instead of the temporary directory of the user.
+func useTempDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	if err := os.Setenv("TMP", dir); err != nil {
+		return err
+	}
+
+	return os.Setenv("TEMP", dir)
+}
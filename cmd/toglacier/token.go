@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/rafaeljusto/toglacier/internal/config"
+	"github.com/urfave/cli"
+)
+
+// tokenSize is the number of random bytes of the generated tokens.
+const tokenSize = 32
+
+// scoped runs the command only when the token informed in the global --token
+// flag grants the scope. Without tokens in the configuration all commands are
+// allowed. The restriction is only advisory, as whoever runs the command can
+// read the configuration and the cloud credentials.
+func scoped(scope config.Scope, action func(*cli.Context) error) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		if !authorized(c, scope) {
+			return nil
+		}
+
+		return action(c)
+	}
+}
+
+// authorized checks if the token informed in the global --token flag grants
+// the scope, telling the user when it doesn't.
+func authorized(c *cli.Context, scope config.Scope) bool {
+	if len(cfg.Tokens) == 0 {
+		return true
+	}
+
+	token, ok := cfg.FindToken(c.GlobalString("token"))
+	if !ok {
+		fmt.Println("invalid or missing token, inform it with the --token flag")
+		exitCode = exitCodeFailure
+		return false
+	}
+
+	if !token.Allows(scope) {
+		fmt.Printf("token “%s” can't run this command, the scope “%s” is required\n", token.Name, scope)
+		exitCode = exitCodeFailure
+		return false
+	}
+
+	return true
+}
+
+// commandToken generates a random token, showing the hash that is stored in
+// the configuration together with the name and the scopes of the token.
+func commandToken(c *cli.Context) error {
+	value := make([]byte, tokenSize)
+	if _, err := rand.Read(value); err != nil {
+		logger.Errorf("error generating token. details: %s", err)
+		return nil
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(value)
+	fmt.Printf("token: %s\n", token)
+	fmt.Printf("hash: %s\n", config.TokenHash(token))
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+// uploadStatus is the progress of the current upload, stored in a file so the
+// status command (another process) can check if a long upload is on track.
+type uploadStatus struct {
+	cloud.UploadStatus
+
+	UpdatedAt time.Time
+}
+
+// uploadStatusFilename returns the file where the progress of the current
+// upload is stored.
+func uploadStatusFilename() string {
+	return cfg.Database.File + ".upload"
+}
+
+// uploadStatusFile stores the progress of the uploads in a file, removing it
+// when the upload finishes, even on failure. When many routes are sent at the
+// same time, the file contains the most recent progress of any of them.
+type uploadStatusFile struct {
+	filename string
+}
+
+// OnUploadProgress stores the progress of the upload. The file is replaced at
+// once, so the status command never reads a partial content. Failures are only
+// logged, as they don't affect the upload.
+func (u uploadStatusFile) OnUploadProgress(status cloud.UploadStatus) {
+	if status.Sent >= status.Total {
+		os.Remove(u.filename)
+		return
+	}
+
+	content, err := json.Marshal(uploadStatus{UploadStatus: status, UpdatedAt: time.Now()})
+	if err != nil {
+		logger.Warningf("toglacier: failed to encode the upload progress. details: %s", err)
+		return
+	}
+
+	if err = u.write(content); err != nil {
+		logger.Warningf("toglacier: failed to store the upload progress. details: %s", err)
+	}
+}
+
+// OnUploadFinished removes the progress of the upload.
+func (u uploadStatusFile) OnUploadFinished() {
+	if err := os.Remove(u.filename); err != nil && !os.IsNotExist(err) {
+		logger.Warningf("toglacier: failed to remove the upload progress. details: %s", err)
+	}
+}
+
+// write stores the content in a temporary file in the same directory, renaming
+// it to the status file. The temporary file is removed on failure.
+func (u uploadStatusFile) write(content []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(u.filename), filepath.Base(u.filename)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), u.filename)
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+// readUploadStatus returns the progress of the current upload. When there's no
+// upload in progress ok is false.
+func readUploadStatus(filename string) (status uploadStatus, ok bool, err error) {
+	content, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return uploadStatus{}, false, nil
+	} else if err != nil {
+		return uploadStatus{}, false, err
+	}
+
+	if err = json.Unmarshal(content, &status); err != nil {
+		return uploadStatus{}, false, err
+	}
+
+	return status, true, nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/config"
+)
+
+// webhookShutdownTimeout limits the time waiting for the pending requests when
+// the webhook is stopped. The triggered backups aren't waited, as they run in
+// background.
+const webhookShutdownTimeout = 5 * time.Second
+
+// webhook receives the requests of external systems (e.g. CI pipelines) to run
+// an immediate backup, with the same failure policy of the scheduled backups.
+type webhook struct {
+	failurePolicy  *toglacier.FailurePolicy
+	ignorePatterns []*regexp.Regexp
+	running        int32
+}
+
+// startWebhook listens for the trigger requests in the configured address,
+// using TLS when the certificate and the key are informed or self-signed. As
+// the webhook sends backups, it is only started when there are tokens to
+// authenticate the requests. The returned function stops the webhook.
+//
+// On error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func startWebhook(failurePolicy *toglacier.FailurePolicy, ignorePatterns []*regexp.Regexp) (func(), error) {
+	if cfg.Webhook.Address == "" {
+		return func() {}, nil
+	}
+
+	if len(cfg.Tokens) == 0 {
+		return nil, errors.WithStack(newError(cfg.Webhook.Address, ErrorCodeWebhookTokens, nil))
+	}
+
+	cert, key, err := apiListener{
+		name:       "webhook",
+		address:    cfg.Webhook.Address,
+		cert:       cfg.Webhook.Cert,
+		key:        cfg.Webhook.Key,
+		selfSigned: cfg.Webhook.SelfSigned,
+	}.tls()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	listener, err := net.Listen("tcp", cfg.Webhook.Address)
+	if err != nil {
+		return nil, errors.WithStack(newError(cfg.Webhook.Address, ErrorCodeListen, err))
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/trigger/backup", &webhook{
+		failurePolicy:  failurePolicy,
+		ignorePatterns: ignorePatterns,
+	})
+	mux.Handle("/approve/backup", approvalHook{})
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		var err error
+		if cert != "" {
+			err = server.ServeTLS(listener, cert, key)
+		} else {
+			err = server.Serve(listener)
+		}
+
+		if err != http.ErrServerClosed {
+			logger.Errorf("toglacier: webhook stopped. details: %s", err)
+		}
+	}()
+
+	logger.Infof("toglacier: webhook listening on %s", listener.Addr())
+
+	return func() {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+		defer shutdownCancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Errorf("toglacier: error stopping the webhook. details: %s", err)
+		}
+	}, nil
+}
+
+// ServeHTTP authenticates the request with a bearer token that grants the
+// backup scope, and starts the backup in background. Only one triggered backup
+// runs at a time, and the paused and blackout states of the scheduler are
+// respected.
+func (w *webhook) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	token, ok := authorizeRequest(response, request, config.ScopeBackup)
+	if !ok {
+		return
+	}
+
+	// there's a single set of paths in the configuration, so only the default
+	// profile exists
+	if profile := request.URL.Query().Get("profile"); profile != "" && profile != "default" {
+		http.Error(response, fmt.Sprintf("unknown profile “%s”", profile), http.StatusBadRequest)
+		return
+	}
+
+	if until, ok := blackoutUntil(time.Now()); ok {
+		http.Error(response, fmt.Sprintf("blackout window open until %s", until.Format(time.RFC3339)), http.StatusConflict)
+		return
+	}
+
+	if toGlacier.SkipPaused("backup") {
+		http.Error(response, "backups are paused", http.StatusConflict)
+		return
+	}
+
+	if !atomic.CompareAndSwapInt32(&w.running, 0, 1) {
+		http.Error(response, "triggered backup already running", http.StatusConflict)
+		return
+	}
+
+	comment := request.URL.Query().Get("comment")
+	logger.Infof("toglacier: backup triggered by the webhook with token “%s”", token.Name)
+
+	go func() {
+		defer atomic.StoreInt32(&w.running, 0)
+
+		if _, err := runBackup(w.failurePolicy, w.ignorePatterns, comment); err != nil {
+			logger.Error(err)
+		}
+		saveState()
+	}()
+
+	response.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(response, "backup started")
+}
+
+// approvalHook allows the next backup above the confirmation threshold, as the
+// approve command, so the deferred backup can be approved remotely.
+type approvalHook struct{}
+
+// ServeHTTP authenticates the request with a bearer token that grants the
+// backup scope, and stores the approval used by the next backup.
+func (approvalHook) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	token, ok := authorizeRequest(response, request, config.ScopeBackup)
+	if !ok {
+		return
+	}
+
+	until, err := approveNextBackup()
+	if err != nil {
+		logger.Errorf("toglacier: error storing the approval. details: %s", err)
+		http.Error(response, "error storing the approval", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Infof("toglacier: next big backup approved by the webhook with token “%s”", token.Name)
+	fmt.Fprintf(response, "backup approved until %s\n", until.Format(time.RFC3339))
+}
@@ -0,0 +1,29 @@
+package toglacier
+
+import (
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/report"
+)
+
+// backupDeadline stops the analysis of the files when the maximum duration of
+// the backup is reached, flagging the backup report as partial.
+type backupDeadline struct {
+	toGlacier ToGlacier
+	at        time.Time
+	report    *report.SendBackup
+}
+
+func (b *backupDeadline) Reached(path string) bool {
+	if b.report.Partial {
+		return true
+	}
+
+	if b.toGlacier.now().Before(b.at) {
+		return false
+	}
+
+	b.toGlacier.Logger.Warningf("toglacier: backup maximum duration of %s reached, files from “%s” on weren't analyzed", b.toGlacier.BackupMaxDuration, path)
+	b.report.Partial = true
+	return true
+}
@@ -0,0 +1,171 @@
+package toglacier_test
+
+import (
+	"context"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_BackupMaxDuration(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarning:  func(args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	lastBackup := storage.Backup{
+		Backup: cloud.Backup{
+			ID:        "AWSID122",
+			CreatedAt: time.Date(2017, 9, 13, 10, 30, 0, 0, time.UTC),
+		},
+		Info: archive.Info{
+			"data/file2.txt": archive.ItemInfo{
+				ID:       "AWSID122",
+				Status:   archive.ItemInfoStatusNew,
+				Checksum: "file2 old checksum",
+			},
+		},
+	}
+
+	scenarios := []struct {
+		description         string
+		maxDuration         time.Duration
+		expectedPartial     bool
+		expectedArchiveInfo archive.Info
+	}{
+		{
+			description:     "it should send a partial backup when the maximum duration is reached",
+			maxDuration:     90 * time.Minute,
+			expectedPartial: true,
+			expectedArchiveInfo: archive.Info{
+				"data/file1.txt": archive.ItemInfo{
+					ID:       "AWSID123",
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: "Vv8wEqa84XEXFWCDQOvteidl/ESTNUFBualiWb7rHWg=",
+				},
+				"data/file2.txt": archive.ItemInfo{
+					ID:       "AWSID122",
+					Status:   archive.ItemInfoStatusUnmodified,
+					Checksum: "file2 old checksum",
+				},
+			},
+		},
+		{
+			description: "it should analyze all files without a maximum duration",
+			expectedArchiveInfo: archive.Info{
+				"data/file1.txt": archive.ItemInfo{
+					ID:       "AWSID123",
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: "Vv8wEqa84XEXFWCDQOvteidl/ESTNUFBualiWb7rHWg=",
+				},
+				"data/file2.txt": archive.ItemInfo{
+					ID:       "AWSID123",
+					Status:   archive.ItemInfoStatusModified,
+					Checksum: "+1wZK5lXPKObAN3mgA9Xt0tr7YrX+twaiV3heiO+sHs=",
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			clock := &slowClock{now: time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)}
+
+			builder := archive.NewTARBuilder(logger)
+			builder.Clock = fakeClock{now: time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)}
+			builder.FileSystem = slowFileSystem{
+				FileSystem: archive.NewIOFileSystem(fstest.MapFS{
+					"data/file1.txt": &fstest.MapFile{Data: []byte("content of file1"), Mode: 0600},
+					"data/file2.txt": &fstest.MapFile{Data: []byte("content of file2"), Mode: 0600},
+				}),
+				clock: clock,
+			}
+
+			reports := report.NewCollector()
+			var saved storage.Backup
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Archive: builder,
+				Cloud: mockCloud{
+					mockSend: func(filename, comment string) (cloud.Backup, error) {
+						return cloud.Backup{ID: "AWSID123"}, nil
+					},
+					mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+						return cloud.Backup{ID: "AWSID124"}, nil
+					},
+				},
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return storage.Backups{lastBackup}, nil
+					},
+					mockSave: func(b storage.Backup) error {
+						saved = b
+						return nil
+					},
+				},
+				Logger:            logger,
+				Reports:           reports,
+				Clock:             clock,
+				BackupMaxDuration: scenario.maxDuration,
+			}
+
+			if err := toGlacier.Backup([]string{"data"}, "", 0, nil, ""); err != nil {
+				t.Fatalf("unexpected error “%v”", err)
+			}
+
+			backupReports := reports.Extract(func(r report.Report) bool {
+				_, ok := r.(report.SendBackup)
+				return ok
+			})
+
+			if len(backupReports) != 1 {
+				t.Fatalf("expected one backup report and got %d", len(backupReports))
+			}
+
+			if partial := backupReports[0].(report.SendBackup).Partial; partial != scenario.expectedPartial {
+				t.Errorf("unexpected partial report. expected “%t” and got “%t”", scenario.expectedPartial, partial)
+			}
+
+			if saved.Backup.Partial != scenario.expectedPartial {
+				t.Errorf("unexpected partial backup. expected “%t” and got “%t”", scenario.expectedPartial, saved.Backup.Partial)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedArchiveInfo, saved.Info) {
+				t.Errorf("archive info don't match.\n%s", Diff(scenario.expectedArchiveInfo, saved.Info))
+			}
+		})
+	}
+}
+
+// slowClock is a clock that only moves forward when files are read.
+type slowClock struct {
+	now time.Time
+}
+
+func (s *slowClock) Now() time.Time {
+	return s.now
+}
+
+// slowFileSystem takes one hour to open each file.
+type slowFileSystem struct {
+	archive.FileSystem
+	clock *slowClock
+}
+
+func (s slowFileSystem) Open(name string) (fs.File, error) {
+	s.clock.now = s.clock.now.Add(time.Hour)
+	return s.FileSystem.Open(name)
+}
@@ -0,0 +1,97 @@
+package toglacier
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/rafaeljusto/toglacier/internal/log"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// DesktopSender desktop notification API to make it easy to mock the
+// notification mechanism of the operating system.
+type DesktopSender interface {
+	Notify(title, message string) error
+}
+
+// DesktopSenderFunc helper function to create a fast implementation of the
+// DesktopSender interface.
+type DesktopSenderFunc func(title, message string) error
+
+// Notify shows the desktop notification.
+func (d DesktopSenderFunc) Notify(title, message string) error {
+	return d(title, message)
+}
+
+// DesktopNotifier pops up a notification on the machine being backed up when a
+// backup completes or an action fails, for workstation users running the tool
+// locally. It uses the notification mechanism of the operating system:
+// libnotify (notify-send) on Linux and BSD, the notification center on macOS
+// and toast notifications on Windows.
+type DesktopNotifier struct {
+	NopEvents
+
+	// Sender shows the notifications. By default the notification mechanism of
+	// the operating system is used.
+	Sender DesktopSender
+
+	// ErrorsOnly only notifies the failures, ignoring the completed backups.
+	ErrorsOnly bool
+
+	// Logger reports the notifications that couldn't be shown, as they don't
+	// interrupt the actions.
+	Logger log.Logger
+}
+
+// OnBackupComplete notifies that the backup was sent to the cloud.
+func (d DesktopNotifier) OnBackupComplete(backup storage.Backup) {
+	if d.ErrorsOnly {
+		return
+	}
+
+	d.notify(report.Translate("toglacier backup completed"),
+		report.Translate("Backup “%s” sent to the cloud.", backup.Backup.ID))
+}
+
+// OnRetrievalComplete notifies that the files of the backup were written to
+// disk.
+func (d DesktopNotifier) OnRetrievalComplete(retrieval report.RetrieveBackup) {
+	if d.ErrorsOnly {
+		return
+	}
+
+	d.notify(report.Translate("toglacier retrieval completed"),
+		report.Translate("Backup “%s” retrieved: %d files restored, %d skipped and %d failed.",
+			retrieval.ID, retrieval.Restored, retrieval.Skipped, retrieval.Failed))
+}
+
+// OnError notifies that the action failed.
+func (d DesktopNotifier) OnError(action string, err error) {
+	d.notify(report.Translate("toglacier failure"),
+		report.Translate("Action “%s” failed: %s", action, err))
+}
+
+// notify shows the notification, only logging when it isn't possible (e.g. no
+// graphical session).
+func (d DesktopNotifier) notify(title, message string) {
+	sender := d.Sender
+	if sender == nil {
+		sender = DesktopSenderFunc(notifyDesktop)
+	}
+
+	if err := sender.Notify(title, message); err != nil && d.Logger != nil {
+		d.Logger.Warningf("toglacier: failed to show desktop notification “%s”. details: %s", title, err)
+	}
+}
+
+// desktopCommandError adds the output of the command that shows the
+// notification to the error, to help the diagnosis.
+func desktopCommandError(output []byte, err error) error {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return err
+	}
+
+	return fmt.Errorf("%s: %s", err, output)
+}
@@ -0,0 +1,27 @@
+// +build darwin
+
+package toglacier
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// desktopScript shows the notification in the notification center. The title
+// and the message are read from environment variables, so they don't need to
+// be escaped in the script.
+const desktopScript = `display notification (system attribute "TOGLACIER_MESSAGE") with title (system attribute "TOGLACIER_TITLE")`
+
+// notifyDesktop shows the notification in the macOS notification center.
+func notifyDesktop(title, message string) error {
+	cmd := exec.Command("osascript", "-e", desktopScript)
+	cmd.Env = append(os.Environ(), "TOGLACIER_TITLE="+title, "TOGLACIER_MESSAGE="+message)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.WithStack(newError(nil, ErrorCodeDesktopNotification, desktopCommandError(output, err)))
+	}
+
+	return nil
+}
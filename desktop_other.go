@@ -0,0 +1,20 @@
+// +build !windows,!darwin
+
+package toglacier
+
+import (
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// notifyDesktop shows the notification with libnotify, that is available in
+// most Linux and BSD desktop environments.
+func notifyDesktop(title, message string) error {
+	output, err := exec.Command("notify-send", "--app-name=toglacier", title, message).CombinedOutput()
+	if err != nil {
+		return errors.WithStack(newError(nil, ErrorCodeDesktopNotification, desktopCommandError(output, err)))
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package toglacier_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestDesktopNotifier(t *testing.T) {
+	type notification struct {
+		title   string
+		message string
+	}
+
+	scenarios := []struct {
+		description      string
+		errorsOnly       bool
+		notifyError      error
+		event            func(events toglacier.Events)
+		expected         []notification
+		expectedWarnings []string
+	}{
+		{
+			description: "it should notify a completed backup",
+			event: func(events toglacier.Events) {
+				events.OnBackupComplete(storage.Backup{Backup: cloud.Backup{ID: "AWSID123"}})
+			},
+			expected: []notification{
+				{title: "toglacier backup completed", message: "Backup “AWSID123” sent to the cloud."},
+			},
+		},
+		{
+			description: "it should ignore a completed backup when only errors are notified",
+			errorsOnly:  true,
+			event: func(events toglacier.Events) {
+				events.OnBackupComplete(storage.Backup{Backup: cloud.Backup{ID: "AWSID123"}})
+			},
+		},
+		{
+			description: "it should notify a completed retrieval",
+			event: func(events toglacier.Events) {
+				events.OnRetrievalComplete(report.RetrieveBackup{ID: "AWSID123", Restored: 10, Skipped: 2, Failed: 1})
+			},
+			expected: []notification{
+				{title: "toglacier retrieval completed", message: "Backup “AWSID123” retrieved: 10 files restored, 2 skipped and 1 failed."},
+			},
+		},
+		{
+			description: "it should notify a failure",
+			errorsOnly:  true,
+			event: func(events toglacier.Events) {
+				events.OnError("backup", errors.New("connection error"))
+			},
+			expected: []notification{
+				{title: "toglacier failure", message: "Action “backup” failed: connection error"},
+			},
+		},
+		{
+			description: "it should ignore the other events",
+			event: func(events toglacier.Events) {
+				events.OnBackupStart([]string{"/data"})
+				events.OnBackupRemoved("AWSID123")
+			},
+		},
+		{
+			description: "it should log when the notification can't be shown",
+			notifyError: errors.New("no graphical session"),
+			event: func(events toglacier.Events) {
+				events.OnError("remove backup", errors.New("connection error"))
+			},
+			expected: []notification{
+				{title: "toglacier failure", message: "Action “remove backup” failed: connection error"},
+			},
+			expectedWarnings: []string{
+				"toglacier: failed to show desktop notification “toglacier failure”. details: no graphical session",
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var notifications []notification
+			var warnings []string
+
+			notifier := toglacier.DesktopNotifier{
+				Sender: toglacier.DesktopSenderFunc(func(title, message string) error {
+					notifications = append(notifications, notification{title: title, message: message})
+					return scenario.notifyError
+				}),
+				ErrorsOnly: scenario.errorsOnly,
+				Logger: mockLogger{
+					mockWarningf: func(format string, args ...interface{}) {
+						warnings = append(warnings, fmt.Sprintf(format, args...))
+					},
+				},
+			}
+
+			scenario.event(notifier)
+
+			if !reflect.DeepEqual(scenario.expected, notifications) {
+				t.Errorf("notifications don't match.\n%s", Diff(scenario.expected, notifications))
+			}
+
+			if !reflect.DeepEqual(scenario.expectedWarnings, warnings) {
+				t.Errorf("warnings don't match.\n%s", Diff(scenario.expectedWarnings, warnings))
+			}
+		})
+	}
+}
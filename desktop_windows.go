@@ -0,0 +1,35 @@
+// +build windows
+
+package toglacier
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// desktopScript shows a toast notification using the Windows Runtime API. The
+// title and the message are read from environment variables, so they don't
+// need to be escaped in the script.
+const desktopScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName("text")
+$text.Item(0).AppendChild($template.CreateTextNode($env:TOGLACIER_TITLE)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode($env:TOGLACIER_MESSAGE)) > $null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier("toglacier").Show($toast)
+`
+
+// notifyDesktop shows a toast notification with PowerShell.
+func notifyDesktop(title, message string) error {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", desktopScript)
+	cmd.Env = append(os.Environ(), "TOGLACIER_TITLE="+title, "TOGLACIER_MESSAGE="+message)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.WithStack(newError(nil, ErrorCodeDesktopNotification, desktopCommandError(output, err)))
+	}
+
+	return nil
+}
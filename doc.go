@@ -1,2 +1,36 @@
 // Package toglacier have all the functions to manage your backups in the cloud.
+//
+// Other Go programs can embed toglacier as a backup library, creating the
+// instance with the New function and the desired options:
+//
+//     t, err := toglacier.New(
+//       toglacier.WithAWSCloud(accountID, accessKeyID, secretAccessKey, region, vaultName),
+//       toglacier.WithBoltDBStorage("/var/lib/toglacier/toglacier.db"),
+//       toglacier.WithLogger(logger),
+//     )
+//
+//     if err != nil {
+//       // handle the error
+//     }
+//
+//     if err := t.Backup([]string{"/data"}, "", 0, nil, ""); err != nil {
+//       // handle the error
+//     }
+//
+// To react to the backups, retrievals and removals, implement the Events
+// interface (embedding NopEvents to receive only the desired events) and inform
+// it with the WithEvents option:
+//
+//     type monitor struct {
+//       toglacier.NopEvents
+//     }
+//
+//     func (m monitor) OnError(action string, err error) {
+//       // notify the monitoring system
+//     }
+//
+//     t, err := toglacier.New(
+//       // ...
+//       toglacier.WithEvents(monitor{}),
+//     )
 package toglacier
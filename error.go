@@ -12,6 +12,140 @@ const (
 	// ErrorCodeModifyTolerance error when too many files were modified between
 	// backups. This is an alert for ransomware infection.
 	ErrorCodeModifyTolerance ErrorCode = "modify-tolerance"
+
+	// ErrorCodeCloudNotDefined error when creating a ToGlacier instance without
+	// informing the cloud service.
+	ErrorCodeCloudNotDefined ErrorCode = "cloud-not-defined"
+
+	// ErrorCodeStorageNotDefined error when creating a ToGlacier instance
+	// without informing the local storage.
+	ErrorCodeStorageNotDefined ErrorCode = "storage-not-defined"
+
+	// ErrorCodeEncodingCatalog error while building the catalog file that is
+	// sent after each backup.
+	ErrorCodeEncodingCatalog ErrorCode = "encoding-catalog"
+
+	// ErrorCodeDecodingCatalog error while reading a catalog file retrieved from
+	// the cloud.
+	ErrorCodeDecodingCatalog ErrorCode = "decoding-catalog"
+
+	// ErrorCodeCatalogNotFound error when there's no catalog in the cloud to
+	// rebuild the local storage.
+	ErrorCodeCatalogNotFound ErrorCode = "catalog-not-found"
+
+	// ErrorCodeVaultPolicyNotSupported error when trying to lock the vault of a
+	// cloud service that doesn't support vault policies.
+	ErrorCodeVaultPolicyNotSupported ErrorCode = "vault-policy-not-supported"
+
+	// ErrorCodeVaultSettingsNotSupported error when trying to change the access
+	// policy or tags of the vault in a cloud service that doesn't support it.
+	ErrorCodeVaultSettingsNotSupported ErrorCode = "vault-settings-not-supported"
+
+	// ErrorCodeEmptyStorage error when looking for orphan archives without any
+	// backup in the local storage, as all archives would be considered orphans.
+	ErrorCodeEmptyStorage ErrorCode = "empty-storage"
+
+	// ErrorCodeStreamBackup error when trying to extract the backup of a stream,
+	// that can only be retrieved to a stream.
+	ErrorCodeStreamBackup ErrorCode = "stream-backup"
+
+	// ErrorCodeReadingStream error while storing the data of the stream before
+	// sending it to the cloud.
+	ErrorCodeReadingStream ErrorCode = "reading-stream"
+
+	// ErrorCodeWritingStream error while writing the retrieved backup to the
+	// stream.
+	ErrorCodeWritingStream ErrorCode = "writing-stream"
+
+	// ErrorCodeSource error while running the command of a source, or while
+	// storing its output.
+	ErrorCodeSource ErrorCode = "source"
+
+	// ErrorCodeStateNotSupported error when trying to keep the state of the
+	// local storage in a cloud service that doesn't support it.
+	ErrorCodeStateNotSupported ErrorCode = "state-not-supported"
+
+	// ErrorCodeEncodingState error while building the state file of the local
+	// storage.
+	ErrorCodeEncodingState ErrorCode = "encoding-state"
+
+	// ErrorCodeDecodingState error while reading the state file of the local
+	// storage retrieved from the cloud.
+	ErrorCodeDecodingState ErrorCode = "decoding-state"
+
+	// ErrorCodeRemovingBackups error when some of the old backups couldn't be
+	// removed. The other old backups are still removed.
+	ErrorCodeRemovingBackups ErrorCode = "removing-backups"
+
+	// ErrorCodeBackupNotFound error when trying to move to the trash a backup
+	// that doesn't exist in the local storage.
+	ErrorCodeBackupNotFound ErrorCode = "backup-not-found"
+
+	// ErrorCodeNotTrashed error when trying to restore a backup that isn't in
+	// the trash.
+	ErrorCodeNotTrashed ErrorCode = "not-trashed"
+
+	// ErrorCodeEmptyingTrash error when some of the expired backups of the trash
+	// couldn't be removed. They stay in the trash for the next attempt.
+	ErrorCodeEmptyingTrash ErrorCode = "emptying-trash"
+
+	// ErrorCodeDesktopNotification error while running the command of the
+	// operating system that shows the desktop notification.
+	ErrorCodeDesktopNotification ErrorCode = "desktop-notification"
+
+	// ErrorCodeTelegram error while sending a message to the Telegram Bot API.
+	ErrorCodeTelegram ErrorCode = "telegram"
+
+	// ErrorCodeNagios error while writing the passive check result to the
+	// external command file of Nagios or Icinga.
+	ErrorCodeNagios ErrorCode = "nagios"
+
+	// ErrorCodeTagsNotSupported error when trying to name a backup in a local
+	// storage that doesn't keep tags.
+	ErrorCodeTagsNotSupported ErrorCode = "tags-not-supported"
+
+	// ErrorCodeInvalidTag error when the name of the backup is empty, reserved
+	// or can be confused with a backup id.
+	ErrorCodeInvalidTag ErrorCode = "invalid-tag"
+
+	// ErrorCodeMount error while serving the files of the backup in a
+	// read-only filesystem.
+	ErrorCodeMount ErrorCode = "mount"
+
+	// ErrorCodeMountNotSupported error when trying to mount a backup in an
+	// operating system without FUSE.
+	ErrorCodeMountNotSupported ErrorCode = "mount-not-supported"
+
+	// ErrorCodeJobsNotSupported error when trying to follow the jobs of a cloud
+	// without asynchronous jobs.
+	ErrorCodeJobsNotSupported ErrorCode = "jobs-not-supported"
+
+	// ErrorCodeMissingSecret error when the header of the retrieved archive
+	// shows that it is encrypted, but no backup secret was informed.
+	ErrorCodeMissingSecret ErrorCode = "missing-secret"
+
+	// ErrorCodeUnencryptedArchive error when the header of the retrieved
+	// archive shows that it isn't encrypted, but a backup secret was informed.
+	// As the header isn't authenticated, the archive could have been replaced.
+	ErrorCodeUnencryptedArchive ErrorCode = "unencrypted-archive"
+
+	// ErrorCodeReadOnly error when trying to change the backups in the cloud in
+	// the read-only mode.
+	ErrorCodeReadOnly ErrorCode = "read-only"
+
+	// ErrorCodeApprovalRequired error when the archive of the backup is bigger
+	// than the confirmation threshold and the upload wasn't approved.
+	ErrorCodeApprovalRequired ErrorCode = "approval-required"
+
+	// ErrorCodeUnknownReferences error when removing old backups while the
+	// archive information of a kept backup isn't in the local storage and
+	// couldn't be retrieved from the cloud, so the archives that it references
+	// are unknown.
+	ErrorCodeUnknownReferences ErrorCode = "unknown-references"
+
+	// ErrorCodeArchiveInfoNotFound error when the archive information of a
+	// backup isn't in the local storage, in the catalog or in the archive.
+	ErrorCodeArchiveInfoNotFound ErrorCode = "archive-info-not-found"
 )
 
 // ErrorCode stores the error type that occurred while processing commands from
@@ -23,11 +157,99 @@ func (e ErrorCode) String() string {
 	switch e {
 	case ErrorCodeModifyTolerance:
 		return "too many files modified, aborting for precaution"
+	case ErrorCodeCloudNotDefined:
+		return "cloud service not defined"
+	case ErrorCodeStorageNotDefined:
+		return "local storage not defined"
+	case ErrorCodeEncodingCatalog:
+		return "error encoding catalog"
+	case ErrorCodeDecodingCatalog:
+		return "error decoding catalog"
+	case ErrorCodeCatalogNotFound:
+		return "no catalog found in the cloud"
+	case ErrorCodeVaultPolicyNotSupported:
+		return "cloud doesn't support vault policies"
+	case ErrorCodeVaultSettingsNotSupported:
+		return "cloud doesn't support vault settings"
+	case ErrorCodeEmptyStorage:
+		return "local storage is empty, bootstrap it from a catalog first"
+	case ErrorCodeStreamBackup:
+		return "backup of a stream, retrieve it to a stream"
+	case ErrorCodeReadingStream:
+		return "error reading stream"
+	case ErrorCodeWritingStream:
+		return "error writing stream"
+	case ErrorCodeSource:
+		return "error running source"
+	case ErrorCodeStateNotSupported:
+		return "cloud doesn't support keeping the state"
+	case ErrorCodeEncodingState:
+		return "error encoding state"
+	case ErrorCodeDecodingState:
+		return "error decoding state"
+	case ErrorCodeRemovingBackups:
+		return "error removing old backups"
+	case ErrorCodeBackupNotFound:
+		return "backup not found"
+	case ErrorCodeNotTrashed:
+		return "backup not in the trash"
+	case ErrorCodeEmptyingTrash:
+		return "error emptying the trash"
+	case ErrorCodeDesktopNotification:
+		return "error showing desktop notification"
+	case ErrorCodeTelegram:
+		return "error sending telegram message"
+	case ErrorCodeNagios:
+		return "error writing nagios check result"
+	case ErrorCodeTagsNotSupported:
+		return "local storage doesn't support tags"
+	case ErrorCodeInvalidTag:
+		return "invalid tag name"
+	case ErrorCodeMount:
+		return "error mounting backup"
+	case ErrorCodeMountNotSupported:
+		return "operating system doesn't support mounting backups"
+	case ErrorCodeJobsNotSupported:
+		return "cloud doesn't support following jobs"
+	case ErrorCodeMissingSecret:
+		return "archive is encrypted, but the backup secret is missing"
+	case ErrorCodeUnencryptedArchive:
+		return "archive isn't encrypted, but a backup secret is configured"
+	case ErrorCodeReadOnly:
+		return "operation disabled in the read-only mode"
+	case ErrorCodeApprovalRequired:
+		return "backup bigger than the confirmation threshold, waiting for approval"
+	case ErrorCodeUnknownReferences:
+		return "archive information of kept backups is missing, old backups not removed"
+	case ErrorCodeArchiveInfoNotFound:
+		return "archive information of the backup not found"
 	}
 
 	return "unknown error code"
 }
 
+var (
+	// ErrModifyTolerance is matched with errors.Is when the backup was refused
+	// because too many files were modified, a possible ransomware infection.
+	ErrModifyTolerance = errors.New("toglacier: too many files modified")
+
+	// ErrCatalogNotFound is matched with errors.Is when there's no catalog in
+	// the cloud to rebuild the local storage.
+	ErrCatalogNotFound = errors.New("toglacier: catalog not found")
+
+	// ErrEmptyStorage is matched with errors.Is when looking for orphan archives
+	// without any backup in the local storage.
+	ErrEmptyStorage = errors.New("toglacier: empty local storage")
+
+	// ErrReadOnly is matched with errors.Is when the operation was refused
+	// because of the read-only mode.
+	ErrReadOnly = errors.New("toglacier: read-only mode")
+
+	// ErrApprovalRequired is matched with errors.Is when the backup was deferred
+	// because its archive is bigger than the confirmation threshold.
+	ErrApprovalRequired = errors.New("toglacier: approval required")
+)
+
 // Error stores error details from a problem occurred while executing high level
 // commands from toglacier.
 type Error struct {
@@ -49,6 +271,31 @@ func (e Error) Error() string {
 	return e.String()
 }
 
+// Unwrap returns the low level error, so it can be inspected with errors.Is and
+// errors.As.
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// Is allows comparing the error with the sentinel errors of the package (e.g.
+// errors.Is(err, toglacier.ErrModifyTolerance)).
+func (e Error) Is(target error) bool {
+	switch target {
+	case ErrModifyTolerance:
+		return e.Code == ErrorCodeModifyTolerance
+	case ErrCatalogNotFound:
+		return e.Code == ErrorCodeCatalogNotFound
+	case ErrEmptyStorage:
+		return e.Code == ErrorCodeEmptyStorage
+	case ErrReadOnly:
+		return e.Code == ErrorCodeReadOnly
+	case ErrApprovalRequired:
+		return e.Code == ErrorCodeApprovalRequired
+	}
+
+	return false
+}
+
 // String translate the error to a human readable text.
 func (e Error) String() string {
 	var paths string
@@ -71,10 +318,8 @@ func ErrorEqual(first, second error) bool {
 		return first == second
 	}
 
-	err1, ok1 := errors.Cause(first).(*Error)
-	err2, ok2 := errors.Cause(second).(*Error)
-
-	if !ok1 || !ok2 {
+	var err1, err2 *Error
+	if !errors.As(first, &err1) || !errors.As(second, &err2) {
 		return false
 	}
 
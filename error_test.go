@@ -1,10 +1,11 @@
 package toglacier_test
 
 import (
-	"errors"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
 )
 
 func TestError_Error(t *testing.T) {
@@ -43,6 +44,171 @@ func TestError_Error(t *testing.T) {
 			err:         &toglacier.Error{Code: toglacier.ErrorCodeModifyTolerance},
 			expected:    "toglacier: too many files modified, aborting for precaution",
 		},
+		{
+			description: "it should show the correct error message for cloud not defined",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeCloudNotDefined},
+			expected:    "toglacier: cloud service not defined",
+		},
+		{
+			description: "it should show the correct error message for storage not defined",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeStorageNotDefined},
+			expected:    "toglacier: local storage not defined",
+		},
+		{
+			description: "it should show the correct error message for catalog encoding problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeEncodingCatalog},
+			expected:    "toglacier: error encoding catalog",
+		},
+		{
+			description: "it should show the correct error message for catalog decoding problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeDecodingCatalog},
+			expected:    "toglacier: error decoding catalog",
+		},
+		{
+			description: "it should show the correct error message for catalog not found",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeCatalogNotFound},
+			expected:    "toglacier: no catalog found in the cloud",
+		},
+		{
+			description: "it should show the correct error message for vault policy not supported",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeVaultPolicyNotSupported},
+			expected:    "toglacier: cloud doesn't support vault policies",
+		},
+		{
+			description: "it should show the correct error message for vault settings not supported",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeVaultSettingsNotSupported},
+			expected:    "toglacier: cloud doesn't support vault settings",
+		},
+		{
+			description: "it should show the correct error message for empty local storage",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeEmptyStorage},
+			expected:    "toglacier: local storage is empty, bootstrap it from a catalog first",
+		},
+		{
+			description: "it should show the correct error message for stream backup",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeStreamBackup},
+			expected:    "toglacier: backup of a stream, retrieve it to a stream",
+		},
+		{
+			description: "it should show the correct error message for stream reading problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeReadingStream},
+			expected:    "toglacier: error reading stream",
+		},
+		{
+			description: "it should show the correct error message for stream writing problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeWritingStream},
+			expected:    "toglacier: error writing stream",
+		},
+		{
+			description: "it should show the correct error message for source problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeSource},
+			expected:    "toglacier: error running source",
+		},
+		{
+			description: "it should show the correct error message for state not supported",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeStateNotSupported},
+			expected:    "toglacier: cloud doesn't support keeping the state",
+		},
+		{
+			description: "it should show the correct error message for state encoding problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeEncodingState},
+			expected:    "toglacier: error encoding state",
+		},
+		{
+			description: "it should show the correct error message for state decoding problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeDecodingState},
+			expected:    "toglacier: error decoding state",
+		},
+		{
+			description: "it should show the correct error message for old backups removal problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeRemovingBackups},
+			expected:    "toglacier: error removing old backups",
+		},
+		{
+			description: "it should show the correct error message for backup not found problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeBackupNotFound},
+			expected:    "toglacier: backup not found",
+		},
+		{
+			description: "it should show the correct error message for backup not in the trash problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeNotTrashed},
+			expected:    "toglacier: backup not in the trash",
+		},
+		{
+			description: "it should show the correct error message for trash emptying problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeEmptyingTrash},
+			expected:    "toglacier: error emptying the trash",
+		},
+		{
+			description: "it should show the correct error message for desktop notification problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeDesktopNotification},
+			expected:    "toglacier: error showing desktop notification",
+		},
+		{
+			description: "it should show the correct error message for telegram problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeTelegram},
+			expected:    "toglacier: error sending telegram message",
+		},
+		{
+			description: "it should show the correct error message for nagios problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeNagios},
+			expected:    "toglacier: error writing nagios check result",
+		},
+		{
+			description: "it should show the correct error message for tags not supported",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeTagsNotSupported},
+			expected:    "toglacier: local storage doesn't support tags",
+		},
+		{
+			description: "it should show the correct error message for invalid tag",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeInvalidTag},
+			expected:    "toglacier: invalid tag name",
+		},
+		{
+			description: "it should show the correct error message for mount problem",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeMount},
+			expected:    "toglacier: error mounting backup",
+		},
+		{
+			description: "it should show the correct error message for mount not supported",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeMountNotSupported},
+			expected:    "toglacier: operating system doesn't support mounting backups",
+		},
+		{
+			description: "it should show the correct error message for jobs not supported",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeJobsNotSupported},
+			expected:    "toglacier: cloud doesn't support following jobs",
+		},
+		{
+			description: "it should show the correct error message for missing backup secret",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeMissingSecret},
+			expected:    "toglacier: archive is encrypted, but the backup secret is missing",
+		},
+		{
+			description: "it should show the correct error message for unencrypted archive",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeUnencryptedArchive},
+			expected:    "toglacier: archive isn't encrypted, but a backup secret is configured",
+		},
+		{
+			description: "it should show the correct error message for read-only mode",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeReadOnly},
+			expected:    "toglacier: operation disabled in the read-only mode",
+		},
+		{
+			description: "it should show the correct error message for approval required",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeApprovalRequired},
+			expected:    "toglacier: backup bigger than the confirmation threshold, waiting for approval",
+		},
+		{
+			description: "it should show the correct error message for unknown references",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeUnknownReferences},
+			expected:    "toglacier: archive information of kept backups is missing, old backups not removed",
+		},
+		{
+			description: "it should show the correct error message for archive information not found",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeArchiveInfoNotFound},
+			expected:    "toglacier: archive information of the backup not found",
+		},
 		{
 			description: "it should detect when the code doesn't exist",
 			err:         &toglacier.Error{Code: toglacier.ErrorCode("i-dont-exist")},
@@ -59,6 +225,68 @@ func TestError_Error(t *testing.T) {
 	}
 }
 
+func TestError_Is(t *testing.T) {
+	scenarios := []struct {
+		description string
+		err         error
+		target      error
+		expected    bool
+	}{
+		{
+			description: "it should detect when too many files were modified",
+			err:         errors.Wrap(&toglacier.Error{Code: toglacier.ErrorCodeModifyTolerance}, "backup"),
+			target:      toglacier.ErrModifyTolerance,
+			expected:    true,
+		},
+		{
+			description: "it should detect a missing catalog",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeCatalogNotFound},
+			target:      toglacier.ErrCatalogNotFound,
+			expected:    true,
+		},
+		{
+			description: "it should detect an empty local storage",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeEmptyStorage},
+			target:      toglacier.ErrEmptyStorage,
+			expected:    true,
+		},
+		{
+			description: "it should detect an operation refused in the read-only mode",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeReadOnly},
+			target:      toglacier.ErrReadOnly,
+			expected:    true,
+		},
+		{
+			description: "it should detect a backup waiting for approval",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeApprovalRequired},
+			target:      toglacier.ErrApprovalRequired,
+			expected:    true,
+		},
+		{
+			description: "it should detect the low level error of other packages",
+			err: &toglacier.Error{
+				Code: toglacier.ErrorCodeModifyTolerance,
+				Err:  &cloud.Error{Code: cloud.ErrorCodeCancelled},
+			},
+			target:   cloud.ErrCancelled,
+			expected: true,
+		},
+		{
+			description: "it should not match a different sentinel error",
+			err:         &toglacier.Error{Code: toglacier.ErrorCodeModifyTolerance},
+			target:      toglacier.ErrEmptyStorage,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if is := errors.Is(scenario.err, scenario.target); is != scenario.expected {
+				t.Errorf("results don't match. expected “%t” and got “%t”", scenario.expected, is)
+			}
+		})
+	}
+}
+
 func TestErrorEqual(t *testing.T) {
 	scenarios := []struct {
 		description string
@@ -0,0 +1,156 @@
+package toglacier
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// FailurePolicy defines how a scheduled action should react when it fails.
+// Instead of giving up at the first problem, the action is retried after
+// RetryDelay, and only after EscalateAfter consecutive failures the Escalate
+// function is called. This reduces the noise from transient problems (network
+// blips) while guaranteeing that persistent problems are reported.
+type FailurePolicy struct {
+	// RetryDelay is the amount of time to wait before retrying a failed action.
+	RetryDelay time.Duration
+
+	// EscalateAfter is the number of consecutive failures that will trigger the
+	// escalation. Values lower than 1 escalates on the first failure.
+	EscalateAfter int
+
+	// Escalate is called when the number of consecutive failures reaches the
+	// limit, with the last error detected.
+	Escalate func(failures int, err error)
+
+	// Logger is used to keep track of the retries.
+	Logger log.Logger
+
+	failures     int
+	failuresLock sync.Mutex
+}
+
+// Run executes the action following the failure policy. It will block until
+// the action succeeds, the escalation is triggered or the context is cancelled.
+// The last error detected is returned when the action didn't succeed. A backup
+// waiting for approval or cancelled by the user isn't a failure, so it is
+// returned without retrying or escalating.
+func (f *FailurePolicy) Run(ctx context.Context, action func() error) error {
+	for {
+		err := action()
+		if err == nil {
+			f.reset()
+			return nil
+		}
+
+		if errors.Is(err, ErrApprovalRequired) || cancelled(err) || errors.Cause(err) == context.Canceled {
+			return errors.WithStack(err)
+		}
+
+		failures := f.fail()
+		if failures >= f.EscalateAfter {
+			f.reset()
+			f.Logger.Warningf("toglacier: action failed %d consecutive times, escalating", failures)
+
+			if f.Escalate != nil {
+				f.Escalate(failures, err)
+			}
+			return errors.WithStack(err)
+		}
+
+		f.Logger.Infof("toglacier: action failed (%d/%d), retrying in %s. details: %s", failures, f.EscalateAfter, f.RetryDelay, err)
+
+		select {
+		case <-time.After(f.RetryDelay):
+		case <-ctx.Done():
+			return errors.WithStack(err)
+		}
+	}
+}
+
+// Failures returns the current number of consecutive failures.
+func (f *FailurePolicy) Failures() int {
+	f.failuresLock.Lock()
+	defer f.failuresLock.Unlock()
+	return f.failures
+}
+
+func (f *FailurePolicy) fail() int {
+	f.failuresLock.Lock()
+	defer f.failuresLock.Unlock()
+	f.failures++
+	return f.failures
+}
+
+func (f *FailurePolicy) reset() {
+	f.failuresLock.Lock()
+	defer f.failuresLock.Unlock()
+	f.failures = 0
+}
+
+const (
+	// DefaultStepAttempts is how many times a discrete step of the backup is
+	// executed when it fails with a temporary error, used when ToGlacier
+	// doesn't define it.
+	DefaultStepAttempts = 3
+
+	// DefaultStepRetryDelay is the amount of time to wait between the attempts
+	// of a discrete step of the backup, used when ToGlacier doesn't define it.
+	DefaultStepRetryDelay = 5 * time.Second
+)
+
+// temporary checks if the low level error could be solved by trying again. The
+// cloud and storage errors classify themselves as temporary or permanent.
+func temporary(err error) bool {
+	temporaryErr, ok := errors.Cause(err).(interface {
+		Temporary() bool
+	})
+
+	return ok && temporaryErr.Temporary()
+}
+
+// cancelled checks if the low level error was caused by the cancellation of the
+// context, so there's no reason to keep trying.
+func cancelled(err error) bool {
+	cloudErr, ok := errors.Cause(err).(*cloud.Error)
+	return ok && cloudErr.Code == cloud.ErrorCodeCancelled
+}
+
+// retryStep executes the step again while it fails with a temporary error, so
+// a transient problem after a successful upload doesn't fail the entire
+// backup. Permanent errors are returned immediately.
+func (t ToGlacier) retryStep(name string, step func() error) error {
+	attempts := t.StepAttempts
+	if attempts <= 0 {
+		attempts = DefaultStepAttempts
+	}
+
+	delay := t.StepRetryDelay
+	if delay <= 0 {
+		delay = DefaultStepRetryDelay
+	}
+
+	ctx := t.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := step()
+		if err == nil || attempt >= attempts || !temporary(err) {
+			return errors.WithStack(err)
+		}
+
+		t.Logger.Infof("toglacier: %s failed with a temporary error (%d/%d), retrying in %s. details: %s", name, attempt, attempts, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return errors.WithStack(err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package toglacier_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+func TestFailurePolicy_Run(t *testing.T) {
+	scenarios := []struct {
+		description         string
+		escalateAfter       int
+		results             []error
+		cancelled           bool
+		expectedAttempts    int
+		expectedEscalations []int
+		expectedFailures    int
+		expectedError       error
+	}{
+		{
+			description:      "it should run an action correctly at the first attempt",
+			escalateAfter:    3,
+			results:          []error{nil},
+			expectedAttempts: 1,
+		},
+		{
+			description:   "it should retry an action until it succeeds",
+			escalateAfter: 3,
+			results: []error{
+				errors.New("network blip"),
+				errors.New("network blip"),
+				nil,
+			},
+			expectedAttempts: 3,
+		},
+		{
+			description:   "it should escalate after consecutive failures",
+			escalateAfter: 3,
+			results: []error{
+				errors.New("network blip 1"),
+				errors.New("network blip 2"),
+				errors.New("network blip 3"),
+			},
+			expectedAttempts:    3,
+			expectedEscalations: []int{3},
+			expectedError:       errors.New("network blip 3"),
+		},
+		{
+			description:   "it should escalate on the first failure when there's no tolerance",
+			escalateAfter: 0,
+			results: []error{
+				errors.New("network blip"),
+			},
+			expectedAttempts:    1,
+			expectedEscalations: []int{1},
+			expectedError:       errors.New("network blip"),
+		},
+		{
+			description:   "it should stop retrying when the context is cancelled",
+			escalateAfter: 3,
+			results: []error{
+				errors.New("network blip"),
+			},
+			cancelled:        true,
+			expectedAttempts: 1,
+			expectedFailures: 1,
+			expectedError:    errors.New("network blip"),
+		},
+		{
+			description:   "it should not retry a backup waiting for approval",
+			escalateAfter: 3,
+			results: []error{
+				&toglacier.Error{Code: toglacier.ErrorCodeApprovalRequired},
+			},
+			expectedAttempts: 1,
+			expectedError:    &toglacier.Error{Code: toglacier.ErrorCodeApprovalRequired},
+		},
+		{
+			description:   "it should not retry or escalate a cancelled upload",
+			escalateAfter: 0,
+			results: []error{
+				&cloud.Error{ID: "backup-1", Code: cloud.ErrorCodeCancelled},
+			},
+			expectedAttempts: 1,
+			expectedError:    &cloud.Error{ID: "backup-1", Code: cloud.ErrorCodeCancelled},
+		},
+		{
+			description:   "it should not retry or escalate a cancelled action",
+			escalateAfter: 0,
+			results: []error{
+				errors.WithStack(context.Canceled),
+			},
+			expectedAttempts: 1,
+			expectedError:    context.Canceled,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var escalations []int
+			failurePolicy := toglacier.FailurePolicy{
+				RetryDelay:    time.Millisecond,
+				EscalateAfter: scenario.escalateAfter,
+				Escalate: func(failures int, err error) {
+					escalations = append(escalations, failures)
+				},
+				Logger: mockLogger{
+					mockDebug:    func(args ...interface{}) {},
+					mockDebugf:   func(format string, args ...interface{}) {},
+					mockInfo:     func(args ...interface{}) {},
+					mockInfof:    func(format string, args ...interface{}) {},
+					mockWarning:  func(args ...interface{}) {},
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if scenario.cancelled {
+				failurePolicy.RetryDelay = time.Hour
+				cancel()
+			}
+
+			var attempts int
+			err := failurePolicy.Run(ctx, func() error {
+				attempts++
+				if attempts > len(scenario.results) {
+					t.Fatalf("unexpected attempt %d", attempts)
+				}
+				return scenario.results[attempts-1]
+			})
+
+			if attempts != scenario.expectedAttempts {
+				t.Errorf("attempts don't match. expected “%d” and got “%d”", scenario.expectedAttempts, attempts)
+			}
+
+			if len(escalations) != len(scenario.expectedEscalations) {
+				t.Errorf("escalations don't match. expected “%v” and got “%v”", scenario.expectedEscalations, escalations)
+
+			} else {
+				for i := range escalations {
+					if escalations[i] != scenario.expectedEscalations[i] {
+						t.Errorf("escalations don't match. expected “%v” and got “%v”", scenario.expectedEscalations, escalations)
+						break
+					}
+				}
+			}
+
+			if failures := failurePolicy.Failures(); failures != scenario.expectedFailures {
+				t.Errorf("failures don't match. expected “%d” and got “%d”", scenario.expectedFailures, failures)
+			}
+
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
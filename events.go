@@ -0,0 +1,114 @@
+package toglacier
+
+import (
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// Events receives the notifications of the actions performed by ToGlacier, so
+// the caller can react to them (e.g. notify a monitoring system) without
+// parsing the reports. The methods are called synchronously from the action
+// (and concurrently when there're many routes), so they should return quickly.
+type Events interface {
+	// OnBackupStart is called before building the archive of the paths. When
+	// there are routes it is called once for each route.
+	OnBackupStart(paths []string)
+
+	// OnBackupComplete is called after the backup is sent to the cloud and saved
+	// in the local storage.
+	OnBackupComplete(backup storage.Backup)
+
+	// OnRetrievalReady is called when the archives of a backup retrieval were
+	// downloaded from the cloud, before extracting them.
+	OnRetrievalReady(id string, archiveIDs []string)
+
+	// OnRetrievalComplete is called after the files of a backup retrieval were
+	// written to disk, with the restored, skipped and failed files.
+	OnRetrievalComplete(retrieval report.RetrieveBackup)
+
+	// OnBackupRemoved is called after the backup is removed from the cloud and
+	// from the local storage.
+	OnBackupRemoved(id string)
+
+	// OnError is called when an action fails. The action can be "backup",
+	// "retrieve backup", "remove backup" or "send spooled".
+	OnError(action string, err error)
+}
+
+// NopEvents ignores all events. It can be embedded to implement only the
+// desired events.
+type NopEvents struct{}
+
+// OnBackupStart does nothing.
+func (NopEvents) OnBackupStart(paths []string) {}
+
+// OnBackupComplete does nothing.
+func (NopEvents) OnBackupComplete(backup storage.Backup) {}
+
+// OnRetrievalReady does nothing.
+func (NopEvents) OnRetrievalReady(id string, archiveIDs []string) {}
+
+// OnRetrievalComplete does nothing.
+func (NopEvents) OnRetrievalComplete(retrieval report.RetrieveBackup) {}
+
+// OnBackupRemoved does nothing.
+func (NopEvents) OnBackupRemoved(id string) {}
+
+// OnError does nothing.
+func (NopEvents) OnError(action string, err error) {}
+
+// events returns the Events defined by the caller, or an implementation that
+// ignores all events.
+func (t ToGlacier) events() Events {
+	if t.Events == nil {
+		return NopEvents{}
+	}
+
+	return t.Events
+}
+
+// MultiEvents forwards the events to all receivers, in order. Useful to
+// notify many systems at the same time (e.g. desktop and monitoring).
+type MultiEvents []Events
+
+// OnBackupStart forwards the event to all receivers.
+func (m MultiEvents) OnBackupStart(paths []string) {
+	for _, events := range m {
+		events.OnBackupStart(paths)
+	}
+}
+
+// OnBackupComplete forwards the event to all receivers.
+func (m MultiEvents) OnBackupComplete(backup storage.Backup) {
+	for _, events := range m {
+		events.OnBackupComplete(backup)
+	}
+}
+
+// OnRetrievalReady forwards the event to all receivers.
+func (m MultiEvents) OnRetrievalReady(id string, archiveIDs []string) {
+	for _, events := range m {
+		events.OnRetrievalReady(id, archiveIDs)
+	}
+}
+
+// OnRetrievalComplete forwards the event to all receivers.
+func (m MultiEvents) OnRetrievalComplete(retrieval report.RetrieveBackup) {
+	for _, events := range m {
+		events.OnRetrievalComplete(retrieval)
+	}
+}
+
+// OnBackupRemoved forwards the event to all receivers.
+func (m MultiEvents) OnBackupRemoved(id string) {
+	for _, events := range m {
+		events.OnBackupRemoved(id)
+	}
+}
+
+// OnError forwards the event to all receivers.
+func (m MultiEvents) OnError(action string, err error) {
+	for _, events := range m {
+		events.OnError(action, err)
+	}
+}
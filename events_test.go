@@ -0,0 +1,327 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_Events(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarning:  func(args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	scenarios := []struct {
+		description    string
+		archive        archive.Archive
+		cloud          cloud.Cloud
+		storage        storage.Storage
+		action         func(toglacier.ToGlacier) error
+		expectedEvents []string
+	}{
+		{
+			description: "it should notify a backup",
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					return f.Name(), archive.Info{
+						"/data/file1": archive.ItemInfo{
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test"}, nil
+				},
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123457"}, nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+				mockSave: func(b storage.Backup) error {
+					return nil
+				},
+			},
+			action: func(t toglacier.ToGlacier) error {
+				return t.Backup([]string{"/data"}, "", 0, nil, "")
+			},
+			expectedEvents: []string{
+				"backup start [/data]",
+				"backup complete 123456 (1 files)",
+			},
+		},
+		{
+			description: "it should notify a backup failure",
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					return "", nil, errors.New("path not found")
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+			},
+			action: func(t toglacier.ToGlacier) error {
+				return t.Backup([]string{"/data"}, "", 0, nil, "")
+			},
+			expectedEvents: []string{
+				"backup start [/data]",
+				"error backup: path not found",
+			},
+		},
+		{
+			description: "it should notify a backup retrieval",
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					return archive.Info{
+						"/data/file1": archive.ItemInfo{
+							ID:       "123456",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (map[string]string, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					return map[string]string{ids[0]: f.Name()}, nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+			},
+			action: func(t toglacier.ToGlacier) error {
+				return t.RetrieveBackup("123456", "", false, archive.OverwriteReplace)
+			},
+			expectedEvents: []string{
+				"retrieval ready 123456 [123456]",
+				"retrieval complete 123456 [123456]",
+			},
+		},
+		{
+			description: "it should notify a backup retrieval failure",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("local storage corrupted")
+				},
+			},
+			action: func(t toglacier.ToGlacier) error {
+				return t.RetrieveBackup("123456", "", false, archive.OverwriteReplace)
+			},
+			expectedEvents: []string{
+				"error retrieve backup: local storage corrupted",
+			},
+		},
+		{
+			description: "it should notify a backup removal",
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test"}},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			action: func(t toglacier.ToGlacier) error {
+				return t.RemoveBackups("123456")
+			},
+			expectedEvents: []string{
+				"backup removed 123456",
+			},
+		},
+		{
+			description: "it should notify a backup removal failure",
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return errors.New("backup not found")
+				},
+			},
+			action: func(t toglacier.ToGlacier) error {
+				return t.RemoveBackups("123456")
+			},
+			expectedEvents: []string{
+				"error remove backup: backup not found",
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var events []string
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Archive: scenario.archive,
+				Cloud:   scenario.cloud,
+				Storage: scenario.storage,
+				Logger:  logger,
+				Events: mockEvents{
+					mockOnBackupStart: func(paths []string) {
+						events = append(events, fmt.Sprintf("backup start %v", paths))
+					},
+					mockOnBackupComplete: func(backup storage.Backup) {
+						events = append(events, fmt.Sprintf("backup complete %s (%d files)", backup.Backup.ID, len(backup.Info)))
+					},
+					mockOnRetrievalReady: func(id string, archiveIDs []string) {
+						events = append(events, fmt.Sprintf("retrieval ready %s %v", id, archiveIDs))
+					},
+					mockOnRetrievalComplete: func(retrieval report.RetrieveBackup) {
+						events = append(events, fmt.Sprintf("retrieval complete %s %v", retrieval.ID, retrieval.Archives))
+					},
+					mockOnBackupRemoved: func(id string) {
+						events = append(events, fmt.Sprintf("backup removed %s", id))
+					},
+					mockOnError: func(action string, err error) {
+						events = append(events, fmt.Sprintf("error %s: %s", action, err))
+					},
+				},
+			}
+
+			scenario.action(toGlacier)
+
+			if !reflect.DeepEqual(scenario.expectedEvents, events) {
+				t.Errorf("events don't match.\n%s", Diff(scenario.expectedEvents, events))
+			}
+		})
+	}
+}
+
+func TestMultiEvents(t *testing.T) {
+	var received []string
+	receiver := func(name string) toglacier.Events {
+		return mockEvents{
+			mockOnBackupStart: func(paths []string) {
+				received = append(received, fmt.Sprintf("%s: backup start %v", name, paths))
+			},
+			mockOnBackupComplete: func(backup storage.Backup) {
+				received = append(received, fmt.Sprintf("%s: backup complete %s", name, backup.Backup.ID))
+			},
+			mockOnRetrievalReady: func(id string, archiveIDs []string) {
+				received = append(received, fmt.Sprintf("%s: retrieval ready %s %v", name, id, archiveIDs))
+			},
+			mockOnRetrievalComplete: func(retrieval report.RetrieveBackup) {
+				received = append(received, fmt.Sprintf("%s: retrieval complete %s", name, retrieval.ID))
+			},
+			mockOnBackupRemoved: func(id string) {
+				received = append(received, fmt.Sprintf("%s: backup removed %s", name, id))
+			},
+			mockOnError: func(action string, err error) {
+				received = append(received, fmt.Sprintf("%s: %s error %s", name, action, err))
+			},
+		}
+	}
+
+	events := toglacier.MultiEvents{receiver("first"), receiver("second")}
+	events.OnBackupStart([]string{"/data"})
+	events.OnBackupComplete(storage.Backup{Backup: cloud.Backup{ID: "123456"}})
+	events.OnRetrievalReady("123456", []string{"123456"})
+	events.OnRetrievalComplete(report.RetrieveBackup{ID: "123456"})
+	events.OnBackupRemoved("123456")
+	events.OnError("backup", errors.New("connection error"))
+
+	expected := []string{
+		"first: backup start [/data]",
+		"second: backup start [/data]",
+		"first: backup complete 123456",
+		"second: backup complete 123456",
+		"first: retrieval ready 123456 [123456]",
+		"second: retrieval ready 123456 [123456]",
+		"first: retrieval complete 123456",
+		"second: retrieval complete 123456",
+		"first: backup removed 123456",
+		"second: backup removed 123456",
+		"first: backup error connection error",
+		"second: backup error connection error",
+	}
+
+	if !reflect.DeepEqual(expected, received) {
+		t.Errorf("events don't match.\n%s", Diff(expected, received))
+	}
+}
+
+type mockEvents struct {
+	mockOnBackupStart       func(paths []string)
+	mockOnBackupComplete    func(backup storage.Backup)
+	mockOnRetrievalReady    func(id string, archiveIDs []string)
+	mockOnRetrievalComplete func(retrieval report.RetrieveBackup)
+	mockOnBackupRemoved     func(id string)
+	mockOnError             func(action string, err error)
+}
+
+func (m mockEvents) OnBackupStart(paths []string) {
+	m.mockOnBackupStart(paths)
+}
+
+func (m mockEvents) OnBackupComplete(backup storage.Backup) {
+	m.mockOnBackupComplete(backup)
+}
+
+func (m mockEvents) OnRetrievalReady(id string, archiveIDs []string) {
+	m.mockOnRetrievalReady(id, archiveIDs)
+}
+
+func (m mockEvents) OnRetrievalComplete(retrieval report.RetrieveBackup) {
+	m.mockOnRetrievalComplete(retrieval)
+}
+
+func (m mockEvents) OnBackupRemoved(id string) {
+	m.mockOnBackupRemoved(id)
+}
+
+func (m mockEvents) OnError(action string, err error) {
+	m.mockOnError(action, err)
+}
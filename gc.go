@@ -0,0 +1,142 @@
+package toglacier
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+// OrphanBackups lists the archives in the cloud that aren't referenced by any
+// backup of the local storage or of the recovery journal, usually left behind
+// by aborted or double-uploaded runs. The companion archives (parity data and
+// catalog) of a referenced backup aren't orphans. When the vault is shared
+// between machines (MachineID defined) only the archives created by this
+// machine are considered, as the others are referenced by the local storage of
+// the other machines. As the cloud inventory is generated only once a day, an
+// archive removed recently could still be listed. On error it will return an
+// Error type encapsulated in a traceable error. To retrieve the desired error
+// you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) OrphanBackups() ([]cloud.Backup, error) {
+	var remoteBackups []cloud.Backup
+	for _, c := range t.clouds() {
+		cloudBackups, err := c.List(t.Context)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		remoteBackups = append(remoteBackups, cloudBackups...)
+	}
+
+	// retrieve local backups information only after the remote backups, because
+	// the remote backups operations can take a while, and a concurrent action
+	// could change the local backups during this time
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if len(backups) == 0 {
+		// without local backups (e.g. a new server) every archive would be an
+		// orphan, and removing them would destroy all backups
+		return nil, errors.WithStack(newError(nil, ErrorCodeEmptyStorage, nil))
+	}
+
+	if t.Journal != nil {
+		pending, err := t.Journal.List(t.Context)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		backups = append(backups, pending...)
+	}
+
+	referenced := make(map[string]bool)
+	for _, backup := range backups {
+		referenced[backup.Backup.ID] = true
+	}
+
+	var orphans []cloud.Backup
+	for _, remoteBackup := range remoteBackups {
+		if referenced[remoteBackup.ID] {
+			continue
+		}
+
+		if t.MachineID != "" && remoteBackup.MachineID != t.MachineID {
+			t.Logger.Debugf("toglacier: ignoring archive “%s” of machine “%s”", remoteBackup.ID, remoteBackup.MachineID)
+			continue
+		}
+
+		orphans = append(orphans, remoteBackup)
+	}
+
+	return orphans, nil
+}
+
+// RemoveOrphanBackups deletes the orphan archives, found with OrphanBackups,
+// from the cloud together with their companion archives. The local storage
+// isn't changed, as it doesn't reference them. The removal is recorded in the
+// audit log of the destructive operations. On error it will return an Error
+// type encapsulated in a traceable error. To retrieve the desired error you can
+// do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) RemoveOrphanBackups(orphans ...cloud.Backup) (err error) {
+	if err := t.writable("remove orphan backups"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var removed []string
+	defer func() {
+		if len(removed) > 0 || err != nil {
+			t.recordOperation("remove orphan backups", removed, err)
+		}
+	}()
+
+	for _, orphan := range orphans {
+		orphanCloud := t.vaultCloud(orphan.VaultName)
+
+		if err := orphanCloud.Remove(t.Context, orphan.ID); err != nil {
+			return errors.WithStack(err)
+		}
+		removed = append(removed, orphan.ID)
+
+		for _, companionID := range []string{orphan.ParityID, orphan.CatalogID} {
+			if companionID == "" {
+				continue
+			}
+
+			if err := orphanCloud.Remove(t.Context, companionID); err != nil {
+				t.Logger.Warningf("toglacier: failed to remove companion archive “%s” of orphan archive “%s”. details: %s", companionID, orphan.ID, err)
+			}
+		}
+
+		t.Logger.Infof("toglacier: orphan archive “%s” removed", orphan.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/log"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_OrphanBackups(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		machineID     string
+		cloud         cloud.Cloud
+		storage       storage.Storage
+		journal       storage.Journal
+		logger        log.Logger
+		expected      []cloud.Backup
+		expectedError error
+	}{
+		{
+			description: "it should list the archives not referenced locally",
+			machineID:   "server1",
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{ID: "123456", CreatedAt: now, VaultName: "test", MachineID: "server1"},
+						{ID: "123457", CreatedAt: now, VaultName: "test", MachineID: "server1", ParityID: "123458"},
+						{ID: "123459", CreatedAt: now, VaultName: "test", MachineID: "server2"},
+						{ID: "123460", CreatedAt: now, VaultName: "test"},
+						{ID: "123461", CreatedAt: now, VaultName: "test", MachineID: "server1"},
+					}, nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test", MachineID: "server1"}},
+					}, nil
+				},
+			},
+			journal: mockJournal{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{Backup: cloud.Backup{ID: "123461", CreatedAt: now, VaultName: "test", MachineID: "server1"}},
+					}, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebugf: func(format string, args ...interface{}) {},
+			},
+			expected: []cloud.Backup{
+				{ID: "123457", CreatedAt: now, VaultName: "test", MachineID: "server1", ParityID: "123458"},
+			},
+		},
+		{
+			description: "it should consider the archives of all machines when there's no machine identifier",
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{ID: "123456", CreatedAt: now, VaultName: "test"},
+						{ID: "123457", CreatedAt: now, VaultName: "test", MachineID: "server2"},
+					}, nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test"}},
+					}, nil
+				},
+			},
+			expected: []cloud.Backup{
+				{ID: "123457", CreatedAt: now, VaultName: "test", MachineID: "server2"},
+			},
+		},
+		{
+			description: "it should detect when the local storage is empty",
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{ID: "123456", CreatedAt: now, VaultName: "test"},
+					}, nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+			},
+			expectedError: &toglacier.Error{Code: toglacier.ErrorCodeEmptyStorage},
+		},
+		{
+			description: "it should detect an error while listing the remote archives",
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return nil, errors.New("error listing backups")
+				},
+			},
+			expectedError: errors.New("error listing backups"),
+		},
+		{
+			description: "it should detect an error while listing the local backups",
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return nil, nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("error listing local backups")
+				},
+			},
+			expectedError: errors.New("error listing local backups"),
+		},
+		{
+			description: "it should detect an error while listing the journal",
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return nil, nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test"}},
+					}, nil
+				},
+			},
+			journal: mockJournal{
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("error listing journal")
+				},
+			},
+			expectedError: errors.New("error listing journal"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context:   context.Background(),
+				Cloud:     scenario.cloud,
+				Storage:   scenario.storage,
+				Journal:   scenario.journal,
+				Logger:    scenario.logger,
+				MachineID: scenario.machineID,
+			}
+
+			orphans, err := toGlacier.OrphanBackups()
+			if !reflect.DeepEqual(scenario.expected, orphans) {
+				t.Errorf("orphans don't match.\n%s", Diff(scenario.expected, orphans))
+			}
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_RemoveOrphanBackups(t *testing.T) {
+	scenarios := []struct {
+		description     string
+		orphans         []cloud.Backup
+		removeError     map[string]error
+		expectedRemoved []string
+		expectedError   error
+	}{
+		{
+			description: "it should remove the orphan archives and their companions",
+			orphans: []cloud.Backup{
+				{ID: "123456", VaultName: "test", ParityID: "123457", CatalogID: "123458"},
+				{ID: "123459", VaultName: "test"},
+			},
+			expectedRemoved: []string{"123456", "123457", "123458", "123459"},
+		},
+		{
+			description: "it should ignore errors while removing the companion archives",
+			orphans: []cloud.Backup{
+				{ID: "123456", VaultName: "test", ParityID: "123457", CatalogID: "123458"},
+			},
+			removeError: map[string]error{
+				"123457": errors.New("error removing parity"),
+			},
+			expectedRemoved: []string{"123456", "123458"},
+		},
+		{
+			description: "it should detect an error while removing an orphan archive",
+			orphans: []cloud.Backup{
+				{ID: "123456", VaultName: "test"},
+				{ID: "123459", VaultName: "test"},
+			},
+			removeError: map[string]error{
+				"123456": errors.New("error removing backup"),
+			},
+			expectedError: errors.New("error removing backup"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var removed []string
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud: mockCloud{
+					mockRemove: func(id string) error {
+						if err := scenario.removeError[id]; err != nil {
+							return err
+						}
+
+						removed = append(removed, id)
+						return nil
+					},
+				},
+				Logger: mockLogger{
+					mockInfof:    func(format string, args ...interface{}) {},
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			err := toGlacier.RemoveOrphanBackups(scenario.orphans...)
+			if !reflect.DeepEqual(scenario.expectedRemoved, removed) {
+				t.Errorf("removed archives don't match. expected “%v” and got “%v”", scenario.expectedRemoved, removed)
+			}
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+type mockJournal struct {
+	mockSave   func(storage.Backup) error
+	mockList   func() (storage.Backups, error)
+	mockRemove func(id string) error
+}
+
+func (m mockJournal) Save(ctx context.Context, b storage.Backup) error {
+	return m.mockSave(b)
+}
+
+func (m mockJournal) List(ctx context.Context) (storage.Backups, error) {
+	return m.mockList()
+}
+
+func (m mockJournal) Remove(ctx context.Context, id string) error {
+	return m.mockRemove(id)
+}
@@ -0,0 +1,62 @@
+package toglacier
+
+import (
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// BackupsDay stores the backups created on the same calendar day, so many runs
+// of the same day (e.g. manual backups) can be handled as one.
+type BackupsDay struct {
+	// Day is the midnight of the calendar day in the location used to group the
+	// backups.
+	Day time.Time
+
+	// Backups of the day, in the same order of the grouped backups.
+	Backups storage.Backups
+}
+
+// GroupBackupsByDay groups the backups created on the same calendar day of the
+// location. The days are returned in the order of their first backup, so
+// backups sorted from the newest to the oldest have the newest run of each day
+// first. When the location isn't informed the local time is used.
+func GroupBackupsByDay(backups storage.Backups, location *time.Location) []BackupsDay {
+	if location == nil {
+		location = time.Local
+	}
+
+	var days []BackupsDay
+	positions := make(map[time.Time]int)
+
+	for _, backup := range backups {
+		day := calendarDay(backup.Backup.CreatedAt, location)
+
+		position, ok := positions[day]
+		if !ok {
+			position = len(days)
+			positions[day] = position
+			days = append(days, BackupsDay{Day: day})
+		}
+
+		days[position].Backups = append(days[position].Backups, backup)
+	}
+
+	return days
+}
+
+// calendarDay returns the midnight of the day of the date in the location.
+func calendarDay(date time.Time, location *time.Location) time.Time {
+	year, month, day := date.In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, location)
+}
+
+// groupLocation returns the location of the calendar days used to group the
+// backups.
+func (t ToGlacier) groupLocation() *time.Location {
+	if t.Location != nil {
+		return t.Location
+	}
+
+	return t.now().Location()
+}
@@ -0,0 +1,72 @@
+package toglacier_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestGroupBackupsByDay(t *testing.T) {
+	saoPaulo := time.FixedZone("BRT", -3*60*60)
+
+	backups := storage.Backups{
+		{Backup: cloud.Backup{ID: "123455", CreatedAt: time.Date(2017, 9, 14, 20, 0, 0, 0, time.UTC)}},
+		{Backup: cloud.Backup{ID: "123454", CreatedAt: time.Date(2017, 9, 14, 1, 0, 0, 0, time.UTC)}},
+		{Backup: cloud.Backup{ID: "123453", CreatedAt: time.Date(2017, 9, 13, 9, 0, 0, 0, time.UTC)}},
+	}
+
+	scenarios := []struct {
+		description string
+		backups     storage.Backups
+		location    *time.Location
+		expected    []toglacier.BackupsDay
+	}{
+		{
+			description: "it should group the backups of the same day",
+			backups:     backups,
+			location:    time.UTC,
+			expected: []toglacier.BackupsDay{
+				{
+					Day:     time.Date(2017, 9, 14, 0, 0, 0, 0, time.UTC),
+					Backups: storage.Backups{backups[0], backups[1]},
+				},
+				{
+					Day:     time.Date(2017, 9, 13, 0, 0, 0, 0, time.UTC),
+					Backups: storage.Backups{backups[2]},
+				},
+			},
+		},
+		{
+			description: "it should group the backups using the calendar days of the location",
+			backups:     backups,
+			location:    saoPaulo,
+			expected: []toglacier.BackupsDay{
+				{
+					Day:     time.Date(2017, 9, 14, 0, 0, 0, 0, saoPaulo),
+					Backups: storage.Backups{backups[0]},
+				},
+				{
+					Day:     time.Date(2017, 9, 13, 0, 0, 0, 0, saoPaulo),
+					Backups: storage.Backups{backups[1], backups[2]},
+				},
+			},
+		},
+		{
+			description: "it should ignore an empty list of backups",
+			location:    time.UTC,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			days := toglacier.GroupBackupsByDay(scenario.backups, scenario.location)
+			if !reflect.DeepEqual(scenario.expected, days) {
+				t.Errorf("days don't match.\n%s", Diff(scenario.expected, days))
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package toglacier
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+// archiveHeader returns the format header sent before the archive content, or
+// nil when the header is disabled. The data of a stream isn't an archive, and
+// the envelop is only identified when the archive was encrypted.
+func (t ToGlacier) archiveHeader(archiveInfo archive.Info, backupSecret string) ([]byte, error) {
+	if t.Header.Format == "" {
+		return nil, nil
+	}
+
+	header := t.Header
+	if _, ok := archiveInfo.Stream(); ok {
+		header = archive.NewHeader(archive.FormatStream, header.Envelop)
+	}
+
+	if backupSecret == "" {
+		header.Envelop = ""
+	}
+
+	content, err := header.Encode()
+	return content, errors.WithStack(err)
+}
+
+// readHeader reads the format header of the retrieved archive, returning the
+// archive and the envelop that built it, and the backup secret that must be
+// used to decrypt it (empty when the archive isn't encrypted). The archive file
+// isn't changed, as the archive operations skip the header. Archives without
+// header, built by older versions, keep the configured archive and envelop.
+// The header isn't authenticated, so when a backup secret is informed an
+// archive that claims to be unencrypted is refused, as it could have been
+// replaced, unless the unencrypted archives are allowed (e.g. backups sent
+// before the secret was configured).
+func (t ToGlacier) readHeader(filename, backupSecret string) (archive.Archive, archive.Envelop, string, error) {
+	if t.Header.Format == "" {
+		return t.Archive, t.Envelop, backupSecret, nil
+	}
+
+	header, size, err := archive.ReadHeader(filename)
+	if err != nil {
+		return nil, nil, "", errors.WithStack(err)
+	} else if size == 0 {
+		return t.Archive, t.Envelop, backupSecret, nil
+	}
+
+	t.Logger.Debugf("toglacier: archive “%s” built with format “%s” and envelop “%s”", filename, header.Format, header.Envelop)
+
+	chosenArchive := t.Archive
+	if header.Format != t.Header.Format && header.Format != archive.FormatStream {
+		if chosenArchive, err = archive.NewArchive(header.Format, t.Logger); err != nil {
+			return nil, nil, "", errors.WithStack(err)
+		}
+	}
+
+	if header.Envelop == "" && backupSecret != "" && !t.AllowUnencrypted {
+		return nil, nil, "", errors.WithStack(newError(nil, ErrorCodeUnencryptedArchive, nil))
+	} else if header.Envelop == "" {
+		if backupSecret != "" {
+			t.Logger.Warningf("toglacier: archive “%s” isn't encrypted, restoring it without the backup secret", filename)
+		}
+		return chosenArchive, t.Envelop, "", nil
+	} else if backupSecret == "" {
+		return nil, nil, "", errors.WithStack(newError(nil, ErrorCodeMissingSecret, nil))
+	}
+
+	chosenEnvelop := t.Envelop
+	if header.Envelop != t.Header.Envelop {
+		if chosenEnvelop, err = archive.NewEnvelop(header.Envelop, t.Logger); err != nil {
+			return nil, nil, "", errors.WithStack(err)
+		}
+	}
+
+	return chosenArchive, chosenEnvelop, backupSecret, nil
+}
@@ -0,0 +1,389 @@
+package toglacier_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_Header(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarning:  func(args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	scenarios := []struct {
+		description      string
+		backupSecret     string
+		retrieveSecret   string
+		allowUnencrypted bool
+		expectedError    error
+		expectedContent  string
+	}{
+		{
+			description:     "it should restore with the format and the envelop of the header",
+			backupSecret:    "1234567890123456",
+			retrieveSecret:  "1234567890123456",
+			expectedContent: "content of file1",
+		},
+		{
+			description:     "it should restore an archive that isn't encrypted",
+			expectedContent: "content of file1",
+		},
+		{
+			description:    "it should refuse an archive that isn't encrypted when the secret is informed",
+			retrieveSecret: "1234567890123456",
+			expectedError:  &toglacier.Error{Code: toglacier.ErrorCodeUnencryptedArchive},
+		},
+		{
+			description:      "it should restore an archive that isn't encrypted when the secret is informed and it is allowed",
+			retrieveSecret:   "1234567890123456",
+			allowUnencrypted: true,
+			expectedContent:  "content of file1",
+		},
+		{
+			description:   "it should detect when the secret of an encrypted archive is missing",
+			backupSecret:  "1234567890123456",
+			expectedError: &toglacier.Error{Code: toglacier.ErrorCodeMissingSecret},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			// the backup is built in zip, while the retrieval is configured to tar
+			builder := archive.NewZIPBuilder(logger)
+			builder.Clock = fakeClock{now: time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)}
+			builder.FileSystem = archive.NewIOFileSystem(fstest.MapFS{
+				"data/file1.txt": &fstest.MapFile{Data: []byte("content of file1"), Mode: 0600},
+			})
+
+			sentFilename := filepath.Join(dir, "sent")
+			var backups storage.Backups
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Archive: builder,
+				Envelop: archive.NewOFBEnvelop(logger),
+				Cloud: headerCloud{
+					mockCloud: mockCloud{
+						mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+							return cloud.Backup{ID: "AWSID124"}, nil
+						},
+					},
+					target: sentFilename,
+				},
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return backups, nil
+					},
+					mockSave: func(b storage.Backup) error {
+						backups = storage.Backups{b}
+						return nil
+					},
+				},
+				Logger: logger,
+				Header: archive.NewHeader(archive.FormatZIP, archive.EnvelopOFB),
+			}
+
+			if err = toGlacier.Backup([]string{"data"}, scenario.backupSecret, 0, nil, ""); err != nil {
+				t.Fatalf("unexpected error “%v”", err)
+			}
+
+			if _, size, err := archive.ReadHeader(sentFilename); err != nil || size == 0 {
+				t.Fatalf("header not sent before the archive (size %d). details: %v", size, err)
+			}
+
+			toGlacier.Archive = archive.NewTARBuilder(logger)
+			toGlacier.Header = archive.NewHeader(archive.FormatTAR, archive.EnvelopOFB)
+			toGlacier.AllowUnencrypted = scenario.allowUnencrypted
+			toGlacier.Cloud = mockCloud{
+				mockGet: func(ids ...string) (map[string]string, error) {
+					filename := filepath.Join(dir, "downloaded")
+					return map[string]string{"AWSID123": filename}, copyFile(sentFilename, filename)
+				},
+			}
+			toGlacier.Storage = mockStorage{
+				mockList: func() (storage.Backups, error) {
+					// without the archive information all files of the archive are
+					// extracted
+					return storage.Backups{{Backup: backups[0].Backup}}, nil
+				},
+				mockSave: func(b storage.Backup) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+			}
+
+			// the files are extracted in the current directory
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("error retrieving the current directory. details: %s", err)
+			}
+			defer os.Chdir(wd)
+
+			if err = os.Chdir(dir); err != nil {
+				t.Fatalf("error changing the current directory. details: %s", err)
+			}
+
+			err = toGlacier.RetrieveBackup("AWSID123", scenario.retrieveSecret, false, archive.OverwriteReplace)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Fatalf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if scenario.expectedContent == "" {
+				return
+			}
+
+			content, err := ioutil.ReadFile(filepath.Join(dir, "backup-20170914103000", "data", "file1.txt"))
+			if err != nil {
+				t.Fatalf("error reading the extracted file. details: %s", err)
+			}
+
+			if string(content) != scenario.expectedContent {
+				t.Errorf("extracted content don't match. expected “%s” and got “%s”", scenario.expectedContent, content)
+			}
+		})
+	}
+}
+
+func TestToGlacier_HeaderMixedChain(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarning:  func(args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	scenarios := []struct {
+		description      string
+		allowUnencrypted bool
+		expectedError    error
+		expectedContent  map[string]string
+	}{
+		{
+			description:      "it should restore a chain with unencrypted and encrypted archives when it is allowed",
+			allowUnencrypted: true,
+			expectedContent: map[string]string{
+				"file1.txt": "content of file1",
+				"file2.txt": "content of file2",
+			},
+		},
+		{
+			description:   "it should refuse a chain with an unencrypted archive when the secret is informed",
+			expectedError: &toglacier.Error{Code: toglacier.ErrorCodeUnencryptedArchive},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			// the archive information is compared with the extracted paths, so the
+			// files must be in the disk with the absolute path
+			dataDir := filepath.Join(dir, "data")
+			if err = os.Mkdir(dataDir, 0700); err != nil {
+				t.Fatalf("error creating the data directory. details: %s", err)
+			}
+
+			if err = ioutil.WriteFile(filepath.Join(dataDir, "file1.txt"), []byte("content of file1"), 0600); err != nil {
+				t.Fatalf("error creating file. details: %s", err)
+			}
+
+			builder := archive.NewTARBuilder(logger)
+			builder.Clock = fakeClock{now: time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)}
+
+			var backups storage.Backups
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Archive: builder,
+				Envelop: archive.NewOFBEnvelop(logger),
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return backups, nil
+					},
+					mockSave: func(b storage.Backup) error {
+						backups = append(backups, b)
+						return nil
+					},
+				},
+				Logger: logger,
+				Header: archive.NewHeader(archive.FormatTAR, archive.EnvelopOFB),
+			}
+
+			// the first backup is sent before the secret was configured
+			sent := make(map[string]string)
+			for i, backupSecret := range []string{"", "1234567890123456"} {
+				id := fmt.Sprintf("AWSID%d", 123+i)
+				sent[id] = filepath.Join(dir, "sent-"+id)
+
+				toGlacier.Cloud = headerCloud{
+					mockCloud: mockCloud{
+						mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+							return cloud.Backup{ID: backupID + "-catalog"}, nil
+						},
+					},
+					target: sent[id],
+					id:     id,
+				}
+
+				if err = toGlacier.Backup([]string{dataDir}, backupSecret, 0, nil, ""); err != nil {
+					t.Fatalf("unexpected error “%v”", err)
+				}
+
+				if err = ioutil.WriteFile(filepath.Join(dataDir, "file2.txt"), []byte("content of file2"), 0600); err != nil {
+					t.Fatalf("error creating file. details: %s", err)
+				}
+			}
+
+			toGlacier.AllowUnencrypted = scenario.allowUnencrypted
+			toGlacier.Cloud = mockCloud{
+				mockGet: func(ids ...string) (map[string]string, error) {
+					filenames := make(map[string]string)
+					for _, id := range ids {
+						filenames[id] = filepath.Join(dir, "downloaded-"+id)
+						if err := copyFile(sent[id], filenames[id]); err != nil {
+							return nil, err
+						}
+					}
+					return filenames, nil
+				},
+			}
+			toGlacier.Storage = mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return backups, nil
+				},
+				mockSave: func(b storage.Backup) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+			}
+
+			// the files are extracted in the current directory
+			restoreDir := filepath.Join(dir, "restore")
+			if err = os.Mkdir(restoreDir, 0700); err != nil {
+				t.Fatalf("error creating the restore directory. details: %s", err)
+			}
+
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("error retrieving the current directory. details: %s", err)
+			}
+			defer os.Chdir(wd)
+
+			if err = os.Chdir(restoreDir); err != nil {
+				t.Fatalf("error changing the current directory. details: %s", err)
+			}
+
+			err = toGlacier.RetrieveBackup("AWSID124", "1234567890123456", false, archive.OverwriteReplace)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Fatalf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			for name, expectedContent := range scenario.expectedContent {
+				content, err := ioutil.ReadFile(filepath.Join(restoreDir, "backup-20170914103000", dataDir, name))
+				if err != nil {
+					t.Fatalf("error reading the extracted file. details: %s", err)
+				}
+
+				if string(content) != expectedContent {
+					t.Errorf("extracted content of “%s” don't match. expected “%s” and got “%s”", name, expectedContent, content)
+				}
+			}
+		})
+	}
+}
+
+// headerCloud stores the content sent to the cloud, including the format header
+// of the context. When the id isn't informed AWSID123 is used.
+type headerCloud struct {
+	mockCloud
+	target string
+	id     string
+}
+
+func (h headerCloud) Send(ctx context.Context, filename, comment string) (cloud.Backup, error) {
+	archiveFile, err := cloud.OpenArchive(ctx, filename)
+	if err != nil {
+		return cloud.Backup{}, err
+	}
+	defer archiveFile.Close()
+
+	target, err := os.Create(h.target)
+	if err != nil {
+		return cloud.Backup{}, err
+	}
+	defer target.Close()
+
+	if _, err = io.Copy(target, archiveFile); err != nil {
+		return cloud.Backup{}, err
+	}
+
+	id := h.id
+	if id == "" {
+		id = "AWSID123"
+	}
+
+	return cloud.Backup{ID: id}, nil
+}
+
+// copyFile copies the content of a file to a new file, so it can be used after
+// the original file is removed.
+func copyFile(from, to string) error {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	_, err = io.Copy(target, source)
+	return err
+}
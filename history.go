@@ -0,0 +1,39 @@
+package toglacier
+
+import (
+	"sort"
+
+	"github.com/rafaeljusto/toglacier/internal/report"
+)
+
+// addHistoryReport charts the size and the duration of the retained backups in
+// the HTML reports, so the growth trends are visible at a glance. A problem
+// listing the backups is recorded in the report.
+func (t ToGlacier) addHistoryReport() {
+	historyReport := report.NewHistory()
+
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		historyReport.Errors = append(historyReport.Errors, err)
+		t.reports().Add(historyReport)
+		return
+	}
+
+	if !t.AllMachines {
+		backups = t.machineBackups(backups)
+	}
+
+	if len(backups) == 0 {
+		return
+	}
+
+	for _, backup := range backups {
+		historyReport.Backups = append(historyReport.Backups, backup.Backup)
+	}
+
+	sort.SliceStable(historyReport.Backups, func(i, j int) bool {
+		return historyReport.Backups[i].CreatedAt.Before(historyReport.Backups[j].CreatedAt)
+	})
+
+	t.reports().Add(historyReport)
+}
@@ -0,0 +1,155 @@
+package toglacier
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// backupInfo returns the archive information of the backup, retrieving it from
+// the cloud when the local storage doesn't have it (e.g. backups saved by older
+// versions or synchronized from the cloud inventory). The catalog is retrieved
+// when the backup has one, as it is much smaller than the archive, otherwise
+// the archive itself is retrieved. The retrieved information is saved in the
+// local storage, so it is retrieved only once.
+func (t ToGlacier) backupInfo(backup storage.Backup, backups storage.Backups, backupSecret string) (archive.Info, error) {
+	if backup.Info != nil {
+		return backup.Info, nil
+	}
+
+	t.Logger.Infof("toglacier: archive information of backup “%s” isn't in the local storage, retrieving it from the cloud", backup.Backup.ID)
+
+	// all parts of an incremental backup are stored in the same route
+	t.Cloud = t.vaultCloud(backup.Backup.VaultName)
+
+	var err error
+	if backup.Backup.CatalogID != "" {
+		if backup.Info, err = t.catalogInfo(backup, backupSecret); err != nil {
+			t.Logger.Warningf("toglacier: failed to retrieve the catalog of backup “%s”, retrieving the archive. details: %s", backup.Backup.ID, err)
+		}
+	}
+
+	if backup.Info == nil {
+		if backup.Info, err = t.archiveInfo(backup, backups, backupSecret); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	if err = t.synchronizeArchiveInfo(backup, backups); err != nil {
+		t.Logger.Warningf("toglacier: failed to save the archive information of backup “%s”. details: %s", backup.Backup.ID, err)
+	}
+
+	return backup.Info, nil
+}
+
+// catalogInfo retrieves the archive information from the catalog of the
+// backup.
+func (t ToGlacier) catalogInfo(backup storage.Backup, backupSecret string) (archive.Info, error) {
+	filenames, err := t.Cloud.Get(t.Context, backup.Backup.CatalogID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	c, err := t.readCatalog(filenames[backup.Backup.CatalogID], backupSecret)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if c.Backup.Backup.ID != backup.Backup.ID || c.Backup.Info == nil {
+		return nil, errors.WithStack(newError(nil, ErrorCodeArchiveInfoNotFound, nil))
+	}
+
+	return c.Backup.Info, nil
+}
+
+// archiveInfo retrieves the archive information stored together with the files
+// in the archive of the backup.
+func (t ToGlacier) archiveInfo(backup storage.Backup, backups storage.Backups, backupSecret string) (archive.Info, error) {
+	id := backup.Backup.ID
+
+	progress, err := t.Storage.RestoreProgress(t.Context, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if progress.Downloaded == nil {
+		progress.Downloaded = make(map[string]string)
+	}
+
+	filenames, err := t.download(id, progress, backups, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	filename := filenames[id]
+	defer func() {
+		if err := os.Remove(filename); err != nil {
+			t.Logger.Warningf("toglacier: failed to remove file “%s”. details: %s", filename, err)
+		}
+	}()
+
+	archiveInfo, err := t.downloadedInfo(filename, backupSecret)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err = t.Storage.RemoveRestoreProgress(t.Context, id); err != nil {
+		t.Logger.Warningf("toglacier: failed to remove the restore progress of backup “%s”. details: %s", id, err)
+	}
+
+	return archiveInfo, nil
+}
+
+// retrievalInfo returns the archive information of a backup being retrieved
+// that isn't in the local storage, so only the files matching a pattern are
+// extracted from the main archive. The catalog is retrieved when the backup
+// has one, otherwise the main archive is downloaded and kept, so it isn't
+// downloaded again for the extraction.
+func (t ToGlacier) retrievalInfo(id string, backup storage.Backup, backups storage.Backups, progress storage.RestoreProgress, backupSecret string) (archive.Info, error) {
+	if backup.Backup.CatalogID != "" {
+		archiveInfo, err := t.catalogInfo(backup, backupSecret)
+		if err == nil {
+			return archiveInfo, nil
+		}
+		t.Logger.Warningf("toglacier: failed to retrieve the catalog of backup “%s”, retrieving the archive. details: %s", id, err)
+	}
+
+	filenames, err := t.download(id, progress, backups, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	archiveInfo, err := t.downloadedInfo(filenames[id], backupSecret)
+	return archiveInfo, errors.WithStack(err)
+}
+
+// downloadedInfo reads the archive information from a downloaded archive,
+// without extracting the files.
+func (t ToGlacier) downloadedInfo(filename, backupSecret string) (archive.Info, error) {
+	_, envelop, secret, err := t.readHeader(filename, backupSecret)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	decryptedFilename := filename
+	if secret != "" {
+		if decryptedFilename, err = envelop.Decrypt(filename, secret); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		defer os.Remove(decryptedFilename)
+	}
+
+	index, err := archive.OpenIndex(t.Context, t.Logger, decryptedFilename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer index.Close()
+
+	if index.Info() == nil {
+		return nil, errors.WithStack(newError(nil, ErrorCodeArchiveInfoNotFound, nil))
+	}
+
+	return index.Info(), nil
+}
@@ -1,6 +1,10 @@
 package archive
 
-import "regexp"
+import (
+	"context"
+	"io"
+	"regexp"
+)
 
 const (
 	// ItemInfoStatusNew refers to an item that appeared for the first time in the
@@ -18,6 +22,10 @@ const (
 	// ItemInfoStatusDeleted refers to an item that disappeared since the last
 	// archive built.
 	ItemInfoStatusDeleted ItemInfoStatus = "deleted"
+
+	// ItemInfoStatusStream refers to data backed up from a stream (e.g. a
+	// database dump), that is stored as is instead of inside an archive.
+	ItemInfoStatusStream ItemInfoStatus = "stream"
 )
 
 // ItemInfoStatus describes the current archive's item state.
@@ -35,6 +43,10 @@ type ItemInfo struct {
 	ID       string
 	Status   ItemInfoStatus
 	Checksum string
+
+	// Fuzzy informs that the file kept changing while it was added to the
+	// archive, so the archived content could mix old and new parts.
+	Fuzzy bool `json:",omitempty"`
 }
 
 // Info stores extra information from the archive's items for allowing
@@ -75,6 +87,22 @@ func (a Info) Statistics() map[ItemInfoStatus]int {
 	return statistic
 }
 
+// Stream returns the name of the stream when the information belongs to a
+// backup of a stream instead of an archive.
+func (a Info) Stream() (string, bool) {
+	if len(a) != 1 {
+		return "", false
+	}
+
+	for name, itemInfo := range a {
+		if itemInfo.Status == ItemInfoStatusStream {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
 // FilterByStatuses returns the archive information only containing the items
 // that have the desired statuses.
 func (a Info) FilterByStatuses(statuses ...ItemInfoStatus) Info {
@@ -91,16 +119,38 @@ func (a Info) FilterByStatuses(statuses ...ItemInfoStatus) Info {
 }
 
 // Archive manages an archive joining all paths in a file, extracting and
-// calculating Checksums.
+// calculating Checksums. The context allows cancelling long file scans and
+// extractions.
 type Archive interface {
-	Build(lastArchiveInfo Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, Info, error)
-	Extract(filename string, filter []string) (Info, error)
+	Build(ctx context.Context, lastArchiveInfo Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, Info, error)
+	Extract(ctx context.Context, filename string, filter []string) (Info, error)
 	FileChecksum(filename string) (string, error)
 }
 
+// StreamExtractor is an optional capability of an Archive, extracting the
+// archive while it is read, so the whole archive doesn't need to be stored on
+// disk before the extraction.
+type StreamExtractor interface {
+	ExtractReader(ctx context.Context, r io.Reader, filter []string) (Info, error)
+}
+
 // Envelop manages the security of an archive encrypting and decrypting the
 // content.
 type Envelop interface {
 	Encrypt(filename, secret string) (string, error)
 	Decrypt(encryptedFilename, secret string) (string, error)
 }
+
+// StreamEnvelop is an optional capability of an Envelop, decrypting the
+// content while it is read. The content can only be trusted after it is
+// completely read without errors.
+type StreamEnvelop interface {
+	DecryptReader(encryptedFilename, secret string) (io.ReadCloser, error)
+}
+
+// Parity generates redundancy data for an archive, allowing to repair the
+// archive when some parts of it get corrupted.
+type Parity interface {
+	Generate(filename string, redundancy int) (string, error)
+	Repair(filename, parityFilename string) error
+}
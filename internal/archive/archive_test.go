@@ -1,6 +1,12 @@
 package archive_test
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
 	"reflect"
 	"testing"
 
@@ -17,6 +23,7 @@ func TestItemInfoStatus_Useful(t *testing.T) {
 		{description: "it should consider modified as useful", itemInfoStatus: archive.ItemInfoStatusModified, expected: true},
 		{description: "it should not consider unmodified as useful", itemInfoStatus: archive.ItemInfoStatusUnmodified, expected: false},
 		{description: "it should not consider deleted as useful", itemInfoStatus: archive.ItemInfoStatusDeleted, expected: false},
+		{description: "it should not consider stream as useful", itemInfoStatus: archive.ItemInfoStatusStream, expected: false},
 		{description: "it should not consider unknown as useful", itemInfoStatus: archive.ItemInfoStatus("unknown"), expected: false},
 	}
 
@@ -30,6 +37,48 @@ func TestItemInfoStatus_Useful(t *testing.T) {
 	}
 }
 
+func TestInfo_Stream(t *testing.T) {
+	scenarios := []struct {
+		description      string
+		info             archive.Info
+		expectedName     string
+		expectedIsStream bool
+	}{
+		{
+			description: "it should detect a stream backup",
+			info: archive.Info{
+				"db.sql": archive.ItemInfo{
+					ID:     "12345",
+					Status: archive.ItemInfoStatusStream,
+				},
+			},
+			expectedName:     "db.sql",
+			expectedIsStream: true,
+		},
+		{
+			description: "it should not detect a stream in an archive backup",
+			info: archive.Info{
+				"file1": archive.ItemInfo{
+					ID:     "12345",
+					Status: archive.ItemInfoStatusNew,
+				},
+			},
+		},
+		{
+			description: "it should not detect a stream when there's no information",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			name, isStream := scenario.info.Stream()
+			if name != scenario.expectedName || isStream != scenario.expectedIsStream {
+				t.Errorf("unexpected stream “%s” (%t), expected “%s” (%t)", name, isStream, scenario.expectedName, scenario.expectedIsStream)
+			}
+		})
+	}
+}
+
 func TestInfo_FilterByStatuses(t *testing.T) {
 	scenarios := []struct {
 		description string
@@ -109,3 +158,71 @@ func TestInfo_FilterByStatuses(t *testing.T) {
 		})
 	}
 }
+
+// benchmarkDataset describes the generated files used to measure the archive
+// operations. Many small files stress the per-file overhead, while few huge
+// files stress the data throughput.
+type benchmarkDataset struct {
+	description string
+	files       int
+	size        int
+}
+
+var benchmarkDatasets = []benchmarkDataset{
+	{description: "many small files", files: 1000, size: 4 * 1024},
+	{description: "few huge files", files: 4, size: 8 * 1024 * 1024},
+}
+
+// generate creates the files of the dataset in a temporary directory, filled
+// with pseudo-random data so compression and encryption can't take shortcuts.
+func (d benchmarkDataset) generate(b *testing.B) string {
+	dir, err := ioutil.TempDir("", "toglacier-bench")
+	if err != nil {
+		b.Fatalf("error creating temporary directory. details %s", err)
+	}
+
+	random := rand.New(rand.NewSource(int64(d.files)))
+	content := make([]byte, d.size)
+
+	for i := 0; i < d.files; i++ {
+		random.Read(content)
+
+		filename := path.Join(dir, fmt.Sprintf("file%d", i))
+		if err = ioutil.WriteFile(filename, content, 0600); err != nil {
+			b.Fatalf("error creating temporary file. details %s", err)
+		}
+	}
+
+	return dir
+}
+
+// totalSize is the number of bytes of the dataset, used to report the
+// throughput of the benchmarks.
+func (d benchmarkDataset) totalSize() int64 {
+	return int64(d.files) * int64(d.size)
+}
+
+func newBenchmarkLogger() mockLogger {
+	return mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarning:  func(args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+}
+
+// archive builds a TAR archive with the files of the dataset, so the
+// operations over the final archive (e.g. encryption) can be measured.
+func (d benchmarkDataset) archive(b *testing.B) string {
+	dir := d.generate(b)
+	defer os.RemoveAll(dir)
+
+	filename, _, err := archive.NewTARBuilder(newBenchmarkLogger()).Build(context.Background(), nil, nil, dir)
+	if err != nil {
+		b.Fatalf("error building archive. details %s", err)
+	}
+
+	return filename
+}
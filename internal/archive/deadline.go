@@ -0,0 +1,22 @@
+package archive
+
+// Deadline limits the time spent building the archive, so a backup of large
+// directories doesn't run for an unbounded time.
+type Deadline interface {
+	// Reached is checked before analyzing each file. When it returns true the
+	// file isn't analyzed, keeping its information of the last archive, and the
+	// archive only contains the files analyzed before.
+	Reached(path string) bool
+}
+
+// deadlineReached informs if the file isn't analyzed because the deadline was
+// reached, keeping the information of the last archive for it (or for the
+// files of the directory).
+func deadlineReached(deadline Deadline, path string, lastArchiveInfo, archiveInfo Info) bool {
+	if deadline == nil || !deadline.Reached(path) {
+		return false
+	}
+
+	keepLastInfo(path, lastArchiveInfo, archiveInfo)
+	return true
+}
@@ -0,0 +1,152 @@
+package archive_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+func TestDeadline(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	fileSystem := archive.NewIOFileSystem(fstest.MapFS{
+		"data/dir1/file3": &fstest.MapFile{Data: []byte("file3 test"), Mode: 0600},
+		"data/file1":      &fstest.MapFile{Data: []byte("file1 test"), Mode: 0600},
+		"data/file2":      &fstest.MapFile{Data: []byte("file2 test"), Mode: 0600},
+	})
+
+	lastArchiveInfo := archive.Info{
+		"data/dir1/file3": archive.ItemInfo{
+			ID:       "AWSID122",
+			Status:   archive.ItemInfoStatusNew,
+			Checksum: "file3 old checksum",
+		},
+		"data/file2": archive.ItemInfo{
+			ID:       "AWSID122",
+			Status:   archive.ItemInfoStatusNew,
+			Checksum: "file2 old checksum",
+		},
+	}
+
+	builders := map[string]func(deadline archive.Deadline) archive.Archive{
+		"tar": func(deadline archive.Deadline) archive.Archive {
+			builder := archive.NewTARBuilder(logger)
+			builder.FileSystem = fileSystem
+			builder.Deadline = deadline
+			return builder
+		},
+		"zip": func(deadline archive.Deadline) archive.Archive {
+			builder := archive.NewZIPBuilder(logger)
+			builder.FileSystem = fileSystem
+			builder.Deadline = deadline
+			return builder
+		},
+	}
+
+	scenarios := []struct {
+		description         string
+		deadline            *mockDeadline
+		expectedFiles       []string
+		expectedArchiveInfo archive.Info
+	}{
+		{
+			description: "it should stop analyzing the files after the deadline",
+			deadline:    &mockDeadline{at: "data/file1", sticky: true},
+			expectedFiles: []string{
+				"data/dir1/file3",
+			},
+			expectedArchiveInfo: archive.Info{
+				"data/dir1/file3": archive.ItemInfo{
+					Status:   archive.ItemInfoStatusModified,
+					Checksum: "sFwN7pdLHnHZHCmTuhFWYvYTYz9g8XzISkAR1+UOS5c=",
+				},
+				"data/file2": archive.ItemInfo{
+					ID:       "AWSID122",
+					Status:   archive.ItemInfoStatusUnmodified,
+					Checksum: "file2 old checksum",
+				},
+			},
+		},
+		{
+			description: "it should keep the files of a directory that wasn't analyzed",
+			deadline:    &mockDeadline{at: "data/dir1"},
+			expectedFiles: []string{
+				"data/file1",
+				"data/file2",
+			},
+			expectedArchiveInfo: archive.Info{
+				"data/dir1/file3": archive.ItemInfo{
+					ID:       "AWSID122",
+					Status:   archive.ItemInfoStatusUnmodified,
+					Checksum: "file3 old checksum",
+				},
+				"data/file1": archive.ItemInfo{
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+				},
+				"data/file2": archive.ItemInfo{
+					Status:   archive.ItemInfoStatusModified,
+					Checksum: "xZzITM+6yGsa9masWjGdi+yAA0DlqCzTf/1795fy5Pk=",
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		for name, builder := range builders {
+			t.Run(fmt.Sprintf("%s (%s)", scenario.description, name), func(t *testing.T) {
+				scenario.deadline.reached = false
+
+				filename, archiveInfo, err := builder(scenario.deadline).Build(context.Background(), lastArchiveInfo, nil, "data")
+				if filename != "" {
+					defer os.Remove(filename)
+				}
+
+				if err != nil {
+					t.Fatalf("unexpected error “%v”", err)
+				}
+
+				if !reflect.DeepEqual(scenario.expectedArchiveInfo, archiveInfo) {
+					t.Errorf("archive info don't match.\n%s", Diff(scenario.expectedArchiveInfo, archiveInfo))
+				}
+
+				files, err := archiveFiles(name, filename)
+				if err != nil {
+					t.Fatalf("error reading archive. details: %s", err)
+				}
+
+				if !reflect.DeepEqual(scenario.expectedFiles, files) {
+					t.Errorf("archive files don't match.\n%s", Diff(scenario.expectedFiles, files))
+				}
+			})
+		}
+	}
+}
+
+// mockDeadline is reached on the given path. When sticky all the following
+// paths also reach the deadline.
+type mockDeadline struct {
+	at      string
+	sticky  bool
+	reached bool
+}
+
+func (m *mockDeadline) Reached(path string) bool {
+	if path == m.at {
+		m.reached = true
+		return true
+	}
+
+	return m.sticky && m.reached
+}
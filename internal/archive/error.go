@@ -85,6 +85,63 @@ const (
 
 	// ErrorCodeExtractingFile problem extracting file from TAR.
 	ErrorCodeExtractingFile ErrorCode = "extracting-file"
+
+	// ErrorCodeCancelled action cancelled by the user.
+	ErrorCodeCancelled ErrorCode = "cancelled"
+
+	// ErrorCodeUnknownFormat the archive format isn't registered.
+	ErrorCodeUnknownFormat ErrorCode = "unknown-format"
+
+	// ErrorCodeUnknownEnvelop the envelop isn't registered.
+	ErrorCodeUnknownEnvelop ErrorCode = "unknown-envelop"
+
+	// ErrorCodeZIPGeneration error adding all files to the ZIP.
+	ErrorCodeZIPGeneration ErrorCode = "zip-generation"
+
+	// ErrorCodeReadingZIP error while reading the ZIP content.
+	ErrorCodeReadingZIP ErrorCode = "reading-zip"
+
+	// ErrorCodeRedundancy the redundancy percentage is out of range.
+	ErrorCodeRedundancy ErrorCode = "redundancy"
+
+	// ErrorCodeReadingFile error while reading the content of a file.
+	ErrorCodeReadingFile ErrorCode = "reading-file"
+
+	// ErrorCodeWritingFile error while writing the content of a file.
+	ErrorCodeWritingFile ErrorCode = "writing-file"
+
+	// ErrorCodeGeneratingParity error while calculating the parity data.
+	ErrorCodeGeneratingParity ErrorCode = "generating-parity"
+
+	// ErrorCodeParityFormat the parity file has an invalid format.
+	ErrorCodeParityFormat ErrorCode = "parity-format"
+
+	// ErrorCodeRepairingArchive error while rebuilding the corrupted parts of
+	// the archive.
+	ErrorCodeRepairingArchive ErrorCode = "repairing-archive"
+
+	// ErrorCodeWatching error while watching the backup paths for changes.
+	ErrorCodeWatching ErrorCode = "watching"
+
+	// ErrorCodeChecksumMismatch the extracted file doesn't have the checksum
+	// stored in the archive information.
+	ErrorCodeChecksumMismatch ErrorCode = "checksum-mismatch"
+
+	// ErrorCodeUnknownPolicy the policy for the existing files when
+	// extracting doesn't exist.
+	ErrorCodeUnknownPolicy ErrorCode = "unknown-policy"
+
+	// ErrorCodeWritingHeader error while writing the format header of the
+	// archive.
+	ErrorCodeWritingHeader ErrorCode = "writing-header"
+
+	// ErrorCodeReadingHeader error while reading the format header of the
+	// archive.
+	ErrorCodeReadingHeader ErrorCode = "reading-header"
+
+	// ErrorCodeHeaderVersion the format header of the archive was written by a
+	// newer version of the tool.
+	ErrorCodeHeaderVersion ErrorCode = "header-version"
 )
 
 // ErrorCode stores the error type that occurred to easy automatize an external
@@ -114,6 +171,23 @@ var errorCodeString = map[ErrorCode]string{
 	ErrorCodeReadingTAR:            "error reading tar",
 	ErrorCodeCreatingDirectories:   "error while creating directories",
 	ErrorCodeExtractingFile:        "error extracting file",
+	ErrorCodeCancelled:             "action cancelled by the user",
+	ErrorCodeUnknownFormat:         "unknown archive format",
+	ErrorCodeUnknownEnvelop:        "unknown envelop",
+	ErrorCodeZIPGeneration:         "error generating zip file",
+	ErrorCodeReadingZIP:            "error reading zip",
+	ErrorCodeRedundancy:            "invalid redundancy percentage",
+	ErrorCodeReadingFile:           "error reading file",
+	ErrorCodeWritingFile:           "error writing file",
+	ErrorCodeGeneratingParity:      "error generating parity data",
+	ErrorCodeParityFormat:          "invalid parity file format",
+	ErrorCodeRepairingArchive:      "error repairing archive",
+	ErrorCodeWatching:              "error watching the backup paths",
+	ErrorCodeChecksumMismatch:      "extracted file checksum doesn't match",
+	ErrorCodeUnknownPolicy:         "unknown policy for the existing files",
+	ErrorCodeWritingHeader:         "error writing the archive header",
+	ErrorCodeReadingHeader:         "error reading the archive header",
+	ErrorCodeHeaderVersion:         "unsupported archive header version",
 }
 
 // String translate the error code to a human readable text.
@@ -125,6 +199,16 @@ func (e ErrorCode) String() string {
 	return "unknown error code"
 }
 
+var (
+	// ErrCancelled is matched with errors.Is when the archive action was
+	// cancelled by the user.
+	ErrCancelled = errors.New("archive: action cancelled by the user")
+
+	// ErrAuthFailed is matched with errors.Is when the encrypted archive was
+	// modified or the secret is wrong.
+	ErrAuthFailed = errors.New("archive: authentication failed")
+)
+
 // Error stores error details from archive operations.
 type Error struct {
 	Filename string
@@ -145,6 +229,25 @@ func (e Error) Error() string {
 	return e.String()
 }
 
+// Unwrap returns the low level error, so it can be inspected with errors.Is and
+// errors.As.
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// Is allows comparing the error with the sentinel errors of the package (e.g.
+// errors.Is(err, archive.ErrAuthFailed)).
+func (e Error) Is(target error) bool {
+	switch target {
+	case ErrCancelled:
+		return e.Code == ErrorCodeCancelled
+	case ErrAuthFailed:
+		return e.Code == ErrorCodeAuthFailed
+	}
+
+	return false
+}
+
 // String translate the error to a human readable text.
 func (e Error) String() string {
 	var filename string
@@ -167,10 +270,8 @@ func ErrorEqual(first, second error) bool {
 		return first == second
 	}
 
-	err1, ok1 := errors.Cause(first).(*Error)
-	err2, ok2 := errors.Cause(second).(*Error)
-
-	if !ok1 || !ok2 {
+	var err1, err2 *Error
+	if !errors.As(first, &err1) || !errors.As(second, &err2) {
 		return false
 	}
 
@@ -200,6 +301,14 @@ const (
 	// file.
 	PathErrorCodeWritingTARHeader PathErrorCode = "writing-tar-header"
 
+	// PathErrorCodeCreateZIPHeader error while creating the ZIP header from the
+	// path information.
+	PathErrorCodeCreateZIPHeader PathErrorCode = "create-zip-header"
+
+	// PathErrorCodeWritingZIPHeader error while writing the header into the ZIP
+	// file.
+	PathErrorCodeWritingZIPHeader PathErrorCode = "writing-zip-header"
+
 	// PathErrorCodeOpeningFile error while opening file.
 	PathErrorCodeOpeningFile PathErrorCode = "opening-file"
 
@@ -213,6 +322,9 @@ const (
 	// PathErrorCodeRewindingFile error while moving back to the beginning of the
 	// file.
 	PathErrorCodeRewindingFile PathErrorCode = "rewinding-file"
+
+	// PathErrorCodeReadingFile error while reading the file content.
+	PathErrorCodeReadingFile PathErrorCode = "reading-file"
 )
 
 // PathErrorCode stores the error type that occurred to easy automatize an
@@ -228,6 +340,10 @@ func (p PathErrorCode) String() string {
 		return "error creating tar header"
 	case PathErrorCodeWritingTARHeader:
 		return "error writing header in tar"
+	case PathErrorCodeCreateZIPHeader:
+		return "error creating zip header"
+	case PathErrorCodeWritingZIPHeader:
+		return "error writing header in zip"
 	case PathErrorCodeOpeningFile:
 		return "error opening file"
 	case PathErrorCodeWritingFile:
@@ -236,6 +352,8 @@ func (p PathErrorCode) String() string {
 		return "error calculating hash SHA256 from file"
 	case PathErrorCodeRewindingFile:
 		return "error moving to the beginning of the file"
+	case PathErrorCodeReadingFile:
+		return "error reading file"
 	}
 
 	return "unknown error code"
@@ -261,6 +379,12 @@ func (p PathError) Error() string {
 	return p.String()
 }
 
+// Unwrap returns the low level error, so it can be inspected with errors.Is and
+// errors.As.
+func (p PathError) Unwrap() error {
+	return p.Err
+}
+
 // String translate the error to a human readable text.
 func (p PathError) String() string {
 	var path string
@@ -283,10 +407,8 @@ func PathErrorEqual(first, second error) bool {
 		return first == second
 	}
 
-	err1, ok1 := errors.Cause(first).(*PathError)
-	err2, ok2 := errors.Cause(second).(*PathError)
-
-	if !ok1 || !ok2 {
+	var err1, err2 *PathError
+	if !errors.As(first, &err1) || !errors.As(second, &err2) {
 		return false
 	}
 
@@ -1,9 +1,10 @@
 package archive_test
 
 import (
-	"errors"
+	"os"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/rafaeljusto/toglacier/internal/archive"
 )
 
@@ -148,6 +149,91 @@ func TestError_Error(t *testing.T) {
 			err:         &archive.Error{Code: archive.ErrorCodeExtractingFile},
 			expected:    "archive: error extracting file",
 		},
+		{
+			description: "it should show the correct error message for cancelled action",
+			err:         &archive.Error{Code: archive.ErrorCodeCancelled},
+			expected:    "archive: action cancelled by the user",
+		},
+		{
+			description: "it should show the correct error message for unknown archive format",
+			err:         &archive.Error{Code: archive.ErrorCodeUnknownFormat},
+			expected:    "archive: unknown archive format",
+		},
+		{
+			description: "it should show the correct error message for unknown envelop",
+			err:         &archive.Error{Code: archive.ErrorCodeUnknownEnvelop},
+			expected:    "archive: unknown envelop",
+		},
+		{
+			description: "it should show the correct error message for ZIP generation problem",
+			err:         &archive.Error{Code: archive.ErrorCodeZIPGeneration},
+			expected:    "archive: error generating zip file",
+		},
+		{
+			description: "it should show the correct error message for reading ZIP problem",
+			err:         &archive.Error{Code: archive.ErrorCodeReadingZIP},
+			expected:    "archive: error reading zip",
+		},
+		{
+			description: "it should show the correct error message for invalid redundancy",
+			err:         &archive.Error{Code: archive.ErrorCodeRedundancy},
+			expected:    "archive: invalid redundancy percentage",
+		},
+		{
+			description: "it should show the correct error message for reading file problem",
+			err:         &archive.Error{Code: archive.ErrorCodeReadingFile},
+			expected:    "archive: error reading file",
+		},
+		{
+			description: "it should show the correct error message for writing file problem",
+			err:         &archive.Error{Code: archive.ErrorCodeWritingFile},
+			expected:    "archive: error writing file",
+		},
+		{
+			description: "it should show the correct error message for parity generation problem",
+			err:         &archive.Error{Code: archive.ErrorCodeGeneratingParity},
+			expected:    "archive: error generating parity data",
+		},
+		{
+			description: "it should show the correct error message for invalid parity file",
+			err:         &archive.Error{Code: archive.ErrorCodeParityFormat},
+			expected:    "archive: invalid parity file format",
+		},
+		{
+			description: "it should show the correct error message for archive repair problem",
+			err:         &archive.Error{Code: archive.ErrorCodeRepairingArchive},
+			expected:    "archive: error repairing archive",
+		},
+		{
+			description: "it should show the correct error message for watching problems",
+			err:         &archive.Error{Code: archive.ErrorCodeWatching},
+			expected:    "archive: error watching the backup paths",
+		},
+		{
+			description: "it should show the correct error message for checksum mismatch",
+			err:         &archive.Error{Code: archive.ErrorCodeChecksumMismatch},
+			expected:    "archive: extracted file checksum doesn't match",
+		},
+		{
+			description: "it should show the correct error message for unknown overwrite policy",
+			err:         &archive.Error{Code: archive.ErrorCodeUnknownPolicy},
+			expected:    "archive: unknown policy for the existing files",
+		},
+		{
+			description: "it should show the correct error message for writing header problem",
+			err:         &archive.Error{Code: archive.ErrorCodeWritingHeader},
+			expected:    "archive: error writing the archive header",
+		},
+		{
+			description: "it should show the correct error message for reading header problem",
+			err:         &archive.Error{Code: archive.ErrorCodeReadingHeader},
+			expected:    "archive: error reading the archive header",
+		},
+		{
+			description: "it should show the correct error message for unsupported header version",
+			err:         &archive.Error{Code: archive.ErrorCodeHeaderVersion},
+			expected:    "archive: unsupported archive header version",
+		},
 		{
 			description: "it should detect when the code doesn't exist",
 			err:         &archive.Error{Code: archive.ErrorCode("i-dont-exist")},
@@ -164,6 +250,50 @@ func TestError_Error(t *testing.T) {
 	}
 }
 
+func TestError_Is(t *testing.T) {
+	scenarios := []struct {
+		description string
+		err         error
+		target      error
+		expected    bool
+	}{
+		{
+			description: "it should detect a cancellation",
+			err:         errors.Wrap(&archive.Error{Code: archive.ErrorCodeCancelled}, "building archive"),
+			target:      archive.ErrCancelled,
+			expected:    true,
+		},
+		{
+			description: "it should detect an authentication failure",
+			err:         &archive.Error{Code: archive.ErrorCodeAuthFailed},
+			target:      archive.ErrAuthFailed,
+			expected:    true,
+		},
+		{
+			description: "it should detect the low level error of a path",
+			err: &archive.Error{
+				Code: archive.ErrorCodeTARGeneration,
+				Err:  &archive.PathError{Code: archive.PathErrorCodeOpeningFile, Err: os.ErrPermission},
+			},
+			target:   os.ErrPermission,
+			expected: true,
+		},
+		{
+			description: "it should not match a different sentinel error",
+			err:         &archive.Error{Code: archive.ErrorCodeCancelled},
+			target:      archive.ErrAuthFailed,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if is := errors.Is(scenario.err, scenario.target); is != scenario.expected {
+				t.Errorf("results don't match. expected “%t” and got “%t”", scenario.expected, is)
+			}
+		})
+	}
+}
+
 func TestErrorEqual(t *testing.T) {
 	scenarios := []struct {
 		description string
@@ -307,6 +437,16 @@ func TestPathError_Error(t *testing.T) {
 			err:         &archive.PathError{Code: archive.PathErrorCodeWritingTARHeader},
 			expected:    "archive: error writing header in tar",
 		},
+		{
+			description: "it should show the correct error message for ZIP header creation problem",
+			err:         &archive.PathError{Code: archive.PathErrorCodeCreateZIPHeader},
+			expected:    "archive: error creating zip header",
+		},
+		{
+			description: "it should show the correct error message for writing ZIP header problem",
+			err:         &archive.PathError{Code: archive.PathErrorCodeWritingZIPHeader},
+			expected:    "archive: error writing header in zip",
+		},
 		{
 			description: "it should show the correct error message for opening file problem",
 			err:         &archive.PathError{Code: archive.PathErrorCodeOpeningFile},
@@ -327,6 +467,11 @@ func TestPathError_Error(t *testing.T) {
 			err:         &archive.PathError{Code: archive.PathErrorCodeRewindingFile},
 			expected:    "archive: error moving to the beginning of the file",
 		},
+		{
+			description: "it should show the correct error message for reading file problem",
+			err:         &archive.PathError{Code: archive.PathErrorCodeReadingFile},
+			expected:    "archive: error reading file",
+		},
 		{
 			description: "it should detect when the code doesn't exist",
 			err:         &archive.PathError{Code: archive.PathErrorCode("i-dont-exist")},
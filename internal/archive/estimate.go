@@ -0,0 +1,83 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// Estimate totalizes the files and bytes of the backup paths before the
+// archive is built. It is an upper limit, as unmodified files aren't added to
+// incremental archives.
+type Estimate struct {
+	Files int64
+	Bytes int64
+}
+
+// Progress receives the archive build progress, useful for progress bars.
+type Progress interface {
+	// OnEstimate is called before the archive is built with the totalization of
+	// the backup paths.
+	OnEstimate(estimate Estimate)
+
+	// OnFile is called after each file of the backup paths is analyzed. The
+	// added flag is false when the file is unmodified since the last archive.
+	OnFile(path string, size int64, added bool)
+}
+
+// Estimator totalizes the backup paths without building the archive, useful
+// for disk-space preflight checks.
+type Estimator interface {
+	Estimate(ctx context.Context, ignorePatterns []*regexp.Regexp, backupPaths ...string) (Estimate, error)
+}
+
+// estimate walks the backup paths with the same rules used to build the
+// archive, without reading the files content. When tolerant the paths that
+// can't be read are ignored.
+func estimate(ctx context.Context, logger log.Logger, fileSystem FileSystem, tolerant bool, ignorePatterns []*regexp.Regexp, backupPaths ...string) (Estimate, error) {
+	var e Estimate
+
+	for _, backupPath := range backupPaths {
+		if backupPath == "" {
+			continue
+		}
+
+		err := fileSystem.Walk(backupPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if tolerant {
+					// the build decides if the path can be skipped
+					return nil
+				}
+				return errors.WithStack(newPathError(path, PathErrorCodeInfo, err))
+			}
+
+			if ctx.Err() != nil {
+				return errors.WithStack(newError(path, ErrorCodeCancelled, ctx.Err()))
+			}
+
+			for _, ignorePattern := range ignorePatterns {
+				if ignorePattern.MatchString(path) {
+					return nil
+				}
+			}
+
+			// only regular files are added to the archive
+			if info.Mode().IsRegular() {
+				e.Files++
+				e.Bytes += info.Size()
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return Estimate{}, errors.WithStack(err)
+		}
+	}
+
+	logger.Debugf("archive: estimated %d files with %d bytes", e.Files, e.Bytes)
+	return e, nil
+}
@@ -0,0 +1,325 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// ExistingBackupSuffix is added to the name of the existing files replaced by
+// an extraction with the OverwriteBackup policy.
+const ExistingBackupSuffix = ".toglacier-bak"
+
+const (
+	// OverwriteReplace replaces the existing files by the extracted ones.
+	OverwriteReplace OverwritePolicy = iota
+
+	// OverwriteSkip keeps the existing files, extracting only the files that
+	// don't exist.
+	OverwriteSkip
+
+	// OverwriteBackup keeps a copy of the existing files, renamed with the
+	// ExistingBackupSuffix, before replacing them.
+	OverwriteBackup
+
+	// OverwriteNewer replaces the existing files only when the extracted ones
+	// were modified after them.
+	OverwriteNewer
+)
+
+// OverwritePolicy defines what happens with the existing files when extracting
+// an archive.
+type OverwritePolicy int
+
+var overwritePolicyString = map[OverwritePolicy]string{
+	OverwriteReplace: "overwrite",
+	OverwriteSkip:    "skip",
+	OverwriteBackup:  "rename",
+	OverwriteNewer:   "newer-only",
+}
+
+// String returns the name of the policy, as accepted by ParseOverwritePolicy.
+func (o OverwritePolicy) String() string {
+	if name, ok := overwritePolicyString[o]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// ParseOverwritePolicy returns the policy with the given name (overwrite, skip,
+// rename or newer-only). On error it will return an Error type encapsulated in
+// a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func ParseOverwritePolicy(name string) (OverwritePolicy, error) {
+	for policy, policyName := range overwritePolicyString {
+		if policyName == name {
+			return policy, nil
+		}
+	}
+
+	return OverwriteReplace, errors.WithStack(newError("", ErrorCodeUnknownPolicy, errors.Errorf("policy “%s” doesn't exist", name)))
+}
+
+// ExtractProgress receives the result of the extraction of each file, useful
+// for reports.
+type ExtractProgress interface {
+	// OnExtract is called for each file selected for the extraction after it is
+	// moved to its place. The extracted flag is false when the existing file was
+	// kept due to the overwrite policy.
+	OnExtract(path string, size int64, extracted bool)
+}
+
+// ExtractFailure is implemented by the ExtractProgress that also wants to know
+// about the files that weren't extracted because their content doesn't match
+// the archive information.
+type ExtractFailure interface {
+	// OnExtractFailure is called for each file that was discarded, with the
+	// reason.
+	OnExtractFailure(path string, err error)
+}
+
+// extraction writes the extracted files with temporary names in their
+// directories, moving them to their places only after all files were extracted
+// and their checksums were verified with the archive information. So a failed
+// extraction doesn't leave half-written files in place of good ones. A file
+// with a wrong checksum is discarded and reported, and the other files are
+// still moved, unless all or nothing must be extracted.
+type extraction struct {
+	logger        log.Logger
+	overwrite     OverwritePolicy
+	allOrNothing  bool
+	progress      ExtractProgress
+	normalization Normalization
+	files         []extractedFile
+}
+
+// extractedFile is a file waiting to be moved to its place.
+type extractedFile struct {
+	name     string
+	path     string
+	tmpPath  string
+	checksum string
+	size     int64
+}
+
+// write stores the content of the file in a temporary file of the same
+// directory. The name is the original path of the file, used to find it in the
+// archive information, and the path is where the file is extracted, with the
+// archived name converted to the normalization form. The modification time is
+// compared with the existing file when only newer files replace the existing
+// ones.
+func (e *extraction) write(name, path string, mode os.FileMode, modTime time.Time, content io.Reader) (int64, error) {
+	path = e.destination(path)
+
+	if e.keepExisting(path, modTime) {
+		return 0, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), extractDirectoryPermission); err != nil {
+		return 0, errors.WithStack(newError(path, ErrorCodeCreatingDirectories, err))
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".toglacier-")
+	if err != nil {
+		return 0, errors.WithStack(newError(path, ErrorCodeTmpFileCreation, err))
+	}
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmpFile, hash), content)
+	if err == nil {
+		err = tmpFile.Chmod(mode)
+	}
+
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return 0, errors.WithStack(newError(path, ErrorCodeExtractingFile, err))
+	}
+
+	e.files = append(e.files, extractedFile{
+		name:     name,
+		path:     path,
+		tmpPath:  tmpFile.Name(),
+		checksum: base64.StdEncoding.EncodeToString(hash.Sum(nil)),
+		size:     written,
+	})
+
+	return written, nil
+}
+
+// special creates the device file or FIFO with a temporary name in its
+// directory, moved to its place with the other files. As special files can't
+// always be created (e.g. devices without root privileges or outside Linux),
+// the failures are only logged.
+func (e *extraction) special(name, path string, mode os.FileMode, modTime time.Time, devMajor, devMinor int64) error {
+	path = e.destination(path)
+
+	if e.keepExisting(path, modTime) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), extractDirectoryPermission); err != nil {
+		return errors.WithStack(newError(path, ErrorCodeCreatingDirectories, err))
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.toglacier-%d", filepath.Base(path), time.Now().UnixNano()))
+	if err := makeSpecial(tmpPath, mode, devMajor, devMinor); err != nil {
+		e.logger.Warningf("archive: special file “%s” couldn't be created. details: %s", path, err)
+		return nil
+	}
+
+	// special files have no checksum to verify
+	e.files = append(e.files, extractedFile{
+		name:    name,
+		path:    path,
+		tmpPath: tmpPath,
+	})
+
+	return nil
+}
+
+// destination returns where the file of the path is extracted. Only the name
+// stored in the archive is converted to the normalization form, keeping the
+// root directory of the archive as it is.
+func (e *extraction) destination(path string) string {
+	parts := strings.SplitN(path, string(os.PathSeparator), 2)
+	if len(parts) < 2 {
+		return e.normalization.apply(path)
+	}
+
+	return parts[0] + string(os.PathSeparator) + e.normalization.apply(parts[1])
+}
+
+// keepExisting informs if an existing file is kept due to the overwrite
+// policy, reporting it.
+func (e *extraction) keepExisting(path string, modTime time.Time) bool {
+	existing, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case e.overwrite == OverwriteSkip:
+		e.logger.Infof("archive: path “%s” already exists and will not be overwritten", path)
+		e.kept(path)
+		return true
+
+	case e.overwrite == OverwriteNewer && !modTime.After(existing.ModTime()):
+		e.logger.Infof("archive: path “%s” is newer than the extracted one and will not be overwritten", path)
+		e.kept(path)
+		return true
+	}
+
+	return false
+}
+
+// commit verifies the checksums of the extracted files with the archive
+// information and moves them to their places. A file whose checksum doesn't
+// match is discarded, keeping the existing one, and reported to the progress
+// when it implements ExtractFailure. With the all or nothing option no file is
+// moved when a checksum doesn't match. Files without checksum in the archive
+// information aren't verified. The temporary files that weren't moved are
+// removed on error.
+func (e *extraction) commit(info Info) error {
+	var verified []extractedFile
+	for _, file := range e.files {
+		itemInfo, ok := info[file.name]
+		if !ok || itemInfo.Checksum == "" {
+			e.logger.Debugf("archive: path “%s” has no checksum to verify", file.path)
+			verified = append(verified, file)
+			continue
+		}
+
+		if itemInfo.Checksum == file.checksum {
+			verified = append(verified, file)
+			continue
+		}
+
+		err := errors.WithStack(newError(file.path, ErrorCodeChecksumMismatch,
+			fmt.Errorf("expected checksum “%s” and got “%s”", itemInfo.Checksum, file.checksum)))
+
+		if e.allOrNothing {
+			e.rollback()
+			return err
+		}
+
+		e.logger.Warningf("archive: path “%s” will not be extracted. details: %s", file.path, err)
+		if err := os.Remove(file.tmpPath); err != nil && !os.IsNotExist(err) {
+			e.logger.Warningf("archive: failed to remove temporary file “%s”. details: %s", file.tmpPath, err)
+		}
+
+		if failure, ok := e.progress.(ExtractFailure); ok {
+			failure.OnExtractFailure(file.path, err)
+		}
+	}
+	e.files = verified
+
+	for i, file := range e.files {
+		if e.overwrite == OverwriteBackup {
+			if _, err := os.Lstat(file.path); err == nil {
+				if err := os.Rename(file.path, file.path+ExistingBackupSuffix); err != nil {
+					e.files = e.files[i:]
+					e.rollback()
+					return errors.WithStack(newError(file.path, ErrorCodeExtractingFile, err))
+				}
+			}
+		}
+
+		if err := os.Rename(file.tmpPath, file.path); err != nil {
+			e.files = e.files[i:]
+			e.rollback()
+			return errors.WithStack(newError(file.path, ErrorCodeExtractingFile, err))
+		}
+
+		if e.progress != nil {
+			e.progress.OnExtract(file.path, file.size, true)
+		}
+	}
+
+	e.files = nil
+	return nil
+}
+
+// kept reports an existing file that wasn't replaced.
+func (e *extraction) kept(path string) {
+	if e.progress != nil {
+		e.progress.OnExtract(path, 0, false)
+	}
+}
+
+// rollback removes the temporary files that weren't moved to their places. It
+// does nothing after a commit.
+func (e *extraction) rollback() {
+	for _, file := range e.files {
+		if err := os.Remove(file.tmpPath); err != nil && !os.IsNotExist(err) {
+			e.logger.Warningf("archive: failed to remove temporary file “%s”. details: %s", file.tmpPath, err)
+		}
+	}
+
+	e.files = nil
+}
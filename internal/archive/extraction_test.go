@@ -0,0 +1,436 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+func TestTARBuilder_ExtractOverwrite(t *testing.T) {
+	checksum := func(content string) string {
+		hash := sha256.Sum256([]byte(content))
+		return base64.StdEncoding.EncodeToString(hash[:])
+	}
+
+	now := time.Now()
+	path := filepath.Join("backup-20170914103000", "data", "file.txt")
+
+	scenarios := []struct {
+		description       string
+		overwrite         archive.OverwritePolicy
+		existing          string
+		existingTime      time.Time
+		checksum          string
+		expectedFiles     map[string]string
+		expectedExtracted []string
+		expectedError     error
+	}{
+		{
+			description: "it should replace an existing file",
+			overwrite:   archive.OverwriteReplace,
+			existing:    "old content",
+			checksum:    checksum("new content"),
+			expectedFiles: map[string]string{
+				"file.txt": "new content",
+			},
+			expectedExtracted: []string{path + " 11 true"},
+		},
+		{
+			description: "it should keep an existing file",
+			overwrite:   archive.OverwriteSkip,
+			existing:    "old content",
+			checksum:    checksum("new content"),
+			expectedFiles: map[string]string{
+				"file.txt": "old content",
+			},
+			expectedExtracted: []string{path + " 0 false"},
+		},
+		{
+			description: "it should extract a file that doesn't exist when keeping the existing files",
+			overwrite:   archive.OverwriteSkip,
+			checksum:    checksum("new content"),
+			expectedFiles: map[string]string{
+				"file.txt": "new content",
+			},
+			expectedExtracted: []string{path + " 11 true"},
+		},
+		{
+			description: "it should keep a copy of an existing file",
+			overwrite:   archive.OverwriteBackup,
+			existing:    "old content",
+			checksum:    checksum("new content"),
+			expectedFiles: map[string]string{
+				"file.txt": "new content",
+				"file.txt" + archive.ExistingBackupSuffix: "old content",
+			},
+			expectedExtracted: []string{path + " 11 true"},
+		},
+		{
+			description:  "it should replace an older existing file",
+			overwrite:    archive.OverwriteNewer,
+			existing:     "old content",
+			existingTime: now.Add(-2 * time.Hour),
+			checksum:     checksum("new content"),
+			expectedFiles: map[string]string{
+				"file.txt": "new content",
+			},
+			expectedExtracted: []string{path + " 11 true"},
+		},
+		{
+			description:  "it should keep a newer existing file",
+			overwrite:    archive.OverwriteNewer,
+			existing:     "old content",
+			existingTime: now,
+			checksum:     checksum("new content"),
+			expectedFiles: map[string]string{
+				"file.txt": "old content",
+			},
+			expectedExtracted: []string{path + " 0 false"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			tarFile, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary file. details: %s", err)
+			}
+			defer os.Remove(tarFile.Name())
+
+			info, err := json.Marshal(archive.Info{
+				"/data/file.txt": archive.ItemInfo{
+					ID:       "AWSID123",
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: scenario.checksum,
+				},
+			})
+			if err != nil {
+				t.Fatalf("error encoding archive info. details: %s", err)
+			}
+
+			tarArchive := tar.NewWriter(tarFile)
+			for name, content := range map[string]string{
+				"backup-20170914103000/data/file.txt":              "new content",
+				"backup-20170914103000/" + archive.TARInfoFilename: string(info),
+			} {
+				header := &tar.Header{
+					Name:     name,
+					Mode:     0600,
+					ModTime:  now.Add(-time.Hour),
+					Size:     int64(len(content)),
+					Typeflag: tar.TypeReg,
+				}
+
+				if err = tarArchive.WriteHeader(header); err != nil {
+					t.Fatalf("error writing tar header. details: %s", err)
+				}
+
+				if _, err = tarArchive.Write([]byte(content)); err != nil {
+					t.Fatalf("error writing tar content. details: %s", err)
+				}
+			}
+			tarArchive.Close()
+			tarFile.Close()
+
+			extractedDir := filepath.Join(dir, "backup-20170914103000", "data")
+			if scenario.existing != "" {
+				if err = os.MkdirAll(extractedDir, 0755); err != nil {
+					t.Fatalf("error creating directory. details: %s", err)
+				}
+
+				if err = ioutil.WriteFile(filepath.Join(extractedDir, "file.txt"), []byte(scenario.existing), 0600); err != nil {
+					t.Fatalf("error writing existing file. details: %s", err)
+				}
+
+				if !scenario.existingTime.IsZero() {
+					if err = os.Chtimes(filepath.Join(extractedDir, "file.txt"), scenario.existingTime, scenario.existingTime); err != nil {
+						t.Fatalf("error changing existing file time. details: %s", err)
+					}
+				}
+			}
+
+			// the files are extracted in the current directory
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("error retrieving the current directory. details: %s", err)
+			}
+			defer os.Chdir(wd)
+
+			if err = os.Chdir(dir); err != nil {
+				t.Fatalf("error changing the current directory. details: %s", err)
+			}
+
+			builder := archive.NewTARBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			})
+			builder.Overwrite = scenario.overwrite
+
+			var progressRecorder mockProgress
+			builder.ExtractProgress = &progressRecorder
+
+			_, err = builder.Extract(context.Background(), tarFile.Name(), nil)
+			if !archive.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			// no temporary file should be left behind
+			files := make(map[string]string)
+			entries, _ := ioutil.ReadDir(extractedDir)
+			for _, entry := range entries {
+				content, err := ioutil.ReadFile(filepath.Join(extractedDir, entry.Name()))
+				if err != nil {
+					t.Fatalf("error reading extracted file. details: %s", err)
+				}
+				files[entry.Name()] = string(content)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedFiles, files) {
+				t.Errorf("files don't match.\n%s", Diff(scenario.expectedFiles, files))
+			}
+
+			if !reflect.DeepEqual(scenario.expectedExtracted, progressRecorder.extracted) {
+				t.Errorf("extracted files don't match.\n%s", Diff(scenario.expectedExtracted, progressRecorder.extracted))
+			}
+		})
+	}
+}
+
+func TestTARBuilder_ExtractChecksumMismatch(t *testing.T) {
+	checksum := func(content string) string {
+		hash := sha256.Sum256([]byte(content))
+		return base64.StdEncoding.EncodeToString(hash[:])
+	}
+
+	badPath := filepath.Join("backup-20170914103000", "data", "bad.txt")
+	goodPath := filepath.Join("backup-20170914103000", "data", "good.txt")
+
+	scenarios := []struct {
+		description       string
+		allOrNothing      bool
+		expectedFiles     map[string]string
+		expectedExtracted []string
+		expectedFailed    []string
+		expectedError     error
+	}{
+		{
+			description: "it should keep only the existing file that doesn't match the checksum",
+			expectedFiles: map[string]string{
+				"bad.txt":  "old content",
+				"good.txt": "new content",
+			},
+			expectedExtracted: []string{goodPath + " 11 true"},
+			expectedFailed:    []string{badPath},
+		},
+		{
+			description:  "it should keep all existing files when all or nothing is extracted",
+			allOrNothing: true,
+			expectedFiles: map[string]string{
+				"bad.txt":  "old content",
+				"good.txt": "old content",
+			},
+			expectedError: &archive.Error{
+				Filename: badPath,
+				Code:     archive.ErrorCodeChecksumMismatch,
+				Err:      errors.New("expected checksum “" + checksum("other content") + "” and got “" + checksum("new content") + "”"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			tarFile, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary file. details: %s", err)
+			}
+			defer os.Remove(tarFile.Name())
+
+			info, err := json.Marshal(archive.Info{
+				"/data/bad.txt": archive.ItemInfo{
+					ID:       "AWSID123",
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: checksum("other content"),
+				},
+				"/data/good.txt": archive.ItemInfo{
+					ID:       "AWSID123",
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: checksum("new content"),
+				},
+			})
+			if err != nil {
+				t.Fatalf("error encoding archive info. details: %s", err)
+			}
+
+			tarArchive := tar.NewWriter(tarFile)
+			for _, item := range []struct {
+				name    string
+				content string
+			}{
+				{name: "backup-20170914103000/data/bad.txt", content: "new content"},
+				{name: "backup-20170914103000/data/good.txt", content: "new content"},
+				{name: "backup-20170914103000/" + archive.TARInfoFilename, content: string(info)},
+			} {
+				header := &tar.Header{
+					Name:     item.name,
+					Mode:     0600,
+					ModTime:  time.Now(),
+					Size:     int64(len(item.content)),
+					Typeflag: tar.TypeReg,
+				}
+
+				if err = tarArchive.WriteHeader(header); err != nil {
+					t.Fatalf("error writing tar header. details: %s", err)
+				}
+
+				if _, err = tarArchive.Write([]byte(item.content)); err != nil {
+					t.Fatalf("error writing tar content. details: %s", err)
+				}
+			}
+			tarArchive.Close()
+			tarFile.Close()
+
+			extractedDir := filepath.Join(dir, "backup-20170914103000", "data")
+			if err = os.MkdirAll(extractedDir, 0755); err != nil {
+				t.Fatalf("error creating directory. details: %s", err)
+			}
+
+			for _, name := range []string{"bad.txt", "good.txt"} {
+				if err = ioutil.WriteFile(filepath.Join(extractedDir, name), []byte("old content"), 0600); err != nil {
+					t.Fatalf("error writing existing file. details: %s", err)
+				}
+			}
+
+			// the files are extracted in the current directory
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("error retrieving the current directory. details: %s", err)
+			}
+			defer os.Chdir(wd)
+
+			if err = os.Chdir(dir); err != nil {
+				t.Fatalf("error changing the current directory. details: %s", err)
+			}
+
+			builder := archive.NewTARBuilder(mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			})
+			builder.AllOrNothing = scenario.allOrNothing
+
+			var progressRecorder mockProgress
+			builder.ExtractProgress = &progressRecorder
+
+			_, err = builder.Extract(context.Background(), tarFile.Name(), nil)
+			if !archive.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			// no temporary file should be left behind
+			files := make(map[string]string)
+			entries, _ := ioutil.ReadDir(extractedDir)
+			for _, entry := range entries {
+				content, err := ioutil.ReadFile(filepath.Join(extractedDir, entry.Name()))
+				if err != nil {
+					t.Fatalf("error reading extracted file. details: %s", err)
+				}
+				files[entry.Name()] = string(content)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedFiles, files) {
+				t.Errorf("files don't match.\n%s", Diff(scenario.expectedFiles, files))
+			}
+
+			if !reflect.DeepEqual(scenario.expectedExtracted, progressRecorder.extracted) {
+				t.Errorf("extracted files don't match.\n%s", Diff(scenario.expectedExtracted, progressRecorder.extracted))
+			}
+
+			if !reflect.DeepEqual(scenario.expectedFailed, progressRecorder.failed) {
+				t.Errorf("failed files don't match.\n%s", Diff(scenario.expectedFailed, progressRecorder.failed))
+			}
+		})
+	}
+}
+
+func TestParseOverwritePolicy(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		name          string
+		expected      archive.OverwritePolicy
+		expectedError error
+	}{
+		{
+			description: "it should parse the overwrite policy",
+			name:        "overwrite",
+			expected:    archive.OverwriteReplace,
+		},
+		{
+			description: "it should parse the skip policy",
+			name:        "skip",
+			expected:    archive.OverwriteSkip,
+		},
+		{
+			description: "it should parse the rename policy",
+			name:        "rename",
+			expected:    archive.OverwriteBackup,
+		},
+		{
+			description: "it should parse the newer-only policy",
+			name:        "newer-only",
+			expected:    archive.OverwriteNewer,
+		},
+		{
+			description: "it should detect an unknown policy",
+			name:        "merge",
+			expected:    archive.OverwriteReplace,
+			expectedError: &archive.Error{
+				Code: archive.ErrorCodeUnknownPolicy,
+				Err:  errors.New("policy “merge” doesn't exist"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			policy, err := archive.ParseOverwritePolicy(scenario.name)
+			if !archive.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if policy != scenario.expected {
+				t.Errorf("policies don't match. expected “%s” and got “%s”", scenario.expected, policy)
+			}
+
+			if err == nil && policy.String() != scenario.name {
+				t.Errorf("names don't match. expected “%s” and got “%s”", scenario.name, policy.String())
+			}
+		})
+	}
+}
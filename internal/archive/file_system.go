@@ -0,0 +1,61 @@
+package archive
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileSystem gives access to the files that are scanned and added to the
+// archive. Useful for mocking in test environments, or to build archives from
+// something else than the local disk.
+type FileSystem interface {
+	// Walk walks the file tree rooted at root, calling walkFn for each file or
+	// directory in the tree, including root, in lexical order. It follows the
+	// same rules of filepath.Walk.
+	Walk(root string, walkFn filepath.WalkFunc) error
+
+	// Open opens the named file for reading.
+	Open(name string) (fs.File, error)
+}
+
+// osFileSystem uses the files from the local disk.
+type osFileSystem struct{}
+
+func (osFileSystem) Walk(root string, walkFn filepath.WalkFunc) error {
+	return filepath.Walk(root, walkFn)
+}
+
+func (osFileSystem) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+// NewIOFileSystem adapts a fs.FS (e.g. fstest.MapFS) to be used when building
+// archives. The paths are slash-separated and relative to the root of fsys, as
+// defined by the fs package.
+func NewIOFileSystem(fsys fs.FS) FileSystem {
+	return ioFileSystem{fsys: fsys}
+}
+
+type ioFileSystem struct {
+	fsys fs.FS
+}
+
+func (i ioFileSystem) Walk(root string, walkFn filepath.WalkFunc) error {
+	return fs.WalkDir(i.fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return walkFn(path, nil, err)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return walkFn(path, nil, err)
+		}
+
+		return walkFn(path, info, nil)
+	})
+}
+
+func (i ioFileSystem) Open(name string) (fs.File, error) {
+	return i.fsys.Open(name)
+}
@@ -0,0 +1,175 @@
+package archive
+
+import "github.com/pkg/errors"
+
+// gfExp and gfLog are the exponential and logarithm tables of the Galois field
+// GF(2^8), generated with the primitive polynomial x^8 + x^4 + x^3 + x^2 + 1.
+// The exponential table is duplicated to avoid the modulo operation when
+// multiplying.
+var (
+	gfExp [510]byte
+	gfLog [256]byte
+)
+
+// gfMulTable stores the multiplication of all elements of the Galois field, so
+// the parity calculation is only table lookups and XOR operations.
+var gfMulTable [256][256]byte
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfExp[i+255] = byte(x)
+		gfLog[x] = byte(i)
+
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			gfMulTable[a][b] = gfExp[int(gfLog[a])+int(gfLog[b])]
+		}
+	}
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+func gfPow(a byte, n int) byte {
+	if n == 0 {
+		return 1
+	}
+
+	if a == 0 {
+		return 0
+	}
+
+	return gfExp[(int(gfLog[a])*n)%255]
+}
+
+// errSingularMatrix is returned when the matrix can't be inverted.
+var errSingularMatrix = errors.New("singular matrix")
+
+// gfMatrix is a matrix of Galois field elements.
+type gfMatrix [][]byte
+
+func newGFMatrix(rows, cols int) gfMatrix {
+	m := make(gfMatrix, rows)
+	for r := range m {
+		m[r] = make([]byte, cols)
+	}
+	return m
+}
+
+// vandermonde builds a matrix where each element is the row number raised to
+// the power of the column number. Any square subset of rows is invertible.
+func vandermonde(rows, cols int) gfMatrix {
+	m := newGFMatrix(rows, cols)
+	for r := range m {
+		for c := range m[r] {
+			m[r][c] = gfPow(byte(r), c)
+		}
+	}
+	return m
+}
+
+func (m gfMatrix) multiply(other gfMatrix) gfMatrix {
+	result := newGFMatrix(len(m), len(other[0]))
+	for r := range result {
+		for c := range result[r] {
+			var value byte
+			for i := range m[r] {
+				value ^= gfMulTable[m[r][i]][other[i][c]]
+			}
+			result[r][c] = value
+		}
+	}
+	return result
+}
+
+// invert uses the Gauss-Jordan elimination to find the inverse of a square
+// matrix.
+func (m gfMatrix) invert() (gfMatrix, error) {
+	size := len(m)
+
+	// work matrix is the original matrix with the identity on the right side
+	work := newGFMatrix(size, size*2)
+	for r := range m {
+		copy(work[r], m[r])
+		work[r][size+r] = 1
+	}
+
+	for c := 0; c < size; c++ {
+		if work[c][c] == 0 {
+			for r := c + 1; r < size; r++ {
+				if work[r][c] != 0 {
+					work[c], work[r] = work[r], work[c]
+					break
+				}
+			}
+		}
+
+		if work[c][c] == 0 {
+			return nil, errSingularMatrix
+		}
+
+		if pivot := work[c][c]; pivot != 1 {
+			for i := range work[c] {
+				work[c][i] = gfDiv(work[c][i], pivot)
+			}
+		}
+
+		for r := 0; r < size; r++ {
+			if r == c || work[r][c] == 0 {
+				continue
+			}
+
+			factor := work[r][c]
+			for i := range work[r] {
+				work[r][i] ^= gfMulTable[factor][work[c][i]]
+			}
+		}
+	}
+
+	inverse := newGFMatrix(size, size)
+	for r := range inverse {
+		copy(inverse[r], work[r][size:])
+	}
+	return inverse, nil
+}
+
+// encodingMatrix builds a systematic matrix, where the first rows are the
+// identity (data parts are kept untouched) and the remaining rows generate the
+// parity parts. Any combination of dataShards rows is invertible, so the data
+// can be rebuilt with any dataShards parts.
+func encodingMatrix(dataShards, totalShards int) (gfMatrix, error) {
+	v := vandermonde(totalShards, dataShards)
+
+	top, err := v[:dataShards].invert()
+	if err != nil {
+		return nil, err
+	}
+
+	return v.multiply(top), nil
+}
+
+// gfMulAdd multiplies the input by the coefficient, adding (XOR) the result to
+// the output.
+func gfMulAdd(coefficient byte, input, output []byte) {
+	if coefficient == 0 {
+		return
+	}
+
+	table := &gfMulTable[coefficient]
+	for i, value := range input {
+		output[i] ^= table[value]
+	}
+}
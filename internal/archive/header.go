@@ -0,0 +1,162 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// HeaderVersion is the version of the header written in the archives. It is
+// only increased when the header changes in a way that older versions of the
+// tool can't read it, as unknown attributes are ignored.
+const HeaderVersion = 1
+
+// headerLabel identifies the archives that start with a header. The header
+// attributes follow the label in a single JSON line.
+const headerLabel = "toglacier:"
+
+// headerMaxSize is the maximum number of bytes of the header line, so a file
+// that only looks like it has a header isn't read entirely.
+const headerMaxSize = 4096
+
+// formatCompression is the compression used by the registered formats. A new
+// compressed format must also be added here, so the header identifies it.
+var formatCompression = map[string]string{
+	FormatTARGzip: "gzip",
+	FormatZIP:     "deflate",
+}
+
+// Header identifies how the archive was built, so it can be restored
+// independently of the configuration used at the restore time. It is written
+// before the encrypted content, so it can be read without the secret.
+type Header struct {
+	// Version of the header, used to detect archives written by newer versions
+	// of the tool.
+	Version int `json:"version"`
+
+	// Format is the registered archive format (e.g. tar, zip) or FormatStream
+	// for the data backed up from a stream.
+	Format string `json:"format"`
+
+	// Compression of the archive content. Informative only, as it is defined by
+	// the format.
+	Compression string `json:"compression,omitempty"`
+
+	// Envelop is the registered envelop that encrypted the archive. When empty
+	// the archive isn't encrypted.
+	Envelop string `json:"envelop,omitempty"`
+}
+
+// NewHeader builds the header of the archives with the given format and
+// envelop, in the current header version.
+func NewHeader(format, envelop string) Header {
+	return Header{
+		Version:     HeaderVersion,
+		Format:      format,
+		Compression: formatCompression[format],
+		Envelop:     envelop,
+	}
+}
+
+// Encode returns the header line that is sent before the archive content. On
+// error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (h Header) Encode() ([]byte, error) {
+	content, err := json.Marshal(h)
+	if err != nil {
+		return nil, errors.WithStack(newError("", ErrorCodeWritingHeader, err))
+	}
+
+	return []byte(headerLabel + string(content) + "\n"), nil
+}
+
+// ReadHeader reads the header at the beginning of the file, returning its size
+// so the archive content can be read after it. The file isn't changed, and the
+// archive operations (decrypt, extract, index and repair) skip the header by
+// themselves. Archives built by older versions of the tool don't have a
+// header, returning a zero size. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func ReadHeader(filename string) (Header, int64, error) {
+	archive, err := os.Open(filename)
+	if err != nil {
+		return Header{}, 0, errors.WithStack(newError(filename, ErrorCodeOpeningFile, err))
+	}
+	defer archive.Close()
+
+	header, size, err := readHeader(archive, filename)
+	return header, size, errors.WithStack(err)
+}
+
+// readHeader reads the header from the beginning of the content, returning the
+// header and its size in bytes (zero when there's no header). The filename
+// identifies the content in the errors.
+func readHeader(r io.ReaderAt, filename string) (Header, int64, error) {
+	reader := bufio.NewReader(io.NewSectionReader(r, 0, headerMaxSize))
+	if label, err := reader.Peek(len(headerLabel)); err != nil || string(label) != headerLabel {
+		// without the label the archive was built before the header existed
+		return Header{}, 0, nil
+	}
+
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return Header{}, 0, errors.WithStack(newError(filename, ErrorCodeReadingHeader, err))
+	}
+
+	var header Header
+	decoder := json.NewDecoder(bytes.NewReader(bytes.TrimPrefix(line, []byte(headerLabel))))
+	if err = decoder.Decode(&header); err != nil {
+		return Header{}, 0, errors.WithStack(newError(filename, ErrorCodeReadingHeader, err))
+	}
+
+	if header.Version > HeaderVersion {
+		return Header{}, 0, errors.WithStack(newError(filename, ErrorCodeHeaderVersion,
+			errors.Errorf("header version %d is newer than the supported version %d", header.Version, HeaderVersion)))
+	}
+
+	return header, int64(len(line)), nil
+}
+
+// skipHeader moves the file after the header, returning the header size. The
+// file must be at the beginning.
+func skipHeader(f *os.File) (int64, error) {
+	_, size, err := readHeader(f, f.Name())
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	if _, err = f.Seek(size, io.SeekStart); err != nil {
+		return 0, errors.WithStack(newError(f.Name(), ErrorCodeRewindingFile, err))
+	}
+
+	return size, nil
+}
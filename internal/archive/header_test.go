@@ -0,0 +1,120 @@
+package archive_test
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+func TestReadHeader(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		header         *archive.Header
+		content        string
+		expectedHeader archive.Header
+		expectedSize   int64
+		expectedError  error
+	}{
+		{
+			description: "it should read the header without changing the file",
+			header: func() *archive.Header {
+				header := archive.NewHeader(archive.FormatTARGzip, archive.EnvelopOFB)
+				return &header
+			}(),
+			content: "encrypted:content",
+			expectedHeader: archive.Header{
+				Version:     archive.HeaderVersion,
+				Format:      archive.FormatTARGzip,
+				Compression: "gzip",
+				Envelop:     archive.EnvelopOFB,
+			},
+			expectedSize: int64(len(`toglacier:{"version":1,"format":"tar+gzip","compression":"gzip","envelop":"ofb"}` + "\n")),
+		},
+		{
+			description: "it should detect an archive without header",
+			content:     "encrypted:content",
+		},
+		{
+			description:    "it should ignore unknown attributes of the header",
+			content:        `toglacier:{"version":1,"format":"zip","checksum":"abc"}` + "\ncontent",
+			expectedHeader: archive.Header{Version: 1, Format: archive.FormatZIP},
+			expectedSize:   int64(len(`toglacier:{"version":1,"format":"zip","checksum":"abc"}` + "\n")),
+		},
+		{
+			description: "it should detect a header written by a newer version",
+			content:     `toglacier:{"version":2,"format":"zip"}` + "\ncontent",
+			expectedError: &archive.Error{
+				Code: archive.ErrorCodeHeaderVersion,
+				Err:  errors.New("header version 2 is newer than the supported version 1"),
+			},
+		},
+		{
+			description: "it should detect a header that isn't terminated",
+			content:     `toglacier:{"version":1,` + strings.Repeat(" ", 5000),
+			expectedError: &archive.Error{
+				Code: archive.ErrorCodeReadingHeader,
+				Err:  errors.New("EOF"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			file, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary file. details: %s", err)
+			}
+			defer os.Remove(file.Name())
+
+			content := scenario.content
+			if scenario.header != nil {
+				encoded, err := scenario.header.Encode()
+				if err != nil {
+					t.Fatalf("unexpected error encoding the header. details: %s", err)
+				}
+				content = string(encoded) + content
+			}
+
+			_, err = file.WriteString(content)
+			file.Close()
+
+			if err != nil {
+				t.Fatalf("error writing temporary file. details: %s", err)
+			}
+
+			header, size, err := archive.ReadHeader(file.Name())
+
+			// the filename is random, so it isn't compared
+			var archiveErr *archive.Error
+			if errors.As(err, &archiveErr) {
+				archiveErr.Filename = ""
+			}
+
+			if !archive.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedHeader, header) {
+				t.Errorf("headers don't match.\n%s", Diff(scenario.expectedHeader, header))
+			}
+
+			if scenario.expectedSize != size {
+				t.Errorf("header sizes don't match. expected “%d” and got “%d”", scenario.expectedSize, size)
+			}
+
+			written, err := ioutil.ReadFile(file.Name())
+			if err != nil {
+				t.Fatalf("error reading temporary file. details: %s", err)
+			}
+
+			if content != string(written) {
+				t.Errorf("file changed. expected “%s” and got “%s”", content, written)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// IndexEntry describes a file stored in the archive.
+type IndexEntry struct {
+	// Name is the original path of the file, the same used in the archive
+	// information.
+	Name string
+
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+
+	// offset is where the content of the file starts in an uncompressed
+	// tarball.
+	offset int64
+
+	// zipItem is the file in the zip archive.
+	zipItem *zip.File
+}
+
+// Index gives access to the files of an archive without extracting them, so
+// each file can be read on demand (e.g. by a mounted filesystem). Compressed
+// tarballs are uncompressed once to a temporary file, as gzip doesn't allow
+// reading from an arbitrary position.
+type Index struct {
+	logger log.Logger
+
+	file          *os.File
+	temporaryFile bool
+	zipReader     *zip.Reader
+
+	// base is where the archive starts in the file, after the format header
+	base int64
+
+	info    Info
+	entries map[string]IndexEntry
+
+	// cursor keeps the last compressed zip file being read, so a sequential
+	// read doesn't uncompress the file from the beginning on each call
+	cursorLock sync.Mutex
+	cursor     *zipCursor
+}
+
+// zipCursor is a compressed zip file read until a position.
+type zipCursor struct {
+	name     string
+	position int64
+	content  io.ReadCloser
+}
+
+// OpenIndex lists the files of the tarball (compressed or not) or zip archive.
+// The archive must be already decrypted. The index must be closed after use,
+// to release the archive and remove the temporary files. On error it will
+// return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func OpenIndex(ctx context.Context, logger log.Logger, filename string) (*Index, error) {
+	logger.Debugf("archive: indexing archive %s", filename)
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.WithStack(newError(filename, ErrorCodeOpeningFile, err))
+	}
+
+	index := &Index{
+		logger:  logger,
+		file:    f,
+		entries: make(map[string]IndexEntry),
+	}
+
+	if index.base, err = skipHeader(f); err != nil {
+		index.Close()
+		return nil, errors.WithStack(err)
+	}
+
+	magicNumber, err := bufio.NewReader(f).Peek(len(zipMagicNumber))
+	if err != nil && err != io.EOF {
+		index.Close()
+		return nil, errors.WithStack(newError(filename, ErrorCodeReadingFile, err))
+	}
+
+	switch {
+	case bytes.HasPrefix(magicNumber, zipMagicNumber):
+		err = index.indexZIP(ctx, filename)
+
+	case bytes.HasPrefix(magicNumber, gzipMagicNumber):
+		if err = index.uncompress(filename); err == nil {
+			err = index.indexTAR(ctx, filename)
+		}
+
+	default:
+		err = index.indexTAR(ctx, filename)
+	}
+
+	if err != nil {
+		index.Close()
+		return nil, errors.WithStack(err)
+	}
+
+	logger.Infof("archive: %d files indexed in archive %s", len(index.entries), filename)
+	return index, nil
+}
+
+// Info returns the archive information stored together with the files.
+func (i *Index) Info() Info {
+	return i.info
+}
+
+// Entries returns the files of the archive sorted by name.
+func (i *Index) Entries() []IndexEntry {
+	entries := make([]IndexEntry, 0, len(i.entries))
+	for _, entry := range i.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(j, k int) bool {
+		return entries[j].Name < entries[k].Name
+	})
+
+	return entries
+}
+
+// Entry returns the file of the archive with the given name.
+func (i *Index) Entry(name string) (IndexEntry, bool) {
+	entry, ok := i.entries[name]
+	return entry, ok
+}
+
+// ReadAt reads the content of the file from the given offset. It follows the
+// io.ReaderAt contract, returning io.EOF when the end of the file is reached.
+// On error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (i *Index) ReadAt(name string, p []byte, offset int64) (int, error) {
+	entry, ok := i.entries[name]
+	if !ok {
+		return 0, errors.WithStack(newError(name, ErrorCodeReadingFile, os.ErrNotExist))
+	}
+
+	if offset >= entry.Size {
+		return 0, io.EOF
+	}
+
+	if entry.zipItem != nil && entry.zipItem.Method != zip.Store {
+		return i.readCompressed(entry, p, offset)
+	}
+
+	dataOffset := entry.offset
+	if entry.zipItem != nil {
+		var err error
+		if dataOffset, err = entry.zipItem.DataOffset(); err != nil {
+			return 0, errors.WithStack(newError(name, ErrorCodeReadingZIP, err))
+		}
+		dataOffset += i.base
+	}
+
+	n, err := io.NewSectionReader(i.file, dataOffset, entry.Size).ReadAt(p, offset)
+	if err != nil && err != io.EOF {
+		return n, errors.WithStack(newError(name, ErrorCodeReadingFile, err))
+	}
+
+	return n, err
+}
+
+// Close releases the archive, removing the uncompressed tarball.
+func (i *Index) Close() error {
+	i.cursorLock.Lock()
+	if i.cursor != nil {
+		i.cursor.content.Close()
+		i.cursor = nil
+	}
+	i.cursorLock.Unlock()
+
+	if err := i.file.Close(); err != nil {
+		return errors.WithStack(newError(i.file.Name(), ErrorCodeReadingFile, err))
+	}
+
+	if i.temporaryFile {
+		if err := os.Remove(i.file.Name()); err != nil {
+			return errors.WithStack(newError(i.file.Name(), ErrorCodeWritingFile, err))
+		}
+	}
+
+	return nil
+}
+
+// uncompress replaces the compressed tarball by an uncompressed temporary copy.
+func (i *Index) uncompress(filename string) error {
+	if _, err := i.file.Seek(i.base, io.SeekStart); err != nil {
+		return errors.WithStack(newError(filename, ErrorCodeRewindingFile, err))
+	}
+
+	gzipReader, err := gzip.NewReader(i.file)
+	if err != nil {
+		return errors.WithStack(newError(filename, ErrorCodeReadingTAR, err))
+	}
+	defer gzipReader.Close()
+
+	tmpFile, err := ioutil.TempFile("", "toglacier-")
+	if err != nil {
+		return errors.WithStack(newError(filename, ErrorCodeTmpFileCreation, err))
+	}
+
+	if _, err = io.Copy(tmpFile, gzipReader); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return errors.WithStack(newError(filename, ErrorCodeReadingTAR, err))
+	}
+
+	i.file.Close()
+	i.file = tmpFile
+	i.temporaryFile = true
+	i.base = 0
+	return nil
+}
+
+func (i *Index) indexTAR(ctx context.Context, filename string) error {
+	if _, err := i.file.Seek(i.base, io.SeekStart); err != nil {
+		return errors.WithStack(newError(filename, ErrorCodeRewindingFile, err))
+	}
+
+	// the tar reader doesn't read ahead, so after each header the position of
+	// the file is where the content starts
+	position := &countingReader{reader: i.file}
+	tarReader := tar.NewReader(position)
+
+	for {
+		if ctx.Err() != nil {
+			return errors.WithStack(newError(filename, ErrorCodeCancelled, ctx.Err()))
+		}
+
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.WithStack(newError(filename, ErrorCodeReadingTAR, err))
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := normalizeHeaderName(header.Name)
+		if name == TARInfoFilename {
+			if err := json.NewDecoder(tarReader).Decode(&i.info); err != nil {
+				return errors.WithStack(newError(filename, ErrorCodeDecodingInfo, err))
+			}
+			continue
+		}
+
+		i.entries[name] = IndexEntry{
+			Name:    name,
+			Size:    header.Size,
+			Mode:    header.FileInfo().Mode(),
+			ModTime: header.ModTime,
+			offset:  i.base + position.read,
+		}
+	}
+
+	return nil
+}
+
+func (i *Index) indexZIP(ctx context.Context, filename string) error {
+	stat, err := i.file.Stat()
+	if err != nil {
+		return errors.WithStack(newError(filename, ErrorCodeReadingZIP, err))
+	}
+
+	zipSize := stat.Size() - i.base
+	if i.zipReader, err = zip.NewReader(io.NewSectionReader(i.file, i.base, zipSize), zipSize); err != nil {
+		return errors.WithStack(newError(filename, ErrorCodeReadingZIP, err))
+	}
+
+	for _, zipItem := range i.zipReader.File {
+		if ctx.Err() != nil {
+			return errors.WithStack(newError(filename, ErrorCodeCancelled, ctx.Err()))
+		}
+
+		if !zipItem.Mode().IsRegular() {
+			continue
+		}
+
+		name := normalizeHeaderName(filepath.FromSlash(zipItem.Name))
+		if name == TARInfoFilename {
+			if i.info, err = (ZIPBuilder{}).decodeInfo(zipItem); err != nil {
+				return errors.WithStack(newError(filename, ErrorCodeDecodingInfo, err))
+			}
+			continue
+		}
+
+		i.entries[name] = IndexEntry{
+			Name:    name,
+			Size:    int64(zipItem.UncompressedSize64),
+			Mode:    zipItem.Mode(),
+			ModTime: zipItem.Modified,
+			zipItem: zipItem,
+		}
+	}
+
+	return nil
+}
+
+// readCompressed reads a compressed zip file, continuing from the last read
+// when possible.
+func (i *Index) readCompressed(entry IndexEntry, p []byte, offset int64) (int, error) {
+	i.cursorLock.Lock()
+	defer i.cursorLock.Unlock()
+
+	if i.cursor == nil || i.cursor.name != entry.Name || i.cursor.position > offset {
+		if i.cursor != nil {
+			i.cursor.content.Close()
+			i.cursor = nil
+		}
+
+		content, err := entry.zipItem.Open()
+		if err != nil {
+			return 0, errors.WithStack(newError(entry.Name, ErrorCodeReadingZIP, err))
+		}
+
+		i.cursor = &zipCursor{name: entry.Name, content: content}
+	}
+
+	if skip := offset - i.cursor.position; skip > 0 {
+		skipped, err := io.CopyN(ioutil.Discard, i.cursor.content, skip)
+		i.cursor.position += skipped
+		if err != nil {
+			return 0, errors.WithStack(newError(entry.Name, ErrorCodeReadingZIP, err))
+		}
+	}
+
+	n, err := io.ReadFull(i.cursor.content, p)
+	i.cursor.position += int64(n)
+
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return n, io.EOF
+	} else if err != nil {
+		return n, errors.WithStack(newError(entry.Name, ErrorCodeReadingZIP, err))
+	}
+
+	return n, nil
+}
+
+// countingReader keeps the number of bytes read.
+type countingReader struct {
+	reader io.Reader
+	read   int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.read += int64(n)
+	return n, err
+}
@@ -0,0 +1,146 @@
+package archive_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+func TestOpenIndex(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarning:  func(args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	// big enough to be read in many parts and to be compressed
+	bigContent := strings.Repeat("toglacier index ", 10000)
+
+	scenarios := []struct {
+		description   string
+		archive       archive.Archive
+		files         map[string]string
+		readSize      int
+		expectedError error
+	}{
+		{
+			description: "it should index a tarball",
+			archive:     archive.NewTARBuilder(logger),
+			files: map[string]string{
+				"file1.txt":     "file1 content",
+				"dir/file2.txt": bigContent,
+			},
+			readSize: 1000,
+		},
+		{
+			description: "it should index a compressed tarball",
+			archive:     archive.NewTARGzipBuilder(logger),
+			files: map[string]string{
+				"file1.txt":     "file1 content",
+				"dir/file2.txt": bigContent,
+			},
+			readSize: 1000,
+		},
+		{
+			description: "it should index a zip archive",
+			archive:     archive.NewZIPBuilder(logger),
+			files: map[string]string{
+				"file1.txt":     "file1 content",
+				"dir/file2.txt": bigContent,
+			},
+			readSize: 1000,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			for name, content := range scenario.files {
+				name = filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+					t.Fatalf("error creating directory. details: %s", err)
+				}
+
+				if err := ioutil.WriteFile(name, []byte(content), 0600); err != nil {
+					t.Fatalf("error writing file. details: %s", err)
+				}
+			}
+
+			filename, archiveInfo, err := scenario.archive.Build(context.Background(), nil, nil, dir)
+			if err != nil {
+				t.Fatalf("error building archive. details: %s", err)
+			}
+			defer os.Remove(filename)
+
+			index, err := archive.OpenIndex(context.Background(), logger, filename)
+			if !archive.ErrorEqual(scenario.expectedError, err) {
+				t.Fatalf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if err != nil {
+				return
+			}
+			defer index.Close()
+
+			if !reflect.DeepEqual(archiveInfo, index.Info()) {
+				t.Errorf("archive information don't match.\n%s", Diff(archiveInfo, index.Info()))
+			}
+
+			var names []string
+			for _, entry := range index.Entries() {
+				names = append(names, entry.Name)
+			}
+
+			expectedNames := []string{filepath.Join(dir, "dir/file2.txt"), filepath.Join(dir, "file1.txt")}
+			if !reflect.DeepEqual(expectedNames, names) {
+				t.Errorf("names don't match.\n%s", Diff(expectedNames, names))
+			}
+
+			for name, expectedContent := range scenario.files {
+				name = filepath.Join(dir, name)
+
+				var content []byte
+				buffer := make([]byte, scenario.readSize)
+				for {
+					n, err := index.ReadAt(name, buffer, int64(len(content)))
+					content = append(content, buffer[:n]...)
+
+					if err == io.EOF {
+						break
+					} else if err != nil {
+						t.Fatalf("error reading file “%s”. details: %s", name, err)
+					}
+				}
+
+				if expectedContent != string(content) {
+					t.Errorf("content of file “%s” don't match. expected %d bytes and got %d bytes", name, len(expectedContent), len(content))
+				}
+			}
+
+			// reading again from the beginning of a file
+			buffer := make([]byte, 5)
+			if n, err := index.ReadAt(filepath.Join(dir, "dir/file2.txt"), buffer, 0); err != nil || string(buffer[:n]) != "togla" {
+				t.Errorf("unexpected content “%s” or error “%v” reading the file again", buffer[:n], err)
+			}
+
+			if _, err := index.ReadAt(filepath.Join(dir, "unknown.txt"), buffer, 0); err == nil {
+				t.Error("expected an error reading an unknown file")
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+// +build !windows
+
+package archive
+
+import (
+	"os"
+	"syscall"
+)
+
+// inode returns the device and the inode number of the file, when the file
+// information comes from the local disk.
+func inode(info os.FileInfo) (dev, ino uint64, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return uint64(stat.Dev), uint64(stat.Ino), true
+}
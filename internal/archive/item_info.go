@@ -0,0 +1,74 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// generateItemInfo compares the current file with the last archive information
+// to detect if the file was created or modified, and therefore should be added
+// to the archive. When the changes are informed, the checksum of the files
+// that weren't modified since the last archive isn't calculated.
+func generateItemInfo(logger log.Logger, fileSystem FileSystem, changes Changes, path string, lastArchiveInfo Info) (itemInfo ItemInfo, add bool, err error) {
+	if lastItemInfo, ok := lastArchiveInfo[path]; ok && lastItemInfo.Status != ItemInfoStatusDeleted &&
+		changes != nil && !changes.Modified(path) {
+
+		lastItemInfo.Status = ItemInfoStatusUnmodified
+		logger.Debugf("archive: path “%s” not changed since the last archive", path)
+		return lastItemInfo, false, nil
+	}
+
+	encodedChecksum, err := fileChecksum(logger, fileSystem, path)
+	if err != nil {
+		return itemInfo, true, errors.WithStack(err)
+	}
+
+	var ok bool
+	itemInfo, ok = lastArchiveInfo[path]
+
+	if !ok {
+		add = true
+		itemInfo.Status = ItemInfoStatusNew
+		itemInfo.Checksum = encodedChecksum
+		logger.Debugf("archive: path “%s” is new since the last archive", path)
+
+	} else if encodedChecksum == itemInfo.Checksum {
+		add = false // don't need to add an unmodified file to the archive
+		itemInfo.Status = ItemInfoStatusUnmodified
+		logger.Debugf("archive: path “%s” unmodified since the last archive", path)
+
+	} else {
+		add = true
+		itemInfo.ID = ""
+		itemInfo.Status = ItemInfoStatusModified
+		itemInfo.Fuzzy = false
+		itemInfo.Checksum = encodedChecksum
+		logger.Debugf("archive: path “%s” was modified since the last archive", path)
+	}
+
+	return
+}
+
+// fileChecksum returns the file SHA256 hash encoded in base64.
+func fileChecksum(logger log.Logger, fileSystem FileSystem, filename string) (string, error) {
+	file, err := fileSystem.Open(filename)
+	if err != nil {
+		return "", errors.WithStack(newPathError(filename, PathErrorCodeOpeningFile, err))
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+
+	written, err := io.Copy(hash, file)
+	if err != nil {
+		return "", errors.WithStack(newPathError(filename, PathErrorCodeSHA256, err))
+	}
+
+	encodedChecksum := base64.StdEncoding.EncodeToString(hash.Sum(nil))
+	logger.Debugf("archive: path “%s” hash calculated over %d bytes: %s", filename, written, encodedChecksum)
+	return encodedChecksum, nil
+}
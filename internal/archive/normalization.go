@@ -0,0 +1,41 @@
+package archive
+
+import "golang.org/x/text/unicode/norm"
+
+const (
+	// NormalizationNone keeps the file names as they were stored in the
+	// archive.
+	NormalizationNone Normalization = "none"
+
+	// NormalizationNFC composes the accented characters of the file names (e.g.
+	// “é” as a single character), the form used by Linux and Windows.
+	NormalizationNFC Normalization = "nfc"
+
+	// NormalizationNFD decomposes the accented characters of the file names
+	// (e.g. “é” as “e” followed by the accent), the form used by macOS.
+	NormalizationNFD Normalization = "nfd"
+)
+
+// Normalization defines the Unicode normalization form of the names of the
+// extracted files. Archives built on macOS store decomposed names, that look
+// like duplicates of the existing files when extracted on Linux without
+// normalization. When empty the names are kept.
+type Normalization string
+
+// apply converts the name to the normalization form.
+func (n Normalization) apply(name string) string {
+	switch n {
+	case NormalizationNFC:
+		return norm.NFC.String(name)
+	case NormalizationNFD:
+		return norm.NFD.String(name)
+	}
+
+	return name
+}
+
+// samePath checks if the paths are equivalent, independently of the Unicode
+// normalization form used to store them.
+func samePath(path1, path2 string) bool {
+	return path1 == path2 || norm.NFC.String(path1) == norm.NFC.String(path2)
+}
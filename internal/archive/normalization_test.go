@@ -0,0 +1,218 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+func TestNormalization(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	// names as stored by macOS, with decomposed accents
+	decomposed := "data/cafe\u0301.txt"
+	composed := "data/caf\u00e9.txt"
+	longName := "data/" + strings.Repeat("d", 120) + "/" + strings.Repeat("f", 200) + ".txt"
+	special := "data/file with spaces & $pecial #chars (1)!.txt"
+	unicode := "data/日本語 ✓.txt"
+
+	fileSystem := archive.NewIOFileSystem(fstest.MapFS{
+		decomposed: &fstest.MapFile{Data: []byte("decomposed"), Mode: 0600},
+		longName:   &fstest.MapFile{Data: []byte("long name"), Mode: 0600},
+		special:    &fstest.MapFile{Data: []byte("special"), Mode: 0600},
+		unicode:    &fstest.MapFile{Data: []byte("unicode"), Mode: 0600},
+	})
+
+	clock := fakeClock{
+		mockNow: func() time.Time {
+			return time.Date(2017, 9, 1, 10, 30, 15, 0, time.UTC)
+		},
+	}
+
+	builders := map[string]func(normalization archive.Normalization) archive.Archive{
+		"tar": func(normalization archive.Normalization) archive.Archive {
+			builder := archive.NewTARBuilder(logger)
+			builder.FileSystem = fileSystem
+			builder.Clock = clock
+			builder.Normalization = normalization
+			return builder
+		},
+		"zip": func(normalization archive.Normalization) archive.Archive {
+			builder := archive.NewZIPBuilder(logger)
+			builder.FileSystem = fileSystem
+			builder.Clock = clock
+			builder.Normalization = normalization
+			return builder
+		},
+	}
+
+	scenarios := []struct {
+		description   string
+		normalization archive.Normalization
+		filter        []string
+		expectedFiles []string
+	}{
+		{
+			description:   "it should extract composed names",
+			normalization: archive.NormalizationNFC,
+			expectedFiles: []string{composed, longName, special, unicode},
+		},
+		{
+			description:   "it should extract decomposed names",
+			normalization: archive.NormalizationNFD,
+			expectedFiles: []string{decomposed, longName, special, unicode},
+		},
+		{
+			description:   "it should keep the names without normalization",
+			expectedFiles: []string{decomposed, longName, special, unicode},
+		},
+		{
+			description:   "it should extract a file filtered in a different normalization form",
+			normalization: archive.NormalizationNFC,
+			filter:        []string{"/" + composed},
+			expectedFiles: []string{composed},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		for name, builder := range builders {
+			t.Run(fmt.Sprintf("%s (%s)", scenario.description, name), func(t *testing.T) {
+				filename, _, err := builder(scenario.normalization).Build(context.Background(), nil, nil, "data")
+				if filename != "" {
+					defer os.Remove(filename)
+				}
+
+				if err != nil {
+					t.Fatalf("unexpected error building the archive. details: %s", err)
+				}
+
+				dir, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details: %s", err)
+				}
+				defer os.RemoveAll(dir)
+
+				// the files are extracted in the current directory
+				wd, err := os.Getwd()
+				if err != nil {
+					t.Fatalf("error retrieving the current directory. details: %s", err)
+				}
+				defer os.Chdir(wd)
+
+				if err = os.Chdir(dir); err != nil {
+					t.Fatalf("error changing the current directory. details: %s", err)
+				}
+
+				if _, err = builder(scenario.normalization).Extract(context.Background(), filename, scenario.filter); err != nil {
+					t.Fatalf("unexpected error extracting the archive. details: %s", err)
+				}
+
+				baseDir := "backup-20170901103015"
+
+				var files []string
+				err = filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
+					if err != nil || info.IsDir() {
+						return err
+					}
+
+					path, err = filepath.Rel(baseDir, path)
+					files = append(files, filepath.ToSlash(path))
+					return err
+				})
+
+				if err != nil {
+					t.Fatalf("error listing the extracted files. details: %s", err)
+				}
+
+				sort.Strings(files)
+				sort.Strings(scenario.expectedFiles)
+
+				if !reflect.DeepEqual(scenario.expectedFiles, files) {
+					t.Errorf("extracted files don't match.\n%s", Diff(scenario.expectedFiles, files))
+				}
+			})
+		}
+	}
+}
+
+func TestNormalization_rootDirectory(t *testing.T) {
+	tarFile, err := ioutil.TempFile("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary file. details: %s", err)
+	}
+	defer os.Remove(tarFile.Name())
+
+	// the root directory isn't a name of the backup paths, so it is kept even
+	// when it isn't in the normalization form
+	rootDir := "backup-cafe\u0301"
+
+	tarArchive := tar.NewWriter(tarFile)
+	header := &tar.Header{
+		Name:     rootDir + "/data/cafe\u0301.txt",
+		Mode:     0600,
+		ModTime:  time.Now(),
+		Size:     int64(len("decomposed")),
+		Typeflag: tar.TypeReg,
+	}
+
+	if err = tarArchive.WriteHeader(header); err != nil {
+		t.Fatalf("error writing tar header. details: %s", err)
+	}
+
+	if _, err = tarArchive.Write([]byte("decomposed")); err != nil {
+		t.Fatalf("error writing tar content. details: %s", err)
+	}
+	tarArchive.Close()
+	tarFile.Close()
+
+	dir, err := ioutil.TempDir("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary directory. details: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the files are extracted in the current directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error retrieving the current directory. details: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	if err = os.Chdir(dir); err != nil {
+		t.Fatalf("error changing the current directory. details: %s", err)
+	}
+
+	builder := archive.NewTARBuilder(mockLogger{
+		mockDebug:  func(args ...interface{}) {},
+		mockDebugf: func(format string, args ...interface{}) {},
+		mockInfo:   func(args ...interface{}) {},
+		mockInfof:  func(format string, args ...interface{}) {},
+	})
+	builder.Normalization = archive.NormalizationNFC
+
+	if _, err = builder.Extract(context.Background(), tarFile.Name(), nil); err != nil {
+		t.Fatalf("unexpected error extracting the archive. details: %s", err)
+	}
+
+	expected := filepath.Join(rootDir, "data", "caf\u00e9.txt")
+	if _, err = os.Stat(expected); err != nil {
+		t.Errorf("file not extracted to “%s”. details: %s", expected, err)
+	}
+}
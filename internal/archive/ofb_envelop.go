@@ -6,6 +6,7 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"hash"
 	"io"
 	"io/ioutil"
 	"os"
@@ -127,9 +128,9 @@ func (o OFBEnvelop) Encrypt(filename, secret string) (string, error) {
 }
 
 // Decrypt do what we expect, decrypting the content with a shared secret. It
-// authenticates the data using HMAC-SHA256. It will return the decrypted
-// filename or an Error type encapsulated in a traceable error. To retrieve
-// the desired error you can do:
+// authenticates the data using HMAC-SHA256, skipping the format header of the
+// archive. It will return the decrypted filename or an Error type encapsulated
+// in a traceable error. To retrieve the desired error you can do:
 //
 //     type causer interface {
 //       Cause() error
@@ -152,22 +153,119 @@ func (o OFBEnvelop) Decrypt(encryptedFilename, secret string) (string, error) {
 	}
 	defer encryptedArchive.Close()
 
+	if _, err = skipHeader(encryptedArchive); err != nil {
+		return "", errors.WithStack(err)
+	}
+
 	archive, err := ioutil.TempFile("", "toglacier-")
 	if err != nil {
 		return "", errors.WithStack(newError(encryptedFilename, ErrorCodeTmpFileCreation, err))
 	}
 	defer archive.Close()
 
+	reader, authHash, err := o.openEncrypted(encryptedArchive, secret)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if reader == nil {
+		// the file isn't encrypted, so let's return it as it is
+		return encryptedFilename, nil
+	}
+
+	written, err := io.Copy(archive, reader)
+	if err != nil {
+		return "", errors.WithStack(newError(encryptedFilename, ErrorCodeDecryptingFile, err))
+	}
+
+	o.logger.Debugf("archive: decrypted %d bytes", written)
+
+	hash, err := hmacSHA256(archive, secret)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if !hmac.Equal(authHash, hash) {
+		return "", errors.WithStack(newError("", ErrorCodeAuthFailed, nil))
+	}
+
+	o.logger.Infof("archive: file “%s” decrypted", archive.Name())
+	return archive.Name(), nil
+}
+
+// DecryptReader decrypts the content with a shared secret while it is read,
+// without storing the decrypted content in a temporary file. As the data is
+// authenticated using HMAC-SHA256 only after reading all the content, the
+// authentication failure is returned by the last read, and the content read
+// before must be discarded. When the file isn't encrypted it is read as it is,
+// after the format header.
+// On error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (o OFBEnvelop) DecryptReader(encryptedFilename, secret string) (io.ReadCloser, error) {
+	o.logger.Debugf("archive: decrypting file “%s” while reading", encryptedFilename)
+
+	encryptedArchive, err := os.Open(encryptedFilename)
+	if err != nil {
+		return nil, errors.WithStack(newError(encryptedFilename, ErrorCodeOpeningFile, err))
+	}
+
+	headerSize, err := skipHeader(encryptedArchive)
+	if err != nil {
+		encryptedArchive.Close()
+		return nil, errors.WithStack(err)
+	}
+
+	reader, authHash, err := o.openEncrypted(encryptedArchive, secret)
+	if err != nil {
+		encryptedArchive.Close()
+		return nil, errors.WithStack(err)
+	}
+
+	if reader == nil {
+		if _, err = encryptedArchive.Seek(headerSize, io.SeekStart); err != nil {
+			encryptedArchive.Close()
+			return nil, errors.WithStack(newError(encryptedFilename, ErrorCodeRewindingFile, err))
+		}
+
+		return encryptedArchive, nil
+	}
+
+	hash := hmac.New(sha256.New, []byte(secret))
+	return &authenticatedReader{
+		file:     encryptedArchive,
+		reader:   io.TeeReader(reader, hash),
+		hash:     hash,
+		authHash: authHash,
+	}, nil
+}
+
+// openEncrypted reads the header of the encrypted file, returning the reader of
+// the decrypted content and the expected authentication hash. When the file
+// isn't encrypted the reader is nil.
+func (o OFBEnvelop) openEncrypted(encryptedArchive *os.File, secret string) (io.Reader, []byte, error) {
+	encryptedFilename := encryptedArchive.Name()
 	encryptedLabelBuffer := make([]byte, len(encryptedLabel))
 	n, err := encryptedArchive.Read(encryptedLabelBuffer)
 
 	if err == io.EOF || string(encryptedLabelBuffer) != encryptedLabel {
-		// if we couldn't read the encrypted label, maybe the file isn't encrypted,
-		// so let's return it as it is
-		return encryptedFilename, nil
+		// if we couldn't read the encrypted label, maybe the file isn't encrypted
+		return nil, nil, nil
 
 	} else if err != nil {
-		return "", errors.WithStack(newError(encryptedFilename, ErrorCodeReadingLabel, err))
+		return nil, nil, errors.WithStack(newError(encryptedFilename, ErrorCodeReadingLabel, err))
 	}
 
 	o.logger.Debugf("archive: read %d bytes from encrypted file (encrypted label)", n)
@@ -176,7 +274,7 @@ func (o OFBEnvelop) Decrypt(encryptedFilename, secret string) (string, error) {
 
 	n, err = encryptedArchive.Read(authHash)
 	if err != nil {
-		return "", errors.WithStack(newError(encryptedFilename, ErrorCodeReadingAuth, err))
+		return nil, nil, errors.WithStack(newError(encryptedFilename, ErrorCodeReadingAuth, err))
 	}
 
 	o.logger.Debugf("archive: read %d bytes from encrypted file (auth)", n)
@@ -185,39 +283,42 @@ func (o OFBEnvelop) Decrypt(encryptedFilename, secret string) (string, error) {
 
 	n, err = encryptedArchive.Read(iv)
 	if err != nil {
-		return "", errors.WithStack(newError(encryptedFilename, ErrorCodeReadingIV, err))
+		return nil, nil, errors.WithStack(newError(encryptedFilename, ErrorCodeReadingIV, err))
 	}
 
 	o.logger.Debugf("archive: read %d bytes from encrypted file (iv)", n)
 
 	block, err := aes.NewCipher([]byte(secret))
 	if err != nil {
-		return "", errors.WithStack(newError(encryptedFilename, ErrorCodeInitCipher, err))
+		return nil, nil, errors.WithStack(newError(encryptedFilename, ErrorCodeInitCipher, err))
 	}
 
-	reader := cipher.StreamReader{
+	return cipher.StreamReader{
 		S: cipher.NewOFB(block, iv),
 		R: encryptedArchive,
-	}
-
-	written, err := io.Copy(archive, reader)
-	if err != nil {
-		return "", errors.WithStack(newError(encryptedFilename, ErrorCodeDecryptingFile, err))
-	}
+	}, authHash, nil
+}
 
-	o.logger.Debugf("archive: decrypted %d bytes", written)
+// authenticatedReader calculates the HMAC-SHA256 of the decrypted content while
+// it is read, comparing it with the expected hash at the end of the content.
+type authenticatedReader struct {
+	file     *os.File
+	reader   io.Reader
+	hash     hash.Hash
+	authHash []byte
+}
 
-	hash, err := hmacSHA256(archive, secret)
-	if err != nil {
-		return "", errors.WithStack(err)
+func (a *authenticatedReader) Read(p []byte) (int, error) {
+	n, err := a.reader.Read(p)
+	if err == io.EOF && !hmac.Equal(a.authHash, a.hash.Sum(nil)) {
+		return n, errors.WithStack(newError("", ErrorCodeAuthFailed, nil))
 	}
 
-	if !hmac.Equal(authHash, hash) {
-		return "", errors.WithStack(newError("", ErrorCodeAuthFailed, nil))
-	}
+	return n, err
+}
 
-	o.logger.Infof("archive: file “%s” decrypted", archive.Name())
-	return archive.Name(), nil
+func (a *authenticatedReader) Close() error {
+	return a.file.Close()
 }
 
 func hmacSHA256(f *os.File, secret string) ([]byte, error) {
@@ -408,6 +408,142 @@ func TestOFBEnvelop_EncryptDecrypt(t *testing.T) {
 	}
 }
 
+func TestOFBEnvelop_DecryptReader(t *testing.T) {
+	envelop := archive.NewOFBEnvelop(mockLogger{
+		mockDebug:  func(args ...interface{}) {},
+		mockDebugf: func(format string, args ...interface{}) {},
+		mockInfo:   func(args ...interface{}) {},
+		mockInfof:  func(format string, args ...interface{}) {},
+	})
+
+	scenarios := []struct {
+		description   string
+		encrypt       bool
+		tamper        bool
+		secret        string
+		expectedFile  string
+		expectedError error
+	}{
+		{
+			description:  "it should decrypt the archive while reading",
+			encrypt:      true,
+			secret:       "12345678901234567890123456789012",
+			expectedFile: "Important information for the test backup",
+		},
+		{
+			description:  "it should read an unencrypted archive as it is",
+			secret:       "12345678901234567890123456789012",
+			expectedFile: "Important information for the test backup",
+		},
+		{
+			description: "it should detect when the authentication fails at the end of the content",
+			encrypt:     true,
+			tamper:      true,
+			secret:      "12345678901234567890123456789012",
+			expectedError: &archive.Error{
+				Code: archive.ErrorCodeAuthFailed,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "toglacier-test-")
+			if err != nil {
+				t.Fatalf("error creating file. details: %s", err)
+			}
+			f.WriteString("Important information for the test backup")
+			f.Close()
+			defer os.Remove(f.Name())
+
+			filename := f.Name()
+			if scenario.encrypt {
+				if filename, err = envelop.Encrypt(filename, scenario.secret); err != nil {
+					t.Fatalf("error encrypting file. details: %s", err)
+				}
+				defer os.Remove(filename)
+			}
+
+			if scenario.tamper {
+				content, err := ioutil.ReadFile(filename)
+				if err != nil {
+					t.Fatalf("error reading encrypted file. details: %s", err)
+				}
+
+				content[len(content)-1]++
+				if err = ioutil.WriteFile(filename, content, 0600); err != nil {
+					t.Fatalf("error writing encrypted file. details: %s", err)
+				}
+			}
+
+			reader, err := envelop.DecryptReader(filename, scenario.secret)
+			if err != nil {
+				t.Fatalf("error opening the encrypted file. details: %s", err)
+			}
+			defer reader.Close()
+
+			content, err := ioutil.ReadAll(reader)
+			if !archive.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if scenario.expectedError == nil && scenario.expectedFile != string(content) {
+				t.Errorf("files don't match. expected “%s” and got “%s”", scenario.expectedFile, string(content))
+			}
+		})
+	}
+}
+
+func BenchmarkOFBEnvelop_Encrypt(b *testing.B) {
+	ofbEnvelop := archive.NewOFBEnvelop(newBenchmarkLogger())
+
+	for _, dataset := range benchmarkDatasets {
+		filename := dataset.archive(b)
+		defer os.Remove(filename)
+
+		b.Run(dataset.description, func(b *testing.B) {
+			b.SetBytes(dataset.totalSize())
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				encryptedFilename, err := ofbEnvelop.Encrypt(filename, "1234567890123456")
+				if err != nil {
+					b.Fatalf("error encrypting archive. details %s", err)
+				}
+				os.Remove(encryptedFilename)
+			}
+		})
+	}
+}
+
+func BenchmarkOFBEnvelop_Decrypt(b *testing.B) {
+	ofbEnvelop := archive.NewOFBEnvelop(newBenchmarkLogger())
+
+	for _, dataset := range benchmarkDatasets {
+		filename := dataset.archive(b)
+		defer os.Remove(filename)
+
+		encryptedFilename, err := ofbEnvelop.Encrypt(filename, "1234567890123456")
+		if err != nil {
+			b.Fatalf("error encrypting archive. details %s", err)
+		}
+		defer os.Remove(encryptedFilename)
+
+		b.Run(dataset.description, func(b *testing.B) {
+			b.SetBytes(dataset.totalSize())
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				decryptedFilename, err := ofbEnvelop.Decrypt(encryptedFilename, "1234567890123456")
+				if err != nil {
+					b.Fatalf("error decrypting archive. details %s", err)
+				}
+				os.Remove(decryptedFilename)
+			}
+		})
+	}
+}
+
 type mockReader struct {
 	mockRead func(p []byte) (n int, err error)
 }
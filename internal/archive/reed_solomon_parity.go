@@ -0,0 +1,479 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// parityDataShards is the number of parts that the archive is split to
+// calculate the parity data. Each redundancy percentage adds one parity part.
+const parityDataShards = 100
+
+// parityChunkSize is the amount of bytes of each part processed at once,
+// limiting the memory usage for big archives.
+const parityChunkSize = 64 * 1024
+
+// parityMagicNumber identifies the beginning of a parity file.
+var parityMagicNumber = []byte("TGPARITY")
+
+// parityHeader describes how the parity data was generated. It is stored in
+// the end of the parity file, after the parity parts, followed by its size.
+type parityHeader struct {
+	Size         int64    `json:"size"`
+	DataShards   int      `json:"dataShards"`
+	ParityShards int      `json:"parityShards"`
+	ShardSize    int64    `json:"shardSize"`
+	Checksums    []string `json:"checksums"`
+}
+
+// ReedSolomonParity generates parity data using the Reed-Solomon erasure code.
+// The archive is split in 100 parts and each redundancy percentage generates
+// one parity part, that can rebuild any corrupted part of the archive.
+type ReedSolomonParity struct {
+	logger log.Logger
+}
+
+// NewReedSolomonParity returns a ReedSolomonParity with all necessary
+// initializations.
+func NewReedSolomonParity(logger log.Logger) *ReedSolomonParity {
+	return &ReedSolomonParity{
+		logger: logger,
+	}
+}
+
+// Generate creates a parity file for the archive. The redundancy is the
+// percentage (1 - 100) of the archive size that will be generated as parity
+// data, and also the percentage of the archive that can be corrupted and still
+// be repaired. It will return the parity filename or an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (r ReedSolomonParity) Generate(filename string, redundancy int) (string, error) {
+	r.logger.Debugf("archive: generating parity data with %d%% of redundancy for file “%s”", redundancy, filename)
+
+	if redundancy < 1 || redundancy > 100 {
+		return "", errors.WithStack(newError(filename, ErrorCodeRedundancy, errors.Errorf("redundancy %d%% out of range", redundancy)))
+	}
+
+	archive, err := os.Open(filename)
+	if err != nil {
+		return "", errors.WithStack(newError(filename, ErrorCodeOpeningFile, err))
+	}
+	defer archive.Close()
+
+	archiveInfo, err := archive.Stat()
+	if err != nil {
+		return "", errors.WithStack(newError(filename, ErrorCodeReadingFile, err))
+	}
+
+	header := parityHeader{
+		Size:         archiveInfo.Size(),
+		DataShards:   parityDataShards,
+		ParityShards: redundancy,
+		ShardSize:    (archiveInfo.Size() + parityDataShards - 1) / parityDataShards,
+	}
+
+	matrix, err := encodingMatrix(header.DataShards, header.DataShards+header.ParityShards)
+	if err != nil {
+		return "", errors.WithStack(newError(filename, ErrorCodeGeneratingParity, err))
+	}
+
+	parityFile, err := ioutil.TempFile("", "toglacier-")
+	if err != nil {
+		return "", errors.WithStack(newError("", ErrorCodeTmpFileCreation, err))
+	}
+	defer parityFile.Close()
+
+	if _, err = parityFile.Write(parityMagicNumber); err != nil {
+		return "", errors.WithStack(newError(parityFile.Name(), ErrorCodeWritingFile, err))
+	}
+
+	shards := r.newShards(header)
+	hashes := r.newHashes(header)
+
+	for offset := int64(0); offset < header.ShardSize; offset += parityChunkSize {
+		chunkSize := header.ShardSize - offset
+		if chunkSize > parityChunkSize {
+			chunkSize = parityChunkSize
+		}
+
+		for i := 0; i < header.DataShards; i++ {
+			shard := shards[i][:chunkSize]
+			if err = readShard(archive, int64(i)*header.ShardSize+offset, header.Size, shard); err != nil {
+				return "", errors.WithStack(newError(filename, ErrorCodeReadingFile, err))
+			}
+			hashes[i].Write(shard)
+		}
+
+		for i := header.DataShards; i < len(shards); i++ {
+			shard := shards[i][:chunkSize]
+			for j := range shard {
+				shard[j] = 0
+			}
+
+			for k := 0; k < header.DataShards; k++ {
+				gfMulAdd(matrix[i][k], shards[k][:chunkSize], shard)
+			}
+			hashes[i].Write(shard)
+
+			position := int64(len(parityMagicNumber)) + int64(i-header.DataShards)*header.ShardSize + offset
+			if _, err = parityFile.WriteAt(shard, position); err != nil {
+				return "", errors.WithStack(newError(parityFile.Name(), ErrorCodeWritingFile, err))
+			}
+		}
+	}
+
+	for _, h := range hashes {
+		header.Checksums = append(header.Checksums, base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	}
+
+	if err = r.writeHeader(parityFile, header); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	r.logger.Infof("archive: parity file “%s” generated successfully for file “%s”", parityFile.Name(), filename)
+	return parityFile.Name(), nil
+}
+
+func (r ReedSolomonParity) writeHeader(parityFile *os.File, header parityHeader) error {
+	content, err := json.Marshal(header)
+	if err != nil {
+		return errors.WithStack(newError(parityFile.Name(), ErrorCodeGeneratingParity, err))
+	}
+
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(content)))
+
+	position := int64(len(parityMagicNumber)) + int64(header.ParityShards)*header.ShardSize
+	if _, err = parityFile.WriteAt(append(content, size...), position); err != nil {
+		return errors.WithStack(newError(parityFile.Name(), ErrorCodeWritingFile, err))
+	}
+
+	return nil
+}
+
+// Repair verifies the archive using the checksums stored in the parity file,
+// rebuilding the corrupted parts when possible. The archive is modified in
+// place. On error it will return an Error type encapsulated in a traceable
+// error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (r ReedSolomonParity) Repair(filename, parityFilename string) error {
+	r.logger.Debugf("archive: verifying file “%s” with parity file “%s”", filename, parityFilename)
+
+	parityFile, err := os.Open(parityFilename)
+	if err != nil {
+		return errors.WithStack(newError(parityFilename, ErrorCodeOpeningFile, err))
+	}
+	defer parityFile.Close()
+
+	header, err := r.readHeader(parityFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	archiveFile, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if err != nil {
+		return errors.WithStack(newError(filename, ErrorCodeOpeningFile, err))
+	}
+	defer archiveFile.Close()
+
+	// the parity data protects the archive content, generated before the format
+	// header was added
+	archive := parityArchive{File: archiveFile}
+	if archive.base, err = skipHeader(archiveFile); err != nil {
+		return errors.WithStack(err)
+	}
+
+	corrupted, err := r.verify(archive, parityFile, header)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var corruptedData []int
+	for i := range corrupted {
+		if corrupted[i] && i < header.DataShards {
+			corruptedData = append(corruptedData, i)
+		}
+	}
+
+	if len(corruptedData) == 0 {
+		// the archive could have garbage in the end
+		if err = archive.Truncate(header.Size); err != nil {
+			return errors.WithStack(newError(filename, ErrorCodeRepairingArchive, err))
+		}
+
+		r.logger.Infof("archive: file “%s” verified successfully", filename)
+		return nil
+	}
+
+	// we need the same number of data parts from the original archive to
+	// rebuild it, choosing first the data parts to minimize the calculations
+	var available []int
+	for i := 0; i < len(corrupted) && len(available) < header.DataShards; i++ {
+		if !corrupted[i] {
+			available = append(available, i)
+		}
+	}
+
+	if len(available) < header.DataShards {
+		return errors.WithStack(newError(filename, ErrorCodeRepairingArchive,
+			errors.Errorf("%d corrupted parts, but only %d can be repaired", len(corrupted)-len(available), header.ParityShards)))
+	}
+
+	matrix, err := encodingMatrix(header.DataShards, header.DataShards+header.ParityShards)
+	if err != nil {
+		return errors.WithStack(newError(filename, ErrorCodeRepairingArchive, err))
+	}
+
+	subMatrix := make(gfMatrix, len(available))
+	for i, shard := range available {
+		subMatrix[i] = matrix[shard]
+	}
+
+	decodeMatrix, err := subMatrix.invert()
+	if err != nil {
+		return errors.WithStack(newError(filename, ErrorCodeRepairingArchive, err))
+	}
+
+	shards := r.newShards(header)
+	repaired := make([]byte, parityChunkSize)
+
+	for offset := int64(0); offset < header.ShardSize; offset += parityChunkSize {
+		chunkSize := header.ShardSize - offset
+		if chunkSize > parityChunkSize {
+			chunkSize = parityChunkSize
+		}
+
+		for _, i := range available {
+			if err = r.readChunk(archive, parityFile, header, i, offset, shards[i][:chunkSize]); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		for _, i := range corruptedData {
+			chunk := repaired[:chunkSize]
+			for j := range chunk {
+				chunk[j] = 0
+			}
+
+			for k, shard := range available {
+				gfMulAdd(decodeMatrix[i][k], shards[shard][:chunkSize], chunk)
+			}
+
+			position := int64(i)*header.ShardSize + offset
+			if position >= header.Size {
+				continue
+			}
+
+			if position+chunkSize > header.Size {
+				chunk = chunk[:header.Size-position]
+			}
+
+			if _, err = archive.WriteAt(chunk, position); err != nil {
+				return errors.WithStack(newError(filename, ErrorCodeRepairingArchive, err))
+			}
+		}
+	}
+
+	if err = archive.Truncate(header.Size); err != nil {
+		return errors.WithStack(newError(filename, ErrorCodeRepairingArchive, err))
+	}
+
+	r.logger.Infof("archive: %d corrupted parts repaired in file “%s”", len(corruptedData), filename)
+	return nil
+}
+
+func (r ReedSolomonParity) readHeader(parityFile *os.File) (parityHeader, error) {
+	var header parityHeader
+
+	info, err := parityFile.Stat()
+	if err != nil {
+		return header, errors.WithStack(newError(parityFile.Name(), ErrorCodeReadingFile, err))
+	}
+
+	if info.Size() < int64(len(parityMagicNumber))+8 {
+		return header, errors.WithStack(newError(parityFile.Name(), ErrorCodeParityFormat, errors.New("file too small")))
+	}
+
+	magicNumber := make([]byte, len(parityMagicNumber))
+	if _, err = parityFile.ReadAt(magicNumber, 0); err != nil {
+		return header, errors.WithStack(newError(parityFile.Name(), ErrorCodeReadingFile, err))
+	}
+
+	if !bytes.Equal(magicNumber, parityMagicNumber) {
+		return header, errors.WithStack(newError(parityFile.Name(), ErrorCodeParityFormat, errors.New("invalid magic number")))
+	}
+
+	size := make([]byte, 8)
+	if _, err = parityFile.ReadAt(size, info.Size()-8); err != nil {
+		return header, errors.WithStack(newError(parityFile.Name(), ErrorCodeReadingFile, err))
+	}
+
+	headerSize := int64(binary.BigEndian.Uint64(size))
+	if headerSize <= 0 || headerSize > info.Size()-int64(len(parityMagicNumber))-8 {
+		return header, errors.WithStack(newError(parityFile.Name(), ErrorCodeParityFormat, errors.New("invalid header size")))
+	}
+
+	content := make([]byte, headerSize)
+	if _, err = parityFile.ReadAt(content, info.Size()-8-headerSize); err != nil {
+		return header, errors.WithStack(newError(parityFile.Name(), ErrorCodeReadingFile, err))
+	}
+
+	if err = json.Unmarshal(content, &header); err != nil {
+		return header, errors.WithStack(newError(parityFile.Name(), ErrorCodeParityFormat, err))
+	}
+
+	totalShards := header.DataShards + header.ParityShards
+	if header.DataShards < 1 || header.ParityShards < 1 || totalShards > 256 || len(header.Checksums) != totalShards {
+		return header, errors.WithStack(newError(parityFile.Name(), ErrorCodeParityFormat, errors.New("invalid header")))
+	}
+
+	return header, nil
+}
+
+// verify compares the checksum of each part with the one stored in the parity
+// file, returning which parts are corrupted.
+func (r ReedSolomonParity) verify(archive parityArchive, parityFile *os.File, header parityHeader) ([]bool, error) {
+	hashes := r.newHashes(header)
+	chunk := make([]byte, parityChunkSize)
+
+	for i := range hashes {
+		for offset := int64(0); offset < header.ShardSize; offset += parityChunkSize {
+			chunkSize := header.ShardSize - offset
+			if chunkSize > parityChunkSize {
+				chunkSize = parityChunkSize
+			}
+
+			if err := r.readChunk(archive, parityFile, header, i, offset, chunk[:chunkSize]); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			hashes[i].Write(chunk[:chunkSize])
+		}
+	}
+
+	corrupted := make([]bool, len(hashes))
+	for i, h := range hashes {
+		if base64.StdEncoding.EncodeToString(h.Sum(nil)) != header.Checksums[i] {
+			r.logger.Debugf("archive: part %d of file “%s” is corrupted", i, archive.Name())
+			corrupted[i] = true
+		}
+	}
+
+	return corrupted, nil
+}
+
+// readChunk reads a piece of the part, that could be stored in the archive
+// (data) or in the parity file (parity).
+func (r ReedSolomonParity) readChunk(archive parityArchive, parityFile *os.File, header parityHeader, shard int, offset int64, chunk []byte) error {
+	if shard < header.DataShards {
+		if err := readShard(archive, int64(shard)*header.ShardSize+offset, header.Size, chunk); err != nil {
+			return errors.WithStack(newError(archive.Name(), ErrorCodeReadingFile, err))
+		}
+		return nil
+	}
+
+	position := int64(len(parityMagicNumber)) + int64(shard-header.DataShards)*header.ShardSize + offset
+	if _, err := parityFile.ReadAt(chunk, position); err != nil {
+		return errors.WithStack(newError(parityFile.Name(), ErrorCodeReadingFile, err))
+	}
+
+	return nil
+}
+
+func (r ReedSolomonParity) newShards(header parityHeader) [][]byte {
+	chunkSize := header.ShardSize
+	if chunkSize > parityChunkSize {
+		chunkSize = parityChunkSize
+	}
+
+	shards := make([][]byte, header.DataShards+header.ParityShards)
+	for i := range shards {
+		shards[i] = make([]byte, chunkSize)
+	}
+	return shards
+}
+
+func (r ReedSolomonParity) newHashes(header parityHeader) []hash.Hash {
+	hashes := make([]hash.Hash, header.DataShards+header.ParityShards)
+	for i := range hashes {
+		hashes[i] = sha256.New()
+	}
+	return hashes
+}
+
+// readShard fills the buffer with the file content starting at the given
+// position. Any content after the limit is replaced by zeros, as the last data
+// parts are padded to have the same size.
+func readShard(f io.ReaderAt, position, limit int64, buffer []byte) error {
+	n := int64(len(buffer))
+	if position >= limit {
+		n = 0
+	} else if position+n > limit {
+		n = limit - position
+	}
+
+	read, err := f.ReadAt(buffer[:n], position)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	// a truncated file will have the missing content replaced by zeros, and the
+	// checksum will detect the problem
+	for i := int64(read); i < int64(len(buffer)); i++ {
+		buffer[i] = 0
+	}
+
+	return nil
+}
+
+// parityArchive is the archive being repaired, where the positions start after
+// the format header of the file.
+type parityArchive struct {
+	*os.File
+	base int64
+}
+
+func (p parityArchive) ReadAt(b []byte, offset int64) (int, error) {
+	return p.File.ReadAt(b, p.base+offset)
+}
+
+func (p parityArchive) WriteAt(b []byte, offset int64) (int, error) {
+	return p.File.WriteAt(b, p.base+offset)
+}
+
+func (p parityArchive) Truncate(size int64) error {
+	return p.File.Truncate(p.base + size)
+}
@@ -0,0 +1,283 @@
+package archive_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+func TestReedSolomonParity_Generate(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		parity        *archive.ReedSolomonParity
+		filename      string
+		redundancy    int
+		expected      func(parityFilename string) error
+		expectedError error
+	}{
+		{
+			description: "it should generate the parity file correctly",
+			parity: archive.NewReedSolomonParity(mockLogger{
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}),
+			filename: func() string {
+				return writeParityTestFile(t, 100003)
+			}(),
+			redundancy: 10,
+			expected: func(parityFilename string) error {
+				content, err := ioutil.ReadFile(parityFilename)
+				if err != nil {
+					return err
+				}
+
+				if !bytes.HasPrefix(content, []byte("TGPARITY")) {
+					return errors.New("parity file without magic number")
+				}
+
+				// 10 parity parts of 1001 bytes each, plus the header
+				if len(content) < 10*1001 {
+					return errors.New("parity file too small")
+				}
+
+				return nil
+			},
+		},
+		{
+			description: "it should detect an invalid redundancy",
+			parity: archive.NewReedSolomonParity(mockLogger{
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}),
+			filename:   "toglacier-test",
+			redundancy: 101,
+			expectedError: &archive.Error{
+				Filename: "toglacier-test",
+				Code:     archive.ErrorCodeRedundancy,
+				Err:      errors.New("redundancy 101% out of range"),
+			},
+		},
+		{
+			description: "it should detect when the file doesn't exist",
+			parity: archive.NewReedSolomonParity(mockLogger{
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}),
+			filename:   path.Join(os.TempDir(), "toglacier-idontexist"),
+			redundancy: 10,
+			expectedError: &archive.Error{
+				Filename: path.Join(os.TempDir(), "toglacier-idontexist"),
+				Code:     archive.ErrorCodeOpeningFile,
+				Err: &os.PathError{
+					Op:   "open",
+					Path: path.Join(os.TempDir(), "toglacier-idontexist"),
+					Err:  errors.New("no such file or directory"),
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			parityFilename, err := scenario.parity.Generate(scenario.filename, scenario.redundancy)
+			defer os.Remove(parityFilename)
+
+			if scenario.expected != nil {
+				if scenarioErr := scenario.expected(parityFilename); scenarioErr != nil {
+					t.Errorf("unexpected parity file. details: %s", scenarioErr)
+				}
+			}
+
+			if !archive.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestReedSolomonParity_Repair(t *testing.T) {
+	parity := archive.NewReedSolomonParity(mockLogger{
+		mockDebugf: func(format string, args ...interface{}) {},
+		mockInfof:  func(format string, args ...interface{}) {},
+	})
+
+	generate := func(size, redundancy int) (filename, parityFilename string, content []byte) {
+		filename = writeParityTestFile(t, size)
+
+		var err error
+		if parityFilename, err = parity.Generate(filename, redundancy); err != nil {
+			t.Fatalf("error generating parity file. details: %s", err)
+		}
+
+		if content, err = ioutil.ReadFile(filename); err != nil {
+			t.Fatalf("error reading archive. details: %s", err)
+		}
+
+		return
+	}
+
+	corrupt := func(filename string, positions ...int64) {
+		f, err := os.OpenFile(filename, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("error opening archive. details: %s", err)
+		}
+		defer f.Close()
+
+		for _, position := range positions {
+			if _, err = f.WriteAt([]byte("corrupted"), position); err != nil {
+				t.Fatalf("error corrupting archive. details: %s", err)
+			}
+		}
+	}
+
+	type scenario struct {
+		description    string
+		filename       string
+		parityFilename string
+		expected       []byte
+		expectedError  error
+	}
+
+	scenarios := []scenario{
+		func() scenario {
+			var s scenario
+			s.description = "it should keep an intact archive"
+			s.filename, s.parityFilename, s.expected = generate(100003, 5)
+			return s
+		}(),
+		func() scenario {
+			var s scenario
+			s.description = "it should repair a corrupted archive"
+			s.filename, s.parityFilename, s.expected = generate(100003, 5)
+			corrupt(s.filename, 0, 5000, 99990)
+			return s
+		}(),
+		func() scenario {
+			var s scenario
+			s.description = "it should repair a truncated archive"
+			s.filename, s.parityFilename, s.expected = generate(100003, 5)
+			if err := os.Truncate(s.filename, 99000); err != nil {
+				t.Fatalf("error truncating archive. details: %s", err)
+			}
+			return s
+		}(),
+		func() scenario {
+			var s scenario
+			s.description = "it should repair the archive after the format header"
+			s.filename, s.parityFilename, s.expected = generate(100003, 5)
+
+			// the header is sent before the archive, after the parity generation
+			header, err := archive.NewHeader(archive.FormatTARGzip, "").Encode()
+			if err != nil {
+				t.Fatalf("error encoding the header. details: %s", err)
+			}
+			s.expected = append(header, s.expected...)
+
+			if err = ioutil.WriteFile(s.filename, s.expected, 0600); err != nil {
+				t.Fatalf("error writing archive. details: %s", err)
+			}
+			corrupt(s.filename, int64(len(header)), int64(len(header))+50000)
+			return s
+		}(),
+		func() scenario {
+			var s scenario
+			s.description = "it should repair a small archive"
+			s.filename, s.parityFilename, s.expected = generate(1000, 1)
+			corrupt(s.filename, 0)
+			return s
+		}(),
+		func() scenario {
+			var s scenario
+			s.description = "it should detect when there are too many corrupted parts"
+			s.filename, s.parityFilename, _ = generate(100003, 1)
+			corrupt(s.filename, 0, 50000)
+			s.expectedError = &archive.Error{
+				Filename: s.filename,
+				Code:     archive.ErrorCodeRepairingArchive,
+				Err:      errors.New("2 corrupted parts, but only 1 can be repaired"),
+			}
+			return s
+		}(),
+		func() scenario {
+			var s scenario
+			s.description = "it should detect an invalid parity file"
+			s.filename = writeParityTestFile(t, 100)
+			s.parityFilename = writeParityTestFile(t, 100)
+			s.expectedError = &archive.Error{
+				Filename: s.parityFilename,
+				Code:     archive.ErrorCodeParityFormat,
+				Err:      errors.New("invalid magic number"),
+			}
+			return s
+		}(),
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			defer os.Remove(scenario.filename)
+			defer os.Remove(scenario.parityFilename)
+
+			err := parity.Repair(scenario.filename, scenario.parityFilename)
+
+			if scenario.expected != nil {
+				content, readErr := ioutil.ReadFile(scenario.filename)
+				if readErr != nil {
+					t.Fatalf("error reading archive. details: %s", readErr)
+				}
+
+				if !bytes.Equal(scenario.expected, content) {
+					t.Error("archive content doesn't match the original one")
+				}
+			}
+
+			if !archive.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func BenchmarkReedSolomonParity_Generate(b *testing.B) {
+	parity := archive.NewReedSolomonParity(newBenchmarkLogger())
+
+	for _, dataset := range benchmarkDatasets {
+		filename := dataset.archive(b)
+		defer os.Remove(filename)
+
+		b.Run(dataset.description, func(b *testing.B) {
+			b.SetBytes(dataset.totalSize())
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				parityFilename, err := parity.Generate(filename, 10)
+				if err != nil {
+					b.Fatalf("error generating parity file. details %s", err)
+				}
+				os.Remove(parityFilename)
+			}
+		})
+	}
+}
+
+func writeParityTestFile(t *testing.T, size int) string {
+	file, err := ioutil.TempFile("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary file. details %s", err)
+	}
+	defer file.Close()
+
+	content := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(content)
+
+	if _, err = file.Write(content); err != nil {
+		t.Fatalf("error writing temporary file. details %s", err)
+	}
+
+	return file.Name()
+}
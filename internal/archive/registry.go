@@ -0,0 +1,134 @@
+package archive
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+const (
+	// FormatTAR builds archives using the TAR computer software utility.
+	FormatTAR = "tar"
+
+	// FormatTARGzip builds TAR archives compressed with gzip.
+	FormatTARGzip = "tar+gzip"
+
+	// FormatZIP builds compressed ZIP archives, that are easier to open on
+	// Windows.
+	FormatZIP = "zip"
+
+	// FormatStream identifies in the archive header the data backed up from a
+	// stream, that isn't built as an archive.
+	FormatStream = "stream"
+
+	// EnvelopOFB encrypts archives using AES in OFB mode, authenticated with
+	// HMAC-SHA256.
+	EnvelopOFB = "ofb"
+)
+
+// The registry is internal to toglacier: the Archive and Envelop interfaces use
+// types of this package, so the programs that embed toglacier can't implement
+// them, and new formats (e.g. tar+zstd) are added in this package. There's no
+// tar+zstd format, as the dependencies of toglacier don't have a zstd encoder.
+
+// ArchiveFactory creates an archive implementation.
+type ArchiveFactory func(logger log.Logger) Archive
+
+// EnvelopFactory creates an envelop implementation.
+type EnvelopFactory func(logger log.Logger) Envelop
+
+var registry = struct {
+	sync.RWMutex
+	archives map[string]ArchiveFactory
+	envelops map[string]EnvelopFactory
+}{
+	archives: map[string]ArchiveFactory{
+		FormatTAR: func(logger log.Logger) Archive {
+			return NewTARBuilder(logger)
+		},
+		FormatTARGzip: func(logger log.Logger) Archive {
+			return NewTARGzipBuilder(logger)
+		},
+		FormatZIP: func(logger log.Logger) Archive {
+			return NewZIPBuilder(logger)
+		},
+	},
+	envelops: map[string]EnvelopFactory{
+		EnvelopOFB: func(logger log.Logger) Envelop {
+			return NewOFBEnvelop(logger)
+		},
+	},
+}
+
+// RegisterArchive makes an archive format available by name, so it can be
+// chosen in the configuration. If the name already exists it will be replaced.
+func RegisterArchive(name string, factory ArchiveFactory) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.archives[name] = factory
+}
+
+// RegisterEnvelop makes an envelop available by name, so it can be chosen in
+// the configuration. If the name already exists it will be replaced.
+func RegisterEnvelop(name string, factory EnvelopFactory) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.envelops[name] = factory
+}
+
+// NewArchive creates the archive registered with the given name. On error it
+// will return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func NewArchive(name string, logger log.Logger) (Archive, error) {
+	registry.RLock()
+	factory, ok := registry.archives[name]
+	registry.RUnlock()
+
+	if !ok {
+		return nil, errors.WithStack(newError("", ErrorCodeUnknownFormat, errors.Errorf("format “%s” not registered", name)))
+	}
+
+	return factory(logger), nil
+}
+
+// NewEnvelop creates the envelop registered with the given name. On error it
+// will return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func NewEnvelop(name string, logger log.Logger) (Envelop, error) {
+	registry.RLock()
+	factory, ok := registry.envelops[name]
+	registry.RUnlock()
+
+	if !ok {
+		return nil, errors.WithStack(newError("", ErrorCodeUnknownEnvelop, errors.Errorf("envelop “%s” not registered", name)))
+	}
+
+	return factory(logger), nil
+}
@@ -0,0 +1,109 @@
+package archive_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+func TestNewArchive(t *testing.T) {
+	archive.RegisterArchive("custom", func(logger log.Logger) archive.Archive {
+		return archive.NewTARGzipBuilder(logger)
+	})
+
+	// functions can't be compared, so the logger mock must be empty
+	logger := mockLogger{}
+
+	scenarios := []struct {
+		description   string
+		name          string
+		expected      archive.Archive
+		expectedError error
+	}{
+		{
+			description: "it should create a tar archive",
+			name:        archive.FormatTAR,
+			expected:    archive.NewTARBuilder(logger),
+		},
+		{
+			description: "it should create a compressed tar archive",
+			name:        archive.FormatTARGzip,
+			expected:    archive.NewTARGzipBuilder(logger),
+		},
+		{
+			description: "it should create a zip archive",
+			name:        archive.FormatZIP,
+			expected:    archive.NewZIPBuilder(logger),
+		},
+		{
+			description: "it should create a registered archive",
+			name:        "custom",
+			expected:    archive.NewTARGzipBuilder(logger),
+		},
+		{
+			description: "it should detect an unknown archive format",
+			name:        "rar",
+			expectedError: &archive.Error{
+				Code: archive.ErrorCodeUnknownFormat,
+				Err:  errors.New("format “rar” not registered"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			a, err := archive.NewArchive(scenario.name, logger)
+
+			if !reflect.DeepEqual(scenario.expected, a) {
+				t.Errorf("archives don't match. expected “%#v” and got “%#v”", scenario.expected, a)
+			}
+
+			if !archive.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNewEnvelop(t *testing.T) {
+	// functions can't be compared, so the logger mock must be empty
+	logger := mockLogger{}
+
+	scenarios := []struct {
+		description   string
+		name          string
+		expected      archive.Envelop
+		expectedError error
+	}{
+		{
+			description: "it should create an ofb envelop",
+			name:        archive.EnvelopOFB,
+			expected:    archive.NewOFBEnvelop(logger),
+		},
+		{
+			description: "it should detect an unknown envelop",
+			name:        "rot13",
+			expectedError: &archive.Error{
+				Code: archive.ErrorCodeUnknownEnvelop,
+				Err:  errors.New("envelop “rot13” not registered"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			e, err := archive.NewEnvelop(scenario.name, logger)
+
+			if !reflect.DeepEqual(scenario.expected, e) {
+				t.Errorf("envelops don't match. expected “%#v” and got “%#v”", scenario.expected, e)
+			}
+
+			if !archive.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
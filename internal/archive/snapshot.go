@@ -0,0 +1,126 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// snapshot is a temporary copy of a file, read while the file wasn't
+// changing, so the archive doesn't contain a copy that is half old and half
+// new. It is only used when the builders are configured to copy the files
+// first, as it doubles the disk I/O.
+type snapshot struct {
+	file     *os.File
+	size     int64
+	modTime  time.Time
+	checksum string
+
+	// fuzzy is true when the file kept changing in all the attempts to read it
+	fuzzy bool
+}
+
+// takeSnapshot copies the file to a temporary file, reading it again up to
+// retries times when its size or modification time changes during the copy.
+// When the file keeps changing the last copy is used and marked as fuzzy. The
+// snapshot must be closed after use, to remove the temporary file.
+func takeSnapshot(logger log.Logger, fileSystem FileSystem, path string, retries int) (*snapshot, error) {
+	tmpFile, err := ioutil.TempFile("", "toglacier-")
+	if err != nil {
+		return nil, errors.WithStack(newError(path, ErrorCodeTmpFileCreation, err))
+	}
+
+	s := &snapshot{file: tmpFile}
+
+	for attempt := 0; attempt <= retries; attempt++ {
+		var changed bool
+		if changed, err = s.copy(fileSystem, path); err != nil {
+			s.Close()
+			return nil, errors.WithStack(err)
+		}
+
+		if !changed {
+			s.fuzzy = false
+			return s, nil
+		}
+
+		s.fuzzy = true
+		logger.Debugf("archive: path “%s” changed while it was read (attempt %d of %d)", path, attempt+1, retries+1)
+	}
+
+	logger.Warningf("archive: path “%s” kept changing while it was read, the archived copy is fuzzy", path)
+	return s, nil
+}
+
+// copy replaces the content of the snapshot with the current content of the
+// file, informing if the file changed during the copy.
+func (s *snapshot) copy(fileSystem FileSystem, path string) (changed bool, err error) {
+	file, err := fileSystem.Open(path)
+	if err != nil {
+		return false, errors.WithStack(newPathError(path, PathErrorCodeOpeningFile, err))
+	}
+	defer file.Close()
+
+	before, err := file.Stat()
+	if err != nil {
+		return false, errors.WithStack(newPathError(path, PathErrorCodeInfo, err))
+	}
+
+	if _, err = s.file.Seek(0, io.SeekStart); err != nil {
+		return false, errors.WithStack(newPathError(path, PathErrorCodeRewindingFile, err))
+	}
+
+	if err = s.file.Truncate(0); err != nil {
+		return false, errors.WithStack(newPathError(path, PathErrorCodeRewindingFile, err))
+	}
+
+	hash := sha256.New()
+	if s.size, err = io.Copy(io.MultiWriter(s.file, hash), file); err != nil {
+		return false, errors.WithStack(newPathError(path, PathErrorCodeReadingFile, err))
+	}
+
+	after, err := file.Stat()
+	if err != nil {
+		return false, errors.WithStack(newPathError(path, PathErrorCodeInfo, err))
+	}
+
+	if _, err = s.file.Seek(0, io.SeekStart); err != nil {
+		return false, errors.WithStack(newPathError(path, PathErrorCodeRewindingFile, err))
+	}
+
+	s.modTime = after.ModTime()
+	s.checksum = base64.StdEncoding.EncodeToString(hash.Sum(nil))
+
+	return fileChanged(before, after) || s.size != after.Size(), nil
+}
+
+// fileChanged compares the information of the file before and after reading
+// it: the size, the modification time and the inode, as a file replaced (e.g.
+// saved with a rename) can keep the size and the modification time. The inode
+// is ignored when unknown (e.g. files from memory or from Windows).
+func fileChanged(before, after os.FileInfo) bool {
+	if before.Size() != after.Size() || !before.ModTime().Equal(after.ModTime()) {
+		return true
+	}
+
+	beforeDev, beforeIno, beforeOK := inode(before)
+	afterDev, afterIno, afterOK := inode(after)
+	return beforeOK && afterOK && (beforeDev != afterDev || beforeIno != afterIno)
+}
+
+// Read reads the copy of the file.
+func (s *snapshot) Read(p []byte) (int, error) {
+	return s.file.Read(p)
+}
+
+// Close removes the temporary copy of the file.
+func (s *snapshot) Close() error {
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
@@ -0,0 +1,240 @@
+package archive_test
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+func TestChangeRetries(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	builders := map[string]func(fileSystem archive.FileSystem) archive.Archive{
+		"tar": func(fileSystem archive.FileSystem) archive.Archive {
+			builder := archive.NewTARBuilder(logger)
+			builder.FileSystem = fileSystem
+			builder.Snapshot = true
+			builder.ChangeRetries = 3
+			return builder
+		},
+		"zip": func(fileSystem archive.FileSystem) archive.Archive {
+			builder := archive.NewZIPBuilder(logger)
+			builder.FileSystem = fileSystem
+			builder.Snapshot = true
+			builder.ChangeRetries = 3
+			return builder
+		},
+	}
+
+	scenarios := []struct {
+		description         string
+		changes             int
+		expectedArchiveInfo archive.Info
+	}{
+		{
+			description: "it should archive a file that doesn't change",
+			expectedArchiveInfo: archive.Info{
+				"data/file1": archive.ItemInfo{
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+				},
+			},
+		},
+		{
+			description: "it should read again a file that stops changing",
+			changes:     2,
+			expectedArchiveInfo: archive.Info{
+				"data/file1": archive.ItemInfo{
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+				},
+			},
+		},
+		{
+			description: "it should mark as fuzzy a file that keeps changing",
+			changes:     10,
+			expectedArchiveInfo: archive.Info{
+				"data/file1": archive.ItemInfo{
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+					Fuzzy:    true,
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		for name, builder := range builders {
+			t.Run(fmt.Sprintf("%s (%s)", scenario.description, name), func(t *testing.T) {
+				changes := scenario.changes
+				fileSystem := changingFileSystem{
+					FileSystem: archive.NewIOFileSystem(fstest.MapFS{
+						"data/file1": &fstest.MapFile{Data: []byte("file1 test"), Mode: 0600},
+					}),
+					changes: &changes,
+				}
+
+				filename, archiveInfo, err := builder(fileSystem).Build(context.Background(), nil, nil, "data")
+				if filename != "" {
+					defer os.Remove(filename)
+				}
+
+				if err != nil {
+					t.Fatalf("unexpected error “%v”", err)
+				}
+
+				if !reflect.DeepEqual(scenario.expectedArchiveInfo, archiveInfo) {
+					t.Errorf("archive info don't match.\n%s", Diff(scenario.expectedArchiveInfo, archiveInfo))
+				}
+
+				files, err := archiveFiles(name, filename)
+				if err != nil {
+					t.Fatalf("error reading archive. details: %s", err)
+				}
+
+				if expectedFiles := []string{"data/file1"}; !reflect.DeepEqual(expectedFiles, files) {
+					t.Errorf("archive files don't match.\n%s", Diff(expectedFiles, files))
+				}
+			})
+		}
+	}
+}
+
+func TestChangeDetection(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	builders := map[string]func(fileSystem archive.FileSystem) archive.Archive{
+		"tar": func(fileSystem archive.FileSystem) archive.Archive {
+			builder := archive.NewTARBuilder(logger)
+			builder.FileSystem = fileSystem
+			return builder
+		},
+		"zip": func(fileSystem archive.FileSystem) archive.Archive {
+			builder := archive.NewZIPBuilder(logger)
+			builder.FileSystem = fileSystem
+			return builder
+		},
+	}
+
+	scenarios := []struct {
+		description         string
+		changes             int
+		expectedArchiveInfo archive.Info
+	}{
+		{
+			description: "it should archive directly a file that doesn't change",
+			expectedArchiveInfo: archive.Info{
+				"data/file1": archive.ItemInfo{
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+				},
+			},
+		},
+		{
+			description: "it should mark as fuzzy a file that changes while it is archived directly",
+			changes:     1,
+			expectedArchiveInfo: archive.Info{
+				"data/file1": archive.ItemInfo{
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+					Fuzzy:    true,
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		for name, builder := range builders {
+			t.Run(fmt.Sprintf("%s (%s)", scenario.description, name), func(t *testing.T) {
+				changes := scenario.changes
+				fileSystem := changingFileSystem{
+					FileSystem: archive.NewIOFileSystem(fstest.MapFS{
+						"data/file1": &fstest.MapFile{Data: []byte("file1 test"), Mode: 0600},
+					}),
+					changes: &changes,
+				}
+
+				filename, archiveInfo, err := builder(fileSystem).Build(context.Background(), nil, nil, "data")
+				if filename != "" {
+					defer os.Remove(filename)
+				}
+
+				if err != nil {
+					t.Fatalf("unexpected error “%v”", err)
+				}
+
+				if !reflect.DeepEqual(scenario.expectedArchiveInfo, archiveInfo) {
+					t.Errorf("archive info don't match.\n%s", Diff(scenario.expectedArchiveInfo, archiveInfo))
+				}
+			})
+		}
+	}
+}
+
+// changingFileSystem reports a new modification time on each information
+// request of the opened files, until the given number of changes is detected.
+type changingFileSystem struct {
+	archive.FileSystem
+	changes *int
+}
+
+func (c changingFileSystem) Open(name string) (fs.File, error) {
+	file, err := c.FileSystem.Open(name)
+	if err != nil || *c.changes <= 0 {
+		return file, err
+	}
+
+	return &changingFile{File: file, changes: c.changes}, nil
+}
+
+type changingFile struct {
+	fs.File
+	changes *int
+	stats   int
+}
+
+func (c *changingFile) Stat() (fs.FileInfo, error) {
+	info, err := c.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	c.stats++
+	if c.stats > 1 {
+		// the file changed since the last request
+		*c.changes--
+	}
+
+	return changingFileInfo{
+		FileInfo: info,
+		modTime:  time.Date(2017, 9, 14, 10, 30, c.stats, 0, time.UTC),
+	}, nil
+}
+
+type changingFileInfo struct {
+	fs.FileInfo
+	modTime time.Time
+}
+
+func (c changingFileInfo) ModTime() time.Time {
+	return c.modTime
+}
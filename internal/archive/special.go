@@ -0,0 +1,45 @@
+package archive
+
+import (
+	"os"
+
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+const (
+	// SpecialFilesSkip leaves the device files, sockets and FIFOs out of the
+	// archive, logging a warning for each one.
+	SpecialFilesSkip SpecialFiles = "skip"
+
+	// SpecialFilesArchive adds the device files and FIFOs to the tarball as
+	// entries without content, created again when extracted on Linux. Sockets
+	// are always skipped, as they only exist while the program that created
+	// them is running, and zip archives can't store special files.
+	SpecialFilesArchive SpecialFiles = "archive"
+)
+
+// SpecialFiles defines how the device files, sockets and FIFOs found in the
+// backup paths are handled. When empty they are skipped.
+type SpecialFiles string
+
+// specialModes are the file modes of the special files.
+const specialModes = os.ModeDevice | os.ModeCharDevice | os.ModeNamedPipe | os.ModeSocket
+
+// isSpecial checks if the file is a device file, a socket or a FIFO.
+func isSpecial(mode os.FileMode) bool {
+	return mode&specialModes != 0
+}
+
+// specialItemInfo compares the special file with the last archive information.
+// As there's no content to compare, the special file is only added to the
+// archive when it wasn't in the last one.
+func specialItemInfo(logger log.Logger, path string, lastArchiveInfo Info) (itemInfo ItemInfo, add bool) {
+	if lastItemInfo, ok := lastArchiveInfo[path]; ok && lastItemInfo.Status != ItemInfoStatusDeleted {
+		lastItemInfo.Status = ItemInfoStatusUnmodified
+		logger.Debugf("archive: special file “%s” unmodified since the last archive", path)
+		return lastItemInfo, false
+	}
+
+	logger.Debugf("archive: special file “%s” is new since the last archive", path)
+	return ItemInfo{Status: ItemInfoStatusNew}, true
+}
@@ -0,0 +1,35 @@
+// +build linux
+
+package archive
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// makeSpecial creates the device file or FIFO. Device files can only be created
+// with root privileges.
+func makeSpecial(path string, mode os.FileMode, devMajor, devMinor int64) error {
+	var fileType uint32
+
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		fileType = unix.S_IFIFO
+	case mode&os.ModeCharDevice != 0:
+		fileType = unix.S_IFCHR
+	case mode&os.ModeDevice != 0:
+		fileType = unix.S_IFBLK
+	default:
+		return errors.Errorf("file mode “%s” isn't a device file or FIFO", mode)
+	}
+
+	dev := unix.Mkdev(uint32(devMajor), uint32(devMinor))
+	if err := unix.Mknod(path, fileType|uint32(mode.Perm()), int(dev)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// the permissions are affected by the umask when creating the file
+	return errors.WithStack(os.Chmod(path, mode.Perm()))
+}
@@ -0,0 +1,83 @@
+// +build linux
+
+package archive_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+func TestSpecialFiles_Extract(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	dir, err := ioutil.TempDir("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary directory. details: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err = syscall.Mkfifo(filepath.Join(dir, "fifo"), 0640); err != nil {
+		t.Fatalf("error creating fifo. details: %s", err)
+	}
+
+	builder := archive.NewTARBuilder(logger)
+	builder.SpecialFiles = archive.SpecialFilesArchive
+
+	filename, _, err := builder.Build(context.Background(), nil, nil, dir)
+	if filename != "" {
+		defer os.Remove(filename)
+	}
+
+	if err != nil {
+		t.Fatalf("unexpected error building the archive. details: %s", err)
+	}
+
+	extractDir, err := ioutil.TempDir("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary directory. details: %s", err)
+	}
+	defer os.RemoveAll(extractDir)
+
+	// the files are extracted in the current directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error retrieving the current directory. details: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	if err = os.Chdir(extractDir); err != nil {
+		t.Fatalf("error changing the current directory. details: %s", err)
+	}
+
+	if _, err = builder.Extract(context.Background(), filename, nil); err != nil {
+		t.Fatalf("unexpected error extracting the archive. details: %s", err)
+	}
+
+	var fifos []string
+	err = filepath.Walk(extractDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode()&os.ModeNamedPipe != 0 {
+			fifos = append(fifos, filepath.Base(path)+" "+info.Mode().String())
+		}
+		return err
+	})
+
+	if err != nil {
+		t.Fatalf("error listing the extracted files. details: %s", err)
+	}
+
+	if len(fifos) != 1 || fifos[0] != "fifo prw-r-----" {
+		t.Errorf("unexpected extracted fifos %v", fifos)
+	}
+}
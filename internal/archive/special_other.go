@@ -0,0 +1,15 @@
+// +build !linux
+
+package archive
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// makeSpecial isn't supported outside Linux.
+func makeSpecial(path string, mode os.FileMode, devMajor, devMinor int64) error {
+	return errors.Errorf("special files can't be created on %s", runtime.GOOS)
+}
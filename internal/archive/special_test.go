@@ -0,0 +1,174 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+func TestSpecialFiles(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	fileSystem := archive.NewIOFileSystem(fstest.MapFS{
+		"data/file1":  &fstest.MapFile{Data: []byte("file1 test"), Mode: 0600},
+		"data/fifo":   &fstest.MapFile{Mode: fs.ModeNamedPipe | 0600},
+		"data/socket": &fstest.MapFile{Mode: fs.ModeSocket | 0600},
+		"data/tty":    &fstest.MapFile{Mode: fs.ModeDevice | fs.ModeCharDevice | 0620},
+	})
+
+	file1 := archive.ItemInfo{
+		Status:   archive.ItemInfoStatusNew,
+		Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+	}
+
+	scenarios := []struct {
+		description         string
+		builder             func() archive.Archive
+		format              string
+		lastArchiveInfo     archive.Info
+		expectedArchiveInfo archive.Info
+		expectedEntries     []string
+	}{
+		{
+			description: "it should skip the special files by default",
+			builder: func() archive.Archive {
+				builder := archive.NewTARBuilder(logger)
+				builder.FileSystem = fileSystem
+				return builder
+			},
+			format: "tar",
+			expectedArchiveInfo: archive.Info{
+				"data/file1": file1,
+			},
+			expectedEntries: []string{"data/file1 48"},
+		},
+		{
+			description: "it should archive the devices and FIFOs",
+			builder: func() archive.Archive {
+				builder := archive.NewTARBuilder(logger)
+				builder.FileSystem = fileSystem
+				builder.SpecialFiles = archive.SpecialFilesArchive
+				return builder
+			},
+			format: "tar",
+			expectedArchiveInfo: archive.Info{
+				"data/fifo":  archive.ItemInfo{Status: archive.ItemInfoStatusNew},
+				"data/file1": file1,
+				"data/tty":   archive.ItemInfo{Status: archive.ItemInfoStatusNew},
+			},
+			expectedEntries: []string{"data/fifo 54", "data/file1 48", "data/tty 51"},
+		},
+		{
+			description: "it should keep the special files of the last archive",
+			builder: func() archive.Archive {
+				builder := archive.NewTARBuilder(logger)
+				builder.FileSystem = fileSystem
+				builder.SpecialFiles = archive.SpecialFilesArchive
+				return builder
+			},
+			format: "tar",
+			lastArchiveInfo: archive.Info{
+				"data/fifo": archive.ItemInfo{ID: "AWSID122", Status: archive.ItemInfoStatusNew},
+				"data/tty":  archive.ItemInfo{ID: "AWSID122", Status: archive.ItemInfoStatusDeleted},
+			},
+			expectedArchiveInfo: archive.Info{
+				"data/fifo":  archive.ItemInfo{ID: "AWSID122", Status: archive.ItemInfoStatusUnmodified},
+				"data/file1": file1,
+				"data/tty":   archive.ItemInfo{Status: archive.ItemInfoStatusNew},
+			},
+			expectedEntries: []string{"data/file1 48", "data/tty 51"},
+		},
+		{
+			description: "it should skip the special files in zip",
+			builder: func() archive.Archive {
+				builder := archive.NewZIPBuilder(logger)
+				builder.FileSystem = fileSystem
+				return builder
+			},
+			format: "zip",
+			expectedArchiveInfo: archive.Info{
+				"data/file1": file1,
+			},
+			expectedEntries: []string{"data/file1"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			filename, archiveInfo, err := scenario.builder().Build(context.Background(), scenario.lastArchiveInfo, nil, "data")
+			if filename != "" {
+				defer os.Remove(filename)
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error “%v”", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedArchiveInfo, archiveInfo) {
+				t.Errorf("archive info don't match.\n%s", Diff(scenario.expectedArchiveInfo, archiveInfo))
+			}
+
+			var entries []string
+			if scenario.format == "tar" {
+				entries, err = tarEntries(filename)
+			} else {
+				entries, err = archiveFiles(scenario.format, filename)
+			}
+
+			if err != nil {
+				t.Fatalf("error reading archive. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedEntries, entries) {
+				t.Errorf("archive entries don't match.\n%s", Diff(scenario.expectedEntries, entries))
+			}
+		})
+	}
+}
+
+// tarEntries lists the entries of the tarball that aren't directories with
+// their types, without the base directory.
+func tarEntries(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+
+	tarReader := tar.NewReader(f)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		// drop the base directory (e.g. backup-20170901103015)
+		parts := strings.SplitN(header.Name, "/", 2)
+		if header.Typeflag == tar.TypeDir || len(parts) != 2 || parts[1] == archive.TARInfoFilename {
+			continue
+		}
+
+		entries = append(entries, fmt.Sprintf("%s %d", parts[1], header.Typeflag))
+	}
+
+	return entries, nil
+}
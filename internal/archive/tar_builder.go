@@ -2,8 +2,10 @@ package archive
 
 import (
 	"archive/tar"
-	"crypto/sha256"
-	"encoding/base64"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"io"
 	"io/ioutil"
@@ -11,7 +13,6 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
-	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rafaeljusto/toglacier/internal/log"
@@ -28,10 +29,72 @@ var volumeLetterRX = regexp.MustCompile(`^[a-zA-Z]:`)
 // created while extracting a tarball.
 const extractDirectoryPermission os.FileMode = 0755
 
+// gzipMagicNumber identifies the beginning of a gzip compressed file.
+var gzipMagicNumber = []byte{0x1f, 0x8b}
+
 // TARBuilder join all paths into an archive using the TAR computer software
 // utility.
 type TARBuilder struct {
 	logger log.Logger
+	gzip   bool
+
+	// FileSystem gives access to the backup paths. When not defined the local
+	// disk is used.
+	FileSystem FileSystem
+
+	// Clock defines the name of the tarball root directory. When not defined the
+	// system clock is used.
+	Clock Clock
+
+	// Progress receives the estimate of the backup paths and each analyzed
+	// file. When not defined the progress isn't reported.
+	Progress Progress
+
+	// Changes informs the files modified since the last archive, so the
+	// checksum of the other files isn't calculated. When not defined all files
+	// are analyzed.
+	Changes Changes
+
+	// Overwrite defines what happens with the existing files when extracting
+	// the tarball. By default they are replaced.
+	Overwrite OverwritePolicy
+
+	// ExtractProgress receives the result of each extracted file. When not
+	// defined the extraction isn't reported.
+	ExtractProgress ExtractProgress
+
+	// AllOrNothing keeps all existing files when any extracted file doesn't
+	// match its checksum. By default only the mismatching files are discarded.
+	AllOrNothing bool
+
+	// Normalization defines the Unicode normalization form of the names of the
+	// files extracted from the tarball. By default the names are kept.
+	Normalization Normalization
+
+	// Tolerance decides if the files that can't be read are left out of the
+	// tarball. When not defined the build fails on the first unreadable file.
+	Tolerance PathTolerance
+
+	// SpecialFiles defines if the device files and FIFOs are added to the
+	// tarball. By default they are skipped with a warning.
+	SpecialFiles SpecialFiles
+
+	// Deadline stops the analysis of the files when reached, building the tarball
+	// with the files analyzed until then. When not defined all files are
+	// analyzed.
+	Deadline Deadline
+
+	// Snapshot copies each file to a temporary file before it is added, so a
+	// file that changes while it is read can be read again. By default the
+	// files are added directly, and the ones that change while they are added
+	// are marked as fuzzy in the archive information.
+	Snapshot bool
+
+	// ChangeRetries is the number of times a file that changes while it is
+	// copied to the temporary file is read again, when Snapshot is enabled.
+	// When it keeps changing the last copy is added and marked as fuzzy in the
+	// archive information.
+	ChangeRetries int
 }
 
 // NewTARBuilder returns a TARBuilder with all necessary initializations.
@@ -41,11 +104,20 @@ func NewTARBuilder(logger log.Logger) *TARBuilder {
 	}
 }
 
+// NewTARGzipBuilder returns a TARBuilder that compresses the tarball with gzip.
+func NewTARGzipBuilder(logger log.Logger) *TARBuilder {
+	return &TARBuilder{
+		logger: logger,
+		gzip:   true,
+	}
+}
+
 // Build builds a tarball containing all the desired files that you want to
 // backup. A control file is added to the tarball root so we can control
 // incremental archives (send only what was modified). Files and directories can
 // be ignores in the backupPaths using the regular expressions in the
-// ignorePatterns parameter. On success it will return an open file, so the
+// ignorePatterns parameter. The scan of the backup paths stops when the context
+// is cancelled. On success it will return an open file, so the
 // caller is responsible for closing it. If no file was written to the tarball,
 // an empty filename is returned. On error it will return an Error or PathError
 // type encapsulated in a traceable error. To retrieve the desired error you can
@@ -65,17 +137,33 @@ func NewTARBuilder(logger log.Logger) *TARBuilder {
 //         // unknown error
 //       }
 //     }
-func (t TARBuilder) Build(lastArchiveInfo Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, Info, error) {
+func (t TARBuilder) Build(ctx context.Context, lastArchiveInfo Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, Info, error) {
 	t.logger.Debugf("archive: build tar for backup paths %v", backupPaths)
 
+	if t.Progress != nil {
+		estimate, err := t.Estimate(ctx, ignorePatterns, backupPaths...)
+		if err != nil {
+			return "", nil, errors.WithStack(err)
+		}
+		t.Progress.OnEstimate(estimate)
+	}
+
 	tarFile, err := ioutil.TempFile("", "toglacier-")
 	if err != nil {
 		return "", nil, errors.WithStack(newError("", ErrorCodeTARCreation, err))
 	}
 	defer tarFile.Close()
 
-	tarArchive := tar.NewWriter(tarFile)
-	basePath := "backup-" + time.Now().Format("20060102150405")
+	var tarWriter io.Writer = tarFile
+	var gzipWriter *gzip.Writer
+
+	if t.gzip {
+		gzipWriter = gzip.NewWriter(tarFile)
+		tarWriter = gzipWriter
+	}
+
+	tarArchive := tar.NewWriter(tarWriter)
+	basePath := "backup-" + t.clock().Now().Format("20060102150405")
 
 	archiveInfo := make(Info)
 	hasFiles := false
@@ -87,7 +175,7 @@ func (t TARBuilder) Build(lastArchiveInfo Info, ignorePatterns []*regexp.Regexp,
 
 		t.logger.Debugf("archive: analyzing backup path “%s”", path)
 
-		tmpArchiveInfo, tmpHasFiles, err := t.build(lastArchiveInfo, tarArchive, basePath, path, ignorePatterns)
+		tmpArchiveInfo, tmpHasFiles, err := t.build(ctx, lastArchiveInfo, tarArchive, basePath, path, ignorePatterns)
 		if err != nil {
 			return "", nil, errors.WithStack(err)
 		}
@@ -118,6 +206,12 @@ func (t TARBuilder) Build(lastArchiveInfo Info, ignorePatterns []*regexp.Regexp,
 		return "", nil, errors.WithStack(newError(tarFile.Name(), ErrorCodeTARGeneration, err))
 	}
 
+	if gzipWriter != nil {
+		if err := gzipWriter.Close(); err != nil {
+			return "", nil, errors.WithStack(newError(tarFile.Name(), ErrorCodeTARGeneration, err))
+		}
+	}
+
 	if !hasFiles {
 		// force fd close to remove the empty tarball.
 		tarFile.Close()
@@ -131,13 +225,21 @@ func (t TARBuilder) Build(lastArchiveInfo Info, ignorePatterns []*regexp.Regexp,
 	return tarFile.Name(), archiveInfo, nil
 }
 
-func (t TARBuilder) build(lastArchiveInfo Info, tarArchive *tar.Writer, baseDir, source string, ignorePatterns []*regexp.Regexp) (archiveInfo Info, hasFiles bool, err error) {
+func (t TARBuilder) build(ctx context.Context, lastArchiveInfo Info, tarArchive *tar.Writer, baseDir, source string, ignorePatterns []*regexp.Regexp) (archiveInfo Info, hasFiles bool, err error) {
 	var directories []*tar.Header
 	archiveInfo = make(Info)
 
-	walkErr := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+	walkErr := t.fileSystem().Walk(source, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			return errors.WithStack(newPathError(path, PathErrorCodeInfo, err))
+			err = errors.WithStack(newPathError(path, PathErrorCodeInfo, err))
+			if skipPath(t.logger, t.Tolerance, path, err, lastArchiveInfo, archiveInfo) {
+				return nil
+			}
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return errors.WithStack(newError(path, ErrorCodeCancelled, ctx.Err()))
 		}
 
 		t.logger.Debugf("archive: walking into path “%s”", path)
@@ -149,13 +251,28 @@ func (t TARBuilder) build(lastArchiveInfo Info, tarArchive *tar.Writer, baseDir,
 			}
 		}
 
+		if deadlineReached(t.Deadline, path, lastArchiveInfo, archiveInfo) {
+			t.logger.Debugf("archive: path “%s” not analyzed, deadline reached", path)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// sockets can't be stored in the tarball, as they only exist while the
+		// program that created them is running
+		if isSpecial(info.Mode()) && (t.SpecialFiles != SpecialFilesArchive || info.Mode()&os.ModeSocket != 0) {
+			t.logger.Warningf("archive: special file “%s”, with mode “%s”, is not going to be added to the tar", path, info.Mode())
+			return nil
+		}
+
 		header, err := tar.FileInfoHeader(info, path)
 		if err != nil {
 			return errors.WithStack(newPathError(path, PathErrorCodeCreateTARHeader, err))
 		}
 
-		// we only accept regular files and directories
-		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeDir {
+		// we only accept regular files, directories and the allowed special files
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeDir && !isSpecial(info.Mode()) {
 			t.logger.Infof("archive: path “%s”, with type “%d”, is not going to be added to the tar", path, header.Typeflag)
 			return nil
 		}
@@ -174,12 +291,27 @@ func (t TARBuilder) build(lastArchiveInfo Info, tarArchive *tar.Writer, baseDir,
 			return nil
 		}
 
-		itemInfo, add, err := t.generateItemInfo(path, lastArchiveInfo)
+		var itemInfo ItemInfo
+		var add bool
+
+		if isSpecial(info.Mode()) {
+			itemInfo, add = specialItemInfo(t.logger, path, lastArchiveInfo)
+		} else {
+			itemInfo, add, err = generateItemInfo(t.logger, t.fileSystem(), t.Changes, path, lastArchiveInfo)
+		}
+
 		if err != nil {
+			if skipPath(t.logger, t.Tolerance, path, err, lastArchiveInfo, archiveInfo) {
+				return nil
+			}
 			return errors.WithStack(err)
 		}
 		archiveInfo[path] = itemInfo
 
+		if t.Progress != nil {
+			t.Progress.OnFile(path, info.Size(), add)
+		}
+
 		if !add {
 			// TODO: if the file is ignored, we should check the directories slice to
 			// remove unnecessary entries
@@ -187,8 +319,6 @@ func (t TARBuilder) build(lastArchiveInfo Info, tarArchive *tar.Writer, baseDir,
 			return nil
 		}
 
-		hasFiles = true
-
 		// only write directory (FIFO order) when we are sure that a file will be
 		// written to the tarball. Otherwise we could have a tarball with empty
 		// directories
@@ -204,41 +334,52 @@ func (t TARBuilder) build(lastArchiveInfo Info, tarArchive *tar.Writer, baseDir,
 		// round
 		directories = nil
 
-		return errors.WithStack(t.writeTarball(path, info, header, tarArchive))
+		switch {
+		case isSpecial(info.Mode()):
+			err = t.writeSpecial(path, header, tarArchive)
+		case t.Snapshot:
+			err = t.writeSnapshot(path, header, tarArchive, &itemInfo)
+		default:
+			err = t.writeTarball(path, info, header, tarArchive, &itemInfo)
+		}
+
+		if err != nil {
+			delete(archiveInfo, path)
+			if skipPath(t.logger, t.Tolerance, path, err, lastArchiveInfo, archiveInfo) {
+				return nil
+			}
+			return errors.WithStack(err)
+		}
+
+		archiveInfo[path] = itemInfo
+		hasFiles = true
+		return nil
 	})
 
 	return archiveInfo, hasFiles, errors.WithStack(walkErr)
 }
 
-func (t TARBuilder) generateItemInfo(path string, lastArchiveInfo Info) (itemInfo ItemInfo, add bool, err error) {
-	encodedChecksum, err := t.FileChecksum(path)
-	if err != nil {
-		return itemInfo, true, errors.WithStack(err)
-	}
-
-	var ok bool
-	itemInfo, ok = lastArchiveInfo[path]
-
-	if !ok {
-		add = true
-		itemInfo.Status = ItemInfoStatusNew
-		itemInfo.Checksum = encodedChecksum
-		t.logger.Debugf("archive: path “%s” is new since the last archive", path)
-
-	} else if encodedChecksum == itemInfo.Checksum {
-		add = false // don't need to add an unmodified file to the tarball
-		itemInfo.Status = ItemInfoStatusUnmodified
-		t.logger.Debugf("archive: path “%s” unmodified since the last archive", path)
-
-	} else {
-		add = true
-		itemInfo.ID = ""
-		itemInfo.Status = ItemInfoStatusModified
-		itemInfo.Checksum = encodedChecksum
-		t.logger.Debugf("archive: path “%s” was modified since the last archive", path)
-	}
-
-	return
+// Estimate totalizes the files and bytes of the backup paths without reading
+// the files content, following the same rules of Build. On error it will return
+// an Error or PathError type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       case *archive.PathError:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t TARBuilder) Estimate(ctx context.Context, ignorePatterns []*regexp.Regexp, backupPaths ...string) (Estimate, error) {
+	return estimate(ctx, t.logger, t.fileSystem(), t.Tolerance != nil, ignorePatterns, backupPaths...)
 }
 
 // FileChecksum returns the file SHA256 hash encoded in base64. On error it will
@@ -258,22 +399,7 @@ func (t TARBuilder) generateItemInfo(path string, lastArchiveInfo Info) (itemInf
 //       }
 //     }
 func (t TARBuilder) FileChecksum(filename string) (string, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return "", errors.WithStack(newPathError(filename, PathErrorCodeOpeningFile, err))
-	}
-	defer file.Close()
-
-	hash := sha256.New()
-
-	written, err := io.Copy(hash, file)
-	if err != nil {
-		return "", errors.WithStack(newPathError(filename, PathErrorCodeSHA256, err))
-	}
-
-	encodedChecksum := base64.StdEncoding.EncodeToString(hash.Sum(nil))
-	t.logger.Debugf("archive: path “%s” hash calculated over %d bytes: %s", filename, written, encodedChecksum)
-	return encodedChecksum, nil
+	return fileChecksum(t.logger, t.fileSystem(), filename)
 }
 
 func (t TARBuilder) addInfo(archiveInfo Info, tarArchive *tar.Writer, baseDir string) error {
@@ -282,59 +408,135 @@ func (t TARBuilder) addInfo(archiveInfo Info, tarArchive *tar.Writer, baseDir st
 		return newError("", ErrorCodeEncodingInfo, err)
 	}
 
-	file, err := ioutil.TempFile("", "toglacier-")
-	if err != nil {
-		return errors.WithStack(newError("", ErrorCodeTmpFileCreation, err))
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     filepath.Join(baseDir, TARInfoFilename),
+		Mode:     0644,
+		Size:     int64(len(content)),
+		ModTime:  t.clock().Now(),
+	}
+
+	t.logger.Debugf("archive: writing tar header “%s”", header.Name)
+
+	if err := tarArchive.WriteHeader(header); err != nil {
+		return errors.WithStack(newPathError("", PathErrorCodeWritingTARHeader, err))
 	}
-	defer file.Close()
 
-	n, err := file.Write(content)
+	n, err := tarArchive.Write(content)
 	if err != nil {
 		return errors.WithStack(newPathError("", PathErrorCodeWritingFile, err))
 	}
 
-	t.logger.Debugf("archive: wrote %d bytes in archive information file “%s”", n, file.Name())
+	t.logger.Debugf("archive: wrote %d bytes in archive information file “%s”", n, header.Name)
+	return nil
+}
 
-	info, err := file.Stat()
+func (t TARBuilder) writeTarball(path string, info os.FileInfo, header *tar.Header, tarArchive *tar.Writer, itemInfo *ItemInfo) error {
+	// the file is opened before writing the header, so a file that can't be
+	// read doesn't leave an incomplete entry in the tarball
+	file, err := t.fileSystem().Open(path)
 	if err != nil {
-		return errors.WithStack(newPathError(file.Name(), PathErrorCodeInfo, err))
+		return errors.WithStack(newPathError(path, PathErrorCodeOpeningFile, err))
+	}
+	defer file.Close()
+
+	t.logger.Debugf("archive: writing tar header “%s”", header.Name)
+
+	if err := tarArchive.WriteHeader(header); err != nil {
+		return errors.WithStack(newPathError(path, PathErrorCodeWritingTARHeader, err))
 	}
 
-	header, err := tar.FileInfoHeader(info, file.Name())
+	written, err := io.CopyN(tarArchive, file, info.Size())
+	if err != nil && err != io.EOF {
+		return errors.WithStack(newPathError(path, PathErrorCodeWritingFile, err))
+	}
+
+	// the information of the path was retrieved before the file was opened, so
+	// a file replaced in the meantime is also detected
+	after, err := file.Stat()
 	if err != nil {
-		return errors.WithStack(newPathError(file.Name(), PathErrorCodeCreateTARHeader, err))
+		return errors.WithStack(newPathError(path, PathErrorCodeInfo, err))
 	}
-	header.Name = filepath.Join(baseDir, TARInfoFilename)
 
-	return errors.WithStack(t.writeTarball(file.Name(), info, header, tarArchive))
+	if fileChanged(info, after) {
+		t.logger.Warningf("archive: path “%s” changed while it was read, the archived copy is fuzzy", path)
+		itemInfo.Fuzzy = true
+	}
+
+	t.logger.Debugf("archive: path “%s” copied to tar (%d bytes)", path, written)
+	return nil
 }
 
-func (t TARBuilder) writeTarball(path string, info os.FileInfo, header *tar.Header, tarArchive *tar.Writer) error {
+// writeSpecial writes the header of the device file or FIFO, that has no
+// content.
+func (t TARBuilder) writeSpecial(path string, header *tar.Header, tarArchive *tar.Writer) error {
 	t.logger.Debugf("archive: writing tar header “%s”", header.Name)
 
 	if err := tarArchive.WriteHeader(header); err != nil {
 		return errors.WithStack(newPathError(path, PathErrorCodeWritingTARHeader, err))
 	}
 
-	file, err := os.Open(path)
+	return nil
+}
+
+// writeSnapshot writes a copy of the file read while it wasn't changing, so the
+// content in the tarball matches the checksum of the archive information.
+func (t TARBuilder) writeSnapshot(path string, header *tar.Header, tarArchive *tar.Writer, itemInfo *ItemInfo) error {
+	s, err := takeSnapshot(t.logger, t.fileSystem(), path, t.ChangeRetries)
 	if err != nil {
-		return errors.WithStack(newPathError(path, PathErrorCodeOpeningFile, err))
+		return errors.WithStack(err)
 	}
-	defer file.Close()
+	defer s.Close()
 
-	written, err := io.CopyN(tarArchive, file, info.Size())
-	if err != nil && err != io.EOF {
+	header.Size = s.size
+	header.ModTime = s.modTime
+
+	t.logger.Debugf("archive: writing tar header “%s”", header.Name)
+
+	if err = tarArchive.WriteHeader(header); err != nil {
+		return errors.WithStack(newPathError(path, PathErrorCodeWritingTARHeader, err))
+	}
+
+	written, err := io.Copy(tarArchive, s)
+	if err != nil {
 		return errors.WithStack(newPathError(path, PathErrorCodeWritingFile, err))
 	}
 
+	itemInfo.Checksum = s.checksum
+	itemInfo.Fuzzy = s.fuzzy
+
 	t.logger.Debugf("archive: path “%s” copied to tar (%d bytes)", path, written)
 	return nil
 }
 
+// fileSystem returns the file system of the backup paths, falling back to the
+// local disk.
+func (t TARBuilder) fileSystem() FileSystem {
+	if t.FileSystem == nil {
+		return osFileSystem{}
+	}
+
+	return t.FileSystem
+}
+
+// clock returns the defined clock, falling back to the system clock.
+func (t TARBuilder) clock() Clock {
+	if t.Clock == nil {
+		return realClock{}
+	}
+
+	return t.Clock
+}
+
 // Extract uncompress all files from the tarball to the current path. You can
 // select the files that are extracted with the filter parameter, if nil all
-// files are extracted. On error it will return an Error type encapsulated in a
-// traceable error. To retrieve the desired error you can do:
+// files are extracted. The files are written with temporary names and only
+// replace the existing ones, according to the Overwrite policy, after all of
+// them are extracted and their checksums are verified with the archive
+// information. The files that don't match are discarded, or none is moved with
+// the AllOrNothing option. The extraction stops when the context is cancelled.
+// On error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
 //
 //     type causer interface {
 //       Cause() error
@@ -348,7 +550,7 @@ func (t TARBuilder) writeTarball(path string, info os.FileInfo, header *tar.Head
 //         // unknown error
 //       }
 //     }
-func (t TARBuilder) Extract(filename string, filter []string) (Info, error) {
+func (t TARBuilder) Extract(ctx context.Context, filename string, filter []string) (Info, error) {
 	t.logger.Debugf("archive: extract tar %s", filename)
 
 	f, err := os.Open(filename)
@@ -357,10 +559,129 @@ func (t TARBuilder) Extract(filename string, filter []string) (Info, error) {
 	}
 	defer f.Close()
 
-	tarReader := tar.NewReader(f)
+	if _, err = skipHeader(f); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	content := bufio.NewReader(f)
+	if magicNumber, err := content.Peek(len(zipMagicNumber)); err == nil && bytes.Equal(magicNumber, zipMagicNumber) {
+		return t.zipBuilder().Extract(ctx, filename, filter)
+	}
+
+	// the files that weren't moved to their places are removed on error
+	files := t.extraction()
+	defer files.rollback()
+
+	info, err := t.extract(ctx, filename, content, filter, files)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err = files.commit(info); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return info, nil
+}
+
+// ExtractReader uncompress the files from the tarball while it is read, without
+// storing the tarball on disk. It works like Extract, and the content is read
+// until the end even when all the selected files were already extracted, so
+// readers that verify the content at the end (e.g. authentication) can detect
+// problems before the files are moved to their places. As a zip archive can't
+// be read sequentially, it is stored in a temporary file before the
+// extraction. On error it will return an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t TARBuilder) ExtractReader(ctx context.Context, r io.Reader, filter []string) (Info, error) {
+	t.logger.Debug("archive: extract tar while reading")
+
+	content := bufio.NewReader(r)
+	if magicNumber, err := content.Peek(len(zipMagicNumber)); err == nil && bytes.Equal(magicNumber, zipMagicNumber) {
+		return t.zipBuilder().ExtractReader(ctx, content, filter)
+	}
+
+	files := t.extraction()
+	defer files.rollback()
+
+	info, err := t.extract(ctx, "", content, filter, files)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// the files are only moved to their places after the content is verified
+	if _, err := io.Copy(ioutil.Discard, content); err != nil {
+		return nil, errors.WithStack(newError("", ErrorCodeReadingTAR, err))
+	}
+
+	if err = files.commit(info); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return info, nil
+}
+
+// extraction starts the extraction of the files with the builder overwrite
+// policy.
+func (t TARBuilder) extraction() *extraction {
+	return &extraction{
+		logger:        t.logger,
+		overwrite:     t.Overwrite,
+		allOrNothing:  t.AllOrNothing,
+		progress:      t.ExtractProgress,
+		normalization: t.Normalization,
+	}
+}
+
+// zipBuilder returns a ZIPBuilder with the same extraction settings, used for
+// zip archives found when extracting.
+func (t TARBuilder) zipBuilder() *ZIPBuilder {
+	builder := NewZIPBuilder(t.logger)
+	builder.Overwrite = t.Overwrite
+	builder.AllOrNothing = t.AllOrNothing
+	builder.ExtractProgress = t.ExtractProgress
+	builder.Normalization = t.Normalization
+	return builder
+}
+
+// extract uncompress the selected files of the tarball, detecting if it is
+// compressed. The files are only moved to their places when the extraction is
+// committed. The filename is only used to identify the errors.
+func (t TARBuilder) extract(ctx context.Context, filename string, content *bufio.Reader, filter []string, files *extraction) (Info, error) {
+	// detect compressed tarballs independently of the builder configuration, so
+	// backups created with a different format can still be extracted
+	var tarContent io.Reader = content
+
+	if magicNumber, err := content.Peek(len(gzipMagicNumber)); err == nil && bytes.Equal(magicNumber, gzipMagicNumber) {
+		gzipReader, err := gzip.NewReader(content)
+		if err != nil {
+			return nil, errors.WithStack(newError(filename, ErrorCodeReadingTAR, err))
+		}
+		defer gzipReader.Close()
+
+		tarContent = gzipReader
+	}
+
+	tarReader := tar.NewReader(tarContent)
 	var info Info
 
 	for {
+		if ctx.Err() != nil {
+			return nil, errors.WithStack(newError(filename, ErrorCodeCancelled, ctx.Err()))
+		}
+
 		header, err := tarReader.Next()
 
 		if err == io.EOF {
@@ -390,24 +711,26 @@ func (t TARBuilder) Extract(filename string, filter []string) (Info, error) {
 				continue
 			}
 
-			dir := filepath.Dir(header.Name)
-			if err := os.MkdirAll(dir, extractDirectoryPermission); err != nil {
-				return nil, errors.WithStack(newError(filename, ErrorCodeCreatingDirectories, err))
-			}
-
-			tarFile, err := os.OpenFile(header.Name, os.O_WRONLY|os.O_CREATE, os.FileMode(header.Mode))
+			written, err := files.write(name, header.Name, header.FileInfo().Mode(), header.ModTime, tarReader)
 			if err != nil {
-				return nil, errors.WithStack(newError(header.Name, ErrorCodeOpeningFile, err))
+				return nil, errors.WithStack(err)
 			}
 
-			written, err := io.Copy(tarFile, tarReader)
-			tarFile.Close()
+			t.logger.Debugf("archive: path “%s” extracted from tar (%d bytes)", header.Name, written)
 
-			if err != nil {
-				return nil, errors.WithStack(newError(tarFile.Name(), ErrorCodeExtractingFile, err))
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			name := normalizeHeaderName(header.Name)
+
+			if filter != nil && !shouldExtract(name, filter) {
+				t.logger.Debugf("archive: ignoring extraction of path “%s”", header.Name)
+				continue
+			}
+
+			if err := files.special(name, header.Name, header.FileInfo().Mode(), header.ModTime, header.Devmajor, header.Devminor); err != nil {
+				return nil, errors.WithStack(err)
 			}
 
-			t.logger.Debugf("archive: path “%s” extracted from tar (%d bytes)", tarFile.Name(), written)
+			t.logger.Debugf("archive: special file “%s” extracted from tar", header.Name)
 
 		default:
 			t.logger.Infof("archive: path “%s”, with type “%d”, is not going to be extracted from the tar", header.Name, header.Typeflag)
@@ -445,7 +768,7 @@ func normalizeHeaderName(name string) string {
 
 func shouldExtract(name string, filter []string) bool {
 	for _, item := range filter {
-		if name == item {
+		if samePath(name, item) {
 			return true
 		}
 	}
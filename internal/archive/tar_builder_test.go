@@ -2,10 +2,15 @@ package archive_test
 
 import (
 	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path"
@@ -14,6 +19,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/aryann/difflib"
@@ -22,12 +28,24 @@ import (
 )
 
 func TestTARBuilder_Build(t *testing.T) {
+	var progressRecorder mockProgress
+
+	cancelledDir, err := ioutil.TempDir("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary directory. details %s", err)
+	}
+
+	if err := ioutil.WriteFile(path.Join(cancelledDir, "file1"), []byte("file1 test"), 0600); err != nil {
+		t.Fatalf("error creating temporary file. details %s", err)
+	}
+
 	scenarios := []struct {
 		description         string
 		archive             *archive.TARBuilder
 		lastArchiveInfo     func(backupPaths []string) archive.Info
 		ignorePatterns      []*regexp.Regexp
 		backupPaths         []string
+		cancelled           bool
 		expected            func(filename string) error
 		expectedArchiveInfo func(backupPaths []string) archive.Info
 		expectedError       error
@@ -461,6 +479,102 @@ func TestTARBuilder_Build(t *testing.T) {
 				})
 			},
 		},
+		{
+			description: "it should create a compressed archive correctly from file path",
+			archive: archive.NewTARGzipBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}),
+			backupPaths: func() []string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString("file test")
+
+				return []string{f.Name()}
+			}(),
+			expected: func(filename string) error {
+				f, err := os.Open(filename)
+				if err != nil {
+					return fmt.Errorf("error opening archive. details: %s", err)
+				}
+				defer f.Close()
+
+				basePath := `backup-[0-9]+`
+				expectedFiles := []*regexp.Regexp{
+					regexp.MustCompile(`^` + path.Join(basePath, archive.TARInfoFilename) + `$`),
+					regexp.MustCompile(`^` + path.Join(basePath, `tmp`, `toglacier-test[0-9]+`) + `$`),
+				}
+
+				gz, err := gzip.NewReader(f)
+				if err != nil {
+					return fmt.Errorf("archive isn't compressed. details: %s", err)
+				}
+				defer gz.Close()
+
+				tr := tar.NewReader(gz)
+				for {
+					hdr, err := tr.Next()
+					if err == io.EOF {
+						break
+					} else if err != nil {
+						return err
+					}
+
+					if len(expectedFiles) == 0 {
+						return fmt.Errorf("content “%s” shouldn't be here", hdr.Name)
+					}
+
+					found := false
+					for i, expectedFile := range expectedFiles {
+						if expectedFile.MatchString(hdr.Name) {
+							expectedFiles = append(expectedFiles[:i], expectedFiles[i+1:]...)
+							found = true
+							break
+						}
+					}
+
+					if !found {
+						return fmt.Errorf("file “%s” did not match with any of the expected files", hdr.Name)
+					}
+				}
+
+				if len(expectedFiles) > 0 {
+					return errors.New("not all files were found in the archive")
+				}
+
+				return nil
+			},
+			expectedArchiveInfo: func(backupPaths []string) archive.Info {
+				return archive.Info(map[string]archive.ItemInfo{
+					path.Join(backupPaths[0]): {
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "ih/0rvVdKZfnQdoKwTj5gbNVE+Re3o7D+woelvakOiE=",
+					},
+				})
+			},
+		},
+		{
+			description: "it should stop scanning the paths when the context is cancelled",
+			archive: archive.NewTARBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}),
+			backupPaths: []string{cancelledDir},
+			cancelled:   true,
+			expectedError: &archive.Error{
+				Filename: cancelledDir,
+				Code:     archive.ErrorCodeCancelled,
+				Err:      context.Canceled,
+			},
+		},
 		{
 			description: "it should detect when the path does not exist",
 			archive: archive.NewTARBuilder(mockLogger{
@@ -580,6 +694,210 @@ func TestTARBuilder_Build(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "it should create an archive from an in-memory file system",
+			archive: func() *archive.TARBuilder {
+				builder := archive.NewTARBuilder(mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				})
+				builder.FileSystem = archive.NewIOFileSystem(fstest.MapFS{
+					"data/file1":      &fstest.MapFile{Data: []byte("file1 test"), Mode: 0600},
+					"data/dir1/file2": &fstest.MapFile{Data: []byte("file2 test"), Mode: 0600},
+				})
+				builder.Clock = fakeClock{
+					mockNow: func() time.Time {
+						return time.Date(2017, 9, 1, 10, 30, 15, 0, time.UTC)
+					},
+				}
+				return builder
+			}(),
+			backupPaths: []string{"data"},
+			expected: func(filename string) error {
+				defer os.Remove(filename)
+
+				f, err := os.Open(filename)
+				if err != nil {
+					return fmt.Errorf("error opening archive. details: %s", err)
+				}
+				defer f.Close()
+
+				tr := tar.NewReader(f)
+				var names []string
+				for {
+					hdr, err := tr.Next()
+					if err == io.EOF {
+						break
+					} else if err != nil {
+						return err
+					}
+
+					names = append(names, hdr.Name)
+				}
+
+				expectedNames := []string{
+					"backup-20170901103015/data/",
+					"backup-20170901103015/data/dir1/",
+					"backup-20170901103015/data/dir1/file2",
+					"backup-20170901103015/data/file1",
+					"backup-20170901103015/" + archive.TARInfoFilename,
+				}
+
+				if !reflect.DeepEqual(expectedNames, names) {
+					return fmt.Errorf("unexpected archive content %v", names)
+				}
+
+				return nil
+			},
+			expectedArchiveInfo: func(backupPaths []string) archive.Info {
+				return archive.Info{
+					"data/file1": {
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+					},
+					"data/dir1/file2": {
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "xZzITM+6yGsa9masWjGdi+yAA0DlqCzTf/1795fy5Pk=",
+					},
+				}
+			},
+		},
+		{
+			description: "it should report the progress while building the archive",
+			archive: func() *archive.TARBuilder {
+				builder := archive.NewTARBuilder(mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				})
+				builder.FileSystem = archive.NewIOFileSystem(fstest.MapFS{
+					"data/file1":      &fstest.MapFile{Data: []byte("file1 test"), Mode: 0600},
+					"data/dir1/file2": &fstest.MapFile{Data: []byte("file2 test"), Mode: 0600},
+				})
+				builder.Progress = &progressRecorder
+				return builder
+			}(),
+			backupPaths: []string{"data"},
+			lastArchiveInfo: func(backupPaths []string) archive.Info {
+				return archive.Info{
+					"data/file1": {
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+					},
+				}
+			},
+			expected: func(filename string) error {
+				defer os.Remove(filename)
+
+				expectedEstimate := archive.Estimate{Files: 2, Bytes: 20}
+				if progressRecorder.estimate != expectedEstimate {
+					return fmt.Errorf("unexpected estimate %+v", progressRecorder.estimate)
+				}
+
+				expectedFiles := []string{"data/dir1/file2 10 true", "data/file1 10 false"}
+				if !reflect.DeepEqual(expectedFiles, progressRecorder.files) {
+					return fmt.Errorf("unexpected files progress %v", progressRecorder.files)
+				}
+
+				return nil
+			},
+			expectedArchiveInfo: func(backupPaths []string) archive.Info {
+				return archive.Info{
+					"data/file1": {
+						Status:   archive.ItemInfoStatusUnmodified,
+						Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+					},
+					"data/dir1/file2": {
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "xZzITM+6yGsa9masWjGdi+yAA0DlqCzTf/1795fy5Pk=",
+					},
+				}
+			},
+		},
+		{
+			description: "it should only read the files modified since the last archive",
+			archive: func() *archive.TARBuilder {
+				builder := archive.NewTARBuilder(mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				})
+				builder.FileSystem = archive.NewIOFileSystem(fstest.MapFS{
+					"data/file1":      &fstest.MapFile{Data: []byte("file1 test"), Mode: 0600},
+					"data/dir1/file2": &fstest.MapFile{Data: []byte("file2 test"), Mode: 0600},
+				})
+				builder.Changes = mockChanges{
+					mockModified: func(path string) bool {
+						return path == "data/dir1/file2"
+					},
+				}
+				return builder
+			}(),
+			backupPaths: []string{"data"},
+			lastArchiveInfo: func(backupPaths []string) archive.Info {
+				return archive.Info{
+					"data/file1": {
+						ID:       "reference1",
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "checksum not verified",
+					},
+					"data/dir1/file2": {
+						ID:       "reference2",
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "old checksum",
+					},
+				}
+			},
+			expected: func(filename string) error {
+				defer os.Remove(filename)
+
+				if filename == "" {
+					return errors.New("modified file not added to the archive")
+				}
+
+				return nil
+			},
+			expectedArchiveInfo: func(backupPaths []string) archive.Info {
+				return archive.Info{
+					"data/file1": {
+						ID:       "reference1",
+						Status:   archive.ItemInfoStatusUnmodified,
+						Checksum: "checksum not verified",
+					},
+					"data/dir1/file2": {
+						Status:   archive.ItemInfoStatusModified,
+						Checksum: "xZzITM+6yGsa9masWjGdi+yAA0DlqCzTf/1795fy5Pk=",
+					},
+				}
+			},
+		},
+		{
+			description: "it should detect a missing path in the in-memory file system",
+			archive: func() *archive.TARBuilder {
+				builder := archive.NewTARBuilder(mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				})
+				builder.FileSystem = archive.NewIOFileSystem(fstest.MapFS{})
+				return builder
+			}(),
+			backupPaths: []string{"data"},
+			expectedError: &archive.PathError{
+				Path: "data",
+				Code: archive.PathErrorCodeInfo,
+				Err: &fs.PathError{
+					Op:   "open",
+					Path: "data",
+					Err:  fs.ErrNotExist,
+				},
+			},
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -591,7 +909,14 @@ func TestTARBuilder_Build(t *testing.T) {
 				lastArchiveInfo = scenario.lastArchiveInfo(backupPaths)
 			}
 
-			filename, archiveInfo, err := scenario.archive.Build(lastArchiveInfo, scenario.ignorePatterns, backupPaths...)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if scenario.cancelled {
+				cancel()
+			}
+
+			filename, archiveInfo, err := scenario.archive.Build(ctx, lastArchiveInfo, scenario.ignorePatterns, backupPaths...)
 			if scenario.expectedError == nil && scenario.expected != nil {
 				if err = scenario.expected(filename); err != nil {
 					t.Errorf("unexpected archive content (%s). details: %s", filename, err)
@@ -722,6 +1047,7 @@ func TestTARBuilder_Extract(t *testing.T) {
 		archive             *archive.TARBuilder
 		filename            string
 		filter              []string
+		cancelled           bool
 		expected            func() error
 		expectedArchiveInfo archive.Info
 		expectedError       error
@@ -871,6 +1197,148 @@ func TestTARBuilder_Extract(t *testing.T) {
 			}
 			return s
 		}(),
+		func() scenario {
+			var s scenario
+			s.description = "it should extract a compressed archive correctly"
+			s.archive = archive.NewTARBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			})
+
+			tarFile, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary file. details %s", err)
+			}
+			defer tarFile.Close()
+
+			gzipWriter := gzip.NewWriter(tarFile)
+			defer gzipWriter.Close()
+
+			tarArchive := tar.NewWriter(gzipWriter)
+			defer tarArchive.Close()
+
+			baseDir := "backup-" + time.Now().Format("20060102150405.000000000")
+			dir1 := writeDir(tarArchive, baseDir)
+			file1 := writeFile(tarArchive, filepath.Join(baseDir, dir1), "", "this is test 1")
+
+			archiveInfo := archive.Info{
+				file1: archive.ItemInfo{
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: "34dd713af2cf182e27310b36bf26254d5c75335f76a8f9ca4e0d0428c2bbf709",
+				},
+			}
+
+			archiveInfoData, err := json.Marshal(archiveInfo)
+			if err != nil {
+				t.Fatalf("error encoding archive info. details %s", err)
+			}
+			writeFile(tarArchive, baseDir, archive.TARInfoFilename, string(archiveInfoData))
+
+			s.filename = tarFile.Name()
+			s.expected = func() error {
+				filename1 := filepath.Join(baseDir, dir1, file1)
+
+				content, err := ioutil.ReadFile(filename1)
+				if err != nil {
+					return fmt.Errorf("error opening file “%s”. details: %s", filename1, err)
+				}
+
+				if string(content) != "this is test 1" {
+					return fmt.Errorf("expected content “this is test 1” and got “%s” in file “%s”", string(content), filename1)
+				}
+
+				return nil
+			}
+			s.expectedArchiveInfo = archiveInfo
+			s.clean = func() {
+				os.RemoveAll(baseDir)
+			}
+			return s
+		}(),
+		func() scenario {
+			var s scenario
+			s.description = "it should extract a zip archive correctly"
+			s.archive = archive.NewTARBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			})
+
+			zipFile, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary file. details %s", err)
+			}
+			defer zipFile.Close()
+
+			zipArchive := zip.NewWriter(zipFile)
+			defer zipArchive.Close()
+
+			baseDir := "backup-" + time.Now().Format("20060102150405.000000000")
+
+			header := &zip.FileHeader{
+				Name:   baseDir + "/file1",
+				Method: zip.Deflate,
+			}
+			header.SetMode(0600)
+
+			writer, err := zipArchive.CreateHeader(header)
+			if err != nil {
+				t.Fatalf("error writing zip header. details %s", err)
+			}
+
+			if _, err = io.WriteString(writer, "this is test 1"); err != nil {
+				t.Fatalf("error writing content to zip. details %s", err)
+			}
+
+			s.filename = zipFile.Name()
+			s.expected = func() error {
+				filename1 := filepath.Join(baseDir, "file1")
+
+				content, err := ioutil.ReadFile(filename1)
+				if err != nil {
+					return fmt.Errorf("error opening file “%s”. details: %s", filename1, err)
+				}
+
+				if string(content) != "this is test 1" {
+					return fmt.Errorf("expected content “this is test 1” and got “%s” in file “%s”", string(content), filename1)
+				}
+
+				return nil
+			}
+			s.clean = func() {
+				os.RemoveAll(baseDir)
+			}
+			return s
+		}(),
+		func() scenario {
+			var s scenario
+			s.description = "it should stop the extraction when the context is cancelled"
+			s.archive = archive.NewTARBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			})
+
+			file, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary file. details %s", err)
+			}
+			defer file.Close()
+
+			s.filename = file.Name()
+			s.cancelled = true
+			s.expectedError = &archive.Error{
+				Filename: file.Name(),
+				Code:     archive.ErrorCodeCancelled,
+				Err:      context.Canceled,
+			}
+
+			return s
+		}(),
 		{
 			description: "it should detect when the file doesn't exist",
 			archive: archive.NewTARBuilder(mockLogger{
@@ -954,7 +1422,14 @@ func TestTARBuilder_Extract(t *testing.T) {
 
 	for _, scenario := range scenarios {
 		t.Run(scenario.description, func(t *testing.T) {
-			archiveInfo, err := scenario.archive.Extract(scenario.filename, scenario.filter)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if scenario.cancelled {
+				cancel()
+			}
+
+			archiveInfo, err := scenario.archive.Extract(ctx, scenario.filename, scenario.filter)
 
 			if scenario.expected != nil {
 				if scenarioErr := scenario.expected(); scenarioErr != nil {
@@ -977,6 +1452,244 @@ func TestTARBuilder_Extract(t *testing.T) {
 	}
 }
 
+func TestTARBuilder_ExtractReader(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarning:  func(args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	scenarios := []struct {
+		description   string
+		archive       archive.Archive
+		reader        func(filename string) io.Reader
+		expectedError error
+	}{
+		{
+			description: "it should extract the selected files of a tarball while reading",
+			archive:     archive.NewTARBuilder(logger),
+		},
+		{
+			description: "it should extract the selected files of a compressed tarball while reading",
+			archive:     archive.NewTARGzipBuilder(logger),
+		},
+		{
+			description: "it should extract the selected files of a zip archive while reading",
+			archive:     archive.NewZIPBuilder(logger),
+		},
+		{
+			description: "it should detect an error reading the content",
+			archive:     archive.NewTARBuilder(logger),
+			reader: func(filename string) io.Reader {
+				content, err := ioutil.ReadFile(filename)
+				if err != nil {
+					t.Fatalf("error reading archive. details: %s", err)
+				}
+
+				return io.MultiReader(bytes.NewReader(content), mockReader{
+					mockRead: func(p []byte) (int, error) {
+						return 0, errors.New("authentication failed")
+					},
+				})
+			},
+			expectedError: &archive.Error{
+				Code: archive.ErrorCodeReadingTAR,
+				Err:  errors.New("authentication failed"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			if err = os.MkdirAll(filepath.Join(dir, "source", "dir"), 0755); err != nil {
+				t.Fatalf("error creating directory. details: %s", err)
+			}
+
+			files := map[string]string{
+				filepath.Join(dir, "source", "file1.txt"):        "file1 content",
+				filepath.Join(dir, "source", "dir", "file2.txt"): "file2 content",
+			}
+
+			for name, content := range files {
+				if err = ioutil.WriteFile(name, []byte(content), 0600); err != nil {
+					t.Fatalf("error writing file. details: %s", err)
+				}
+			}
+
+			filename, archiveInfo, err := scenario.archive.Build(context.Background(), nil, nil, filepath.Join(dir, "source"))
+			if err != nil {
+				t.Fatalf("error building archive. details: %s", err)
+			}
+			defer os.Remove(filename)
+
+			// the files are extracted in the current directory
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("error retrieving the current directory. details: %s", err)
+			}
+			defer os.Chdir(wd)
+
+			if err = os.Chdir(dir); err != nil {
+				t.Fatalf("error changing the current directory. details: %s", err)
+			}
+
+			var reader io.Reader
+			if scenario.reader != nil {
+				reader = scenario.reader(filename)
+			} else {
+				f, err := os.Open(filename)
+				if err != nil {
+					t.Fatalf("error opening archive. details: %s", err)
+				}
+				defer f.Close()
+				reader = f
+			}
+
+			selected := filepath.Join(dir, "source", "dir", "file2.txt")
+			extractor := scenario.archive.(archive.StreamExtractor)
+
+			info, err := extractor.ExtractReader(context.Background(), reader, []string{selected})
+			if !archive.ErrorEqual(scenario.expectedError, err) {
+				t.Fatalf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(archiveInfo, info) {
+				t.Errorf("archive info don't match.\n%v", Diff(archiveInfo, info))
+			}
+
+			// only the selected file should be extracted, inside the backup
+			// directory
+			var extracted []string
+			err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+
+				if info.IsDir() && path == filepath.Join(dir, "source") {
+					return filepath.SkipDir
+				}
+
+				if info.Mode().IsRegular() {
+					extracted = append(extracted, path)
+				}
+				return nil
+			})
+
+			if err != nil {
+				t.Fatalf("error listing extracted files. details: %s", err)
+			}
+
+			if len(extracted) != 1 || !strings.HasSuffix(extracted[0], selected) {
+				t.Fatalf("unexpected extracted files: %v", extracted)
+			}
+
+			if content, err := ioutil.ReadFile(extracted[0]); err != nil || string(content) != "file2 content" {
+				t.Errorf("unexpected content “%s” or error “%v” reading the extracted file", content, err)
+			}
+		})
+	}
+}
+
+func TestTARBuilder_Estimate(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		archive        *archive.TARBuilder
+		backupPaths    []string
+		ignorePatterns []*regexp.Regexp
+		cancelled      bool
+		expected       archive.Estimate
+		expectedError  error
+	}{
+		{
+			description: "it should totalize the files and bytes of the backup paths",
+			archive: func() *archive.TARBuilder {
+				builder := archive.NewTARBuilder(mockLogger{
+					mockDebugf: func(format string, args ...interface{}) {},
+				})
+				builder.FileSystem = archive.NewIOFileSystem(fstest.MapFS{
+					"data/file1":      &fstest.MapFile{Data: []byte("file1 test"), Mode: 0600},
+					"data/dir1/file2": &fstest.MapFile{Data: []byte("file2"), Mode: 0600},
+					"data/file3.tmp":  &fstest.MapFile{Data: []byte("ignored"), Mode: 0600},
+					"other/file4":     &fstest.MapFile{Data: []byte("file4 test"), Mode: 0600},
+				})
+				return builder
+			}(),
+			backupPaths:    []string{"data", "", "other"},
+			ignorePatterns: []*regexp.Regexp{regexp.MustCompile(`^.*\.tmp$`)},
+			expected:       archive.Estimate{Files: 3, Bytes: 25},
+		},
+		{
+			description: "it should detect a missing path",
+			archive: func() *archive.TARBuilder {
+				builder := archive.NewTARBuilder(mockLogger{})
+				builder.FileSystem = archive.NewIOFileSystem(fstest.MapFS{})
+				return builder
+			}(),
+			backupPaths: []string{"data"},
+			expectedError: &archive.PathError{
+				Path: "data",
+				Code: archive.PathErrorCodeInfo,
+				Err: &fs.PathError{
+					Op:   "open",
+					Path: "data",
+					Err:  fs.ErrNotExist,
+				},
+			},
+		},
+		{
+			description: "it should stop when the context is cancelled",
+			archive: func() *archive.TARBuilder {
+				builder := archive.NewTARBuilder(mockLogger{})
+				builder.FileSystem = archive.NewIOFileSystem(fstest.MapFS{
+					"data/file1": &fstest.MapFile{Data: []byte("file1 test"), Mode: 0600},
+				})
+				return builder
+			}(),
+			backupPaths: []string{"data"},
+			cancelled:   true,
+			expectedError: &archive.Error{
+				Filename: "data",
+				Code:     archive.ErrorCodeCancelled,
+				Err:      context.Canceled,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if scenario.cancelled {
+				cancel()
+			}
+
+			estimate, err := scenario.archive.Estimate(ctx, scenario.ignorePatterns, scenario.backupPaths...)
+
+			if scenario.expected != estimate {
+				t.Errorf("estimates don't match. expected “%+v” and got “%+v”", scenario.expected, estimate)
+			}
+
+			if !archive.ErrorEqual(scenario.expectedError, err) && !archive.PathErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
 func TestTARBuilder_FileChecksum(t *testing.T) {
 	scenarios := []struct {
 		description   string
@@ -1042,6 +1755,63 @@ func TestTARBuilder_FileChecksum(t *testing.T) {
 	}
 }
 
+func BenchmarkTARBuilder_Build(b *testing.B) {
+	builders := []struct {
+		description string
+		archive     *archive.TARBuilder
+	}{
+		{description: "tar", archive: archive.NewTARBuilder(newBenchmarkLogger())},
+		{description: "tar+gzip", archive: archive.NewTARGzipBuilder(newBenchmarkLogger())},
+	}
+
+	for _, dataset := range benchmarkDatasets {
+		dir := dataset.generate(b)
+		defer os.RemoveAll(dir)
+
+		for _, builder := range builders {
+			b.Run(builder.description+" with "+dataset.description, func(b *testing.B) {
+				b.SetBytes(dataset.totalSize())
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					filename, _, err := builder.archive.Build(context.Background(), nil, nil, dir)
+					if err != nil {
+						b.Fatalf("error building archive. details %s", err)
+					}
+					os.Remove(filename)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkTARBuilder_FileChecksum(b *testing.B) {
+	tarBuilder := archive.NewTARBuilder(newBenchmarkLogger())
+
+	for _, dataset := range benchmarkDatasets {
+		dir := dataset.generate(b)
+		defer os.RemoveAll(dir)
+
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			b.Fatalf("error reading directory. details %s", err)
+		}
+
+		b.Run(dataset.description, func(b *testing.B) {
+			b.SetBytes(dataset.totalSize())
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				for _, file := range files {
+					if _, err := tarBuilder.FileChecksum(path.Join(dir, file.Name())); err != nil {
+						b.Fatalf("error calculating checksum. details %s", err)
+					}
+				}
+			}
+		})
+	}
+}
+
 type mockLogger struct {
 	mockDebug    func(args ...interface{})
 	mockDebugf   func(format string, args ...interface{})
@@ -1075,6 +1845,37 @@ func (m mockLogger) Warningf(format string, args ...interface{}) {
 	m.mockWarningf(format, args...)
 }
 
+type mockProgress struct {
+	estimate  archive.Estimate
+	files     []string
+	extracted []string
+	failed    []string
+}
+
+func (m *mockProgress) OnEstimate(estimate archive.Estimate) {
+	m.estimate = estimate
+}
+
+func (m *mockProgress) OnFile(path string, size int64, added bool) {
+	m.files = append(m.files, fmt.Sprintf("%s %d %t", path, size, added))
+}
+
+func (m *mockProgress) OnExtract(path string, size int64, extracted bool) {
+	m.extracted = append(m.extracted, fmt.Sprintf("%s %d %t", path, size, extracted))
+}
+
+func (m *mockProgress) OnExtractFailure(path string, err error) {
+	m.failed = append(m.failed, path)
+}
+
+type fakeClock struct {
+	mockNow func() time.Time
+}
+
+func (f fakeClock) Now() time.Time {
+	return f.mockNow()
+}
+
 // Diff is useful to see the difference when comparing two complex types.
 func Diff(a, b interface{}) []difflib.DiffRecord {
 	return difflib.Diff(strings.SplitAfter(spew.Sdump(a), "\n"), strings.SplitAfter(spew.Sdump(b), "\n"))
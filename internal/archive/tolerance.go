@@ -0,0 +1,70 @@
+package archive
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// PathTolerance decides what happens with the files that can't be read while
+// the archive is built (e.g. permission denied or removed during the backup).
+type PathTolerance interface {
+	// OnPathError is called when the file can't be read. When it returns true
+	// the file is left out of the archive and the build continues, otherwise
+	// the build fails with the error.
+	OnPathError(path string, err error) bool
+}
+
+// skipPath informs if the file that couldn't be read is left out of the
+// archive. Only the errors reading the files can be tolerated, as the other
+// errors (e.g. writing the archive) would fail for the following files too.
+// When the file (or the files of the directory) was in the last archive its
+// information is kept.
+func skipPath(logger log.Logger, tolerance PathTolerance, path string, pathErr error, lastArchiveInfo, archiveInfo Info) bool {
+	if tolerance == nil {
+		return false
+	}
+
+	specificErr, ok := errors.Cause(pathErr).(*PathError)
+	if !ok {
+		return false
+	}
+
+	switch specificErr.Code {
+	case PathErrorCodeInfo, PathErrorCodeOpeningFile, PathErrorCodeSHA256, PathErrorCodeReadingFile:
+	default:
+		return false
+	}
+
+	if !tolerance.OnPathError(path, pathErr) {
+		return false
+	}
+
+	logger.Warningf("archive: path “%s” skipped. details: %s", path, pathErr)
+	keepLastInfo(path, lastArchiveInfo, archiveInfo)
+	return true
+}
+
+// keepLastInfo keeps the information of the last archive for the path (or the
+// files of the directory) that wasn't analyzed, so it isn't considered
+// removed.
+func keepLastInfo(path string, lastArchiveInfo, archiveInfo Info) {
+	dirPrefix := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+	for lastPath, lastItemInfo := range lastArchiveInfo {
+		if lastPath != path && !strings.HasPrefix(lastPath, dirPrefix) {
+			continue
+		}
+
+		if _, ok := archiveInfo[lastPath]; ok {
+			// already analyzed before the error
+			continue
+		}
+
+		if lastItemInfo.Status != ItemInfoStatusDeleted {
+			lastItemInfo.Status = ItemInfoStatusUnmodified
+			archiveInfo[lastPath] = lastItemInfo
+		}
+	}
+}
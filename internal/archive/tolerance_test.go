@@ -0,0 +1,224 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+func TestPathTolerance(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	fileSystem := unreadableFileSystem{
+		FileSystem: archive.NewIOFileSystem(fstest.MapFS{
+			"data/file1": &fstest.MapFile{Data: []byte("file1 test"), Mode: 0600},
+			"data/file2": &fstest.MapFile{Data: []byte("file2 test"), Mode: 0600},
+			"data/file3": &fstest.MapFile{Data: []byte("file3 test"), Mode: 0600},
+		}),
+		unreadable: map[string]bool{
+			"data/file2": true,
+			"data/file3": true,
+		},
+	}
+
+	lastArchiveInfo := archive.Info{
+		"data/file2": archive.ItemInfo{
+			ID:       "AWSID122",
+			Status:   archive.ItemInfoStatusNew,
+			Checksum: "file2 checksum",
+		},
+	}
+
+	builders := map[string]func(tolerance archive.PathTolerance) archive.Archive{
+		"tar": func(tolerance archive.PathTolerance) archive.Archive {
+			builder := archive.NewTARBuilder(logger)
+			builder.FileSystem = fileSystem
+			builder.Tolerance = tolerance
+			return builder
+		},
+		"zip": func(tolerance archive.PathTolerance) archive.Archive {
+			builder := archive.NewZIPBuilder(logger)
+			builder.FileSystem = fileSystem
+			builder.Tolerance = tolerance
+			return builder
+		},
+	}
+
+	scenarios := []struct {
+		description         string
+		tolerance           *mockTolerance
+		expectedSkipped     []string
+		expectedFiles       []string
+		expectedArchiveInfo archive.Info
+		expectedError       bool
+	}{
+		{
+			description:     "it should skip the unreadable files",
+			tolerance:       &mockTolerance{limit: 2},
+			expectedSkipped: []string{"data/file2", "data/file3"},
+			expectedFiles:   []string{"data/file1"},
+			expectedArchiveInfo: archive.Info{
+				"data/file1": archive.ItemInfo{
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+				},
+				"data/file2": archive.ItemInfo{
+					ID:       "AWSID122",
+					Status:   archive.ItemInfoStatusUnmodified,
+					Checksum: "file2 checksum",
+				},
+			},
+		},
+		{
+			description:     "it should fail when the tolerance is exceeded",
+			tolerance:       &mockTolerance{limit: 1},
+			expectedSkipped: []string{"data/file2"},
+			expectedError:   true,
+		},
+		{
+			description:   "it should fail on the first unreadable file without tolerance",
+			expectedError: true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		for name, builder := range builders {
+			t.Run(fmt.Sprintf("%s (%s)", scenario.description, name), func(t *testing.T) {
+				var tolerance archive.PathTolerance
+				if scenario.tolerance != nil {
+					scenario.tolerance.skipped = nil
+					tolerance = scenario.tolerance
+				}
+
+				filename, archiveInfo, err := builder(tolerance).Build(context.Background(), lastArchiveInfo, nil, "data")
+				if filename != "" {
+					defer os.Remove(filename)
+				}
+
+				if scenario.expectedError != (err != nil) {
+					t.Fatalf("unexpected error “%v”", err)
+				}
+
+				if scenario.tolerance != nil && !reflect.DeepEqual(scenario.expectedSkipped, scenario.tolerance.skipped) {
+					t.Errorf("skipped files don't match.\n%s", Diff(scenario.expectedSkipped, scenario.tolerance.skipped))
+				}
+
+				if err != nil {
+					return
+				}
+
+				if !reflect.DeepEqual(scenario.expectedArchiveInfo, archiveInfo) {
+					t.Errorf("archive info don't match.\n%s", Diff(scenario.expectedArchiveInfo, archiveInfo))
+				}
+
+				files, err := archiveFiles(name, filename)
+				if err != nil {
+					t.Fatalf("error reading archive. details: %s", err)
+				}
+
+				if !reflect.DeepEqual(scenario.expectedFiles, files) {
+					t.Errorf("archive files don't match.\n%s", Diff(scenario.expectedFiles, files))
+				}
+			})
+		}
+	}
+}
+
+// archiveFiles lists the regular files of the archive, without the base
+// directory.
+func archiveFiles(format, filename string) ([]string, error) {
+	var names []string
+
+	switch format {
+	case "tar":
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		tarReader := tar.NewReader(f)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+
+			if header.Typeflag == tar.TypeReg {
+				names = append(names, header.Name)
+			}
+		}
+
+	case "zip":
+		zipReader, err := zip.OpenReader(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer zipReader.Close()
+
+		for _, zipItem := range zipReader.File {
+			if zipItem.Mode().IsRegular() {
+				names = append(names, zipItem.Name)
+			}
+		}
+	}
+
+	var files []string
+	for _, name := range names {
+		// drop the base directory (e.g. backup-20170901103015)
+		parts := strings.SplitN(name, "/", 2)
+		if len(parts) == 2 && parts[1] != archive.TARInfoFilename {
+			files = append(files, parts[1])
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+type mockTolerance struct {
+	limit   int
+	skipped []string
+}
+
+func (m *mockTolerance) OnPathError(path string, err error) bool {
+	if len(m.skipped) >= m.limit {
+		return false
+	}
+
+	m.skipped = append(m.skipped, path)
+	return true
+}
+
+// unreadableFileSystem denies access to the content of some files.
+type unreadableFileSystem struct {
+	archive.FileSystem
+	unreadable map[string]bool
+}
+
+func (u unreadableFileSystem) Open(name string) (fs.File, error) {
+	if u.unreadable[name] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+
+	return u.FileSystem.Open(name)
+}
@@ -0,0 +1,274 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// Changes are the files modified since the last backup, so the builders only
+// read the content of these files instead of calculating the checksum of all
+// files of the backup paths.
+type Changes interface {
+	// Modified informs if the file was modified since the changes were cleared.
+	// When the changes are unknown (e.g. lost notifications) all files are
+	// considered modified.
+	Modified(path string) bool
+
+	// Clear forgets the changes detected before the given moment, after they
+	// were stored in a backup.
+	Clear(until time.Time)
+}
+
+// Watcher detects the files modified in the backup paths using the file
+// system notifications (inotify, kqueue or ReadDirectoryChangesW). Until the
+// first Clear the changes are unknown, as files could be modified before the
+// watcher started.
+type Watcher struct {
+	logger         log.Logger
+	ignorePatterns []*regexp.Regexp
+	roots          []string
+	notifier       *fsnotify.Watcher
+	done           chan struct{}
+
+	modifiedLock sync.Mutex
+	modified     map[string]time.Time
+
+	// unknownSince is the moment when notifications were lost. When zero all
+	// changes are known.
+	unknownSince time.Time
+
+	// unwatched is set when a directory couldn't be watched, so the changes
+	// are never known again.
+	unwatched bool
+}
+
+// NewWatcher starts watching the backup paths recursively, ignoring the paths
+// that match the ignore patterns. The Close method must be called to release
+// the resources. On error it will return an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func NewWatcher(logger log.Logger, ignorePatterns []*regexp.Regexp, backupPaths ...string) (*Watcher, error) {
+	notifier, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.WithStack(newError("", ErrorCodeWatching, err))
+	}
+
+	w := &Watcher{
+		logger:         logger,
+		ignorePatterns: ignorePatterns,
+		notifier:       notifier,
+		done:           make(chan struct{}),
+		modified:       make(map[string]time.Time),
+		unknownSince:   time.Now(),
+	}
+
+	for _, backupPath := range backupPaths {
+		if backupPath == "" {
+			continue
+		}
+
+		if err := w.watch(backupPath, false); err != nil {
+			notifier.Close()
+			return nil, errors.WithStack(err)
+		}
+		w.roots = append(w.roots, filepath.Clean(backupPath))
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// watch adds the directories of the path to the notifier. When the path was
+// just created the files are marked as modified, as they could be written
+// before the directory was watched.
+func (w *Watcher) watch(root string, created bool) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && created {
+				// removed right after it was created
+				return nil
+			}
+			return errors.WithStack(newError(path, ErrorCodeWatching, err))
+		}
+
+		if w.ignored(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.IsDir() {
+			if created {
+				w.modifiedLock.Lock()
+				w.modified[path] = time.Now()
+				w.modifiedLock.Unlock()
+			}
+			return nil
+		}
+
+		if err := w.notifier.Add(path); err != nil {
+			return errors.WithStack(newError(path, ErrorCodeWatching, err))
+		}
+
+		w.logger.Debugf("archive: watching directory “%s”", path)
+		return nil
+	})
+}
+
+// ignored informs if the path matches any of the ignore patterns.
+func (w *Watcher) ignored(path string) bool {
+	for _, ignorePattern := range w.ignorePatterns {
+		if ignorePattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// run records the notifications until the watcher is closed.
+func (w *Watcher) run() {
+	defer close(w.done)
+
+	for {
+		select {
+		case event, ok := <-w.notifier.Events:
+			if !ok {
+				return
+			}
+			w.notify(event)
+
+		case err, ok := <-w.notifier.Errors:
+			if !ok {
+				return
+			}
+
+			// notifications could be lost (e.g. queue overflow), so all files will
+			// be analyzed in the next backup
+			w.logger.Warningf("archive: error watching the backup paths, all files will be analyzed in the next backup. details: %s", err)
+
+			w.modifiedLock.Lock()
+			w.unknownSince = time.Now()
+			w.modifiedLock.Unlock()
+		}
+	}
+}
+
+// notify records the path of the notification as modified, watching the new
+// directories.
+func (w *Watcher) notify(event fsnotify.Event) {
+	// permission changes don't modify the content
+	if event.Op == fsnotify.Chmod || w.ignored(event.Name) {
+		return
+	}
+
+	w.logger.Debugf("archive: path “%s” modified (%s)", event.Name, event.Op)
+
+	w.modifiedLock.Lock()
+	w.modified[event.Name] = time.Now()
+	w.modifiedLock.Unlock()
+
+	if event.Op&fsnotify.Create == 0 {
+		return
+	}
+
+	if info, err := os.Stat(event.Name); err != nil || !info.IsDir() {
+		return
+	}
+
+	if err := w.watch(event.Name, true); err != nil {
+		w.logger.Warningf("archive: failed to watch the new directory, all files will be analyzed in the next backups. details: %s", err)
+
+		w.modifiedLock.Lock()
+		w.unwatched = true
+		w.modifiedLock.Unlock()
+	}
+}
+
+// Modified informs if the file was modified since the changes were cleared.
+// When the changes are unknown, or the file is outside the watched paths (e.g.
+// output of sources), the file is considered modified.
+func (w *Watcher) Modified(path string) bool {
+	w.modifiedLock.Lock()
+	defer w.modifiedLock.Unlock()
+
+	if w.unwatched || !w.unknownSince.IsZero() || !w.watched(path) {
+		return true
+	}
+
+	_, ok := w.modified[path]
+	return ok
+}
+
+// watched informs if the path is inside one of the watched paths.
+func (w *Watcher) watched(path string) bool {
+	path = filepath.Clean(path)
+	for _, root := range w.roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clear forgets the changes detected before the given moment, after they were
+// stored in a backup. The changes become known when the notifications were
+// lost before the given moment, as all files were analyzed in the backup.
+func (w *Watcher) Clear(until time.Time) {
+	w.modifiedLock.Lock()
+	defer w.modifiedLock.Unlock()
+
+	for path, modifiedAt := range w.modified {
+		if modifiedAt.Before(until) {
+			delete(w.modified, path)
+		}
+	}
+
+	if !w.unknownSince.IsZero() && w.unknownSince.Before(until) {
+		w.unknownSince = time.Time{}
+	}
+}
+
+// Close stops watching the backup paths. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (w *Watcher) Close() error {
+	if err := w.notifier.Close(); err != nil {
+		return errors.WithStack(newError("", ErrorCodeWatching, err))
+	}
+
+	<-w.done
+	return nil
+}
@@ -0,0 +1,160 @@
+package archive_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+func TestWatcher(t *testing.T) {
+	writeFile := func(t *testing.T, filename string) {
+		if err := ioutil.WriteFile(filename, []byte("modified"), 0600); err != nil {
+			t.Fatalf("error writing file. details: %s", err)
+		}
+	}
+
+	// the notifications are asynchronous
+	waitModified := func(t *testing.T, watcher *archive.Watcher, filename string) {
+		for i := 0; i < 100 && !watcher.Modified(filename); i++ {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	scenarios := []struct {
+		description    string
+		ignorePatterns []*regexp.Regexp
+		action         func(t *testing.T, dir string, watcher *archive.Watcher)
+		expected       map[string]bool
+	}{
+		{
+			description: "it should consider all files modified before the first clear",
+			action:      func(t *testing.T, dir string, watcher *archive.Watcher) {},
+			expected: map[string]bool{
+				"file1":      true,
+				"dir1/file2": true,
+			},
+		},
+		{
+			description: "it should detect a modified file",
+			action: func(t *testing.T, dir string, watcher *archive.Watcher) {
+				watcher.Clear(time.Now())
+				writeFile(t, path.Join(dir, "dir1", "file2"))
+				waitModified(t, watcher, path.Join(dir, "dir1", "file2"))
+			},
+			expected: map[string]bool{
+				"file1":      false,
+				"dir1/file2": true,
+			},
+		},
+		{
+			description: "it should detect the files of a new directory",
+			action: func(t *testing.T, dir string, watcher *archive.Watcher) {
+				watcher.Clear(time.Now())
+				if err := os.Mkdir(path.Join(dir, "dir2"), 0700); err != nil {
+					t.Fatalf("error creating directory. details: %s", err)
+				}
+				waitModified(t, watcher, path.Join(dir, "dir2"))
+
+				writeFile(t, path.Join(dir, "dir2", "file3"))
+				waitModified(t, watcher, path.Join(dir, "dir2", "file3"))
+			},
+			expected: map[string]bool{
+				"file1":      false,
+				"dir2/file3": true,
+			},
+		},
+		{
+			description:    "it should ignore the files that match the ignore patterns",
+			ignorePatterns: []*regexp.Regexp{regexp.MustCompile(`file1$`)},
+			action: func(t *testing.T, dir string, watcher *archive.Watcher) {
+				watcher.Clear(time.Now())
+				writeFile(t, path.Join(dir, "file1"))
+				writeFile(t, path.Join(dir, "dir1", "file2"))
+				waitModified(t, watcher, path.Join(dir, "dir1", "file2"))
+			},
+			expected: map[string]bool{
+				"file1":      false,
+				"dir1/file2": true,
+			},
+		},
+		{
+			description: "it should consider modified the files outside the watched paths",
+			action: func(t *testing.T, dir string, watcher *archive.Watcher) {
+				watcher.Clear(time.Now())
+			},
+			expected: map[string]bool{
+				"file1":             false,
+				"../toglacier-file": true,
+			},
+		},
+		{
+			description: "it should forget the changes stored in a backup",
+			action: func(t *testing.T, dir string, watcher *archive.Watcher) {
+				watcher.Clear(time.Now())
+				writeFile(t, path.Join(dir, "file1"))
+				waitModified(t, watcher, path.Join(dir, "file1"))
+				watcher.Clear(time.Now())
+			},
+			expected: map[string]bool{
+				"file1":      false,
+				"dir1/file2": false,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			if err := os.Mkdir(path.Join(dir, "dir1"), 0700); err != nil {
+				t.Fatalf("error creating directory. details: %s", err)
+			}
+			writeFile(t, path.Join(dir, "file1"))
+			writeFile(t, path.Join(dir, "dir1", "file2"))
+
+			watcher, err := archive.NewWatcher(mockLogger{
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			}, scenario.ignorePatterns, dir)
+
+			if err != nil {
+				t.Fatalf("error creating the watcher. details: %s", err)
+			}
+			defer watcher.Close()
+
+			scenario.action(t, dir, watcher)
+
+			modified := make(map[string]bool)
+			for filename := range scenario.expected {
+				modified[filename] = watcher.Modified(path.Join(dir, filename))
+			}
+
+			if !reflect.DeepEqual(scenario.expected, modified) {
+				t.Errorf("modified files don't match.\n%s", Diff(scenario.expected, modified))
+			}
+		})
+	}
+}
+
+type mockChanges struct {
+	mockModified func(path string) bool
+	mockClear    func(until time.Time)
+}
+
+func (m mockChanges) Modified(path string) bool {
+	return m.mockModified(path)
+}
+
+func (m mockChanges) Clear(until time.Time) {
+	m.mockClear(until)
+}
@@ -0,0 +1,657 @@
+package archive
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// zipMagicNumber identifies the beginning of a zip file (local file header).
+var zipMagicNumber = []byte{'P', 'K', 0x03, 0x04}
+
+// ZIPBuilder join all paths into a compressed zip archive. Zip files can be
+// opened in most operating systems without extra tools, what makes it easier
+// to restore backups on Windows. Large archives are written using the zip64
+// extension automatically.
+type ZIPBuilder struct {
+	logger log.Logger
+
+	// FileSystem gives access to the backup paths. When not defined the local
+	// disk is used.
+	FileSystem FileSystem
+
+	// Clock defines the name of the archive root directory and the modification
+	// time of the control file. When not defined the system clock is used.
+	Clock Clock
+
+	// Progress receives the estimate of the backup paths and each analyzed
+	// file. When not defined the progress isn't reported.
+	Progress Progress
+
+	// Changes informs the files modified since the last archive, so the
+	// checksum of the other files isn't calculated. When not defined all files
+	// are analyzed.
+	Changes Changes
+
+	// Overwrite defines what happens with the existing files when extracting
+	// the zip. By default they are replaced.
+	Overwrite OverwritePolicy
+
+	// ExtractProgress receives the result of each extracted file. When not
+	// defined the extraction isn't reported.
+	ExtractProgress ExtractProgress
+
+	// AllOrNothing keeps all existing files when any extracted file doesn't
+	// match its checksum. By default only the mismatching files are discarded.
+	AllOrNothing bool
+
+	// Normalization defines the Unicode normalization form of the names of the
+	// files extracted from the zip. By default the names are kept.
+	Normalization Normalization
+
+	// Tolerance decides if the files that can't be read are left out of the
+	// zip. When not defined the build fails on the first unreadable file.
+	Tolerance PathTolerance
+
+	// Deadline stops the analysis of the files when reached, building the zip
+	// with the files analyzed until then. When not defined all files are
+	// analyzed.
+	Deadline Deadline
+
+	// Snapshot copies each file to a temporary file before it is added, so a
+	// file that changes while it is read can be read again. By default the
+	// files are added directly, and the ones that change while they are added
+	// are marked as fuzzy in the archive information.
+	Snapshot bool
+
+	// ChangeRetries is the number of times a file that changes while it is
+	// copied to the temporary file is read again, when Snapshot is enabled.
+	// When it keeps changing the last copy is added and marked as fuzzy in the
+	// archive information.
+	ChangeRetries int
+}
+
+// NewZIPBuilder returns a ZIPBuilder with all necessary initializations.
+func NewZIPBuilder(logger log.Logger) *ZIPBuilder {
+	return &ZIPBuilder{
+		logger: logger,
+	}
+}
+
+// Build builds a zip archive containing all the desired files that you want to
+// backup. A control file is added to the archive root so we can control
+// incremental archives (send only what was modified). Files and directories can
+// be ignores in the backupPaths using the regular expressions in the
+// ignorePatterns parameter. The scan of the backup paths stops when the context
+// is cancelled. If no file was written to the archive, an empty filename is
+// returned. On error it will return an Error or PathError type encapsulated in
+// a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       case *archive.PathError:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (z ZIPBuilder) Build(ctx context.Context, lastArchiveInfo Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, Info, error) {
+	z.logger.Debugf("archive: build zip for backup paths %v", backupPaths)
+
+	if z.Progress != nil {
+		estimate, err := z.Estimate(ctx, ignorePatterns, backupPaths...)
+		if err != nil {
+			return "", nil, errors.WithStack(err)
+		}
+		z.Progress.OnEstimate(estimate)
+	}
+
+	zipFile, err := ioutil.TempFile("", "toglacier-")
+	if err != nil {
+		return "", nil, errors.WithStack(newError("", ErrorCodeTmpFileCreation, err))
+	}
+	defer zipFile.Close()
+
+	zipArchive := zip.NewWriter(zipFile)
+	basePath := "backup-" + z.clock().Now().Format("20060102150405")
+
+	archiveInfo := make(Info)
+	hasFiles := false
+	for _, path := range backupPaths {
+		if path == "" {
+			z.logger.Info("archive: empty backup path ignored")
+			continue
+		}
+
+		z.logger.Debugf("archive: analyzing backup path “%s”", path)
+
+		tmpArchiveInfo, tmpHasFiles, err := z.build(ctx, lastArchiveInfo, zipArchive, basePath, path, ignorePatterns)
+		if err != nil {
+			return "", nil, errors.WithStack(err)
+		}
+		archiveInfo.Merge(tmpArchiveInfo)
+
+		if tmpHasFiles {
+			hasFiles = true
+		}
+	}
+
+	// if there're no files in the zip there's no reason to create this backup
+	if hasFiles {
+		archiveInfo.MergeLast(lastArchiveInfo)
+		if err := z.addInfo(archiveInfo, zipArchive, basePath); err != nil {
+			return "", nil, errors.WithStack(err)
+		}
+
+		statistic := archiveInfo.Statistics()
+		z.logger.Infof("archive: %d new files; %d modified files; %d unmodified files; %d deleted files",
+			statistic[ItemInfoStatusNew],
+			statistic[ItemInfoStatusModified],
+			statistic[ItemInfoStatusUnmodified],
+			statistic[ItemInfoStatusDeleted],
+		)
+	}
+
+	if err := zipArchive.Close(); err != nil {
+		return "", nil, errors.WithStack(newError(zipFile.Name(), ErrorCodeZIPGeneration, err))
+	}
+
+	if !hasFiles {
+		// force fd close to remove the empty zip.
+		zipFile.Close()
+		os.Remove(zipFile.Name())
+
+		z.logger.Info("archive: zip file not created because no files were added")
+		return "", nil, nil
+	}
+
+	z.logger.Infof("archive: zip file “%s” created successfully", zipFile.Name())
+	return zipFile.Name(), archiveInfo, nil
+}
+
+func (z ZIPBuilder) build(ctx context.Context, lastArchiveInfo Info, zipArchive *zip.Writer, baseDir, source string, ignorePatterns []*regexp.Regexp) (archiveInfo Info, hasFiles bool, err error) {
+	var directories []*zip.FileHeader
+	archiveInfo = make(Info)
+
+	walkErr := z.fileSystem().Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			err = errors.WithStack(newPathError(path, PathErrorCodeInfo, err))
+			if skipPath(z.logger, z.Tolerance, path, err, lastArchiveInfo, archiveInfo) {
+				return nil
+			}
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return errors.WithStack(newError(path, ErrorCodeCancelled, ctx.Err()))
+		}
+
+		z.logger.Debugf("archive: walking into path “%s”", path)
+
+		for _, ignorePattern := range ignorePatterns {
+			if ignorePattern.MatchString(path) {
+				z.logger.Infof("archive: path “%s” ignored", path)
+				return nil
+			}
+		}
+
+		if deadlineReached(z.Deadline, path, lastArchiveInfo, archiveInfo) {
+			z.logger.Debugf("archive: path “%s” not analyzed, deadline reached", path)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// zip can't store special files (devices, sockets and FIFOs)
+		if isSpecial(info.Mode()) {
+			z.logger.Warningf("archive: special file “%s”, with mode “%s”, is not going to be added to the zip", path, info.Mode())
+			return nil
+		}
+
+		// we only accept regular files and directories
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			z.logger.Infof("archive: path “%s”, with mode “%s”, is not going to be added to the zip", path, info.Mode())
+			return nil
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return errors.WithStack(newPathError(path, PathErrorCodeCreateZIPHeader, err))
+		}
+
+		// store the full path in the zip to avoid conflicts when appending
+		// multiple backup paths. In Windows environment we need to drop the volume
+		// letter before joining the path. Zip always use slash as a path separator
+		header.Name = filepath.ToSlash(filepath.Join(baseDir, volumeLetterRX.ReplaceAllString(path, "")))
+
+		if info.IsDir() {
+			header.Name += "/"
+
+			// forward directory creation to when a file is written
+			directories = append(directories, header)
+			return nil
+		}
+
+		itemInfo, add, err := generateItemInfo(z.logger, z.fileSystem(), z.Changes, path, lastArchiveInfo)
+		if err != nil {
+			if skipPath(z.logger, z.Tolerance, path, err, lastArchiveInfo, archiveInfo) {
+				return nil
+			}
+			return errors.WithStack(err)
+		}
+		archiveInfo[path] = itemInfo
+
+		if z.Progress != nil {
+			z.Progress.OnFile(path, info.Size(), add)
+		}
+
+		if !add {
+			z.logger.Debugf("archive: path “%s” ignored", path)
+			return nil
+		}
+
+		// only write directory (FIFO order) when we are sure that a file will be
+		// written to the zip. Otherwise we could have a zip with empty directories
+		for _, directory := range directories {
+			z.logger.Debugf("archive: writing zip header for directory “%s”", directory.Name)
+
+			if _, err = zipArchive.CreateHeader(directory); err != nil {
+				return errors.WithStack(newPathError(path, PathErrorCodeWritingZIPHeader, err))
+			}
+		}
+
+		// after the directories are created, we can clear the slice for the next
+		// round
+		directories = nil
+
+		header.Method = zip.Deflate
+		if z.Snapshot {
+			err = z.writeSnapshot(path, header, zipArchive, &itemInfo)
+		} else {
+			err = z.writeZip(path, info, header, zipArchive, &itemInfo)
+		}
+
+		if err != nil {
+			delete(archiveInfo, path)
+			if skipPath(z.logger, z.Tolerance, path, err, lastArchiveInfo, archiveInfo) {
+				return nil
+			}
+			return errors.WithStack(err)
+		}
+
+		archiveInfo[path] = itemInfo
+		hasFiles = true
+		return nil
+	})
+
+	return archiveInfo, hasFiles, errors.WithStack(walkErr)
+}
+
+// Estimate totalizes the files and bytes of the backup paths without reading
+// the files content, following the same rules of Build. On error it will return
+// an Error or PathError type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       case *archive.PathError:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (z ZIPBuilder) Estimate(ctx context.Context, ignorePatterns []*regexp.Regexp, backupPaths ...string) (Estimate, error) {
+	return estimate(ctx, z.logger, z.fileSystem(), z.Tolerance != nil, ignorePatterns, backupPaths...)
+}
+
+// FileChecksum returns the file SHA256 hash encoded in base64. On error it will
+// return a PathError type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.PathError:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (z ZIPBuilder) FileChecksum(filename string) (string, error) {
+	return fileChecksum(z.logger, z.fileSystem(), filename)
+}
+
+func (z ZIPBuilder) addInfo(archiveInfo Info, zipArchive *zip.Writer, baseDir string) error {
+	content, err := json.Marshal(archiveInfo)
+	if err != nil {
+		return newError("", ErrorCodeEncodingInfo, err)
+	}
+
+	header := &zip.FileHeader{
+		Name:   filepath.ToSlash(filepath.Join(baseDir, TARInfoFilename)),
+		Method: zip.Deflate,
+	}
+	header.Modified = z.clock().Now()
+	header.SetMode(0644)
+
+	z.logger.Debugf("archive: writing zip header “%s”", header.Name)
+
+	writer, err := zipArchive.CreateHeader(header)
+	if err != nil {
+		return errors.WithStack(newPathError("", PathErrorCodeWritingZIPHeader, err))
+	}
+
+	n, err := writer.Write(content)
+	if err != nil {
+		return errors.WithStack(newPathError("", PathErrorCodeWritingFile, err))
+	}
+
+	z.logger.Debugf("archive: wrote %d bytes in archive information file “%s”", n, header.Name)
+	return nil
+}
+
+func (z ZIPBuilder) writeZip(path string, info os.FileInfo, header *zip.FileHeader, zipArchive *zip.Writer, itemInfo *ItemInfo) error {
+	// the file is opened before writing the header, so a file that can't be
+	// read doesn't leave an empty entry in the zip
+	file, err := z.fileSystem().Open(path)
+	if err != nil {
+		return errors.WithStack(newPathError(path, PathErrorCodeOpeningFile, err))
+	}
+	defer file.Close()
+
+	z.logger.Debugf("archive: writing zip header “%s”", header.Name)
+
+	writer, err := zipArchive.CreateHeader(header)
+	if err != nil {
+		return errors.WithStack(newPathError(path, PathErrorCodeWritingZIPHeader, err))
+	}
+
+	written, err := io.Copy(writer, file)
+	if err != nil {
+		return errors.WithStack(newPathError(path, PathErrorCodeWritingFile, err))
+	}
+
+	// the information of the path was retrieved before the file was opened, so
+	// a file replaced in the meantime is also detected
+	after, err := file.Stat()
+	if err != nil {
+		return errors.WithStack(newPathError(path, PathErrorCodeInfo, err))
+	}
+
+	if fileChanged(info, after) {
+		z.logger.Warningf("archive: path “%s” changed while it was read, the archived copy is fuzzy", path)
+		itemInfo.Fuzzy = true
+	}
+
+	z.logger.Debugf("archive: path “%s” copied to zip (%d bytes)", path, written)
+	return nil
+}
+
+// writeSnapshot writes a copy of the file read while it wasn't changing, so the
+// content in the zip matches the checksum of the archive information.
+func (z ZIPBuilder) writeSnapshot(path string, header *zip.FileHeader, zipArchive *zip.Writer, itemInfo *ItemInfo) error {
+	s, err := takeSnapshot(z.logger, z.fileSystem(), path, z.ChangeRetries)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer s.Close()
+
+	header.Modified = s.modTime
+
+	z.logger.Debugf("archive: writing zip header “%s”", header.Name)
+
+	writer, err := zipArchive.CreateHeader(header)
+	if err != nil {
+		return errors.WithStack(newPathError(path, PathErrorCodeWritingZIPHeader, err))
+	}
+
+	written, err := io.Copy(writer, s)
+	if err != nil {
+		return errors.WithStack(newPathError(path, PathErrorCodeWritingFile, err))
+	}
+
+	itemInfo.Checksum = s.checksum
+	itemInfo.Fuzzy = s.fuzzy
+
+	z.logger.Debugf("archive: path “%s” copied to zip (%d bytes)", path, written)
+	return nil
+}
+
+// fileSystem returns the file system of the backup paths, falling back to the
+// local disk.
+func (z ZIPBuilder) fileSystem() FileSystem {
+	if z.FileSystem == nil {
+		return osFileSystem{}
+	}
+
+	return z.FileSystem
+}
+
+// clock returns the defined clock, falling back to the system clock.
+func (z ZIPBuilder) clock() Clock {
+	if z.Clock == nil {
+		return realClock{}
+	}
+
+	return z.Clock
+}
+
+// Extract uncompress all files from the zip to the current path. You can select
+// the files that are extracted with the filter parameter, if nil all files are
+// extracted. If the file isn't a zip archive, it will be extracted as a
+// tarball, so backups created with a different format can still be retrieved.
+// The extraction stops when the context is cancelled. On error it will return
+// an Error type encapsulated in a traceable error. To retrieve the desired
+// error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (z ZIPBuilder) Extract(ctx context.Context, filename string, filter []string) (Info, error) {
+	z.logger.Debugf("archive: extract zip %s", filename)
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.WithStack(newError(filename, ErrorCodeOpeningFile, err))
+	}
+	defer f.Close()
+
+	headerSize, err := skipHeader(f)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if !z.isZIP(f) {
+		return z.tarBuilder().Extract(ctx, filename, filter)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, errors.WithStack(newError(filename, ErrorCodeReadingZIP, err))
+	}
+
+	// the zip is read after the format header
+	zipSize := stat.Size() - headerSize
+	zipReader, err := zip.NewReader(io.NewSectionReader(f, headerSize, zipSize), zipSize)
+	if err != nil {
+		return nil, errors.WithStack(newError(filename, ErrorCodeReadingZIP, err))
+	}
+
+	// the files that weren't moved to their places are removed on error
+	files := &extraction{logger: z.logger, overwrite: z.Overwrite, allOrNothing: z.AllOrNothing, progress: z.ExtractProgress, normalization: z.Normalization}
+	defer files.rollback()
+
+	var info Info
+
+	for _, zipItem := range zipReader.File {
+		if ctx.Err() != nil {
+			return nil, errors.WithStack(newError(filename, ErrorCodeCancelled, ctx.Err()))
+		}
+
+		if zipItem.FileInfo().IsDir() {
+			// we will create the directories only when extracting files from the
+			// zip, because not all files will be extracted
+			continue
+		}
+
+		if !zipItem.Mode().IsRegular() {
+			z.logger.Infof("archive: path “%s”, with mode “%s”, is not going to be extracted from the zip", zipItem.Name, zipItem.Mode())
+			continue
+		}
+
+		path := filepath.FromSlash(zipItem.Name)
+		name := normalizeHeaderName(path)
+
+		if name == TARInfoFilename {
+			if info, err = z.decodeInfo(zipItem); err != nil {
+				return nil, errors.WithStack(newError(filename, ErrorCodeDecodingInfo, err))
+			}
+			continue
+		}
+
+		if filter != nil && !shouldExtract(name, filter) {
+			z.logger.Debugf("archive: ignoring extraction of path “%s”", zipItem.Name)
+			continue
+		}
+
+		written, err := z.extractFile(zipItem, name, path, files)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		z.logger.Debugf("archive: path “%s” extracted from zip (%d bytes)", path, written)
+	}
+
+	if err = files.commit(info); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return info, nil
+}
+
+// tarBuilder returns a TARBuilder with the same extraction settings, used for
+// tarballs found when extracting.
+func (z ZIPBuilder) tarBuilder() *TARBuilder {
+	builder := NewTARBuilder(z.logger)
+	builder.Overwrite = z.Overwrite
+	builder.AllOrNothing = z.AllOrNothing
+	builder.ExtractProgress = z.ExtractProgress
+	builder.Normalization = z.Normalization
+	return builder
+}
+
+// ExtractReader uncompress the files from the zip while it is read. As the list
+// of files is stored at the end of a zip archive, the content is stored in a
+// temporary file before the extraction, removed afterwards. If the content
+// isn't a zip archive, it will be extracted as a tarball while it is read. On
+// error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *archive.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (z ZIPBuilder) ExtractReader(ctx context.Context, r io.Reader, filter []string) (Info, error) {
+	z.logger.Debug("archive: extract zip while reading")
+
+	content := bufio.NewReader(r)
+	if magicNumber, err := content.Peek(len(zipMagicNumber)); err != nil || !bytes.Equal(magicNumber, zipMagicNumber) {
+		return z.tarBuilder().ExtractReader(ctx, content, filter)
+	}
+
+	tmpFile, err := ioutil.TempFile("", "toglacier-")
+	if err != nil {
+		return nil, errors.WithStack(newError("", ErrorCodeTmpFileCreation, err))
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = io.Copy(tmpFile, content)
+	tmpFile.Close()
+
+	if err != nil {
+		return nil, errors.WithStack(newError(tmpFile.Name(), ErrorCodeReadingZIP, err))
+	}
+
+	return z.Extract(ctx, tmpFile.Name(), filter)
+}
+
+// isZIP checks if the content from the current position of the file is a zip
+// archive.
+func (z ZIPBuilder) isZIP(f *os.File) bool {
+	magicNumber := make([]byte, len(zipMagicNumber))
+	if _, err := io.ReadFull(f, magicNumber); err != nil {
+		// file too small to be a zip
+		return false
+	}
+
+	return bytes.Equal(magicNumber, zipMagicNumber)
+}
+
+func (z ZIPBuilder) decodeInfo(zipItem *zip.File) (Info, error) {
+	content, err := zipItem.Open()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer content.Close()
+
+	var info Info
+	if err := json.NewDecoder(content).Decode(&info); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return info, nil
+}
+
+func (z ZIPBuilder) extractFile(zipItem *zip.File, name, path string, files *extraction) (int64, error) {
+	content, err := zipItem.Open()
+	if err != nil {
+		return 0, errors.WithStack(newError(path, ErrorCodeReadingZIP, err))
+	}
+	defer content.Close()
+
+	written, err := files.write(name, path, zipItem.Mode(), zipItem.Modified, content)
+	return written, errors.WithStack(err)
+}
@@ -0,0 +1,667 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+func TestZIPBuilder_Build(t *testing.T) {
+	cancelledDir, err := ioutil.TempDir("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary directory. details %s", err)
+	}
+
+	if err := ioutil.WriteFile(path.Join(cancelledDir, "file1"), []byte("file1 test"), 0600); err != nil {
+		t.Fatalf("error creating temporary file. details %s", err)
+	}
+
+	scenarios := []struct {
+		description         string
+		archive             *archive.ZIPBuilder
+		lastArchiveInfo     func(backupPaths []string) archive.Info
+		ignorePatterns      []*regexp.Regexp
+		backupPaths         []string
+		cancelled           bool
+		expected            func(filename string) error
+		expectedArchiveInfo func(backupPaths []string) archive.Info
+		expectedError       error
+	}{
+		{
+			description: "it should create an archive correctly from directory path",
+			archive: archive.NewZIPBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}),
+			lastArchiveInfo: func(backupPaths []string) archive.Info {
+				return archive.Info{
+					path.Join(backupPaths[0], "file1"): {
+						ID:       "reference1",
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+					},
+				}
+			},
+			ignorePatterns: []*regexp.Regexp{
+				regexp.MustCompile(`^.*\~\$.*$`),
+			},
+			backupPaths: func() []string {
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "file2"), []byte("file2 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "~$file3"), []byte("file3 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				if err := os.Symlink(path.Join(d, "file2"), path.Join(d, "link1")); err != nil {
+					t.Fatalf("error creating temporary link. details %s", err)
+				}
+
+				if err := os.Mkdir(path.Join(d, "dir1"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "dir1", "file3"), []byte("file3 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				// add an empty directory to see if it's going to be ignored
+				return []string{d, ""}
+			}(),
+			expected: func(filename string) error {
+				zipReader, err := zip.OpenReader(filename)
+				if err != nil {
+					return fmt.Errorf("error opening archive. details: %s", err)
+				}
+				defer zipReader.Close()
+
+				basePath := `backup-[0-9]+`
+				expectedFiles := []*regexp.Regexp{
+					regexp.MustCompile(`^` + path.Join(basePath, archive.TARInfoFilename) + `$`),
+					regexp.MustCompile(`^` + path.Join(basePath, `tmp`, `toglacier-test[0-9]+`) + `/$`),
+					regexp.MustCompile(`^` + path.Join(basePath, `tmp`, `toglacier-test[0-9]+`, `file2`) + `$`),
+					regexp.MustCompile(`^` + path.Join(basePath, `tmp`, `toglacier-test[0-9]+`, `dir1`) + `/$`),
+					regexp.MustCompile(`^` + path.Join(basePath, `tmp`, `toglacier-test[0-9]+`, `dir1`, `file3`) + `$`),
+				}
+
+				for _, zipItem := range zipReader.File {
+					if len(expectedFiles) == 0 {
+						return fmt.Errorf("content “%s” shouldn't be here", zipItem.Name)
+					}
+
+					found := false
+					for i, expectedFile := range expectedFiles {
+						if expectedFile.MatchString(zipItem.Name) {
+							expectedFiles = append(expectedFiles[:i], expectedFiles[i+1:]...)
+							found = true
+							break
+						}
+					}
+
+					if !found {
+						return fmt.Errorf("file “%s” did not match with any of the expected files", zipItem.Name)
+					}
+				}
+
+				if len(expectedFiles) > 0 {
+					return errors.New("not all files were found in the archive")
+				}
+
+				return nil
+			},
+			expectedArchiveInfo: func(backupPaths []string) archive.Info {
+				return archive.Info(map[string]archive.ItemInfo{
+					path.Join(backupPaths[0], "file1"): {
+						ID:       "reference1",
+						Status:   archive.ItemInfoStatusUnmodified,
+						Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+					},
+					path.Join(backupPaths[0], "file2"): {
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "xZzITM+6yGsa9masWjGdi+yAA0DlqCzTf/1795fy5Pk=",
+					},
+					path.Join(backupPaths[0], "dir1", "file3"): {
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "sFwN7pdLHnHZHCmTuhFWYvYTYz9g8XzISkAR1+UOS5c=",
+					},
+				})
+			},
+		},
+		{
+			description: "it should ignore the build when all files are unmodified",
+			archive: archive.NewZIPBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}),
+			lastArchiveInfo: func(backupPaths []string) archive.Info {
+				return archive.Info{
+					path.Join(backupPaths[0], "file1"): {
+						ID:       "reference1",
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+					},
+				}
+			},
+			backupPaths: func() []string {
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				return []string{d}
+			}(),
+			expected: func(filename string) error {
+				if filename != "" {
+					return fmt.Errorf("unexpected archive “%s” created", filename)
+				}
+
+				return nil
+			},
+		},
+		{
+			description: "it should stop scanning the paths when the context is cancelled",
+			archive: archive.NewZIPBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}),
+			backupPaths: []string{cancelledDir},
+			cancelled:   true,
+			expectedError: &archive.Error{
+				Filename: cancelledDir,
+				Code:     archive.ErrorCodeCancelled,
+				Err:      context.Canceled,
+			},
+		},
+		{
+			description: "it should detect when the path does not exist",
+			archive: archive.NewZIPBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}),
+			backupPaths: []string{"idontexist12345"},
+			expectedError: &archive.PathError{
+				Path: "idontexist12345",
+				Code: archive.PathErrorCodeInfo,
+				Err: &os.PathError{
+					Op:   "lstat",
+					Path: "idontexist12345",
+					Err:  errors.New("no such file or directory"),
+				},
+			},
+		},
+		{
+			description: "it should create an archive from an in-memory file system",
+			archive: func() *archive.ZIPBuilder {
+				builder := archive.NewZIPBuilder(mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				})
+				builder.FileSystem = archive.NewIOFileSystem(fstest.MapFS{
+					"data/file1":      &fstest.MapFile{Data: []byte("file1 test"), Mode: 0600},
+					"data/dir1/file2": &fstest.MapFile{Data: []byte("file2 test"), Mode: 0600},
+				})
+				builder.Clock = fakeClock{
+					mockNow: func() time.Time {
+						return time.Date(2017, 9, 1, 10, 30, 15, 0, time.UTC)
+					},
+				}
+				return builder
+			}(),
+			backupPaths: []string{"data"},
+			expected: func(filename string) error {
+				defer os.Remove(filename)
+
+				zr, err := zip.OpenReader(filename)
+				if err != nil {
+					return fmt.Errorf("error opening archive. details: %s", err)
+				}
+				defer zr.Close()
+
+				var names []string
+				for _, file := range zr.File {
+					names = append(names, file.Name)
+				}
+
+				expectedNames := []string{
+					"backup-20170901103015/data/",
+					"backup-20170901103015/data/dir1/",
+					"backup-20170901103015/data/dir1/file2",
+					"backup-20170901103015/data/file1",
+					"backup-20170901103015/" + archive.TARInfoFilename,
+				}
+
+				if !reflect.DeepEqual(expectedNames, names) {
+					return fmt.Errorf("unexpected archive content %v", names)
+				}
+
+				return nil
+			},
+			expectedArchiveInfo: func(backupPaths []string) archive.Info {
+				return archive.Info{
+					"data/file1": {
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "+pJSD0LPX/FSn3AwOnGKsCXJSMN3o9JPyWzVv4RYqpU=",
+					},
+					"data/dir1/file2": {
+						Status:   archive.ItemInfoStatusNew,
+						Checksum: "xZzITM+6yGsa9masWjGdi+yAA0DlqCzTf/1795fy5Pk=",
+					},
+				}
+			},
+		},
+		{
+			description: "it should detect a missing path in the in-memory file system",
+			archive: func() *archive.ZIPBuilder {
+				builder := archive.NewZIPBuilder(mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				})
+				builder.FileSystem = archive.NewIOFileSystem(fstest.MapFS{})
+				return builder
+			}(),
+			backupPaths: []string{"data"},
+			expectedError: &archive.PathError{
+				Path: "data",
+				Code: archive.PathErrorCodeInfo,
+				Err: &fs.PathError{
+					Op:   "open",
+					Path: "data",
+					Err:  fs.ErrNotExist,
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			backupPaths := scenario.backupPaths
+
+			var lastArchiveInfo archive.Info
+			if scenario.lastArchiveInfo != nil {
+				lastArchiveInfo = scenario.lastArchiveInfo(backupPaths)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if scenario.cancelled {
+				cancel()
+			}
+
+			filename, archiveInfo, err := scenario.archive.Build(ctx, lastArchiveInfo, scenario.ignorePatterns, backupPaths...)
+			if scenario.expectedError == nil && scenario.expected != nil {
+				if err = scenario.expected(filename); err != nil {
+					t.Errorf("unexpected archive content (%s). details: %s", filename, err)
+				}
+
+				if archiveInfo != nil && scenario.expectedArchiveInfo == nil {
+					t.Error("unexpected archive info")
+
+				} else if scenario.expectedArchiveInfo != nil {
+					expectedArchiveInfo := scenario.expectedArchiveInfo(backupPaths)
+					if !reflect.DeepEqual(expectedArchiveInfo, archiveInfo) {
+						t.Errorf("archive info don't match.\n%v", Diff(expectedArchiveInfo, archiveInfo))
+					}
+				}
+			}
+
+			if !archive.ErrorEqual(scenario.expectedError, err) && !archive.PathErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestZIPBuilder_Extract(t *testing.T) {
+	writeFile := func(zipArchive *zip.Writer, baseDir, name, content string) string {
+		if name == "" {
+			file, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary file. details %s", err)
+			}
+			file.Close()
+			os.Remove(file.Name())
+
+			name = file.Name()
+		}
+
+		header := &zip.FileHeader{
+			Name:   filepath.ToSlash(filepath.Join(baseDir, name)),
+			Method: zip.Deflate,
+		}
+		header.SetMode(0600)
+
+		writer, err := zipArchive.CreateHeader(header)
+		if err != nil {
+			t.Fatalf("error writing zip header. details %s", err)
+		}
+
+		if _, err = io.WriteString(writer, content); err != nil {
+			t.Fatalf("error writing content to zip. details %s", err)
+		}
+
+		return name
+	}
+
+	type scenario struct {
+		description         string
+		archive             *archive.ZIPBuilder
+		filename            string
+		filter              []string
+		cancelled           bool
+		expected            func() error
+		expectedArchiveInfo archive.Info
+		expectedError       error
+		clean               func()
+	}
+
+	scenarios := []scenario{
+		func() scenario {
+			var s scenario
+			s.description = "it should extract an archive correctly with filters"
+			s.archive = archive.NewZIPBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			})
+
+			zipFile, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary file. details %s", err)
+			}
+			defer zipFile.Close()
+
+			zipArchive := zip.NewWriter(zipFile)
+			defer zipArchive.Close()
+
+			baseDir := "backup-" + time.Now().Format("20060102150405.000000000")
+			file1 := writeFile(zipArchive, baseDir, "", "this is test 1")
+			file2 := writeFile(zipArchive, baseDir, "", "this is test 2")
+
+			archiveInfo := archive.Info{
+				file1: archive.ItemInfo{
+					ID:       "AWS123456",
+					Status:   archive.ItemInfoStatusModified,
+					Checksum: "xQcKtcv0VpiRaJvk2VL6vR/Ztx2CP+5xGA2/hUdkrlU=",
+				},
+				file2: archive.ItemInfo{
+					Status:   archive.ItemInfoStatusNew,
+					Checksum: "CmJqL6EKdRAGTVUHqqcBgMS4ad4dmQyZvfvAmQ09lIg=",
+				},
+			}
+
+			archiveInfoData, err := json.Marshal(archiveInfo)
+			if err != nil {
+				t.Fatalf("error encoding archive info. details %s", err)
+			}
+			writeFile(zipArchive, baseDir, archive.TARInfoFilename, string(archiveInfoData))
+
+			s.filename = zipFile.Name()
+			s.filter = []string{file2}
+			s.expected = func() error {
+				filename1 := filepath.Join(baseDir, file1)
+
+				if _, err := os.Stat(filename1); !os.IsNotExist(err) {
+					return fmt.Errorf("file “%s” extracted when it shouldn't", filename1)
+				}
+
+				filename2 := filepath.Join(baseDir, file2)
+
+				content, err := ioutil.ReadFile(filename2)
+				if err != nil {
+					return fmt.Errorf("error opening file “%s”. details: %s", filename2, err)
+				}
+
+				if string(content) != "this is test 2" {
+					return fmt.Errorf("expected content “this is test 2” and got “%s” in file “%s”", string(content), filename2)
+				}
+
+				return nil
+			}
+			s.expectedArchiveInfo = archiveInfo
+			s.clean = func() {
+				os.RemoveAll(baseDir)
+			}
+			return s
+		}(),
+		func() scenario {
+			var s scenario
+			s.description = "it should extract a tarball when the file isn't a zip"
+			s.archive = archive.NewZIPBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			})
+
+			tarFile, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary file. details %s", err)
+			}
+			defer tarFile.Close()
+
+			tarArchive := tar.NewWriter(tarFile)
+			defer tarArchive.Close()
+
+			baseDir := "backup-" + time.Now().Format("20060102150405.000000000")
+			content := "this is test 1"
+
+			header := &tar.Header{
+				Name:     filepath.Join(baseDir, "file1"),
+				Mode:     0600,
+				Size:     int64(len(content)),
+				Typeflag: tar.TypeReg,
+			}
+
+			if err = tarArchive.WriteHeader(header); err != nil {
+				t.Fatalf("error writing tar header. details %s", err)
+			}
+
+			if _, err = io.WriteString(tarArchive, content); err != nil {
+				t.Fatalf("error writing content to tar. details %s", err)
+			}
+
+			s.filename = tarFile.Name()
+			s.expected = func() error {
+				filename1 := filepath.Join(baseDir, "file1")
+
+				content, err := ioutil.ReadFile(filename1)
+				if err != nil {
+					return fmt.Errorf("error opening file “%s”. details: %s", filename1, err)
+				}
+
+				if string(content) != "this is test 1" {
+					return fmt.Errorf("expected content “this is test 1” and got “%s” in file “%s”", string(content), filename1)
+				}
+
+				return nil
+			}
+			s.clean = func() {
+				os.RemoveAll(baseDir)
+			}
+			return s
+		}(),
+		func() scenario {
+			var s scenario
+			s.description = "it should stop the extraction when the context is cancelled"
+			s.archive = archive.NewZIPBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			})
+
+			zipFile, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary file. details %s", err)
+			}
+			defer zipFile.Close()
+
+			zipArchive := zip.NewWriter(zipFile)
+			defer zipArchive.Close()
+
+			baseDir := "backup-" + time.Now().Format("20060102150405.000000000")
+			writeFile(zipArchive, baseDir, "", "this is test 1")
+
+			s.filename = zipFile.Name()
+			s.cancelled = true
+			s.expectedError = &archive.Error{
+				Filename: zipFile.Name(),
+				Code:     archive.ErrorCodeCancelled,
+				Err:      context.Canceled,
+			}
+			s.clean = func() {
+				os.RemoveAll(baseDir)
+			}
+			return s
+		}(),
+		{
+			description: "it should detect when the file doesn't exist",
+			archive: archive.NewZIPBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}),
+			filename: path.Join(os.TempDir(), "toglacier-idontexist.zip"),
+			expectedError: &archive.Error{
+				Filename: path.Join(os.TempDir(), "toglacier-idontexist.zip"),
+				Code:     archive.ErrorCodeOpeningFile,
+				Err: &os.PathError{
+					Op:   "open",
+					Path: path.Join(os.TempDir(), "toglacier-idontexist.zip"),
+					Err:  errors.New("no such file or directory"),
+				},
+			},
+		},
+		func() scenario {
+			var s scenario
+			s.description = "it should detect a corrupted archive info"
+			s.archive = archive.NewZIPBuilder(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			})
+
+			zipFile, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary file. details %s", err)
+			}
+			defer zipFile.Close()
+
+			zipArchive := zip.NewWriter(zipFile)
+			defer zipArchive.Close()
+
+			baseDir := "backup-" + time.Now().Format("20060102150405.000000000")
+			writeFile(zipArchive, baseDir, archive.TARInfoFilename, "{{{{")
+
+			s.filename = zipFile.Name()
+			s.expectedError = &archive.Error{
+				Filename: zipFile.Name(),
+				Code:     archive.ErrorCodeDecodingInfo,
+				Err:      errors.New(`invalid character '{' looking for beginning of object key string`), // json.SyntaxError message is a private attribute
+			}
+			s.clean = func() {
+				os.RemoveAll(baseDir)
+			}
+			return s
+		}(),
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if scenario.cancelled {
+				cancel()
+			}
+
+			archiveInfo, err := scenario.archive.Extract(ctx, scenario.filename, scenario.filter)
+
+			if scenario.expected != nil {
+				if scenarioErr := scenario.expected(); scenarioErr != nil {
+					t.Error(scenarioErr)
+				}
+			}
+
+			if !reflect.DeepEqual(scenario.expectedArchiveInfo, archiveInfo) {
+				t.Errorf("archive info don't match.\n%v", Diff(scenario.expectedArchiveInfo, archiveInfo))
+			}
+
+			if !archive.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if scenario.clean != nil {
+				scenario.clean()
+			}
+		})
+	}
+}
+
+func BenchmarkZIPBuilder_Build(b *testing.B) {
+	zipBuilder := archive.NewZIPBuilder(newBenchmarkLogger())
+
+	for _, dataset := range benchmarkDatasets {
+		dir := dataset.generate(b)
+		defer os.RemoveAll(dir)
+
+		b.Run(dataset.description, func(b *testing.B) {
+			b.SetBytes(dataset.totalSize())
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				filename, _, err := zipBuilder.Build(context.Background(), nil, nil, dir)
+				if err != nil {
+					b.Fatalf("error building archive. details %s", err)
+				}
+				os.Remove(filename)
+			}
+		})
+	}
+}
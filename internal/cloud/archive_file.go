@@ -0,0 +1,139 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// headerKey stores in the context the header sent before the archive content.
+type headerKey struct{}
+
+// WithHeader returns a context where the archives sent to the cloud start with
+// the given header, so the archive file doesn't need to be rewritten to carry
+// it. Only the content sent is affected, the local file is kept as it is.
+func WithHeader(ctx context.Context, header []byte) context.Context {
+	return context.WithValue(ctx, headerKey{}, header)
+}
+
+// ArchiveFile is the content of an archive sent to the cloud: the header of
+// the context followed by the file. It can be rewound, as some clouds read the
+// content more than once (e.g. to compute the checksums).
+type ArchiveFile struct {
+	file   *os.File
+	header []byte
+	size   int64
+	reader *positionReader
+}
+
+// OpenArchive opens the file to be sent to the cloud, adding the header
+// defined in the context before the file content. On error it will return an
+// Error type encapsulated in a traceable error. To retrieve the desired error
+// you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func OpenArchive(ctx context.Context, filename string) (*ArchiveFile, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.WithStack(newError("", ErrorCodeOpeningArchive, err))
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, errors.WithStack(newError("", ErrorCodeArchiveInfo, err))
+	}
+
+	header, _ := ctx.Value(headerKey{}).([]byte)
+
+	a := &ArchiveFile{
+		file:   file,
+		header: header,
+		size:   int64(len(header)) + info.Size(),
+	}
+	a.reader = a.readerFrom(0)
+	return a, nil
+}
+
+// HasHeader informs if there's content sent before the file.
+func (a *ArchiveFile) HasHeader() bool {
+	return len(a.header) > 0
+}
+
+// Size returns the number of bytes sent to the cloud, including the header.
+func (a *ArchiveFile) Size() int64 {
+	return a.size
+}
+
+// Read reads the header and then the file content.
+func (a *ArchiveFile) Read(p []byte) (int, error) {
+	return a.reader.Read(p)
+}
+
+// Seek moves to a position of the content sent to the cloud, where the
+// beginning of the file is right after the header.
+func (a *ArchiveFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += a.reader.position
+	case io.SeekEnd:
+		offset += a.size
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	a.reader = a.readerFrom(offset)
+	return offset, nil
+}
+
+// Close releases the file.
+func (a *ArchiveFile) Close() error {
+	return a.file.Close()
+}
+
+// readerFrom builds the reader of the header followed by the file, starting
+// at the given position.
+func (a *ArchiveFile) readerFrom(position int64) *positionReader {
+	var header []byte
+	if position < int64(len(a.header)) {
+		header = a.header[position:]
+	}
+
+	filePosition := position - int64(len(a.header))
+	if filePosition < 0 {
+		filePosition = 0
+	}
+
+	return &positionReader{
+		reader:   io.MultiReader(bytes.NewReader(header), io.NewSectionReader(a.file, filePosition, a.size)),
+		position: position,
+	}
+}
+
+// positionReader keeps track of the position of the content read.
+type positionReader struct {
+	reader   io.Reader
+	position int64
+}
+
+func (p *positionReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.position += int64(n)
+	return n, err
+}
@@ -0,0 +1,103 @@
+package cloud_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+func TestArchiveFile(t *testing.T) {
+	scenarios := []struct {
+		description     string
+		ctx             context.Context
+		seek            int64
+		expectedSize    int64
+		expectedContent string
+	}{
+		{
+			description:     "it should send the header before the file",
+			ctx:             cloud.WithHeader(context.Background(), []byte("header\n")),
+			expectedSize:    int64(len("header\ncontent")),
+			expectedContent: "header\ncontent",
+		},
+		{
+			description:     "it should send only the file without header",
+			ctx:             context.Background(),
+			expectedSize:    int64(len("content")),
+			expectedContent: "content",
+		},
+		{
+			description:     "it should rewind to a position inside the header",
+			ctx:             cloud.WithHeader(context.Background(), []byte("header\n")),
+			seek:            3,
+			expectedSize:    int64(len("header\ncontent")),
+			expectedContent: "der\ncontent",
+		},
+		{
+			description:     "it should rewind to a position inside the file",
+			ctx:             cloud.WithHeader(context.Background(), []byte("header\n")),
+			seek:            9,
+			expectedSize:    int64(len("header\ncontent")),
+			expectedContent: "ntent",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary file. details: %s", err)
+			}
+			defer os.Remove(f.Name())
+
+			_, err = f.WriteString("content")
+			f.Close()
+
+			if err != nil {
+				t.Fatalf("error writing temporary file. details: %s", err)
+			}
+
+			archiveFile, err := cloud.OpenArchive(scenario.ctx, f.Name())
+			if err != nil {
+				t.Fatalf("unexpected error opening the archive. details: %s", err)
+			}
+			defer archiveFile.Close()
+
+			// the content is read once before rewinding, like the checksum
+			// calculation does
+			if _, err = ioutil.ReadAll(archiveFile); err != nil {
+				t.Fatalf("unexpected error reading the archive. details: %s", err)
+			}
+
+			if _, err = archiveFile.Seek(scenario.seek, io.SeekStart); err != nil {
+				t.Fatalf("unexpected error rewinding the archive. details: %s", err)
+			}
+
+			content, err := ioutil.ReadAll(archiveFile)
+			if err != nil {
+				t.Fatalf("unexpected error reading the archive. details: %s", err)
+			}
+
+			if scenario.expectedSize != archiveFile.Size() {
+				t.Errorf("sizes don't match. expected “%d” and got “%d”", scenario.expectedSize, archiveFile.Size())
+			}
+
+			if scenario.expectedContent != string(content) {
+				t.Errorf("contents don't match. expected “%s” and got “%s”", scenario.expectedContent, content)
+			}
+
+			written, err := ioutil.ReadFile(f.Name())
+			if err != nil {
+				t.Fatalf("error reading temporary file. details: %s", err)
+			}
+
+			if string(written) != "content" {
+				t.Errorf("file changed to “%s”", written)
+			}
+		})
+	}
+}
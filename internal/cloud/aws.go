@@ -7,13 +7,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -24,44 +24,40 @@ import (
 	"github.com/aws/aws-sdk-go/service/glacier/glacieriface"
 	"github.com/pkg/errors"
 	"github.com/rafaeljusto/toglacier/internal/log"
+	"github.com/rafaeljusto/toglacier/internal/proxy"
+	"github.com/rafaeljusto/toglacier/internal/trace"
 )
 
-var multipartUploadLimit int64 = 104857600 // 100 MB in bytes
-
-// MultipartUploadLimit defines the limit where we decide if we will send the
-// file in one shot or if we will use multipart upload strategy. By default we
-// use 100 MB.
-func MultipartUploadLimit(value int64) {
-	atomic.StoreInt64(&multipartUploadLimit, value)
-}
-
-var partSize int64 = 4194304 // 4 MB (in bytes) will limit the archive in 40GB
-
-// PartSize the size of each part of the multipart upload except the last, in
-// bytes. The last part can be smaller than this part size. By default we use
-// 4MB.
-func PartSize(value int64) {
-	// TODO: Part size must be a power of two and be between 1048576 and
-	// 4294967296 bytes
-
-	atomic.StoreInt64(&partSize, value)
-}
-
-var waitJobTime = struct {
-	time.Duration
-	sync.RWMutex
-}{
-	Duration: time.Minute,
-}
-
-// WaitJobTime is the amount of time that we wait for the job to complete, as it
-// takes some time, we will sleep for a long time before we check again. By
-// default we use 1 minute.
-func WaitJobTime(value time.Duration) {
-	waitJobTime.Lock()
-	defer waitJobTime.Unlock()
-	waitJobTime.Duration = value
-}
+const (
+	// DefaultMultipartUploadLimit is the archive size limit (100 MB in bytes)
+	// used when the AWS configuration doesn't define one.
+	DefaultMultipartUploadLimit int64 = 104857600
+
+	// DefaultPartSize is the multipart upload part size (4 MB in bytes, limiting
+	// the archive in 40GB) used when the AWS configuration doesn't define one.
+	DefaultPartSize int64 = 4194304
+
+	// DefaultProgressInterval is the minimum interval between the progress log
+	// entries of a multipart upload used when the AWS configuration doesn't
+	// define one.
+	DefaultProgressInterval = 30 * time.Second
+
+	// DefaultWaitJobTime is the amount of time between the checks of the
+	// retrieval jobs used when the AWS configuration doesn't define one. As the
+	// jobs take hours to complete, we sleep for a long time before we check
+	// again.
+	DefaultWaitJobTime = time.Minute
+
+	// DefaultDownloadRetries is the number of times an archive is downloaded
+	// again when its tree hash doesn't match, used when the AWS configuration
+	// doesn't define one.
+	DefaultDownloadRetries = 2
+
+	// DefaultHashBufferSize is the amount of data read from the archive at once
+	// while computing the hashes of a small file upload (8 MB in bytes), used
+	// when the AWS configuration doesn't define one.
+	DefaultHashBufferSize int64 = 8 * hashChunkSize
+)
 
 // AWSConfig stores all necessary parameters to initialize a AWS session.
 type AWSConfig struct {
@@ -70,6 +66,50 @@ type AWSConfig struct {
 	SecretAccessKey string
 	Region          string
 	VaultName       string
+	MachineID       string
+
+	// Proxy used to reach the AWS Glacier service. When empty the proxy
+	// environment variables (HTTPS_PROXY) are used.
+	Proxy *url.URL
+
+	// RateLimiter limits the requests sent to the AWS Glacier service. When nil
+	// there's no limit.
+	RateLimiter *RateLimiter
+
+	// MultipartUploadLimit defines the limit where we decide if we will send the
+	// file in one shot or if we will use multipart upload strategy. When zero
+	// DefaultMultipartUploadLimit is used.
+	MultipartUploadLimit int64
+
+	// PartSize the size of each part of the multipart upload except the last, in
+	// bytes. The last part can be smaller than this part size. When zero
+	// DefaultPartSize is used.
+	PartSize int64
+
+	// ProgressInterval is the minimum interval between the progress log entries
+	// of a multipart upload. When zero DefaultProgressInterval is used.
+	ProgressInterval time.Duration
+
+	// Progress receives the throughput and the ETA of the multipart uploads.
+	// When nil the progress is only logged.
+	Progress UploadProgress
+
+	// WaitJobTime is the amount of time between the checks of the retrieval
+	// jobs. When zero DefaultWaitJobTime is used.
+	WaitJobTime time.Duration
+
+	// DownloadRetries defines how many times an archive is downloaded again
+	// when its tree hash doesn't match the one informed in the job description.
+	// When zero DefaultDownloadRetries is used, and when negative the archive
+	// isn't downloaded again.
+	DownloadRetries int
+
+	// HashBufferSize is the amount of data read from the archive at once while
+	// computing the hashes of a small file upload. Archives that fit in this
+	// buffer are read only once, as they are sent from memory. The value is
+	// rounded up to a multiple of 1MB, that is the size of the tree hash
+	// leaves. When zero DefaultHashBufferSize is used.
+	HashBufferSize int64
 }
 
 // AWSCloud is the Amazon solution for storing the backups in the cloud. It uses
@@ -78,8 +118,40 @@ type AWSCloud struct {
 	Logger    log.Logger
 	AccountID string
 	VaultName string
+	MachineID string
 	Glacier   glacieriface.GlacierAPI
 	Clock     Clock
+
+	// MultipartUploadLimit defines the limit where we decide if we will send the
+	// file in one shot or if we will use multipart upload strategy. When zero
+	// DefaultMultipartUploadLimit is used.
+	MultipartUploadLimit int64
+
+	// PartSize the size of each part of the multipart upload except the last, in
+	// bytes. When zero DefaultPartSize is used.
+	PartSize int64
+
+	// ProgressInterval is the minimum interval between the progress log entries
+	// of a multipart upload. When zero DefaultProgressInterval is used.
+	ProgressInterval time.Duration
+
+	// Progress receives the throughput and the ETA of the multipart uploads.
+	// When nil the progress is only logged.
+	Progress UploadProgress
+
+	// WaitJobTime is the amount of time between the checks of the retrieval
+	// jobs. When zero DefaultWaitJobTime is used.
+	WaitJobTime time.Duration
+
+	// DownloadRetries defines how many times an archive is downloaded again
+	// when its tree hash doesn't match. When zero DefaultDownloadRetries is
+	// used, and when negative the archive isn't downloaded again.
+	DownloadRetries int
+
+	// HashBufferSize is the amount of data read from the archive at once while
+	// computing the hashes of a small file upload, rounded up to a multiple of
+	// 1MB. When zero DefaultHashBufferSize is used.
+	HashBufferSize int64
 }
 
 // jobResult contains the result data after a archive download. It is used in
@@ -92,9 +164,10 @@ type jobResult struct {
 
 // NewAWSCloud initializes the Amazon cloud object, defining the account ID and
 // vault name that are going to be used in the AWS Glacier service. For more
-// details set the debug flag to receive low level information in the standard
-// output. On error it will return an Error type. To retrieve the desired error
-// you can do:
+// details set the trace flag to receive the requests and responses as debug
+// messages in the logger, without the credentials and without the archive
+// contents. On error it will return an Error type. To retrieve the desired
+// error you can do:
 //
 //     type causer interface {
 //       Cause() error
@@ -108,7 +181,7 @@ type jobResult struct {
 //         // unknown error
 //       }
 //     }
-func NewAWSCloud(logger log.Logger, config AWSConfig, debug bool) (*AWSCloud, error) {
+func NewAWSCloud(logger log.Logger, config AWSConfig, trace bool) (*AWSCloud, error) {
 	var err error
 
 	// this environment variables are used by the AWS library, so we need to set
@@ -117,25 +190,115 @@ func NewAWSCloud(logger log.Logger, config AWSConfig, debug bool) (*AWSCloud, er
 	os.Setenv("AWS_SECRET_ACCESS_KEY", config.SecretAccessKey)
 	os.Setenv("AWS_REGION", config.Region)
 
-	awsSession, err := session.NewSession()
+	awsConfig := aws.NewConfig()
+	if config.Proxy != nil {
+		awsConfig.WithHTTPClient(proxy.HTTPClient(config.Proxy))
+	}
+
+	// the debug handlers are only installed when the client is created with the
+	// log level already defined
+	if trace {
+		awsConfig.WithLogLevel(aws.LogDebugWithHTTPBody | aws.LogDebugWithRequestErrors | aws.LogDebugWithRequestRetries | aws.LogDebugWithSigning)
+		awsConfig.WithLogger(newTraceLogger(logger, config.AccessKeyID, config.SecretAccessKey))
+	}
+
+	awsSession, err := session.NewSession(awsConfig)
 	if err != nil {
 		return nil, errors.WithStack(newError("", ErrorCodeInitializingSession, err))
 	}
 
 	awsGlacier := glacier.New(awsSession)
-	if debug {
-		awsGlacier.Config.WithLogLevel(aws.LogDebugWithHTTPBody | aws.LogDebugWithRequestErrors | aws.LogDebugWithRequestRetries | aws.LogDebugWithSigning)
+	if config.RateLimiter != nil {
+		awsGlacier.Handlers.Sign.PushFrontNamed(config.RateLimiter.handler())
+	}
+
+	if trace {
+		awsGlacier.Handlers.Build.PushBackNamed(traceBodyHandler)
 	}
 
 	return &AWSCloud{
-		Logger:    logger,
-		AccountID: config.AccountID,
-		VaultName: config.VaultName,
-		Glacier:   awsGlacier,
-		Clock:     realClock{},
+		Logger:               logger,
+		AccountID:            config.AccountID,
+		VaultName:            config.VaultName,
+		MachineID:            config.MachineID,
+		Glacier:              awsGlacier,
+		Clock:                realClock{},
+		MultipartUploadLimit: config.MultipartUploadLimit,
+		PartSize:             config.PartSize,
+		ProgressInterval:     config.ProgressInterval,
+		Progress:             config.Progress,
+		WaitJobTime:          config.WaitJobTime,
+		DownloadRetries:      config.DownloadRetries,
+		HashBufferSize:       config.HashBufferSize,
 	}, nil
 }
 
+// multipartUploadLimit returns the archive size limit to use the small file
+// strategy, falling back to the default when it isn't defined.
+func (a *AWSCloud) multipartUploadLimit() int64 {
+	if a.MultipartUploadLimit <= 0 {
+		return DefaultMultipartUploadLimit
+	}
+	return a.MultipartUploadLimit
+}
+
+// progressInterval returns the minimum interval between the progress log
+// entries, falling back to the default when not defined.
+func (a *AWSCloud) progressInterval() time.Duration {
+	if a.ProgressInterval <= 0 {
+		return DefaultProgressInterval
+	}
+	return a.ProgressInterval
+}
+
+// waitJobTime returns the amount of time between the checks of the retrieval
+// jobs, falling back to the default when it isn't defined.
+func (a *AWSCloud) waitJobTime() time.Duration {
+	if a.WaitJobTime <= 0 {
+		return DefaultWaitJobTime
+	}
+	return a.WaitJobTime
+}
+
+// downloadRetries returns the number of times a corrupted archive is
+// downloaded again, falling back to the default when it isn't defined.
+func (a *AWSCloud) downloadRetries() int {
+	switch {
+	case a.DownloadRetries == 0:
+		return DefaultDownloadRetries
+	case a.DownloadRetries < 0:
+		return 0
+	}
+	return a.DownloadRetries
+}
+
+// hashBufferSize returns the size of the buffer used to compute the hashes of
+// a small file upload, rounded up to a multiple of the tree hash leaves and
+// falling back to the default when it isn't defined.
+func (a *AWSCloud) hashBufferSize() int64 {
+	size := a.HashBufferSize
+	if size <= 0 {
+		return DefaultHashBufferSize
+	}
+
+	if remainder := size % hashChunkSize; remainder != 0 {
+		size += hashChunkSize - remainder
+	}
+	return size
+}
+
+// partSize returns the multipart upload part size, falling back to the default
+// when it isn't defined.
+func (a *AWSCloud) partSize() int64 {
+	// TODO: Part size must be a power of two and be between 1048576 and
+	// 4294967296 bytes
+
+	if a.PartSize <= 0 {
+		return DefaultPartSize
+	}
+	return a.PartSize
+}
+
 // Send uploads the file to the cloud and return the backup archive information.
 // It already has the logic to send directly if it's a small file or use
 // multipart strategy if it's a large file. If an error occurs it will be an
@@ -156,64 +319,84 @@ func NewAWSCloud(logger log.Logger, config AWSConfig, debug bool) (*AWSCloud, er
 //         // unknown error
 //       }
 //     }
-func (a *AWSCloud) Send(ctx context.Context, filename string) (Backup, error) {
+func (a *AWSCloud) Send(ctx context.Context, filename, comment string) (Backup, error) {
+	return a.send(ctx, filename, comment, companion{})
+}
+
+// SendParity uploads the parity file of a backup to the cloud, identifying it
+// in the archive description. The errors are the same of Send.
+func (a *AWSCloud) SendParity(ctx context.Context, filename, backupID string) (Backup, error) {
+	return a.send(ctx, filename, "", companion{kind: companionParity, backupID: backupID})
+}
+
+// SendCatalog uploads the catalog file of a backup to the cloud, identifying it
+// in the archive description. The errors are the same of Send.
+func (a *AWSCloud) SendCatalog(ctx context.Context, filename, backupID string) (Backup, error) {
+	return a.send(ctx, filename, "", companion{kind: companionCatalog, backupID: backupID})
+}
+
+func (a *AWSCloud) send(ctx context.Context, filename, comment string, of companion) (Backup, error) {
 	a.Logger.Debugf("cloud: sending file “%s” to aws cloud", filename)
 
-	archive, err := os.Open(filename)
+	archive, err := OpenArchive(ctx, filename)
 	if err != nil {
-		return Backup{}, errors.WithStack(newError("", ErrorCodeOpeningArchive, err))
+		return Backup{}, errors.WithStack(err)
 	}
 	defer archive.Close()
 
-	archiveInfo, err := archive.Stat()
-	if err != nil {
-		return Backup{}, errors.WithStack(newError("", ErrorCodeArchiveInfo, err))
-	}
-
 	var backup Backup
 
-	if archiveInfo.Size() <= multipartUploadLimit {
-		a.Logger.Debugf("cloud: using small file strategy (%d)", archiveInfo.Size())
-		backup, err = a.sendSmall(ctx, archive)
+	if archive.Size() <= a.multipartUploadLimit() {
+		a.Logger.Debugf("cloud: using small file strategy (%d)", archive.Size())
+		backup, err = a.sendSmall(ctx, archive, comment, of)
 
 	} else {
-		a.Logger.Debugf("cloud: using big file strategy (%d)", archiveInfo.Size())
-		backup, err = a.sendBig(ctx, archive, archiveInfo.Size())
+		a.Logger.Debugf("cloud: using big file strategy (%d)", archive.Size())
+		backup, err = a.sendBig(ctx, archive, archive.Size(), comment, of)
 	}
 
 	if err == nil {
 		a.Logger.Infof("cloud: file “%s” sent successfully to the aws cloud", filename)
-		backup.Size = archiveInfo.Size()
+		backup.Size = archive.Size()
 	}
 
 	return backup, err
 }
 
-func (a *AWSCloud) sendSmall(ctx context.Context, archive io.ReadSeeker) (Backup, error) {
+func (a *AWSCloud) sendSmall(ctx context.Context, archive io.ReadSeeker, comment string, of companion) (Backup, error) {
 	backup := Backup{
 		CreatedAt: a.Clock.Now(),
 		Location:  LocationAWS,
+		MachineID: a.MachineID,
+		Comment:   comment,
+	}
+
+	// computeHashes already rewind the file seek at the beginning and at the end
+	// of the function, so we don't need to wore about it. Small archives are
+	// sent from the memory used to compute the hashes
+	hash, body, release, err := computeHashes(archive, a.hashBufferSize())
+	if err != nil {
+		return Backup{}, errors.WithStack(newError("", ErrorCodeOpeningArchive, err))
 	}
+	defer release()
 
-	// ComputeHashes already rewind the file seek at the beginning and at the end
-	// of the function, so we don't need to wore about it
-	hash := glacier.ComputeHashes(archive)
+	treeHash := hex.EncodeToString(hash.TreeHash())
 
 	uploadArchiveInput := glacier.UploadArchiveInput{
 		AccountId:          aws.String(a.AccountID),
-		ArchiveDescription: aws.String(fmt.Sprintf("backup file from %s", backup.CreatedAt.Format(time.RFC3339))),
-		Body:               archive,
-		Checksum:           aws.String(hex.EncodeToString(hash.TreeHash)),
+		ArchiveDescription: aws.String(archiveDescription(backup, of)),
+		Body:               body,
+		Checksum:           aws.String(treeHash),
 		VaultName:          aws.String(a.VaultName),
 	}
 
-	archiveCreationOutput, err := a.Glacier.UploadArchiveWithContext(ctx, &uploadArchiveInput)
+	archiveCreationOutput, err := a.Glacier.UploadArchiveWithContext(ctx, &uploadArchiveInput, withLinearHash(hash.LinearHash()))
 	if err != nil {
 		return Backup{}, errors.WithStack(a.checkCancellation(newError("", ErrorCodeSendingArchive, err)))
 	}
 
-	if hex.EncodeToString(hash.TreeHash) != *archiveCreationOutput.Checksum {
-		a.Logger.Debugf("cloud: local archive checksum (%s) different from remote checksum (%s)", hex.EncodeToString(hash.TreeHash), *archiveCreationOutput.Checksum)
+	if treeHash != *archiveCreationOutput.Checksum {
+		a.Logger.Debugf("cloud: local archive checksum (%s) different from remote checksum (%s)", treeHash, *archiveCreationOutput.Checksum)
 		return Backup{}, errors.WithStack(newError("", ErrorCodeComparingChecksums, nil))
 	}
 
@@ -225,15 +408,19 @@ func (a *AWSCloud) sendSmall(ctx context.Context, archive io.ReadSeeker) (Backup
 	return backup, nil
 }
 
-func (a *AWSCloud) sendBig(ctx context.Context, archive io.ReadSeeker, archiveSize int64) (Backup, error) {
+func (a *AWSCloud) sendBig(ctx context.Context, archive io.ReadSeeker, archiveSize int64, comment string, of companion) (Backup, error) {
+	partSize := a.partSize()
+
 	backup := Backup{
 		CreatedAt: a.Clock.Now(),
 		Location:  LocationAWS,
+		MachineID: a.MachineID,
+		Comment:   comment,
 	}
 
 	initiateMultipartUploadInput := glacier.InitiateMultipartUploadInput{
 		AccountId:          aws.String(a.AccountID),
-		ArchiveDescription: aws.String(fmt.Sprintf("backup file from %s", backup.CreatedAt.Format(time.RFC3339))),
+		ArchiveDescription: aws.String(archiveDescription(backup, of)),
 		PartSize:           aws.String(strconv.FormatInt(partSize, 10)),
 		VaultName:          aws.String(a.VaultName),
 	}
@@ -243,77 +430,84 @@ func (a *AWSCloud) sendBig(ctx context.Context, archive io.ReadSeeker, archiveSi
 		return Backup{}, errors.WithStack(a.checkCancellation(newError("", ErrorCodeInitMultipart, err)))
 	}
 
-	var offset int64
-	var part = make([]byte, partSize)
+	part := getBuffer(partSize)
+	defer putBuffer(part)
+
+	// the archive tree hash is built from the leaves of the parts tree hashes,
+	// so the archive doesn't need to be read again. This is only possible when
+	// the parts are aligned to the leaves
+	archiveHash := newTreeHash()
+	alignedParts := partSize%hashChunkSize == 0
 
+	progress := newUploadProgress(a.Logger, a.Clock, a.Progress, a.progressInterval(), archiveSize)
+	defer progress.finish()
+
+	var offset int64
 	for offset = 0; offset < archiveSize; offset += partSize {
 		a.Logger.Debugf("cloud: sending part %d/%d", offset, archiveSize)
 
 		var n int
-		if n, err = archive.Read(part); err != nil {
+		if n, err = io.ReadFull(archive, part); err != nil && err != io.ErrUnexpectedEOF {
 			return Backup{}, errors.WithStack(newMultipartError(offset, archiveSize, MultipartErrorCodeReadingArchive, err))
 		}
 
+		hash := newTreeHash()
+		hash.Write(part[:n])
+
+		if alignedParts {
+			archiveHash.appendChunks(hash)
+		} else {
+			archiveHash.Write(part[:n])
+		}
+
+		partTreeHash := hex.EncodeToString(hash.TreeHash())
 		body := bytes.NewReader(part[:n])
-		hash := glacier.ComputeHashes(body)
 
 		uploadMultipartPartInput := glacier.UploadMultipartPartInput{
 			AccountId: aws.String(a.AccountID),
 			Body:      body,
-			Checksum:  aws.String(hex.EncodeToString(hash.TreeHash)),
+			Checksum:  aws.String(partTreeHash),
 			Range:     aws.String(fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(n)-1, archiveSize)),
 			UploadId:  initiateMultipartUploadOutput.UploadId,
 			VaultName: aws.String(a.VaultName),
 		}
 
-		var uploadMultipartPartOutput *glacier.UploadMultipartPartOutput
-		if uploadMultipartPartOutput, err = a.Glacier.UploadMultipartPartWithContext(ctx, &uploadMultipartPartInput); err != nil {
-			abortMultipartUploadInput := glacier.AbortMultipartUploadInput{
-				AccountId: aws.String(a.AccountID),
-				UploadId:  initiateMultipartUploadOutput.UploadId,
-				VaultName: aws.String(a.VaultName),
-			}
+		partCtx, span := trace.Start(ctx, "send part")
+		span.SetAttribute("offset", offset)
+		span.SetAttribute("size", n)
 
-			a.Glacier.AbortMultipartUploadWithContext(ctx, &abortMultipartUploadInput)
+		var uploadMultipartPartOutput *glacier.UploadMultipartPartOutput
+		uploadMultipartPartOutput, err = a.Glacier.UploadMultipartPartWithContext(partCtx, &uploadMultipartPartInput, withLinearHash(hash.LinearHash()))
+		span.End(err)
+		if err != nil {
+			a.abortMultipartUpload(initiateMultipartUploadOutput.UploadId)
 			return Backup{}, errors.WithStack(a.checkCancellation(newMultipartError(offset, archiveSize, MultipartErrorCodeSendingArchive, err)))
 		}
 
 		// verify checksum of each uploaded part
-		if *uploadMultipartPartOutput.Checksum != hex.EncodeToString(hash.TreeHash) {
-			a.Logger.Debugf("cloud: local archive part %d/%d checksum (%s) different from remote checksum (%s)", offset, archiveSize, hex.EncodeToString(hash.TreeHash), *uploadMultipartPartOutput.Checksum)
-
-			abortMultipartUploadInput := glacier.AbortMultipartUploadInput{
-				AccountId: aws.String(a.AccountID),
-				UploadId:  initiateMultipartUploadOutput.UploadId,
-				VaultName: aws.String(a.VaultName),
-			}
+		if *uploadMultipartPartOutput.Checksum != partTreeHash {
+			a.Logger.Debugf("cloud: local archive part %d/%d checksum (%s) different from remote checksum (%s)", offset, archiveSize, partTreeHash, *uploadMultipartPartOutput.Checksum)
 
-			a.Glacier.AbortMultipartUploadWithContext(ctx, &abortMultipartUploadInput)
+			a.abortMultipartUpload(initiateMultipartUploadOutput.UploadId)
 			return Backup{}, errors.WithStack(newMultipartError(offset, archiveSize, MultipartErrorCodeComparingChecksums, err))
 		}
+
+		progress.partSent(offset, int64(n))
 	}
 
-	// ComputeHashes already rewind the file seek at the beginning and at the end
-	// of the function, so we don't need to wore about it
-	hash := glacier.ComputeHashes(archive)
+	treeHash := hex.EncodeToString(archiveHash.TreeHash())
 
 	completeMultipartUploadInput := glacier.CompleteMultipartUploadInput{
 		AccountId:   aws.String(a.AccountID),
 		ArchiveSize: aws.String(strconv.FormatInt(archiveSize, 10)),
-		Checksum:    aws.String(hex.EncodeToString(hash.TreeHash)),
+		Checksum:    aws.String(treeHash),
 		UploadId:    initiateMultipartUploadOutput.UploadId,
 		VaultName:   aws.String(a.VaultName),
 	}
 
 	archiveCreationOutput, err := a.Glacier.CompleteMultipartUploadWithContext(ctx, &completeMultipartUploadInput)
 	if err != nil {
-		abortMultipartUploadInput := glacier.AbortMultipartUploadInput{
-			AccountId: aws.String(a.AccountID),
-			UploadId:  initiateMultipartUploadOutput.UploadId,
-			VaultName: aws.String(a.VaultName),
-		}
-
-		a.Glacier.AbortMultipartUploadWithContext(ctx, &abortMultipartUploadInput)
+		a.abortMultipartUpload(initiateMultipartUploadOutput.UploadId)
 		return Backup{}, errors.WithStack(a.checkCancellation(newError(*initiateMultipartUploadOutput.UploadId, ErrorCodeCompleteMultipart, err)))
 	}
 
@@ -322,8 +516,8 @@ func (a *AWSCloud) sendBig(ctx context.Context, archive io.ReadSeeker, archiveSi
 	backup.Checksum = *archiveCreationOutput.Checksum
 	backup.VaultName = a.VaultName
 
-	if hex.EncodeToString(hash.TreeHash) != *archiveCreationOutput.Checksum {
-		a.Logger.Debugf("cloud: local archive checksum (%s) different from remote checksum (%s)", hex.EncodeToString(hash.TreeHash), *archiveCreationOutput.Checksum)
+	if treeHash != *archiveCreationOutput.Checksum {
+		a.Logger.Debugf("cloud: local archive checksum (%s) different from remote checksum (%s)", treeHash, *archiveCreationOutput.Checksum)
 
 		// something went wrong with the uploaded archive, better remove it
 		if err := a.Remove(ctx, backup.ID); err != nil {
@@ -338,6 +532,21 @@ func (a *AWSCloud) sendBig(ctx context.Context, archive io.ReadSeeker, archiveSi
 	return backup, nil
 }
 
+// abortMultipartUpload discards the parts already sent of a multipart upload,
+// so they aren't kept (and charged) in the cloud. The context of the upload
+// isn't used, as the upload is also aborted when it is cancelled.
+func (a *AWSCloud) abortMultipartUpload(uploadID *string) {
+	abortMultipartUploadInput := glacier.AbortMultipartUploadInput{
+		AccountId: aws.String(a.AccountID),
+		UploadId:  uploadID,
+		VaultName: aws.String(a.VaultName),
+	}
+
+	if _, err := a.Glacier.AbortMultipartUploadWithContext(context.Background(), &abortMultipartUploadInput); err != nil {
+		a.Logger.Debugf("cloud: failed to abort multipart upload %s. details: %s", aws.StringValue(uploadID), err)
+	}
+}
+
 // List retrieves all the uploaded backups information in the cloud. If an error
 // occurs it will be an Error or JobsError type encapsulated in a traceable
 // error. To retrieve the desired error you can do:
@@ -373,7 +582,7 @@ func (a *AWSCloud) List(ctx context.Context) ([]Backup, error) {
 		return nil, errors.WithStack(a.checkCancellation(newError("", ErrorCodeInitJob, err)))
 	}
 
-	if err = a.waitJobs(ctx, *initiateJobOutput.JobId); err != nil {
+	if _, err = a.waitJobs(ctx, *initiateJobOutput.JobId); err != nil {
 		return nil, errors.WithStack(err)
 	}
 
@@ -389,22 +598,26 @@ func (a *AWSCloud) List(ctx context.Context) ([]Backup, error) {
 	}
 	defer jobOutputOutput.Body.Close()
 
-	// http://docs.aws.amazon.com/amazonglacier/latest/dev/api-job-output-get.html#api-job-output-get-responses-elements
-	inventory := struct {
-		VaultARN      string `json:"VaultARN"`
-		InventoryDate string `json:"InventoryDate"`
-		ArchiveList   AWSInventoryArchiveList
-	}{}
-
-	jsonDecoder := json.NewDecoder(jobOutputOutput.Body)
-	if err := jsonDecoder.Decode(&inventory); err != nil {
-		return nil, errors.WithStack(newError(*initiateJobOutput.JobId, ErrorCodeDecodingData, err))
-	}
-
-	sort.Sort(inventory.ArchiveList)
-
 	var backups []Backup
-	for _, archive := range inventory.ArchiveList {
+	companions := make(map[companion]string)
+	companionDates := make(map[companion]time.Time)
+
+	// the inventory of huge vaults doesn't fit in memory, so each archive is
+	// processed as soon as it is decoded
+	err = decodeAWSInventory(jobOutputOutput.Body, func(archive AWSInventoryArchive) {
+		machineID, comment, of := parseArchiveDescription(archive.ArchiveDescription)
+
+		// companion archives (parity and catalog) aren't backups, they are linked
+		// to the backup that they belong to. If the companion was sent more than
+		// once, the newest one is used
+		if of.backupID != "" {
+			if date, ok := companionDates[of]; !ok || !archive.CreationDate.Before(date) {
+				companions[of] = archive.ArchiveID
+				companionDates[of] = archive.CreationDate
+			}
+			return
+		}
+
 		backups = append(backups, Backup{
 			ID:        archive.ArchiveID,
 			CreatedAt: archive.CreationDate,
@@ -412,11 +625,21 @@ func (a *AWSCloud) List(ctx context.Context) ([]Backup, error) {
 			VaultName: a.VaultName,
 			Size:      int64(archive.Size),
 			Location:  LocationAWS,
+			MachineID: machineID,
+			Comment:   comment,
 		})
+	})
+
+	if err != nil {
+		return nil, errors.WithStack(newError(*initiateJobOutput.JobId, ErrorCodeDecodingData, err))
 	}
 
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.Before(backups[j].CreatedAt)
+	})
+
 	a.Logger.Info("cloud: remote backups listed successfully from the aws cloud")
-	return backups, nil
+	return linkCompanions(backups, companions), nil
 }
 
 // Get retrieves a specific backup file and stores it locally in a file. The
@@ -441,9 +664,15 @@ func (a *AWSCloud) List(ctx context.Context) ([]Backup, error) {
 func (a *AWSCloud) Get(ctx context.Context, ids ...string) (map[string]string, error) {
 	a.Logger.Debugf("cloud: retrieving archives “%v” from the aws cloud", ids)
 
+	tracker := retrievalTracker(ctx)
 	jobIDs := make(map[string]string)
 
 	for _, id := range ids {
+		if jobID := a.trackedJob(ctx, tracker, id); jobID != "" {
+			jobIDs[id] = jobID
+			continue
+		}
+
 		initiateJobInput := glacier.InitiateJobInput{
 			AccountId: aws.String(a.AccountID),
 			JobParameters: &glacier.JobParameters{
@@ -459,6 +688,9 @@ func (a *AWSCloud) Get(ctx context.Context, ids ...string) (map[string]string, e
 		}
 
 		jobIDs[id] = *initiateJobOutput.JobId
+		if err = tracker.JobInitiated(id, jobIDs[id]); err != nil {
+			a.Logger.Warningf("cloud: failed to persist the job “%s” retrieving backup “%s”. details: %s", jobIDs[id], id, err)
+		}
 	}
 
 	jobs := make([]string, 0, len(jobIDs))
@@ -466,7 +698,11 @@ func (a *AWSCloud) Get(ctx context.Context, ids ...string) (map[string]string, e
 		jobs = append(jobs, job)
 	}
 
-	if err := a.waitJobs(ctx, jobs...); err != nil {
+	jobsCtx, span := trace.Start(ctx, "wait jobs")
+	span.SetAttribute("jobs", len(jobs))
+	checksums, err := a.waitJobs(jobsCtx, jobs...)
+	span.End(err)
+	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
@@ -475,7 +711,7 @@ func (a *AWSCloud) Get(ctx context.Context, ids ...string) (map[string]string, e
 
 	for id, jobID := range jobIDs {
 		waitGroup.Add(1)
-		go a.get(ctx, id, jobID, &waitGroup, jobResults)
+		go a.get(ctx, id, jobID, checksums[jobID], &waitGroup, jobResults)
 	}
 
 	waitGroup.Wait()
@@ -492,49 +728,173 @@ func (a *AWSCloud) Get(ctx context.Context, ids ...string) (map[string]string, e
 	return filenames, nil
 }
 
-func (a *AWSCloud) get(ctx context.Context, id, jobID string, waitGroup *sync.WaitGroup, result chan<- jobResult) {
+// trackedJob returns the job initiated in a previous attempt to retrieve the
+// archive, when it still exists in the cloud and didn't fail. The jobs output
+// is only available for some hours after completing.
+func (a *AWSCloud) trackedJob(ctx context.Context, tracker RetrievalTracker, id string) string {
+	jobID := tracker.Job(id)
+	if jobID == "" {
+		return ""
+	}
+
+	job, err := a.DescribeJob(ctx, jobID)
+	if err != nil {
+		a.Logger.Debugf("cloud: job “%s” retrieving backup “%s” not available anymore. details: %s", jobID, id, err)
+		return ""
+	} else if job.Status == JobStatusFailed {
+		a.Logger.Debugf("cloud: job “%s” retrieving backup “%s” failed", jobID, id)
+		return ""
+	}
+
+	a.Logger.Infof("cloud: resuming job “%s” retrieving backup “%s” from the aws cloud", jobID, id)
+	return jobID
+}
+
+// get downloads the archive, retrying when the downloaded data doesn't match
+// the expected tree hash, as a corrupted archive would only be detected later
+// with confusing errors while decrypting or extracting it.
+func (a *AWSCloud) get(ctx context.Context, id, jobID, checksum string, waitGroup *sync.WaitGroup, result chan<- jobResult) {
 	defer waitGroup.Done()
 
+	retries := a.downloadRetries()
+
+	for attempt := 0; ; attempt++ {
+		downloadCtx, span := trace.Start(ctx, "download archive")
+		span.SetAttribute("archive.id", id)
+		span.SetAttribute("attempt", attempt)
+		filename, err := a.download(downloadCtx, id, jobID, checksum)
+		span.End(err)
+		if err != nil {
+			if errors.Is(err, ErrChecksumMismatch) && attempt < retries {
+				a.Logger.Warningf("cloud: backup “%s” corrupted while downloading from the aws cloud, trying again", id)
+				continue
+			}
+
+			result <- jobResult{
+				id:  id,
+				err: errors.WithStack(err),
+			}
+			return
+		}
+
+		a.Logger.Infof("cloud: backup “%s” retrieved successfully from the aws cloud and saved in temporary file “%s”", id, filename)
+
+		result <- jobResult{
+			id:       id,
+			filename: filename,
+		}
+		return
+	}
+}
+
+// download stores the job output in a temporary file, computing the tree hash
+// while the data is written. Each part stored is reported to the retrieval
+// tracker, and the download continues after the parts stored in a previous
+// attempt. When the tree hash doesn't match the checksum the file is removed.
+// An empty checksum disables the verification.
+func (a *AWSCloud) download(ctx context.Context, id, jobID, checksum string) (string, error) {
+	tracker := retrievalTracker(ctx)
+
+	backup, err := os.OpenFile(path.Join(os.TempDir(), "backup-"+id+".tar"), os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return "", errors.WithStack(newError(id, ErrorCodeCreatingArchive, err))
+	}
+	defer backup.Close()
+
+	offset, hash, err := a.resumeDownload(tracker, id, backup)
+	if err != nil {
+		return "", errors.WithStack(newError(id, ErrorCodeCreatingArchive, err))
+	}
+
 	jobOutputInput := glacier.GetJobOutputInput{
 		AccountId: aws.String(a.AccountID),
 		JobId:     aws.String(jobID),
 		VaultName: aws.String(a.VaultName),
 	}
 
+	if offset > 0 {
+		a.Logger.Infof("cloud: resuming the download of backup “%s” after %d bytes", id, offset)
+		jobOutputInput.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+
 	jobOutputOutput, err := a.Glacier.GetJobOutputWithContext(ctx, &jobOutputInput)
 	if err != nil {
-		result <- jobResult{
-			id:  id,
-			err: errors.WithStack(a.checkCancellation(newError(id, ErrorCodeJobComplete, err))),
-		}
-		return
+		return "", errors.WithStack(a.checkCancellation(newError(id, ErrorCodeJobComplete, err)))
 	}
 	defer jobOutputOutput.Body.Close()
 
-	backup, err := os.Create(path.Join(os.TempDir(), "backup-"+id+".tar"))
-	if err != nil {
-		result <- jobResult{
-			id:  id,
-			err: errors.WithStack(newError(id, ErrorCodeCreatingArchive, err)),
+	output := io.MultiWriter(backup, hash)
+	for {
+		n, copyErr := io.CopyN(output, jobOutputOutput.Body, a.partSize())
+		if copyErr != nil && copyErr != io.EOF {
+			return "", errors.WithStack(a.checkCancellation(newError(id, ErrorCodeCopyingData, copyErr)))
+		}
+
+		if n > 0 {
+			offset += n
+
+			// the part must be in the disk before it is reported as downloaded
+			if err = backup.Sync(); err != nil {
+				return "", errors.WithStack(newError(id, ErrorCodeCopyingData, err))
+			}
+
+			if err = tracker.PartDownloaded(id, backup.Name(), offset); err != nil {
+				a.Logger.Warningf("cloud: failed to persist the download progress of backup “%s”. details: %s", id, err)
+			}
+		}
+
+		if copyErr == io.EOF {
+			break
 		}
-		return
 	}
-	defer backup.Close()
 
-	if _, err := io.Copy(backup, jobOutputOutput.Body); err != nil {
-		result <- jobResult{
-			id:  id,
-			err: errors.WithStack(newError(id, ErrorCodeCopyingData, err)),
+	if checksum == "" {
+		a.Logger.Debugf("cloud: no checksum available for backup “%s”, skipping verification", id)
+		return backup.Name(), nil
+	}
+
+	if treeHash := hex.EncodeToString(hash.TreeHash()); treeHash != checksum {
+		a.Logger.Debugf("cloud: downloaded archive checksum (%s) different from remote checksum (%s)", treeHash, checksum)
+		backup.Close()
+		os.Remove(backup.Name())
+
+		if err := tracker.PartDownloaded(id, backup.Name(), 0); err != nil {
+			a.Logger.Warningf("cloud: failed to persist the download progress of backup “%s”. details: %s", id, err)
 		}
-		return
+		return "", errors.WithStack(newError(id, ErrorCodeComparingChecksums, nil))
+	}
+
+	return backup.Name(), nil
+}
+
+// resumeDownload keeps the bytes of the archive stored in a previous attempt,
+// as reported to the retrieval tracker, and returns the position where the
+// download continues with the tree hash of the bytes kept. Anything stored
+// after the last reported part is discarded.
+func (a *AWSCloud) resumeDownload(tracker RetrievalTracker, id string, backup *os.File) (int64, *treeHash, error) {
+	hash := newTreeHash()
+
+	filename, offset := tracker.Part(id)
+	if filename != backup.Name() {
+		offset = 0
 	}
 
-	a.Logger.Infof("cloud: backup “%s” retrieved successfully from the aws cloud and saved in temporary file “%s”", id, backup.Name())
+	if offset > 0 {
+		if _, err := io.CopyN(hash, backup, offset); err != nil {
+			a.Logger.Debugf("cloud: downloaded parts of backup “%s” not available, downloading it again. details: %s", id, err)
+			hash, offset = newTreeHash(), 0
+		}
+	}
+
+	if err := backup.Truncate(offset); err != nil {
+		return 0, nil, err
+	}
 
-	result <- jobResult{
-		id:       id,
-		filename: backup.Name(),
+	if _, err := backup.Seek(offset, io.SeekStart); err != nil {
+		return 0, nil, err
 	}
+
+	return offset, hash, nil
 }
 
 // Remove erase a specific backup from the cloud. If an error occurs it will be
@@ -570,19 +930,348 @@ func (a *AWSCloud) Remove(ctx context.Context, id string) error {
 	return nil
 }
 
+// InitiateLock attaches the lock policy to the vault, returning the lock
+// identifier that must be used to complete the lock in 24 hours. If an error
+// occurs it will be an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AWSCloud) InitiateLock(ctx context.Context, policy string) (string, error) {
+	a.Logger.Debugf("cloud: initiating lock of vault %s in the aws cloud", a.VaultName)
+
+	initiateVaultLockInput := glacier.InitiateVaultLockInput{
+		AccountId: aws.String(a.AccountID),
+		Policy: &glacier.VaultLockPolicy{
+			Policy: aws.String(policy),
+		},
+		VaultName: aws.String(a.VaultName),
+	}
+
+	initiateVaultLockOutput, err := a.Glacier.InitiateVaultLockWithContext(ctx, &initiateVaultLockInput)
+	if err != nil {
+		return "", errors.WithStack(a.checkCancellation(newError("", ErrorCodeInitiatingVaultLock, err)))
+	}
+
+	a.Logger.Infof("cloud: lock of vault “%s” initiated successfully in the aws cloud", a.VaultName)
+	return *initiateVaultLockOutput.LockId, nil
+}
+
+// CompleteLock makes the vault lock policy immutable. If an error occurs it
+// will be an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AWSCloud) CompleteLock(ctx context.Context, lockID string) error {
+	a.Logger.Debugf("cloud: completing lock %s of vault %s in the aws cloud", lockID, a.VaultName)
+
+	completeVaultLockInput := glacier.CompleteVaultLockInput{
+		AccountId: aws.String(a.AccountID),
+		LockId:    aws.String(lockID),
+		VaultName: aws.String(a.VaultName),
+	}
+
+	if _, err := a.Glacier.CompleteVaultLockWithContext(ctx, &completeVaultLockInput); err != nil {
+		return errors.WithStack(a.checkCancellation(newError(lockID, ErrorCodeCompletingVaultLock, err)))
+	}
+
+	a.Logger.Infof("cloud: lock of vault “%s” completed successfully in the aws cloud", a.VaultName)
+	return nil
+}
+
+// AbortLock removes the vault lock policy that wasn't completed yet. If an
+// error occurs it will be an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AWSCloud) AbortLock(ctx context.Context) error {
+	a.Logger.Debugf("cloud: aborting lock of vault %s in the aws cloud", a.VaultName)
+
+	abortVaultLockInput := glacier.AbortVaultLockInput{
+		AccountId: aws.String(a.AccountID),
+		VaultName: aws.String(a.VaultName),
+	}
+
+	if _, err := a.Glacier.AbortVaultLockWithContext(ctx, &abortVaultLockInput); err != nil {
+		return errors.WithStack(a.checkCancellation(newError("", ErrorCodeAbortingVaultLock, err)))
+	}
+
+	a.Logger.Infof("cloud: lock of vault “%s” aborted successfully in the aws cloud", a.VaultName)
+	return nil
+}
+
+// SetAccessPolicy replaces the access policy of the vault, or removes it when
+// the policy is empty. If an error occurs it will be an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AWSCloud) SetAccessPolicy(ctx context.Context, policy string) error {
+	a.Logger.Debugf("cloud: changing access policy of vault %s in the aws cloud", a.VaultName)
+
+	if policy == "" {
+		deleteVaultAccessPolicyInput := glacier.DeleteVaultAccessPolicyInput{
+			AccountId: aws.String(a.AccountID),
+			VaultName: aws.String(a.VaultName),
+		}
+
+		if _, err := a.Glacier.DeleteVaultAccessPolicyWithContext(ctx, &deleteVaultAccessPolicyInput); err != nil {
+			return errors.WithStack(a.checkCancellation(newError("", ErrorCodeVaultAccessPolicy, err)))
+		}
+
+		a.Logger.Infof("cloud: access policy of vault “%s” removed successfully in the aws cloud", a.VaultName)
+		return nil
+	}
+
+	setVaultAccessPolicyInput := glacier.SetVaultAccessPolicyInput{
+		AccountId: aws.String(a.AccountID),
+		Policy: &glacier.VaultAccessPolicy{
+			Policy: aws.String(policy),
+		},
+		VaultName: aws.String(a.VaultName),
+	}
+
+	if _, err := a.Glacier.SetVaultAccessPolicyWithContext(ctx, &setVaultAccessPolicyInput); err != nil {
+		return errors.WithStack(a.checkCancellation(newError("", ErrorCodeVaultAccessPolicy, err)))
+	}
+
+	a.Logger.Infof("cloud: access policy of vault “%s” changed successfully in the aws cloud", a.VaultName)
+	return nil
+}
+
+// SetTags reconciles the vault tags with the given ones. Only the differences
+// are sent to the cloud. If an error occurs it will be an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AWSCloud) SetTags(ctx context.Context, tags map[string]string) error {
+	a.Logger.Debugf("cloud: changing tags of vault %s in the aws cloud", a.VaultName)
+
+	listTagsForVaultInput := glacier.ListTagsForVaultInput{
+		AccountId: aws.String(a.AccountID),
+		VaultName: aws.String(a.VaultName),
+	}
+
+	listTagsForVaultOutput, err := a.Glacier.ListTagsForVaultWithContext(ctx, &listTagsForVaultInput)
+	if err != nil {
+		return errors.WithStack(a.checkCancellation(newError("", ErrorCodeVaultTags, err)))
+	}
+
+	var removeKeys []*string
+	for key := range listTagsForVaultOutput.Tags {
+		if _, ok := tags[key]; !ok {
+			removeKeys = append(removeKeys, aws.String(key))
+		}
+	}
+
+	addTags := make(map[string]*string)
+	for key, value := range tags {
+		if current, ok := listTagsForVaultOutput.Tags[key]; !ok || current == nil || *current != value {
+			addTags[key] = aws.String(value)
+		}
+	}
+
+	if len(removeKeys) > 0 {
+		// keep the order predictable, as it's easier to follow in the logs
+		sort.Slice(removeKeys, func(i, j int) bool {
+			return *removeKeys[i] < *removeKeys[j]
+		})
+
+		removeTagsFromVaultInput := glacier.RemoveTagsFromVaultInput{
+			AccountId: aws.String(a.AccountID),
+			TagKeys:   removeKeys,
+			VaultName: aws.String(a.VaultName),
+		}
+
+		if _, err = a.Glacier.RemoveTagsFromVaultWithContext(ctx, &removeTagsFromVaultInput); err != nil {
+			return errors.WithStack(a.checkCancellation(newError("", ErrorCodeVaultTags, err)))
+		}
+	}
+
+	if len(addTags) > 0 {
+		addTagsToVaultInput := glacier.AddTagsToVaultInput{
+			AccountId: aws.String(a.AccountID),
+			Tags:      addTags,
+			VaultName: aws.String(a.VaultName),
+		}
+
+		if _, err = a.Glacier.AddTagsToVaultWithContext(ctx, &addTagsToVaultInput); err != nil {
+			return errors.WithStack(a.checkCancellation(newError("", ErrorCodeVaultTags, err)))
+		}
+	}
+
+	a.Logger.Infof("cloud: tags of vault “%s” changed successfully in the aws cloud (%d added or updated, %d removed)", a.VaultName, len(addTags), len(removeKeys))
+	return nil
+}
+
+// ListJobs retrieves the archive and inventory retrieval jobs of the vault,
+// including the ones completed recently. If an error occurs it will be an Error
+// type encapsulated in a traceable error. To retrieve the desired error you can
+// do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AWSCloud) ListJobs(ctx context.Context) ([]Job, error) {
+	a.Logger.Debugf("cloud: listing jobs of vault %s in the aws cloud", a.VaultName)
+
+	var jobs []Job
+	var marker *string
+
+	for {
+		listJobsInput := glacier.ListJobsInput{
+			AccountId: aws.String(a.AccountID),
+			VaultName: aws.String(a.VaultName),
+			Marker:    marker,
+		}
+
+		listJobsOutput, err := a.Glacier.ListJobsWithContext(ctx, &listJobsInput)
+		if err != nil {
+			return nil, errors.WithStack(a.checkCancellation(newError("", ErrorCodeListingJobs, err)))
+		}
+
+		for _, jobDescription := range listJobsOutput.JobList {
+			jobs = append(jobs, newAWSJob(jobDescription))
+		}
+
+		if aws.StringValue(listJobsOutput.Marker) == "" {
+			break
+		}
+		marker = listJobsOutput.Marker
+	}
+
+	a.Logger.Infof("cloud: %d jobs listed in vault “%s” in the aws cloud", len(jobs), a.VaultName)
+	return jobs, nil
+}
+
+// DescribeJob retrieves the current status of the job. If an error occurs it
+// will be an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AWSCloud) DescribeJob(ctx context.Context, id string) (Job, error) {
+	a.Logger.Debugf("cloud: describing job %s of vault %s in the aws cloud", id, a.VaultName)
+
+	describeJobInput := glacier.DescribeJobInput{
+		AccountId: aws.String(a.AccountID),
+		JobId:     aws.String(id),
+		VaultName: aws.String(a.VaultName),
+	}
+
+	jobDescription, err := a.Glacier.DescribeJobWithContext(ctx, &describeJobInput)
+	if err != nil {
+		return Job{}, errors.WithStack(a.checkCancellation(newError(id, ErrorCodeDescribingJob, err)))
+	}
+
+	return newAWSJob(jobDescription), nil
+}
+
+// newAWSJob converts the job description of the AWS Glacier. The dates are in
+// the ISO 8601 format.
+func newAWSJob(jobDescription *glacier.JobDescription) Job {
+	job := Job{
+		ID:            aws.StringValue(jobDescription.JobId),
+		Action:        aws.StringValue(jobDescription.Action),
+		ArchiveID:     aws.StringValue(jobDescription.ArchiveId),
+		Tier:          aws.StringValue(jobDescription.Tier),
+		Status:        JobStatus(aws.StringValue(jobDescription.StatusCode)),
+		StatusMessage: aws.StringValue(jobDescription.StatusMessage),
+	}
+
+	// an invalid date is left empty, as it is only informative
+	job.CreatedAt, _ = time.Parse(time.RFC3339Nano, aws.StringValue(jobDescription.CreationDate))
+	job.CompletedAt, _ = time.Parse(time.RFC3339Nano, aws.StringValue(jobDescription.CompletionDate))
+	return job
+}
+
 // Close ends the AWS session. As there's nothing to close here, this will not
 // perform any action.
 func (a *AWSCloud) Close() error {
 	return nil
 }
 
-func (a *AWSCloud) waitJobs(ctx context.Context, jobs ...string) error {
+// waitJobs blocks until all jobs succeed, returning the SHA256 tree hash of the
+// archives retrieved by each job, indexed by the job identifier.
+func (a *AWSCloud) waitJobs(ctx context.Context, jobs ...string) (map[string]string, error) {
 	sort.Strings(jobs)
 	a.Logger.Debugf("cloud: waiting for jobs %v", jobs)
 
-	waitJobTime.RLock()
-	sleep := waitJobTime.Duration
-	waitJobTime.RUnlock()
+	sleep := a.waitJobTime()
+
+	checksums := make(map[string]string)
 
 	for {
 		listJobsInput := glacier.ListJobsInput{
@@ -592,7 +1281,7 @@ func (a *AWSCloud) waitJobs(ctx context.Context, jobs ...string) error {
 
 		listJobsOutput, err := a.Glacier.ListJobsWithContext(ctx, &listJobsInput)
 		if err != nil {
-			return errors.WithStack(a.checkCancellation(newJobsError(jobs, JobsErrorCodeRetrievingJob, err)))
+			return nil, errors.WithStack(a.checkCancellation(newJobsError(jobs, JobsErrorCodeRetrievingJob, err)))
 		}
 
 		jobsRemaining := make([]string, len(jobs))
@@ -623,15 +1312,16 @@ func (a *AWSCloud) waitJobs(ctx context.Context, jobs ...string) error {
 			if *jobDescription.StatusCode == "Succeeded" {
 				// remove the job that already succeeded
 				jobs = append(jobs[:i], jobs[i+1:]...)
+				checksums[*jobDescription.JobId] = aws.StringValue(jobDescription.SHA256TreeHash)
 				a.Logger.Debugf("cloud: job %s succeeded, still need to proccess jobs %v", *jobDescription.JobId, jobs)
 
 			} else if *jobDescription.StatusCode == "Failed" {
-				return errors.WithStack(newError(*jobDescription.JobId, ErrorCodeJobFailed, errors.New(*jobDescription.StatusMessage)))
+				return nil, errors.WithStack(newError(*jobDescription.JobId, ErrorCodeJobFailed, errors.New(*jobDescription.StatusMessage)))
 			}
 		}
 
 		if len(jobsRemaining) > 0 {
-			return errors.WithStack(newJobsError(jobsRemaining, JobsErrorCodeJobNotFound, nil))
+			return nil, errors.WithStack(newJobsError(jobsRemaining, JobsErrorCodeJobNotFound, nil))
 		}
 
 		if len(jobs) == 0 {
@@ -646,11 +1336,11 @@ func (a *AWSCloud) waitJobs(ctx context.Context, jobs ...string) error {
 			continue
 		case <-ctx.Done():
 			a.Logger.Debugf("cloud: jobs %v cancelled by user", jobs)
-			return errors.WithStack(newJobsError(jobs, JobsErrorCodeCancelled, ctx.Err()))
+			return nil, errors.WithStack(newJobsError(jobs, JobsErrorCodeCancelled, ctx.Err()))
 		}
 	}
 
-	return nil
+	return checksums, nil
 }
 
 func (a *AWSCloud) checkCancellation(err error) error {
@@ -683,9 +1373,9 @@ func (a *AWSCloud) checkCancellation(err error) error {
 	return err
 }
 
-// AWSInventoryArchiveList stores the archive information retrieved from AWS
+// AWSInventoryArchive stores the information of an archive retrieved from AWS
 // Glacier service.
-type AWSInventoryArchiveList []struct {
+type AWSInventoryArchive struct {
 	ArchiveID          string    `json:"ArchiveId"`
 	ArchiveDescription string    `json:"ArchiveDescription"`
 	CreationDate       time.Time `json:"CreationDate"`
@@ -693,14 +1383,61 @@ type AWSInventoryArchiveList []struct {
 	SHA256TreeHash     string    `json:"SHA256TreeHash"`
 }
 
-func (a AWSInventoryArchiveList) Len() int {
-	return len(a)
-}
+// decodeAWSInventory walks through the inventory JSON, calling handle for each
+// archive of the ArchiveList without loading the whole list in memory. The
+// other inventory attributes are ignored.
+//
+// http://docs.aws.amazon.com/amazonglacier/latest/dev/api-job-output-get.html#api-job-output-get-responses-elements
+func decodeAWSInventory(r io.Reader, handle func(AWSInventoryArchive)) error {
+	decoder := json.NewDecoder(r)
+
+	if err := expectJSONDelim(decoder, '{'); err != nil {
+		return err
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		if key, ok := token.(string); !ok || key != "ArchiveList" {
+			var ignored json.RawMessage
+			if err = decoder.Decode(&ignored); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = expectJSONDelim(decoder, '['); err != nil {
+			return err
+		}
 
-func (a AWSInventoryArchiveList) Less(i, j int) bool {
-	return a[i].CreationDate.Before(a[j].CreationDate)
+		for decoder.More() {
+			var archive AWSInventoryArchive
+			if err = decoder.Decode(&archive); err != nil {
+				return err
+			}
+			handle(archive)
+		}
+
+		if err = expectJSONDelim(decoder, ']'); err != nil {
+			return err
+		}
+	}
+
+	return expectJSONDelim(decoder, '}')
 }
 
-func (a AWSInventoryArchiveList) Swap(i, j int) {
-	a[i], a[j] = a[j], a[i]
+func expectJSONDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if token != delim {
+		return fmt.Errorf("unexpected token “%v”, expected “%s”", token, delim)
+	}
+
+	return nil
 }
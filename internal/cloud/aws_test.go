@@ -3,12 +3,14 @@ package cloud_test
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"path"
 	"reflect"
@@ -32,7 +34,7 @@ func TestNewAWSCloud(t *testing.T) {
 		description   string
 		logger        log.Logger
 		config        cloud.AWSConfig
-		debug         bool
+		trace         bool
 		expected      *cloud.AWSCloud
 		expectedEnv   map[string]string
 		expectedError error
@@ -45,11 +47,38 @@ func TestNewAWSCloud(t *testing.T) {
 				SecretAccessKey: "secret",
 				Region:          "us-east-1",
 				VaultName:       "vault",
+				MachineID:       "server1",
 			},
-			debug: true,
+			trace: true,
 			expected: &cloud.AWSCloud{
 				AccountID: "account",
 				VaultName: "vault",
+				MachineID: "server1",
+			},
+			expectedEnv: map[string]string{
+				"AWS_ACCESS_KEY_ID":     "keyid",
+				"AWS_SECRET_ACCESS_KEY": "secret",
+				"AWS_REGION":            "us-east-1",
+			},
+		},
+		{
+			description: "it should build a AWS cloud instance with custom upload settings",
+			config: cloud.AWSConfig{
+				AccountID:            "account",
+				AccessKeyID:          "keyid",
+				SecretAccessKey:      "secret",
+				Region:               "us-east-1",
+				VaultName:            "vault",
+				MachineID:            "server1",
+				MultipartUploadLimit: 1048576,
+				PartSize:             1048576,
+			},
+			expected: &cloud.AWSCloud{
+				AccountID:            "account",
+				VaultName:            "vault",
+				MachineID:            "server1",
+				MultipartUploadLimit: 1048576,
+				PartSize:             1048576,
 			},
 			expectedEnv: map[string]string{
 				"AWS_ACCESS_KEY_ID":     "keyid",
@@ -63,7 +92,7 @@ func TestNewAWSCloud(t *testing.T) {
 		t.Run(scenario.description, func(t *testing.T) {
 			os.Clearenv()
 
-			awsCloud, err := cloud.NewAWSCloud(scenario.logger, scenario.config, scenario.debug)
+			awsCloud, err := cloud.NewAWSCloud(scenario.logger, scenario.config, scenario.trace)
 
 			// we are not interested on testing low level structures from AWS library
 			// or clock controlling layer
@@ -88,17 +117,16 @@ func TestNewAWSCloud(t *testing.T) {
 }
 
 func TestAWSCloud_Send(t *testing.T) {
-	defer cloud.MultipartUploadLimit(102400)
-	defer cloud.PartSize(4096)
-
 	ctx := context.Background()
 	ctx, cancel := context.WithCancel(ctx)
 
 	scenarios := []struct {
 		description          string
 		filename             string
+		comment              string
 		multipartUploadLimit int64
 		partSize             int64
+		hashBufferSize       int64
 		awsCloud             cloud.AWSCloud
 		randomSource         io.Reader
 		goFunc               func()
@@ -150,8 +178,67 @@ func TestAWSCloud_Send(t *testing.T) {
 				},
 				AccountID: "account",
 				VaultName: "vault",
+				MachineID: "server1",
 				Glacier: mockGlacierAPI{
-					mockUploadArchiveWithContext: func(aws.Context, *glacier.UploadArchiveInput, ...request.Option) (*glacier.ArchiveCreationOutput, error) {
+					mockUploadArchiveWithContext: func(ctx aws.Context, input *glacier.UploadArchiveInput, opts ...request.Option) (*glacier.ArchiveCreationOutput, error) {
+						if description := *input.ArchiveDescription; description != "backup file from 2016-12-27T08:14:53Z (machine server1)" {
+							return nil, fmt.Errorf("unexpected archive description “%s”", description)
+						}
+
+						return &glacier.ArchiveCreationOutput{
+							ArchiveId: aws.String("AWSID123"),
+							Checksum:  aws.String("cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705"),
+							Location:  aws.String("/archive/AWSID123"),
+						}, nil
+					},
+				},
+				Clock: fakeClock{
+					mockNow: func() time.Time {
+						return time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC)
+					},
+				},
+			},
+			expected: cloud.Backup{
+				ID:        "AWSID123",
+				CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+				Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+				VaultName: "vault",
+				Size:      41,
+				Location:  cloud.LocationAWS,
+				MachineID: "server1",
+			},
+		},
+		{
+			description: "it should send a small backup with a comment",
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test-")
+				if err != nil {
+					t.Fatalf("error creating file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString("Important information for the test backup")
+				return f.Name()
+			}(),
+			comment:              `before "OS" upgrade ✓`,
+			multipartUploadLimit: 102400,
+			partSize:             4096,
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				MachineID: "server1",
+				Glacier: mockGlacierAPI{
+					mockUploadArchiveWithContext: func(ctx aws.Context, input *glacier.UploadArchiveInput, opts ...request.Option) (*glacier.ArchiveCreationOutput, error) {
+						if description := *input.ArchiveDescription; description != `backup file from 2016-12-27T08:14:53Z "before _OS_ upgrade _" (machine server1)` {
+							return nil, fmt.Errorf("unexpected archive description “%s”", description)
+						}
+
 						return &glacier.ArchiveCreationOutput{
 							ArchiveId: aws.String("AWSID123"),
 							Checksum:  aws.String("cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705"),
@@ -172,6 +259,57 @@ func TestAWSCloud_Send(t *testing.T) {
 				VaultName: "vault",
 				Size:      41,
 				Location:  cloud.LocationAWS,
+				MachineID: "server1",
+				Comment:   `before "OS" upgrade ✓`,
+			},
+		},
+		{
+			description: "it should send a small backup bigger than the hash buffer",
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test-")
+				if err != nil {
+					t.Fatalf("error creating file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString(strings.Repeat("Important information for the test backup\n", 60000))
+				return f.Name()
+			}(),
+			multipartUploadLimit: 104857600,
+			partSize:             4096,
+			hashBufferSize:       1048576,
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockUploadArchiveWithContext: func(ctx aws.Context, input *glacier.UploadArchiveInput, opts ...request.Option) (*glacier.ArchiveCreationOutput, error) {
+						hash := glacier.ComputeHashes(input.Body)
+						return &glacier.ArchiveCreationOutput{
+							ArchiveId: aws.String("AWSID123"),
+							Checksum:  aws.String(hex.EncodeToString(hash.TreeHash)),
+							Location:  aws.String("/archive/AWSID123"),
+						}, nil
+					},
+				},
+				Clock: fakeClock{
+					mockNow: func() time.Time {
+						return time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC)
+					},
+				},
+			},
+			expected: cloud.Backup{
+				ID:        "AWSID123",
+				CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+				Checksum:  "a97de4fe3156cf3a1f5c8505ca3e094e0f457a50870210a2dfa5e0aaec3c8b90",
+				VaultName: "vault",
+				Size:      2520000,
+				Location:  cloud.LocationAWS,
 			},
 		},
 		{
@@ -324,6 +462,68 @@ func TestAWSCloud_Send(t *testing.T) {
 				Location:  cloud.LocationAWS,
 			},
 		},
+		{
+			description: "it should send a big backup with parts aligned to the tree hash leaves",
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test-")
+				if err != nil {
+					t.Fatalf("error creating file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString(strings.Repeat("Important information for the test backup\n", 60000))
+				return f.Name()
+			}(),
+			multipartUploadLimit: 1024,
+			partSize:             1048576,
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockInitiateMultipartUploadWithContext: func(aws.Context, *glacier.InitiateMultipartUploadInput, ...request.Option) (*glacier.InitiateMultipartUploadOutput, error) {
+						return &glacier.InitiateMultipartUploadOutput{
+							UploadId: aws.String("UPLOAD123"),
+						}, nil
+					},
+					mockUploadMultipartPartWithContext: func(ctx aws.Context, u *glacier.UploadMultipartPartInput, opts ...request.Option) (*glacier.UploadMultipartPartOutput, error) {
+						hash := glacier.ComputeHashes(u.Body)
+						return &glacier.UploadMultipartPartOutput{
+							Checksum: aws.String(hex.EncodeToString(hash.TreeHash)),
+						}, nil
+					},
+					mockCompleteMultipartUploadWithContext: func(ctx aws.Context, c *glacier.CompleteMultipartUploadInput, opts ...request.Option) (*glacier.ArchiveCreationOutput, error) {
+						if *c.Checksum != "a97de4fe3156cf3a1f5c8505ca3e094e0f457a50870210a2dfa5e0aaec3c8b90" {
+							return nil, fmt.Errorf("unexpected archive checksum “%s”", *c.Checksum)
+						}
+
+						return &glacier.ArchiveCreationOutput{
+							ArchiveId: aws.String("AWSID123"),
+							Checksum:  c.Checksum,
+							Location:  aws.String("/archive/AWSID123"),
+						}, nil
+					},
+				},
+				Clock: fakeClock{
+					mockNow: func() time.Time {
+						return time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC)
+					},
+				},
+			},
+			expected: cloud.Backup{
+				ID:        "AWSID123",
+				CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+				Checksum:  "a97de4fe3156cf3a1f5c8505ca3e094e0f457a50870210a2dfa5e0aaec3c8b90",
+				VaultName: "vault",
+				Size:      2520000,
+				Location:  cloud.LocationAWS,
+			},
+		},
 		{
 			description: "it should detect an error initiating a big backup upload",
 			filename: func() string {
@@ -726,7 +926,12 @@ func TestAWSCloud_Send(t *testing.T) {
 							Location:  aws.String("/archive/AWSID123"),
 						}, nil
 					},
-					mockAbortMultipartUploadWithContext: func(aws.Context, *glacier.AbortMultipartUploadInput, ...request.Option) (*glacier.AbortMultipartUploadOutput, error) {
+					mockAbortMultipartUploadWithContext: func(ctx aws.Context, a *glacier.AbortMultipartUploadInput, opts ...request.Option) (*glacier.AbortMultipartUploadOutput, error) {
+						// the parts already sent must be discarded even when the
+						// upload was cancelled
+						if ctx.Err() != nil {
+							t.Error("multipart upload aborted with a cancelled context")
+						}
 						return nil, nil
 					},
 				},
@@ -752,14 +957,15 @@ func TestAWSCloud_Send(t *testing.T) {
 
 	for _, scenario := range scenarios {
 		t.Run(scenario.description, func(t *testing.T) {
-			cloud.MultipartUploadLimit(scenario.multipartUploadLimit)
-			cloud.PartSize(scenario.partSize)
+			scenario.awsCloud.MultipartUploadLimit = scenario.multipartUploadLimit
+			scenario.awsCloud.PartSize = scenario.partSize
+			scenario.awsCloud.HashBufferSize = scenario.hashBufferSize
 
 			if scenario.goFunc != nil {
 				go scenario.goFunc()
 			}
 
-			backup, err := scenario.awsCloud.Send(ctx, scenario.filename)
+			backup, err := scenario.awsCloud.Send(ctx, scenario.filename, scenario.comment)
 			if !reflect.DeepEqual(scenario.expected, backup) {
 				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backup))
 			}
@@ -770,99 +976,424 @@ func TestAWSCloud_Send(t *testing.T) {
 	}
 }
 
-func TestAWSCloud_List(t *testing.T) {
-	defer cloud.WaitJobTime(time.Minute)
-	cloud.WaitJobTime(100 * time.Millisecond)
-
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
-
+func TestAWSCloud_SendProgress(t *testing.T) {
 	scenarios := []struct {
-		description   string
-		awsCloud      cloud.AWSCloud
-		goFunc        func()
-		expected      []cloud.Backup
-		expectedError error
+		description      string
+		parts            int
+		clockSteps       []time.Duration
+		progressInterval time.Duration
+		expected         []string
+		expectedStatus   cloud.UploadStatus
 	}{
 		{
-			description: "it should list all backups correctly",
-			awsCloud: cloud.AWSCloud{
+			description:      "it should log the progress at most once per interval",
+			parts:            3,
+			progressInterval: 15 * time.Second,
+			expected: []string{
+				"cloud: upload progress offset=1048576 size=1048576 sent=2097152 total=3145728 progress=66.7% throughput=104857B/s eta=10s",
+			},
+			expectedStatus: cloud.UploadStatus{
+				Sent:       3145728,
+				Total:      3145728,
+				Throughput: 104857.6,
+			},
+		},
+		{
+			description:      "it should log the progress of each part when the interval is short",
+			parts:            2,
+			progressInterval: time.Second,
+			expected: []string{
+				"cloud: upload progress offset=0 size=1048576 sent=1048576 total=2097152 progress=50.0% throughput=104857B/s eta=10s",
+				"cloud: upload progress offset=1048576 size=1048576 sent=2097152 total=2097152 progress=100.0% throughput=104857B/s eta=0s",
+			},
+			expectedStatus: cloud.UploadStatus{
+				Sent:       2097152,
+				Total:      2097152,
+				Throughput: 104857.6,
+			},
+		},
+		{
+			description: "it should calculate the throughput with the parts sent in the last minute",
+			parts:       8,
+			// the last part takes 70 seconds, while the others take 10 seconds
+			clockSteps:       []time.Duration{10 * time.Second, 10 * time.Second, 10 * time.Second, 10 * time.Second, 10 * time.Second, 10 * time.Second, 10 * time.Second, 10 * time.Second, 10 * time.Second, 70 * time.Second},
+			progressInterval: time.Hour,
+			expectedStatus: cloud.UploadStatus{
+				Sent:       8388608,
+				Total:      8388608,
+				Throughput: 1048576.0 / 70,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "toglacier-test-")
+			if err != nil {
+				t.Fatalf("error creating file. details: %s", err)
+			}
+			f.Write(bytes.Repeat([]byte{'a'}, scenario.parts*1048576))
+			f.Close()
+			defer os.Remove(f.Name())
+
+			// each time the clock is read, 10 seconds have passed (or the next
+			// scenario step)
+			now := time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC)
+			var clockCalls int
+
+			var entries []string
+			var status cloud.UploadStatus
+			var finished bool
+			awsCloud := cloud.AWSCloud{
 				Logger: mockLogger{
 					mockDebug:  func(args ...interface{}) {},
 					mockDebugf: func(format string, args ...interface{}) {},
 					mockInfo:   func(args ...interface{}) {},
-					mockInfof:  func(format string, args ...interface{}) {},
+					mockInfof: func(format string, args ...interface{}) {
+						if strings.HasPrefix(format, "cloud: upload progress") {
+							entries = append(entries, fmt.Sprintf(format, args...))
+						}
+					},
 				},
 				AccountID: "account",
 				VaultName: "vault",
 				Glacier: mockGlacierAPI{
-					mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
-						return &glacier.InitiateJobOutput{
-							JobId: aws.String("JOBID123"),
+					mockInitiateMultipartUploadWithContext: func(ctx aws.Context, i *glacier.InitiateMultipartUploadInput, opts ...request.Option) (*glacier.InitiateMultipartUploadOutput, error) {
+						return &glacier.InitiateMultipartUploadOutput{
+							UploadId: aws.String("UPLOAD123"),
 						}, nil
 					},
-					mockListJobsWithContext: func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
-						return &glacier.ListJobsOutput{
-							JobList: []*glacier.JobDescription{
-								{
-									JobId:      aws.String("JOBID123"),
-									Completed:  aws.Bool(true),
-									StatusCode: aws.String("Succeeded"),
-								},
-							},
+					mockUploadMultipartPartWithContext: func(ctx aws.Context, u *glacier.UploadMultipartPartInput, opts ...request.Option) (*glacier.UploadMultipartPartOutput, error) {
+						hash := glacier.ComputeHashes(u.Body)
+						return &glacier.UploadMultipartPartOutput{
+							Checksum: aws.String(hex.EncodeToString(hash.TreeHash)),
 						}, nil
 					},
-					mockGetJobOutputWithContext: func(aws.Context, *glacier.GetJobOutputInput, ...request.Option) (*glacier.GetJobOutputOutput, error) {
-						inventory := struct {
-							VaultARN      string `json:"VaultARN"`
-							InventoryDate string `json:"InventoryDate"`
-							ArchiveList   cloud.AWSInventoryArchiveList
-						}{
-							ArchiveList: cloud.AWSInventoryArchiveList{
-								{
-									ArchiveID:          "AWSID123",
-									ArchiveDescription: "another test backup",
-									CreationDate:       time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
-									Size:               4000,
-									SHA256TreeHash:     "a75e723eaf6da1db780e0a9b6a2046eba1a6bc20e8e69ffcb7c633e5e51f2502",
-								},
-								{
-									ArchiveID:          "AWSID122",
-									ArchiveDescription: "great test",
-									CreationDate:       time.Date(2016, 11, 7, 12, 0, 0, 0, time.UTC),
-									Size:               2456,
-									SHA256TreeHash:     "223072246f6eedbf1271bd1576f01b4b67c8e1cb1142599d5ef615673f513a5f",
-								},
-							},
-						}
-
-						body, err := json.Marshal(inventory)
-						if err != nil {
-							t.Fatalf("error build job output response. details: %s", err)
+					mockCompleteMultipartUploadWithContext: func(ctx aws.Context, c *glacier.CompleteMultipartUploadInput, opts ...request.Option) (*glacier.ArchiveCreationOutput, error) {
+						return &glacier.ArchiveCreationOutput{
+							ArchiveId: aws.String("AWSID123"),
+							Checksum:  c.Checksum,
+							Location:  aws.String("/archive/AWSID123"),
+						}, nil
+					},
+				},
+				Clock: fakeClock{
+					mockNow: func() time.Time {
+						step := 10 * time.Second
+						if clockCalls < len(scenario.clockSteps) {
+							step = scenario.clockSteps[clockCalls]
 						}
+						clockCalls++
 
-						return &glacier.GetJobOutputOutput{
-							Body: ioutil.NopCloser(bytes.NewBuffer(body)),
-						}, nil
+						now = now.Add(step)
+						return now
 					},
 				},
-			},
-			expected: []cloud.Backup{
-				{
-					ID:        "AWSID122",
-					CreatedAt: time.Date(2016, 11, 7, 12, 0, 0, 0, time.UTC),
-					Checksum:  "223072246f6eedbf1271bd1576f01b4b67c8e1cb1142599d5ef615673f513a5f",
-					VaultName: "vault",
-					Size:      2456,
-					Location:  cloud.LocationAWS,
+				MultipartUploadLimit: 1024,
+				PartSize:             1048576,
+				ProgressInterval:     scenario.progressInterval,
+				Progress: mockUploadProgress{
+					mockOnUploadProgress: func(s cloud.UploadStatus) {
+						if finished {
+							t.Errorf("progress informed after the upload finished")
+						}
+						status = s
+					},
+					mockOnUploadFinished: func() {
+						finished = true
+					},
 				},
-				{
-					ID:        "AWSID123",
+			}
+
+			if _, err := awsCloud.Send(context.Background(), f.Name(), ""); err != nil {
+				t.Fatalf("unexpected error sending the backup. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, entries) {
+				t.Errorf("progress entries don't match.\n%s", Diff(scenario.expected, entries))
+			}
+
+			if !finished {
+				t.Errorf("upload finish not informed")
+			}
+
+			if !reflect.DeepEqual(scenario.expectedStatus, status) {
+				t.Errorf("upload status don't match.\n%s", Diff(scenario.expectedStatus, status))
+			}
+		})
+	}
+}
+
+func TestAWSCloud_SendParity(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		filename      string
+		awsCloud      cloud.AWSCloud
+		backupID      string
+		expected      cloud.Backup
+		expectedError error
+	}{
+		{
+			description: "it should send a parity file correctly",
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test-")
+				if err != nil {
+					t.Fatalf("error creating file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString("Important information for the test backup")
+				return f.Name()
+			}(),
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				MachineID: "server1",
+				Glacier: mockGlacierAPI{
+					mockUploadArchiveWithContext: func(ctx aws.Context, input *glacier.UploadArchiveInput, opts ...request.Option) (*glacier.ArchiveCreationOutput, error) {
+						if description := *input.ArchiveDescription; description != "parity file of AWSID123 from 2016-12-27T08:14:53Z (machine server1)" {
+							return nil, fmt.Errorf("unexpected archive description “%s”", description)
+						}
+
+						return &glacier.ArchiveCreationOutput{
+							ArchiveId: aws.String("AWSID124"),
+							Checksum:  aws.String("cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705"),
+							Location:  aws.String("/archive/AWSID124"),
+						}, nil
+					},
+				},
+				Clock: fakeClock{
+					mockNow: func() time.Time {
+						return time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC)
+					},
+				},
+			},
+			backupID: "AWSID123",
+			expected: cloud.Backup{
+				ID:        "AWSID124",
+				CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+				Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+				VaultName: "vault",
+				Size:      41,
+				Location:  cloud.LocationAWS,
+				MachineID: "server1",
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			scenario.awsCloud.MultipartUploadLimit = 102400
+			scenario.awsCloud.PartSize = 4096
+
+			backup, err := scenario.awsCloud.SendParity(context.Background(), scenario.filename, scenario.backupID)
+			if !reflect.DeepEqual(scenario.expected, backup) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backup))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) && !cloud.MultipartErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAWSCloud_SendCatalog(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		filename      string
+		awsCloud      cloud.AWSCloud
+		backupID      string
+		expected      cloud.Backup
+		expectedError error
+	}{
+		{
+			description: "it should send a catalog file correctly",
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test-")
+				if err != nil {
+					t.Fatalf("error creating file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString("Important information for the test backup")
+				return f.Name()
+			}(),
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				MachineID: "server1",
+				Glacier: mockGlacierAPI{
+					mockUploadArchiveWithContext: func(ctx aws.Context, input *glacier.UploadArchiveInput, opts ...request.Option) (*glacier.ArchiveCreationOutput, error) {
+						if description := *input.ArchiveDescription; description != "catalog file of AWSID123 from 2016-12-27T08:14:53Z (machine server1)" {
+							return nil, fmt.Errorf("unexpected archive description “%s”", description)
+						}
+
+						return &glacier.ArchiveCreationOutput{
+							ArchiveId: aws.String("AWSID124"),
+							Checksum:  aws.String("cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705"),
+							Location:  aws.String("/archive/AWSID124"),
+						}, nil
+					},
+				},
+				Clock: fakeClock{
+					mockNow: func() time.Time {
+						return time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC)
+					},
+				},
+			},
+			backupID: "AWSID123",
+			expected: cloud.Backup{
+				ID:        "AWSID124",
+				CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+				Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+				VaultName: "vault",
+				Size:      41,
+				Location:  cloud.LocationAWS,
+				MachineID: "server1",
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			scenario.awsCloud.MultipartUploadLimit = 102400
+			scenario.awsCloud.PartSize = 4096
+
+			backup, err := scenario.awsCloud.SendCatalog(context.Background(), scenario.filename, scenario.backupID)
+			if !reflect.DeepEqual(scenario.expected, backup) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backup))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) && !cloud.MultipartErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAWSCloud_List(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithCancel(ctx)
+
+	scenarios := []struct {
+		description   string
+		awsCloud      cloud.AWSCloud
+		goFunc        func()
+		expected      []cloud.Backup
+		expectedError error
+	}{
+		{
+			description: "it should list all backups correctly",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
+						return &glacier.InitiateJobOutput{
+							JobId: aws.String("JOBID123"),
+						}, nil
+					},
+					mockListJobsWithContext: func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+						return &glacier.ListJobsOutput{
+							JobList: []*glacier.JobDescription{
+								{
+									JobId:      aws.String("JOBID123"),
+									Completed:  aws.Bool(true),
+									StatusCode: aws.String("Succeeded"),
+								},
+							},
+						}, nil
+					},
+					mockGetJobOutputWithContext: func(aws.Context, *glacier.GetJobOutputInput, ...request.Option) (*glacier.GetJobOutputOutput, error) {
+						inventory := struct {
+							VaultARN      string `json:"VaultARN"`
+							InventoryDate string `json:"InventoryDate"`
+							ArchiveList   []cloud.AWSInventoryArchive
+							Extra         map[string][]int
+						}{
+							VaultARN:      "arn:aws:glacier:us-east-1:account:vaults/vault",
+							InventoryDate: "2016-12-28T00:00:00Z",
+							Extra:         map[string][]int{"unknown": {1, 2}},
+							ArchiveList: []cloud.AWSInventoryArchive{
+								{
+									ArchiveID:          "AWSID123",
+									ArchiveDescription: `backup file from 2016-12-27T08:14:53Z "before OS upgrade" (machine server1)`,
+									CreationDate:       time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+									Size:               4000,
+									SHA256TreeHash:     "a75e723eaf6da1db780e0a9b6a2046eba1a6bc20e8e69ffcb7c633e5e51f2502",
+								},
+								{
+									ArchiveID:          "AWSID122",
+									ArchiveDescription: "great test",
+									CreationDate:       time.Date(2016, 11, 7, 12, 0, 0, 0, time.UTC),
+									Size:               2456,
+									SHA256TreeHash:     "223072246f6eedbf1271bd1576f01b4b67c8e1cb1142599d5ef615673f513a5f",
+								},
+								{
+									ArchiveID:          "AWSID124",
+									ArchiveDescription: "parity file of AWSID123 from 2016-12-27T08:15:00Z (machine server1)",
+									CreationDate:       time.Date(2016, 12, 27, 8, 15, 0, 0, time.UTC),
+									Size:               400,
+									SHA256TreeHash:     "0b2a8e9c5f3d5e0b9bb6a4c1e7f9f2bd1a4c55a4f7e4d2c0a3b8e6f1d9c7b5a3",
+								},
+								{
+									ArchiveID:          "AWSID125",
+									ArchiveDescription: "catalog file of AWSID123 from 2016-12-27T08:15:10Z (machine server1)",
+									CreationDate:       time.Date(2016, 12, 27, 8, 15, 10, 0, time.UTC),
+									Size:               200,
+									SHA256TreeHash:     "5a7c1e0d3f9b2a4c6e8d0f1b3a5c7e9d2f4b6a8c0e1d3f5b7a9c2e4d6f8b0a1c",
+								},
+							},
+						}
+
+						body, err := json.Marshal(inventory)
+						if err != nil {
+							t.Fatalf("error build job output response. details: %s", err)
+						}
+
+						return &glacier.GetJobOutputOutput{
+							Body: ioutil.NopCloser(bytes.NewBuffer(body)),
+						}, nil
+					},
+				},
+			},
+			expected: []cloud.Backup{
+				{
+					ID:        "AWSID122",
+					CreatedAt: time.Date(2016, 11, 7, 12, 0, 0, 0, time.UTC),
+					Checksum:  "223072246f6eedbf1271bd1576f01b4b67c8e1cb1142599d5ef615673f513a5f",
+					VaultName: "vault",
+					Size:      2456,
+					Location:  cloud.LocationAWS,
+				},
+				{
+					ID:        "AWSID123",
 					CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
 					Checksum:  "a75e723eaf6da1db780e0a9b6a2046eba1a6bc20e8e69ffcb7c633e5e51f2502",
 					VaultName: "vault",
 					Size:      4000,
 					Location:  cloud.LocationAWS,
+					MachineID: "server1",
+					Comment:   "before OS upgrade",
+					ParityID:  "AWSID124",
+					CatalogID: "AWSID125",
 				},
 			},
 		},
@@ -1024,9 +1555,9 @@ func TestAWSCloud_List(t *testing.T) {
 						inventory := struct {
 							VaultARN      string `json:"VaultARN"`
 							InventoryDate string `json:"InventoryDate"`
-							ArchiveList   cloud.AWSInventoryArchiveList
+							ArchiveList   []cloud.AWSInventoryArchive
 						}{
-							ArchiveList: cloud.AWSInventoryArchiveList{
+							ArchiveList: []cloud.AWSInventoryArchive{
 								{
 									ArchiveID:          "AWSID123",
 									ArchiveDescription: "another test backup",
@@ -1138,11 +1669,11 @@ func TestAWSCloud_List(t *testing.T) {
 			expectedError: &cloud.Error{
 				ID:   "JOBID123",
 				Code: cloud.ErrorCodeDecodingData,
-				Err:  errors.New("invalid character '{' looking for beginning of object key string"),
+				Err:  errors.New("object member name must be a string"),
 			},
 		},
 		{
-			description: "it should detect when the action is cancelled by the user",
+			description: "it should detect an inventory with an invalid archive list",
 			awsCloud: cloud.AWSCloud{
 				Logger: mockLogger{
 					mockDebug:  func(args ...interface{}) {},
@@ -1158,32 +1689,73 @@ func TestAWSCloud_List(t *testing.T) {
 							JobId: aws.String("JOBID123"),
 						}, nil
 					},
-					mockListJobsWithContext: func() func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
-						var i int
-						return func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
-							// sleep for a small amount of time to allow the task to be
-							// cancelled
-							time.Sleep(200 * time.Millisecond)
-
-							i++
-							return &glacier.ListJobsOutput{
-								JobList: []*glacier.JobDescription{
-									{
-										JobId:      aws.String("JOBID123"),
-										Completed:  aws.Bool(i == 2),
-										StatusCode: aws.String("Succeeded"),
-									},
+					mockListJobsWithContext: func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+						return &glacier.ListJobsOutput{
+							JobList: []*glacier.JobDescription{
+								{
+									JobId:      aws.String("JOBID123"),
+									Completed:  aws.Bool(true),
+									StatusCode: aws.String("Succeeded"),
 								},
-							}, nil
-						}
-					}(),
+							},
+						}, nil
+					},
 					mockGetJobOutputWithContext: func(aws.Context, *glacier.GetJobOutputInput, ...request.Option) (*glacier.GetJobOutputOutput, error) {
-						inventory := struct {
-							VaultARN      string `json:"VaultARN"`
-							InventoryDate string `json:"InventoryDate"`
-							ArchiveList   cloud.AWSInventoryArchiveList
-						}{
-							ArchiveList: cloud.AWSInventoryArchiveList{
+						return &glacier.GetJobOutputOutput{
+							Body: ioutil.NopCloser(bytes.NewBufferString(`{"VaultARN":"arn","ArchiveList":"invalid"}`)),
+						}, nil
+					},
+				},
+			},
+			expectedError: &cloud.Error{
+				ID:   "JOBID123",
+				Code: cloud.ErrorCodeDecodingData,
+				Err:  errors.New("unexpected token “invalid”, expected “[”"),
+			},
+		},
+		{
+			description: "it should detect when the action is cancelled by the user",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
+						return &glacier.InitiateJobOutput{
+							JobId: aws.String("JOBID123"),
+						}, nil
+					},
+					mockListJobsWithContext: func() func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+						var i int
+						return func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+							// sleep for a small amount of time to allow the task to be
+							// cancelled
+							time.Sleep(200 * time.Millisecond)
+
+							i++
+							return &glacier.ListJobsOutput{
+								JobList: []*glacier.JobDescription{
+									{
+										JobId:      aws.String("JOBID123"),
+										Completed:  aws.Bool(i == 2),
+										StatusCode: aws.String("Succeeded"),
+									},
+								},
+							}, nil
+						}
+					}(),
+					mockGetJobOutputWithContext: func(aws.Context, *glacier.GetJobOutputInput, ...request.Option) (*glacier.GetJobOutputOutput, error) {
+						inventory := struct {
+							VaultARN      string `json:"VaultARN"`
+							InventoryDate string `json:"InventoryDate"`
+							ArchiveList   []cloud.AWSInventoryArchive
+						}{
+							ArchiveList: []cloud.AWSInventoryArchive{
 								{
 									ArchiveID:          "AWSID123",
 									ArchiveDescription: "another test backup",
@@ -1268,9 +1840,9 @@ func TestAWSCloud_List(t *testing.T) {
 						inventory := struct {
 							VaultARN      string `json:"VaultARN"`
 							InventoryDate string `json:"InventoryDate"`
-							ArchiveList   cloud.AWSInventoryArchiveList
+							ArchiveList   []cloud.AWSInventoryArchive
 						}{
-							ArchiveList: cloud.AWSInventoryArchiveList{
+							ArchiveList: []cloud.AWSInventoryArchive{
 								{
 									ArchiveID:          "AWSID123",
 									ArchiveDescription: "another test backup",
@@ -1314,6 +1886,8 @@ func TestAWSCloud_List(t *testing.T) {
 
 	for _, scenario := range scenarios {
 		t.Run(scenario.description, func(t *testing.T) {
+			scenario.awsCloud.WaitJobTime = 100 * time.Millisecond
+
 			if scenario.goFunc != nil {
 				go scenario.goFunc()
 			}
@@ -1330,9 +1904,6 @@ func TestAWSCloud_List(t *testing.T) {
 }
 
 func TestAWSCloud_Get(t *testing.T) {
-	defer cloud.WaitJobTime(time.Minute)
-	cloud.WaitJobTime(100 * time.Millisecond)
-
 	ctx := context.Background()
 	ctx, cancel := context.WithCancel(ctx)
 
@@ -1551,296 +2122,1131 @@ func TestAWSCloud_Get(t *testing.T) {
 					},
 				},
 			},
-			expected: map[string]string{
-				"AWSID123": path.Join(os.TempDir(), "backup-AWSID123.tar"),
+			expected: map[string]string{
+				"AWSID123": path.Join(os.TempDir(), "backup-AWSID123.tar"),
+			},
+		},
+		{
+			description: "it should detect an error while retrieving the job data",
+			id:          "AWSID123",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
+						return &glacier.InitiateJobOutput{
+							JobId: aws.String("JOBID123"),
+						}, nil
+					},
+					mockListJobsWithContext: func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+						return &glacier.ListJobsOutput{
+							JobList: []*glacier.JobDescription{
+								{
+									JobId:      aws.String("JOBID123"),
+									Completed:  aws.Bool(true),
+									StatusCode: aws.String("Succeeded"),
+								},
+							},
+						}, nil
+					},
+					mockGetJobOutputWithContext: func(aws.Context, *glacier.GetJobOutputInput, ...request.Option) (*glacier.GetJobOutputOutput, error) {
+						return nil, errors.New("job corrupted")
+					},
+				},
+			},
+			expectedError: &cloud.Error{
+				ID:   "AWSID123",
+				Code: cloud.ErrorCodeJobComplete,
+				Err:  errors.New("job corrupted"),
+			},
+		},
+		{
+			description: "it should download again a backup that was corrupted",
+			id:          "AWSID123",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:    func(args ...interface{}) {},
+					mockDebugf:   func(format string, args ...interface{}) {},
+					mockInfo:     func(args ...interface{}) {},
+					mockInfof:    func(format string, args ...interface{}) {},
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: func() mockGlacierAPI {
+					var downloads int
+
+					return mockGlacierAPI{
+						mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
+							return &glacier.InitiateJobOutput{
+								JobId: aws.String("JOBID123"),
+							}, nil
+						},
+						mockListJobsWithContext: func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+							return &glacier.ListJobsOutput{
+								JobList: []*glacier.JobDescription{
+									{
+										JobId:          aws.String("JOBID123"),
+										Completed:      aws.Bool(true),
+										StatusCode:     aws.String("Succeeded"),
+										SHA256TreeHash: aws.String("cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705"),
+									},
+								},
+							}, nil
+						},
+						mockGetJobOutputWithContext: func(aws.Context, *glacier.GetJobOutputInput, ...request.Option) (*glacier.GetJobOutputOutput, error) {
+							downloads++
+							if downloads == 1 {
+								return &glacier.GetJobOutputOutput{
+									Body: ioutil.NopCloser(bytes.NewBufferString("Corrupted information for the test backup")),
+								}, nil
+							}
+
+							return &glacier.GetJobOutputOutput{
+								Body: ioutil.NopCloser(bytes.NewBufferString("Important information for the test backup")),
+							}, nil
+						},
+					}
+				}(),
+			},
+			expected: map[string]string{
+				"AWSID123": path.Join(os.TempDir(), "backup-AWSID123.tar"),
+			},
+		},
+		{
+			description: "it should detect when the downloaded backup is always corrupted",
+			id:          "AWSID123",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:    func(args ...interface{}) {},
+					mockDebugf:   func(format string, args ...interface{}) {},
+					mockInfo:     func(args ...interface{}) {},
+					mockInfof:    func(format string, args ...interface{}) {},
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
+						return &glacier.InitiateJobOutput{
+							JobId: aws.String("JOBID123"),
+						}, nil
+					},
+					mockListJobsWithContext: func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+						return &glacier.ListJobsOutput{
+							JobList: []*glacier.JobDescription{
+								{
+									JobId:          aws.String("JOBID123"),
+									Completed:      aws.Bool(true),
+									StatusCode:     aws.String("Succeeded"),
+									SHA256TreeHash: aws.String("cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705"),
+								},
+							},
+						}, nil
+					},
+					mockGetJobOutputWithContext: func(aws.Context, *glacier.GetJobOutputInput, ...request.Option) (*glacier.GetJobOutputOutput, error) {
+						return &glacier.GetJobOutputOutput{
+							Body: ioutil.NopCloser(bytes.NewBufferString("Corrupted information for the test backup")),
+						}, nil
+					},
+				},
+			},
+			expectedError: &cloud.Error{
+				ID:   "AWSID123",
+				Code: cloud.ErrorCodeComparingChecksums,
+			},
+		},
+		{
+			description: "it should detect when the task was cancelled by the user while the job was not done (sleeping)",
+			id:          "AWSID123",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
+						return &glacier.InitiateJobOutput{
+							JobId: aws.String("JOBID123"),
+						}, nil
+					},
+					mockListJobsWithContext: func() func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+						var i int
+						return func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+							// sleep for a small amount of time to allow the task to be
+							// cancelled
+							time.Sleep(200 * time.Millisecond)
+
+							i++
+							return &glacier.ListJobsOutput{
+								JobList: []*glacier.JobDescription{
+									{
+										JobId:      aws.String("JOBID123"),
+										Completed:  aws.Bool(i == 2),
+										StatusCode: aws.String("Succeeded"),
+									},
+								},
+							}, nil
+						}
+					}(),
+					mockGetJobOutputWithContext: func(aws.Context, *glacier.GetJobOutputInput, ...request.Option) (*glacier.GetJobOutputOutput, error) {
+						return &glacier.GetJobOutputOutput{
+							Body: ioutil.NopCloser(bytes.NewBufferString("Important information for the test backup")),
+						}, nil
+					},
+				},
+			},
+			goFunc: func() {
+				// wait for the send task to start
+				time.Sleep(100 * time.Millisecond)
+				cancel()
+			},
+			expectedError: &cloud.JobsError{
+				Jobs: []string{"JOBID123"},
+				Code: cloud.JobsErrorCodeCancelled,
+				Err:  context.Canceled,
+			},
+		},
+		{
+			description: "it should detect when the task was cancelled by the user while the job was not done (listing)",
+			id:          "AWSID123",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
+						return &glacier.InitiateJobOutput{
+							JobId: aws.String("JOBID123"),
+						}, nil
+					},
+					mockListJobsWithContext: func() func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+						var i int
+						return func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+							// sleep for a small amount of time to allow the task to be
+							// cancelled
+							select {
+							case <-time.After(200 * time.Millisecond):
+							// do nothing
+							case <-ctx.Done():
+								return nil, awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
+							}
+
+							i++
+							return &glacier.ListJobsOutput{
+								JobList: []*glacier.JobDescription{
+									{
+										JobId:      aws.String("JOBID123"),
+										Completed:  aws.Bool(i == 2),
+										StatusCode: aws.String("Succeeded"),
+									},
+								},
+							}, nil
+						}
+					}(),
+					mockGetJobOutputWithContext: func(aws.Context, *glacier.GetJobOutputInput, ...request.Option) (*glacier.GetJobOutputOutput, error) {
+						return &glacier.GetJobOutputOutput{
+							Body: ioutil.NopCloser(bytes.NewBufferString("Important information for the test backup")),
+						}, nil
+					},
+				},
+			},
+			goFunc: func() {
+				// wait for the send task to start
+				time.Sleep(100 * time.Millisecond)
+				cancel()
+			},
+			expectedError: &cloud.JobsError{
+				Jobs: []string{"JOBID123"},
+				Code: cloud.JobsErrorCodeCancelled,
+				Err:  awserr.New(request.CanceledErrorCode, "request context canceled", context.Canceled),
+			},
+		},
+		{
+			description: "it should detect when the task was cancelled by the user while the downloading the backup",
+			id:          "AWSID123",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
+						return &glacier.InitiateJobOutput{
+							JobId: aws.String("JOBID123"),
+						}, nil
+					},
+					mockListJobsWithContext: func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+						return &glacier.ListJobsOutput{
+							JobList: []*glacier.JobDescription{
+								{
+									JobId:      aws.String("JOBID123"),
+									Completed:  aws.Bool(true),
+									StatusCode: aws.String("Succeeded"),
+								},
+							},
+						}, nil
+					},
+					mockGetJobOutputWithContext: func(ctx aws.Context, g *glacier.GetJobOutputInput, opts ...request.Option) (*glacier.GetJobOutputOutput, error) {
+						select {
+						case <-time.After(200 * time.Millisecond):
+						// do nothing
+						case <-ctx.Done():
+							return nil, awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
+						}
+
+						return &glacier.GetJobOutputOutput{
+							Body: ioutil.NopCloser(bytes.NewBufferString("Important information for the test backup")),
+						}, nil
+					},
+				},
+			},
+			goFunc: func() {
+				// wait for the send task to start
+				time.Sleep(100 * time.Millisecond)
+				cancel()
+			},
+			expectedError: &cloud.Error{
+				ID:   "AWSID123",
+				Code: cloud.ErrorCodeCancelled,
+				Err:  awserr.New(request.CanceledErrorCode, "request context canceled", context.Canceled),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			scenario.awsCloud.WaitJobTime = 100 * time.Millisecond
+
+			if scenario.goFunc != nil {
+				go scenario.goFunc()
+			}
+
+			filename, err := scenario.awsCloud.Get(ctx, scenario.id)
+			if !reflect.DeepEqual(scenario.expected, filename) {
+				t.Errorf("filenames don't match.\n%s", Diff(scenario.expected, filename))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) && !cloud.JobsErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAWSCloud_GetResume(t *testing.T) {
+	content := "Important information for the test backup"
+	checksum := sha256.Sum256([]byte(content))
+	filename := path.Join(os.TempDir(), "backup-AWSID123.tar")
+	defer os.Remove(filename)
+
+	// the bytes after the reported part are discarded
+	if err := ioutil.WriteFile(filename, []byte(content[:10]+"garbage"), 0600); err != nil {
+		t.Fatalf("error writing the downloaded part. details: %s", err)
+	}
+
+	tracker := &mockRetrievalTracker{
+		jobs:     map[string]string{"AWSID123": "JOBID123"},
+		parts:    map[string]int64{"AWSID123": 10},
+		filename: filename,
+	}
+
+	awsCloud := cloud.AWSCloud{
+		Logger: mockLogger{
+			mockDebug:  func(args ...interface{}) {},
+			mockDebugf: func(format string, args ...interface{}) {},
+			mockInfo:   func(args ...interface{}) {},
+			mockInfof:  func(format string, args ...interface{}) {},
+		},
+		AccountID: "account",
+		VaultName: "vault",
+		PartSize:  8,
+		Glacier: mockGlacierAPI{
+			mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
+				t.Error("unexpected job initiated")
+				return nil, errors.New("unexpected job")
+			},
+			mockDescribeJobWithContext: func(aws.Context, *glacier.DescribeJobInput, ...request.Option) (*glacier.JobDescription, error) {
+				return &glacier.JobDescription{
+					JobId:      aws.String("JOBID123"),
+					Completed:  aws.Bool(true),
+					StatusCode: aws.String("Succeeded"),
+				}, nil
+			},
+			mockListJobsWithContext: func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
+				return &glacier.ListJobsOutput{
+					JobList: []*glacier.JobDescription{
+						{
+							JobId:          aws.String("JOBID123"),
+							Completed:      aws.Bool(true),
+							StatusCode:     aws.String("Succeeded"),
+							SHA256TreeHash: aws.String(hex.EncodeToString(checksum[:])),
+						},
+					},
+				}, nil
+			},
+			mockGetJobOutputWithContext: func(_ aws.Context, input *glacier.GetJobOutputInput, _ ...request.Option) (*glacier.GetJobOutputOutput, error) {
+				if aws.StringValue(input.Range) != "bytes=10-" {
+					t.Errorf("unexpected range “%s”", aws.StringValue(input.Range))
+				}
+
+				return &glacier.GetJobOutputOutput{
+					Body: ioutil.NopCloser(bytes.NewBufferString(content[10:])),
+				}, nil
+			},
+		},
+	}
+
+	awsCloud.WaitJobTime = 100 * time.Millisecond
+	filenames, err := awsCloud.Get(cloud.WithRetrievalTracker(context.Background(), tracker), "AWSID123")
+	if err != nil {
+		t.Fatalf("unexpected error. details: %s", err)
+	}
+
+	if filenames["AWSID123"] != filename {
+		t.Errorf("unexpected filename “%s”", filenames["AWSID123"])
+	}
+
+	downloaded, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("error reading the downloaded file. details: %s", err)
+	}
+
+	if string(downloaded) != content {
+		t.Errorf("unexpected content “%s”", downloaded)
+	}
+
+	expectedParts := []int64{18, 26, 34, 41}
+	if !reflect.DeepEqual(expectedParts, tracker.reported) {
+		t.Errorf("reported parts don't match.\n%s", Diff(expectedParts, tracker.reported))
+	}
+}
+
+func TestAWSCloud_Remove(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		id            string
+		awsCloud      cloud.AWSCloud
+		expectedError error
+	}{
+		{
+			description: "it should remove a backup correctly",
+			id:          "AWSID123",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockDeleteArchiveWithContext: func(aws.Context, *glacier.DeleteArchiveInput, ...request.Option) (*glacier.DeleteArchiveOutput, error) {
+						return &glacier.DeleteArchiveOutput{}, nil
+					},
+				},
+			},
+		},
+		{
+			description: "it should detect an error while removing a backup",
+			id:          "AWSID123",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockDeleteArchiveWithContext: func(aws.Context, *glacier.DeleteArchiveInput, ...request.Option) (*glacier.DeleteArchiveOutput, error) {
+						return nil, errors.New("no backup here")
+					},
+				},
+			},
+			expectedError: &cloud.Error{
+				ID:   "AWSID123",
+				Code: cloud.ErrorCodeRemovingArchive,
+				Err:  errors.New("no backup here"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			err := scenario.awsCloud.Remove(context.Background(), scenario.id)
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAWSCloud_InitiateLock(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		policy        string
+		awsCloud      cloud.AWSCloud
+		expected      string
+		expectedError error
+	}{
+		{
+			description: "it should initiate the vault lock correctly",
+			policy:      `{"Version":"2012-10-17","Statement":[]}`,
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockInitiateVaultLockWithContext: func(ctx aws.Context, input *glacier.InitiateVaultLockInput, opts ...request.Option) (*glacier.InitiateVaultLockOutput, error) {
+						if policy := *input.Policy.Policy; policy != `{"Version":"2012-10-17","Statement":[]}` {
+							return nil, fmt.Errorf("unexpected policy “%s”", policy)
+						}
+
+						return &glacier.InitiateVaultLockOutput{
+							LockId: aws.String("LOCKID123"),
+						}, nil
+					},
+				},
+			},
+			expected: "LOCKID123",
+		},
+		{
+			description: "it should detect an error while initiating the vault lock",
+			policy:      "invalid policy",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockInitiateVaultLockWithContext: func(ctx aws.Context, input *glacier.InitiateVaultLockInput, opts ...request.Option) (*glacier.InitiateVaultLockOutput, error) {
+						return nil, errors.New("invalid policy")
+					},
+				},
+			},
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeInitiatingVaultLock,
+				Err:  errors.New("invalid policy"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			lockID, err := scenario.awsCloud.InitiateLock(context.Background(), scenario.policy)
+			if scenario.expected != lockID {
+				t.Errorf("lock ids don't match. expected “%s” and got “%s”", scenario.expected, lockID)
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAWSCloud_CompleteLock(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		lockID        string
+		awsCloud      cloud.AWSCloud
+		expectedError error
+	}{
+		{
+			description: "it should complete the vault lock correctly",
+			lockID:      "LOCKID123",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockCompleteVaultLockWithContext: func(ctx aws.Context, input *glacier.CompleteVaultLockInput, opts ...request.Option) (*glacier.CompleteVaultLockOutput, error) {
+						if lockID := *input.LockId; lockID != "LOCKID123" {
+							return nil, fmt.Errorf("unexpected lock id “%s”", lockID)
+						}
+
+						return &glacier.CompleteVaultLockOutput{}, nil
+					},
+				},
+			},
+		},
+		{
+			description: "it should detect an error while completing the vault lock",
+			lockID:      "LOCKID123",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockCompleteVaultLockWithContext: func(ctx aws.Context, input *glacier.CompleteVaultLockInput, opts ...request.Option) (*glacier.CompleteVaultLockOutput, error) {
+						return nil, errors.New("lock expired")
+					},
+				},
+			},
+			expectedError: &cloud.Error{
+				ID:   "LOCKID123",
+				Code: cloud.ErrorCodeCompletingVaultLock,
+				Err:  errors.New("lock expired"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			err := scenario.awsCloud.CompleteLock(context.Background(), scenario.lockID)
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAWSCloud_AbortLock(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		awsCloud      cloud.AWSCloud
+		expectedError error
+	}{
+		{
+			description: "it should abort the vault lock correctly",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockAbortVaultLockWithContext: func(ctx aws.Context, input *glacier.AbortVaultLockInput, opts ...request.Option) (*glacier.AbortVaultLockOutput, error) {
+						return &glacier.AbortVaultLockOutput{}, nil
+					},
+				},
+			},
+		},
+		{
+			description: "it should detect an error while aborting the vault lock",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockAbortVaultLockWithContext: func(ctx aws.Context, input *glacier.AbortVaultLockInput, opts ...request.Option) (*glacier.AbortVaultLockOutput, error) {
+						return nil, errors.New("vault already locked")
+					},
+				},
+			},
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeAbortingVaultLock,
+				Err:  errors.New("vault already locked"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			err := scenario.awsCloud.AbortLock(context.Background())
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAWSCloud_SetAccessPolicy(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		policy        string
+		awsCloud      cloud.AWSCloud
+		expectedError error
+	}{
+		{
+			description: "it should replace the access policy correctly",
+			policy:      `{"Version":"2012-10-17","Statement":[]}`,
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockSetVaultAccessPolicyWithContext: func(ctx aws.Context, input *glacier.SetVaultAccessPolicyInput, opts ...request.Option) (*glacier.SetVaultAccessPolicyOutput, error) {
+						if policy := *input.Policy.Policy; policy != `{"Version":"2012-10-17","Statement":[]}` {
+							return nil, fmt.Errorf("unexpected policy “%s”", policy)
+						}
+
+						return &glacier.SetVaultAccessPolicyOutput{}, nil
+					},
+				},
+			},
+		},
+		{
+			description: "it should remove the access policy when it's empty",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockDeleteVaultAccessPolicyWithContext: func(ctx aws.Context, input *glacier.DeleteVaultAccessPolicyInput, opts ...request.Option) (*glacier.DeleteVaultAccessPolicyOutput, error) {
+						return &glacier.DeleteVaultAccessPolicyOutput{}, nil
+					},
+				},
+			},
+		},
+		{
+			description: "it should detect an error while replacing the access policy",
+			policy:      "invalid policy",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockSetVaultAccessPolicyWithContext: func(ctx aws.Context, input *glacier.SetVaultAccessPolicyInput, opts ...request.Option) (*glacier.SetVaultAccessPolicyOutput, error) {
+						return nil, errors.New("invalid policy")
+					},
+				},
+			},
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeVaultAccessPolicy,
+				Err:  errors.New("invalid policy"),
+			},
+		},
+		{
+			description: "it should detect an error while removing the access policy",
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockDeleteVaultAccessPolicyWithContext: func(ctx aws.Context, input *glacier.DeleteVaultAccessPolicyInput, opts ...request.Option) (*glacier.DeleteVaultAccessPolicyOutput, error) {
+						return nil, errors.New("vault not found")
+					},
+				},
+			},
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeVaultAccessPolicy,
+				Err:  errors.New("vault not found"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			err := scenario.awsCloud.SetAccessPolicy(context.Background(), scenario.policy)
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAWSCloud_SetTags(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		tags          map[string]string
+		awsCloud      cloud.AWSCloud
+		expectedError error
+	}{
+		{
+			description: "it should add, update and remove tags correctly",
+			tags: map[string]string{
+				"environment": "production",
+				"owner":       "infra",
+				"retention":   "7y",
+			},
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockListTagsForVaultWithContext: func(ctx aws.Context, input *glacier.ListTagsForVaultInput, opts ...request.Option) (*glacier.ListTagsForVaultOutput, error) {
+						return &glacier.ListTagsForVaultOutput{
+							Tags: map[string]*string{
+								"environment": aws.String("production"),
+								"owner":       aws.String("dev"),
+								"temporary":   aws.String("yes"),
+								"old":         aws.String("yes"),
+							},
+						}, nil
+					},
+					mockRemoveTagsFromVaultWithContext: func(ctx aws.Context, input *glacier.RemoveTagsFromVaultInput, opts ...request.Option) (*glacier.RemoveTagsFromVaultOutput, error) {
+						if keys := aws.StringValueSlice(input.TagKeys); !reflect.DeepEqual(keys, []string{"old", "temporary"}) {
+							return nil, fmt.Errorf("unexpected keys to remove %v", keys)
+						}
+
+						return &glacier.RemoveTagsFromVaultOutput{}, nil
+					},
+					mockAddTagsToVaultWithContext: func(ctx aws.Context, input *glacier.AddTagsToVaultInput, opts ...request.Option) (*glacier.AddTagsToVaultOutput, error) {
+						expected := map[string]string{
+							"owner":     "infra",
+							"retention": "7y",
+						}
+
+						if tags := aws.StringValueMap(input.Tags); !reflect.DeepEqual(tags, expected) {
+							return nil, fmt.Errorf("unexpected tags to add %v", tags)
+						}
+
+						return &glacier.AddTagsToVaultOutput{}, nil
+					},
+				},
+			},
+		},
+		{
+			description: "it should not change anything when the tags are the same",
+			tags: map[string]string{
+				"environment": "production",
+			},
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockListTagsForVaultWithContext: func(ctx aws.Context, input *glacier.ListTagsForVaultInput, opts ...request.Option) (*glacier.ListTagsForVaultOutput, error) {
+						return &glacier.ListTagsForVaultOutput{
+							Tags: map[string]*string{
+								"environment": aws.String("production"),
+							},
+						}, nil
+					},
+				},
+			},
+		},
+		{
+			description: "it should detect an error while listing the tags",
+			tags: map[string]string{
+				"environment": "production",
+			},
+			awsCloud: cloud.AWSCloud{
+				Logger: mockLogger{
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				AccountID: "account",
+				VaultName: "vault",
+				Glacier: mockGlacierAPI{
+					mockListTagsForVaultWithContext: func(ctx aws.Context, input *glacier.ListTagsForVaultInput, opts ...request.Option) (*glacier.ListTagsForVaultOutput, error) {
+						return nil, errors.New("vault not found")
+					},
+				},
+			},
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeVaultTags,
+				Err:  errors.New("vault not found"),
 			},
 		},
 		{
-			description: "it should detect an error while retrieving the job data",
-			id:          "AWSID123",
+			description: "it should detect an error while removing the tags",
 			awsCloud: cloud.AWSCloud{
 				Logger: mockLogger{
-					mockDebug:  func(args ...interface{}) {},
 					mockDebugf: func(format string, args ...interface{}) {},
-					mockInfo:   func(args ...interface{}) {},
 					mockInfof:  func(format string, args ...interface{}) {},
 				},
 				AccountID: "account",
 				VaultName: "vault",
 				Glacier: mockGlacierAPI{
-					mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
-						return &glacier.InitiateJobOutput{
-							JobId: aws.String("JOBID123"),
-						}, nil
-					},
-					mockListJobsWithContext: func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
-						return &glacier.ListJobsOutput{
-							JobList: []*glacier.JobDescription{
-								{
-									JobId:      aws.String("JOBID123"),
-									Completed:  aws.Bool(true),
-									StatusCode: aws.String("Succeeded"),
-								},
+					mockListTagsForVaultWithContext: func(ctx aws.Context, input *glacier.ListTagsForVaultInput, opts ...request.Option) (*glacier.ListTagsForVaultOutput, error) {
+						return &glacier.ListTagsForVaultOutput{
+							Tags: map[string]*string{
+								"environment": aws.String("production"),
 							},
 						}, nil
 					},
-					mockGetJobOutputWithContext: func(aws.Context, *glacier.GetJobOutputInput, ...request.Option) (*glacier.GetJobOutputOutput, error) {
-						return nil, errors.New("job corrupted")
+					mockRemoveTagsFromVaultWithContext: func(ctx aws.Context, input *glacier.RemoveTagsFromVaultInput, opts ...request.Option) (*glacier.RemoveTagsFromVaultOutput, error) {
+						return nil, errors.New("access denied")
 					},
 				},
 			},
 			expectedError: &cloud.Error{
-				ID:   "AWSID123",
-				Code: cloud.ErrorCodeJobComplete,
-				Err:  errors.New("job corrupted"),
+				Code: cloud.ErrorCodeVaultTags,
+				Err:  errors.New("access denied"),
 			},
 		},
 		{
-			description: "it should detect when the task was cancelled by the user while the job was not done (sleeping)",
-			id:          "AWSID123",
+			description: "it should detect an error while adding the tags",
+			tags: map[string]string{
+				"environment": "production",
+			},
 			awsCloud: cloud.AWSCloud{
 				Logger: mockLogger{
-					mockDebug:  func(args ...interface{}) {},
 					mockDebugf: func(format string, args ...interface{}) {},
-					mockInfo:   func(args ...interface{}) {},
 					mockInfof:  func(format string, args ...interface{}) {},
 				},
 				AccountID: "account",
 				VaultName: "vault",
 				Glacier: mockGlacierAPI{
-					mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
-						return &glacier.InitiateJobOutput{
-							JobId: aws.String("JOBID123"),
-						}, nil
+					mockListTagsForVaultWithContext: func(ctx aws.Context, input *glacier.ListTagsForVaultInput, opts ...request.Option) (*glacier.ListTagsForVaultOutput, error) {
+						return &glacier.ListTagsForVaultOutput{}, nil
 					},
-					mockListJobsWithContext: func() func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
-						var i int
-						return func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
-							// sleep for a small amount of time to allow the task to be
-							// cancelled
-							time.Sleep(200 * time.Millisecond)
-
-							i++
-							return &glacier.ListJobsOutput{
-								JobList: []*glacier.JobDescription{
-									{
-										JobId:      aws.String("JOBID123"),
-										Completed:  aws.Bool(i == 2),
-										StatusCode: aws.String("Succeeded"),
-									},
-								},
-							}, nil
-						}
-					}(),
-					mockGetJobOutputWithContext: func(aws.Context, *glacier.GetJobOutputInput, ...request.Option) (*glacier.GetJobOutputOutput, error) {
-						return &glacier.GetJobOutputOutput{
-							Body: ioutil.NopCloser(bytes.NewBufferString("Important information for the test backup")),
-						}, nil
+					mockAddTagsToVaultWithContext: func(ctx aws.Context, input *glacier.AddTagsToVaultInput, opts ...request.Option) (*glacier.AddTagsToVaultOutput, error) {
+						return nil, errors.New("too many tags")
 					},
 				},
 			},
-			goFunc: func() {
-				// wait for the send task to start
-				time.Sleep(100 * time.Millisecond)
-				cancel()
-			},
-			expectedError: &cloud.JobsError{
-				Jobs: []string{"JOBID123"},
-				Code: cloud.JobsErrorCodeCancelled,
-				Err:  context.Canceled,
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeVaultTags,
+				Err:  errors.New("too many tags"),
 			},
 		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			err := scenario.awsCloud.SetTags(context.Background(), scenario.tags)
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAWSCloud_ListJobs(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		awsCloud      cloud.AWSCloud
+		expected      []cloud.Job
+		expectedError error
+	}{
 		{
-			description: "it should detect when the task was cancelled by the user while the job was not done (listing)",
-			id:          "AWSID123",
+			description: "it should list the jobs of all pages correctly",
 			awsCloud: cloud.AWSCloud{
 				Logger: mockLogger{
-					mockDebug:  func(args ...interface{}) {},
 					mockDebugf: func(format string, args ...interface{}) {},
-					mockInfo:   func(args ...interface{}) {},
 					mockInfof:  func(format string, args ...interface{}) {},
 				},
 				AccountID: "account",
 				VaultName: "vault",
 				Glacier: mockGlacierAPI{
-					mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
-						return &glacier.InitiateJobOutput{
-							JobId: aws.String("JOBID123"),
-						}, nil
-					},
-					mockListJobsWithContext: func() func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
-						var i int
-						return func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
-							// sleep for a small amount of time to allow the task to be
-							// cancelled
-							select {
-							case <-time.After(200 * time.Millisecond):
-							// do nothing
-							case <-ctx.Done():
-								return nil, awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
-							}
-
-							i++
+					mockListJobsWithContext: func(ctx aws.Context, input *glacier.ListJobsInput, opts ...request.Option) (*glacier.ListJobsOutput, error) {
+						if input.Marker == nil {
 							return &glacier.ListJobsOutput{
 								JobList: []*glacier.JobDescription{
 									{
-										JobId:      aws.String("JOBID123"),
-										Completed:  aws.Bool(i == 2),
-										StatusCode: aws.String("Succeeded"),
+										Action:       aws.String("ArchiveRetrieval"),
+										ArchiveId:    aws.String("AWSID123"),
+										CreationDate: aws.String("2017-09-14T10:30:00.000Z"),
+										JobId:        aws.String("JOBID1"),
+										StatusCode:   aws.String("InProgress"),
+										Tier:         aws.String("Standard"),
 									},
 								},
+								Marker: aws.String("JOBID1"),
 							}, nil
 						}
-					}(),
-					mockGetJobOutputWithContext: func(aws.Context, *glacier.GetJobOutputInput, ...request.Option) (*glacier.GetJobOutputOutput, error) {
-						return &glacier.GetJobOutputOutput{
-							Body: ioutil.NopCloser(bytes.NewBufferString("Important information for the test backup")),
+
+						if *input.Marker != "JOBID1" {
+							return nil, fmt.Errorf("unexpected marker “%s”", *input.Marker)
+						}
+
+						return &glacier.ListJobsOutput{
+							JobList: []*glacier.JobDescription{
+								{
+									Action:         aws.String("InventoryRetrieval"),
+									CompletionDate: aws.String("2017-09-14T14:00:00.000Z"),
+									CreationDate:   aws.String("2017-09-14T10:00:00.000Z"),
+									JobId:          aws.String("JOBID2"),
+									StatusCode:     aws.String("Succeeded"),
+									StatusMessage:  aws.String("Succeeded"),
+								},
+							},
 						}, nil
 					},
 				},
 			},
-			goFunc: func() {
-				// wait for the send task to start
-				time.Sleep(100 * time.Millisecond)
-				cancel()
-			},
-			expectedError: &cloud.JobsError{
-				Jobs: []string{"JOBID123"},
-				Code: cloud.JobsErrorCodeCancelled,
-				Err:  awserr.New(request.CanceledErrorCode, "request context canceled", context.Canceled),
+			expected: []cloud.Job{
+				{
+					ID:        "JOBID1",
+					Action:    "ArchiveRetrieval",
+					ArchiveID: "AWSID123",
+					Tier:      "Standard",
+					Status:    cloud.JobStatusInProgress,
+					CreatedAt: time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC),
+				},
+				{
+					ID:            "JOBID2",
+					Action:        "InventoryRetrieval",
+					Status:        cloud.JobStatusSucceeded,
+					StatusMessage: "Succeeded",
+					CreatedAt:     time.Date(2017, 9, 14, 10, 0, 0, 0, time.UTC),
+					CompletedAt:   time.Date(2017, 9, 14, 14, 0, 0, 0, time.UTC),
+				},
 			},
 		},
 		{
-			description: "it should detect when the task was cancelled by the user while the downloading the backup",
-			id:          "AWSID123",
+			description: "it should detect an error while listing the jobs",
 			awsCloud: cloud.AWSCloud{
 				Logger: mockLogger{
 					mockDebug:  func(args ...interface{}) {},
 					mockDebugf: func(format string, args ...interface{}) {},
-					mockInfo:   func(args ...interface{}) {},
-					mockInfof:  func(format string, args ...interface{}) {},
 				},
 				AccountID: "account",
 				VaultName: "vault",
 				Glacier: mockGlacierAPI{
-					mockInitiateJobWithContext: func(aws.Context, *glacier.InitiateJobInput, ...request.Option) (*glacier.InitiateJobOutput, error) {
-						return &glacier.InitiateJobOutput{
-							JobId: aws.String("JOBID123"),
-						}, nil
-					},
-					mockListJobsWithContext: func(aws.Context, *glacier.ListJobsInput, ...request.Option) (*glacier.ListJobsOutput, error) {
-						return &glacier.ListJobsOutput{
-							JobList: []*glacier.JobDescription{
-								{
-									JobId:      aws.String("JOBID123"),
-									Completed:  aws.Bool(true),
-									StatusCode: aws.String("Succeeded"),
-								},
-							},
-						}, nil
-					},
-					mockGetJobOutputWithContext: func(ctx aws.Context, g *glacier.GetJobOutputInput, opts ...request.Option) (*glacier.GetJobOutputOutput, error) {
-						select {
-						case <-time.After(200 * time.Millisecond):
-						// do nothing
-						case <-ctx.Done():
-							return nil, awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
-						}
-
-						return &glacier.GetJobOutputOutput{
-							Body: ioutil.NopCloser(bytes.NewBufferString("Important information for the test backup")),
-						}, nil
+					mockListJobsWithContext: func(ctx aws.Context, input *glacier.ListJobsInput, opts ...request.Option) (*glacier.ListJobsOutput, error) {
+						return nil, errors.New("vault not found")
 					},
 				},
 			},
-			goFunc: func() {
-				// wait for the send task to start
-				time.Sleep(100 * time.Millisecond)
-				cancel()
-			},
 			expectedError: &cloud.Error{
-				ID:   "AWSID123",
-				Code: cloud.ErrorCodeCancelled,
-				Err:  awserr.New(request.CanceledErrorCode, "request context canceled", context.Canceled),
+				Code: cloud.ErrorCodeListingJobs,
+				Err:  errors.New("vault not found"),
 			},
 		},
 	}
 
 	for _, scenario := range scenarios {
 		t.Run(scenario.description, func(t *testing.T) {
-			if scenario.goFunc != nil {
-				go scenario.goFunc()
+			jobs, err := scenario.awsCloud.ListJobs(context.Background())
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
 			}
 
-			filename, err := scenario.awsCloud.Get(ctx, scenario.id)
-			if !reflect.DeepEqual(scenario.expected, filename) {
-				t.Errorf("filenames don't match.\n%s", Diff(scenario.expected, filename))
-			}
-			if !cloud.ErrorEqual(scenario.expectedError, err) && !cloud.JobsErrorEqual(scenario.expectedError, err) {
-				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			if !reflect.DeepEqual(scenario.expected, jobs) {
+				t.Errorf("jobs don't match.\n%s", Diff(scenario.expected, jobs))
 			}
 		})
 	}
 }
 
-func TestAWSCloud_Remove(t *testing.T) {
+func TestAWSCloud_DescribeJob(t *testing.T) {
 	scenarios := []struct {
 		description   string
 		id            string
 		awsCloud      cloud.AWSCloud
+		expected      cloud.Job
 		expectedError error
 	}{
 		{
-			description: "it should remove a backup correctly",
-			id:          "AWSID123",
+			description: "it should describe the job correctly",
+			id:          "JOBID1",
 			awsCloud: cloud.AWSCloud{
 				Logger: mockLogger{
-					mockDebug:  func(args ...interface{}) {},
 					mockDebugf: func(format string, args ...interface{}) {},
-					mockInfo:   func(args ...interface{}) {},
-					mockInfof:  func(format string, args ...interface{}) {},
 				},
 				AccountID: "account",
 				VaultName: "vault",
 				Glacier: mockGlacierAPI{
-					mockDeleteArchiveWithContext: func(aws.Context, *glacier.DeleteArchiveInput, ...request.Option) (*glacier.DeleteArchiveOutput, error) {
-						return &glacier.DeleteArchiveOutput{}, nil
+					mockDescribeJobWithContext: func(ctx aws.Context, input *glacier.DescribeJobInput, opts ...request.Option) (*glacier.JobDescription, error) {
+						if *input.JobId != "JOBID1" {
+							return nil, fmt.Errorf("unexpected job “%s”", *input.JobId)
+						}
+
+						return &glacier.JobDescription{
+							Action:         aws.String("ArchiveRetrieval"),
+							ArchiveId:      aws.String("AWSID123"),
+							CompletionDate: aws.String("2017-09-14T14:30:00.000Z"),
+							CreationDate:   aws.String("2017-09-14T10:30:00.000Z"),
+							JobId:          aws.String("JOBID1"),
+							StatusCode:     aws.String("Failed"),
+							StatusMessage:  aws.String("archive not found"),
+						}, nil
 					},
 				},
 			},
+			expected: cloud.Job{
+				ID:            "JOBID1",
+				Action:        "ArchiveRetrieval",
+				ArchiveID:     "AWSID123",
+				Status:        cloud.JobStatusFailed,
+				StatusMessage: "archive not found",
+				CreatedAt:     time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC),
+				CompletedAt:   time.Date(2017, 9, 14, 14, 30, 0, 0, time.UTC),
+			},
 		},
 		{
-			description: "it should detect an error while removing a backup",
-			id:          "AWSID123",
+			description: "it should detect an error while describing the job",
+			id:          "JOBID1",
 			awsCloud: cloud.AWSCloud{
 				Logger: mockLogger{
 					mockDebug:  func(args ...interface{}) {},
 					mockDebugf: func(format string, args ...interface{}) {},
-					mockInfo:   func(args ...interface{}) {},
-					mockInfof:  func(format string, args ...interface{}) {},
 				},
 				AccountID: "account",
 				VaultName: "vault",
 				Glacier: mockGlacierAPI{
-					mockDeleteArchiveWithContext: func(aws.Context, *glacier.DeleteArchiveInput, ...request.Option) (*glacier.DeleteArchiveOutput, error) {
-						return nil, errors.New("no backup here")
+					mockDescribeJobWithContext: func(ctx aws.Context, input *glacier.DescribeJobInput, opts ...request.Option) (*glacier.JobDescription, error) {
+						return nil, errors.New("job not found")
 					},
 				},
 			},
 			expectedError: &cloud.Error{
-				ID:   "AWSID123",
-				Code: cloud.ErrorCodeRemovingArchive,
-				Err:  errors.New("no backup here"),
+				ID:   "JOBID1",
+				Code: cloud.ErrorCodeDescribingJob,
+				Err:  errors.New("job not found"),
 			},
 		},
 	}
 
 	for _, scenario := range scenarios {
 		t.Run(scenario.description, func(t *testing.T) {
-			err := scenario.awsCloud.Remove(context.Background(), scenario.id)
+			job, err := scenario.awsCloud.DescribeJob(context.Background(), scenario.id)
 			if !cloud.ErrorEqual(scenario.expectedError, err) {
 				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
 			}
+
+			if !reflect.DeepEqual(scenario.expected, job) {
+				t.Errorf("job doesn't match.\n%s", Diff(scenario.expected, job))
+			}
 		})
 	}
 }
@@ -1876,6 +3282,97 @@ func TestAWSCloud_Close(t *testing.T) {
 	}
 }
 
+func BenchmarkAWSCloud_Send(b *testing.B) {
+	scenarios := []struct {
+		description          string
+		size                 int
+		multipartUploadLimit int64
+		partSize             int64
+	}{
+		{
+			description:          "small archive",
+			size:                 1024 * 1024,
+			multipartUploadLimit: 104857600,
+			partSize:             4194304,
+		},
+		{
+			description:          "big archive",
+			size:                 32 * 1024 * 1024,
+			multipartUploadLimit: 1048576,
+			partSize:             1048576,
+		},
+	}
+
+	awsCloud := cloud.AWSCloud{
+		Logger: mockLogger{
+			mockDebug:  func(args ...interface{}) {},
+			mockDebugf: func(format string, args ...interface{}) {},
+			mockInfo:   func(args ...interface{}) {},
+			mockInfof:  func(format string, args ...interface{}) {},
+		},
+		AccountID: "account",
+		VaultName: "vault",
+		Glacier: mockGlacierAPI{
+			mockUploadArchiveWithContext: func(ctx aws.Context, u *glacier.UploadArchiveInput, opts ...request.Option) (*glacier.ArchiveCreationOutput, error) {
+				return &glacier.ArchiveCreationOutput{
+					ArchiveId: aws.String("AWSID123"),
+					Checksum:  u.Checksum,
+					Location:  aws.String("/archive/AWSID123"),
+				}, nil
+			},
+			mockInitiateMultipartUploadWithContext: func(aws.Context, *glacier.InitiateMultipartUploadInput, ...request.Option) (*glacier.InitiateMultipartUploadOutput, error) {
+				return &glacier.InitiateMultipartUploadOutput{
+					UploadId: aws.String("UPLOAD123"),
+				}, nil
+			},
+			mockUploadMultipartPartWithContext: func(ctx aws.Context, u *glacier.UploadMultipartPartInput, opts ...request.Option) (*glacier.UploadMultipartPartOutput, error) {
+				return &glacier.UploadMultipartPartOutput{
+					Checksum: u.Checksum,
+				}, nil
+			},
+			mockCompleteMultipartUploadWithContext: func(ctx aws.Context, c *glacier.CompleteMultipartUploadInput, opts ...request.Option) (*glacier.ArchiveCreationOutput, error) {
+				return &glacier.ArchiveCreationOutput{
+					ArchiveId: aws.String("AWSID123"),
+					Checksum:  c.Checksum,
+					Location:  aws.String("/archive/AWSID123"),
+				}, nil
+			},
+		},
+		Clock: fakeClock{
+			mockNow: func() time.Time {
+				return time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC)
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		f, err := ioutil.TempFile("", "toglacier-bench-")
+		if err != nil {
+			b.Fatalf("error creating file. details: %s", err)
+		}
+		defer os.Remove(f.Name())
+
+		content := make([]byte, scenario.size)
+		rand.New(rand.NewSource(int64(scenario.size))).Read(content)
+		f.Write(content)
+		f.Close()
+
+		b.Run(scenario.description, func(b *testing.B) {
+			awsCloud.MultipartUploadLimit = scenario.multipartUploadLimit
+			awsCloud.PartSize = scenario.partSize
+
+			b.SetBytes(int64(scenario.size))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := awsCloud.Send(context.Background(), f.Name(), ""); err != nil {
+					b.Fatalf("error sending archive. details: %s", err)
+				}
+			}
+		})
+	}
+}
+
 type mockGlacierAPI struct {
 	mockAbortMultipartUpload                   func(*glacier.AbortMultipartUploadInput) (*glacier.AbortMultipartUploadOutput, error)
 	mockAbortMultipartUploadWithContext        func(aws.Context, *glacier.AbortMultipartUploadInput, ...request.Option) (*glacier.AbortMultipartUploadOutput, error)
@@ -2434,6 +3931,45 @@ func (g mockGlacierAPI) WaitUntilVaultNotExistsWithContext(c aws.Context, d *gla
 	return g.mockWaitUntilVaultNotExistsWithContext(c, d, w...)
 }
 
+type mockUploadProgress struct {
+	mockOnUploadProgress func(status cloud.UploadStatus)
+	mockOnUploadFinished func()
+}
+
+func (m mockUploadProgress) OnUploadProgress(status cloud.UploadStatus) {
+	m.mockOnUploadProgress(status)
+}
+
+func (m mockUploadProgress) OnUploadFinished() {
+	m.mockOnUploadFinished()
+}
+
+type mockRetrievalTracker struct {
+	jobs     map[string]string
+	parts    map[string]int64
+	filename string
+	reported []int64
+}
+
+func (m *mockRetrievalTracker) Job(id string) string {
+	return m.jobs[id]
+}
+
+func (m *mockRetrievalTracker) JobInitiated(id, jobID string) error {
+	m.jobs[id] = jobID
+	return nil
+}
+
+func (m *mockRetrievalTracker) Part(id string) (string, int64) {
+	return m.filename, m.parts[id]
+}
+
+func (m *mockRetrievalTracker) PartDownloaded(id, filename string, size int64) error {
+	m.parts[id] = size
+	m.reported = append(m.reported, size)
+	return nil
+}
+
 type fakeClock struct {
 	mockNow func() time.Time
 }
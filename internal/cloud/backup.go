@@ -2,6 +2,7 @@ package cloud
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -24,8 +25,151 @@ type Backup struct {
 	// Size backup archive size.
 	Size int64
 
+	// Duration is the time spent to build, encrypt and send the archive. It is
+	// zero when unknown, as older backups and the audit file storage don't keep
+	// it.
+	Duration time.Duration
+
 	// Location defines where the backup was stored.
 	Location Location
+
+	// MachineID identifies the machine that created the backup. This is useful
+	// when many machines share the same vault.
+	MachineID string
+
+	// Comment is a note informed by the user when the backup was created, so
+	// meaningful backups can be identified later (e.g. “before OS upgrade”).
+	Comment string
+
+	// ParityID identifies the companion archive with the redundancy data, used
+	// to repair a corrupted download. Empty when no parity data was generated.
+	ParityID string
+
+	// CatalogID identifies the companion archive with the backup record and the
+	// archive information, used to rebuild the local storage without
+	// downloading the backups. Empty when no catalog was sent.
+	CatalogID string
+
+	// ReplicaID identifies the copy of the archive in the replica (e.g. a vault
+	// in another region), used when the archive can't be retrieved. Empty when
+	// no copy was sent.
+	ReplicaID string
+
+	// Partial is true when the build of the archive was stopped after the
+	// maximum duration, so the files not analyzed keep referencing the older
+	// archives. The audit file storage doesn't keep it.
+	Partial bool
+
+	// Stream is the name of the stream (e.g. a database dump) stored as is in
+	// the archive, instead of the backup paths. Empty for the backups of paths.
+	Stream string
+}
+
+const (
+	// companionParity is the archive with the parity data of a backup.
+	companionParity companionKind = "parity"
+
+	// companionCatalog is the archive with the catalog of a backup.
+	companionCatalog companionKind = "catalog"
+)
+
+// companionKind identifies the type of data stored in a companion archive.
+type companionKind string
+
+// companionKinds lists all companion types that can be stored in the object
+// metadata.
+var companionKinds = []companionKind{companionParity, companionCatalog}
+
+// companion identifies an archive that isn't a backup, but extra data that
+// belongs to a backup. An empty companion is used for the backups themselves.
+type companion struct {
+	kind     companionKind
+	backupID string
+}
+
+// archiveDescriptionMachineID is used to retrieve the machine identifier from
+// the archive description.
+var archiveDescriptionMachineID = regexp.MustCompile(`\(machine ([^)]+)\)$`)
+
+// archiveDescriptionComment is used to retrieve the backup comment from the
+// archive description.
+var archiveDescriptionComment = regexp.MustCompile(`^backup file from [^ ]+ "([^"]*)"`)
+
+// maxCommentLength limits the comment stored in the archive description, as the
+// description can't have more than 1024 characters.
+const maxCommentLength = 512
+
+// archiveDescriptionCompanion is used to retrieve the type of the companion
+// archive and the backup that it belongs to.
+var archiveDescriptionCompanion = regexp.MustCompile(`^(parity|catalog) file of ([^ ]+) `)
+
+// archiveDescription builds the text stored together with the archive in the
+// cloud. When the companion is informed the archive contains extra data of the
+// given backup. The description must contain only printable ASCII characters,
+// so any other character in the comment or in the machine identifier is
+// replaced.
+func archiveDescription(backup Backup, of companion) string {
+	description := fmt.Sprintf("backup file from %s", backup.CreatedAt.Format(time.RFC3339))
+	if of.backupID != "" {
+		description = fmt.Sprintf("%s file of %s from %s", of.kind, of.backupID, backup.CreatedAt.Format(time.RFC3339))
+
+	} else if backup.Comment != "" {
+		comment := backup.Comment
+		if len(comment) > maxCommentLength {
+			comment = comment[:maxCommentLength]
+		}
+
+		description = fmt.Sprintf(`%s "%s"`, description, descriptionField(comment, `"`))
+	}
+
+	if backup.MachineID == "" {
+		return description
+	}
+
+	return fmt.Sprintf("%s (machine %s)", description, descriptionField(backup.MachineID, "()"))
+}
+
+// descriptionField replaces the characters that aren't printable ASCII or that
+// are reserved by the archive description format.
+func descriptionField(value, reserved string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 32 || r > 126 || strings.ContainsRune(reserved, r) {
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+// parseArchiveDescription retrieves the machine identifier, the comment and the
+// backup that the companion archive belongs to from the archive description. If
+// the description doesn't contain the information (old backups) empty values
+// are returned.
+func parseArchiveDescription(description string) (machineID, comment string, of companion) {
+	if match := archiveDescriptionMachineID.FindStringSubmatch(description); match != nil {
+		machineID = match[1]
+	}
+
+	if match := archiveDescriptionComment.FindStringSubmatch(description); match != nil {
+		comment = match[1]
+	}
+
+	if match := archiveDescriptionCompanion.FindStringSubmatch(description); match != nil {
+		of = companion{kind: companionKind(match[1]), backupID: match[2]}
+	}
+
+	return
+}
+
+// linkCompanions stores the companion archive ids in the backups that they
+// belong to. The companions map contains the companion as key and the
+// companion archive id as value.
+func linkCompanions(backups []Backup, companions map[companion]string) []Backup {
+	for i := range backups {
+		backups[i].ParityID = companions[companion{kind: companionParity, backupID: backups[i].ID}]
+		backups[i].CatalogID = companions[companion{kind: companionCatalog, backupID: backups[i].ID}]
+	}
+
+	return backups
 }
 
 const (
@@ -34,6 +178,13 @@ const (
 
 	// LocationGCS indicates that the backup was stored in Google Cloud Storage.
 	LocationGCS Location = "gcs"
+
+	// LocationS3 indicates that the backup was stored in a S3-compatible
+	// service.
+	LocationS3 Location = "s3"
+
+	// LocationRclone indicates that the backup was stored in a rclone remote.
+	LocationRclone Location = "rclone"
 )
 
 // Location contains the cloud that is current storing the backup data.
@@ -49,6 +200,10 @@ func ParseLocation(value string) (Location, error) {
 		return LocationAWS, nil
 	case string(LocationGCS):
 		return LocationGCS, nil
+	case string(LocationS3):
+		return LocationS3, nil
+	case string(LocationRclone):
+		return LocationRclone, nil
 	}
 
 	// not return a library error here because this is used by the library itself
@@ -58,5 +213,5 @@ func ParseLocation(value string) (Location, error) {
 
 // Defined returns true if the location has a valid value.
 func (l Location) Defined() bool {
-	return l == LocationAWS || l == LocationGCS
+	return l == LocationAWS || l == LocationGCS || l == LocationS3 || l == LocationRclone
 }
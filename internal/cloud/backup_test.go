@@ -25,6 +25,16 @@ func TestParseLocation(t *testing.T) {
 			value:       "  GCS  ",
 			expected:    cloud.LocationGCS,
 		},
+		{
+			description: "it should convert a s3 location correctly",
+			value:       "  S3  ",
+			expected:    cloud.LocationS3,
+		},
+		{
+			description: "it should convert a rclone location correctly",
+			value:       "  Rclone  ",
+			expected:    cloud.LocationRclone,
+		},
 		{
 			description:   "it should detect an unknown location",
 			value:         "unknown-location",
@@ -7,9 +7,21 @@ import (
 // Cloud offers all necessary operations to manage backups in the cloud.
 type Cloud interface {
 	// Send uploads the file to the cloud and return the backup archive
-	// information. The upload operation can be cancelled anytime using the
-	// context.
-	Send(ctx context.Context, filename string) (Backup, error)
+	// information. The comment is an optional note stored together with the
+	// backup. The upload operation can be cancelled anytime using the context.
+	Send(ctx context.Context, filename, comment string) (Backup, error)
+
+	// SendParity uploads the parity file of the backup identified by backupID,
+	// returning the parity archive information. The parity archive is
+	// identified in the cloud, so it isn't listed as a backup. The upload
+	// operation can be cancelled anytime using the context.
+	SendParity(ctx context.Context, filename, backupID string) (Backup, error)
+
+	// SendCatalog uploads the catalog file of the backup identified by backupID,
+	// returning the catalog archive information. The catalog archive is
+	// identified in the cloud, so it isn't listed as a backup. The upload
+	// operation can be cancelled anytime using the context.
+	SendCatalog(ctx context.Context, filename, backupID string) (Backup, error)
 
 	// List retrieves all the uploaded backups information in the cloud. The
 	// operation can be cancelled anytime using the context.
@@ -27,3 +39,66 @@ type Cloud interface {
 	// Close ends the cloud service session.
 	Close() error
 }
+
+// VaultPolicy offers the operations to enforce WORM (write once, read many)
+// retention in the place where the backups are stored, so they can't be
+// removed before the period required by regulations. Not all clouds support
+// it.
+type VaultPolicy interface {
+	// InitiateLock attaches the lock policy (JSON document) to the vault,
+	// returning the lock identifier. The lock stays in progress for 24 hours,
+	// giving time to test the policy before completing it. The operation can be
+	// cancelled anytime using the context.
+	InitiateLock(ctx context.Context, policy string) (lockID string, err error)
+
+	// CompleteLock makes the lock policy immutable. After that the policy can't
+	// be changed or removed anymore. The operation can be cancelled anytime
+	// using the context.
+	CompleteLock(ctx context.Context, lockID string) error
+
+	// AbortLock removes a lock policy that wasn't completed yet. The operation
+	// can be cancelled anytime using the context.
+	AbortLock(ctx context.Context) error
+}
+
+// VaultSettings offers the operations to manage the place where the backups
+// are stored declaratively, so it can be kept as described in the
+// configuration. Not all clouds support it.
+type VaultSettings interface {
+	// SetAccessPolicy replaces the access policy (JSON document) of the vault.
+	// An empty policy removes the current one. The operation can be cancelled
+	// anytime using the context.
+	SetAccessPolicy(ctx context.Context, policy string) error
+
+	// SetTags makes the vault tags equal to the given ones, adding, updating or
+	// removing tags when necessary. The operation can be cancelled anytime
+	// using the context.
+	SetTags(ctx context.Context, tags map[string]string) error
+}
+
+// State offers the operations to keep the state of the local storage in the
+// cloud, so toglacier can run without a persistent disk (e.g. Kubernetes
+// CronJob). The state is a single object replaced on each save, so not all
+// clouds support it.
+type State interface {
+	// SaveState uploads the file with the state, replacing the previous one.
+	// The operation can be cancelled anytime using the context.
+	SaveState(ctx context.Context, filename string) error
+
+	// LoadState retrieves the state and stores it locally in a file. When there's
+	// no state yet an empty filename is returned. The operation can be cancelled
+	// anytime using the context.
+	LoadState(ctx context.Context) (filename string, err error)
+}
+
+// Jobs offers the operations to follow the asynchronous jobs of the cloud, as
+// the retrieval of an archive can take hours. Not all clouds have jobs.
+type Jobs interface {
+	// ListJobs retrieves the jobs in progress and the ones that completed
+	// recently. The operation can be cancelled anytime using the context.
+	ListJobs(ctx context.Context) ([]Job, error)
+
+	// DescribeJob retrieves the current status of the job. The operation can be
+	// cancelled anytime using the context.
+	DescribeJob(ctx context.Context, id string) (Job, error)
+}
@@ -77,6 +77,40 @@ const (
 	// ErrorCodeClosingConnection problem while closing the connection with the
 	// cloud.
 	ErrorCodeClosingConnection = "closing-connection"
+
+	// ErrorCodeInitiatingVaultLock error while attaching the lock policy to the
+	// vault.
+	ErrorCodeInitiatingVaultLock ErrorCode = "initiating-vault-lock"
+
+	// ErrorCodeCompletingVaultLock error while making the vault lock policy
+	// immutable.
+	ErrorCodeCompletingVaultLock ErrorCode = "completing-vault-lock"
+
+	// ErrorCodeAbortingVaultLock error while removing a vault lock policy that
+	// is in progress.
+	ErrorCodeAbortingVaultLock ErrorCode = "aborting-vault-lock"
+
+	// ErrorCodeVaultAccessPolicy error while replacing or removing the access
+	// policy of the vault.
+	ErrorCodeVaultAccessPolicy ErrorCode = "vault-access-policy"
+
+	// ErrorCodeVaultTags error while retrieving or changing the tags of the
+	// vault.
+	ErrorCodeVaultTags ErrorCode = "vault-tags"
+
+	// ErrorCodeSavingState error while uploading the state of the local storage
+	// to the cloud.
+	ErrorCodeSavingState ErrorCode = "saving-state"
+
+	// ErrorCodeLoadingState error while retrieving the state of the local
+	// storage from the cloud.
+	ErrorCodeLoadingState ErrorCode = "loading-state"
+
+	// ErrorCodeListingJobs error while retrieving the jobs of the vault.
+	ErrorCodeListingJobs ErrorCode = "listing-jobs"
+
+	// ErrorCodeDescribingJob error while retrieving the status of a job.
+	ErrorCodeDescribingJob ErrorCode = "describing-job"
 )
 
 // ErrorCode stores the error type that occurred while performing any operation
@@ -103,6 +137,15 @@ var errorCodeString = map[ErrorCode]string{
 	ErrorCodeIterating:           "error iterating in results",
 	ErrorCodeDownloadingArchive:  "error while downloading the archive",
 	ErrorCodeClosingConnection:   "error closing connection",
+	ErrorCodeInitiatingVaultLock: "error initiating vault lock",
+	ErrorCodeCompletingVaultLock: "error completing vault lock",
+	ErrorCodeAbortingVaultLock:   "error aborting vault lock",
+	ErrorCodeVaultAccessPolicy:   "error changing vault access policy",
+	ErrorCodeVaultTags:           "error changing vault tags",
+	ErrorCodeSavingState:         "error saving state",
+	ErrorCodeLoadingState:        "error loading state",
+	ErrorCodeListingJobs:         "error listing jobs",
+	ErrorCodeDescribingJob:       "error describing job",
 }
 
 // String translate the error code to a human readable text.
@@ -114,6 +157,43 @@ func (e ErrorCode) String() string {
 	return "unknown error code"
 }
 
+// temporaryErrorCodes are the problems that could be solved by trying again,
+// usually network failures or throttling while talking to the cloud. Problems
+// with the local archive, failed jobs and cancellations are permanent.
+var temporaryErrorCodes = map[ErrorCode]bool{
+	ErrorCodeRemoteArchiveInfo:  true,
+	ErrorCodeSendingArchive:     true,
+	ErrorCodeComparingChecksums: true,
+	ErrorCodeInitMultipart:      true,
+	ErrorCodeCompleteMultipart:  true,
+	ErrorCodeInitJob:            true,
+	ErrorCodeJobComplete:        true,
+	ErrorCodeCopyingData:        true,
+	ErrorCodeRemovingArchive:    true,
+	ErrorCodeIterating:          true,
+	ErrorCodeDownloadingArchive: true,
+	ErrorCodeClosingConnection:  true,
+	ErrorCodeVaultTags:          true,
+}
+
+var (
+	// ErrCancelled is matched with errors.Is when any cloud action, including
+	// multipart uploads and jobs monitoring, was cancelled by the user.
+	ErrCancelled = errors.New("cloud: action cancelled by the user")
+
+	// ErrChecksumMismatch is matched with errors.Is when the uploaded archive,
+	// or one of its parts, doesn't match the hash calculated by the cloud.
+	ErrChecksumMismatch = errors.New("cloud: checksum mismatch")
+
+	// ErrJobFailed is matched with errors.Is when an offline task in the cloud
+	// failed to complete.
+	ErrJobFailed = errors.New("cloud: job failed")
+
+	// ErrJobNotFound is matched with errors.Is when an offline task is missing
+	// from the cloud.
+	ErrJobNotFound = errors.New("cloud: job not found")
+)
+
 // Error stores error details from cloud operations.
 type Error struct {
 	ID   string
@@ -134,6 +214,27 @@ func (e Error) Error() string {
 	return e.String()
 }
 
+// Unwrap returns the low level error, so it can be inspected with errors.Is and
+// errors.As.
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// Is allows comparing the error with the sentinel errors of the package (e.g.
+// errors.Is(err, cloud.ErrCancelled)).
+func (e Error) Is(target error) bool {
+	switch target {
+	case ErrCancelled:
+		return e.Code == ErrorCodeCancelled
+	case ErrChecksumMismatch:
+		return e.Code == ErrorCodeComparingChecksums
+	case ErrJobFailed:
+		return e.Code == ErrorCodeJobFailed
+	}
+
+	return false
+}
+
 // String translate the error to a human readable text.
 func (e Error) String() string {
 	var id string
@@ -149,6 +250,11 @@ func (e Error) String() string {
 	return fmt.Sprintf("cloud: %s%s%s", id, e.Code, err)
 }
 
+// Temporary returns true when the operation could succeed if executed again.
+func (e Error) Temporary() bool {
+	return temporaryErrorCodes[e.Code]
+}
+
 // ErrorEqual compares two Error objects. This is useful to compare down to the
 // low level errors.
 func ErrorEqual(first, second error) bool {
@@ -156,10 +262,8 @@ func ErrorEqual(first, second error) bool {
 		return first == second
 	}
 
-	err1, ok1 := errors.Cause(first).(*Error)
-	err2, ok2 := errors.Cause(second).(*Error)
-
-	if !ok1 || !ok2 {
+	var err1, err2 *Error
+	if !errors.As(first, &err1) || !errors.As(second, &err2) {
 		return false
 	}
 
@@ -236,6 +340,25 @@ func (c MultipartError) Error() string {
 	return c.String()
 }
 
+// Unwrap returns the low level error, so it can be inspected with errors.Is and
+// errors.As.
+func (c MultipartError) Unwrap() error {
+	return c.Err
+}
+
+// Is allows comparing the error with the sentinel errors of the package (e.g.
+// errors.Is(err, cloud.ErrCancelled)).
+func (c MultipartError) Is(target error) bool {
+	switch target {
+	case ErrCancelled:
+		return c.Code == MultipartErrorCodeCancelled
+	case ErrChecksumMismatch:
+		return c.Code == MultipartErrorCodeComparingChecksums
+	}
+
+	return false
+}
+
 // String translate the error to a human readable text.
 func (c MultipartError) String() string {
 	var err string
@@ -246,6 +369,12 @@ func (c MultipartError) String() string {
 	return fmt.Sprintf("cloud: offset %d/%d, %s%s", c.Offset, c.Size, c.Code, err)
 }
 
+// Temporary returns true when the operation could succeed if executed again.
+// Only the problems while communicating with the cloud are temporary.
+func (c MultipartError) Temporary() bool {
+	return c.Code == MultipartErrorCodeSendingArchive || c.Code == MultipartErrorCodeComparingChecksums
+}
+
 // MultipartErrorEqual compares two MultipartError objects. This is useful to
 // compare down to the low level errors.
 func MultipartErrorEqual(first, second error) bool {
@@ -253,10 +382,8 @@ func MultipartErrorEqual(first, second error) bool {
 		return first == second
 	}
 
-	err1, ok1 := errors.Cause(first).(*MultipartError)
-	err2, ok2 := errors.Cause(second).(*MultipartError)
-
-	if !ok1 || !ok2 {
+	var err1, err2 *MultipartError
+	if !errors.As(first, &err1) || !errors.As(second, &err2) {
 		return false
 	}
 
@@ -326,6 +453,25 @@ func (c JobsError) Error() string {
 	return c.String()
 }
 
+// Unwrap returns the low level error, so it can be inspected with errors.Is and
+// errors.As.
+func (c JobsError) Unwrap() error {
+	return c.Err
+}
+
+// Is allows comparing the error with the sentinel errors of the package (e.g.
+// errors.Is(err, cloud.ErrJobNotFound)).
+func (c JobsError) Is(target error) bool {
+	switch target {
+	case ErrCancelled:
+		return c.Code == JobsErrorCodeCancelled
+	case ErrJobNotFound:
+		return c.Code == JobsErrorCodeJobNotFound
+	}
+
+	return false
+}
+
 // String translate the error to a human readable text.
 func (c JobsError) String() string {
 	var jobs string
@@ -341,6 +487,12 @@ func (c JobsError) String() string {
 	return fmt.Sprintf("cloud: %s%s%s", jobs, c.Code, err)
 }
 
+// Temporary returns true when the operation could succeed if executed again.
+// Only the problems while communicating with the cloud are temporary.
+func (c JobsError) Temporary() bool {
+	return c.Code == JobsErrorCodeRetrievingJob
+}
+
 // JobsErrorEqual compares two JobsError objects. This is useful to compare down
 // to the low level errors.
 func JobsErrorEqual(first, second error) bool {
@@ -348,10 +500,8 @@ func JobsErrorEqual(first, second error) bool {
 		return first == second
 	}
 
-	err1, ok1 := errors.Cause(first).(*JobsError)
-	err2, ok2 := errors.Cause(second).(*JobsError)
-
-	if !ok1 || !ok2 {
+	var err1, err2 *JobsError
+	if !errors.As(first, &err1) || !errors.As(second, &err2) {
 		return false
 	}
 
@@ -1,9 +1,10 @@
 package cloud_test
 
 import (
-	"errors"
+	"context"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/rafaeljusto/toglacier/internal/cloud"
 )
 
@@ -133,6 +134,51 @@ func TestError_Error(t *testing.T) {
 			err:         &cloud.Error{Code: cloud.ErrorCodeClosingConnection},
 			expected:    "cloud: error closing connection",
 		},
+		{
+			description: "it should show the correct error message for initiating vault lock problem",
+			err:         &cloud.Error{Code: cloud.ErrorCodeInitiatingVaultLock},
+			expected:    "cloud: error initiating vault lock",
+		},
+		{
+			description: "it should show the correct error message for completing vault lock problem",
+			err:         &cloud.Error{Code: cloud.ErrorCodeCompletingVaultLock},
+			expected:    "cloud: error completing vault lock",
+		},
+		{
+			description: "it should show the correct error message for aborting vault lock problem",
+			err:         &cloud.Error{Code: cloud.ErrorCodeAbortingVaultLock},
+			expected:    "cloud: error aborting vault lock",
+		},
+		{
+			description: "it should show the correct error message for vault access policy problem",
+			err:         &cloud.Error{Code: cloud.ErrorCodeVaultAccessPolicy},
+			expected:    "cloud: error changing vault access policy",
+		},
+		{
+			description: "it should show the correct error message for vault tags problem",
+			err:         &cloud.Error{Code: cloud.ErrorCodeVaultTags},
+			expected:    "cloud: error changing vault tags",
+		},
+		{
+			description: "it should show the correct error message for state saving problem",
+			err:         &cloud.Error{Code: cloud.ErrorCodeSavingState},
+			expected:    "cloud: error saving state",
+		},
+		{
+			description: "it should show the correct error message for state loading problem",
+			err:         &cloud.Error{Code: cloud.ErrorCodeLoadingState},
+			expected:    "cloud: error loading state",
+		},
+		{
+			description: "it should show the correct error message for jobs listing problem",
+			err:         &cloud.Error{Code: cloud.ErrorCodeListingJobs},
+			expected:    "cloud: error listing jobs",
+		},
+		{
+			description: "it should show the correct error message for job description problem",
+			err:         &cloud.Error{ID: "JOB123", Code: cloud.ErrorCodeDescribingJob},
+			expected:    "cloud: id “JOB123”, error describing job",
+		},
 		{
 			description: "it should detect when the code doesn't exist",
 			err:         &cloud.Error{Code: cloud.ErrorCode("i-dont-exist")},
@@ -149,6 +195,121 @@ func TestError_Error(t *testing.T) {
 	}
 }
 
+func TestError_Temporary(t *testing.T) {
+	scenarios := []struct {
+		description string
+		err         interface {
+			Temporary() bool
+		}
+		expected bool
+	}{
+		{
+			description: "it should detect a temporary error while sending the archive",
+			err:         &cloud.Error{Code: cloud.ErrorCodeSendingArchive},
+			expected:    true,
+		},
+		{
+			description: "it should detect a permanent error while opening the archive",
+			err:         &cloud.Error{Code: cloud.ErrorCodeOpeningArchive},
+		},
+		{
+			description: "it should detect that a cancellation is permanent",
+			err:         &cloud.Error{Code: cloud.ErrorCodeCancelled},
+		},
+		{
+			description: "it should detect a temporary error while sending an archive part",
+			err:         &cloud.MultipartError{Code: cloud.MultipartErrorCodeSendingArchive},
+			expected:    true,
+		},
+		{
+			description: "it should detect a permanent error while reading an archive part",
+			err:         &cloud.MultipartError{Code: cloud.MultipartErrorCodeReadingArchive},
+		},
+		{
+			description: "it should detect a temporary error while retrieving the jobs",
+			err:         &cloud.JobsError{Code: cloud.JobsErrorCodeRetrievingJob},
+			expected:    true,
+		},
+		{
+			description: "it should detect a permanent error when the job isn't found",
+			err:         &cloud.JobsError{Code: cloud.JobsErrorCodeJobNotFound},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if temporary := scenario.err.Temporary(); temporary != scenario.expected {
+				t.Errorf("temporary flag doesn't match. expected “%t” and got “%t”", scenario.expected, temporary)
+			}
+		})
+	}
+}
+
+func TestError_Is(t *testing.T) {
+	scenarios := []struct {
+		description string
+		err         error
+		target      error
+		expected    bool
+	}{
+		{
+			description: "it should detect a cancellation",
+			err:         errors.Wrap(&cloud.Error{Code: cloud.ErrorCodeCancelled}, "sending backup"),
+			target:      cloud.ErrCancelled,
+			expected:    true,
+		},
+		{
+			description: "it should detect a cancellation of an archive part",
+			err:         &cloud.MultipartError{Code: cloud.MultipartErrorCodeCancelled},
+			target:      cloud.ErrCancelled,
+			expected:    true,
+		},
+		{
+			description: "it should detect a cancellation while monitoring jobs",
+			err:         &cloud.JobsError{Code: cloud.JobsErrorCodeCancelled},
+			target:      cloud.ErrCancelled,
+			expected:    true,
+		},
+		{
+			description: "it should detect a checksum mismatch of an archive part",
+			err:         &cloud.MultipartError{Code: cloud.MultipartErrorCodeComparingChecksums},
+			target:      cloud.ErrChecksumMismatch,
+			expected:    true,
+		},
+		{
+			description: "it should detect a failed job",
+			err:         &cloud.Error{Code: cloud.ErrorCodeJobFailed},
+			target:      cloud.ErrJobFailed,
+			expected:    true,
+		},
+		{
+			description: "it should detect a missing job",
+			err:         &cloud.JobsError{Code: cloud.JobsErrorCodeJobNotFound},
+			target:      cloud.ErrJobNotFound,
+			expected:    true,
+		},
+		{
+			description: "it should detect the low level error",
+			err:         &cloud.Error{Code: cloud.ErrorCodeSendingArchive, Err: context.DeadlineExceeded},
+			target:      context.DeadlineExceeded,
+			expected:    true,
+		},
+		{
+			description: "it should not match a different sentinel error",
+			err:         &cloud.Error{Code: cloud.ErrorCodeSendingArchive},
+			target:      cloud.ErrCancelled,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if is := errors.Is(scenario.err, scenario.target); is != scenario.expected {
+				t.Errorf("results don't match. expected “%t” and got “%t”", scenario.expected, is)
+			}
+		})
+	}
+}
+
 func TestErrorEqual(t *testing.T) {
 	scenarios := []struct {
 		description string
@@ -170,6 +331,20 @@ func TestErrorEqual(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			description: "it should detect equal Error instances wrapped with context",
+			err1: &cloud.Error{
+				ID:   "AWSID123",
+				Code: cloud.ErrorCodeInitializingSession,
+				Err:  errors.New("low level error"),
+			},
+			err2: errors.Wrap(&cloud.Error{
+				ID:   "AWSID123",
+				Code: cloud.ErrorCodeInitializingSession,
+				Err:  errors.New("low level error"),
+			}, "initializing"),
+			expected: true,
+		},
 		{
 			description: "it should detect when the ID is different",
 			err1: &cloud.Error{
@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"regexp"
@@ -20,6 +21,28 @@ import (
 	"google.golang.org/api/option"
 )
 
+// gcsMetadataMachineID is the object metadata key that stores the machine
+// identifier.
+const gcsMetadataMachineID = "machine-id"
+
+// gcsMetadataComment is the object metadata key that stores the note informed
+// by the user when the backup was created.
+const gcsMetadataComment = "comment"
+
+// gcsMetadataCompanionOf is the suffix of the object metadata key that stores
+// the backup that the companion object belongs to. The key is prefixed with the
+// companion type (e.g. parity-of).
+const gcsMetadataCompanionOf = "-of"
+
+// gcsMetadataState is the object metadata key that identifies the object
+// storing the state of the local storage, so it isn't listed as a backup.
+const gcsMetadataState = "state"
+
+// gcsStateObject is the name of the object storing the state of the local
+// storage. When the machine identifier is defined it is used as a suffix, so
+// many machines can share the same bucket.
+const gcsStateObject = "toglacier-state"
+
 // nonLetterDigit will remove all characters that could cause problems when
 // generating a backup id.
 var nonLetterDigit = regexp.MustCompile(`[^a-zA-Z0-9]`)
@@ -29,6 +52,7 @@ type GCSConfig struct {
 	Project     string
 	Bucket      string
 	AccountFile string
+	MachineID   string
 }
 
 // GCSClient contains all used methods from the Google Cloud Storage SDK client
@@ -52,7 +76,7 @@ type GCSBucket interface {
 // locally.
 type GCSObjectHandler interface {
 	Read(ctx gcscontext.Context, obj *storage.ObjectHandle, w io.Writer) error
-	Write(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader) error
+	Write(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader, metadata map[string]string) error
 	Attrs(ctx gcscontext.Context, obj *storage.ObjectHandle) (*storage.ObjectAttrs, error)
 	Delete(ctx gcscontext.Context, obj *storage.ObjectHandle) error
 	Iterate(it *storage.ObjectIterator) (*storage.ObjectAttrs, error)
@@ -71,9 +95,10 @@ func (g gcsObjectHandler) Read(ctx gcscontext.Context, obj *storage.ObjectHandle
 	return err
 }
 
-func (g gcsObjectHandler) Write(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader) error {
+func (g gcsObjectHandler) Write(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader, metadata map[string]string) error {
 	w := obj.NewWriter(ctx)
 	w.ContentType = "application/octet-stream"
+	w.Metadata = metadata
 
 	if _, err := io.Copy(w, r); err != nil {
 		return err
@@ -102,6 +127,7 @@ type GCS struct {
 	Client        GCSClient
 	Bucket        GCSBucket
 	BucketName    string
+	MachineID     string
 	ObjectHandler GCSObjectHandler
 }
 
@@ -131,6 +157,7 @@ func NewGCS(ctx context.Context, logger log.Logger, config GCSConfig) (*GCS, err
 		Client:        c,
 		Bucket:        c.Bucket(config.Bucket),
 		BucketName:    config.Bucket,
+		MachineID:     config.MachineID,
 		ObjectHandler: gcsObjectHandler{},
 	}, nil
 }
@@ -151,12 +178,56 @@ func NewGCS(ctx context.Context, logger log.Logger, config GCSConfig) (*GCS, err
 //         // unknown error
 //       }
 //     }
-func (g *GCS) Send(ctx context.Context, filename string) (Backup, error) {
+func (g *GCS) Send(ctx context.Context, filename, comment string) (Backup, error) {
+	return g.send(ctx, filename, comment, companion{})
+}
+
+// SendParity uploads the parity file of a backup to the cloud, identifying it
+// in the object metadata. If an error occurs it will be an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (g *GCS) SendParity(ctx context.Context, filename, backupID string) (Backup, error) {
+	return g.send(ctx, filename, "", companion{kind: companionParity, backupID: backupID})
+}
+
+// SendCatalog uploads the catalog file of a backup to the cloud, identifying it
+// in the object metadata. If an error occurs it will be an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (g *GCS) SendCatalog(ctx context.Context, filename, backupID string) (Backup, error) {
+	return g.send(ctx, filename, "", companion{kind: companionCatalog, backupID: backupID})
+}
+
+func (g *GCS) send(ctx context.Context, filename, comment string, of companion) (Backup, error) {
 	g.Logger.Debugf("cloud: sending file “%s” to google cloud", filename)
 
-	f, err := os.Open(filename)
+	f, err := OpenArchive(ctx, filename)
 	if err != nil {
-		return Backup{}, errors.WithStack(newError("", ErrorCodeOpeningArchive, err))
+		return Backup{}, errors.WithStack(err)
 	}
 	defer f.Close()
 
@@ -165,7 +236,28 @@ func (g *GCS) Send(ctx context.Context, filename string) (Backup, error) {
 	filenameHash := sha256.Sum256([]byte(filename))
 	id := fmt.Sprintf("%s%d", nonLetterDigit.ReplaceAllString(base64.StdEncoding.EncodeToString(filenameHash[:]), ""), time.Now().UnixNano())
 
-	if err = g.ObjectHandler.Write(ctx, g.Bucket.Object(id), f); err != nil {
+	var metadata map[string]string
+	if g.MachineID != "" {
+		metadata = map[string]string{
+			gcsMetadataMachineID: g.MachineID,
+		}
+	}
+
+	if comment != "" {
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[gcsMetadataComment] = comment
+	}
+
+	if of.backupID != "" {
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[string(of.kind)+gcsMetadataCompanionOf] = of.backupID
+	}
+
+	if err = g.ObjectHandler.Write(ctx, g.Bucket.Object(id), f, metadata); err != nil {
 		return Backup{}, errors.WithStack(g.checkCancellation(newError("", ErrorCodeSendingArchive, err)))
 	}
 
@@ -181,6 +273,8 @@ func (g *GCS) Send(ctx context.Context, filename string) (Backup, error) {
 		VaultName: g.BucketName,
 		Size:      attrs.Size,
 		Location:  LocationGCS,
+		MachineID: attrs.Metadata[gcsMetadataMachineID],
+		Comment:   attrs.Metadata[gcsMetadataComment],
 	}, nil
 }
 
@@ -204,6 +298,7 @@ func (g *GCS) List(ctx context.Context) ([]Backup, error) {
 	g.Logger.Debug("cloud: retrieving list of archives from the google cloud")
 
 	var backups []Backup
+	companions := make(map[companion]string)
 	it := g.Bucket.Objects(ctx, nil)
 
 	for {
@@ -216,6 +311,18 @@ func (g *GCS) List(ctx context.Context) ([]Backup, error) {
 			return nil, errors.WithStack(g.checkCancellation(newError("", ErrorCodeIterating, err)))
 		}
 
+		// the state object isn't a backup
+		if objAttrs.Metadata[gcsMetadataState] != "" {
+			continue
+		}
+
+		// companion objects (parity and catalog) aren't backups, they are linked
+		// to the backup that they belong to
+		if of := gcsCompanion(objAttrs.Metadata); of.backupID != "" {
+			companions[of] = objAttrs.Name
+			continue
+		}
+
 		backups = append(backups, Backup{
 			ID:        objAttrs.Name,
 			CreatedAt: objAttrs.Created,
@@ -223,11 +330,25 @@ func (g *GCS) List(ctx context.Context) ([]Backup, error) {
 			VaultName: g.BucketName,
 			Size:      objAttrs.Size,
 			Location:  LocationGCS,
+			MachineID: objAttrs.Metadata[gcsMetadataMachineID],
+			Comment:   objAttrs.Metadata[gcsMetadataComment],
 		})
 	}
 
 	g.Logger.Info("cloud: remote backups listed successfully from the google cloud")
-	return backups, nil
+	return linkCompanions(backups, companions), nil
+}
+
+// gcsCompanion retrieves from the object metadata the backup that the companion
+// object belongs to. An empty companion is returned for backup objects.
+func gcsCompanion(metadata map[string]string) companion {
+	for _, kind := range companionKinds {
+		if backupID := metadata[string(kind)+gcsMetadataCompanionOf]; backupID != "" {
+			return companion{kind: kind, backupID: backupID}
+		}
+	}
+
+	return companion{}
 }
 
 // Get retrieves a specific backup file and stores it locally in a file. The
@@ -330,6 +451,98 @@ func (g *GCS) Remove(ctx context.Context, id string) error {
 	return nil
 }
 
+// SaveState uploads the file with the state of the local storage, replacing
+// the previous one. If an error occurs it will be an Error type encapsulated in
+// a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (g *GCS) SaveState(ctx context.Context, filename string) error {
+	g.Logger.Debugf("cloud: sending state “%s” to the google cloud", filename)
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return errors.WithStack(newError("", ErrorCodeOpeningArchive, err))
+	}
+	defer f.Close()
+
+	metadata := map[string]string{
+		gcsMetadataState: "true",
+	}
+
+	if g.MachineID != "" {
+		metadata[gcsMetadataMachineID] = g.MachineID
+	}
+
+	if err = g.ObjectHandler.Write(ctx, g.Bucket.Object(g.stateObject()), f, metadata); err != nil {
+		return errors.WithStack(g.checkCancellation(newError("", ErrorCodeSavingState, err)))
+	}
+
+	g.Logger.Info("cloud: state sent successfully to the google cloud")
+	return nil
+}
+
+// LoadState retrieves the state of the local storage and stores it locally in a
+// file. When there's no state in the cloud yet an empty filename is returned.
+// If an error occurs it will be an Error type encapsulated in a traceable
+// error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (g *GCS) LoadState(ctx context.Context) (string, error) {
+	g.Logger.Debug("cloud: retrieving state from the google cloud")
+
+	state, err := ioutil.TempFile("", "toglacier-state-")
+	if err != nil {
+		return "", errors.WithStack(newError("", ErrorCodeCreatingArchive, err))
+	}
+	defer state.Close()
+
+	if err = g.ObjectHandler.Read(ctx, g.Bucket.Object(g.stateObject()), state); err != nil {
+		os.Remove(state.Name())
+
+		if errors.Cause(err) == storage.ErrObjectNotExist {
+			g.Logger.Info("cloud: no state found in the google cloud")
+			return "", nil
+		}
+
+		return "", errors.WithStack(g.checkCancellation(newError("", ErrorCodeLoadingState, err)))
+	}
+
+	g.Logger.Infof("cloud: state retrieved successfully from the google cloud and saved in temporary file “%s”", state.Name())
+	return state.Name(), nil
+}
+
+// stateObject returns the name of the object storing the state of the local
+// storage.
+func (g *GCS) stateObject() string {
+	if g.MachineID == "" {
+		return gcsStateObject
+	}
+
+	return gcsStateObject + "-" + nonLetterDigit.ReplaceAllString(g.MachineID, "")
+}
+
 // Close ends the Google Cloud session.
 func (g *GCS) Close() error {
 	if g == nil || g.Client == nil {
@@ -128,6 +128,7 @@ func TestGCS_Send(t *testing.T) {
 	scenarios := []struct {
 		description   string
 		filename      string
+		comment       string
 		gcs           cloud.GCS
 		goFunc        func()
 		expected      cloud.Backup
@@ -165,6 +166,7 @@ func TestGCS_Send(t *testing.T) {
 				f.WriteString("Important information for the test backup")
 				return f.Name()
 			}(),
+			comment: "before OS upgrade",
 			gcs: cloud.GCS{
 				Logger: mockLogger{
 					mockDebug:  func(args ...interface{}) {},
@@ -183,8 +185,17 @@ func TestGCS_Send(t *testing.T) {
 					},
 				},
 				BucketName: "backup",
+				MachineID:  "server1",
 				ObjectHandler: mockGCSObjectHandler{
-					mockWrite: func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader) error {
+					mockWrite: func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader, metadata map[string]string) error {
+						if machineID := metadata["machine-id"]; machineID != "server1" {
+							return fmt.Errorf("unexpected machine id “%s”", machineID)
+						}
+
+						if comment := metadata["comment"]; comment != "before OS upgrade" {
+							return fmt.Errorf("unexpected comment “%s”", comment)
+						}
+
 						return nil
 					},
 					mockAttrs: func(ctx gcscontext.Context, obj *storage.ObjectHandle) (*storage.ObjectAttrs, error) {
@@ -199,6 +210,10 @@ func TestGCS_Send(t *testing.T) {
 								return hash
 							}(),
 							Created: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+							Metadata: map[string]string{
+								"machine-id": "server1",
+								"comment":    "before OS upgrade",
+							},
 						}, nil
 					},
 				},
@@ -210,6 +225,8 @@ func TestGCS_Send(t *testing.T) {
 				VaultName: "backup",
 				Size:      41,
 				Location:  cloud.LocationGCS,
+				MachineID: "server1",
+				Comment:   "before OS upgrade",
 			},
 		},
 		{
@@ -243,7 +260,7 @@ func TestGCS_Send(t *testing.T) {
 				},
 				BucketName: "backup",
 				ObjectHandler: mockGCSObjectHandler{
-					mockWrite: func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader) error {
+					mockWrite: func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader, metadata map[string]string) error {
 						return errors.New("error uploading data")
 					},
 				},
@@ -284,7 +301,7 @@ func TestGCS_Send(t *testing.T) {
 				},
 				BucketName: "backup",
 				ObjectHandler: mockGCSObjectHandler{
-					mockWrite: func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader) error {
+					mockWrite: func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader, metadata map[string]string) error {
 						return nil
 					},
 					mockAttrs: func(ctx gcscontext.Context, obj *storage.ObjectHandle) (*storage.ObjectAttrs, error) {
@@ -328,7 +345,7 @@ func TestGCS_Send(t *testing.T) {
 				},
 				BucketName: "backup",
 				ObjectHandler: mockGCSObjectHandler{
-					mockWrite: func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader) error {
+					mockWrite: func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader, metadata map[string]string) error {
 						// sleep for a small amount of time to allow the task to be
 						// cancelled
 						select {
@@ -374,7 +391,157 @@ func TestGCS_Send(t *testing.T) {
 				go scenario.goFunc()
 			}
 
-			backup, err := scenario.gcs.Send(ctx, scenario.filename)
+			backup, err := scenario.gcs.Send(ctx, scenario.filename, scenario.comment)
+			if !reflect.DeepEqual(scenario.expected, backup) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backup))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestGCS_SendParity(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		filename      string
+		gcs           cloud.GCS
+		backupID      string
+		expected      cloud.Backup
+		expectedError error
+	}{
+		{
+			description: "it should send a parity file correctly",
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test-")
+				if err != nil {
+					t.Fatalf("error creating file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString("Important information for the test backup")
+				return f.Name()
+			}(),
+			gcs: cloud.GCS{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				Bucket: mockGCSBucket{
+					mockObject: func(name string) *storage.ObjectHandle {
+						return &storage.ObjectHandle{}
+					},
+				},
+				BucketName: "backup",
+				ObjectHandler: mockGCSObjectHandler{
+					mockWrite: func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader, metadata map[string]string) error {
+						if parityOf := metadata["parity-of"]; parityOf != "GCSID123" {
+							return fmt.Errorf("unexpected parity reference “%s”", parityOf)
+						}
+
+						return nil
+					},
+					mockAttrs: func(ctx gcscontext.Context, obj *storage.ObjectHandle) (*storage.ObjectAttrs, error) {
+						return &storage.ObjectAttrs{
+							Name:    "GCSID124",
+							Size:    41,
+							Created: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+						}, nil
+					},
+				},
+			},
+			backupID: "GCSID123",
+			expected: cloud.Backup{
+				ID:        "GCSID124",
+				CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+				VaultName: "backup",
+				Size:      41,
+				Location:  cloud.LocationGCS,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			backup, err := scenario.gcs.SendParity(context.Background(), scenario.filename, scenario.backupID)
+			if !reflect.DeepEqual(scenario.expected, backup) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backup))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestGCS_SendCatalog(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		filename      string
+		gcs           cloud.GCS
+		backupID      string
+		expected      cloud.Backup
+		expectedError error
+	}{
+		{
+			description: "it should send a catalog file correctly",
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test-")
+				if err != nil {
+					t.Fatalf("error creating file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString("Important information for the test backup")
+				return f.Name()
+			}(),
+			gcs: cloud.GCS{
+				Logger: mockLogger{
+					mockDebug:  func(args ...interface{}) {},
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+					mockInfof:  func(format string, args ...interface{}) {},
+				},
+				Bucket: mockGCSBucket{
+					mockObject: func(name string) *storage.ObjectHandle {
+						return &storage.ObjectHandle{}
+					},
+				},
+				BucketName: "backup",
+				ObjectHandler: mockGCSObjectHandler{
+					mockWrite: func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader, metadata map[string]string) error {
+						if catalogOf := metadata["catalog-of"]; catalogOf != "GCSID123" {
+							return fmt.Errorf("unexpected catalog reference “%s”", catalogOf)
+						}
+
+						return nil
+					},
+					mockAttrs: func(ctx gcscontext.Context, obj *storage.ObjectHandle) (*storage.ObjectAttrs, error) {
+						return &storage.ObjectAttrs{
+							Name:    "GCSID124",
+							Size:    41,
+							Created: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+						}, nil
+					},
+				},
+			},
+			backupID: "GCSID123",
+			expected: cloud.Backup{
+				ID:        "GCSID124",
+				CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+				VaultName: "backup",
+				Size:      41,
+				Location:  cloud.LocationGCS,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			backup, err := scenario.gcs.SendCatalog(context.Background(), scenario.filename, scenario.backupID)
 			if !reflect.DeepEqual(scenario.expected, backup) {
 				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backup))
 			}
@@ -447,6 +614,39 @@ func TestGCS_List(t *testing.T) {
 										return hash
 									}(),
 									Created: time.Date(2017, 9, 13, 13, 27, 53, 0, time.UTC),
+									Metadata: map[string]string{
+										"machine-id": "server1",
+									},
+								}, nil
+							case 3:
+								return &storage.ObjectAttrs{
+									Name:    "GCSID125",
+									Size:    8,
+									Created: time.Date(2017, 9, 13, 13, 28, 0, 0, time.UTC),
+									Metadata: map[string]string{
+										"machine-id": "server1",
+										"parity-of":  "GCSID124",
+									},
+								}, nil
+							case 4:
+								return &storage.ObjectAttrs{
+									Name:    "GCSID126",
+									Size:    4,
+									Created: time.Date(2017, 9, 13, 13, 28, 10, 0, time.UTC),
+									Metadata: map[string]string{
+										"machine-id": "server1",
+										"catalog-of": "GCSID124",
+									},
+								}, nil
+							case 5:
+								return &storage.ObjectAttrs{
+									Name:    "toglacier-state-server1",
+									Size:    16,
+									Created: time.Date(2017, 9, 13, 13, 28, 20, 0, time.UTC),
+									Metadata: map[string]string{
+										"machine-id": "server1",
+										"state":      "true",
+									},
 								}, nil
 							default:
 								return nil, iterator.Done
@@ -471,6 +671,9 @@ func TestGCS_List(t *testing.T) {
 					VaultName: "backup",
 					Size:      72,
 					Location:  cloud.LocationGCS,
+					MachineID: "server1",
+					ParityID:  "GCSID125",
+					CatalogID: "GCSID126",
 				},
 			},
 		},
@@ -872,6 +1075,205 @@ func TestGCS_Remove(t *testing.T) {
 	}
 }
 
+func TestGCS_SaveState(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		filename      string
+		gcs           cloud.GCS
+		expectedError error
+	}{
+		{
+			description: "it should send the state correctly",
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test-")
+				if err != nil {
+					t.Fatalf("error creating file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString("local storage state")
+				return f.Name()
+			}(),
+			gcs: cloud.GCS{
+				Logger: mockLogger{
+					mockDebugf: func(format string, args ...interface{}) {},
+					mockInfo:   func(args ...interface{}) {},
+				},
+				Bucket: mockGCSBucket{
+					mockObject: func(name string) *storage.ObjectHandle {
+						if name != "toglacier-state-server1" {
+							t.Errorf("unexpected state object “%s”", name)
+						}
+
+						return &storage.ObjectHandle{}
+					},
+				},
+				BucketName: "backup",
+				MachineID:  "server-1",
+				ObjectHandler: mockGCSObjectHandler{
+					mockWrite: func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader, metadata map[string]string) error {
+						expected := map[string]string{
+							"state":      "true",
+							"machine-id": "server-1",
+						}
+
+						if !reflect.DeepEqual(expected, metadata) {
+							return fmt.Errorf("unexpected metadata %v", metadata)
+						}
+
+						return nil
+					},
+				},
+			},
+		},
+		{
+			description: "it should detect an error while writing the object",
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test-")
+				if err != nil {
+					t.Fatalf("error creating file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString("local storage state")
+				return f.Name()
+			}(),
+			gcs: cloud.GCS{
+				Logger: mockLogger{
+					mockDebugf: func(format string, args ...interface{}) {},
+				},
+				Bucket: mockGCSBucket{
+					mockObject: func(name string) *storage.ObjectHandle {
+						return &storage.ObjectHandle{}
+					},
+				},
+				BucketName: "backup",
+				ObjectHandler: mockGCSObjectHandler{
+					mockWrite: func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader, metadata map[string]string) error {
+						return errors.New("error writing object")
+					},
+				},
+			},
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeSavingState,
+				Err:  errors.New("error writing object"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			err := scenario.gcs.SaveState(context.Background(), scenario.filename)
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestGCS_LoadState(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		gcs           cloud.GCS
+		expected      string
+		expectedError error
+	}{
+		{
+			description: "it should retrieve the state correctly",
+			gcs: cloud.GCS{
+				Logger: mockLogger{
+					mockDebug: func(args ...interface{}) {},
+					mockInfof: func(format string, args ...interface{}) {},
+				},
+				Bucket: mockGCSBucket{
+					mockObject: func(name string) *storage.ObjectHandle {
+						if name != "toglacier-state" {
+							t.Errorf("unexpected state object “%s”", name)
+						}
+
+						return &storage.ObjectHandle{}
+					},
+				},
+				BucketName: "backup",
+				ObjectHandler: mockGCSObjectHandler{
+					mockRead: func(ctx gcscontext.Context, obj *storage.ObjectHandle, w io.Writer) error {
+						_, err := w.Write([]byte("local storage state"))
+						return err
+					},
+				},
+			},
+			expected: "local storage state",
+		},
+		{
+			description: "it should detect when there's no state yet",
+			gcs: cloud.GCS{
+				Logger: mockLogger{
+					mockDebug: func(args ...interface{}) {},
+					mockInfo:  func(args ...interface{}) {},
+				},
+				Bucket: mockGCSBucket{
+					mockObject: func(name string) *storage.ObjectHandle {
+						return &storage.ObjectHandle{}
+					},
+				},
+				BucketName: "backup",
+				ObjectHandler: mockGCSObjectHandler{
+					mockRead: func(ctx gcscontext.Context, obj *storage.ObjectHandle, w io.Writer) error {
+						return storage.ErrObjectNotExist
+					},
+				},
+			},
+		},
+		{
+			description: "it should detect an error while reading the object",
+			gcs: cloud.GCS{
+				Logger: mockLogger{
+					mockDebug: func(args ...interface{}) {},
+				},
+				Bucket: mockGCSBucket{
+					mockObject: func(name string) *storage.ObjectHandle {
+						return &storage.ObjectHandle{}
+					},
+				},
+				BucketName: "backup",
+				ObjectHandler: mockGCSObjectHandler{
+					mockRead: func(ctx gcscontext.Context, obj *storage.ObjectHandle, w io.Writer) error {
+						return errors.New("error reading object")
+					},
+				},
+			},
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeLoadingState,
+				Err:  errors.New("error reading object"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			filename, err := scenario.gcs.LoadState(context.Background())
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			var content string
+			if filename != "" {
+				defer os.Remove(filename)
+
+				data, err := ioutil.ReadFile(filename)
+				if err != nil {
+					t.Fatalf("error reading state file. details: %s", err)
+				}
+				content = string(data)
+			}
+
+			if content != scenario.expected {
+				t.Errorf("states don't match. expected “%s” and got “%s”", scenario.expected, content)
+			}
+		})
+	}
+}
+
 func TestGCS_Close(t *testing.T) {
 	scenarios := []struct {
 		description   string
@@ -976,7 +1378,7 @@ func (m mockGCSBucket) Attrs(ctx gcscontext.Context) (*storage.BucketAttrs, erro
 
 type mockGCSObjectHandler struct {
 	mockRead    func(ctx gcscontext.Context, obj *storage.ObjectHandle, w io.Writer) error
-	mockWrite   func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader) error
+	mockWrite   func(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader, metadata map[string]string) error
 	mockAttrs   func(ctx gcscontext.Context, obj *storage.ObjectHandle) (*storage.ObjectAttrs, error)
 	mockDelete  func(ctx gcscontext.Context, obj *storage.ObjectHandle) error
 	mockIterate func(it *storage.ObjectIterator) (*storage.ObjectAttrs, error)
@@ -986,8 +1388,8 @@ func (m mockGCSObjectHandler) Read(ctx gcscontext.Context, obj *storage.ObjectHa
 	return m.mockRead(ctx, obj, w)
 }
 
-func (m mockGCSObjectHandler) Write(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader) error {
-	return m.mockWrite(ctx, obj, r)
+func (m mockGCSObjectHandler) Write(ctx gcscontext.Context, obj *storage.ObjectHandle, r io.Reader, metadata map[string]string) error {
+	return m.mockWrite(ctx, obj, r, metadata)
 }
 
 func (m mockGCSObjectHandler) Attrs(ctx gcscontext.Context, obj *storage.ObjectHandle) (*storage.ObjectAttrs, error) {
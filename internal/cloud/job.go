@@ -0,0 +1,63 @@
+package cloud
+
+import (
+	"time"
+)
+
+const (
+	// JobStatusInProgress the job is still running in the cloud.
+	JobStatusInProgress JobStatus = "InProgress"
+
+	// JobStatusSucceeded the job completed and its result can be retrieved.
+	JobStatusSucceeded JobStatus = "Succeeded"
+
+	// JobStatusFailed the job completed without a result.
+	JobStatusFailed JobStatus = "Failed"
+)
+
+// JobStatus defines the current state of a job in the cloud.
+type JobStatus string
+
+// Job is an asynchronous task in the cloud, like the retrieval of an archive
+// or of the inventory, that can take hours to complete.
+type Job struct {
+	// ID identifies the job in the cloud.
+	ID string
+
+	// Action is the task performed by the job, as informed by the cloud (e.g.
+	// ArchiveRetrieval or InventoryRetrieval).
+	Action string
+
+	// ArchiveID is the archive being retrieved. It is empty for jobs that don't
+	// retrieve an archive.
+	ArchiveID string
+
+	// Tier defines how fast the job is processed, as informed by the cloud (e.g.
+	// Expedited, Standard or Bulk).
+	Tier string
+
+	// Status is the current state of the job, with an optional message
+	// detailing it.
+	Status        JobStatus
+	StatusMessage string
+
+	// CreatedAt is when the job was requested, and CompletedAt when the job
+	// completed, zero while it is in progress.
+	CreatedAt   time.Time
+	CompletedAt time.Time
+}
+
+// Completed informs if the job isn't running anymore.
+func (j Job) Completed() bool {
+	return j.Status != JobStatusInProgress
+}
+
+// Age returns for how long the job is running, or how long it took when
+// completed.
+func (j Job) Age(now time.Time) time.Duration {
+	if j.Completed() && !j.CompletedAt.IsZero() {
+		return j.CompletedAt.Sub(j.CreatedAt)
+	}
+
+	return now.Sub(j.CreatedAt)
+}
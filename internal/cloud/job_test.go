@@ -0,0 +1,52 @@
+package cloud_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+func TestJob_Age(t *testing.T) {
+	now := time.Date(2017, 9, 14, 14, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description string
+		job         cloud.Job
+		expected    time.Duration
+	}{
+		{
+			description: "it should calculate the age of a job in progress",
+			job: cloud.Job{
+				Status:    cloud.JobStatusInProgress,
+				CreatedAt: now.Add(-3 * time.Hour),
+			},
+			expected: 3 * time.Hour,
+		},
+		{
+			description: "it should calculate the duration of a completed job",
+			job: cloud.Job{
+				Status:      cloud.JobStatusSucceeded,
+				CreatedAt:   now.Add(-5 * time.Hour),
+				CompletedAt: now.Add(-time.Hour),
+			},
+			expected: 4 * time.Hour,
+		},
+		{
+			description: "it should calculate the age of a completed job without completion date",
+			job: cloud.Job{
+				Status:    cloud.JobStatusFailed,
+				CreatedAt: now.Add(-2 * time.Hour),
+			},
+			expected: 2 * time.Hour,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if age := scenario.job.Age(now); age != scenario.expected {
+				t.Errorf("ages don't match. expected “%s” and got “%s”", scenario.expected, age)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package cloud
+
+import (
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// throughputWindow is the period of the most recent parts used to calculate
+// the upload throughput, so the estimation follows the network changes.
+const throughputWindow = time.Minute
+
+// UploadStatus is the progress of an upload at a given moment.
+type UploadStatus struct {
+	// Sent is the number of bytes already sent.
+	Sent int64
+
+	// Total is the size of the archive in bytes.
+	Total int64
+
+	// Throughput is the current speed in bytes per second, calculated with the
+	// parts sent in the last minute.
+	Throughput float64
+
+	// ETA is the estimated time to send the remaining bytes with the current
+	// throughput. Zero when the throughput is unknown or the upload finished.
+	ETA time.Duration
+}
+
+// Percentage returns the percentage (0 - 100) of the archive already sent.
+func (u UploadStatus) Percentage() float64 {
+	if u.Total <= 0 {
+		return 0
+	}
+	return float64(u.Sent) * 100 / float64(u.Total)
+}
+
+// UploadProgress receives the progress of the uploads, useful to check if a
+// long upload is on track.
+type UploadProgress interface {
+	// OnUploadProgress is called after each part of the archive is sent.
+	OnUploadProgress(status UploadStatus)
+
+	// OnUploadFinished is called when the upload ends, successfully or not, so
+	// the progress of an interrupted upload isn't kept.
+	OnUploadFinished()
+}
+
+// uploadSample is the number of bytes sent at a given moment.
+type uploadSample struct {
+	at   time.Time
+	sent int64
+}
+
+// uploadProgress tracks the throughput of a multipart upload, notifying the
+// progress receiver of each part and logging the progress at most once per
+// interval, so long uploads are observable without the debug messages.
+type uploadProgress struct {
+	logger   log.Logger
+	clock    Clock
+	receiver UploadProgress
+	interval time.Duration
+	size     int64
+	sent     int64
+	samples  []uploadSample
+	lastLog  time.Time
+}
+
+func newUploadProgress(logger log.Logger, clock Clock, receiver UploadProgress, interval time.Duration, size int64) *uploadProgress {
+	now := clock.Now()
+
+	return &uploadProgress{
+		logger:   logger,
+		clock:    clock,
+		receiver: receiver,
+		interval: interval,
+		size:     size,
+		samples:  []uploadSample{{at: now}},
+		lastLog:  now,
+	}
+}
+
+// partSent records the part that was sent. The offset and size of the part,
+// the cumulative percentage, the throughput and the ETA are logged when the
+// interval has elapsed.
+func (u *uploadProgress) partSent(offset, size int64) {
+	u.sent += size
+
+	now := u.clock.Now()
+	u.samples = append(u.samples, uploadSample{at: now, sent: u.sent})
+
+	// the oldest sample in the window is the reference, keeping at least one
+	// sample before the current one
+	for len(u.samples) > 2 && now.Sub(u.samples[1].at) >= throughputWindow {
+		u.samples = u.samples[1:]
+	}
+
+	status := UploadStatus{
+		Sent:  u.sent,
+		Total: u.size,
+	}
+
+	if elapsed := now.Sub(u.samples[0].at); elapsed > 0 {
+		status.Throughput = float64(u.sent-u.samples[0].sent) / elapsed.Seconds()
+	}
+
+	if status.Throughput > 0 {
+		status.ETA = time.Duration(float64(u.size-u.sent) / status.Throughput * float64(time.Second))
+	}
+
+	if u.receiver != nil {
+		u.receiver.OnUploadProgress(status)
+	}
+
+	if now.Sub(u.lastLog) < u.interval {
+		return
+	}
+	u.lastLog = now
+
+	u.logger.Infof("cloud: upload progress offset=%d size=%d sent=%d total=%d progress=%.1f%% throughput=%dB/s eta=%s",
+		offset, size, u.sent, u.size, status.Percentage(), int64(status.Throughput), status.ETA.Round(time.Second))
+}
+
+// finish notifies the progress receiver that the upload ended.
+func (u *uploadProgress) finish() {
+	if u.receiver != nil {
+		u.receiver.OnUploadFinished()
+	}
+}
@@ -0,0 +1,76 @@
+package cloud
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// RateLimiter spreads the requests to the cloud, so concurrent operations
+// (part uploads, job polls and routes backed up at the same time) don't
+// exceed the requests per second accepted by the service before it starts
+// throttling. The same limiter can be shared by many clouds.
+type RateLimiter struct {
+	interval time.Duration
+
+	next     time.Time
+	nextLock sync.Mutex
+}
+
+// NewRateLimiter creates a limiter that allows the given number of requests
+// per second. When the value isn't positive there's no limit and nil is
+// returned, that is a valid limiter.
+func NewRateLimiter(requestsPerSecond float64) *RateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+
+	return &RateLimiter{
+		interval: time.Duration(float64(time.Second) / requestsPerSecond),
+	}
+}
+
+// Wait blocks until the next request can be sent or the context is cancelled.
+// The requests are released in the order they arrive.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+
+	r.nextLock.Lock()
+	now := time.Now()
+	slot := r.next
+	if slot.Before(now) {
+		slot = now
+	}
+	r.next = slot.Add(r.interval)
+	r.nextLock.Unlock()
+
+	if delay := slot.Sub(now); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// handler waits for the limiter before each request is signed (including
+// retries), so the signature isn't old when the request is sent.
+func (r *RateLimiter) handler() request.NamedHandler {
+	return request.NamedHandler{
+		Name: "toglacier.RateLimiter",
+		Fn: func(req *request.Request) {
+			if err := r.Wait(req.Context()); err != nil {
+				req.Error = err
+			}
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package cloud_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	scenarios := []struct {
+		description         string
+		requestsPerSecond   float64
+		requests            int
+		timeout             time.Duration
+		expectedMinDuration time.Duration
+		expectedMaxDuration time.Duration
+		expectedError       error
+	}{
+		{
+			description:         "it should spread the requests",
+			requestsPerSecond:   100,
+			requests:            6,
+			expectedMinDuration: 50 * time.Millisecond,
+			expectedMaxDuration: time.Second,
+		},
+		{
+			description:         "it should not limit the requests when there's no limit",
+			requests:            100,
+			expectedMaxDuration: 50 * time.Millisecond,
+		},
+		{
+			description:         "it should stop waiting when the context is cancelled",
+			requestsPerSecond:   0.1,
+			requests:            2,
+			timeout:             10 * time.Millisecond,
+			expectedMaxDuration: time.Second,
+			expectedError:       context.DeadlineExceeded,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			rateLimiter := cloud.NewRateLimiter(scenario.requestsPerSecond)
+			ctx, cancel := context.WithCancel(context.Background())
+			if scenario.timeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, scenario.timeout)
+			}
+			defer cancel()
+
+			var err error
+			start := time.Now()
+			for i := 0; i < scenario.requests && err == nil; i++ {
+				err = rateLimiter.Wait(ctx)
+			}
+			duration := time.Since(start)
+
+			if err != scenario.expectedError {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if duration < scenario.expectedMinDuration || duration > scenario.expectedMaxDuration {
+				t.Errorf("unexpected duration “%s”, expected between “%s” and “%s”", duration, scenario.expectedMinDuration, scenario.expectedMaxDuration)
+			}
+		})
+	}
+}
@@ -0,0 +1,538 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// DefaultRcloneBinary is the rclone program used when the configuration
+// doesn't define one. It is searched in the PATH.
+const DefaultRcloneBinary = "rclone"
+
+// rcloneInfoExtension is the extension of the object that stores the
+// information of a backup, as most providers don't support object metadata.
+const rcloneInfoExtension = ".info"
+
+// rcloneStateObject is the name of the object storing the state of the local
+// storage. When the machine identifier is defined it is used as a suffix, so
+// many machines can share the same remote.
+const rcloneStateObject = "toglacier-state"
+
+// RcloneConfig stores all necessary parameters to use a rclone remote.
+type RcloneConfig struct {
+	// Remote is the rclone remote and path where the backups are stored (e.g.
+	// b2:bucket/toglacier). The remote must be configured with "rclone config".
+	Remote string
+
+	// Binary is the rclone program. When empty DefaultRcloneBinary is used.
+	Binary string
+
+	// ConfigFile is the rclone configuration file. When empty the rclone
+	// default location is used.
+	ConfigFile string
+
+	MachineID string
+}
+
+// RcloneCommand runs the rclone program, reading the standard input of the
+// command from stdin (when not nil) and writing the standard output in stdout.
+// This is necessary to make it easy to test the components locally.
+type RcloneCommand interface {
+	Run(ctx context.Context, stdin io.Reader, stdout io.Writer, args ...string) error
+}
+
+type rcloneCommand struct {
+	binary     string
+	configFile string
+}
+
+func (r rcloneCommand) Run(ctx context.Context, stdin io.Reader, stdout io.Writer, args ...string) error {
+	if r.configFile != "" {
+		args = append([]string{"--config", r.configFile}, args...)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.binary, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if details := strings.TrimSpace(stderr.String()); details != "" {
+			return fmt.Errorf("%s (%s)", err, details)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// rcloneInfo is the information of a backup stored together with it, as most
+// providers don't support object metadata.
+type rcloneInfo struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Checksum  string    `json:"checksum"`
+	Size      int64     `json:"size"`
+	MachineID string    `json:"machineId,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	Kind      string    `json:"kind,omitempty"`
+	Of        string    `json:"of,omitempty"`
+}
+
+// Rclone stores the backups in any provider supported by rclone
+// (https://rclone.org), using a remote configured by name. The backups are
+// copied as files, so there's no need to wait for retrieval jobs.
+type Rclone struct {
+	Logger    log.Logger
+	Remote    string
+	MachineID string
+	Command   RcloneCommand
+}
+
+// NewRclone initializes the access to the rclone remote. On error it will
+// return an Error type. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func NewRclone(logger log.Logger, config RcloneConfig) (*Rclone, error) {
+	if !strings.Contains(config.Remote, ":") {
+		return nil, errors.WithStack(newError("", ErrorCodeInitializingSession,
+			fmt.Errorf("invalid remote “%s”", config.Remote)))
+	}
+
+	binary := config.Binary
+	if binary == "" {
+		binary = DefaultRcloneBinary
+	}
+
+	binary, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, errors.WithStack(newError("", ErrorCodeInitializingSession, err))
+	}
+
+	return &Rclone{
+		Logger:    logger,
+		Remote:    config.Remote,
+		MachineID: config.MachineID,
+		Command: rcloneCommand{
+			binary:     binary,
+			configFile: config.ConfigFile,
+		},
+	}, nil
+}
+
+// Send uploads the file to the cloud and return the backup archive information.
+// If an error occurs it will be an Error type encapsulated in a traceable
+// error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (r *Rclone) Send(ctx context.Context, filename, comment string) (Backup, error) {
+	return r.send(ctx, filename, comment, companion{})
+}
+
+// SendParity uploads the parity file of the backup identified by backupID. The
+// backup is stored in the archive information, so the parity archive isn't
+// listed as a backup. If an error occurs it will be an Error type encapsulated
+// in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (r *Rclone) SendParity(ctx context.Context, filename, backupID string) (Backup, error) {
+	return r.send(ctx, filename, "", companion{kind: companionParity, backupID: backupID})
+}
+
+// SendCatalog uploads the catalog file of the backup identified by backupID.
+// The backup is stored in the archive information, so the catalog archive
+// isn't listed as a backup. If an error occurs it will be an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (r *Rclone) SendCatalog(ctx context.Context, filename, backupID string) (Backup, error) {
+	return r.send(ctx, filename, "", companion{kind: companionCatalog, backupID: backupID})
+}
+
+func (r *Rclone) send(ctx context.Context, filename, comment string, of companion) (Backup, error) {
+	r.Logger.Debugf("cloud: sending file “%s” to the rclone remote", filename)
+
+	f, err := OpenArchive(ctx, filename)
+	if err != nil {
+		return Backup{}, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return Backup{}, errors.WithStack(newError("", ErrorCodeArchiveInfo, err))
+	}
+
+	// id will be defined as the filename hash with the current epoch, this is
+	// important to avoid duplicated ids
+	filenameHash := sha256.Sum256([]byte(filename))
+	id := fmt.Sprintf("%s%d", nonLetterDigit.ReplaceAllString(base64.StdEncoding.EncodeToString(filenameHash[:]), ""), time.Now().UnixNano())
+
+	info := rcloneInfo{
+		ID:        id,
+		CreatedAt: time.Now().UTC(),
+		Checksum:  base64.StdEncoding.EncodeToString(hash.Sum(nil)),
+		Size:      size,
+		MachineID: r.MachineID,
+		Comment:   comment,
+		Kind:      string(of.kind),
+		Of:        of.backupID,
+	}
+
+	// the header isn't in the file, so the archive is streamed to the remote
+	if f.HasHeader() {
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			return Backup{}, errors.WithStack(newError("", ErrorCodeOpeningArchive, err))
+		}
+		err = r.Command.Run(ctx, f, ioutil.Discard, "rcat", r.remotePath(id))
+	} else {
+		err = r.Command.Run(ctx, nil, ioutil.Discard, "copyto", filename, r.remotePath(id))
+	}
+
+	if err != nil {
+		return Backup{}, errors.WithStack(r.checkCancellation(newError("", ErrorCodeSendingArchive, err)))
+	}
+
+	// the information is sent after the archive, so an interrupted upload isn't
+	// listed as a backup
+	if err = r.sendInfo(ctx, info); err != nil {
+		return Backup{}, errors.WithStack(err)
+	}
+
+	return r.backup(info), nil
+}
+
+// sendInfo uploads the information of the archive as a JSON object next to
+// it.
+func (r *Rclone) sendInfo(ctx context.Context, info rcloneInfo) error {
+	infoFile, err := ioutil.TempFile("", "toglacier-rclone-")
+	if err != nil {
+		return errors.WithStack(newError(info.ID, ErrorCodeSendingArchive, err))
+	}
+	defer os.Remove(infoFile.Name())
+	defer infoFile.Close()
+
+	if err = json.NewEncoder(infoFile).Encode(info); err != nil {
+		return errors.WithStack(newError(info.ID, ErrorCodeSendingArchive, err))
+	}
+
+	if err = infoFile.Close(); err != nil {
+		return errors.WithStack(newError(info.ID, ErrorCodeSendingArchive, err))
+	}
+
+	if err = r.Command.Run(ctx, nil, ioutil.Discard, "copyto", infoFile.Name(), r.remotePath(info.ID+rcloneInfoExtension)); err != nil {
+		return errors.WithStack(r.checkCancellation(newError(info.ID, ErrorCodeSendingArchive, err)))
+	}
+
+	return nil
+}
+
+// List retrieves all the uploaded backups information in the cloud. The
+// information of all backups is retrieved at once. If an error occurs it will
+// be an Error type encapsulated in a traceable error. To retrieve the desired
+// error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (r *Rclone) List(ctx context.Context) ([]Backup, error) {
+	r.Logger.Debug("cloud: retrieving list of archives from the rclone remote")
+
+	var output bytes.Buffer
+	if err := r.Command.Run(ctx, nil, &output, "cat", "--include", "*"+rcloneInfoExtension, r.Remote); err != nil {
+		return nil, errors.WithStack(r.checkCancellation(newError("", ErrorCodeIterating, err)))
+	}
+
+	var backups []Backup
+	companions := make(map[companion]string)
+
+	decoder := json.NewDecoder(&output)
+	for {
+		var info rcloneInfo
+		if err := decoder.Decode(&info); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.WithStack(newError("", ErrorCodeDecodingData, err))
+		}
+
+		// companion archives (parity and catalog) aren't backups, they are linked
+		// to the backup that they belong to
+		if info.Of != "" {
+			companions[companion{kind: companionKind(info.Kind), backupID: info.Of}] = info.ID
+			continue
+		}
+
+		backups = append(backups, r.backup(info))
+	}
+
+	r.Logger.Info("cloud: remote backups listed successfully from the rclone remote")
+	return linkCompanions(backups, companions), nil
+}
+
+// Get retrieves a specific backup file and stores it locally in a file. The
+// filename storing the location of the file is returned. If an error occurs it
+// will be an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (r *Rclone) Get(ctx context.Context, ids ...string) (map[string]string, error) {
+	r.Logger.Debugf("cloud: retrieving archives “%v” from the rclone remote", ids)
+
+	filenames := make(map[string]string)
+	for _, id := range ids {
+		filename := path.Join(os.TempDir(), "backup-"+id+".tar")
+		if err := r.Command.Run(ctx, nil, ioutil.Discard, "copyto", r.remotePath(id), filename); err != nil {
+			return nil, errors.WithStack(r.checkCancellation(newError(id, ErrorCodeDownloadingArchive, err)))
+		}
+
+		r.Logger.Infof("cloud: backup “%s” retrieved successfully from the rclone remote and saved in temporary file “%s”", id, filename)
+		filenames[id] = filename
+	}
+
+	return filenames, nil
+}
+
+// Remove erase a specific backup from the cloud. If an error occurs it will be
+// an Error type encapsulated in a traceable error. To retrieve the desired
+// error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (r *Rclone) Remove(ctx context.Context, id string) error {
+	r.Logger.Debugf("cloud: removing archive %s from the rclone remote", id)
+
+	// the information is removed first, so a partial removal isn't listed as a
+	// backup anymore
+	for _, name := range []string{id + rcloneInfoExtension, id} {
+		if err := r.Command.Run(ctx, nil, ioutil.Discard, "deletefile", r.remotePath(name)); err != nil {
+			return errors.WithStack(r.checkCancellation(newError(id, ErrorCodeRemovingArchive, err)))
+		}
+	}
+
+	r.Logger.Infof("cloud: backup “%s” removed successfully from the rclone remote", id)
+	return nil
+}
+
+// SaveState uploads the file with the state of the local storage, replacing
+// the previous one. If an error occurs it will be an Error type encapsulated in
+// a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (r *Rclone) SaveState(ctx context.Context, filename string) error {
+	r.Logger.Debugf("cloud: sending state “%s” to the rclone remote", filename)
+
+	if err := r.Command.Run(ctx, nil, ioutil.Discard, "copyto", filename, r.remotePath(r.stateObject())); err != nil {
+		return errors.WithStack(r.checkCancellation(newError("", ErrorCodeSavingState, err)))
+	}
+
+	r.Logger.Info("cloud: state sent successfully to the rclone remote")
+	return nil
+}
+
+// LoadState retrieves the state of the local storage and stores it locally in a
+// file. When there's no state in the cloud yet an empty filename is returned.
+// If an error occurs it will be an Error type encapsulated in a traceable
+// error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (r *Rclone) LoadState(ctx context.Context) (string, error) {
+	r.Logger.Debug("cloud: retrieving state from the rclone remote")
+
+	// rclone doesn't have a specific exit code for a missing file in all
+	// providers, so the state is searched before
+	var output bytes.Buffer
+	if err := r.Command.Run(ctx, nil, &output, "lsf", "--files-only", "--include", "/"+r.stateObject(), r.Remote); err != nil {
+		return "", errors.WithStack(r.checkCancellation(newError("", ErrorCodeLoadingState, err)))
+	}
+
+	if strings.TrimSpace(output.String()) == "" {
+		r.Logger.Info("cloud: no state found in the rclone remote")
+		return "", nil
+	}
+
+	state, err := ioutil.TempFile("", "toglacier-state-")
+	if err != nil {
+		return "", errors.WithStack(newError("", ErrorCodeCreatingArchive, err))
+	}
+	state.Close()
+
+	if err = r.Command.Run(ctx, nil, ioutil.Discard, "copyto", r.remotePath(r.stateObject()), state.Name()); err != nil {
+		os.Remove(state.Name())
+		return "", errors.WithStack(r.checkCancellation(newError("", ErrorCodeLoadingState, err)))
+	}
+
+	r.Logger.Infof("cloud: state retrieved successfully from the rclone remote and saved in temporary file “%s”", state.Name())
+	return state.Name(), nil
+}
+
+// stateObject returns the name of the object storing the state of the local
+// storage.
+func (r *Rclone) stateObject() string {
+	if r.MachineID == "" {
+		return rcloneStateObject
+	}
+
+	return rcloneStateObject + "-" + nonLetterDigit.ReplaceAllString(r.MachineID, "")
+}
+
+// Close ends the rclone session. There's nothing to close, as each command is
+// independent.
+func (r *Rclone) Close() error {
+	return nil
+}
+
+// remotePath returns the location of the file in the remote.
+func (r *Rclone) remotePath(name string) string {
+	if strings.HasSuffix(r.Remote, ":") || strings.HasSuffix(r.Remote, "/") {
+		return r.Remote + name
+	}
+
+	return r.Remote + "/" + name
+}
+
+// backup builds the backup information from the archive information.
+func (r *Rclone) backup(info rcloneInfo) Backup {
+	return Backup{
+		ID:        info.ID,
+		CreatedAt: info.CreatedAt,
+		Checksum:  info.Checksum,
+		VaultName: r.Remote,
+		Size:      info.Size,
+		Location:  LocationRclone,
+		MachineID: info.MachineID,
+		Comment:   info.Comment,
+	}
+}
+
+func (r *Rclone) checkCancellation(err error) error {
+	v, ok := err.(*Error)
+	if !ok {
+		return err
+	}
+
+	cancellation := errors.Cause(v.Err) == context.Canceled || errors.Cause(v.Err) == context.DeadlineExceeded
+
+	if cancellation {
+		r.Logger.Debug("operation cancelled by user")
+		return newError(v.ID, ErrorCodeCancelled, v.Err)
+	}
+
+	return err
+}
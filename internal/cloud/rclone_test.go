@@ -0,0 +1,515 @@
+package cloud_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+func TestNewRclone(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		config        cloud.RcloneConfig
+		expected      *cloud.Rclone
+		expectedError error
+	}{
+		{
+			description: "it should initialize the remote correctly",
+			config: cloud.RcloneConfig{
+				Remote:    "b2:bucket/toglacier",
+				Binary:    os.Args[0],
+				MachineID: "server1",
+			},
+			expected: &cloud.Rclone{
+				Remote:    "b2:bucket/toglacier",
+				MachineID: "server1",
+			},
+		},
+		{
+			description: "it should detect an invalid remote",
+			config: cloud.RcloneConfig{
+				Remote: "bucket/toglacier",
+			},
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeInitializingSession,
+				Err:  errors.New("invalid remote “bucket/toglacier”"),
+			},
+		},
+		{
+			description: "it should detect when the rclone program doesn't exist",
+			config: cloud.RcloneConfig{
+				Remote: "b2:bucket/toglacier",
+				Binary: "toglacier-idontexist",
+			},
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeInitializingSession,
+				Err:  errors.New(`exec: "toglacier-idontexist": executable file not found in $PATH`),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			rclone, err := cloud.NewRclone(mockLogger{}, scenario.config)
+
+			// the logger and the command can't be compared
+			if rclone != nil {
+				rclone.Logger = nil
+				rclone.Command = nil
+			}
+
+			if !reflect.DeepEqual(scenario.expected, rclone) {
+				t.Errorf("remotes don't match.\n%s", Diff(scenario.expected, rclone))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestRclone_Send(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		filename      string
+		comment       string
+		remote        *fakeRclone
+		expected      cloud.Backup
+		expectedError error
+	}{
+		{
+			description: "it should send a backup correctly",
+			filename:    writeTestFile(t, "Important information for the test backup"),
+			comment:     "before OS upgrade",
+			remote:      newFakeRclone(),
+			expected: cloud.Backup{
+				Checksum:  "y2MyTSw1zfy0Uh4VykUYvQ7Z3CNkqfR951FRs/m0twU=",
+				VaultName: "remote:backups",
+				Size:      41,
+				Location:  cloud.LocationRclone,
+				MachineID: "server1",
+				Comment:   "before OS upgrade",
+			},
+		},
+		{
+			description:   "it should detect when the file doesn't exist",
+			filename:      path.Join(os.TempDir(), "toglacier-idontexist"),
+			remote:        newFakeRclone(),
+			expectedError: &cloud.Error{Code: cloud.ErrorCodeOpeningArchive, Err: errors.New("open " + path.Join(os.TempDir(), "toglacier-idontexist") + ": no such file or directory")},
+		},
+		{
+			description: "it should detect an error while copying the file",
+			filename:    writeTestFile(t, "Important information for the test backup"),
+			remote: func() *fakeRclone {
+				remote := newFakeRclone()
+				remote.failures["copyto"] = errors.New("exit status 1 (Failed to copyto: permission denied)")
+				return remote
+			}(),
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeSendingArchive,
+				Err:  errors.New("exit status 1 (Failed to copyto: permission denied)"),
+			},
+		},
+		{
+			description: "it should detect when the operation is cancelled",
+			filename:    writeTestFile(t, "Important information for the test backup"),
+			remote: func() *fakeRclone {
+				remote := newFakeRclone()
+				remote.failures["copyto"] = context.Canceled
+				return remote
+			}(),
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeCancelled,
+				Err:  context.Canceled,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			defer os.Remove(scenario.filename)
+
+			rclone := scenario.remote.rclone()
+			backup, err := rclone.Send(context.Background(), scenario.filename, scenario.comment)
+
+			// the id and the creation date are generated when sending the backup
+			if backup.ID != "" {
+				scenario.expected.ID = backup.ID
+				scenario.expected.CreatedAt = backup.CreatedAt
+			}
+
+			if !reflect.DeepEqual(scenario.expected, backup) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backup))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if backup.ID != "" {
+				if _, ok := scenario.remote.objects[backup.ID]; !ok {
+					t.Errorf("backup “%s” not stored in the remote", backup.ID)
+				}
+				if _, ok := scenario.remote.objects[backup.ID+".info"]; !ok {
+					t.Errorf("information of the backup “%s” not stored in the remote", backup.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestRclone_List(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		remote        *fakeRclone
+		expected      []cloud.Backup
+		expectedError error
+	}{
+		{
+			description: "it should list all backups linking the companion archives",
+			remote: func() *fakeRclone {
+				remote := newFakeRclone()
+				remote.objects["backup1"] = []byte("backup 1")
+				remote.objects["backup1.info"] = []byte(`{"id":"backup1","createdAt":"2017-09-13T13:27:53Z","checksum":"abc","size":8,"machineId":"server1","comment":"weekly"}` + "\n")
+				remote.objects["backup2"] = []byte("backup 2")
+				remote.objects["backup2.info"] = []byte(`{"id":"backup2","createdAt":"2017-09-14T13:27:53Z","checksum":"def","size":8}` + "\n")
+				remote.objects["parity1"] = []byte("parity 1")
+				remote.objects["parity1.info"] = []byte(`{"id":"parity1","createdAt":"2017-09-13T13:27:54Z","checksum":"ghi","size":8,"kind":"parity","of":"backup1"}` + "\n")
+				remote.objects["toglacier-state-server1"] = []byte("local storage state")
+				return remote
+			}(),
+			expected: []cloud.Backup{
+				{
+					ID:        "backup1",
+					CreatedAt: time.Date(2017, 9, 13, 13, 27, 53, 0, time.UTC),
+					Checksum:  "abc",
+					VaultName: "remote:backups",
+					Size:      8,
+					Location:  cloud.LocationRclone,
+					MachineID: "server1",
+					Comment:   "weekly",
+					ParityID:  "parity1",
+				},
+				{
+					ID:        "backup2",
+					CreatedAt: time.Date(2017, 9, 14, 13, 27, 53, 0, time.UTC),
+					Checksum:  "def",
+					VaultName: "remote:backups",
+					Size:      8,
+					Location:  cloud.LocationRclone,
+				},
+			},
+		},
+		{
+			description: "it should detect an invalid archive information",
+			remote: func() *fakeRclone {
+				remote := newFakeRclone()
+				remote.objects["backup1.info"] = []byte("{{{")
+				return remote
+			}(),
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeDecodingData,
+				Err:  errors.New("invalid character '{' looking for beginning of object key string"),
+			},
+		},
+		{
+			description: "it should detect an error while retrieving the archives information",
+			remote: func() *fakeRclone {
+				remote := newFakeRclone()
+				remote.failures["cat"] = errors.New("exit status 3 (directory not found)")
+				return remote
+			}(),
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeIterating,
+				Err:  errors.New("exit status 3 (directory not found)"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			rclone := scenario.remote.rclone()
+			backups, err := rclone.List(context.Background())
+
+			if !reflect.DeepEqual(scenario.expected, backups) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backups))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestRclone_Get(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		ids           []string
+		remote        *fakeRclone
+		expected      map[string]string
+		expectedError error
+	}{
+		{
+			description: "it should retrieve the backups correctly",
+			ids:         []string{"backup1", "backup2"},
+			remote: func() *fakeRclone {
+				remote := newFakeRclone()
+				remote.objects["backup1"] = []byte("backup 1")
+				remote.objects["backup2"] = []byte("backup 2")
+				return remote
+			}(),
+			expected: map[string]string{
+				"backup1": "backup 1",
+				"backup2": "backup 2",
+			},
+		},
+		{
+			description: "it should detect when the backup doesn't exist",
+			ids:         []string{"backup1"},
+			remote:      newFakeRclone(),
+			expectedError: &cloud.Error{
+				ID:   "backup1",
+				Code: cloud.ErrorCodeDownloadingArchive,
+				Err:  errors.New("exit status 3 (directory not found)"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			rclone := scenario.remote.rclone()
+			filenames, err := rclone.Get(context.Background(), scenario.ids...)
+
+			var contents map[string]string
+			for id, filename := range filenames {
+				content, err := ioutil.ReadFile(filename)
+				if err != nil {
+					t.Fatalf("error reading backup “%s”. details: %s", id, err)
+				}
+				os.Remove(filename)
+
+				if contents == nil {
+					contents = make(map[string]string)
+				}
+				contents[id] = string(content)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, contents) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, contents))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestRclone_Remove(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		id            string
+		remote        *fakeRclone
+		expected      []string
+		expectedError error
+	}{
+		{
+			description: "it should remove the backup and its information",
+			id:          "backup1",
+			remote: func() *fakeRclone {
+				remote := newFakeRclone()
+				remote.objects["backup1"] = []byte("backup 1")
+				remote.objects["backup1.info"] = []byte(`{"id":"backup1"}`)
+				remote.objects["backup2"] = []byte("backup 2")
+				return remote
+			}(),
+			expected: []string{"backup2"},
+		},
+		{
+			description: "it should detect an error while removing the backup",
+			id:          "backup1",
+			remote: func() *fakeRclone {
+				remote := newFakeRclone()
+				remote.objects["backup1"] = []byte("backup 1")
+				remote.failures["deletefile"] = errors.New("exit status 1 (permission denied)")
+				return remote
+			}(),
+			expected: []string{"backup1"},
+			expectedError: &cloud.Error{
+				ID:   "backup1",
+				Code: cloud.ErrorCodeRemovingArchive,
+				Err:  errors.New("exit status 1 (permission denied)"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			rclone := scenario.remote.rclone()
+			err := rclone.Remove(context.Background(), scenario.id)
+
+			var objects []string
+			for name := range scenario.remote.objects {
+				objects = append(objects, name)
+			}
+			sort.Strings(objects)
+
+			if !reflect.DeepEqual(scenario.expected, objects) {
+				t.Errorf("objects don't match.\n%s", Diff(scenario.expected, objects))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestRclone_State(t *testing.T) {
+	remote := newFakeRclone()
+	rclone := remote.rclone()
+
+	filename, err := rclone.LoadState(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error loading the missing state. details: %s", err)
+	}
+
+	if filename != "" {
+		t.Errorf("unexpected state file “%s” without state", filename)
+	}
+
+	state := writeTestFile(t, "local storage state")
+	defer os.Remove(state)
+
+	if err = rclone.SaveState(context.Background(), state); err != nil {
+		t.Fatalf("unexpected error saving the state. details: %s", err)
+	}
+
+	if _, ok := remote.objects["toglacier-state-server1"]; !ok {
+		t.Error("state not stored in the remote")
+	}
+
+	if filename, err = rclone.LoadState(context.Background()); err != nil {
+		t.Fatalf("unexpected error loading the state. details: %s", err)
+	}
+	defer os.Remove(filename)
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("error reading the state. details: %s", err)
+	}
+
+	if string(content) != "local storage state" {
+		t.Errorf("unexpected state “%s”", content)
+	}
+
+	remote.failures["lsf"] = errors.New("exit status 1 (permission denied)")
+	expectedError := &cloud.Error{
+		Code: cloud.ErrorCodeLoadingState,
+		Err:  errors.New("exit status 1 (permission denied)"),
+	}
+
+	if _, err = rclone.LoadState(context.Background()); !cloud.ErrorEqual(expectedError, err) {
+		t.Errorf("errors don't match. expected: “%v” and got “%v”", expectedError, err)
+	}
+}
+
+// fakeRclone simulates the rclone program with an in-memory remote called
+// "remote:backups".
+type fakeRclone struct {
+	objects map[string][]byte
+
+	// failures is the error returned for each rclone command, when defined.
+	failures map[string]error
+}
+
+func newFakeRclone() *fakeRclone {
+	return &fakeRclone{
+		objects:  make(map[string][]byte),
+		failures: make(map[string]error),
+	}
+}
+
+func (f *fakeRclone) rclone() *cloud.Rclone {
+	return &cloud.Rclone{
+		Logger: mockLogger{
+			mockDebug:  func(args ...interface{}) {},
+			mockDebugf: func(format string, args ...interface{}) {},
+			mockInfo:   func(args ...interface{}) {},
+			mockInfof:  func(format string, args ...interface{}) {},
+		},
+		Remote:    "remote:backups",
+		MachineID: "server1",
+		Command:   f,
+	}
+}
+
+func (f *fakeRclone) Run(ctx context.Context, stdin io.Reader, stdout io.Writer, args ...string) error {
+	if err := f.failures[args[0]]; err != nil {
+		return err
+	}
+
+	const remote = "remote:backups/"
+
+	switch args[0] {
+	case "copyto":
+		if name := strings.TrimPrefix(args[1], remote); name != args[1] {
+			content, ok := f.objects[name]
+			if !ok {
+				return errors.New("exit status 3 (directory not found)")
+			}
+			return ioutil.WriteFile(args[2], content, 0600)
+		}
+
+		content, err := ioutil.ReadFile(args[1])
+		if err != nil {
+			return err
+		}
+		f.objects[strings.TrimPrefix(args[2], remote)] = content
+
+	case "rcat":
+		content, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		f.objects[strings.TrimPrefix(args[1], remote)] = content
+
+	case "cat":
+		var names []string
+		for name := range f.objects {
+			if ok, _ := path.Match(args[2], name); ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			stdout.Write(f.objects[name])
+		}
+
+	case "deletefile":
+		name := strings.TrimPrefix(args[1], remote)
+		if _, ok := f.objects[name]; !ok {
+			return errors.New("exit status 4 (object not found)")
+		}
+		delete(f.objects, name)
+
+	case "lsf":
+		if _, ok := f.objects[strings.TrimPrefix(args[3], "/")]; ok {
+			fmt.Fprintln(stdout, strings.TrimPrefix(args[3], "/"))
+		}
+
+	default:
+		return fmt.Errorf("unknown command “%s”", args[0])
+	}
+
+	return nil
+}
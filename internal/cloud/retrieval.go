@@ -0,0 +1,51 @@
+package cloud
+
+import "context"
+
+// retrievalKey stores in the context the tracker of the retrievals.
+type retrievalKey struct{}
+
+// RetrievalTracker persists the state of the archives retrieved from the cloud
+// as soon as it changes, so an interrupted retrieval continues from the jobs
+// already initiated and the parts already downloaded, instead of waiting hours
+// for new jobs. Only the clouds with retrieval jobs use it.
+type RetrievalTracker interface {
+	// Job returns the job initiated before to retrieve the archive, or an
+	// empty string when there's none.
+	Job(id string) string
+
+	// JobInitiated is called as soon as the job to retrieve the archive is
+	// initiated.
+	JobInitiated(id, jobID string) error
+
+	// Part returns the file where the archive was being downloaded and the
+	// number of bytes already stored in it.
+	Part(id string) (filename string, size int64)
+
+	// PartDownloaded is called after each part of the archive is stored in the
+	// file, with the number of bytes stored so far.
+	PartDownloaded(id, filename string, size int64) error
+}
+
+// WithRetrievalTracker returns a context where the retrievals of archives
+// report their state to the tracker and resume from it.
+func WithRetrievalTracker(ctx context.Context, tracker RetrievalTracker) context.Context {
+	return context.WithValue(ctx, retrievalKey{}, tracker)
+}
+
+// retrievalTracker returns the tracker of the context. When there's none the
+// state of the retrievals isn't persisted.
+func retrievalTracker(ctx context.Context) RetrievalTracker {
+	if tracker, ok := ctx.Value(retrievalKey{}).(RetrievalTracker); ok && tracker != nil {
+		return tracker
+	}
+	return noRetrievalTracker{}
+}
+
+// noRetrievalTracker doesn't persist the state of the retrievals.
+type noRetrievalTracker struct{}
+
+func (noRetrievalTracker) Job(string) string                          { return "" }
+func (noRetrievalTracker) JobInitiated(string, string) error          { return nil }
+func (noRetrievalTracker) Part(string) (string, int64)                { return "", 0 }
+func (noRetrievalTracker) PartDownloaded(string, string, int64) error { return nil }
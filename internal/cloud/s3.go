@@ -0,0 +1,937 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+	"github.com/rafaeljusto/toglacier/internal/proxy"
+)
+
+// DefaultS3Region is the region used to sign the requests when the S3
+// configuration doesn't define one. Most S3-compatible services accept any
+// region.
+const DefaultS3Region = "us-east-1"
+
+// s3MetadataPrefix is the header prefix of the object metadata defined by the
+// user.
+const s3MetadataPrefix = "X-Amz-Meta-"
+
+// s3MetadataMachineID is the object metadata header that stores the machine
+// identifier.
+const s3MetadataMachineID = s3MetadataPrefix + "Machine-Id"
+
+// s3MetadataComment is the object metadata header that stores the note
+// informed by the user when the backup was created. As the metadata must be
+// ASCII, the comment is escaped.
+const s3MetadataComment = s3MetadataPrefix + "Comment"
+
+// s3MetadataCompanionOf is the suffix of the object metadata header that stores
+// the backup that the companion object belongs to. The header is prefixed with
+// the companion type (e.g. Parity-Of).
+const s3MetadataCompanionOf = "-Of"
+
+// s3MetadataState is the object metadata header that identifies the object
+// storing the state of the local storage, so it isn't listed as a backup.
+const s3MetadataState = s3MetadataPrefix + "State"
+
+// s3ResponseTimeout limits the wait for the response of the S3-compatible
+// service after the request is sent. The whole request isn't limited, as big
+// objects can take hours to be transferred.
+const s3ResponseTimeout = time.Minute
+
+// DefaultS3PartSize is the size of each part of the multipart uploads. Smaller
+// files are sent in a single request.
+const DefaultS3PartSize int64 = 64 << 20 // 64MB
+
+// s3MaxParts is the maximum number of parts of a multipart upload, so the part
+// size is increased for huge files.
+const s3MaxParts = 10000
+
+// s3KeySeparator separates the backup identifier from the metadata encoded in
+// the object key.
+const s3KeySeparator = "."
+
+// s3StateObject is the name of the object storing the state of the local
+// storage. When the machine identifier is defined it is used as a suffix, so
+// many machines can share the same bucket.
+const s3StateObject = "toglacier-state"
+
+// S3Config stores all necessary parameters to initialize a session with a
+// S3-compatible service.
+type S3Config struct {
+	// Endpoint is the URL of the service (e.g. https://s3.wasabisys.com).
+	Endpoint string
+
+	// Region used to sign the requests. When empty DefaultS3Region is used.
+	Region string
+
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	MachineID       string
+
+	// PathStyle addresses the bucket in the URL path
+	// (https://endpoint/bucket/object) instead of in the host name
+	// (https://bucket.endpoint/object). Usually needed by self-hosted services
+	// (MinIO, Ceph).
+	PathStyle bool
+
+	// Proxy used to reach the service. When empty the proxy environment
+	// variables (HTTPS_PROXY) are used.
+	Proxy *url.URL
+
+	// Trace writes the requests and responses as debug messages in the logger,
+	// without the credentials and the object contents.
+	Trace bool
+
+	// PartSize is the size of each part of the multipart uploads. The services
+	// require at least 5MB. When zero DefaultS3PartSize is used.
+	PartSize int64
+}
+
+// S3HTTPClient contains all used methods from the HTTP client that sends the
+// requests to the S3-compatible service. This is necessary to make it easy to
+// test the components locally.
+type S3HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// S3 stores the backups in any service compatible with the Amazon S3 API
+// (e.g. MinIO, Wasabi, Ceph). Different from AWS Glacier the objects are
+// available right away, so there's no need to wait for retrieval jobs.
+type S3 struct {
+	Logger     log.Logger
+	Endpoint   *url.URL
+	Region     string
+	Bucket     string
+	MachineID  string
+	PathStyle  bool
+	PartSize   int64
+	HTTPClient S3HTTPClient
+	Signer     *v4.Signer
+}
+
+// NewS3 initializes the session with the S3-compatible service. On error it
+// will return an Error type. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func NewS3(logger log.Logger, config S3Config) (*S3, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, errors.WithStack(newError("", ErrorCodeInitializingSession, err))
+	}
+
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, errors.WithStack(newError("", ErrorCodeInitializingSession,
+			fmt.Errorf("invalid endpoint “%s”", config.Endpoint)))
+	}
+
+	region := config.Region
+	if region == "" {
+		region = DefaultS3Region
+	}
+
+	client := &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+	if config.Proxy != nil {
+		client = proxy.HTTPClient(config.Proxy)
+	}
+	client.Transport.(*http.Transport).ResponseHeaderTimeout = s3ResponseTimeout
+
+	var httpClient S3HTTPClient = client
+
+	if config.Trace {
+		httpClient = traceHTTPClient{
+			client: httpClient,
+			logger: newTraceLogger(logger, config.AccessKeyID, config.SecretAccessKey),
+		}
+	}
+
+	return &S3{
+		Logger:     logger,
+		Endpoint:   endpoint,
+		Region:     region,
+		Bucket:     config.Bucket,
+		MachineID:  config.MachineID,
+		PathStyle:  config.PathStyle,
+		PartSize:   config.PartSize,
+		HTTPClient: httpClient,
+		Signer:     v4.NewSigner(credentials.NewStaticCredentials(config.AccessKeyID, config.SecretAccessKey, "")),
+	}, nil
+}
+
+// Send uploads the file to the cloud and return the backup archive information.
+// Files bigger than the part size are sent in parts. If an error occurs it will
+// be an Error type encapsulated in a traceable error. To retrieve the desired
+// error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s *S3) Send(ctx context.Context, filename, comment string) (Backup, error) {
+	return s.send(ctx, filename, comment, companion{})
+}
+
+// SendParity uploads the parity file of the backup identified by backupID. The
+// backup is stored in the object metadata, so the parity object isn't listed
+// as a backup. If an error occurs it will be an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s *S3) SendParity(ctx context.Context, filename, backupID string) (Backup, error) {
+	return s.send(ctx, filename, "", companion{kind: companionParity, backupID: backupID})
+}
+
+// SendCatalog uploads the catalog file of the backup identified by backupID.
+// The backup is stored in the object metadata, so the catalog object isn't
+// listed as a backup. If an error occurs it will be an Error type encapsulated
+// in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s *S3) SendCatalog(ctx context.Context, filename, backupID string) (Backup, error) {
+	return s.send(ctx, filename, "", companion{kind: companionCatalog, backupID: backupID})
+}
+
+func (s *S3) send(ctx context.Context, filename, comment string, of companion) (Backup, error) {
+	s.Logger.Debugf("cloud: sending file “%s” to the s3 service", filename)
+
+	// id will be defined as the filename hash with the current epoch, this is
+	// important to avoid duplicated ids
+	filenameHash := sha256.Sum256([]byte(filename))
+	id := fmt.Sprintf("%s%d", nonLetterDigit.ReplaceAllString(base64.StdEncoding.EncodeToString(filenameHash[:]), ""), time.Now().UnixNano())
+
+	metadata := make(http.Header)
+	if s.MachineID != "" {
+		metadata.Set(s3MetadataMachineID, s.MachineID)
+	}
+
+	if comment != "" {
+		// metadata values must be ASCII
+		metadata.Set(s3MetadataComment, url.PathEscape(comment))
+	}
+
+	if of.backupID != "" {
+		metadata.Set(s3MetadataPrefix+string(of.kind)+s3MetadataCompanionOf, of.backupID)
+	}
+
+	if len(comment) > maxCommentLength {
+		comment = comment[:maxCommentLength]
+	}
+
+	key := s3Key(id, s3KeyMetadata{
+		MachineID: s.MachineID,
+		Comment:   comment,
+		Kind:      of.kind,
+		BackupID:  of.backupID,
+	})
+
+	if err := s.put(ctx, key, filename, metadata, ErrorCodeSendingArchive); err != nil {
+		return Backup{}, errors.WithStack(err)
+	}
+
+	header, err := s.head(ctx, key)
+	if err != nil {
+		return Backup{}, errors.WithStack(s.checkCancellation(newError("", ErrorCodeRemoteArchiveInfo, err)))
+	}
+
+	return s.backup(id, header), nil
+}
+
+// s3KeyMetadata is the backup information encoded in the object key, as the
+// listing of the S3 API doesn't return the object metadata.
+type s3KeyMetadata struct {
+	MachineID string        `json:"m,omitempty"`
+	Comment   string        `json:"c,omitempty"`
+	Kind      companionKind `json:"k,omitempty"`
+	BackupID  string        `json:"o,omitempty"`
+}
+
+// s3Key builds the object key with the backup identifier followed by the
+// metadata, so the backups can be listed without inspecting each object.
+func s3Key(id string, metadata s3KeyMetadata) string {
+	// only strings are encoded, so it can't fail
+	encoded, _ := json.Marshal(metadata)
+	return id + s3KeySeparator + base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// parseS3Key retrieves the backup identifier and the metadata from the object
+// key. Objects sent by older versions use the identifier as key, so ok is false
+// and the metadata must be retrieved from the object.
+func parseS3Key(key string) (id string, metadata s3KeyMetadata, ok bool) {
+	i := strings.Index(key, s3KeySeparator)
+	if i < 0 {
+		return key, metadata, false
+	}
+
+	encoded, err := base64.RawURLEncoding.DecodeString(key[i+len(s3KeySeparator):])
+	if err != nil {
+		return key, metadata, false
+	}
+
+	if err = json.Unmarshal(encoded, &metadata); err != nil {
+		return key, metadata, false
+	}
+
+	return key[:i], metadata, true
+}
+
+// put uploads the file to the object, storing the metadata headers together.
+// Files bigger than the part size are sent in parts. Upload problems are
+// reported with the given error code.
+func (s *S3) put(ctx context.Context, key, filename string, metadata http.Header, code ErrorCode) error {
+	f, err := OpenArchive(ctx, filename)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+
+	metadata.Set("Content-Type", "application/octet-stream")
+
+	if partSize := s.partSize(f.Size()); f.Size() > partSize {
+		return errors.WithStack(s.putMultipart(ctx, key, f, partSize, metadata, code))
+	}
+
+	response, err := s.do(ctx, http.MethodPut, key, nil, f, f.Size(), metadata)
+	if err != nil {
+		return errors.WithStack(s.checkCancellation(newError("", code, err)))
+	}
+	response.Body.Close()
+
+	return nil
+}
+
+// s3CompleteMultipartUpload is the list of parts sent to finish a multipart
+// upload.
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	} `xml:"Part"`
+}
+
+// putMultipart uploads the file in parts, so a big archive doesn't depend on a
+// single long request. The upload is aborted on error, so the sent parts
+// aren't kept in the bucket.
+func (s *S3) putMultipart(ctx context.Context, key string, f *ArchiveFile, partSize int64, metadata http.Header, code ErrorCode) error {
+	response, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": []string{""}}, nil, 0, metadata)
+	if err != nil {
+		return errors.WithStack(s.checkCancellation(newError("", ErrorCodeInitMultipart, err)))
+	}
+
+	var initiate struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(response.Body).Decode(&initiate)
+	response.Body.Close()
+
+	if err != nil {
+		return errors.WithStack(newError("", ErrorCodeDecodingData, err))
+	}
+
+	part := getBuffer(partSize)
+	defer putBuffer(part)
+
+	var complete s3CompleteMultipartUpload
+	for number, offset := 1, int64(0); offset < f.Size(); number, offset = number+1, offset+partSize {
+		s.Logger.Debugf("cloud: sending part %d/%d", offset, f.Size())
+
+		n, err := io.ReadFull(f, part)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			s.abortMultipart(key, initiate.UploadID)
+			return errors.WithStack(newMultipartError(offset, f.Size(), MultipartErrorCodeReadingArchive, err))
+		}
+
+		query := url.Values{
+			"partNumber": []string{strconv.Itoa(number)},
+			"uploadId":   []string{initiate.UploadID},
+		}
+
+		response, err := s.do(ctx, http.MethodPut, key, query, bytes.NewReader(part[:n]), int64(n), nil)
+		if err != nil {
+			s.abortMultipart(key, initiate.UploadID)
+			return errors.WithStack(s.checkCancellation(newError("", code, err)))
+		}
+		response.Body.Close()
+
+		complete.Parts = append(complete.Parts, struct {
+			PartNumber int    `xml:"PartNumber"`
+			ETag       string `xml:"ETag"`
+		}{PartNumber: number, ETag: response.Header.Get("ETag")})
+	}
+
+	// only strings and numbers are encoded, so it can't fail
+	body, _ := xml.Marshal(complete)
+
+	query := url.Values{"uploadId": []string{initiate.UploadID}}
+	if response, err = s.do(ctx, http.MethodPost, key, query, bytes.NewReader(body), int64(len(body)), nil); err != nil {
+		s.abortMultipart(key, initiate.UploadID)
+		return errors.WithStack(s.checkCancellation(newError("", ErrorCodeCompleteMultipart, err)))
+	}
+	defer response.Body.Close()
+
+	// the service can report an error after sending the success status, while
+	// the parts are combined
+	responseErr := &s3ResponseError{StatusCode: response.StatusCode}
+	if xml.NewDecoder(response.Body).Decode(responseErr); responseErr.Code != "" {
+		s.abortMultipart(key, initiate.UploadID)
+		return errors.WithStack(newError("", ErrorCodeCompleteMultipart, responseErr))
+	}
+
+	return nil
+}
+
+// abortMultipart removes the parts already sent of a failed multipart upload.
+// The upload could have been cancelled, so the context isn't used.
+func (s *S3) abortMultipart(key, uploadID string) {
+	response, err := s.do(context.Background(), http.MethodDelete, key, url.Values{"uploadId": []string{uploadID}}, nil, 0, nil)
+	if err != nil {
+		s.Logger.Debugf("cloud: failed to abort multipart upload %s. details: %s", uploadID, err)
+		return
+	}
+	response.Body.Close()
+}
+
+// partSize returns the size of each part of the multipart upload of a file with
+// the given size, increased when the file would need too many parts.
+func (s *S3) partSize(size int64) int64 {
+	partSize := s.PartSize
+	if partSize <= 0 {
+		partSize = DefaultS3PartSize
+	}
+
+	if minimum := (size + s3MaxParts - 1) / s3MaxParts; partSize < minimum {
+		partSize = minimum
+	}
+
+	return partSize
+}
+
+// List retrieves all the uploaded backups information in the cloud. The S3 API
+// doesn't return the object metadata in the listing, so it is read from the
+// object keys, and only the objects sent by older versions are inspected. If an
+// error occurs it will be an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s *S3) List(ctx context.Context) ([]Backup, error) {
+	s.Logger.Debug("cloud: retrieving list of archives from the s3 service")
+
+	objects, err := s.objects(ctx, "")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var backups []Backup
+	companions := make(map[companion]string)
+
+	for _, object := range objects {
+		// the state object isn't a backup
+		if strings.HasPrefix(object.Key, s3StateObject) {
+			continue
+		}
+
+		if id, metadata, ok := parseS3Key(object.Key); ok {
+			if metadata.BackupID != "" {
+				companions[companion{kind: metadata.Kind, backupID: metadata.BackupID}] = id
+				continue
+			}
+
+			backups = append(backups, Backup{
+				ID:        id,
+				CreatedAt: object.LastModified.UTC(),
+				Checksum:  strings.Trim(object.ETag, `"`),
+				VaultName: s.Bucket,
+				Size:      object.Size,
+				Location:  LocationS3,
+				MachineID: metadata.MachineID,
+				Comment:   metadata.Comment,
+			})
+			continue
+		}
+
+		key := object.Key
+		header, err := s.head(ctx, key)
+		if err != nil {
+			return nil, errors.WithStack(s.checkCancellation(newError(key, ErrorCodeRemoteArchiveInfo, err)))
+		}
+
+		// the state object isn't a backup
+		if header.Get(s3MetadataState) != "" {
+			continue
+		}
+
+		// companion objects (parity and catalog) aren't backups, they are linked
+		// to the backup that they belong to
+		if of := s3Companion(header); of.backupID != "" {
+			companions[of] = key
+			continue
+		}
+
+		backups = append(backups, s.backup(key, header))
+	}
+
+	s.Logger.Info("cloud: remote backups listed successfully from the s3 service")
+	return linkCompanions(backups, companions), nil
+}
+
+// s3Object is an object returned by the ListObjectsV2 action.
+type s3Object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         int64     `xml:"Size"`
+}
+
+// s3ListResult is the page of objects returned by the ListObjectsV2 action.
+type s3ListResult struct {
+	Contents              []s3Object `xml:"Contents"`
+	IsTruncated           bool       `xml:"IsTruncated"`
+	NextContinuationToken string     `xml:"NextContinuationToken"`
+}
+
+// objects retrieves all objects in the bucket starting with the prefix,
+// following the pages of the listing.
+func (s *S3) objects(ctx context.Context, prefix string) ([]s3Object, error) {
+	var objects []s3Object
+	query := url.Values{"list-type": []string{"2"}}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+
+	for {
+		response, err := s.do(ctx, http.MethodGet, "", query, nil, 0, nil)
+		if err != nil {
+			return nil, errors.WithStack(s.checkCancellation(newError("", ErrorCodeIterating, err)))
+		}
+
+		var result s3ListResult
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+
+		if err != nil {
+			return nil, errors.WithStack(newError("", ErrorCodeDecodingData, err))
+		}
+
+		objects = append(objects, result.Contents...)
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// s3Companion retrieves from the object metadata the backup that the companion
+// object belongs to. An empty companion is returned for backup objects.
+func s3Companion(header http.Header) companion {
+	for _, kind := range companionKinds {
+		if backupID := header.Get(s3MetadataPrefix + string(kind) + s3MetadataCompanionOf); backupID != "" {
+			return companion{kind: kind, backupID: backupID}
+		}
+	}
+
+	return companion{}
+}
+
+// Get retrieves a specific backup file and stores it locally in a file. The
+// filename storing the location of the file is returned. If an error occurs it
+// will be an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s *S3) Get(ctx context.Context, ids ...string) (map[string]string, error) {
+	s.Logger.Debugf("cloud: retrieving archives “%v” from the s3 service", ids)
+
+	filenames := make(map[string]string)
+	for _, id := range ids {
+		key, err := s.objectKey(ctx, id)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		filename := path.Join(os.TempDir(), "backup-"+id+".tar")
+		if err := s.get(ctx, id, key, filename, ErrorCodeDownloadingArchive); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		s.Logger.Infof("cloud: backup “%s” retrieved successfully from the s3 service and saved in temporary file “%s”", id, filename)
+		filenames[id] = filename
+	}
+
+	return filenames, nil
+}
+
+// objectKey returns the key of the object storing the backup, that contains the
+// metadata after the identifier. Objects sent by older versions use the
+// identifier as key.
+func (s *S3) objectKey(ctx context.Context, id string) (string, error) {
+	objects, err := s.objects(ctx, id)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	for _, object := range objects {
+		if objectID, _, _ := parseS3Key(object.Key); objectID == id {
+			return object.Key, nil
+		}
+	}
+
+	return id, nil
+}
+
+// get downloads the object to the file. Download problems are reported with
+// the given identifier and error code.
+func (s *S3) get(ctx context.Context, id, key, filename string, code ErrorCode) error {
+	response, err := s.do(ctx, http.MethodGet, key, nil, nil, 0, nil)
+	if err != nil {
+		return errors.WithStack(s.checkCancellation(newError(id, code, err)))
+	}
+	defer response.Body.Close()
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return errors.WithStack(newError(id, ErrorCodeCreatingArchive, err))
+	}
+	defer f.Close()
+
+	if _, err = io.Copy(f, response.Body); err != nil {
+		return errors.WithStack(s.checkCancellation(newError(id, ErrorCodeCopyingData, err)))
+	}
+
+	return nil
+}
+
+// Remove erase a specific backup from the cloud. If an error occurs it will be
+// an Error type encapsulated in a traceable error. To retrieve the desired
+// error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s *S3) Remove(ctx context.Context, id string) error {
+	s.Logger.Debugf("cloud: removing archive %s from the s3 service", id)
+
+	key, err := s.objectKey(ctx, id)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	response, err := s.do(ctx, http.MethodDelete, key, nil, nil, 0, nil)
+	if err != nil {
+		return errors.WithStack(s.checkCancellation(newError(id, ErrorCodeRemovingArchive, err)))
+	}
+	response.Body.Close()
+
+	s.Logger.Infof("cloud: backup “%s” removed successfully from the s3 service", id)
+	return nil
+}
+
+// SaveState uploads the file with the state of the local storage, replacing
+// the previous one. If an error occurs it will be an Error type encapsulated in
+// a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s *S3) SaveState(ctx context.Context, filename string) error {
+	s.Logger.Debugf("cloud: sending state “%s” to the s3 service", filename)
+
+	metadata := make(http.Header)
+	metadata.Set(s3MetadataState, "true")
+	if s.MachineID != "" {
+		metadata.Set(s3MetadataMachineID, s.MachineID)
+	}
+
+	if err := s.put(ctx, s.stateObject(), filename, metadata, ErrorCodeSavingState); err != nil {
+		return errors.WithStack(err)
+	}
+
+	s.Logger.Info("cloud: state sent successfully to the s3 service")
+	return nil
+}
+
+// LoadState retrieves the state of the local storage and stores it locally in a
+// file. When there's no state in the cloud yet an empty filename is returned.
+// If an error occurs it will be an Error type encapsulated in a traceable
+// error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s *S3) LoadState(ctx context.Context) (string, error) {
+	s.Logger.Debug("cloud: retrieving state from the s3 service")
+
+	state, err := ioutil.TempFile("", "toglacier-state-")
+	if err != nil {
+		return "", errors.WithStack(newError("", ErrorCodeCreatingArchive, err))
+	}
+	state.Close()
+
+	if err = s.get(ctx, s.stateObject(), s.stateObject(), state.Name(), ErrorCodeLoadingState); err != nil {
+		os.Remove(state.Name())
+
+		if causeErr, ok := errors.Cause(err).(*Error); ok {
+			if responseErr, ok := errors.Cause(causeErr.Err).(*s3ResponseError); ok && responseErr.StatusCode == http.StatusNotFound {
+				s.Logger.Info("cloud: no state found in the s3 service")
+				return "", nil
+			}
+		}
+
+		return "", errors.WithStack(err)
+	}
+
+	s.Logger.Infof("cloud: state retrieved successfully from the s3 service and saved in temporary file “%s”", state.Name())
+	return state.Name(), nil
+}
+
+// stateObject returns the name of the object storing the state of the local
+// storage.
+func (s *S3) stateObject() string {
+	if s.MachineID == "" {
+		return s3StateObject
+	}
+
+	return s3StateObject + "-" + nonLetterDigit.ReplaceAllString(s.MachineID, "")
+}
+
+// Close ends the S3 session. There's no connection to close, as each request
+// is independent.
+func (s *S3) Close() error {
+	return nil
+}
+
+// head retrieves the headers of the object, where the metadata is stored.
+func (s *S3) head(ctx context.Context, key string) (http.Header, error) {
+	response, err := s.do(ctx, http.MethodHead, key, nil, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	response.Header.Set("Content-Length", fmt.Sprintf("%d", response.ContentLength))
+	return response.Header, nil
+}
+
+// backup builds the backup information from the object headers.
+func (s *S3) backup(id string, header http.Header) Backup {
+	var size int64
+	fmt.Sscan(header.Get("Content-Length"), &size)
+
+	createdAt, _ := http.ParseTime(header.Get("Last-Modified"))
+
+	comment := header.Get(s3MetadataComment)
+	if unescaped, err := url.PathUnescape(comment); err == nil {
+		comment = unescaped
+	}
+
+	return Backup{
+		ID:        id,
+		CreatedAt: createdAt.UTC(),
+		Checksum:  strings.Trim(header.Get("ETag"), `"`),
+		VaultName: s.Bucket,
+		Size:      size,
+		Location:  LocationS3,
+		MachineID: header.Get(s3MetadataMachineID),
+		Comment:   comment,
+	}
+}
+
+// do sends the signed request to the service. Responses with an error status
+// are converted to an s3ResponseError. The caller must close the response
+// body.
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, body io.ReadSeeker, size int64, header http.Header) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, s.objectURL(key, query), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range header {
+		request.Header[name] = values
+	}
+
+	if _, err = s.Signer.Sign(request, body, "s3", s.Region, time.Now()); err != nil {
+		return nil, err
+	}
+	request.ContentLength = size
+
+	response, err := s.HTTPClient.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		return nil, err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		defer response.Body.Close()
+		return nil, newS3ResponseError(response)
+	}
+
+	return response, nil
+}
+
+// objectURL builds the URL of the object, addressing the bucket in the path or
+// in the host name.
+func (s *S3) objectURL(key string, query url.Values) string {
+	objectURL := *s.Endpoint
+	objectURL.RawQuery = query.Encode()
+
+	if s.PathStyle {
+		objectURL.Path = path.Join("/", objectURL.Path, s.Bucket, key)
+	} else {
+		objectURL.Host = s.Bucket + "." + objectURL.Host
+		objectURL.Path = path.Join("/", objectURL.Path, key)
+	}
+
+	return objectURL.String()
+}
+
+// s3ResponseError is the error returned by the service in the response body.
+type s3ResponseError struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+// newS3ResponseError decodes the error from the response. Responses without
+// body (e.g. HEAD) only have the status.
+func newS3ResponseError(response *http.Response) *s3ResponseError {
+	responseErr := &s3ResponseError{StatusCode: response.StatusCode}
+	xml.NewDecoder(response.Body).Decode(responseErr)
+	return responseErr
+}
+
+func (s *s3ResponseError) Error() string {
+	if s.Code == "" {
+		return fmt.Sprintf("%d %s", s.StatusCode, http.StatusText(s.StatusCode))
+	}
+
+	return fmt.Sprintf("%d %s: %s", s.StatusCode, s.Code, s.Message)
+}
+
+func (s *S3) checkCancellation(err error) error {
+	v, ok := err.(*Error)
+	if !ok {
+		return err
+	}
+
+	cancellation := errors.Cause(v.Err) == context.Canceled || errors.Cause(v.Err) == context.DeadlineExceeded
+
+	if cancellation {
+		s.Logger.Debug("operation cancelled by user")
+		return newError(v.ID, ErrorCodeCancelled, v.Err)
+	}
+
+	return err
+}
@@ -0,0 +1,869 @@
+package cloud_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+func TestNewS3(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		config        cloud.S3Config
+		expected      *cloud.S3
+		expectedError error
+	}{
+		{
+			description: "it should initialize the session correctly",
+			config: cloud.S3Config{
+				Endpoint:  "https://s3.example.com",
+				Bucket:    "backup",
+				MachineID: "server1",
+				PathStyle: true,
+			},
+			expected: &cloud.S3{
+				Endpoint:  &url.URL{Scheme: "https", Host: "s3.example.com"},
+				Region:    cloud.DefaultS3Region,
+				Bucket:    "backup",
+				MachineID: "server1",
+				PathStyle: true,
+			},
+		},
+		{
+			description: "it should detect an invalid endpoint",
+			config: cloud.S3Config{
+				Endpoint: "s3.example.com",
+				Bucket:   "backup",
+			},
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeInitializingSession,
+				Err:  errors.New("invalid endpoint “s3.example.com”"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			s3, err := cloud.NewS3(mockLogger{}, scenario.config)
+
+			// the logger, the HTTP client and the signer can't be compared
+			if s3 != nil {
+				s3.Logger = nil
+				s3.HTTPClient = nil
+				s3.Signer = nil
+			}
+
+			if !reflect.DeepEqual(scenario.expected, s3) {
+				t.Errorf("sessions don't match.\n%s", Diff(scenario.expected, s3))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestS3_Send(t *testing.T) {
+	scenarios := []struct {
+		description      string
+		filename         string
+		comment          string
+		partSize         int64
+		server           *fakeS3
+		expected         cloud.Backup
+		expectedMetadata http.Header
+		expectedParts    int
+		expectedError    error
+	}{
+		{
+			description: "it should send a backup addressing the bucket in the path",
+			filename:    writeTestFile(t, "Important information for the test backup"),
+			comment:     "before OS upgrade",
+			server:      newFakeS3("backup", true),
+			expected: cloud.Backup{
+				CreatedAt: time.Date(2017, 9, 13, 13, 27, 53, 0, time.UTC),
+				Checksum:  "79f928b697ee1b5b02f0bae25828362e",
+				VaultName: "backup",
+				Size:      41,
+				Location:  cloud.LocationS3,
+				MachineID: "server1",
+				Comment:   "before OS upgrade",
+			},
+			expectedMetadata: http.Header{
+				"X-Amz-Meta-Machine-Id": []string{"server1"},
+				"X-Amz-Meta-Comment":    []string{"before%20OS%20upgrade"},
+			},
+		},
+		{
+			description: "it should send a backup addressing the bucket in the host name",
+			filename:    writeTestFile(t, "Important information for the test backup"),
+			server:      newFakeS3("backup", false),
+			expected: cloud.Backup{
+				CreatedAt: time.Date(2017, 9, 13, 13, 27, 53, 0, time.UTC),
+				Checksum:  "79f928b697ee1b5b02f0bae25828362e",
+				VaultName: "backup",
+				Size:      41,
+				Location:  cloud.LocationS3,
+				MachineID: "server1",
+			},
+			expectedMetadata: http.Header{
+				"X-Amz-Meta-Machine-Id": []string{"server1"},
+			},
+		},
+		{
+			description: "it should send a big backup in parts",
+			filename:    writeTestFile(t, "Important information for the test backup"),
+			partSize:    16,
+			server:      newFakeS3("backup", true),
+			expected: cloud.Backup{
+				CreatedAt: time.Date(2017, 9, 13, 13, 27, 53, 0, time.UTC),
+				Checksum:  "79f928b697ee1b5b02f0bae25828362e",
+				VaultName: "backup",
+				Size:      41,
+				Location:  cloud.LocationS3,
+				MachineID: "server1",
+			},
+			expectedMetadata: http.Header{
+				"X-Amz-Meta-Machine-Id": []string{"server1"},
+			},
+			expectedParts: 3,
+		},
+		{
+			description: "it should abort the upload when a part fails",
+			filename:    writeTestFile(t, "Important information for the test backup"),
+			partSize:    16,
+			server: func() *fakeS3 {
+				server := newFakeS3("backup", true)
+				server.failures[http.MethodPut] = http.StatusInternalServerError
+				return server
+			}(),
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeSendingArchive,
+				Err:  errors.New("500 InternalError: We encountered an internal error"),
+			},
+		},
+		{
+			description:   "it should detect when the file doesn't exist",
+			filename:      path.Join(os.TempDir(), "toglacier-idontexist"),
+			server:        newFakeS3("backup", true),
+			expectedError: &cloud.Error{Code: cloud.ErrorCodeOpeningArchive, Err: errors.New("open " + path.Join(os.TempDir(), "toglacier-idontexist") + ": no such file or directory")},
+		},
+		{
+			description: "it should detect an error while sending the object",
+			filename:    writeTestFile(t, "Important information for the test backup"),
+			server: func() *fakeS3 {
+				server := newFakeS3("backup", true)
+				server.failures[http.MethodPut] = http.StatusForbidden
+				return server
+			}(),
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeSendingArchive,
+				Err:  errors.New("403 AccessDenied: Access Denied"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			defer os.Remove(scenario.filename)
+
+			s3, closeServer := scenario.server.start(t)
+			defer closeServer()
+			s3.PartSize = scenario.partSize
+
+			backup, err := s3.Send(context.Background(), scenario.filename, scenario.comment)
+
+			// the id is generated when sending the backup
+			if backup.ID != "" {
+				scenario.expected.ID = backup.ID
+			}
+
+			if !reflect.DeepEqual(scenario.expected, backup) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backup))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if scenario.expectedMetadata != nil {
+				if metadata := scenario.server.object(backup.ID).metadata; !reflect.DeepEqual(scenario.expectedMetadata, metadata) {
+					t.Errorf("metadata don't match.\n%s", Diff(scenario.expectedMetadata, metadata))
+				}
+			}
+
+			if parts := scenario.server.parts; scenario.expectedParts != parts {
+				t.Errorf("parts don't match. expected: %d and got %d", scenario.expectedParts, parts)
+			}
+
+			if uploads := len(scenario.server.uploads); uploads > 0 {
+				t.Errorf("%d multipart uploads weren't completed or aborted", uploads)
+			}
+		})
+	}
+}
+
+func TestS3_SendParity(t *testing.T) {
+	server := newFakeS3("backup", true)
+	s3, closeServer := server.start(t)
+	defer closeServer()
+
+	filename := writeTestFile(t, "parity")
+	defer os.Remove(filename)
+
+	backup, err := s3.SendParity(context.Background(), filename, "S3ID123")
+	if err != nil {
+		t.Fatalf("unexpected error. details: %s", err)
+	}
+
+	expected := http.Header{
+		"X-Amz-Meta-Machine-Id": []string{"server1"},
+		"X-Amz-Meta-Parity-Of":  []string{"S3ID123"},
+	}
+
+	if metadata := server.object(backup.ID).metadata; !reflect.DeepEqual(expected, metadata) {
+		t.Errorf("metadata don't match.\n%s", Diff(expected, metadata))
+	}
+}
+
+func TestS3_List(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		server        *fakeS3
+		expected      []cloud.Backup
+		expectedError error
+	}{
+		{
+			description: "it should list all backups correctly",
+			server: func() *fakeS3 {
+				server := newFakeS3("backup", true)
+				server.add("S3ID123", "backup 1", nil)
+				server.add("S3ID124", "backup 2", http.Header{
+					"X-Amz-Meta-Machine-Id": []string{"server1"},
+					"X-Amz-Meta-Comment":    []string{"before%20OS%20upgrade"},
+				})
+				server.add("S3ID125", "parity", http.Header{
+					"X-Amz-Meta-Machine-Id": []string{"server1"},
+					"X-Amz-Meta-Parity-Of":  []string{"S3ID124"},
+				})
+				server.add("S3ID126", "catalog", http.Header{
+					"X-Amz-Meta-Machine-Id": []string{"server1"},
+					"X-Amz-Meta-Catalog-Of": []string{"S3ID124"},
+				})
+				server.add("toglacier-state-server1", "state", http.Header{
+					"X-Amz-Meta-Machine-Id": []string{"server1"},
+					"X-Amz-Meta-State":      []string{"true"},
+				})
+				return server
+			}(),
+			expected: []cloud.Backup{
+				{
+					ID:        "S3ID123",
+					CreatedAt: time.Date(2017, 9, 13, 13, 27, 53, 0, time.UTC),
+					Checksum:  "9d8a1872d17f427a6125c748feb6e719",
+					VaultName: "backup",
+					Size:      8,
+					Location:  cloud.LocationS3,
+				},
+				{
+					ID:        "S3ID124",
+					CreatedAt: time.Date(2017, 9, 13, 13, 27, 53, 0, time.UTC),
+					Checksum:  "24750e9a9dd6df895a0207d5636d6f2e",
+					VaultName: "backup",
+					Size:      8,
+					Location:  cloud.LocationS3,
+					MachineID: "server1",
+					Comment:   "before OS upgrade",
+					ParityID:  "S3ID125",
+					CatalogID: "S3ID126",
+				},
+			},
+		},
+		{
+			description: "it should list the backups from the object keys",
+			server: func() *fakeS3 {
+				server := newFakeS3("backup", true)
+				server.add("S3ID124.eyJtIjoic2VydmVyMSIsImMiOiJiZWZvcmUgT1MgdXBncmFkZSJ9", "backup 2", nil)
+				server.add("S3ID125.eyJtIjoic2VydmVyMSIsImsiOiJwYXJpdHkiLCJvIjoiUzNJRDEyNCJ9", "parity", nil)
+				server.add("toglacier-state-server1", "state", nil)
+				// the metadata is in the keys, so the objects aren't inspected
+				server.failures[http.MethodHead] = http.StatusInternalServerError
+				return server
+			}(),
+			expected: []cloud.Backup{
+				{
+					ID:        "S3ID124",
+					CreatedAt: time.Date(2017, 9, 13, 13, 27, 53, 0, time.UTC),
+					Checksum:  "24750e9a9dd6df895a0207d5636d6f2e",
+					VaultName: "backup",
+					Size:      8,
+					Location:  cloud.LocationS3,
+					MachineID: "server1",
+					Comment:   "before OS upgrade",
+					ParityID:  "S3ID125",
+				},
+			},
+		},
+		{
+			description: "it should detect an error while listing the objects",
+			server: func() *fakeS3 {
+				server := newFakeS3("backup", true)
+				server.failures[http.MethodGet] = http.StatusInternalServerError
+				return server
+			}(),
+			expectedError: &cloud.Error{
+				Code: cloud.ErrorCodeIterating,
+				Err:  errors.New("500 InternalError: We encountered an internal error"),
+			},
+		},
+		{
+			description: "it should detect an error while retrieving the object metadata",
+			server: func() *fakeS3 {
+				server := newFakeS3("backup", true)
+				server.add("S3ID123", "backup 1", nil)
+				server.failures[http.MethodHead] = http.StatusInternalServerError
+				return server
+			}(),
+			expectedError: &cloud.Error{
+				ID:   "S3ID123",
+				Code: cloud.ErrorCodeRemoteArchiveInfo,
+				Err:  errors.New("500 Internal Server Error"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			s3, closeServer := scenario.server.start(t)
+			defer closeServer()
+
+			backups, err := s3.List(context.Background())
+			if !reflect.DeepEqual(scenario.expected, backups) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backups))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestS3_Get(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		ids           []string
+		server        *fakeS3
+		expected      map[string]string
+		expectedError error
+	}{
+		{
+			description: "it should retrieve a backup correctly",
+			ids:         []string{"S3ID123"},
+			server: func() *fakeS3 {
+				server := newFakeS3("backup", true)
+				server.add("S3ID123", "This is a test", nil)
+				return server
+			}(),
+			expected: map[string]string{
+				"S3ID123": path.Join(os.TempDir(), "backup-S3ID123.tar"),
+			},
+		},
+		{
+			description: "it should retrieve a backup with the metadata in the key",
+			ids:         []string{"S3ID123"},
+			server: func() *fakeS3 {
+				server := newFakeS3("backup", true)
+				server.add("S3ID1234.eyJtIjoic2VydmVyMSJ9", "Another test", nil)
+				server.add("S3ID123.eyJtIjoic2VydmVyMSJ9", "This is a test", nil)
+				return server
+			}(),
+			expected: map[string]string{
+				"S3ID123": path.Join(os.TempDir(), "backup-S3ID123.tar"),
+			},
+		},
+		{
+			description: "it should detect when the backup doesn't exist",
+			ids:         []string{"S3ID123"},
+			server:      newFakeS3("backup", true),
+			expectedError: &cloud.Error{
+				ID:   "S3ID123",
+				Code: cloud.ErrorCodeDownloadingArchive,
+				Err:  errors.New("404 NoSuchKey: The specified key does not exist."),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			s3, closeServer := scenario.server.start(t)
+			defer closeServer()
+
+			filenames, err := s3.Get(context.Background(), scenario.ids...)
+			if !reflect.DeepEqual(scenario.expected, filenames) {
+				t.Errorf("filenames don't match.\n%s", Diff(scenario.expected, filenames))
+			}
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			for id, filename := range filenames {
+				content, err := ioutil.ReadFile(filename)
+				if err != nil {
+					t.Fatalf("error reading the retrieved backup. details: %s", err)
+				}
+				os.Remove(filename)
+
+				if expected := string(scenario.server.object(id).content); string(content) != expected {
+					t.Errorf("contents don't match. expected “%s” and got “%s”", expected, content)
+				}
+			}
+		})
+	}
+}
+
+func TestS3_Remove(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		id            string
+		server        *fakeS3
+		expectedError error
+	}{
+		{
+			description: "it should remove a backup correctly",
+			id:          "S3ID123",
+			server: func() *fakeS3 {
+				server := newFakeS3("backup", true)
+				server.add("S3ID123", "This is a test", nil)
+				return server
+			}(),
+		},
+		{
+			description: "it should remove a backup with the metadata in the key",
+			id:          "S3ID123",
+			server: func() *fakeS3 {
+				server := newFakeS3("backup", true)
+				server.add("S3ID123.eyJtIjoic2VydmVyMSJ9", "This is a test", nil)
+				return server
+			}(),
+		},
+		{
+			description: "it should detect an error while removing the object",
+			id:          "S3ID123",
+			server: func() *fakeS3 {
+				server := newFakeS3("backup", true)
+				server.failures[http.MethodDelete] = http.StatusForbidden
+				return server
+			}(),
+			expectedError: &cloud.Error{
+				ID:   "S3ID123",
+				Code: cloud.ErrorCodeRemovingArchive,
+				Err:  errors.New("403 AccessDenied: Access Denied"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			s3, closeServer := scenario.server.start(t)
+			defer closeServer()
+
+			err := s3.Remove(context.Background(), scenario.id)
+			if !cloud.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected: “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if scenario.server.object(scenario.id).content != nil && err == nil {
+				t.Errorf("object “%s” wasn't removed", scenario.id)
+			}
+		})
+	}
+}
+
+func TestS3_State(t *testing.T) {
+	server := newFakeS3("backup", true)
+	s3, closeServer := server.start(t)
+	defer closeServer()
+
+	filename, err := s3.LoadState(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error loading the missing state. details: %s", err)
+	}
+
+	if filename != "" {
+		t.Errorf("unexpected state file “%s” without state", filename)
+	}
+
+	state := writeTestFile(t, "local storage state")
+	defer os.Remove(state)
+
+	if err = s3.SaveState(context.Background(), state); err != nil {
+		t.Fatalf("unexpected error saving the state. details: %s", err)
+	}
+
+	expectedMetadata := http.Header{
+		"X-Amz-Meta-Machine-Id": []string{"server1"},
+		"X-Amz-Meta-State":      []string{"true"},
+	}
+
+	if metadata := server.objects["toglacier-state-server1"].metadata; !reflect.DeepEqual(expectedMetadata, metadata) {
+		t.Errorf("metadata don't match.\n%s", Diff(expectedMetadata, metadata))
+	}
+
+	if filename, err = s3.LoadState(context.Background()); err != nil {
+		t.Fatalf("unexpected error loading the state. details: %s", err)
+	}
+	defer os.Remove(filename)
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("error reading the state. details: %s", err)
+	}
+
+	if string(content) != "local storage state" {
+		t.Errorf("unexpected state “%s”", content)
+	}
+
+	server.failures[http.MethodGet] = http.StatusForbidden
+	expectedError := &cloud.Error{
+		ID:   "toglacier-state-server1",
+		Code: cloud.ErrorCodeLoadingState,
+		Err:  errors.New("403 AccessDenied: Access Denied"),
+	}
+
+	if _, err = s3.LoadState(context.Background()); !cloud.ErrorEqual(expectedError, err) {
+		t.Errorf("errors don't match. expected: “%v” and got “%v”", expectedError, err)
+	}
+}
+
+// writeTestFile creates a temporary file with the content.
+func writeTestFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "toglacier-test-")
+	if err != nil {
+		t.Fatalf("error creating file. details: %s", err)
+	}
+	defer f.Close()
+
+	f.WriteString(content)
+	return f.Name()
+}
+
+// fakeS3 is an in-memory S3-compatible service storing the objects of a
+// single bucket. The listing is split in pages of two objects.
+type fakeS3 struct {
+	sync.Mutex
+
+	bucket    string
+	pathStyle bool
+	objects   map[string]fakeS3Object
+
+	// uploads are the multipart uploads in progress and parts the number of
+	// parts received.
+	uploads map[string]*fakeS3Upload
+	parts   int
+
+	// failures is the status returned for each method, when defined.
+	failures map[string]int
+}
+
+type fakeS3Upload struct {
+	key      string
+	metadata http.Header
+	parts    map[string][]byte
+}
+
+type fakeS3Object struct {
+	content  []byte
+	metadata http.Header
+}
+
+func newFakeS3(bucket string, pathStyle bool) *fakeS3 {
+	return &fakeS3{
+		bucket:    bucket,
+		pathStyle: pathStyle,
+		objects:   make(map[string]fakeS3Object),
+		uploads:   make(map[string]*fakeS3Upload),
+		failures:  make(map[string]int),
+	}
+}
+
+func (f *fakeS3) add(key, content string, metadata http.Header) {
+	f.objects[key] = fakeS3Object{content: []byte(content), metadata: metadata}
+}
+
+// object returns the object of the backup, that can have the metadata encoded
+// in the key after the identifier.
+func (f *fakeS3) object(id string) fakeS3Object {
+	for key, object := range f.objects {
+		if key == id || strings.HasPrefix(key, id+".") {
+			return object
+		}
+	}
+
+	return fakeS3Object{}
+}
+
+// start runs the service, returning a session addressing the bucket as the
+// service expects and the function that stops the service.
+func (f *fakeS3) start(t *testing.T) (*cloud.S3, func()) {
+	server := httptest.NewServer(f)
+
+	endpoint, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("error parsing server address. details: %s", err)
+	}
+
+	// the bucket in the host name can't be resolved, so all connections go to
+	// the service
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, endpoint.Host)
+			},
+		},
+	}
+
+	return &cloud.S3{
+		Logger: mockLogger{
+			mockDebug:  func(args ...interface{}) {},
+			mockDebugf: func(format string, args ...interface{}) {},
+			mockInfo:   func(args ...interface{}) {},
+			mockInfof:  func(format string, args ...interface{}) {},
+		},
+		Endpoint:   endpoint,
+		Region:     cloud.DefaultS3Region,
+		Bucket:     f.bucket,
+		MachineID:  "server1",
+		PathStyle:  f.pathStyle,
+		HTTPClient: httpClient,
+		Signer:     v4.NewSigner(credentials.NewStaticCredentials("AKID", "SECRET", "")),
+	}, server.Close
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		f.fail(w, r, http.StatusForbidden)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if f.pathStyle {
+		if key != f.bucket && !strings.HasPrefix(key, f.bucket+"/") {
+			f.fail(w, r, http.StatusNotFound)
+			return
+		}
+		key = strings.TrimPrefix(strings.TrimPrefix(key, f.bucket), "/")
+
+	} else if !strings.HasPrefix(r.Host, f.bucket+".") {
+		f.fail(w, r, http.StatusNotFound)
+		return
+	}
+
+	if status := f.failures[r.Method]; status != 0 {
+		f.fail(w, r, status)
+		return
+	}
+
+	var metadata http.Header
+	for name, values := range r.Header {
+		if strings.HasPrefix(name, "X-Amz-Meta-") {
+			if metadata == nil {
+				metadata = make(http.Header)
+			}
+			metadata[name] = values
+		}
+	}
+
+	uploadID := r.URL.Query().Get("uploadId")
+
+	switch r.Method {
+	case http.MethodPost:
+		if _, ok := r.URL.Query()["uploads"]; ok {
+			uploadID = strconv.Itoa(len(f.uploads) + 1)
+			f.uploads[uploadID] = &fakeS3Upload{key: key, metadata: metadata, parts: make(map[string][]byte)}
+			xml.NewEncoder(w).Encode(struct {
+				XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+				UploadID string   `xml:"UploadId"`
+			}{UploadID: uploadID})
+			return
+		}
+
+		upload, ok := f.uploads[uploadID]
+		if !ok {
+			f.fail(w, r, http.StatusNotFound)
+			return
+		}
+
+		var complete struct {
+			Parts []struct {
+				PartNumber string `xml:"PartNumber"`
+				ETag       string `xml:"ETag"`
+			} `xml:"Part"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&complete); err != nil {
+			f.fail(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		var content bytes.Buffer
+		for _, part := range complete.Parts {
+			hash := md5.Sum(upload.parts[part.PartNumber])
+			if part.ETag != `"`+hex.EncodeToString(hash[:])+`"` {
+				f.fail(w, r, http.StatusInternalServerError)
+				return
+			}
+			content.Write(upload.parts[part.PartNumber])
+		}
+
+		f.objects[upload.key] = fakeS3Object{content: content.Bytes(), metadata: upload.metadata}
+		delete(f.uploads, uploadID)
+
+	case http.MethodPut:
+		content, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			f.fail(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		if uploadID != "" {
+			upload, ok := f.uploads[uploadID]
+			if !ok {
+				f.fail(w, r, http.StatusNotFound)
+				return
+			}
+
+			upload.parts[r.URL.Query().Get("partNumber")] = content
+			f.parts++
+
+			hash := md5.Sum(content)
+			w.Header().Set("ETag", `"`+hex.EncodeToString(hash[:])+`"`)
+			return
+		}
+
+		f.objects[key] = fakeS3Object{content: content, metadata: metadata}
+
+	case http.MethodHead, http.MethodGet:
+		if key == "" && r.URL.Query().Get("list-type") == "2" {
+			f.list(w, r)
+			return
+		}
+
+		object, ok := f.objects[key]
+		if !ok {
+			f.fail(w, r, http.StatusNotFound)
+			return
+		}
+
+		for name, values := range object.metadata {
+			w.Header()[name] = values
+		}
+
+		hash := md5.Sum(object.content)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(hash[:])+`"`)
+		w.Header().Set("Last-Modified", time.Date(2017, 9, 13, 13, 27, 53, 0, time.UTC).Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.content)))
+
+		if r.Method == http.MethodGet {
+			w.Write(object.content)
+		}
+
+	case http.MethodDelete:
+		if uploadID != "" {
+			delete(f.uploads, uploadID)
+		} else {
+			delete(f.objects, key)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	start, _ := strconv.Atoi(r.URL.Query().Get("continuation-token"))
+
+	type content struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		ETag         string    `xml:"ETag"`
+		Size         int       `xml:"Size"`
+	}
+
+	result := struct {
+		XMLName               xml.Name  `xml:"ListBucketResult"`
+		Contents              []content `xml:"Contents"`
+		IsTruncated           bool      `xml:"IsTruncated"`
+		NextContinuationToken string    `xml:"NextContinuationToken,omitempty"`
+	}{}
+
+	for i := start; i < len(keys) && i < start+2; i++ {
+		object := f.objects[keys[i]]
+		hash := md5.Sum(object.content)
+
+		result.Contents = append(result.Contents, content{
+			Key:          keys[i],
+			LastModified: time.Date(2017, 9, 13, 13, 27, 53, 0, time.UTC),
+			ETag:         `"` + hex.EncodeToString(hash[:]) + `"`,
+			Size:         len(object.content),
+		})
+	}
+
+	if start+2 < len(keys) {
+		result.IsTruncated = true
+		result.NextContinuationToken = strconv.Itoa(start + 2)
+	}
+
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) fail(w http.ResponseWriter, r *http.Request, status int) {
+	codes := map[int][2]string{
+		http.StatusForbidden:           {"AccessDenied", "Access Denied"},
+		http.StatusNotFound:            {"NoSuchKey", "The specified key does not exist."},
+		http.StatusInternalServerError: {"InternalError", "We encountered an internal error"},
+	}
+
+	w.WriteHeader(status)
+
+	// responses of HEAD requests don't have body
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}{
+		Code:    codes[status][0],
+		Message: codes[status][1],
+	})
+}
@@ -0,0 +1,128 @@
+package cloud
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// traceMaxSize limits the size of each trace entry, as the HTTP bodies dumped
+// by the AWS library contain the archive parts.
+const traceMaxSize = 4096
+
+// traceRedactions removes the signatures and temporary credentials from the
+// requests dumped by the AWS library.
+var traceRedactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{
+		pattern:     regexp.MustCompile(`(?im)^(Authorization|X-Amz-Security-Token):[^\r\n]*`),
+		replacement: "${1}: [REDACTED]",
+	},
+	{
+		pattern:     regexp.MustCompile(`(?i)(X-Amz-(?:Signature|Credential|Security-Token)=)[^&\s]*`),
+		replacement: "${1}[REDACTED]",
+	},
+}
+
+// traceArchiveOperations are the AWS Glacier operations that carry the archive
+// contents in the HTTP body, so only their headers are traced.
+var traceArchiveOperations = map[string]bool{
+	"GetJobOutput":        true,
+	"UploadArchive":       true,
+	"UploadMultipartPart": true,
+}
+
+// traceBodyHandler disables the HTTP body in the trace of the operations that
+// transfer the archive contents. Besides flooding the log, dumping the body
+// would read the whole archive part in memory.
+var traceBodyHandler = request.NamedHandler{
+	Name: "toglacier.TraceBody",
+	Fn: func(r *request.Request) {
+		if r.Operation == nil || !traceArchiveOperations[r.Operation.Name] {
+			return
+		}
+
+		logLevel := r.Config.LogLevel.Value() &^ aws.LogDebugWithHTTPBody
+		r.Config.LogLevel = aws.LogLevel(logLevel)
+	},
+}
+
+// traceLogger writes the requests and responses of the AWS library in the
+// logger as debug messages, without the credentials.
+type traceLogger struct {
+	logger  log.Logger
+	secrets []string
+}
+
+// newTraceLogger creates the AWS library logger that hides the given secrets
+// (e.g. access key ID and secret access key) from the log messages.
+func newTraceLogger(logger log.Logger, secrets ...string) traceLogger {
+	t := traceLogger{logger: logger}
+	for _, secret := range secrets {
+		if secret != "" {
+			t.secrets = append(t.secrets, secret)
+		}
+	}
+	return t
+}
+
+// Log redacts and writes the message of the AWS library.
+func (t traceLogger) Log(args ...interface{}) {
+	if t.logger == nil {
+		return
+	}
+
+	t.logger.Debugf("cloud: trace %s", t.redact(fmt.Sprint(args...)))
+}
+
+// redact removes the credentials of the message and truncates it to
+// traceMaxSize bytes.
+func (t traceLogger) redact(message string) string {
+	for _, redaction := range traceRedactions {
+		message = redaction.pattern.ReplaceAllString(message, redaction.replacement)
+	}
+
+	for _, secret := range t.secrets {
+		message = strings.Replace(message, secret, "[REDACTED]", -1)
+	}
+
+	if len(message) > traceMaxSize {
+		message = fmt.Sprintf("%s... (%d bytes truncated)", message[:traceMaxSize], len(message)-traceMaxSize)
+	}
+
+	return message
+}
+
+// traceHTTPClient logs the requests and responses sent by the HTTP client,
+// without the bodies, as they carry the archive contents.
+type traceHTTPClient struct {
+	client S3HTTPClient
+	logger traceLogger
+}
+
+// Do logs the request, sends it and logs the response.
+func (t traceHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if dump, err := httputil.DumpRequestOut(req, false); err == nil {
+		t.logger.Log(fmt.Sprintf("Request %s %s:\n%s", req.Method, req.URL.Path, dump))
+	}
+
+	response, err := t.client.Do(req)
+	if err != nil {
+		t.logger.Log(fmt.Sprintf("Request %s %s failed: %s", req.Method, req.URL.Path, err))
+		return response, err
+	}
+
+	if dump, err := httputil.DumpResponse(response, false); err == nil {
+		t.logger.Log(fmt.Sprintf("Response %s %s:\n%s", req.Method, req.URL.Path, dump))
+	}
+
+	return response, nil
+}
@@ -0,0 +1,228 @@
+package cloud_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/glacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+func TestAWSCloud_Trace(t *testing.T) {
+	defer os.Clearenv()
+
+	scenarios := []struct {
+		description string
+		message     string
+		expected    string
+	}{
+		{
+			description: "it should redact the authorization header",
+			message: "DEBUG: Request glacier/UploadArchive Details:\n" +
+				"POST /123456789012/vaults/vault/archives HTTP/1.1\r\n" +
+				"Authorization: AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20170914/us-east-1/glacier/aws4_request, SignedHeaders=host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7\r\n" +
+				"X-Amz-Security-Token: FQoDYXdzEJr1K2JhbmFuYQ\r\n" +
+				"X-Amz-Date: 20170914T103000Z\r\n",
+			expected: "cloud: trace DEBUG: Request glacier/UploadArchive Details:\n" +
+				"POST /123456789012/vaults/vault/archives HTTP/1.1\r\n" +
+				"Authorization: [REDACTED]\r\n" +
+				"X-Amz-Security-Token: [REDACTED]\r\n" +
+				"X-Amz-Date: 20170914T103000Z\r\n",
+		},
+		{
+			description: "it should redact the presigned query parameters",
+			message:     "GET /vault?X-Amz-Credential=AKIDEXAMPLE%2F20170914&X-Amz-Signature=5d672d79&X-Amz-Expires=300 HTTP/1.1",
+			expected:    "cloud: trace GET /vault?X-Amz-Credential=[REDACTED]&X-Amz-Signature=[REDACTED]&X-Amz-Expires=300 HTTP/1.1",
+		},
+		{
+			description: "it should redact the credentials anywhere in the message",
+			message:     "CANONICAL STRING keyid secret",
+			expected:    "cloud: trace CANONICAL STRING [REDACTED] [REDACTED]",
+		},
+		{
+			description: "it should truncate the large bodies",
+			message:     strings.Repeat("x", 5000),
+			expected:    "cloud: trace " + strings.Repeat("x", 4096) + "... (904 bytes truncated)",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var messages []string
+
+			logger := mockLogger{
+				mockDebugf: func(format string, args ...interface{}) {
+					messages = append(messages, fmt.Sprintf(format, args...))
+				},
+			}
+
+			awsCloud, err := cloud.NewAWSCloud(logger, cloud.AWSConfig{
+				AccountID:       "account",
+				AccessKeyID:     "keyid",
+				SecretAccessKey: "secret",
+				Region:          "us-east-1",
+				VaultName:       "vault",
+			}, true)
+
+			if err != nil {
+				t.Fatalf("unexpected error “%v”", err)
+			}
+
+			awsCloud.Glacier.(*glacier.Glacier).Config.Logger.Log(scenario.message)
+
+			if len(messages) != 1 || messages[0] != scenario.expected {
+				t.Errorf("unexpected trace messages.\n%s", Diff([]string{scenario.expected}, messages))
+			}
+		})
+	}
+}
+
+func TestAWSCloud_TraceBody(t *testing.T) {
+	defer os.Clearenv()
+
+	awsCloud, err := cloud.NewAWSCloud(mockLogger{}, cloud.AWSConfig{
+		AccountID:       "account",
+		AccessKeyID:     "keyid",
+		SecretAccessKey: "secret",
+		Region:          "us-east-1",
+		VaultName:       "vault",
+	}, true)
+
+	if err != nil {
+		t.Fatalf("unexpected error “%v”", err)
+	}
+
+	awsGlacier := awsCloud.Glacier.(*glacier.Glacier)
+
+	scenarios := []struct {
+		description string
+		request     *request.Request
+		expected    bool
+	}{
+		{
+			description: "it should log the body of the operations without archive contents",
+			request: func() *request.Request {
+				r, _ := awsGlacier.DescribeJobRequest(&glacier.DescribeJobInput{
+					AccountId: aws.String("account"),
+					JobId:     aws.String("JOBID123"),
+					VaultName: aws.String("vault"),
+				})
+				return r
+			}(),
+			expected: true,
+		},
+		{
+			description: "it should not log the body of an archive upload",
+			request: func() *request.Request {
+				r, _ := awsGlacier.UploadArchiveRequest(&glacier.UploadArchiveInput{
+					AccountId: aws.String("account"),
+					Body:      strings.NewReader("archive content"),
+					VaultName: aws.String("vault"),
+				})
+				return r
+			}(),
+		},
+		{
+			description: "it should not log the body of an archive part upload",
+			request: func() *request.Request {
+				r, _ := awsGlacier.UploadMultipartPartRequest(&glacier.UploadMultipartPartInput{
+					AccountId: aws.String("account"),
+					Body:      strings.NewReader("archive content"),
+					Range:     aws.String("bytes 0-14/*"),
+					UploadId:  aws.String("UPLOAD123"),
+					VaultName: aws.String("vault"),
+				})
+				return r
+			}(),
+		},
+		{
+			description: "it should not log the body of a job output",
+			request: func() *request.Request {
+				r, _ := awsGlacier.GetJobOutputRequest(&glacier.GetJobOutputInput{
+					AccountId: aws.String("account"),
+					JobId:     aws.String("JOBID123"),
+					VaultName: aws.String("vault"),
+				})
+				return r
+			}(),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if err := scenario.request.Build(); err != nil {
+				t.Fatalf("unexpected error building the request. details: %s", err)
+			}
+
+			if body := scenario.request.Config.LogLevel.Matches(aws.LogDebugWithHTTPBody); body != scenario.expected {
+				t.Errorf("body logging doesn't match. expected “%t” and got “%t”", scenario.expected, body)
+			}
+
+			if !awsGlacier.Config.LogLevel.Matches(aws.LogDebugWithHTTPBody) {
+				t.Error("body logging disabled for all requests")
+			}
+		})
+	}
+}
+
+func TestS3_Trace(t *testing.T) {
+	server := newFakeS3("backup", true)
+	server.add("S3ID123", "This is a test", nil)
+
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	var messages []string
+
+	logger := mockLogger{
+		mockDebug: func(args ...interface{}) {},
+		mockDebugf: func(format string, args ...interface{}) {
+			messages = append(messages, fmt.Sprintf(format, args...))
+		},
+		mockInfo:  func(args ...interface{}) {},
+		mockInfof: func(format string, args ...interface{}) {},
+	}
+
+	s3, err := cloud.NewS3(logger, cloud.S3Config{
+		Endpoint:        httpServer.URL,
+		Bucket:          "backup",
+		AccessKeyID:     "keyid",
+		SecretAccessKey: "secret",
+		MachineID:       "server1",
+		PathStyle:       true,
+		Trace:           true,
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error “%v”", err)
+	}
+
+	filenames, err := s3.Get(context.Background(), "S3ID123")
+	if err != nil {
+		t.Fatalf("unexpected error “%v”", err)
+	}
+	os.Remove(filenames["S3ID123"])
+
+	var request, response bool
+	for _, message := range messages {
+		if strings.HasPrefix(message, "cloud: trace Request GET /backup/S3ID123") {
+			request = true
+		} else if strings.HasPrefix(message, "cloud: trace Response GET /backup/S3ID123") {
+			response = true
+		}
+
+		if strings.Contains(message, "This is a test") || strings.Contains(message, "keyid") || strings.Contains(message, "Signature=") {
+			t.Errorf("trace message with contents or credentials:\n%s", message)
+		}
+	}
+
+	if !request || !response {
+		t.Errorf("request or response not traced:\n%s", strings.Join(messages, "\n"))
+	}
+}
@@ -0,0 +1,162 @@
+package cloud
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/glacier"
+)
+
+// hashChunkSize is the size of the leaves of the SHA256 tree hash, as defined
+// by the AWS Glacier service.
+//
+// http://docs.aws.amazon.com/amazonglacier/latest/dev/checksum-calculations.html
+const hashChunkSize = 1048576 // 1 MB in bytes
+
+// buffers reuses the memory used to read the archives between uploads and
+// parts, as each upload would allocate megabytes of data.
+var buffers sync.Pool
+
+// getBuffer returns a buffer from the pool with the given size. The buffer must
+// be returned with putBuffer after use.
+func getBuffer(size int64) []byte {
+	if buffer, ok := buffers.Get().(*[]byte); ok && int64(cap(*buffer)) >= size {
+		return (*buffer)[:size]
+	}
+
+	return make([]byte, size)
+}
+
+func putBuffer(buffer []byte) {
+	buffers.Put(&buffer)
+}
+
+// treeHash computes the linear and the tree hashes in a single pass over the
+// written data.
+type treeHash struct {
+	linear       hash.Hash
+	chunk        hash.Hash
+	chunkWritten int
+	hashes       [][]byte
+}
+
+func newTreeHash() *treeHash {
+	return &treeHash{
+		linear: sha256.New(),
+		chunk:  sha256.New(),
+	}
+}
+
+// Write splits the data in the tree hash leaves, never returning an error.
+func (t *treeHash) Write(p []byte) (int, error) {
+	t.linear.Write(p)
+	written := len(p)
+
+	for len(p) > 0 {
+		n := hashChunkSize - t.chunkWritten
+		if n > len(p) {
+			n = len(p)
+		}
+
+		t.chunk.Write(p[:n])
+		t.chunkWritten += n
+		p = p[n:]
+
+		if t.chunkWritten == hashChunkSize {
+			t.hashes = append(t.hashes, t.chunk.Sum(nil))
+			t.chunk.Reset()
+			t.chunkWritten = 0
+		}
+	}
+
+	return written, nil
+}
+
+// chunks returns the hashes of the tree hash leaves, including the last
+// incomplete leaf.
+func (t *treeHash) chunks() [][]byte {
+	if t.chunkWritten == 0 {
+		return t.hashes
+	}
+
+	return append(t.hashes[:len(t.hashes):len(t.hashes)], t.chunk.Sum(nil))
+}
+
+// appendChunks adds the leaves of another tree hash, that must be aligned to
+// the leaves size except when it is the last one. It is useful to build the
+// tree hash of an archive from the tree hashes of its parts without hashing
+// the data again. The linear hash isn't updated.
+func (t *treeHash) appendChunks(other *treeHash) {
+	t.hashes = append(t.hashes, other.chunks()...)
+}
+
+// LinearHash returns the SHA256 of all written data.
+func (t *treeHash) LinearHash() []byte {
+	return t.linear.Sum(nil)
+}
+
+// TreeHash returns the root of the SHA256 tree hash of all written data.
+func (t *treeHash) TreeHash() []byte {
+	return glacier.ComputeTreeHash(t.chunks())
+}
+
+// computeHashes reads the archive once with a pooled buffer of the given size,
+// computing the linear and tree hashes. When the whole archive fits in the
+// buffer, the returned body is the data already in memory, so the archive isn't
+// read again to send it. Otherwise the archive is rewound to be sent. The
+// returned function must be called after the body is used, to release the
+// buffer.
+func computeHashes(archive io.ReadSeeker, bufferSize int64) (hash *treeHash, body io.ReadSeeker, release func(), err error) {
+	if _, err = archive.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, nil, err
+	}
+
+	buffer := getBuffer(bufferSize)
+	release = func() {
+		putBuffer(buffer)
+	}
+
+	hash = newTreeHash()
+
+	n, err := io.ReadFull(archive, buffer)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		hash.Write(buffer[:n])
+		return hash, bytes.NewReader(buffer[:n]), release, nil
+
+	} else if err != nil {
+		release()
+		return nil, nil, nil, err
+	}
+
+	hash.Write(buffer)
+	if _, err = io.CopyBuffer(hash, readerOnly{archive}, buffer); err != nil {
+		release()
+		return nil, nil, nil, err
+	}
+
+	if _, err = archive.Seek(0, io.SeekStart); err != nil {
+		release()
+		return nil, nil, nil, err
+	}
+
+	return hash, archive, release, nil
+}
+
+// readerOnly hides other interfaces of the reader (like io.WriterTo), so
+// io.CopyBuffer really uses the informed buffer.
+type readerOnly struct {
+	io.Reader
+}
+
+// withLinearHash informs the already computed linear hash of the request body,
+// so the request signer doesn't need to read the body again.
+func withLinearHash(linearHash []byte) request.Option {
+	return func(r *request.Request) {
+		r.HTTPRequest.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(linearHash))
+	}
+}
@@ -1,12 +1,20 @@
 package config
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
 	"io/ioutil"
-	"path"
+	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"time"
+	"unicode"
 	"unsafe"
 
 	"github.com/kelseyhightower/envconfig"
@@ -18,33 +26,96 @@ import (
 // prefix is used as a namespace identifier for environment variables.
 const prefix = "toglacier"
 
+// config stores the global configuration, kept only for compatibility with
+// the functions that don't work with instances.
 var config unsafe.Pointer
 
 // Config stores all the necessary information to send backups to the cloud and
 // keep track in the local storage.
 type Config struct {
-	Paths           []string   `yaml:"paths"`
-	KeepBackups     int        `yaml:"keep backups" split_words:"true"`
-	BackupSecret    aesKey     `yaml:"backup secret" split_words:"true"`
-	ModifyTolerance Percentage `yaml:"modify tolerance" split_words:"true"`
-	IgnorePatterns  []Pattern  `yaml:"ignore patterns" split_words:"true"`
-	Cloud           CloudType  `yaml:"cloud"`
+	Paths             []string   `yaml:"paths"`
+	KeepBackups       int        `yaml:"keep backups" split_words:"true"`
+	GroupByDay        bool       `yaml:"group by day" split_words:"true"`
+	BackupSecret      aesKey     `yaml:"backup secret" split_words:"true"`
+	ModifyTolerance   Percentage `yaml:"modify tolerance" split_words:"true"`
+	ConfirmAbove      Size       `yaml:"confirm above" split_words:"true"`
+	MaxUnreadable     int        `yaml:"max unreadable" split_words:"true"`
+	Snapshot          bool       `yaml:"snapshot"`
+	ChangeRetries     int        `yaml:"change retries" split_words:"true"`
+	RebaseAfter       Duration   `yaml:"rebase after" split_words:"true"`
+	FullBackupEvery   Duration   `yaml:"full backup every" split_words:"true"`
+	BackupMaxDuration Duration   `yaml:"backup max duration" split_words:"true"`
+	TrashPeriod       Duration   `yaml:"trash period" split_words:"true"`
+	IgnorePatterns    []Pattern  `yaml:"ignore patterns" split_words:"true"`
+	Cloud             CloudType  `yaml:"cloud"`
+	MachineID         string     `yaml:"machine id" split_words:"true"`
+	Routes            []Route    `yaml:"routes"`
+	Sources           []Source   `yaml:"sources"`
+	DockerImage       string     `yaml:"docker image" split_words:"true"`
+	Concurrency       int        `yaml:"concurrency"`
+	RemoveConcurrency int        `yaml:"remove concurrency" split_words:"true"`
+	MaxCPUs           int        `yaml:"max cpus" envconfig:"max_cpus"`
+	Watch             bool       `yaml:"watch"`
+	ReadOnly          bool       `yaml:"read only" split_words:"true"`
+	Proxy             ProxyURL   `yaml:"proxy"`
+	Blackouts         []Blackout `yaml:"blackouts"`
+	Tokens            []Token    `yaml:"tokens"`
+	Include           string     `yaml:"include" ignored:"true"`
+	DataDir           string     `yaml:"data dir" split_words:"true"`
 
 	Scheduler struct {
 		Backup            Scheduler `yaml:"backup"`
 		RemoveOldBackups  Scheduler `yaml:"remove old backups" split_words:"true"`
 		ListRemoteBackups Scheduler `yaml:"list remote backups" split_words:"true"`
 		SendReport        Scheduler `yaml:"send report" split_words:"true"`
+		SendSpooled       Scheduler `yaml:"send spooled" split_words:"true"`
+		EmptyTrash        Scheduler `yaml:"empty trash" split_words:"true"`
+		Timezone          Timezone  `yaml:"timezone"`
 	} `yaml:"scheduler" envconfig:"scheduler"`
 
+	Failure struct {
+		RetryDelay    time.Duration `yaml:"retry delay" split_words:"true"`
+		EscalateAfter int           `yaml:"escalate after" split_words:"true"`
+		LogLines      int           `yaml:"log lines" split_words:"true"`
+	} `yaml:"failure" envconfig:"failure"`
+
+	Replica struct {
+		VaultName string `yaml:"vault name" split_words:"true"`
+		Region    string `yaml:"region"`
+	} `yaml:"replica" envconfig:"replica"`
+
+	Spool struct {
+		Dir     string `yaml:"dir"`
+		MaxSize int64  `yaml:"max size" split_words:"true"`
+	} `yaml:"spool" envconfig:"spool"`
+
+	Priority struct {
+		Nice    int     `yaml:"nice"`
+		IOClass IOClass `yaml:"io class" envconfig:"io_class"`
+		IOLevel int     `yaml:"io level" envconfig:"io_level"`
+	} `yaml:"priority" envconfig:"priority"`
+
+	Archive struct {
+		Format           string        `yaml:"format"`
+		Envelop          string        `yaml:"envelop"`
+		Redundancy       Percentage    `yaml:"redundancy"`
+		Normalization    Normalization `yaml:"normalization"`
+		SpecialFiles     SpecialFiles  `yaml:"special files" split_words:"true"`
+		AllowUnencrypted bool          `yaml:"allow unencrypted" split_words:"true"`
+	} `yaml:"archive" envconfig:"archive"`
+
 	Database struct {
-		Type DatabaseType `yaml:"type"`
-		File string       `yaml:"file"`
+		Type      DatabaseType `yaml:"type"`
+		File      string       `yaml:"file"`
+		NoSync    bool         `yaml:"no sync" split_words:"true"`
+		AllocSize int          `yaml:"alloc size" split_words:"true"`
+		Stateless bool         `yaml:"stateless"`
 	} `yaml:"database" envconfig:"db"`
 
 	Log struct {
-		File  string   `yaml:"file"`
-		Level LogLevel `yaml:"level"`
+		File   string                 `yaml:"file"`
+		Level  LogLevel               `yaml:"level"`
+		Levels map[LogModule]LogLevel `yaml:"levels"`
 	} `yaml:"log" envconfig:"log"`
 
 	Email struct {
@@ -55,14 +126,47 @@ type Config struct {
 		From     string      `yaml:"from"`
 		To       []string    `yaml:"to"`
 		Format   EmailFormat `yaml:"format"`
+		Locale   EmailLocale `yaml:"locale"`
 	} `yaml:"email" envconfig:"email"`
 
+	Desktop struct {
+		Enabled    bool `yaml:"enabled"`
+		ErrorsOnly bool `yaml:"errors only" split_words:"true"`
+	} `yaml:"desktop" envconfig:"desktop"`
+
+	Tracing struct {
+		Endpoint string `yaml:"endpoint"`
+	} `yaml:"tracing" envconfig:"tracing"`
+
+	Webhook struct {
+		Address    string `yaml:"address"`
+		Cert       string `yaml:"cert"`
+		Key        string `yaml:"key"`
+		SelfSigned bool   `yaml:"self signed" split_words:"true"`
+	} `yaml:"webhook" envconfig:"webhook"`
+
+	Telegram struct {
+		BotToken encrypted `yaml:"bot token" split_words:"true"`
+		ChatID   string    `yaml:"chat id" split_words:"true"`
+	} `yaml:"telegram" envconfig:"telegram"`
+
+	Notifications struct {
+		Nagios struct {
+			CommandFile string `yaml:"command file" split_words:"true"`
+			Host        string `yaml:"host"`
+			Service     string `yaml:"service"`
+		} `yaml:"nagios" envconfig:"nagios"`
+	} `yaml:"notifications" envconfig:"notifications"`
+
 	AWS struct {
-		AccountID       encrypted `yaml:"account id" split_words:"true"`
-		AccessKeyID     encrypted `yaml:"access key id" split_words:"true"`
-		SecretAccessKey encrypted `yaml:"secret access key" split_words:"true"`
-		Region          string    `yaml:"region"`
-		VaultName       string    `yaml:"vault name" split_words:"true"`
+		AccountID         encrypted         `yaml:"account id" split_words:"true"`
+		AccessKeyID       encrypted         `yaml:"access key id" split_words:"true"`
+		SecretAccessKey   encrypted         `yaml:"secret access key" split_words:"true"`
+		Region            string            `yaml:"region"`
+		VaultName         string            `yaml:"vault name" split_words:"true"`
+		VaultAccessPolicy string            `yaml:"vault access policy" split_words:"true"`
+		VaultTags         map[string]string `yaml:"vault tags" split_words:"true"`
+		RequestsPerSecond float64           `yaml:"requests per second" split_words:"true"`
 	} `yaml:"aws" envconfig:"aws"`
 
 	GCS struct {
@@ -70,43 +174,279 @@ type Config struct {
 		Bucket      string `yaml:"bucket"`
 		AccountFile string `yaml:"account file" split_words:"true"`
 	} `yaml:"gcs" envconfig:"gcs"`
+
+	S3 struct {
+		Endpoint        string    `yaml:"endpoint"`
+		Region          string    `yaml:"region"`
+		Bucket          string    `yaml:"bucket"`
+		AccessKeyID     encrypted `yaml:"access key id" split_words:"true"`
+		SecretAccessKey encrypted `yaml:"secret access key" split_words:"true"`
+		PathStyle       bool      `yaml:"path style" split_words:"true"`
+	} `yaml:"s3" envconfig:"s3"`
+
+	Rclone struct {
+		Remote     string `yaml:"remote"`
+		Binary     string `yaml:"binary"`
+		ConfigFile string `yaml:"config file" split_words:"true"`
+	} `yaml:"rclone" envconfig:"rclone"`
+}
+
+// New returns a configuration instance with all default values.
+func New() *Config {
+	c := new(Config)
+	c.setDefaults()
+	return c
+}
+
+// Load builds an independent configuration instance with the default values,
+// overwritten by the YAML file (when the filename isn't empty) and by the
+// environment variables. Many configurations can be loaded in the same process
+// (e.g. different profiles). On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *config.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func Load(filename string) (*Config, error) {
+	c := New()
+
+	if filename != "" {
+		if err := c.LoadFile(filename); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	if err := c.LoadEnvironment(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return c, nil
+}
+
+// LoadStrict works like Load, but rejects the unknown attributes of the YAML
+// file (e.g. a typo like "keep backup"), that would be silently ignored
+// keeping the default value. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *config.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func LoadStrict(filename string) (*Config, error) {
+	c := New()
+
+	if filename != "" {
+		if err := c.LoadFileStrict(filename); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	if err := c.LoadEnvironment(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return c, nil
+}
+
+// LoadFile parse an YAML file and fill the configuration parameters. On error
+// it will return an Error type encapsulated in a traceable error. To retrieve
+// the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *config.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (c *Config) LoadFile(filename string) error {
+	return errors.WithStack(c.loadFile(filename, yaml.Unmarshal))
+}
+
+// LoadFileStrict works like LoadFile, but rejects the unknown or duplicated
+// attributes of the YAML file. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *config.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (c *Config) LoadFileStrict(filename string) error {
+	return errors.WithStack(c.loadFile(filename, yaml.UnmarshalStrict))
+}
+
+// loadFile parses the YAML file followed by the snippets of the include
+// directory, in the lexical order of their names (e.g. 10-fleet.yml before
+// 20-host.yml). Each snippet overrides only the attributes it informs, and
+// lists are replaced instead of appended. A relative include directory is
+// relative to the directory of the file, and the snippets can't include other
+// directories.
+func (c *Config) loadFile(filename string, unmarshal func([]byte, interface{}) error) error {
+	if err := c.loadSnippet(filename, unmarshal); err != nil {
+		return errors.WithStack(err)
+	}
+
+	origin := c.Include
+	if origin == "" {
+		return nil
+	}
+
+	include := origin
+
+	if !filepath.IsAbs(include) {
+		include = filepath.Join(filepath.Dir(filename), include)
+	}
+
+	files, err := ioutil.ReadDir(include)
+	if err != nil {
+		return errors.WithStack(newError(include, ErrorCodeReadingFile, err))
+	}
+
+	for _, file := range files {
+		extension := filepath.Ext(file.Name())
+		if file.IsDir() || (extension != ".yml" && extension != ".yaml") {
+			continue
+		}
+
+		snippet := filepath.Join(include, file.Name())
+		if err = c.loadSnippet(snippet, unmarshal); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if c.Include != origin {
+			return errors.WithStack(newError(snippet, ErrorCodeNestedInclude, nil))
+		}
+	}
+
+	return nil
+}
+
+// loadSnippet parses a single YAML file over the current configuration.
+func (c *Config) loadSnippet(filename string, unmarshal func([]byte, interface{}) error) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return errors.WithStack(newError(filename, ErrorCodeReadingFile, err))
+	}
+
+	if err = unmarshal(content, c); err != nil {
+		return errors.WithStack(newError(filename, ErrorCodeParsingYAML, err))
+	}
+
+	return nil
+}
+
+// LoadEnvironment analysis all project environment variables, filling the
+// configuration parameters. On error it will return an Error type encapsulated
+// in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *config.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (c *Config) LoadEnvironment() error {
+	if err := envconfig.Process(prefix, c); err != nil {
+		return errors.WithStack(newError("", ErrorCodeReadingEnvVars, err))
+	}
+
+	return nil
+}
+
+// setDefaults defines all default configuration values.
+func (c *Config) setDefaults() {
+	c.KeepBackups = 10
+	c.Cloud = CloudTypeAWS
+	c.MachineID, _ = os.Hostname()
+	c.Scheduler.Backup.Value, _ = cron.Parse("0 0 0 * * *")             // everyday at 00:00:00
+	c.Scheduler.RemoveOldBackups.Value, _ = cron.Parse("0 0 1 * * FRI") // every friday at 01:00:00
+	c.Scheduler.ListRemoteBackups.Value, _ = cron.Parse("0 0 12 1 * *") // every first day of the month at 12:00:00
+	c.Scheduler.SendReport.Value, _ = cron.Parse("0 0 6 * * FRI")       // every friday at 06:00:00
+	c.Scheduler.SendSpooled.Value, _ = cron.Parse("0 0 * * * *")        // every hour
+	c.Scheduler.EmptyTrash.Value, _ = cron.Parse("0 0 * * * *")         // every hour
+	c.Failure.RetryDelay = 10 * time.Minute
+	c.Failure.EscalateAfter = 3
+	c.Failure.LogLines = 50
+	c.RemoveConcurrency = 4
+	c.ChangeRetries = 3
+	c.Archive.Format = "tar"
+	c.Archive.Envelop = "ofb"
+	c.Archive.Normalization = NormalizationNone
+	c.Archive.SpecialFiles = SpecialFilesSkip
+	c.Database.Type = DatabaseTypeBoltDB
+	c.Log.Level = LogLevelError
+	c.Email.Format = EmailFormatHTML
+	c.Email.Locale = EmailLocaleEN
 }
 
 // Current return the actual system configuration, stored internally in a global
 // variable.
+//
+// Deprecated: use Load to build a configuration instance instead.
 func Current() *Config {
 	return (*Config)(atomic.LoadPointer(&config))
 }
 
 // Update modify the current system configuration.
+//
+// Deprecated: use Load to build a configuration instance instead.
 func Update(c *Config) {
 	atomic.StorePointer(&config, unsafe.Pointer(c))
 }
 
-// Default defines all default configuration values.
+// Default defines all default configuration values in the current system
+// configuration.
+//
+// Deprecated: use New to build a configuration instance instead.
 func Default() {
 	c := Current()
 	if c == nil {
 		c = new(Config)
 	}
 
-	c.KeepBackups = 10
-	c.Cloud = CloudTypeAWS
-	c.Scheduler.Backup.Value, _ = cron.Parse("0 0 0 * * *")             // everyday at 00:00:00
-	c.Scheduler.RemoveOldBackups.Value, _ = cron.Parse("0 0 1 * * FRI") // every friday at 01:00:00
-	c.Scheduler.ListRemoteBackups.Value, _ = cron.Parse("0 0 12 1 * *") // every first day of the month at 12:00:00
-	c.Scheduler.SendReport.Value, _ = cron.Parse("0 0 6 * * FRI")       // every friday at 06:00:00
-	c.Database.Type = DatabaseTypeBoltDB
-	c.Database.File = path.Join("var", "log", "toglacier", "toglacier.db")
-	c.Log.Level = LogLevelError
-	c.Email.Format = EmailFormatHTML
-
+	c.setDefaults()
 	Update(c)
 }
 
-// LoadFromFile parse an YAML file and fill the system configuration parameters.
-// On error it will return an Error type encapsulated in a traceable error. To
-// retrieve the desired error you can do:
+// LoadFromFile parse an YAML file and fill the current system configuration
+// parameters. On error it will return an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
 //
 //     type causer interface {
 //       Cause() error
@@ -120,28 +460,25 @@ func Default() {
 //         // unknown error
 //       }
 //     }
+//
+// Deprecated: use Load or Config.LoadFile instead.
 func LoadFromFile(filename string) error {
-	content, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return errors.WithStack(newError(filename, ErrorCodeReadingFile, err))
-	}
-
 	c := Current()
 	if c == nil {
 		c = new(Config)
 	}
 
-	if err = yaml.Unmarshal(content, c); err != nil {
-		return errors.WithStack(newError(filename, ErrorCodeParsingYAML, err))
+	if err := c.LoadFile(filename); err != nil {
+		return errors.WithStack(err)
 	}
 
 	Update(c)
 	return nil
 }
 
-// LoadFromEnvironment analysis all project environment variables. On error it
-// will return an Error type encapsulated in a traceable error. To retrieve the
-// desired error you can do:
+// LoadFromEnvironment analysis all project environment variables, filling the
+// current system configuration. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
 //
 //     type causer interface {
 //       Cause() error
@@ -155,31 +492,55 @@ func LoadFromFile(filename string) error {
 //         // unknown error
 //       }
 //     }
+//
+// Deprecated: use Load or Config.LoadEnvironment instead.
 func LoadFromEnvironment() error {
 	c := Current()
 	if c == nil {
 		c = new(Config)
 	}
 
-	if err := envconfig.Process(prefix, c); err != nil {
-		return errors.WithStack(newError("", ErrorCodeReadingEnvVars, err))
+	if err := c.LoadEnvironment(); err != nil {
+		return errors.WithStack(err)
 	}
 
 	Update(c)
 	return nil
 }
 
+// Fingerprint returns a short hash of the configuration, so the administrator
+// can identify which configuration is running (e.g. in the reports). Only the
+// non-secret attributes are part of the hash, as a short unsalted hash of a
+// weak secret could be reversed by brute force. The secrets only inform if they
+// are defined, and the proxy password is hidden.
+func (c *Config) Fingerprint() string {
+	// all configuration types can be serialized, so it will never fail
+	content, _ := json.Marshal(c)
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:8])
+}
+
 const (
 	// CloudTypeAWS will backup archives to Amazon AWS Glacier cloud service.
 	CloudTypeAWS CloudType = "aws"
 
 	// CloudTypeGCS will backup archives to Google Cloud Storage service.
 	CloudTypeGCS CloudType = "gcs"
+
+	// CloudTypeS3 will backup archives to a service compatible with the Amazon
+	// S3 API (e.g. MinIO, Wasabi, Ceph).
+	CloudTypeS3 CloudType = "s3"
+
+	// CloudTypeRclone will backup archives to any provider supported by rclone,
+	// using a configured remote.
+	CloudTypeRclone CloudType = "rclone"
 )
 
 var cloudTypeValid = map[string]bool{
-	string(CloudTypeAWS): true,
-	string(CloudTypeGCS): true,
+	string(CloudTypeAWS):    true,
+	string(CloudTypeGCS):    true,
+	string(CloudTypeS3):     true,
+	string(CloudTypeRclone): true,
 }
 
 // CloudType defines the cloud service type that will be used to manage
@@ -286,10 +647,53 @@ func (l *LogLevel) UnmarshalText(value []byte) error {
 	return nil
 }
 
+const (
+	// LogModuleArchive identifies the log entries of the archive build and
+	// extraction, including the file scan.
+	LogModuleArchive LogModule = "archive"
+
+	// LogModuleCloud identifies the log entries of the uploads, downloads and
+	// removals in the cloud.
+	LogModuleCloud LogModule = "cloud"
+
+	// LogModuleStorage identifies the log entries of the local storage.
+	LogModuleStorage LogModule = "storage"
+)
+
+var logModuleValid = map[string]bool{
+	string(LogModuleArchive): true,
+	string(LogModuleCloud):   true,
+	string(LogModuleStorage): true,
+}
+
+// LogModule identifies a part of the tool with its own log level, overriding
+// the general log level.
+type LogModule string
+
+// UnmarshalText ensure that the module with its own log level exists.
+func (l *LogModule) UnmarshalText(value []byte) error {
+	logModule := string(value)
+	logModule = strings.TrimSpace(logModule)
+	logModule = strings.ToLower(logModule)
+
+	if ok := logModuleValid[logModule]; !ok {
+		return newError("", ErrorCodeLogModule, nil)
+	}
+
+	*l = LogModule(logModule)
+	return nil
+}
+
 type encrypted struct {
 	Value string
 }
 
+// MarshalJSON only informs if the value is defined, so the secret isn't part
+// of the serialized configuration (e.g. in the fingerprint).
+func (e encrypted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Value != "")
+}
+
 // UnmarshalText automatically decrypts a value from the configuration. On error
 // it will return an Error type encapsulated in a traceable error. To retrieve
 // the desired error you can do:
@@ -391,6 +795,145 @@ func (e *EmailFormat) UnmarshalText(value []byte) error {
 	return nil
 }
 
+const (
+	// EmailLocaleEN reports in English.
+	EmailLocaleEN EmailLocale = "en"
+
+	// EmailLocalePTBR reports in Brazilian Portuguese.
+	EmailLocalePTBR EmailLocale = "pt-BR"
+)
+
+var emailLocaleValid = map[string]EmailLocale{
+	strings.ToLower(string(EmailLocaleEN)):   EmailLocaleEN,
+	strings.ToLower(string(EmailLocalePTBR)): EmailLocalePTBR,
+}
+
+// EmailLocale defines the language of the report e-mails. By default "en" is
+// used.
+type EmailLocale string
+
+// UnmarshalText ensure that the email locale defined in the configuration is
+// known. The locale is case insensitive and also accepts an underscore as
+// separator (e.g. "pt_br").
+func (e *EmailLocale) UnmarshalText(value []byte) error {
+	emailLocale := string(value)
+	emailLocale = strings.TrimSpace(emailLocale)
+	emailLocale = strings.ToLower(emailLocale)
+	emailLocale = strings.Replace(emailLocale, "_", "-", -1)
+
+	locale, ok := emailLocaleValid[emailLocale]
+	if !ok {
+		return newError("", ErrorCodeEmailLocale, nil)
+	}
+
+	*e = locale
+	return nil
+}
+
+const (
+	// IOClassBestEffort shares the disk with the other processes, using the I/O
+	// level to define the priority.
+	IOClassBestEffort IOClass = "best-effort"
+
+	// IOClassIdle only uses the disk when no other process needs it.
+	IOClassIdle IOClass = "idle"
+)
+
+var ioClassValid = map[string]bool{
+	string(IOClassBestEffort): true,
+	string(IOClassIdle):       true,
+}
+
+// IOClass defines the I/O scheduling class used while building the archives.
+// By default the I/O priority is not changed.
+type IOClass string
+
+// UnmarshalText ensure that the I/O class defined in the configuration is
+// valid.
+func (i *IOClass) UnmarshalText(value []byte) error {
+	ioClass := string(value)
+	ioClass = strings.TrimSpace(ioClass)
+	ioClass = strings.ToLower(ioClass)
+
+	if ok := ioClassValid[ioClass]; !ok {
+		return newError("", ErrorCodeIOClass, nil)
+	}
+
+	*i = IOClass(ioClass)
+	return nil
+}
+
+const (
+	// NormalizationNone keeps the names of the extracted files.
+	NormalizationNone Normalization = "none"
+
+	// NormalizationNFC extracts the files with composed names, the form used by
+	// Linux and Windows.
+	NormalizationNFC Normalization = "nfc"
+
+	// NormalizationNFD extracts the files with decomposed names, the form used
+	// by macOS.
+	NormalizationNFD Normalization = "nfd"
+)
+
+var normalizationValid = map[string]bool{
+	string(NormalizationNone): true,
+	string(NormalizationNFC):  true,
+	string(NormalizationNFD):  true,
+}
+
+// Normalization defines the Unicode normalization form of the names of the
+// extracted files. By default "nfc" is used.
+type Normalization string
+
+// UnmarshalText ensure that the Unicode normalization form defined in the
+// configuration is valid.
+func (n *Normalization) UnmarshalText(value []byte) error {
+	normalization := string(value)
+	normalization = strings.TrimSpace(normalization)
+	normalization = strings.ToLower(normalization)
+
+	if ok := normalizationValid[normalization]; !ok {
+		return newError("", ErrorCodeNormalization, nil)
+	}
+
+	*n = Normalization(normalization)
+	return nil
+}
+
+const (
+	// SpecialFilesSkip leaves the device files, sockets and FIFOs out of the
+	// backups.
+	SpecialFilesSkip SpecialFiles = "skip"
+
+	// SpecialFilesArchive adds the device files and FIFOs to the tar backups.
+	SpecialFilesArchive SpecialFiles = "archive"
+)
+
+var specialFilesValid = map[string]bool{
+	string(SpecialFilesSkip):    true,
+	string(SpecialFilesArchive): true,
+}
+
+// SpecialFiles defines how the device files, sockets and FIFOs found in the
+// backup paths are handled. By default "skip" is used.
+type SpecialFiles string
+
+// UnmarshalText ensure that the special files policy defined in the
+// configuration is valid.
+func (s *SpecialFiles) UnmarshalText(value []byte) error {
+	specialFiles := string(value)
+	specialFiles = strings.TrimSpace(specialFiles)
+	specialFiles = strings.ToLower(specialFiles)
+
+	if ok := specialFilesValid[specialFiles]; !ok {
+		return newError("", ErrorCodeSpecialFiles, nil)
+	}
+
+	*s = SpecialFiles(specialFiles)
+	return nil
+}
+
 // Percentage stores a valid percentage value.
 type Percentage float64
 
@@ -414,6 +957,78 @@ func (p *Percentage) UnmarshalText(value []byte) error {
 	return nil
 }
 
+// Duration stores a period of time. Besides the Go duration format (e.g.
+// "36h"), long periods can be informed in days (e.g. "180d").
+type Duration time.Duration
+
+// UnmarshalText verifies if the duration has a valid format.
+func (d *Duration) UnmarshalText(value []byte) error {
+	duration := string(value)
+	duration = strings.TrimSpace(duration)
+	duration = strings.ToLower(duration)
+
+	if days := strings.TrimSuffix(duration, "d"); days != duration {
+		number, err := strconv.Atoi(days)
+		if err != nil {
+			return newError("", ErrorCodeDurationFormat, err)
+		}
+
+		*d = Duration(time.Duration(number) * 24 * time.Hour)
+		return nil
+	}
+
+	number, err := time.ParseDuration(duration)
+	if err != nil {
+		return newError("", ErrorCodeDurationFormat, err)
+	}
+
+	*d = Duration(number)
+	return nil
+}
+
+// sizeUnits converts the units of a size to bytes, using multiples of 1024.
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
+	"tb":  1 << 40,
+	"tib": 1 << 40,
+}
+
+// Size stores an amount of data in bytes. A unit can be informed after the
+// number (e.g. "50GB"), otherwise the number is in bytes.
+type Size int64
+
+// UnmarshalText verifies if the size is a valid number with a known unit.
+func (s *Size) UnmarshalText(value []byte) error {
+	size := string(value)
+	size = strings.TrimSpace(size)
+	size = strings.ToLower(size)
+
+	number := strings.TrimRightFunc(size, unicode.IsLetter)
+	unit, ok := sizeUnits[strings.TrimPrefix(size, number)]
+	if !ok {
+		return newError("", ErrorCodeSizeFormat, nil)
+	}
+
+	bytes, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil {
+		return newError("", ErrorCodeSizeFormat, err)
+	}
+
+	if bytes < 0 {
+		return newError("", ErrorCodeSizeFormat, nil)
+	}
+
+	*s = Size(bytes * float64(unit))
+	return nil
+}
+
 // Pattern stores a valid regular expression.
 type Pattern struct {
 	Value *regexp.Regexp
@@ -433,6 +1048,338 @@ func (p *Pattern) UnmarshalText(value []byte) error {
 	return nil
 }
 
+// MarshalText returns the regular expression, as the compiled one can't be
+// serialized.
+func (p Pattern) MarshalText() ([]byte, error) {
+	if p.Value == nil {
+		return nil, nil
+	}
+
+	return []byte(p.Value.String()), nil
+}
+
+// Route sends the backups of the paths under the prefix to another vault (or
+// bucket), optionally in another region. The e-mail settings of the backup
+// reports can be overridden for the route, only in the configuration file.
+type Route struct {
+	Prefix    string     `yaml:"prefix"`
+	VaultName string     `yaml:"vault name"`
+	Region    string     `yaml:"region"`
+	Email     RouteEmail `yaml:"email"`
+}
+
+// RouteEmail overrides the e-mail settings for the backup reports of a route.
+// When the recipients or the format are empty the global e-mail settings are
+// used.
+type RouteEmail struct {
+	To         []string    `yaml:"to"`
+	Format     EmailFormat `yaml:"format"`
+	ErrorsOnly bool        `yaml:"errors only"`
+}
+
+// Defined informs if any e-mail setting was overridden.
+func (r RouteEmail) Defined() bool {
+	return len(r.To) > 0 || r.Format != "" || r.ErrorsOnly
+}
+
+// UnmarshalText parses the route in the format used by environment variables:
+// <prefix>=<vault name>[@<region>].
+func (r *Route) UnmarshalText(value []byte) error {
+	route := string(value)
+	route = strings.TrimSpace(route)
+
+	separator := strings.LastIndex(route, "=")
+	if separator <= 0 || separator == len(route)-1 {
+		return newError("", ErrorCodeRouteFormat, nil)
+	}
+
+	r.Prefix = route[:separator]
+	r.VaultName = route[separator+1:]
+
+	if separator = strings.Index(r.VaultName, "@"); separator >= 0 {
+		r.Region = r.VaultName[separator+1:]
+		r.VaultName = r.VaultName[:separator]
+	}
+
+	if r.VaultName == "" {
+		return newError("", ErrorCodeRouteFormat, nil)
+	}
+
+	return nil
+}
+
+// Source is a command whose standard output is added to the backup with the
+// given name (e.g. a database dump), or a Docker volume stored as a tarball
+// with the include and exclude filters. When the timeout is zero the source can
+// run for any time.
+type Source struct {
+	Name         string   `yaml:"name"`
+	Command      string   `yaml:"command"`
+	DockerVolume string   `yaml:"docker volume"`
+	Include      []string `yaml:"include"`
+	Exclude      []string `yaml:"exclude"`
+	Timeout      Duration `yaml:"timeout"`
+}
+
+// UnmarshalText parses the source in the format used by environment
+// variables: <name>[@<timeout>]=<command>.
+func (s *Source) UnmarshalText(value []byte) error {
+	source := string(value)
+	source = strings.TrimSpace(source)
+
+	separator := strings.Index(source, "=")
+	if separator <= 0 || separator == len(source)-1 {
+		return newError("", ErrorCodeSourceFormat, nil)
+	}
+
+	s.Name = source[:separator]
+	s.Command = strings.TrimSpace(source[separator+1:])
+
+	if separator = strings.Index(s.Name, "@"); separator >= 0 {
+		if err := s.Timeout.UnmarshalText([]byte(s.Name[separator+1:])); err != nil {
+			return newError("", ErrorCodeSourceFormat, err)
+		}
+		s.Name = s.Name[:separator]
+	}
+
+	if s.Name == "" || s.Command == "" {
+		return newError("", ErrorCodeSourceFormat, nil)
+	}
+
+	return nil
+}
+
+// Blackout is a period when the scheduled actions are deferred (e.g. business
+// hours or end-of-month processing). The window opens on each activation of
+// the start scheduler and stays open for the duration.
+type Blackout struct {
+	Start    Scheduler `yaml:"start"`
+	Duration Duration  `yaml:"duration"`
+}
+
+// UnmarshalText parses the blackout in the format used by environment
+// variables: <start scheduler>@<duration>.
+func (b *Blackout) UnmarshalText(value []byte) error {
+	blackout := string(value)
+	blackout = strings.TrimSpace(blackout)
+
+	separator := strings.Index(blackout, "@")
+	if separator <= 0 || separator == len(blackout)-1 {
+		return newError("", ErrorCodeBlackoutFormat, nil)
+	}
+
+	if err := b.Start.UnmarshalText([]byte(blackout[:separator])); err != nil {
+		return newError("", ErrorCodeBlackoutFormat, err)
+	}
+
+	if err := b.Duration.UnmarshalText([]byte(blackout[separator+1:])); err != nil {
+		return newError("", ErrorCodeBlackoutFormat, err)
+	}
+
+	return nil
+}
+
+// Until returns when the blackout window open at the given moment closes. When
+// the window isn't open ok is false.
+func (b Blackout) Until(now time.Time) (until time.Time, ok bool) {
+	duration := time.Duration(b.Duration)
+	if b.Start.Value == nil || duration <= 0 {
+		return time.Time{}, false
+	}
+
+	// the last activation before the given moment defines when the window
+	// closes, as windows of consecutive activations can overlap
+	start := b.Start.Value.Next(now.Add(-duration))
+	if start.IsZero() || start.After(now) {
+		return time.Time{}, false
+	}
+
+	for next := b.Start.Value.Next(start); !next.IsZero() && !next.After(now); next = b.Start.Value.Next(next) {
+		start = next
+	}
+
+	return start.Add(duration), true
+}
+
+const (
+	// ScopeList allows listing and checking the backups.
+	ScopeList Scope = "list"
+
+	// ScopeRestore allows retrieving and mounting the backups.
+	ScopeRestore Scope = "restore"
+
+	// ScopeBackup allows sending new backups.
+	ScopeBackup Scope = "backup"
+
+	// ScopeRemove allows removing backups and restoring them from the trash.
+	ScopeRemove Scope = "remove"
+
+	// ScopeAdmin allows all commands, including the vault and the local storage
+	// management.
+	ScopeAdmin Scope = "admin"
+)
+
+var scopeValid = map[string]bool{
+	string(ScopeList):    true,
+	string(ScopeRestore): true,
+	string(ScopeBackup):  true,
+	string(ScopeRemove):  true,
+	string(ScopeAdmin):   true,
+}
+
+// Scope is a group of commands that can be run with a token.
+type Scope string
+
+// UnmarshalText ensure that the scope exists.
+func (s *Scope) UnmarshalText(value []byte) error {
+	scope := string(value)
+	scope = strings.TrimSpace(scope)
+	scope = strings.ToLower(scope)
+
+	if ok := scopeValid[scope]; !ok {
+		return newError("", ErrorCodeScope, nil)
+	}
+
+	*s = Scope(scope)
+	return nil
+}
+
+// Token grants the commands of its scopes to whoever informs it, restricting
+// the commands on shared machines. Only the SHA-256 hash of the token (hex
+// encoded) is stored, so the token can't be recovered from the
+// configuration.
+type Token struct {
+	Name   string  `yaml:"name"`
+	Hash   string  `yaml:"hash"`
+	Scopes []Scope `yaml:"scopes"`
+}
+
+// UnmarshalText parses the token in the format used by environment variables:
+// <name>@<scope>[+<scope>...]=<hash>.
+func (t *Token) UnmarshalText(value []byte) error {
+	token := string(value)
+	token = strings.TrimSpace(token)
+
+	separator := strings.Index(token, "=")
+	if separator <= 0 || separator == len(token)-1 {
+		return newError("", ErrorCodeTokenFormat, nil)
+	}
+
+	t.Name = token[:separator]
+	t.Hash = strings.ToLower(strings.TrimSpace(token[separator+1:]))
+
+	separator = strings.Index(t.Name, "@")
+	if separator <= 0 || separator == len(t.Name)-1 {
+		return newError("", ErrorCodeTokenFormat, nil)
+	}
+
+	scopes := strings.Split(t.Name[separator+1:], "+")
+	t.Name = t.Name[:separator]
+	t.Scopes = make([]Scope, len(scopes))
+
+	for i, scope := range scopes {
+		if err := t.Scopes[i].UnmarshalText([]byte(scope)); err != nil {
+			return newError("", ErrorCodeTokenFormat, err)
+		}
+	}
+
+	return nil
+}
+
+// Allows checks if the token grants the scope. The admin scope grants all
+// scopes.
+func (t Token) Allows(scope Scope) bool {
+	for _, tokenScope := range t.Scopes {
+		if tokenScope == scope || tokenScope == ScopeAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TokenHash returns the hash of the token stored in the configuration.
+func TokenHash(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// FindToken returns the configured token with the hash of the informed token.
+// When there's no such token ok is false.
+func (c Config) FindToken(token string) (Token, bool) {
+	if token == "" {
+		return Token{}, false
+	}
+
+	hash := TokenHash(token)
+	for _, configToken := range c.Tokens {
+		if subtle.ConstantTimeCompare([]byte(strings.ToLower(configToken.Hash)), []byte(hash)) == 1 {
+			return configToken, true
+		}
+	}
+
+	return Token{}, false
+}
+
+// ProxyURL stores a valid proxy address, used to reach the cloud and the SMTP
+// server. The supported schemes are http, https, socks5 and socks5h.
+type ProxyURL struct {
+	Value *url.URL
+}
+
+// MarshalJSON hides the password of the proxy address, so it isn't part of the
+// serialized configuration (e.g. in the fingerprint).
+func (p ProxyURL) MarshalJSON() ([]byte, error) {
+	if p.Value == nil {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(p.Value.Redacted())
+}
+
+// UnmarshalText parses the proxy address, that can be encrypted as it could
+// contain credentials. On error it will return an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *config.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (p *ProxyURL) UnmarshalText(value []byte) error {
+	var proxy encrypted
+	if err := proxy.UnmarshalText(value); err != nil {
+		return errors.WithStack(err)
+	}
+
+	proxy.Value = strings.TrimSpace(proxy.Value)
+	if proxy.Value == "" {
+		p.Value = nil
+		return nil
+	}
+
+	proxyURL, err := url.Parse(proxy.Value)
+	if err != nil {
+		return errors.WithStack(newError("", ErrorCodeProxy, err))
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return errors.WithStack(newError("", ErrorCodeProxy, nil))
+	}
+
+	p.Value = proxyURL
+	return nil
+}
+
 // Scheduler stores the periodicity of an action.
 type Scheduler struct {
 	Value cron.Schedule
@@ -458,3 +1405,50 @@ func (s *Scheduler) UnmarshalText(value []byte) error {
 
 	return nil
 }
+
+// Timezone stores the location used to evaluate the schedulers and the
+// blackout windows, so machines in different regions can share the same
+// wall-clock schedule.
+type Timezone struct {
+	Value *time.Location
+}
+
+// UnmarshalText loads the location from the IANA time zone database (e.g.
+// America/Sao_Paulo). An empty value keeps the local time of the host.
+func (t *Timezone) UnmarshalText(value []byte) error {
+	timezone := string(value)
+	timezone = strings.TrimSpace(timezone)
+
+	if timezone == "" {
+		t.Value = nil
+		return nil
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return newError("", ErrorCodeTimezone, err)
+	}
+
+	t.Value = location
+	return nil
+}
+
+// MarshalText returns the name of the location, as the loaded one can't be
+// serialized.
+func (t Timezone) MarshalText() ([]byte, error) {
+	if t.Value == nil {
+		return nil, nil
+	}
+
+	return []byte(t.Value.String()), nil
+}
+
+// Location returns the configured location, or the local time of the host
+// when there's none.
+func (t Timezone) Location() *time.Location {
+	if t.Value == nil {
+		return time.Local
+	}
+
+	return t.Value
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// legacyDatabaseFile is the database location used before the per-user data
+// directory, kept while the file exists so older installations keep their
+// local storage.
+var legacyDatabaseFile = filepath.Join(string(filepath.Separator), "var", "log", "toglacier", "toglacier.db")
+
+// DataDir returns the directory of the tool data (local storage, journal,
+// control files, log and temporary archives) for the current user, following
+// the conventions of the operating system (e.g. $XDG_DATA_HOME/toglacier on
+// Linux). When the home directory of the user can't be found (e.g. services
+// without a home), the system data directory is used (e.g. /var/lib/toglacier
+// on Linux).
+func DataDir() string {
+	if dir := userDataDir(); dir != "" {
+		return dir
+	}
+
+	return systemDataDir()
+}
+
+// DataDirectory returns the configured data directory, or the per-user data
+// directory when it isn't configured.
+func (c Config) DataDirectory() string {
+	if c.DataDir != "" {
+		return c.DataDir
+	}
+
+	return DataDir()
+}
+
+// DatabaseFile returns the local storage file. Without an explicit file, it is
+// stored in the configured data directory or in the per-user data directory.
+// Installations with the database in the old default location keep using it.
+func (c Config) DatabaseFile() string {
+	if c.Database.File != "" {
+		return c.Database.File
+	}
+
+	if c.DataDir == "" {
+		if _, err := os.Stat(legacyDatabaseFile); err == nil {
+			return legacyDatabaseFile
+		}
+	}
+
+	return filepath.Join(c.DataDirectory(), "toglacier.db")
+}
+
+// LogFile returns the log file. Without an explicit file, it is stored in the
+// data directory.
+func (c Config) LogFile() string {
+	if c.Log.File != "" {
+		return c.Log.File
+	}
+
+	return filepath.Join(c.DataDirectory(), "toglacier.log")
+}
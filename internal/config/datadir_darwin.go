@@ -0,0 +1,23 @@
+// +build darwin
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// userDataDir uses the application support directory of the user.
+func userDataDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, "Library", "Application Support", "toglacier")
+}
+
+// systemDataDir uses the application support directory shared by all users.
+func systemDataDir() string {
+	return filepath.Join(string(filepath.Separator), "Library", "Application Support", "toglacier")
+}
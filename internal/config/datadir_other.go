@@ -0,0 +1,29 @@
+// +build !windows,!darwin
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// userDataDir follows the XDG base directory specification, used by most
+// Linux and BSD distributions.
+func userDataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "toglacier")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".local", "share", "toglacier")
+}
+
+// systemDataDir follows the filesystem hierarchy standard for the state of the
+// services.
+func systemDataDir() string {
+	return filepath.Join(string(filepath.Separator), "var", "lib", "toglacier")
+}
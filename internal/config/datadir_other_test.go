@@ -0,0 +1,73 @@
+// +build !windows,!darwin
+
+package config_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier/internal/config"
+)
+
+func TestDataDir(t *testing.T) {
+	scenarios := []struct {
+		description string
+		env         map[string]string
+		expected    string
+	}{
+		{
+			description: "it should use the XDG data directory",
+			env: map[string]string{
+				"HOME":          "/home/alice",
+				"XDG_DATA_HOME": "/srv/data",
+			},
+			expected: "/srv/data/toglacier",
+		},
+		{
+			description: "it should use the default XDG data directory in the home",
+			env: map[string]string{
+				"HOME": "/home/alice",
+			},
+			expected: "/home/alice/.local/share/toglacier",
+		},
+		{
+			description: "it should ignore a relative XDG data directory",
+			env: map[string]string{
+				"HOME":          "/home/alice",
+				"XDG_DATA_HOME": "data",
+			},
+			expected: "/home/alice/.local/share/toglacier",
+		},
+		{
+			description: "it should use the system data directory without a home",
+			expected:    "/var/lib/toglacier",
+		},
+	}
+
+	originalEnv := map[string]string{
+		"HOME":          os.Getenv("HOME"),
+		"XDG_DATA_HOME": os.Getenv("XDG_DATA_HOME"),
+	}
+
+	defer func() {
+		for key, value := range originalEnv {
+			os.Setenv(key, value)
+		}
+	}()
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			for key := range originalEnv {
+				os.Unsetenv(key)
+			}
+
+			for key, value := range scenario.env {
+				os.Setenv(key, value)
+			}
+
+			if dir := config.DataDir(); dir != scenario.expected {
+				t.Errorf("data directories don't match. expected “%s” and got “%s”", scenario.expected, dir)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_DatabaseFile(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		config         func() *Config
+		legacy         bool
+		expectedFile   string
+		expectedLegacy bool
+	}{
+		{
+			description: "it should keep the configured file",
+			config: func() *Config {
+				c := New()
+				c.Database.File = "/var/lib/toglacier/audit.log"
+				c.DataDir = "/srv/toglacier"
+				return c
+			},
+			expectedFile: "/var/lib/toglacier/audit.log",
+		},
+		{
+			description: "it should store the file in the configured data directory",
+			config: func() *Config {
+				c := New()
+				c.DataDir = "/srv/toglacier"
+				return c
+			},
+			legacy:       true,
+			expectedFile: filepath.Join("/srv/toglacier", "toglacier.db"),
+		},
+		{
+			description: "it should store the file in the data directory of the user",
+			config: func() *Config {
+				return New()
+			},
+			expectedFile: filepath.Join(DataDir(), "toglacier.db"),
+		},
+		{
+			description: "it should keep the file of the old default location",
+			config: func() *Config {
+				return New()
+			},
+			legacy:         true,
+			expectedLegacy: true,
+		},
+	}
+
+	defer func(file string) {
+		legacyDatabaseFile = file
+	}(legacyDatabaseFile)
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			// the old default location is replaced by a file of the test
+			legacyDatabaseFile = filepath.Join(dir, "var", "log", "toglacier", "toglacier.db")
+
+			if scenario.legacy {
+				if err = os.MkdirAll(filepath.Dir(legacyDatabaseFile), 0700); err != nil {
+					t.Fatalf("error creating the old default location. details: %s", err)
+				}
+
+				if err = ioutil.WriteFile(legacyDatabaseFile, nil, 0600); err != nil {
+					t.Fatalf("error creating the old database. details: %s", err)
+				}
+			}
+
+			if scenario.expectedLegacy {
+				scenario.expectedFile = legacyDatabaseFile
+			}
+
+			if file := scenario.config().DatabaseFile(); file != scenario.expectedFile {
+				t.Errorf("database files don't match. expected “%s” and got “%s”", scenario.expectedFile, file)
+			}
+		})
+	}
+}
+
+func TestConfig_LogFile(t *testing.T) {
+	scenarios := []struct {
+		description  string
+		config       func() *Config
+		expectedFile string
+	}{
+		{
+			description: "it should keep the configured file",
+			config: func() *Config {
+				c := New()
+				c.Log.File = "/var/log/toglacier/toglacier.log"
+				c.DataDir = "/srv/toglacier"
+				return c
+			},
+			expectedFile: "/var/log/toglacier/toglacier.log",
+		},
+		{
+			description: "it should store the file in the configured data directory",
+			config: func() *Config {
+				c := New()
+				c.DataDir = "/srv/toglacier"
+				return c
+			},
+			expectedFile: filepath.Join("/srv/toglacier", "toglacier.log"),
+		},
+		{
+			description: "it should store the file in the data directory of the user",
+			config: func() *Config {
+				return New()
+			},
+			expectedFile: filepath.Join(DataDir(), "toglacier.log"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if file := scenario.config().LogFile(); file != scenario.expectedFile {
+				t.Errorf("log files don't match. expected “%s” and got “%s”", scenario.expectedFile, file)
+			}
+		})
+	}
+}
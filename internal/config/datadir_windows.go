@@ -0,0 +1,27 @@
+// +build windows
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// userDataDir uses the local application data directory of the user, as the
+// local storage shouldn't roam between machines.
+func userDataDir() string {
+	if dir := os.Getenv("LocalAppData"); dir != "" {
+		return filepath.Join(dir, "toglacier")
+	}
+
+	return ""
+}
+
+// systemDataDir uses the application data directory shared by all users.
+func systemDataDir() string {
+	if dir := os.Getenv("ProgramData"); dir != "" {
+		return filepath.Join(dir, "toglacier")
+	}
+
+	return filepath.Join(`C:\ProgramData`, "toglacier")
+}
@@ -0,0 +1,144 @@
+package config
+
+// descriptions explains the attributes of the configuration file, identified
+// by their path (e.g. "scheduler.backup"). The attributes of the items of a
+// list are identified by the list name (e.g. "routes.prefix").
+var descriptions = map[string]string{
+	"paths":                             "paths is the list of all locations that you want to backup. It could be a directory or a specific file.",
+	"keep backups":                      "keep backups defines the number of recent backups to preserve (by creation date). By default we will keep the last 10 backups.",
+	"group by day":                      "group by day treats all backups created on the same calendar day (in the scheduler timezone) as a single backup when preserving the recent backups, so ad-hoc manual runs don't take the place of the daily backups. By default each backup is counted.",
+	"backup secret":                     "backup secret is an optional passphrase used to encrypt (OFB) and sign (HMAC256) the backups. It can be encrypted with the \"toglacier encrypt\" command.",
+	"modify tolerance":                  "modify tolerance defines the percentage of modified files that can be tolerated between two backups, to detect ransomware infections. Values 0% or 100% disables this check. By default is 0%.",
+	"confirm above":                     "confirm above defines the archive size that requires an approval before the upload (e.g. 50GB), avoiding surprise uploads on metered links.",
+	"max unreadable":                    "max unreadable is the maximum number of files that can't be read (e.g. permission denied or removed during the backup) left out of each backup. By default the backup fails on the first unreadable file.",
+	"snapshot":                          "snapshot copies each file to a temporary file before it is added to the archive, so a file that changes while it is read (e.g. a log being written) can be read again.",
+	"change retries":                    "change retries is the number of times a file that changes while it is copied to the temporary file is read again, when the snapshot is enabled. By default is 3.",
+	"rebase after":                      "rebase after defines the age of an archive after which its unmodified files are sent again in the next backup, so old archives stop being referenced and can be removed. By default the files are never sent again.",
+	"full backup every":                 "full backup every defines the maximum age of the last full backup (a backup with all files). By default only the first backup is a full backup.",
+	"backup max duration":               "backup max duration limits the time spent analyzing the files of each backup, so a backup doesn't run into the next business day. By default the backups run until all files are analyzed.",
+	"trash period":                      "trash period defines how long the removed backups stay in the trash before they are removed from the cloud, so the removal can be undone with the restore-trash command. By default the backups are removed immediately.",
+	"ignore patterns":                   "ignore patterns removes from the backup the files that match one or more patterns of this list (e.g. temporary or lock files).",
+	"cloud":                             "cloud determinates the cloud service will be used to manage the backups. The possible values are aws, gcs, s3 or rclone. By default aws will be used.",
+	"machine id":                        "machine id identifies this machine when many machines share the same vault or bucket. By default the hostname is used.",
+	"routes":                            "routes send the backups of the paths under a prefix to another vault (or bucket), optionally in another region (only for aws).",
+	"routes.prefix":                     "prefix of the backup paths sent to the vault (or bucket) of the route.",
+	"routes.vault name":                 "vault name is the vault (or bucket) that stores the backups of the route.",
+	"routes.region":                     "region of the vault of the route (only for aws). By default the region of the cloud is used.",
+	"routes.email":                      "email overrides the e-mail settings of the backup reports of the route.",
+	"routes.email.to":                   "to defines the e-mail addresses that receive the reports of the route.",
+	"routes.email.format":               "format overrides the e-mail content type of the route reports (plain or html).",
+	"routes.email.errors only":          "errors only sends the reports of the route only when there are failures.",
+	"sources":                           "sources are commands whose standard output is added to the backup with the given name, like database dumps.",
+	"sources.name":                      "name of the file added to the backup with the output of the source.",
+	"sources.command":                   "command executed by the system shell whose standard output is added to the backup.",
+	"sources.docker volume":             "docker volume is a Docker volume stored as a tarball (the name defaults to <volume>.tar), read from a temporary container where the volume is mounted read-only.",
+	"sources.include":                   "include are the paths (relative to the volume root) of the Docker volume added to the source.",
+	"sources.exclude":                   "exclude are patterns of the files of the Docker volume left out of the source.",
+	"sources.timeout":                   "timeout limits the time spent by the source. By default there's no limit.",
+	"docker image":                      "docker image of the temporary container used to read the Docker volumes, it must have a tar command. By default alpine (pinned by digest) is used.",
+	"concurrency":                       "concurrency is the maximum number of routes backed up at the same time. By default the routes are backed up one after another.",
+	"remove concurrency":                "remove concurrency is the maximum number of old backups removed from the cloud at the same time. By default 4 backups are removed at the same time.",
+	"max cpus":                          "max cpus limits the number of CPU cores used at the same time by hashing, compression and encryption, so the backups don't starve the other workloads of the server. By default all cores are used.",
+	"watch":                             "watch detects the files modified in the backup paths while the scheduler is running, so only these files are read in the next backup, instead of calculating the checksum of all files. By default all files are analyzed.",
+	"read only":                         "read only disables the commands that change the backups in the cloud (e.g. sync, remove, gc --remove), for machines that only browse and restore the backups.",
+	"proxy":                             "proxy used to reach AWS Glacier and the SMTP server, when the host can't access the internet directly.",
+	"blackouts":                         "blackouts are periods when the scheduled actions are deferred, running when the window closes (e.g. business hours or end-of-month processing). By default the actions are never deferred.",
+	"blackouts.start":                   "start is the scheduler (same format of the scheduler section) that opens the window.",
+	"blackouts.duration":                "duration is the amount of time the window stays open.",
+	"tokens":                            "tokens restrict the commands on machines shared by many operators. The scopes of the commands are only advisory, and they are enforced only in the webhook. By default all commands are allowed.",
+	"tokens.name":                       "name identifies the token in the logs.",
+	"tokens.hash":                       "hash is the SHA-256 hash of the token, generated with the token command.",
+	"tokens.scopes":                     "scopes are the commands granted by the token: list, restore, backup, remove or admin (all commands).",
+	"include":                           "include is a directory of snippets (.yml or .yaml files) merged over this file in the order of their names, so the defaults of many hosts can be shipped in this file while each host adds its own attributes.",
+	"data dir":                          "data dir stores the local storage and the log when their files aren't informed, the temporary archives and the outputs of the sources. By default the data directory of the user is used.",
+	"scheduler":                         "scheduler defines the periodicity of actions performed by the tool. Each action is a cron expression composed by 6 space-separated fields: seconds, minutes, hours, day of month, month and day of week.",
+	"scheduler.backup":                  "backup synchronize the backups paths with the cloud. By default it runs everyday at 00:00:00.",
+	"scheduler.remove old backups":      "remove old backups guarantees that only recent most recente backups (parameter keep backups) will be preserved. By default it runs every friday at 01:00:00.",
+	"scheduler.list remote backups":     "list remote backups synchronize the remote inventory with the local storage information. By default it runs on the first day of the month at 12:00:00.",
+	"scheduler.send report":             "send report keeps the administrator informed by the tool events via e-mail. By default it runs every friday at 06:00:00.",
+	"scheduler.send spooled":            "send spooled retries the upload of the archives kept in the spool. By default it runs every hour.",
+	"scheduler.empty trash":             "empty trash removes from the cloud the backups that are in the trash for longer than the trash period. By default it runs every hour.",
+	"scheduler.timezone":                "timezone of the schedulers and the blackout windows, as a name of the IANA time zone database (e.g. America/Sao_Paulo or UTC). By default the local time of the host is used.",
+	"failure":                           "failure defines how the scheduled backup reacts to errors.",
+	"failure.retry delay":               "retry delay is the amount of time to wait before retrying a failed backup. By default it waits 10 minutes.",
+	"failure.escalate after":            "escalate after is the number of consecutive failures that will send an alert e-mail. By default the alert is sent after 3 failures.",
+	"failure.log lines":                 "log lines is the number of log lines of the failed backup attached to the alert e-mail, so the problem can be diagnosed without accessing the log file. By default the last 50 lines are attached.",
+	"replica":                           "replica receives a copy of each backup in another vault (or bucket), optionally in another region (only for aws), using the same credentials. By default no copy is sent.",
+	"replica.vault name":                "vault name is the vault (or bucket) that receives the copies.",
+	"replica.region":                    "region of the vault of the copies (only for aws). By default the region of the cloud is used.",
+	"spool":                             "spool keeps the built (and encrypted) archives that couldn't be sent to the cloud (network or provider outage) in a local directory, instead of failing the backup. By default no archive is spooled.",
+	"spool.dir":                         "dir is the local directory that keeps the archives.",
+	"spool.max size":                    "max size limits the bytes of all spooled archives. By default unlimited.",
+	"priority":                          "priority lowers the CPU (nice, 1 - 19) and I/O (io class best-effort with io level 0 - 7, or idle) priority of the process when the first archive is built, so the backup scan doesn't affect latency-sensitive services. The priority isn't raised back after the build, so the rest of the process (e.g. the scheduler) keeps it. By default the priority is not changed.",
+	"priority.nice":                     "nice is the CPU priority (1 - 19) of the process from the first archive build on.",
+	"priority.io class":                 "io class is the I/O scheduling class (best-effort or idle) of the process from the first archive build on.",
+	"priority.io level":                 "io level is the I/O priority (0 - 7) of the best-effort class.",
+	"archive":                           "archive defines how the backup files are packed and encrypted.",
+	"archive.format":                    "format of the backup archive. The possible values are tar, tar+gzip (tar compressed with gzip) or zip (easier to open on Windows). By default tar is used.",
+	"archive.envelop":                   "envelop defines the algorithm used to encrypt the archive when a backup secret is informed. By default ofb is used.",
+	"archive.redundancy":                "redundancy is the percentage of parity data (Reed-Solomon) sent with each backup, used to repair an archive that was corrupted in the cloud or during the download. By default no parity data is sent.",
+	"archive.normalization":             "normalization is the Unicode normalization form of the names of the files extracted when retrieving a backup. The possible values are nfc (composed names, used by Linux and Windows), nfd (decomposed names, used by macOS) or none to keep the names as they were stored. By default the names are kept (none).",
+	"archive.special files":             "special files defines how the device files, sockets and FIFOs found in the backup paths (e.g. in /var) are handled. The possible values are skip (left out of the backup with a warning) or archive (stored as tar device entries and created again when retrieved on Linux). By default skip is used.",
+	"archive.allow unencrypted":         "allow unencrypted restores the archives whose header claims they aren't encrypted even when the backup secret is informed, for backups sent before the secret was configured. As the header isn't authenticated, a replaced archive isn't detected. By default those archives are refused.",
+	"database":                          "database contains information about the local storage.",
+	"database.type":                     "type defines the format of the local storage. The possible values are auditfile and boltdb. By default boltdb is used.",
+	"database.file":                     "file stores the location of the database file. By default \"toglacier.db\" in the data directory is used.",
+	"database.no sync":                  "no sync skips syncing the BoltDB changes to the disk. By default all changes are synced.",
+	"database.alloc size":               "alloc size is the amount of bytes allocated each time the BoltDB file grows. By default 16MB are used.",
+	"database.stateless":                "stateless loads the local storage from the cloud before each command and saves it back after it, so toglacier can run without a persistent disk. Only supported by the gcs, s3 and rclone clouds. By default the local storage is kept only in the file.",
+	"log":                               "log contains information about the messages generated by the tool and library.",
+	"log.file":                          "file stores the location of the log file. By default \"toglacier.log\" in the data directory is used.",
+	"log.level":                         "level defines the verbosity of the messages in the log file. The possible values are debug, info, warning, error, fatal or panic. By default error is used.",
+	"log.levels":                        "levels overrides the verbosity of some modules, e.g. to debug slow uploads without the messages of the files scan.",
+	"email":                             "email contains all data necessary to send an e-mail for periodic reports.",
+	"email.server":                      "server defines the e-mail server address without port.",
+	"email.port":                        "port is the e-mail server port, usually 587 for SMTP communication.",
+	"email.username":                    "username is used for authenticating with the e-mail server before sending the e-mail.",
+	"email.password":                    "password is used for authenticating with the e-mail server before sending the e-mail. It can be encrypted with the \"toglacier encrypt\" command.",
+	"email.from":                        "from is the e-mail address that will be show as the sender.",
+	"email.to":                          "to defines a list of all e-mail addresses that will receive the e-mail.",
+	"email.format":                      "format defines the e-mail content type, if you use an old e-mail client without HTML support is usually a good idea to choose for plain text content. The possible values are plain or html. By default html format is used.",
+	"email.locale":                      "locale defines the language of the reports and of the e-mail subjects. The possible values are en (English) or pt-BR (Brazilian Portuguese). By default en is used.",
+	"desktop":                           "desktop pops up a notification on the machine being backed up when a backup completes or an action fails, useful for workstation users running toglacier locally.",
+	"desktop.enabled":                   "enabled turns on the desktop notifications. By default they are disabled.",
+	"desktop.errors only":               "errors only notifies only the failures, ignoring the completed backups.",
+	"tracing":                           "tracing exports how long each stage of the backups and retrievals takes (build, encrypt, send parts, jobs, download and extract) to an OpenTelemetry collector.",
+	"tracing.endpoint":                  "endpoint is the traces address of the collector, that receives the spans with the OTLP/HTTP protocol in JSON encoding. By default the stages aren't traced.",
+	"webhook":                           "webhook receives requests of external systems (e.g. CI pipelines) to run an immediate backup while the scheduler is running (start command). Each request must inform a token with the backup scope. By default the webhook is disabled.",
+	"webhook.address":                   "address where the webhook listens (e.g. 127.0.0.1:8443).",
+	"webhook.cert":                      "cert is the certificate file in PEM format that enables TLS, with the key. Without them the requests are received in plain HTTP, only allowed in the loopback interface.",
+	"webhook.key":                       "key is the private key file of the certificate in PEM format.",
+	"webhook.self signed":               "self signed generates a self-signed certificate in the cert and key files (by default next to the local storage) when it doesn't exist yet or expired.",
+	"telegram":                          "telegram also sends the alerts to a Telegram chat, using a bot created with the BotFather (https://core.telegram.org/bots#botfather).",
+	"telegram.bot token":                "bot token is the token informed by the BotFather when the bot is created. It can be encrypted with the \"toglacier encrypt\" command.",
+	"telegram.chat id":                  "chat id identifies the user, group or channel that receives the alerts.",
+	"notifications":                     "notifications reports the result of the actions to monitoring systems.",
+	"notifications.nagios":              "nagios writes a passive service check result in the external command file of Nagios or Icinga after each backup: OK when the backup is sent, with the size and duration as performance data, or CRITICAL when an action fails.",
+	"notifications.nagios.command file": "command file is the external command file (named pipe) of the monitoring system.",
+	"notifications.nagios.host":         "host is the name of the host in the monitoring system. By default the machine id is used.",
+	"notifications.nagios.service":      "service is the name of the service in the monitoring system. By default toglacier is used.",
+	"aws":                               "aws contains all necessary information to manage backups in the AWS Glacier Cloud Storage (https://aws.amazon.com/glacier).",
+	"aws.account id":                    "account id is the identifier of you AWS account. It can be encrypted with the \"toglacier encrypt\" command.",
+	"aws.access key id":                 "access key id is the identification for a specific application. It can be encrypted with the \"toglacier encrypt\" command.",
+	"aws.secret access key":             "secret access key contains the passphrase to authenticate with the AWS API. It can be encrypted with the \"toglacier encrypt\" command.",
+	"aws.region":                        "region defines which Amazon datacenter is being used to store your AWS Glacier vault. Possible values are at http://docs.aws.amazon.com/general/latest/gr/rande.html#glacier_region (Region column).",
+	"aws.vault name":                    "vault specifies the AWS Glacier directory to store all backups.",
+	"aws.vault access policy":           "vault access policy is a JSON file with the access policy of the vault, replaced when running \"vault apply\".",
+	"aws.vault tags":                    "vault tags are the tags of the vault, reconciled when running \"vault apply\".",
+	"aws.requests per second":           "requests per second limits the requests sent to AWS Glacier, shared by all operations (part uploads, job polls and routes), to avoid the service throttling. By default there's no limit.",
+	"gcs":                               "gcs contains all necessary information to manage backups in the Google Cloud Storage (https://cloud.google.com/storage/archival/).",
+	"gcs.project":                       "project defines the Google Cloud project that will be used.",
+	"gcs.bucket":                        "bucket is the name of the container of all backup files in Google Cloud Storage.",
+	"gcs.account file":                  "account file contains the authentication data to allow managing backups in the cloud.",
+	"s3":                                "s3 contains all necessary information to manage backups in a service compatible with the Amazon S3 API (e.g. MinIO, Wasabi, Ceph).",
+	"s3.endpoint":                       "endpoint is the URL of the service.",
+	"s3.region":                         "region used to sign the requests. By default us-east-1 is used, that is accepted by most services.",
+	"s3.bucket":                         "bucket is the name of the container of all backup files.",
+	"s3.access key id":                  "access key id is the identification of the credentials of the service. It can be encrypted with the \"toglacier encrypt\" command.",
+	"s3.secret access key":              "secret access key is the passphrase of the access key id. It can be encrypted with the \"toglacier encrypt\" command.",
+	"s3.path style":                     "path style addresses the bucket in the URL path (https://endpoint/bucket) instead of in the host name (https://bucket.endpoint), usually needed by self-hosted services (MinIO, Ceph). By default the host name is used.",
+	"rclone":                            "rclone contains all necessary information to manage backups in any provider supported by rclone (https://rclone.org).",
+	"rclone.remote":                     "remote is the rclone remote, configured with \"rclone config\", followed by the path where the backups are stored.",
+	"rclone.binary":                     "binary is the rclone program. By default it is searched in the PATH.",
+	"rclone.config file":                "config file is the rclone configuration file. By default the rclone default location is used.",
+}
@@ -53,6 +53,10 @@ const (
 	// ErrorCodePercentageRange percentage must be between 0 and 100.
 	ErrorCodePercentageRange ErrorCode = "percentage-range"
 
+	// ErrorCodeDurationFormat invalid duration format, it should be a Go
+	// duration (e.g. "36h") or a number of days (e.g. "180d").
+	ErrorCodeDurationFormat ErrorCode = "duration-format"
+
 	// ErrorCodePattern invalid pattern detected when parsing the regular
 	// expression.
 	ErrorCodePattern ErrorCode = "pattern"
@@ -64,6 +68,62 @@ const (
 	// ErrorCodeSchedulerValue one or more values of the scheduler is invalid.
 	// Could be an invalid syntax or range.
 	ErrorCodeSchedulerValue ErrorCode = "scheduler-value"
+
+	// ErrorCodeRouteFormat invalid route format, it should be
+	// <prefix>=<vault name>[@<region>].
+	ErrorCodeRouteFormat ErrorCode = "route-format"
+
+	// ErrorCodeSourceFormat invalid source format, it should be
+	// <name>[@<timeout>]=<command>.
+	ErrorCodeSourceFormat ErrorCode = "source-format"
+
+	// ErrorCodeProxy invalid proxy address, it should be an URL with the http,
+	// https, socks5 or socks5h scheme.
+	ErrorCodeProxy ErrorCode = "proxy"
+
+	// ErrorCodeIOClass informed I/O class is unknown, it should be
+	// "best-effort" or "idle".
+	ErrorCodeIOClass ErrorCode = "io-class"
+
+	// ErrorCodeBlackoutFormat invalid blackout format, it should be
+	// <start scheduler>@<duration>.
+	ErrorCodeBlackoutFormat ErrorCode = "blackout-format"
+
+	// ErrorCodeTimezone informed timezone isn't in the IANA time zone
+	// database (e.g. America/Sao_Paulo).
+	ErrorCodeTimezone ErrorCode = "timezone"
+
+	// ErrorCodeEmailLocale informed email locale is unknown, it should be "en"
+	// or "pt-BR".
+	ErrorCodeEmailLocale ErrorCode = "email-locale"
+
+	// ErrorCodeNormalization informed Unicode normalization form is unknown, it
+	// should be "none", "nfc" or "nfd".
+	ErrorCodeNormalization ErrorCode = "normalization"
+
+	// ErrorCodeSpecialFiles informed special files policy is unknown, it should
+	// be "skip" or "archive".
+	ErrorCodeSpecialFiles ErrorCode = "special-files"
+
+	// ErrorCodeLogModule informed module of the log levels is unknown, it should
+	// be "archive", "cloud" or "storage".
+	ErrorCodeLogModule ErrorCode = "log-module"
+
+	// ErrorCodeScope informed scope of the token is unknown, it should be
+	// "list", "restore", "backup", "remove" or "admin".
+	ErrorCodeScope ErrorCode = "scope"
+
+	// ErrorCodeTokenFormat invalid token format, it should be
+	// <name>@<scope>[+<scope>...]=<hash>.
+	ErrorCodeTokenFormat ErrorCode = "token-format"
+
+	// ErrorCodeNestedInclude an included file has its own include directory,
+	// only the main configuration file can include other files.
+	ErrorCodeNestedInclude ErrorCode = "nested-include"
+
+	// ErrorCodeSizeFormat invalid size format, it should be a number optionally
+	// followed by the unit (e.g. 50GB).
+	ErrorCodeSizeFormat ErrorCode = "size-format"
 )
 
 // ErrorCode stores the error type that occurred while reading
@@ -84,9 +144,24 @@ var errorCodeString = map[ErrorCode]string{
 	ErrorCodeEmailFormat:      "invalid email format",
 	ErrorCodePercentageFormat: "invalid percentage format",
 	ErrorCodePercentageRange:  "invalid percentage range",
+	ErrorCodeDurationFormat:   "invalid duration format",
 	ErrorCodePattern:          "invalid pattern",
 	ErrorCodeSchedulerFormat:  "wrong number of space-separated values in scheduler",
 	ErrorCodeSchedulerValue:   "invalid value in scheduler",
+	ErrorCodeRouteFormat:      "invalid route format",
+	ErrorCodeSourceFormat:     "invalid source format",
+	ErrorCodeProxy:            "invalid proxy address",
+	ErrorCodeIOClass:          "invalid io class",
+	ErrorCodeBlackoutFormat:   "invalid blackout format",
+	ErrorCodeTimezone:         "invalid timezone",
+	ErrorCodeEmailLocale:      "invalid email locale",
+	ErrorCodeNormalization:    "invalid unicode normalization",
+	ErrorCodeSpecialFiles:     "invalid special files policy",
+	ErrorCodeLogModule:        "invalid log module",
+	ErrorCodeScope:            "invalid token scope",
+	ErrorCodeTokenFormat:      "invalid token format",
+	ErrorCodeNestedInclude:    "include not allowed in an included file",
+	ErrorCodeSizeFormat:       "invalid size format",
 }
 
 // String translate the error code to a human readable text.
@@ -119,6 +194,12 @@ func (e Error) Error() string {
 	return e.String()
 }
 
+// Unwrap returns the low level error, so it can be inspected with errors.Is and
+// errors.As.
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
 // String translate the error to a human readable text.
 func (e Error) String() string {
 	var filename string
@@ -141,10 +222,8 @@ func ErrorEqual(first, second error) bool {
 		return first == second
 	}
 
-	err1, ok1 := errors.Cause(first).(*Error)
-	err2, ok2 := errors.Cause(second).(*Error)
-
-	if !ok1 || !ok2 {
+	var err1, err2 *Error
+	if !errors.As(first, &err1) || !errors.As(second, &err2) {
 		return false
 	}
 
@@ -2,6 +2,8 @@ package config_test
 
 import (
 	"errors"
+	"os"
+	"syscall"
 	"testing"
 
 	"github.com/rafaeljusto/toglacier/internal/config"
@@ -103,6 +105,11 @@ func TestError_Error(t *testing.T) {
 			err:         &config.Error{Code: config.ErrorCodePercentageRange},
 			expected:    "config: invalid percentage range",
 		},
+		{
+			description: "it should show the correct error message for invalid duration format",
+			err:         &config.Error{Code: config.ErrorCodeDurationFormat},
+			expected:    "config: invalid duration format",
+		},
 		{
 			description: "it should show the correct error message for invalid pattern",
 			err:         &config.Error{Code: config.ErrorCodePattern},
@@ -118,6 +125,76 @@ func TestError_Error(t *testing.T) {
 			err:         &config.Error{Code: config.ErrorCodeSchedulerValue},
 			expected:    "config: invalid value in scheduler",
 		},
+		{
+			description: "it should show the correct error message for invalid route format",
+			err:         &config.Error{Code: config.ErrorCodeRouteFormat},
+			expected:    "config: invalid route format",
+		},
+		{
+			description: "it should show the correct error message for invalid source format",
+			err:         &config.Error{Code: config.ErrorCodeSourceFormat},
+			expected:    "config: invalid source format",
+		},
+		{
+			description: "it should show the correct error message for invalid proxy address",
+			err:         &config.Error{Code: config.ErrorCodeProxy},
+			expected:    "config: invalid proxy address",
+		},
+		{
+			description: "it should show the correct error message for invalid io class",
+			err:         &config.Error{Code: config.ErrorCodeIOClass},
+			expected:    "config: invalid io class",
+		},
+		{
+			description: "it should show the correct error message for invalid blackout format",
+			err:         &config.Error{Code: config.ErrorCodeBlackoutFormat},
+			expected:    "config: invalid blackout format",
+		},
+		{
+			description: "it should show the correct error message for invalid timezone",
+			err:         &config.Error{Code: config.ErrorCodeTimezone},
+			expected:    "config: invalid timezone",
+		},
+		{
+			description: "it should show the correct error message for invalid email locale",
+			err:         &config.Error{Code: config.ErrorCodeEmailLocale},
+			expected:    "config: invalid email locale",
+		},
+		{
+			description: "it should show the correct error message for invalid unicode normalization",
+			err:         &config.Error{Code: config.ErrorCodeNormalization},
+			expected:    "config: invalid unicode normalization",
+		},
+		{
+			description: "it should show the correct error message for invalid special files policy",
+			err:         &config.Error{Code: config.ErrorCodeSpecialFiles},
+			expected:    "config: invalid special files policy",
+		},
+		{
+			description: "it should show the correct error message for invalid log module",
+			err:         &config.Error{Code: config.ErrorCodeLogModule},
+			expected:    "config: invalid log module",
+		},
+		{
+			description: "it should show the correct error message for invalid token scope",
+			err:         &config.Error{Code: config.ErrorCodeScope},
+			expected:    "config: invalid token scope",
+		},
+		{
+			description: "it should show the correct error message for invalid token format",
+			err:         &config.Error{Code: config.ErrorCodeTokenFormat},
+			expected:    "config: invalid token format",
+		},
+		{
+			description: "it should show the correct error message for nested include",
+			err:         &config.Error{Code: config.ErrorCodeNestedInclude},
+			expected:    "config: include not allowed in an included file",
+		},
+		{
+			description: "it should show the correct error message for invalid size format",
+			err:         &config.Error{Code: config.ErrorCodeSizeFormat},
+			expected:    "config: invalid size format",
+		},
 		{
 			description: "it should detect when the code doesn't exist",
 			err:         &config.Error{Code: config.ErrorCode("i-dont-exist")},
@@ -134,6 +211,43 @@ func TestError_Error(t *testing.T) {
 	}
 }
 
+func TestError_Unwrap(t *testing.T) {
+	scenarios := []struct {
+		description string
+		err         error
+		target      error
+		expected    bool
+	}{
+		{
+			description: "it should detect the low level error",
+			err: &config.Error{
+				Filename: "toglacier-idontexist.tmp",
+				Code:     config.ErrorCodeReadingFile,
+				Err: &os.PathError{
+					Op:   "open",
+					Path: "toglacier-idontexist.tmp",
+					Err:  syscall.ENOENT,
+				},
+			},
+			target:   os.ErrNotExist,
+			expected: true,
+		},
+		{
+			description: "it should detect when there's no low level error",
+			err:         &config.Error{Code: config.ErrorCodeReadingFile},
+			target:      os.ErrNotExist,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if is := errors.Is(scenario.err, scenario.target); is != scenario.expected {
+				t.Errorf("results don't match. expected “%t” and got “%t”", scenario.expected, is)
+			}
+		})
+	}
+}
+
 func TestErrorEqual(t *testing.T) {
 	scenarios := []struct {
 		description string
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron"
+	"gopkg.in/yaml.v2"
+)
+
+// Example returns a configuration file with all attributes commented and
+// filled with their default values, generated from the configuration
+// attributes so it follows the current version of the tool. Each attribute is
+// preceded by its description. Lists of mappings (e.g. routes, tokens) are
+// empty, with the attributes of their items listed in a comment.
+func Example() []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintln(&buffer, "#")
+	fmt.Fprintln(&buffer, "# toglacier tool configuration file")
+	fmt.Fprintln(&buffer, "# https://github.com/rafaeljusto/toglacier")
+	fmt.Fprintln(&buffer, "#")
+	fmt.Fprintf(&buffer, "# Generated by the version %s with the default values of all attributes.\n", Version)
+	fmt.Fprintln(&buffer, "# Uncomment the attributes to change them, the environment variables still")
+	fmt.Fprintln(&buffer, "# override this file.")
+	fmt.Fprintln(&buffer, "#")
+	fmt.Fprintln(&buffer)
+
+	writeExample(&buffer, reflect.ValueOf(New()).Elem(), "")
+	return buffer.Bytes()
+}
+
+// writeExample writes the attributes of the structure as commented YAML lines,
+// each one preceded by its description and separated by an empty line. The
+// path identifies the structure in the configuration file (e.g. "scheduler"),
+// being empty for the first level.
+func writeExample(buffer *bytes.Buffer, value reflect.Value, path string) {
+	level := 0
+	if path != "" {
+		level = strings.Count(path, ".") + 1
+	}
+	indentation := strings.Repeat("  ", level)
+
+	for i := 0; i < value.NumField(); i++ {
+		name, ok := yamlName(value.Type().Field(i))
+		if !ok {
+			continue
+		}
+
+		field := value.Field(i)
+		fieldPath := attributePath(path, name)
+
+		if i > 0 {
+			fmt.Fprintln(buffer)
+		}
+		writeExampleDescription(buffer, indentation, fieldPath)
+
+		switch {
+		case examplePlaceholders[fieldPath] != "":
+			writeExampleAttribute(buffer, indentation+name, examplePlaceholders[fieldPath])
+
+		case isText(field.Type()):
+			writeExampleAttribute(buffer, indentation+name, exampleText(field))
+
+		case field.Kind() == reflect.Struct:
+			fmt.Fprintf(buffer, "# %s%s:\n", indentation, name)
+			writeExample(buffer, field, fieldPath)
+
+		case field.Kind() == reflect.Slice:
+			writeExampleList(buffer, name, field, level, fieldPath)
+
+		case field.Kind() == reflect.Map:
+			fmt.Fprintf(buffer, "# %s%s: {}\n", indentation, name)
+
+		default:
+			writeExampleAttribute(buffer, indentation+name, exampleScalar(field.Interface()))
+		}
+	}
+}
+
+// examplePlaceholders replaces the default values that depend on the machine
+// generating the example, so the file can be shared between machines.
+var examplePlaceholders = map[string]string{
+	"machine id": "<hostname>",
+}
+
+// exampleDescriptionWidth is the maximum width of the description lines,
+// without the comment markers.
+const exampleDescriptionWidth = 76
+
+// writeExampleDescription writes the description of the attribute as a YAML
+// comment, so it remains a comment when the attribute is uncommented.
+func writeExampleDescription(buffer *bytes.Buffer, indentation, path string) {
+	if description := descriptions[path]; description != "" {
+		writeExampleComment(buffer, indentation, description)
+	}
+}
+
+// writeExampleComment writes the text as YAML comment lines, breaking the
+// lines between words.
+func writeExampleComment(buffer *bytes.Buffer, indentation, text string) {
+	var line string
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(indentation)+len(line)+len(word)+1 > exampleDescriptionWidth {
+			fmt.Fprintf(buffer, "# %s# %s\n", indentation, line)
+			line = ""
+		}
+
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	fmt.Fprintf(buffer, "# %s# %s\n", indentation, line)
+}
+
+// attributePath identifies the attribute in the configuration file, joining
+// the names of its groups with dots (e.g. "scheduler.backup").
+func attributePath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// writeExampleAttribute writes the attribute with its value. Empty values
+// aren't informed, as some attributes parsed from text (e.g. io class) don't
+// accept an empty text.
+func writeExampleAttribute(buffer *bytes.Buffer, name, value string) {
+	if value == "" || value == `""` {
+		fmt.Fprintf(buffer, "# %s:\n", name)
+		return
+	}
+
+	fmt.Fprintf(buffer, "# %s: %s\n", name, value)
+}
+
+// writeExampleList writes the items of a list. A list of mappings only
+// informs the attributes of its items, with their descriptions.
+func writeExampleList(buffer *bytes.Buffer, name string, field reflect.Value, level int, path string) {
+	indentation := strings.Repeat("  ", level)
+
+	itemType := field.Type().Elem()
+	if itemType.Kind() == reflect.Struct && hasYAMLFields(itemType) {
+		var attributes []string
+		for i := 0; i < itemType.NumField(); i++ {
+			if attribute, ok := yamlName(itemType.Field(i)); ok {
+				attributes = append(attributes, attribute)
+			}
+		}
+
+		fmt.Fprintf(buffer, "# %s%s: []\n", indentation, name)
+		fmt.Fprintf(buffer, "# %s  # item attributes: %s\n", indentation, strings.Join(attributes, ", "))
+		for _, attribute := range attributes {
+			if description := descriptions[attributePath(path, attribute)]; description != "" {
+				writeExampleComment(buffer, indentation+"  ", "- "+description)
+			}
+		}
+		return
+	}
+
+	if field.Len() == 0 {
+		fmt.Fprintf(buffer, "# %s%s: []\n", indentation, name)
+		return
+	}
+
+	fmt.Fprintf(buffer, "# %s%s:\n", indentation, name)
+	for i := 0; i < field.Len(); i++ {
+		item := field.Index(i)
+		if isText(item.Type()) {
+			fmt.Fprintf(buffer, "# %s  - %s\n", indentation, exampleText(item))
+		} else {
+			fmt.Fprintf(buffer, "# %s  - %s\n", indentation, exampleScalar(item.Interface()))
+		}
+	}
+}
+
+// isText checks if the attribute is parsed from text in the configuration
+// file, instead of being informed as a mapping or a list.
+func isText(t reflect.Type) bool {
+	if t == durationType {
+		return true
+	}
+
+	if !reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return false
+	}
+
+	return t.Kind() != reflect.Struct || !hasYAMLFields(t)
+}
+
+// exampleText converts the value of an attribute parsed from text back to the
+// text of the configuration file.
+func exampleText(value reflect.Value) string {
+	switch v := value.Interface().(type) {
+	case time.Duration:
+		return v.String()
+	case Duration:
+		return time.Duration(v).String()
+	case Percentage:
+		return strconv.FormatFloat(float64(v), 'f', -1, 64)
+	case Scheduler:
+		return exampleScalar(schedulerText(v))
+	case ProxyURL:
+		if v.Value != nil {
+			return exampleScalar(v.Value.String())
+		}
+		return exampleScalar("")
+	case encrypted:
+		return exampleScalar(v.Value)
+	case aesKey:
+		return exampleScalar(v.Value)
+	case encoding.TextMarshaler:
+		text, _ := v.MarshalText()
+		return exampleScalar(string(text))
+	}
+
+	if value.Kind() == reflect.String {
+		return exampleScalar(value.String())
+	}
+
+	return exampleScalar(value.Interface())
+}
+
+// exampleScalar writes the value as YAML, quoting the strings when needed.
+func exampleScalar(value interface{}) string {
+	content, err := yaml.Marshal(value)
+	if err != nil {
+		return `""`
+	}
+
+	return strings.TrimSpace(string(content))
+}
+
+// schedulerText rebuilds the cron format of the scheduler (e.g. "0 0 0 * * *"),
+// listing the selected values of each field. Week days and months are written
+// as numbers.
+func schedulerText(s Scheduler) string {
+	spec, ok := s.Value.(*cron.SpecSchedule)
+	if !ok {
+		return ""
+	}
+
+	fields := []struct {
+		bits     uint64
+		min, max uint
+	}{
+		{bits: spec.Second, min: 0, max: 59},
+		{bits: spec.Minute, min: 0, max: 59},
+		{bits: spec.Hour, min: 0, max: 23},
+		{bits: spec.Dom, min: 1, max: 31},
+		{bits: spec.Month, min: 1, max: 12},
+		{bits: spec.Dow, min: 0, max: 6},
+	}
+
+	var parts []string
+	for _, field := range fields {
+		// the star bit is the most significant bit of the field
+		if field.bits&(1<<63) != 0 {
+			parts = append(parts, "*")
+			continue
+		}
+
+		var values []string
+		for i := field.min; i <= field.max; i++ {
+			if field.bits&(1<<i) != 0 {
+				values = append(values, strconv.FormatUint(uint64(i), 10))
+			}
+		}
+		parts = append(parts, strings.Join(values, ","))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,87 @@
+package config_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier/internal/config"
+	"gopkg.in/yaml.v2"
+)
+
+func TestExample(t *testing.T) {
+	example := string(config.Example())
+
+	scenarios := []struct {
+		description string
+		line        string
+	}{
+		{
+			description: "it should comment a number attribute with its default value",
+			line:        "# keep backups: 10",
+		},
+		{
+			description: "it should comment a scheduler with its default value",
+			line:        "#   remove old backups: 0 0 1 * * 5",
+		},
+		{
+			description: "it should comment a duration with its default value",
+			line:        "#   retry delay: 10m0s",
+		},
+		{
+			description: "it should comment the attributes of the items of a list",
+			line:        "#   # item attributes: name, hash, scopes",
+		},
+		{
+			description: "it should describe an attribute",
+			line:        "# # keep backups defines the number of recent backups to preserve (by creation",
+		},
+		{
+			description: "it should describe an attribute of a group",
+			line:        "#   # backup synchronize the backups paths with the cloud. By default it runs",
+		},
+		{
+			description: "it should use a placeholder for the machine id",
+			line:        "# machine id: <hostname>",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if !strings.Contains(example, "\n"+scenario.line+"\n") {
+				t.Errorf("line “%s” not found in the example:\n%s", scenario.line, example)
+			}
+		})
+	}
+
+	t.Run("it should load the default values when all attributes are uncommented", func(t *testing.T) {
+		// the header of the example ends in the first empty line
+		var content []string
+		for _, line := range strings.Split(example[strings.Index(example, "\n\n"):], "\n") {
+			content = append(content, strings.TrimPrefix(line, "# "))
+		}
+
+		c := new(config.Config)
+		if err := yaml.UnmarshalStrict([]byte(strings.Join(content, "\n")), c); err != nil {
+			t.Fatalf("unexpected error loading the example. details: %s", err)
+		}
+
+		// empty lists and maps are informed, so the defaults are compared with
+		// the same empty values
+		expected := config.New()
+		expected.Paths = []string{}
+		expected.IgnorePatterns = []config.Pattern{}
+		expected.Routes = []config.Route{}
+		expected.Sources = []config.Source{}
+		expected.Blackouts = []config.Blackout{}
+		expected.Tokens = []config.Token{}
+		expected.Log.Levels = map[config.LogModule]config.LogLevel{}
+		expected.Email.To = []string{}
+		expected.AWS.VaultTags = map[string]string{}
+		expected.MachineID = "<hostname>"
+
+		if !reflect.DeepEqual(expected, c) {
+			t.Errorf("config don't match.\n%s", Diff(expected, c))
+		}
+	})
+}
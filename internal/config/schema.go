@@ -0,0 +1,153 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// schemaVersion is the JSON schema draft used to describe the configuration.
+const schemaVersion = "http://json-schema.org/draft-07/schema#"
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// Schema returns the JSON schema of the YAML configuration file, generated from
+// the configuration attributes, so editors can validate and complete the file.
+// Unknown attributes are rejected, as in LoadStrict.
+func Schema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(Config{}), "")
+	schema["$schema"] = schemaVersion
+	schema["title"] = "toglacier configuration"
+
+	content, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(content, '\n'), nil
+}
+
+// typeSchema describes the values accepted for the type. Types parsed from
+// text (e.g. schedulers, durations, encrypted values) are strings, and the ones
+// that can also be informed as a mapping (e.g. routes, tokens) accept both
+// forms. The path identifies the attributes of the type in the configuration
+// file, to describe them.
+func typeSchema(t reflect.Type, path string) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == durationType {
+		return map[string]interface{}{"type": []string{"string", "integer"}}
+	}
+
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		switch t.Kind() {
+		case reflect.Struct:
+			if !hasYAMLFields(t) {
+				break
+			}
+
+			return map[string]interface{}{
+				"anyOf": []interface{}{
+					map[string]interface{}{"type": "string"},
+					structSchema(t, path),
+				},
+			}
+
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Float32, reflect.Float64:
+			return map[string]interface{}{"type": []string{"string", "number"}}
+		}
+
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(t.Elem(), path),
+		}
+
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": typeSchema(t.Elem(), path),
+		}
+
+	case reflect.Struct:
+		return structSchema(t, path)
+	}
+
+	return map[string]interface{}{"type": "string"}
+}
+
+// structSchema describes the attributes of the structure using the names of
+// the YAML tags, without allowing unknown attributes. Each attribute has its
+// description, when available.
+func structSchema(t reflect.Type, path string) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name, ok := yamlName(field); ok {
+			fieldPath := attributePath(path, name)
+			property := typeSchema(field.Type, fieldPath)
+			if description := descriptions[fieldPath]; description != "" {
+				property["description"] = description
+			}
+			properties[name] = property
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// hasYAMLFields checks if the structure has attributes with YAML tags, so it
+// can be informed as a mapping in the configuration file.
+func hasYAMLFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("yaml"); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// yamlName returns the attribute name in the configuration file, following the
+// rules of the YAML library: without tag the lowercase field name is used.
+func yamlName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+
+	tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	switch tag {
+	case "-":
+		return "", false
+	case "":
+		return strings.ToLower(field.Name), true
+	}
+
+	return tag, true
+}
@@ -0,0 +1,126 @@
+package config_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier/internal/config"
+)
+
+func TestSchema(t *testing.T) {
+	content, err := config.Schema()
+	if err != nil {
+		t.Fatalf("unexpected error generating the schema. details: %s", err)
+	}
+
+	var schema map[string]interface{}
+	if err = json.Unmarshal(content, &schema); err != nil {
+		t.Fatalf("invalid schema. details: %s", err)
+	}
+
+	scenarios := []struct {
+		description string
+		path        string
+		expected    interface{}
+	}{
+		{
+			description: "it should reject unknown attributes",
+			path:        "additionalProperties",
+			expected:    false,
+		},
+		{
+			description: "it should describe a number attribute",
+			path:        "properties/keep backups/type",
+			expected:    "integer",
+		},
+		{
+			description: "it should describe a list attribute",
+			path:        "properties/paths/items/type",
+			expected:    "string",
+		},
+		{
+			description: "it should describe an attribute parsed from text",
+			path:        "properties/scheduler/properties/backup/type",
+			expected:    "string",
+		},
+		{
+			description: "it should describe a percentage as a number or a string",
+			path:        "properties/modify tolerance/type",
+			expected:    []interface{}{"string", "number"},
+		},
+		{
+			description: "it should describe a duration as a number or a string",
+			path:        "properties/failure/properties/retry delay/type",
+			expected:    []interface{}{"string", "integer"},
+		},
+		{
+			description: "it should reject unknown attributes in a group",
+			path:        "properties/webhook/additionalProperties",
+			expected:    false,
+		},
+		{
+			description: "it should describe a map attribute",
+			path:        "properties/aws/properties/vault tags/additionalProperties/type",
+			expected:    "string",
+		},
+		{
+			description: "it should describe the mapping of an attribute also parsed from text",
+			path:        "properties/tokens/items/anyOf/1/properties/scopes/items/type",
+			expected:    "string",
+		},
+		{
+			description: "it should describe the purpose of an attribute in a group",
+			path:        "properties/email/properties/server/description",
+			expected:    "server defines the e-mail server address without port.",
+		},
+		{
+			description: "it should describe the purpose of an attribute of the items of a list",
+			path:        "properties/tokens/items/anyOf/1/properties/name/description",
+			expected:    "name identifies the token in the logs.",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var value interface{} = schema
+			for _, part := range strings.Split(scenario.path, "/") {
+				switch v := value.(type) {
+				case map[string]interface{}:
+					value = v[part]
+				case []interface{}:
+					if index, err := strconv.Atoi(part); err == nil && index < len(v) {
+						value = v[index]
+					} else {
+						value = nil
+					}
+				default:
+					value = nil
+				}
+			}
+
+			if !reflect.DeepEqual(scenario.expected, value) {
+				t.Errorf("schema values don't match. expected “%v” and got “%v”", scenario.expected, value)
+			}
+		})
+	}
+}
+
+func TestSchema_published(t *testing.T) {
+	content, err := config.Schema()
+	if err != nil {
+		t.Fatalf("unexpected error generating the schema. details: %s", err)
+	}
+
+	published, err := ioutil.ReadFile("../../cmd/toglacier/toglacier.schema.json")
+	if err != nil {
+		t.Fatalf("error reading the published schema. details: %s", err)
+	}
+
+	if string(content) != string(published) {
+		t.Errorf("published schema is outdated, regenerate it with “toglacier config schema”")
+	}
+}
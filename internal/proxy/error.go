@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrorCodeScheme proxy URL scheme isn't supported.
+	ErrorCodeScheme ErrorCode = "scheme"
+
+	// ErrorCodeConnecting error connecting to the proxy.
+	ErrorCodeConnecting ErrorCode = "connecting"
+
+	// ErrorCodeHandshake proxy didn't accept the connection to the destination.
+	ErrorCodeHandshake ErrorCode = "handshake"
+
+	// ErrorCodeSendingEmail error sending the e-mail through the proxy.
+	ErrorCodeSendingEmail ErrorCode = "sending-email"
+)
+
+// ErrorCode stores the error type that occurred while connecting through the
+// proxy.
+type ErrorCode string
+
+// String translate the error code to a human readable text.
+func (e ErrorCode) String() string {
+	switch e {
+	case ErrorCodeScheme:
+		return "unsupported proxy scheme"
+	case ErrorCodeConnecting:
+		return "error connecting to the proxy"
+	case ErrorCodeHandshake:
+		return "proxy refused the connection"
+	case ErrorCodeSendingEmail:
+		return "error sending e-mail through the proxy"
+	}
+
+	return "unknown error code"
+}
+
+// Error stores error details from a problem occurred while connecting through
+// the proxy.
+type Error struct {
+	Code ErrorCode
+	Err  error
+}
+
+func newError(code ErrorCode, err error) *Error {
+	return &Error{
+		Code: code,
+		Err:  errors.WithStack(err),
+	}
+}
+
+// Error returns the error in a human readable format.
+func (e Error) Error() string {
+	return e.String()
+}
+
+// Unwrap returns the low level error, so it can be inspected with errors.Is and
+// errors.As.
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// String translate the error to a human readable text.
+func (e Error) String() string {
+	var err string
+	if e.Err != nil {
+		err = fmt.Sprintf(". details: %s", e.Err)
+	}
+
+	return fmt.Sprintf("proxy: %s%s", e.Code, err)
+}
+
+// ErrorEqual compares two Error objects. This is useful to compare down to the
+// low level errors.
+func ErrorEqual(first, second error) bool {
+	if first == nil || second == nil {
+		return first == second
+	}
+
+	var err1, err2 *Error
+	if !errors.As(first, &err1) || !errors.As(second, &err2) {
+		return false
+	}
+
+	if err1.Code != err2.Code {
+		return false
+	}
+
+	errCause1 := errors.Cause(err1.Err)
+	errCause2 := errors.Cause(err2.Err)
+
+	if errCause1 == nil || errCause2 == nil {
+		return errCause1 == errCause2
+	}
+
+	return errCause1.Error() == errCause2.Error()
+}
@@ -0,0 +1,154 @@
+package proxy_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier/internal/proxy"
+)
+
+func TestError_Error(t *testing.T) {
+	scenarios := []struct {
+		description string
+		err         *proxy.Error
+		expected    string
+	}{
+		{
+			description: "it should show the message with the low level error",
+			err: &proxy.Error{
+				Code: proxy.ErrorCodeConnecting,
+				Err:  errors.New("low level error"),
+			},
+			expected: "proxy: error connecting to the proxy. details: low level error",
+		},
+		{
+			description: "it should show the correct error message for unsupported scheme",
+			err:         &proxy.Error{Code: proxy.ErrorCodeScheme},
+			expected:    "proxy: unsupported proxy scheme",
+		},
+		{
+			description: "it should show the correct error message for connection problem",
+			err:         &proxy.Error{Code: proxy.ErrorCodeConnecting},
+			expected:    "proxy: error connecting to the proxy",
+		},
+		{
+			description: "it should show the correct error message for refused connection",
+			err:         &proxy.Error{Code: proxy.ErrorCodeHandshake},
+			expected:    "proxy: proxy refused the connection",
+		},
+		{
+			description: "it should show the correct error message for e-mail sending problem",
+			err:         &proxy.Error{Code: proxy.ErrorCodeSendingEmail},
+			expected:    "proxy: error sending e-mail through the proxy",
+		},
+		{
+			description: "it should detect when the code doesn't exist",
+			err:         &proxy.Error{Code: proxy.ErrorCode("i-dont-exist")},
+			expected:    "proxy: unknown error code",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if msg := scenario.err.Error(); msg != scenario.expected {
+				t.Errorf("errors don't match. expected “%s” and got “%s”", scenario.expected, msg)
+			}
+		})
+	}
+}
+
+func TestErrorEqual(t *testing.T) {
+	scenarios := []struct {
+		description string
+		err1        error
+		err2        error
+		expected    bool
+	}{
+		{
+			description: "it should detect equal Error instances",
+			err1: &proxy.Error{
+				Code: proxy.ErrorCodeConnecting,
+				Err:  errors.New("low level error"),
+			},
+			err2: &proxy.Error{
+				Code: proxy.ErrorCodeConnecting,
+				Err:  errors.New("low level error"),
+			},
+			expected: true,
+		},
+		{
+			description: "it should detect when the code is different",
+			err1: &proxy.Error{
+				Code: proxy.ErrorCodeConnecting,
+				Err:  errors.New("low level error"),
+			},
+			err2: &proxy.Error{
+				Code: proxy.ErrorCode("unknown-error"),
+				Err:  errors.New("low level error"),
+			},
+			expected: false,
+		},
+		{
+			description: "it should detect when the low level error is different",
+			err1: &proxy.Error{
+				Code: proxy.ErrorCodeConnecting,
+				Err:  errors.New("low level error 1"),
+			},
+			err2: &proxy.Error{
+				Code: proxy.ErrorCodeConnecting,
+				Err:  errors.New("low level error 2"),
+			},
+			expected: false,
+		},
+		{
+			description: "it should detect when both errors are undefined",
+			expected:    true,
+		},
+		{
+			description: "it should detect when only one error is undefined",
+			err1: &proxy.Error{
+				Code: proxy.ErrorCodeConnecting,
+				Err:  errors.New("low level error"),
+			},
+			expected: false,
+		},
+		{
+			description: "it should detect when both causes of the error are undefined",
+			err1: &proxy.Error{
+				Code: proxy.ErrorCodeConnecting,
+			},
+			err2: &proxy.Error{
+				Code: proxy.ErrorCodeConnecting,
+			},
+			expected: true,
+		},
+		{
+			description: "it should detect when only one causes of the error is undefined",
+			err1: &proxy.Error{
+				Code: proxy.ErrorCodeConnecting,
+				Err:  errors.New("low level error"),
+			},
+			err2: &proxy.Error{
+				Code: proxy.ErrorCodeConnecting,
+			},
+			expected: false,
+		},
+		{
+			description: "it should detect when one the error isn't Error type",
+			err1: &proxy.Error{
+				Code: proxy.ErrorCodeConnecting,
+				Err:  errors.New("low level error"),
+			},
+			err2:     errors.New("low level error"),
+			expected: false,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if equal := proxy.ErrorEqual(scenario.err1, scenario.err2); equal != scenario.expected {
+				t.Errorf("results don't match. expected “%t” and got “%t”", scenario.expected, equal)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"time"
+)
+
+const (
+	// chartWidth is the width in pixels of the inline charts.
+	chartWidth = 600
+
+	// chartHeight is the height in pixels of the inline charts, including the
+	// space for the maximum value label.
+	chartHeight = 150
+
+	// chartLabelHeight is the space in pixels reserved for the maximum value
+	// label on the top of the chart.
+	chartLabelHeight = 20
+)
+
+// barChart draws the values as an inline SVG bar chart, so it can be embedded
+// in the HTML e-mails without external resources. Each bar has a title, shown
+// by the e-mail clients when the mouse is over it, and the maximum value is
+// labeled on the top of the chart.
+func barChart(values []float64, titles []string, maxLabel string) string {
+	var max float64
+	for _, value := range values {
+		if value > max {
+			max = value
+		}
+	}
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(&buffer, "\n        <text x=\"0\" y=\"12\" font-size=\"12\" fill=\"grey\">%s</text>", html.EscapeString(maxLabel))
+
+	barWidth := float64(chartWidth) / float64(len(values))
+	for i, value := range values {
+		var height float64
+		if max > 0 {
+			height = value / max * (chartHeight - chartLabelHeight)
+		}
+
+		fmt.Fprintf(&buffer, "\n        <rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"#66ccff\"><title>%s</title></rect>",
+			float64(i)*barWidth, chartHeight-height, barWidth*0.8, height, html.EscapeString(titles[i]))
+	}
+
+	buffer.WriteString("\n      </svg>")
+	return buffer.String()
+}
+
+// formatSize describes the number of bytes with the most suitable unit.
+func formatSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", size, units[unit])
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// formatDuration truncates the duration to seconds, as the fraction of seconds
+// isn't relevant to follow the backups duration.
+func formatDuration(duration time.Duration) time.Duration {
+	return duration - duration%time.Second
+}
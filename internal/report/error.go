@@ -44,6 +44,12 @@ func (e Error) Error() string {
 	return e.String()
 }
 
+// Unwrap returns the low level error, so it can be inspected with errors.Is and
+// errors.As.
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
 // String translate the error to a human readable text.
 func (e Error) String() string {
 	var err string
@@ -61,10 +67,8 @@ func ErrorEqual(first, second error) bool {
 		return first == second
 	}
 
-	err1, ok1 := errors.Cause(first).(*Error)
-	err2, ok2 := errors.Cause(second).(*Error)
-
-	if !ok1 || !ok2 {
+	var err1, err2 *Error
+	if !errors.As(first, &err1) || !errors.As(second, &err2) {
 		return false
 	}
 
@@ -0,0 +1,190 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode/utf8"
+)
+
+const (
+	// LocaleEN builds the reports in English.
+	LocaleEN Locale = "en"
+
+	// LocalePTBR builds the reports in Brazilian Portuguese.
+	LocalePTBR Locale = "pt-BR"
+)
+
+// Locale defines the language used in the reports text. By default "en" is
+// used.
+type Locale string
+
+// catalogs stores the translations of the report messages for each locale.
+// The messages are identified by their English text, so a missing translation
+// falls back to English.
+var catalogs = map[Locale]map[string]string{
+	LocalePTBR: {
+		"toglacier report":      "relatório do toglacier",
+		"toglacier report [%s]": "relatório do toglacier [%s]",
+		"toglacier alert":       "alerta do toglacier",
+		"Errors":                "Erros",
+		"Log":                   "Log",
+		"Durations":             "Durações",
+		"Backups":               "Backups",
+		"Backup":                "Backup",
+		"ID":                    "ID",
+		"ID:":                   "ID:",
+		"Date":                  "Data",
+		"Date:":                 "Data:",
+		"Vault":                 "Cofre",
+		"Vault:":                "Cofre:",
+		"Checksum":              "Checksum",
+		"Checksum:":             "Checksum:",
+		"Location":              "Local",
+		"Location:":             "Local:",
+		"Machine":               "Máquina",
+		"Machine:":              "Máquina:",
+		"Comment":               "Comentário",
+		"Comment:":              "Comentário:",
+		"Paths:":                "Caminhos:",
+		"Build:":                "Geração:",
+		"Encrypt:":              "Cifragem:",
+		"Send:":                 "Envio:",
+		"List:":                 "Listagem:",
+		"Remove:":               "Remoção:",
+		"User":                  "Usuário",
+		"User:":                 "Usuário:",
+		"Pinned at":             "Fixado em",
+		"Pinned at:":            "Fixado em:",
+		"Until":                 "Até",
+		"Until:":                "Até:",
+		"Reason":                "Motivo",
+		"Reason:":               "Motivo:",
+		"unpinned":              "sem prazo",
+		"Archives:":             "Arquivos:",
+		"Restored:":             "Restaurados:",
+		"Skipped:":              "Ignorados:",
+		"Failed:":               "Falhas:",
+		"Size:":                 "Tamanho:",
+		"Download:":             "Download:",
+		"Extract:":              "Extração:",
+		"To send it, run:":      "Para enviá-lo, execute:",
+
+		"Backups Sent":       "Backups Enviados",
+		"List Backup":        "Listagem de Backups",
+		"Remove Old Backups": "Remoção de Backups Antigos",
+		"Escalation":         "Alerta",
+		"Paused":             "Pausado",
+		"Approval Required":  "Aprovação Necessária",
+		"Schedule":           "Agendamento",
+		"Recovery Journal":   "Diário de Recuperação",
+		"Upload Spool":       "Fila de Envio",
+		"Trash":              "Lixeira",
+		"Pinned Backups":     "Backups Fixados",
+		"Backup Retrieved":   "Backup Recuperado",
+		"Skipped Files":      "Arquivos Ignorados",
+		"Partial Backup":     "Backup Parcial",
+		"Test report":        "Relatório de teste",
+
+		"Preserved": "Preservados",
+		"Pinned":    "Fixados",
+		"Pending":   "Pendentes",
+		"Recovered": "Recuperados",
+		"Spooled":   "Na fila",
+		"Sent":      "Enviados",
+		"Trashed":   "Na lixeira",
+		"Removed":   "Removidos",
+		"Next runs": "Próximas execuções",
+
+		"Backups History": "Histórico de Backups",
+		"Size":            "Tamanho",
+		"Duration":        "Duração",
+		"max. %s":         "máx. %s",
+
+		"Old backups still referenced by newer backups.":                                           "Backups antigos ainda referenciados por backups mais novos.",
+		"Backups protected from the removal by the administrator.":                                 "Backups protegidos da remoção pelo administrador.",
+		"Backups sent to the cloud that aren't in the local storage yet.":                          "Backups enviados para a nuvem que ainda não estão no armazenamento local.",
+		"Archives that couldn't be sent to the cloud, they will be sent later.":                    "Arquivos que não puderam ser enviados para a nuvem, eles serão enviados mais tarde.",
+		"Backups removed by the user, they can be restored until they are removed from the cloud.": "Backups removidos pelo usuário, eles podem ser restaurados até serem removidos da nuvem.",
+		"Testing the notification mechanisms.":                                                     "Testando os mecanismos de notificação.",
+		"Action “%s” failed %d consecutive times.":                                                 "A ação “%s” falhou %d vezes consecutivas.",
+		"Action “%s” skipped, scheduler paused until resumed.":                                     "A ação “%s” não foi executada, agendamento pausado até ser retomado.",
+		"Action “%s” skipped, scheduler paused until %s.":                                          "A ação “%s” não foi executada, agendamento pausado até %s.",
+		"Backup of %s deferred, above the confirmation threshold of %s.":                           "Backup de %s adiado, acima do limite de confirmação de %s.",
+		"Configuration fingerprint: %s":                                                            "Impressão digital da configuração: %s",
+		"Pending archives:":                                                                        "Arquivos pendentes:",
+		"removed at %s":                                                                            "removido em %s",
+		"The maximum duration was reached, the files not analyzed keep their previous version.":    "A duração máxima foi atingida, os arquivos não analisados mantêm a versão anterior.",
+
+		"toglacier backup completed":     "backup do toglacier concluído",
+		"toglacier failure":              "falha do toglacier",
+		"Backup “%s” sent to the cloud.": "Backup “%s” enviado para a nuvem.",
+		"Action “%s” failed: %s":         "A ação “%s” falhou: %s",
+		"toglacier retrieval":            "recuperação do toglacier",
+		"toglacier retrieval completed":  "recuperação do toglacier concluída",
+
+		"Backup “%s” retrieved: %d files restored, %d skipped and %d failed.": "Backup “%s” recuperado: %d arquivos restaurados, %d ignorados e %d com falha.",
+	},
+}
+
+var (
+	locale     = LocaleEN
+	localeLock sync.RWMutex
+)
+
+// SetLocale defines the language of the reports built from now on. Unknown
+// locales build the reports in English.
+func SetLocale(l Locale) {
+	localeLock.Lock()
+	defer localeLock.Unlock()
+
+	locale = l
+}
+
+// currentLocale returns the language of the reports.
+func currentLocale() Locale {
+	localeLock.RLock()
+	defer localeLock.RUnlock()
+
+	return locale
+}
+
+// Translate returns the message in the language of the reports, formatting it
+// with the given arguments. When there's no translation the English message is
+// used.
+func Translate(message string, args ...interface{}) string {
+	if translated, ok := catalogs[currentLocale()][message]; ok {
+		message = translated
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+
+	return fmt.Sprintf(message, args...)
+}
+
+// newTemplate parses the report template with the functions to translate the
+// messages. The "tr" function translates the message, formatting it with the
+// arguments, "underline" returns a dash for each letter of the translated
+// message and "label" pads the translated message with spaces to align the
+// values in the given width. The "size" and "duration" functions describe the
+// backup size and duration in a readable way.
+func newTemplate(tmpl string) *template.Template {
+	return template.Must(template.New("report").Funcs(template.FuncMap{
+		"tr": Translate,
+		"underline": func(message string) string {
+			return strings.Repeat("-", utf8.RuneCountInString(Translate(message)))
+		},
+		"label": func(message string, width int) string {
+			message = Translate(message)
+			if utf8.RuneCountInString(message) >= width {
+				return message + " "
+			}
+			return fmt.Sprintf("%-*s", width, message)
+		},
+		"size":     formatSize,
+		"duration": formatDuration,
+	}).Parse(tmpl))
+}
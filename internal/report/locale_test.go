@@ -0,0 +1,131 @@
+package report_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/report"
+)
+
+func TestSetLocale(t *testing.T) {
+	date := time.Date(2017, 3, 10, 14, 10, 46, 0, time.UTC)
+
+	scenarios := []struct {
+		description string
+		locale      report.Locale
+		report      report.Report
+		format      report.Format
+		expected    string
+	}{
+		{
+			description: "it should build a plain report in Brazilian Portuguese",
+			locale:      report.LocalePTBR,
+			report: func() report.Report {
+				r := report.NewEscalation("backup", 3)
+				r.CreatedAt = date
+				r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+				return r
+			}(),
+			format: report.FormatPlain,
+			expected: `
+[2017-03-10 14:10:46] Alerta
+
+  A ação “backup” falhou 3 vezes consecutivas.
+
+  Erros
+  -----
+
+    * timeout connecting to aws
+`,
+		},
+		{
+			description: "it should align the translated labels of a plain report",
+			locale:      report.LocalePTBR,
+			report: func() report.Report {
+				r := report.NewPins()
+				r.CreatedAt = date
+				r.Pinned = append(r.Pinned, report.PinnedBackup{
+					ID:       "123456",
+					User:     "john",
+					Reason:   "before the migration",
+					PinnedAt: date.Add(-time.Hour),
+				})
+				return r
+			}(),
+			format: report.FormatPlain,
+			expected: `
+[2017-03-10 14:10:46] Backups Fixados
+
+  * ID:        123456
+    Usuário:   john
+    Fixado em: 2017-03-10 13:10:46
+    Até:       sem prazo
+    Motivo:    before the migration
+`,
+		},
+		{
+			description: "it should build a HTML report in Brazilian Portuguese",
+			locale:      report.LocalePTBR,
+			report: func() report.Report {
+				r := report.NewPaused("backup", date.Add(time.Hour))
+				r.CreatedAt = date
+				return r
+			}(),
+			format: report.FormatHTML,
+			expected: `
+    <section class="report">
+      <h1>Pausado</h1>
+      <div class="date">
+        2017-03-10 14:10:46
+      </div>
+      <p>A ação “backup” não foi executada, agendamento pausado até 2017-03-10 15:10:46.</p>
+    </section>
+`,
+		},
+		{
+			description: "it should build the report in English when the locale is unknown",
+			locale:      report.Locale("xx"),
+			report: func() report.Report {
+				r := report.NewTest()
+				r.CreatedAt = date
+				return r
+			}(),
+			format: report.FormatPlain,
+			expected: `
+[2017-03-10 14:10:46] Test report
+
+  Testing the notification mechanisms.
+`,
+		},
+	}
+
+	defer report.SetLocale(report.LocaleEN)
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			report.SetLocale(scenario.locale)
+
+			output, err := scenario.report.Build(scenario.format)
+			if err != nil {
+				t.Fatalf("unexpected error. details: %s", err)
+			}
+
+			outputLines := strings.Split(strings.TrimSpace(output), "\n")
+			for i := range outputLines {
+				outputLines[i] = strings.TrimRight(outputLines[i], " ")
+			}
+
+			expectedLines := strings.Split(strings.TrimSpace(scenario.expected), "\n")
+			for i := range expectedLines {
+				expectedLines[i] = strings.TrimRight(expectedLines[i], " ")
+			}
+
+			if !reflect.DeepEqual(expectedLines, outputLines) {
+				t.Errorf("output don't match.\n%s", Diff(expectedLines, outputLines))
+			}
+		})
+	}
+}
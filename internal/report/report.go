@@ -7,17 +7,14 @@ import (
 	"bytes"
 	"fmt"
 	"sync"
-	"text/template"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rafaeljusto/toglacier/internal/cloud"
 )
 
-var (
-	reports     []Report
-	reportsLock sync.Mutex
-)
+// defaultCollector stores the reports added with the package functions.
+var defaultCollector = NewCollector()
 
 const (
 	// FormatPlain send e-mail containing only ascii characters.
@@ -44,11 +41,11 @@ func (f Format) String() string {
 }
 
 const formatHTMLPrefix = `<!DOCTYPE html>
-<html lang="en">
+<html lang="{{.}}">
   <head>
     <meta charset="utf-8">
     <meta name="viewport" content="width=device-width, initial-scale=1">
-    <title>toglacier report</title>
+    <title>{{tr "toglacier report"}}</title>
     <style type="text/css">
       body {
         font-family: "sans-serif";
@@ -102,6 +99,10 @@ func newBasic() basic {
 	}
 }
 
+func (b basic) hasErrors() bool {
+	return len(b.Errors) > 0
+}
+
 // SendBackup stores all useful information of an uploaded backup. It includes
 // performance data for system improvements.
 type SendBackup struct {
@@ -109,6 +110,9 @@ type SendBackup struct {
 
 	Backup    cloud.Backup
 	Paths     []string
+	Route     string   // vault name of the route, empty for the default cloud
+	Skipped   []string // files that couldn't be read, with the error
+	Partial   bool     // build stopped after the maximum duration
 	Durations struct {
 		Build   time.Duration
 		Encrypt time.Duration
@@ -146,56 +150,80 @@ func (s SendBackup) Build(f Format) (string, error) {
 	case FormatHTML:
 		tmpl = `
     <section class="report">
-      <h1>Backups Sent</h1>
+      <h1>{{tr "Backups Sent"}}</h1>
       <div class="date">
         {{.CreatedAt.Format "2006-01-02 15:04:05"}}
       </div>
       {{if ne .Backup.ID "" -}}
-      <h2>Backup</h2>
+      <h2>{{tr "Backup"}}</h2>
       <div>
-        <label>ID:</label>
+        <label>{{tr "ID:"}}</label>
         <span>{{.Backup.ID}}</span>
       </div>
       <div>
-        <label>Date:</label>
+        <label>{{tr "Date:"}}</label>
         <span>{{.Backup.CreatedAt.Format "2006-01-02 15:04:05"}}</span>
       </div>
       <div>
-        <label>Vault:</label>
+        <label>{{tr "Vault:"}}</label>
         <span>{{.Backup.VaultName}}</span>
       </div>
       <div>
-        <label>Checksum:</label>
+        <label>{{tr "Checksum:"}}</label>
         <span>{{.Backup.Checksum}}</span>
       </div>
       <div>
-        <label>Location:</label>
+        <label>{{tr "Location:"}}</label>
         <span>{{.Backup.Location}}</span>
       </div>
+      {{- if ne .Backup.MachineID ""}}
+      <div>
+        <label>{{tr "Machine:"}}</label>
+        <span>{{.Backup.MachineID}}</span>
+      </div>
+      {{- end}}
+      {{- if ne .Backup.Comment ""}}
+      <div>
+        <label>{{tr "Comment:"}}</label>
+        <span>{{.Backup.Comment}}</span>
+      </div>
+      {{- end}}
       {{- end}}
       <div>
-        <label>Paths:</label>
+        <label>{{tr "Paths:"}}</label>
         <ul>
           {{range $path := .Paths -}}
           <li>{{$path}}</li>
           {{- end}}
         </ul>
       </div>
-      <h2>Durations</h2>
+      {{- if .Skipped}}
+      <h2>{{tr "Skipped Files"}}</h2>
+      <ul>
+        {{range $skipped := .Skipped -}}
+        <li>{{$skipped}}</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+      {{- if .Partial}}
+      <h2>{{tr "Partial Backup"}}</h2>
+      <p>{{tr "The maximum duration was reached, the files not analyzed keep their previous version."}}</p>
+      {{- end}}
+      <h2>{{tr "Durations"}}</h2>
       <div>
-        <label>Build:</label>
+        <label>{{tr "Build:"}}</label>
         <span>{{.Durations.Build}}</span>
       </div>
       <div>
-        <label>Encrypt:</label>
+        <label>{{tr "Encrypt:"}}</label>
         <span>{{.Durations.Encrypt}}</span>
       </div>
       <div>
-        <label>Send:</label>
+        <label>{{tr "Send:"}}</label>
         <span>{{.Durations.Send}}</span>
       </div>
       {{if .Errors -}}
-      <h2>Errors</h2>
+      <h2>{{tr "Errors"}}</h2>
       <ul>
         {{range $err := .Errors -}}
         <li>{{$err}}</li>
@@ -210,30 +238,51 @@ func (s SendBackup) Build(f Format) (string, error) {
 
 	default:
 		tmpl = `
-[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] Backups Sent
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Backups Sent"}}
 
   {{if ne .Backup.ID "" -}}
-  Backup
-  ------
-
-    ID:          {{.Backup.ID}}
-    Date:        {{.Backup.CreatedAt.Format "2006-01-02 15:04:05"}}
-    Vault:       {{.Backup.VaultName}}
-    Checksum:    {{.Backup.Checksum}}
-    Location:    {{.Backup.Location}}
-    Paths:       {{range $path := .Paths}}{{$path}} {{end}}
+  {{tr "Backup"}}
+  {{underline "Backup"}}
+
+    {{label "ID:" 13}}{{.Backup.ID}}
+    {{label "Date:" 13}}{{.Backup.CreatedAt.Format "2006-01-02 15:04:05"}}
+    {{label "Vault:" 13}}{{.Backup.VaultName}}
+    {{label "Checksum:" 13}}{{.Backup.Checksum}}
+    {{label "Location:" 13}}{{.Backup.Location}}
+    {{- if ne .Backup.MachineID ""}}
+    {{label "Machine:" 13}}{{.Backup.MachineID}}
+    {{- end}}
+    {{- if ne .Backup.Comment ""}}
+    {{label "Comment:" 13}}{{.Backup.Comment}}
+    {{- end}}
+    {{label "Paths:" 13}}{{range $path := .Paths}}{{$path}} {{end}}
+  {{- end}}
+  {{- if .Skipped}}
+
+  {{tr "Skipped Files"}}
+  {{underline "Skipped Files"}}
+    {{range $skipped := .Skipped}}
+    * {{$skipped}}
+    {{- end}}
+  {{- end}}
+  {{- if .Partial}}
+
+  {{tr "Partial Backup"}}
+  {{underline "Partial Backup"}}
+
+    {{tr "The maximum duration was reached, the files not analyzed keep their previous version."}}
   {{- end}}
 
-  Durations
-  ---------
+  {{tr "Durations"}}
+  {{underline "Durations"}}
 
-    Build:       {{.Durations.Build}}
-    Encrypt:     {{.Durations.Encrypt}}
-    Send:        {{.Durations.Send}}
+    {{label "Build:" 13}}{{.Durations.Build}}
+    {{label "Encrypt:" 13}}{{.Durations.Encrypt}}
+    {{label "Send:" 13}}{{.Durations.Send}}
 
   {{if .Errors -}}
-  Errors
-  ------
+  {{tr "Errors"}}
+  {{underline "Errors"}}
     {{range $err := .Errors}}
     * {{$err}}
     {{- end -}}
@@ -241,7 +290,7 @@ func (s SendBackup) Build(f Format) (string, error) {
   `
 	}
 
-	t := template.Must(template.New("report").Parse(tmpl))
+	t := newTemplate(tmpl)
 
 	var buffer bytes.Buffer
 	if err := t.Execute(&buffer, s); err != nil {
@@ -290,17 +339,17 @@ func (l ListBackups) Build(f Format) (string, error) {
 	case FormatHTML:
 		tmpl = `
     <section class="report">
-      <h1>List Backup</h1>
+      <h1>{{tr "List Backup"}}</h1>
       <div class="date">
         {{.CreatedAt.Format "2006-01-02 15:04:05"}}
       </div>
-      <h2>Durations</h2>
+      <h2>{{tr "Durations"}}</h2>
       <div>
-        <label>List:</label>
+        <label>{{tr "List:"}}</label>
         <span>{{.Durations.List}}</span>
       </div>
       {{if .Errors -}}
-      <h2>Errors</h2>
+      <h2>{{tr "Errors"}}</h2>
       <ul>
         {{range $err := .Errors -}}
         <li>{{$err}}</li>
@@ -315,16 +364,16 @@ func (l ListBackups) Build(f Format) (string, error) {
 
 	default:
 		tmpl = `
-[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] List Backup
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "List Backup"}}
 
-  Durations
-  ---------
+  {{tr "Durations"}}
+  {{underline "Durations"}}
 
-    List:        {{.Durations.List}}
+    {{label "List:" 13}}{{.Durations.List}}
 
   {{if .Errors -}}
-  Errors
-  ------
+  {{tr "Errors"}}
+  {{underline "Errors"}}
     {{range $err := .Errors}}
     * {{$err}}
     {{- end -}}
@@ -332,7 +381,7 @@ func (l ListBackups) Build(f Format) (string, error) {
   `
 	}
 
-	t := template.Must(template.New("report").Parse(tmpl))
+	t := newTemplate(tmpl)
 
 	var buffer bytes.Buffer
 	if err := t.Execute(&buffer, l); err != nil {
@@ -342,11 +391,14 @@ func (l ListBackups) Build(f Format) (string, error) {
 }
 
 // RemoveOldBackups stores useful information about the removed backups,
-// including performance issues.
+// including performance issues. The old backups that couldn't be removed
+// because they are still referenced by newer backups are listed as preserved.
 type RemoveOldBackups struct {
 	basic
 
 	Backups   []cloud.Backup
+	Preserved []cloud.Backup
+	Pinned    []cloud.Backup
 	Durations struct {
 		List   time.Duration
 		Remove time.Duration
@@ -384,19 +436,21 @@ func (r RemoveOldBackups) Build(f Format) (string, error) {
 	case FormatHTML:
 		tmpl = `
     <section class="report">
-      <h1>Remove Old Backups</h1>
+      <h1>{{tr "Remove Old Backups"}}</h1>
       <div class="date">
         {{.CreatedAt.Format "2006-01-02 15:04:05"}}
       </div>
-      <h2>Backups</h2>
+      <h2>{{tr "Backups"}}</h2>
       <table>
         <thead>
           <tr>
-            <th>ID</th>
-            <th>Date</th>
-            <th>Vault</th>
-            <th>Checksum</th>
-            <th>Location</th>
+            <th>{{tr "ID"}}</th>
+            <th>{{tr "Date"}}</th>
+            <th>{{tr "Vault"}}</th>
+            <th>{{tr "Checksum"}}</th>
+            <th>{{tr "Location"}}</th>
+            <th>{{tr "Machine"}}</th>
+            <th>{{tr "Comment"}}</th>
           </tr>
         </thead>
         <tbody>
@@ -406,20 +460,40 @@ func (r RemoveOldBackups) Build(f Format) (string, error) {
           <td>{{$backup.VaultName}}</td>
           <td>{{$backup.Checksum}}</td>
           <td>{{$backup.Location}}</td>
+          <td>{{$backup.MachineID}}</td>
+          <td>{{$backup.Comment}}</td>
           {{- end}}
         </tbody>
       </table>
-      <h2>Durations</h2>
+      {{if .Preserved -}}
+      <h2>{{tr "Preserved"}}</h2>
+      <p>{{tr "Old backups still referenced by newer backups."}}</p>
+      <ul>
+        {{range $backup := .Preserved -}}
+        <li>{{$backup.ID}} ({{$backup.CreatedAt.Format "2006-01-02 15:04:05"}})</li>
+        {{end -}}
+      </ul>
+      {{end -}}
+      {{if .Pinned -}}
+      <h2>{{tr "Pinned"}}</h2>
+      <p>{{tr "Backups protected from the removal by the administrator."}}</p>
+      <ul>
+        {{range $backup := .Pinned -}}
+        <li>{{$backup.ID}} ({{$backup.CreatedAt.Format "2006-01-02 15:04:05"}})</li>
+        {{end -}}
+      </ul>
+      {{end -}}
+      <h2>{{tr "Durations"}}</h2>
       <div>
-        <label>List:</label>
+        <label>{{tr "List:"}}</label>
         <span>{{.Durations.List}}</span>
       </div>
       <div>
-        <label>Remove:</label>
+        <label>{{tr "Remove:"}}</label>
         <span>{{.Durations.Remove}}</span>
       </div>
       {{if .Errors -}}
-      <h2>Errors</h2>
+      <h2>{{tr "Errors"}}</h2>
       <ul>
         {{range $err := .Errors -}}
         <li>{{$err}}</li>
@@ -434,27 +508,53 @@ func (r RemoveOldBackups) Build(f Format) (string, error) {
 
 	default:
 		tmpl = `
-[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] Remove Old Backups
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Remove Old Backups"}}
 
-  Backups
-  -------
+  {{tr "Backups"}}
+  {{underline "Backups"}}
     {{range $backup := .Backups}}
-    * ID:        {{$backup.ID}}
-      Date:      {{$backup.CreatedAt.Format "2006-01-02 15:04:05"}}
-      Vault:     {{$backup.VaultName}}
-      Checksum:  {{$backup.Checksum}}
-      Location:  {{$backup.Location}}
+    * {{label "ID:" 11}}{{$backup.ID}}
+      {{label "Date:" 11}}{{$backup.CreatedAt.Format "2006-01-02 15:04:05"}}
+      {{label "Vault:" 11}}{{$backup.VaultName}}
+      {{label "Checksum:" 11}}{{$backup.Checksum}}
+      {{label "Location:" 11}}{{$backup.Location}}
+      {{- if ne $backup.MachineID ""}}
+      {{label "Machine:" 11}}{{$backup.MachineID}}
+      {{- end}}
+      {{- if ne $backup.Comment ""}}
+      {{label "Comment:" 11}}{{$backup.Comment}}
+      {{- end}}
     {{- end}}
 
-  Durations
-  ---------
+  {{if .Preserved -}}
+  {{tr "Preserved"}}
+  {{underline "Preserved"}}
 
-    List:        {{.Durations.List}}
-    Remove:      {{.Durations.Remove}}
+    {{tr "Old backups still referenced by newer backups."}}
+    {{range $backup := .Preserved}}
+    * {{$backup.ID}} ({{$backup.CreatedAt.Format "2006-01-02 15:04:05"}})
+    {{- end}}
+
+  {{end -}}
+  {{if .Pinned -}}
+  {{tr "Pinned"}}
+  {{underline "Pinned"}}
+
+    {{tr "Backups protected from the removal by the administrator."}}
+    {{range $backup := .Pinned}}
+    * {{$backup.ID}} ({{$backup.CreatedAt.Format "2006-01-02 15:04:05"}})
+    {{- end}}
+
+  {{end -}}
+  {{tr "Durations"}}
+  {{underline "Durations"}}
+
+    {{label "List:" 13}}{{.Durations.List}}
+    {{label "Remove:" 13}}{{.Durations.Remove}}
 
   {{if .Errors -}}
-  Errors
-  ------
+  {{tr "Errors"}}
+  {{underline "Errors"}}
     {{range $err := .Errors}}
     * {{$err}}
     {{- end -}}
@@ -462,7 +562,7 @@ func (r RemoveOldBackups) Build(f Format) (string, error) {
   `
 	}
 
-	t := template.Must(template.New("report").Parse(tmpl))
+	t := newTemplate(tmpl)
 
 	var buffer bytes.Buffer
 	if err := t.Execute(&buffer, r); err != nil {
@@ -471,21 +571,30 @@ func (r RemoveOldBackups) Build(f Format) (string, error) {
 	return buffer.String(), nil
 }
 
-// Test is a simple test report only to check if everything is working well.
-type Test struct {
+// Escalation is a high-severity report sent when an action failed too many
+// consecutive times. The last log lines of the failed run can be attached, so
+// the problem can be diagnosed without accessing the log file.
+type Escalation struct {
 	basic
+
+	Action   string
+	Failures int
+	Log      []string
 }
 
-// NewTest initialize a new test report to verify the notification mechanisms.
-func NewTest() Test {
-	return Test{
-		basic: newBasic(),
+// NewEscalation initialize a new report item to alert about consecutive
+// failures of an action.
+func NewEscalation(action string, failures int) Escalation {
+	return Escalation{
+		basic:    newBasic(),
+		Action:   action,
+		Failures: failures,
 	}
 }
 
-// Build creates a report for testing purpose. On error it will return an
-// Error type encapsulated in a traceable error. To retrieve the desired error
-// you can do:
+// Build creates a report alerting about consecutive failures. On error it will
+// return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
 //
 //     type causer interface {
 //       Cause() error
@@ -499,26 +608,34 @@ func NewTest() Test {
 //         // unknown error
 //       }
 //     }
-func (tr Test) Build(f Format) (string, error) {
+func (e Escalation) Build(f Format) (string, error) {
 	var tmpl string
 
 	switch f {
 	case FormatHTML:
 		tmpl = `
     <section class="report">
-      <h1>Test report</h1>
+      <h1>{{tr "Escalation"}}</h1>
       <div class="date">
         {{.CreatedAt.Format "2006-01-02 15:04:05"}}
       </div>
-      <p>Testing the notification mechanisms.</p>
+      <p>{{tr "Action “%s” failed %d consecutive times." .Action .Failures}}</p>
       {{if .Errors -}}
-      <h2>Errors</h2>
+      <h2>{{tr "Errors"}}</h2>
       <ul>
         {{range $err := .Errors -}}
         <li>{{$err}}</li>
         {{end -}}
       </ul>
       {{- end}}
+      {{- if .Log}}
+      <h2>{{tr "Log"}}</h2>
+      <pre>
+{{- range $line := .Log}}
+{{$line}}
+{{- end}}
+      </pre>
+      {{- end}}
     </section>
   `
 
@@ -527,50 +644,131 @@ func (tr Test) Build(f Format) (string, error) {
 
 	default:
 		tmpl = `
-[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] Test report
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Escalation"}}
 
-  Testing the notification mechanisms.
+  {{tr "Action “%s” failed %d consecutive times." .Action .Failures}}
 
   {{if .Errors -}}
-  Errors
-  ------
+  {{tr "Errors"}}
+  {{underline "Errors"}}
     {{range $err := .Errors}}
     * {{$err}}
     {{- end -}}
   {{- end}}
+  {{- if .Log}}
+
+  {{tr "Log"}}
+  {{underline "Log"}}
+    {{range $line := .Log}}
+    {{$line}}
+    {{- end -}}
+  {{- end}}
   `
 	}
 
-	t := template.Must(template.New("report").Parse(tmpl))
+	t := newTemplate(tmpl)
 
 	var buffer bytes.Buffer
-	if err := t.Execute(&buffer, tr); err != nil {
+	if err := t.Execute(&buffer, e); err != nil {
 		return "", errors.WithStack(newError(ErrorCodeTemplate, err))
 	}
 	return buffer.String(), nil
 }
 
-// Add stores the report information to be retrieved later.
-func Add(r Report) {
-	reportsLock.Lock()
-	defer reportsLock.Unlock()
+// Paused stores a scheduled action that was skipped because the scheduled
+// actions were suspended.
+type Paused struct {
+	basic
 
-	reports = append(reports, r)
+	Action string
+	Until  time.Time
 }
 
-// Clear removes all reports from the internal cache. Useful for testing
-// environments.
-func Clear() {
-	reportsLock.Lock()
-	defer reportsLock.Unlock()
+// NewPaused initialize a new report item for an action skipped while paused. A
+// zero until date means that the actions are suspended until resumed.
+func NewPaused(action string, until time.Time) Paused {
+	return Paused{
+		basic:  newBasic(),
+		Action: action,
+		Until:  until,
+	}
+}
+
+// Build creates a report informing the skipped action. On error it will return
+// an Error type encapsulated in a traceable error. To retrieve the desired
+// error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *report.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (p Paused) Build(f Format) (string, error) {
+	var tmpl string
+
+	switch f {
+	case FormatHTML:
+		tmpl = `
+    <section class="report">
+      <h1>{{tr "Paused"}}</h1>
+      <div class="date">
+        {{.CreatedAt.Format "2006-01-02 15:04:05"}}
+      </div>
+      <p>{{if .Until.IsZero}}{{tr "Action “%s” skipped, scheduler paused until resumed." .Action}}{{else}}{{tr "Action “%s” skipped, scheduler paused until %s." .Action (.Until.Format "2006-01-02 15:04:05")}}{{end}}</p>
+    </section>
+  `
+
+	case FormatPlain:
+		fallthrough
+
+	default:
+		tmpl = `
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Paused"}}
+
+  {{if .Until.IsZero}}{{tr "Action “%s” skipped, scheduler paused until resumed." .Action}}{{else}}{{tr "Action “%s” skipped, scheduler paused until %s." .Action (.Until.Format "2006-01-02 15:04:05")}}{{end}}
+  `
+	}
+
+	t := newTemplate(tmpl)
 
-	reports = []Report{}
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, p); err != nil {
+		return "", errors.WithStack(newError(ErrorCodeTemplate, err))
+	}
+	return buffer.String(), nil
 }
 
-// Build generates the report in the specify format. Every time this function is
-// called the internal cache of reports is cleared. On error it will return an
-// Error type encapsulated in a traceable error. To retrieve the desired error
-// you can do:
+// Approval stores a backup that was deferred because its archive is bigger
+// than the confirmation threshold. The command approves the upload.
+type Approval struct {
+	basic
+
+	Size      int64
+	Threshold int64
+	Command   string
+}
+
+// NewApproval initialize a new report item asking to approve the upload of a
+// big backup.
+func NewApproval(size, threshold int64, command string) Approval {
+	return Approval{
+		basic:     newBasic(),
+		Size:      size,
+		Threshold: threshold,
+		Command:   command,
+	}
+}
+
+// Build creates a report asking to approve the upload. On error it will return
+// an Error type encapsulated in a traceable error. To retrieve the desired
+// error you can do:
 //
 //     type causer interface {
 //       Cause() error
@@ -584,26 +782,1146 @@ func Clear() {
 //         // unknown error
 //       }
 //     }
-func Build(f Format) (string, error) {
-	reportsLock.Lock()
-	defer reportsLock.Unlock()
-	defer func() {
-		reports = nil
-	}()
+func (a Approval) Build(f Format) (string, error) {
+	var tmpl string
 
-	var buffer string
-	for _, r := range reports {
-		tmp, err := r.Build(f)
-		if err != nil {
-			return "", errors.WithStack(err)
-		}
+	switch f {
+	case FormatHTML:
+		tmpl = `
+    <section class="report">
+      <h1>{{tr "Approval Required"}}</h1>
+      <div class="date">
+        {{.CreatedAt.Format "2006-01-02 15:04:05"}}
+      </div>
+      <p>{{tr "Backup of %s deferred, above the confirmation threshold of %s." (size .Size) (size .Threshold)}}</p>
+      <p>{{tr "To send it, run:"}} <code>{{.Command}}</code></p>
+    </section>
+  `
 
-		// using fmt.Sprintln to create a cross platform line break
-		buffer += fmt.Sprintln(tmp)
+	case FormatPlain:
+		fallthrough
+
+	default:
+		tmpl = `
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Approval Required"}}
+
+  {{tr "Backup of %s deferred, above the confirmation threshold of %s." (size .Size) (size .Threshold)}}
+
+  {{tr "To send it, run:"}} {{.Command}}
+  `
 	}
 
-	if f == FormatHTML {
-		buffer = formatHTMLPrefix + buffer + formatHTMLSuffix
+	t := newTemplate(tmpl)
+
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, a); err != nil {
+		return "", errors.WithStack(newError(ErrorCodeTemplate, err))
+	}
+	return buffer.String(), nil
+}
+
+// ScheduledAction stores when an action of the scheduler will run again.
+type ScheduledAction struct {
+	Action string
+	Next   time.Time
+}
+
+// Schedule stores the next runs of the scheduled actions and the fingerprint of
+// the active configuration, so the administrator can confirm that the expected
+// schedule and configuration are running.
+type Schedule struct {
+	basic
+
+	ConfigFingerprint string
+	NextRuns          []ScheduledAction
+}
+
+// NewSchedule initialize a new report item with the schedule and configuration
+// details.
+func NewSchedule(configFingerprint string) Schedule {
+	return Schedule{
+		basic:             newBasic(),
+		ConfigFingerprint: configFingerprint,
+	}
+}
+
+// Build creates a report with the next runs of the scheduled actions and the
+// configuration fingerprint. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *report.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s Schedule) Build(f Format) (string, error) {
+	var tmpl string
+
+	switch f {
+	case FormatHTML:
+		tmpl = `
+    <section class="report">
+      <h1>{{tr "Schedule"}}</h1>
+      <div class="date">
+        {{.CreatedAt.Format "2006-01-02 15:04:05"}}
+      </div>
+      <p>{{tr "Configuration fingerprint: %s" .ConfigFingerprint}}</p>
+      {{if .NextRuns -}}
+      <h2>{{tr "Next runs"}}</h2>
+      <table>
+        {{range $nextRun := .NextRuns -}}
+        <tr>
+          <th>{{$nextRun.Action}}</th>
+          <td>{{$nextRun.Next.Format "2006-01-02 15:04:05"}}</td>
+        </tr>
+        {{end -}}
+      </table>
+      {{- end}}
+    </section>
+  `
+
+	case FormatPlain:
+		fallthrough
+
+	default:
+		tmpl = `
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Schedule"}}
+
+  {{tr "Configuration fingerprint: %s" .ConfigFingerprint}}
+
+  {{if .NextRuns -}}
+  {{tr "Next runs"}}
+  {{underline "Next runs"}}
+    {{range $nextRun := .NextRuns}}
+    * {{$nextRun.Action}}: {{$nextRun.Next.Format "2006-01-02 15:04:05"}}
+    {{- end -}}
+  {{- end}}
+  `
+	}
+
+	t := newTemplate(tmpl)
+
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, s); err != nil {
+		return "", errors.WithStack(newError(ErrorCodeTemplate, err))
+	}
+	return buffer.String(), nil
+}
+
+// Journal stores the backups that were sent to the cloud but couldn't be saved
+// in the local storage. They are kept in the recovery journal until they are
+// saved in the local storage, as until then they are invisible to the tool.
+type Journal struct {
+	basic
+
+	Pending   []cloud.Backup
+	Recovered []cloud.Backup
+}
+
+// NewJournal initialize a new report item to warn about inconsistencies
+// between the cloud and the local storage.
+func NewJournal() Journal {
+	return Journal{
+		basic: newBasic(),
+	}
+}
+
+// Build creates a report with the backups pending in the recovery journal and
+// the ones that were recovered. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *report.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (j Journal) Build(f Format) (string, error) {
+	var tmpl string
+
+	switch f {
+	case FormatHTML:
+		tmpl = `
+    <section class="report">
+      <h1>{{tr "Recovery Journal"}}</h1>
+      <div class="date">
+        {{.CreatedAt.Format "2006-01-02 15:04:05"}}
+      </div>
+      {{if .Pending -}}
+      <h2>{{tr "Pending"}}</h2>
+      <p>{{tr "Backups sent to the cloud that aren't in the local storage yet."}}</p>
+      <ul>
+        {{range $backup := .Pending -}}
+        <li>{{$backup.ID}} ({{$backup.VaultName}}, {{$backup.CreatedAt.Format "2006-01-02 15:04:05"}})</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+      {{if .Recovered -}}
+      <h2>{{tr "Recovered"}}</h2>
+      <ul>
+        {{range $backup := .Recovered -}}
+        <li>{{$backup.ID}} ({{$backup.VaultName}}, {{$backup.CreatedAt.Format "2006-01-02 15:04:05"}})</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+      {{if .Errors -}}
+      <h2>{{tr "Errors"}}</h2>
+      <ul>
+        {{range $err := .Errors -}}
+        <li>{{$err}}</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+    </section>
+  `
+
+	case FormatPlain:
+		fallthrough
+
+	default:
+		tmpl = `
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Recovery Journal"}}
+
+  {{if .Pending -}}
+  {{tr "Pending"}}
+  {{underline "Pending"}}
+
+    {{tr "Backups sent to the cloud that aren't in the local storage yet."}}
+    {{range $backup := .Pending}}
+    * {{$backup.ID}} ({{$backup.VaultName}}, {{$backup.CreatedAt.Format "2006-01-02 15:04:05"}})
+    {{- end}}
+
+  {{end -}}
+  {{if .Recovered -}}
+  {{tr "Recovered"}}
+  {{underline "Recovered"}}
+    {{range $backup := .Recovered}}
+    * {{$backup.ID}} ({{$backup.VaultName}}, {{$backup.CreatedAt.Format "2006-01-02 15:04:05"}})
+    {{- end}}
+
+  {{end -}}
+  {{if .Errors -}}
+  {{tr "Errors"}}
+  {{underline "Errors"}}
+    {{range $err := .Errors}}
+    * {{$err}}
+    {{- end -}}
+  {{- end}}
+  `
+	}
+
+	t := newTemplate(tmpl)
+
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, j); err != nil {
+		return "", errors.WithStack(newError(ErrorCodeTemplate, err))
+	}
+	return buffer.String(), nil
+}
+
+// Spool stores the archives that couldn't be sent to the cloud and were kept in
+// the spool directory, and the spooled archives that were sent later. Pending
+// is the number of archives still waiting in the spool.
+type Spool struct {
+	basic
+
+	Spooled []string
+	Sent    []cloud.Backup
+	Pending int
+}
+
+// NewSpool initialize a new report item to inform the archives that are
+// waiting in the spool directory.
+func NewSpool() Spool {
+	return Spool{
+		basic: newBasic(),
+	}
+}
+
+// Build creates a report with the archives added to the spool directory and
+// the ones that were sent. On error it will return an Error type encapsulated
+// in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *report.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s Spool) Build(f Format) (string, error) {
+	var tmpl string
+
+	switch f {
+	case FormatHTML:
+		tmpl = `
+    <section class="report">
+      <h1>{{tr "Upload Spool"}}</h1>
+      <div class="date">
+        {{.CreatedAt.Format "2006-01-02 15:04:05"}}
+      </div>
+      <div>
+        <label>{{tr "Pending archives:"}}</label>
+        <span>{{.Pending}}</span>
+      </div>
+      {{if .Spooled -}}
+      <h2>{{tr "Spooled"}}</h2>
+      <p>{{tr "Archives that couldn't be sent to the cloud, they will be sent later."}}</p>
+      <ul>
+        {{range $id := .Spooled -}}
+        <li>{{$id}}</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+      {{if .Sent -}}
+      <h2>{{tr "Sent"}}</h2>
+      <ul>
+        {{range $backup := .Sent -}}
+        <li>{{$backup.ID}} ({{$backup.VaultName}}, {{$backup.CreatedAt.Format "2006-01-02 15:04:05"}})</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+      {{if .Errors -}}
+      <h2>{{tr "Errors"}}</h2>
+      <ul>
+        {{range $err := .Errors -}}
+        <li>{{$err}}</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+    </section>
+  `
+
+	case FormatPlain:
+		fallthrough
+
+	default:
+		tmpl = `
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Upload Spool"}}
+
+  {{label "Pending archives:" 18}}{{.Pending}}
+
+  {{if .Spooled -}}
+  {{tr "Spooled"}}
+  {{underline "Spooled"}}
+
+    {{tr "Archives that couldn't be sent to the cloud, they will be sent later."}}
+    {{range $id := .Spooled}}
+    * {{$id}}
+    {{- end}}
+
+  {{end -}}
+  {{if .Sent -}}
+  {{tr "Sent"}}
+  {{underline "Sent"}}
+    {{range $backup := .Sent}}
+    * {{$backup.ID}} ({{$backup.VaultName}}, {{$backup.CreatedAt.Format "2006-01-02 15:04:05"}})
+    {{- end}}
+
+  {{end -}}
+  {{if .Errors -}}
+  {{tr "Errors"}}
+  {{underline "Errors"}}
+    {{range $err := .Errors}}
+    * {{$err}}
+    {{- end -}}
+  {{- end}}
+  `
+	}
+
+	t := newTemplate(tmpl)
+
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, s); err != nil {
+		return "", errors.WithStack(newError(ErrorCodeTemplate, err))
+	}
+	return buffer.String(), nil
+}
+
+// TrashedBackup is a backup waiting in the trash, that will be removed from the
+// cloud when the grace period expires.
+type TrashedBackup struct {
+	ID       string
+	RemoveAt time.Time
+}
+
+// Trash stores the backups moved to the trash by the user, and the trashed
+// backups removed from the cloud after the grace period.
+type Trash struct {
+	basic
+
+	Trashed []TrashedBackup
+	Removed []cloud.Backup
+}
+
+// NewTrash initialize a new report item to inform the backups moved to the
+// trash or removed from it.
+func NewTrash() Trash {
+	return Trash{
+		basic: newBasic(),
+	}
+}
+
+// Build creates a report with the backups moved to the trash and the ones
+// removed from the cloud. On error it will return an Error type encapsulated in
+// a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *report.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (tr Trash) Build(f Format) (string, error) {
+	var tmpl string
+
+	switch f {
+	case FormatHTML:
+		tmpl = `
+    <section class="report">
+      <h1>{{tr "Trash"}}</h1>
+      <div class="date">
+        {{.CreatedAt.Format "2006-01-02 15:04:05"}}
+      </div>
+      {{if .Trashed -}}
+      <h2>{{tr "Trashed"}}</h2>
+      <p>{{tr "Backups removed by the user, they can be restored until they are removed from the cloud."}}</p>
+      <ul>
+        {{range $trashed := .Trashed -}}
+        <li>{{$trashed.ID}} ({{tr "removed at %s" ($trashed.RemoveAt.Format "2006-01-02 15:04:05")}})</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+      {{if .Removed -}}
+      <h2>{{tr "Removed"}}</h2>
+      <ul>
+        {{range $backup := .Removed -}}
+        <li>{{$backup.ID}} ({{$backup.VaultName}}, {{$backup.CreatedAt.Format "2006-01-02 15:04:05"}})</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+      {{if .Errors -}}
+      <h2>{{tr "Errors"}}</h2>
+      <ul>
+        {{range $err := .Errors -}}
+        <li>{{$err}}</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+    </section>
+  `
+
+	case FormatPlain:
+		fallthrough
+
+	default:
+		tmpl = `
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Trash"}}
+
+  {{if .Trashed -}}
+  {{tr "Trashed"}}
+  {{underline "Trashed"}}
+
+    {{tr "Backups removed by the user, they can be restored until they are removed from the cloud."}}
+    {{range $trashed := .Trashed}}
+    * {{$trashed.ID}} ({{tr "removed at %s" ($trashed.RemoveAt.Format "2006-01-02 15:04:05")}})
+    {{- end}}
+
+  {{end -}}
+  {{if .Removed -}}
+  {{tr "Removed"}}
+  {{underline "Removed"}}
+    {{range $backup := .Removed}}
+    * {{$backup.ID}} ({{$backup.VaultName}}, {{$backup.CreatedAt.Format "2006-01-02 15:04:05"}})
+    {{- end}}
+
+  {{end -}}
+  {{if .Errors -}}
+  {{tr "Errors"}}
+  {{underline "Errors"}}
+    {{range $err := .Errors}}
+    * {{$err}}
+    {{- end -}}
+  {{- end}}
+  `
+	}
+
+	t := newTemplate(tmpl)
+
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, tr); err != nil {
+		return "", errors.WithStack(newError(ErrorCodeTemplate, err))
+	}
+	return buffer.String(), nil
+}
+
+// PinnedBackup is a backup protected from the old backups removal (legal
+// hold).
+type PinnedBackup struct {
+	ID       string
+	User     string
+	Reason   string
+	PinnedAt time.Time
+
+	// Until is when the pin expires. When zero the backup is pinned until it
+	// is unpinned.
+	Until time.Time
+}
+
+// Pins stores the active pins, so the backups under legal hold are visible to
+// the administrator for compliance purposes.
+type Pins struct {
+	basic
+
+	Pinned []PinnedBackup
+}
+
+// NewPins initialize a new report item to inform the backups protected from
+// the old backups removal.
+func NewPins() Pins {
+	return Pins{
+		basic: newBasic(),
+	}
+}
+
+// Build creates a report with the active pins, informing who placed them, when
+// and why. On error it will return an Error type encapsulated in a traceable
+// error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *report.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (p Pins) Build(f Format) (string, error) {
+	var tmpl string
+
+	switch f {
+	case FormatHTML:
+		tmpl = `
+    <section class="report">
+      <h1>{{tr "Pinned Backups"}}</h1>
+      <div class="date">
+        {{.CreatedAt.Format "2006-01-02 15:04:05"}}
+      </div>
+      <table>
+        <thead>
+          <tr>
+            <th>{{tr "ID"}}</th>
+            <th>{{tr "User"}}</th>
+            <th>{{tr "Pinned at"}}</th>
+            <th>{{tr "Until"}}</th>
+            <th>{{tr "Reason"}}</th>
+          </tr>
+        </thead>
+        <tbody>
+          {{range $pinned := .Pinned -}}
+          <tr>
+            <td>{{$pinned.ID}}</td>
+            <td>{{$pinned.User}}</td>
+            <td>{{$pinned.PinnedAt.Format "2006-01-02 15:04:05"}}</td>
+            <td>{{if $pinned.Until.IsZero}}{{tr "unpinned"}}{{else}}{{$pinned.Until.Format "2006-01-02 15:04:05"}}{{end}}</td>
+            <td>{{$pinned.Reason}}</td>
+          </tr>
+          {{end -}}
+        </tbody>
+      </table>
+      {{if .Errors -}}
+      <h2>{{tr "Errors"}}</h2>
+      <ul>
+        {{range $err := .Errors -}}
+        <li>{{$err}}</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+    </section>
+  `
+
+	case FormatPlain:
+		fallthrough
+
+	default:
+		tmpl = `
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Pinned Backups"}}
+  {{range $pinned := .Pinned}}
+  * {{label "ID:" 11}}{{$pinned.ID}}
+    {{label "User:" 11}}{{$pinned.User}}
+    {{label "Pinned at:" 11}}{{$pinned.PinnedAt.Format "2006-01-02 15:04:05"}}
+    {{label "Until:" 11}}{{if $pinned.Until.IsZero}}{{tr "unpinned"}}{{else}}{{$pinned.Until.Format "2006-01-02 15:04:05"}}{{end}}
+    {{- if ne $pinned.Reason ""}}
+    {{label "Reason:" 11}}{{$pinned.Reason}}
+    {{- end}}
+  {{- end}}
+
+  {{if .Errors -}}
+  {{tr "Errors"}}
+  {{underline "Errors"}}
+    {{range $err := .Errors}}
+    * {{$err}}
+    {{- end -}}
+  {{- end}}
+  `
+	}
+
+	t := newTemplate(tmpl)
+
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, p); err != nil {
+		return "", errors.WithStack(newError(ErrorCodeTemplate, err))
+	}
+	return buffer.String(), nil
+}
+
+// History stores the backups of the retained history, from the oldest to the
+// newest, so the growth of the archives and of the backup durations can be
+// followed.
+type History struct {
+	basic
+
+	Backups []cloud.Backup
+}
+
+// NewHistory initialize a new report item with the retained backups.
+func NewHistory() History {
+	return History{
+		basic: newBasic(),
+	}
+}
+
+// SizeChart draws the size of the archives as an inline SVG chart.
+func (h History) SizeChart() string {
+	values := make([]float64, len(h.Backups))
+	titles := make([]string, len(h.Backups))
+	var max int64
+
+	for i, backup := range h.Backups {
+		values[i] = float64(backup.Size)
+		titles[i] = fmt.Sprintf("%s: %s", backup.CreatedAt.Format("2006-01-02 15:04:05"), formatSize(backup.Size))
+
+		if backup.Size > max {
+			max = backup.Size
+		}
+	}
+
+	return barChart(values, titles, Translate("max. %s", formatSize(max)))
+}
+
+// DurationChart draws the duration of the backups as an inline SVG chart.
+// When the durations are unknown an empty string is returned.
+func (h History) DurationChart() string {
+	values := make([]float64, len(h.Backups))
+	titles := make([]string, len(h.Backups))
+	var max time.Duration
+
+	for i, backup := range h.Backups {
+		duration := formatDuration(backup.Duration)
+		values[i] = duration.Seconds()
+		titles[i] = fmt.Sprintf("%s: %s", backup.CreatedAt.Format("2006-01-02 15:04:05"), duration)
+
+		if duration > max {
+			max = duration
+		}
+	}
+
+	if max == 0 {
+		return ""
+	}
+
+	return barChart(values, titles, Translate("max. %s", max))
+}
+
+// Build creates a report with charts of the backups history. The plain format
+// lists the backups, as it can't contain charts. On error it will return an
+// Error type encapsulated in a traceable error. To retrieve the desired error
+// you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *report.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (h History) Build(f Format) (string, error) {
+	var tmpl string
+
+	switch f {
+	case FormatHTML:
+		tmpl = `
+    <section class="report">
+      <h1>{{tr "Backups History"}}</h1>
+      <div class="date">
+        {{.CreatedAt.Format "2006-01-02 15:04:05"}}
+      </div>
+      {{if .Backups -}}
+      <h2>{{tr "Size"}}</h2>
+      {{.SizeChart}}
+      {{- with .DurationChart}}
+      <h2>{{tr "Duration"}}</h2>
+      {{.}}
+      {{- end}}
+      {{- end}}
+      {{if .Errors -}}
+      <h2>{{tr "Errors"}}</h2>
+      <ul>
+        {{range $err := .Errors -}}
+        <li>{{$err}}</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+    </section>
+  `
+
+	case FormatPlain:
+		fallthrough
+
+	default:
+		tmpl = `
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Backups History"}}
+  {{range $backup := .Backups}}
+  * {{$backup.CreatedAt.Format "2006-01-02 15:04:05"}}  {{printf "%-10s" (size $backup.Size)}}{{if $backup.Duration}}{{duration $backup.Duration}}{{end}}
+  {{- end}}
+
+  {{if .Errors -}}
+  {{tr "Errors"}}
+  {{underline "Errors"}}
+    {{range $err := .Errors}}
+    * {{$err}}
+    {{- end -}}
+  {{- end}}
+  `
+	}
+
+	t := newTemplate(tmpl)
+
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, h); err != nil {
+		return "", errors.WithStack(newError(ErrorCodeTemplate, err))
+	}
+	return buffer.String(), nil
+}
+
+// RetrieveBackup stores the result of a backup retrieval, so the administrator
+// knows what was written to disk even when the retrieval takes hours.
+type RetrieveBackup struct {
+	basic
+
+	ID        string
+	Archives  []string // archives downloaded and extracted to restore the files
+	Restored  int
+	Skipped   int // unmodified files or existing files kept by the overwrite policy
+	Failed    int
+	Size      int64 // bytes written to disk
+	Durations struct {
+		Download time.Duration
+		Extract  time.Duration
+	}
+}
+
+// NewRetrieveBackup initialize a new report item for the backup retrieval
+// action.
+func NewRetrieveBackup() RetrieveBackup {
+	return RetrieveBackup{
+		basic: newBasic(),
+	}
+}
+
+// Build creates a report with the files restored from a backup. On error it
+// will return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *report.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (r RetrieveBackup) Build(f Format) (string, error) {
+	var tmpl string
+
+	switch f {
+	case FormatHTML:
+		tmpl = `
+    <section class="report">
+      <h1>{{tr "Backup Retrieved"}}</h1>
+      <div class="date">
+        {{.CreatedAt.Format "2006-01-02 15:04:05"}}
+      </div>
+      <h2>{{tr "Backup"}}</h2>
+      <div>
+        <label>{{tr "ID:"}}</label>
+        <span>{{.ID}}</span>
+      </div>
+      <div>
+        <label>{{tr "Archives:"}}</label>
+        <ul>
+          {{range $archive := .Archives -}}
+          <li>{{$archive}}</li>
+          {{end -}}
+        </ul>
+      </div>
+      <div>
+        <label>{{tr "Restored:"}}</label>
+        <span>{{.Restored}}</span>
+      </div>
+      <div>
+        <label>{{tr "Skipped:"}}</label>
+        <span>{{.Skipped}}</span>
+      </div>
+      <div>
+        <label>{{tr "Failed:"}}</label>
+        <span>{{.Failed}}</span>
+      </div>
+      <div>
+        <label>{{tr "Size:"}}</label>
+        <span>{{size .Size}}</span>
+      </div>
+      <h2>{{tr "Durations"}}</h2>
+      <div>
+        <label>{{tr "Download:"}}</label>
+        <span>{{duration .Durations.Download}}</span>
+      </div>
+      <div>
+        <label>{{tr "Extract:"}}</label>
+        <span>{{duration .Durations.Extract}}</span>
+      </div>
+      {{if .Errors -}}
+      <h2>{{tr "Errors"}}</h2>
+      <ul>
+        {{range $err := .Errors -}}
+        <li>{{$err}}</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+    </section>
+  `
+
+	case FormatPlain:
+		fallthrough
+
+	default:
+		tmpl = `
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Backup Retrieved"}}
+
+  {{tr "Backup"}}
+  {{underline "Backup"}}
+
+    {{label "ID:" 13}}{{.ID}}
+    {{label "Archives:" 13}}{{range $archive := .Archives}}{{$archive}} {{end}}
+    {{label "Restored:" 13}}{{.Restored}}
+    {{label "Skipped:" 13}}{{.Skipped}}
+    {{label "Failed:" 13}}{{.Failed}}
+    {{label "Size:" 13}}{{size .Size}}
+
+  {{tr "Durations"}}
+  {{underline "Durations"}}
+
+    {{label "Download:" 13}}{{duration .Durations.Download}}
+    {{label "Extract:" 13}}{{duration .Durations.Extract}}
+
+  {{if .Errors -}}
+  {{tr "Errors"}}
+  {{underline "Errors"}}
+    {{range $err := .Errors}}
+    * {{$err}}
+    {{- end -}}
+  {{- end}}
+  `
+	}
+
+	t := newTemplate(tmpl)
+
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, r); err != nil {
+		return "", errors.WithStack(newError(ErrorCodeTemplate, err))
+	}
+	return buffer.String(), nil
+}
+
+// Test is a simple test report only to check if everything is working well.
+type Test struct {
+	basic
+}
+
+// NewTest initialize a new test report to verify the notification mechanisms.
+func NewTest() Test {
+	return Test{
+		basic: newBasic(),
+	}
+}
+
+// Build creates a report for testing purpose. On error it will return an
+// Error type encapsulated in a traceable error. To retrieve the desired error
+// you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *report.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (tr Test) Build(f Format) (string, error) {
+	var tmpl string
+
+	switch f {
+	case FormatHTML:
+		tmpl = `
+    <section class="report">
+      <h1>{{tr "Test report"}}</h1>
+      <div class="date">
+        {{.CreatedAt.Format "2006-01-02 15:04:05"}}
+      </div>
+      <p>{{tr "Testing the notification mechanisms."}}</p>
+      {{if .Errors -}}
+      <h2>{{tr "Errors"}}</h2>
+      <ul>
+        {{range $err := .Errors -}}
+        <li>{{$err}}</li>
+        {{end -}}
+      </ul>
+      {{- end}}
+    </section>
+  `
+
+	case FormatPlain:
+		fallthrough
+
+	default:
+		tmpl = `
+[{{.CreatedAt.Format "2006-01-02 15:04:05"}}] {{tr "Test report"}}
+
+  {{tr "Testing the notification mechanisms."}}
+
+  {{if .Errors -}}
+  {{tr "Errors"}}
+  {{underline "Errors"}}
+    {{range $err := .Errors}}
+    * {{$err}}
+    {{- end -}}
+  {{- end}}
+  `
+	}
+
+	t := newTemplate(tmpl)
+
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, tr); err != nil {
+		return "", errors.WithStack(newError(ErrorCodeTemplate, err))
+	}
+	return buffer.String(), nil
+}
+
+// Collector stores the reports of the actions performed by the tool until they
+// are built. Each instance has its own reports, so many instances can be used
+// at the same time (e.g. different configurations in the same process). It is
+// safe for concurrent use.
+type Collector struct {
+	reports     []Report
+	reportsLock sync.Mutex
+}
+
+// NewCollector creates a collector without reports.
+func NewCollector() *Collector {
+	return new(Collector)
+}
+
+// Default returns the collector used by the package functions Add, Clear and
+// Build.
+func Default() *Collector {
+	return defaultCollector
+}
+
+// Add stores the report information to be retrieved later.
+func (c *Collector) Add(r Report) {
+	c.reportsLock.Lock()
+	defer c.reportsLock.Unlock()
+
+	c.reports = append(c.reports, r)
+}
+
+// Extract removes from the collector the reports that match, returning them.
+// Useful to send some reports to other recipients.
+func (c *Collector) Extract(match func(Report) bool) []Report {
+	c.reportsLock.Lock()
+	defer c.reportsLock.Unlock()
+
+	var extracted, remaining []Report
+	for _, r := range c.reports {
+		if match(r) {
+			extracted = append(extracted, r)
+		} else {
+			remaining = append(remaining, r)
+		}
+	}
+
+	c.reports = remaining
+	return extracted
+}
+
+// Incomplete informs if any report has errors, or a backup that left files
+// out (unreadable files or a build stopped after the maximum duration), even
+// when the actions succeeded.
+func (c *Collector) Incomplete() bool {
+	c.reportsLock.Lock()
+	defer c.reportsLock.Unlock()
+
+	for _, r := range c.reports {
+		if sendBackup, ok := r.(SendBackup); ok && (sendBackup.Partial || len(sendBackup.Skipped) > 0) {
+			return true
+		}
+
+		if withErrors, ok := r.(interface{ hasErrors() bool }); ok && withErrors.hasErrors() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Clear removes all reports from the collector.
+func (c *Collector) Clear() {
+	c.reportsLock.Lock()
+	defer c.reportsLock.Unlock()
+
+	c.reports = nil
+}
+
+// Build generates the report in the specify format. Every time this function is
+// called the reports of the collector are cleared. On error it will return an
+// Error type encapsulated in a traceable error. To retrieve the desired error
+// you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *report.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (c *Collector) Build(f Format) (string, error) {
+	c.reportsLock.Lock()
+	defer c.reportsLock.Unlock()
+	defer func() {
+		c.reports = nil
+	}()
+
+	return build(f, c.reports...)
+}
+
+// Add stores the report information in the default collector to be retrieved
+// later.
+func Add(r Report) {
+	defaultCollector.Add(r)
+}
+
+// Clear removes all reports from the default collector. Useful for testing
+// environments.
+func Clear() {
+	defaultCollector.Clear()
+}
+
+// Build generates the report of the default collector in the specify format.
+// Every time this function is called the reports of the default collector are
+// cleared. On error it will return an Error type encapsulated in a traceable
+// error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *report.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func Build(f Format) (string, error) {
+	return defaultCollector.Build(f)
+}
+
+// BuildReports generates the content of the given reports in the specify
+// format. It doesn't touch the collectors, so it is useful for notifications
+// that must be sent immediately. On error it will return an Error type
+// encapsulated in a traceable error.
+func BuildReports(f Format, r ...Report) (string, error) {
+	return build(f, r...)
+}
+
+func build(f Format, reports ...Report) (string, error) {
+	var buffer string
+	for _, r := range reports {
+		tmp, err := r.Build(f)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+
+		// using fmt.Sprintln to create a cross platform line break
+		buffer += fmt.Sprintln(tmp)
+	}
+
+	if f == FormatHTML {
+		// the HTML document is identified with the language of the reports
+		var prefix bytes.Buffer
+		if err := newTemplate(formatHTMLPrefix).Execute(&prefix, currentLocale()); err != nil {
+			return "", errors.WithStack(newError(ErrorCodeTemplate, err))
+		}
+
+		buffer = prefix.String() + buffer + formatHTMLSuffix
 	}
 
 	return buffer, nil
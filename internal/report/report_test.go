@@ -2,8 +2,10 @@ package report_test
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -66,8 +68,12 @@ func TestBuild(t *testing.T) {
 						VaultName: "vault",
 						Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
 						Location:  cloud.LocationAWS,
+						MachineID: "server1",
+						Comment:   "before OS upgrade",
 					}
 					r.Paths = []string{"/data/important-files"}
+					r.Skipped = []string{"/data/important-files/secret.txt: permission denied"}
+					r.Partial = true
 					r.Durations.Build = 2 * time.Second
 					r.Durations.Encrypt = 6 * time.Second
 					r.Durations.Send = 6 * time.Minute
@@ -101,6 +107,22 @@ func TestBuild(t *testing.T) {
 							VaultName: "vault",
 							Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
 							Location:  cloud.LocationAWS,
+							MachineID: "server1",
+							Comment:   "before OS upgrade",
+						},
+					}
+					r.Preserved = []cloud.Backup{
+						{
+							ID:        "AWSID122",
+							CreatedAt: date.Add(-time.Hour),
+							VaultName: "vault",
+						},
+					}
+					r.Pinned = []cloud.Backup{
+						{
+							ID:        "AWSID121",
+							CreatedAt: date.Add(-48 * time.Hour),
+							VaultName: "vault",
 						},
 					}
 					r.Durations.List = 6 * time.Hour
@@ -114,6 +136,140 @@ func TestBuild(t *testing.T) {
 					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
 					return r
 				}(),
+				func() report.Report {
+					r := report.NewEscalation("backup", 3)
+					r.CreatedAt = date
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					r.Log = []string{
+						"2017-03-10 14:10:40 [info] backup: analyzing path “/data”",
+						"2017-03-10 14:10:46 [error] timeout connecting to aws",
+					}
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewPaused("backup", date.Add(time.Hour))
+					r.CreatedAt = date
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewPaused("remove old backups", time.Time{})
+					r.CreatedAt = date
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewApproval(60*1024*1024*1024, 50*1024*1024*1024, "toglacier approve")
+					r.CreatedAt = date
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewSchedule("4f8a0c1d2e3b")
+					r.CreatedAt = date
+					r.NextRuns = append(r.NextRuns, report.ScheduledAction{
+						Action: "backup",
+						Next:   date.Add(10 * time.Hour),
+					})
+					r.NextRuns = append(r.NextRuns, report.ScheduledAction{
+						Action: "send report",
+						Next:   date.Add(24 * time.Hour),
+					})
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewJournal()
+					r.CreatedAt = date
+					r.Pending = append(r.Pending, cloud.Backup{
+						ID:        "AWSID124",
+						CreatedAt: date.Add(-time.Minute),
+						VaultName: "vault",
+					})
+					r.Recovered = append(r.Recovered, cloud.Backup{
+						ID:        "AWSID122",
+						CreatedAt: date.Add(-24 * time.Hour),
+						VaultName: "vault",
+					})
+					r.Errors = append(r.Errors, errors.New("database locked"))
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewSpool()
+					r.CreatedAt = date
+					r.Spooled = append(r.Spooled, "1489155046000000000")
+					r.Sent = append(r.Sent, cloud.Backup{
+						ID:        "AWSID125",
+						CreatedAt: date.Add(-time.Hour),
+						VaultName: "vault",
+					})
+					r.Pending = 1
+					r.Errors = append(r.Errors, errors.New("network unreachable"))
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewTrash()
+					r.CreatedAt = date
+					r.Trashed = append(r.Trashed, report.TrashedBackup{
+						ID:       "AWSID126",
+						RemoveAt: date.Add(7 * 24 * time.Hour),
+					})
+					r.Removed = append(r.Removed, cloud.Backup{
+						ID:        "AWSID120",
+						CreatedAt: date.Add(-48 * time.Hour),
+						VaultName: "vault",
+					})
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewPins()
+					r.CreatedAt = date
+					r.Pinned = append(r.Pinned, report.PinnedBackup{
+						ID:       "AWSID121",
+						User:     "john",
+						Reason:   "before migration",
+						PinnedAt: date.Add(-24 * time.Hour),
+					})
+					r.Pinned = append(r.Pinned, report.PinnedBackup{
+						ID:       "AWSID122",
+						User:     "mary",
+						PinnedAt: date.Add(-time.Hour),
+						Until:    date.Add(30 * 24 * time.Hour),
+					})
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewHistory()
+					r.CreatedAt = date
+					r.Backups = []cloud.Backup{
+						{
+							ID:        "AWSID123",
+							CreatedAt: date.Add(-24 * time.Hour),
+							Size:      1048576,
+							Duration:  2*time.Minute + 30*time.Second + 500*time.Millisecond,
+						},
+						{
+							ID:        "AWSID124",
+							CreatedAt: date,
+							Size:      2097152,
+							Duration:  5 * time.Minute,
+						},
+					}
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewRetrieveBackup()
+					r.CreatedAt = date
+					r.ID = "AWSID123"
+					r.Archives = []string{"AWSID122", "AWSID123"}
+					r.Restored = 10
+					r.Skipped = 2
+					r.Failed = 1
+					r.Size = 1048576
+					r.Durations.Download = 3*time.Hour + 500*time.Millisecond
+					r.Durations.Extract = 30 * time.Second
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					return r
+				}(),
 			},
 			format: report.FormatPlain,
 			expected: `[2017-03-10 14:10:46] Backups Sent
@@ -126,8 +282,20 @@ func TestBuild(t *testing.T) {
     Vault:       vault
     Checksum:    cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705
     Location:    aws
+    Machine:     server1
+    Comment:     before OS upgrade
     Paths:       /data/important-files
 
+  Skipped Files
+  -------------
+
+    * /data/important-files/secret.txt: permission denied
+
+  Partial Backup
+  --------------
+
+    The maximum duration was reached, the files not analyzed keep their previous version.
+
   Durations
   ---------
 
@@ -181,6 +349,22 @@ func TestBuild(t *testing.T) {
       Vault:     vault
       Checksum:  cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705
       Location:  aws
+      Machine:   server1
+      Comment:   before OS upgrade
+
+  Preserved
+  ---------
+
+    Old backups still referenced by newer backups.
+
+    * AWSID122 (2017-03-10 13:10:46)
+
+  Pinned
+  ------
+
+    Backups protected from the removal by the administrator.
+
+    * AWSID121 (2017-03-08 14:10:46)
 
   Durations
   ---------
@@ -198,6 +382,165 @@ func TestBuild(t *testing.T) {
 
   Testing the notification mechanisms.
 
+  Errors
+  ------
+
+    * timeout connecting to aws
+
+
+[2017-03-10 14:10:46] Escalation
+
+  Action “backup” failed 3 consecutive times.
+
+  Errors
+  ------
+
+    * timeout connecting to aws
+
+  Log
+  ---
+
+    2017-03-10 14:10:40 [info] backup: analyzing path “/data”
+    2017-03-10 14:10:46 [error] timeout connecting to aws
+
+
+[2017-03-10 14:10:46] Paused
+
+  Action “backup” skipped, scheduler paused until 2017-03-10 15:10:46.
+
+
+[2017-03-10 14:10:46] Paused
+
+  Action “remove old backups” skipped, scheduler paused until resumed.
+
+
+[2017-03-10 14:10:46] Approval Required
+
+  Backup of 60.0 GB deferred, above the confirmation threshold of 50.0 GB.
+
+  To send it, run: toglacier approve
+  
+
+[2017-03-10 14:10:46] Schedule
+
+  Configuration fingerprint: 4f8a0c1d2e3b
+
+  Next runs
+  ---------
+
+    * backup: 2017-03-11 00:10:46
+    * send report: 2017-03-11 14:10:46
+
+
+[2017-03-10 14:10:46] Recovery Journal
+
+  Pending
+  -------
+
+    Backups sent to the cloud that aren't in the local storage yet.
+
+    * AWSID124 (vault, 2017-03-10 14:09:46)
+
+  Recovered
+  ---------
+
+    * AWSID122 (vault, 2017-03-09 14:10:46)
+
+  Errors
+  ------
+
+    * database locked
+
+
+[2017-03-10 14:10:46] Upload Spool
+
+  Pending archives: 1
+
+  Spooled
+  -------
+
+    Archives that couldn't be sent to the cloud, they will be sent later.
+
+    * 1489155046000000000
+
+  Sent
+  ----
+
+    * AWSID125 (vault, 2017-03-10 13:10:46)
+
+  Errors
+  ------
+
+    * network unreachable
+
+
+[2017-03-10 14:10:46] Trash
+
+  Trashed
+  -------
+
+    Backups removed by the user, they can be restored until they are removed from the cloud.
+
+    * AWSID126 (removed at 2017-03-17 14:10:46)
+
+  Removed
+  -------
+
+    * AWSID120 (vault, 2017-03-08 14:10:46)
+
+  Errors
+  ------
+
+    * timeout connecting to aws
+
+
+[2017-03-10 14:10:46] Pinned Backups
+
+  * ID:        AWSID121
+    User:      john
+    Pinned at: 2017-03-09 14:10:46
+    Until:     unpinned
+    Reason:    before migration
+  * ID:        AWSID122
+    User:      mary
+    Pinned at: 2017-03-10 13:10:46
+    Until:     2017-04-09 14:10:46
+
+  Errors
+  ------
+
+    * timeout connecting to aws
+
+
+[2017-03-10 14:10:46] Backups History
+
+  * 2017-03-09 14:10:46  1.0 MB    2m30s
+  * 2017-03-10 14:10:46  2.0 MB    5m0s
+
+  Errors
+  ------
+
+    * timeout connecting to aws
+
+
+[2017-03-10 14:10:46] Backup Retrieved
+
+  Backup
+  ------
+
+    ID:          AWSID123
+    Archives:    AWSID122 AWSID123 
+    Restored:    10
+    Skipped:     2
+    Failed:      1
+    Size:        1.0 MB
+
+  Durations
+  ---------
+
+    Download:    3h0m0s
+    Extract:     30s
+
   Errors
   ------
 
@@ -215,8 +558,12 @@ func TestBuild(t *testing.T) {
 						VaultName: "vault",
 						Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
 						Location:  cloud.LocationAWS,
+						MachineID: "server1",
+						Comment:   "before OS upgrade",
 					}
 					r.Paths = []string{"/data/important-files"}
+					r.Skipped = []string{"/data/important-files/secret.txt: permission denied"}
+					r.Partial = true
 					r.Durations.Build = 2 * time.Second
 					r.Durations.Encrypt = 6 * time.Second
 					r.Durations.Send = 6 * time.Minute
@@ -250,6 +597,22 @@ func TestBuild(t *testing.T) {
 							VaultName: "vault",
 							Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
 							Location:  cloud.LocationAWS,
+							MachineID: "server1",
+							Comment:   "before OS upgrade",
+						},
+					}
+					r.Preserved = []cloud.Backup{
+						{
+							ID:        "AWSID122",
+							CreatedAt: date.Add(-time.Hour),
+							VaultName: "vault",
+						},
+					}
+					r.Pinned = []cloud.Backup{
+						{
+							ID:        "AWSID121",
+							CreatedAt: date.Add(-48 * time.Hour),
+							VaultName: "vault",
 						},
 					}
 					r.Durations.List = 6 * time.Hour
@@ -263,6 +626,140 @@ func TestBuild(t *testing.T) {
 					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
 					return r
 				}(),
+				func() report.Report {
+					r := report.NewEscalation("backup", 3)
+					r.CreatedAt = date
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					r.Log = []string{
+						"2017-03-10 14:10:40 [info] backup: analyzing path “/data”",
+						"2017-03-10 14:10:46 [error] timeout connecting to aws",
+					}
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewPaused("backup", date.Add(time.Hour))
+					r.CreatedAt = date
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewPaused("remove old backups", time.Time{})
+					r.CreatedAt = date
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewApproval(60*1024*1024*1024, 50*1024*1024*1024, "toglacier approve")
+					r.CreatedAt = date
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewSchedule("4f8a0c1d2e3b")
+					r.CreatedAt = date
+					r.NextRuns = append(r.NextRuns, report.ScheduledAction{
+						Action: "backup",
+						Next:   date.Add(10 * time.Hour),
+					})
+					r.NextRuns = append(r.NextRuns, report.ScheduledAction{
+						Action: "send report",
+						Next:   date.Add(24 * time.Hour),
+					})
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewJournal()
+					r.CreatedAt = date
+					r.Pending = append(r.Pending, cloud.Backup{
+						ID:        "AWSID124",
+						CreatedAt: date.Add(-time.Minute),
+						VaultName: "vault",
+					})
+					r.Recovered = append(r.Recovered, cloud.Backup{
+						ID:        "AWSID122",
+						CreatedAt: date.Add(-24 * time.Hour),
+						VaultName: "vault",
+					})
+					r.Errors = append(r.Errors, errors.New("database locked"))
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewSpool()
+					r.CreatedAt = date
+					r.Spooled = append(r.Spooled, "1489155046000000000")
+					r.Sent = append(r.Sent, cloud.Backup{
+						ID:        "AWSID125",
+						CreatedAt: date.Add(-time.Hour),
+						VaultName: "vault",
+					})
+					r.Pending = 1
+					r.Errors = append(r.Errors, errors.New("network unreachable"))
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewTrash()
+					r.CreatedAt = date
+					r.Trashed = append(r.Trashed, report.TrashedBackup{
+						ID:       "AWSID126",
+						RemoveAt: date.Add(7 * 24 * time.Hour),
+					})
+					r.Removed = append(r.Removed, cloud.Backup{
+						ID:        "AWSID120",
+						CreatedAt: date.Add(-48 * time.Hour),
+						VaultName: "vault",
+					})
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewPins()
+					r.CreatedAt = date
+					r.Pinned = append(r.Pinned, report.PinnedBackup{
+						ID:       "AWSID121",
+						User:     "john",
+						Reason:   "before migration",
+						PinnedAt: date.Add(-24 * time.Hour),
+					})
+					r.Pinned = append(r.Pinned, report.PinnedBackup{
+						ID:       "AWSID122",
+						User:     "mary",
+						PinnedAt: date.Add(-time.Hour),
+						Until:    date.Add(30 * 24 * time.Hour),
+					})
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewHistory()
+					r.CreatedAt = date
+					r.Backups = []cloud.Backup{
+						{
+							ID:        "AWSID123",
+							CreatedAt: date.Add(-24 * time.Hour),
+							Size:      1048576,
+							Duration:  2*time.Minute + 30*time.Second + 500*time.Millisecond,
+						},
+						{
+							ID:        "AWSID124",
+							CreatedAt: date,
+							Size:      2097152,
+							Duration:  5 * time.Minute,
+						},
+					}
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewRetrieveBackup()
+					r.CreatedAt = date
+					r.ID = "AWSID123"
+					r.Archives = []string{"AWSID122", "AWSID123"}
+					r.Restored = 10
+					r.Skipped = 2
+					r.Failed = 1
+					r.Size = 1048576
+					r.Durations.Download = 3*time.Hour + 500*time.Millisecond
+					r.Durations.Extract = 30 * time.Second
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					return r
+				}(),
 			},
 			format: report.FormatHTML,
 			expected: `<!DOCTYPE html>
@@ -329,12 +826,26 @@ func TestBuild(t *testing.T) {
         <label>Location:</label>
         <span>aws</span>
       </div>
+      <div>
+        <label>Machine:</label>
+        <span>server1</span>
+      </div>
+      <div>
+        <label>Comment:</label>
+        <span>before OS upgrade</span>
+      </div>
       <div>
         <label>Paths:</label>
         <ul>
           <li>/data/important-files</li>
         </ul>
       </div>
+      <h2>Skipped Files</h2>
+      <ul>
+        <li>/data/important-files/secret.txt: permission denied</li>
+        </ul>
+      <h2>Partial Backup</h2>
+      <p>The maximum duration was reached, the files not analyzed keep their previous version.</p>
       <h2>Durations</h2>
       <div>
         <label>Build:</label>
@@ -418,6 +929,8 @@ func TestBuild(t *testing.T) {
             <th>Vault</th>
             <th>Checksum</th>
             <th>Location</th>
+            <th>Machine</th>
+            <th>Comment</th>
           </tr>
         </thead>
         <tbody>
@@ -426,11 +939,23 @@ func TestBuild(t *testing.T) {
           <td>vault</td>
           <td>cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705</td>
           <td>aws</td>
+          <td>server1</td>
+          <td>before OS upgrade</td>
         </tbody>
       </table>
-      <h2>Durations</h2>
-      <div>
-        <label>List:</label>
+      <h2>Preserved</h2>
+      <p>Old backups still referenced by newer backups.</p>
+      <ul>
+        <li>AWSID122 (2017-03-10 13:10:46)</li>
+      </ul>
+      <h2>Pinned</h2>
+      <p>Backups protected from the removal by the administrator.</p>
+      <ul>
+        <li>AWSID121 (2017-03-08 14:10:46)</li>
+      </ul>
+      <h2>Durations</h2>
+      <div>
+        <label>List:</label>
         <span>6h0m0s</span>
       </div>
       <div>
@@ -456,6 +981,251 @@ func TestBuild(t *testing.T) {
       </ul>
     </section>
 
+
+    <section class="report">
+      <h1>Escalation</h1>
+      <div class="date">
+        2017-03-10 14:10:46
+      </div>
+      <p>Action “backup” failed 3 consecutive times.</p>
+      <h2>Errors</h2>
+      <ul>
+        <li>timeout connecting to aws</li>
+      </ul>
+      <h2>Log</h2>
+      <pre>
+2017-03-10 14:10:40 [info] backup: analyzing path “/data”
+2017-03-10 14:10:46 [error] timeout connecting to aws
+      </pre>
+    </section>
+
+
+    <section class="report">
+      <h1>Paused</h1>
+      <div class="date">
+        2017-03-10 14:10:46
+      </div>
+      <p>Action “backup” skipped, scheduler paused until 2017-03-10 15:10:46.</p>
+    </section>
+
+
+    <section class="report">
+      <h1>Paused</h1>
+      <div class="date">
+        2017-03-10 14:10:46
+      </div>
+      <p>Action “remove old backups” skipped, scheduler paused until resumed.</p>
+    </section>
+  
+
+    <section class="report">
+      <h1>Approval Required</h1>
+      <div class="date">
+        2017-03-10 14:10:46
+      </div>
+      <p>Backup of 60.0 GB deferred, above the confirmation threshold of 50.0 GB.</p>
+      <p>To send it, run: <code>toglacier approve</code></p>
+    </section>
+
+
+    <section class="report">
+      <h1>Schedule</h1>
+      <div class="date">
+        2017-03-10 14:10:46
+      </div>
+      <p>Configuration fingerprint: 4f8a0c1d2e3b</p>
+      <h2>Next runs</h2>
+      <table>
+        <tr>
+          <th>backup</th>
+          <td>2017-03-11 00:10:46</td>
+        </tr>
+        <tr>
+          <th>send report</th>
+          <td>2017-03-11 14:10:46</td>
+        </tr>
+      </table>
+    </section>
+
+
+    <section class="report">
+      <h1>Recovery Journal</h1>
+      <div class="date">
+        2017-03-10 14:10:46
+      </div>
+      <h2>Pending</h2>
+      <p>Backups sent to the cloud that aren't in the local storage yet.</p>
+      <ul>
+        <li>AWSID124 (vault, 2017-03-10 14:09:46)</li>
+        </ul>
+      <h2>Recovered</h2>
+      <ul>
+        <li>AWSID122 (vault, 2017-03-09 14:10:46)</li>
+        </ul>
+      <h2>Errors</h2>
+      <ul>
+        <li>database locked</li>
+        </ul>
+    </section>
+
+
+    <section class="report">
+      <h1>Upload Spool</h1>
+      <div class="date">
+        2017-03-10 14:10:46
+      </div>
+      <div>
+        <label>Pending archives:</label>
+        <span>1</span>
+      </div>
+      <h2>Spooled</h2>
+      <p>Archives that couldn't be sent to the cloud, they will be sent later.</p>
+      <ul>
+        <li>1489155046000000000</li>
+        </ul>
+      <h2>Sent</h2>
+      <ul>
+        <li>AWSID125 (vault, 2017-03-10 13:10:46)</li>
+        </ul>
+      <h2>Errors</h2>
+      <ul>
+        <li>network unreachable</li>
+        </ul>
+    </section>
+
+
+    <section class="report">
+      <h1>Trash</h1>
+      <div class="date">
+        2017-03-10 14:10:46
+      </div>
+      <h2>Trashed</h2>
+      <p>Backups removed by the user, they can be restored until they are removed from the cloud.</p>
+      <ul>
+        <li>AWSID126 (removed at 2017-03-17 14:10:46)</li>
+        </ul>
+      <h2>Removed</h2>
+      <ul>
+        <li>AWSID120 (vault, 2017-03-08 14:10:46)</li>
+        </ul>
+      <h2>Errors</h2>
+      <ul>
+        <li>timeout connecting to aws</li>
+        </ul>
+    </section>
+
+
+    <section class="report">
+      <h1>Pinned Backups</h1>
+      <div class="date">
+        2017-03-10 14:10:46
+      </div>
+      <table>
+        <thead>
+          <tr>
+            <th>ID</th>
+            <th>User</th>
+            <th>Pinned at</th>
+            <th>Until</th>
+            <th>Reason</th>
+          </tr>
+        </thead>
+        <tbody>
+          <tr>
+            <td>AWSID121</td>
+            <td>john</td>
+            <td>2017-03-09 14:10:46</td>
+            <td>unpinned</td>
+            <td>before migration</td>
+          </tr>
+          <tr>
+            <td>AWSID122</td>
+            <td>mary</td>
+            <td>2017-03-10 13:10:46</td>
+            <td>2017-04-09 14:10:46</td>
+            <td></td>
+          </tr>
+          </tbody>
+      </table>
+      <h2>Errors</h2>
+      <ul>
+        <li>timeout connecting to aws</li>
+      </ul>
+    </section>
+
+
+    <section class="report">
+      <h1>Backups History</h1>
+      <div class="date">
+        2017-03-10 14:10:46
+      </div>
+      <h2>Size</h2>
+      <svg xmlns="http://www.w3.org/2000/svg" width="600" height="150" viewBox="0 0 600 150">
+        <text x="0" y="12" font-size="12" fill="grey">max. 2.0 MB</text>
+        <rect x="0.0" y="85.0" width="240.0" height="65.0" fill="#66ccff"><title>2017-03-09 14:10:46: 1.0 MB</title></rect>
+        <rect x="300.0" y="20.0" width="240.0" height="130.0" fill="#66ccff"><title>2017-03-10 14:10:46: 2.0 MB</title></rect>
+      </svg>
+      <h2>Duration</h2>
+      <svg xmlns="http://www.w3.org/2000/svg" width="600" height="150" viewBox="0 0 600 150">
+        <text x="0" y="12" font-size="12" fill="grey">max. 5m0s</text>
+        <rect x="0.0" y="85.0" width="240.0" height="65.0" fill="#66ccff"><title>2017-03-09 14:10:46: 2m30s</title></rect>
+        <rect x="300.0" y="20.0" width="240.0" height="130.0" fill="#66ccff"><title>2017-03-10 14:10:46: 5m0s</title></rect>
+      </svg>
+      <h2>Errors</h2>
+      <ul>
+        <li>timeout connecting to aws</li>
+      </ul>
+    </section>
+
+
+    <section class="report">
+      <h1>Backup Retrieved</h1>
+      <div class="date">
+        2017-03-10 14:10:46
+      </div>
+      <h2>Backup</h2>
+      <div>
+        <label>ID:</label>
+        <span>AWSID123</span>
+      </div>
+      <div>
+        <label>Archives:</label>
+        <ul>
+          <li>AWSID122</li>
+          <li>AWSID123</li>
+        </ul>
+      </div>
+      <div>
+        <label>Restored:</label>
+        <span>10</span>
+      </div>
+      <div>
+        <label>Skipped:</label>
+        <span>2</span>
+      </div>
+      <div>
+        <label>Failed:</label>
+        <span>1</span>
+      </div>
+      <div>
+        <label>Size:</label>
+        <span>1.0 MB</span>
+      </div>
+      <h2>Durations</h2>
+      <div>
+        <label>Download:</label>
+        <span>3h0m0s</span>
+      </div>
+      <div>
+        <label>Extract:</label>
+        <span>30s</span>
+      </div>
+      <h2>Errors</h2>
+      <ul>
+        <li>timeout connecting to aws</li>
+      </ul>
+    </section>
+
   </body>
 </html>`,
 		},
@@ -512,6 +1282,283 @@ func TestBuild(t *testing.T) {
 	}
 }
 
+func TestBuildReports(t *testing.T) {
+	date := time.Date(2017, 3, 10, 14, 10, 46, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		cached        []report.Report
+		reports       []report.Report
+		format        report.Format
+		expected      string
+		expectedError error
+	}{
+		{
+			description: "it should build only the given reports",
+			cached: []report.Report{
+				report.NewTest(),
+			},
+			reports: []report.Report{
+				func() report.Report {
+					r := report.NewEscalation("backup", 3)
+					r.CreatedAt = date
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					return r
+				}(),
+			},
+			format: report.FormatPlain,
+			expected: `[2017-03-10 14:10:46] Escalation
+
+  Action “backup” failed 3 consecutive times.
+
+  Errors
+  ------
+
+    * timeout connecting to aws`,
+		},
+		{
+			description: "it should detect an error while building a report",
+			reports: []report.Report{
+				mockReport{
+					mockBuild: func(report.Format) (string, error) {
+						return "", &report.Error{
+							Code: report.ErrorCodeTemplate,
+							Err:  errors.New("error generating report"),
+						}
+					},
+				},
+			},
+			format: report.FormatPlain,
+			expectedError: &report.Error{
+				Code: report.ErrorCodeTemplate,
+				Err:  errors.New("error generating report"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		report.Clear()
+
+		t.Run(scenario.description, func(t *testing.T) {
+			for _, r := range scenario.cached {
+				report.Add(r)
+			}
+
+			output, err := report.BuildReports(scenario.format, scenario.reports...)
+			output = strings.TrimSpace(output)
+
+			outputLines := strings.Split(output, "\n")
+			for i := range outputLines {
+				outputLines[i] = strings.TrimSpace(outputLines[i])
+			}
+
+			scenario.expected = strings.TrimSpace(scenario.expected)
+			expectedLines := strings.Split(scenario.expected, "\n")
+			for i := range expectedLines {
+				expectedLines[i] = strings.TrimSpace(expectedLines[i])
+			}
+
+			if !reflect.DeepEqual(expectedLines, outputLines) {
+				t.Errorf("output don't match.\n%s", Diff(expectedLines, outputLines))
+			}
+
+			if !report.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestCollector(t *testing.T) {
+	scenarios := []struct {
+		description string
+		collectors  int
+		reports     int
+	}{
+		{
+			description: "it should store the reports of a collector used concurrently",
+			collectors:  1,
+			reports:     100,
+		},
+		{
+			description: "it should keep the reports of each collector independent",
+			collectors:  4,
+			reports:     50,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		report.Clear()
+
+		t.Run(scenario.description, func(t *testing.T) {
+			collectors := make([]*report.Collector, scenario.collectors)
+			for i := range collectors {
+				collectors[i] = report.NewCollector()
+			}
+
+			var wg sync.WaitGroup
+			for i, collector := range collectors {
+				for j := 0; j < scenario.reports; j++ {
+					wg.Add(1)
+					go func(collector *report.Collector, content string) {
+						defer wg.Done()
+
+						collector.Add(mockReport{
+							mockBuild: func(report.Format) (string, error) {
+								return content, nil
+							},
+						})
+					}(collector, fmt.Sprintf("collector %d", i))
+				}
+			}
+			wg.Wait()
+
+			for i, collector := range collectors {
+				output, err := collector.Build(report.FormatPlain)
+				if err != nil {
+					t.Fatalf("unexpected error. details: %s", err)
+				}
+
+				lines := strings.Split(strings.TrimSpace(output), "\n")
+				if len(lines) != scenario.reports {
+					t.Errorf("unexpected number of reports in collector %d. expected “%d” and got “%d”", i, scenario.reports, len(lines))
+				}
+
+				for _, line := range lines {
+					if line != fmt.Sprintf("collector %d", i) {
+						t.Errorf("unexpected report “%s” in collector %d", line, i)
+						break
+					}
+				}
+
+				// the reports are cleared after building
+				if output, _ = collector.Build(report.FormatPlain); output != "" {
+					t.Errorf("unexpected reports after building: %s", output)
+				}
+			}
+
+			// the default collector isn't affected
+			if output, _ := report.Build(report.FormatPlain); output != "" {
+				t.Errorf("unexpected reports in the default collector: %s", output)
+			}
+		})
+	}
+}
+
+func TestCollector_Extract(t *testing.T) {
+	newReport := func(content string) report.Report {
+		return mockReport{
+			mockBuild: func(report.Format) (string, error) {
+				return content, nil
+			},
+		}
+	}
+
+	scenarios := []struct {
+		description       string
+		reports           []string
+		match             string
+		expectedExtracted string
+		expectedRemaining string
+	}{
+		{
+			description:       "it should extract the matching reports",
+			reports:           []string{"photos", "documents", "photos"},
+			match:             "photos",
+			expectedExtracted: "photos\nphotos\n",
+			expectedRemaining: "documents\n",
+		},
+		{
+			description:       "it should keep all reports when nothing matches",
+			reports:           []string{"documents"},
+			match:             "photos",
+			expectedRemaining: "documents\n",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			collector := report.NewCollector()
+			for _, content := range scenario.reports {
+				collector.Add(newReport(content))
+			}
+
+			extracted := collector.Extract(func(r report.Report) bool {
+				content, _ := r.Build(report.FormatPlain)
+				return content == scenario.match
+			})
+
+			output, err := report.BuildReports(report.FormatPlain, extracted...)
+			if err != nil {
+				t.Fatalf("unexpected error. details: %s", err)
+			}
+
+			if output != scenario.expectedExtracted {
+				t.Errorf("extracted reports don't match. expected “%s” and got “%s”", scenario.expectedExtracted, output)
+			}
+
+			if output, _ = collector.Build(report.FormatPlain); output != scenario.expectedRemaining {
+				t.Errorf("remaining reports don't match. expected “%s” and got “%s”", scenario.expectedRemaining, output)
+			}
+		})
+	}
+}
+
+func TestCollector_Incomplete(t *testing.T) {
+	scenarios := []struct {
+		description string
+		reports     func() []report.Report
+		expected    bool
+	}{
+		{
+			description: "it should detect a complete run",
+			reports: func() []report.Report {
+				return []report.Report{report.NewSendBackup(), report.NewSpool()}
+			},
+		},
+		{
+			description: "it should detect the skipped files",
+			reports: func() []report.Report {
+				sendBackup := report.NewSendBackup()
+				sendBackup.Skipped = []string{"/data/file1.txt: permission denied"}
+				return []report.Report{sendBackup}
+			},
+			expected: true,
+		},
+		{
+			description: "it should detect a partial backup",
+			reports: func() []report.Report {
+				sendBackup := report.NewSendBackup()
+				sendBackup.Partial = true
+				return []report.Report{sendBackup}
+			},
+			expected: true,
+		},
+		{
+			description: "it should detect the errors of any report",
+			reports: func() []report.Report {
+				spool := report.NewSpool()
+				spool.Errors = append(spool.Errors, errors.New("connection error"))
+				return []report.Report{report.NewSendBackup(), spool}
+			},
+			expected: true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			collector := report.NewCollector()
+			for _, r := range scenario.reports() {
+				collector.Add(r)
+			}
+
+			if incomplete := collector.Incomplete(); incomplete != scenario.expected {
+				t.Errorf("unexpected result. expected “%t” and got “%t”", scenario.expected, incomplete)
+			}
+		})
+	}
+}
+
 type mockReport struct {
 	mockBuild func(report.Format) (string, error)
 }
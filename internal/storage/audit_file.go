@@ -2,17 +2,39 @@ package storage
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/archive"
 	"github.com/rafaeljusto/toglacier/internal/cloud"
 	"github.com/rafaeljusto/toglacier/internal/log"
 )
 
+// auditFileVersionPrefix starts the first line of the audit file, followed by
+// the version of its format. Audit files without it are from before the
+// versioning (version 0). Older versions of the tool fail to read an audit
+// file with this line, as they refuse any malformed line.
+const auditFileVersionPrefix = "# toglacier audit file version "
+
+// AuditFileVersion is the version of the audit file format written by this
+// version of the tool. Older audit files are upgraded automatically, and newer
+// ones are refused, as they could be damaged.
+const AuditFileVersion = 1
+
 // AuditFile stores all backup information in a simple text file.
 type AuditFile struct {
 	logger   log.Logger
@@ -30,10 +52,17 @@ func NewAuditFile(logger log.Logger, filename string) *AuditFile {
 // Save a backup information. It stores the backup information one per line with
 // the following columns:
 //
-//     [datetime] [vaultName] [archiveID] [checksum] [size] [location]
+//     [datetime] [vaultName] [archiveID] [checksum] [size] [location] [machineID]
 //
-// The audit file doesn't store backup extra information. On error it will
-// return an Error type encapsulated in a traceable error. To retrieve the
+// The machine identifier column is only written when defined, and it is
+// escaped as it can contain spaces. The first line has the version of the
+// format (AuditFileVersion), and it is added to audit files of older
+// versions. To keep the
+// audit file format simple, the archive information of the backup is stored in
+// JSON in a separated directory, with the same name of the audit file and the
+// extension “.info”, one file per backup. The archive information is stored
+// before the line, so a listed backup always has its information. On error it
+// will return an Error type encapsulated in a traceable error. To retrieve the
 // desired error you can do:
 //
 //     type causer interface {
@@ -48,17 +77,45 @@ func NewAuditFile(logger log.Logger, filename string) *AuditFile {
 //         // unknown error
 //       }
 //     }
-func (a *AuditFile) Save(backup Backup) error {
+func (a *AuditFile) Save(ctx context.Context, backup Backup) error {
 	a.logger.Debugf("storage: saving backup “%s” in audit file storage", backup.Backup.ID)
 
-	auditFile, err := os.OpenFile(a.Filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	// a newer audit file is refused before anything is written
+	if err := a.stamp(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := a.saveInfo(backup); err != nil {
+		return errors.WithStack(err)
+	}
+
+	auditFile, err := os.OpenFile(a.Filename, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0600)
 	if err != nil {
 		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
 	}
 	defer auditFile.Close()
 
-	audit := fmt.Sprintf("%s %s %s %s %d %s\n", backup.Backup.CreatedAt.Format(time.RFC3339), backup.Backup.VaultName, backup.Backup.ID, backup.Backup.Checksum, backup.Backup.Size, backup.Backup.Location)
-	if _, err = auditFile.WriteString(audit); err != nil {
+	line := auditLine(backup)
+
+	// if the last line was truncated (e.g. power loss), the new line must not be
+	// appended to it, otherwise a valid line would also be lost
+	if truncated, err := lastLineTruncated(auditFile); err != nil {
+		return errors.WithStack(newError(ErrorCodeReadingFile, err))
+	} else if truncated {
+		line = "\n" + line
+	}
+
+	if _, err = auditFile.WriteString(line); err != nil {
+		return errors.WithStack(newError(ErrorCodeWritingFile, err))
+	}
+
+	// the backup was already sent, so its information must reach the disk before
+	// we report success
+	if err = auditFile.Sync(); err != nil {
 		return errors.WithStack(newError(ErrorCodeWritingFile, err))
 	}
 
@@ -66,8 +123,10 @@ func (a *AuditFile) Save(backup Backup) error {
 	return nil
 }
 
-// List all backup information in the storage. As the audit file doesn't store
-// backup extra information, it will be always nil. On error it will return an
+// List all backup information in the storage. The archive information is only
+// nil for the backups saved by older versions, that didn't store it. Corrupted
+// lines (e.g. a line truncated by a power loss) are ignored and reported in the
+// logs, and can be dropped with the Compact method. On error it will return an
 // Error type encapsulated in a traceable error. To retrieve the desired error
 // you can do:
 //
@@ -83,9 +142,13 @@ func (a *AuditFile) Save(backup Backup) error {
 //         // unknown error
 //       }
 //     }
-func (a *AuditFile) List() (Backups, error) {
+func (a *AuditFile) List(ctx context.Context) (Backups, error) {
 	a.logger.Debug("storage: listing backups from audit file storage")
 
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
 	auditFile, err := os.Open(a.Filename)
 	if err != nil {
 		// if the file doesn't exist we can presume that there's no backups yet
@@ -97,57 +160,180 @@ func (a *AuditFile) List() (Backups, error) {
 	}
 	defer auditFile.Close()
 
-	var backups Backups
-
-	scanner := bufio.NewScanner(auditFile)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		lineParts := strings.Split(line, " ")
+	backups, _, _, err := a.read(ctx, auditFile)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
 
-		if len(lineParts) < 4 || len(lineParts) > 6 {
-			return nil, errors.WithStack(newError(ErrorCodeFormat, err))
+	for i := range backups {
+		if backups[i].Info, err = a.info(backups[i].Backup.ID); err != nil {
+			return nil, errors.WithStack(err)
 		}
 
-		var backup Backup
+		// the audit line doesn't have a column for the stream, that is recovered
+		// from the archive information stored with the backup
+		backups[i].Backup.Stream, _ = backups[i].Info.Stream()
+	}
+
+	a.logger.Infof("storage: backups listed successfully from audit file storage")
+	return backups, nil
+}
+
+// Remove a specific backup information from the storage.  On error it will
+// return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) Remove(ctx context.Context, id string) error {
+	a.logger.Debugf("storage: removing backup “%s” from audit file storage", id)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	backups, err := a.List(ctx)
+	if err != nil {
+		return err
+	}
 
-		if backup.Backup.CreatedAt, err = time.Parse(time.RFC3339, lineParts[0]); err != nil {
-			return nil, errors.WithStack(newError(ErrorCodeDateFormat, err))
+	var content bytes.Buffer
+	content.WriteString(auditFileHeader())
+	for _, backup := range backups {
+		if backup.Backup.ID == id {
+			continue
 		}
 
-		backup.Backup.VaultName = lineParts[1]
-		backup.Backup.ID = lineParts[2]
-		backup.Backup.Checksum = lineParts[3]
+		content.WriteString(auditLine(backup))
+	}
 
-		if len(lineParts) >= 5 {
-			backup.Backup.Size, err = strconv.ParseInt(lineParts[4], 10, 64)
-			if err != nil {
-				return nil, errors.WithStack(newError(ErrorCodeSizeFormat, err))
-			}
+	if err = writeFileAtomically(a.Filename, content.Bytes()); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = a.removeInfo(id); err != nil {
+		return errors.WithStack(err)
+	}
+
+	a.logger.Infof("storage: backup “%s” removed successfully from audit file storage", id)
+	return nil
+}
+
+// RemoveBatch removes the backups of the ids rewriting the audit file only
+// once. The updated backups replace the stored ones, including their archive
+// information. On error it will return an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) RemoveBatch(ctx context.Context, updated Backups, ids []string) error {
+	a.logger.Debugf("storage: removing %d backups from audit file storage", len(ids))
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	backups, err := a.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	removed := make(map[string]bool)
+	for _, id := range ids {
+		removed[id] = true
+	}
+
+	updatedBackups := make(map[string]Backup)
+	for _, backup := range updated {
+		updatedBackups[backup.Backup.ID] = backup
+	}
+
+	var content bytes.Buffer
+	content.WriteString(auditFileHeader())
+	for _, backup := range backups {
+		if removed[backup.Backup.ID] {
+			continue
 		}
 
-		if len(lineParts) >= 6 {
-			backup.Backup.Location, err = cloud.ParseLocation(lineParts[5])
-			if err != nil {
-				return nil, errors.WithStack(newError(ErrorCodeLocation, err))
+		if updatedBackup, ok := updatedBackups[backup.Backup.ID]; ok {
+			if err = a.saveInfo(updatedBackup); err != nil {
+				return errors.WithStack(err)
 			}
+			backup = updatedBackup
+		}
 
-		} else {
-			// default location is AWS for backward compatibility
-			backup.Backup.Location = cloud.LocationAWS
+		content.WriteString(auditLine(backup))
+	}
+
+	if err = writeFileAtomically(a.Filename, content.Bytes()); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, id := range ids {
+		if err = a.removeInfo(id); err != nil {
+			return errors.WithStack(err)
 		}
+	}
 
-		backups.Add(backup)
+	a.logger.Infof("storage: %d backups removed successfully from audit file storage", len(ids))
+	return nil
+}
+
+// SaveInventoryDate stores when the local storage was synchronized with the
+// cloud inventory. To keep the audit file format simple, the date is stored in
+// a separated file, with the same name of the audit file and the extension
+// “.inventory”. On error it will return an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) SaveInventoryDate(ctx context.Context, date time.Time) error {
+	a.logger.Debugf("storage: saving inventory date “%s” in audit file storage", date.Format(time.RFC3339))
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	if err := writeFileAtomically(a.inventoryFilename(), []byte(date.Format(time.RFC3339Nano))); err != nil {
+		return errors.WithStack(err)
 	}
 
-	a.logger.Infof("storage: backups listed successfully from audit file storage")
-	return backups, nil
+	a.logger.Info("storage: inventory date saved successfully in audit file storage")
+	return nil
 }
 
-// Remove a specific backup information from the storage.  On error it will
+// InventoryDate returns when the local storage was synchronized with the cloud
+// inventory. If it never happened a zero time is returned. On error it will
 // return an Error type encapsulated in a traceable error. To retrieve the
 // desired error you can do:
 //
@@ -163,39 +349,1312 @@ func (a *AuditFile) List() (Backups, error) {
 //         // unknown error
 //       }
 //     }
-func (a *AuditFile) Remove(id string) error {
-	a.logger.Debugf("storage: removing backup “%s” from audit file storage", id)
+func (a *AuditFile) InventoryDate(ctx context.Context) (time.Time, error) {
+	a.logger.Debug("storage: retrieving inventory date from audit file storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	content, err := ioutil.ReadFile(a.inventoryFilename())
+	if err != nil {
+		// if the file doesn't exist we can presume that there was no
+		// synchronization yet
+		if pathErr, ok := err.(*os.PathError); ok && os.IsNotExist(pathErr.Err) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
 
-	backups, err := a.List()
+	date, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(content)))
 	if err != nil {
+		return time.Time{}, errors.WithStack(newError(ErrorCodeDateFormat, err))
+	}
+
+	a.logger.Info("storage: inventory date retrieved successfully from audit file storage")
+	return date, nil
+}
+
+// SavePause suspends the scheduled actions until the given date. A zero date
+// suspends them until resumed. To keep the audit file format simple, the date
+// is stored in a separated file, with the same name of the audit file and the
+// extension “.pause”. On error it will return an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) SavePause(ctx context.Context, until time.Time) error {
+	a.logger.Debugf("storage: saving pause until “%s” in audit file storage", until.Format(time.RFC3339))
+
+	if err := checkCancellation(ctx); err != nil {
 		return err
 	}
 
-	backupName := a.Filename + "." + time.Now().Format("20060102150405")
-	a.logger.Debugf("storage: moving current audit file to “%s”", backupName)
-	if err = os.Rename(a.Filename, backupName); err != nil {
-		return errors.WithStack(newError(ErrorCodeMovingFile, err))
+	if err := writeFileAtomically(a.pauseFilename(), []byte(until.Format(time.RFC3339Nano))); err != nil {
+		return errors.WithStack(err)
 	}
 
-	auditFile, err := os.OpenFile(a.Filename, os.O_CREATE|os.O_WRONLY, 0600)
-	if err != nil {
-		// TODO: recover backup file
-		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	a.logger.Info("storage: pause saved successfully in audit file storage")
+	return nil
+}
+
+// Pause returns if the scheduled actions are suspended and until when. A zero
+// date means that they are suspended until resumed. On error it will return an
+// Error type encapsulated in a traceable error. To retrieve the desired error
+// you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) Pause(ctx context.Context) (bool, time.Time, error) {
+	a.logger.Debug("storage: retrieving pause from audit file storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return false, time.Time{}, err
 	}
-	defer auditFile.Close()
 
-	for _, backup := range backups {
-		if backup.Backup.ID == id {
-			continue
+	content, err := ioutil.ReadFile(a.pauseFilename())
+	if err != nil {
+		// if the file doesn't exist the scheduled actions were never paused or
+		// were already resumed
+		if pathErr, ok := err.(*os.PathError); ok && os.IsNotExist(pathErr.Err) {
+			return false, time.Time{}, nil
 		}
 
-		audit := fmt.Sprintf("%s %s %s %s %d %s\n", backup.Backup.CreatedAt.Format(time.RFC3339), backup.Backup.VaultName, backup.Backup.ID, backup.Backup.Checksum, backup.Backup.Size, backup.Backup.Location)
-		if _, err = auditFile.WriteString(audit); err != nil {
-			// TODO: recover backup file
-			return errors.WithStack(newError(ErrorCodeWritingFile, err))
-		}
+		return false, time.Time{}, errors.WithStack(newError(ErrorCodeReadingFile, err))
 	}
 
-	a.logger.Infof("storage: backup “%s” removed successfully from audit file storage", id)
+	until, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(content)))
+	if err != nil {
+		return false, time.Time{}, errors.WithStack(newError(ErrorCodeDateFormat, err))
+	}
+
+	a.logger.Info("storage: pause retrieved successfully from audit file storage")
+	return true, until, nil
+}
+
+// RemovePause resumes the scheduled actions. On error it will return an Error
+// type encapsulated in a traceable error. To retrieve the desired error you can
+// do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) RemovePause(ctx context.Context) error {
+	a.logger.Debug("storage: removing pause from audit file storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	if err := os.Remove(a.pauseFilename()); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(newError(ErrorCodeWritingFile, err))
+	}
+
+	a.logger.Info("storage: pause removed successfully from audit file storage")
+	return nil
+}
+
+// SaveRestoreProgress stores the progress of an ongoing backup retrieval. To
+// keep the audit file format simple, the progress of all retrievals is stored
+// in JSON in a separated file, with the same name of the audit file and the
+// extension “.restore”. On error it will return an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) SaveRestoreProgress(ctx context.Context, id string, progress RestoreProgress) error {
+	a.logger.Debugf("storage: saving restore progress of backup “%s” in audit file storage", id)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	restores, err := a.restores()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	restores[id] = progress
+	if err = a.saveRestores(restores); err != nil {
+		return errors.WithStack(err)
+	}
+
+	a.logger.Infof("storage: restore progress of backup “%s” saved successfully in audit file storage", id)
 	return nil
 }
+
+// RestoreProgress returns the progress of a backup retrieval. If there's no
+// retrieval in progress an empty progress is returned. On error it will return
+// an Error type encapsulated in a traceable error. To retrieve the desired
+// error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) RestoreProgress(ctx context.Context, id string) (RestoreProgress, error) {
+	a.logger.Debugf("storage: retrieving restore progress of backup “%s” from audit file storage", id)
+
+	if err := checkCancellation(ctx); err != nil {
+		return RestoreProgress{}, err
+	}
+
+	restores, err := a.restores()
+	if err != nil {
+		return RestoreProgress{}, errors.WithStack(err)
+	}
+
+	a.logger.Infof("storage: restore progress of backup “%s” retrieved successfully from audit file storage", id)
+	return restores[id], nil
+}
+
+// RestoreProgresses returns the progress of all ongoing backup retrievals by
+// backup id. On error it will return an Error type encapsulated in a traceable
+// error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) RestoreProgresses(ctx context.Context) (map[string]RestoreProgress, error) {
+	a.logger.Debug("storage: retrieving all restore progresses from audit file storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	restores, err := a.restores()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	a.logger.Info("storage: restore progresses retrieved successfully from audit file storage")
+	return restores, nil
+}
+
+// RemoveRestoreProgress erases the progress of a finished backup retrieval. On
+// error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) RemoveRestoreProgress(ctx context.Context, id string) error {
+	a.logger.Debugf("storage: removing restore progress of backup “%s” from audit file storage", id)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	restores, err := a.restores()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if _, ok := restores[id]; !ok {
+		return nil
+	}
+
+	delete(restores, id)
+
+	if len(restores) == 0 {
+		// don't leave an empty file behind when there's no retrieval in progress
+		if err = os.Remove(a.restoreFilename()); err != nil {
+			return errors.WithStack(newError(ErrorCodeWritingFile, err))
+		}
+
+	} else if err = a.saveRestores(restores); err != nil {
+		return errors.WithStack(err)
+	}
+
+	a.logger.Infof("storage: restore progress of backup “%s” removed successfully from audit file storage", id)
+	return nil
+}
+
+func (a *AuditFile) restores() (map[string]RestoreProgress, error) {
+	restores := make(map[string]RestoreProgress)
+
+	content, err := ioutil.ReadFile(a.restoreFilename())
+	if err != nil {
+		// if the file doesn't exist there's no retrieval in progress
+		if pathErr, ok := err.(*os.PathError); ok && os.IsNotExist(pathErr.Err) {
+			return restores, nil
+		}
+
+		return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+
+	if err = json.Unmarshal(content, &restores); err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeDecodingRestoreProgress, err))
+	}
+
+	return restores, nil
+}
+
+func (a *AuditFile) saveRestores(restores map[string]RestoreProgress) error {
+	encoded, err := json.MarshalIndent(restores, "", "  ")
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingRestoreProgress, err))
+	}
+
+	if err = writeFileAtomically(a.restoreFilename(), encoded); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// SavePin protects the backup from the old backups removal. To keep the audit
+// file format simple, the pins are stored in JSON in a separated file, with the
+// same name of the audit file and the extension “.pins”. The audit trail is
+// appended to another file, with the extension “.pins.log”, before the pin is
+// stored, so no change is left unrecorded. On error it will return an Error
+// type encapsulated in a traceable error. To retrieve the desired error you
+// can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) SavePin(ctx context.Context, pin Pin) error {
+	a.logger.Debugf("storage: saving pin of backup “%s” in audit file storage", pin.ID)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	pins, err := a.pins()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	index := sort.Search(len(pins), func(i int) bool {
+		return pins[i].ID >= pin.ID
+	})
+
+	if index < len(pins) && pins[index].ID == pin.ID {
+		pins[index] = pin
+
+	} else {
+		pins = append(pins, Pin{})
+		copy(pins[index+1:], pins[index:])
+		pins[index] = pin
+	}
+
+	if err = a.addPinEvent(PinEvent{Pin: pin, Action: PinActionPin}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = a.savePins(pins); err != nil {
+		return errors.WithStack(err)
+	}
+
+	a.logger.Infof("storage: pin of backup “%s” saved successfully in audit file storage", pin.ID)
+	return nil
+}
+
+// Pins returns the backups protected from the old backups removal, including
+// the expired pins, sorted by id. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) Pins(ctx context.Context) ([]Pin, error) {
+	a.logger.Debug("storage: listing pins from audit file storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	pins, err := a.pins()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	a.logger.Info("storage: pins listed successfully from audit file storage")
+	return pins, nil
+}
+
+// RemovePin allows the backup to be removed again with the old backups. The
+// audit trail is appended before the pin is removed, so no change is left
+// unrecorded. On error it will return an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) RemovePin(ctx context.Context, unpin Pin) error {
+	a.logger.Debugf("storage: removing pin of backup “%s” from audit file storage", unpin.ID)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	pins, err := a.pins()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	index := sort.Search(len(pins), func(i int) bool {
+		return pins[i].ID >= unpin.ID
+	})
+
+	if index == len(pins) || pins[index].ID != unpin.ID {
+		return nil
+	}
+
+	pins = append(pins[:index], pins[index+1:]...)
+
+	if err = a.addPinEvent(PinEvent{Pin: unpin, Action: PinActionUnpin}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if len(pins) == 0 {
+		// don't leave an empty file behind when there's no pinned backup
+		if err = os.Remove(a.pinsFilename()); err != nil {
+			return errors.WithStack(newError(ErrorCodeWritingFile, err))
+		}
+
+	} else if err = a.savePins(pins); err != nil {
+		return errors.WithStack(err)
+	}
+
+	a.logger.Infof("storage: pin of backup “%s” removed successfully from audit file storage", unpin.ID)
+	return nil
+}
+
+// PinEvents returns the pin audit trail, in the order that the changes
+// happened. On error it will return an Error type encapsulated in a traceable
+// error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) PinEvents(ctx context.Context) ([]PinEvent, error) {
+	a.logger.Debug("storage: listing pin audit trail from audit file storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(a.pinEventsFilename())
+	if err != nil {
+		// if the file doesn't exist no backup was ever pinned
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+	defer f.Close()
+
+	var events []PinEvent
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var event PinEvent
+		if err = json.Unmarshal([]byte(line), &event); err != nil {
+			return nil, errors.WithStack(newError(ErrorCodeDecodingPin, err))
+		}
+
+		events = append(events, event)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+
+	a.logger.Info("storage: pin audit trail listed successfully from audit file storage")
+	return events, nil
+}
+
+// SaveOperation appends the destructive operation to the audit log. To keep
+// the audit file format simple, the operations are stored in JSON, one per
+// line, in a separated file with the same name of the audit file and the
+// extension “.operations.log”. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) SaveOperation(ctx context.Context, operation Operation) error {
+	a.logger.Debugf("storage: saving operation “%s” in audit file storage", operation.Action)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(operation)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingOperation, err))
+	}
+
+	if err = appendLine(a.operationsFilename(), encoded); err != nil {
+		return errors.WithStack(err)
+	}
+
+	a.logger.Infof("storage: operation “%s” saved successfully in audit file storage", operation.Action)
+	return nil
+}
+
+// Operations returns the audit log of the destructive operations, in the order
+// that they were recorded. On error it will return an Error type encapsulated
+// in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) Operations(ctx context.Context) ([]Operation, error) {
+	a.logger.Debug("storage: listing operations from audit file storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(a.operationsFilename())
+	if err != nil {
+		// if the file doesn't exist no operation was ever recorded
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+	defer f.Close()
+
+	var operations []Operation
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var operation Operation
+		if err = json.Unmarshal([]byte(line), &operation); err != nil {
+			return nil, errors.WithStack(newError(ErrorCodeDecodingOperation, err))
+		}
+
+		operations = append(operations, operation)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+
+	a.logger.Info("storage: operations listed successfully from audit file storage")
+	return operations, nil
+}
+
+// SaveTag assigns the name to the backup, replacing a tag with the same name.
+// Like the pins, the tags are stored in JSON in a separated file, with the same
+// name of the audit file and the extension “.tags”. On error it will return an
+// Error type encapsulated in a traceable error. To retrieve the desired error
+// you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) SaveTag(ctx context.Context, tag Tag) error {
+	a.logger.Debugf("storage: saving tag “%s” of backup “%s” in audit file storage", tag.Name, tag.ID)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	tags, err := a.tags()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	index := sort.Search(len(tags), func(i int) bool {
+		return tags[i].Name >= tag.Name
+	})
+
+	if index < len(tags) && tags[index].Name == tag.Name {
+		tags[index] = tag
+
+	} else {
+		tags = append(tags, Tag{})
+		copy(tags[index+1:], tags[index:])
+		tags[index] = tag
+	}
+
+	if err = a.saveTags(tags); err != nil {
+		return errors.WithStack(err)
+	}
+
+	a.logger.Infof("storage: tag “%s” of backup “%s” saved successfully in audit file storage", tag.Name, tag.ID)
+	return nil
+}
+
+// Tags returns the names of the backups sorted by name. On error it will
+// return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) Tags(ctx context.Context) ([]Tag, error) {
+	a.logger.Debug("storage: listing tags from audit file storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	tags, err := a.tags()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	a.logger.Info("storage: tags listed successfully from audit file storage")
+	return tags, nil
+}
+
+// RemoveTag removes the name of the backup. On error it will return an Error
+// type encapsulated in a traceable error. To retrieve the desired error you
+// can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) RemoveTag(ctx context.Context, name string) error {
+	a.logger.Debugf("storage: removing tag “%s” from audit file storage", name)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	tags, err := a.tags()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	index := sort.Search(len(tags), func(i int) bool {
+		return tags[i].Name >= name
+	})
+
+	if index == len(tags) || tags[index].Name != name {
+		return nil
+	}
+
+	tags = append(tags[:index], tags[index+1:]...)
+
+	if len(tags) == 0 {
+		// don't leave an empty file behind when there's no tagged backup
+		if err = os.Remove(a.tagsFilename()); err != nil {
+			return errors.WithStack(newError(ErrorCodeWritingFile, err))
+		}
+
+	} else if err = a.saveTags(tags); err != nil {
+		return errors.WithStack(err)
+	}
+
+	a.logger.Infof("storage: tag “%s” removed successfully from audit file storage", name)
+	return nil
+}
+
+func (a *AuditFile) pins() ([]Pin, error) {
+	content, err := ioutil.ReadFile(a.pinsFilename())
+	if err != nil {
+		// if the file doesn't exist no backup is pinned
+		if pathErr, ok := err.(*os.PathError); ok && os.IsNotExist(pathErr.Err) {
+			return nil, nil
+		}
+
+		return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+
+	var pins []Pin
+	if err = json.Unmarshal(content, &pins); err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeDecodingPin, err))
+	}
+
+	return pins, nil
+}
+
+func (a *AuditFile) savePins(pins []Pin) error {
+	encoded, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingPin, err))
+	}
+
+	if err = writeFileAtomically(a.pinsFilename(), encoded); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func (a *AuditFile) tags() ([]Tag, error) {
+	content, err := ioutil.ReadFile(a.tagsFilename())
+	if err != nil {
+		// if the file doesn't exist no backup is tagged
+		if pathErr, ok := err.(*os.PathError); ok && os.IsNotExist(pathErr.Err) {
+			return nil, nil
+		}
+
+		return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+
+	var tags []Tag
+	if err = json.Unmarshal(content, &tags); err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeDecodingTag, err))
+	}
+
+	return tags, nil
+}
+
+func (a *AuditFile) saveTags(tags []Tag) error {
+	encoded, err := json.MarshalIndent(tags, "", "  ")
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingTag, err))
+	}
+
+	if err = writeFileAtomically(a.tagsFilename(), encoded); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// saveInfo stores the archive information of the backup. Backups without
+// archive information (e.g. retrieved from the cloud inventory) don't have a
+// file.
+func (a *AuditFile) saveInfo(backup Backup) error {
+	if backup.Info == nil {
+		return nil
+	}
+
+	encoded, err := json.Marshal(backup.Info)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingBackup, err))
+	}
+
+	if err = os.MkdirAll(a.infoDir(), 0700); err != nil {
+		return errors.WithStack(newError(ErrorCodeWritingFile, err))
+	}
+
+	return errors.WithStack(writeFileAtomically(a.infoFilename(backup.Backup.ID), encoded))
+}
+
+// info returns the archive information of the backup, or nil when it wasn't
+// stored.
+func (a *AuditFile) info(id string) (archive.Info, error) {
+	content, err := ioutil.ReadFile(a.infoFilename(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+
+	var info archive.Info
+	if err = json.Unmarshal(content, &info); err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeDecodingBackup, err))
+	}
+
+	return info, nil
+}
+
+// removeInfo removes the archive information of the backup, if there's any.
+func (a *AuditFile) removeInfo(id string) error {
+	if err := os.Remove(a.infoFilename(id)); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(newError(ErrorCodeWritingFile, err))
+	}
+
+	return nil
+}
+
+// addPinEvent appends the event to the pin audit trail. The file is only
+// appended, one JSON event per line, so the previous records are never
+// rewritten.
+func (a *AuditFile) addPinEvent(event PinEvent) error {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingPin, err))
+	}
+
+	return errors.WithStack(appendLine(a.pinEventsFilename(), encoded))
+}
+
+// appendLine adds the line to the end of the file, only returning after it is
+// flushed to the disk.
+func appendLine(filename string, line []byte) error {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer f.Close()
+
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		return errors.WithStack(newError(ErrorCodeWritingFile, err))
+	}
+
+	if err = f.Sync(); err != nil {
+		return errors.WithStack(newError(ErrorCodeWritingFile, err))
+	}
+
+	return nil
+}
+
+// auditEmptyField fills an optional column that is followed by other columns,
+// so the line can still be split by spaces.
+const auditEmptyField = "-"
+
+// auditLine builds the audit file representation of the backup. The machine,
+// parity, catalog and replica identifiers and the comment are optional to keep
+// the compatibility with older audit files. The comment is quoted and the
+// machine identifier is escaped, as they can contain spaces. The comment is
+// always the last column.
+func auditLine(backup Backup) string {
+	audit := fmt.Sprintf("%s %s %s %s %d %s", backup.Backup.CreatedAt.Format(time.RFC3339), backup.Backup.VaultName, backup.Backup.ID, backup.Backup.Checksum, backup.Backup.Size, backup.Backup.Location)
+
+	var comment string
+	if backup.Backup.Comment != "" {
+		comment = strconv.Quote(backup.Backup.Comment)
+	}
+
+	optionalFields := []string{
+		url.PathEscape(backup.Backup.MachineID),
+		backup.Backup.ParityID,
+		backup.Backup.CatalogID,
+	}
+
+	// the replica identifier is only written when it exists, so the comment
+	// stays in the same column of older audit files
+	if backup.Backup.ReplicaID != "" {
+		optionalFields = append(optionalFields, backup.Backup.ReplicaID)
+	}
+	optionalFields = append(optionalFields, comment)
+
+	// ignore the empty fields in the end of the line
+	for len(optionalFields) > 0 && optionalFields[len(optionalFields)-1] == "" {
+		optionalFields = optionalFields[:len(optionalFields)-1]
+	}
+
+	for _, field := range optionalFields {
+		if field == "" {
+			field = auditEmptyField
+		}
+		audit += " " + field
+	}
+
+	return audit + "\n"
+}
+
+// auditFileHeader builds the first line of the audit file, with the version of
+// the format.
+func auditFileHeader() string {
+	return fmt.Sprintf("%s%d\n", auditFileVersionPrefix, AuditFileVersion)
+}
+
+// parseAuditFileVersion retrieves the version of the format from the first
+// line of the audit file. When the line isn't the version, ok is false and the
+// audit file is from before the versioning. Versions newer than the
+// AuditFileVersion are refused.
+func parseAuditFileVersion(line string) (version int, ok bool, err error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, auditFileVersionPrefix) {
+		return 0, false, nil
+	}
+
+	if version, err = strconv.Atoi(strings.TrimPrefix(line, auditFileVersionPrefix)); err != nil {
+		return 0, false, errors.WithStack(newError(ErrorCodeSchemaVersion, err))
+	}
+
+	if version > AuditFileVersion {
+		return 0, false, errors.WithStack(newError(ErrorCodeSchemaVersion,
+			fmt.Errorf("audit file version %d is newer than the supported version %d", version, AuditFileVersion)))
+	}
+
+	return version, true, nil
+}
+
+// stamp writes the version of the format in the first line of the audit file,
+// creating it when it doesn't exist. The audit files of older versions are
+// rewritten with the version before their lines.
+func (a *AuditFile) stamp() error {
+	auditFile, err := os.OpenFile(a.Filename, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+
+	firstLine, err := bufio.NewReader(auditFile).ReadString('\n')
+	auditFile.Close()
+
+	if err != nil && err != io.EOF {
+		return errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+
+	if _, ok, err := parseAuditFileVersion(firstLine); err != nil || ok {
+		return errors.WithStack(err)
+	}
+
+	content, err := ioutil.ReadFile(a.Filename)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+
+	if len(content) > 0 {
+		a.logger.Infof("storage: migrating audit file storage to version %d", AuditFileVersion)
+	}
+
+	return errors.WithStack(writeFileAtomically(a.Filename, append([]byte(auditFileHeader()), content...)))
+}
+
+// lastLineTruncated checks if the file doesn't end with a line break.
+func lastLineTruncated(f *os.File) (bool, error) {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return false, err
+	}
+
+	lastByte := make([]byte, 1)
+	if _, err = f.ReadAt(lastByte, info.Size()-1); err != nil {
+		return false, err
+	}
+
+	return lastByte[0] != '\n', nil
+}
+
+// writeFileAtomically replaces the content of the file. The content is written
+// and synced in a temporary file of the same directory, that is renamed over
+// the original file, so a power loss in the middle of the write keeps the
+// previous content instead of a truncated file.
+func writeFileAtomically(filename string, content []byte) error {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+
+	// after the rename the temporary file doesn't exist anymore, so the removal
+	// only cleans up failed writes
+	defer os.Remove(tmpFile.Name())
+
+	if _, err = tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return errors.WithStack(newError(ErrorCodeWritingFile, err))
+	}
+
+	if err = tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return errors.WithStack(newError(ErrorCodeWritingFile, err))
+	}
+
+	if err = tmpFile.Close(); err != nil {
+		return errors.WithStack(newError(ErrorCodeWritingFile, err))
+	}
+
+	if err = os.Rename(tmpFile.Name(), filename); err != nil {
+		return errors.WithStack(newError(ErrorCodeMovingFile, err))
+	}
+
+	// the rename is only durable after the directory is synced. Some systems
+	// (e.g. Windows) don't allow syncing directories, so it's done in a best
+	// effort basis
+	if dir, err := os.Open(filepath.Dir(filename)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+
+	return nil
+}
+
+// Compact rewrites the audit file keeping only the latest information of each
+// backup. The corrupted lines are moved to a file with the .corrupt extension
+// next to the audit file, so they can still be recovered manually. The audit
+// file only grows when the same backup is saved again, so the file is only
+// rewritten when there's something to drop. It returns the number of dropped
+// lines. On error it will return an
+// Error type encapsulated in a traceable error. To retrieve the desired error
+// you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (a *AuditFile) Compact(ctx context.Context) (int, error) {
+	a.logger.Debug("storage: compacting audit file storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return 0, err
+	}
+
+	auditFile, err := os.Open(a.Filename)
+	if err != nil {
+		// nothing to compact when there's no audit file yet
+		if pathErr, ok := err.(*os.PathError); ok && os.IsNotExist(pathErr.Err) {
+			return 0, nil
+		}
+
+		return 0, errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer auditFile.Close()
+
+	backups, lines, corrupted, err := a.read(ctx, auditFile)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	dropped := lines - len(backups)
+	if dropped == 0 {
+		a.logger.Info("storage: audit file storage is already compacted")
+		return 0, nil
+	}
+
+	// the corrupted lines are kept before the rewrite, so they aren't lost if
+	// the rewrite is interrupted
+	if len(corrupted) > 0 {
+		if err = appendLine(a.corruptFilename(), []byte(strings.Join(corrupted, "\n"))); err != nil {
+			return 0, errors.WithStack(err)
+		}
+
+		a.logger.Warningf("storage: %d corrupted lines of audit file “%s” moved to “%s”", len(corrupted), a.Filename, a.corruptFilename())
+	}
+
+	var content bytes.Buffer
+	content.WriteString(auditFileHeader())
+	for _, backup := range backups {
+		content.WriteString(auditLine(backup))
+	}
+
+	if err = writeFileAtomically(a.Filename, content.Bytes()); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	a.logger.Infof("storage: audit file storage compacted successfully, %d lines dropped", dropped)
+	return dropped, nil
+}
+
+// read parses all lines of the audit file, returning the latest information of
+// each backup, the number of non-empty lines and the corrupted lines. Corrupted
+// lines are reported in the logs and ignored.
+func (a *AuditFile) read(ctx context.Context, auditFile io.Reader) (Backups, int, []string, error) {
+	var backups Backups
+	var corrupted []string
+	var lines, lineNumber int
+
+	scanner := bufio.NewScanner(auditFile)
+	for scanner.Scan() {
+		if err := checkCancellation(ctx); err != nil {
+			return nil, 0, nil, err
+		}
+
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if lineNumber == 1 {
+			if _, ok, err := parseAuditFileVersion(line); err != nil {
+				return nil, 0, nil, errors.WithStack(err)
+			} else if ok {
+				continue
+			}
+		}
+		lines++
+
+		backup, err := parseAuditLine(line)
+		if err != nil {
+			a.logger.Warningf("storage: ignoring corrupted line %d of audit file “%s”. details: %s", lineNumber, a.Filename, err)
+			corrupted = append(corrupted, line)
+			continue
+		}
+
+		backups.Add(backup)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, 0, nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+
+	return backups, lines, corrupted, nil
+}
+
+// parseAuditLine decodes a line of the audit file, built with the auditLine
+// function.
+func parseAuditLine(line string) (Backup, error) {
+	// the comment is the only column that can contain spaces
+	lineParts := strings.SplitN(line, " ", 10)
+
+	if len(lineParts) < 4 {
+		return Backup{}, errors.WithStack(newError(ErrorCodeFormat, nil))
+	}
+
+	var backup Backup
+	var err error
+
+	if backup.Backup.CreatedAt, err = time.Parse(time.RFC3339, lineParts[0]); err != nil {
+		return Backup{}, errors.WithStack(newError(ErrorCodeDateFormat, err))
+	}
+
+	backup.Backup.VaultName = lineParts[1]
+	backup.Backup.ID = lineParts[2]
+	backup.Backup.Checksum = lineParts[3]
+
+	if len(lineParts) >= 5 {
+		backup.Backup.Size, err = strconv.ParseInt(lineParts[4], 10, 64)
+		if err != nil {
+			return Backup{}, errors.WithStack(newError(ErrorCodeSizeFormat, err))
+		}
+	}
+
+	if len(lineParts) >= 6 {
+		backup.Backup.Location, err = cloud.ParseLocation(lineParts[5])
+		if err != nil {
+			return Backup{}, errors.WithStack(newError(ErrorCodeLocation, err))
+		}
+
+	} else {
+		// default location is AWS for backward compatibility
+		backup.Backup.Location = cloud.LocationAWS
+	}
+
+	if len(lineParts) >= 7 && lineParts[6] != auditEmptyField {
+		if backup.Backup.MachineID, err = url.PathUnescape(lineParts[6]); err != nil {
+			return Backup{}, errors.WithStack(newError(ErrorCodeFormat, err))
+		}
+	}
+
+	if len(lineParts) >= 8 && lineParts[7] != auditEmptyField {
+		backup.Backup.ParityID = lineParts[7]
+	}
+
+	if len(lineParts) >= 9 && lineParts[8] != auditEmptyField {
+		backup.Backup.CatalogID = lineParts[8]
+	}
+
+	if len(lineParts) >= 10 {
+		comment := lineParts[9]
+
+		// older audit files don't have the replica identifier before the comment,
+		// that is always quoted
+		if !strings.HasPrefix(comment, `"`) {
+			replicaParts := strings.SplitN(comment, " ", 2)
+			if replicaParts[0] != auditEmptyField {
+				backup.Backup.ReplicaID = replicaParts[0]
+			}
+
+			comment = ""
+			if len(replicaParts) > 1 {
+				comment = replicaParts[1]
+			}
+		}
+
+		if comment != "" {
+			if backup.Backup.Comment, err = strconv.Unquote(comment); err != nil {
+				return Backup{}, errors.WithStack(newError(ErrorCodeFormat, err))
+			}
+		}
+	}
+
+	return backup, nil
+}
+
+func (a *AuditFile) inventoryFilename() string {
+	return a.Filename + ".inventory"
+}
+
+func (a *AuditFile) pauseFilename() string {
+	return a.Filename + ".pause"
+}
+
+func (a *AuditFile) restoreFilename() string {
+	return a.Filename + ".restore"
+}
+
+func (a *AuditFile) pinsFilename() string {
+	return a.Filename + ".pins"
+}
+
+func (a *AuditFile) pinEventsFilename() string {
+	return a.Filename + ".pins.log"
+}
+
+func (a *AuditFile) operationsFilename() string {
+	return a.Filename + ".operations.log"
+}
+
+func (a *AuditFile) corruptFilename() string {
+	return a.Filename + ".corrupt"
+}
+
+func (a *AuditFile) tagsFilename() string {
+	return a.Filename + ".tags"
+}
+
+func (a *AuditFile) infoDir() string {
+	return a.Filename + ".info"
+}
+
+// infoFilename returns the file of the archive information of the backup. The
+// backup ID is hashed, as it can be too long for a file name and contain path
+// separators.
+func (a *AuditFile) infoFilename(id string) string {
+	hash := sha256.Sum256([]byte(id))
+	return filepath.Join(a.infoDir(), hex.EncodeToString(hash[:])+".json")
+}
@@ -1,24 +1,30 @@
 package storage_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"reflect"
-	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/aryann/difflib"
 	"github.com/davecgh/go-spew/spew"
+	"github.com/rafaeljusto/toglacier/internal/archive"
 	"github.com/rafaeljusto/toglacier/internal/cloud"
 	"github.com/rafaeljusto/toglacier/internal/log"
 	"github.com/rafaeljusto/toglacier/internal/storage"
 )
 
+// auditFileHeader is the first line of the audit files written by the current
+// version.
+const auditFileHeader = "# toglacier audit file version 1\n"
+
 func TestAuditFile_Save(t *testing.T) {
 	now := time.Now()
 
@@ -57,7 +63,220 @@ func TestAuditFile_Save(t *testing.T) {
 					Location:  cloud.LocationAWS,
 				},
 			},
-			expected: fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)),
+			expected: auditFileHeader + fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)),
+		},
+		{
+			description: "it should not append a backup information to a truncated line",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString(fmt.Sprintf("%s test 654321 ca34f0", now.Format(time.RFC3339)))
+				return f.Name()
+			}(),
+			backup: storage.Backup{
+				Backup: cloud.Backup{
+					ID:        "123456",
+					CreatedAt: now,
+					Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+					VaultName: "test",
+					Size:      120,
+					Location:  cloud.LocationAWS,
+				},
+			},
+			expected: auditFileHeader + fmt.Sprintf("%s test 654321 ca34f0\n%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339), now.Format(time.RFC3339)),
+		},
+		{
+			description: "it should save a backup information with machine identifier correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			backup: storage.Backup{
+				Backup: cloud.Backup{
+					ID:        "123456",
+					CreatedAt: now,
+					Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+					VaultName: "test",
+					Size:      120,
+					Location:  cloud.LocationAWS,
+					MachineID: "server1",
+				},
+			},
+			expected: auditFileHeader + fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws server1\n", now.Format(time.RFC3339)),
+		},
+		{
+			description: "it should save a backup information with a machine identifier containing spaces",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			backup: storage.Backup{
+				Backup: cloud.Backup{
+					ID:        "123456",
+					CreatedAt: now,
+					Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+					VaultName: "test",
+					Size:      120,
+					Location:  cloud.LocationAWS,
+					MachineID: "file server",
+					ParityID:  "123457",
+				},
+			},
+			expected: auditFileHeader + fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws file%%20server 123457\n", now.Format(time.RFC3339)),
+		},
+		{
+			description: "it should save a backup information with parity identifier correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			backup: storage.Backup{
+				Backup: cloud.Backup{
+					ID:        "123456",
+					CreatedAt: now,
+					Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+					VaultName: "test",
+					Size:      120,
+					Location:  cloud.LocationAWS,
+					ParityID:  "123457",
+				},
+			},
+			expected: auditFileHeader + fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws - 123457\n", now.Format(time.RFC3339)),
+		},
+		{
+			description: "it should save a backup information with catalog identifier correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			backup: storage.Backup{
+				Backup: cloud.Backup{
+					ID:        "123456",
+					CreatedAt: now,
+					Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+					VaultName: "test",
+					Size:      120,
+					Location:  cloud.LocationAWS,
+					MachineID: "server1",
+					CatalogID: "123458",
+				},
+			},
+			expected: auditFileHeader + fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws server1 - 123458\n", now.Format(time.RFC3339)),
+		},
+		{
+			description: "it should save a backup information with replica identifier correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			backup: storage.Backup{
+				Backup: cloud.Backup{
+					ID:        "123456",
+					CreatedAt: now,
+					Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+					VaultName: "test",
+					Size:      120,
+					Location:  cloud.LocationAWS,
+					Comment:   "weekly",
+					ReplicaID: "123459",
+				},
+			},
+			expected: auditFileHeader + fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws - - - 123459 \"weekly\"\n", now.Format(time.RFC3339)),
+		},
+		{
+			description: "it should save a backup information with comment correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			backup: storage.Backup{
+				Backup: cloud.Backup{
+					ID:        "123456",
+					CreatedAt: now,
+					Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+					VaultName: "test",
+					Size:      120,
+					Location:  cloud.LocationAWS,
+					Comment:   `before "OS" upgrade`,
+				},
+			},
+			expected: auditFileHeader + fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws - - - \"before \\\"OS\\\" upgrade\"\n", now.Format(time.RFC3339)),
 		},
 		{
 			description: "it should detect when the filename refers to a directory",
@@ -97,7 +316,7 @@ func TestAuditFile_Save(t *testing.T) {
 	for _, scenario := range scenarios {
 		t.Run(scenario.description, func(t *testing.T) {
 			auditFile := storage.NewAuditFile(scenario.logger, scenario.filename)
-			err := auditFile.Save(scenario.backup)
+			err := auditFile.Save(context.Background(), scenario.backup)
 
 			auditFileContent, auditFileErr := ioutil.ReadFile(scenario.filename)
 			if auditFileErr != nil && scenario.expectedError == nil {
@@ -118,10 +337,17 @@ func TestAuditFile_Save(t *testing.T) {
 func TestAuditFile_List(t *testing.T) {
 	now := time.Now()
 
+	// the audit file stores the dates with seconds precision
+	nowSeconds, err := time.Parse(time.RFC3339, now.Format(time.RFC3339))
+	if err != nil {
+		t.Fatalf("error parsing current time. details: %s", err)
+	}
+
 	scenarios := []struct {
 		description   string
 		logger        log.Logger
 		filename      string
+		cancelled     bool
 		expected      storage.Backups
 		expectedError error
 	}{
@@ -180,7 +406,7 @@ func TestAuditFile_List(t *testing.T) {
 			},
 		},
 		{
-			description: "it should list all backups information correctly with format transition",
+			description: "it should list all backups information correctly with machine identifier",
 			logger: mockLogger{
 				mockDebug:  func(args ...interface{}) {},
 				mockDebugf: func(format string, args ...interface{}) {},
@@ -194,7 +420,7 @@ func TestAuditFile_List(t *testing.T) {
 				}
 				defer f.Close()
 
-				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 0  \n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws server1\n", now.Format(time.RFC3339)))
 				return f.Name()
 			}(),
 			expected: storage.Backups{
@@ -210,13 +436,15 @@ func TestAuditFile_List(t *testing.T) {
 						}(),
 						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
 						VaultName: "test",
+						Size:      120,
 						Location:  cloud.LocationAWS,
+						MachineID: "server1",
 					},
 				},
 			},
 		},
 		{
-			description: "it should list all backups information correctly with backward compatibility (no size)",
+			description: "it should list a backup with a machine identifier containing spaces",
 			logger: mockLogger{
 				mockDebug:  func(args ...interface{}) {},
 				mockDebugf: func(format string, args ...interface{}) {},
@@ -230,7 +458,7 @@ func TestAuditFile_List(t *testing.T) {
 				}
 				defer f.Close()
 
-				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws file%%20server 123457\n", now.Format(time.RFC3339)))
 				return f.Name()
 			}(),
 			expected: storage.Backups{
@@ -246,13 +474,16 @@ func TestAuditFile_List(t *testing.T) {
 						}(),
 						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
 						VaultName: "test",
+						Size:      120,
 						Location:  cloud.LocationAWS,
+						MachineID: "file server",
+						ParityID:  "123457",
 					},
 				},
 			},
 		},
 		{
-			description: "it should list all backups information correctly with backward compatibility (no location)",
+			description: "it should list all backups information correctly with parity and catalog identifiers",
 			logger: mockLogger{
 				mockDebug:  func(args ...interface{}) {},
 				mockDebugf: func(format string, args ...interface{}) {},
@@ -266,7 +497,9 @@ func TestAuditFile_List(t *testing.T) {
 				}
 				defer f.Close()
 
-				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws server1 123457\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123458 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws - 123459\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123460 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws server1 - 123461\n", now.Format(time.RFC3339)))
 				return f.Name()
 			}(),
 			expected: storage.Backups{
@@ -284,24 +517,49 @@ func TestAuditFile_List(t *testing.T) {
 						VaultName: "test",
 						Size:      120,
 						Location:  cloud.LocationAWS,
+						MachineID: "server1",
+						ParityID:  "123457",
+					},
+				},
+				{
+					Backup: cloud.Backup{
+						ID: "123458",
+						CreatedAt: func() time.Time {
+							c, err := time.Parse(time.RFC3339, now.Format(time.RFC3339))
+							if err != nil {
+								t.Fatalf("error parsing current time. details: %s", err)
+							}
+							return c
+						}(),
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Size:      120,
+						Location:  cloud.LocationAWS,
+						ParityID:  "123459",
+					},
+				},
+				{
+					Backup: cloud.Backup{
+						ID: "123460",
+						CreatedAt: func() time.Time {
+							c, err := time.Parse(time.RFC3339, now.Format(time.RFC3339))
+							if err != nil {
+								t.Fatalf("error parsing current time. details: %s", err)
+							}
+							return c
+						}(),
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Size:      120,
+						Location:  cloud.LocationAWS,
+						MachineID: "server1",
+						CatalogID: "123461",
 					},
 				},
 			},
 		},
 		{
-			description: "it should return no backups when the audit file doesn't exist",
-			logger: mockLogger{
-				mockDebug:  func(args ...interface{}) {},
-				mockDebugf: func(format string, args ...interface{}) {},
-				mockInfo:   func(args ...interface{}) {},
-				mockInfof:  func(format string, args ...interface{}) {},
-			},
-			filename: func() string {
-				return path.Join(os.TempDir(), "toglacier-idontexist")
-			}(),
-		},
-		{
-			description: "it should detect when the audit file has no read permission",
+			description: "it should list all backups information correctly with replica identifier",
 			logger: mockLogger{
 				mockDebug:  func(args ...interface{}) {},
 				mockDebugf: func(format string, args ...interface{}) {},
@@ -309,30 +567,56 @@ func TestAuditFile_List(t *testing.T) {
 				mockInfof:  func(format string, args ...interface{}) {},
 			},
 			filename: func() string {
-				n := path.Join(os.TempDir(), "toglacier-test-noperm")
-				if _, err := os.Stat(n); os.IsNotExist(err) {
-					f, err := os.OpenFile(n, os.O_CREATE, os.FileMode(0077))
-					if err != nil {
-						t.Fatalf("error creating a temporary file. details: %s", err)
-					}
-					defer f.Close()
-
-					f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)))
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
 				}
+				defer f.Close()
 
-				return n
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws - - - 123457\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123458 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws - - - 123459 \"before upgrade\"\n", now.Format(time.RFC3339)))
+				return f.Name()
 			}(),
-			expectedError: &storage.Error{
-				Code: storage.ErrorCodeOpeningFile,
-				Err: &os.PathError{
-					Op:   "open",
-					Path: path.Join(os.TempDir(), "toglacier-test-noperm"),
-					Err:  errors.New("permission denied"),
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID: "123456",
+						CreatedAt: func() time.Time {
+							c, err := time.Parse(time.RFC3339, now.Format(time.RFC3339))
+							if err != nil {
+								t.Fatalf("error parsing current time. details: %s", err)
+							}
+							return c
+						}(),
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Size:      120,
+						Location:  cloud.LocationAWS,
+						ReplicaID: "123457",
+					},
+				},
+				{
+					Backup: cloud.Backup{
+						ID: "123458",
+						CreatedAt: func() time.Time {
+							c, err := time.Parse(time.RFC3339, now.Format(time.RFC3339))
+							if err != nil {
+								t.Fatalf("error parsing current time. details: %s", err)
+							}
+							return c
+						}(),
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Size:      120,
+						Location:  cloud.LocationAWS,
+						Comment:   "before upgrade",
+						ReplicaID: "123459",
+					},
 				},
 			},
 		},
 		{
-			description: "it should detect when the filename references to a directory",
+			description: "it should list all backups information correctly with comment",
 			logger: mockLogger{
 				mockDebug:  func(args ...interface{}) {},
 				mockDebugf: func(format string, args ...interface{}) {},
@@ -340,23 +624,38 @@ func TestAuditFile_List(t *testing.T) {
 				mockInfof:  func(format string, args ...interface{}) {},
 			},
 			filename: func() string {
-				d := path.Join(os.TempDir(), "toglacier-test-dir")
-				if err := os.MkdirAll(d, os.ModePerm); err != nil {
-					t.Fatalf("error creating a temporary directory. details: %s", err)
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
 				}
-				return d
+				defer f.Close()
+
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws server1 - - \"before \\\"OS\\\" upgrade\"\n", now.Format(time.RFC3339)))
+				return f.Name()
 			}(),
-			expectedError: &storage.Error{
-				Code: storage.ErrorCodeReadingFile,
-				Err: &os.PathError{
-					Op:   "read",
-					Path: path.Join(os.TempDir(), "toglacier-test-dir"),
-					Err:  errors.New("is a directory"),
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID: "123456",
+						CreatedAt: func() time.Time {
+							c, err := time.Parse(time.RFC3339, now.Format(time.RFC3339))
+							if err != nil {
+								t.Fatalf("error parsing current time. details: %s", err)
+							}
+							return c
+						}(),
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Size:      120,
+						Location:  cloud.LocationAWS,
+						MachineID: "server1",
+						Comment:   `before "OS" upgrade`,
+					},
 				},
 			},
 		},
 		{
-			description: "it should detect when an audit file line has the wrong number of columns",
+			description: "it should list all backups information correctly with format transition",
 			logger: mockLogger{
 				mockDebug:  func(args ...interface{}) {},
 				mockDebugf: func(format string, args ...interface{}) {},
@@ -370,15 +669,29 @@ func TestAuditFile_List(t *testing.T) {
 				}
 				defer f.Close()
 
-				f.WriteString(fmt.Sprintf("%s test 123456\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 0  \n", now.Format(time.RFC3339)))
 				return f.Name()
 			}(),
-			expectedError: &storage.Error{
-				Code: storage.ErrorCodeFormat,
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID: "123456",
+						CreatedAt: func() time.Time {
+							c, err := time.Parse(time.RFC3339, now.Format(time.RFC3339))
+							if err != nil {
+								t.Fatalf("error parsing current time. details: %s", err)
+							}
+							return c
+						}(),
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Location:  cloud.LocationAWS,
+					},
+				},
 			},
 		},
 		{
-			description: "it should detect when the audit file contains an invalid date",
+			description: "it should list all backups information correctly with backward compatibility (no size)",
 			logger: mockLogger{
 				mockDebug:  func(args ...interface{}) {},
 				mockDebugf: func(format string, args ...interface{}) {},
@@ -392,22 +705,29 @@ func TestAuditFile_List(t *testing.T) {
 				}
 				defer f.Close()
 
-				f.WriteString("XXXX test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n")
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7\n", now.Format(time.RFC3339)))
 				return f.Name()
 			}(),
-			expectedError: &storage.Error{
-				Code: storage.ErrorCodeDateFormat,
-				Err: &time.ParseError{
-					Layout:     time.RFC3339,
-					Value:      "XXXX",
-					LayoutElem: "2006",
-					ValueElem:  "XXXX",
-					Message:    fmt.Sprintf(` as "%s": cannot parse "XXXX" as "2006"`, time.RFC3339),
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID: "123456",
+						CreatedAt: func() time.Time {
+							c, err := time.Parse(time.RFC3339, now.Format(time.RFC3339))
+							if err != nil {
+								t.Fatalf("error parsing current time. details: %s", err)
+							}
+							return c
+						}(),
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Location:  cloud.LocationAWS,
+					},
 				},
 			},
 		},
 		{
-			description: "it should detect when the audit file contains an invalid size",
+			description: "it should list all backups information correctly with backward compatibility (no location)",
 			logger: mockLogger{
 				mockDebug:  func(args ...interface{}) {},
 				mockDebugf: func(format string, args ...interface{}) {},
@@ -421,20 +741,30 @@ func TestAuditFile_List(t *testing.T) {
 				}
 				defer f.Close()
 
-				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 XXXX aws\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120\n", now.Format(time.RFC3339)))
 				return f.Name()
 			}(),
-			expectedError: &storage.Error{
-				Code: storage.ErrorCodeSizeFormat,
-				Err: &strconv.NumError{
-					Func: "ParseInt",
-					Num:  "XXXX",
-					Err:  errors.New("invalid syntax"),
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID: "123456",
+						CreatedAt: func() time.Time {
+							c, err := time.Parse(time.RFC3339, now.Format(time.RFC3339))
+							if err != nil {
+								t.Fatalf("error parsing current time. details: %s", err)
+							}
+							return c
+						}(),
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Size:      120,
+						Location:  cloud.LocationAWS,
+					},
 				},
 			},
 		},
 		{
-			description: "it should detect when the audit file contains an invalid location",
+			description: "it should return no backups when the audit file doesn't exist",
 			logger: mockLogger{
 				mockDebug:  func(args ...interface{}) {},
 				mockDebugf: func(format string, args ...interface{}) {},
@@ -442,51 +772,11 @@ func TestAuditFile_List(t *testing.T) {
 				mockInfof:  func(format string, args ...interface{}) {},
 			},
 			filename: func() string {
-				f, err := ioutil.TempFile("", "toglacier-test")
-				if err != nil {
-					t.Fatalf("error creating a temporary file. details: %s", err)
-				}
-				defer f.Close()
-
-				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 XXXX\n", now.Format(time.RFC3339)))
-				return f.Name()
+				return path.Join(os.TempDir(), "toglacier-idontexist")
 			}(),
-			expectedError: &storage.Error{
-				Code: storage.ErrorCodeLocation,
-				Err:  errors.New("unknown location “xxxx”"),
-			},
 		},
-	}
-
-	for _, scenario := range scenarios {
-		t.Run(scenario.description, func(t *testing.T) {
-			auditFile := storage.NewAuditFile(scenario.logger, scenario.filename)
-			backups, err := auditFile.List()
-
-			if !reflect.DeepEqual(scenario.expected, backups) {
-				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backups))
-			}
-
-			if !storage.ErrorEqual(scenario.expectedError, err) {
-				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
-			}
-		})
-	}
-}
-
-func TestAuditFile_Remove(t *testing.T) {
-	now := time.Now()
-
-	scenarios := []struct {
-		description   string
-		logger        log.Logger
-		filename      string
-		id            string
-		expected      string
-		expectedError error
-	}{
 		{
-			description: "it should remove a backup information correctly",
+			description: "it should detect when the audit file has no read permission",
 			logger: mockLogger{
 				mockDebug:  func(args ...interface{}) {},
 				mockDebugf: func(format string, args ...interface{}) {},
@@ -494,27 +784,61 @@ func TestAuditFile_Remove(t *testing.T) {
 				mockInfof:  func(format string, args ...interface{}) {},
 			},
 			filename: func() string {
-				f, err := ioutil.TempFile("", "toglacier-test")
-				if err != nil {
-					t.Fatalf("error creating a temporary file. details: %s", err)
+				n := path.Join(os.TempDir(), "toglacier-test-noperm")
+				if _, err := os.Stat(n); os.IsNotExist(err) {
+					f, err := os.OpenFile(n, os.O_CREATE, os.FileMode(0077))
+					if err != nil {
+						t.Fatalf("error creating a temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)))
 				}
-				defer f.Close()
 
-				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 100 aws\n", now.Format(time.RFC3339)))
-				f.WriteString(fmt.Sprintf("%s test 123457 913b87897ffb6dca07e9f17e280aa8ecb9886dffeda8a15efeafec11dec0d108 200 aws\n", now.Add(time.Second).Format(time.RFC3339)))
-				return f.Name()
+				return n
 			}(),
-			id:       "123457",
-			expected: fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 100 aws\n", now.Format(time.RFC3339)),
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeOpeningFile,
+				Err: &os.PathError{
+					Op:   "open",
+					Path: path.Join(os.TempDir(), "toglacier-test-noperm"),
+					Err:  errors.New("permission denied"),
+				},
+			},
 		},
 		{
-			description: "it should remove a backup information correctly with backward compatibility (no size)",
+			description: "it should detect when the filename references to a directory",
 			logger: mockLogger{
 				mockDebug:  func(args ...interface{}) {},
 				mockDebugf: func(format string, args ...interface{}) {},
 				mockInfo:   func(args ...interface{}) {},
 				mockInfof:  func(format string, args ...interface{}) {},
 			},
+			filename: func() string {
+				d := path.Join(os.TempDir(), "toglacier-test-dir")
+				if err := os.MkdirAll(d, os.ModePerm); err != nil {
+					t.Fatalf("error creating a temporary directory. details: %s", err)
+				}
+				return d
+			}(),
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeReadingFile,
+				Err: &os.PathError{
+					Op:   "read",
+					Path: path.Join(os.TempDir(), "toglacier-test-dir"),
+					Err:  errors.New("is a directory"),
+				},
+			},
+		},
+		{
+			description: "it should ignore an audit file line with the wrong number of columns",
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
 			filename: func() string {
 				f, err := ioutil.TempFile("", "toglacier-test")
 				if err != nil {
@@ -522,20 +846,31 @@ func TestAuditFile_Remove(t *testing.T) {
 				}
 				defer f.Close()
 
-				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7\n", now.Format(time.RFC3339)))
-				f.WriteString(fmt.Sprintf("%s test 123457 913b87897ffb6dca07e9f17e280aa8ecb9886dffeda8a15efeafec11dec0d108\n", now.Add(time.Second).Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123456\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 654321 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)))
 				return f.Name()
 			}(),
-			id:       "123457",
-			expected: fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 0 aws\n", now.Format(time.RFC3339)),
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "654321",
+						CreatedAt: nowSeconds,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Size:      120,
+						Location:  cloud.LocationAWS,
+					},
+				},
+			},
 		},
 		{
-			description: "it should remove a backup information correctly with backward compatibility (no location)",
+			description: "it should ignore an audit file line with an invalid date",
 			logger: mockLogger{
-				mockDebug:  func(args ...interface{}) {},
-				mockDebugf: func(format string, args ...interface{}) {},
-				mockInfo:   func(args ...interface{}) {},
-				mockInfof:  func(format string, args ...interface{}) {},
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
 			},
 			filename: func() string {
 				f, err := ioutil.TempFile("", "toglacier-test")
@@ -544,21 +879,304 @@ func TestAuditFile_Remove(t *testing.T) {
 				}
 				defer f.Close()
 
-				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120\n", now.Format(time.RFC3339)))
-				f.WriteString(fmt.Sprintf("%s test 123457 913b87897ffb6dca07e9f17e280aa8ecb9886dffeda8a15efeafec11dec0d108 120\n", now.Add(time.Second).Format(time.RFC3339)))
+				f.WriteString("XXXX test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n")
+				f.WriteString(fmt.Sprintf("%s test 654321 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)))
 				return f.Name()
 			}(),
-			id:       "123457",
-			expected: fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)),
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "654321",
+						CreatedAt: nowSeconds,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Size:      120,
+						Location:  cloud.LocationAWS,
+					},
+				},
+			},
 		},
 		{
-			description: "it should detect when the audit file has no read permission",
+			description: "it should ignore an audit file line with an invalid size",
 			logger: mockLogger{
-				mockDebug:  func(args ...interface{}) {},
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 XXXX aws\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 654321 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)))
+				return f.Name()
+			}(),
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "654321",
+						CreatedAt: nowSeconds,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Size:      120,
+						Location:  cloud.LocationAWS,
+					},
+				},
+			},
+		},
+		{
+			description: "it should ignore an audit file line with an invalid location",
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 XXXX\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 654321 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)))
+				return f.Name()
+			}(),
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "654321",
+						CreatedAt: nowSeconds,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Size:      120,
+						Location:  cloud.LocationAWS,
+					},
+				},
+			},
+		},
+		{
+			description: "it should stop listing when the context is cancelled",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			cancelled: true,
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeCancelled,
+				Err:  context.Canceled,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			auditFile := storage.NewAuditFile(scenario.logger, scenario.filename)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if scenario.cancelled {
+				cancel()
+			}
+
+			backups, err := auditFile.List(ctx)
+
+			if !reflect.DeepEqual(scenario.expected, backups) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backups))
+			}
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAuditFile_Version(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+	line := fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339))
+	newLine := fmt.Sprintf("%s test 123457 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339))
+
+	scenarios := []struct {
+		description       string
+		content           string
+		expected          string
+		expectedListError error
+		expectedSaveError error
+	}{
+		{
+			description: "it should stamp the audit file of an older version",
+			content:     line,
+			expected:    auditFileHeader + line + newLine,
+		},
+		{
+			description: "it should keep the audit file in the current version",
+			content:     auditFileHeader + line,
+			expected:    auditFileHeader + line + newLine,
+		},
+		{
+			description: "it should refuse the audit file of a newer version",
+			content:     "# toglacier audit file version 2\n" + line,
+			expected:    "# toglacier audit file version 2\n" + line,
+			expectedListError: &storage.Error{
+				Code: storage.ErrorCodeSchemaVersion,
+				Err:  errors.New("audit file version 2 is newer than the supported version 1"),
+			},
+			expectedSaveError: &storage.Error{
+				Code: storage.ErrorCodeSchemaVersion,
+				Err:  errors.New("audit file version 2 is newer than the supported version 1"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating a temporary file. details: %s", err)
+			}
+			defer os.Remove(f.Name())
+			defer os.RemoveAll(f.Name() + ".info")
+
+			f.WriteString(scenario.content)
+			f.Close()
+
+			auditFile := storage.NewAuditFile(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}, f.Name())
+
+			backups, err := auditFile.List(context.Background())
+			if !storage.ErrorEqual(scenario.expectedListError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedListError, err)
+			}
+
+			// the version line isn't a backup
+			if err == nil && len(backups) != 1 {
+				t.Errorf("expected one backup and got %d", len(backups))
+			}
+
+			err = auditFile.Save(context.Background(), storage.Backup{
+				Backup: cloud.Backup{
+					ID:        "123457",
+					CreatedAt: now,
+					Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+					VaultName: "test",
+					Size:      120,
+					Location:  cloud.LocationAWS,
+				},
+			})
+
+			if !storage.ErrorEqual(scenario.expectedSaveError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedSaveError, err)
+			}
+
+			content, err := ioutil.ReadFile(f.Name())
+			if err != nil {
+				t.Fatalf("error reading audit file. details: %s", err)
+			}
+
+			if scenario.expected != string(content) {
+				t.Errorf("audit file don't match. expected “%s” and got “%s”", scenario.expected, string(content))
+			}
+		})
+	}
+}
+
+func TestAuditFile_Compact(t *testing.T) {
+	now := time.Now()
+
+	scenarios := []struct {
+		description     string
+		logger          log.Logger
+		filename        string
+		permissions     bool
+		expected        string
+		expectedCorrupt string
+		expectedCount   int
+		expectedError   error
+	}{
+		{
+			description: "it should keep only the latest information of each backup",
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 654321 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws server1 - CATALOG1\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s tes", now.Format(time.RFC3339)))
+				return f.Name()
+			}(),
+			expected: auditFileHeader + fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws server1 - CATALOG1\n", now.Format(time.RFC3339)) +
+				fmt.Sprintf("%s test 654321 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)),
+			expectedCorrupt: fmt.Sprintf("%s tes\n", now.Format(time.RFC3339)),
+			expectedCount:   2,
+		},
+		{
+			description: "it should not rewrite an audit file already compacted",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
 				mockDebugf: func(format string, args ...interface{}) {},
 				mockInfo:   func(args ...interface{}) {},
 				mockInfof:  func(format string, args ...interface{}) {},
 			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString(fmt.Sprintf("%s test 654321 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)))
+				return f.Name()
+			}(),
+			expected: fmt.Sprintf("%s test 654321 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)) +
+				fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)),
+		},
+		{
+			description: "it should ignore when the audit file doesn't exist",
+			logger: mockLogger{
+				mockDebug: func(args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-idontexist"),
+		},
+		{
+			description: "it should detect when the audit file has no read permission",
+			logger: mockLogger{
+				mockDebug: func(args ...interface{}) {},
+			},
 			filename: func() string {
 				n := path.Join(os.TempDir(), "toglacier-test-noperm")
 				if _, err := os.Stat(n); os.IsNotExist(err) {
@@ -567,13 +1185,11 @@ func TestAuditFile_Remove(t *testing.T) {
 						t.Fatalf("error creating a temporary file. details: %s", err)
 					}
 					defer f.Close()
-
-					f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 aws\n", now.Format(time.RFC3339)))
 				}
 
 				return n
 			}(),
-			id: "123456",
+			permissions: true,
 			expectedError: &storage.Error{
 				Code: storage.ErrorCodeOpeningFile,
 				Err: &os.PathError{
@@ -587,16 +1203,32 @@ func TestAuditFile_Remove(t *testing.T) {
 
 	for _, scenario := range scenarios {
 		t.Run(scenario.description, func(t *testing.T) {
+			if scenario.permissions && os.Geteuid() == 0 {
+				t.Skip("the file permissions are ignored for the root user")
+			}
+
 			auditFile := storage.NewAuditFile(scenario.logger, scenario.filename)
-			err := auditFile.Remove(scenario.id)
+			count, err := auditFile.Compact(context.Background())
+			defer os.Remove(scenario.filename + ".corrupt")
 
-			auditFileContent, auditFileErr := ioutil.ReadFile(scenario.filename)
-			if auditFileErr != nil && scenario.expectedError == nil {
-				t.Errorf("error reading audit file. details: %s", auditFileErr)
+			if count != scenario.expectedCount {
+				t.Errorf("dropped lines don't match. expected “%d” and got “%d”", scenario.expectedCount, count)
 			}
 
-			if !reflect.DeepEqual(scenario.expected, string(auditFileContent)) {
-				t.Errorf("audit file don't match. expected “%v” and got “%v”", scenario.expected, string(auditFileContent))
+			if scenario.expected != "" {
+				auditFileContent, auditFileErr := ioutil.ReadFile(scenario.filename)
+				if auditFileErr != nil {
+					t.Fatalf("error reading audit file. details: %s", auditFileErr)
+				}
+
+				if scenario.expected != string(auditFileContent) {
+					t.Errorf("audit file don't match. expected “%v” and got “%v”", scenario.expected, string(auditFileContent))
+				}
+			}
+
+			corruptContent, _ := ioutil.ReadFile(scenario.filename + ".corrupt")
+			if scenario.expectedCorrupt != string(corruptContent) {
+				t.Errorf("corrupted lines don't match. expected “%v” and got “%v”", scenario.expectedCorrupt, string(corruptContent))
 			}
 
 			if !storage.ErrorEqual(scenario.expectedError, err) {
@@ -606,6 +1238,986 @@ func TestAuditFile_Remove(t *testing.T) {
 	}
 }
 
+func TestAuditFile_Remove(t *testing.T) {
+	now := time.Now()
+
+	scenarios := []struct {
+		description   string
+		logger        log.Logger
+		filename      string
+		id            string
+		expected      string
+		expectedError error
+	}{
+		{
+			description: "it should remove a backup information correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 100 aws\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123457 913b87897ffb6dca07e9f17e280aa8ecb9886dffeda8a15efeafec11dec0d108 200 aws\n", now.Add(time.Second).Format(time.RFC3339)))
+				return f.Name()
+			}(),
+			id:       "123457",
+			expected: auditFileHeader + fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 100 aws\n", now.Format(time.RFC3339)),
+		},
+		{
+			description: "it should remove a backup information correctly with backward compatibility (no size)",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123457 913b87897ffb6dca07e9f17e280aa8ecb9886dffeda8a15efeafec11dec0d108\n", now.Add(time.Second).Format(time.RFC3339)))
+				return f.Name()
+			}(),
+			id:       "123457",
+			expected: auditFileHeader + fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 0 aws\n", now.Format(time.RFC3339)),
+		},
+		{
+			description: "it should remove a backup information correctly with backward compatibility (no location)",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123457 913b87897ffb6dca07e9f17e280aa8ecb9886dffeda8a15efeafec11dec0d108 120\n", now.Add(time.Second).Format(time.RFC3339)))
+				return f.Name()
+			}(),
+			id:       "123457",
+			expected: auditFileHeader + fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 120 aws\n", now.Format(time.RFC3339)),
+		},
+		{
+			description: "it should detect when the audit file has no read permission",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				n := path.Join(os.TempDir(), "toglacier-test-noperm")
+				if _, err := os.Stat(n); os.IsNotExist(err) {
+					f, err := os.OpenFile(n, os.O_CREATE, os.FileMode(0077))
+					if err != nil {
+						t.Fatalf("error creating a temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 aws\n", now.Format(time.RFC3339)))
+				}
+
+				return n
+			}(),
+			id: "123456",
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeOpeningFile,
+				Err: &os.PathError{
+					Op:   "open",
+					Path: path.Join(os.TempDir(), "toglacier-test-noperm"),
+					Err:  errors.New("permission denied"),
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			auditFile := storage.NewAuditFile(scenario.logger, scenario.filename)
+			err := auditFile.Remove(context.Background(), scenario.id)
+
+			auditFileContent, auditFileErr := ioutil.ReadFile(scenario.filename)
+			if auditFileErr != nil && scenario.expectedError == nil {
+				t.Errorf("error reading audit file. details: %s", auditFileErr)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, string(auditFileContent)) {
+				t.Errorf("audit file don't match. expected “%v” and got “%v”", scenario.expected, string(auditFileContent))
+			}
+
+			// the audit file is rewritten atomically, so no temporary file should be
+			// left behind
+			if tmpFiles, _ := filepath.Glob(scenario.filename + ".tmp*"); len(tmpFiles) > 0 {
+				t.Errorf("temporary files left behind: %v", tmpFiles)
+			}
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAuditFile_RemoveBatch(t *testing.T) {
+	now := time.Now()
+
+	scenarios := []struct {
+		description   string
+		logger        log.Logger
+		filename      string
+		permissions   bool
+		ids           []string
+		expected      string
+		expectedError error
+	}{
+		{
+			description: "it should remove many backups information rewriting the file once",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 100 aws\n", now.Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123457 913b87897ffb6dca07e9f17e280aa8ecb9886dffeda8a15efeafec11dec0d108 200 aws\n", now.Add(time.Second).Format(time.RFC3339)))
+				f.WriteString(fmt.Sprintf("%s test 123458 5f9c426fb1e150c1c09dda260bb962c7602b595df7586a1f3899735b839b138f 300 aws\n", now.Add(time.Minute).Format(time.RFC3339)))
+				return f.Name()
+			}(),
+			ids:      []string{"123456", "123458"},
+			expected: auditFileHeader + fmt.Sprintf("%s test 123457 913b87897ffb6dca07e9f17e280aa8ecb9886dffeda8a15efeafec11dec0d108 200 aws\n", now.Add(time.Second).Format(time.RFC3339)),
+		},
+		{
+			description: "it should detect when the audit file has no read permission",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				n := path.Join(os.TempDir(), "toglacier-test-noperm")
+				if _, err := os.Stat(n); os.IsNotExist(err) {
+					f, err := os.OpenFile(n, os.O_CREATE, os.FileMode(0077))
+					if err != nil {
+						t.Fatalf("error creating a temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					f.WriteString(fmt.Sprintf("%s test 123456 ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7 aws\n", now.Format(time.RFC3339)))
+				}
+
+				return n
+			}(),
+			ids:         []string{"123456"},
+			permissions: true,
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeOpeningFile,
+				Err: &os.PathError{
+					Op:   "open",
+					Path: path.Join(os.TempDir(), "toglacier-test-noperm"),
+					Err:  errors.New("permission denied"),
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if scenario.permissions && os.Geteuid() == 0 {
+				t.Skip("the file permissions are ignored for the root user")
+			}
+
+			auditFile := storage.NewAuditFile(scenario.logger, scenario.filename)
+			err := auditFile.RemoveBatch(context.Background(), nil, scenario.ids)
+
+			auditFileContent, auditFileErr := ioutil.ReadFile(scenario.filename)
+			if auditFileErr != nil && scenario.expectedError == nil {
+				t.Errorf("error reading audit file. details: %s", auditFileErr)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, string(auditFileContent)) {
+				t.Errorf("audit file don't match. expected “%v” and got “%v”", scenario.expected, string(auditFileContent))
+			}
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAuditFile_Info(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	info := archive.Info{
+		"file1": archive.ItemInfo{
+			ID:       "123456",
+			Status:   archive.ItemInfoStatusNew,
+			Checksum: "a7eb4a5b5ba6b8fe3d0f1f3ca9a3e6b2d0d2d1c3",
+		},
+		"file2": archive.ItemInfo{
+			ID:       "123455",
+			Status:   archive.ItemInfoStatusUnmodified,
+			Checksum: "b8fc5b6c6cb7c9af4e1a2a4db0b4f7c3e1e3e2d4",
+		},
+	}
+
+	updatedInfo := archive.Info{
+		"file1": archive.ItemInfo{
+			ID:       "123456",
+			Status:   archive.ItemInfoStatusNew,
+			Checksum: "a7eb4a5b5ba6b8fe3d0f1f3ca9a3e6b2d0d2d1c3",
+		},
+	}
+
+	streamInfo := archive.Info{
+		"db.sql": archive.ItemInfo{
+			ID:       "123456",
+			Status:   archive.ItemInfoStatusStream,
+			Checksum: "xZzITM+6yGsa9masWjGdi+yAA0DlqCzTf/1795fy5Pk=",
+		},
+	}
+
+	scenarios := []struct {
+		description string
+		action      func(auditFile *storage.AuditFile) error
+		expected    storage.Backups
+	}{
+		{
+			description: "it should keep the archive information of the backups",
+			action: func(auditFile *storage.AuditFile) error {
+				if err := auditFile.Save(context.Background(), storage.Backup{
+					Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS},
+					Info:   info,
+				}); err != nil {
+					return err
+				}
+
+				return auditFile.Save(context.Background(), storage.Backup{
+					Backup: cloud.Backup{ID: "123457", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS},
+				})
+			},
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS},
+					Info:   info,
+				},
+				{
+					Backup: cloud.Backup{ID: "123457", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS},
+				},
+			},
+		},
+		{
+			description: "it should replace and remove the archive information of the backups",
+			action: func(auditFile *storage.AuditFile) error {
+				for _, id := range []string{"123456", "123457", "123458"} {
+					if err := auditFile.Save(context.Background(), storage.Backup{
+						Backup: cloud.Backup{ID: id, CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS},
+						Info:   info,
+					}); err != nil {
+						return err
+					}
+				}
+
+				if err := auditFile.Remove(context.Background(), "123456"); err != nil {
+					return err
+				}
+
+				updated := storage.Backups{
+					{
+						Backup: cloud.Backup{ID: "123457", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS},
+						Info:   updatedInfo,
+					},
+				}
+
+				if err := auditFile.RemoveBatch(context.Background(), updated, []string{"123458"}); err != nil {
+					return err
+				}
+
+				// a backup saved again with the same ID must not find the
+				// information of the removed one
+				return auditFile.Save(context.Background(), storage.Backup{
+					Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS},
+				})
+			},
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS},
+				},
+				{
+					Backup: cloud.Backup{ID: "123457", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS},
+					Info:   updatedInfo,
+				},
+			},
+		},
+		{
+			description: "it should recover the stream from the archive information",
+			action: func(auditFile *storage.AuditFile) error {
+				return auditFile.Save(context.Background(), storage.Backup{
+					Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS, Stream: "db.sql"},
+					Info:   streamInfo,
+				})
+			},
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS, Stream: "db.sql"},
+					Info:   streamInfo,
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating a temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			auditFile := storage.NewAuditFile(mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			}, filepath.Join(dir, "audit.log"))
+
+			if err := scenario.action(auditFile); err != nil {
+				t.Fatalf("unexpected error. details: %s", err)
+			}
+
+			backups, err := auditFile.List(context.Background())
+			if err != nil {
+				t.Fatalf("error listing the backups. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, backups) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backups))
+			}
+		})
+	}
+}
+
+func TestAuditFile_InventoryDate(t *testing.T) {
+	date := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		logger        log.Logger
+		filename      string
+		permissions   bool
+		date          time.Time
+		expected      time.Time
+		expectedError error
+	}{
+		{
+			description: "it should save and retrieve the inventory date correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-inventory"),
+			date:     date,
+			expected: date,
+		},
+		{
+			description: "it should return a zero date when there was no synchronization",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-idontexist"),
+		},
+		{
+			description: "it should detect an invalid inventory date",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				n := path.Join(os.TempDir(), "toglacier-test-invalid")
+				if err := ioutil.WriteFile(n+".inventory", []byte("yesterday"), 0600); err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+
+				return n
+			}(),
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeDateFormat,
+				Err:  &time.ParseError{Layout: time.RFC3339Nano, Value: "yesterday", LayoutElem: "2006", ValueElem: "yesterday", Message: ""},
+			},
+		},
+		{
+			description: "it should detect when the inventory file has no read permission",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				n := path.Join(os.TempDir(), "toglacier-test-noperm")
+				if _, err := os.Stat(n + ".inventory"); os.IsNotExist(err) {
+					f, err := os.OpenFile(n+".inventory", os.O_CREATE, os.FileMode(0077))
+					if err != nil {
+						t.Fatalf("error creating a temporary file. details: %s", err)
+					}
+					defer f.Close()
+				}
+
+				return n
+			}(),
+			permissions: true,
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeReadingFile,
+				Err: &os.PathError{
+					Op:   "open",
+					Path: path.Join(os.TempDir(), "toglacier-test-noperm.inventory"),
+					Err:  errors.New("permission denied"),
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if scenario.permissions && os.Geteuid() == 0 {
+				t.Skip("the file permissions are ignored for the root user")
+			}
+
+			auditFile := storage.NewAuditFile(scenario.logger, scenario.filename)
+
+			if !scenario.date.IsZero() {
+				if err := auditFile.SaveInventoryDate(context.Background(), scenario.date); err != nil {
+					t.Fatalf("error saving inventory date. details: %s", err)
+				}
+			}
+
+			date, err := auditFile.InventoryDate(context.Background())
+			if !date.Equal(scenario.expected) {
+				t.Errorf("dates don't match. expected “%s” and got “%s”", scenario.expected, date)
+			}
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAuditFile_Pause(t *testing.T) {
+	until := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description    string
+		logger         log.Logger
+		filename       string
+		pause          bool
+		until          time.Time
+		resume         bool
+		expectedPaused bool
+		expectedUntil  time.Time
+		expectedError  error
+	}{
+		{
+			description: "it should save and retrieve the pause correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename:       path.Join(os.TempDir(), "toglacier-test-pause"),
+			pause:          true,
+			until:          until,
+			expectedPaused: true,
+			expectedUntil:  until,
+		},
+		{
+			description: "it should pause until resumed",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename:       path.Join(os.TempDir(), "toglacier-test-pause-resumed"),
+			pause:          true,
+			expectedPaused: true,
+		},
+		{
+			description: "it should resume the scheduled actions",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-pause-resume"),
+			pause:    true,
+			until:    until,
+			resume:   true,
+		},
+		{
+			description: "it should detect when it was never paused",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-idontexist"),
+		},
+		{
+			description: "it should detect an invalid pause date",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				n := path.Join(os.TempDir(), "toglacier-test-invalid")
+				if err := ioutil.WriteFile(n+".pause", []byte("tomorrow"), 0600); err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+
+				return n
+			}(),
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeDateFormat,
+				Err:  &time.ParseError{Layout: time.RFC3339Nano, Value: "tomorrow", LayoutElem: "2006", ValueElem: "tomorrow", Message: ""},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			auditFile := storage.NewAuditFile(scenario.logger, scenario.filename)
+
+			if scenario.pause {
+				if err := auditFile.SavePause(context.Background(), scenario.until); err != nil {
+					t.Fatalf("error saving pause. details: %s", err)
+				}
+			}
+
+			if scenario.resume {
+				if err := auditFile.RemovePause(context.Background()); err != nil {
+					t.Fatalf("error removing pause. details: %s", err)
+				}
+			}
+
+			paused, until, err := auditFile.Pause(context.Background())
+			if paused != scenario.expectedPaused {
+				t.Errorf("paused states don't match. expected “%t” and got “%t”", scenario.expectedPaused, paused)
+			}
+
+			if !until.Equal(scenario.expectedUntil) {
+				t.Errorf("dates don't match. expected “%s” and got “%s”", scenario.expectedUntil, until)
+			}
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestAuditFile_Pins(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	pin123 := storage.Pin{ID: "AWSID123", User: "john", Reason: "before migration", CreatedAt: now}
+	pin124 := storage.Pin{ID: "AWSID124", Until: now.Add(24 * time.Hour), User: "john", CreatedAt: now}
+	unpin123 := storage.Pin{ID: "AWSID123", User: "mary", Reason: "migration finished", CreatedAt: now.Add(time.Hour)}
+
+	scenarios := []struct {
+		description    string
+		pin            []storage.Pin
+		unpin          []storage.Pin
+		expected       []storage.Pin
+		expectedEvents []storage.PinEvent
+	}{
+		{
+			description: "it should save and list the pins sorted by id",
+			pin:         []storage.Pin{pin124, pin123, pin124},
+			expected:    []storage.Pin{pin123, pin124},
+			expectedEvents: []storage.PinEvent{
+				{Pin: pin124, Action: storage.PinActionPin},
+				{Pin: pin123, Action: storage.PinActionPin},
+				{Pin: pin124, Action: storage.PinActionPin},
+			},
+		},
+		{
+			description: "it should remove a pin",
+			pin:         []storage.Pin{pin123, pin124},
+			unpin:       []storage.Pin{unpin123, {ID: "AWSID999"}},
+			expected:    []storage.Pin{pin124},
+			expectedEvents: []storage.PinEvent{
+				{Pin: pin123, Action: storage.PinActionPin},
+				{Pin: pin124, Action: storage.PinActionPin},
+				{Pin: unpin123, Action: storage.PinActionUnpin},
+			},
+		},
+		{
+			description: "it should remove all pins",
+			pin:         []storage.Pin{pin123},
+			unpin:       []storage.Pin{unpin123},
+			expectedEvents: []storage.PinEvent{
+				{Pin: pin123, Action: storage.PinActionPin},
+				{Pin: unpin123, Action: storage.PinActionUnpin},
+			},
+		},
+		{
+			description: "it should detect when no backup was pinned",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			auditFile := storage.NewAuditFile(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}, path.Join(dir, "toglacier.audit"))
+
+			for _, pin := range scenario.pin {
+				if err := auditFile.SavePin(context.Background(), pin); err != nil {
+					t.Fatalf("error saving pin. details: %s", err)
+				}
+			}
+
+			for _, unpin := range scenario.unpin {
+				if err := auditFile.RemovePin(context.Background(), unpin); err != nil {
+					t.Fatalf("error removing pin. details: %s", err)
+				}
+			}
+
+			pins, err := auditFile.Pins(context.Background())
+			if err != nil {
+				t.Fatalf("error listing pins. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, pins) {
+				t.Errorf("pins don't match.\n%s", Diff(scenario.expected, pins))
+			}
+
+			events, err := auditFile.PinEvents(context.Background())
+			if err != nil {
+				t.Fatalf("error listing pin audit trail. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedEvents, events) {
+				t.Errorf("pin audit trails don't match.\n%s", Diff(scenario.expectedEvents, events))
+			}
+		})
+	}
+}
+
+func TestAuditFile_Tags(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	preUpgrade := storage.Tag{Name: "pre-upgrade", ID: "AWSID123", CreatedAt: now}
+	monthly := storage.Tag{Name: "monthly", ID: "AWSID124", CreatedAt: now}
+	preUpgradeMoved := storage.Tag{Name: "pre-upgrade", ID: "AWSID125", CreatedAt: now.Add(time.Hour)}
+
+	scenarios := []struct {
+		description string
+		tags        []storage.Tag
+		remove      []string
+		expected    []storage.Tag
+	}{
+		{
+			description: "it should save and list the tags sorted by name",
+			tags:        []storage.Tag{preUpgrade, monthly},
+			expected:    []storage.Tag{monthly, preUpgrade},
+		},
+		{
+			description: "it should replace a tag with the same name",
+			tags:        []storage.Tag{preUpgrade, monthly, preUpgradeMoved},
+			expected:    []storage.Tag{monthly, preUpgradeMoved},
+		},
+		{
+			description: "it should remove a tag",
+			tags:        []storage.Tag{preUpgrade, monthly},
+			remove:      []string{"pre-upgrade", "unknown"},
+			expected:    []storage.Tag{monthly},
+		},
+		{
+			description: "it should remove all tags",
+			tags:        []storage.Tag{preUpgrade},
+			remove:      []string{"pre-upgrade"},
+		},
+		{
+			description: "it should detect when no backup was tagged",
+			remove:      []string{"pre-upgrade"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			auditFile := storage.NewAuditFile(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}, path.Join(dir, "toglacier.audit"))
+
+			for _, tag := range scenario.tags {
+				if err := auditFile.SaveTag(context.Background(), tag); err != nil {
+					t.Fatalf("error saving tag. details: %s", err)
+				}
+			}
+
+			for _, name := range scenario.remove {
+				if err := auditFile.RemoveTag(context.Background(), name); err != nil {
+					t.Fatalf("error removing tag. details: %s", err)
+				}
+			}
+
+			tags, err := auditFile.Tags(context.Background())
+			if err != nil {
+				t.Fatalf("error listing tags. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, tags) {
+				t.Errorf("tags don't match.\n%s", Diff(scenario.expected, tags))
+			}
+		})
+	}
+}
+
+func TestAuditFile_Operations(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	removeOperation := storage.Operation{
+		CreatedAt: now,
+		Command:   "remove",
+		Action:    "remove backups",
+		Backups:   []string{"AWSID123", "AWSID124"},
+	}
+
+	compactOperation := storage.Operation{
+		CreatedAt: now.Add(time.Hour),
+		Command:   "db",
+		Action:    "compact storage",
+		Error:     "error writing the storage file",
+	}
+
+	scenarios := []struct {
+		description string
+		operations  []storage.Operation
+		expected    []storage.Operation
+	}{
+		{
+			description: "it should list the operations in the order that they were recorded",
+			operations:  []storage.Operation{removeOperation, compactOperation, removeOperation},
+			expected:    []storage.Operation{removeOperation, compactOperation, removeOperation},
+		},
+		{
+			description: "it should detect when no operation was recorded",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			auditFile := storage.NewAuditFile(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}, path.Join(dir, "toglacier.audit"))
+
+			for _, operation := range scenario.operations {
+				if err := auditFile.SaveOperation(context.Background(), operation); err != nil {
+					t.Fatalf("error saving operation. details: %s", err)
+				}
+			}
+
+			operations, err := auditFile.Operations(context.Background())
+			if err != nil {
+				t.Fatalf("error listing operations. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, operations) {
+				t.Errorf("operations don't match.\n%s", Diff(scenario.expected, operations))
+			}
+		})
+	}
+}
+
+func TestAuditFile_RestoreProgress(t *testing.T) {
+	progress := storage.RestoreProgress{
+		Downloaded: map[string]string{
+			"AWSID124": "/tmp/backup-AWSID124.tar",
+		},
+		Extracted: map[string]bool{
+			"AWSID123": true,
+		},
+	}
+
+	scenarios := []struct {
+		description   string
+		logger        log.Logger
+		filename      string
+		progress      *storage.RestoreProgress
+		remove        bool
+		expected      storage.RestoreProgress
+		expectedError error
+	}{
+		{
+			description: "it should save and retrieve the restore progress correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-restore"),
+			progress: &progress,
+			expected: progress,
+		},
+		{
+			description: "it should return an empty progress when there's no retrieval in progress",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-idontexist"),
+		},
+		{
+			description: "it should remove the restore progress of a finished retrieval",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-restore-finished"),
+			progress: &progress,
+			remove:   true,
+		},
+		{
+			description: "it should detect an invalid restore progress",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				n := path.Join(os.TempDir(), "toglacier-test-invalid")
+				if err := ioutil.WriteFile(n+".restore", []byte("{"), 0600); err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+
+				return n
+			}(),
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeDecodingRestoreProgress,
+				Err:  errors.New("unexpected end of JSON input"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			auditFile := storage.NewAuditFile(scenario.logger, scenario.filename)
+
+			if scenario.progress != nil {
+				if err := auditFile.SaveRestoreProgress(context.Background(), "AWSID123", *scenario.progress); err != nil {
+					t.Fatalf("error saving restore progress. details: %s", err)
+				}
+			}
+
+			if scenario.remove {
+				if err := auditFile.RemoveRestoreProgress(context.Background(), "AWSID123"); err != nil {
+					t.Fatalf("error removing restore progress. details: %s", err)
+				}
+			}
+
+			progress, err := auditFile.RestoreProgress(context.Background(), "AWSID123")
+			if !reflect.DeepEqual(scenario.expected, progress) {
+				t.Errorf("restore progress doesn't match. expected “%v” and got “%v”", scenario.expected, progress)
+			}
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			restores, err := auditFile.RestoreProgresses(context.Background())
+			if err != nil {
+				t.Fatalf("error listing restore progresses. details: %s", err)
+			}
+
+			expectedRestores := make(map[string]storage.RestoreProgress)
+			if scenario.progress != nil && !scenario.remove {
+				expectedRestores["AWSID123"] = scenario.expected
+			}
+
+			if !reflect.DeepEqual(expectedRestores, restores) {
+				t.Errorf("restore progresses don't match. expected “%v” and got “%v”", expectedRestores, restores)
+			}
+		})
+	}
+}
+
 type mockLogger struct {
 	mockDebug    func(args ...interface{})
 	mockDebugf   func(format string, args ...interface{})
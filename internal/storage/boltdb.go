@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"context"
+	"encoding/binary"
 	"encoding/json"
 	"os"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/pkg/errors"
@@ -14,6 +17,39 @@ import (
 // stored.
 var BoltDBBucket = []byte("toglacier")
 
+// BoltDBMetadataBucket defines the bucket in the BoltDB database where the
+// information about the storage itself is stored, like the date of the last
+// synchronization with the cloud inventory.
+var BoltDBMetadataBucket = []byte("toglacier-metadata")
+
+// boltDBInventoryDateKey is the key in the metadata bucket that stores the date
+// of the last synchronization with the cloud inventory.
+var boltDBInventoryDateKey = []byte("inventory-date")
+
+// boltDBPauseKey is the key in the metadata bucket that stores until when the
+// scheduled actions are suspended.
+var boltDBPauseKey = []byte("pause-until")
+
+// BoltDBRestoreBucket defines the bucket in the BoltDB database where the
+// progress of the ongoing backup retrievals is stored.
+var BoltDBRestoreBucket = []byte("toglacier-restore")
+
+// BoltDBPinBucket defines the bucket in the BoltDB database where the backups
+// protected from the old backups removal are stored.
+var BoltDBPinBucket = []byte("toglacier-pin")
+
+// BoltDBPinAuditBucket defines the bucket in the BoltDB database where the
+// append-only audit trail of the pins is stored.
+var BoltDBPinAuditBucket = []byte("toglacier-pin-audit")
+
+// BoltDBOperationBucket defines the bucket in the BoltDB database where the
+// append-only audit log of the destructive operations is stored.
+var BoltDBOperationBucket = []byte("toglacier-operation")
+
+// BoltDBTagBucket defines the bucket in the BoltDB database where the
+// human-friendly names of the backups are stored.
+var BoltDBTagBucket = []byte("toglacier-tag")
+
 // BoltDBFileMode defines the file mode used for the BoltDB database file. By
 // default only the owner has permission to access the file.
 var BoltDBFileMode = os.FileMode(0600)
@@ -24,6 +60,29 @@ var BoltDBFileMode = os.FileMode(0600)
 type BoltDB struct {
 	logger   log.Logger
 	Filename string
+
+	// NoSync skips the fsync after each database change. It speeds up the
+	// writes, but a power loss could leave the database file corrupted, so it
+	// should only be used when the local storage can be rebuilt from the cloud.
+	NoSync bool
+
+	// AllocSize is the amount of space (in bytes) allocated each time the
+	// database file grows. When zero the BoltDB default (16MB) is used.
+	AllocSize int
+
+	// Now retrieves the current time of the operations recorded by the storage
+	// itself, like the schema migrations. When nil the system clock is used.
+	Now func() time.Time
+}
+
+// now returns the current time using the defined clock, falling back to the
+// system clock.
+func (b BoltDB) now() time.Time {
+	if b.Now == nil {
+		return time.Now()
+	}
+
+	return b.Now()
 }
 
 // NewBoltDB initializes a BoltDB storage.
@@ -49,10 +108,14 @@ func NewBoltDB(logger log.Logger, filename string) *BoltDB {
 //         // unknown error
 //       }
 //     }
-func (b *BoltDB) Save(backup Backup) error {
+func (b *BoltDB) Save(ctx context.Context, backup Backup) error {
 	b.logger.Debugf("storage: saving backup “%s” in boltdb storage", backup.Backup.ID)
 
-	db, err := bolt.Open(b.Filename, BoltDBFileMode, nil)
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	db, err := b.open()
 	if err != nil {
 		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
 	}
@@ -102,10 +165,14 @@ func (b *BoltDB) Save(backup Backup) error {
 //         // unknown error
 //       }
 //     }
-func (b BoltDB) List() (Backups, error) {
+func (b BoltDB) List(ctx context.Context) (Backups, error) {
 	b.logger.Debug("storage: listing backups from boltdb storage")
 
-	db, err := bolt.Open(b.Filename, BoltDBFileMode, nil)
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	db, err := b.open()
 	if err != nil {
 		return nil, errors.WithStack(newError(ErrorCodeOpeningFile, err))
 	}
@@ -121,6 +188,10 @@ func (b BoltDB) List() (Backups, error) {
 		}
 
 		err = bucket.ForEach(func(k, v []byte) error {
+			if err := checkCancellation(ctx); err != nil {
+				return err
+			}
+
 			var backup Backup
 			if err = json.Unmarshal(v, &backup); err != nil {
 				return errors.WithStack(newError(ErrorCodeDecodingBackup, err))
@@ -166,10 +237,14 @@ func (b BoltDB) List() (Backups, error) {
 //         // unknown error
 //       }
 //     }
-func (b BoltDB) Remove(id string) error {
+func (b BoltDB) Remove(ctx context.Context, id string) error {
 	b.logger.Debugf("storage: removing backup “%s” from boltdb storage", id)
 
-	db, err := bolt.Open(b.Filename, BoltDBFileMode, nil)
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	db, err := b.open()
 	if err != nil {
 		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
 	}
@@ -195,3 +270,1098 @@ func (b BoltDB) Remove(id string) error {
 	b.logger.Infof("storage: backup “%s” removed successfully from boltdb storage", id)
 	return nil
 }
+
+// RemoveBatch saves the updated backups and removes the backups of the ids in a
+// single database transaction. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) RemoveBatch(ctx context.Context, updated Backups, ids []string) error {
+	b.logger.Debugf("storage: removing %d backups from boltdb storage", len(ids))
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBBucket)
+		if bucket == nil {
+			return errors.WithStack(newError(ErrorCodeDatabaseNotFound, nil))
+		}
+
+		for _, backup := range updated {
+			encoded, err := json.Marshal(backup)
+			if err != nil {
+				return errors.WithStack(newError(ErrorCodeEncodingBackup, err))
+			}
+
+			if err = bucket.Put([]byte(backup.Backup.ID), encoded); err != nil {
+				return errors.WithStack(newError(ErrorCodeSave, err))
+			}
+		}
+
+		for _, id := range ids {
+			if err := bucket.Delete([]byte(id)); err != nil {
+				return errors.WithStack(newError(ErrorCodeDelete, err))
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeUpdatingDatabase, err))
+	}
+
+	b.logger.Infof("storage: %d backups removed successfully from boltdb storage", len(ids))
+	return nil
+}
+
+// SaveInventoryDate stores when the local storage was synchronized with the
+// cloud inventory. On error it will return an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) SaveInventoryDate(ctx context.Context, date time.Time) error {
+	b.logger.Debugf("storage: saving inventory date “%s” in boltdb storage", date.Format(time.RFC3339))
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		var bucket *bolt.Bucket
+		if bucket, err = tx.CreateBucketIfNotExists(BoltDBMetadataBucket); err != nil {
+			return errors.WithStack(newError(ErrorAccessingBucket, err))
+		}
+
+		if err = bucket.Put(boltDBInventoryDateKey, []byte(date.Format(time.RFC3339Nano))); err != nil {
+			return errors.WithStack(newError(ErrorCodeSave, err))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeUpdatingDatabase, err))
+	}
+
+	b.logger.Info("storage: inventory date saved successfully in boltdb storage")
+	return nil
+}
+
+// InventoryDate returns when the local storage was synchronized with the cloud
+// inventory. If it never happened a zero time is returned. On error it will
+// return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) InventoryDate(ctx context.Context) (time.Time, error) {
+	b.logger.Debug("storage: retrieving inventory date from boltdb storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return time.Time{}, errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	var date time.Time
+
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBMetadataBucket)
+		if bucket == nil {
+			// never synchronized
+			return nil
+		}
+
+		value := bucket.Get(boltDBInventoryDateKey)
+		if value == nil {
+			return nil
+		}
+
+		if date, err = time.Parse(time.RFC3339Nano, string(value)); err != nil {
+			return errors.WithStack(newError(ErrorCodeDateFormat, err))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return time.Time{}, errors.WithStack(newError(ErrorCodeListingDatabase, err))
+	}
+
+	b.logger.Info("storage: inventory date retrieved successfully from boltdb storage")
+	return date, nil
+}
+
+// SavePause suspends the scheduled actions until the given date. A zero date
+// suspends them until resumed. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) SavePause(ctx context.Context, until time.Time) error {
+	b.logger.Debugf("storage: saving pause until “%s” in boltdb storage", until.Format(time.RFC3339))
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		var bucket *bolt.Bucket
+		if bucket, err = tx.CreateBucketIfNotExists(BoltDBMetadataBucket); err != nil {
+			return errors.WithStack(newError(ErrorAccessingBucket, err))
+		}
+
+		if err = bucket.Put(boltDBPauseKey, []byte(until.Format(time.RFC3339Nano))); err != nil {
+			return errors.WithStack(newError(ErrorCodeSave, err))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeUpdatingDatabase, err))
+	}
+
+	b.logger.Info("storage: pause saved successfully in boltdb storage")
+	return nil
+}
+
+// Pause returns if the scheduled actions are suspended and until when. A zero
+// date means that they are suspended until resumed. On error it will return an
+// Error type encapsulated in a traceable error. To retrieve the desired error
+// you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) Pause(ctx context.Context) (bool, time.Time, error) {
+	b.logger.Debug("storage: retrieving pause from boltdb storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return false, time.Time{}, err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return false, time.Time{}, errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	var paused bool
+	var until time.Time
+
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBMetadataBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		value := bucket.Get(boltDBPauseKey)
+		if value == nil {
+			return nil
+		}
+
+		if until, err = time.Parse(time.RFC3339Nano, string(value)); err != nil {
+			return errors.WithStack(newError(ErrorCodeDateFormat, err))
+		}
+
+		paused = true
+		return nil
+	})
+
+	if err != nil {
+		return false, time.Time{}, errors.WithStack(newError(ErrorCodeListingDatabase, err))
+	}
+
+	b.logger.Info("storage: pause retrieved successfully from boltdb storage")
+	return paused, until, nil
+}
+
+// RemovePause resumes the scheduled actions. On error it will return an Error
+// type encapsulated in a traceable error. To retrieve the desired error you can
+// do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) RemovePause(ctx context.Context) error {
+	b.logger.Debug("storage: removing pause from boltdb storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBMetadataBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		if err = bucket.Delete(boltDBPauseKey); err != nil {
+			return errors.WithStack(newError(ErrorCodeDelete, err))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeUpdatingDatabase, err))
+	}
+
+	b.logger.Info("storage: pause removed successfully from boltdb storage")
+	return nil
+}
+
+// SaveRestoreProgress stores the progress of an ongoing backup retrieval. On
+// error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) SaveRestoreProgress(ctx context.Context, id string, progress RestoreProgress) error {
+	b.logger.Debugf("storage: saving restore progress of backup “%s” in boltdb storage", id)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	encoded, err := json.Marshal(progress)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingRestoreProgress, err))
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		var bucket *bolt.Bucket
+		if bucket, err = tx.CreateBucketIfNotExists(BoltDBRestoreBucket); err != nil {
+			return errors.WithStack(newError(ErrorAccessingBucket, err))
+		}
+
+		if err = bucket.Put([]byte(id), encoded); err != nil {
+			return errors.WithStack(newError(ErrorCodeSave, err))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeUpdatingDatabase, err))
+	}
+
+	b.logger.Infof("storage: restore progress of backup “%s” saved successfully in boltdb storage", id)
+	return nil
+}
+
+// RestoreProgress returns the progress of a backup retrieval. If there's no
+// retrieval in progress an empty progress is returned. On error it will return
+// an Error type encapsulated in a traceable error. To retrieve the desired
+// error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) RestoreProgress(ctx context.Context, id string) (RestoreProgress, error) {
+	b.logger.Debugf("storage: retrieving restore progress of backup “%s” from boltdb storage", id)
+
+	if err := checkCancellation(ctx); err != nil {
+		return RestoreProgress{}, err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return RestoreProgress{}, errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	var progress RestoreProgress
+
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBRestoreBucket)
+		if bucket == nil {
+			// no restore was ever interrupted
+			return nil
+		}
+
+		value := bucket.Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+
+		if err = json.Unmarshal(value, &progress); err != nil {
+			return errors.WithStack(newError(ErrorCodeDecodingRestoreProgress, err))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return RestoreProgress{}, errors.WithStack(newError(ErrorCodeListingDatabase, err))
+	}
+
+	b.logger.Infof("storage: restore progress of backup “%s” retrieved successfully from boltdb storage", id)
+	return progress, nil
+}
+
+// RestoreProgresses returns the progress of all ongoing backup retrievals by
+// backup id. On error it will return an Error type encapsulated in a traceable
+// error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) RestoreProgresses(ctx context.Context) (map[string]RestoreProgress, error) {
+	b.logger.Debug("storage: retrieving all restore progresses from boltdb storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	restores := make(map[string]RestoreProgress)
+
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBRestoreBucket)
+		if bucket == nil {
+			// no restore was ever interrupted
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			var progress RestoreProgress
+			if err := json.Unmarshal(v, &progress); err != nil {
+				return errors.WithStack(newError(ErrorCodeDecodingRestoreProgress, err))
+			}
+
+			restores[string(k)] = progress
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeListingDatabase, err))
+	}
+
+	b.logger.Info("storage: restore progresses retrieved successfully from boltdb storage")
+	return restores, nil
+}
+
+// RemoveRestoreProgress erases the progress of a finished backup retrieval. On
+// error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) RemoveRestoreProgress(ctx context.Context, id string) error {
+	b.logger.Debugf("storage: removing restore progress of backup “%s” from boltdb storage", id)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBRestoreBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		if err = bucket.Delete([]byte(id)); err != nil {
+			return errors.WithStack(newError(ErrorCodeDelete, err))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeUpdatingDatabase, err))
+	}
+
+	b.logger.Infof("storage: restore progress of backup “%s” removed successfully from boltdb storage", id)
+	return nil
+}
+
+// SavePin protects the backup from the old backups removal. The pin and its
+// audit trail record are stored in the same transaction. On error it will
+// return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) SavePin(ctx context.Context, pin Pin) error {
+	b.logger.Debugf("storage: saving pin of backup “%s” in boltdb storage", pin.ID)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(pin)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingPin, err))
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		var bucket *bolt.Bucket
+		if bucket, err = tx.CreateBucketIfNotExists(BoltDBPinBucket); err != nil {
+			return errors.WithStack(newError(ErrorAccessingBucket, err))
+		}
+
+		if err = bucket.Put([]byte(pin.ID), encoded); err != nil {
+			return errors.WithStack(newError(ErrorCodeSave, err))
+		}
+
+		return errors.WithStack(addPinEvent(tx, PinEvent{Pin: pin, Action: PinActionPin}))
+	})
+
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeUpdatingDatabase, err))
+	}
+
+	b.logger.Infof("storage: pin of backup “%s” saved successfully in boltdb storage", pin.ID)
+	return nil
+}
+
+// Pins returns the backups protected from the old backups removal, including
+// the expired pins, sorted by id. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) Pins(ctx context.Context) ([]Pin, error) {
+	b.logger.Debug("storage: listing pins from boltdb storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	var pins []Pin
+
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBPinBucket)
+		if bucket == nil {
+			// no backup was ever pinned
+			return nil
+		}
+
+		// the keys are iterated in byte order
+		return bucket.ForEach(func(k, v []byte) error {
+			var pin Pin
+			if err := json.Unmarshal(v, &pin); err != nil {
+				return errors.WithStack(newError(ErrorCodeDecodingPin, err))
+			}
+
+			pins = append(pins, pin)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeListingDatabase, err))
+	}
+
+	b.logger.Info("storage: pins listed successfully from boltdb storage")
+	return pins, nil
+}
+
+// RemovePin allows the backup to be removed again with the old backups. The
+// removal and its audit trail record are stored in the same transaction. On
+// error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) RemovePin(ctx context.Context, unpin Pin) error {
+	b.logger.Debugf("storage: removing pin of backup “%s” from boltdb storage", unpin.ID)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBPinBucket)
+		if bucket == nil || bucket.Get([]byte(unpin.ID)) == nil {
+			return nil
+		}
+
+		if err = bucket.Delete([]byte(unpin.ID)); err != nil {
+			return errors.WithStack(newError(ErrorCodeDelete, err))
+		}
+
+		return errors.WithStack(addPinEvent(tx, PinEvent{Pin: unpin, Action: PinActionUnpin}))
+	})
+
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeUpdatingDatabase, err))
+	}
+
+	b.logger.Infof("storage: pin of backup “%s” removed successfully from boltdb storage", unpin.ID)
+	return nil
+}
+
+// PinEvents returns the pin audit trail, in the order that the changes
+// happened. On error it will return an Error type encapsulated in a traceable
+// error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) PinEvents(ctx context.Context) ([]PinEvent, error) {
+	b.logger.Debug("storage: listing pin audit trail from boltdb storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	var events []PinEvent
+
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBPinAuditBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		// the keys are big endian sequences, so the byte order is the order
+		// that the events were added
+		return bucket.ForEach(func(k, v []byte) error {
+			var event PinEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return errors.WithStack(newError(ErrorCodeDecodingPin, err))
+			}
+
+			events = append(events, event)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeListingDatabase, err))
+	}
+
+	b.logger.Info("storage: pin audit trail listed successfully from boltdb storage")
+	return events, nil
+}
+
+// SaveOperation appends the destructive operation to the audit log. On error
+// it will return an Error type encapsulated in a traceable error. To retrieve
+// the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) SaveOperation(ctx context.Context, operation Operation) error {
+	b.logger.Debugf("storage: saving operation “%s” in boltdb storage", operation.Action)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(operation)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingOperation, err))
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		return errors.WithStack(appendSequenced(tx, BoltDBOperationBucket, encoded))
+	})
+
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeUpdatingDatabase, err))
+	}
+
+	b.logger.Infof("storage: operation “%s” saved successfully in boltdb storage", operation.Action)
+	return nil
+}
+
+// Operations returns the audit log of the destructive operations, in the order
+// that they were recorded. On error it will return an Error type encapsulated
+// in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) Operations(ctx context.Context) ([]Operation, error) {
+	b.logger.Debug("storage: listing operations from boltdb storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	var operations []Operation
+
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBOperationBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			var operation Operation
+			if err := json.Unmarshal(v, &operation); err != nil {
+				return errors.WithStack(newError(ErrorCodeDecodingOperation, err))
+			}
+
+			operations = append(operations, operation)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeListingDatabase, err))
+	}
+
+	b.logger.Info("storage: operations listed successfully from boltdb storage")
+	return operations, nil
+}
+
+// SaveTag assigns the name to the backup, replacing a tag with the same name.
+// On error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) SaveTag(ctx context.Context, tag Tag) error {
+	b.logger.Debugf("storage: saving tag “%s” of backup “%s” in boltdb storage", tag.Name, tag.ID)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(tag)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingTag, err))
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		var bucket *bolt.Bucket
+		if bucket, err = tx.CreateBucketIfNotExists(BoltDBTagBucket); err != nil {
+			return errors.WithStack(newError(ErrorAccessingBucket, err))
+		}
+
+		if err = bucket.Put([]byte(tag.Name), encoded); err != nil {
+			return errors.WithStack(newError(ErrorCodeSave, err))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeUpdatingDatabase, err))
+	}
+
+	b.logger.Infof("storage: tag “%s” of backup “%s” saved successfully in boltdb storage", tag.Name, tag.ID)
+	return nil
+}
+
+// Tags returns the names of the backups sorted by name. On error it will
+// return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) Tags(ctx context.Context) ([]Tag, error) {
+	b.logger.Debug("storage: listing tags from boltdb storage")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	var tags []Tag
+
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBTagBucket)
+		if bucket == nil {
+			// no backup was ever tagged
+			return nil
+		}
+
+		// the keys are iterated in byte order
+		return bucket.ForEach(func(k, v []byte) error {
+			var tag Tag
+			if err := json.Unmarshal(v, &tag); err != nil {
+				return errors.WithStack(newError(ErrorCodeDecodingTag, err))
+			}
+
+			tags = append(tags, tag)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeListingDatabase, err))
+	}
+
+	b.logger.Info("storage: tags listed successfully from boltdb storage")
+	return tags, nil
+}
+
+// RemoveTag removes the name of the backup. On error it will return an Error
+// type encapsulated in a traceable error. To retrieve the desired error you
+// can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (b BoltDB) RemoveTag(ctx context.Context, name string) error {
+	b.logger.Debugf("storage: removing tag “%s” from boltdb storage", name)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	db, err := b.open()
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(BoltDBTagBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		if err = bucket.Delete([]byte(name)); err != nil {
+			return errors.WithStack(newError(ErrorCodeDelete, err))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeUpdatingDatabase, err))
+	}
+
+	b.logger.Infof("storage: tag “%s” removed successfully from boltdb storage", name)
+	return nil
+}
+
+// addPinEvent appends the event to the pin audit trail inside the transaction.
+func addPinEvent(tx *bolt.Tx, event PinEvent) error {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingPin, err))
+	}
+
+	return errors.WithStack(appendSequenced(tx, BoltDBPinAuditBucket, encoded))
+}
+
+// appendSequenced stores the value in the bucket with the next sequence as key.
+// The keys are big endian, so the byte order is the order that the values were
+// appended.
+func appendSequenced(tx *bolt.Tx, bucketName, value []byte) error {
+	bucket, err := tx.CreateBucketIfNotExists(bucketName)
+	if err != nil {
+		return errors.WithStack(newError(ErrorAccessingBucket, err))
+	}
+
+	sequence, err := bucket.NextSequence()
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeSave, err))
+	}
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, sequence)
+
+	if err = bucket.Put(key, value); err != nil {
+		return errors.WithStack(newError(ErrorCodeSave, err))
+	}
+
+	return nil
+}
+
+// open the database file applying the durability options.
+func (b *BoltDB) open() (*bolt.DB, error) {
+	db, err := bolt.Open(b.Filename, BoltDBFileMode, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	db.NoSync = b.NoSync
+	if b.AllocSize > 0 {
+		db.AllocSize = b.AllocSize
+	}
+
+	if err = b.migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -1,6 +1,7 @@
 package storage_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/boltdb/bolt"
+	"github.com/rafaeljusto/toglacier/internal/archive"
 	"github.com/rafaeljusto/toglacier/internal/cloud"
 	"github.com/rafaeljusto/toglacier/internal/log"
 	"github.com/rafaeljusto/toglacier/internal/storage"
@@ -23,6 +25,8 @@ func TestBoltDB_Save(t *testing.T) {
 		description   string
 		logger        log.Logger
 		filename      string
+		noSync        bool
+		allocSize     int
 		backup        storage.Backup
 		expectedError error
 	}{
@@ -54,6 +58,36 @@ func TestBoltDB_Save(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "it should save a backup without syncing the database",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			noSync:    true,
+			allocSize: 1048576,
+			backup: storage.Backup{
+				Backup: cloud.Backup{
+					ID:        "123456",
+					CreatedAt: now,
+					Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+					VaultName: "test",
+					Size:      120,
+					Location:  cloud.LocationAWS,
+				},
+			},
+		},
 		{
 			description: "it should fail when backup id is empty",
 			logger: mockLogger{
@@ -122,7 +156,9 @@ func TestBoltDB_Save(t *testing.T) {
 	for _, scenario := range scenarios {
 		t.Run(scenario.description, func(t *testing.T) {
 			boltDB := storage.NewBoltDB(scenario.logger, scenario.filename)
-			err := boltDB.Save(scenario.backup)
+			boltDB.NoSync = scenario.noSync
+			boltDB.AllocSize = scenario.allocSize
+			err := boltDB.Save(context.Background(), scenario.backup)
 
 			if !storage.ErrorEqual(scenario.expectedError, err) {
 				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
@@ -138,6 +174,7 @@ func TestBoltDB_List(t *testing.T) {
 		description   string
 		logger        log.Logger
 		filename      string
+		cancelled     bool
 		expected      storage.Backups
 		expectedError error
 	}{
@@ -489,12 +526,42 @@ func TestBoltDB_List(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "it should stop listing when the context is cancelled",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			cancelled: true,
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeCancelled,
+				Err:  context.Canceled,
+			},
+		},
 	}
 
 	for _, scenario := range scenarios {
 		t.Run(scenario.description, func(t *testing.T) {
 			boltDB := storage.NewBoltDB(scenario.logger, scenario.filename)
-			backups, err := boltDB.List()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if scenario.cancelled {
+				cancel()
+			}
+
+			backups, err := boltDB.List(ctx)
 
 			if !reflect.DeepEqual(scenario.expected, backups) {
 				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backups))
@@ -646,11 +713,893 @@ func TestBoltDB_Remove(t *testing.T) {
 	for _, scenario := range scenarios {
 		t.Run(scenario.description, func(t *testing.T) {
 			boltDB := storage.NewBoltDB(scenario.logger, scenario.filename)
-			err := boltDB.Remove(scenario.id)
+			err := boltDB.Remove(context.Background(), scenario.id)
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestBoltDB_RemoveBatch(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		logger        log.Logger
+		filename      string
+		updated       storage.Backups
+		ids           []string
+		expected      storage.Backups
+		expectedError error
+	}{
+		{
+			description: "it should remove and update the backups in a single transaction",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				backups := storage.Backups{
+					{Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test"}},
+					{Backup: cloud.Backup{ID: "123457", CreatedAt: now.Add(time.Second), VaultName: "test"}},
+					{
+						Backup: cloud.Backup{ID: "123458", CreatedAt: now.Add(time.Minute), VaultName: "test"},
+						Info: archive.Info{
+							"file1": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusUnmodified},
+							"file2": archive.ItemInfo{ID: "123458", Status: archive.ItemInfoStatusNew},
+						},
+					},
+				}
+
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				f.Close()
+
+				boltDB, err := bolt.Open(f.Name(), storage.BoltDBFileMode, nil)
+				if err != nil {
+					t.Fatalf("error opening database. details: %s", err)
+				}
+				defer boltDB.Close()
+
+				err = boltDB.Update(func(tx *bolt.Tx) error {
+					var bucket *bolt.Bucket
+					if bucket, err = tx.CreateBucketIfNotExists(storage.BoltDBBucket); err != nil {
+						t.Fatalf("error creating or opening bucket. details: %s", err)
+					}
+
+					for _, backup := range backups {
+						encoded, err := json.Marshal(backup)
+						if err != nil {
+							t.Fatalf("error encoding backup. details: %s", err)
+						}
+
+						if err = bucket.Put([]byte(backup.Backup.ID), encoded); err != nil {
+							t.Fatalf("error putting data in bucket. details: %s", err)
+						}
+					}
+
+					return nil
+				})
+
+				if err != nil {
+					t.Fatalf("error updating bucket. details: %s", err)
+				}
+
+				return f.Name()
+			}(),
+			updated: storage.Backups{
+				{
+					Backup: cloud.Backup{ID: "123458", CreatedAt: now.Add(time.Minute), VaultName: "test"},
+					Info: archive.Info{
+						"file2": archive.ItemInfo{ID: "123458", Status: archive.ItemInfoStatusNew},
+					},
+				},
+			},
+			ids: []string{"123456", "123457"},
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{ID: "123458", CreatedAt: now.Add(time.Minute), VaultName: "test", Location: cloud.LocationAWS},
+					Info: archive.Info{
+						"file2": archive.ItemInfo{ID: "123458", Status: archive.ItemInfoStatusNew},
+					},
+				},
+			},
+		},
+		{
+			description: "it should detect when the database bucket doesn't exist",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				f.Close()
+
+				boltDB, err := bolt.Open(f.Name(), storage.BoltDBFileMode, nil)
+				if err != nil {
+					t.Fatalf("error opening database. details: %s", err)
+				}
+				defer boltDB.Close()
+
+				return f.Name()
+			}(),
+			ids: []string{"123456"},
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeUpdatingDatabase,
+				Err: &storage.Error{
+					Code: storage.ErrorCodeDatabaseNotFound,
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			boltDB := storage.NewBoltDB(scenario.logger, scenario.filename)
+			err := boltDB.RemoveBatch(context.Background(), scenario.updated, scenario.ids)
 
 			if !storage.ErrorEqual(scenario.expectedError, err) {
 				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
 			}
+
+			if scenario.expectedError != nil {
+				return
+			}
+
+			backups, err := boltDB.List(context.Background())
+			if err != nil {
+				t.Fatalf("error listing backups. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, backups) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backups))
+			}
+		})
+	}
+}
+
+func TestBoltDB_InventoryDate(t *testing.T) {
+	date := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		logger        log.Logger
+		filename      string
+		permissions   bool
+		date          time.Time
+		expected      time.Time
+		expectedError error
+	}{
+		{
+			description: "it should save and retrieve the inventory date correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			date:     date,
+			expected: date,
+		},
+		{
+			description: "it should return a zero date when there was no synchronization",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+		},
+		{
+			description: "it should detect an invalid inventory date in the database",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				f.Close()
+
+				db, err := bolt.Open(f.Name(), storage.BoltDBFileMode, nil)
+				if err != nil {
+					t.Fatalf("error opening database. details: %s", err)
+				}
+				defer db.Close()
+
+				err = db.Update(func(tx *bolt.Tx) error {
+					bucket, err := tx.CreateBucketIfNotExists(storage.BoltDBMetadataBucket)
+					if err != nil {
+						return err
+					}
+
+					return bucket.Put([]byte("inventory-date"), []byte("yesterday"))
+				})
+
+				if err != nil {
+					t.Fatalf("error updating database. details: %s", err)
+				}
+
+				return f.Name()
+			}(),
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeListingDatabase,
+				Err: &storage.Error{
+					Code: storage.ErrorCodeDateFormat,
+					Err:  &time.ParseError{Layout: time.RFC3339Nano, Value: "yesterday", LayoutElem: "2006", ValueElem: "yesterday", Message: ""},
+				},
+			},
+		},
+		{
+			description: "it should fail to use a database file with no permission",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				n := path.Join(os.TempDir(), "toglacier-test-noperm")
+				if _, err := os.Stat(n); os.IsNotExist(err) {
+					f, err := os.OpenFile(n, os.O_CREATE, os.FileMode(0077))
+					if err != nil {
+						t.Fatalf("error creating a temporary file. details: %s", err)
+					}
+					defer f.Close()
+				}
+
+				return n
+			}(),
+			permissions: true,
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeOpeningFile,
+				Err: &os.PathError{
+					Op:   "open",
+					Path: path.Join(os.TempDir(), "toglacier-test-noperm"),
+					Err:  errors.New("permission denied"),
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if scenario.permissions && os.Geteuid() == 0 {
+				t.Skip("the file permissions are ignored for the root user")
+			}
+
+			boltDB := storage.NewBoltDB(scenario.logger, scenario.filename)
+
+			if !scenario.date.IsZero() {
+				if err := boltDB.SaveInventoryDate(context.Background(), scenario.date); err != nil {
+					t.Fatalf("error saving inventory date. details: %s", err)
+				}
+			}
+
+			date, err := boltDB.InventoryDate(context.Background())
+			if !date.Equal(scenario.expected) {
+				t.Errorf("dates don't match. expected “%s” and got “%s”", scenario.expected, date)
+			}
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestBoltDB_Pause(t *testing.T) {
+	until := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description    string
+		logger         log.Logger
+		filename       string
+		pause          bool
+		until          time.Time
+		resume         bool
+		expectedPaused bool
+		expectedUntil  time.Time
+		expectedError  error
+	}{
+		{
+			description: "it should save and retrieve the pause correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			pause:          true,
+			until:          until,
+			expectedPaused: true,
+			expectedUntil:  until,
+		},
+		{
+			description: "it should pause until resumed",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			pause:          true,
+			expectedPaused: true,
+		},
+		{
+			description: "it should resume the scheduled actions",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			pause:  true,
+			until:  until,
+			resume: true,
+		},
+		{
+			description: "it should detect when it was never paused",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+		},
+		{
+			description: "it should detect an invalid pause date",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				f.Close()
+
+				db, err := bolt.Open(f.Name(), storage.BoltDBFileMode, nil)
+				if err != nil {
+					t.Fatalf("error opening database. details: %s", err)
+				}
+				defer db.Close()
+
+				err = db.Update(func(tx *bolt.Tx) error {
+					bucket, err := tx.CreateBucketIfNotExists(storage.BoltDBMetadataBucket)
+					if err != nil {
+						return err
+					}
+
+					return bucket.Put([]byte("pause-until"), []byte("tomorrow"))
+				})
+
+				if err != nil {
+					t.Fatalf("error updating database. details: %s", err)
+				}
+
+				return f.Name()
+			}(),
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeListingDatabase,
+				Err: &storage.Error{
+					Code: storage.ErrorCodeDateFormat,
+					Err:  &time.ParseError{Layout: time.RFC3339Nano, Value: "tomorrow", LayoutElem: "2006", ValueElem: "tomorrow", Message: ""},
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			boltDB := storage.NewBoltDB(scenario.logger, scenario.filename)
+
+			if scenario.pause {
+				if err := boltDB.SavePause(context.Background(), scenario.until); err != nil {
+					t.Fatalf("error saving pause. details: %s", err)
+				}
+			}
+
+			if scenario.resume {
+				if err := boltDB.RemovePause(context.Background()); err != nil {
+					t.Fatalf("error removing pause. details: %s", err)
+				}
+			}
+
+			paused, until, err := boltDB.Pause(context.Background())
+			if paused != scenario.expectedPaused {
+				t.Errorf("paused states don't match. expected “%t” and got “%t”", scenario.expectedPaused, paused)
+			}
+
+			if !until.Equal(scenario.expectedUntil) {
+				t.Errorf("dates don't match. expected “%s” and got “%s”", scenario.expectedUntil, until)
+			}
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestBoltDB_Pins(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	pin123 := storage.Pin{ID: "AWSID123", User: "john", Reason: "before migration", CreatedAt: now}
+	pin124 := storage.Pin{ID: "AWSID124", Until: now.Add(24 * time.Hour), User: "john", CreatedAt: now}
+	unpin123 := storage.Pin{ID: "AWSID123", User: "mary", Reason: "migration finished", CreatedAt: now.Add(time.Hour)}
+
+	scenarios := []struct {
+		description    string
+		pin            []storage.Pin
+		unpin          []storage.Pin
+		expected       []storage.Pin
+		expectedEvents []storage.PinEvent
+	}{
+		{
+			description: "it should save and list the pins sorted by id",
+			pin:         []storage.Pin{pin124, pin123, pin124},
+			expected:    []storage.Pin{pin123, pin124},
+			expectedEvents: []storage.PinEvent{
+				{Pin: pin124, Action: storage.PinActionPin},
+				{Pin: pin123, Action: storage.PinActionPin},
+				{Pin: pin124, Action: storage.PinActionPin},
+			},
+		},
+		{
+			description: "it should remove a pin",
+			pin:         []storage.Pin{pin123, pin124},
+			unpin:       []storage.Pin{unpin123, {ID: "AWSID999"}},
+			expected:    []storage.Pin{pin124},
+			expectedEvents: []storage.PinEvent{
+				{Pin: pin123, Action: storage.PinActionPin},
+				{Pin: pin124, Action: storage.PinActionPin},
+				{Pin: unpin123, Action: storage.PinActionUnpin},
+			},
+		},
+		{
+			description: "it should remove all pins",
+			pin:         []storage.Pin{pin123},
+			unpin:       []storage.Pin{unpin123},
+			expectedEvents: []storage.PinEvent{
+				{Pin: pin123, Action: storage.PinActionPin},
+				{Pin: unpin123, Action: storage.PinActionUnpin},
+			},
+		},
+		{
+			description: "it should detect when no backup was pinned",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			boltDB := storage.NewBoltDB(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}, path.Join(dir, "toglacier.db"))
+
+			for _, pin := range scenario.pin {
+				if err := boltDB.SavePin(context.Background(), pin); err != nil {
+					t.Fatalf("error saving pin. details: %s", err)
+				}
+			}
+
+			for _, unpin := range scenario.unpin {
+				if err := boltDB.RemovePin(context.Background(), unpin); err != nil {
+					t.Fatalf("error removing pin. details: %s", err)
+				}
+			}
+
+			pins, err := boltDB.Pins(context.Background())
+			if err != nil {
+				t.Fatalf("error listing pins. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, pins) {
+				t.Errorf("pins don't match.\n%s", Diff(scenario.expected, pins))
+			}
+
+			events, err := boltDB.PinEvents(context.Background())
+			if err != nil {
+				t.Fatalf("error listing pin audit trail. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedEvents, events) {
+				t.Errorf("pin audit trails don't match.\n%s", Diff(scenario.expectedEvents, events))
+			}
+		})
+	}
+}
+
+func TestBoltDB_Tags(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	preUpgrade := storage.Tag{Name: "pre-upgrade", ID: "AWSID123", CreatedAt: now}
+	monthly := storage.Tag{Name: "monthly", ID: "AWSID124", CreatedAt: now}
+	preUpgradeMoved := storage.Tag{Name: "pre-upgrade", ID: "AWSID125", CreatedAt: now.Add(time.Hour)}
+
+	scenarios := []struct {
+		description string
+		tags        []storage.Tag
+		remove      []string
+		expected    []storage.Tag
+	}{
+		{
+			description: "it should save and list the tags sorted by name",
+			tags:        []storage.Tag{preUpgrade, monthly},
+			expected:    []storage.Tag{monthly, preUpgrade},
+		},
+		{
+			description: "it should replace a tag with the same name",
+			tags:        []storage.Tag{preUpgrade, monthly, preUpgradeMoved},
+			expected:    []storage.Tag{monthly, preUpgradeMoved},
+		},
+		{
+			description: "it should remove a tag",
+			tags:        []storage.Tag{preUpgrade, monthly},
+			remove:      []string{"pre-upgrade", "unknown"},
+			expected:    []storage.Tag{monthly},
+		},
+		{
+			description: "it should remove all tags",
+			tags:        []storage.Tag{preUpgrade},
+			remove:      []string{"pre-upgrade"},
+		},
+		{
+			description: "it should detect when no backup was tagged",
+			remove:      []string{"pre-upgrade"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			boltDB := storage.NewBoltDB(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}, path.Join(dir, "toglacier.db"))
+
+			for _, tag := range scenario.tags {
+				if err := boltDB.SaveTag(context.Background(), tag); err != nil {
+					t.Fatalf("error saving tag. details: %s", err)
+				}
+			}
+
+			for _, name := range scenario.remove {
+				if err := boltDB.RemoveTag(context.Background(), name); err != nil {
+					t.Fatalf("error removing tag. details: %s", err)
+				}
+			}
+
+			tags, err := boltDB.Tags(context.Background())
+			if err != nil {
+				t.Fatalf("error listing tags. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, tags) {
+				t.Errorf("tags don't match.\n%s", Diff(scenario.expected, tags))
+			}
+		})
+	}
+}
+
+func TestBoltDB_Operations(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	removeOperation := storage.Operation{
+		CreatedAt: now,
+		Command:   "remove",
+		Action:    "remove backups",
+		Backups:   []string{"AWSID123", "AWSID124"},
+	}
+
+	compactOperation := storage.Operation{
+		CreatedAt: now.Add(time.Hour),
+		Command:   "db",
+		Action:    "compact storage",
+		Error:     "error writing the storage file",
+	}
+
+	scenarios := []struct {
+		description string
+		operations  []storage.Operation
+		expected    []storage.Operation
+	}{
+		{
+			description: "it should list the operations in the order that they were recorded",
+			operations:  []storage.Operation{removeOperation, compactOperation, removeOperation},
+			expected:    []storage.Operation{removeOperation, compactOperation, removeOperation},
+		},
+		{
+			description: "it should detect when no operation was recorded",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			boltDB := storage.NewBoltDB(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}, path.Join(dir, "toglacier.db"))
+
+			for _, operation := range scenario.operations {
+				if err := boltDB.SaveOperation(context.Background(), operation); err != nil {
+					t.Fatalf("error saving operation. details: %s", err)
+				}
+			}
+
+			operations, err := boltDB.Operations(context.Background())
+			if err != nil {
+				t.Fatalf("error listing operations. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, operations) {
+				t.Errorf("operations don't match.\n%s", Diff(scenario.expected, operations))
+			}
+		})
+	}
+}
+
+func TestBoltDB_RestoreProgress(t *testing.T) {
+	progress := storage.RestoreProgress{
+		Downloaded: map[string]string{
+			"AWSID124": "/tmp/backup-AWSID124.tar",
+		},
+		Extracted: map[string]bool{
+			"AWSID123": true,
+		},
+	}
+
+	scenarios := []struct {
+		description   string
+		logger        log.Logger
+		filename      string
+		progress      *storage.RestoreProgress
+		remove        bool
+		expected      storage.RestoreProgress
+		expectedError error
+	}{
+		{
+			description: "it should save and retrieve the restore progress correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			progress: &progress,
+			expected: progress,
+		},
+		{
+			description: "it should return an empty progress when there's no retrieval in progress",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+		},
+		{
+			description: "it should remove the restore progress of a finished retrieval",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				defer f.Close()
+
+				return f.Name()
+			}(),
+			progress: &progress,
+			remove:   true,
+		},
+		{
+			description: "it should detect an invalid restore progress in the database",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				f, err := ioutil.TempFile("", "toglacier-")
+				if err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+				f.Close()
+
+				db, err := bolt.Open(f.Name(), storage.BoltDBFileMode, nil)
+				if err != nil {
+					t.Fatalf("error opening database. details: %s", err)
+				}
+				defer db.Close()
+
+				err = db.Update(func(tx *bolt.Tx) error {
+					bucket, err := tx.CreateBucketIfNotExists(storage.BoltDBRestoreBucket)
+					if err != nil {
+						return err
+					}
+
+					return bucket.Put([]byte("AWSID123"), []byte("{"))
+				})
+
+				if err != nil {
+					t.Fatalf("error updating database. details: %s", err)
+				}
+
+				return f.Name()
+			}(),
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeListingDatabase,
+				Err: &storage.Error{
+					Code: storage.ErrorCodeDecodingRestoreProgress,
+					Err:  errors.New("unexpected end of JSON input"),
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			boltDB := storage.NewBoltDB(scenario.logger, scenario.filename)
+
+			if scenario.progress != nil {
+				if err := boltDB.SaveRestoreProgress(context.Background(), "AWSID123", *scenario.progress); err != nil {
+					t.Fatalf("error saving restore progress. details: %s", err)
+				}
+			}
+
+			if scenario.remove {
+				if err := boltDB.RemoveRestoreProgress(context.Background(), "AWSID123"); err != nil {
+					t.Fatalf("error removing restore progress. details: %s", err)
+				}
+			}
+
+			progress, err := boltDB.RestoreProgress(context.Background(), "AWSID123")
+			if !reflect.DeepEqual(scenario.expected, progress) {
+				t.Errorf("restore progress doesn't match. expected “%v” and got “%v”", scenario.expected, progress)
+			}
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			restores, err := boltDB.RestoreProgresses(context.Background())
+			if err != nil {
+				t.Fatalf("error listing restore progresses. details: %s", err)
+			}
+
+			expectedRestores := make(map[string]storage.RestoreProgress)
+			if scenario.progress != nil && !scenario.remove {
+				expectedRestores["AWSID123"] = scenario.expected
+			}
+
+			if !reflect.DeepEqual(expectedRestores, restores) {
+				t.Errorf("restore progresses don't match. expected “%v” and got “%v”", expectedRestores, restores)
+			}
 		})
 	}
 }
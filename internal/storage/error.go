@@ -64,6 +64,52 @@ const (
 	// ErrorCodeLocation invalid location in backup file. If informed, the valid
 	// values are "aws" or "gcs".
 	ErrorCodeLocation ErrorCode = "location"
+
+	// ErrorCodeEncodingRestoreProgress failed to encode the restore progress to
+	// a storage representation.
+	ErrorCodeEncodingRestoreProgress ErrorCode = "encoding-restore-progress"
+
+	// ErrorCodeDecodingRestoreProgress failed to decode the restore progress to
+	// the original format.
+	ErrorCodeDecodingRestoreProgress ErrorCode = "decoding-restore-progress"
+
+	// ErrorCodeCancelled action cancelled by the user.
+	ErrorCodeCancelled ErrorCode = "cancelled"
+
+	// ErrorCodeSpoolFull the archive doesn't fit in the spool directory limit.
+	ErrorCodeSpoolFull ErrorCode = "spool-full"
+
+	// ErrorCodeEncodingPin failed to encode the pin or its audit trail to the
+	// storage representation.
+	ErrorCodeEncodingPin ErrorCode = "encoding-pin"
+
+	// ErrorCodeDecodingPin failed to decode the pin or its audit trail to the
+	// original representation.
+	ErrorCodeDecodingPin ErrorCode = "decoding-pin"
+
+	// ErrorCodeEncodingOperation failed to encode the operation to the audit
+	// log representation.
+	ErrorCodeEncodingOperation ErrorCode = "encoding-operation"
+
+	// ErrorCodeDecodingOperation failed to decode the operation from the audit
+	// log representation.
+	ErrorCodeDecodingOperation ErrorCode = "decoding-operation"
+
+	// ErrorCodeEncodingTag failed to encode the tags to the storage
+	// representation.
+	ErrorCodeEncodingTag ErrorCode = "encoding-tag"
+
+	// ErrorCodeDecodingTag failed to decode the tags to the original
+	// representation.
+	ErrorCodeDecodingTag ErrorCode = "decoding-tag"
+
+	// ErrorCodeSchemaVersion the database structure version is invalid or newer
+	// than the supported by this version of the tool.
+	ErrorCodeSchemaVersion ErrorCode = "schema-version"
+
+	// ErrorCodeMigration failed to upgrade the database structure to the current
+	// version.
+	ErrorCodeMigration ErrorCode = "migration"
 )
 
 // ErrorCode stores the error type that occurred while managing the local
@@ -71,23 +117,35 @@ const (
 type ErrorCode string
 
 var errorCodeString = map[ErrorCode]string{
-	ErrorCodeOpeningFile:      "error opening the storage file",
-	ErrorCodeWritingFile:      "error writing the storage file",
-	ErrorCodeReadingFile:      "error reading the storage file",
-	ErrorCodeMovingFile:       "error moving the storage file",
-	ErrorCodeFormat:           "unexpected storage file format",
-	ErrorCodeSizeFormat:       "invalid size format",
-	ErrorCodeDateFormat:       "invalid date format",
-	ErrorCodeEncodingBackup:   "failed to encode backup to a storage representation",
-	ErrorCodeDecodingBackup:   "failed to decode backup to the original representation",
-	ErrorCodeDatabaseNotFound: "database not found",
-	ErrorCodeUpdatingDatabase: "failed to update database",
-	ErrorCodeListingDatabase:  "failed to list backups in the database",
-	ErrorCodeSave:             "failed to save the item in the database",
-	ErrorCodeDelete:           "failed to remove the item from the database",
-	ErrorCodeIterating:        "error while iterating over the database results",
-	ErrorAccessingBucket:      "failed to open or create a database bucket",
-	ErrorCodeLocation:         "invalid cloud location",
+	ErrorCodeOpeningFile:             "error opening the storage file",
+	ErrorCodeWritingFile:             "error writing the storage file",
+	ErrorCodeReadingFile:             "error reading the storage file",
+	ErrorCodeMovingFile:              "error moving the storage file",
+	ErrorCodeFormat:                  "unexpected storage file format",
+	ErrorCodeSizeFormat:              "invalid size format",
+	ErrorCodeDateFormat:              "invalid date format",
+	ErrorCodeEncodingBackup:          "failed to encode backup to a storage representation",
+	ErrorCodeDecodingBackup:          "failed to decode backup to the original representation",
+	ErrorCodeDatabaseNotFound:        "database not found",
+	ErrorCodeUpdatingDatabase:        "failed to update database",
+	ErrorCodeListingDatabase:         "failed to list backups in the database",
+	ErrorCodeSave:                    "failed to save the item in the database",
+	ErrorCodeDelete:                  "failed to remove the item from the database",
+	ErrorCodeIterating:               "error while iterating over the database results",
+	ErrorAccessingBucket:             "failed to open or create a database bucket",
+	ErrorCodeLocation:                "invalid cloud location",
+	ErrorCodeEncodingRestoreProgress: "failed to encode restore progress to a storage representation",
+	ErrorCodeDecodingRestoreProgress: "failed to decode restore progress to the original representation",
+	ErrorCodeCancelled:               "action cancelled by the user",
+	ErrorCodeSpoolFull:               "spool directory is full",
+	ErrorCodeEncodingPin:             "failed to encode pin to a storage representation",
+	ErrorCodeDecodingPin:             "failed to decode pin to the original representation",
+	ErrorCodeEncodingOperation:       "failed to encode operation to the audit log representation",
+	ErrorCodeDecodingOperation:       "failed to decode operation from the audit log representation",
+	ErrorCodeEncodingTag:             "failed to encode tag to a storage representation",
+	ErrorCodeDecodingTag:             "failed to decode tag to the original representation",
+	ErrorCodeSchemaVersion:           "unsupported database schema version",
+	ErrorCodeMigration:               "failed to migrate the database schema",
 }
 
 // String translate the error code to a human readable text.
@@ -99,6 +157,30 @@ func (e ErrorCode) String() string {
 	return "unknown error code"
 }
 
+// temporaryErrorCodes are the problems that could be solved by trying again,
+// like a database file locked by another process or a full disk. The format
+// and encoding problems will always fail the same way.
+var temporaryErrorCodes = map[ErrorCode]bool{
+	ErrorCodeOpeningFile:      true,
+	ErrorCodeWritingFile:      true,
+	ErrorCodeReadingFile:      true,
+	ErrorCodeMovingFile:       true,
+	ErrorCodeUpdatingDatabase: true,
+	ErrorCodeListingDatabase:  true,
+	ErrorCodeSave:             true,
+	ErrorCodeDelete:           true,
+}
+
+var (
+	// ErrCancelled is matched with errors.Is when the local storage action was
+	// cancelled by the user.
+	ErrCancelled = errors.New("storage: action cancelled by the user")
+
+	// ErrDatabaseNotFound is matched with errors.Is when the database structure
+	// is missing from the local storage.
+	ErrDatabaseNotFound = errors.New("storage: database not found")
+)
+
 // Error stores error details from a problem occurred while managing the local
 // storage.
 type Error struct {
@@ -118,6 +200,25 @@ func (e Error) Error() string {
 	return e.String()
 }
 
+// Unwrap returns the low level error, so it can be inspected with errors.Is and
+// errors.As.
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// Is allows comparing the error with the sentinel errors of the package (e.g.
+// errors.Is(err, storage.ErrCancelled)).
+func (e Error) Is(target error) bool {
+	switch target {
+	case ErrCancelled:
+		return e.Code == ErrorCodeCancelled
+	case ErrDatabaseNotFound:
+		return e.Code == ErrorCodeDatabaseNotFound
+	}
+
+	return false
+}
+
 // String translate the error to a human readable text.
 func (e Error) String() string {
 	var err string
@@ -128,6 +229,18 @@ func (e Error) String() string {
 	return fmt.Sprintf("storage: %s%s", e.Code, err)
 }
 
+// Temporary returns true when the operation could succeed if executed again.
+// A temporary problem caused by a permanent one (e.g. opening a database with
+// an unsupported schema version) isn't temporary.
+func (e Error) Temporary() bool {
+	var cause *Error
+	if errors.As(e.Err, &cause) && !cause.Temporary() {
+		return false
+	}
+
+	return temporaryErrorCodes[e.Code]
+}
+
 // ErrorEqual compares two Error objects. This is useful to compare down to the
 // low level errors.
 func ErrorEqual(first, second error) bool {
@@ -135,10 +248,8 @@ func ErrorEqual(first, second error) bool {
 		return first == second
 	}
 
-	err1, ok1 := errors.Cause(first).(*Error)
-	err2, ok2 := errors.Cause(second).(*Error)
-
-	if !ok1 || !ok2 {
+	var err1, err2 *Error
+	if !errors.As(first, &err1) || !errors.As(second, &err2) {
 		return false
 	}
 
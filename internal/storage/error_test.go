@@ -1,9 +1,10 @@
 package storage_test
 
 import (
-	"errors"
+	"os"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/rafaeljusto/toglacier/internal/storage"
 )
 
@@ -91,6 +92,66 @@ func TestError_Error(t *testing.T) {
 			err:         &storage.Error{Code: storage.ErrorCodeLocation},
 			expected:    "storage: invalid cloud location",
 		},
+		{
+			description: "it should show the correct error message for encoding restore progress problem",
+			err:         &storage.Error{Code: storage.ErrorCodeEncodingRestoreProgress},
+			expected:    "storage: failed to encode restore progress to a storage representation",
+		},
+		{
+			description: "it should show the correct error message for decoding restore progress problem",
+			err:         &storage.Error{Code: storage.ErrorCodeDecodingRestoreProgress},
+			expected:    "storage: failed to decode restore progress to the original representation",
+		},
+		{
+			description: "it should show the correct error message for cancelled action",
+			err:         &storage.Error{Code: storage.ErrorCodeCancelled},
+			expected:    "storage: action cancelled by the user",
+		},
+		{
+			description: "it should show the correct error message for full spool directory",
+			err:         &storage.Error{Code: storage.ErrorCodeSpoolFull},
+			expected:    "storage: spool directory is full",
+		},
+		{
+			description: "it should show the correct error message for encoding pin problem",
+			err:         &storage.Error{Code: storage.ErrorCodeEncodingPin},
+			expected:    "storage: failed to encode pin to a storage representation",
+		},
+		{
+			description: "it should show the correct error message for decoding pin problem",
+			err:         &storage.Error{Code: storage.ErrorCodeDecodingPin},
+			expected:    "storage: failed to decode pin to the original representation",
+		},
+		{
+			description: "it should show the correct error message for encoding operation problem",
+			err:         &storage.Error{Code: storage.ErrorCodeEncodingOperation},
+			expected:    "storage: failed to encode operation to the audit log representation",
+		},
+		{
+			description: "it should show the correct error message for decoding operation problem",
+			err:         &storage.Error{Code: storage.ErrorCodeDecodingOperation},
+			expected:    "storage: failed to decode operation from the audit log representation",
+		},
+		{
+			description: "it should show the correct error message for encoding tag problem",
+			err:         &storage.Error{Code: storage.ErrorCodeEncodingTag},
+			expected:    "storage: failed to encode tag to a storage representation",
+		},
+		{
+			description: "it should show the correct error message for decoding tag problem",
+			err:         &storage.Error{Code: storage.ErrorCodeDecodingTag},
+			expected:    "storage: failed to decode tag to the original representation",
+		},
+		{
+			description: "it should show the correct error message for schema version problem",
+			err:         &storage.Error{Code: storage.ErrorCodeSchemaVersion},
+			expected:    "storage: unsupported database schema version",
+		},
+		{
+			description: "it should show the correct error message for migration problem",
+			err:         &storage.Error{Code: storage.ErrorCodeMigration},
+			expected:    "storage: failed to migrate the database schema",
+		},
 		{
 			description: "it should detect when the code doesn't exist",
 			err:         &storage.Error{Code: storage.ErrorCode("i-dont-exist")},
@@ -107,6 +168,89 @@ func TestError_Error(t *testing.T) {
 	}
 }
 
+func TestError_Temporary(t *testing.T) {
+	scenarios := []struct {
+		description string
+		err         *storage.Error
+		expected    bool
+	}{
+		{
+			description: "it should detect a temporary error while opening the file",
+			err:         &storage.Error{Code: storage.ErrorCodeOpeningFile},
+			expected:    true,
+		},
+		{
+			description: "it should detect a temporary error while saving the item",
+			err:         &storage.Error{Code: storage.ErrorCodeSave},
+			expected:    true,
+		},
+		{
+			description: "it should detect a permanent error in the file format",
+			err:         &storage.Error{Code: storage.ErrorCodeFormat},
+		},
+		{
+			description: "it should detect a permanent error while decoding a backup",
+			err:         &storage.Error{Code: storage.ErrorCodeDecodingBackup},
+		},
+		{
+			description: "it should detect a permanent error while opening the file",
+			err: &storage.Error{
+				Code: storage.ErrorCodeOpeningFile,
+				Err:  &storage.Error{Code: storage.ErrorCodeSchemaVersion},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if temporary := scenario.err.Temporary(); temporary != scenario.expected {
+				t.Errorf("temporary flag doesn't match. expected “%t” and got “%t”", scenario.expected, temporary)
+			}
+		})
+	}
+}
+
+func TestError_Is(t *testing.T) {
+	scenarios := []struct {
+		description string
+		err         error
+		target      error
+		expected    bool
+	}{
+		{
+			description: "it should detect a cancellation",
+			err:         errors.Wrap(&storage.Error{Code: storage.ErrorCodeCancelled}, "listing backups"),
+			target:      storage.ErrCancelled,
+			expected:    true,
+		},
+		{
+			description: "it should detect a missing database",
+			err:         &storage.Error{Code: storage.ErrorCodeDatabaseNotFound},
+			target:      storage.ErrDatabaseNotFound,
+			expected:    true,
+		},
+		{
+			description: "it should detect the low level error",
+			err:         &storage.Error{Code: storage.ErrorCodeOpeningFile, Err: os.ErrNotExist},
+			target:      os.ErrNotExist,
+			expected:    true,
+		},
+		{
+			description: "it should not match a different sentinel error",
+			err:         &storage.Error{Code: storage.ErrorCodeOpeningFile},
+			target:      storage.ErrCancelled,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			if is := errors.Is(scenario.err, scenario.target); is != scenario.expected {
+				t.Errorf("results don't match. expected “%t” and got “%t”", scenario.expected, is)
+			}
+		})
+	}
+}
+
 func TestErrorEqual(t *testing.T) {
 	scenarios := []struct {
 		description string
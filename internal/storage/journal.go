@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// JournalFile stores the pending backups in a JSON file. The file is replaced
+// atomically on each change and removed when there's nothing pending.
+type JournalFile struct {
+	logger   log.Logger
+	Filename string
+}
+
+// NewJournalFile initializes a new JournalFile object.
+func NewJournalFile(logger log.Logger, filename string) *JournalFile {
+	return &JournalFile{
+		logger:   logger,
+		Filename: filename,
+	}
+}
+
+// Save a pending backup information, including the extra information of the
+// backup. If the backup id already exists it will be replaced. On error it will
+// return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (j *JournalFile) Save(ctx context.Context, backup Backup) error {
+	j.logger.Debugf("storage: saving backup “%s” in journal file", backup.Backup.ID)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	backups, err := j.read()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	backups.Add(backup)
+	if err = j.write(backups); err != nil {
+		return errors.WithStack(err)
+	}
+
+	j.logger.Infof("storage: backup “%s” saved successfully in journal file", backup.Backup.ID)
+	return nil
+}
+
+// List all pending backups. On error it will return an Error type encapsulated
+// in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (j *JournalFile) List(ctx context.Context) (Backups, error) {
+	j.logger.Debug("storage: listing backups from journal file")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	backups, err := j.read()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	j.logger.Info("storage: backups listed successfully from journal file")
+	return backups, nil
+}
+
+// Remove a pending backup. On error it will return an Error type encapsulated
+// in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (j *JournalFile) Remove(ctx context.Context, id string) error {
+	j.logger.Debugf("storage: removing backup “%s” from journal file", id)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	backups, err := j.read()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var remaining Backups
+	for _, backup := range backups {
+		if backup.Backup.ID != id {
+			remaining = append(remaining, backup)
+		}
+	}
+
+	if len(remaining) == len(backups) {
+		return nil
+	}
+
+	if len(remaining) == 0 {
+		// don't leave an empty file behind when there's nothing pending
+		if err = os.Remove(j.Filename); err != nil {
+			return errors.WithStack(newError(ErrorCodeWritingFile, err))
+		}
+
+	} else if err = j.write(remaining); err != nil {
+		return errors.WithStack(err)
+	}
+
+	j.logger.Infof("storage: backup “%s” removed successfully from journal file", id)
+	return nil
+}
+
+func (j *JournalFile) read() (Backups, error) {
+	content, err := ioutil.ReadFile(j.Filename)
+	if err != nil {
+		// if the file doesn't exist there's no pending backup
+		if pathErr, ok := err.(*os.PathError); ok && os.IsNotExist(pathErr.Err) {
+			return nil, nil
+		}
+
+		return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+
+	var backups Backups
+	if err = json.Unmarshal(content, &backups); err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeDecodingBackup, err))
+	}
+
+	return backups, nil
+}
+
+func (j *JournalFile) write(backups Backups) error {
+	encoded, err := json.MarshalIndent(backups, "", "  ")
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingBackup, err))
+	}
+
+	return errors.WithStack(writeFileAtomically(j.Filename, encoded))
+}
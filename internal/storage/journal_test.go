@@ -0,0 +1,151 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/log"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestJournalFile(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	backup1 := storage.Backup{
+		Backup: cloud.Backup{
+			ID:        "AWSID123",
+			CreatedAt: now,
+			Checksum:  "a11ff7a2cbcb2cc6d9d8e0b1a5e6e0c3ecc4a22e0d2a5e1a37a2c8c0b47e0d27",
+			VaultName: "test",
+			Size:      120,
+		},
+		Info: archive.Info{
+			"/data/important/file1.txt": archive.ItemInfo{
+				ID:       "AWSID123",
+				Status:   archive.ItemInfoStatusNew,
+				Checksum: "4e5fa22b8a8e1a4e7ba1ea1cd5f5bc3cfa22e3e1f8d8e2c6e1c2e0a3b6e0d1c4",
+			},
+		},
+	}
+
+	backup2 := storage.Backup{
+		Backup: cloud.Backup{
+			ID:        "AWSID124",
+			CreatedAt: now.Add(time.Hour),
+			Checksum:  "c6d9d8e0b1a5e6e0c3ecc4a22e0d2a5e1a37a2c8c0b47e0d27a11ff7a2cbcb2c",
+			VaultName: "test",
+			Size:      240,
+		},
+	}
+
+	scenarios := []struct {
+		description   string
+		logger        log.Logger
+		filename      string
+		save          storage.Backups
+		remove        []string
+		expected      storage.Backups
+		expectedError error
+	}{
+		{
+			description: "it should save and list the pending backups correctly",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-journal"),
+			save:     storage.Backups{backup2, backup1},
+			expected: storage.Backups{backup1, backup2},
+		},
+		{
+			description: "it should remove a pending backup",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-journal-remove"),
+			save:     storage.Backups{backup1, backup2},
+			remove:   []string{"AWSID123", "AWSID999"},
+			expected: storage.Backups{backup2},
+		},
+		{
+			description: "it should remove the file when there's nothing pending",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-journal-empty"),
+			save:     storage.Backups{backup1},
+			remove:   []string{"AWSID123"},
+		},
+		{
+			description: "it should detect an invalid journal file",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				n := path.Join(os.TempDir(), "toglacier-test-journal-invalid")
+				if err := ioutil.WriteFile(n, []byte("["), 0600); err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+
+				return n
+			}(),
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeDecodingBackup,
+				Err:  errors.New("unexpected end of JSON input"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			defer os.Remove(scenario.filename)
+			journal := storage.NewJournalFile(scenario.logger, scenario.filename)
+
+			for _, backup := range scenario.save {
+				if err := journal.Save(context.Background(), backup); err != nil {
+					t.Fatalf("error saving pending backup. details: %s", err)
+				}
+			}
+
+			for _, id := range scenario.remove {
+				if err := journal.Remove(context.Background(), id); err != nil {
+					t.Fatalf("error removing pending backup. details: %s", err)
+				}
+			}
+
+			backups, err := journal.List(context.Background())
+			if !reflect.DeepEqual(scenario.expected, backups) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backups))
+			}
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if scenario.remove != nil && scenario.expected == nil {
+				if _, err := os.Stat(scenario.filename); !os.IsNotExist(err) {
+					t.Errorf("journal file wasn't removed")
+				}
+			}
+		})
+	}
+}
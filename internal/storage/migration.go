@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+// boltDBSchemaVersionKey is the key in the metadata bucket that stores the
+// version of the database structure. Databases without it are from before the
+// versioning (version 0).
+var boltDBSchemaVersionKey = []byte("schema-version")
+
+// boltDBMigration upgrades the database structure to its version. The
+// migrations run in order inside a single transaction, so a failure keeps the
+// database in the previous version.
+type boltDBMigration struct {
+	version     int
+	description string
+	migrate     func(tx *bolt.Tx) error
+}
+
+// boltDBMigrations are all the changes of the database structure. New fields
+// that can't be decoded from the older databases must add a migration here,
+// with the next version. The first version only stamps the existing databases,
+// as their structure didn't change.
+var boltDBMigrations = []boltDBMigration{
+	{
+		version:     1,
+		description: "schema version stamp",
+	},
+}
+
+// BoltDBSchemaVersion is the version of the database structure written by this
+// version of the tool. Older databases are upgraded automatically, and newer
+// ones are refused, as they could be damaged.
+var BoltDBSchemaVersion = boltDBMigrations[len(boltDBMigrations)-1].version
+
+// errBoltDBExisting stops the walk through the buckets of the database at the
+// first one, as it is enough to know that the database isn't new.
+var errBoltDBExisting = errors.New("existing database")
+
+// migrate upgrades the database to the BoltDBSchemaVersion. The upgrade of an
+// existing database is recorded in the audit log of the destructive
+// operations, in the same transaction.
+func (b *BoltDB) migrate(db *bolt.DB) error {
+	var version int
+	var existing bool
+
+	err := db.View(func(tx *bolt.Tx) (err error) {
+		// a new database doesn't have buckets, so only its version is stamped
+		existing = tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			return errBoltDBExisting
+		}) != nil
+
+		version, err = boltDBVersion(tx)
+		return err
+	})
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if version > BoltDBSchemaVersion {
+		return errors.WithStack(newError(ErrorCodeSchemaVersion,
+			fmt.Errorf("database schema version %d is newer than the supported version %d", version, BoltDBSchemaVersion)))
+	}
+
+	if version == BoltDBSchemaVersion {
+		return nil
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, migration := range boltDBMigrations {
+			if migration.version <= version || migration.migrate == nil {
+				continue
+			}
+
+			b.logger.Infof("storage: migrating boltdb storage to schema version %d (%s)", migration.version, migration.description)
+			if err := migration.migrate(tx); err != nil {
+				return errors.WithStack(newError(ErrorCodeMigration, err))
+			}
+		}
+
+		bucket, err := tx.CreateBucketIfNotExists(BoltDBMetadataBucket)
+		if err != nil {
+			return errors.WithStack(newError(ErrorAccessingBucket, err))
+		}
+
+		if err = bucket.Put(boltDBSchemaVersionKey, []byte(strconv.Itoa(BoltDBSchemaVersion))); err != nil {
+			return errors.WithStack(newError(ErrorCodeSave, err))
+		}
+
+		if !existing {
+			return nil
+		}
+
+		encoded, err := json.Marshal(Operation{
+			CreatedAt: b.now(),
+			Action:    fmt.Sprintf("migrate storage from schema version %d to %d", version, BoltDBSchemaVersion),
+		})
+
+		if err != nil {
+			return errors.WithStack(newError(ErrorCodeEncodingOperation, err))
+		}
+
+		return errors.WithStack(appendSequenced(tx, BoltDBOperationBucket, encoded))
+	})
+
+	return errors.WithStack(err)
+}
+
+// boltDBVersion retrieves the version of the database structure.
+func boltDBVersion(tx *bolt.Tx) (int, error) {
+	bucket := tx.Bucket(BoltDBMetadataBucket)
+	if bucket == nil {
+		return 0, nil
+	}
+
+	value := bucket.Get(boltDBSchemaVersionKey)
+	if value == nil {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(string(value))
+	if err != nil {
+		return 0, errors.WithStack(newError(ErrorCodeSchemaVersion, err))
+	}
+
+	return version, nil
+}
@@ -0,0 +1,153 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestBoltDB_SchemaVersion(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:  func(args ...interface{}) {},
+		mockDebugf: func(format string, args ...interface{}) {},
+		mockInfo:   func(args ...interface{}) {},
+		mockInfof:  func(format string, args ...interface{}) {},
+	}
+
+	now := time.Date(2026, 10, 16, 17, 0, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description        string
+		version            string
+		expectedVersion    string
+		expectedOperations []storage.Operation
+		expectedError      error
+	}{
+		{
+			description:     "it should stamp a new database",
+			expectedVersion: fmt.Sprintf("%d", storage.BoltDBSchemaVersion),
+		},
+		{
+			description:     "it should record the migration of an existing database",
+			version:         "0",
+			expectedVersion: fmt.Sprintf("%d", storage.BoltDBSchemaVersion),
+			expectedOperations: []storage.Operation{
+				{
+					CreatedAt: now,
+					Action:    fmt.Sprintf("migrate storage from schema version 0 to %d", storage.BoltDBSchemaVersion),
+				},
+			},
+		},
+		{
+			description:     "it should keep a database in the current version",
+			version:         fmt.Sprintf("%d", storage.BoltDBSchemaVersion),
+			expectedVersion: fmt.Sprintf("%d", storage.BoltDBSchemaVersion),
+		},
+		{
+			description:     "it should refuse a database from a newer version",
+			version:         fmt.Sprintf("%d", storage.BoltDBSchemaVersion+1),
+			expectedVersion: fmt.Sprintf("%d", storage.BoltDBSchemaVersion+1),
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeOpeningFile,
+				Err: &storage.Error{
+					Code: storage.ErrorCodeSchemaVersion,
+					Err:  fmt.Errorf("database schema version %d is newer than the supported version %d", storage.BoltDBSchemaVersion+1, storage.BoltDBSchemaVersion),
+				},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating a temporary file. details: %s", err)
+			}
+			f.Close()
+			defer os.Remove(f.Name())
+
+			if scenario.version != "" {
+				if err = setSchemaVersion(f.Name(), scenario.version); err != nil {
+					t.Fatalf("error setting the schema version. details: %s", err)
+				}
+			}
+
+			boltDB := storage.NewBoltDB(logger, f.Name())
+			boltDB.Now = func() time.Time { return now }
+			_, err = boltDB.List(context.Background())
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			var storageErr *storage.Error
+			if errors.As(err, &storageErr) && storageErr.Temporary() {
+				t.Errorf("unexpected temporary error")
+			}
+
+			version, err := schemaVersion(f.Name())
+			if err != nil {
+				t.Fatalf("error reading the schema version. details: %s", err)
+			}
+
+			if version != scenario.expectedVersion {
+				t.Errorf("schema versions don't match. expected “%s” and got “%s”", scenario.expectedVersion, version)
+			}
+
+			if scenario.expectedError != nil {
+				return
+			}
+
+			operations, err := boltDB.Operations(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error listing the operations. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedOperations, operations) {
+				t.Errorf("recorded operations don't match. expected “%v” and got “%v”", scenario.expectedOperations, operations)
+			}
+		})
+	}
+}
+
+func setSchemaVersion(filename, version string) error {
+	db, err := bolt.Open(filename, storage.BoltDBFileMode, nil)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(storage.BoltDBMetadataBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte("schema-version"), []byte(version))
+	})
+}
+
+func schemaVersion(filename string) (string, error) {
+	db, err := bolt.Open(filename, storage.BoltDBFileMode, nil)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	var version string
+	err = db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(storage.BoltDBMetadataBucket); bucket != nil {
+			version = string(bucket.Get([]byte("schema-version")))
+		}
+		return nil
+	})
+
+	return version, err
+}
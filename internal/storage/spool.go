@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// spoolArchiveExtension and spoolInfoExtension are the extensions of the files
+// stored in the spool directory for each archive.
+const (
+	spoolArchiveExtension = ".archive"
+	spoolInfoExtension    = ".json"
+)
+
+// SpooledArchive is a built (and encrypted) archive that couldn't be sent to
+// the cloud, kept in the spool until it is sent.
+type SpooledArchive struct {
+	// ID identifies the archive in the spool. The archives are sent in the
+	// order of their identifiers.
+	ID string
+
+	// Filename is the location of the archive in the spool.
+	Filename string
+
+	// VaultName is the route of the archive. Empty for the default cloud.
+	VaultName string
+
+	// Comment informed by the user when the backup was created.
+	Comment string
+
+	// ReplicaOf is the backup, already in the cloud, that couldn't be copied to
+	// the replica. When filled the archive is sent only to the replica.
+	ReplicaOf string `json:",omitempty"`
+
+	// CreatedAt is the moment that the archive was spooled.
+	CreatedAt time.Time
+
+	// Size of the archive in bytes.
+	Size int64
+
+	// Info is the archive information, saved in the local storage after the
+	// archive is sent.
+	Info archive.Info
+}
+
+// SpoolDir keeps the archives that couldn't be sent to the cloud in a local
+// directory, each one with a JSON file describing it. The directory size can be
+// bounded, so a long outage doesn't fill the disk.
+type SpoolDir struct {
+	logger log.Logger
+	Dir    string
+
+	// MaxSize is the maximum number of bytes of all archives in the spool. When
+	// zero the spool isn't bounded.
+	MaxSize int64
+}
+
+// NewSpoolDir initializes a new SpoolDir object.
+func NewSpoolDir(logger log.Logger, dir string, maxSize int64) *SpoolDir {
+	return &SpoolDir{
+		logger:  logger,
+		Dir:     dir,
+		MaxSize: maxSize,
+	}
+}
+
+// Add moves the archive file to the spool. The identifier, the location and
+// the size of the spooled archive are filled by the spool. On error it will
+// return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s *SpoolDir) Add(ctx context.Context, filename string, spooled SpooledArchive) (SpooledArchive, error) {
+	s.logger.Debugf("storage: adding archive “%s” to the spool directory", filename)
+
+	if err := checkCancellation(ctx); err != nil {
+		return SpooledArchive{}, err
+	}
+
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return SpooledArchive{}, errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+
+	spooledArchives, err := s.List(ctx)
+	if err != nil {
+		return SpooledArchive{}, errors.WithStack(err)
+	}
+
+	if s.MaxSize > 0 {
+		size := stat.Size()
+		for _, spooledArchive := range spooledArchives {
+			size += spooledArchive.Size
+		}
+
+		if size > s.MaxSize {
+			return SpooledArchive{}, errors.WithStack(newError(ErrorCodeSpoolFull,
+				fmt.Errorf("%d bytes needed and the limit is %d bytes", size, s.MaxSize)))
+		}
+	}
+
+	if err = os.MkdirAll(s.Dir, 0700); err != nil {
+		return SpooledArchive{}, errors.WithStack(newError(ErrorCodeWritingFile, err))
+	}
+
+	spooled.ID = strconv.FormatInt(time.Now().UnixNano(), 10)
+	spooled.Filename = filepath.Join(s.Dir, spooled.ID+spoolArchiveExtension)
+	spooled.Size = stat.Size()
+
+	if err = moveFile(filename, spooled.Filename); err != nil {
+		return SpooledArchive{}, errors.WithStack(err)
+	}
+
+	encoded, err := json.MarshalIndent(spooled, "", "  ")
+	if err != nil {
+		os.Remove(spooled.Filename)
+		return SpooledArchive{}, errors.WithStack(newError(ErrorCodeEncodingBackup, err))
+	}
+
+	if err = writeFileAtomically(s.infoFilename(spooled.ID), encoded); err != nil {
+		os.Remove(spooled.Filename)
+		return SpooledArchive{}, errors.WithStack(err)
+	}
+
+	s.logger.Infof("storage: archive “%s” added successfully to the spool directory", spooled.ID)
+	return spooled, nil
+}
+
+// List all spooled archives in the order that they must be sent. On error it
+// will return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s *SpoolDir) List(ctx context.Context) ([]SpooledArchive, error) {
+	s.logger.Debug("storage: listing archives from the spool directory")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	// the files are sorted by name, that is the order of the identifiers
+	files, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		// if the directory doesn't exist there's no spooled archive
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+
+	var spooledArchives []SpooledArchive
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), spoolInfoExtension) {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(s.Dir, file.Name()))
+		if err != nil {
+			return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+		}
+
+		var spooled SpooledArchive
+		if err = json.Unmarshal(content, &spooled); err != nil {
+			return nil, errors.WithStack(newError(ErrorCodeDecodingBackup, err))
+		}
+
+		// the spool directory could be moved, so the location is always rebuilt
+		spooled.Filename = filepath.Join(s.Dir, spooled.ID+spoolArchiveExtension)
+		spooledArchives = append(spooledArchives, spooled)
+	}
+
+	s.logger.Info("storage: archives listed successfully from the spool directory")
+	return spooledArchives, nil
+}
+
+// Remove a spooled archive that was already sent. On error it will return an
+// Error type encapsulated in a traceable error. To retrieve the desired error
+// you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (s *SpoolDir) Remove(ctx context.Context, id string) error {
+	s.logger.Debugf("storage: removing archive “%s” from the spool directory", id)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	// the description is removed first, so a partial removal isn't listed
+	for _, filename := range []string{s.infoFilename(id), filepath.Join(s.Dir, id+spoolArchiveExtension)} {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return errors.WithStack(newError(ErrorCodeWritingFile, err))
+		}
+	}
+
+	s.logger.Infof("storage: archive “%s” removed successfully from the spool directory", id)
+	return nil
+}
+
+func (s *SpoolDir) infoFilename(id string) string {
+	return filepath.Join(s.Dir, id+spoolInfoExtension)
+}
+
+// moveFile renames the file, copying it when the destination is in another
+// file system.
+func moveFile(from, to string) error {
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	source, err := os.Open(from)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeOpeningFile, err))
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeMovingFile, err))
+	}
+
+	if _, err = io.Copy(target, source); err != nil {
+		target.Close()
+		os.Remove(to)
+		return errors.WithStack(newError(ErrorCodeMovingFile, err))
+	}
+
+	if err = target.Close(); err != nil {
+		os.Remove(to)
+		return errors.WithStack(newError(ErrorCodeMovingFile, err))
+	}
+
+	os.Remove(from)
+	return nil
+}
@@ -0,0 +1,159 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestSpoolDir(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	archiveInfo := archive.Info{
+		"/data/important/file1.txt": archive.ItemInfo{
+			Status:   archive.ItemInfoStatusNew,
+			Checksum: "4e5fa22b8a8e1a4e7ba1ea1cd5f5bc3cfa22e3e1f8d8e2c6e1c2e0a3b6e0d1c4",
+		},
+	}
+
+	scenarios := []struct {
+		description   string
+		maxSize       int64
+		add           []string
+		remove        int
+		expected      []storage.SpooledArchive
+		expectedFiles int
+		expectedError error
+	}{
+		{
+			description: "it should add and list the spooled archives in order",
+			add:         []string{"archive 1", "archive 22"},
+			expected: []storage.SpooledArchive{
+				{VaultName: "test", Comment: "weekly", CreatedAt: now, Size: 9, Info: archiveInfo},
+				{VaultName: "test", Comment: "weekly", CreatedAt: now, Size: 10, Info: archiveInfo},
+			},
+			expectedFiles: 4,
+		},
+		{
+			description: "it should remove a spooled archive",
+			add:         []string{"archive 1", "archive 22"},
+			remove:      1,
+			expected: []storage.SpooledArchive{
+				{VaultName: "test", Comment: "weekly", CreatedAt: now, Size: 10, Info: archiveInfo},
+			},
+			expectedFiles: 2,
+		},
+		{
+			description: "it should detect when the spool directory is full",
+			maxSize:     15,
+			add:         []string{"archive 1", "archive 22"},
+			expected: []storage.SpooledArchive{
+				{VaultName: "test", Comment: "weekly", CreatedAt: now, Size: 9, Info: archiveInfo},
+			},
+			expectedFiles: 2,
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeSpoolFull,
+				Err:  errors.New("19 bytes needed and the limit is 15 bytes"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			spool := storage.NewSpoolDir(mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			}, path.Join(dir, "spool"), scenario.maxSize)
+
+			var addErr error
+			for _, content := range scenario.add {
+				filename := path.Join(dir, "archive")
+				if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+					t.Fatalf("error creating archive. details: %s", err)
+				}
+
+				spooled := storage.SpooledArchive{
+					VaultName: "test",
+					Comment:   "weekly",
+					CreatedAt: now,
+					Info:      archiveInfo,
+				}
+
+				if _, addErr = spool.Add(context.Background(), filename, spooled); addErr != nil {
+					break
+				}
+
+				if _, err := os.Stat(filename); !os.IsNotExist(err) {
+					t.Errorf("archive “%s” wasn't moved to the spool", content)
+				}
+			}
+
+			if !storage.ErrorEqual(scenario.expectedError, addErr) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, addErr)
+			}
+
+			spooledArchives, err := spool.List(context.Background())
+			if err != nil {
+				t.Fatalf("error listing the spooled archives. details: %s", err)
+			}
+
+			if scenario.remove > 0 {
+				for _, spooled := range spooledArchives[:scenario.remove] {
+					if err := spool.Remove(context.Background(), spooled.ID); err != nil {
+						t.Fatalf("error removing spooled archive. details: %s", err)
+					}
+				}
+
+				if spooledArchives, err = spool.List(context.Background()); err != nil {
+					t.Fatalf("error listing the spooled archives. details: %s", err)
+				}
+			}
+
+			for i, spooled := range spooledArchives {
+				content, err := ioutil.ReadFile(spooled.Filename)
+				if err != nil {
+					t.Errorf("error reading spooled archive “%s”. details: %s", spooled.ID, err)
+				} else if int64(len(content)) != spooled.Size {
+					t.Errorf("unexpected content “%s” in spooled archive “%s”", content, spooled.ID)
+				}
+
+				if i > 0 && spooled.ID <= spooledArchives[i-1].ID {
+					t.Errorf("spooled archives out of order: “%s” after “%s”", spooled.ID, spooledArchives[i-1].ID)
+				}
+
+				// the identifier and the location are defined by the spool
+				spooledArchives[i].ID = ""
+				spooledArchives[i].Filename = ""
+			}
+
+			if !reflect.DeepEqual(scenario.expected, spooledArchives) {
+				t.Errorf("spooled archives don't match.\n%s", Diff(scenario.expected, spooledArchives))
+			}
+
+			files, err := ioutil.ReadDir(path.Join(dir, "spool"))
+			if err != nil {
+				t.Fatalf("error reading the spool directory. details: %s", err)
+			}
+
+			if len(files) != scenario.expectedFiles {
+				t.Errorf("unexpected number of files in the spool directory: %d", len(files))
+			}
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package storage
 
 import (
+	"context"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rafaeljusto/toglacier/internal/archive"
 	"github.com/rafaeljusto/toglacier/internal/cloud"
 )
@@ -15,6 +18,116 @@ type Backup struct {
 	Info   archive.Info
 }
 
+// RestoreProgress keeps track of an ongoing backup retrieval, so a restore
+// interrupted by a crash or a restart can continue from the archives that were
+// already downloaded instead of retrieving everything again from the cloud.
+type RestoreProgress struct {
+	// Info is the archive information of the main backup when it was
+	// retrieved from the cloud, as it isn't available in the local storage.
+	Info archive.Info `json:"info,omitempty"`
+
+	// Downloaded maps the archive ID to the local file where it was stored.
+	Downloaded map[string]string `json:"downloaded,omitempty"`
+
+	// Extracted contains the archive IDs that were already extracted.
+	Extracted map[string]bool `json:"extracted,omitempty"`
+
+	// Jobs maps the archive ID to the cloud job initiated to retrieve it, so
+	// the retrieval doesn't wait for a new job.
+	Jobs map[string]string `json:"jobs,omitempty"`
+
+	// Parts maps the archive ID to the part of the archive downloaded so far.
+	Parts map[string]PartialDownload `json:"parts,omitempty"`
+}
+
+// PartialDownload is the beginning of an archive already stored locally while
+// it is downloaded from the cloud.
+type PartialDownload struct {
+	// Filename is the local file where the archive is stored.
+	Filename string `json:"filename"`
+
+	// Size is the number of bytes already stored.
+	Size int64 `json:"size"`
+}
+
+// Pin protects a backup from the old backups removal (legal hold), keeping who
+// placed it, when and why for compliance purposes.
+type Pin struct {
+	// ID identifies the pinned backup.
+	ID string `json:"id"`
+
+	// Until is when the pin expires. When zero the backup is pinned until it
+	// is unpinned.
+	Until time.Time `json:"until"`
+
+	// User that placed or removed the pin.
+	User string `json:"user,omitempty"`
+
+	// Reason is a note explaining why the pin was placed or removed.
+	Reason string `json:"reason,omitempty"`
+
+	// CreatedAt is when the pin was placed or removed.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Active informs if the pin still protects the backup at the given moment.
+func (p Pin) Active(now time.Time) bool {
+	return p.Until.IsZero() || now.Before(p.Until)
+}
+
+// PinAction is the change recorded in the pin audit trail.
+type PinAction string
+
+const (
+	// PinActionPin the backup was pinned.
+	PinActionPin PinAction = "pin"
+
+	// PinActionUnpin the pin of the backup was removed.
+	PinActionUnpin PinAction = "unpin"
+)
+
+// PinEvent is a record of the append-only audit trail of the pins.
+type PinEvent struct {
+	Pin
+
+	Action PinAction `json:"action"`
+}
+
+// Tag is a human-friendly name of a backup (e.g. pre-upgrade), that can be
+// used instead of the backup ID.
+type Tag struct {
+	// Name of the backup. Each name refers to only one backup.
+	Name string `json:"name"`
+
+	// ID identifies the tagged backup.
+	ID string `json:"id"`
+
+	// CreatedAt is when the tag was assigned.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Operation is a record of the audit log of the destructive operations, like
+// the removal of backups or the rewrite of the local storage. It is kept apart
+// from the log file, so it isn't lost when the logs are rotated.
+type Operation struct {
+	// CreatedAt is when the operation finished.
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Command that initiated the operation. The operations of the scheduler
+	// are initiated by the start command.
+	Command string `json:"command"`
+
+	// Action is the operation performed (e.g. remove old backups).
+	Action string `json:"action"`
+
+	// Backups affected by the operation.
+	Backups []string `json:"backups,omitempty"`
+
+	// Error is the result of the operation. When empty the operation
+	// succeeded.
+	Error string `json:"error,omitempty"`
+}
+
 // Backups represents a sorted list of backups that are ordered by id. It has
 // the necessary methods so you could use the sort package of the standard
 // library.
@@ -79,16 +192,289 @@ func (b Backups) ValidInfo(archiveInfo archive.Info) bool {
 	return true
 }
 
+// InventoryMerge stores the result of merging the local backups with the cloud
+// inventory.
+type InventoryMerge struct {
+	// Backups is the final view of the local storage after the merge.
+	Backups Backups
+
+	// Save contains the backups that are new or were changed by the cloud
+	// inventory, and must be saved in the local storage.
+	Save Backups
+
+	// Remove contains the ids of the backups that don't exist anymore in the
+	// cloud, and must be removed from the local storage.
+	Remove []string
+}
+
+// MergeInventory synchronizes the local backups with the cloud inventory. The
+// backups are matched by id, so the cloud information is updated without
+// losing the local extra information (like the archive information) of the
+// backups that still exist remotely. Local backups created after recentLimit
+// are kept even if they aren't in the inventory, as the cloud inventory is
+// usually generated only once a day.
+func (b Backups) MergeInventory(inventory []cloud.Backup, recentLimit time.Time) InventoryMerge {
+	var merge InventoryMerge
+
+	inInventory := make(map[string]bool)
+	for _, remoteBackup := range inventory {
+		inInventory[remoteBackup.ID] = true
+
+		localBackup, ok := b.Search(remoteBackup.ID)
+		if !ok {
+			backup := Backup{Backup: remoteBackup}
+			merge.Backups.Add(backup)
+			merge.Save.Add(backup)
+			continue
+		}
+
+		backup := localBackup.merge(remoteBackup)
+		merge.Backups.Add(backup)
+
+		if !sameBackup(localBackup.Backup, backup.Backup) {
+			merge.Save.Add(backup)
+		}
+	}
+
+	for _, localBackup := range b {
+		if inInventory[localBackup.Backup.ID] {
+			continue
+		}
+
+		if localBackup.Backup.CreatedAt.After(recentLimit) {
+			// recent backups could not be in the inventory yet
+			merge.Backups.Add(localBackup)
+			continue
+		}
+
+		merge.Remove = append(merge.Remove, localBackup.Backup.ID)
+	}
+
+	return merge
+}
+
+// merge updates the backup with the cloud information. Only the attributes
+// informed by the cloud are replaced, so any extra information stored locally
+// is preserved.
+func (b Backup) merge(remoteBackup cloud.Backup) Backup {
+	if !remoteBackup.CreatedAt.IsZero() {
+		b.Backup.CreatedAt = remoteBackup.CreatedAt
+	}
+
+	if remoteBackup.Checksum != "" {
+		b.Backup.Checksum = remoteBackup.Checksum
+	}
+
+	if remoteBackup.VaultName != "" {
+		b.Backup.VaultName = remoteBackup.VaultName
+	}
+
+	if remoteBackup.Size > 0 {
+		b.Backup.Size = remoteBackup.Size
+	}
+
+	if remoteBackup.Location.Defined() {
+		b.Backup.Location = remoteBackup.Location
+	}
+
+	if remoteBackup.MachineID != "" {
+		b.Backup.MachineID = remoteBackup.MachineID
+	}
+
+	// the archive description only stores ASCII characters, so the local
+	// comment is more accurate
+	if b.Backup.Comment == "" {
+		b.Backup.Comment = remoteBackup.Comment
+	}
+
+	if remoteBackup.ParityID != "" {
+		b.Backup.ParityID = remoteBackup.ParityID
+	}
+
+	if remoteBackup.CatalogID != "" {
+		b.Backup.CatalogID = remoteBackup.CatalogID
+	}
+
+	if remoteBackup.ReplicaID != "" {
+		b.Backup.ReplicaID = remoteBackup.ReplicaID
+	}
+
+	return b
+}
+
+func sameBackup(b1, b2 cloud.Backup) bool {
+	return b1.ID == b2.ID &&
+		b1.CreatedAt.Equal(b2.CreatedAt) &&
+		b1.Checksum == b2.Checksum &&
+		b1.VaultName == b2.VaultName &&
+		b1.Size == b2.Size &&
+		b1.Location == b2.Location &&
+		b1.MachineID == b2.MachineID &&
+		b1.Comment == b2.Comment &&
+		b1.ParityID == b2.ParityID &&
+		b1.CatalogID == b2.CatalogID &&
+		b1.ReplicaID == b2.ReplicaID
+}
+
 // Storage represents all commands to manage backups information locally. After
 // the backup is uploaded we must keep track of them locally to speed up
 // recovery and cloud cleanup (remove old ones).
 type Storage interface {
 	// Save a backup information.
-	Save(Backup) error
+	Save(ctx context.Context, backup Backup) error
 
 	// List all backup informations in the storage.
-	List() (Backups, error)
+	List(ctx context.Context) (Backups, error)
 
 	// Remove a specific backup information from the storage.
-	Remove(id string) error
+	Remove(ctx context.Context, id string) error
+
+	// SaveInventoryDate stores when the local storage was synchronized with the
+	// cloud inventory.
+	SaveInventoryDate(ctx context.Context, date time.Time) error
+
+	// InventoryDate returns when the local storage was synchronized with the
+	// cloud inventory. If it never happened a zero time is returned.
+	InventoryDate(ctx context.Context) (time.Time, error)
+
+	// SaveRestoreProgress stores the progress of an ongoing backup retrieval.
+	SaveRestoreProgress(ctx context.Context, id string, progress RestoreProgress) error
+
+	// RestoreProgress returns the progress of a backup retrieval. If there's no
+	// retrieval in progress an empty progress is returned.
+	RestoreProgress(ctx context.Context, id string) (RestoreProgress, error)
+
+	// RemoveRestoreProgress erases the progress of a finished backup retrieval.
+	RemoveRestoreProgress(ctx context.Context, id string) error
+
+	// SavePause suspends the scheduled actions until the given date. A zero
+	// date suspends them until RemovePause is called.
+	SavePause(ctx context.Context, until time.Time) error
+
+	// Pause returns if the scheduled actions are suspended and until when. A
+	// zero date means that they are suspended until resumed.
+	Pause(ctx context.Context) (paused bool, until time.Time, err error)
+
+	// RemovePause resumes the scheduled actions.
+	RemovePause(ctx context.Context) error
+
+	// SavePin protects the backup from the old backups removal, recording it
+	// in the pin audit trail. A pin of the same backup is replaced.
+	SavePin(ctx context.Context, pin Pin) error
+
+	// Pins returns the protected backups, including the expired pins, sorted
+	// by id.
+	Pins(ctx context.Context) ([]Pin, error)
+
+	// RemovePin allows the backup to be removed again with the old backups,
+	// recording who removed it, when and why in the pin audit trail. Nothing
+	// is recorded when the backup isn't pinned.
+	RemovePin(ctx context.Context, unpin Pin) error
+
+	// PinEvents returns the pin audit trail, in the order that the changes
+	// happened.
+	PinEvents(ctx context.Context) ([]PinEvent, error)
+}
+
+// Compactor is implemented by the local storages that accumulate obsolete or
+// corrupted records over time, like the audit file.
+type Compactor interface {
+	// Compact rewrites the local storage keeping only the latest information of
+	// each backup, dropping the corrupted records. It returns the number of
+	// records that were dropped.
+	Compact(ctx context.Context) (int, error)
+}
+
+// BatchRemover is implemented by the local storages that can remove many
+// backups in a single transaction, so a bulk removal never leaves the
+// references of the remaining backups half updated.
+type BatchRemover interface {
+	// RemoveBatch saves the updated backups and removes the backups of the ids
+	// in a single transaction.
+	RemoveBatch(ctx context.Context, updated Backups, ids []string) error
+}
+
+// OperationLog is implemented by the local storages that keep an append-only
+// audit log of the destructive operations.
+type OperationLog interface {
+	// SaveOperation appends the operation to the audit log.
+	SaveOperation(ctx context.Context, operation Operation) error
+
+	// Operations returns the audit log, in the order that the operations
+	// were recorded.
+	Operations(ctx context.Context) ([]Operation, error)
+}
+
+// RestoreLister is implemented by the local storages that can list all the
+// ongoing backup retrievals.
+type RestoreLister interface {
+	// RestoreProgresses returns the progress of all ongoing backup retrievals
+	// by backup id.
+	RestoreProgresses(ctx context.Context) (map[string]RestoreProgress, error)
+}
+
+// Tagger is implemented by the local storages that keep human-friendly names
+// of the backups.
+type Tagger interface {
+	// SaveTag assigns the name to the backup. A tag with the same name is
+	// replaced.
+	SaveTag(ctx context.Context, tag Tag) error
+
+	// Tags returns the names of the backups sorted by name.
+	Tags(ctx context.Context) ([]Tag, error)
+
+	// RemoveTag removes the name. Nothing happens when the name doesn't
+	// exist.
+	RemoveTag(ctx context.Context, name string) error
+}
+
+// Journal keeps the backups that were sent to the cloud but couldn't be saved
+// in the local storage, so they can be saved later instead of becoming
+// orphaned archives that are invisible to the tool.
+type Journal interface {
+	// Save a pending backup information.
+	Save(ctx context.Context, backup Backup) error
+
+	// List all pending backups.
+	List(ctx context.Context) (Backups, error)
+
+	// Remove a pending backup that was already saved in the local storage.
+	Remove(ctx context.Context, id string) error
+}
+
+// Trash keeps the backups removed by the user during a grace period, so the
+// removal can be undone before the archives are deleted from the cloud.
+type Trash interface {
+	// Add moves the backup to the trash.
+	Add(ctx context.Context, trashed TrashedBackup) error
+
+	// List all trashed backups in the order that they were trashed.
+	List(ctx context.Context) ([]TrashedBackup, error)
+
+	// Remove a backup from the trash, when it was restored or deleted from the
+	// cloud.
+	Remove(ctx context.Context, id string) error
+}
+
+// Spool keeps the archives that couldn't be sent to the cloud, so they can be
+// sent later.
+type Spool interface {
+	// Add moves the archive file to the spool.
+	Add(ctx context.Context, filename string, spooled SpooledArchive) (SpooledArchive, error)
+
+	// List all spooled archives in the order that they must be sent.
+	List(ctx context.Context) ([]SpooledArchive, error)
+
+	// Remove a spooled archive that was already sent.
+	Remove(ctx context.Context, id string) error
+}
+
+// checkCancellation returns an error when the context was cancelled, so long
+// storage operations can be interrupted.
+func checkCancellation(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return errors.WithStack(newError(ErrorCodeCancelled, ctx.Err()))
+	}
+
+	return nil
 }
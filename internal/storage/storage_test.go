@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/rafaeljusto/toglacier/internal/archive"
 	"github.com/rafaeljusto/toglacier/internal/cloud"
@@ -380,3 +381,230 @@ func TestBackups_ValidInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestBackups_MergeInventory(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description string
+		backups     storage.Backups
+		inventory   []cloud.Backup
+		recentLimit time.Time
+		expected    storage.InventoryMerge
+	}{
+		{
+			description: "it should add new backups from the inventory",
+			inventory: []cloud.Backup{
+				{
+					ID:        "1234",
+					CreatedAt: now,
+					Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+					VaultName: "test",
+					Size:      120,
+					Location:  cloud.LocationAWS,
+				},
+			},
+			recentLimit: now.Add(-24 * time.Hour),
+			expected: storage.InventoryMerge{
+				Backups: storage.Backups{
+					{
+						Backup: cloud.Backup{
+							ID:        "1234",
+							CreatedAt: now,
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+							Size:      120,
+							Location:  cloud.LocationAWS,
+						},
+					},
+				},
+				Save: storage.Backups{
+					{
+						Backup: cloud.Backup{
+							ID:        "1234",
+							CreatedAt: now,
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+							Size:      120,
+							Location:  cloud.LocationAWS,
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "it should update changed backups keeping the local information",
+			backups: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "1234",
+						CreatedAt: now.Add(-time.Hour),
+						Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+						VaultName: "test",
+						Size:      120,
+						Location:  cloud.LocationAWS,
+						Comment:   "before OS upgrade ✓",
+					},
+					Info: archive.Info{
+						"file1": archive.ItemInfo{
+							ID:       "1234",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "915bd6a5873681a273f405c62993b6a96237eab9150fc525c9d57af0becb7ec1",
+						},
+					},
+				},
+			},
+			inventory: []cloud.Backup{
+				{
+					ID:        "1234",
+					CreatedAt: now,
+					Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+					VaultName: "test",
+					Comment:   "before OS upgrade _",
+				},
+			},
+			recentLimit: now.Add(-24 * time.Hour),
+			expected: storage.InventoryMerge{
+				Backups: storage.Backups{
+					{
+						Backup: cloud.Backup{
+							ID:        "1234",
+							CreatedAt: now,
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+							Size:      120,
+							Location:  cloud.LocationAWS,
+							Comment:   "before OS upgrade ✓",
+						},
+						Info: archive.Info{
+							"file1": archive.ItemInfo{
+								ID:       "1234",
+								Status:   archive.ItemInfoStatusNew,
+								Checksum: "915bd6a5873681a273f405c62993b6a96237eab9150fc525c9d57af0becb7ec1",
+							},
+						},
+					},
+				},
+				Save: storage.Backups{
+					{
+						Backup: cloud.Backup{
+							ID:        "1234",
+							CreatedAt: now,
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+							Size:      120,
+							Location:  cloud.LocationAWS,
+							Comment:   "before OS upgrade ✓",
+						},
+						Info: archive.Info{
+							"file1": archive.ItemInfo{
+								ID:       "1234",
+								Status:   archive.ItemInfoStatusNew,
+								Checksum: "915bd6a5873681a273f405c62993b6a96237eab9150fc525c9d57af0becb7ec1",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "it should not save backups that didn't change",
+			backups: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "1234",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						Size:      120,
+						Location:  cloud.LocationAWS,
+					},
+					Info: archive.Info{
+						"file1": archive.ItemInfo{
+							ID:       "1234",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "915bd6a5873681a273f405c62993b6a96237eab9150fc525c9d57af0becb7ec1",
+						},
+					},
+				},
+			},
+			inventory: []cloud.Backup{
+				{
+					ID:        "1234",
+					CreatedAt: now.In(time.FixedZone("BRT", -3*60*60)),
+					Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+					VaultName: "test",
+					Size:      120,
+					Location:  cloud.LocationAWS,
+				},
+			},
+			recentLimit: now.Add(-24 * time.Hour),
+			expected: storage.InventoryMerge{
+				Backups: storage.Backups{
+					{
+						Backup: cloud.Backup{
+							ID:        "1234",
+							CreatedAt: now.In(time.FixedZone("BRT", -3*60*60)),
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+							Size:      120,
+							Location:  cloud.LocationAWS,
+						},
+						Info: archive.Info{
+							"file1": archive.ItemInfo{
+								ID:       "1234",
+								Status:   archive.ItemInfoStatusNew,
+								Checksum: "915bd6a5873681a273f405c62993b6a96237eab9150fc525c9d57af0becb7ec1",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "it should remove old backups that aren't in the inventory and keep the recent ones",
+			backups: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "1234",
+						CreatedAt: now.Add(-48 * time.Hour),
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+					},
+				},
+				{
+					Backup: cloud.Backup{
+						ID:        "1235",
+						CreatedAt: now.Add(-time.Hour),
+						Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+						VaultName: "test",
+					},
+				},
+			},
+			recentLimit: now.Add(-24 * time.Hour),
+			expected: storage.InventoryMerge{
+				Backups: storage.Backups{
+					{
+						Backup: cloud.Backup{
+							ID:        "1235",
+							CreatedAt: now.Add(-time.Hour),
+							Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+							VaultName: "test",
+						},
+					},
+				},
+				Remove: []string{"1234"},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			merge := scenario.backups.MergeInventory(scenario.inventory, scenario.recentLimit)
+
+			if !reflect.DeepEqual(scenario.expected, merge) {
+				t.Errorf("unexpected merge.\n%v", Diff(scenario.expected, merge))
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// TrashedBackup is a backup removed by the user that is still stored in the
+// cloud and in the local storage until the grace period of the trash expires.
+type TrashedBackup struct {
+	// ID identifies the backup in the cloud.
+	ID string
+
+	// TrashedAt is when the backup was moved to the trash.
+	TrashedAt time.Time
+}
+
+// TrashFile stores the trashed backups in a JSON file. The file is replaced
+// atomically on each change and removed when the trash is empty.
+type TrashFile struct {
+	logger   log.Logger
+	Filename string
+}
+
+// NewTrashFile initializes a new TrashFile object.
+func NewTrashFile(logger log.Logger, filename string) *TrashFile {
+	return &TrashFile{
+		logger:   logger,
+		Filename: filename,
+	}
+}
+
+// Add moves the backup to the trash. If the backup is already in the trash it
+// keeps the first trashed date. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t *TrashFile) Add(ctx context.Context, trashed TrashedBackup) error {
+	t.logger.Debugf("storage: adding backup “%s” to the trash file", trashed.ID)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	trashedBackups, err := t.read()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, trashedBackup := range trashedBackups {
+		if trashedBackup.ID == trashed.ID {
+			return nil
+		}
+	}
+
+	trashedBackups = append(trashedBackups, trashed)
+	if err = t.write(trashedBackups); err != nil {
+		return errors.WithStack(err)
+	}
+
+	t.logger.Infof("storage: backup “%s” added successfully to the trash file", trashed.ID)
+	return nil
+}
+
+// List all trashed backups in the order that they were trashed. On error it
+// will return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t *TrashFile) List(ctx context.Context) ([]TrashedBackup, error) {
+	t.logger.Debug("storage: listing backups from trash file")
+
+	if err := checkCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	trashedBackups, err := t.read()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sort.SliceStable(trashedBackups, func(i, j int) bool {
+		return trashedBackups[i].TrashedAt.Before(trashedBackups[j].TrashedAt)
+	})
+
+	t.logger.Info("storage: backups listed successfully from trash file")
+	return trashedBackups, nil
+}
+
+// Remove a backup from the trash. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t *TrashFile) Remove(ctx context.Context, id string) error {
+	t.logger.Debugf("storage: removing backup “%s” from trash file", id)
+
+	if err := checkCancellation(ctx); err != nil {
+		return err
+	}
+
+	trashedBackups, err := t.read()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var remaining []TrashedBackup
+	for _, trashedBackup := range trashedBackups {
+		if trashedBackup.ID != id {
+			remaining = append(remaining, trashedBackup)
+		}
+	}
+
+	if len(remaining) == len(trashedBackups) {
+		return nil
+	}
+
+	if len(remaining) == 0 {
+		// don't leave an empty file behind when the trash is empty
+		if err = os.Remove(t.Filename); err != nil {
+			return errors.WithStack(newError(ErrorCodeWritingFile, err))
+		}
+
+	} else if err = t.write(remaining); err != nil {
+		return errors.WithStack(err)
+	}
+
+	t.logger.Infof("storage: backup “%s” removed successfully from trash file", id)
+	return nil
+}
+
+func (t *TrashFile) read() ([]TrashedBackup, error) {
+	content, err := ioutil.ReadFile(t.Filename)
+	if err != nil {
+		// if the file doesn't exist the trash is empty
+		if pathErr, ok := err.(*os.PathError); ok && os.IsNotExist(pathErr.Err) {
+			return nil, nil
+		}
+
+		return nil, errors.WithStack(newError(ErrorCodeReadingFile, err))
+	}
+
+	var trashedBackups []TrashedBackup
+	if err = json.Unmarshal(content, &trashedBackups); err != nil {
+		return nil, errors.WithStack(newError(ErrorCodeDecodingBackup, err))
+	}
+
+	return trashedBackups, nil
+}
+
+func (t *TrashFile) write(trashedBackups []TrashedBackup) error {
+	encoded, err := json.MarshalIndent(trashedBackups, "", "  ")
+	if err != nil {
+		return errors.WithStack(newError(ErrorCodeEncodingBackup, err))
+	}
+
+	return errors.WithStack(writeFileAtomically(t.Filename, encoded))
+}
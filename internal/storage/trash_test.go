@@ -0,0 +1,137 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/log"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestTrashFile(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	trashed1 := storage.TrashedBackup{ID: "AWSID123", TrashedAt: now}
+	trashed2 := storage.TrashedBackup{ID: "AWSID124", TrashedAt: now.Add(time.Hour)}
+
+	scenarios := []struct {
+		description   string
+		logger        log.Logger
+		filename      string
+		add           []storage.TrashedBackup
+		remove        []string
+		expected      []storage.TrashedBackup
+		expectedError error
+	}{
+		{
+			description: "it should add and list the trashed backups in the order that they were trashed",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-trash"),
+			add:      []storage.TrashedBackup{trashed2, trashed1},
+			expected: []storage.TrashedBackup{trashed1, trashed2},
+		},
+		{
+			description: "it should keep the first trashed date of a backup",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-trash-again"),
+			add:      []storage.TrashedBackup{trashed1, {ID: "AWSID123", TrashedAt: now.Add(time.Hour)}},
+			expected: []storage.TrashedBackup{trashed1},
+		},
+		{
+			description: "it should remove a trashed backup",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-trash-remove"),
+			add:      []storage.TrashedBackup{trashed1, trashed2},
+			remove:   []string{"AWSID123", "AWSID999"},
+			expected: []storage.TrashedBackup{trashed2},
+		},
+		{
+			description: "it should remove the file when the trash is empty",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: path.Join(os.TempDir(), "toglacier-test-trash-empty"),
+			add:      []storage.TrashedBackup{trashed1},
+			remove:   []string{"AWSID123"},
+		},
+		{
+			description: "it should detect an invalid trash file",
+			logger: mockLogger{
+				mockDebug:  func(args ...interface{}) {},
+				mockDebugf: func(format string, args ...interface{}) {},
+				mockInfo:   func(args ...interface{}) {},
+				mockInfof:  func(format string, args ...interface{}) {},
+			},
+			filename: func() string {
+				n := path.Join(os.TempDir(), "toglacier-test-trash-invalid")
+				if err := ioutil.WriteFile(n, []byte("["), 0600); err != nil {
+					t.Fatalf("error creating a temporary file. details: %s", err)
+				}
+
+				return n
+			}(),
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeDecodingBackup,
+				Err:  errors.New("unexpected end of JSON input"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			defer os.Remove(scenario.filename)
+			trash := storage.NewTrashFile(scenario.logger, scenario.filename)
+
+			for _, trashed := range scenario.add {
+				if err := trash.Add(context.Background(), trashed); err != nil {
+					t.Fatalf("error adding trashed backup. details: %s", err)
+				}
+			}
+
+			for _, id := range scenario.remove {
+				if err := trash.Remove(context.Background(), id); err != nil {
+					t.Fatalf("error removing trashed backup. details: %s", err)
+				}
+			}
+
+			trashedBackups, err := trash.List(context.Background())
+			if !reflect.DeepEqual(scenario.expected, trashedBackups) {
+				t.Errorf("trashed backups don't match.\n%s", Diff(scenario.expected, trashedBackups))
+			}
+
+			if !storage.ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if scenario.remove != nil && scenario.expected == nil {
+				if _, err := os.Stat(scenario.filename); !os.IsNotExist(err) {
+					t.Errorf("trash file wasn't removed")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// OTLP span kind and status codes.
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusCodeOK     = 1
+	otlpStatusCodeError  = 2
+)
+
+// OTLPTracer exports the spans to an OpenTelemetry collector with the OTLP/HTTP
+// protocol in JSON encoding. The spans of an operation are sent together when
+// its first span (root) ends. Failures to export are only logged, so they don't
+// affect the operations.
+type OTLPTracer struct {
+	Logger log.Logger
+
+	// Endpoint is the address that receives the traces (e.g.
+	// http://localhost:4318/v1/traces).
+	Endpoint string
+
+	// Attributes identify the source of the spans (e.g. service.name).
+	Attributes map[string]interface{}
+
+	// HTTPClient sends the traces to the endpoint.
+	HTTPClient *http.Client
+
+	lock  sync.Mutex
+	spans map[string][]otlpSpan
+}
+
+// NewOTLPTracer creates a tracer that exports the spans to the endpoint,
+// identifying them with the service name and instance ID (e.g. machine ID).
+func NewOTLPTracer(logger log.Logger, endpoint, serviceName, instanceID string) *OTLPTracer {
+	attributes := map[string]interface{}{
+		"service.name": serviceName,
+	}
+
+	if instanceID != "" {
+		attributes["service.instance.id"] = instanceID
+	}
+
+	return &OTLPTracer{
+		Logger:     logger,
+		Endpoint:   endpoint,
+		Attributes: attributes,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type otlpSpanKey struct{}
+
+// Start creates a span child of the span in the context, or the root span of a
+// new trace.
+func (o *OTLPTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &otlpActiveSpan{
+		tracer:     o,
+		name:       name,
+		spanID:     randomID(8),
+		start:      time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+
+	if parent, ok := ctx.Value(otlpSpanKey{}).(*otlpActiveSpan); ok {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		s.traceID = randomID(16)
+	}
+
+	return context.WithValue(ctx, otlpSpanKey{}, s), s
+}
+
+// finish stores the ended span, exporting all spans of the trace when it is
+// the root span.
+func (o *OTLPTracer) finish(span otlpSpan, root bool) {
+	o.lock.Lock()
+	if o.spans == nil {
+		o.spans = make(map[string][]otlpSpan)
+	}
+	o.spans[span.TraceID] = append(o.spans[span.TraceID], span)
+
+	var spans []otlpSpan
+	if root {
+		spans = o.spans[span.TraceID]
+		delete(o.spans, span.TraceID)
+	}
+	o.lock.Unlock()
+
+	if root {
+		o.export(spans)
+	}
+}
+
+// export sends the spans to the collector.
+func (o *OTLPTracer) export(spans []otlpSpan) {
+	request := otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: otlpAttributes(o.Attributes),
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: "toglacier"},
+						Spans: spans,
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		o.Logger.Warningf("trace: error encoding %d spans. details: %s", len(spans), err)
+		return
+	}
+
+	httpClient := o.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, err := httpClient.Post(o.Endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		o.Logger.Warningf("trace: error sending %d spans to “%s”. details: %s", len(spans), o.Endpoint, err)
+		return
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		o.Logger.Warningf("trace: unexpected status “%s” sending %d spans to “%s”", response.Status, len(spans), o.Endpoint)
+		return
+	}
+
+	o.Logger.Debugf("trace: %d spans sent to “%s”", len(spans), o.Endpoint)
+}
+
+// otlpActiveSpan is a span that didn't end yet.
+type otlpActiveSpan struct {
+	tracer   *OTLPTracer
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	start    time.Time
+
+	lock       sync.Mutex
+	attributes map[string]interface{}
+}
+
+func (s *otlpActiveSpan) SetAttribute(key string, value interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.attributes[key] = value
+}
+
+func (s *otlpActiveSpan) End(err error) {
+	status := otlpStatus{Code: otlpStatusCodeOK}
+	if err != nil {
+		status = otlpStatus{Code: otlpStatusCodeError, Message: err.Error()}
+	}
+
+	s.lock.Lock()
+	span := otlpSpan{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentID,
+		Name:              s.name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(time.Now().UnixNano(), 10),
+		Attributes:        otlpAttributes(s.attributes),
+		Status:            status,
+	}
+	s.lock.Unlock()
+
+	s.tracer.finish(span, s.parentID == "")
+}
+
+// randomID generates a trace or span ID with the given number of bytes,
+// encoded in hexadecimal as expected by the OTLP JSON encoding.
+func randomID(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// otlpAttributes converts the attributes to the OTLP format, sorted by key.
+func otlpAttributes(attributes map[string]interface{}) []otlpAttribute {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var converted []otlpAttribute
+	for _, key := range keys {
+		var value map[string]interface{}
+
+		switch v := attributes[key].(type) {
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+
+		converted = append(converted, otlpAttribute{Key: key, Value: value})
+	}
+
+	return converted
+}
+
+// otlpRequest is the body of the OTLP/HTTP traces request
+// (ExportTraceServiceRequest).
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,170 @@
+package trace_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier/internal/trace"
+)
+
+func TestOTLPTracer(t *testing.T) {
+	logger := mockLogger{
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	type exportedSpan struct {
+		TraceID      string `json:"traceId"`
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		Name         string `json:"name"`
+		Attributes   []struct {
+			Key   string                 `json:"key"`
+			Value map[string]interface{} `json:"value"`
+		} `json:"attributes"`
+		Status struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"status"`
+	}
+
+	type exportRequest struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string                 `json:"key"`
+					Value map[string]interface{} `json:"value"`
+				} `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []exportedSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+
+	var requests []exportRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("unexpected content type “%s”", contentType)
+		}
+
+		var request exportRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("error decoding request. details: %s", err)
+		}
+		requests = append(requests, request)
+	}))
+	defer server.Close()
+
+	tracer := trace.NewOTLPTracer(logger, server.URL, "toglacier", "server1")
+	ctx := trace.WithTracer(context.Background(), tracer)
+
+	ctx, backupSpan := trace.Start(ctx, "backup")
+	_, buildSpan := trace.Start(ctx, "build")
+	buildSpan.SetAttribute("files", 10)
+	buildSpan.End(nil)
+
+	if len(requests) > 0 {
+		t.Fatalf("unexpected export before the root span ends")
+	}
+
+	_, sendSpan := trace.Start(ctx, "send")
+	sendSpan.End(errors.New("connection reset"))
+	backupSpan.End(nil)
+
+	if len(requests) != 1 {
+		t.Fatalf("expected one export and got %d", len(requests))
+	}
+
+	resourceSpans := requests[0].ResourceSpans
+	if len(resourceSpans) != 1 || len(resourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected export format “%#v”", requests[0])
+	}
+
+	var resource []string
+	for _, attribute := range resourceSpans[0].Resource.Attributes {
+		resource = append(resource, attribute.Key+"="+attribute.Value["stringValue"].(string))
+	}
+
+	expectedResource := []string{"service.instance.id=server1", "service.name=toglacier"}
+	if !reflect.DeepEqual(expectedResource, resource) {
+		t.Errorf("resource attributes don't match. expected “%v” and got “%v”", expectedResource, resource)
+	}
+
+	spans := resourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans and got %d", len(spans))
+	}
+
+	build, send, backup := spans[0], spans[1], spans[2]
+
+	if build.Name != "build" || send.Name != "send" || backup.Name != "backup" {
+		t.Errorf("unexpected span names “%s”, “%s” and “%s”", build.Name, send.Name, backup.Name)
+	}
+
+	if len(backup.TraceID) != 32 || len(backup.SpanID) != 16 || backup.ParentSpanID != "" {
+		t.Errorf("unexpected root span identifiers “%#v”", backup)
+	}
+
+	for _, span := range []exportedSpan{build, send} {
+		if span.TraceID != backup.TraceID || span.ParentSpanID != backup.SpanID {
+			t.Errorf("span “%s” isn't a child of the root span", span.Name)
+		}
+	}
+
+	if len(build.Attributes) != 1 || build.Attributes[0].Key != "files" || build.Attributes[0].Value["intValue"] != "10" {
+		t.Errorf("unexpected build attributes “%#v”", build.Attributes)
+	}
+
+	if build.Status.Code != 1 || send.Status.Code != 2 || send.Status.Message != "connection reset" {
+		t.Errorf("unexpected span status “%#v” and “%#v”", build.Status, send.Status)
+	}
+}
+
+func TestStart(t *testing.T) {
+	ctx, span := trace.Start(context.Background(), "backup")
+	span.SetAttribute("files", 10)
+	span.End(nil)
+
+	if ctx != context.Background() {
+		t.Errorf("unexpected context change without a tracer")
+	}
+}
+
+type mockLogger struct {
+	mockDebug    func(args ...interface{})
+	mockDebugf   func(format string, args ...interface{})
+	mockInfo     func(args ...interface{})
+	mockInfof    func(format string, args ...interface{})
+	mockWarning  func(args ...interface{})
+	mockWarningf func(format string, args ...interface{})
+}
+
+func (m mockLogger) Debug(args ...interface{}) {
+	m.mockDebug(args...)
+}
+
+func (m mockLogger) Debugf(format string, args ...interface{}) {
+	m.mockDebugf(format, args...)
+}
+
+func (m mockLogger) Info(args ...interface{}) {
+	m.mockInfo(args...)
+}
+
+func (m mockLogger) Infof(format string, args ...interface{}) {
+	m.mockInfof(format, args...)
+}
+
+func (m mockLogger) Warning(args ...interface{}) {
+	m.mockWarning(args...)
+}
+
+func (m mockLogger) Warningf(format string, args ...interface{}) {
+	m.mockWarningf(format, args...)
+}
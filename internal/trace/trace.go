@@ -0,0 +1,50 @@
+// Package trace records how long each stage of the operations takes (e.g.
+// build, encrypt and send of a backup), so the time spent can be analyzed in a
+// tracing system.
+package trace
+
+import "context"
+
+// Tracer creates the spans of the operation stages.
+type Tracer interface {
+	// Start creates a span with the given name, child of the span stored in the
+	// context. The returned context stores the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span measures a stage of an operation.
+type Span interface {
+	// SetAttribute adds a detail of the stage (e.g. size of the archive).
+	SetAttribute(key string, value interface{})
+
+	// End finishes the stage, that failed when the error isn't nil.
+	End(err error)
+}
+
+type tracerKey struct{}
+
+// WithTracer stores the tracer in the context, so the packages that only
+// receive the context can create spans.
+func WithTracer(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// Start creates a span with the tracer stored in the context. When there's no
+// tracer the span does nothing.
+func Start(ctx context.Context, name string) (context.Context, Span) {
+	if ctx == nil {
+		return ctx, nopSpan{}
+	}
+
+	if tracer, ok := ctx.Value(tracerKey{}).(Tracer); ok && tracer != nil {
+		return tracer.Start(ctx, name)
+	}
+
+	return ctx, nopSpan{}
+}
+
+// nopSpan ignores all the details of the stage.
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(key string, value interface{}) {}
+func (nopSpan) End(err error)                              {}
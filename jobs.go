@@ -0,0 +1,114 @@
+package toglacier
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+// ListJobs retrieves the asynchronous jobs of the cloud, like the archive and
+// inventory retrievals, sorted by creation date. So it is possible to follow a
+// retrieval that takes hours. On error it will return an Error or cloud.Error
+// type encapsulated in a traceable error. To retrieve the desired error you
+// can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) ListJobs() ([]cloud.Job, error) {
+	cloudJobs, ok := t.Cloud.(cloud.Jobs)
+	if !ok {
+		return nil, errors.WithStack(newError(nil, ErrorCodeJobsNotSupported, nil))
+	}
+
+	jobs, err := cloudJobs.ListJobs(t.Context)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	return jobs, nil
+}
+
+// WaitJobs checks the status of the jobs in progress on each interval, until
+// all of them complete or the context is cancelled. After each check the
+// current state of all jobs is informed to onCheck, when defined. The jobs
+// that already completed aren't checked again. On error it will return an
+// Error or cloud.Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) WaitJobs(jobs []cloud.Job, interval time.Duration, onCheck func([]cloud.Job)) ([]cloud.Job, error) {
+	cloudJobs, ok := t.Cloud.(cloud.Jobs)
+	if !ok {
+		return nil, errors.WithStack(newError(nil, ErrorCodeJobsNotSupported, nil))
+	}
+
+	jobs = append([]cloud.Job(nil), jobs...)
+
+	for {
+		var inProgress int
+		for _, job := range jobs {
+			if !job.Completed() {
+				inProgress++
+			}
+		}
+
+		if inProgress == 0 {
+			return jobs, nil
+		}
+
+		t.Logger.Debugf("toglacier: %d jobs in progress, waiting %s for next check", inProgress, interval)
+
+		select {
+		case <-time.After(interval):
+		case <-t.Context.Done():
+			return jobs, errors.WithStack(t.Context.Err())
+		}
+
+		for i, job := range jobs {
+			if job.Completed() {
+				continue
+			}
+
+			current, err := cloudJobs.DescribeJob(t.Context, job.ID)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			jobs[i] = current
+		}
+
+		if onCheck != nil {
+			onCheck(jobs)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package toglacier_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+func TestToGlacier_ListJobs(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		cloud         cloud.Cloud
+		expected      []cloud.Job
+		expectedError error
+	}{
+		{
+			description: "it should list the jobs sorted by creation date",
+			cloud: mockJobsCloud{
+				mockListJobs: func() ([]cloud.Job, error) {
+					return []cloud.Job{
+						{ID: "JOBID2", Status: cloud.JobStatusInProgress, CreatedAt: now},
+						{ID: "JOBID1", Status: cloud.JobStatusSucceeded, CreatedAt: now.Add(-time.Hour)},
+					}, nil
+				},
+			},
+			expected: []cloud.Job{
+				{ID: "JOBID1", Status: cloud.JobStatusSucceeded, CreatedAt: now.Add(-time.Hour)},
+				{ID: "JOBID2", Status: cloud.JobStatusInProgress, CreatedAt: now},
+			},
+		},
+		{
+			description:   "it should detect when the cloud doesn't support jobs",
+			cloud:         mockCloud{},
+			expectedError: &toglacier.Error{Code: toglacier.ErrorCodeJobsNotSupported},
+		},
+		{
+			description: "it should detect an error while listing the jobs",
+			cloud: mockJobsCloud{
+				mockListJobs: func() ([]cloud.Job, error) {
+					return nil, errors.New("vault not found")
+				},
+			},
+			expectedError: errors.New("vault not found"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud,
+			}
+
+			jobs, err := toGlacier.ListJobs()
+			if !reflect.DeepEqual(scenario.expected, jobs) {
+				t.Errorf("jobs don't match.\n%s", Diff(scenario.expected, jobs))
+			}
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_WaitJobs(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description    string
+		jobs           []cloud.Job
+		cloud          cloud.Cloud
+		cancelled      bool
+		expected       []cloud.Job
+		expectedChecks int
+		expectedError  error
+	}{
+		{
+			description: "it should wait until all jobs complete",
+			jobs: []cloud.Job{
+				{ID: "JOBID1", Status: cloud.JobStatusSucceeded, CreatedAt: now},
+				{ID: "JOBID2", Status: cloud.JobStatusInProgress, CreatedAt: now},
+			},
+			cloud: func() cloud.Cloud {
+				var checks int
+				return mockJobsCloud{
+					mockDescribeJob: func(id string) (cloud.Job, error) {
+						if id != "JOBID2" {
+							return cloud.Job{}, fmt.Errorf("unexpected job “%s”", id)
+						}
+
+						checks++
+						if checks < 3 {
+							return cloud.Job{ID: "JOBID2", Status: cloud.JobStatusInProgress, CreatedAt: now}, nil
+						}
+						return cloud.Job{ID: "JOBID2", Status: cloud.JobStatusFailed, CreatedAt: now}, nil
+					},
+				}
+			}(),
+			expected: []cloud.Job{
+				{ID: "JOBID1", Status: cloud.JobStatusSucceeded, CreatedAt: now},
+				{ID: "JOBID2", Status: cloud.JobStatusFailed, CreatedAt: now},
+			},
+			expectedChecks: 3,
+		},
+		{
+			description: "it should return immediately when there's no job in progress",
+			jobs: []cloud.Job{
+				{ID: "JOBID1", Status: cloud.JobStatusSucceeded, CreatedAt: now},
+			},
+			cloud: mockJobsCloud{},
+			expected: []cloud.Job{
+				{ID: "JOBID1", Status: cloud.JobStatusSucceeded, CreatedAt: now},
+			},
+		},
+		{
+			description: "it should stop waiting when the context is cancelled",
+			jobs: []cloud.Job{
+				{ID: "JOBID1", Status: cloud.JobStatusInProgress, CreatedAt: now},
+			},
+			cloud:     mockJobsCloud{},
+			cancelled: true,
+			expected: []cloud.Job{
+				{ID: "JOBID1", Status: cloud.JobStatusInProgress, CreatedAt: now},
+			},
+			expectedError: context.Canceled,
+		},
+		{
+			description:   "it should detect when the cloud doesn't support jobs",
+			cloud:         mockCloud{},
+			expectedError: &toglacier.Error{Code: toglacier.ErrorCodeJobsNotSupported},
+		},
+		{
+			description: "it should detect an error while describing a job",
+			jobs: []cloud.Job{
+				{ID: "JOBID1", Status: cloud.JobStatusInProgress, CreatedAt: now},
+			},
+			cloud: mockJobsCloud{
+				mockDescribeJob: func(id string) (cloud.Job, error) {
+					return cloud.Job{}, errors.New("job not found")
+				},
+			},
+			expectedError: errors.New("job not found"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			interval := time.Millisecond
+			if scenario.cancelled {
+				// the next check would never happen before the cancellation
+				interval = time.Hour
+				cancel()
+			}
+
+			toGlacier := toglacier.ToGlacier{
+				Context: ctx,
+				Cloud:   scenario.cloud,
+				Logger: mockLogger{
+					mockDebugf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			var checks int
+			jobs, err := toGlacier.WaitJobs(scenario.jobs, interval, func([]cloud.Job) {
+				checks++
+			})
+
+			if !reflect.DeepEqual(scenario.expected, jobs) {
+				t.Errorf("jobs don't match.\n%s", Diff(scenario.expected, jobs))
+			}
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+			if scenario.expectedChecks != checks {
+				t.Errorf("checks don't match. expected %d and got %d", scenario.expectedChecks, checks)
+			}
+		})
+	}
+}
+
+// mockJobsCloud is a cloud that also supports following the jobs.
+type mockJobsCloud struct {
+	mockCloud
+	mockListJobs    func() ([]cloud.Job, error)
+	mockDescribeJob func(id string) (cloud.Job, error)
+}
+
+func (m mockJobsCloud) ListJobs(ctx context.Context) ([]cloud.Job, error) {
+	return m.mockListJobs()
+}
+
+func (m mockJobsCloud) DescribeJob(ctx context.Context, id string) (cloud.Job, error) {
+	return m.mockDescribeJob(id)
+}
@@ -0,0 +1,106 @@
+package toglacier
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+	"golang.org/x/text/unicode/norm"
+)
+
+// LatestBackup finds the most recent backup in the local storage, so it can be
+// retrieved without informing the archive ID. With a glob pattern, the most
+// recent backup containing a file matching it (or inside a directory matching
+// it) is returned, which also selects the route of the files. When the archive
+// information of a backup isn't in the local storage, it is retrieved from the
+// cloud with the backupSecret to look for the pattern. The backups of streams
+// are ignored, as they aren't restored to files. When there's no
+// backup it will return an Error type (ErrorCodeBackupNotFound) encapsulated
+// in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) LatestBackup(pattern, backupSecret string) (storage.Backup, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return storage.Backup{}, errors.WithStack(err)
+	}
+
+	// the backups are listed from the newest to the oldest
+	backups, err := t.ListBackups(false, 0)
+	if err != nil {
+		return storage.Backup{}, errors.WithStack(err)
+	}
+
+	for _, backup := range backups {
+		if backup.Backup.Stream != "" {
+			continue
+		}
+
+		if pattern == "" {
+			return backup, nil
+		}
+
+		if backup.Info, err = t.backupInfo(backup, backups, backupSecret); err != nil {
+			t.Logger.Warningf("toglacier: failed to retrieve the archive information of backup “%s”, it will be ignored. details: %s", backup.Backup.ID, err)
+			continue
+		}
+
+		if containsPath(backup.Info, pattern) {
+			return backup, nil
+		}
+	}
+
+	var paths []string
+	if pattern != "" {
+		paths = []string{pattern}
+	}
+
+	return storage.Backup{}, errors.WithStack(newError(paths, ErrorCodeBackupNotFound, nil))
+}
+
+// containsPath checks if the backup can restore a file matching the pattern.
+func containsPath(archiveInfo archive.Info, pattern string) bool {
+	for path, itemInfo := range archiveInfo {
+		if itemInfo.Status != archive.ItemInfoStatusDeleted && matchPath(pattern, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchPath checks if the path, or one of its parent directories, matches the
+// glob pattern. An empty pattern matches all paths. Both are compared in the
+// same Unicode normalization form, as backups built on macOS store decomposed
+// names.
+func matchPath(pattern, path string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	pattern = norm.NFC.String(pattern)
+	path = norm.NFC.String(path)
+
+	for {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,313 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_LatestBackup(t *testing.T) {
+	backups := storage.Backups{
+		{
+			Backup: cloud.Backup{ID: "AWSID121", CreatedAt: time.Date(2017, 9, 12, 0, 0, 0, 0, time.UTC)},
+			Info: archive.Info{
+				"/data/documents/report.odt": archive.ItemInfo{ID: "AWSID121", Status: archive.ItemInfoStatusNew},
+			},
+		},
+		{
+			Backup: cloud.Backup{ID: "AWSID122", CreatedAt: time.Date(2017, 9, 13, 0, 0, 0, 0, time.UTC)},
+			Info: archive.Info{
+				"/data/documents/report.odt":  archive.ItemInfo{ID: "AWSID121", Status: archive.ItemInfoStatusUnmodified},
+				"/data/photos/beach.jpg":      archive.ItemInfo{ID: "AWSID122", Status: archive.ItemInfoStatusNew},
+				"/data/photos/cafe\u0301.jpg": archive.ItemInfo{ID: "AWSID122", Status: archive.ItemInfoStatusNew},
+			},
+		},
+		{
+			Backup: cloud.Backup{ID: "AWSID123", CreatedAt: time.Date(2017, 9, 14, 0, 0, 0, 0, time.UTC)},
+			Info: archive.Info{
+				"/data/documents/report.odt": archive.ItemInfo{ID: "AWSID121", Status: archive.ItemInfoStatusUnmodified},
+				"/data/photos/beach.jpg":     archive.ItemInfo{ID: "AWSID122", Status: archive.ItemInfoStatusDeleted},
+			},
+		},
+		{
+			Backup: cloud.Backup{ID: "AWSID124", CreatedAt: time.Date(2017, 9, 15, 0, 0, 0, 0, time.UTC), Stream: "db.sql"},
+			Info: archive.Info{
+				"db.sql": archive.ItemInfo{ID: "AWSID124", Status: archive.ItemInfoStatusStream},
+			},
+		},
+	}
+
+	scenarios := []struct {
+		description   string
+		pattern       string
+		storage       storage.Storage
+		get           func(ids ...string) (map[string]string, error)
+		expected      string
+		expectedError error
+	}{
+		{
+			description: "it should find the latest backup",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return backups, nil
+				},
+			},
+			expected: "AWSID123",
+		},
+		{
+			description: "it should find the latest backup containing a matching file",
+			pattern:     "/data/photos/*.jpg",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return backups, nil
+				},
+			},
+			expected: "AWSID122",
+		},
+		{
+			description: "it should find a file stored in a different normalization form",
+			pattern:     "/data/photos/caf\u00e9*",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return backups, nil
+				},
+			},
+			expected: "AWSID122",
+		},
+		{
+			description: "it should find the latest backup containing a matching directory",
+			pattern:     "/data/doc*",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return backups, nil
+				},
+			},
+			expected: "AWSID123",
+		},
+		{
+			description: "it should detect when no backup contains a matching file",
+			pattern:     "/data/videos/*",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return backups, nil
+				},
+			},
+			expectedError: &toglacier.Error{
+				Paths: []string{"/data/videos/*"},
+				Code:  toglacier.ErrorCodeBackupNotFound,
+			},
+		},
+		{
+			description: "it should retrieve the missing archive information to find a matching file",
+			pattern:     "/data/music/*",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return append(storage.Backups{
+						{
+							// synchronized from the cloud inventory, without archive information
+							Backup: cloud.Backup{ID: "AWSID125", CreatedAt: time.Date(2017, 9, 16, 0, 0, 0, 0, time.UTC), CatalogID: "CATALOG125"},
+						},
+					}, backups...), nil
+				},
+				mockSave: func(backup storage.Backup) error {
+					return nil
+				},
+			},
+			get: func(ids ...string) (map[string]string, error) {
+				if len(ids) != 1 || ids[0] != "CATALOG125" {
+					return nil, fmt.Errorf("unexpected archives “%v”", ids)
+				}
+
+				return map[string]string{
+					"CATALOG125": writeCatalogTestFile(t, `{"backup":{"Backup":{"ID":"AWSID125"},"Info":{"/data/music/song.mp3":{"ID":"AWSID125","Status":"new"}}}}`),
+				}, nil
+			},
+			expected: "AWSID125",
+		},
+		{
+			description:   "it should detect an invalid pattern",
+			pattern:       "/data/[",
+			expectedError: filepath.ErrBadPattern,
+		},
+		{
+			description: "it should detect an error listing the backups",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("local storage corrupted")
+				},
+			},
+			expectedError: errors.New("local storage corrupted"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage,
+				Cloud: mockCloud{
+					mockGet: scenario.get,
+				},
+				Logger: mockLogger{
+					mockInfof:    func(format string, args ...interface{}) {},
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			backup, err := toGlacier.LatestBackup(scenario.pattern, "")
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if backup.Backup.ID != scenario.expected {
+				t.Errorf("backups don't match. expected “%s” and got “%s”", scenario.expected, backup.Backup.ID)
+			}
+		})
+	}
+}
+
+func TestToGlacier_RetrieveBackupPaths(t *testing.T) {
+	backups := storage.Backups{
+		{
+			Backup: cloud.Backup{ID: "AWSID122", VaultName: "vault"},
+		},
+		{
+			Backup: cloud.Backup{ID: "AWSID123", VaultName: "vault"},
+			Info: archive.Info{
+				"/data/documents/report.odt": archive.ItemInfo{ID: "AWSID122", Status: archive.ItemInfoStatusUnmodified},
+				"/data/documents/budget.ods": archive.ItemInfo{ID: "AWSID123", Status: archive.ItemInfoStatusNew},
+				"/data/photos/beach.jpg":     archive.ItemInfo{ID: "AWSID123", Status: archive.ItemInfoStatusNew},
+			},
+		},
+		{
+			// synchronized from the cloud inventory, without archive information
+			Backup: cloud.Backup{ID: "AWSID124", VaultName: "vault", CatalogID: "CATALOG124"},
+		},
+	}
+
+	scenarios := []struct {
+		description      string
+		id               string
+		pattern          string
+		expectedArchives map[string][]string
+		expectedError    error
+	}{
+		{
+			description: "it should retrieve only the archives of the matching files",
+			id:          "AWSID123",
+			pattern:     "/data/documents/*.odt",
+			expectedArchives: map[string][]string{
+				"AWSID122": {"/data/documents/report.odt"},
+			},
+		},
+		{
+			description: "it should retrieve the files inside a matching directory",
+			id:          "AWSID123",
+			pattern:     "/data/documents",
+			expectedArchives: map[string][]string{
+				"AWSID122": {"/data/documents/report.odt"},
+				"AWSID123": {"/data/documents/budget.ods"},
+			},
+		},
+		{
+			description: "it should retrieve all files without pattern",
+			id:          "AWSID123",
+			expectedArchives: map[string][]string{
+				"AWSID122": {"/data/documents/report.odt"},
+				"AWSID123": {"/data/documents/budget.ods", "/data/photos/beach.jpg"},
+			},
+		},
+		{
+			description: "it should retrieve the missing archive information to extract only the matching files",
+			id:          "AWSID124",
+			pattern:     "/data/music",
+			expectedArchives: map[string][]string{
+				"AWSID124": {"/data/music/song.mp3"},
+			},
+		},
+		{
+			description:   "it should detect an invalid pattern",
+			id:            "AWSID123",
+			pattern:       "/data/[",
+			expectedError: filepath.ErrBadPattern,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			extracted := make(map[string][]string)
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return backups, nil
+					},
+					mockSave: func(backup storage.Backup) error {
+						return nil
+					},
+					mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+						return storage.RestoreProgress{}, nil
+					},
+					mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+						return nil
+					},
+					mockRemoveRestoreProgress: func(id string) error {
+						return nil
+					},
+				},
+				Cloud: mockCloud{
+					mockGet: func(ids ...string) (map[string]string, error) {
+						filenames := make(map[string]string)
+						for _, id := range ids {
+							if id == "CATALOG124" {
+								filenames[id] = writeCatalogTestFile(t, `{"backup":{"Backup":{"ID":"AWSID124"},"Info":{"/data/music/song.mp3":{"ID":"AWSID124","Status":"new"},"/data/photos/sea.jpg":{"ID":"AWSID124","Status":"new"}}}}`)
+								continue
+							}
+							filenames[id] = fmt.Sprintf("toglacier-archive-%s.tar", id)
+						}
+						return filenames, nil
+					},
+				},
+				Archive: mockArchive{
+					mockExtract: func(filename string, filter []string) (archive.Info, error) {
+						sort.Strings(filter)
+
+						var id string
+						fmt.Sscanf(filename, "toglacier-archive-%8s.tar", &id)
+						extracted[id] = filter
+
+						return archive.Info{}, nil
+					},
+				},
+				Logger: mockLogger{
+					mockInfof:    func(format string, args ...interface{}) {},
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			err := toGlacier.RetrieveBackupPaths(scenario.id, scenario.pattern, "", false, archive.OverwriteReplace)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if scenario.expectedArchives == nil {
+				scenario.expectedArchives = make(map[string][]string)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedArchives, extracted) {
+				t.Errorf("extracted archives don't match.\n%s", Diff(scenario.expectedArchives, extracted))
+			}
+		})
+	}
+}
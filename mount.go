@@ -0,0 +1,258 @@
+package toglacier
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+)
+
+// mountNode is a file or directory of the mounted backup. The directories
+// don't exist in the archives, they are built from the path of the files.
+type mountNode struct {
+	children map[string]*mountNode
+	modTime  time.Time
+
+	// index and entry locate the content of a file in the archive
+	index *archive.Index
+	entry archive.IndexEntry
+}
+
+// isDir informs if the node is a directory.
+func (m *mountNode) isDir() bool {
+	return m.children != nil
+}
+
+// names returns the sorted names of the directory content.
+func (m *mountNode) names() []string {
+	names := make([]string, 0, len(m.children))
+	for name := range m.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mountFile is a file of the backup stored in one of its archives.
+type mountFile struct {
+	index *archive.Index
+	entry archive.IndexEntry
+}
+
+// newMountTree builds the directories of the backup from the path of the
+// files. The directories are dated with the backup creation.
+func newMountTree(files []mountFile, modTime time.Time) *mountNode {
+	root := &mountNode{
+		children: make(map[string]*mountNode),
+		modTime:  modTime,
+	}
+
+	for _, file := range files {
+		parts := strings.FieldsFunc(filepath.ToSlash(file.entry.Name), func(r rune) bool {
+			return r == '/'
+		})
+
+		if len(parts) == 0 {
+			continue
+		}
+
+		dir := root
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := dir.children[part]
+			if !ok {
+				child = &mountNode{
+					children: make(map[string]*mountNode),
+					modTime:  modTime,
+				}
+				dir.children[part] = child
+			}
+
+			if !child.isDir() {
+				// a file with the same name of the directory, the archive is
+				// inconsistent
+				dir = nil
+				break
+			}
+
+			dir = child
+		}
+
+		if dir == nil {
+			continue
+		}
+
+		name := parts[len(parts)-1]
+		if _, ok := dir.children[name]; ok {
+			continue
+		}
+
+		dir.children[name] = &mountNode{
+			modTime: file.entry.ModTime,
+			index:   file.index,
+			entry:   file.entry,
+		}
+	}
+
+	return root
+}
+
+// MountBackup retrieves the archives of the backup and exposes its files in a
+// read-only filesystem in the directory, without extracting them. The archives
+// already downloaded by an interrupted backup retrieval are reused, and the
+// downloaded archives are kept in the retrieval progress, so a following
+// retrieval of the same backup doesn't need to download them again. It blocks
+// until the filesystem is unmounted or the context is cancelled. On error it
+// will return an Error, archive.Error, cloud.Error or storage.Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *archive.Error:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) MountBackup(id, backupSecret, dir string) (err error) {
+	defer func() {
+		if err != nil {
+			t.events().OnError("mount backup", err)
+		}
+	}()
+
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	selectedBackup, ok := backups.Search(id)
+	if !ok {
+		t.Logger.Warningf("toglacier: backup “%s” not found in local storage", id)
+	}
+
+	if selectedBackup.Backup.Stream != "" {
+		return errors.WithStack(newError([]string{selectedBackup.Backup.Stream}, ErrorCodeStreamBackup, nil))
+	}
+
+	// all parts of an incremental backup are stored in the same route
+	t.Cloud = t.vaultCloud(selectedBackup.Backup.VaultName)
+
+	progress, err := t.Storage.RestoreProgress(t.Context, id)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if progress.Downloaded == nil {
+		progress.Downloaded = make(map[string]string)
+	}
+
+	indexes := make(map[string]*archive.Index)
+	var decryptedFilenames []string
+
+	defer func() {
+		for archiveID, index := range indexes {
+			if err := index.Close(); err != nil {
+				t.Logger.Warningf("toglacier: failed to close archive “%s”. details: %s", archiveID, err)
+			}
+		}
+
+		for _, filename := range decryptedFilenames {
+			if err := os.Remove(filename); err != nil {
+				t.Logger.Warningf("toglacier: failed to remove file “%s”. details: %s", filename, err)
+			}
+		}
+	}()
+
+	openIndexes := func(archiveIDs ...string) error {
+		filenames, err := t.download(id, progress, backups, archiveIDs...)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for archiveID, filename := range filenames {
+			_, envelop, secret, err := t.readHeader(filename, backupSecret)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			// the downloaded archive is kept encrypted, so it can still be used
+			// by the backup retrieval
+			if secret != "" {
+				if filename, err = envelop.Decrypt(filename, secret); err != nil {
+					return errors.WithStack(err)
+				}
+				decryptedFilenames = append(decryptedFilenames, filename)
+			}
+
+			if indexes[archiveID], err = archive.OpenIndex(t.Context, t.Logger, filename); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		return nil
+	}
+
+	// when the backup isn't in the local storage the archive information is
+	// retrieved from the main archive
+	if selectedBackup.Info == nil {
+		if err = openIndexes(id); err != nil {
+			return errors.WithStack(err)
+		}
+
+		selectedBackup.Info = indexes[id].Info()
+	}
+
+	pendingIDs := make(map[string]bool)
+	for _, itemInfo := range selectedBackup.Info {
+		if _, ok := indexes[itemInfo.ID]; !ok && itemInfo.Status != archive.ItemInfoStatusDeleted {
+			pendingIDs[itemInfo.ID] = true
+		}
+	}
+
+	archiveIDs := make([]string, 0, len(pendingIDs))
+	for archiveID := range pendingIDs {
+		archiveIDs = append(archiveIDs, archiveID)
+	}
+	sort.Strings(archiveIDs)
+
+	if len(archiveIDs) > 0 {
+		if err = openIndexes(archiveIDs...); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	var files []mountFile
+	for path, itemInfo := range selectedBackup.Info {
+		if itemInfo.Status == archive.ItemInfoStatusDeleted {
+			continue
+		}
+
+		index, ok := indexes[itemInfo.ID]
+		if !ok {
+			continue
+		}
+
+		if entry, ok := index.Entry(path); ok {
+			files = append(files, mountFile{index: index, entry: entry})
+		} else {
+			t.Logger.Warningf("toglacier: file “%s” not found in archive “%s”", path, itemInfo.ID)
+		}
+	}
+
+	t.Logger.Infof("toglacier: mounting %d files of backup “%s” in “%s”", len(files), id, dir)
+	return errors.WithStack(t.mount(dir, newMountTree(files, selectedBackup.Backup.CreatedAt)))
+}
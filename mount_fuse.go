@@ -0,0 +1,123 @@
+// +build linux darwin freebsd
+
+package toglacier
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+)
+
+// mount serves the files of the backup with FUSE until the directory is
+// unmounted (e.g. fusermount -u) or the context is cancelled.
+func (t ToGlacier) mount(dir string, root *mountNode) error {
+	conn, err := fuse.Mount(dir, fuse.ReadOnly(), fuse.FSName("toglacier"), fuse.Subtype("toglacier"))
+	if err != nil {
+		return errors.WithStack(newError([]string{dir}, ErrorCodeMount, err))
+	}
+	defer conn.Close()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- fs.Serve(conn, mountFS{root: root, logger: t.Logger})
+	}()
+
+	select {
+	case err = <-served:
+	case <-t.Context.Done():
+		if err = fuse.Unmount(dir); err != nil {
+			return errors.WithStack(newError([]string{dir}, ErrorCodeMount, err))
+		}
+		err = <-served
+	}
+
+	if err != nil {
+		return errors.WithStack(newError([]string{dir}, ErrorCodeMount, err))
+	}
+
+	<-conn.Ready
+	if conn.MountError != nil {
+		return errors.WithStack(newError([]string{dir}, ErrorCodeMount, conn.MountError))
+	}
+
+	return nil
+}
+
+// mountFS is the read-only filesystem of the backup.
+type mountFS struct {
+	root   *mountNode
+	logger log.Logger
+}
+
+// Root returns the top directory of the backup.
+func (m mountFS) Root() (fs.Node, error) {
+	return mountFSNode{node: m.root, logger: m.logger}, nil
+}
+
+// mountFSNode is a file or directory of the read-only filesystem. As the file
+// doesn't need to be opened, the node is also used to read the file.
+type mountFSNode struct {
+	node   *mountNode
+	logger log.Logger
+}
+
+// Attr fills the file or directory metadata, without the write permissions.
+func (m mountFSNode) Attr(ctx context.Context, attr *fuse.Attr) error {
+	attr.Mtime = m.node.modTime
+
+	if m.node.isDir() {
+		attr.Mode = os.ModeDir | 0555
+		return nil
+	}
+
+	attr.Mode = m.node.entry.Mode.Perm() &^ 0222
+	attr.Size = uint64(m.node.entry.Size)
+	return nil
+}
+
+// Lookup finds the file or directory in the directory.
+func (m mountFSNode) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	child, ok := m.node.children[name]
+	if !ok {
+		return nil, fuse.ENOENT
+	}
+
+	return mountFSNode{node: child, logger: m.logger}, nil
+}
+
+// ReadDirAll lists the directory content.
+func (m mountFSNode) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	var dirents []fuse.Dirent
+	for _, name := range m.node.names() {
+		direntType := fuse.DT_File
+		if m.node.children[name].isDir() {
+			direntType = fuse.DT_Dir
+		}
+
+		dirents = append(dirents, fuse.Dirent{Name: name, Type: direntType})
+	}
+
+	return dirents, nil
+}
+
+// Read retrieves the file content from the archive.
+func (m mountFSNode) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) error {
+	if m.node.isDir() {
+		return fuse.Errno(fuse.EIO)
+	}
+
+	buffer := make([]byte, req.Size)
+	n, err := m.node.index.ReadAt(m.node.entry.Name, buffer, req.Offset)
+	if err != nil && err != io.EOF {
+		m.logger.Warningf("toglacier: failed to read file “%s” from archive. details: %s", m.node.entry.Name, err)
+		return fuse.EIO
+	}
+
+	resp.Data = buffer[:n]
+	return nil
+}
@@ -0,0 +1,10 @@
+// +build !linux,!darwin,!freebsd
+
+package toglacier
+
+import "github.com/pkg/errors"
+
+// mount isn't supported without FUSE.
+func (t ToGlacier) mount(dir string, root *mountNode) error {
+	return errors.WithStack(newError([]string{dir}, ErrorCodeMountNotSupported, nil))
+}
@@ -0,0 +1,108 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_MountBackup(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	backups := storage.Backups{
+		{
+			Backup: cloud.Backup{ID: "AWSID123", CreatedAt: now},
+			Info: archive.Info{
+				"/data/a.txt": archive.ItemInfo{ID: "AWSID123", Status: archive.ItemInfoStatusNew},
+			},
+		},
+		{
+			Backup: cloud.Backup{ID: "AWSID124", CreatedAt: now, Stream: "db.sql"},
+			Info: archive.Info{
+				"db.sql": archive.ItemInfo{ID: "AWSID124", Status: archive.ItemInfoStatusStream},
+			},
+		},
+	}
+
+	scenarios := []struct {
+		description   string
+		id            string
+		listError     error
+		getError      error
+		expectedError error
+	}{
+		{
+			description: "it should refuse to mount a backup of a stream",
+			id:          "AWSID124",
+			expectedError: &toglacier.Error{
+				Paths: []string{"db.sql"},
+				Code:  toglacier.ErrorCodeStreamBackup,
+			},
+		},
+		{
+			description:   "it should detect an error listing the backups",
+			id:            "AWSID123",
+			listError:     errors.New("database corrupted"),
+			expectedError: errors.New("database corrupted"),
+		},
+		{
+			description:   "it should detect an error retrieving the archives",
+			id:            "AWSID123",
+			getError:      errors.New("vault unavailable"),
+			expectedError: errors.New("vault unavailable"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var mountErr error
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud: mockCloud{
+					mockGet: func(ids ...string) (map[string]string, error) {
+						return nil, scenario.getError
+					},
+				},
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return backups, scenario.listError
+					},
+					mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+						return storage.RestoreProgress{}, nil
+					},
+					mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+						return nil
+					},
+				},
+				Events: mockEvents{
+					mockOnError: func(operation string, err error) {
+						if operation == "mount backup" {
+							mountErr = err
+						}
+					},
+				},
+				Logger: mockLogger{
+					mockDebugf:   func(format string, args ...interface{}) {},
+					mockInfof:    func(format string, args ...interface{}) {},
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			err := toGlacier.MountBackup(scenario.id, "", "/mnt/restore")
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if mountErr != err {
+				t.Errorf("error event doesn't match. expected “%v” and got “%v”", err, mountErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package toglacier
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/log"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// DefaultNagiosService is the service of the passive checks when none is
+// informed.
+const DefaultNagiosService = "toglacier"
+
+// Nagios service states used in the passive check results.
+const (
+	nagiosOK       = 0
+	nagiosCritical = 2
+)
+
+// NagiosCheck writes the result of the actions as a passive service check in
+// the external command file of Nagios or Icinga, so traditional monitoring
+// stacks can follow the backups without parsing the reports. A completed
+// backup is reported as OK, with the backup size and duration as performance
+// data, and a failed action as CRITICAL. The service must be configured to
+// accept passive checks, and a freshness threshold is recommended to detect
+// when the backups stop running.
+type NagiosCheck struct {
+	NopEvents
+
+	// CommandFile is the external command file (a named pipe) of the
+	// monitoring system (e.g. /usr/local/nagios/var/rw/nagios.cmd).
+	CommandFile string
+
+	// Host is the name of the host in the monitoring system.
+	Host string
+
+	// Service is the name of the service in the monitoring system. When empty
+	// the DefaultNagiosService is used.
+	Service string
+
+	// Clock retrieves the time of the check result. When not defined the system
+	// clock is used.
+	Clock Clock
+
+	// Logger reports the check results that couldn't be written, as they don't
+	// interrupt the actions.
+	Logger log.Logger
+}
+
+// OnBackupComplete reports the backup as OK.
+func (n NagiosCheck) OnBackupComplete(backup storage.Backup) {
+	output := fmt.Sprintf("OK - backup %s sent to the cloud|size=%dB duration=%.0fs",
+		backup.Backup.ID, backup.Backup.Size, backup.Backup.Duration.Seconds())
+
+	n.write(nagiosOK, output)
+}
+
+// OnError reports the action as CRITICAL.
+func (n NagiosCheck) OnError(action string, err error) {
+	// the pipe character starts the performance data of the output
+	message := strings.Replace(err.Error(), "|", "/", -1)
+	n.write(nagiosCritical, fmt.Sprintf("CRITICAL - %s failed: %s", action, message))
+}
+
+// write sends the passive check result to the command file, only logging when
+// it isn't possible (e.g. monitoring system stopped).
+func (n NagiosCheck) write(code int, output string) {
+	if err := n.writeCommand(code, output); err != nil && n.Logger != nil {
+		n.Logger.Warningf("toglacier: failed to write nagios check result. details: %s", err)
+	}
+}
+
+func (n NagiosCheck) writeCommand(code int, output string) error {
+	clock := n.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	service := n.Service
+	if service == "" {
+		service = DefaultNagiosService
+	}
+
+	// each external command is a single line
+	output = strings.NewReplacer("\n", " ", "\r", " ").Replace(output)
+
+	command := fmt.Sprintf("[%d] PROCESS_SERVICE_CHECK_RESULT;%s;%s;%d;%s\n",
+		clock.Now().Unix(), n.Host, service, code, output)
+
+	// the command file is a named pipe, so it is opened without blocking to fail
+	// immediately when the monitoring system isn't reading it
+	file, err := os.OpenFile(n.CommandFile, os.O_WRONLY|os.O_APPEND|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return errors.WithStack(newError(nil, ErrorCodeNagios, err))
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(command); err != nil {
+		return errors.WithStack(newError(nil, ErrorCodeNagios, err))
+	}
+
+	return nil
+}
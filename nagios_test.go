@@ -0,0 +1,116 @@
+package toglacier_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestNagiosCheck(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	dir, err := ioutil.TempDir("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary directory. details: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	scenarios := []struct {
+		description      string
+		commandFile      string
+		host             string
+		service          string
+		event            func(events toglacier.Events)
+		expected         string
+		expectedWarnings []string
+	}{
+		{
+			description: "it should report a completed backup as OK",
+			commandFile: path.Join(dir, "nagios.cmd"),
+			host:        "server1",
+			service:     "backup",
+			event: func(events toglacier.Events) {
+				events.OnBackupComplete(storage.Backup{
+					Backup: cloud.Backup{ID: "AWSID123", Size: 1048576, Duration: 90 * time.Second},
+				})
+			},
+			expected: "[1505385000] PROCESS_SERVICE_CHECK_RESULT;server1;backup;0;OK - backup AWSID123 sent to the cloud|size=1048576B duration=90s\n",
+		},
+		{
+			description: "it should report a failure as CRITICAL using the default service",
+			commandFile: path.Join(dir, "nagios.cmd"),
+			host:        "server1",
+			event: func(events toglacier.Events) {
+				events.OnError("backup", errors.New("connection error |\nretry later"))
+			},
+			expected: "[1505385000] PROCESS_SERVICE_CHECK_RESULT;server1;toglacier;2;CRITICAL - backup failed: connection error / retry later\n",
+		},
+		{
+			description: "it should ignore the other events",
+			commandFile: path.Join(dir, "nagios.cmd"),
+			host:        "server1",
+			event: func(events toglacier.Events) {
+				events.OnBackupStart([]string{"/data"})
+				events.OnBackupRemoved("AWSID123")
+			},
+		},
+		{
+			description: "it should log when the command file doesn't exist",
+			commandFile: path.Join(dir, "idontexist", "nagios.cmd"),
+			host:        "server1",
+			event: func(events toglacier.Events) {
+				events.OnError("backup", errors.New("connection error"))
+			},
+			expectedWarnings: []string{
+				fmt.Sprintf("toglacier: failed to write nagios check result. details: toglacier: error writing nagios check result. details: open %s: no such file or directory", path.Join(dir, "idontexist", "nagios.cmd")),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			// the command file of the monitoring system always exists
+			if err := ioutil.WriteFile(path.Join(dir, "nagios.cmd"), nil, 0600); err != nil {
+				t.Fatalf("error creating command file. details: %s", err)
+			}
+
+			var warnings []string
+
+			nagiosCheck := toglacier.NagiosCheck{
+				CommandFile: scenario.commandFile,
+				Host:        scenario.host,
+				Service:     scenario.service,
+				Clock:       fakeClock{now: now},
+				Logger: mockLogger{
+					mockWarningf: func(format string, args ...interface{}) {
+						warnings = append(warnings, fmt.Sprintf(format, args...))
+					},
+				},
+			}
+
+			scenario.event(nagiosCheck)
+
+			content, err := ioutil.ReadFile(path.Join(dir, "nagios.cmd"))
+			if err != nil {
+				t.Fatalf("error reading command file. details: %s", err)
+			}
+
+			if string(content) != scenario.expected {
+				t.Errorf("check results don't match.\n%s", Diff(scenario.expected, string(content)))
+			}
+
+			if !reflect.DeepEqual(scenario.expectedWarnings, warnings) {
+				t.Errorf("warnings don't match.\n%s", Diff(scenario.expectedWarnings, warnings))
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package toglacier
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// recordOperation appends the destructive operation to the audit log, when the
+// local storage keeps one. A failure to record the operation doesn't change
+// its result, so it is only logged.
+func (t ToGlacier) recordOperation(action string, backups []string, operationErr error) {
+	operationLog, ok := t.Storage.(storage.OperationLog)
+	if !ok {
+		return
+	}
+
+	operation := storage.Operation{
+		CreatedAt: t.now(),
+		Command:   t.Command,
+		Action:    action,
+		Backups:   backups,
+	}
+
+	if operationErr != nil {
+		operation.Error = operationErr.Error()
+	}
+
+	// the operation is recorded even when it was interrupted by the user
+	if err := operationLog.SaveOperation(context.Background(), operation); err != nil {
+		t.Logger.Warningf("toglacier: failed to record operation “%s” in the audit log. details: %s", action, err)
+	}
+}
+
+// Operations returns the audit log of the destructive operations, in the order
+// that they were recorded. When the local storage doesn't keep an audit log no
+// operation is returned. On error it will return a storage.Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) Operations() ([]storage.Operation, error) {
+	operationLog, ok := t.Storage.(storage.OperationLog)
+	if !ok {
+		return nil, nil
+	}
+
+	operations, err := operationLog.Operations(t.Context)
+	return operations, errors.WithStack(err)
+}
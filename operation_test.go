@@ -0,0 +1,312 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_RecordOperation(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	backups := storage.Backups{
+		{Backup: cloud.Backup{ID: "AWSID123", CreatedAt: now.Add(-time.Hour)}},
+	}
+
+	scenarios := []struct {
+		description        string
+		cloud              cloud.Cloud
+		trash              storage.Trash
+		action             func(toGlacier toglacier.ToGlacier) error
+		saveOperationError error
+		expected           []storage.Operation
+	}{
+		{
+			description: "it should record the removal of backups",
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.RemoveBackups("AWSID123")
+			},
+			expected: []storage.Operation{
+				{
+					CreatedAt: now,
+					Command:   "remove",
+					Action:    "remove backups",
+					Backups:   []string{"AWSID123"},
+				},
+			},
+		},
+		{
+			description: "it should record a failed removal of backups",
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return errors.New("connection error")
+				},
+			},
+			action: func(toGlacier toglacier.ToGlacier) error {
+				toGlacier.RemoveBackups("AWSID123")
+				return nil
+			},
+			expected: []storage.Operation{
+				{
+					CreatedAt: now,
+					Command:   "remove",
+					Action:    "remove backups",
+					Backups:   []string{"AWSID123"},
+					Error:     "connection error",
+				},
+			},
+		},
+		{
+			description: "it should record the backups moved to the trash",
+			trash: mockTrash{
+				mockAdd: func(trashed storage.TrashedBackup) error {
+					return nil
+				},
+			},
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.RemoveBackups("AWSID123")
+			},
+			expected: []storage.Operation{
+				{
+					CreatedAt: now,
+					Command:   "remove",
+					Action:    "trash backups",
+					Backups:   []string{"AWSID123"},
+				},
+			},
+		},
+		{
+			description: "it should record the removal of old backups",
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.RemoveOldBackups(0, "")
+			},
+			expected: []storage.Operation{
+				{
+					CreatedAt: now,
+					Command:   "remove",
+					Action:    "remove old backups",
+					Backups:   []string{"AWSID123"},
+				},
+			},
+		},
+		{
+			description: "it should not record the removal of old backups when all backups are recent",
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.RemoveOldBackups(10, "")
+			},
+		},
+		{
+			description: "it should record the local storage replaced by the state of the cloud",
+			cloud: mockStateCloud{
+				mockLoadState: func() (string, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					f.WriteString(`[{"Backup":{"ID":"AWSID124","CreatedAt":"2017-09-14T10:30:00Z","VaultName":"test"}}]`)
+					return f.Name(), nil
+				},
+			},
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.LoadState("")
+			},
+			expected: []storage.Operation{
+				{
+					CreatedAt: now,
+					Command:   "remove",
+					Action:    "load state",
+					Backups:   []string{"AWSID124"},
+				},
+			},
+		},
+		{
+			description: "it should record the local storage rebuilt from a catalog",
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (map[string]string, error) {
+					return map[string]string{
+						"CATALOG1": writeCatalogTestFile(t, `{"backup":{"Backup":{"ID":"AWSID124"}}}`),
+					}, nil
+				},
+			},
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.BootstrapCatalog("CATALOG1", "")
+			},
+			expected: []storage.Operation{
+				{
+					CreatedAt: now,
+					Command:   "remove",
+					Action:    "bootstrap catalog",
+					Backups:   []string{"AWSID124"},
+				},
+			},
+		},
+		{
+			description: "it should not record when there's no state in the cloud",
+			cloud: mockStateCloud{
+				mockLoadState: func() (string, error) {
+					return "", nil
+				},
+			},
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.LoadState("")
+			},
+		},
+		{
+			description: "it should keep the result of the operation when it can't be recorded",
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.RemoveBackups("AWSID123")
+			},
+			saveOperationError: errors.New("disk full"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var recorded []storage.Operation
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud,
+				Storage: mockOperationLogStorage{
+					mockStorage: mockStorage{
+						mockList: func() (storage.Backups, error) {
+							return backups, nil
+						},
+						mockRemove: func(id string) error {
+							return nil
+						},
+						mockSave: func(b storage.Backup) error {
+							return nil
+						},
+						mockRemovePause: func() error {
+							return nil
+						},
+						mockPins: func() ([]storage.Pin, error) {
+							return nil, nil
+						},
+					},
+					mockSaveOperation: func(operation storage.Operation) error {
+						if scenario.saveOperationError != nil {
+							return scenario.saveOperationError
+						}
+
+						recorded = append(recorded, operation)
+						return nil
+					},
+				},
+				Command: "remove",
+				Trash:   scenario.trash,
+				Clock:   fakeClock{now: now},
+				Logger: mockLogger{
+					mockDebugf:   func(format string, args ...interface{}) {},
+					mockInfof:    func(format string, args ...interface{}) {},
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			if err := scenario.action(toGlacier); err != nil {
+				t.Fatalf("unexpected error. details: %s", err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, recorded) {
+				t.Errorf("operations don't match.\n%s", Diff(scenario.expected, recorded))
+			}
+		})
+	}
+}
+
+func TestToGlacier_Operations(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		storage       storage.Storage
+		expected      []storage.Operation
+		expectedError error
+	}{
+		{
+			description: "it should list the recorded operations",
+			storage: mockOperationLogStorage{
+				mockOperations: func() ([]storage.Operation, error) {
+					return []storage.Operation{
+						{CreatedAt: now, Command: "db", Action: "compact storage"},
+					}, nil
+				},
+			},
+			expected: []storage.Operation{
+				{CreatedAt: now, Command: "db", Action: "compact storage"},
+			},
+		},
+		{
+			description: "it should ignore a local storage without audit log",
+			storage:     mockStorage{},
+		},
+		{
+			description: "it should detect an error listing the operations",
+			storage: mockOperationLogStorage{
+				mockOperations: func() ([]storage.Operation, error) {
+					return nil, errors.New("file corrupted")
+				},
+			},
+			expectedError: errors.New("file corrupted"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage,
+			}
+
+			operations, err := toGlacier.Operations()
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, operations) {
+				t.Errorf("operations don't match.\n%s", Diff(scenario.expected, operations))
+			}
+		})
+	}
+}
+
+// mockOperationLogStorage is a local storage that keeps an audit log of the
+// destructive operations.
+type mockOperationLogStorage struct {
+	mockStorage
+	mockSaveOperation func(operation storage.Operation) error
+	mockOperations    func() ([]storage.Operation, error)
+}
+
+func (m mockOperationLogStorage) SaveOperation(ctx context.Context, operation storage.Operation) error {
+	return m.mockSaveOperation(operation)
+}
+
+func (m mockOperationLogStorage) Operations(ctx context.Context) ([]storage.Operation, error) {
+	return m.mockOperations()
+}
@@ -0,0 +1,514 @@
+package toglacier_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+	"github.com/rafaeljusto/toglacier/internal/trace"
+)
+
+func TestNew(t *testing.T) {
+	type contextKey string
+	ctx := context.WithValue(context.Background(), contextKey("key"), "value")
+
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+	clock := fakeClock{now: now}
+	collector := report.NewCollector()
+
+	scenarios := []struct {
+		description         string
+		options             []toglacier.Option
+		expectedContext     context.Context
+		expectedNow         time.Time
+		expectedMachineID   string
+		expectedCommand     string
+		expectedRedundancy  int
+		expectedRoutes      []string
+		expectedReplica     string
+		expectedConcurrency int
+		expectedRemoveConc  int
+		expectedSources     []toglacier.Source
+		expectedSourcesDir  string
+		expectedDockerImage string
+		expectedNoSync      bool
+		expectedAllocSize   int
+		expectedJournal     string
+		expectedSpool       string
+		expectedTrash       string
+		expectedPriority    toglacier.Priority
+		expectedChanges     archive.Changes
+		expectedRebase      time.Duration
+		expectedFullBackup  time.Duration
+		expectedUnreadable  int
+		expectedMaxDuration time.Duration
+		expectedSnapshot    bool
+		expectedRetries     int
+		expectedNormalize   archive.Normalization
+		expectedSpecial     archive.SpecialFiles
+		expectedUnencrypted bool
+		expectedEvents      toglacier.Events
+		expectedTracing     string
+		expectedReports     *report.Collector
+		expectedMessengers  []string
+		expectedGroupByDay  *time.Location
+		expectedCloud       cloud.Cloud
+		expectedProgress    cloud.UploadProgress
+		expectedError       error
+	}{
+		{
+			description: "it should create an instance with default values",
+			options: []toglacier.Option{
+				toglacier.WithAWSCloud("000000000000", "AAAAAAAAAAAAAAAAAAAA", "secret", "us-east-1", "test"),
+				toglacier.WithAuditFileStorage("toglacier-test-audit"),
+			},
+			expectedContext: context.Background(),
+		},
+		{
+			description: "it should create an instance with custom values",
+			options: []toglacier.Option{
+				toglacier.WithAWSCloud("000000000000", "AAAAAAAAAAAAAAAAAAAA", "secret", "us-east-1", "test"),
+				toglacier.WithBoltDBStorage("toglacier-test.db"),
+				toglacier.WithContext(ctx),
+				toglacier.WithLogger(mockLogger{}),
+				toglacier.WithClock(clock),
+				toglacier.WithMachineID("server1"),
+				toglacier.WithCommand("start"),
+				toglacier.WithRedundancy(10),
+				toglacier.WithRoute("/data/photos", "photos", "us-west-2"),
+				toglacier.WithRoute("/data/documents", "documents", ""),
+				toglacier.WithRouteNotification("/data/photos", toglacier.Notification{To: []string{"family@example.com"}}),
+				toglacier.WithReplica("test-replica", "eu-west-1"),
+				toglacier.WithConcurrency(2),
+				toglacier.WithRemoveConcurrency(8),
+				toglacier.WithSource("db.sql", "pg_dump mydb", 30*time.Minute),
+				toglacier.WithDockerVolumeSource("", "pgdata", nil, []string{"*.log"}, time.Hour),
+				toglacier.WithSourcesDir("/var/lib/toglacier/sources"),
+				toglacier.WithDockerImage("busybox"),
+				toglacier.WithBoltDBOptions(true, 1048576),
+				toglacier.WithProxy(&url.URL{Scheme: "socks5", Host: "proxy.example.com:1080"}),
+				toglacier.WithRequestsPerSecond(5),
+				toglacier.WithTrace(true),
+				toglacier.WithOTLPTracing("http://localhost:4318/v1/traces"),
+				toglacier.WithUploadProgress(fakeUploadProgress{name: "monitor"}),
+				toglacier.WithJournal("toglacier-test.db.journal"),
+				toglacier.WithSpool("/var/spool/toglacier", 1073741824),
+				toglacier.WithTrash("toglacier-test.db.trash", 7*24*time.Hour),
+				toglacier.WithPriority(19, toglacier.IOClassIdle, 0),
+				toglacier.WithChanges(mockChanges{}),
+				toglacier.WithRebaseAfter(180 * 24 * time.Hour),
+				toglacier.WithFullBackupEvery(30 * 24 * time.Hour),
+				toglacier.WithMaxUnreadable(5),
+				toglacier.WithBackupMaxDuration(6 * time.Hour),
+				toglacier.WithSnapshot(true),
+				toglacier.WithChangeRetries(3),
+				toglacier.WithNormalization(archive.NormalizationNFC),
+				toglacier.WithSpecialFiles(archive.SpecialFilesArchive),
+				toglacier.WithAllowUnencrypted(true),
+				toglacier.WithEvents(toglacier.NopEvents{}),
+				toglacier.WithReports(collector),
+				toglacier.WithTelegram("abc123", "-1001234567890"),
+				toglacier.WithGroupByDay(time.UTC),
+			},
+			expectedContext:     ctx,
+			expectedNow:         now,
+			expectedMachineID:   "server1",
+			expectedCommand:     "start",
+			expectedRedundancy:  10,
+			expectedRoutes:      []string{"/data/photos=photos (family@example.com)", "/data/documents=documents"},
+			expectedReplica:     "test-replica",
+			expectedConcurrency: 2,
+			expectedRemoveConc:  8,
+			expectedSources: []toglacier.Source{
+				{Name: "db.sql", Command: "pg_dump mydb", Timeout: 30 * time.Minute},
+				{DockerVolume: "pgdata", Exclude: []string{"*.log"}, Timeout: time.Hour},
+			},
+			expectedDockerImage: "busybox",
+			expectedSourcesDir:  "/var/lib/toglacier/sources",
+			expectedNoSync:      true,
+			expectedAllocSize:   1048576,
+			expectedJournal:     "toglacier-test.db.journal",
+			expectedSpool:       "/var/spool/toglacier (1073741824 bytes)",
+			expectedTrash:       "toglacier-test.db.trash (168h0m0s)",
+			expectedPriority:    toglacier.Priority{Nice: 19, IOClass: toglacier.IOClassIdle},
+			expectedChanges:     mockChanges{},
+			expectedRebase:      180 * 24 * time.Hour,
+			expectedFullBackup:  30 * 24 * time.Hour,
+			expectedUnreadable:  5,
+			expectedMaxDuration: 6 * time.Hour,
+			expectedSnapshot:    true,
+			expectedRetries:     3,
+			expectedNormalize:   archive.NormalizationNFC,
+			expectedSpecial:     archive.SpecialFilesArchive,
+			expectedUnencrypted: true,
+			expectedEvents:      toglacier.NopEvents{},
+			expectedTracing:     "http://localhost:4318/v1/traces",
+			expectedReports:     collector,
+			expectedMessengers:  []string{"telegram -1001234567890 (proxy)"},
+			expectedGroupByDay:  time.UTC,
+			expectedProgress:    fakeUploadProgress{name: "monitor"},
+		},
+		{
+			description: "it should create an instance with a S3-compatible cloud",
+			options: []toglacier.Option{
+				toglacier.WithS3Cloud("http://minio.example.com:9000", "", "backups", "AAAAAAAAAAAAAAAAAAAA", "secret", true),
+				toglacier.WithAuditFileStorage("toglacier-test-audit"),
+				toglacier.WithMachineID("server1"),
+			},
+			expectedContext:   context.Background(),
+			expectedMachineID: "server1",
+			expectedCloud: &cloud.S3{
+				Endpoint:  &url.URL{Scheme: "http", Host: "minio.example.com:9000"},
+				Region:    cloud.DefaultS3Region,
+				Bucket:    "backups",
+				MachineID: "server1",
+				PathStyle: true,
+			},
+		},
+		{
+			description: "it should create an instance with a rclone remote",
+			options: []toglacier.Option{
+				toglacier.WithRcloneCloud("b2:backups/toglacier", os.Args[0], ""),
+				toglacier.WithAuditFileStorage("toglacier-test-audit"),
+				toglacier.WithMachineID("server1"),
+			},
+			expectedContext:   context.Background(),
+			expectedMachineID: "server1",
+			expectedCloud: &cloud.Rclone{
+				Remote:    "b2:backups/toglacier",
+				MachineID: "server1",
+			},
+		},
+		{
+			description: "it should detect an unknown archive format",
+			options: []toglacier.Option{
+				toglacier.WithAWSCloud("000000000000", "AAAAAAAAAAAAAAAAAAAA", "secret", "us-east-1", "test"),
+				toglacier.WithAuditFileStorage("toglacier-test-audit"),
+				toglacier.WithArchiveFormat("rar"),
+			},
+			expectedError: &archive.Error{
+				Code: archive.ErrorCodeUnknownFormat,
+				Err:  errors.New("format “rar” not registered"),
+			},
+		},
+		{
+			description: "it should detect when the cloud isn't informed",
+			options: []toglacier.Option{
+				toglacier.WithBoltDBStorage("toglacier-test.db"),
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeCloudNotDefined,
+			},
+		},
+		{
+			description: "it should detect when the local storage isn't informed",
+			options: []toglacier.Option{
+				toglacier.WithAWSCloud("000000000000", "AAAAAAAAAAAAAAAAAAAA", "secret", "us-east-1", "test"),
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeStorageNotDefined,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier, err := toglacier.New(scenario.options...)
+
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if toGlacier.Context != scenario.expectedContext {
+				t.Errorf("contexts don't match. expected “%v” and got “%v”", scenario.expectedContext, toGlacier.Context)
+			}
+
+			if s3, ok := scenario.expectedCloud.(*cloud.S3); ok {
+				if cloudS3, ok := toGlacier.Cloud.(*cloud.S3); !ok {
+					t.Errorf("unexpected cloud type %T", toGlacier.Cloud)
+				} else if cloudS3.Endpoint.String() != s3.Endpoint.String() || cloudS3.Region != s3.Region ||
+					cloudS3.Bucket != s3.Bucket || cloudS3.MachineID != s3.MachineID || cloudS3.PathStyle != s3.PathStyle {
+					t.Errorf("s3 clouds don't match.\n%s", Diff(s3, cloudS3))
+				}
+			} else if rclone, ok := scenario.expectedCloud.(*cloud.Rclone); ok {
+				if cloudRclone, ok := toGlacier.Cloud.(*cloud.Rclone); !ok {
+					t.Errorf("unexpected cloud type %T", toGlacier.Cloud)
+				} else if cloudRclone.Remote != rclone.Remote || cloudRclone.MachineID != rclone.MachineID {
+					t.Errorf("rclone clouds don't match.\n%s", Diff(rclone, cloudRclone))
+				}
+			} else if awsCloud, ok := toGlacier.Cloud.(*cloud.AWSCloud); !ok {
+				t.Errorf("unexpected cloud type %T", toGlacier.Cloud)
+			} else if awsCloud.Progress != scenario.expectedProgress {
+				t.Errorf("upload progress don't match. expected “%#v” and got “%#v”", scenario.expectedProgress, awsCloud.Progress)
+			}
+
+			if toGlacier.Storage == nil {
+				t.Error("local storage not defined")
+			} else if _, ok := toGlacier.Storage.(*storage.AuditFile); !ok {
+				if boltDB, ok := toGlacier.Storage.(*storage.BoltDB); !ok {
+					t.Errorf("unexpected local storage type %T", toGlacier.Storage)
+				} else if boltDB.NoSync != scenario.expectedNoSync || boltDB.AllocSize != scenario.expectedAllocSize {
+					t.Errorf("boltdb options don't match. expected “%t/%d” and got “%t/%d”", scenario.expectedNoSync, scenario.expectedAllocSize, boltDB.NoSync, boltDB.AllocSize)
+				}
+			}
+
+			if toGlacier.Archive == nil || toGlacier.Envelop == nil || toGlacier.Parity == nil || toGlacier.Logger == nil || toGlacier.Clock == nil {
+				t.Fatal("default values not defined")
+			}
+
+			if !scenario.expectedNow.IsZero() && !toGlacier.Clock.Now().Equal(scenario.expectedNow) {
+				t.Errorf("clocks don't match. expected “%s” and got “%s”", scenario.expectedNow, toGlacier.Clock.Now())
+			}
+
+			if toGlacier.MachineID != scenario.expectedMachineID {
+				t.Errorf("machine identifiers don't match. expected “%s” and got “%s”", scenario.expectedMachineID, toGlacier.MachineID)
+			}
+
+			if toGlacier.Command != scenario.expectedCommand {
+				t.Errorf("commands don't match. expected “%s” and got “%s”", scenario.expectedCommand, toGlacier.Command)
+			}
+
+			if toGlacier.Redundancy != scenario.expectedRedundancy {
+				t.Errorf("redundancies don't match. expected “%d” and got “%d”", scenario.expectedRedundancy, toGlacier.Redundancy)
+			}
+
+			if toGlacier.Concurrency != scenario.expectedConcurrency {
+				t.Errorf("concurrencies don't match. expected “%d” and got “%d”", scenario.expectedConcurrency, toGlacier.Concurrency)
+			}
+
+			if toGlacier.RemoveConcurrency != scenario.expectedRemoveConc {
+				t.Errorf("remove concurrencies don't match. expected “%d” and got “%d”", scenario.expectedRemoveConc, toGlacier.RemoveConcurrency)
+			}
+
+			if !reflect.DeepEqual(toGlacier.Sources, scenario.expectedSources) || toGlacier.SourcesDir != scenario.expectedSourcesDir {
+				t.Errorf("sources don't match. expected “%v” (%s) and got “%v” (%s)", scenario.expectedSources, scenario.expectedSourcesDir, toGlacier.Sources, toGlacier.SourcesDir)
+			}
+
+			if toGlacier.DockerImage != scenario.expectedDockerImage {
+				t.Errorf("docker images don't match. expected “%s” and got “%s”", scenario.expectedDockerImage, toGlacier.DockerImage)
+			}
+
+			var journal string
+			if journalFile, ok := toGlacier.Journal.(*storage.JournalFile); ok {
+				journal = journalFile.Filename
+			}
+
+			if journal != scenario.expectedJournal {
+				t.Errorf("journals don't match. expected “%s” and got “%s”", scenario.expectedJournal, journal)
+			}
+
+			var spool string
+			if spoolDir, ok := toGlacier.Spool.(*storage.SpoolDir); ok {
+				spool = fmt.Sprintf("%s (%d bytes)", spoolDir.Dir, spoolDir.MaxSize)
+			}
+
+			if spool != scenario.expectedSpool {
+				t.Errorf("spools don't match. expected “%s” and got “%s”", scenario.expectedSpool, spool)
+			}
+
+			var trash string
+			if trashFile, ok := toGlacier.Trash.(*storage.TrashFile); ok {
+				trash = fmt.Sprintf("%s (%s)", trashFile.Filename, toGlacier.TrashPeriod)
+			}
+
+			if trash != scenario.expectedTrash {
+				t.Errorf("trashes don't match. expected “%s” and got “%s”", scenario.expectedTrash, trash)
+			}
+
+			if toGlacier.Priority != scenario.expectedPriority {
+				t.Errorf("priorities don't match. expected “%#v” and got “%#v”", scenario.expectedPriority, toGlacier.Priority)
+			}
+
+			if (scenario.expectedChanges == nil) != (toGlacier.Changes == nil) {
+				t.Errorf("changes don't match. expected “%#v” and got “%#v”", scenario.expectedChanges, toGlacier.Changes)
+			}
+
+			if builder, ok := toGlacier.Archive.(*archive.TARBuilder); !ok {
+				t.Errorf("unexpected archive type %T", toGlacier.Archive)
+			} else if (scenario.expectedChanges == nil) != (builder.Changes == nil) {
+				t.Errorf("archive changes don't match. expected “%#v” and got “%#v”", scenario.expectedChanges, builder.Changes)
+			} else if builder.Snapshot != scenario.expectedSnapshot {
+				t.Errorf("archive snapshots don't match. expected “%t” and got “%t”", scenario.expectedSnapshot, builder.Snapshot)
+			} else if builder.ChangeRetries != scenario.expectedRetries {
+				t.Errorf("archive change retries don't match. expected “%d” and got “%d”", scenario.expectedRetries, builder.ChangeRetries)
+			} else if builder.Normalization != scenario.expectedNormalize {
+				t.Errorf("archive normalizations don't match. expected “%s” and got “%s”", scenario.expectedNormalize, builder.Normalization)
+			} else if builder.SpecialFiles != scenario.expectedSpecial {
+				t.Errorf("archive special files don't match. expected “%s” and got “%s”", scenario.expectedSpecial, builder.SpecialFiles)
+			}
+
+			if toGlacier.AllowUnencrypted != scenario.expectedUnencrypted {
+				t.Errorf("unencrypted archives permissions don't match. expected “%t” and got “%t”", scenario.expectedUnencrypted, toGlacier.AllowUnencrypted)
+			}
+
+			if toGlacier.RebaseAfter != scenario.expectedRebase {
+				t.Errorf("rebase periods don't match. expected “%s” and got “%s”", scenario.expectedRebase, toGlacier.RebaseAfter)
+			}
+
+			if toGlacier.FullBackupEvery != scenario.expectedFullBackup {
+				t.Errorf("full backup periods don't match. expected “%s” and got “%s”", scenario.expectedFullBackup, toGlacier.FullBackupEvery)
+			}
+
+			if toGlacier.MaxUnreadable != scenario.expectedUnreadable {
+				t.Errorf("unreadable files limits don't match. expected “%d” and got “%d”", scenario.expectedUnreadable, toGlacier.MaxUnreadable)
+			}
+
+			if toGlacier.BackupMaxDuration != scenario.expectedMaxDuration {
+				t.Errorf("backup maximum durations don't match. expected “%s” and got “%s”", scenario.expectedMaxDuration, toGlacier.BackupMaxDuration)
+			}
+
+			var tracing string
+			if tracer, ok := toGlacier.Tracer.(*trace.OTLPTracer); ok {
+				tracing = tracer.Endpoint
+			}
+
+			if tracing != scenario.expectedTracing {
+				t.Errorf("tracing endpoints don't match. expected “%s” and got “%s”", scenario.expectedTracing, tracing)
+			}
+
+			if toGlacier.Events != scenario.expectedEvents {
+				t.Errorf("events don't match. expected “%#v” and got “%#v”", scenario.expectedEvents, toGlacier.Events)
+			}
+
+			if scenario.expectedReports != nil && toGlacier.Reports != scenario.expectedReports {
+				t.Errorf("unexpected reports collector")
+			} else if toGlacier.Reports == nil || toGlacier.Reports == report.Default() {
+				t.Errorf("the instance should have its own reports collector")
+			}
+
+			if toGlacier.GroupByDay != (scenario.expectedGroupByDay != nil) || toGlacier.Location != scenario.expectedGroupByDay {
+				t.Errorf("group by day don't match. expected “%v” and got “%v” (%t)", scenario.expectedGroupByDay, toGlacier.Location, toGlacier.GroupByDay)
+			}
+
+			var messengers []string
+			for _, messenger := range toGlacier.Messengers {
+				telegram, ok := messenger.(toglacier.TelegramMessenger)
+				if !ok {
+					t.Errorf("unexpected messenger type %T", messenger)
+					continue
+				}
+
+				description := "telegram " + telegram.ChatID
+				if telegram.Client != nil {
+					description += " (proxy)"
+				}
+				messengers = append(messengers, description)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedMessengers, messengers) {
+				t.Errorf("messengers don't match.\n%s", Diff(scenario.expectedMessengers, messengers))
+			}
+
+			var routes []string
+			for _, route := range toGlacier.Routes {
+				if _, ok := route.Cloud.(*cloud.AWSCloud); !ok {
+					t.Errorf("unexpected route cloud type %T", route.Cloud)
+				}
+
+				description := route.Prefix + "=" + route.VaultName
+				if route.Notification != nil {
+					description += " (" + strings.Join(route.Notification.To, ",") + ")"
+				}
+				routes = append(routes, description)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedRoutes, routes) {
+				t.Errorf("routes don't match. expected “%v” and got “%v”", scenario.expectedRoutes, routes)
+			}
+
+			var replica string
+			if toGlacier.Replica != nil {
+				if awsCloud, ok := toGlacier.Replica.(*cloud.AWSCloud); !ok {
+					t.Errorf("unexpected replica cloud type %T", toGlacier.Replica)
+				} else {
+					replica = awsCloud.VaultName
+				}
+			}
+
+			if replica != scenario.expectedReplica {
+				t.Errorf("replicas don't match. expected “%s” and got “%s”", scenario.expectedReplica, replica)
+			}
+		})
+	}
+}
+
+func TestNew_ModuleLogger(t *testing.T) {
+	var general, storageMessages []string
+	newLogger := func(messages *[]string) mockLogger {
+		return mockLogger{
+			mockDebug:    func(args ...interface{}) { *messages = append(*messages, fmt.Sprint(args...)) },
+			mockDebugf:   func(format string, args ...interface{}) { *messages = append(*messages, fmt.Sprintf(format, args...)) },
+			mockInfo:     func(args ...interface{}) { *messages = append(*messages, fmt.Sprint(args...)) },
+			mockInfof:    func(format string, args ...interface{}) { *messages = append(*messages, fmt.Sprintf(format, args...)) },
+			mockWarning:  func(args ...interface{}) { *messages = append(*messages, fmt.Sprint(args...)) },
+			mockWarningf: func(format string, args ...interface{}) { *messages = append(*messages, fmt.Sprintf(format, args...)) },
+		}
+	}
+
+	auditFile, err := ioutil.TempFile("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary file. details: %s", err)
+	}
+	auditFile.Close()
+	defer os.Remove(auditFile.Name())
+
+	toGlacier, err := toglacier.New(
+		toglacier.WithAWSCloud("000000000000", "AAAAAAAAAAAAAAAAAAAA", "secret", "us-east-1", "test"),
+		toglacier.WithAuditFileStorage(auditFile.Name()),
+		toglacier.WithLogger(newLogger(&general)),
+		toglacier.WithModuleLogger(toglacier.ModuleStorage, newLogger(&storageMessages)),
+	)
+
+	if err != nil {
+		t.Fatalf("unexpected error “%v”", err)
+	}
+
+	if _, err = toGlacier.Storage.List(context.Background()); err != nil {
+		t.Fatalf("unexpected error listing the backups “%v”", err)
+	}
+
+	if len(general) > 0 {
+		t.Errorf("unexpected messages in the general logger “%v”", general)
+	}
+
+	expected := []string{
+		"storage: listing backups from audit file storage",
+		"storage: backups listed successfully from audit file storage",
+	}
+
+	if !reflect.DeepEqual(expected, storageMessages) {
+		t.Errorf("storage messages don't match.\n%s", Diff(expected, storageMessages))
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f fakeClock) Now() time.Time {
+	return f.now
+}
+
+type fakeUploadProgress struct {
+	name string
+}
+
+func (fakeUploadProgress) OnUploadProgress(status cloud.UploadStatus) {}
+
+func (fakeUploadProgress) OnUploadFinished() {}
@@ -0,0 +1,34 @@
+// +build !windows
+
+package toglacier
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// privateDir checks if the directory belongs to the current user, so another
+// user can't read or replace the files stored in it (e.g. a directory created
+// beforehand in a shared place). The permissions of the group and of the other
+// users are removed.
+func privateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("“%s” isn't a directory", dir)
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Geteuid() {
+		return fmt.Errorf("directory “%s” belongs to another user (uid %d)", dir, stat.Uid)
+	}
+
+	if info.Mode().Perm()&0077 != 0 {
+		return os.Chmod(dir, 0700)
+	}
+
+	return nil
+}
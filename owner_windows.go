@@ -0,0 +1,21 @@
+package toglacier
+
+import (
+	"fmt"
+	"os"
+)
+
+// privateDir checks if the path is a directory. The access control of Windows
+// is inherited from the parent directory, that is private in the user profile.
+func privateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("“%s” isn't a directory", dir)
+	}
+
+	return nil
+}
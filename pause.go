@@ -0,0 +1,111 @@
+package toglacier
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/report"
+)
+
+// Pause suspends the scheduled actions for the given duration (e.g. during
+// maintenance windows). When the duration is zero the actions are suspended
+// until Resume is called. The pause is stored in the local storage, so it can
+// be requested by another process while the scheduler is running. On error it
+// will return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) Pause(duration time.Duration) error {
+	var until time.Time
+	if duration > 0 {
+		until = t.now().Add(duration)
+	}
+
+	return errors.WithStack(t.Storage.SavePause(t.Context, until))
+}
+
+// Resume restarts the scheduled actions suspended with Pause. On error it will
+// return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) Resume() error {
+	return errors.WithStack(t.Storage.RemovePause(t.Context))
+}
+
+// Paused returns if the scheduled actions are suspended and until when. A zero
+// date means that they are suspended until resumed. An expired pause is
+// resumed automatically. On error it will return an Error type encapsulated in
+// a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) Paused() (bool, time.Time, error) {
+	paused, until, err := t.Storage.Pause(t.Context)
+	if err != nil {
+		return false, time.Time{}, errors.WithStack(err)
+	}
+
+	if !paused || until.IsZero() || t.now().Before(until) {
+		return paused, until, nil
+	}
+
+	if err = t.Storage.RemovePause(t.Context); err != nil {
+		return false, time.Time{}, errors.WithStack(err)
+	}
+
+	return false, time.Time{}, nil
+}
+
+// SkipPaused checks if the scheduled action must be skipped because the
+// scheduled actions are suspended. Skipped actions are added to the reports,
+// so the paused state is visible to the administrator. If the pause can't be
+// retrieved the action isn't skipped, as it is safer to keep the backups
+// running.
+func (t ToGlacier) SkipPaused(action string) bool {
+	paused, until, err := t.Paused()
+	if err != nil {
+		t.Logger.Warningf("toglacier: failed to retrieve the pause state. details: %s", err)
+		return false
+	}
+
+	if !paused {
+		return false
+	}
+
+	t.Logger.Infof("toglacier: action “%s” skipped while paused", action)
+	t.reports().Add(report.NewPaused(action, until))
+	return true
+}
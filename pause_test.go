@@ -0,0 +1,290 @@
+package toglacier_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/log"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_Pause(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		duration      time.Duration
+		storage       storage.Storage
+		expectedError error
+	}{
+		{
+			description: "it should pause the scheduled actions for a period",
+			duration:    2 * time.Hour,
+			storage: mockStorage{
+				mockSavePause: func(until time.Time) error {
+					if expected := now.Add(2 * time.Hour); !until.Equal(expected) {
+						return fmt.Errorf("unexpected pause date “%s”", until)
+					}
+
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should pause the scheduled actions until resumed",
+			storage: mockStorage{
+				mockSavePause: func(until time.Time) error {
+					if !until.IsZero() {
+						return fmt.Errorf("unexpected pause date “%s”", until)
+					}
+
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should detect an error saving the pause",
+			duration:    time.Hour,
+			storage: mockStorage{
+				mockSavePause: func(until time.Time) error {
+					return errors.New("error saving pause")
+				},
+			},
+			expectedError: errors.New("error saving pause"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage,
+				Clock:   fakeClock{now: now},
+			}
+
+			err := toGlacier.Pause(scenario.duration)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_Resume(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		storage       storage.Storage
+		expectedError error
+	}{
+		{
+			description: "it should resume the scheduled actions",
+			storage: mockStorage{
+				mockRemovePause: func() error {
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should detect an error removing the pause",
+			storage: mockStorage{
+				mockRemovePause: func() error {
+					return errors.New("error removing pause")
+				},
+			},
+			expectedError: errors.New("error removing pause"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage,
+			}
+
+			err := toGlacier.Resume()
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_Paused(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description    string
+		storage        storage.Storage
+		expectedPaused bool
+		expectedUntil  time.Time
+		expectedError  error
+	}{
+		{
+			description: "it should detect when the scheduled actions are paused",
+			storage: mockStorage{
+				mockPause: func() (bool, time.Time, error) {
+					return true, now.Add(time.Hour), nil
+				},
+			},
+			expectedPaused: true,
+			expectedUntil:  now.Add(time.Hour),
+		},
+		{
+			description: "it should detect when the scheduled actions are paused until resumed",
+			storage: mockStorage{
+				mockPause: func() (bool, time.Time, error) {
+					return true, time.Time{}, nil
+				},
+			},
+			expectedPaused: true,
+		},
+		{
+			description: "it should detect when the scheduled actions aren't paused",
+			storage: mockStorage{
+				mockPause: func() (bool, time.Time, error) {
+					return false, time.Time{}, nil
+				},
+			},
+		},
+		{
+			description: "it should resume the scheduled actions when the pause expired",
+			storage: mockStorage{
+				mockPause: func() (bool, time.Time, error) {
+					return true, now.Add(-time.Minute), nil
+				},
+				mockRemovePause: func() error {
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should detect an error retrieving the pause",
+			storage: mockStorage{
+				mockPause: func() (bool, time.Time, error) {
+					return false, time.Time{}, errors.New("error retrieving pause")
+				},
+			},
+			expectedError: errors.New("error retrieving pause"),
+		},
+		{
+			description: "it should detect an error removing an expired pause",
+			storage: mockStorage{
+				mockPause: func() (bool, time.Time, error) {
+					return true, now.Add(-time.Minute), nil
+				},
+				mockRemovePause: func() error {
+					return errors.New("error removing pause")
+				},
+			},
+			expectedError: errors.New("error removing pause"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage,
+				Clock:   fakeClock{now: now},
+			}
+
+			paused, until, err := toGlacier.Paused()
+			if paused != scenario.expectedPaused {
+				t.Errorf("paused states don't match. expected “%t” and got “%t”", scenario.expectedPaused, paused)
+			}
+
+			if !until.Equal(scenario.expectedUntil) {
+				t.Errorf("dates don't match. expected “%s” and got “%s”", scenario.expectedUntil, until)
+			}
+
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_SkipPaused(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description    string
+		action         string
+		storage        storage.Storage
+		logger         log.Logger
+		expected       bool
+		expectedReport string
+	}{
+		{
+			description: "it should skip the action while paused",
+			action:      "backup",
+			storage: mockStorage{
+				mockPause: func() (bool, time.Time, error) {
+					return true, now.Add(time.Hour), nil
+				},
+			},
+			logger: mockLogger{
+				mockInfof: func(format string, args ...interface{}) {},
+			},
+			expected:       true,
+			expectedReport: "Action “backup” skipped, scheduler paused until 2017-09-14 11:30:00.",
+		},
+		{
+			description: "it should run the action when not paused",
+			action:      "backup",
+			storage: mockStorage{
+				mockPause: func() (bool, time.Time, error) {
+					return false, time.Time{}, nil
+				},
+			},
+		},
+		{
+			description: "it should run the action when the pause can't be retrieved",
+			action:      "backup",
+			storage: mockStorage{
+				mockPause: func() (bool, time.Time, error) {
+					return false, time.Time{}, errors.New("error retrieving pause")
+				},
+			},
+			logger: mockLogger{
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			report.Clear()
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage,
+				Logger:  scenario.logger,
+				Clock:   fakeClock{now: now},
+			}
+
+			if skip := toGlacier.SkipPaused(scenario.action); skip != scenario.expected {
+				t.Errorf("skip decisions don't match. expected “%t” and got “%t”", scenario.expected, skip)
+			}
+
+			built, err := report.Build(report.FormatPlain)
+			if err != nil {
+				t.Fatalf("error building reports. details: %s", err)
+			}
+
+			if scenario.expectedReport == "" && built != "" {
+				t.Errorf("unexpected report “%s”", built)
+
+			} else if !strings.Contains(built, scenario.expectedReport) {
+				t.Errorf("report doesn't contain “%s”. got “%s”", scenario.expectedReport, built)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package toglacier
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// Pin protects the backup from the old backups removal, so known-good
+// milestone backups (e.g. before a migration) are kept regardless of their
+// age. The archives referenced by a pinned backup are also kept. The hold
+// expires after the until date, when it isn't zero. Who placed the hold and
+// why is recorded in the pin audit trail. The backup must exist in the local
+// storage. On error it will return an Error or
+// storage.Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) Pin(id, user, reason string, until time.Time) error {
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if _, ok := backups.Search(id); !ok {
+		return errors.WithStack(newError(nil, ErrorCodeBackupNotFound, fmt.Errorf("backup “%s” isn't in the local storage", id)))
+	}
+
+	pin := storage.Pin{
+		ID:        id,
+		Until:     until,
+		User:      user,
+		Reason:    reason,
+		CreatedAt: t.now(),
+	}
+
+	return errors.WithStack(t.Storage.SavePin(t.Context, pin))
+}
+
+// Unpin allows the backup to be removed again with the old backups. Who removed
+// the hold and why is recorded in the pin audit trail. On error it will return
+// a storage.Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) Unpin(id, user, reason string) error {
+	unpin := storage.Pin{
+		ID:        id,
+		User:      user,
+		Reason:    reason,
+		CreatedAt: t.now(),
+	}
+
+	return errors.WithStack(t.Storage.RemovePin(t.Context, unpin))
+}
+
+// Pins returns the pinned backups, sorted by id, including the ones with an
+// expired hold. On error it will return a storage.Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) Pins() ([]storage.Pin, error) {
+	pins, err := t.Storage.Pins(t.Context)
+	return pins, errors.WithStack(err)
+}
+
+// PinEvents returns the pin audit trail, with all holds placed and removed in
+// the order that they happened. On error it will return a storage.Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) PinEvents() ([]storage.PinEvent, error) {
+	events, err := t.Storage.PinEvents(t.Context)
+	return events, errors.WithStack(err)
+}
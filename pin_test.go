@@ -0,0 +1,152 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_Pin(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		id            string
+		storage       storage.Storage
+		expectedError error
+	}{
+		{
+			description: "it should pin a backup",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{{Backup: cloud.Backup{ID: "AWSID123"}}}, nil
+				},
+				mockSavePin: func(pin storage.Pin) error {
+					expected := storage.Pin{
+						ID:        "AWSID123",
+						Until:     now.Add(30 * 24 * time.Hour),
+						User:      "john",
+						Reason:    "before migration",
+						CreatedAt: now,
+					}
+
+					if !reflect.DeepEqual(expected, pin) {
+						return fmt.Errorf("pinning unexpected backup.\n%s", Diff(expected, pin))
+					}
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should detect when the backup doesn't exist",
+			id:          "AWSID999",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{{Backup: cloud.Backup{ID: "AWSID123"}}}, nil
+				},
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeBackupNotFound,
+				Err:  errors.New("backup “AWSID999” isn't in the local storage"),
+			},
+		},
+		{
+			description: "it should detect an error listing the backups",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("error listing backups")
+				},
+			},
+			expectedError: errors.New("error listing backups"),
+		},
+		{
+			description: "it should detect an error saving the pin",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{{Backup: cloud.Backup{ID: "AWSID123"}}}, nil
+				},
+				mockSavePin: func(pin storage.Pin) error {
+					return errors.New("error saving pin")
+				},
+			},
+			expectedError: errors.New("error saving pin"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage,
+				Clock:   fakeClock{now: now},
+			}
+
+			err := toGlacier.Pin(scenario.id, "john", "before migration", now.Add(30*24*time.Hour))
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_Unpin(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		storage       storage.Storage
+		expectedError error
+	}{
+		{
+			description: "it should unpin a backup",
+			storage: mockStorage{
+				mockRemovePin: func(unpin storage.Pin) error {
+					expected := storage.Pin{
+						ID:        "AWSID123",
+						User:      "john",
+						Reason:    "migration finished",
+						CreatedAt: now,
+					}
+
+					if !reflect.DeepEqual(expected, unpin) {
+						return fmt.Errorf("unpinning unexpected backup.\n%s", Diff(expected, unpin))
+					}
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should detect an error removing the pin",
+			storage: mockStorage{
+				mockRemovePin: func(unpin storage.Pin) error {
+					return errors.New("error removing pin")
+				},
+			},
+			expectedError: errors.New("error removing pin"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage,
+				Clock:   fakeClock{now: now},
+			}
+
+			if err := toGlacier.Unpin("AWSID123", "john", "migration finished"); !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package toglacier
+
+import "sync"
+
+// IOClass is the I/O scheduling class used while building the archives.
+type IOClass string
+
+const (
+	// IOClassBestEffort shares the disk with the other processes, using the
+	// level (0 - 7, 7 is the lowest) to define the priority.
+	IOClassBestEffort IOClass = "best-effort"
+
+	// IOClassIdle only uses the disk when no other process needs it.
+	IOClassIdle IOClass = "idle"
+)
+
+// Priority lowers the scheduling and I/O priority of the process when the
+// archives are built, so the backup scan doesn't affect latency-sensitive
+// services running in the same host. The lowered priority isn't limited to
+// the build: it isn't raised back, as it requires privileges (CAP_SYS_NICE)
+// and the threads that built the archive are reused by the rest of the
+// process, so everything else (uploads, retrievals, the scheduler) also runs
+// with it until the process ends. Only supported on Linux.
+type Priority struct {
+	// Nice is the CPU scheduling niceness (1 - 19, 19 is the lowest priority).
+	// When zero the CPU priority is not changed.
+	Nice int
+
+	// IOClass is the I/O scheduling class. When empty the I/O priority is not
+	// changed.
+	IOClass IOClass
+
+	// IOLevel is the priority inside the best-effort I/O class (0 - 7, 7 is the
+	// lowest priority).
+	IOLevel int
+}
+
+// enabled informs if any priority change was requested.
+func (p Priority) enabled() bool {
+	return p.Nice != 0 || p.IOClass != ""
+}
+
+// priorityLock avoids archives built at the same time (concurrent routes)
+// changing the priority of the threads together.
+var priorityLock sync.Mutex
+
+// lowerPriority lowers the priority of the process for the rest of its
+// execution. Failures are only logged, as the backup can still be built with
+// the original priority.
+func (t ToGlacier) lowerPriority() {
+	if !t.Priority.enabled() {
+		return
+	}
+
+	priorityLock.Lock()
+	defer priorityLock.Unlock()
+
+	if err := setPriority(t.Priority); err != nil {
+		t.Logger.Warningf("toglacier: failed to lower the priority while building the archive. details: %s", err)
+	}
+}
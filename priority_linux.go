@@ -0,0 +1,88 @@
+// +build linux
+
+package toglacier
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioClassBE    = 2
+	ioprioClassIdle  = 3
+)
+
+// setPriority lowers the CPU and I/O priority of all threads of the process,
+// as in Linux they are scheduled independently. New threads inherit the
+// priority of the thread that created them.
+func setPriority(p Priority) error {
+	pid := os.Getpid()
+
+	// the getpriority system call returns 20 - nice to avoid negative values
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, pid)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	originalNice := 20 - prio
+
+	originalIOPrio, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(pid), 0)
+	if errno != 0 {
+		return errors.WithStack(errno)
+	}
+
+	var ioprio uintptr
+	switch p.IOClass {
+	case IOClassBestEffort:
+		ioprio = ioprioClassBE<<ioprioClassShift | uintptr(p.IOLevel)
+	case IOClassIdle:
+		ioprio = ioprioClassIdle << ioprioClassShift
+	}
+
+	// a nice value lower than the current one would raise the priority
+	nice := p.Nice
+	if nice < originalNice {
+		nice = originalNice
+	}
+
+	if p.IOClass == "" {
+		ioprio = originalIOPrio
+	}
+
+	return errors.WithStack(applyPriority(nice, ioprio))
+}
+
+// applyPriority sets the nice value and the I/O priority of all threads of the
+// process. Threads that finished in the meantime are ignored. A failure in one
+// of the priorities doesn't stop the other from being applied, and the first
+// error is returned.
+func applyPriority(nice int, ioprio uintptr) error {
+	tasks, err := ioutil.ReadDir("/proc/self/task")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var firstErr error
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, nice); err != nil && err != syscall.ESRCH && firstErr == nil {
+			firstErr = errors.WithStack(err)
+		}
+
+		_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprio)
+		if errno != 0 && errno != syscall.ESRCH && firstErr == nil {
+			firstErr = errors.WithStack(errno)
+		}
+	}
+
+	return firstErr
+}
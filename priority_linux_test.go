@@ -0,0 +1,106 @@
+// +build linux
+
+package toglacier_test
+
+import (
+	"context"
+	"regexp"
+	"syscall"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_Backup_Priority(t *testing.T) {
+	ioprio := func() uintptr {
+		value, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_GET, 1, uintptr(syscall.Gettid()), 0)
+		if errno != 0 {
+			t.Fatalf("error retrieving the I/O priority. details: %s", errno)
+		}
+		return value
+	}
+
+	nice := func() int {
+		prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, syscall.Gettid())
+		if err != nil {
+			t.Fatalf("error retrieving the priority. details: %s", err)
+		}
+		return 20 - prio
+	}
+
+	originalNice := nice()
+	originalIOPrio := ioprio()
+
+	scenarios := []struct {
+		description    string
+		priority       toglacier.Priority
+		expectedNice   int
+		expectedIOPrio uintptr
+	}{
+		{
+			description:    "it should not change the priority when not defined",
+			expectedNice:   originalNice,
+			expectedIOPrio: originalIOPrio,
+		},
+		{
+			description: "it should lower the I/O priority inside the best-effort class",
+			priority: toglacier.Priority{
+				IOClass: toglacier.IOClassBestEffort,
+				IOLevel: 7,
+			},
+			expectedNice:   originalNice,
+			expectedIOPrio: 2<<13 | 7,
+		},
+		{
+			// the priority is never raised back, so this scenario must be the last
+			// one
+			description: "it should lower the priority while building the archive",
+			priority: toglacier.Priority{
+				Nice:    10,
+				IOClass: toglacier.IOClassIdle,
+			},
+			expectedNice:   10,
+			expectedIOPrio: 3 << 13,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Archive: mockArchive{
+					mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+						if n := nice(); n != scenario.expectedNice {
+							t.Errorf("unexpected nice value %d while building the archive", n)
+						}
+
+						if p := ioprio(); p != scenario.expectedIOPrio {
+							t.Errorf("unexpected I/O priority %d while building the archive", p)
+						}
+
+						return "", nil, nil
+					},
+				},
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return nil, nil
+					},
+				},
+				Priority: scenario.priority,
+				Logger: mockLogger{
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			if err := toGlacier.Backup([]string{"/data"}, "", 0, nil, ""); err != nil {
+				t.Fatalf("unexpected error. details: %s", err)
+			}
+
+			if p := ioprio(); p != scenario.expectedIOPrio {
+				t.Errorf("I/O priority %d not kept after building the archive", p)
+			}
+		})
+	}
+}
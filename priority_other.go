@@ -0,0 +1,14 @@
+// +build !linux
+
+package toglacier
+
+import (
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// setPriority isn't supported outside Linux.
+func setPriority(p Priority) error {
+	return errors.Errorf("priority controls not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,13 @@
+package toglacier
+
+import "github.com/pkg/errors"
+
+// writable checks if the action can change the backups in the cloud. In the
+// read-only mode an Error type encapsulated in a traceable error is returned.
+func (t ToGlacier) writable(action string) error {
+	if !t.ReadOnly {
+		return nil
+	}
+
+	return errors.WithStack(newError(nil, ErrorCodeReadOnly, errors.Errorf("%s isn't allowed", action)))
+}
@@ -0,0 +1,114 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rafaeljusto/toglacier"
+)
+
+func TestToGlacier_ReadOnly(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		action        func(toGlacier toglacier.ToGlacier) error
+		expectedError error
+	}{
+		{
+			description: "it should refuse to send a backup",
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.Backup([]string{"data"}, "", 0, nil, "")
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeReadOnly,
+				Err:  errors.New("backup isn't allowed"),
+			},
+		},
+		{
+			description: "it should refuse to send a stream",
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.BackupStream(strings.NewReader("data"), "dump", "", "")
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeReadOnly,
+				Err:  errors.New("backup isn't allowed"),
+			},
+		},
+		{
+			description: "it should refuse to send the spooled archives",
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.SendSpooled("")
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeReadOnly,
+				Err:  errors.New("send spooled archives isn't allowed"),
+			},
+		},
+		{
+			description: "it should refuse to remove backups",
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.RemoveBackups("AWSID123")
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeReadOnly,
+				Err:  errors.New("remove backups isn't allowed"),
+			},
+		},
+		{
+			description: "it should refuse to remove old backups",
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.RemoveOldBackups(10, "")
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeReadOnly,
+				Err:  errors.New("remove old backups isn't allowed"),
+			},
+		},
+		{
+			description: "it should refuse to empty the trash",
+			action: func(toGlacier toglacier.ToGlacier) error {
+				_, err := toGlacier.EmptyTrash()
+				return err
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeReadOnly,
+				Err:  errors.New("empty trash isn't allowed"),
+			},
+		},
+		{
+			description: "it should refuse to lock the vault",
+			action: func(toGlacier toglacier.ToGlacier) error {
+				_, err := toGlacier.LockVault("{}")
+				return err
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeReadOnly,
+				Err:  errors.New("lock vault isn't allowed"),
+			},
+		},
+		{
+			description: "it should keep the state in the cloud",
+			action: func(toGlacier toglacier.ToGlacier) error {
+				return toGlacier.SaveState("")
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			// the cloud and the local storage must not be used, so any call
+			// panics
+			toGlacier := toglacier.ToGlacier{
+				Context:  context.Background(),
+				Cloud:    mockCloud{},
+				Storage:  mockStorage{},
+				ReadOnly: true,
+			}
+
+			if err := scenario.action(toGlacier); !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package toglacier
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// Route stores the backups of the paths under a prefix in another place of the
+// cloud (e.g. a vault in another region), so different retention domains (like
+// photos and documents) can be managed separately.
+type Route struct {
+	// Prefix of the backup paths that are stored in this route.
+	Prefix string
+
+	// VaultName is the name of the vault (or bucket) where the route stores the
+	// backups. It is used to find the route of an existing backup.
+	VaultName string
+
+	// Cloud where the backups of this route are stored.
+	Cloud cloud.Cloud
+
+	// Notification overrides the e-mail settings for the backup reports of the
+	// route. When not defined the reports are sent with the other ones.
+	Notification *Notification
+}
+
+// Notification overrides the e-mail settings for the backup reports of a
+// route, so each route (e.g. family photos or server configs) can report to
+// different people.
+type Notification struct {
+	// To are the recipients of the reports. When empty the default recipients
+	// are used.
+	To []string
+
+	// Format of the reports. When empty the default format is used.
+	Format report.Format
+
+	// ErrorsOnly sends the reports only when a backup of the route fails,
+	// discarding them otherwise.
+	ErrorsOnly bool
+}
+
+// routeLocks avoids sending concurrent backups to the same route. The default
+// cloud uses the empty vault name.
+var routeLocks = struct {
+	sync.Mutex
+	locks map[string]*sync.Mutex
+}{
+	locks: make(map[string]*sync.Mutex),
+}
+
+// lockRoute waits until there's no other backup being sent to the route of the
+// vault, returning the function that releases the route.
+func lockRoute(vaultName string) (unlock func()) {
+	routeLocks.Lock()
+	lock, ok := routeLocks.locks[vaultName]
+	if !ok {
+		lock = new(sync.Mutex)
+		routeLocks.locks[vaultName] = lock
+	}
+	routeLocks.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// routedPaths are the backup paths sent to the same route. A nil route means
+// the default cloud.
+type routedPaths struct {
+	route          *Route
+	paths          []string
+	ignorePatterns []*regexp.Regexp
+}
+
+// routePaths groups the backup paths by the route with the longest matching
+// prefix. When a backup path contains a route prefix, the prefix is sent to
+// the route and ignored in the original backup path. The default cloud group
+// is always the first one.
+func (t ToGlacier) routePaths(backupPaths []string) []routedPaths {
+	if len(t.Routes) == 0 {
+		return []routedPaths{{paths: backupPaths}}
+	}
+
+	groups := make([]routedPaths, len(t.Routes)+1)
+	for i := range t.Routes {
+		groups[i+1].route = &t.Routes[i]
+	}
+
+	// route prefixes found inside the backup paths are also analyzed, as they
+	// could contain other route prefixes
+	pending := append([]string{}, backupPaths...)
+	added := make(map[string]bool)
+
+	for len(pending) > 0 {
+		path := filepath.Clean(pending[0])
+		pending = pending[1:]
+
+		if added[path] {
+			continue
+		}
+		added[path] = true
+
+		group, longestPrefix := 0, ""
+		for i, route := range t.Routes {
+			if routeContains(route.Prefix, path) && len(route.Prefix) > len(longestPrefix) {
+				group, longestPrefix = i+1, route.Prefix
+			}
+		}
+
+		groups[group].paths = append(groups[group].paths, path)
+
+		for _, route := range t.Routes {
+			prefix := filepath.Clean(route.Prefix)
+			if prefix == path || !routeContains(path, prefix) {
+				continue
+			}
+
+			// the route prefix is inside the backup path, so it must be stored in
+			// the route and not in the original backup
+			groups[group].ignorePatterns = append(groups[group].ignorePatterns, routePattern(prefix))
+			pending = append(pending, prefix)
+		}
+	}
+
+	var routed []routedPaths
+	for _, group := range groups {
+		if len(group.paths) > 0 {
+			routed = append(routed, group)
+		}
+	}
+
+	return routed
+}
+
+// routeContains checks if the path is the prefix itself or is inside the
+// prefix directory.
+func routeContains(prefix, path string) bool {
+	prefix = filepath.Clean(prefix)
+	path = filepath.Clean(path)
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, string(os.PathSeparator))+string(os.PathSeparator))
+}
+
+func routePattern(prefix string) *regexp.Regexp {
+	prefix = regexp.QuoteMeta(filepath.Clean(prefix))
+	return regexp.MustCompile("^" + prefix + "($|" + regexp.QuoteMeta(string(os.PathSeparator)) + ")")
+}
+
+// vaultRoute returns the route that stores the backups of the vault. A nil
+// route means the default cloud.
+func (t ToGlacier) vaultRoute(vaultName string) *Route {
+	for i, route := range t.Routes {
+		if route.VaultName != "" && route.VaultName == vaultName {
+			return &t.Routes[i]
+		}
+	}
+
+	return nil
+}
+
+// vaultCloud returns the cloud where the backups of the vault are stored.
+func (t ToGlacier) vaultCloud(vaultName string) cloud.Cloud {
+	if route := t.vaultRoute(vaultName); route != nil {
+		return route.Cloud
+	}
+
+	return t.Cloud
+}
+
+// backupCloud returns the cloud where the backup is stored. Without routes
+// there's only the default cloud, so the local storage isn't checked.
+func (t ToGlacier) backupCloud(id string) (cloud.Cloud, error) {
+	if len(t.Routes) == 0 {
+		return t.Cloud, nil
+	}
+
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	backup, _ := backups.Search(id)
+	return t.vaultCloud(backup.Backup.VaultName), nil
+}
+
+// routeBackups keeps only the backups stored in the route. A nil route means
+// the default cloud.
+func (t ToGlacier) routeBackups(backups storage.Backups, route *Route) storage.Backups {
+	if len(t.Routes) == 0 {
+		return backups
+	}
+
+	routeBackups := make(storage.Backups, 0, len(backups))
+	for _, backup := range backups {
+		if t.vaultRoute(backup.Backup.VaultName) == route {
+			routeBackups = append(routeBackups, backup)
+		}
+	}
+
+	return routeBackups
+}
+
+// backupsByRoute groups the backups by the route where they are stored, keeping
+// the order. Without routes all backups are in the same group.
+func (t ToGlacier) backupsByRoute(backups storage.Backups) []storage.Backups {
+	if len(t.Routes) == 0 {
+		return []storage.Backups{backups}
+	}
+
+	groups := []storage.Backups{t.routeBackups(backups, nil)}
+	for i := range t.Routes {
+		groups = append(groups, t.routeBackups(backups, &t.Routes[i]))
+	}
+
+	return groups
+}
+
+// clouds returns the default cloud and the cloud of each route.
+func (t ToGlacier) clouds() []cloud.Cloud {
+	clouds := []cloud.Cloud{t.Cloud}
+	for _, route := range t.Routes {
+		clouds = append(clouds, route.Cloud)
+	}
+
+	return clouds
+}
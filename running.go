@@ -0,0 +1,127 @@
+package toglacier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RunningOperation is a backup or retrieval in progress, that can be
+// cancelled by its identifier.
+type RunningOperation struct {
+	// ID identifies the operation while it is running (e.g. “backup-1”). The
+	// identifiers are unique within the process.
+	ID string
+
+	// Action is what the operation is doing (e.g. “backup”).
+	Action string
+
+	// StartedAt is when the operation started.
+	StartedAt time.Time
+}
+
+// runningOperation is a running operation and the function that cancels its
+// context.
+type runningOperation struct {
+	RunningOperation
+	cancel context.CancelFunc
+}
+
+// Running keeps the backups and retrievals in progress, so a specific one can
+// be cancelled without stopping the others (e.g. a long retrieval started by
+// mistake while the scheduler is running). It is safe for concurrent use.
+type Running struct {
+	lock       sync.Mutex
+	sequence   int
+	operations map[string]runningOperation
+}
+
+// NewRunning initializes a registry without running operations.
+func NewRunning() *Running {
+	return &Running{
+		operations: make(map[string]runningOperation),
+	}
+}
+
+// start registers an operation, returning a context derived from ctx that is
+// cancelled when the operation is cancelled, and a function that must be
+// called when the operation finishes.
+func (r *Running) start(ctx context.Context, action string, startedAt time.Time) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.sequence++
+	id := fmt.Sprintf("%s-%d", action, r.sequence)
+	r.operations[id] = runningOperation{
+		RunningOperation: RunningOperation{
+			ID:        id,
+			Action:    action,
+			StartedAt: startedAt,
+		},
+		cancel: cancel,
+	}
+
+	return ctx, func() {
+		r.lock.Lock()
+		delete(r.operations, id)
+		r.lock.Unlock()
+		cancel()
+	}
+}
+
+// List returns the operations in progress sorted by the start date.
+func (r *Running) List() []RunningOperation {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	operations := make([]RunningOperation, 0, len(r.operations))
+	for _, operation := range r.operations {
+		operations = append(operations, operation.RunningOperation)
+	}
+
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].StartedAt.Equal(operations[j].StartedAt) {
+			return operations[i].ID < operations[j].ID
+		}
+		return operations[i].StartedAt.Before(operations[j].StartedAt)
+	})
+
+	return operations
+}
+
+// Cancel stops the operation with the given identifier, cancelling its
+// context. The operation is removed from the registry when it finishes. It
+// returns false when there's no running operation with the identifier.
+func (r *Running) Cancel(id string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	operation, ok := r.operations[id]
+	if !ok {
+		return false
+	}
+
+	operation.cancel()
+	return true
+}
+
+// startOperation registers the operation in the Running registry, returning
+// the context that must be used by the operation and the function that must
+// be called when it finishes. When there's no registry the operation can't be
+// cancelled individually.
+func (t ToGlacier) startOperation(action string) (context.Context, func()) {
+	if t.Running == nil {
+		return t.Context, func() {}
+	}
+
+	ctx := t.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return t.Running.start(ctx, action, t.now())
+}
@@ -0,0 +1,132 @@
+package toglacier_test
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_Running(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		cancelID      func([]toglacier.RunningOperation) string
+		expectedFound bool
+		expectedError error
+	}{
+		{
+			description: "it should cancel a running retrieval",
+			cancelID: func(operations []toglacier.RunningOperation) string {
+				if len(operations) != 1 {
+					return ""
+				}
+				return operations[0].ID
+			},
+			expectedFound: true,
+			expectedError: context.Canceled,
+		},
+		{
+			description: "it should ignore an unknown operation",
+			cancelID: func([]toglacier.RunningOperation) string {
+				return "backup-999"
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			running := toglacier.NewRunning()
+
+			var operations []toglacier.RunningOperation
+			var found bool
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Envelop: mockEnvelop{},
+				Cloud: runningCloud{
+					mockGet: func(ctx context.Context, ids ...string) (map[string]string, error) {
+						operations = running.List()
+						found = running.Cancel(scenario.cancelID(operations))
+
+						if ctx.Err() != nil {
+							return nil, ctx.Err()
+						}
+
+						f, err := ioutil.TempFile("", "toglacier-test")
+						if err != nil {
+							t.Fatalf("error creating temporary file. details: %s", err)
+						}
+						defer f.Close()
+
+						f.WriteString("content")
+						return map[string]string{ids[0]: f.Name()}, nil
+					},
+				},
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return storage.Backups{
+							{
+								Backup: cloud.Backup{ID: "123456", Stream: "db.sql"},
+								Info: archive.Info{
+									"db.sql": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusStream},
+								},
+							},
+						}, nil
+					},
+					mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+						return storage.RestoreProgress{}, nil
+					},
+					mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+						return nil
+					},
+					mockRemoveRestoreProgress: func(id string) error {
+						return nil
+					},
+				},
+				Logger: mockLogger{
+					mockDebugf:   func(format string, args ...interface{}) {},
+					mockInfof:    func(format string, args ...interface{}) {},
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+				Clock:   fakeClock{now: now},
+				Running: running,
+			}
+
+			err := toGlacier.RetrieveBackupStream("123456", "", ioutil.Discard)
+			if errors.Cause(err) != scenario.expectedError {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if len(operations) != 1 || operations[0].Action != "retrieve" || !operations[0].StartedAt.Equal(now) {
+				t.Errorf("unexpected running operations %v", operations)
+			}
+
+			if found != scenario.expectedFound {
+				t.Errorf("unexpected cancel result. expected “%t” and got “%t”", scenario.expectedFound, found)
+			}
+
+			if remaining := running.List(); len(remaining) > 0 {
+				t.Errorf("operations still running after the retrieval: %v", remaining)
+			}
+		})
+	}
+}
+
+// runningCloud is a cloud that exposes the context of the retrieval, so it can
+// detect when the operation is cancelled.
+type runningCloud struct {
+	mockCloud
+	mockGet func(ctx context.Context, ids ...string) (map[string]string, error)
+}
+
+func (r runningCloud) Get(ctx context.Context, ids ...string) (map[string]string, error) {
+	return r.mockGet(ctx, ids...)
+}
@@ -0,0 +1,228 @@
+package toglacier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultDockerImage is the image of the temporary container used to read the
+// Docker volumes, that needs only a tar command. The image is pinned by digest,
+// so a replaced tag can't change what reads the volumes.
+const DefaultDockerImage = "alpine:3.19.1@sha256:c5b1261d6d3e43071626931fc004f70149baeba2c8ec672bd4f27761f8e1ad6b"
+
+// sourceWaitDelay is the time to wait for the children of the command, that
+// could still hold the output, after the command is killed by the timeout.
+const sourceWaitDelay = time.Second
+
+// dockerKillTimeout limits the command that stops the temporary container
+// after the source times out.
+const dockerKillTimeout = 30 * time.Second
+
+// Source is a command whose standard output is added to the backup (e.g. a
+// database dump), so there's no need for wrapper scripts that run the command
+// before the backup.
+type Source struct {
+	// Name of the file where the output is stored, inside the SourcesDir.
+	Name string
+
+	// Command executed by the system shell (sh or cmd). Ignored when the
+	// DockerVolume is informed.
+	Command string
+
+	// DockerVolume is the name of a Docker volume whose content is stored as a
+	// tarball, read from a temporary container where the volume is mounted.
+	DockerVolume string
+
+	// Include limits the paths of the Docker volume that are stored, relative
+	// to the volume root. When empty the whole volume is stored.
+	Include []string
+
+	// Exclude are patterns (e.g. "*.log") of the Docker volume paths that
+	// aren't stored.
+	Exclude []string
+
+	// Timeout limits the command execution. When zero there's no limit.
+	Timeout time.Duration
+}
+
+// runSources executes the sources, returning the files with their output that
+// must be added to the backup paths. The files are replaced only when the
+// command succeeds.
+func (t ToGlacier) runSources() ([]string, error) {
+	if len(t.Sources) == 0 {
+		return nil, nil
+	}
+
+	// the outputs are usually database dumps, that only the current user can
+	// read
+	dir := t.sourcesDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.WithStack(newError(nil, ErrorCodeSource, err))
+	}
+
+	if err := privateDir(dir); err != nil {
+		return nil, errors.WithStack(newError(nil, ErrorCodeSource, err))
+	}
+
+	var paths []string
+	for _, source := range t.Sources {
+		path, err := t.runSource(dir, source)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+func (t ToGlacier) runSource(dir string, source Source) (string, error) {
+	if source.Name == "" && source.DockerVolume != "" {
+		source.Name = source.DockerVolume + ".tar"
+	}
+
+	if source.Name == "" || filepath.Base(source.Name) != source.Name {
+		return "", errors.WithStack(newError([]string{source.Name}, ErrorCodeSource, errors.New("invalid name")))
+	}
+
+	ctx := t.Context
+	if source.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, source.Timeout)
+		defer cancel()
+	}
+
+	output, err := ioutil.TempFile(dir, "."+source.Name+"-")
+	if err != nil {
+		return "", errors.WithStack(newError([]string{source.Name}, ErrorCodeSource, err))
+	}
+	defer os.Remove(output.Name())
+	defer output.Close()
+
+	t.Logger.Infof("toglacier: running source “%s”", source.Name)
+
+	var stderr bytes.Buffer
+	cmd := t.sourceCommand(ctx, source)
+	cmd.Stdout = output
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = sourceWaitDelay
+
+	if err = cmd.Run(); err != nil {
+		// killing the Docker client doesn't stop the container, that is removed
+		// after being killed
+		if source.DockerVolume != "" && ctx.Err() != nil {
+			t.killContainer(dockerContainerName(source))
+		}
+
+		if details := strings.TrimSpace(stderr.String()); details != "" {
+			err = errors.Errorf("%s (%s)", err, details)
+		}
+
+		return "", errors.WithStack(newError([]string{source.Name}, ErrorCodeSource, err))
+	}
+
+	if err = output.Close(); err != nil {
+		return "", errors.WithStack(newError([]string{source.Name}, ErrorCodeSource, err))
+	}
+
+	path := filepath.Join(dir, source.Name)
+	if err = os.Rename(output.Name(), path); err != nil {
+		return "", errors.WithStack(newError([]string{source.Name}, ErrorCodeSource, err))
+	}
+
+	return path, nil
+}
+
+// sourceCommand builds the command that writes the source content in the
+// standard output.
+func (t ToGlacier) sourceCommand(ctx context.Context, source Source) *exec.Cmd {
+	if source.DockerVolume != "" {
+		return exec.CommandContext(ctx, "docker", dockerVolumeArgs(t.dockerImage(), dockerContainerName(source), source)...)
+	}
+
+	return shellCommand(ctx, source.Command)
+}
+
+// dockerVolumeArgs builds the arguments of the Docker client to write a tarball
+// of the volume in the standard output. The volume is mounted read-only in a
+// temporary container, so the snapshot doesn't change it. The container is
+// named, so it can be killed after a timeout.
+func dockerVolumeArgs(image, name string, source Source) []string {
+	args := []string{
+		"run", "--rm", "--name", name, "--network", "none",
+		"--volume", source.DockerVolume + ":/volume:ro",
+		image,
+		"tar", "-c", "-f", "-", "-C", "/volume",
+	}
+
+	for _, exclude := range source.Exclude {
+		args = append(args, "--exclude="+exclude)
+	}
+
+	if len(source.Include) == 0 {
+		return append(args, ".")
+	}
+
+	return append(args, source.Include...)
+}
+
+// dockerContainerName returns the name of the temporary container that reads
+// the Docker volume.
+func dockerContainerName(source Source) string {
+	return fmt.Sprintf("toglacier-%d-%s", os.Getpid(), source.DockerVolume)
+}
+
+// killContainer stops the temporary container of a Docker volume source that
+// timed out. Failures are only logged, as the source already failed.
+func (t ToGlacier) killContainer(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerKillTimeout)
+	defer cancel()
+
+	if output, err := exec.CommandContext(ctx, "docker", "kill", name).CombinedOutput(); err != nil {
+		t.Logger.Warningf("toglacier: failed to kill the container “%s”. details: %s (%s)", name, err, strings.TrimSpace(string(output)))
+	}
+}
+
+// shellCommand builds the command to run in the system shell, so pipes and
+// redirections can be used (e.g. "pg_dump mydb | gzip").
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// dockerImage returns the image of the temporary container used to read the
+// Docker volumes.
+func (t ToGlacier) dockerImage() string {
+	if t.DockerImage == "" {
+		return DefaultDockerImage
+	}
+
+	return t.DockerImage
+}
+
+// sourcesDir returns the directory where the output of the sources is stored.
+// The same directory is used between backups, so unchanged outputs are
+// detected by the incremental backup. The directory in the system temporary
+// directory is only a fallback for library users, the tool stores the outputs
+// in its data directory.
+func (t ToGlacier) sourcesDir() string {
+	if t.SourcesDir == "" {
+		return filepath.Join(os.TempDir(), "toglacier-sources")
+	}
+
+	return t.SourcesDir
+}
@@ -0,0 +1,191 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_Backup_Sources(t *testing.T) {
+	sourcesDir, err := ioutil.TempDir("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary directory. details: %s", err)
+	}
+	defer os.RemoveAll(sourcesDir)
+
+	// the permissions of the other users must be removed
+	if err = os.Chmod(sourcesDir, 0755); err != nil {
+		t.Fatalf("error changing the temporary directory permissions. details: %s", err)
+	}
+
+	// fake Docker client that writes the received arguments, storing the killed
+	// containers in a file
+	binDir, err := ioutil.TempDir("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary directory. details: %s", err)
+	}
+	defer os.RemoveAll(binDir)
+
+	if err := ioutil.WriteFile(filepath.Join(binDir, "docker"), []byte(`#!/bin/sh
+case "$*" in
+kill*) echo "$@" >> "$0.kill"; exit 0;;
+*slow:/volume*) exec sleep 5;;
+esac
+echo "$@"
+`), 0755); err != nil {
+		t.Fatalf("error creating fake docker client. details: %s", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	scenarios := []struct {
+		description     string
+		sources         []toglacier.Source
+		dockerImage     string
+		expectedPaths   []string
+		expectedOutputs map[string]string
+		expectedKilled  string
+		expectedError   error
+	}{
+		{
+			description: "it should add the output of the sources to the backup paths",
+			sources: []toglacier.Source{
+				{Name: "db.sql", Command: "echo dump"},
+			},
+			expectedPaths: []string{"/data", filepath.Join(sourcesDir, "db.sql")},
+			expectedOutputs: map[string]string{
+				"db.sql": "dump\n",
+			},
+		},
+		{
+			description: "it should add a snapshot of the Docker volumes to the backup paths",
+			sources: []toglacier.Source{
+				{DockerVolume: "pgdata"},
+				{Name: "site.tar", DockerVolume: "www", Include: []string{"html", "conf"}, Exclude: []string{"*.log"}},
+			},
+			dockerImage: "busybox",
+			expectedPaths: []string{
+				"/data",
+				filepath.Join(sourcesDir, "pgdata.tar"),
+				filepath.Join(sourcesDir, "site.tar"),
+			},
+			expectedOutputs: map[string]string{
+				"pgdata.tar": fmt.Sprintf("run --rm --name toglacier-%d-pgdata --network none --volume pgdata:/volume:ro busybox tar -c -f - -C /volume .\n", os.Getpid()),
+				"site.tar":   fmt.Sprintf("run --rm --name toglacier-%d-www --network none --volume www:/volume:ro busybox tar -c -f - -C /volume --exclude=*.log html conf\n", os.Getpid()),
+			},
+		},
+		{
+			description: "it should detect when the source command fails",
+			sources: []toglacier.Source{
+				{Name: "db.sql", Command: "echo failure >&2; exit 3"},
+			},
+			expectedError: &toglacier.Error{
+				Paths: []string{"db.sql"},
+				Code:  toglacier.ErrorCodeSource,
+				Err:   errors.New("exit status 3 (failure)"),
+			},
+		},
+		{
+			description: "it should detect when the source command times out",
+			sources: []toglacier.Source{
+				{Name: "db.sql", Command: "exec sleep 5", Timeout: 10 * time.Millisecond},
+			},
+			expectedError: &toglacier.Error{
+				Paths: []string{"db.sql"},
+				Code:  toglacier.ErrorCodeSource,
+				Err:   errors.New("signal: killed"),
+			},
+		},
+		{
+			description: "it should kill the container when the Docker volume source times out",
+			sources: []toglacier.Source{
+				{DockerVolume: "slow", Timeout: 10 * time.Millisecond},
+			},
+			expectedKilled: fmt.Sprintf("kill toglacier-%d-slow\n", os.Getpid()),
+			expectedError: &toglacier.Error{
+				Paths: []string{"slow.tar"},
+				Code:  toglacier.ErrorCodeSource,
+				Err:   errors.New("signal: killed"),
+			},
+		},
+		{
+			description: "it should detect an invalid source name",
+			sources: []toglacier.Source{
+				{Name: "../db.sql", Command: "echo dump"},
+			},
+			expectedError: &toglacier.Error{
+				Paths: []string{"../db.sql"},
+				Code:  toglacier.ErrorCodeSource,
+				Err:   errors.New("invalid name"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var paths []string
+			os.Remove(filepath.Join(binDir, "docker.kill"))
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Archive: mockArchive{
+					mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+						paths = backupPaths
+						return "", nil, nil
+					},
+				},
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return nil, nil
+					},
+				},
+				Logger: mockLogger{
+					mockInfof: func(format string, args ...interface{}) {},
+				},
+				Sources:     scenario.sources,
+				SourcesDir:  sourcesDir,
+				DockerImage: scenario.dockerImage,
+			}
+
+			err := toGlacier.Backup([]string{"/data"}, "", 0, nil, "")
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedPaths, paths) {
+				t.Errorf("paths don't match. expected “%v” and got “%v”", scenario.expectedPaths, paths)
+			}
+
+			if info, err := os.Stat(sourcesDir); err != nil {
+				t.Fatalf("error checking the sources directory. details: %s", err)
+			} else if info.Mode().Perm() != 0700 {
+				t.Errorf("unexpected permissions “%s” of the sources directory", info.Mode().Perm())
+			}
+
+			for name, expectedOutput := range scenario.expectedOutputs {
+				content, err := ioutil.ReadFile(filepath.Join(sourcesDir, name))
+				if err != nil {
+					t.Fatalf("error reading the source output. details: %s", err)
+				}
+
+				if string(content) != expectedOutput {
+					t.Errorf("source outputs don't match. expected “%s” and got “%s”", expectedOutput, content)
+				}
+			}
+
+			if killed, _ := ioutil.ReadFile(filepath.Join(binDir, "docker.kill")); string(killed) != scenario.expectedKilled {
+				t.Errorf("killed containers don't match. expected “%s” and got “%s”", scenario.expectedKilled, killed)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package toglacier
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// SendSpooled sends the archives kept in the spool to the cloud of their
+// routes, in the order that they were spooled. When an archive still can't be
+// sent, the next archives of the same route wait in the spool for the next
+// attempt, and the problem is recorded in the reports. The copies that couldn't
+// be sent to the replica are also sent. On error it will return
+// an Error or storage.Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) SendSpooled(backupSecret string) (err error) {
+	if err := t.writable("send spooled archives"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if t.Spool == nil {
+		return nil
+	}
+
+	defer func() {
+		if err != nil {
+			t.events().OnError("send spooled", err)
+		}
+	}()
+
+	spooledArchives, err := t.Spool.List(t.Context)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var vaultNames []string
+	routes := make(map[string]bool)
+	for _, spooled := range spooledArchives {
+		if spooled.ReplicaOf == "" && !routes[spooled.VaultName] {
+			routes[spooled.VaultName] = true
+			vaultNames = append(vaultNames, spooled.VaultName)
+		}
+	}
+
+	for _, vaultName := range vaultNames {
+		if err := t.sendSpooledRoute(vaultName, backupSecret); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	t.flushReplicas(backupSecret)
+	return nil
+}
+
+// sendSpooledRoute sends the spooled archives of a route, waiting for any
+// backup being sent to the same route.
+func (t ToGlacier) sendSpooledRoute(vaultName, backupSecret string) error {
+	t.Cloud = t.vaultCloud(vaultName)
+
+	unlock := lockRoute(vaultName)
+	defer unlock()
+
+	var backups storage.Backups
+	err := t.retryStep("listing the backups", func() (err error) {
+		backups, err = t.listBackups(false, 0, false)
+		return err
+	})
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	t.flushSpool(vaultName, t.routeBackups(backups, t.vaultRoute(vaultName)), backupSecret)
+	return nil
+}
+
+// flushSpool sends the spooled archives of the route, stopping at the first
+// archive that can't be sent. The backups that were sent are added to the
+// route backups, and pending informs if there are archives of the route left
+// in the spool.
+func (t ToGlacier) flushSpool(vaultName string, backups storage.Backups, backupSecret string) (storage.Backups, bool) {
+	spooledArchives, err := t.Spool.List(t.Context)
+	if err != nil {
+		t.Logger.Warningf("toglacier: failed to list the spooled archives. details: %s", err)
+		return backups, false
+	}
+
+	spoolReport := report.NewSpool()
+	spoolReport.Pending = len(spooledArchives)
+
+	var routeArchives, pending bool
+	for _, spooled := range spooledArchives {
+		if spooled.VaultName != vaultName || spooled.ReplicaOf != "" {
+			continue
+		}
+
+		routeArchives = true
+		backup, sent := t.sendSpooledArchive(&spoolReport, spooled, backups, backupSecret)
+		if !sent {
+			pending = true
+			break
+		}
+
+		backups = append(backups, backup)
+		spoolReport.Sent = append(spoolReport.Sent, backup.Backup)
+		spoolReport.Pending--
+	}
+
+	if routeArchives {
+		t.reports().Add(spoolReport)
+	}
+
+	return backups, pending
+}
+
+// sendSpooledArchive sends the spooled archive to the cloud, completing the
+// backup like a new one, and removes it from the spool. It informs if the
+// archive was sent.
+func (t ToGlacier) sendSpooledArchive(spoolReport *report.Spool, spooled storage.SpooledArchive, backups storage.Backups, backupSecret string) (storage.Backup, bool) {
+	backupReport := report.NewSendBackup()
+
+	header, err := t.archiveHeader(spooled.Info, backupSecret)
+	if err != nil {
+		spoolReport.Errors = append(spoolReport.Errors, err)
+		return storage.Backup{}, false
+	}
+
+	timeMark := time.Now()
+	if backupReport.Backup, err = t.Cloud.Send(cloud.WithHeader(t.Context, header), spooled.Filename, spooled.Comment); err != nil {
+		spoolReport.Errors = append(spoolReport.Errors, err)
+		return storage.Backup{}, false
+	}
+	backupReport.Durations.Send = time.Now().Sub(timeMark)
+
+	// a backup that couldn't be saved in the local storage is kept in the
+	// journal, so the spooled archive isn't needed anymore
+	t.complete(&backupReport, spooled.Filename, header, spooled.Info, backups, backupSecret, spooled.Comment)
+	t.reports().Add(backupReport)
+
+	if err = t.Spool.Remove(t.Context, spooled.ID); err != nil {
+		t.Logger.Warningf("toglacier: failed to remove the spooled archive “%s” after sending it as backup “%s”. details: %s", spooled.ID, backupReport.Backup.ID, err)
+		spoolReport.Errors = append(spoolReport.Errors, err)
+	}
+
+	return storage.Backup{Backup: backupReport.Backup, Info: spooled.Info}, true
+}
+
+// flushReplicas sends to the replica the copies of the backups that couldn't be
+// sent with them, filling the copy identifier of the backups in the local
+// storage. The copies that still can't be sent stay in the spool for the next
+// attempt.
+func (t ToGlacier) flushReplicas(backupSecret string) {
+	if t.Replica == nil {
+		return
+	}
+
+	spooledArchives, err := t.Spool.List(t.Context)
+	if err != nil {
+		t.Logger.Warningf("toglacier: failed to list the spooled archives. details: %s", err)
+		return
+	}
+
+	spoolReport := report.NewSpool()
+	spoolReport.Pending = len(spooledArchives)
+
+	var replicas bool
+	for _, spooled := range spooledArchives {
+		if spooled.ReplicaOf == "" {
+			continue
+		}
+
+		replicas = true
+		if t.sendSpooledReplica(&spoolReport, spooled, backupSecret) {
+			spoolReport.Pending--
+		}
+	}
+
+	if replicas {
+		t.reports().Add(spoolReport)
+	}
+}
+
+// sendSpooledReplica sends the spooled copy to the replica and stores the copy
+// identifier in its backup, removing the copy from the spool. When the backup
+// was removed meanwhile, the copy isn't needed anymore and is only removed
+// from the spool. It informs if the copy left the spool.
+func (t ToGlacier) sendSpooledReplica(spoolReport *report.Spool, spooled storage.SpooledArchive, backupSecret string) bool {
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		spoolReport.Errors = append(spoolReport.Errors, err)
+		return false
+	}
+
+	if backup, ok := backups.Search(spooled.ReplicaOf); ok {
+		header, err := t.archiveHeader(spooled.Info, backupSecret)
+		if err != nil {
+			spoolReport.Errors = append(spoolReport.Errors, err)
+			return false
+		}
+
+		replicaBackup, err := t.Replica.Send(cloud.WithHeader(t.Context, header), spooled.Filename, spooled.Comment)
+		if err != nil {
+			spoolReport.Errors = append(spoolReport.Errors, err)
+			return false
+		}
+
+		backup.Backup.ReplicaID = replicaBackup.ID
+		if err = t.Storage.Save(t.Context, backup); err != nil {
+			spoolReport.Errors = append(spoolReport.Errors, err)
+			return false
+		}
+		spoolReport.Sent = append(spoolReport.Sent, replicaBackup)
+	} else {
+		t.Logger.Warningf("toglacier: backup “%s” not found, discarding its spooled copy “%s”", spooled.ReplicaOf, spooled.ID)
+	}
+
+	if err = t.Spool.Remove(t.Context, spooled.ID); err != nil {
+		t.Logger.Warningf("toglacier: failed to remove the spooled copy “%s” after sending it to the replica. details: %s", spooled.ID, err)
+		spoolReport.Errors = append(spoolReport.Errors, err)
+	}
+
+	return true
+}
+
+// spoolArchive keeps the archive in the spool to be sent later, recording it
+// in the reports. The sendErr is the reason why the archive wasn't sent, or nil
+// when it waits for older archives of the same route.
+func (t ToGlacier) spoolArchive(filename string, spooled storage.SpooledArchive, sendErr error) error {
+	spoolReport := report.NewSpool()
+	defer func() {
+		t.reports().Add(spoolReport)
+	}()
+
+	if sendErr != nil {
+		t.Logger.Warningf("toglacier: failed to send the archive, keeping it in the spool. details: %s", sendErr)
+	}
+
+	spooled.CreatedAt = t.now()
+	spooled, err := t.Spool.Add(t.Context, filename, spooled)
+
+	if err != nil {
+		spoolReport.Errors = append(spoolReport.Errors, err)
+		return errors.WithStack(err)
+	}
+
+	spoolReport.Spooled = append(spoolReport.Spooled, spooled.ID)
+	if spooledArchives, err := t.Spool.List(t.Context); err == nil {
+		spoolReport.Pending = len(spooledArchives)
+	}
+
+	return nil
+}
@@ -0,0 +1,399 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_BackupStream_Spool(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		cloud         cloud.Cloud
+		replica       cloud.Cloud
+		spool         func(added *[]storage.SpooledArchive) storage.Spool
+		expected      []storage.SpooledArchive
+		expectedError error
+	}{
+		{
+			description: "it should spool the archive when it can't be sent",
+			cloud: mockCloud{
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{}, errors.New("connection error")
+				},
+			},
+			spool: func(added *[]storage.SpooledArchive) storage.Spool {
+				return mockSpool{
+					mockAdd: func(filename string, spooled storage.SpooledArchive) (storage.SpooledArchive, error) {
+						content, err := ioutil.ReadFile(filename)
+						if err != nil {
+							t.Fatalf("error reading the spooled archive. details: %s", err)
+						}
+
+						if string(content) != "file2 test" {
+							t.Errorf("unexpected archive content “%s”", content)
+						}
+
+						*added = append(*added, spooled)
+						spooled.ID = "1"
+						return spooled, nil
+					},
+					mockList: func() ([]storage.SpooledArchive, error) {
+						return *added, nil
+					},
+				}
+			},
+			expected: []storage.SpooledArchive{
+				{
+					Comment:   "nightly dump",
+					CreatedAt: now,
+					Info: archive.Info{
+						"db.sql": archive.ItemInfo{
+							Status:   archive.ItemInfoStatusStream,
+							Checksum: "xZzITM+6yGsa9masWjGdi+yAA0DlqCzTf/1795fy5Pk=",
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "it should spool the archive while there're older archives in the spool",
+			cloud: mockCloud{
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					if comment != "old dump" {
+						t.Errorf("archive sent before the older spooled archive")
+					}
+					return cloud.Backup{}, errors.New("connection error")
+				},
+			},
+			spool: func(added *[]storage.SpooledArchive) storage.Spool {
+				return mockSpool{
+					mockAdd: func(filename string, spooled storage.SpooledArchive) (storage.SpooledArchive, error) {
+						*added = append(*added, spooled)
+						spooled.ID = "2"
+						return spooled, nil
+					},
+					mockList: func() ([]storage.SpooledArchive, error) {
+						return append([]storage.SpooledArchive{{ID: "1", Comment: "old dump"}}, *added...), nil
+					},
+				}
+			},
+			expected: []storage.SpooledArchive{
+				{
+					Comment:   "nightly dump",
+					CreatedAt: now,
+					Info: archive.Info{
+						"db.sql": archive.ItemInfo{
+							Status:   archive.ItemInfoStatusStream,
+							Checksum: "xZzITM+6yGsa9masWjGdi+yAA0DlqCzTf/1795fy5Pk=",
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "it should spool the copy that can't be sent to the replica",
+			cloud: mockCloud{
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "AWS123", CreatedAt: now, VaultName: "test", Comment: comment}, nil
+				},
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{}, errors.New("connection error")
+				},
+			},
+			replica: mockCloud{
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{}, errors.New("connection error")
+				},
+			},
+			spool: func(added *[]storage.SpooledArchive) storage.Spool {
+				return mockSpool{
+					mockAdd: func(filename string, spooled storage.SpooledArchive) (storage.SpooledArchive, error) {
+						*added = append(*added, spooled)
+						spooled.ID = "1"
+						return spooled, nil
+					},
+					mockList: func() ([]storage.SpooledArchive, error) {
+						return *added, nil
+					},
+				}
+			},
+			expected: []storage.SpooledArchive{
+				{
+					Comment:   "nightly dump",
+					ReplicaOf: "AWS123",
+					CreatedAt: now,
+					Info: archive.Info{
+						"db.sql": archive.ItemInfo{
+							ID:       "AWS123",
+							Status:   archive.ItemInfoStatusStream,
+							Checksum: "xZzITM+6yGsa9masWjGdi+yAA0DlqCzTf/1795fy5Pk=",
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "it should detect when the spool is full",
+			cloud: mockCloud{
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{}, errors.New("connection error")
+				},
+			},
+			spool: func(added *[]storage.SpooledArchive) storage.Spool {
+				return mockSpool{
+					mockAdd: func(filename string, spooled storage.SpooledArchive) (storage.SpooledArchive, error) {
+						return storage.SpooledArchive{}, &storage.Error{
+							Code: storage.ErrorCodeSpoolFull,
+							Err:  errors.New("20 bytes needed and the limit is 10 bytes"),
+						}
+					},
+					mockList: func() ([]storage.SpooledArchive, error) {
+						return nil, nil
+					},
+				}
+			},
+			expectedError: &storage.Error{
+				Code: storage.ErrorCodeSpoolFull,
+				Err:  errors.New("20 bytes needed and the limit is 10 bytes"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var added []storage.SpooledArchive
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud,
+				Replica: scenario.replica,
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return nil, nil
+					},
+					mockSave: func(b storage.Backup) error {
+						return nil
+					},
+				},
+				Spool: scenario.spool(&added),
+				Clock: fakeClock{now: now},
+				Logger: mockLogger{
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			err := toGlacier.BackupStream(strings.NewReader("file2 test"), "db.sql", "", "nightly dump")
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, added) {
+				t.Errorf("spooled archives don't match.\n%s", Diff(scenario.expected, added))
+			}
+		})
+	}
+}
+
+func TestToGlacier_SendSpooled(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description     string
+		spooled         []storage.SpooledArchive
+		backups         storage.Backups
+		cloud           func(t *testing.T) cloud.Cloud
+		replica         cloud.Cloud
+		expectedSaved   []string
+		expectedRemoved []string
+		expectedError   error
+	}{
+		{
+			description: "it should send the spooled archives in order",
+			spooled: []storage.SpooledArchive{
+				{ID: "1", Comment: "first", Info: archive.Info{"/data/file1.txt": archive.ItemInfo{Status: archive.ItemInfoStatusNew}}},
+				{ID: "2", Comment: "second", Info: archive.Info{"/data/file2.txt": archive.ItemInfo{Status: archive.ItemInfoStatusNew}}},
+			},
+			cloud: func(t *testing.T) cloud.Cloud {
+				return mockCloud{
+					mockSend: func(filename, comment string) (cloud.Backup, error) {
+						return cloud.Backup{ID: "AWS-" + comment, CreatedAt: now, VaultName: "test", Comment: comment}, nil
+					},
+					mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+						return cloud.Backup{}, errors.New("connection error")
+					},
+				}
+			},
+			expectedSaved:   []string{"AWS-first", "AWS-second"},
+			expectedRemoved: []string{"1", "2"},
+		},
+		{
+			description: "it should keep the spooled archives after a failure",
+			spooled: []storage.SpooledArchive{
+				{ID: "1", Comment: "first", Info: archive.Info{"/data/file1.txt": archive.ItemInfo{Status: archive.ItemInfoStatusNew}}},
+				{ID: "2", Comment: "second", Info: archive.Info{"/data/file2.txt": archive.ItemInfo{Status: archive.ItemInfoStatusNew}}},
+			},
+			cloud: func(t *testing.T) cloud.Cloud {
+				return mockCloud{
+					mockSend: func(filename, comment string) (cloud.Backup, error) {
+						if comment == "second" {
+							t.Errorf("archive sent after a failure")
+						}
+						return cloud.Backup{}, errors.New("connection error")
+					},
+				}
+			},
+		},
+		{
+			description: "it should send the spooled copies to the replica",
+			spooled: []storage.SpooledArchive{
+				{ID: "1", ReplicaOf: "AWS1", Comment: "first"},
+				{ID: "2", ReplicaOf: "AWS2", Comment: "removed"},
+			},
+			backups: storage.Backups{
+				{Backup: cloud.Backup{ID: "AWS1", CreatedAt: now, VaultName: "test"}},
+			},
+			cloud: func(t *testing.T) cloud.Cloud {
+				return mockCloud{}
+			},
+			replica: mockCloud{
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					if comment == "removed" {
+						t.Errorf("copy of a removed backup sent to the replica")
+					}
+					return cloud.Backup{ID: "REPLICA-" + comment, CreatedAt: now, VaultName: "replica", Comment: comment}, nil
+				},
+			},
+			expectedSaved:   []string{"AWS1:REPLICA-first"},
+			expectedRemoved: []string{"1", "2"},
+		},
+		{
+			description: "it should keep the spooled copies without blocking the archives",
+			spooled: []storage.SpooledArchive{
+				{ID: "1", ReplicaOf: "AWS1", Comment: "copy"},
+				{ID: "2", Comment: "first", Info: archive.Info{"/data/file1.txt": archive.ItemInfo{Status: archive.ItemInfoStatusNew}}},
+			},
+			backups: storage.Backups{
+				{Backup: cloud.Backup{ID: "AWS1", CreatedAt: now, VaultName: "test"}},
+			},
+			cloud: func(t *testing.T) cloud.Cloud {
+				return mockCloud{
+					mockSend: func(filename, comment string) (cloud.Backup, error) {
+						return cloud.Backup{ID: "AWS-" + comment, CreatedAt: now, VaultName: "test", Comment: comment}, nil
+					},
+					mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+						return cloud.Backup{}, errors.New("connection error")
+					},
+				}
+			},
+			replica: mockCloud{
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					if comment == "first" {
+						return cloud.Backup{ID: "REPLICA-" + comment, CreatedAt: now, VaultName: "replica", Comment: comment}, nil
+					}
+					return cloud.Backup{}, errors.New("connection error")
+				},
+			},
+			expectedSaved:   []string{"AWS-first:REPLICA-first"},
+			expectedRemoved: []string{"2"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			for i := range scenario.spooled {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary file. details: %s", err)
+				}
+				f.Close()
+				defer os.Remove(f.Name())
+
+				scenario.spooled[i].Filename = f.Name()
+			}
+
+			var saved, removed []string
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud(t),
+				Replica: scenario.replica,
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return scenario.backups, nil
+					},
+					mockSave: func(b storage.Backup) error {
+						for _, itemInfo := range b.Info {
+							if itemInfo.ID != b.Backup.ID {
+								t.Errorf("unexpected archive “%s” for backup “%s”", itemInfo.ID, b.Backup.ID)
+							}
+						}
+
+						id := b.Backup.ID
+						if b.Backup.ReplicaID != "" {
+							id += ":" + b.Backup.ReplicaID
+						}
+
+						saved = append(saved, id)
+						return nil
+					},
+				},
+				Spool: mockSpool{
+					mockList: func() ([]storage.SpooledArchive, error) {
+						return scenario.spooled, nil
+					},
+					mockRemove: func(id string) error {
+						removed = append(removed, id)
+						return nil
+					},
+				},
+				Logger: mockLogger{
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			err := toGlacier.SendSpooled("")
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedSaved, saved) {
+				t.Errorf("saved backups don't match.\n%s", Diff(scenario.expectedSaved, saved))
+			}
+
+			if !reflect.DeepEqual(scenario.expectedRemoved, removed) {
+				t.Errorf("removed archives don't match.\n%s", Diff(scenario.expectedRemoved, removed))
+			}
+		})
+	}
+}
+
+type mockSpool struct {
+	mockAdd    func(filename string, spooled storage.SpooledArchive) (storage.SpooledArchive, error)
+	mockList   func() ([]storage.SpooledArchive, error)
+	mockRemove func(id string) error
+}
+
+func (m mockSpool) Add(ctx context.Context, filename string, spooled storage.SpooledArchive) (storage.SpooledArchive, error) {
+	return m.mockAdd(filename, spooled)
+}
+
+func (m mockSpool) List(ctx context.Context) ([]storage.SpooledArchive, error) {
+	return m.mockList()
+}
+
+func (m mockSpool) Remove(ctx context.Context, id string) error {
+	return m.mockRemove(id)
+}
@@ -0,0 +1,387 @@
+package toglacier
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// state is everything kept in the local storage, stored in the cloud in the
+// stateless mode.
+type state struct {
+	Backups       storage.Backups                    `json:"backups"`
+	InventoryDate time.Time                          `json:"inventoryDate"`
+	Paused        bool                               `json:"paused,omitempty"`
+	PausedUntil   time.Time                          `json:"pausedUntil"`
+	Pins          []storage.Pin                      `json:"pins,omitempty"`
+	Tags          []storage.Tag                      `json:"tags,omitempty"`
+	Trash         []storage.TrashedBackup            `json:"trash,omitempty"`
+	Journal       storage.Backups                    `json:"journal,omitempty"`
+	Restores      map[string]storage.RestoreProgress `json:"restores,omitempty"`
+}
+
+// LoadState replaces the local storage with the state stored in the cloud by
+// SaveState, so toglacier can run without a persistent disk (e.g. Kubernetes
+// CronJob). Besides the backups, the inventory date, the pause, the pins, the
+// tags, the trash, the journal and the restore progresses are replaced. When
+// there's no state in the cloud yet the local storage is kept as it is. If the
+// state is encrypted it can be decrypted if the backupSecret is informed. The
+// replacement is recorded in the audit log of the destructive operations. On
+// error it will return an Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) LoadState(backupSecret string) (err error) {
+	cloudState, ok := t.Cloud.(cloud.State)
+	if !ok {
+		return errors.WithStack(newError(nil, ErrorCodeStateNotSupported, nil))
+	}
+
+	filename, err := cloudState.LoadState(t.Context)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if filename == "" {
+		t.Logger.Infof("toglacier: no state found in the cloud, keeping the local storage")
+		return nil
+	}
+
+	var loaded []string
+	defer func() {
+		t.recordOperation("load state", loaded, err)
+	}()
+
+	var content json.RawMessage
+	if err = t.readJSON(filename, backupSecret, &content, ErrorCodeDecodingState); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var s state
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "[") {
+		// states of older versions only have the backups
+		err = json.Unmarshal(content, &s.Backups)
+	} else {
+		err = json.Unmarshal(content, &s)
+	}
+
+	if err != nil {
+		return errors.WithStack(newError(nil, ErrorCodeDecodingState, err))
+	}
+
+	for _, backup := range s.Backups {
+		loaded = append(loaded, backup.Backup.ID)
+	}
+
+	if err = t.loadStateBackups(s); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = t.loadStateDetails(s); err != nil {
+		return errors.WithStack(err)
+	}
+
+	t.Logger.Infof("toglacier: local storage loaded from the cloud with %d backups", len(s.Backups))
+	return nil
+}
+
+// loadStateBackups replaces the backups of the local storage, the pending
+// backups of the journal and the trashed backups with the ones of the state.
+func (t ToGlacier) loadStateBackups(s state) error {
+	localBackups, err := t.Storage.List(t.Context)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, localBackup := range localBackups {
+		if _, ok := s.Backups.Search(localBackup.Backup.ID); ok {
+			continue
+		}
+
+		if err = t.Storage.Remove(t.Context, localBackup.Backup.ID); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	for _, backup := range s.Backups {
+		if err = t.Storage.Save(t.Context, backup); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if t.Journal != nil {
+		pending, err := t.Journal.List(t.Context)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, backup := range pending {
+			if _, ok := s.Journal.Search(backup.Backup.ID); !ok {
+				if err = t.Journal.Remove(t.Context, backup.Backup.ID); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+
+		for _, backup := range s.Journal {
+			if _, ok := pending.Search(backup.Backup.ID); !ok {
+				if err = t.Journal.Save(t.Context, backup); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+	}
+
+	if t.Trash != nil {
+		trashed, err := t.Trash.List(t.Context)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		stateTrashed := make(map[string]bool)
+		for _, trashedBackup := range s.Trash {
+			stateTrashed[trashedBackup.ID] = true
+		}
+
+		localTrashed := make(map[string]bool)
+		for _, trashedBackup := range trashed {
+			localTrashed[trashedBackup.ID] = true
+
+			if !stateTrashed[trashedBackup.ID] {
+				if err = t.Trash.Remove(t.Context, trashedBackup.ID); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+
+		for _, trashedBackup := range s.Trash {
+			if !localTrashed[trashedBackup.ID] {
+				if err = t.Trash.Add(t.Context, trashedBackup); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadStateDetails replaces the inventory date, the pause, the pins, the tags
+// and the restore progresses of the local storage with the ones of the state.
+// Only the pins that changed are saved, as each change is recorded in the pin
+// audit trail.
+func (t ToGlacier) loadStateDetails(s state) error {
+	if !s.InventoryDate.IsZero() {
+		if err := t.Storage.SaveInventoryDate(t.Context, s.InventoryDate); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if s.Paused {
+		if err := t.Storage.SavePause(t.Context, s.PausedUntil); err != nil {
+			return errors.WithStack(err)
+		}
+	} else if err := t.Storage.RemovePause(t.Context); err != nil {
+		return errors.WithStack(err)
+	}
+
+	pins, err := t.Storage.Pins(t.Context)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	localPins := make(map[string]storage.Pin)
+	for _, pin := range pins {
+		localPins[pin.ID] = pin
+	}
+
+	statePins := make(map[string]bool)
+	for _, pin := range s.Pins {
+		statePins[pin.ID] = true
+
+		if localPin, ok := localPins[pin.ID]; ok && localPin.Until.Equal(pin.Until) && localPin.User == pin.User &&
+			localPin.Reason == pin.Reason && localPin.CreatedAt.Equal(pin.CreatedAt) {
+			continue
+		}
+
+		if err = t.Storage.SavePin(t.Context, pin); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	for _, pin := range pins {
+		if statePins[pin.ID] {
+			continue
+		}
+
+		unpin := storage.Pin{
+			ID:        pin.ID,
+			User:      t.Command,
+			Reason:    "removed in the state loaded from the cloud",
+			CreatedAt: t.now(),
+		}
+
+		if err = t.Storage.RemovePin(t.Context, unpin); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if tagger, ok := t.Storage.(storage.Tagger); ok {
+		tags, err := tagger.Tags(t.Context)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, tag := range tags {
+			if err = tagger.RemoveTag(t.Context, tag.Name); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		for _, tag := range s.Tags {
+			if err = tagger.SaveTag(t.Context, tag); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+
+	if restoreLister, ok := t.Storage.(storage.RestoreLister); ok {
+		restores, err := restoreLister.RestoreProgresses(t.Context)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for id := range restores {
+			if _, ok := s.Restores[id]; !ok {
+				if err = t.Storage.RemoveRestoreProgress(t.Context, id); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+
+		for id, progress := range s.Restores {
+			if err = t.Storage.SaveRestoreProgress(t.Context, id, progress); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// SaveState stores the local storage in the cloud, replacing the previous
+// state, so it can be loaded by LoadState on the next run. Everything that
+// LoadState replaces is stored. The state is
+// encrypted if the backupSecret is informed. On error it will return an Error
+// type encapsulated in a traceable error. To retrieve the desired error you can
+// do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) SaveState(backupSecret string) error {
+	if t.ReadOnly {
+		// the state in the cloud is kept, as the changes in the local storage
+		// while restoring (e.g. the restore progress) aren't useful later
+		return nil
+	}
+
+	cloudState, ok := t.Cloud.(cloud.State)
+	if !ok {
+		return errors.WithStack(newError(nil, ErrorCodeStateNotSupported, nil))
+	}
+
+	s, err := t.currentState()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	filename, err := t.writeJSON(s, "toglacier-state-", backupSecret, ErrorCodeEncodingState)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(filename)
+
+	return errors.WithStack(cloudState.SaveState(t.Context, filename))
+}
+
+// currentState collects everything kept in the local storage.
+func (t ToGlacier) currentState() (state, error) {
+	var s state
+	var err error
+
+	if s.Backups, err = t.Storage.List(t.Context); err != nil {
+		return state{}, errors.WithStack(err)
+	}
+
+	if s.InventoryDate, err = t.Storage.InventoryDate(t.Context); err != nil {
+		return state{}, errors.WithStack(err)
+	}
+
+	if s.Paused, s.PausedUntil, err = t.Storage.Pause(t.Context); err != nil {
+		return state{}, errors.WithStack(err)
+	}
+
+	if s.Pins, err = t.Storage.Pins(t.Context); err != nil {
+		return state{}, errors.WithStack(err)
+	}
+
+	if tagger, ok := t.Storage.(storage.Tagger); ok {
+		if s.Tags, err = tagger.Tags(t.Context); err != nil {
+			return state{}, errors.WithStack(err)
+		}
+	}
+
+	if restoreLister, ok := t.Storage.(storage.RestoreLister); ok {
+		if s.Restores, err = restoreLister.RestoreProgresses(t.Context); err != nil {
+			return state{}, errors.WithStack(err)
+		}
+	}
+
+	if t.Journal != nil {
+		if s.Journal, err = t.Journal.List(t.Context); err != nil {
+			return state{}, errors.WithStack(err)
+		}
+	}
+
+	if t.Trash != nil {
+		if s.Trash, err = t.Trash.List(t.Context); err != nil {
+			return state{}, errors.WithStack(err)
+		}
+	}
+
+	return s, nil
+}
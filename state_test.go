@@ -0,0 +1,392 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_LoadState(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description     string
+		cloud           cloud.Cloud
+		storage         storage.Storage
+		expectedSaved   []string
+		expectedRemoved []string
+		expectedError   error
+	}{
+		{
+			description: "it should replace the local storage with the state of older versions",
+			cloud: mockStateCloud{
+				mockLoadState: func() (string, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					f.WriteString(`[{"Backup":{"ID":"123456","CreatedAt":"2017-09-14T10:30:00Z","VaultName":"test"}},` +
+						`{"Backup":{"ID":"123457","CreatedAt":"2017-09-14T10:30:00Z","VaultName":"test"}}]`)
+					return f.Name(), nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test"}},
+						{Backup: cloud.Backup{ID: "123455", CreatedAt: now, VaultName: "test"}},
+					}, nil
+				},
+			},
+			expectedSaved:   []string{"123456", "123457"},
+			expectedRemoved: []string{"123455"},
+		},
+		{
+			description: "it should keep the local storage when there's no state",
+			cloud: mockStateCloud{
+				mockLoadState: func() (string, error) {
+					return "", nil
+				},
+			},
+			storage: mockStorage{},
+		},
+		{
+			description:   "it should detect when the cloud doesn't support keeping the state",
+			cloud:         mockCloud{},
+			storage:       mockStorage{},
+			expectedError: &toglacier.Error{Code: toglacier.ErrorCodeStateNotSupported},
+		},
+		{
+			description: "it should detect an error while retrieving the state",
+			cloud: mockStateCloud{
+				mockLoadState: func() (string, error) {
+					return "", errors.New("connection error")
+				},
+			},
+			storage:       mockStorage{},
+			expectedError: errors.New("connection error"),
+		},
+		{
+			description: "it should detect an invalid state",
+			cloud: mockStateCloud{
+				mockLoadState: func() (string, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					f.WriteString("{{{")
+					return f.Name(), nil
+				},
+			},
+			storage: mockStorage{},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeDecodingState,
+				Err:  errors.New("invalid character '{' looking for beginning of object key string"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var saved, removed []string
+
+			s := scenario.storage.(mockStorage)
+			s.mockSave = func(b storage.Backup) error {
+				saved = append(saved, b.Backup.ID)
+				return nil
+			}
+			s.mockRemove = func(id string) error {
+				removed = append(removed, id)
+				return nil
+			}
+			s.mockRemovePause = func() error {
+				return nil
+			}
+			s.mockPins = func() ([]storage.Pin, error) {
+				return nil, nil
+			}
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud,
+				Storage: s,
+				Logger: mockLogger{
+					mockInfof:    func(format string, args ...interface{}) {},
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			err := toGlacier.LoadState("")
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedSaved, saved) {
+				t.Errorf("saved backups don't match. expected “%v” and got “%v”", scenario.expectedSaved, saved)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedRemoved, removed) {
+				t.Errorf("removed backups don't match. expected “%v” and got “%v”", scenario.expectedRemoved, removed)
+			}
+		})
+	}
+}
+
+func TestToGlacier_SaveState(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		cloud         cloud.Cloud
+		storage       storage.Storage
+		expectedError error
+	}{
+		{
+			description: "it should send the local storage to the cloud",
+			cloud: mockStateCloud{
+				mockSaveState: func(filename string) error {
+					content, err := ioutil.ReadFile(filename)
+					if err != nil {
+						t.Fatalf("error reading state file. details: %s", err)
+					}
+
+					expected := `{"backups":[{"Backup":{"ID":"123456","CreatedAt":"2017-09-14T10:30:00Z","Checksum":"","VaultName":"test","Size":0,"Duration":0,` +
+						`"Location":"","MachineID":"","Comment":"","ParityID":"","CatalogID":"","ReplicaID":"","Partial":false,"Stream":""},` +
+						`"Info":{"file1":{"ID":"","Status":"new","Checksum":""}}}],"inventoryDate":"2017-09-14T10:30:00Z","pausedUntil":"0001-01-01T00:00:00Z"}` + "\n"
+
+					if string(content) != expected {
+						t.Errorf("unexpected state content “%s”", content)
+					}
+
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test"},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{Status: archive.ItemInfoStatusNew},
+							},
+						},
+					}, nil
+				},
+			},
+		},
+		{
+			description:   "it should detect when the cloud doesn't support keeping the state",
+			cloud:         mockCloud{},
+			storage:       mockStorage{},
+			expectedError: &toglacier.Error{Code: toglacier.ErrorCodeStateNotSupported},
+		},
+		{
+			description: "it should detect an error while listing the local storage",
+			cloud:       mockStateCloud{},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("database corrupted")
+				},
+			},
+			expectedError: errors.New("database corrupted"),
+		},
+		{
+			description: "it should detect an error while sending the state",
+			cloud: mockStateCloud{
+				mockSaveState: func(filename string) error {
+					return errors.New("connection error")
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+			},
+			expectedError: errors.New("connection error"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			s := scenario.storage.(mockStorage)
+			s.mockInventoryDate = func() (time.Time, error) {
+				return now, nil
+			}
+			s.mockPause = func() (bool, time.Time, error) {
+				return false, time.Time{}, nil
+			}
+			s.mockPins = func() ([]storage.Pin, error) {
+				return nil, nil
+			}
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud,
+				Storage: s,
+			}
+
+			err := toGlacier.SaveState("")
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_StateRoundTrip(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarning:  func(args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	newToGlacier := func(dir string, stateCloud cloud.Cloud) toglacier.ToGlacier {
+		return toglacier.ToGlacier{
+			Context: context.Background(),
+			Cloud:   stateCloud,
+			Storage: storage.NewAuditFile(logger, path.Join(dir, "audit.log")),
+			Journal: storage.NewJournalFile(logger, path.Join(dir, "audit.log.journal")),
+			Trash:   storage.NewTrashFile(logger, path.Join(dir, "audit.log.trash")),
+			Logger:  logger,
+			Clock:   fakeClock{now: now},
+		}
+	}
+
+	var state []byte
+	stateCloud := mockStateCloud{
+		mockSaveState: func(filename string) error {
+			var err error
+			state, err = ioutil.ReadFile(filename)
+			return err
+		},
+		mockLoadState: func() (string, error) {
+			f, err := ioutil.TempFile("", "toglacier-test")
+			if err != nil {
+				return "", err
+			}
+			defer f.Close()
+
+			_, err = f.Write(state)
+			return f.Name(), err
+		},
+	}
+
+	sourceDir, err := ioutil.TempDir("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary directory. details: %s", err)
+	}
+	defer os.RemoveAll(sourceDir)
+
+	source := newToGlacier(sourceDir, stateCloud)
+	ctx := context.Background()
+
+	backup := storage.Backup{
+		Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS},
+		Info: archive.Info{
+			"file1": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusNew},
+		},
+	}
+	pending := storage.Backup{
+		Backup: cloud.Backup{ID: "123457", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS},
+	}
+	pin := storage.Pin{ID: "123456", User: "alice", Reason: "legal hold", CreatedAt: now}
+	tag := storage.Tag{Name: "pre-upgrade", ID: "123456", CreatedAt: now}
+	trashed := storage.TrashedBackup{ID: "123458", TrashedAt: now}
+	progress := storage.RestoreProgress{Extracted: map[string]bool{"123456": true}}
+
+	steps := []error{
+		source.Storage.Save(ctx, backup),
+		source.Storage.SaveInventoryDate(ctx, now),
+		source.Storage.SavePause(ctx, now.Add(time.Hour)),
+		source.Storage.SavePin(ctx, pin),
+		source.Storage.(storage.Tagger).SaveTag(ctx, tag),
+		source.Storage.SaveRestoreProgress(ctx, "123456", progress),
+		source.Journal.Save(ctx, pending),
+		source.Trash.Add(ctx, trashed),
+		source.SaveState(""),
+	}
+
+	for i, err := range steps {
+		if err != nil {
+			t.Fatalf("error preparing the source storage (step %d). details: %s", i, err)
+		}
+	}
+
+	targetDir, err := ioutil.TempDir("", "toglacier-test")
+	if err != nil {
+		t.Fatalf("error creating temporary directory. details: %s", err)
+	}
+	defer os.RemoveAll(targetDir)
+
+	target := newToGlacier(targetDir, stateCloud)
+	if err = target.LoadState(""); err != nil {
+		t.Fatalf("unexpected error loading the state. details: %s", err)
+	}
+
+	if backups, err := target.Storage.List(ctx); err != nil || !reflect.DeepEqual(storage.Backups{backup}, backups) {
+		t.Errorf("backups don't match (%v).\n%s", err, Diff(storage.Backups{backup}, backups))
+	}
+
+	if inventoryDate, err := target.Storage.InventoryDate(ctx); err != nil || !inventoryDate.Equal(now) {
+		t.Errorf("unexpected inventory date “%s” (%v)", inventoryDate, err)
+	}
+
+	if paused, until, err := target.Storage.Pause(ctx); err != nil || !paused || !until.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected pause “%t” until “%s” (%v)", paused, until, err)
+	}
+
+	if pins, err := target.Storage.Pins(ctx); err != nil || len(pins) != 1 || pins[0].ID != pin.ID || pins[0].User != pin.User {
+		t.Errorf("unexpected pins “%v” (%v)", pins, err)
+	}
+
+	if tags, err := target.Storage.(storage.Tagger).Tags(ctx); err != nil || len(tags) != 1 || tags[0].Name != tag.Name {
+		t.Errorf("unexpected tags “%v” (%v)", tags, err)
+	}
+
+	if restore, err := target.Storage.RestoreProgress(ctx, "123456"); err != nil || !reflect.DeepEqual(progress, restore) {
+		t.Errorf("unexpected restore progress “%v” (%v)", restore, err)
+	}
+
+	if journal, err := target.Journal.List(ctx); err != nil || len(journal) != 1 || journal[0].Backup.ID != pending.Backup.ID {
+		t.Errorf("unexpected journal “%v” (%v)", journal, err)
+	}
+
+	if trash, err := target.Trash.List(ctx); err != nil || len(trash) != 1 || trash[0].ID != trashed.ID {
+		t.Errorf("unexpected trash “%v” (%v)", trash, err)
+	}
+}
+
+// mockStateCloud is a cloud that also supports keeping the state of the local
+// storage.
+type mockStateCloud struct {
+	mockCloud
+	mockSaveState func(filename string) error
+	mockLoadState func() (string, error)
+}
+
+func (m mockStateCloud) SaveState(ctx context.Context, filename string) error {
+	return m.mockSaveState(filename)
+}
+
+func (m mockStateCloud) LoadState(ctx context.Context) (string, error) {
+	return m.mockLoadState()
+}
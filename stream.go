@@ -0,0 +1,196 @@
+package toglacier
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// BackupStream sends the data read from the stream to the cloud (e.g. the
+// output of a database dump), without building an archive. The name
+// identifies the stream in the backup information, and the backupSecret and the
+// comment work like in the Backup method. The stream is always sent to the
+// default cloud, and it isn't considered when building the next incremental
+// backups.
+func (t ToGlacier) BackupStream(r io.Reader, name, backupSecret, comment string) (err error) {
+	if err := t.writable("backup"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var finish func()
+	t.Context, finish = t.startOperation("backup")
+	defer finish()
+
+	t.recoverJournal()
+
+	defer func() {
+		if err != nil {
+			t.events().OnError("backup", err)
+		}
+	}()
+
+	unlock := lockRoute("")
+	defer unlock()
+
+	var backups storage.Backups
+	err = t.retryStep("listing the backups", func() (err error) {
+		backups, err = t.listBackups(false, 0, false)
+		return err
+	})
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	backupReport := report.NewSendBackup()
+	defer func() {
+		t.reports().Add(backupReport)
+	}()
+
+	t.events().OnBackupStart([]string{name})
+
+	timeMark := time.Now()
+	filename, checksum, err := storeStream(r)
+	if err != nil {
+		backupReport.Errors = append(backupReport.Errors, err)
+		return errors.WithStack(err)
+	}
+	defer os.Remove(filename)
+	backupReport.Durations.Build = time.Now().Sub(timeMark)
+
+	archiveInfo := archive.Info{
+		name: archive.ItemInfo{
+			Status:   archive.ItemInfoStatusStream,
+			Checksum: checksum,
+		},
+	}
+
+	return errors.WithStack(t.upload(&backupReport, filename, archiveInfo, t.routeBackups(backups, nil), backupSecret, "", comment))
+}
+
+// storeStream copies the stream to a temporary file, as the size of the data
+// must be known before sending it to the cloud. The SHA256 hash of the data
+// encoded in base64 is also returned.
+func storeStream(r io.Reader) (filename, checksum string, err error) {
+	file, err := ioutil.TempFile("", "toglacier-stream-")
+	if err != nil {
+		return "", "", errors.WithStack(newError(nil, ErrorCodeReadingStream, err))
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(file, hash), r); err != nil {
+		os.Remove(file.Name())
+		return "", "", errors.WithStack(newError(nil, ErrorCodeReadingStream, err))
+	}
+
+	return file.Name(), base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// RetrieveBackupStream recover a specific backup from the cloud and writes it
+// to the stream, decrypting it if the backupSecret is informed. The backup of a
+// stream is written as it was sent, and the backup of paths is written as the
+// archive (e.g. tarball) without the older archives referenced by an
+// incremental backup.
+func (t ToGlacier) RetrieveBackupStream(id, backupSecret string, w io.Writer) (err error) {
+	var finish func()
+	t.Context, finish = t.startOperation("retrieve")
+	defer finish()
+
+	defer func() {
+		if err != nil {
+			t.events().OnError("retrieve backup", err)
+		}
+	}()
+
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	selectedBackup, ok := backups.Search(id)
+	if !ok {
+		t.Logger.Warningf("toglacier: backup “%s” not found in local storage", id)
+	}
+
+	t.Cloud = t.vaultCloud(selectedBackup.Backup.VaultName)
+
+	progress, err := t.Storage.RestoreProgress(t.Context, id)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if progress.Downloaded == nil {
+		progress.Downloaded = make(map[string]string)
+	}
+
+	filenames, err := t.download(id, progress, backups, id)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	filename := filenames[id]
+	if _, t.Envelop, backupSecret, err = t.readHeader(filename, backupSecret); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if backupSecret != "" {
+		var decryptedFilename string
+
+		if decryptedFilename, err = t.Envelop.Decrypt(filename, backupSecret); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if err = os.Rename(decryptedFilename, filename); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	// the decrypted content doesn't have the format header
+	if err = writeStream(filename, backupSecret == "" && t.Header.Format != "", w); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// after writing the content we don't need the archive anymore, but if
+	// there's some error removing it we don't want to stop the process
+	if err = os.Remove(filename); err != nil {
+		t.Logger.Warningf("toglacier: failed to remove file “%s”. details: %s", filename, err)
+	}
+
+	return errors.WithStack(t.Storage.RemoveRestoreProgress(t.Context, id))
+}
+
+// writeStream copies the content of the file to the stream, skipping the format
+// header when requested.
+func writeStream(filename string, skipHeader bool, w io.Writer) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return errors.WithStack(newError(nil, ErrorCodeWritingStream, err))
+	}
+	defer file.Close()
+
+	if skipHeader {
+		_, size, err := archive.ReadHeader(filename)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if _, err = file.Seek(size, io.SeekStart); err != nil {
+			return errors.WithStack(newError(nil, ErrorCodeWritingStream, err))
+		}
+	}
+
+	if _, err = io.Copy(w, file); err != nil {
+		return errors.WithStack(newError(nil, ErrorCodeWritingStream, err))
+	}
+
+	return nil
+}
@@ -0,0 +1,263 @@
+package toglacier_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_BackupStream(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		stream        string
+		name          string
+		backupSecret  string
+		comment       string
+		envelop       archive.Envelop
+		cloud         cloud.Cloud
+		storage       storage.Storage
+		expectedError error
+	}{
+		{
+			description:  "it should backup correctly a stream",
+			stream:       "file2 test",
+			name:         "db.sql",
+			backupSecret: "1234567890123456",
+			comment:      "nightly dump",
+			envelop: mockEnvelop{
+				mockEncrypt: func(filename, secret string) (string, error) {
+					// the catalog is also encrypted
+					if strings.Contains(filename, "toglacier-stream-") {
+						content, err := ioutil.ReadFile(filename)
+						if err != nil {
+							t.Fatalf("error reading stream file. details: %s", err)
+						}
+
+						if string(content) != "file2 test" {
+							t.Errorf("unexpected stream content “%s”", content)
+						}
+					}
+
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					return f.Name(), nil
+				},
+			},
+			cloud: mockCloud{
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					if comment != "nightly dump" {
+						t.Errorf("unexpected comment “%s”", comment)
+					}
+
+					return cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test", Comment: comment}, nil
+				},
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123457"}, nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+				mockSave: func(b storage.Backup) error {
+					// the duration is measured while the backup runs
+					if b.Backup.Duration <= 0 {
+						t.Errorf("unexpected backup duration “%s”", b.Backup.Duration)
+					}
+					b.Backup.Duration = 0
+
+					expected := storage.Backup{
+						Backup: cloud.Backup{
+							ID:        "123456",
+							CreatedAt: now,
+							VaultName: "test",
+							Comment:   "nightly dump",
+							CatalogID: "123457",
+							Stream:    "db.sql",
+						},
+						Info: archive.Info{
+							"db.sql": archive.ItemInfo{
+								ID:     "123456",
+								Status: archive.ItemInfoStatusStream,
+								// echo -n "file2 test" | openssl dgst -binary -sha256 | openssl base64 -A
+								Checksum: "xZzITM+6yGsa9masWjGdi+yAA0DlqCzTf/1795fy5Pk=",
+							},
+						},
+					}
+
+					if !reflect.DeepEqual(expected, b) {
+						t.Errorf("backups don't match.\n%s", Diff(expected, b))
+					}
+
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should detect an error while sending the stream",
+			stream:      "file2 test",
+			name:        "db.sql",
+			cloud: mockCloud{
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{}, errors.New("connection error")
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+			},
+			expectedError: errors.New("connection error"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Envelop: scenario.envelop,
+				Cloud:   scenario.cloud,
+				Storage: scenario.storage,
+				Logger: mockLogger{
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			err := toGlacier.BackupStream(strings.NewReader(scenario.stream), scenario.name, scenario.backupSecret, scenario.comment)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_RetrieveBackupStream(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		id            string
+		backupSecret  string
+		envelop       archive.Envelop
+		cloud         cloud.Cloud
+		storage       storage.Storage
+		expected      string
+		expectedError error
+	}{
+		{
+			description:  "it should retrieve a backup to the stream",
+			id:           "123456",
+			backupSecret: "1234567890123456",
+			envelop: mockEnvelop{
+				mockDecrypt: func(encryptedFilename, secret string) (string, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					f.WriteString("file2 test")
+					return f.Name(), nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (map[string]string, error) {
+					if !reflect.DeepEqual([]string{"123456"}, ids) {
+						t.Errorf("unexpected ids %v", ids)
+					}
+
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					f.WriteString("encrypted")
+					return map[string]string{ids[0]: f.Name()}, nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{ID: "123456", VaultName: "test", Stream: "db.sql"},
+							Info: archive.Info{
+								"db.sql": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusStream,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+			},
+			expected: "file2 test",
+		},
+		{
+			description: "it should detect an error while retrieving the backup",
+			id:          "123456",
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (map[string]string, error) {
+					return nil, errors.New("connection error")
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+			},
+			expectedError: errors.New("connection error"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Envelop: scenario.envelop,
+				Cloud:   scenario.cloud,
+				Storage: scenario.storage,
+				Logger: mockLogger{
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			var stream bytes.Buffer
+			err := toGlacier.RetrieveBackupStream(scenario.id, scenario.backupSecret, &stream)
+
+			if stream.String() != scenario.expected {
+				t.Errorf("streams don't match. expected “%s” and got “%s”", scenario.expected, stream.String())
+			}
+
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
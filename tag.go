@@ -0,0 +1,153 @@
+package toglacier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// latestBackupName is reserved to retrieve the newest backup, so it can't be
+// used as a tag.
+const latestBackupName = "latest"
+
+// TagBackup names the backup (e.g. pre-upgrade), so the name can be used in all
+// commands instead of the backup id. A tag with the same name is moved to the
+// backup. The backup must exist in the local storage, and the name can't
+// contain spaces or be the id of another backup. On error it will return an
+// Error or storage.Error type encapsulated in a traceable error. To retrieve
+// the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) TagBackup(id, name string) error {
+	tagger, ok := t.Storage.(storage.Tagger)
+	if !ok {
+		return errors.WithStack(newError(nil, ErrorCodeTagsNotSupported, nil))
+	}
+
+	if name == "" || name == latestBackupName || strings.ContainsAny(name, " \t\n") {
+		return errors.WithStack(newError(nil, ErrorCodeInvalidTag, fmt.Errorf("name “%s” is empty, reserved or contains spaces", name)))
+	}
+
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if _, ok := backups.Search(name); ok {
+		return errors.WithStack(newError(nil, ErrorCodeInvalidTag, fmt.Errorf("name “%s” is the id of a backup", name)))
+	}
+
+	if _, ok := backups.Search(id); !ok {
+		return errors.WithStack(newError(nil, ErrorCodeBackupNotFound, fmt.Errorf("backup “%s” isn't in the local storage", id)))
+	}
+
+	tag := storage.Tag{
+		Name:      name,
+		ID:        id,
+		CreatedAt: t.now(),
+	}
+
+	return errors.WithStack(tagger.SaveTag(t.Context, tag))
+}
+
+// UntagBackup removes the name of the backup. The backup itself isn't changed.
+// On error it will return an Error or storage.Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) UntagBackup(name string) error {
+	tagger, ok := t.Storage.(storage.Tagger)
+	if !ok {
+		return errors.WithStack(newError(nil, ErrorCodeTagsNotSupported, nil))
+	}
+
+	return errors.WithStack(tagger.RemoveTag(t.Context, name))
+}
+
+// Tags returns the names of the backups sorted by name. The tags of removed
+// backups are kept, as the backup could still be restored from the trash. When
+// the local storage doesn't keep tags no tag is returned. On error it will
+// return a storage.Error type encapsulated in a traceable error. To retrieve
+// the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) Tags() ([]storage.Tag, error) {
+	tagger, ok := t.Storage.(storage.Tagger)
+	if !ok {
+		return nil, nil
+	}
+
+	tags, err := tagger.Tags(t.Context)
+	return tags, errors.WithStack(err)
+}
+
+// ResolveID returns the backup id of the tag. When there's no tag with the
+// given name it is returned unchanged, as it is already a backup id. On error
+// it will return a storage.Error type encapsulated in a traceable error. To
+// retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) ResolveID(idOrName string) (string, error) {
+	tags, err := t.Tags()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	for _, tag := range tags {
+		if tag.Name == idOrName {
+			return tag.ID, nil
+		}
+	}
+
+	return idOrName, nil
+}
@@ -0,0 +1,322 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_TagBackup(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	backups := storage.Backups{
+		{Backup: cloud.Backup{ID: "AWSID123", CreatedAt: now.Add(-time.Hour)}},
+	}
+
+	scenarios := []struct {
+		description   string
+		storage       func(saved *[]storage.Tag) storage.Storage
+		id            string
+		name          string
+		expected      []storage.Tag
+		expectedError error
+	}{
+		{
+			description: "it should name a backup",
+			storage: func(saved *[]storage.Tag) storage.Storage {
+				return mockTaggerStorage{
+					mockStorage: mockStorage{
+						mockList: func() (storage.Backups, error) {
+							return backups, nil
+						},
+					},
+					mockSaveTag: func(tag storage.Tag) error {
+						*saved = append(*saved, tag)
+						return nil
+					},
+				}
+			},
+			id:   "AWSID123",
+			name: "pre-upgrade",
+			expected: []storage.Tag{
+				{Name: "pre-upgrade", ID: "AWSID123", CreatedAt: now},
+			},
+		},
+		{
+			description: "it should detect when the local storage doesn't support tags",
+			storage: func(saved *[]storage.Tag) storage.Storage {
+				return mockStorage{}
+			},
+			id:   "AWSID123",
+			name: "pre-upgrade",
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeTagsNotSupported,
+			},
+		},
+		{
+			description: "it should refuse a reserved name",
+			storage: func(saved *[]storage.Tag) storage.Storage {
+				return mockTaggerStorage{}
+			},
+			id:   "AWSID123",
+			name: "latest",
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeInvalidTag,
+				Err:  errors.New("name “latest” is empty, reserved or contains spaces"),
+			},
+		},
+		{
+			description: "it should refuse a name with spaces",
+			storage: func(saved *[]storage.Tag) storage.Storage {
+				return mockTaggerStorage{}
+			},
+			id:   "AWSID123",
+			name: "pre upgrade",
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeInvalidTag,
+				Err:  errors.New("name “pre upgrade” is empty, reserved or contains spaces"),
+			},
+		},
+		{
+			description: "it should refuse a name that is the id of a backup",
+			storage: func(saved *[]storage.Tag) storage.Storage {
+				return mockTaggerStorage{
+					mockStorage: mockStorage{
+						mockList: func() (storage.Backups, error) {
+							return backups, nil
+						},
+					},
+				}
+			},
+			id:   "AWSID123",
+			name: "AWSID123",
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeInvalidTag,
+				Err:  errors.New("name “AWSID123” is the id of a backup"),
+			},
+		},
+		{
+			description: "it should detect when the backup doesn't exist",
+			storage: func(saved *[]storage.Tag) storage.Storage {
+				return mockTaggerStorage{
+					mockStorage: mockStorage{
+						mockList: func() (storage.Backups, error) {
+							return backups, nil
+						},
+					},
+				}
+			},
+			id:   "AWSID999",
+			name: "pre-upgrade",
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeBackupNotFound,
+				Err:  errors.New("backup “AWSID999” isn't in the local storage"),
+			},
+		},
+		{
+			description: "it should detect an error saving the tag",
+			storage: func(saved *[]storage.Tag) storage.Storage {
+				return mockTaggerStorage{
+					mockStorage: mockStorage{
+						mockList: func() (storage.Backups, error) {
+							return backups, nil
+						},
+					},
+					mockSaveTag: func(tag storage.Tag) error {
+						return errors.New("disk full")
+					},
+				}
+			},
+			id:            "AWSID123",
+			name:          "pre-upgrade",
+			expectedError: errors.New("disk full"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var saved []storage.Tag
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage(&saved),
+				Clock:   fakeClock{now: now},
+			}
+
+			err := toGlacier.TagBackup(scenario.id, scenario.name)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, saved) {
+				t.Errorf("tags don't match.\n%s", Diff(scenario.expected, saved))
+			}
+		})
+	}
+}
+
+func TestToGlacier_ResolveID(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		storage       storage.Storage
+		idOrName      string
+		expected      string
+		expectedError error
+	}{
+		{
+			description: "it should resolve a tag to the backup id",
+			storage: mockTaggerStorage{
+				mockTags: func() ([]storage.Tag, error) {
+					return []storage.Tag{
+						{Name: "monthly", ID: "AWSID124", CreatedAt: now},
+						{Name: "pre-upgrade", ID: "AWSID123", CreatedAt: now},
+					}, nil
+				},
+			},
+			idOrName: "pre-upgrade",
+			expected: "AWSID123",
+		},
+		{
+			description: "it should keep a backup id",
+			storage: mockTaggerStorage{
+				mockTags: func() ([]storage.Tag, error) {
+					return []storage.Tag{
+						{Name: "pre-upgrade", ID: "AWSID123", CreatedAt: now},
+					}, nil
+				},
+			},
+			idOrName: "AWSID124",
+			expected: "AWSID124",
+		},
+		{
+			description: "it should keep the backup id when the local storage doesn't support tags",
+			storage:     mockStorage{},
+			idOrName:    "AWSID123",
+			expected:    "AWSID123",
+		},
+		{
+			description: "it should detect an error listing the tags",
+			storage: mockTaggerStorage{
+				mockTags: func() ([]storage.Tag, error) {
+					return nil, errors.New("file corrupted")
+				},
+			},
+			idOrName:      "pre-upgrade",
+			expectedError: errors.New("file corrupted"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage,
+			}
+
+			id, err := toGlacier.ResolveID(scenario.idOrName)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if scenario.expected != id {
+				t.Errorf("ids don't match. expected “%s” and got “%s”", scenario.expected, id)
+			}
+		})
+	}
+}
+
+func TestToGlacier_UntagBackup(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		storage       func(removed *[]string) storage.Storage
+		name          string
+		expected      []string
+		expectedError error
+	}{
+		{
+			description: "it should remove the name of a backup",
+			storage: func(removed *[]string) storage.Storage {
+				return mockTaggerStorage{
+					mockRemoveTag: func(name string) error {
+						*removed = append(*removed, name)
+						return nil
+					},
+				}
+			},
+			name:     "pre-upgrade",
+			expected: []string{"pre-upgrade"},
+		},
+		{
+			description: "it should detect when the local storage doesn't support tags",
+			storage: func(removed *[]string) storage.Storage {
+				return mockStorage{}
+			},
+			name: "pre-upgrade",
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeTagsNotSupported,
+			},
+		},
+		{
+			description: "it should detect an error removing the tag",
+			storage: func(removed *[]string) storage.Storage {
+				return mockTaggerStorage{
+					mockRemoveTag: func(name string) error {
+						return fmt.Errorf("error removing tag “%s”", name)
+					},
+				}
+			},
+			name:          "pre-upgrade",
+			expectedError: errors.New("error removing tag “pre-upgrade”"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var removed []string
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage(&removed),
+			}
+
+			err := toGlacier.UntagBackup(scenario.name)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, removed) {
+				t.Errorf("removed tags don't match.\n%s", Diff(scenario.expected, removed))
+			}
+		})
+	}
+}
+
+// mockTaggerStorage is a local storage that keeps human-friendly names of the
+// backups.
+type mockTaggerStorage struct {
+	mockStorage
+	mockSaveTag   func(tag storage.Tag) error
+	mockTags      func() ([]storage.Tag, error)
+	mockRemoveTag func(name string) error
+}
+
+func (m mockTaggerStorage) SaveTag(ctx context.Context, tag storage.Tag) error {
+	return m.mockSaveTag(tag)
+}
+
+func (m mockTaggerStorage) Tags(ctx context.Context) ([]storage.Tag, error) {
+	return m.mockTags()
+}
+
+func (m mockTaggerStorage) RemoveTag(ctx context.Context, name string) error {
+	return m.mockRemoveTag(name)
+}
@@ -0,0 +1,117 @@
+package toglacier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultTelegramURL is the address of the Telegram Bot API.
+const DefaultTelegramURL = "https://api.telegram.org"
+
+// DefaultTelegramTimeout limits each request to the Telegram Bot API, so an
+// unresponsive service doesn't hold the alerts.
+const DefaultTelegramTimeout = 30 * time.Second
+
+// telegramMaxLength is the maximum number of characters of a Telegram message.
+const telegramMaxLength = 4096
+
+// Messenger sends the alerts through an instant messaging service, in addition
+// to the e-mail.
+type Messenger interface {
+	SendMessage(ctx context.Context, message string) error
+}
+
+// TelegramMessenger sends the alerts to a Telegram chat using a bot. The bot
+// must be created with the BotFather, and the chat must have started a
+// conversation with the bot (or added it to the group) before receiving
+// messages.
+type TelegramMessenger struct {
+	// Token is the bot token informed by the BotFather.
+	Token string
+
+	// ChatID identifies the user, group or channel that receives the messages.
+	ChatID string
+
+	// URL is the address of the Telegram Bot API. When empty the
+	// DefaultTelegramURL is used.
+	URL string
+
+	// Client sends the HTTP requests. When not defined a client limited by the
+	// DefaultTelegramTimeout is used.
+	Client *http.Client
+}
+
+// SendMessage sends the message to the chat. Messages longer than the Telegram
+// limit are truncated. On error it will return an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t TelegramMessenger) SendMessage(ctx context.Context, message string) error {
+	if runes := []rune(message); len(runes) > telegramMaxLength {
+		message = string(runes[:telegramMaxLength-1]) + "…"
+	}
+
+	apiURL := t.URL
+	if apiURL == "" {
+		apiURL = DefaultTelegramURL
+	}
+
+	form := url.Values{
+		"chat_id": {t.ChatID},
+		"text":    {message},
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimSuffix(apiURL, "/"), t.Token), strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.WithStack(newError(nil, ErrorCodeTelegram, err))
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := t.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTelegramTimeout}
+	}
+
+	response, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		// the request URL contains the bot token, so only the cause is reported
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return errors.WithStack(newError(nil, ErrorCodeTelegram, err))
+	}
+	defer response.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return errors.WithStack(newError(nil, ErrorCodeTelegram, fmt.Errorf("unexpected response with status %d", response.StatusCode)))
+	}
+
+	if !result.OK {
+		return errors.WithStack(newError(nil, ErrorCodeTelegram, errors.New(result.Description)))
+	}
+
+	return nil
+}
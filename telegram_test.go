@@ -0,0 +1,99 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/rafaeljusto/toglacier"
+)
+
+func TestTelegramMessenger_SendMessage(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		message       string
+		handler       http.HandlerFunc
+		expectedError error
+	}{
+		{
+			description: "it should send a message correctly",
+			message:     "toglacier alert",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/botabc123/sendMessage" {
+					http.Error(w, `{"ok":false,"description":"Not Found"}`, http.StatusNotFound)
+					return
+				}
+
+				if chatID := r.FormValue("chat_id"); chatID != "-1001234567890" {
+					fmt.Fprintf(w, `{"ok":false,"description":"unexpected chat %s"}`, chatID)
+					return
+				}
+
+				if text := r.FormValue("text"); text != "toglacier alert" {
+					fmt.Fprintf(w, `{"ok":false,"description":"unexpected text %s"}`, text)
+					return
+				}
+
+				fmt.Fprint(w, `{"ok":true,"result":{}}`)
+			},
+		},
+		{
+			description: "it should truncate a long message",
+			message:     strings.Repeat("á", 5000),
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if length := utf8.RuneCountInString(r.FormValue("text")); length != 4096 {
+					fmt.Fprintf(w, `{"ok":false,"description":"unexpected length %d"}`, length)
+					return
+				}
+
+				fmt.Fprint(w, `{"ok":true,"result":{}}`)
+			},
+		},
+		{
+			description: "it should detect when the message is refused",
+			message:     "toglacier alert",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`)
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeTelegram,
+				Err:  errors.New("Forbidden: bot was blocked by the user"),
+			},
+		},
+		{
+			description: "it should detect an unexpected response",
+			message:     "toglacier alert",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Bad Gateway", http.StatusBadGateway)
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeTelegram,
+				Err:  errors.New("unexpected response with status 502"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			server := httptest.NewServer(scenario.handler)
+			defer server.Close()
+
+			messenger := toglacier.TelegramMessenger{
+				Token:  "abc123",
+				ChatID: "-1001234567890",
+				URL:    server.URL,
+			}
+
+			err := messenger.SendMessage(context.Background(), scenario.message)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
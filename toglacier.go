@@ -3,11 +3,16 @@ package toglacier
 import (
 	"context"
 	"fmt"
+	"mime"
+	"net"
 	"net/smtp"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -16,6 +21,7 @@ import (
 	"github.com/rafaeljusto/toglacier/internal/log"
 	"github.com/rafaeljusto/toglacier/internal/report"
 	"github.com/rafaeljusto/toglacier/internal/storage"
+	"github.com/rafaeljusto/toglacier/internal/trace"
 )
 
 // ToGlacier manages backups in the cloud.
@@ -26,6 +32,188 @@ type ToGlacier struct {
 	Cloud   cloud.Cloud
 	Storage storage.Storage
 	Logger  log.Logger
+
+	// Clock retrieves the current time. When not defined the system clock is
+	// used.
+	Clock Clock
+
+	// MachineID scopes the listing, the old backups removal and the reports to
+	// the backups created by this machine, useful when many machines share the
+	// same vault. Backups without machine identifier (created by older versions
+	// of the tool) are always considered. When empty all backups are considered.
+	MachineID string
+
+	// AllMachines lists and reports the backups of all machines sharing the
+	// vault, ignoring the MachineID. The other operations (e.g. the old backups
+	// removal) are still scoped to the backups of this machine.
+	AllMachines bool
+
+	// Command identifies what initiated the operations (e.g. the command line
+	// action), and it is recorded in the audit log of the destructive
+	// operations.
+	Command string
+
+	// Parity generates the recovery data used to repair corrupted archives after
+	// the download. When not defined no parity data is sent or used.
+	Parity archive.Parity
+
+	// Redundancy is the percentage (1 - 100) of parity data generated for each
+	// backup. When zero no parity data is sent.
+	Redundancy int
+
+	// Routes stores the backups of some paths in other places of the cloud. The
+	// paths without route are stored in the default Cloud.
+	Routes []Route
+
+	// Replica receives an independent copy of each backup (e.g. a vault in
+	// another region), used to retrieve the archives that can't be retrieved
+	// from their cloud. When not defined no copy is sent.
+	Replica cloud.Cloud
+
+	// Concurrency is the maximum number of routes backed up at the same time.
+	// Values lower than 2 back up the routes one after another.
+	Concurrency int
+
+	// RemoveConcurrency is the maximum number of old backups removed from the
+	// cloud at the same time. Values lower than 2 remove the backups one after
+	// another.
+	RemoveConcurrency int
+
+	// Sources are commands whose output is added to the backup paths (e.g.
+	// database dumps). They are executed before building the archives.
+	Sources []Source
+
+	// SourcesDir is the directory where the output of the sources is stored,
+	// that must belong to the current user. When empty the toglacier-sources
+	// directory of the system temporary directory is used.
+	SourcesDir string
+
+	// DockerImage is the image of the temporary container used by the sources
+	// that read Docker volumes. When empty the DefaultDockerImage is used.
+	DockerImage string
+
+	// Journal keeps the backups that were sent to the cloud but couldn't be
+	// saved in the local storage, so they are saved on the next backup. When
+	// not defined these backups are only reported.
+	Journal storage.Journal
+
+	// Spool keeps the archives that couldn't be sent to the cloud (e.g. network
+	// outage), so they are sent later by SendSpooled or before the next backup
+	// of the same route. When not defined the backup fails.
+	Spool storage.Spool
+
+	// Trash keeps the removed backups during the TrashPeriod, so the removal can
+	// be undone with RestoreTrash. The trashed backups are removed from the
+	// cloud by EmptyTrash. When not defined the backups are removed
+	// immediately.
+	Trash storage.Trash
+
+	// TrashPeriod is the time that a removed backup stays in the trash before
+	// it is removed from the cloud.
+	TrashPeriod time.Duration
+
+	// Changes informs the files modified between the backups, that are
+	// forgotten after each successful backup. It must be the same changes used
+	// by the archive builder. When not defined all files are analyzed.
+	Changes archive.Changes
+
+	// Priority lowers the CPU and I/O priority of the process while the
+	// archives are built. When not defined the priority is not changed.
+	Priority Priority
+
+	// RebaseAfter is the age of an archive after which its files are sent again
+	// in the next backup, so long incremental chains don't keep old archives
+	// forever. When zero the files are never sent again.
+	RebaseAfter time.Duration
+
+	// FullBackupEvery is the maximum age of the last full backup (without
+	// references to older archives). When it's older, the next backup contains
+	// all files. When zero only the first backup is a full backup.
+	FullBackupEvery time.Duration
+
+	// Events receives the notifications of the backups, retrievals and
+	// removals. When not defined the notifications are ignored.
+	Events Events
+
+	// ReadOnly disables the operations that change the backups in the cloud
+	// (e.g. sending and removing backups), for machines that only browse and
+	// restore the backups.
+	ReadOnly bool
+
+	// Header identifies the format, the compression and the envelop in the
+	// beginning of each archive sent, so the archives are restored with them
+	// independently of the configuration at the restore time. When the format
+	// is empty the archives are sent and retrieved without header.
+	Header archive.Header
+
+	// AllowUnencrypted restores the archives whose header claims they aren't
+	// encrypted even when the backup secret is informed, for backups sent
+	// before the secret was configured. As the header isn't authenticated, a
+	// replaced archive isn't detected, so by default they are refused.
+	AllowUnencrypted bool
+
+	// Tracer records how long each stage of the backups and retrievals takes
+	// (e.g. build, encrypt and send). When not defined the stages aren't traced.
+	Tracer trace.Tracer
+
+	// Reports stores the reports of the actions until they are sent. When not
+	// defined the default collector of the report package is used.
+	Reports *report.Collector
+
+	// GroupByDay treats the backups created on the same calendar day as a single
+	// retention slot when removing old backups, so ad-hoc manual runs don't take
+	// the place of the daily backups.
+	GroupByDay bool
+
+	// Location defines the calendar days used to group the backups. When not
+	// defined the location of the Clock is used.
+	Location *time.Location
+
+	// Messengers also receive the alerts, as plain text, through instant
+	// messaging services (e.g. Telegram). When not defined the alerts are only
+	// sent via e-mail.
+	Messengers []Messenger
+
+	// MaxUnreadable is the maximum number of files that can't be read (e.g.
+	// permission denied or removed during the backup) left out of each backup.
+	// They are listed in the backup report, and the backup fails when there are
+	// more unreadable files. When zero the backup fails on the first unreadable
+	// file.
+	MaxUnreadable int
+
+	// BackupMaxDuration limits the time spent analyzing the files of each
+	// backup. When reached the backup is sent with the files analyzed until
+	// then, flagged as partial, and the other files keep referencing the older
+	// archives. When zero the backups run until all files are analyzed.
+	BackupMaxDuration time.Duration
+
+	// Running keeps the backups and retrievals in progress, so each one can be
+	// cancelled by its identifier. When not defined the operations can only be
+	// cancelled by the Context.
+	Running *Running
+
+	// ConfirmAbove is the archive size, in bytes, that requires the Approval
+	// before uploading the backup, avoiding unexpected big uploads (e.g. on
+	// metered links). When zero the backups are always sent.
+	ConfirmAbove int64
+
+	// Approval decides if a backup bigger than ConfirmAbove can be sent. When
+	// not defined these backups are deferred.
+	Approval Approval
+
+	// AllOrNothing keeps all existing files of an archive when any retrieved
+	// file doesn't match its checksum. By default only the mismatching files
+	// aren't restored, and they are reported as failed.
+	AllOrNothing bool
+
+	// StepAttempts is how many times a discrete step of the backup (like saving
+	// the backup in the local storage after the upload) is executed when it
+	// fails with a temporary error. When zero DefaultStepAttempts is used.
+	StepAttempts int
+
+	// StepRetryDelay is the amount of time to wait between the attempts of a
+	// discrete step of the backup. When zero DefaultStepRetryDelay is used.
+	StepRetryDelay time.Duration
 }
 
 // Backup create an archive and send it to the cloud. Optionally encrypt the
@@ -35,27 +223,146 @@ type ToGlacier struct {
 // percentage (0 - 100) of modified files that is tolerated. If there's no need
 // to keep track of the modified files set modifyTolerance to 0 or 100. You
 // could also ignore some files or directories in the backup paths using regular
-// expressions in the ignorePatterns parameter.
-func (t ToGlacier) Backup(backupPaths []string, backupSecret string, modifyTolerance float64, ignorePatterns []*regexp.Regexp) error {
-	backupReport := report.NewSendBackup()
+// expressions in the ignorePatterns parameter. The comment is an optional note
+// stored with the backup (e.g. “before OS upgrade”), so meaningful backups can
+// be identified later. When there are routes, a different backup is sent to
+// each route with the paths under its prefix, and up to Concurrency routes are
+// backed up at the same time. The output of the sources is added to the backup
+// paths, and when a source fails the backup isn't sent. When there's a
+// replica, a copy of each backup is also sent to it. When there's a spool, an
+// archive that can't be sent is kept there instead of failing the backup, as
+// well as a copy that can't be sent to the replica.
+// When the changes are informed, only the content of the modified files is
+// read.
+func (t ToGlacier) Backup(backupPaths []string, backupSecret string, modifyTolerance float64, ignorePatterns []*regexp.Regexp, comment string) (err error) {
+	if err := t.writable("backup"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var finish func()
+	t.Context, finish = t.startOperation("backup")
+	defer finish()
+
+	var span trace.Span
+	t.Context, span = t.startSpan("backup")
+	span.SetAttribute("paths", len(backupPaths))
 	defer func() {
-		report.Add(backupReport)
+		span.End(err)
 	}()
 
+	startedAt := time.Now()
+	t.recoverJournal()
+	if t.Spool != nil {
+		t.flushReplicas(backupSecret)
+	}
+
+	sourcePaths, err := t.runSources()
+	if err != nil {
+		t.events().OnError("backup", err)
+		return errors.WithStack(err)
+	}
+	backupPaths = append(append([]string{}, backupPaths...), sourcePaths...)
+
+	groups := t.routePaths(backupPaths)
+
+	if t.Concurrency < 2 || len(groups) < 2 {
+		for _, group := range groups {
+			if err := t.routeBackup(group, backupSecret, modifyTolerance, ignorePatterns, comment); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		t.clearChanges(startedAt)
+		return nil
+	}
+
+	// the routes are independent, so a failure in one route doesn't stop the
+	// others
+	errs := make([]error, len(groups))
+	semaphore := make(chan struct{}, t.Concurrency)
+	var wg sync.WaitGroup
+
+	for i, group := range groups {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(i int, group routedPaths) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			errs[i] = t.routeBackup(group, backupSecret, modifyTolerance, ignorePatterns, comment)
+		}(i, group)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	t.clearChanges(startedAt)
+	return nil
+}
+
+// routeBackup sends the backup of the paths to their route. Only one backup of
+// each route is sent at a time, as it depends on the previous backup of the
+// route (incremental backups).
+func (t ToGlacier) routeBackup(group routedPaths, backupSecret string, modifyTolerance float64, ignorePatterns []*regexp.Regexp, comment string) (err error) {
+	defer func() {
+		if err != nil {
+			t.events().OnError("backup", err)
+		}
+	}()
+
+	var vaultName string
+	if group.route != nil {
+		t.Cloud = group.route.Cloud
+		vaultName = group.route.VaultName
+	}
+
+	unlock := lockRoute(vaultName)
+	defer unlock()
+
 	// retrieve the latest backup so we can analyze the files that changed
-	backups, err := t.ListBackups(false)
+	var backups storage.Backups
+	err = t.retryStep("listing the backups", func() (err error) {
+		backups, err = t.listBackups(false, 0, false)
+		return err
+	})
+
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	groupIgnorePatterns := append(append([]*regexp.Regexp{}, ignorePatterns...), group.ignorePatterns...)
+	return errors.WithStack(t.backup(group.paths, t.routeBackups(backups, group.route), backupSecret, vaultName, modifyTolerance, groupIgnorePatterns, comment))
+}
+
+func (t ToGlacier) backup(backupPaths []string, backups storage.Backups, backupSecret, vaultName string, modifyTolerance float64, ignorePatterns []*regexp.Regexp, comment string) error {
+	backupReport := report.NewSendBackup()
+	backupReport.Route = vaultName
+	defer func() {
+		t.reports().Add(backupReport)
+	}()
+
+	t.events().OnBackupStart(backupPaths)
+
 	var archiveInfo archive.Info
-	if len(backups) > 0 {
-		// the newest backup is always in the first position
-		archiveInfo = backups[0].Info
+	if lastBackup, ok := lastArchiveBackup(backups); ok && !t.fullBackupDue(backups) {
+		archiveInfo = t.rebase(lastBackup.Info, backups)
 	}
 
 	timeMark := time.Now()
-	filename, archiveInfo, err := t.Archive.Build(archiveInfo, ignorePatterns, backupPaths...)
+	t.lowerPriority()
+	ctx, span := t.startSpan("build")
+	filename, archiveInfo, err := t.builder(&backupReport).Build(ctx, archiveInfo, ignorePatterns, backupPaths...)
+	span.SetAttribute("files", len(archiveInfo))
+	span.SetAttribute("partial", backupReport.Partial)
+	span.End(err)
 	if err != nil {
 		backupReport.Errors = append(backupReport.Errors, err)
 		return errors.WithStack(err)
@@ -75,11 +382,32 @@ func (t ToGlacier) Backup(backupPaths []string, backupSecret string, modifyToler
 		return errors.WithStack(newError(backupPaths, ErrorCodeModifyTolerance, nil))
 	}
 
+	approvedSize, err := t.confirmUpload(backupPaths, filename)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = t.upload(&backupReport, filename, archiveInfo, backups, backupSecret, vaultName, comment)
+	t.approvedUpload(approvedSize, err)
+	return errors.WithStack(err)
+}
+
+// upload encrypts the archive, sends it to the cloud together with the
+// companion archives (parity and catalog) and saves the backup in the local
+// storage. The steps are recorded in the backup report. The vaultName
+// identifies the route of the archive when it needs to be spooled.
+func (t ToGlacier) upload(backupReport *report.SendBackup, filename string, archiveInfo archive.Info, backups storage.Backups, backupSecret, vaultName, comment string) error {
+	var err error
+	var timeMark time.Time
+
 	if backupSecret != "" {
 		var encryptedFilename string
 
 		timeMark = time.Now()
-		if encryptedFilename, err = t.Envelop.Encrypt(filename, backupSecret); err != nil {
+		_, span := t.startSpan("encrypt")
+		encryptedFilename, err = t.Envelop.Encrypt(filename, backupSecret)
+		span.End(err)
+		if err != nil {
 			backupReport.Errors = append(backupReport.Errors, err)
 			return errors.WithStack(err)
 		}
@@ -91,29 +419,283 @@ func (t ToGlacier) Backup(backupPaths []string, backupSecret string, modifyToler
 		}
 	}
 
+	header, err := t.archiveHeader(archiveInfo, backupSecret)
+	if err != nil {
+		backupReport.Errors = append(backupReport.Errors, err)
+		return errors.WithStack(err)
+	}
+
+	if t.Spool != nil {
+		// the archives of the route are sent in the order that they were built,
+		// so a newer archive waits in the spool while an older one is there
+		var pending bool
+		if backups, pending = t.flushSpool(vaultName, backups, backupSecret); pending {
+			return errors.WithStack(t.spoolArchive(filename, storage.SpooledArchive{
+				VaultName: vaultName,
+				Comment:   comment,
+				Info:      archiveInfo,
+			}, nil))
+		}
+	}
+
 	timeMark = time.Now()
-	if backupReport.Backup, err = t.Cloud.Send(t.Context, filename); err != nil {
+	ctx, span := t.startSpan("send")
+	backupReport.Backup, err = t.Cloud.Send(cloud.WithHeader(ctx, header), filename, comment)
+	span.SetAttribute("backup.id", backupReport.Backup.ID)
+	span.SetAttribute("size", backupReport.Backup.Size)
+	span.End(err)
+	if err != nil {
 		backupReport.Errors = append(backupReport.Errors, err)
+		if t.Spool != nil && !cancelled(err) {
+			return errors.WithStack(t.spoolArchive(filename, storage.SpooledArchive{
+				VaultName: vaultName,
+				Comment:   comment,
+				Info:      archiveInfo,
+			}, err))
+		}
 		return errors.WithStack(err)
 	}
 	backupReport.Durations.Send = time.Now().Sub(timeMark)
 
-	// fill backup id for new and modified files
+	return errors.WithStack(t.complete(backupReport, filename, header, archiveInfo, backups, backupSecret, comment))
+}
+
+// complete sends the companion archives (parity, replica and catalog) of the
+// backup that was just sent to the cloud and saves it in the local storage. The
+// header is the format header sent before the archive. When there's a spool,
+// a copy that couldn't be sent to the replica is kept there to be sent later.
+func (t ToGlacier) complete(backupReport *report.SendBackup, filename string, header []byte, archiveInfo archive.Info, backups storage.Backups, backupSecret, comment string) error {
+	backupReport.Backup.Duration = backupReport.Durations.Build + backupReport.Durations.Encrypt + backupReport.Durations.Send
+	backupReport.Backup.Partial = backupReport.Partial
+	backupReport.Backup.Stream, _ = archiveInfo.Stream()
+	backupReport.Backup.ParityID = t.sendParity(filename, backupReport.Backup.ID)
+
+	var replicaErr error
+	if backupReport.Backup.ReplicaID, replicaErr = t.sendReplica(filename, header, backupReport.Backup.ID, comment); replicaErr != nil {
+		backupReport.Errors = append(backupReport.Errors, replicaErr)
+	}
+
+	// fill backup id for new and modified files (or the stream)
 	for path, itemInfo := range archiveInfo {
-		if itemInfo.Status.Useful() {
+		if itemInfo.Status.Useful() || itemInfo.Status == archive.ItemInfoStatusStream {
 			itemInfo.ID = backupReport.Backup.ID
 			archiveInfo[path] = itemInfo
 		}
 	}
 
-	if err := t.Storage.Save(storage.Backup{Backup: backupReport.Backup, Info: archiveInfo}); err != nil {
+	backupReport.Backup.CatalogID = t.sendCatalog(storage.Backup{Backup: backupReport.Backup, Info: archiveInfo}, backups, backupSecret)
+
+	err := t.retryStep("saving the backup", func() error {
+		return t.Storage.Save(t.Context, storage.Backup{Backup: backupReport.Backup, Info: archiveInfo})
+	})
+
+	if err != nil {
 		backupReport.Errors = append(backupReport.Errors, err)
+		t.journalBackup(storage.Backup{Backup: backupReport.Backup, Info: archiveInfo}, err)
 		return errors.WithStack(err)
 	}
 
+	// the copy is retried only when the backup is in the local storage, where
+	// the copy identifier is filled later
+	if replicaErr != nil && t.Spool != nil && !cancelled(replicaErr) {
+		t.spoolArchive(filename, storage.SpooledArchive{
+			Comment:   comment,
+			ReplicaOf: backupReport.Backup.ID,
+			Info:      archiveInfo,
+		}, nil)
+	}
+
+	t.events().OnBackupComplete(storage.Backup{Backup: backupReport.Backup, Info: archiveInfo})
 	return nil
 }
 
+// journalBackup keeps a backup that was sent to the cloud but couldn't be saved
+// in the local storage, so it can be recovered on the next backup instead of
+// becoming an orphaned archive. The inconsistency is always reported.
+func (t ToGlacier) journalBackup(backup storage.Backup, saveErr error) {
+	journalReport := report.NewJournal()
+	journalReport.Pending = append(journalReport.Pending, backup.Backup)
+	journalReport.Errors = append(journalReport.Errors, saveErr)
+	defer func() {
+		t.reports().Add(journalReport)
+	}()
+
+	if t.Journal == nil {
+		t.Logger.Warningf("toglacier: backup “%s” was sent to the cloud but isn't in the local storage", backup.Backup.ID)
+		return
+	}
+
+	if err := t.Journal.Save(t.Context, backup); err != nil {
+		t.Logger.Warningf("toglacier: backup “%s” was sent to the cloud but couldn't be saved in the local storage or in the journal. details: %s", backup.Backup.ID, err)
+		journalReport.Errors = append(journalReport.Errors, err)
+		return
+	}
+
+	t.Logger.Warningf("toglacier: backup “%s” was sent to the cloud but couldn't be saved in the local storage, it will be recovered from the journal on the next backup", backup.Backup.ID)
+}
+
+// recoverJournal saves in the local storage the backups that were sent to the
+// cloud in previous runs but couldn't be saved locally. It runs before the
+// backup, so the incremental backup starts from the latest archive
+// information. The backups that still couldn't be saved stay in the journal.
+func (t ToGlacier) recoverJournal() {
+	if t.Journal == nil {
+		return
+	}
+
+	journalReport := report.NewJournal()
+
+	pending, err := t.Journal.List(t.Context)
+	if err != nil {
+		journalReport.Errors = append(journalReport.Errors, err)
+		t.reports().Add(journalReport)
+		return
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	defer func() {
+		t.reports().Add(journalReport)
+	}()
+
+	for _, backup := range pending {
+		err := t.retryStep("saving the backup from the journal", func() error {
+			return t.Storage.Save(t.Context, backup)
+		})
+
+		if err != nil {
+			journalReport.Pending = append(journalReport.Pending, backup.Backup)
+			journalReport.Errors = append(journalReport.Errors, err)
+			continue
+		}
+
+		// the backup is already in the local storage, so if it stays in the
+		// journal it will only be saved again
+		if err := t.Journal.Remove(t.Context, backup.Backup.ID); err != nil {
+			t.Logger.Warningf("toglacier: failed to remove backup “%s” from the journal. details: %s", backup.Backup.ID, err)
+		}
+
+		journalReport.Recovered = append(journalReport.Recovered, backup.Backup)
+	}
+}
+
+// sendParity generates and uploads the parity data of the archive, returning
+// the parity identifier in the cloud. As the backup was already sent, a failure
+// here only means that the archive can't be repaired later, so it doesn't stop
+// the backup.
+func (t ToGlacier) sendParity(filename, backupID string) string {
+	if t.Parity == nil || t.Redundancy <= 0 {
+		return ""
+	}
+
+	parityFilename, err := t.Parity.Generate(filename, t.Redundancy)
+	if err != nil {
+		t.Logger.Warningf("toglacier: failed to generate parity data for backup “%s”. details: %s", backupID, err)
+		return ""
+	}
+	defer os.Remove(parityFilename)
+
+	parityBackup, err := t.Cloud.SendParity(t.Context, parityFilename, backupID)
+	if err != nil {
+		t.Logger.Warningf("toglacier: failed to send parity data for backup “%s”. details: %s", backupID, err)
+		return ""
+	}
+
+	return parityBackup.ID
+}
+
+// sendReplica uploads a copy of the archive, with the same format header, to
+// the replica, returning the copy identifier in the replica. As the backup was
+// already sent, a failure here only means that there's no copy to fall back
+// to, so it doesn't stop the backup. The failed copy is kept in the spool by
+// the caller, after the backup is saved in the local storage.
+func (t ToGlacier) sendReplica(filename string, header []byte, backupID, comment string) (string, error) {
+	if t.Replica == nil {
+		return "", nil
+	}
+
+	replicaBackup, err := t.Replica.Send(cloud.WithHeader(t.Context, header), filename, comment)
+	if err != nil {
+		t.Logger.Warningf("toglacier: failed to send copy of backup “%s” to the replica. details: %s", backupID, err)
+		return "", errors.WithStack(err)
+	}
+
+	return replicaBackup.ID, nil
+}
+
+// fullBackupDue checks if there's no full backup (a backup that doesn't
+// reference older archives) within the FullBackupEvery period, so the next
+// backup must ignore the previous archive information and contain all files.
+func (t ToGlacier) fullBackupDue(backups storage.Backups) bool {
+	if t.FullBackupEvery <= 0 {
+		return false
+	}
+
+	limit := t.now().Add(-t.FullBackupEvery)
+	for _, backup := range backups {
+		if backup.Backup.CreatedAt.Before(limit) {
+			break
+		}
+
+		if backup.Backup.Stream != "" {
+			continue
+		}
+
+		statistics := backup.Info.Statistics()
+		if len(backup.Info) > 0 && statistics[archive.ItemInfoStatusUnmodified] == 0 && statistics[archive.ItemInfoStatusDeleted] == 0 {
+			return false
+		}
+	}
+
+	t.Logger.Infof("toglacier: no full backup in the last %s, sending all files", t.FullBackupEvery)
+	return true
+}
+
+// lastArchiveBackup returns the newest backup built from the backup paths,
+// ignoring the backups of streams, as its archive information is the starting
+// point of the incremental backup.
+func lastArchiveBackup(backups storage.Backups) (storage.Backup, bool) {
+	// the newest backup is always in the first position
+	for _, backup := range backups {
+		if backup.Backup.Stream == "" {
+			return backup, true
+		}
+	}
+
+	return storage.Backup{}, false
+}
+
+// rebase removes from the last archive information the files only stored in
+// archives older than RebaseAfter, so they are sent again as new files and the
+// old archives stop being referenced.
+func (t ToGlacier) rebase(archiveInfo archive.Info, backups storage.Backups) archive.Info {
+	if t.RebaseAfter <= 0 || len(archiveInfo) == 0 {
+		return archiveInfo
+	}
+
+	limit := t.now().Add(-t.RebaseAfter)
+	oldArchives := make(map[string]bool)
+	for _, backup := range backups {
+		if backup.Backup.CreatedAt.Before(limit) {
+			oldArchives[backup.Backup.ID] = true
+		}
+	}
+
+	rebasedArchiveInfo := make(archive.Info, len(archiveInfo))
+	for path, itemInfo := range archiveInfo {
+		if itemInfo.Status != archive.ItemInfoStatusDeleted && oldArchives[itemInfo.ID] {
+			t.Logger.Debugf("toglacier: path “%s” only stored in old archive “%s”, sending it again", path, itemInfo.ID)
+			continue
+		}
+
+		rebasedArchiveInfo[path] = itemInfo
+	}
+
+	return rebasedArchiveInfo
+}
+
 func (t ToGlacier) modifyToleranceReached(archiveInfo archive.Info, modifyTolerance float64) bool {
 	if len(archiveInfo) == 0 || modifyTolerance == 0 || modifyTolerance == 100 {
 		return false
@@ -137,32 +719,89 @@ func (t ToGlacier) modifyToleranceReached(archiveInfo archive.Info, modifyTolera
 }
 
 // ListBackups show the current backups. With the remote flag it is possible to
-// list the backups tracked locally or retrieve the cloud inventory.
-func (t ToGlacier) ListBackups(remote bool) (storage.Backups, error) {
+// list the backups tracked locally or retrieve the cloud inventory. As
+// retrieving the cloud inventory can take hours, the maxAge parameter allows
+// the caller to accept the last synchronized inventory when it isn't older than
+// the informed duration. A zero maxAge always retrieves a fresh inventory. The
+// backups in the trash aren't listed locally. The backups of other machines are
+// only listed with the AllMachines flag.
+func (t ToGlacier) ListBackups(remote bool, maxAge time.Duration) (storage.Backups, error) {
+	return t.listBackups(remote, maxAge, t.AllMachines)
+}
+
+// listBackups lists the backups like ListBackups, where the backups of other
+// machines are only listed when allMachines is set. The operations that change
+// the backups always list only the backups of this machine.
+func (t ToGlacier) listBackups(remote bool, maxAge time.Duration, allMachines bool) (storage.Backups, error) {
 	if remote {
-		return t.listRemoteBackups()
+		inventoryDate, err := t.inventoryDate(maxAge)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if inventoryDate.IsZero() {
+			backups, err := t.listRemoteBackups()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			if allMachines {
+				return backups, nil
+			}
+			return t.machineBackups(backups), nil
+		}
+
+		t.Logger.Infof("toglacier: using inventory synchronized at %s", inventoryDate.Format(time.RFC3339))
 	}
 
-	backups, err := t.Storage.List()
+	backups, err := t.Storage.List(t.Context)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
+	if backups, err = t.untrashedBackups(backups); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
 	sort.Sort(backupsByCreationDate(backups))
-	return backups, nil
+
+	if allMachines {
+		return backups, nil
+	}
+	return t.machineBackups(backups), nil
+}
+
+// machineBackups keeps only the backups that belongs to the current machine.
+func (t ToGlacier) machineBackups(backups storage.Backups) storage.Backups {
+	if t.MachineID == "" {
+		return backups
+	}
+
+	machineBackups := make(storage.Backups, 0, len(backups))
+	for _, backup := range backups {
+		if backup.Backup.MachineID == "" || backup.Backup.MachineID == t.MachineID {
+			machineBackups = append(machineBackups, backup)
+		}
+	}
+
+	return machineBackups
 }
 
 func (t ToGlacier) listRemoteBackups() (storage.Backups, error) {
 	listBackupsReport := report.NewListBackups()
 	defer func() {
-		report.Add(listBackupsReport)
+		t.reports().Add(listBackupsReport)
 	}()
 
 	timeMark := time.Now()
-	remoteBackups, err := t.Cloud.List(t.Context)
-	if err != nil {
-		listBackupsReport.Errors = append(listBackupsReport.Errors, err)
-		return nil, errors.WithStack(err)
+	var remoteBackups []cloud.Backup
+	for _, c := range t.clouds() {
+		cloudBackups, err := c.List(t.Context)
+		if err != nil {
+			listBackupsReport.Errors = append(listBackupsReport.Errors, err)
+			return nil, errors.WithStack(err)
+		}
+		remoteBackups = append(remoteBackups, cloudBackups...)
 	}
 	listBackupsReport.Durations.List = time.Now().Sub(timeMark)
 
@@ -170,7 +809,7 @@ func (t ToGlacier) listRemoteBackups() (storage.Backups, error) {
 	// remote backups operations can take a while, and a concurrent action could
 	// change the local backups during this time
 
-	backups, err := t.Storage.List()
+	backups, err := t.Storage.List(t.Context)
 	if err != nil {
 		listBackupsReport.Errors = append(listBackupsReport.Errors, err)
 		return nil, errors.WithStack(err)
@@ -190,88 +829,143 @@ func (t ToGlacier) listRemoteBackups() (storage.Backups, error) {
 	// TODO: if the change is greater than 20% something is really wrong, and
 	// maybe the best approach is to do nothing and report the problem.
 
-	var kept []string
-	for _, backup := range backups {
-		// http://docs.aws.amazon.com/amazonglacier/latest/dev/vault-inventory.html#vault-inventory-about
-		//
-		// Amazon Glacier updates a vault inventory approximately once a day,
-		// starting on the day you first upload an archive to the vault. If there
-		// have been no archive additions or deletions to the vault since the last
-		// inventory, the inventory date is not updated. When you initiate a job for
-		// a vault inventory, Amazon Glacier returns the last inventory it
-		// generated, which is a point-in-time snapshot and not real-time data. Note
-		// that after Amazon Glacier creates the first inventory for the vault, it
-		// typically takes half a day and up to a day before that inventory is
-		// available for retrieval.
-		if backup.Backup.CreatedAt.After(time.Now().Add(-24 * time.Hour)) {
-			// recent backups could not be in the inventory yet
-			kept = append(kept, backup.Backup.ID)
-			t.Logger.Debugf("toglacier: backup id “%s” kept because is to recent", backup.Backup.ID)
-			continue
-		}
-
-		if err := t.Storage.Remove(backup.Backup.ID); err != nil {
+	// http://docs.aws.amazon.com/amazonglacier/latest/dev/vault-inventory.html#vault-inventory-about
+	//
+	// Amazon Glacier updates a vault inventory approximately once a day,
+	// starting on the day you first upload an archive to the vault. If there
+	// have been no archive additions or deletions to the vault since the last
+	// inventory, the inventory date is not updated. When you initiate a job for
+	// a vault inventory, Amazon Glacier returns the last inventory it
+	// generated, which is a point-in-time snapshot and not real-time data. Note
+	// that after Amazon Glacier creates the first inventory for the vault, it
+	// typically takes half a day and up to a day before that inventory is
+	// available for retrieval.
+	merge := backups.MergeInventory(remoteBackups, t.now().Add(-24*time.Hour))
+
+	for i, id := range merge.Remove {
+		if err := t.Storage.Remove(t.Context, id); err != nil {
+			t.recordOperation("synchronize storage", merge.Remove[:i], err)
 			listBackupsReport.Errors = append(listBackupsReport.Errors, err)
 			return nil, errors.WithStack(err)
 		}
-	}
-
-	sort.Strings(kept)
-
-	syncBackups := make(storage.Backups, 0, len(remoteBackups))
-	for i, remoteBackup := range remoteBackups {
-		// check if a recent backup appeared in the inventory
-		if j := sort.SearchStrings(kept, remoteBackup.ID); j < len(kept) && kept[j] == remoteBackup.ID {
-			if err := t.Storage.Remove(kept[j]); err != nil {
-				listBackupsReport.Errors = append(listBackupsReport.Errors, err)
-				return nil, errors.WithStack(err)
-			}
 
-			t.Logger.Debugf("toglacier: backup id “%s” removed because it was found remotely", kept[j])
-			kept = append(kept[:j], kept[j+1:]...)
-		}
-
-		// we should keep the archive information to be able to build incremental
-		// backups again. Another alternative is build the archive information from
-		// the uploaded backup, but it is really slow. Anyway, when retrieving the
-		// backup, if there's no archive information, we will try to extract it from
-		// the backup
-		var archiveInfo archive.Info
-		for _, backup := range backups {
-			if backup.Backup.ID == remoteBackup.ID {
-				archiveInfo = backup.Info
-				break
-			}
-		}
+		t.Logger.Debugf("toglacier: backup id “%s” removed because it wasn't found remotely", id)
+	}
 
-		syncBackups = append(syncBackups, storage.Backup{
-			Backup: remoteBackup,
-			Info:   archiveInfo,
-		})
+	if len(merge.Remove) > 0 {
+		t.recordOperation("synchronize storage", merge.Remove, nil)
+	}
 
-		if err := t.Storage.Save(syncBackups[i]); err != nil {
+	// the merge keeps the archive information of the backups that still exist
+	// remotely, as it is necessary to build incremental backups again. Another
+	// alternative is build the archive information from the uploaded backup, but
+	// it is really slow. Anyway, when retrieving the backup, if there's no
+	// archive information, we will try to extract it from the backup
+	for _, backup := range merge.Save {
+		if err := t.Storage.Save(t.Context, backup); err != nil {
 			listBackupsReport.Errors = append(listBackupsReport.Errors, err)
 			return nil, errors.WithStack(err)
 		}
+
+		t.Logger.Debugf("toglacier: backup id “%s” synchronized with the remote information", backup.Backup.ID)
 	}
 
-	// add backups that were kept
-	for _, id := range kept {
-		if backup, ok := backups.Search(id); ok {
-			syncBackups = append(syncBackups, backup)
-		}
+	syncBackups := merge.Backups
+	if syncBackups == nil {
+		syncBackups = make(storage.Backups, 0)
+	}
+
+	if err := t.Storage.SaveInventoryDate(t.Context, t.now()); err != nil {
+		listBackupsReport.Errors = append(listBackupsReport.Errors, err)
+		return nil, errors.WithStack(err)
 	}
 
 	sort.Sort(backupsByCreationDate(syncBackups))
 	return syncBackups, nil
 }
 
+// inventoryDate returns the date of the last synchronization with the cloud
+// inventory only if it's still inside the maxAge limit. Otherwise a zero date
+// is returned.
+func (t ToGlacier) inventoryDate(maxAge time.Duration) (time.Time, error) {
+	if maxAge <= 0 {
+		return time.Time{}, nil
+	}
+
+	inventoryDate, err := t.Storage.InventoryDate(t.Context)
+	if err != nil {
+		return time.Time{}, errors.WithStack(err)
+	}
+
+	if inventoryDate.IsZero() || t.now().Sub(inventoryDate) > maxAge {
+		return time.Time{}, nil
+	}
+
+	return inventoryDate, nil
+}
+
 // RetrieveBackup recover a specific backup from the cloud. If the backup is
 // encrypted it can be decrypted if the backupSecret is informed. Also, it is
 // possible to avoid downloading backups that contain only unmodified files with
-// the skipUnmodified flag.
-func (t ToGlacier) RetrieveBackup(id, backupSecret string, skipUnmodified bool) error {
-	backups, err := t.Storage.List()
+// the skipUnmodified flag. The progress of the retrieval is persisted in the
+// local storage, so if the process is interrupted it will continue from the
+// archives that were already downloaded. When the archives can't be retrieved
+// from their cloud, they are retrieved from the replica. The overwrite policy
+// defines what happens with each existing file (replaced, kept, renamed or
+// replaced only by a newer file), and it is applied when the archive is an
+// archive.TARBuilder or an archive.ZIPBuilder.
+func (t ToGlacier) RetrieveBackup(id, backupSecret string, skipUnmodified bool, overwrite archive.OverwritePolicy) error {
+	return errors.WithStack(t.RetrieveBackupPaths(id, "", backupSecret, skipUnmodified, overwrite))
+}
+
+// RetrieveBackupPaths recover a specific backup from the cloud like
+// RetrieveBackup, but only the files matching the glob pattern (or inside a
+// directory matching it) are restored, and the archives without these files
+// aren't downloaded. When the archive information of the backup isn't in the
+// local storage, it is retrieved from the catalog or the main archive before
+// selecting the files. An empty pattern restores all files. A report with the
+// restored, skipped and failed files is added to the reports.
+func (t ToGlacier) RetrieveBackupPaths(id, pattern, backupSecret string, skipUnmodified bool, overwrite archive.OverwritePolicy) (err error) {
+	var finish func()
+	t.Context, finish = t.startOperation("retrieve")
+	defer finish()
+
+	var span trace.Span
+	t.Context, span = t.startSpan("retrieve")
+	span.SetAttribute("backup.id", id)
+	defer func() {
+		span.End(err)
+	}()
+
+	if _, err = filepath.Match(pattern, ""); err != nil {
+		return errors.WithStack(err)
+	}
+
+	retrieveBackupReport := report.NewRetrieveBackup()
+	retrieveBackupReport.ID = id
+
+	defer func() {
+		sort.Strings(retrieveBackupReport.Archives)
+
+		if err != nil {
+			retrieveBackupReport.Errors = append(retrieveBackupReport.Errors, err)
+			t.events().OnError("retrieve backup", err)
+		} else {
+			t.events().OnRetrievalComplete(retrieveBackupReport)
+		}
+
+		t.reports().Add(retrieveBackupReport)
+
+		// large retrievals can take hours, so the messengers are notified right
+		// away, while the e-mail goes with the other reports
+		if len(t.Messengers) > 0 {
+			t.sendMessages(t.subject(report.Translate("toglacier retrieval")), retrieveBackupReport)
+		}
+	}()
+
+	extractProgress := retrieveProgress{report: &retrieveBackupReport}
+
+	backups, err := t.Storage.List(t.Context)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -281,28 +975,81 @@ func (t ToGlacier) RetrieveBackup(id, backupSecret string, skipUnmodified bool)
 		t.Logger.Warningf("toglacier: backup “%s” not found in local storage")
 	}
 
+	if selectedBackup.Backup.Stream != "" {
+		return errors.WithStack(newError([]string{selectedBackup.Backup.Stream}, ErrorCodeStreamBackup, nil))
+	}
+
+	// all parts of an incremental backup are stored in the same route
+	t.Cloud = t.vaultCloud(selectedBackup.Backup.VaultName)
+
+	progress, err := t.Storage.RestoreProgress(t.Context, id)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if progress.Downloaded == nil {
+		progress.Downloaded = make(map[string]string)
+	}
+
+	if progress.Extracted == nil {
+		progress.Extracted = make(map[string]bool)
+	}
+
 	var ignoreMainBackup bool
 
-	if selectedBackup.Info == nil {
+	if selectedBackup.Info == nil && progress.Info != nil {
+		t.Logger.Infof("toglacier: resuming the retrieval of backup “%s”", id)
+
+		// the main backup was already downloaded and extracted in a previous
+		// attempt
+		selectedBackup.Info = progress.Info
+		ignoreMainBackup = true
+
+	} else if selectedBackup.Info == nil && pattern != "" {
+		// the files matching the pattern are only known with the archive
+		// information, so the main archive is extracted with the other parts
+		timeMark := time.Now()
+		selectedBackup.Info, err = t.retrievalInfo(id, selectedBackup, backups, progress, backupSecret)
+		retrieveBackupReport.Durations.Download += time.Now().Sub(timeMark)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+	} else if selectedBackup.Info == nil {
 		var filenames map[string]string
 
 		// when there's no archive information, retrieve only the desired backup ID.
 		// We will extract the archive information saved in the backup to detect all
 		// other backup parts that we need. This is important when the local storage
 		// got corrupted due to a disaster
-		if filenames, err = t.Cloud.Get(t.Context, id); err != nil {
+		timeMark := time.Now()
+		filenames, err = t.download(id, progress, backups, id)
+		retrieveBackupReport.Durations.Download += time.Now().Sub(timeMark)
+
+		if err != nil {
 			return errors.WithStack(err)
 		}
 
 		// there's only one backup downloaded at this point
-		if selectedBackup.Info, err = t.decryptAndExtract(backupSecret, filenames[id], nil); err != nil {
+		timeMark = time.Now()
+		selectedBackup.Info, err = t.decryptAndExtract(backupSecret, filenames[id], nil, overwrite, extractProgress)
+		retrieveBackupReport.Durations.Extract += time.Now().Sub(timeMark)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		retrieveBackupReport.Archives = append(retrieveBackupReport.Archives, id)
+
+		progress.Info = selectedBackup.Info
+		if err = t.archiveExtracted(id, progress, id); err != nil {
 			return errors.WithStack(err)
 		}
 
 		// synchronize the archive information in the local storage only if the
 		// backup exists
 		if selectedBackup.Backup.ID != "" {
-			if err = t.Storage.Save(selectedBackup); err != nil {
+			if err = t.Storage.Save(t.Context, selectedBackup); err != nil {
 				return errors.WithStack(err)
 			}
 		}
@@ -312,22 +1059,56 @@ func (t ToGlacier) RetrieveBackup(id, backupSecret string, skipUnmodified bool)
 		ignoreMainBackup = true
 	}
 
-	ids, idPaths, err := t.extractIDs(id, selectedBackup.Info, ignoreMainBackup, skipUnmodified)
+	ids, idPaths, unmodified, err := t.extractIDs(id, pattern, selectedBackup.Info, ignoreMainBackup, skipUnmodified)
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	retrieveBackupReport.Skipped += unmodified
+
+	// archives extracted in a previous attempt don't need to be retrieved again
+	var pendingIDs []string
+	for _, archiveID := range ids {
+		if progress.Extracted[archiveID] {
+			t.Logger.Infof("toglacier: archive “%s” was already extracted, it will be ignored", archiveID)
+			continue
+		}
+
+		pendingIDs = append(pendingIDs, archiveID)
+	}
+
+	timeMark := time.Now()
+	filenames, err := t.download(id, progress, backups, pendingIDs...)
+	retrieveBackupReport.Durations.Download += time.Now().Sub(timeMark)
 
-	filenames, err := t.Cloud.Get(t.Context, ids...)
 	if err != nil {
+		for _, archiveID := range pendingIDs {
+			retrieveBackupReport.Failed += len(idPaths[archiveID])
+		}
 		return errors.WithStack(err)
 	}
 
-	for id, filename := range filenames {
-		if selectedBackup, ok = backups.Search(id); !ok {
+	for archiveID, filename := range filenames {
+		if selectedBackup, ok = backups.Search(archiveID); !ok {
 			t.Logger.Warningf("toglacier: backup “%s” not found in local storage")
 		}
 
-		if selectedBackup.Info, err = t.decryptAndExtract(backupSecret, filename, idPaths[id]); err != nil {
+		handled := extractProgress.handled()
+
+		timeMark = time.Now()
+		selectedBackup.Info, err = t.decryptAndExtract(backupSecret, filename, idPaths[archiveID], overwrite, extractProgress)
+		retrieveBackupReport.Durations.Extract += time.Now().Sub(timeMark)
+
+		if err != nil {
+			// the files already reported by the extraction (restored, kept or
+			// discarded) aren't counted again, only the ones left behind
+			if pending := len(idPaths[archiveID]) - (extractProgress.handled() - handled); pending > 0 {
+				retrieveBackupReport.Failed += pending
+			}
+			return errors.WithStack(err)
+		}
+		retrieveBackupReport.Archives = append(retrieveBackupReport.Archives, archiveID)
+
+		if err = t.archiveExtracted(id, progress, archiveID); err != nil {
 			return errors.WithStack(err)
 		}
 
@@ -336,27 +1117,231 @@ func (t ToGlacier) RetrieveBackup(id, backupSecret string, skipUnmodified bool)
 		}
 	}
 
-	return nil
+	return errors.WithStack(t.Storage.RemoveRestoreProgress(t.Context, id))
+}
+
+// download retrieves the archives from the cloud, reusing the files that were
+// already downloaded in a previous attempt of the backup retrieval. The
+// downloaded files are persisted in the restore progress. When the archives
+// have parity data, it is downloaded together to repair any corruption. When
+// the cloud fails, the archives are retrieved from the replica.
+func (t ToGlacier) download(id string, progress storage.RestoreProgress, backups storage.Backups, archiveIDs ...string) (map[string]string, error) {
+	filenames := make(map[string]string)
+
+	var missingIDs []string
+	for _, archiveID := range archiveIDs {
+		if filename, ok := progress.Downloaded[archiveID]; ok {
+			if _, err := os.Stat(filename); err == nil {
+				t.Logger.Infof("toglacier: archive “%s” was already downloaded to “%s”", archiveID, filename)
+				filenames[archiveID] = filename
+				continue
+			}
+		}
+
+		missingIDs = append(missingIDs, archiveID)
+	}
+
+	if len(missingIDs) == 0 {
+		return filenames, nil
+	}
+
+	requestedIDs := missingIDs
+	parityIDs := make(map[string]string)
+
+	if t.Parity != nil {
+		requestedIDs = append([]string(nil), missingIDs...)
+		for _, archiveID := range missingIDs {
+			if backup, ok := backups.Search(archiveID); ok && backup.Backup.ParityID != "" {
+				parityIDs[archiveID] = backup.Backup.ParityID
+				requestedIDs = append(requestedIDs, backup.Backup.ParityID)
+			}
+		}
+	}
+
+	if progress.Jobs == nil {
+		progress.Jobs = make(map[string]string)
+	}
+
+	if progress.Parts == nil {
+		progress.Parts = make(map[string]storage.PartialDownload)
+	}
+
+	tracker := &restoreTracker{
+		toGlacier: t,
+		id:        id,
+		progress:  progress,
+	}
+
+	ctx, span := t.startSpan("download")
+	span.SetAttribute("archives", len(requestedIDs))
+	downloaded, err := t.Cloud.Get(cloud.WithRetrievalTracker(ctx, tracker), requestedIDs...)
+	span.End(err)
+	if err != nil {
+		if downloaded, err = t.replicaGet(missingIDs, backups, err); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		// the parity data is only stored in the cloud of the backup
+		parityIDs = nil
+	}
+
+	for archiveID, parityID := range parityIDs {
+		parityFilename, ok := downloaded[parityID]
+		if !ok {
+			continue
+		}
+		delete(downloaded, parityID)
+
+		// if the archive can't be repaired we still try to use it, as the
+		// extraction will detect if it is really unusable
+		if err := t.Parity.Repair(downloaded[archiveID], parityFilename); err != nil {
+			t.Logger.Warningf("toglacier: failed to repair archive “%s”. details: %s", archiveID, err)
+		}
+
+		if err := os.Remove(parityFilename); err != nil {
+			t.Logger.Warningf("toglacier: failed to remove file “%s”. details: %s", parityFilename, err)
+		}
+	}
+
+	for archiveID, filename := range downloaded {
+		filenames[archiveID] = filename
+		progress.Downloaded[archiveID] = filename
+	}
+
+	for _, archiveID := range requestedIDs {
+		delete(progress.Jobs, archiveID)
+		delete(progress.Parts, archiveID)
+	}
+
+	if err := t.Storage.SaveRestoreProgress(t.Context, id, progress); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	readyIDs := make([]string, 0, len(filenames))
+	for archiveID := range filenames {
+		readyIDs = append(readyIDs, archiveID)
+	}
+	sort.Strings(readyIDs)
+
+	t.events().OnRetrievalReady(id, readyIDs)
+	return filenames, nil
 }
 
-func (t ToGlacier) extractIDs(id string, archiveInfo archive.Info, ignoreMainBackup, skipUnmodified bool) (ids []string, idPaths map[string][]string, err error) {
+// replicaGet retrieves the archives from the replica after the cloud failed
+// with getErr. The cloud error is returned when there's no replica, when the
+// retrieval was cancelled or when some archive doesn't have a copy.
+func (t ToGlacier) replicaGet(archiveIDs []string, backups storage.Backups, getErr error) (map[string]string, error) {
+	if t.Replica == nil {
+		return nil, getErr
+	}
+
+	if cancelled(getErr) {
+		return nil, getErr
+	}
+
+	archiveIDsByReplica := make(map[string]string)
+	replicaIDs := make([]string, 0, len(archiveIDs))
+	for _, archiveID := range archiveIDs {
+		backup, ok := backups.Search(archiveID)
+		if !ok || backup.Backup.ReplicaID == "" {
+			return nil, getErr
+		}
+
+		archiveIDsByReplica[backup.Backup.ReplicaID] = archiveID
+		replicaIDs = append(replicaIDs, backup.Backup.ReplicaID)
+	}
+
+	t.Logger.Warningf("toglacier: failed to retrieve archives “%v”, retrieving them from the replica. details: %s", archiveIDs, getErr)
+
+	replicaDownloaded, err := t.Replica.Get(t.Context, replicaIDs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	downloaded := make(map[string]string)
+	for replicaID, filename := range replicaDownloaded {
+		downloaded[archiveIDsByReplica[replicaID]] = filename
+	}
+
+	return downloaded, nil
+}
+
+// restoreTracker persists in the restore progress the jobs and the parts of the
+// archives retrieved from the cloud as soon as they change, so an interrupted
+// restore doesn't start the retrieval from scratch. The archives are retrieved
+// concurrently, so the progress is protected.
+type restoreTracker struct {
+	toGlacier ToGlacier
+	id        string
+	progress  storage.RestoreProgress
+	mutex     sync.Mutex
+}
+
+// Job returns the job retrieving the archive in a previous restore attempt.
+func (r *restoreTracker) Job(id string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.progress.Jobs[id]
+}
+
+// JobInitiated persists the job retrieving the archive.
+func (r *restoreTracker) JobInitiated(id, jobID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.progress.Jobs[id] = jobID
+	return errors.WithStack(r.toGlacier.Storage.SaveRestoreProgress(r.toGlacier.Context, r.id, r.progress))
+}
+
+// Part returns the part of the archive downloaded in a previous restore
+// attempt.
+func (r *restoreTracker) Part(id string) (string, int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	part := r.progress.Parts[id]
+	return part.Filename, part.Size
+}
+
+// PartDownloaded persists the part of the archive downloaded so far.
+func (r *restoreTracker) PartDownloaded(id, filename string, size int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.progress.Parts[id] = storage.PartialDownload{
+		Filename: filename,
+		Size:     size,
+	}
+	return errors.WithStack(r.toGlacier.Storage.SaveRestoreProgress(r.toGlacier.Context, r.id, r.progress))
+}
+
+// archiveExtracted persists in the restore progress that the archive was
+// extracted, as the downloaded file is removed after the extraction.
+func (t ToGlacier) archiveExtracted(id string, progress storage.RestoreProgress, archiveID string) error {
+	delete(progress.Downloaded, archiveID)
+	progress.Extracted[archiveID] = true
+	return errors.WithStack(t.Storage.SaveRestoreProgress(t.Context, id, progress))
+}
+
+func (t ToGlacier) extractIDs(id, pattern string, archiveInfo archive.Info, ignoreMainBackup, skipUnmodified bool) (ids []string, idPaths map[string][]string, unmodified int, err error) {
 	idPaths = make(map[string][]string)
 	for path, itemInfo := range archiveInfo {
 		// if we already downloaded the main backup we don't need to download it
 		// again, and we should also avoid downloading backups parts just to
-		// retrieve removed files
-		ignore := (ignoreMainBackup && itemInfo.ID == id) || itemInfo.Status == archive.ItemInfoStatusDeleted
+		// retrieve removed files or files that weren't requested
+		ignore := (ignoreMainBackup && itemInfo.ID == id) || itemInfo.Status == archive.ItemInfoStatusDeleted || !matchPath(pattern, path)
 
 		if !ignore && skipUnmodified {
 			var checksum string
 			if checksum, err = t.Archive.FileChecksum(path); err != nil {
-				return nil, nil, errors.WithStack(err)
+				return nil, nil, 0, errors.WithStack(err)
 			}
 
 			// file did not change since this backup
 			if checksum == itemInfo.Checksum {
 				t.Logger.Infof("toglacier: file “%s” unmodified in disk since backup, it will be ignored", path)
 				ignore = true
+				unmodified++
 			}
 		}
 
@@ -371,8 +1356,24 @@ func (t ToGlacier) extractIDs(id string, archiveInfo archive.Info, ignoreMainBac
 	return
 }
 
-func (t ToGlacier) decryptAndExtract(backupSecret, filename string, filter []string) (archive.Info, error) {
-	var err error
+func (t ToGlacier) decryptAndExtract(backupSecret, filename string, filter []string, overwrite archive.OverwritePolicy, progress archive.ExtractProgress) (archiveInfo archive.Info, err error) {
+	var span trace.Span
+	t.Context, span = t.startSpan("extract")
+	defer func() {
+		span.End(err)
+	}()
+
+	if t.Archive, t.Envelop, backupSecret, err = t.readHeader(filename, backupSecret); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	extractor := t.extractor(overwrite, progress)
+	streamEnvelop, isStreamEnvelop := t.Envelop.(archive.StreamEnvelop)
+	streamExtractor, isStreamExtractor := extractor.(archive.StreamExtractor)
+
+	if backupSecret != "" && isStreamEnvelop && isStreamExtractor {
+		return t.decryptAndExtractStream(streamEnvelop, streamExtractor, backupSecret, filename, filter)
+	}
 
 	if backupSecret != "" {
 		var decryptedFilename string
@@ -386,7 +1387,82 @@ func (t ToGlacier) decryptAndExtract(backupSecret, filename string, filter []str
 		}
 	}
 
-	archiveInfo, err := t.Archive.Extract(filename, filter)
+	archiveInfo, err = extractor.Extract(t.Context, filename, filter)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// after extracting the content we don't need the archive anymore, but if
+	// there's some error removing it we don't want to stop the process
+	if err = os.Remove(filename); err != nil {
+		t.Logger.Warningf("toglacier: failed to remove file “%s”. details: %s", filename, err)
+	}
+
+	return archiveInfo, nil
+}
+
+// extractor returns the archive with the overwrite policy, the all or nothing
+// option and the extraction progress, without changing the archive used by
+// other operations.
+func (t ToGlacier) extractor(overwrite archive.OverwritePolicy, progress archive.ExtractProgress) archive.Archive {
+	switch builder := t.Archive.(type) {
+	case *archive.TARBuilder:
+		extractor := *builder
+		extractor.Overwrite = overwrite
+		extractor.AllOrNothing = t.AllOrNothing
+		extractor.ExtractProgress = progress
+		return &extractor
+	case *archive.ZIPBuilder:
+		extractor := *builder
+		extractor.Overwrite = overwrite
+		extractor.AllOrNothing = t.AllOrNothing
+		extractor.ExtractProgress = progress
+		return &extractor
+	}
+
+	return t.Archive
+}
+
+// retrieveProgress counts the files written by the extraction in the backup
+// retrieval report, listing the errors of the files that weren't restored.
+type retrieveProgress struct {
+	report *report.RetrieveBackup
+}
+
+func (r retrieveProgress) OnExtract(path string, size int64, extracted bool) {
+	if !extracted {
+		r.report.Skipped++
+		return
+	}
+
+	r.report.Restored++
+	r.report.Size += size
+}
+
+func (r retrieveProgress) OnExtractFailure(path string, err error) {
+	r.report.Failed++
+	r.report.Errors = append(r.report.Errors, err)
+}
+
+// handled returns the number of files already reported, so a failed archive
+// only counts the files that weren't.
+func (r retrieveProgress) handled() int {
+	return r.report.Restored + r.report.Skipped + r.report.Failed
+}
+
+// decryptAndExtractStream extracts the archive while it is decrypted, so only
+// the selected files are written to disk, without a decrypted copy of the whole
+// archive. The downloaded archive is kept when the extraction fails, so it
+// doesn't need to be retrieved again.
+func (t ToGlacier) decryptAndExtractStream(envelop archive.StreamEnvelop, extractor archive.StreamExtractor, backupSecret, filename string, filter []string) (archive.Info, error) {
+	reader, err := envelop.DecryptReader(filename, backupSecret)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	archiveInfo, err := extractor.ExtractReader(t.Context, reader, filter)
+	reader.Close()
+
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -418,35 +1494,180 @@ func (t ToGlacier) synchronizeArchiveInfo(backup storage.Backup, backups storage
 		return nil
 	}
 
-	return errors.WithStack(t.Storage.Save(backup))
+	return errors.WithStack(t.Storage.Save(t.Context, backup))
 }
 
 // RemoveBackups delete a backups identified by ids from the cloud and from the
 // local storage. It will also try to replace or remove the reference from the
 // removed backup on other backups. When it is possible to replace the reference
 // it will try to get the file version right before the removed backup date.
-func (t ToGlacier) RemoveBackups(ids ...string) error {
+// When the local storage supports it, the references of many backups are
+// updated in a single transaction. When there's a trash, the backups are only
+// moved to the trash, and they are removed by EmptyTrash after the trash
+// period. The removal is recorded in the audit log of the destructive
+// operations.
+func (t ToGlacier) RemoveBackups(ids ...string) (err error) {
+	if err := t.writable("remove backups"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if t.Trash != nil {
+		err = t.trashBackups(ids)
+		t.recordOperation("trash backups", ids, err)
+		return errors.WithStack(err)
+	}
+
+	defer func() {
+		t.recordOperation("remove backups", ids, err)
+	}()
+
+	if batchRemover, ok := t.Storage.(storage.BatchRemover); ok && len(ids) > 1 {
+		return errors.WithStack(t.removeBackupsBatch(batchRemover, ids))
+	}
+
 	for _, id := range ids {
 		if err := t.removeBackup(id); err != nil {
+			t.events().OnError("remove backup", err)
 			return errors.WithStack(err)
 		}
+
+		t.events().OnBackupRemoved(id)
 	}
 
 	return nil
 }
 
+// removeBackupsBatch removes the archives from the cloud one after another, and
+// then updates the local storage in a single transaction. When the removal of
+// an archive fails, the backups already removed from the cloud are still
+// removed from the local storage, so it doesn't reference missing archives.
+func (t ToGlacier) removeBackupsBatch(batchRemover storage.BatchRemover, ids []string) error {
+	var removed []string
+	var removeErr error
+
+	for _, id := range ids {
+		if err := t.removeArchives(id); err != nil {
+			t.events().OnError("remove backup", err)
+			removeErr = errors.WithStack(err)
+			break
+		}
+
+		removed = append(removed, id)
+	}
+
+	if len(removed) == 0 {
+		return removeErr
+	}
+
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		t.events().OnError("remove backup", err)
+		return errors.WithStack(err)
+	}
+
+	modified := make(map[string]bool)
+	for _, id := range removed {
+		for _, backup := range t.rearrangeBackups(backups, id) {
+			modified[backup.Backup.ID] = true
+		}
+
+		// a removed backup can't be the fallback of the references of the next
+		// removed backups
+		for i, backup := range backups {
+			if backup.Backup.ID == id {
+				backups = append(backups[:i], backups[i+1:]...)
+				break
+			}
+		}
+	}
+
+	var updated storage.Backups
+	for _, backup := range backups {
+		if modified[backup.Backup.ID] {
+			updated = append(updated, backup)
+		}
+	}
+
+	if err = batchRemover.RemoveBatch(t.Context, updated, removed); err != nil {
+		// TODO: an error here will cause an inconsistency between the cloud and the
+		// local storage
+		t.events().OnError("remove backup", err)
+		return errors.WithStack(err)
+	}
+
+	for _, id := range removed {
+		t.events().OnBackupRemoved(id)
+	}
+
+	return removeErr
+}
+
 func (t ToGlacier) removeBackup(id string) error {
-	if err := t.Cloud.Remove(t.Context, id); err != nil {
+	if err := t.removeArchives(id); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(t.forgetBackup(id))
+}
+
+// removeArchives removes the archive of the backup from the cloud, together
+// with its companion archives and the copy in the replica. It doesn't change
+// the local storage, so many backups can be removed from the cloud at the same
+// time.
+func (t ToGlacier) removeArchives(id string) error {
+	backupCloud, err := t.backupCloud(id)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// the companion archives are stored together with the backup
+	t.Cloud = backupCloud
+
+	if err = t.Cloud.Remove(t.Context, id); err != nil {
+		return errors.WithStack(err)
+	}
+
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		// TODO: an error here will cause an inconsistency between the cloud and the
+		// local storage
 		return errors.WithStack(err)
 	}
 
+	// the parity data, the catalog and the copy in the replica are useless
+	// without the backup, but if we can't remove them the backup removal is still valid
+	if backup, ok := backups.Search(id); ok {
+		for _, companionID := range []string{backup.Backup.ParityID, backup.Backup.CatalogID} {
+			if companionID == "" {
+				continue
+			}
+
+			if err = t.Cloud.Remove(t.Context, companionID); err != nil {
+				t.Logger.Warningf("toglacier: failed to remove companion archive “%s” of backup “%s”. details: %s", companionID, id, err)
+			}
+		}
+
+		if t.Replica != nil && backup.Backup.ReplicaID != "" {
+			if err = t.Replica.Remove(t.Context, backup.Backup.ReplicaID); err != nil {
+				t.Logger.Warningf("toglacier: failed to remove copy “%s” of backup “%s” from the replica. details: %s", backup.Backup.ReplicaID, id, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// forgetBackup removes the backup from the local storage, replacing the
+// references to its archive in the other backups. The local storage is
+// rewritten, so only one backup can be forgotten at a time.
+func (t ToGlacier) forgetBackup(id string) error {
 	if err := t.rearrangeStorage(id); err != nil {
 		// TODO: an error here will cause an inconsistency between the cloud and the
 		// local storage
 		return errors.WithStack(err)
 	}
 
-	if err := t.Storage.Remove(id); err != nil {
+	if err := t.Storage.Remove(t.Context, id); err != nil {
 		// TODO: an error here will cause an inconsistency between the cloud and the
 		// local storage
 		return errors.WithStack(err)
@@ -460,11 +1681,24 @@ func (t ToGlacier) rearrangeStorage(id string) error {
 	// of the local storage. We will try to replace the reference id by the most
 	// recently version of the file when possible
 
-	backups, err := t.Storage.List()
+	backups, err := t.Storage.List(t.Context)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	for _, backup := range t.rearrangeBackups(backups, id) {
+		if err = t.Storage.Save(t.Context, backup); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+// rearrangeBackups replaces the references to the removed backup in the other
+// backups, returning the modified ones. The backups are sorted by creation
+// date.
+func (t ToGlacier) rearrangeBackups(backups storage.Backups, id string) storage.Backups {
 	// order backups by creation date
 	sort.Sort(backupsByCreationDate(backups))
 
@@ -505,15 +1739,14 @@ func (t ToGlacier) rearrangeStorage(id string) error {
 	// now we need to look for backups that were created after the removed one, so
 	// we can replace the reference of the files or removed if we couldn't find
 	// any match
+	var modified storage.Backups
 	for i := backupIndex - 1; i >= 0; i-- {
 		if t.rearrangeArchiveInfo(id, backups[i].Info, fallbackFiles) {
-			if err = t.Storage.Save(backups[i]); err != nil {
-				return errors.WithStack(err)
-			}
+			modified = append(modified, backups[i])
 		}
 	}
 
-	return nil
+	return modified
 }
 
 func (t ToGlacier) rearrangeArchiveInfo(id string, archiveInfo archive.Info, fallbackFiles map[string]string) (modified bool) {
@@ -543,15 +1776,41 @@ func (t ToGlacier) rearrangeArchiveInfo(id string, archiveInfo archive.Info, fal
 }
 
 // RemoveOldBackups delete old backups from the cloud. This will optimize the
-// cloud space usage, as too old backups aren't used.
-func (t ToGlacier) RemoveOldBackups(keepBackups int) error {
+// cloud space usage, as too old backups aren't used. Old backups that store
+// files still referenced by the kept backups (or by the backups in the recovery
+// journal or in the trash) are preserved, as they are needed to restore the
+// latest state. The pinned backups, and the archives that they reference, are
+// never removed while the hold is active. Up to RemoveConcurrency backups are
+// removed at the same time, and a failure doesn't stop the removal of the
+// other backups. On partial failure it will return an Error type
+// (ErrorCodeRemovingBackups) encapsulated in a traceable error. The removal is
+// recorded in the audit log of the destructive operations. With GroupByDay the
+// keepBackups is the number of calendar days kept, with all their backups.
+//
+// The archive information of a kept backup that isn't in the local storage is
+// retrieved from the cloud, using the backup secret to decrypt it. When it
+// can't be retrieved no backup is removed, and it will return an Error type
+// (ErrorCodeUnknownReferences) encapsulated in a traceable error.
+func (t ToGlacier) RemoveOldBackups(keepBackups int, backupSecret string) (err error) {
+	if err := t.writable("remove old backups"); err != nil {
+		return errors.WithStack(err)
+	}
+
 	removeOldBackupsReport := report.NewRemoveOldBackups()
 	defer func() {
-		report.Add(removeOldBackupsReport)
+		t.reports().Add(removeOldBackupsReport)
+	}()
+
+	var removed []string
+	defer func() {
+		// nothing to record when all backups are recent
+		if len(removed) > 0 || err != nil {
+			t.recordOperation("remove old backups", removed, err)
+		}
 	}()
 
 	timeMark := time.Now()
-	backups, err := t.ListBackups(false)
+	backups, err := t.listBackups(false, 0, false)
 	removeOldBackupsReport.Durations.List = time.Now().Sub(timeMark)
 
 	if err != nil {
@@ -561,58 +1820,386 @@ func (t ToGlacier) RemoveOldBackups(keepBackups int) error {
 
 	sort.Sort(backupsByCreationDate(backups))
 
-	// with the incremental backup we cannot remove backups without checking the
-	// archive info to identify partial backup entries
-	var preserveBackups []string
-	for i := 0; i < keepBackups && i < len(backups); i++ {
-		for _, itemInfo := range backups[i].Info {
-			if itemInfo.Status != archive.ItemInfoStatusDeleted {
-				preserveBackups = append(preserveBackups, itemInfo.ID)
+	pins, err := t.Storage.Pins(t.Context)
+	if err != nil {
+		removeOldBackupsReport.Errors = append(removeOldBackupsReport.Errors, err)
+		return errors.WithStack(err)
+	}
+
+	now := t.now()
+	pinned := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		if pin.Active(now) {
+			pinned[pin.ID] = true
+		}
+	}
+
+	// each route is a different retention domain, so the number of backups is
+	// kept for each one of them. The pinned backups are always kept, without
+	// taking the place of the newest backups. When grouping by day, the backups
+	// of an already kept day don't take another place
+	var keptBackups, oldBackups storage.Backups
+	for _, backups := range t.backupsByRoute(backups) {
+		var kept int
+		var lastKeptDay time.Time
+		for _, backup := range backups {
+			var day time.Time
+			if t.GroupByDay {
+				day = calendarDay(backup.Backup.CreatedAt, t.groupLocation())
+			}
+
+			switch {
+			case pinned[backup.Backup.ID]:
+				keptBackups = append(keptBackups, backup)
+				removeOldBackupsReport.Pinned = append(removeOldBackupsReport.Pinned, backup.Backup)
+			case t.GroupByDay && kept > 0 && day.Equal(lastKeptDay):
+				keptBackups = append(keptBackups, backup)
+			case kept < keepBackups:
+				keptBackups = append(keptBackups, backup)
+				lastKeptDay = day
+				kept++
+			default:
+				oldBackups = append(oldBackups, backup)
+			}
+		}
+	}
+
+	// the backups in the recovery journal are newer than the local ones, and
+	// also reference old archives for the unmodified files
+	if t.Journal != nil {
+		pending, err := t.Journal.List(t.Context)
+		if err != nil {
+			removeOldBackupsReport.Errors = append(removeOldBackupsReport.Errors, err)
+			return errors.WithStack(err)
+		}
+		keptBackups = append(keptBackups, pending...)
+	}
+
+	// the backups in the trash can still be restored, so the archives that they
+	// reference are needed until the trash is emptied
+	trashed, err := t.trashedBackups()
+	if err != nil {
+		removeOldBackupsReport.Errors = append(removeOldBackupsReport.Errors, err)
+		return errors.WithStack(err)
+	}
+	keptBackups = append(keptBackups, trashed...)
+
+	// without the archive info of a kept backup (e.g. saved by older versions
+	// of the audit file or synchronized from the cloud inventory) its
+	// references are unknown, so it is retrieved from the catalog or the archive
+	// of the backup. When it still can't be retrieved an old backup could be
+	// needed to restore it, so none is removed
+	if len(oldBackups) > 0 {
+		for i, backup := range keptBackups {
+			if backup.Info != nil {
+				continue
+			}
+
+			archiveInfo, err := t.backupInfo(backup, backups, backupSecret)
+			if err != nil {
+				t.Logger.Warningf("toglacier: failed to retrieve the archive information of backup “%s”. details: %s", backup.Backup.ID, err)
+				continue
 			}
+			keptBackups[i].Info = archiveInfo
 		}
 	}
-	sort.Strings(preserveBackups)
+
+	if unknown := unknownReferences(keptBackups); len(oldBackups) > 0 && len(unknown) > 0 {
+		err := fmt.Errorf("backups without archive information: %s", strings.Join(unknown, ", "))
+		err = newError(nil, ErrorCodeUnknownReferences, err)
+		removeOldBackupsReport.Errors = append(removeOldBackupsReport.Errors, err)
+		return errors.WithStack(err)
+	}
+
+	// with the incremental backup we cannot remove backups without checking the
+	// archive info to identify partial backup entries. The references are
+	// counted over all routes, as a backup can reference archives sent before
+	// the routes were changed
+	references := referencedArchives(keptBackups)
 
 	timeMark = time.Now()
-	for i := keepBackups; i < len(backups); i++ {
+
+	var removableBackups storage.Backups
+	for _, backup := range oldBackups {
 		// check if the backup isn't referenced by a active backup
-		if j := sort.SearchStrings(preserveBackups, backups[i].Backup.ID); j < len(preserveBackups) && preserveBackups[j] == backups[i].Backup.ID {
+		if references[backup.Backup.ID] > 0 {
+			removeOldBackupsReport.Preserved = append(removeOldBackupsReport.Preserved, backup.Backup)
 			continue
 		}
 
-		removeOldBackupsReport.Backups = append(removeOldBackupsReport.Backups, backups[i].Backup)
-		if err := t.RemoveBackups(backups[i].Backup.ID); err != nil {
-			removeOldBackupsReport.Errors = append(removeOldBackupsReport.Errors, err)
-			return errors.WithStack(err)
+		removableBackups = append(removableBackups, backup)
+	}
+
+	// a failure doesn't stop the removal of the other backups, so the old
+	// backups that can be removed don't wait for the next execution
+	errs := t.removeOldBackups(removableBackups)
+
+	var failures []string
+	for i, backup := range removableBackups {
+		if errs[i] != nil {
+			removeOldBackupsReport.Errors = append(removeOldBackupsReport.Errors, errs[i])
+			failures = append(failures, fmt.Sprintf("backup “%s”: %s", backup.Backup.ID, errs[i]))
+			continue
 		}
+
+		removeOldBackupsReport.Backups = append(removeOldBackupsReport.Backups, backup.Backup)
+		removed = append(removed, backup.Backup.ID)
 	}
+
 	removeOldBackupsReport.Durations.Remove = time.Now().Sub(timeMark)
 
+	if len(failures) > 0 {
+		err := fmt.Errorf("%d of %d backups not removed: %s", len(failures), len(removableBackups), strings.Join(failures, "; "))
+		return errors.WithStack(newError(nil, ErrorCodeRemovingBackups, err))
+	}
+
 	return nil
 }
 
+// removeOldBackups removes the backups with up to RemoveConcurrency removals
+// from the cloud at the same time, returning the error of each backup. The
+// changes in the local storage are still applied one at a time.
+func (t ToGlacier) removeOldBackups(backups storage.Backups) []error {
+	errs := make([]error, len(backups))
+
+	workers := t.RemoveConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	semaphore := make(chan struct{}, workers)
+	var storageLock sync.Mutex
+	var wg sync.WaitGroup
+
+	for i, backup := range backups {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(i int, id string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			err := t.removeArchives(id)
+			if err == nil {
+				storageLock.Lock()
+				err = t.forgetBackup(id)
+				storageLock.Unlock()
+			}
+
+			if err != nil {
+				t.events().OnError("remove backup", err)
+				errs[i] = errors.WithStack(err)
+				return
+			}
+
+			t.events().OnBackupRemoved(id)
+		}(i, backup.Backup.ID)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+// referencedArchives counts the files of the backups stored in each archive,
+// so an archive with references is still needed to restore them.
+func referencedArchives(backups storage.Backups) map[string]int {
+	references := make(map[string]int)
+	for _, backup := range backups {
+		for _, itemInfo := range backup.Info {
+			if itemInfo.Status != archive.ItemInfoStatusDeleted {
+				references[itemInfo.ID]++
+			}
+		}
+	}
+
+	return references
+}
+
+// unknownReferences returns the backups without archive information, whose
+// referenced archives can't be counted.
+func unknownReferences(backups storage.Backups) []string {
+	var ids []string
+	for _, backup := range backups {
+		if backup.Info == nil {
+			ids = append(ids, backup.Backup.ID)
+		}
+	}
+
+	return ids
+}
+
+// CompactStorage rewrites the local storage keeping only the latest
+// information of each backup and dropping the corrupted records, returning the
+// number of dropped records. Local storages that don't accumulate obsolete
+// records (like BoltDB) aren't changed. A rewrite that drops records is
+// recorded in the audit log of the destructive operations. On error it will
+// return an Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) CompactStorage() (int, error) {
+	compactor, ok := t.Storage.(storage.Compactor)
+	if !ok {
+		return 0, nil
+	}
+
+	dropped, err := compactor.Compact(t.Context)
+	if dropped > 0 || err != nil {
+		t.recordOperation("compact storage", nil, err)
+	}
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return dropped, nil
+}
+
 // SendReport send information from the actions performed by this tool via
-// e-mail to an administrator.
+// e-mail to an administrator. The backup reports of the routes with their own
+// notification settings are sent separately. When they can't be sent, they are
+// sent with the other reports. The HTML reports also chart the size and the
+// duration of the retained backups.
 func (t ToGlacier) SendReport(emailInfo EmailInfo) error {
-	r, err := report.Build(emailInfo.Format)
+	for _, route := range t.Routes {
+		if route.Notification != nil {
+			t.sendRouteReport(emailInfo, route)
+		}
+	}
+
+	// only the HTML format can contain charts
+	if emailInfo.Format == report.FormatHTML {
+		t.addHistoryReport()
+	}
+
+	r, err := t.reports().Build(emailInfo.Format)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(t.sendEmail(emailInfo, t.subject(report.Translate("toglacier report")), "", r))
+}
+
+// sendRouteReport sends the backup reports of the route with its notification
+// settings.
+func (t ToGlacier) sendRouteReport(emailInfo EmailInfo, route Route) {
+	reports := t.reports().Extract(func(r report.Report) bool {
+		backupReport, ok := r.(report.SendBackup)
+		return ok && backupReport.Route == route.VaultName
+	})
+
+	var failed bool
+	for _, r := range reports {
+		if len(r.(report.SendBackup).Errors) > 0 {
+			failed = true
+			break
+		}
+	}
+
+	if len(reports) == 0 || (route.Notification.ErrorsOnly && !failed) {
+		return
+	}
+
+	if len(route.Notification.To) > 0 {
+		emailInfo.To = route.Notification.To
+	}
+
+	if route.Notification.Format != "" {
+		emailInfo.Format = route.Notification.Format
+	}
+
+	r, err := report.BuildReports(emailInfo.Format, reports...)
+	if err == nil {
+		err = t.sendEmail(emailInfo, t.subject(report.Translate("toglacier report [%s]", route.Prefix)), "", r)
+	}
+
+	if err != nil {
+		t.Logger.Warningf("toglacier: failed to send the reports of route “%s”, they will be sent with the other reports. details: %s", route.Prefix, err)
+
+		for _, r := range reports {
+			t.reports().Add(r)
+		}
+	}
+}
+
+// SendAlert send a high-severity notification via e-mail to an administrator
+// immediately, without waiting for the periodic report. Only the given reports
+// are sent, the reports stored for the periodic notification are untouched.
+// The alert is also sent to the messengers, and when there are messengers the
+// e-mail is only sent if it has recipients.
+func (t ToGlacier) SendAlert(emailInfo EmailInfo, reports ...report.Report) error {
+	if len(t.Messengers) > 0 {
+		t.sendMessages(t.subject(report.Translate("toglacier alert")), reports...)
+
+		if len(emailInfo.To) == 0 {
+			return nil
+		}
+	}
+
+	r, err := report.BuildReports(emailInfo.Format, reports...)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	priority := "X-Priority: 1 (Highest)\nImportance: high\n"
+	return errors.WithStack(t.sendEmail(emailInfo, t.subject(report.Translate("toglacier alert")), priority, r))
+}
+
+// sendMessages sends the reports in plain text to the messengers. A messenger
+// failure is only logged, so it doesn't stop the other notifications.
+func (t ToGlacier) sendMessages(subject string, reports ...report.Report) {
+	r, err := report.BuildReports(report.FormatPlain, reports...)
+	if err != nil {
+		t.Logger.Warningf("toglacier: failed to build the message to the messengers. details: %s", err)
+		return
+	}
+
+	message := subject + "\n" + r
+	for _, messenger := range t.Messengers {
+		if err := messenger.SendMessage(t.Context, message); err != nil {
+			t.Logger.Warningf("toglacier: failed to send the message to the messenger. details: %s", err)
+		}
+	}
+}
+
+// subject identifies the machine in the e-mail subject, so the administrator
+// can distinguish the reports when many machines share the same vault.
+func (t ToGlacier) subject(subject string) string {
+	if t.MachineID == "" {
+		return subject
+	}
+
+	return fmt.Sprintf("%s (%s)", subject, t.MachineID)
+}
+
+func (t ToGlacier) sendEmail(emailInfo EmailInfo, subject, extraHeaders, content string) error {
 	body := fmt.Sprintf(`From: %s
 To: %s
-Subject: toglacier report
-MIME-Version: 1.0
+Subject: %s
+%sMIME-Version: 1.0
 Content-Type: %s; charset=utf-8
 
-%s`, emailInfo.From, strings.Join(emailInfo.To, ","), emailInfo.Format, r)
+%s`, emailInfo.From, strings.Join(emailInfo.To, ","), mime.QEncoding.Encode("utf-8", subject), extraHeaders, emailInfo.Format, content)
 
 	var auth smtp.Auth
 	if emailInfo.Username != "" && emailInfo.Password != "" {
 		auth = smtp.PlainAuth("", emailInfo.Username, emailInfo.Password, emailInfo.Server)
 	}
 
-	err = emailInfo.Sender.SendMail(fmt.Sprintf("%s:%d", emailInfo.Server, emailInfo.Port), auth, emailInfo.From, emailInfo.To, []byte(body))
+	addr := net.JoinHostPort(emailInfo.Server, strconv.Itoa(emailInfo.Port))
+	err := emailInfo.Sender.SendMail(addr, auth, emailInfo.From, emailInfo.To, []byte(body))
 	return errors.WithStack(err)
 }
 
@@ -656,3 +2243,23 @@ func (b backupsByCreationDate) Less(i, j int) bool {
 
 // Swap change the backups position inside the slice.
 func (b backupsByCreationDate) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+
+// now returns the current time using the defined clock, falling back to the
+// system clock.
+// reports returns the collector of the reports, using the default collector of
+// the report package when none was defined.
+func (t ToGlacier) reports() *report.Collector {
+	if t.Reports == nil {
+		return report.Default()
+	}
+
+	return t.Reports
+}
+
+func (t ToGlacier) now() time.Time {
+	if t.Clock == nil {
+		return time.Now()
+	}
+
+	return t.Clock.Now()
+}
@@ -3,6 +3,7 @@ package toglacier_test
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/smtp"
@@ -12,6 +13,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -35,14 +37,33 @@ func TestToGlacier_Backup(t *testing.T) {
 		backupSecret    string
 		modifyTolerance float64
 		ignorePatterns  []*regexp.Regexp
+		comment         string
 		archive         archive.Archive
 		envelop         archive.Envelop
+		parity          archive.Parity
+		redundancy      int
 		cloud           cloud.Cloud
+		replica         cloud.Cloud
+		routes          []toglacier.Route
+		concurrency     int
+		rebaseAfter     time.Duration
+		fullBackupEvery time.Duration
 		storage         storage.Storage
+		journal         *storage.JournalFile
 		logger          log.Logger
+		expectedJournal storage.Backups
 		expectedError   error
 	}
 
+	journalLogger := mockLogger{
+		mockDebug:  func(args ...interface{}) {},
+		mockDebugf: func(format string, args ...interface{}) {},
+		mockInfo:   func(args ...interface{}) {},
+		mockInfof:  func(format string, args ...interface{}) {},
+	}
+
+	journalDate := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
 	scenarios := []scenario{
 		{
 			description: "it should backup correctly an archive",
@@ -66,6 +87,7 @@ func TestToGlacier_Backup(t *testing.T) {
 			ignorePatterns: []*regexp.Regexp{
 				regexp.MustCompile(`^.*\~\$.*$`),
 			},
+			comment: "before OS upgrade",
 			archive: mockArchive{
 				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
 					if len(backupPaths) == 0 {
@@ -97,17 +119,29 @@ func TestToGlacier_Backup(t *testing.T) {
 				},
 			},
 			cloud: mockCloud{
-				mockSend: func(filename string) (cloud.Backup, error) {
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					if comment != "before OS upgrade" {
+						t.Errorf("unexpected comment “%s”", comment)
+					}
+
 					return cloud.Backup{
 						ID:        "123456",
 						CreatedAt: now,
 						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
 						VaultName: "test",
+						Comment:   comment,
 					}, nil
 				},
 			},
 			storage: mockStorage{
 				mockSave: func(b storage.Backup) error {
+					if b.Backup.Comment != "before OS upgrade" {
+						t.Errorf("unexpected comment “%s”", b.Backup.Comment)
+					}
+
 					return nil
 				},
 				mockList: func() (storage.Backups, error) {
@@ -140,22 +174,214 @@ func TestToGlacier_Backup(t *testing.T) {
 			},
 		},
 		{
-			description: "it should detect when there's a problem listing the current backups",
-			backupPaths: func() []string {
-				d, err := ioutil.TempDir("", "toglacier-test")
-				if err != nil {
-					t.Fatalf("error creating temporary directory. details %s", err)
-				}
+			description: "it should send the paths of each route to the route cloud",
+			backupPaths: []string{"/data"},
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					var filename string
+					switch fmt.Sprintf("%v", backupPaths) {
+					case "[/data]":
+						if len(ignorePatterns) != 1 || !ignorePatterns[0].MatchString("/data/photos/photo1.jpg") || ignorePatterns[0].MatchString("/data/photos-old") {
+							return "", nil, fmt.Errorf("unexpected ignore patterns: %v", ignorePatterns)
+						}
 
-				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
-					t.Fatalf("error creating temporary file. details %s", err)
-				}
+						if _, ok := lastArchiveInfo["/data/file1"]; !ok || len(lastArchiveInfo) != 1 {
+							return "", nil, fmt.Errorf("unexpected last archive info: %v", lastArchiveInfo)
+						}
 
-				return []string{d}
+						filename = "/data/file1"
+
+					case "[/data/photos]":
+						if len(ignorePatterns) != 0 {
+							return "", nil, fmt.Errorf("unexpected ignore patterns: %v", ignorePatterns)
+						}
+
+						if _, ok := lastArchiveInfo["/data/photos/photo1.jpg"]; !ok || len(lastArchiveInfo) != 1 {
+							return "", nil, fmt.Errorf("unexpected last archive info: %v", lastArchiveInfo)
+						}
+
+						filename = "/data/photos/photo1.jpg"
+
+					default:
+						return "", nil, fmt.Errorf("unexpected backup paths: %v", backupPaths)
+					}
+
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					return f.Name(), archive.Info{
+						filename: archive.ItemInfo{
+							Status:   archive.ItemInfoStatusModified,
+							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						VaultName: "test",
+					}, nil
+				},
+			},
+			routes: []toglacier.Route{
+				{
+					Prefix:    "/data/photos",
+					VaultName: "photos",
+					Cloud: mockCloud{
+						mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+							return cloud.Backup{ID: "223459"}, nil
+						},
+						mockSend: func(filename, comment string) (cloud.Backup, error) {
+							return cloud.Backup{
+								ID:        "223456",
+								CreatedAt: now,
+								VaultName: "photos",
+							}, nil
+						},
+					},
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					switch b.Backup.ID {
+					case "123456":
+						if _, ok := b.Info["/data/file1"]; !ok || b.Backup.CatalogID != "123459" {
+							return fmt.Errorf("unexpected backup %#v", b)
+						}
+					case "223456":
+						if _, ok := b.Info["/data/photos/photo1.jpg"]; !ok || b.Backup.CatalogID != "223459" {
+							return fmt.Errorf("unexpected backup %#v", b)
+						}
+					default:
+						return fmt.Errorf("saving unexpected backup %s", b.Backup.ID)
+					}
+
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "223455",
+								CreatedAt: now.Add(-time.Hour),
+								VaultName: "photos",
+							},
+							Info: archive.Info{
+								"/data/photos/photo1.jpg": archive.ItemInfo{
+									ID:     "223455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: now.Add(-2 * time.Hour),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"/data/file1": archive.ItemInfo{
+									ID:     "123455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+		},
+		{
+			description: "it should back up the routes concurrently",
+			backupPaths: []string{"/data", "/data/photos"},
+			archive: func() archive.Archive {
+				var building sync.WaitGroup
+				building.Add(2)
+
+				return mockArchive{
+					mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+						building.Done()
+
+						built := make(chan bool)
+						go func() {
+							building.Wait()
+							close(built)
+						}()
+
+						select {
+						case <-built:
+						case <-time.After(5 * time.Second):
+							return "", nil, errors.New("routes weren't backed up concurrently")
+						}
+
+						f, err := ioutil.TempFile("", "toglacier-test")
+						if err != nil {
+							return "", nil, err
+						}
+						defer f.Close()
+
+						return f.Name(), archive.Info{
+							path.Join(backupPaths[0], "file1"): archive.ItemInfo{
+								Status:   archive.ItemInfoStatusNew,
+								Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+							},
+						}, nil
+					},
+				}
 			}(),
+			cloud: mockCloud{
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						VaultName: "test",
+					}, nil
+				},
+			},
+			routes: []toglacier.Route{
+				{
+					Prefix:    "/data/photos",
+					VaultName: "photos",
+					Cloud: mockCloud{
+						mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+							return cloud.Backup{ID: "223459"}, nil
+						},
+						mockSend: func(filename, comment string) (cloud.Backup, error) {
+							return cloud.Backup{
+								ID:        "223456",
+								CreatedAt: now,
+								VaultName: "photos",
+							}, nil
+						},
+					},
+				},
+			},
+			concurrency: 2,
 			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					return nil
+				},
 				mockList: func() (storage.Backups, error) {
-					return nil, errors.New("problem loading backups from storage")
+					return nil, nil
 				},
 			},
 			logger: mockLogger{
@@ -166,10 +392,9 @@ func TestToGlacier_Backup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("problem loading backups from storage"),
 		},
 		{
-			description: "it should backup correctly an archive with encryption",
+			description: "it should backup correctly an archive with parity data",
 			backupPaths: func() []string {
 				d, err := ioutil.TempDir("", "toglacier-test")
 				if err != nil {
@@ -182,7 +407,6 @@ func TestToGlacier_Backup(t *testing.T) {
 
 				return []string{d}
 			}(),
-			backupSecret: "12345678901234567890123456789012",
 			archive: mockArchive{
 				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
 					f, err := ioutil.TempFile("", "toglacier-test")
@@ -191,11 +415,21 @@ func TestToGlacier_Backup(t *testing.T) {
 					}
 					defer f.Close()
 
-					return f.Name(), nil, nil
+					return f.Name(), archive.Info{
+						path.Join(backupPaths[0], "file1"): archive.ItemInfo{
+							ID:       "",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+						},
+					}, nil
 				},
 			},
-			envelop: mockEnvelop{
-				mockEncrypt: func(filename, secret string) (string, error) {
+			parity: mockParity{
+				mockGenerate: func(filename string, redundancy int) (string, error) {
+					if redundancy != 10 {
+						return "", fmt.Errorf("unexpected redundancy %d", redundancy)
+					}
+
 					f, err := ioutil.TempFile("", "toglacier-test")
 					if err != nil {
 						t.Fatalf("error creating temporary file. details: %s", err)
@@ -205,8 +439,12 @@ func TestToGlacier_Backup(t *testing.T) {
 					return f.Name(), nil
 				},
 			},
+			redundancy: 10,
 			cloud: mockCloud{
-				mockSend: func(filename string) (cloud.Backup, error) {
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
 					return cloud.Backup{
 						ID:        "123456",
 						CreatedAt: now,
@@ -214,9 +452,25 @@ func TestToGlacier_Backup(t *testing.T) {
 						VaultName: "test",
 					}, nil
 				},
+				mockSendParity: func(filename, backupID string) (cloud.Backup, error) {
+					if backupID != "123456" {
+						return cloud.Backup{}, fmt.Errorf("unexpected backup id “%s”", backupID)
+					}
+
+					return cloud.Backup{
+						ID:        "123457",
+						CreatedAt: now,
+						Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+						VaultName: "test",
+					}, nil
+				},
 			},
 			storage: mockStorage{
 				mockSave: func(b storage.Backup) error {
+					if b.Backup.ParityID != "123457" {
+						return fmt.Errorf("unexpected parity id “%s”", b.Backup.ParityID)
+					}
+
 					return nil
 				},
 				mockList: func() (storage.Backups, error) {
@@ -233,16 +487,72 @@ func TestToGlacier_Backup(t *testing.T) {
 			},
 		},
 		{
-			description: "it should detect an error while building the package",
+			description: "it should backup correctly sending a copy to the replica",
 			backupPaths: func() []string {
-				return []string{"idontexist12345"}
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				return []string{d}
 			}(),
+			comment: "weekly",
 			archive: mockArchive{
 				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
-					return "", nil, errors.New("path doesn't exist")
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					return f.Name(), archive.Info{
+						path.Join(backupPaths[0], "file1"): archive.ItemInfo{
+							ID:       "",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+					}, nil
+				},
+			},
+			replica: mockCloud{
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					if comment != "weekly" {
+						return cloud.Backup{}, fmt.Errorf("unexpected comment “%s”", comment)
+					}
+
+					return cloud.Backup{
+						ID:        "223456",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test-replica",
+					}, nil
 				},
 			},
 			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "123456" || b.Backup.ReplicaID != "223456" {
+						return fmt.Errorf("unexpected backup “%s” with replica id “%s”", b.Backup.ID, b.Backup.ReplicaID)
+					}
+
+					return nil
+				},
 				mockList: func() (storage.Backups, error) {
 					return nil, nil
 				},
@@ -255,48 +565,69 @@ func TestToGlacier_Backup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("path doesn't exist"),
 		},
 		{
-			description: "it should detect when there is nothing in the tarball",
+			description: "it should keep the backup when the copy can't be sent to the replica",
 			backupPaths: func() []string {
 				d, err := ioutil.TempDir("", "toglacier-test")
 				if err != nil {
 					t.Fatalf("error creating temporary directory. details %s", err)
 				}
+
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
 				return []string{d}
 			}(),
 			archive: mockArchive{
 				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
-					if len(backupPaths) == 0 {
-						t.Fatalf("no backup path informed")
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
 					}
+					defer f.Close()
 
-					return "", nil, nil
+					return f.Name(), archive.Info{
+						path.Join(backupPaths[0], "file1"): archive.ItemInfo{
+							ID:       "",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+						},
+					}, nil
 				},
 			},
-			storage: mockStorage{
-				mockList: func() (storage.Backups, error) {
-					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "123455",
-								CreatedAt: now.Add(-time.Hour),
-								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
-								VaultName: "test",
-							},
-							Info: archive.Info{
-								"file1": archive.ItemInfo{
-									ID:       "123455",
-									Status:   archive.ItemInfoStatusNew,
-									Checksum: "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
-								},
-							},
-						},
+			cloud: mockCloud{
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
 					}, nil
 				},
 			},
-			logger: mockLogger{
+			replica: mockCloud{
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{}, errors.New("region unavailable")
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ReplicaID != "" {
+						return fmt.Errorf("unexpected replica id “%s”", b.Backup.ReplicaID)
+					}
+
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+			},
+			logger: mockLogger{
 				mockDebug:    func(args ...interface{}) {},
 				mockDebugf:   func(format string, args ...interface{}) {},
 				mockInfo:     func(args ...interface{}) {},
@@ -305,35 +636,22 @@ func TestToGlacier_Backup(t *testing.T) {
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
 		},
-		func() scenario {
-			d, err := ioutil.TempDir("", "toglacier-test")
-			if err != nil {
-				t.Fatalf("error creating temporary directory. details %s", err)
-			}
-
-			if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
-				t.Fatalf("error creating temporary file. details %s", err)
-			}
-
-			if err := ioutil.WriteFile(path.Join(d, "file2"), []byte("file2 test"), os.ModePerm); err != nil {
-				t.Fatalf("error creating temporary file. details %s", err)
-			}
-
-			if err := ioutil.WriteFile(path.Join(d, "file3"), []byte("file3 test"), os.ModePerm); err != nil {
-				t.Fatalf("error creating temporary file. details %s", err)
-			}
+		{
+			description: "it should keep the backup when the parity data can't be sent",
+			backupPaths: func() []string {
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
 
-			var s scenario
-			s.description = "it should detect when the modified tolerance is reached"
-			s.backupPaths = []string{d}
-			s.modifyTolerance = 50.0
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
 
-			s.archive = mockArchive{
+				return []string{d}
+			}(),
+			archive: mockArchive{
 				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
-					if len(backupPaths) == 0 {
-						t.Fatalf("no backup path informed")
-					}
-
 					f, err := ioutil.TempFile("", "toglacier-test")
 					if err != nil {
 						t.Fatalf("error creating temporary file. details: %s", err)
@@ -342,64 +660,64 @@ func TestToGlacier_Backup(t *testing.T) {
 
 					return f.Name(), archive.Info{
 						path.Join(backupPaths[0], "file1"): archive.ItemInfo{
-							ID:       "",
-							Status:   archive.ItemInfoStatusModified,
-							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
-						},
-						path.Join(backupPaths[0], "file2"): archive.ItemInfo{
 							ID:       "",
 							Status:   archive.ItemInfoStatusNew,
 							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
 						},
-						path.Join(backupPaths[0], "file3"): archive.ItemInfo{
-							ID:       "",
-							Status:   archive.ItemInfoStatusModified,
-							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
-						},
 					}, nil
 				},
-			}
+			},
+			parity: mockParity{
+				mockGenerate: func(filename string, redundancy int) (string, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
 
-			s.storage = mockStorage{
-				mockList: func() (storage.Backups, error) {
-					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "123455",
-								CreatedAt: now.Add(-time.Hour),
-								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
-								VaultName: "test",
-							},
-							Info: archive.Info{
-								"file1": archive.ItemInfo{
-									ID:       "123455",
-									Status:   archive.ItemInfoStatusNew,
-									Checksum: "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
-								},
-							},
-						},
+					return f.Name(), nil
+				},
+			},
+			redundancy: 10,
+			cloud: mockCloud{
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
 					}, nil
 				},
-			}
+				mockSendParity: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{}, errors.New("error sending parity data")
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ParityID != "" {
+						return fmt.Errorf("unexpected parity id “%s”", b.Backup.ParityID)
+					}
 
-			s.logger = mockLogger{
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+			},
+			logger: mockLogger{
 				mockDebug:    func(args ...interface{}) {},
 				mockDebugf:   func(format string, args ...interface{}) {},
 				mockInfo:     func(args ...interface{}) {},
 				mockInfof:    func(format string, args ...interface{}) {},
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
-			}
-
-			s.expectedError = toglacier.Error{
-				Paths: []string{d},
-				Code:  toglacier.ErrorCodeModifyTolerance,
-			}
-
-			return s
-		}(),
+			},
+		},
 		{
-			description: "it should detect an error while encrypting the package",
+			description: "it should send the backup catalog with the previous backups",
 			backupPaths: func() []string {
 				d, err := ioutil.TempDir("", "toglacier-test")
 				if err != nil {
@@ -412,7 +730,6 @@ func TestToGlacier_Backup(t *testing.T) {
 
 				return []string{d}
 			}(),
-			backupSecret: "123456",
 			archive: mockArchive{
 				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
 					f, err := ioutil.TempFile("", "toglacier-test")
@@ -421,16 +738,17 @@ func TestToGlacier_Backup(t *testing.T) {
 					}
 					defer f.Close()
 
-					return f.Name(), nil, nil
-				},
-			},
-			envelop: mockEnvelop{
-				mockEncrypt: func(filename, secret string) (string, error) {
-					return "", errors.New("failed to encrypt the archive")
+					return f.Name(), archive.Info{
+						"file1": archive.ItemInfo{
+							ID:       "",
+							Status:   archive.ItemInfoStatusModified,
+							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+						},
+					}, nil
 				},
 			},
 			cloud: mockCloud{
-				mockSend: func(filename string) (cloud.Backup, error) {
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
 					return cloud.Backup{
 						ID:        "123456",
 						CreatedAt: now,
@@ -438,13 +756,68 @@ func TestToGlacier_Backup(t *testing.T) {
 						VaultName: "test",
 					}, nil
 				},
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					content, err := ioutil.ReadFile(filename)
+					if err != nil {
+						return cloud.Backup{}, err
+					}
+
+					var c struct {
+						Backup   storage.Backup  `json:"backup"`
+						Backups  storage.Backups `json:"backups"`
+						Catalogs []string        `json:"catalogs"`
+					}
+
+					if err := json.Unmarshal(content, &c); err != nil {
+						return cloud.Backup{}, err
+					}
+
+					if backupID != "123456" || c.Backup.Backup.ID != "123456" {
+						return cloud.Backup{}, fmt.Errorf("unexpected backup id “%s”", c.Backup.Backup.ID)
+					}
+
+					if c.Backup.Info["file1"].ID != "123456" {
+						return cloud.Backup{}, fmt.Errorf("unexpected archive information %v", c.Backup.Info)
+					}
+
+					if len(c.Backups) != 1 || c.Backups[0].Backup.ID != "123455" || c.Backups[0].Backup.CatalogID != "123450" || c.Backups[0].Info["file1"].ID != "123455" {
+						return cloud.Backup{}, fmt.Errorf("unexpected previous backups %v", c.Backups)
+					}
+
+					if len(c.Catalogs) > 0 {
+						return cloud.Backup{}, fmt.Errorf("unexpected catalogs chain %v", c.Catalogs)
+					}
+
+					return cloud.Backup{ID: "123457"}, nil
+				},
 			},
 			storage: mockStorage{
 				mockSave: func(b storage.Backup) error {
+					if b.Backup.CatalogID != "123457" {
+						return fmt.Errorf("unexpected catalog id “%s”", b.Backup.CatalogID)
+					}
+
 					return nil
 				},
 				mockList: func() (storage.Backups, error) {
-					return nil, nil
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: now.Add(-time.Hour),
+								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+								VaultName: "test",
+								CatalogID: "123450",
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "123455",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
+								},
+							},
+						},
+					}, nil
 				},
 			},
 			logger: mockLogger{
@@ -455,10 +828,9 @@ func TestToGlacier_Backup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("failed to encrypt the archive"),
 		},
 		{
-			description: "it should detect an error while sending the backup",
+			description: "it should detect when there's a problem listing the current backups",
 			backupPaths: func() []string {
 				d, err := ioutil.TempDir("", "toglacier-test")
 				if err != nil {
@@ -471,25 +843,9 @@ func TestToGlacier_Backup(t *testing.T) {
 
 				return []string{d}
 			}(),
-			archive: mockArchive{
-				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
-					f, err := ioutil.TempFile("", "toglacier-test")
-					if err != nil {
-						t.Fatalf("error creating temporary file. details: %s", err)
-					}
-					defer f.Close()
-
-					return f.Name(), nil, nil
-				},
-			},
-			cloud: mockCloud{
-				mockSend: func(filename string) (cloud.Backup, error) {
-					return cloud.Backup{}, errors.New("error sending backup")
-				},
-			},
 			storage: mockStorage{
 				mockList: func() (storage.Backups, error) {
-					return nil, nil
+					return nil, errors.New("problem loading backups from storage")
 				},
 			},
 			logger: mockLogger{
@@ -500,10 +856,10 @@ func TestToGlacier_Backup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("error sending backup"),
+			expectedError: errors.New("problem loading backups from storage"),
 		},
 		{
-			description: "it should detect an error while saving the backup information",
+			description: "it should backup correctly an archive with encryption",
 			backupPaths: func() []string {
 				d, err := ioutil.TempDir("", "toglacier-test")
 				if err != nil {
@@ -516,6 +872,7 @@ func TestToGlacier_Backup(t *testing.T) {
 
 				return []string{d}
 			}(),
+			backupSecret: "12345678901234567890123456789012",
 			archive: mockArchive{
 				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
 					f, err := ioutil.TempFile("", "toglacier-test")
@@ -527,8 +884,22 @@ func TestToGlacier_Backup(t *testing.T) {
 					return f.Name(), nil, nil
 				},
 			},
+			envelop: mockEnvelop{
+				mockEncrypt: func(filename, secret string) (string, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					return f.Name(), nil
+				},
+			},
 			cloud: mockCloud{
-				mockSend: func(filename string) (cloud.Backup, error) {
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
 					return cloud.Backup{
 						ID:        "123456",
 						CreatedAt: now,
@@ -539,7 +910,7 @@ func TestToGlacier_Backup(t *testing.T) {
 			},
 			storage: mockStorage{
 				mockSave: func(b storage.Backup) error {
-					return errors.New("error saving the backup information")
+					return nil
 				},
 				mockList: func() (storage.Backups, error) {
 					return nil, nil
@@ -553,114 +924,70 @@ func TestToGlacier_Backup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("error saving the backup information"),
 		},
-	}
-
-	for _, scenario := range scenarios {
-		t.Run(scenario.description, func(t *testing.T) {
-			toGlacier := toglacier.ToGlacier{
-				Context: context.Background(),
-				Archive: scenario.archive,
-				Envelop: scenario.envelop,
-				Cloud:   scenario.cloud,
-				Storage: scenario.storage,
-				Logger:  scenario.logger,
-			}
-
-			err := toGlacier.Backup(scenario.backupPaths, scenario.backupSecret, scenario.modifyTolerance, scenario.ignorePatterns)
-			if !archive.ErrorEqual(scenario.expectedError, err) && !archive.PathErrorEqual(scenario.expectedError, err) && !ErrorEqual(scenario.expectedError, err) {
-				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
-			}
-		})
-	}
-}
-
-func TestToGlacier_ListBackups(t *testing.T) {
-	now := time.Now()
-
-	scenarios := []struct {
-		description   string
-		remote        bool
-		cloud         cloud.Cloud
-		storage       storage.Storage
-		logger        log.Logger
-		expected      storage.Backups
-		expectedError error
-	}{
 		{
-			description: "it should list the remote backups correctly",
-			remote:      true,
-			cloud: mockCloud{
-				mockList: func() ([]cloud.Backup, error) {
-					return []cloud.Backup{
-						{
-							ID:        "123456",
-							CreatedAt: now,
-							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
-							VaultName: "test",
-						},
-					}, nil
+			description: "it should detect an error while building the package",
+			backupPaths: func() []string {
+				return []string{"idontexist12345"}
+			}(),
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					return "", nil, errors.New("path doesn't exist")
 				},
 			},
 			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "123456" {
-						return fmt.Errorf("adding unexpected id %s", b.Backup.ID)
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("path doesn't exist"),
+		},
+		{
+			description: "it should detect when there is nothing in the tarball",
+			backupPaths: func() []string {
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+				return []string{d}
+			}(),
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					if len(backupPaths) == 0 {
+						t.Fatalf("no backup path informed")
 					}
 
-					return nil
+					return "", nil, nil
 				},
+			},
+			storage: mockStorage{
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "123454",
-								CreatedAt: now.Add(-24 * time.Hour),
-								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
-								VaultName: "test",
-							},
-						},
 						{
 							Backup: cloud.Backup{
 								ID:        "123455",
-								CreatedAt: now.Add(-30 * time.Hour),
-								Checksum:  "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
-								VaultName: "test",
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123456",
 								CreatedAt: now.Add(-time.Hour),
-								Checksum:  "75fcc5623af832086719316b41dcf744893514d8a5fefb376c6426d7911f215f",
+								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
 								VaultName: "test",
 							},
 							Info: archive.Info{
 								"file1": archive.ItemInfo{
-									ID:       "123454",
-									Status:   archive.ItemInfoStatusModified,
-									Checksum: "915bd6a5873681a273f405c62993b6a96237eab9150fc525c9d57af0becb7ec1",
+									ID:       "123455",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
 								},
 							},
 						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123457",
-								CreatedAt: now.Add(-23 * time.Hour),
-								Checksum:  "e1f6e5d1d7c964e46503bcf1812910c005634236ea087d9cadb1abdef3ae9a61",
-								VaultName: "test",
-							},
-						},
 					}, nil
 				},
-				mockRemove: func(id string) error {
-					if id != "123454" && id != "123455" && id != "123456" {
-						return fmt.Errorf("removing unexpected id %s", id)
-					}
-
-					return nil
-				},
 			},
 			logger: mockLogger{
 				mockDebug:    func(args ...interface{}) {},
@@ -670,90 +997,150 @@ func TestToGlacier_ListBackups(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expected: storage.Backups{
-				{
-					Backup: cloud.Backup{
-						ID:        "123456",
-						CreatedAt: now,
-						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
-						VaultName: "test",
-					},
-					Info: archive.Info{
-						"file1": archive.ItemInfo{
-							ID:       "123454",
+		},
+		func() scenario {
+			d, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details %s", err)
+			}
+
+			if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+				t.Fatalf("error creating temporary file. details %s", err)
+			}
+
+			if err := ioutil.WriteFile(path.Join(d, "file2"), []byte("file2 test"), os.ModePerm); err != nil {
+				t.Fatalf("error creating temporary file. details %s", err)
+			}
+
+			if err := ioutil.WriteFile(path.Join(d, "file3"), []byte("file3 test"), os.ModePerm); err != nil {
+				t.Fatalf("error creating temporary file. details %s", err)
+			}
+
+			var s scenario
+			s.description = "it should detect when the modified tolerance is reached"
+			s.backupPaths = []string{d}
+			s.modifyTolerance = 50.0
+
+			s.archive = mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					if len(backupPaths) == 0 {
+						t.Fatalf("no backup path informed")
+					}
+
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					return f.Name(), archive.Info{
+						path.Join(backupPaths[0], "file1"): archive.ItemInfo{
+							ID:       "",
 							Status:   archive.ItemInfoStatusModified,
-							Checksum: "915bd6a5873681a273f405c62993b6a96237eab9150fc525c9d57af0becb7ec1",
+							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
 						},
-					},
-				},
-				{
-					Backup: cloud.Backup{
-						ID:        "123457",
-						CreatedAt: now.Add(-23 * time.Hour),
-						Checksum:  "e1f6e5d1d7c964e46503bcf1812910c005634236ea087d9cadb1abdef3ae9a61",
-						VaultName: "test",
-					},
+						path.Join(backupPaths[0], "file2"): archive.ItemInfo{
+							ID:       "",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+						},
+						path.Join(backupPaths[0], "file3"): archive.ItemInfo{
+							ID:       "",
+							Status:   archive.ItemInfoStatusModified,
+							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+						},
+					}, nil
 				},
-			},
-		},
-		{
-			description: "it should list the local backups correctly",
-			storage: mockStorage{
+			}
+
+			s.storage = mockStorage{
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
 						{
 							Backup: cloud.Backup{
-								ID:        "123456",
-								CreatedAt: now,
-								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+								ID:        "123455",
+								CreatedAt: now.Add(-time.Hour),
+								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
 								VaultName: "test",
 							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "123455",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
+								},
+							},
 						},
 					}, nil
 				},
-			},
-			logger: mockLogger{
+			}
+
+			s.logger = mockLogger{
 				mockDebug:    func(args ...interface{}) {},
 				mockDebugf:   func(format string, args ...interface{}) {},
 				mockInfo:     func(args ...interface{}) {},
 				mockInfof:    func(format string, args ...interface{}) {},
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
+			}
+
+			s.expectedError = toglacier.Error{
+				Paths: []string{d},
+				Code:  toglacier.ErrorCodeModifyTolerance,
+			}
+
+			return s
+		}(),
+		{
+			description: "it should detect an error while encrypting the package",
+			backupPaths: func() []string {
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				return []string{d}
+			}(),
+			backupSecret: "123456",
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					return f.Name(), nil, nil
+				},
 			},
-			expected: storage.Backups{
-				{
-					Backup: cloud.Backup{
+			envelop: mockEnvelop{
+				mockEncrypt: func(filename, secret string) (string, error) {
+					return "", errors.New("failed to encrypt the archive")
+				},
+			},
+			cloud: mockCloud{
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{
 						ID:        "123456",
 						CreatedAt: now,
 						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
 						VaultName: "test",
-					},
-				},
-			},
-		},
-		{
-			description: "it should detect an error while listing the remote backups",
-			remote:      true,
-			cloud: mockCloud{
-				mockList: func() ([]cloud.Backup, error) {
-					return nil, errors.New("error listing backups")
+					}, nil
 				},
 			},
-			logger: mockLogger{
-				mockDebug:    func(args ...interface{}) {},
-				mockDebugf:   func(format string, args ...interface{}) {},
-				mockInfo:     func(args ...interface{}) {},
-				mockInfof:    func(format string, args ...interface{}) {},
-				mockWarning:  func(args ...interface{}) {},
-				mockWarningf: func(format string, args ...interface{}) {},
-			},
-			expectedError: errors.New("error listing backups"),
-		},
-		{
-			description: "it should detect an error while listing the local backups",
 			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					return nil
+				},
 				mockList: func() (storage.Backups, error) {
-					return nil, errors.New("error listing backups")
+					return nil, nil
 				},
 			},
 			logger: mockLogger{
@@ -764,40 +1151,44 @@ func TestToGlacier_ListBackups(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("error listing backups"),
+			expectedError: errors.New("failed to encrypt the archive"),
 		},
 		{
-			description: "it should detect an error while retrieving local backups for synch",
-			remote:      true,
-			cloud: mockCloud{
-				mockList: func() ([]cloud.Backup, error) {
-					return []cloud.Backup{
-						{
-							ID:        "123456",
-							CreatedAt: now,
-							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
-							VaultName: "test",
-						},
-					}, nil
-				},
-			},
-			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "123456" {
-						return fmt.Errorf("adding unexpected id %s", b.Backup.ID)
+			description: "it should detect an error while sending the backup",
+			backupPaths: func() []string {
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				return []string{d}
+			}(),
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
 					}
+					defer f.Close()
 
-					return nil
+					return f.Name(), nil, nil
 				},
-				mockList: func() (storage.Backups, error) {
-					return nil, errors.New("error retrieving backups")
+			},
+			cloud: mockCloud{
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
 				},
-				mockRemove: func(id string) error {
-					if id != "123454" && id != "123455" {
-						return fmt.Errorf("removing unexpected id %s", id)
-					}
-
-					return nil
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{}, errors.New("error sending backup")
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
 				},
 			},
 			logger: mockLogger{
@@ -808,53 +1199,52 @@ func TestToGlacier_ListBackups(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("error retrieving backups"),
+			expectedError: errors.New("error sending backup"),
 		},
 		{
-			description: "it should detect an error while removing local backups due to synch",
-			remote:      true,
+			description: "it should detect an error while saving the backup information",
+			backupPaths: func() []string {
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				return []string{d}
+			}(),
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					return f.Name(), nil, nil
+				},
+			},
 			cloud: mockCloud{
-				mockList: func() ([]cloud.Backup, error) {
-					return []cloud.Backup{
-						{
-							ID:        "123456",
-							CreatedAt: now,
-							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
-							VaultName: "test",
-						},
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
 					}, nil
 				},
 			},
 			storage: mockStorage{
 				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "123456" {
-						return fmt.Errorf("adding unexpected id %s", b.Backup.ID)
-					}
-
-					return nil
+					return errors.New("error saving the backup information")
 				},
 				mockList: func() (storage.Backups, error) {
-					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "123454",
-								CreatedAt: now.Add(-30 * time.Hour),
-								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
-								VaultName: "test",
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123455",
-								CreatedAt: now.Add(-40 * time.Hour),
-								Checksum:  "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
-								VaultName: "test",
-							},
-						},
-					}, nil
-				},
-				mockRemove: func(id string) error {
-					return errors.New("error removing backup")
+					return nil, nil
 				},
 			},
 			logger: mockLogger{
@@ -865,47 +1255,61 @@ func TestToGlacier_ListBackups(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("error removing backup"),
+			expectedError: errors.New("error saving the backup information"),
 		},
 		{
-			description: "it should detect an error while removing local recent backups due to synch",
-			remote:      true,
-			cloud: mockCloud{
-				mockList: func() ([]cloud.Backup, error) {
-					return []cloud.Backup{
-						{
-							ID:        "123456",
-							CreatedAt: now,
-							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
-							VaultName: "test",
-						},
-					}, nil
-				},
-			},
-			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "123456" {
-						return fmt.Errorf("adding unexpected id %s", b.Backup.ID)
+			description: "it should retry a temporary error while saving the backup information",
+			backupPaths: func() []string {
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				return []string{d}
+			}(),
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
 					}
+					defer f.Close()
 
-					return nil
+					return f.Name(), nil, nil
 				},
-				mockList: func() (storage.Backups, error) {
-					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "123456",
-								CreatedAt: now.Add(-time.Hour),
-								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
-								VaultName: "test",
-							},
-						},
-					}, nil
+			},
+			cloud: mockCloud{
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
 				},
-				mockRemove: func(id string) error {
-					return errors.New("error removing backup")
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+					}, nil
 				},
 			},
+			storage: func() mockStorage {
+				var saves int
+
+				return mockStorage{
+					mockSave: func(b storage.Backup) error {
+						if saves++; saves < 3 {
+							return &storage.Error{Code: storage.ErrorCodeSave, Err: errors.New("database locked")}
+						}
+						return nil
+					},
+					mockList: func() (storage.Backups, error) {
+						return nil, nil
+					},
+				}
+			}(),
 			logger: mockLogger{
 				mockDebug:    func(args ...interface{}) {},
 				mockDebugf:   func(format string, args ...interface{}) {},
@@ -914,53 +1318,51 @@ func TestToGlacier_ListBackups(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("error removing backup"),
 		},
 		{
-			description: "it should detect an error while adding new backups due to synch",
-			remote:      true,
+			description: "it should give up retrying a temporary error while saving the backup information",
+			backupPaths: func() []string {
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				return []string{d}
+			}(),
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					return f.Name(), nil, nil
+				},
+			},
 			cloud: mockCloud{
-				mockList: func() ([]cloud.Backup, error) {
-					return []cloud.Backup{
-						{
-							ID:        "123456",
-							CreatedAt: now,
-							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
-							VaultName: "test",
-						},
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
 					}, nil
 				},
 			},
 			storage: mockStorage{
 				mockSave: func(b storage.Backup) error {
-					return errors.New("error adding backup")
+					return &storage.Error{Code: storage.ErrorCodeSave, Err: errors.New("database locked")}
 				},
 				mockList: func() (storage.Backups, error) {
-					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "123454",
-								CreatedAt: now.Add(-time.Second),
-								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
-								VaultName: "test",
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123455",
-								CreatedAt: now.Add(-time.Minute),
-								Checksum:  "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
-								VaultName: "test",
-							},
-						},
-					}, nil
-				},
-				mockRemove: func(id string) error {
-					if id != "123454" && id != "123455" {
-						return fmt.Errorf("removing unexpected id %s", id)
-					}
-
-					return nil
+					return nil, nil
 				},
 			},
 			logger: mockLogger{
@@ -971,180 +1373,55 @@ func TestToGlacier_ListBackups(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("error adding backup"),
+			expectedError: &storage.Error{Code: storage.ErrorCodeSave, Err: errors.New("database locked")},
 		},
-	}
-
-	for _, scenario := range scenarios {
-		t.Run(scenario.description, func(t *testing.T) {
-			toGlacier := toglacier.ToGlacier{
-				Context: context.Background(),
-				Cloud:   scenario.cloud,
-				Storage: scenario.storage,
-				Logger:  scenario.logger,
-			}
-
-			backups, err := toGlacier.ListBackups(scenario.remote)
-
-			if !reflect.DeepEqual(scenario.expected, backups) {
-				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backups))
-			}
+		{
+			description: "it should keep in the journal a backup that couldn't be saved",
+			backupPaths: func() []string {
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
 
-			if !ErrorEqual(scenario.expectedError, err) {
-				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
-			}
-		})
-	}
-}
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
 
-func TestToGlacier_RetrieveBackup(t *testing.T) {
-	scenarios := []struct {
-		description    string
-		id             string
-		backupSecret   string
-		skipUnmodified bool
-		storage        storage.Storage
-		envelop        archive.Envelop
-		cloud          cloud.Cloud
-		archive        archive.Archive
-		logger         log.Logger
-		expectedError  error
-	}{
-		{
-			description: "it should retrieve a backup correctly",
-			id:          "AWSID123",
-			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "AWSID123" && b.Backup.ID != "AWSID122" && b.Backup.ID != "AWSID124" {
-						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+				return []string{d}
+			}(),
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
 					}
-					return nil
-				},
-				mockList: func() (storage.Backups, error) {
-					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "AWSID122",
-								CreatedAt: time.Date(2015, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "8d9ccbb4e474dbd211a7b1f115c7bddaa950842e51a60418c4e943dee29e9113",
-								VaultName: "vault",
-								Size:      41,
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "AWSID123",
-								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
-								VaultName: "vault",
-								Size:      41,
-							},
-							Info: archive.Info{
-								"file1": archive.ItemInfo{
-									ID:       "AWSID123",
-									Status:   archive.ItemInfoStatusNew,
-									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
-								},
-								"file2": archive.ItemInfo{
-									ID:       "AWSID122",
-									Status:   archive.ItemInfoStatusUnmodified,
-									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
-								},
-								"file3": archive.ItemInfo{
-									ID:       "AWSID123",
-									Status:   archive.ItemInfoStatusNew,
-									Checksum: "429713c8e82ae8d02bff0cd368581903ac6d368cfdacc5bb5ec6fc14d13f3fd0",
-								},
-								"file4": archive.ItemInfo{
-									ID:       "AWSID124",
-									Status:   archive.ItemInfoStatusUnmodified,
-									Checksum: "352c30aa6751b62c658473a90d0a3ffcf98e66f00968c5320a2f1c2969db7024",
-								},
-							},
-						},
-					}, nil
+					defer f.Close()
+
+					return f.Name(), nil, nil
 				},
 			},
 			cloud: mockCloud{
-				mockGet: func(ids ...string) (filenames map[string]string, err error) {
-					if len(ids) != 3 {
-						return nil, fmt.Errorf("unexpected number of ids: %v", ids)
-					}
-
-					return map[string]string{
-						"AWSID123": "toglacier-archive-1.tar.gz",
-						"AWSID122": "toglacier-archive-2.tar.gz",
-						"AWSID124": "toglacier-archive-3.tar.gz",
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{
+						ID:        "123456",
+						CreatedAt: journalDate,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
 					}, nil
 				},
 			},
-			archive: mockArchive{
-				mockExtract: func(filename string, filter []string) (archive.Info, error) {
-					sort.Strings(filter)
-
-					switch filename {
-					case "toglacier-archive-1.tar.gz":
-						if len(filter) != 2 || filter[0] != "file1" || filter[1] != "file3" {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-
-						return archive.Info{
-							"file1": archive.ItemInfo{
-								ID:       "AWSID123",
-								Status:   archive.ItemInfoStatusNew,
-								Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
-							},
-							"file2": archive.ItemInfo{
-								ID:       "AWSID122",
-								Status:   archive.ItemInfoStatusUnmodified,
-								Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
-							},
-							"file3": archive.ItemInfo{
-								ID:       "AWSID123",
-								Status:   archive.ItemInfoStatusNew,
-								Checksum: "429713c8e82ae8d02bff0cd368581903ac6d368cfdacc5bb5ec6fc14d13f3fd0",
-							},
-							"file4": archive.ItemInfo{
-								ID:       "AWSID124",
-								Status:   archive.ItemInfoStatusUnmodified,
-								Checksum: "352c30aa6751b62c658473a90d0a3ffcf98e66f00968c5320a2f1c2969db7024",
-							},
-						}, nil
-
-					case "toglacier-archive-2.tar.gz":
-						if len(filter) != 1 || filter[0] != "file2" {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-
-						return archive.Info{
-							"file2": archive.ItemInfo{
-								ID:       "AWSID122",
-								Status:   archive.ItemInfoStatusNew,
-								Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
-							},
-							"file4": archive.ItemInfo{
-								ID:       "AWSID124",
-								Status:   archive.ItemInfoStatusUnmodified,
-								Checksum: "352c30aa6751b62c658473a90d0a3ffcf98e66f00968c5320a2f1c2969db7024",
-							},
-						}, nil
-
-					case "toglacier-archive-3.tar.gz":
-						if len(filter) != 1 || filter[0] != "file4" {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-
-						return archive.Info{
-							"file4": archive.ItemInfo{
-								ID:       "AWSID124",
-								Status:   archive.ItemInfoStatusNew,
-								Checksum: "352c30aa6751b62c658473a90d0a3ffcf98e66f00968c5320a2f1c2969db7024",
-							},
-						}, nil
-					}
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					return errors.New("error saving the backup information")
+				},
+				mockList: func() (storage.Backups, error) {
 					return nil, nil
 				},
 			},
+			journal: storage.NewJournalFile(journalLogger, path.Join(os.TempDir(), "toglacier-test-backup-journal")),
 			logger: mockLogger{
 				mockDebug:    func(args ...interface{}) {},
 				mockDebugf:   func(format string, args ...interface{}) {},
@@ -1153,73 +1430,160 @@ func TestToGlacier_RetrieveBackup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-		},
-		{
-			description:  "it should retrieve an encrypted backup correctly",
-			id:           "AWSID123",
-			backupSecret: "1234567890123456",
-			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "AWSID123" {
-						return fmt.Errorf("unexpected id %s", b.Backup.ID)
-					}
-					return nil
-				},
-				mockList: func() (storage.Backups, error) {
-					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "AWSID123",
-								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
-								VaultName: "vault",
-								Size:      41,
-							},
-						},
-					}, nil
+			expectedJournal: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "123456",
+						CreatedAt: journalDate,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						CatalogID: "123459",
+					},
 				},
 			},
-			envelop: mockEnvelop{
-				mockDecrypt: func(encryptedFilename, secret string) (string, error) {
+			expectedError: errors.New("error saving the backup information"),
+		},
+		{
+			description: "it should recover the backups from the journal before the backup",
+			backupPaths: func() []string {
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				return []string{d}
+			}(),
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
 					f, err := ioutil.TempFile("", "toglacier-test")
 					if err != nil {
 						t.Fatalf("error creating temporary file. details: %s", err)
 					}
 					defer f.Close()
 
-					return f.Name(), nil
+					return f.Name(), nil, nil
 				},
 			},
 			cloud: mockCloud{
-				mockGet: func(ids ...string) (filenames map[string]string, err error) {
-					if len(ids) == 0 {
-						return nil, nil
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{
+						ID:        "123456",
+						CreatedAt: journalDate,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+					}, nil
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "123455" && b.Backup.ID != "123456" {
+						return fmt.Errorf("saving unexpected backup %s", b.Backup.ID)
 					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+			},
+			journal: func() *storage.JournalFile {
+				journal := storage.NewJournalFile(journalLogger, path.Join(os.TempDir(), "toglacier-test-backup-journal-recover"))
 
-					n := path.Join(os.TempDir(), "toglacier-test-getenc")
-					if _, err := os.Stat(n); os.IsNotExist(err) {
-						f, err := os.Create(n)
-						if err != nil {
-							t.Fatalf("error creating a temporary file. details: %s", err)
-						}
-						defer f.Close()
-
-						content, err := hex.DecodeString("656e637279707465643a8fbd41664a1d72b4ea1fcecd618a6ed5c05c95bf65bfda2d4d176e8feff96f710000000000000000000000000000000091d8e827b5136dfac6bb3dbc51f15c17d34947880f91e62799910ea05053969abc28033550b3781111")
-						if err != nil {
-							t.Fatalf("error decoding encrypted archive. details: %s", err)
-						}
+				pending := storage.Backup{
+					Backup: cloud.Backup{
+						ID:        "123455",
+						CreatedAt: journalDate.Add(-time.Hour),
+						VaultName: "test",
+					},
+				}
 
-						f.Write(content)
+				if err := journal.Save(context.Background(), pending); err != nil {
+					t.Fatalf("error saving pending backup. details: %s", err)
+				}
+
+				return journal
+			}(),
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+		},
+		{
+			description: "it should keep in the journal the backups that still can't be recovered",
+			backupPaths: func() []string {
+				d, err := ioutil.TempDir("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary directory. details %s", err)
+				}
+
+				if err := ioutil.WriteFile(path.Join(d, "file1"), []byte("file1 test"), os.ModePerm); err != nil {
+					t.Fatalf("error creating temporary file. details %s", err)
+				}
+
+				return []string{d}
+			}(),
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
 					}
+					defer f.Close()
 
-					return map[string]string{ids[0]: n}, nil
+					return f.Name(), nil, nil
 				},
 			},
-			archive: mockArchive{
-				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+			cloud: mockCloud{
+				mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+					return cloud.Backup{ID: "123459"}, nil
+				},
+				mockSend: func(filename, comment string) (cloud.Backup, error) {
+					return cloud.Backup{
+						ID:        "123456",
+						CreatedAt: journalDate,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+					}, nil
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID == "123455" {
+						return errors.New("error saving the backup information")
+					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
 					return nil, nil
 				},
 			},
+			journal: func() *storage.JournalFile {
+				journal := storage.NewJournalFile(journalLogger, path.Join(os.TempDir(), "toglacier-test-backup-journal-pending"))
+
+				pending := storage.Backup{
+					Backup: cloud.Backup{
+						ID:        "123455",
+						CreatedAt: journalDate.Add(-time.Hour),
+						VaultName: "test",
+					},
+				}
+
+				if err := journal.Save(context.Background(), pending); err != nil {
+					t.Fatalf("error saving pending backup. details: %s", err)
+				}
+
+				return journal
+			}(),
 			logger: mockLogger{
 				mockDebug:    func(args ...interface{}) {},
 				mockDebugf:   func(format string, args ...interface{}) {},
@@ -1228,283 +1592,393 @@ func TestToGlacier_RetrieveBackup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
+			expectedJournal: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "123455",
+						CreatedAt: journalDate.Add(-time.Hour),
+						VaultName: "test",
+					},
+				},
+			},
 		},
 		{
-			description: "it should retrieve a backup correctly with no archive information and all other backup parts",
-			id:          "AWSID123",
-			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "AWSID123" && b.Backup.ID != "AWSID122" {
-						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+			description: "it should send again the files only stored in old archives",
+			backupPaths: []string{"/data"},
+			rebaseAfter: 24 * time.Hour,
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					expected := archive.Info{
+						"/data/file2": archive.ItemInfo{
+							ID:       "123455",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "643e692567bfeedc34f914ce740fa353c624ed6a9662ad158266549dd8fd8b70",
+						},
+						"/data/file3": archive.ItemInfo{
+							ID:       "123450",
+							Status:   archive.ItemInfoStatusDeleted,
+							Checksum: "4c6733f2d51c5cde947835279ce9f031bcacaa2265988ef1353078810695fb20",
+						},
 					}
-					return nil
+
+					if !reflect.DeepEqual(expected, lastArchiveInfo) {
+						t.Errorf("last archive information don't match.\n%s", Diff(expected, lastArchiveInfo))
+					}
+
+					// no files changed, nothing to send
+					return "", nil, nil
 				},
+			},
+			storage: mockStorage{
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
 						{
 							Backup: cloud.Backup{
-								ID:        "AWSID122",
-								CreatedAt: time.Date(2015, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "325152353325adc8854e185ab59daf44c51e78404e1512eea9dca116f3a8c16d",
-								VaultName: "vault",
-								Size:      38,
+								ID:        "123450",
+								CreatedAt: now.Add(-48 * time.Hour),
+								VaultName: "test",
 							},
 						},
 						{
 							Backup: cloud.Backup{
-								ID:        "AWSID123",
-								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
-								VaultName: "vault",
-								Size:      41,
+								ID:        "123455",
+								CreatedAt: now.Add(-time.Hour),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"/data/file1": archive.ItemInfo{
+									ID:       "123450",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+								},
+								"/data/file2": archive.ItemInfo{
+									ID:       "123455",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "643e692567bfeedc34f914ce740fa353c624ed6a9662ad158266549dd8fd8b70",
+								},
+								"/data/file3": archive.ItemInfo{
+									ID:       "123450",
+									Status:   archive.ItemInfoStatusDeleted,
+									Checksum: "4c6733f2d51c5cde947835279ce9f031bcacaa2265988ef1353078810695fb20",
+								},
 							},
 						},
 					}, nil
 				},
 			},
-			cloud: mockCloud{
-				mockGet: func(ids ...string) (filenames map[string]string, err error) {
-					if len(ids) == 0 {
-						return nil, nil
-					}
-
-					switch ids[0] {
-					case "AWSID123":
-						return map[string]string{
-							"AWSID123": "toglacier-archive-1.tar.gz",
-						}, nil
-					case "AWSID122":
-						return map[string]string{
-							"AWSID122": "toglacier-archive-2.tar.gz",
-						}, nil
+			logger: mockLogger{
+				mockDebugf: func(format string, args ...interface{}) {},
+			},
+		},
+		{
+			description:     "it should send all files when there's no recent full backup",
+			backupPaths:     []string{"/data"},
+			fullBackupEvery: 24 * time.Hour,
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					if lastArchiveInfo != nil {
+						t.Errorf("unexpected last archive information: %v", lastArchiveInfo)
 					}
 
-					return nil, fmt.Errorf("unexpected id “%s”", ids[0])
+					return "", nil, nil
 				},
 			},
-			archive: mockArchive{
-				mockExtract: func(filename string, filter []string) (archive.Info, error) {
-					switch filename {
-					case "toglacier-archive-1.tar.gz":
-						if len(filter) != 0 {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-
-						return archive.Info{
-							"file1": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusNew,
-								ID:       "AWSID123",
-								Checksum: "a5b2df3d72bd28d2382b0b4cca4c25fa260e018b58a915f1e5af14485a746ca8",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123450",
+								CreatedAt: now.Add(-48 * time.Hour),
+								VaultName: "test",
 							},
-							"file2": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusUnmodified,
-								ID:       "AWSID122",
-								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
+							Info: archive.Info{
+								"/data/file1": archive.ItemInfo{
+									ID:       "123450",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+								},
 							},
-						}, nil
-
-					case "toglacier-archive-2.tar.gz":
-						if len(filter) != 1 || filter[0] != "file2" {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-
-						return archive.Info{
-							"file2": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusNew,
-								ID:       "AWSID122",
-								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: now.Add(-time.Hour),
+								VaultName: "test",
 							},
-						}, nil
-					}
-					return nil, nil
+							Info: archive.Info{
+								"/data/file1": archive.ItemInfo{
+									ID:       "123450",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+								},
+								"/data/file2": archive.ItemInfo{
+									ID:       "123455",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "643e692567bfeedc34f914ce740fa353c624ed6a9662ad158266549dd8fd8b70",
+								},
+							},
+						},
+					}, nil
 				},
 			},
 			logger: mockLogger{
-				mockDebug:    func(args ...interface{}) {},
-				mockDebugf:   func(format string, args ...interface{}) {},
-				mockInfo:     func(args ...interface{}) {},
-				mockInfof:    func(format string, args ...interface{}) {},
-				mockWarning:  func(args ...interface{}) {},
-				mockWarningf: func(format string, args ...interface{}) {},
+				mockInfof: func(format string, args ...interface{}) {},
 			},
 		},
 		{
-			description: "it should retrieve a backup correctly that does not exist locally",
-			id:          "AWSID123",
-			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "AWSID123" && b.Backup.ID != "AWSID122" {
-						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+			description:     "it should send only the modified files when there's a recent full backup",
+			backupPaths:     []string{"/data"},
+			fullBackupEvery: 24 * time.Hour,
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					expected := archive.Info{
+						"/data/file1": archive.ItemInfo{
+							ID:       "123450",
+							Status:   archive.ItemInfoStatusUnmodified,
+							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+						},
 					}
-					return nil
+
+					if !reflect.DeepEqual(expected, lastArchiveInfo) {
+						t.Errorf("last archive information don't match.\n%s", Diff(expected, lastArchiveInfo))
+					}
+
+					return "", nil, nil
 				},
+			},
+			storage: mockStorage{
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
 						{
 							Backup: cloud.Backup{
-								ID:        "AWSID122",
-								CreatedAt: time.Date(2015, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "325152353325adc8854e185ab59daf44c51e78404e1512eea9dca116f3a8c16d",
-								VaultName: "vault",
-								Size:      38,
+								ID:        "123450",
+								CreatedAt: now.Add(-2 * time.Hour),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"/data/file1": archive.ItemInfo{
+									ID:       "123450",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: now.Add(-time.Hour),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"/data/file1": archive.ItemInfo{
+									ID:       "123450",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+								},
 							},
 						},
 					}, nil
 				},
 			},
-			cloud: mockCloud{
-				mockGet: func(ids ...string) (filenames map[string]string, err error) {
-					if len(ids) == 0 {
-						return nil, nil
-					}
-
-					switch ids[0] {
-					case "AWSID123":
-						return map[string]string{
-							"AWSID123": "toglacier-archive-1.tar.gz",
-						}, nil
-					case "AWSID122":
-						return map[string]string{
-							"AWSID122": "toglacier-archive-2.tar.gz",
-						}, nil
+		},
+		{
+			description: "it should ignore the stream backups when building the incremental backup",
+			backupPaths: []string{"/data"},
+			archive: mockArchive{
+				mockBuild: func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+					expected := archive.Info{
+						"/data/file1": archive.ItemInfo{
+							ID:       "123450",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+						},
 					}
 
-					return nil, fmt.Errorf("unexpected id “%s”", ids[0])
+					if !reflect.DeepEqual(expected, lastArchiveInfo) {
+						t.Errorf("last archive information don't match.\n%s", Diff(expected, lastArchiveInfo))
+					}
+
+					return "", nil, nil
 				},
 			},
-			archive: mockArchive{
-				mockExtract: func(filename string, filter []string) (archive.Info, error) {
-					switch filename {
-					case "toglacier-archive-1.tar.gz":
-						if len(filter) != 0 {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-
-						return archive.Info{
-							"file1": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusNew,
-								ID:       "AWSID123",
-								Checksum: "a5b2df3d72bd28d2382b0b4cca4c25fa260e018b58a915f1e5af14485a746ca8",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123450",
+								CreatedAt: now.Add(-2 * time.Hour),
+								VaultName: "test",
 							},
-							"file2": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusUnmodified,
-								ID:       "AWSID122",
-								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
+							Info: archive.Info{
+								"/data/file1": archive.ItemInfo{
+									ID:       "123450",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "11e87f16676135f6b4bc8da00883e4e02e51595d07841dbc8c16c5d2047a304d",
+								},
 							},
-						}, nil
-
-					case "toglacier-archive-2.tar.gz":
-						if len(filter) != 1 || filter[0] != "file2" {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-
-						return archive.Info{
-							"file2": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusNew,
-								ID:       "AWSID122",
-								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: now.Add(-time.Hour),
+								VaultName: "test",
+								Stream:    "db.sql",
 							},
-						}, nil
-					}
-					return nil, nil
+							Info: archive.Info{
+								"db.sql": archive.ItemInfo{
+									ID:       "123455",
+									Status:   archive.ItemInfoStatusStream,
+									Checksum: "xZzITM+6yGsa9masWjGdi+yAA0DlqCzTf/1795fy5Pk=",
+								},
+							},
+						},
+					}, nil
 				},
 			},
-			logger: mockLogger{
-				mockDebug:    func(args ...interface{}) {},
-				mockDebugf:   func(format string, args ...interface{}) {},
-				mockInfo:     func(args ...interface{}) {},
-				mockInfof:    func(format string, args ...interface{}) {},
-				mockWarning:  func(args ...interface{}) {},
-				mockWarningf: func(format string, args ...interface{}) {},
-			},
 		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context:         context.Background(),
+				Archive:         scenario.archive,
+				Envelop:         scenario.envelop,
+				Cloud:           scenario.cloud,
+				Storage:         scenario.storage,
+				Logger:          scenario.logger,
+				Parity:          scenario.parity,
+				Redundancy:      scenario.redundancy,
+				Replica:         scenario.replica,
+				Routes:          scenario.routes,
+				Concurrency:     scenario.concurrency,
+				RebaseAfter:     scenario.rebaseAfter,
+				FullBackupEvery: scenario.fullBackupEvery,
+				StepRetryDelay:  time.Millisecond,
+			}
+
+			if scenario.journal != nil {
+				defer os.Remove(scenario.journal.Filename)
+				toGlacier.Journal = scenario.journal
+			}
+
+			err := toGlacier.Backup(scenario.backupPaths, scenario.backupSecret, scenario.modifyTolerance, scenario.ignorePatterns, scenario.comment)
+			if !archive.ErrorEqual(scenario.expectedError, err) && !archive.PathErrorEqual(scenario.expectedError, err) && !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if scenario.journal != nil {
+				journal, err := scenario.journal.List(context.Background())
+				if err != nil {
+					t.Fatalf("error listing the journal. details: %s", err)
+				}
+
+				// the duration is measured while the backup runs
+				for i := range journal {
+					journal[i].Backup.Duration = 0
+				}
+
+				if !reflect.DeepEqual(scenario.expectedJournal, journal) {
+					t.Errorf("journals don't match.\n%s", Diff(scenario.expectedJournal, journal))
+				}
+			}
+		})
+	}
+}
+
+func TestToGlacier_ListBackups(t *testing.T) {
+	now := time.Now()
+
+	scenarios := []struct {
+		description   string
+		remote        bool
+		maxAge        time.Duration
+		machineID     string
+		allMachines   bool
+		cloud         cloud.Cloud
+		routes        []toglacier.Route
+		storage       storage.Storage
+		logger        log.Logger
+		expected      storage.Backups
+		expectedError error
+	}{
 		{
-			description:    "it should retrieve a backup correctly skipping unmodified files in disk",
-			id:             "AWSID123",
-			skipUnmodified: true,
+			description: "it should list the remote backups correctly",
+			remote:      true,
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{
+							ID:        "123456",
+							CreatedAt: now,
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+						},
+					}, nil
+				},
+			},
 			storage: mockStorage{
 				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "AWSID123" {
-						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					if b.Backup.ID != "123456" {
+						return fmt.Errorf("adding unexpected id %s", b.Backup.ID)
 					}
+
 					return nil
 				},
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
 						{
 							Backup: cloud.Backup{
-								ID:        "AWSID123",
-								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
-								VaultName: "vault",
-								Size:      41,
+								ID:        "123454",
+								CreatedAt: now.Add(-24 * time.Hour),
+								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+								VaultName: "test",
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: now.Add(-30 * time.Hour),
+								Checksum:  "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
+								VaultName: "test",
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: now.Add(-time.Hour),
+								Checksum:  "75fcc5623af832086719316b41dcf744893514d8a5fefb376c6426d7911f215f",
+								VaultName: "test",
 							},
 							Info: archive.Info{
 								"file1": archive.ItemInfo{
-									ID:       "AWSID123",
-									Status:   archive.ItemInfoStatusNew,
-									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
-								},
-								"file2": archive.ItemInfo{
-									ID:       "AWSID122",
-									Status:   archive.ItemInfoStatusUnmodified,
-									Checksum: "46813af30d24fb7ad0a019b0da4fcde88368133fcfe39c5a8b25a328e6be4ab2",
-								},
-								"file3": archive.ItemInfo{
-									ID:       "AWSID123",
-									Status:   archive.ItemInfoStatusNew,
-									Checksum: "429713c8e82ae8d02bff0cd368581903ac6d368cfdacc5bb5ec6fc14d13f3fd0",
-								},
-								"file4": archive.ItemInfo{
-									ID:       "AWSID124",
-									Status:   archive.ItemInfoStatusUnmodified,
-									Checksum: "79edf074b55cdb3088721e88814523124c7da05001175e14b0dcf78336730fcd",
+									ID:       "123454",
+									Status:   archive.ItemInfoStatusModified,
+									Checksum: "915bd6a5873681a273f405c62993b6a96237eab9150fc525c9d57af0becb7ec1",
 								},
 							},
 						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: now.Add(-23 * time.Hour),
+								Checksum:  "e1f6e5d1d7c964e46503bcf1812910c005634236ea087d9cadb1abdef3ae9a61",
+								VaultName: "test",
+							},
+						},
 					}, nil
 				},
-			},
-			cloud: mockCloud{
-				mockGet: func(ids ...string) (filenames map[string]string, err error) {
-					if len(ids) != 1 {
-						return nil, fmt.Errorf("unexpected number of ids: %v", ids)
+				mockRemove: func(id string) error {
+					if id != "123454" && id != "123455" && id != "123456" {
+						return fmt.Errorf("removing unexpected id %s", id)
 					}
 
-					return map[string]string{
-						"AWSID123": "toglacier-archive-1.tar.gz",
-					}, nil
-				},
-			},
-			archive: mockArchive{
-				mockExtract: func(filename string, filter []string) (archive.Info, error) {
-					sort.Strings(filter)
-
-					switch filename {
-					case "toglacier-archive-1.tar.gz":
-						if len(filter) != 2 || filter[0] != "file1" || filter[1] != "file3" {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-					case "toglacier-archive-2.tar.gz":
-						if len(filter) != 1 || filter[0] != "file2" {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-					}
-					return nil, nil
+					return nil
 				},
-				mockFileChecksum: func(filename string) (string, error) {
-					switch filename {
-					case "file1":
-						return "a9300479a7d2c663b4806af1bce4483f93175cae287979ee0364d057445482c8", nil
-					case "file2":
-						return "46813af30d24fb7ad0a019b0da4fcde88368133fcfe39c5a8b25a328e6be4ab2", nil
-					case "file3":
-						return "64bd312e9c81172627d898d7ad146d2e9ea47f47dd67ea79477ab224ab8fb01b", nil
-					case "file4":
-						return "79edf074b55cdb3088721e88814523124c7da05001175e14b0dcf78336730fcd", nil
-					}
-
-					return "", fmt.Errorf("unexpected filename “%s”", filename)
+				mockSaveInventoryDate: func(date time.Time) error {
+					return nil
 				},
 			},
 			logger: mockLogger{
@@ -1515,83 +1989,76 @@ func TestToGlacier_RetrieveBackup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-		},
-		{
-			description:    "it should detect when there is a problem calculating the file checksum",
-			id:             "AWSID123",
-			skipUnmodified: true,
-			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "AWSID123" {
-						return fmt.Errorf("unexpected id %s", b.Backup.ID)
-					}
-					return nil
-				},
-				mockList: func() (storage.Backups, error) {
-					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "AWSID123",
-								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
-								VaultName: "vault",
-								Size:      41,
-							},
-							Info: archive.Info{
-								"file1": archive.ItemInfo{
-									ID:       "AWSID123",
-									Status:   archive.ItemInfoStatusNew,
-									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
-								},
-								"file2": archive.ItemInfo{
-									ID:       "AWSID122",
-									Status:   archive.ItemInfoStatusUnmodified,
-									Checksum: "46813af30d24fb7ad0a019b0da4fcde88368133fcfe39c5a8b25a328e6be4ab2",
-								},
-								"file3": archive.ItemInfo{
-									ID:       "AWSID123",
-									Status:   archive.ItemInfoStatusNew,
-									Checksum: "429713c8e82ae8d02bff0cd368581903ac6d368cfdacc5bb5ec6fc14d13f3fd0",
-								},
-								"file4": archive.ItemInfo{
-									ID:       "AWSID124",
-									Status:   archive.ItemInfoStatusUnmodified,
-									Checksum: "79edf074b55cdb3088721e88814523124c7da05001175e14b0dcf78336730fcd",
-								},
-							},
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+					},
+					Info: archive.Info{
+						"file1": archive.ItemInfo{
+							ID:       "123454",
+							Status:   archive.ItemInfoStatusModified,
+							Checksum: "915bd6a5873681a273f405c62993b6a96237eab9150fc525c9d57af0becb7ec1",
 						},
-					}, nil
+					},
+				},
+				{
+					Backup: cloud.Backup{
+						ID:        "123457",
+						CreatedAt: now.Add(-23 * time.Hour),
+						Checksum:  "e1f6e5d1d7c964e46503bcf1812910c005634236ea087d9cadb1abdef3ae9a61",
+						VaultName: "test",
+					},
 				},
 			},
+		},
+		{
+			description: "it should list the remote backups of all routes",
+			remote:      true,
 			cloud: mockCloud{
-				mockGet: func(ids ...string) (filenames map[string]string, err error) {
-					if len(ids) != 1 {
-						return nil, fmt.Errorf("unexpected number of ids: %v", ids)
-					}
-
-					return map[string]string{
-						"AWSID123": "toglacier-archive-1.tar.gz",
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{
+							ID:        "123456",
+							CreatedAt: now.Add(-time.Hour),
+							VaultName: "test",
+						},
 					}, nil
 				},
 			},
-			archive: mockArchive{
-				mockExtract: func(filename string, filter []string) (archive.Info, error) {
-					sort.Strings(filter)
-
-					switch filename {
-					case "toglacier-archive-1.tar.gz":
-						if len(filter) != 2 || filter[0] != "file1" || filter[1] != "file3" {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-					case "toglacier-archive-2.tar.gz":
-						if len(filter) != 1 || filter[0] != "file2" {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
+			routes: []toglacier.Route{
+				{
+					Prefix:    "/data/photos",
+					VaultName: "photos",
+					Cloud: mockCloud{
+						mockList: func() ([]cloud.Backup, error) {
+							return []cloud.Backup{
+								{
+									ID:        "223456",
+									CreatedAt: now,
+									VaultName: "photos",
+								},
+							}, nil
+						},
+					},
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "123456" && b.Backup.ID != "223456" {
+						return fmt.Errorf("adding unexpected id %s", b.Backup.ID)
 					}
+
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
 					return nil, nil
 				},
-				mockFileChecksum: func(filename string) (string, error) {
-					return "", errors.New("checksum failed")
+				mockSaveInventoryDate: func(date time.Time) error {
+					return nil
 				},
 			},
 			logger: mockLogger{
@@ -1602,72 +2069,40 @@ func TestToGlacier_RetrieveBackup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("checksum failed"),
-		},
-		{
-			description: "it should detect an error while retrieving a backup part",
-			id:          "AWSID123",
-			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "AWSID123" {
-						return fmt.Errorf("unexpected id %s", b.Backup.ID)
-					}
-					return nil
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "223456",
+						CreatedAt: now,
+						VaultName: "photos",
+					},
 				},
-				mockList: func() (storage.Backups, error) {
-					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "AWSID123",
-								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
-								VaultName: "vault",
-								Size:      41,
-							},
-						},
-					}, nil
+				{
+					Backup: cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now.Add(-time.Hour),
+						VaultName: "test",
+					},
 				},
 			},
+		},
+		{
+			description: "it should detect an error listing the remote backups of a route",
+			remote:      true,
 			cloud: mockCloud{
-				mockGet: func(ids ...string) (filenames map[string]string, err error) {
-					if len(ids) == 0 {
-						return nil, nil
-					}
-
-					switch ids[0] {
-					case "AWSID123":
-						return map[string]string{
-							"AWSID123": "toglacier-archive-1.tar.gz",
-						}, nil
-					case "AWSID122":
-						return nil, errors.New("failed to download backup")
-					}
-
-					return nil, fmt.Errorf("unexpected id “%s”", ids[0])
+				mockList: func() ([]cloud.Backup, error) {
+					return nil, nil
 				},
 			},
-			archive: mockArchive{
-				mockExtract: func(filename string, filter []string) (archive.Info, error) {
-					switch filename {
-					case "toglacier-archive-1.tar.gz":
-						if len(filter) != 0 {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-
-						return archive.Info{
-							"file1": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusNew,
-								ID:       "AWSID123",
-								Checksum: "a5b2df3d72bd28d2382b0b4cca4c25fa260e018b58a915f1e5af14485a746ca8",
-							},
-							"file2": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusUnmodified,
-								ID:       "AWSID122",
-								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
-							},
-						}, nil
-					}
-					return nil, nil
+			routes: []toglacier.Route{
+				{
+					Prefix:    "/data/photos",
+					VaultName: "photos",
+					Cloud: mockCloud{
+						mockList: func() ([]cloud.Backup, error) {
+							return nil, errors.New("error listing backups")
+						},
+					},
 				},
 			},
 			logger: mockLogger{
@@ -1678,45 +2113,51 @@ func TestToGlacier_RetrieveBackup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("failed to download backup"),
+			expectedError: errors.New("error listing backups"),
 		},
 		{
-			description: "it should detect an error listing backups from local storage",
-			id:          "AWSID123",
-			storage: mockStorage{
-				mockList: func() (storage.Backups, error) {
-					return nil, errors.New("error listing the backups")
+			description: "it should keep the local information of backups that didn't change remotely",
+			remote:      true,
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{
+							ID:        "123456",
+							CreatedAt: now.Add(-48 * time.Hour),
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+						},
+					}, nil
 				},
 			},
-			expectedError: errors.New("error listing the backups"),
-		},
-		{
-			description: "it should detect when there's an error retrieving a backup",
-			id:          "AWSID123",
 			storage: mockStorage{
 				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "AWSID123" {
-						return fmt.Errorf("unexpected id %s", b.Backup.ID)
-					}
-					return nil
+					return fmt.Errorf("saving unchanged id %s", b.Backup.ID)
 				},
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
 						{
 							Backup: cloud.Backup{
-								ID:        "AWSID123",
-								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
-								VaultName: "vault",
-								Size:      41,
+								ID:        "123456",
+								CreatedAt: now.Add(-48 * time.Hour),
+								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "123456",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "915bd6a5873681a273f405c62993b6a96237eab9150fc525c9d57af0becb7ec1",
+								},
 							},
 						},
 					}, nil
 				},
-			},
-			cloud: mockCloud{
-				mockGet: func(ids ...string) (filenames map[string]string, err error) {
-					return nil, errors.New("error retrieving the backup")
+				mockRemove: func(id string) error {
+					return fmt.Errorf("removing unexpected id %s", id)
+				},
+				mockSaveInventoryDate: func(date time.Time) error {
+					return nil
 				},
 			},
 			logger: mockLogger{
@@ -1727,63 +2168,40 @@ func TestToGlacier_RetrieveBackup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("error retrieving the backup"),
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now.Add(-48 * time.Hour),
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+					},
+					Info: archive.Info{
+						"file1": archive.ItemInfo{
+							ID:       "123456",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "915bd6a5873681a273f405c62993b6a96237eab9150fc525c9d57af0becb7ec1",
+						},
+					},
+				},
+			},
 		},
 		{
-			description:  "it should detect an error decrypting the backup",
-			id:           "AWSID123",
-			backupSecret: "123456",
+			description: "it should list the local backups correctly",
 			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "AWSID123" {
-						return fmt.Errorf("unexpected id %s", b.Backup.ID)
-					}
-					return nil
-				},
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
 						{
 							Backup: cloud.Backup{
-								ID:        "AWSID123",
-								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
-								VaultName: "vault",
-								Size:      41,
+								ID:        "123456",
+								CreatedAt: now,
+								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+								VaultName: "test",
 							},
 						},
 					}, nil
 				},
 			},
-			envelop: mockEnvelop{
-				mockDecrypt: func(encryptedFilename, secret string) (string, error) {
-					return "", errors.New("invalid encrypted content")
-				},
-			},
-			cloud: mockCloud{
-				mockGet: func(ids ...string) (filenames map[string]string, err error) {
-					if len(ids) == 0 {
-						return nil, errors.New("no ids given")
-					}
-
-					n := path.Join(os.TempDir(), "toglacier-test-getenc")
-					if _, err := os.Stat(n); os.IsNotExist(err) {
-						f, err := os.Create(n)
-						if err != nil {
-							t.Fatalf("error creating a temporary file. details: %s", err)
-						}
-						defer f.Close()
-
-						content, err := hex.DecodeString("656e637279707465643a8fbd41664a1d72b4ea1fcecd618a6ed5c05c95bf65bfda2d4d176e8feff96f710000000000000000000000000000000091d8e827b5136dfac6bb3dbc51f15c17d34947880f91e62799910ea05053969abc28033550b3781111")
-						if err != nil {
-							t.Fatalf("error decoding encrypted archive. details: %s", err)
-						}
-
-						f.Write(content)
-					}
-
-					return map[string]string{ids[0]: n}, nil
-				},
-			},
 			logger: mockLogger{
 				mockDebug:    func(args ...interface{}) {},
 				mockDebugf:   func(format string, args ...interface{}) {},
@@ -1792,70 +2210,52 @@ func TestToGlacier_RetrieveBackup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("invalid encrypted content"),
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+					},
+				},
+			},
 		},
 		{
-			description: "it should detect an error while extracting the backup",
-			id:          "AWSID123",
+			description: "it should list only the local backups of the machine",
+			machineID:   "server1",
 			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "AWSID123" {
-						return fmt.Errorf("unexpected id %s", b.Backup.ID)
-					}
-					return nil
-				},
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
 						{
 							Backup: cloud.Backup{
-								ID:        "AWSID122",
-								CreatedAt: time.Date(2015, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "350c8ae1300b38a6cc74793e28712b5473c5f663bf8085b5c9bb0f191ed68f6d",
-								VaultName: "vault",
-								Size:      89,
+								ID:        "123456",
+								CreatedAt: now,
+								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+								VaultName: "test",
+								MachineID: "server1",
 							},
 						},
 						{
 							Backup: cloud.Backup{
-								ID:        "AWSID123",
-								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
-								VaultName: "vault",
-								Size:      41,
+								ID:        "123457",
+								CreatedAt: now.Add(-time.Hour),
+								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+								VaultName: "test",
+								MachineID: "server2",
 							},
-							Info: archive.Info{
-								"file1": archive.ItemInfo{
-									ID:       "AWSID123",
-									Status:   archive.ItemInfoStatusNew,
-									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
-								},
-								"file2": archive.ItemInfo{
-									ID:       "AWSID122",
-									Status:   archive.ItemInfoStatusUnmodified,
-									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
-								},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123458",
+								CreatedAt: now.Add(-2 * time.Hour),
+								Checksum:  "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
+								VaultName: "test",
 							},
 						},
 					}, nil
 				},
 			},
-			cloud: mockCloud{
-				mockGet: func(ids ...string) (filenames map[string]string, err error) {
-					return map[string]string{
-						"AWSID123": "toglacier-archive-1.tar.gz",
-						"AWSID122": "toglacier-archive-2.tar.gz",
-					}, nil
-				},
-			},
-			archive: mockArchive{
-				mockExtract: func(filename string, filter []string) (archive.Info, error) {
-					switch filename {
-					case "toglacier-archive-2.tar.gz":
-						return nil, errors.New("error extracting backup")
-					}
-					return nil, nil
-				},
-			},
 			logger: mockLogger{
 				mockDebug:    func(args ...interface{}) {},
 				mockDebugf:   func(format string, args ...interface{}) {},
@@ -1864,87 +2264,54 @@ func TestToGlacier_RetrieveBackup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("error extracting backup"),
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						MachineID: "server1",
+					},
+				},
+				{
+					Backup: cloud.Backup{
+						ID:        "123458",
+						CreatedAt: now.Add(-2 * time.Hour),
+						Checksum:  "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
+						VaultName: "test",
+					},
+				},
+			},
 		},
 		{
-			description: "it should detect an error while saving a backup locally",
-			id:          "AWSID123",
+			description: "it should list the local backups of all machines",
+			machineID:   "server1",
+			allMachines: true,
 			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					return errors.New("something went wrong")
-				},
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
 						{
 							Backup: cloud.Backup{
-								ID:        "AWSID122",
-								CreatedAt: time.Date(2015, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "325152353325adc8854e185ab59daf44c51e78404e1512eea9dca116f3a8c16d",
-								VaultName: "vault",
-								Size:      38,
+								ID:        "123456",
+								CreatedAt: now,
+								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+								VaultName: "test",
+								MachineID: "server1",
 							},
 						},
 						{
 							Backup: cloud.Backup{
-								ID:        "AWSID123",
-								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
-								VaultName: "vault",
-								Size:      41,
+								ID:        "123457",
+								CreatedAt: now.Add(-time.Hour),
+								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+								VaultName: "test",
+								MachineID: "server2",
 							},
 						},
 					}, nil
 				},
 			},
-			cloud: mockCloud{
-				mockGet: func(ids ...string) (filenames map[string]string, err error) {
-					if len(ids) == 0 {
-						return nil, nil
-					}
-
-					switch ids[0] {
-					case "AWSID123":
-						return map[string]string{
-							"AWSID123": "toglacier-archive-1.tar.gz",
-						}, nil
-					case "AWSID122":
-						return map[string]string{
-							"AWSID122": "toglacier-archive-2.tar.gz",
-						}, nil
-					}
-
-					return nil, fmt.Errorf("unexpected id “%s”", ids[0])
-				},
-			},
-			archive: mockArchive{
-				mockExtract: func(filename string, filter []string) (archive.Info, error) {
-					switch filename {
-					case "toglacier-archive-1.tar.gz":
-						if len(filter) != 0 {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-
-						return archive.Info{
-							"file1": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusNew,
-								ID:       "AWSID123",
-								Checksum: "a5b2df3d72bd28d2382b0b4cca4c25fa260e018b58a915f1e5af14485a746ca8",
-							},
-							"file2": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusUnmodified,
-								ID:       "AWSID122",
-								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
-							},
-						}, nil
-
-					case "toglacier-archive-2.tar.gz":
-						if len(filter) != 1 || filter[0] != "file2" {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-					}
-					return nil, nil
-				},
-			},
 			logger: mockLogger{
 				mockDebug:    func(args ...interface{}) {},
 				mockDebugf:   func(format string, args ...interface{}) {},
@@ -1953,104 +2320,149 @@ func TestToGlacier_RetrieveBackup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("something went wrong"),
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						MachineID: "server1",
+					},
+				},
+				{
+					Backup: cloud.Backup{
+						ID:        "123457",
+						CreatedAt: now.Add(-time.Hour),
+						Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+						VaultName: "test",
+						MachineID: "server2",
+					},
+				},
+			},
 		},
 		{
-			description: "it should detect an error while saving a backup part locally",
-			id:          "AWSID123",
-			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					return errors.New("something went wrong")
-				},
-				mockList: func() (storage.Backups, error) {
-					return storage.Backups{
+			description: "it should list only the remote backups of the machine",
+			remote:      true,
+			machineID:   "server1",
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
 						{
-							Backup: cloud.Backup{
-								ID:        "AWSID122",
-								CreatedAt: time.Date(2015, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "8d9ccbb4e474dbd211a7b1f115c7bddaa950842e51a60418c4e943dee29e9113",
-								VaultName: "vault",
-								Size:      41,
-							},
+							ID:        "123456",
+							CreatedAt: now.Add(-48 * time.Hour),
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+							MachineID: "server1",
 						},
 						{
-							Backup: cloud.Backup{
-								ID:        "AWSID123",
-								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
-								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
-								VaultName: "vault",
-								Size:      41,
-							},
-							Info: archive.Info{
-								"file1": archive.ItemInfo{
-									ID:       "AWSID123",
-									Status:   archive.ItemInfoStatusNew,
-									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
-								},
-								"file2": archive.ItemInfo{
-									ID:       "AWSID122",
-									Status:   archive.ItemInfoStatusUnmodified,
-									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
-								},
-								"file3": archive.ItemInfo{
-									ID:       "AWSID123",
-									Status:   archive.ItemInfoStatusNew,
-									Checksum: "429713c8e82ae8d02bff0cd368581903ac6d368cfdacc5bb5ec6fc14d13f3fd0",
-								},
-							},
+							ID:        "123457",
+							CreatedAt: now.Add(-48 * time.Hour),
+							Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+							VaultName: "test",
+							MachineID: "server2",
 						},
 					}, nil
 				},
 			},
-			cloud: mockCloud{
-				mockGet: func(ids ...string) (filenames map[string]string, err error) {
-					if len(ids) != 2 {
-						return nil, fmt.Errorf("unexpected number of ids: %v", ids)
-					}
-
-					return map[string]string{
-						"AWSID123": "toglacier-archive-1.tar.gz",
-						"AWSID122": "toglacier-archive-2.tar.gz",
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+				mockSaveInventoryDate: func(date time.Time) error {
+					return nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now.Add(-48 * time.Hour),
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+						MachineID: "server1",
+					},
+				},
+			},
+		},
+		{
+			description: "it should detect an error while listing the remote backups",
+			remote:      true,
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return nil, errors.New("error listing backups")
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("error listing backups"),
+		},
+		{
+			description: "it should detect an error while listing the local backups",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("error listing backups")
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("error listing backups"),
+		},
+		{
+			description: "it should detect an error while retrieving local backups for synch",
+			remote:      true,
+			cloud: mockCloud{
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{
+							ID:        "123456",
+							CreatedAt: now,
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+						},
 					}, nil
 				},
 			},
-			archive: mockArchive{
-				mockExtract: func(filename string, filter []string) (archive.Info, error) {
-					sort.Strings(filter)
-
-					switch filename {
-					case "toglacier-archive-1.tar.gz":
-						if len(filter) != 2 || filter[0] != "file1" || filter[1] != "file3" {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
-
-						return archive.Info{
-							"file1": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusNew,
-								ID:       "AWSID123",
-								Checksum: "a5b2df3d72bd28d2382b0b4cca4c25fa260e018b58a915f1e5af14485a746ca8",
-							},
-							"file2": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusUnmodified,
-								ID:       "AWSID122",
-								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
-							},
-						}, nil
-
-					case "toglacier-archive-2.tar.gz":
-						if len(filter) != 1 || filter[0] != "file2" {
-							return nil, fmt.Errorf("unexpected filter “%v”", filter)
-						}
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "123456" {
+						return fmt.Errorf("adding unexpected id %s", b.Backup.ID)
+					}
 
-						return archive.Info{
-							"file2": archive.ItemInfo{
-								Status:   archive.ItemInfoStatusNew,
-								ID:       "AWSID122",
-								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
-							},
-						}, nil
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("error retrieving backups")
+				},
+				mockRemove: func(id string) error {
+					if id != "123454" && id != "123455" {
+						return fmt.Errorf("removing unexpected id %s", id)
 					}
-					return nil, nil
+
+					return nil
 				},
 			},
 			logger: mockLogger{
@@ -2061,54 +2473,27 @@ func TestToGlacier_RetrieveBackup(t *testing.T) {
 				mockWarning:  func(args ...interface{}) {},
 				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("something went wrong"),
+			expectedError: errors.New("error retrieving backups"),
 		},
-	}
-
-	for _, scenario := range scenarios {
-		t.Run(scenario.description, func(t *testing.T) {
-			toGlacier := toglacier.ToGlacier{
-				Context: context.Background(),
-				Storage: scenario.storage,
-				Envelop: scenario.envelop,
-				Cloud:   scenario.cloud,
-				Archive: scenario.archive,
-				Logger:  scenario.logger,
-			}
-
-			err := toGlacier.RetrieveBackup(scenario.id, scenario.backupSecret, scenario.skipUnmodified)
-
-			if !archive.ErrorEqual(scenario.expectedError, err) && !ErrorEqual(scenario.expectedError, err) {
-				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
-			}
-		})
-	}
-}
-
-func TestToGlacier_RemoveBackups(t *testing.T) {
-	scenarios := []struct {
-		description   string
-		ids           []string
-		cloud         cloud.Cloud
-		storage       storage.Storage
-		expectedError error
-	}{
 		{
-			description: "it should remove a backup correctly (removing references)",
-			ids:         []string{"123456"},
+			description: "it should detect an error while removing local backups due to synch",
+			remote:      true,
 			cloud: mockCloud{
-				mockRemove: func(id string) error {
-					return nil
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{
+							ID:        "123456",
+							CreatedAt: now,
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+						},
+					}, nil
 				},
 			},
 			storage: mockStorage{
 				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "123457" {
-						return fmt.Errorf("saving unexpected backup id “%s”", b.Backup.ID)
-					}
-
-					if len(b.Info) > 0 {
-						return fmt.Errorf("unexpected number (%d) of items info", len(b.Info))
+					if b.Backup.ID != "123456" {
+						return fmt.Errorf("adding unexpected id %s", b.Backup.ID)
 					}
 
 					return nil
@@ -2117,477 +2502,4194 @@ func TestToGlacier_RemoveBackups(t *testing.T) {
 					return storage.Backups{
 						{
 							Backup: cloud.Backup{
-								ID:        "123457",
-								CreatedAt: time.Now(),
-							},
-							Info: archive.Info{
-								"filename1": archive.ItemInfo{
-									ID:     "123456",
-									Status: archive.ItemInfoStatusUnmodified,
-								},
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123456",
-								CreatedAt: time.Now().Add(-10 * time.Minute),
-							},
-							Info: archive.Info{
-								"filename2": archive.ItemInfo{
-									ID:     "123454",
-									Status: archive.ItemInfoStatusUnmodified,
-								},
+								ID:        "123454",
+								CreatedAt: now.Add(-30 * time.Hour),
+								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+								VaultName: "test",
 							},
 						},
 						{
 							Backup: cloud.Backup{
 								ID:        "123455",
-								CreatedAt: time.Now().Add(-20 * time.Minute),
-							},
-							Info: archive.Info{
-								"filename2": archive.ItemInfo{
-									ID:     "123455",
-									Status: archive.ItemInfoStatusNew,
-								},
+								CreatedAt: now.Add(-40 * time.Hour),
+								Checksum:  "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
+								VaultName: "test",
 							},
 						},
 					}, nil
 				},
 				mockRemove: func(id string) error {
-					if id != "123456" {
-						return fmt.Errorf("unexpected id “%s”", id)
-					}
-					return nil
+					return errors.New("error removing backup")
 				},
 			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("error removing backup"),
 		},
 		{
-			description: "it should remove a backup correctly (replacing references)",
-			ids:         []string{"123456"},
+			description: "it should detect an error while updating local backups due to synch",
+			remote:      true,
 			cloud: mockCloud{
-				mockRemove: func(id string) error {
-					return nil
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{
+							ID:        "123456",
+							CreatedAt: now,
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+						},
+					}, nil
 				},
 			},
 			storage: mockStorage{
 				mockSave: func(b storage.Backup) error {
-					if b.Backup.ID != "123457" {
-						return fmt.Errorf("saving unexpected backup id “%s”", b.Backup.ID)
-					}
-
-					if itemInfo, ok := b.Info["filename1"]; !ok || itemInfo.ID != "123455" {
-						return fmt.Errorf("unexpected archive information for backup 123457: %v", b.Info)
-					}
-
-					return nil
+					return errors.New("error updating backup")
 				},
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
 						{
 							Backup: cloud.Backup{
 								ID:        "123456",
-								CreatedAt: time.Now().Add(-10 * time.Minute),
-							},
-							Info: archive.Info{
-								"filename1": archive.ItemInfo{
-									ID:     "123456",
-									Status: archive.ItemInfoStatusModified,
-								},
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123457",
-								CreatedAt: time.Now(),
-							},
-							Info: archive.Info{
-								"filename1": archive.ItemInfo{
-									ID:     "123456",
-									Status: archive.ItemInfoStatusUnmodified,
-								},
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123455",
-								CreatedAt: time.Now().Add(-20 * time.Minute),
-							},
-							Info: archive.Info{
-								"filename1": archive.ItemInfo{
-									ID:     "123455",
-									Status: archive.ItemInfoStatusNew,
-								},
+								CreatedAt: now.Add(-time.Hour),
+								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+								VaultName: "test",
 							},
 						},
 					}, nil
 				},
-				mockRemove: func(id string) error {
-					if id != "123456" {
-						return fmt.Errorf("unexpected id “%s”", id)
-					}
-					return nil
-				},
-			},
-		},
-		{
-			description: "it should detect an error while removing the remote backup",
-			ids:         []string{"123456"},
-			cloud: mockCloud{
-				mockRemove: func(id string) error {
-					return errors.New("error removing backup")
-				},
-			},
-			storage: mockStorage{
-				mockRemove: func(id string) error {
-					return nil
-				},
-			},
-			expectedError: errors.New("error removing backup"),
-		},
-		{
-			description: "it should detect an error listing the backups",
-			ids:         []string{"123456"},
-			cloud: mockCloud{
-				mockRemove: func(id string) error {
-					return nil
-				},
 			},
-			storage: mockStorage{
-				mockList: func() (storage.Backups, error) {
-					return nil, errors.New("failed to list backups")
-				},
-				mockRemove: func(id string) error {
-					return nil
-				},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
 			},
-			expectedError: errors.New("failed to list backups"),
+			expectedError: errors.New("error updating backup"),
 		},
 		{
-			description: "it should detect an error saving the backup",
-			ids:         []string{"123456"},
+			description: "it should detect an error while adding new backups due to synch",
+			remote:      true,
 			cloud: mockCloud{
-				mockRemove: func(id string) error {
-					return nil
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{
+							ID:        "123456",
+							CreatedAt: now,
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+						},
+					}, nil
 				},
 			},
 			storage: mockStorage{
 				mockSave: func(b storage.Backup) error {
-					return errors.New("could not save the backup")
+					return errors.New("error adding backup")
 				},
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
 						{
 							Backup: cloud.Backup{
-								ID:        "123457",
-								CreatedAt: time.Now(),
-							},
-							Info: archive.Info{
-								"filename1": archive.ItemInfo{
-									ID:     "123456",
-									Status: archive.ItemInfoStatusUnmodified,
-								},
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123456",
-								CreatedAt: time.Now().Add(-10 * time.Minute),
-							},
-							Info: archive.Info{
-								"filename2": archive.ItemInfo{
-									ID:     "123454",
-									Status: archive.ItemInfoStatusUnmodified,
-								},
+								ID:        "123454",
+								CreatedAt: now.Add(-time.Second),
+								Checksum:  "03c7c9c26fbb71dbc1546fd2fd5f2fbc3f4a410360e8fc016c41593b2456cf59",
+								VaultName: "test",
 							},
 						},
 						{
 							Backup: cloud.Backup{
 								ID:        "123455",
-								CreatedAt: time.Now().Add(-20 * time.Minute),
-							},
-							Info: archive.Info{
-								"filename2": archive.ItemInfo{
-									ID:     "123455",
-									Status: archive.ItemInfoStatusNew,
-								},
+								CreatedAt: now.Add(-time.Minute),
+								Checksum:  "49ddf1762657fa04e29aa8ca6b22a848ce8a9b590748d6d708dd208309bcfee6",
+								VaultName: "test",
 							},
 						},
 					}, nil
 				},
 				mockRemove: func(id string) error {
+					if id != "123454" && id != "123455" {
+						return fmt.Errorf("removing unexpected id %s", id)
+					}
+
 					return nil
 				},
 			},
-			expectedError: errors.New("could not save the backup"),
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("error adding backup"),
 		},
 		{
-			description: "it should detect an error while removing the local backup",
-			ids:         []string{"123456"},
+			description: "it should use the last synchronized inventory when it is recent",
+			remote:      true,
+			maxAge:      24 * time.Hour,
 			cloud: mockCloud{
-				mockRemove: func(id string) error {
-					return nil
+				mockList: func() ([]cloud.Backup, error) {
+					return nil, errors.New("cloud inventory shouldn't be requested")
 				},
 			},
 			storage: mockStorage{
-				mockSave: func(b storage.Backup) error {
-					return nil
-				},
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "123457",
-								CreatedAt: time.Now(),
-							},
-							Info: archive.Info{
-								"filename1": archive.ItemInfo{
-									ID:     "123456",
-									Status: archive.ItemInfoStatusUnmodified,
-								},
-							},
-						},
 						{
 							Backup: cloud.Backup{
 								ID:        "123456",
-								CreatedAt: time.Now().Add(-10 * time.Minute),
-							},
-							Info: archive.Info{
-								"filename2": archive.ItemInfo{
-									ID:     "123454",
-									Status: archive.ItemInfoStatusUnmodified,
-								},
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123455",
-								CreatedAt: time.Now().Add(-20 * time.Minute),
-							},
-							Info: archive.Info{
-								"filename2": archive.ItemInfo{
-									ID:     "123455",
-									Status: archive.ItemInfoStatusNew,
-								},
+								CreatedAt: now,
+								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+								VaultName: "test",
 							},
 						},
 					}, nil
 				},
-				mockRemove: func(id string) error {
-					return errors.New("error removing backup")
+				mockInventoryDate: func() (time.Time, error) {
+					return now.Add(-time.Hour), nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now,
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+					},
 				},
 			},
-			expectedError: errors.New("error removing backup"),
 		},
-	}
-
-	for _, scenario := range scenarios {
-		t.Run(scenario.description, func(t *testing.T) {
-			toGlacier := toglacier.ToGlacier{
-				Context: context.Background(),
-				Cloud:   scenario.cloud,
-				Storage: scenario.storage,
-			}
-
-			if err := toGlacier.RemoveBackups(scenario.ids...); !ErrorEqual(scenario.expectedError, err) {
-				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
-			}
-		})
-	}
-}
-
-func TestToGlacier_RemoveOldBackups(t *testing.T) {
-	now := time.Now()
-
-	scenarios := []struct {
-		description   string
-		keepBackups   int
-		cloud         cloud.Cloud
-		storage       storage.Storage
-		expectedError error
-	}{
 		{
-			description: "it should remove all old backups correctly",
-			keepBackups: 2,
+			description: "it should retrieve the cloud inventory when the last synchronization is too old",
+			remote:      true,
+			maxAge:      time.Hour,
 			cloud: mockCloud{
-				mockRemove: func(id string) error {
-					if id != "123456" {
-						return fmt.Errorf("unexpected id %s", id)
-					}
-					return nil
+				mockList: func() ([]cloud.Backup, error) {
+					return []cloud.Backup{
+						{
+							ID:        "123456",
+							CreatedAt: now.Add(-48 * time.Hour),
+							Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+							VaultName: "test",
+						},
+					}, nil
 				},
 			},
 			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					return nil
+				},
 				mockList: func() (storage.Backups, error) {
-					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "123456",
-								CreatedAt: now,
-								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
-								VaultName: "test",
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123457",
-								CreatedAt: now.Add(time.Second),
-								Checksum:  "0484ed70359cd1a4337d16a4143a3d247e0a3ecbce01482c318d709ed5161016",
-								VaultName: "test",
-							},
-							Info: archive.Info{
-								"file1": archive.ItemInfo{
-									ID:       "123459",
-									Status:   archive.ItemInfoStatusUnmodified,
-									Checksum: "4c6733f2d51c5cde947835279ce9f031bcacaa2265988ef1353078810695fb20",
-								},
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123458",
-								CreatedAt: now.Add(time.Minute),
-								Checksum:  "5f9c426fb1e150c1c09dda260bb962c7602b595df7586a1f3899735b839b138f",
-								VaultName: "test",
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123459",
-								CreatedAt: now.Add(-time.Hour),
-								Checksum:  "9a16f6eaebe1a7a3c9e456c5a37063d712de11d839040e5963cf864feb16e114",
-								VaultName: "test",
-							},
-						},
-					}, nil
+					return nil, nil
 				},
-				mockRemove: func(id string) error {
-					if id != "123456" {
-						return fmt.Errorf("removing unexpected id %s", id)
-					}
+				mockInventoryDate: func() (time.Time, error) {
+					return now.Add(-2 * time.Hour), nil
+				},
+				mockSaveInventoryDate: func(date time.Time) error {
 					return nil
 				},
 			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expected: storage.Backups{
+				{
+					Backup: cloud.Backup{
+						ID:        "123456",
+						CreatedAt: now.Add(-48 * time.Hour),
+						Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+						VaultName: "test",
+					},
+				},
+			},
 		},
 		{
-			description: "it should detect when there's an error listing the local backups",
-			keepBackups: 2,
+			description: "it should detect an error while retrieving the last inventory date",
+			remote:      true,
+			maxAge:      time.Hour,
 			storage: mockStorage{
-				mockList: func() (storage.Backups, error) {
-					return nil, errors.New("local storage corrupted")
+				mockInventoryDate: func() (time.Time, error) {
+					return time.Time{}, errors.New("error reading inventory date")
 				},
 			},
-			expectedError: errors.New("local storage corrupted"),
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("error reading inventory date"),
 		},
 		{
-			description: "it should detect when there is an error removing an old backup from the cloud",
-			keepBackups: 2,
+			description: "it should detect an error while saving the inventory date",
+			remote:      true,
 			cloud: mockCloud{
-				mockRemove: func(id string) error {
-					return errors.New("backup not found")
+				mockList: func() ([]cloud.Backup, error) {
+					return nil, nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+				mockSaveInventoryDate: func(date time.Time) error {
+					return errors.New("error saving inventory date")
 				},
 			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("error saving inventory date"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context:     context.Background(),
+				Cloud:       scenario.cloud,
+				Storage:     scenario.storage,
+				Logger:      scenario.logger,
+				MachineID:   scenario.machineID,
+				AllMachines: scenario.allMachines,
+				Routes:      scenario.routes,
+			}
+
+			backups, err := toGlacier.ListBackups(scenario.remote, scenario.maxAge)
+
+			if !reflect.DeepEqual(scenario.expected, backups) {
+				t.Errorf("backups don't match.\n%s", Diff(scenario.expected, backups))
+			}
+
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_RetrieveBackup(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		id             string
+		backupSecret   string
+		skipUnmodified bool
+		storage        storage.Storage
+		envelop        archive.Envelop
+		cloud          cloud.Cloud
+		replica        cloud.Cloud
+		archive        archive.Archive
+		parity         archive.Parity
+		logger         log.Logger
+		expectedError  error
+	}{
+		{
+			description: "it should retrieve a backup correctly",
+			id:          "AWSID123",
 			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "AWSID123" && b.Backup.ID != "AWSID122" && b.Backup.ID != "AWSID124" {
+						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					}
+					return nil
+				},
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
 						{
 							Backup: cloud.Backup{
-								ID:        "123456",
-								CreatedAt: now,
-								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
-								VaultName: "test",
+								ID:        "AWSID122",
+								CreatedAt: time.Date(2015, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "8d9ccbb4e474dbd211a7b1f115c7bddaa950842e51a60418c4e943dee29e9113",
+								VaultName: "vault",
+								Size:      41,
 							},
 						},
 						{
 							Backup: cloud.Backup{
-								ID:        "123457",
-								CreatedAt: now.Add(time.Second),
-								Checksum:  "0484ed70359cd1a4337d16a4143a3d247e0a3ecbce01482c318d709ed5161016",
-								VaultName: "test",
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
 							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123458",
-								CreatedAt: now.Add(time.Minute),
-								Checksum:  "5f9c426fb1e150c1c09dda260bb962c7602b595df7586a1f3899735b839b138f",
-								VaultName: "test",
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+								},
+								"file2": archive.ItemInfo{
+									ID:       "AWSID122",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+								},
+								"file3": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "429713c8e82ae8d02bff0cd368581903ac6d368cfdacc5bb5ec6fc14d13f3fd0",
+								},
+								"file4": archive.ItemInfo{
+									ID:       "AWSID124",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "352c30aa6751b62c658473a90d0a3ffcf98e66f00968c5320a2f1c2969db7024",
+								},
 							},
 						},
 					}, nil
 				},
-				mockRemove: func(id string) error {
-					if id != "123456" {
-						return fmt.Errorf("removing unexpected id %s", id)
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					if len(ids) != 3 {
+						return nil, fmt.Errorf("unexpected number of ids: %v", ids)
 					}
-					return nil
+
+					return map[string]string{
+						"AWSID123": "toglacier-archive-1.tar.gz",
+						"AWSID122": "toglacier-archive-2.tar.gz",
+						"AWSID124": "toglacier-archive-3.tar.gz",
+					}, nil
 				},
 			},
-			expectedError: errors.New("backup not found"),
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					sort.Strings(filter)
+
+					switch filename {
+					case "toglacier-archive-1.tar.gz":
+						if len(filter) != 2 || filter[0] != "file1" || filter[1] != "file3" {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+
+						return archive.Info{
+							"file1": archive.ItemInfo{
+								ID:       "AWSID123",
+								Status:   archive.ItemInfoStatusNew,
+								Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+							},
+							"file2": archive.ItemInfo{
+								ID:       "AWSID122",
+								Status:   archive.ItemInfoStatusUnmodified,
+								Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+							},
+							"file3": archive.ItemInfo{
+								ID:       "AWSID123",
+								Status:   archive.ItemInfoStatusNew,
+								Checksum: "429713c8e82ae8d02bff0cd368581903ac6d368cfdacc5bb5ec6fc14d13f3fd0",
+							},
+							"file4": archive.ItemInfo{
+								ID:       "AWSID124",
+								Status:   archive.ItemInfoStatusUnmodified,
+								Checksum: "352c30aa6751b62c658473a90d0a3ffcf98e66f00968c5320a2f1c2969db7024",
+							},
+						}, nil
+
+					case "toglacier-archive-2.tar.gz":
+						if len(filter) != 1 || filter[0] != "file2" {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+
+						return archive.Info{
+							"file2": archive.ItemInfo{
+								ID:       "AWSID122",
+								Status:   archive.ItemInfoStatusNew,
+								Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+							},
+							"file4": archive.ItemInfo{
+								ID:       "AWSID124",
+								Status:   archive.ItemInfoStatusUnmodified,
+								Checksum: "352c30aa6751b62c658473a90d0a3ffcf98e66f00968c5320a2f1c2969db7024",
+							},
+						}, nil
+
+					case "toglacier-archive-3.tar.gz":
+						if len(filter) != 1 || filter[0] != "file4" {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+
+						return archive.Info{
+							"file4": archive.ItemInfo{
+								ID:       "AWSID124",
+								Status:   archive.ItemInfoStatusNew,
+								Checksum: "352c30aa6751b62c658473a90d0a3ffcf98e66f00968c5320a2f1c2969db7024",
+							},
+						}, nil
+					}
+					return nil, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+		},
+		{
+			description: "it should retrieve a backup correctly repairing it with the parity data",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					if _, ok := progress.Downloaded["AWSID125"]; ok {
+						return errors.New("parity data stored in the restore progress")
+					}
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "AWSID123" {
+						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+								ParityID:  "AWSID125",
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+								},
+							},
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					if len(ids) != 2 || ids[0] != "AWSID123" || ids[1] != "AWSID125" {
+						return nil, fmt.Errorf("unexpected ids: %v", ids)
+					}
+
+					return map[string]string{
+						"AWSID123": "toglacier-archive-1.tar.gz",
+						"AWSID125": "toglacier-parity-1",
+					}, nil
+				},
+			},
+			parity: mockParity{
+				mockRepair: func(filename, parityFilename string) error {
+					if filename != "toglacier-archive-1.tar.gz" || parityFilename != "toglacier-parity-1" {
+						return fmt.Errorf("unexpected files “%s” and “%s”", filename, parityFilename)
+					}
+					return nil
+				},
+			},
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					if filename != "toglacier-archive-1.tar.gz" {
+						return nil, fmt.Errorf("unexpected filename “%s”", filename)
+					}
+
+					return archive.Info{
+						"file1": archive.ItemInfo{
+							ID:       "AWSID123",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+						},
+					}, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+		},
+		{
+			description: "it should retrieve a backup from the replica when the cloud fails",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					if filename := progress.Downloaded["AWSID123"]; filename != "" && filename != "toglacier-replica-1.tar.gz" {
+						return fmt.Errorf("unexpected downloaded file “%s”", filename)
+					}
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "AWSID123" {
+						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+								ParityID:  "AWSID125",
+								ReplicaID: "REPLICAID123",
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+								},
+							},
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					return nil, errors.New("region unavailable")
+				},
+			},
+			replica: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					if len(ids) != 1 || ids[0] != "REPLICAID123" {
+						return nil, fmt.Errorf("unexpected ids: %v", ids)
+					}
+
+					return map[string]string{
+						"REPLICAID123": "toglacier-replica-1.tar.gz",
+					}, nil
+				},
+			},
+			parity: mockParity{
+				mockRepair: func(filename, parityFilename string) error {
+					return errors.New("parity data used with the replica")
+				},
+			},
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					if filename != "toglacier-replica-1.tar.gz" {
+						return nil, fmt.Errorf("unexpected filename “%s”", filename)
+					}
+
+					return archive.Info{
+						"file1": archive.ItemInfo{
+							ID:       "AWSID123",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+						},
+					}, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+		},
+		{
+			description: "it should detect when the cloud fails and the backup has no copy in the replica",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+								},
+							},
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					return nil, errors.New("region unavailable")
+				},
+			},
+			replica: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					return nil, fmt.Errorf("unexpected ids: %v", ids)
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("region unavailable"),
+		},
+		{
+			description:  "it should retrieve an encrypted backup correctly",
+			id:           "AWSID123",
+			backupSecret: "1234567890123456",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "AWSID123" {
+						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+							},
+						},
+					}, nil
+				},
+			},
+			envelop: mockEnvelop{
+				mockDecrypt: func(encryptedFilename, secret string) (string, error) {
+					f, err := ioutil.TempFile("", "toglacier-test")
+					if err != nil {
+						t.Fatalf("error creating temporary file. details: %s", err)
+					}
+					defer f.Close()
+
+					return f.Name(), nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					if len(ids) == 0 {
+						return nil, nil
+					}
+
+					n := path.Join(os.TempDir(), "toglacier-test-getenc")
+					if _, err := os.Stat(n); os.IsNotExist(err) {
+						f, err := os.Create(n)
+						if err != nil {
+							t.Fatalf("error creating a temporary file. details: %s", err)
+						}
+						defer f.Close()
+
+						content, err := hex.DecodeString("656e637279707465643a8fbd41664a1d72b4ea1fcecd618a6ed5c05c95bf65bfda2d4d176e8feff96f710000000000000000000000000000000091d8e827b5136dfac6bb3dbc51f15c17d34947880f91e62799910ea05053969abc28033550b3781111")
+						if err != nil {
+							t.Fatalf("error decoding encrypted archive. details: %s", err)
+						}
+
+						f.Write(content)
+					}
+
+					return map[string]string{ids[0]: n}, nil
+				},
+			},
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					return nil, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+		},
+		{
+			description: "it should retrieve a backup correctly with no archive information and all other backup parts",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "AWSID123" && b.Backup.ID != "AWSID122" {
+						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID122",
+								CreatedAt: time.Date(2015, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "325152353325adc8854e185ab59daf44c51e78404e1512eea9dca116f3a8c16d",
+								VaultName: "vault",
+								Size:      38,
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+							},
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					if len(ids) == 0 {
+						return nil, nil
+					}
+
+					switch ids[0] {
+					case "AWSID123":
+						return map[string]string{
+							"AWSID123": "toglacier-archive-1.tar.gz",
+						}, nil
+					case "AWSID122":
+						return map[string]string{
+							"AWSID122": "toglacier-archive-2.tar.gz",
+						}, nil
+					}
+
+					return nil, fmt.Errorf("unexpected id “%s”", ids[0])
+				},
+			},
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					switch filename {
+					case "toglacier-archive-1.tar.gz":
+						if len(filter) != 0 {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+
+						return archive.Info{
+							"file1": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusNew,
+								ID:       "AWSID123",
+								Checksum: "a5b2df3d72bd28d2382b0b4cca4c25fa260e018b58a915f1e5af14485a746ca8",
+							},
+							"file2": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusUnmodified,
+								ID:       "AWSID122",
+								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
+							},
+						}, nil
+
+					case "toglacier-archive-2.tar.gz":
+						if len(filter) != 1 || filter[0] != "file2" {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+
+						return archive.Info{
+							"file2": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusNew,
+								ID:       "AWSID122",
+								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
+							},
+						}, nil
+					}
+					return nil, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+		},
+		{
+			description: "it should retrieve a backup correctly that does not exist locally",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "AWSID123" && b.Backup.ID != "AWSID122" {
+						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID122",
+								CreatedAt: time.Date(2015, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "325152353325adc8854e185ab59daf44c51e78404e1512eea9dca116f3a8c16d",
+								VaultName: "vault",
+								Size:      38,
+							},
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					if len(ids) == 0 {
+						return nil, nil
+					}
+
+					switch ids[0] {
+					case "AWSID123":
+						return map[string]string{
+							"AWSID123": "toglacier-archive-1.tar.gz",
+						}, nil
+					case "AWSID122":
+						return map[string]string{
+							"AWSID122": "toglacier-archive-2.tar.gz",
+						}, nil
+					}
+
+					return nil, fmt.Errorf("unexpected id “%s”", ids[0])
+				},
+			},
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					switch filename {
+					case "toglacier-archive-1.tar.gz":
+						if len(filter) != 0 {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+
+						return archive.Info{
+							"file1": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusNew,
+								ID:       "AWSID123",
+								Checksum: "a5b2df3d72bd28d2382b0b4cca4c25fa260e018b58a915f1e5af14485a746ca8",
+							},
+							"file2": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusUnmodified,
+								ID:       "AWSID122",
+								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
+							},
+						}, nil
+
+					case "toglacier-archive-2.tar.gz":
+						if len(filter) != 1 || filter[0] != "file2" {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+
+						return archive.Info{
+							"file2": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusNew,
+								ID:       "AWSID122",
+								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
+							},
+						}, nil
+					}
+					return nil, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+		},
+		{
+			description:    "it should retrieve a backup correctly skipping unmodified files in disk",
+			id:             "AWSID123",
+			skipUnmodified: true,
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "AWSID123" {
+						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+								},
+								"file2": archive.ItemInfo{
+									ID:       "AWSID122",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "46813af30d24fb7ad0a019b0da4fcde88368133fcfe39c5a8b25a328e6be4ab2",
+								},
+								"file3": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "429713c8e82ae8d02bff0cd368581903ac6d368cfdacc5bb5ec6fc14d13f3fd0",
+								},
+								"file4": archive.ItemInfo{
+									ID:       "AWSID124",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "79edf074b55cdb3088721e88814523124c7da05001175e14b0dcf78336730fcd",
+								},
+							},
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					if len(ids) != 1 {
+						return nil, fmt.Errorf("unexpected number of ids: %v", ids)
+					}
+
+					return map[string]string{
+						"AWSID123": "toglacier-archive-1.tar.gz",
+					}, nil
+				},
+			},
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					sort.Strings(filter)
+
+					switch filename {
+					case "toglacier-archive-1.tar.gz":
+						if len(filter) != 2 || filter[0] != "file1" || filter[1] != "file3" {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+					case "toglacier-archive-2.tar.gz":
+						if len(filter) != 1 || filter[0] != "file2" {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+					}
+					return nil, nil
+				},
+				mockFileChecksum: func(filename string) (string, error) {
+					switch filename {
+					case "file1":
+						return "a9300479a7d2c663b4806af1bce4483f93175cae287979ee0364d057445482c8", nil
+					case "file2":
+						return "46813af30d24fb7ad0a019b0da4fcde88368133fcfe39c5a8b25a328e6be4ab2", nil
+					case "file3":
+						return "64bd312e9c81172627d898d7ad146d2e9ea47f47dd67ea79477ab224ab8fb01b", nil
+					case "file4":
+						return "79edf074b55cdb3088721e88814523124c7da05001175e14b0dcf78336730fcd", nil
+					}
+
+					return "", fmt.Errorf("unexpected filename “%s”", filename)
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+		},
+		{
+			description:    "it should detect when there is a problem calculating the file checksum",
+			id:             "AWSID123",
+			skipUnmodified: true,
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "AWSID123" {
+						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+								},
+								"file2": archive.ItemInfo{
+									ID:       "AWSID122",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "46813af30d24fb7ad0a019b0da4fcde88368133fcfe39c5a8b25a328e6be4ab2",
+								},
+								"file3": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "429713c8e82ae8d02bff0cd368581903ac6d368cfdacc5bb5ec6fc14d13f3fd0",
+								},
+								"file4": archive.ItemInfo{
+									ID:       "AWSID124",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "79edf074b55cdb3088721e88814523124c7da05001175e14b0dcf78336730fcd",
+								},
+							},
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					if len(ids) != 1 {
+						return nil, fmt.Errorf("unexpected number of ids: %v", ids)
+					}
+
+					return map[string]string{
+						"AWSID123": "toglacier-archive-1.tar.gz",
+					}, nil
+				},
+			},
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					sort.Strings(filter)
+
+					switch filename {
+					case "toglacier-archive-1.tar.gz":
+						if len(filter) != 2 || filter[0] != "file1" || filter[1] != "file3" {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+					case "toglacier-archive-2.tar.gz":
+						if len(filter) != 1 || filter[0] != "file2" {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+					}
+					return nil, nil
+				},
+				mockFileChecksum: func(filename string) (string, error) {
+					return "", errors.New("checksum failed")
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("checksum failed"),
+		},
+		{
+			description: "it should detect an error while retrieving a backup part",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "AWSID123" {
+						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+							},
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					if len(ids) == 0 {
+						return nil, nil
+					}
+
+					switch ids[0] {
+					case "AWSID123":
+						return map[string]string{
+							"AWSID123": "toglacier-archive-1.tar.gz",
+						}, nil
+					case "AWSID122":
+						return nil, errors.New("failed to download backup")
+					}
+
+					return nil, fmt.Errorf("unexpected id “%s”", ids[0])
+				},
+			},
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					switch filename {
+					case "toglacier-archive-1.tar.gz":
+						if len(filter) != 0 {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+
+						return archive.Info{
+							"file1": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusNew,
+								ID:       "AWSID123",
+								Checksum: "a5b2df3d72bd28d2382b0b4cca4c25fa260e018b58a915f1e5af14485a746ca8",
+							},
+							"file2": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusUnmodified,
+								ID:       "AWSID122",
+								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
+							},
+						}, nil
+					}
+					return nil, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("failed to download backup"),
+		},
+		{
+			description: "it should resume a backup retrieval reusing the archives already downloaded",
+			id:          "AWSID123",
+			storage: func() storage.Storage {
+				f, err := ioutil.TempFile("", "toglacier-test")
+				if err != nil {
+					t.Fatalf("error creating temporary file. details: %s", err)
+				}
+				f.Close()
+
+				return mockStorage{
+					mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+						if id != "AWSID123" {
+							return fmt.Errorf("unexpected id %s", id)
+						}
+
+						if !progress.Extracted["AWSID124"] {
+							return fmt.Errorf("archive AWSID124 not marked as extracted: %v", progress.Extracted)
+						}
+
+						return nil
+					},
+					mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+						return storage.RestoreProgress{
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+								},
+								"file2": archive.ItemInfo{
+									ID:       "AWSID122",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "429713c8e82ae8d02bff0cd368581903ac6d368cfdacc5bb5ec6fc14d13f3fd0",
+								},
+								"file4": archive.ItemInfo{
+									ID:       "AWSID124",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "352c30aa6751b62c658473a90d0a3ffcf98e66f00968c5320a2f1c2969db7024",
+								},
+							},
+							Downloaded: map[string]string{
+								"AWSID124": f.Name(),
+							},
+							Extracted: map[string]bool{
+								"AWSID123": true,
+								"AWSID122": true,
+							},
+						}, nil
+					},
+					mockRemoveRestoreProgress: func(id string) error {
+						if id != "AWSID123" {
+							return fmt.Errorf("unexpected id %s", id)
+						}
+
+						return nil
+					},
+					mockList: func() (storage.Backups, error) {
+						return nil, nil
+					},
+				}
+			}(),
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					return nil, fmt.Errorf("unexpected download of ids: %v", ids)
+				},
+			},
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					if len(filter) != 1 || filter[0] != "file4" {
+						return nil, fmt.Errorf("unexpected filter “%v”", filter)
+					}
+
+					return archive.Info{
+						"file4": archive.ItemInfo{
+							ID:       "AWSID124",
+							Status:   archive.ItemInfoStatusNew,
+							Checksum: "352c30aa6751b62c658473a90d0a3ffcf98e66f00968c5320a2f1c2969db7024",
+						},
+					}, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+		},
+		{
+			description: "it should detect an error listing backups from local storage",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("error listing the backups")
+				},
+			},
+			expectedError: errors.New("error listing the backups"),
+		},
+		{
+			description: "it should detect an error loading the restore progress",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, errors.New("restore file corrupted")
+				},
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("restore file corrupted"),
+		},
+		{
+			description: "it should detect an error saving the restore progress",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return errors.New("disk full")
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					return map[string]string{
+						"AWSID123": "toglacier-archive-1.tar.gz",
+					}, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("disk full"),
+		},
+		{
+			description: "it should detect when there's an error retrieving a backup",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "AWSID123" {
+						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+							},
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					return nil, errors.New("error retrieving the backup")
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("error retrieving the backup"),
+		},
+		{
+			description:  "it should detect an error decrypting the backup",
+			id:           "AWSID123",
+			backupSecret: "123456",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "AWSID123" {
+						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+							},
+						},
+					}, nil
+				},
+			},
+			envelop: mockEnvelop{
+				mockDecrypt: func(encryptedFilename, secret string) (string, error) {
+					return "", errors.New("invalid encrypted content")
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					if len(ids) == 0 {
+						return nil, errors.New("no ids given")
+					}
+
+					n := path.Join(os.TempDir(), "toglacier-test-getenc")
+					if _, err := os.Stat(n); os.IsNotExist(err) {
+						f, err := os.Create(n)
+						if err != nil {
+							t.Fatalf("error creating a temporary file. details: %s", err)
+						}
+						defer f.Close()
+
+						content, err := hex.DecodeString("656e637279707465643a8fbd41664a1d72b4ea1fcecd618a6ed5c05c95bf65bfda2d4d176e8feff96f710000000000000000000000000000000091d8e827b5136dfac6bb3dbc51f15c17d34947880f91e62799910ea05053969abc28033550b3781111")
+						if err != nil {
+							t.Fatalf("error decoding encrypted archive. details: %s", err)
+						}
+
+						f.Write(content)
+					}
+
+					return map[string]string{ids[0]: n}, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("invalid encrypted content"),
+		},
+		{
+			description: "it should detect an error while extracting the backup",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "AWSID123" {
+						return fmt.Errorf("unexpected id %s", b.Backup.ID)
+					}
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID122",
+								CreatedAt: time.Date(2015, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "350c8ae1300b38a6cc74793e28712b5473c5f663bf8085b5c9bb0f191ed68f6d",
+								VaultName: "vault",
+								Size:      89,
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+								},
+								"file2": archive.ItemInfo{
+									ID:       "AWSID122",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+								},
+							},
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					return map[string]string{
+						"AWSID123": "toglacier-archive-1.tar.gz",
+						"AWSID122": "toglacier-archive-2.tar.gz",
+					}, nil
+				},
+			},
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					switch filename {
+					case "toglacier-archive-2.tar.gz":
+						return nil, errors.New("error extracting backup")
+					}
+					return nil, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("error extracting backup"),
+		},
+		{
+			description: "it should detect an error while saving a backup locally",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					return errors.New("something went wrong")
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID122",
+								CreatedAt: time.Date(2015, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "325152353325adc8854e185ab59daf44c51e78404e1512eea9dca116f3a8c16d",
+								VaultName: "vault",
+								Size:      38,
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+							},
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					if len(ids) == 0 {
+						return nil, nil
+					}
+
+					switch ids[0] {
+					case "AWSID123":
+						return map[string]string{
+							"AWSID123": "toglacier-archive-1.tar.gz",
+						}, nil
+					case "AWSID122":
+						return map[string]string{
+							"AWSID122": "toglacier-archive-2.tar.gz",
+						}, nil
+					}
+
+					return nil, fmt.Errorf("unexpected id “%s”", ids[0])
+				},
+			},
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					switch filename {
+					case "toglacier-archive-1.tar.gz":
+						if len(filter) != 0 {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+
+						return archive.Info{
+							"file1": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusNew,
+								ID:       "AWSID123",
+								Checksum: "a5b2df3d72bd28d2382b0b4cca4c25fa260e018b58a915f1e5af14485a746ca8",
+							},
+							"file2": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusUnmodified,
+								ID:       "AWSID122",
+								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
+							},
+						}, nil
+
+					case "toglacier-archive-2.tar.gz":
+						if len(filter) != 1 || filter[0] != "file2" {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+					}
+					return nil, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("something went wrong"),
+		},
+		{
+			description: "it should detect an error while saving a backup part locally",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+					return nil
+				},
+				mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+					return storage.RestoreProgress{}, nil
+				},
+				mockRemoveRestoreProgress: func(id string) error {
+					return nil
+				},
+				mockSave: func(b storage.Backup) error {
+					return errors.New("something went wrong")
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID122",
+								CreatedAt: time.Date(2015, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "8d9ccbb4e474dbd211a7b1f115c7bddaa950842e51a60418c4e943dee29e9113",
+								VaultName: "vault",
+								Size:      41,
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								CreatedAt: time.Date(2016, 12, 27, 8, 14, 53, 0, time.UTC),
+								Checksum:  "cb63324d2c35cdfcb4521e15ca4518bd0ed9dc2364a9f47de75151b3f9b4b705",
+								VaultName: "vault",
+								Size:      41,
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+								},
+								"file2": archive.ItemInfo{
+									ID:       "AWSID122",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "a6d392677577af12fb1f4ceb510940374c3378455a1485b0226a35ef5ad65242",
+								},
+								"file3": archive.ItemInfo{
+									ID:       "AWSID123",
+									Status:   archive.ItemInfoStatusNew,
+									Checksum: "429713c8e82ae8d02bff0cd368581903ac6d368cfdacc5bb5ec6fc14d13f3fd0",
+								},
+							},
+						},
+					}, nil
+				},
+			},
+			cloud: mockCloud{
+				mockGet: func(ids ...string) (filenames map[string]string, err error) {
+					if len(ids) != 2 {
+						return nil, fmt.Errorf("unexpected number of ids: %v", ids)
+					}
+
+					return map[string]string{
+						"AWSID123": "toglacier-archive-1.tar.gz",
+						"AWSID122": "toglacier-archive-2.tar.gz",
+					}, nil
+				},
+			},
+			archive: mockArchive{
+				mockExtract: func(filename string, filter []string) (archive.Info, error) {
+					sort.Strings(filter)
+
+					switch filename {
+					case "toglacier-archive-1.tar.gz":
+						if len(filter) != 2 || filter[0] != "file1" || filter[1] != "file3" {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+
+						return archive.Info{
+							"file1": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusNew,
+								ID:       "AWSID123",
+								Checksum: "a5b2df3d72bd28d2382b0b4cca4c25fa260e018b58a915f1e5af14485a746ca8",
+							},
+							"file2": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusUnmodified,
+								ID:       "AWSID122",
+								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
+							},
+						}, nil
+
+					case "toglacier-archive-2.tar.gz":
+						if len(filter) != 1 || filter[0] != "file2" {
+							return nil, fmt.Errorf("unexpected filter “%v”", filter)
+						}
+
+						return archive.Info{
+							"file2": archive.ItemInfo{
+								Status:   archive.ItemInfoStatusNew,
+								ID:       "AWSID122",
+								Checksum: "a8c23a9b1441de7f048471994f9500664acb0f6551e418e5b9da5af559606a63",
+							},
+						}, nil
+					}
+					return nil, nil
+				},
+			},
+			logger: mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			},
+			expectedError: errors.New("something went wrong"),
+		},
+		{
+			description: "it should refuse to extract the backup of a stream",
+			id:          "AWSID123",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "AWSID123",
+								VaultName: "test",
+								Stream:    "db.sql",
+							},
+							Info: archive.Info{
+								"db.sql": archive.ItemInfo{
+									ID:     "AWSID123",
+									Status: archive.ItemInfoStatusStream,
+								},
+							},
+						},
+					}, nil
+				},
+			},
+			expectedError: &toglacier.Error{
+				Paths: []string{"db.sql"},
+				Code:  toglacier.ErrorCodeStreamBackup,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage,
+				Envelop: scenario.envelop,
+				Cloud:   scenario.cloud,
+				Replica: scenario.replica,
+				Archive: scenario.archive,
+				Parity:  scenario.parity,
+				Logger:  scenario.logger,
+			}
+
+			err := toGlacier.RetrieveBackup(scenario.id, scenario.backupSecret, scenario.skipUnmodified, archive.OverwriteReplace)
+
+			if !archive.ErrorEqual(scenario.expectedError, err) && !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_RetrieveBackupReport(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarning:  func(args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	type retrieveResult struct {
+		Archives []string
+		Restored int
+		Skipped  int
+		Failed   int
+		Size     int64
+		Errors   int
+	}
+
+	scenarios := []struct {
+		description       string
+		overwrite         archive.OverwritePolicy
+		existing          []string
+		existingDirs      []string
+		getError          error
+		expected          retrieveResult
+		expectedError     error
+		expectedErrorCode archive.ErrorCode
+	}{
+		{
+			description: "it should report the restored files",
+			overwrite:   archive.OverwriteReplace,
+			existing:    []string{"file2.txt"},
+			expected: retrieveResult{
+				Archives: []string{"AWSID123"},
+				Restored: 2,
+				Size:     32,
+			},
+		},
+		{
+			description: "it should report the existing files kept by the overwrite policy",
+			overwrite:   archive.OverwriteSkip,
+			existing:    []string{"file2.txt"},
+			expected: retrieveResult{
+				Archives: []string{"AWSID123"},
+				Restored: 1,
+				Skipped:  1,
+				Size:     16,
+			},
+		},
+		{
+			description: "it should report the files that couldn't be retrieved",
+			overwrite:   archive.OverwriteReplace,
+			getError:    errors.New("connection error"),
+			expected: retrieveResult{
+				Failed: 2,
+				Errors: 1,
+			},
+			expectedError: errors.New("connection error"),
+		},
+		{
+			description:  "it should not count again the files reported before the extraction failed",
+			overwrite:    archive.OverwriteReplace,
+			existingDirs: []string{"file2.txt"},
+			expected: retrieveResult{
+				Restored: 1,
+				Failed:   1,
+				Size:     16,
+				Errors:   1,
+			},
+			// the error has the random name of the temporary file
+			expectedErrorCode: archive.ErrorCodeExtractingFile,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			srcDir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(srcDir)
+
+			for _, name := range []string{"file1.txt", "file2.txt"} {
+				if err = ioutil.WriteFile(path.Join(srcDir, name), []byte("content of "+name[:5]), 0600); err != nil {
+					t.Fatalf("error writing file. details: %s", err)
+				}
+			}
+
+			builder := archive.NewTARBuilder(logger)
+			builder.Clock = fakeClock{now: time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)}
+
+			filename, archiveInfo, err := builder.Build(context.Background(), nil, nil, srcDir)
+			if err != nil {
+				t.Fatalf("error building archive. details: %s", err)
+			}
+			defer os.Remove(filename)
+
+			for name, itemInfo := range archiveInfo {
+				itemInfo.ID = "AWSID123"
+				archiveInfo[name] = itemInfo
+			}
+
+			// the files are extracted in the current directory
+			dstDir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dstDir)
+
+			extractedDir := path.Join(dstDir, "backup-20170914103000", srcDir)
+			if err = os.MkdirAll(extractedDir, 0755); err != nil {
+				t.Fatalf("error creating directory. details: %s", err)
+			}
+
+			for _, name := range scenario.existing {
+				if err = ioutil.WriteFile(path.Join(extractedDir, name), []byte("old content"), 0600); err != nil {
+					t.Fatalf("error writing existing file. details: %s", err)
+				}
+			}
+
+			// a directory with content can't be replaced by the extracted file
+			for _, name := range scenario.existingDirs {
+				if err = os.MkdirAll(path.Join(extractedDir, name, "content"), 0755); err != nil {
+					t.Fatalf("error creating existing directory. details: %s", err)
+				}
+			}
+
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("error retrieving the current directory. details: %s", err)
+			}
+			defer os.Chdir(wd)
+
+			if err = os.Chdir(dstDir); err != nil {
+				t.Fatalf("error changing the current directory. details: %s", err)
+			}
+
+			reports := report.NewCollector()
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return storage.Backups{
+							{
+								Backup: cloud.Backup{ID: "AWSID123"},
+								Info:   archiveInfo,
+							},
+						}, nil
+					},
+					mockSave: func(b storage.Backup) error {
+						return nil
+					},
+					mockRestoreProgress: func(id string) (storage.RestoreProgress, error) {
+						return storage.RestoreProgress{}, nil
+					},
+					mockSaveRestoreProgress: func(id string, progress storage.RestoreProgress) error {
+						return nil
+					},
+					mockRemoveRestoreProgress: func(id string) error {
+						return nil
+					},
+				},
+				Cloud: mockCloud{
+					mockGet: func(ids ...string) (map[string]string, error) {
+						if scenario.getError != nil {
+							return nil, scenario.getError
+						}
+						return map[string]string{"AWSID123": filename}, nil
+					},
+				},
+				Archive: builder,
+				Logger:  logger,
+				Reports: reports,
+			}
+
+			err = toGlacier.RetrieveBackup("AWSID123", "", false, scenario.overwrite)
+			if scenario.expectedErrorCode != "" {
+				if archiveErr, ok := errors.Cause(err).(*archive.Error); !ok || archiveErr.Code != scenario.expectedErrorCode {
+					t.Errorf("errors don't match. expected code “%s” and got “%v”", scenario.expectedErrorCode, err)
+				}
+			} else if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			retrieveReports := reports.Extract(func(r report.Report) bool {
+				_, ok := r.(report.RetrieveBackup)
+				return ok
+			})
+
+			if len(retrieveReports) != 1 {
+				t.Fatalf("expected one backup retrieval report and got %d", len(retrieveReports))
+			}
+
+			retrieveReport := retrieveReports[0].(report.RetrieveBackup)
+			result := retrieveResult{
+				Archives: retrieveReport.Archives,
+				Restored: retrieveReport.Restored,
+				Skipped:  retrieveReport.Skipped,
+				Failed:   retrieveReport.Failed,
+				Size:     retrieveReport.Size,
+				Errors:   len(retrieveReport.Errors),
+			}
+
+			if !reflect.DeepEqual(scenario.expected, result) {
+				t.Errorf("reports don't match.\n%s", Diff(scenario.expected, result))
+			}
+		})
+	}
+}
+
+func TestToGlacier_RemoveBackups(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		ids           []string
+		cloud         cloud.Cloud
+		replica       cloud.Cloud
+		routes        []toglacier.Route
+		storage       storage.Storage
+		logger        log.Logger
+		expectedError error
+	}{
+		{
+			description: "it should remove a backup correctly (removing references)",
+			ids:         []string{"123456"},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "123457" {
+						return fmt.Errorf("saving unexpected backup id “%s”", b.Backup.ID)
+					}
+
+					if len(b.Info) > 0 {
+						return fmt.Errorf("unexpected number (%d) of items info", len(b.Info))
+					}
+
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: time.Now(),
+							},
+							Info: archive.Info{
+								"filename1": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusUnmodified,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: time.Now().Add(-10 * time.Minute),
+							},
+							Info: archive.Info{
+								"filename2": archive.ItemInfo{
+									ID:     "123454",
+									Status: archive.ItemInfoStatusUnmodified,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: time.Now().Add(-20 * time.Minute),
+							},
+							Info: archive.Info{
+								"filename2": archive.ItemInfo{
+									ID:     "123455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id != "123456" {
+						return fmt.Errorf("unexpected id “%s”", id)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should remove a backup from the cloud of its route",
+			ids:         []string{"223456"},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return fmt.Errorf("unexpected id “%s” in the default cloud", id)
+				},
+			},
+			routes: []toglacier.Route{
+				{
+					Prefix:    "/data/photos",
+					VaultName: "photos",
+					Cloud: mockCloud{
+						mockRemove: func(id string) error {
+							if id != "223456" && id != "223458" {
+								return fmt.Errorf("unexpected id “%s”", id)
+							}
+							return nil
+						},
+					},
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "223456",
+								CreatedAt: time.Now().Add(-10 * time.Minute),
+								VaultName: "photos",
+								ParityID:  "223458",
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id != "223456" {
+						return fmt.Errorf("unexpected id “%s”", id)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should remove a backup correctly with its parity data and catalog",
+			ids:         []string{"123456"},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					if id != "123456" && id != "123458" && id != "123459" {
+						return fmt.Errorf("unexpected id “%s”", id)
+					}
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: time.Now().Add(-10 * time.Minute),
+								ParityID:  "123458",
+								CatalogID: "123459",
+							},
+							Info: archive.Info{
+								"filename1": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id != "123456" {
+						return fmt.Errorf("unexpected id “%s”", id)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should remove a backup correctly with its copy in the replica",
+			ids:         []string{"123456"},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					if id != "123456" {
+						return fmt.Errorf("unexpected id “%s”", id)
+					}
+					return nil
+				},
+			},
+			replica: mockCloud{
+				mockRemove: func(id string) error {
+					if id != "223456" {
+						return fmt.Errorf("unexpected replica id “%s”", id)
+					}
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: time.Now().Add(-10 * time.Minute),
+								ReplicaID: "223456",
+							},
+							Info: archive.Info{
+								"filename1": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id != "123456" {
+						return fmt.Errorf("unexpected id “%s”", id)
+					}
+					return nil
+				},
+			},
+			logger: mockLogger{
+				mockWarningf: func(format string, args ...interface{}) {
+					t.Errorf(format, args...)
+				},
+			},
+		},
+		{
+			description: "it should remove a backup correctly (replacing references)",
+			ids:         []string{"123456"},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					if b.Backup.ID != "123457" {
+						return fmt.Errorf("saving unexpected backup id “%s”", b.Backup.ID)
+					}
+
+					if itemInfo, ok := b.Info["filename1"]; !ok || itemInfo.ID != "123455" {
+						return fmt.Errorf("unexpected archive information for backup 123457: %v", b.Info)
+					}
+
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: time.Now().Add(-10 * time.Minute),
+							},
+							Info: archive.Info{
+								"filename1": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusModified,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: time.Now(),
+							},
+							Info: archive.Info{
+								"filename1": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusUnmodified,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: time.Now().Add(-20 * time.Minute),
+							},
+							Info: archive.Info{
+								"filename1": archive.ItemInfo{
+									ID:     "123455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id != "123456" {
+						return fmt.Errorf("unexpected id “%s”", id)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should detect an error while removing the remote backup",
+			ids:         []string{"123456"},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return errors.New("error removing backup")
+				},
+			},
+			storage: mockStorage{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			expectedError: errors.New("error removing backup"),
+		},
+		{
+			description: "it should detect an error listing the backups",
+			ids:         []string{"123456"},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("failed to list backups")
+				},
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			expectedError: errors.New("failed to list backups"),
+		},
+		{
+			description: "it should detect an error saving the backup",
+			ids:         []string{"123456"},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					return errors.New("could not save the backup")
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: time.Now(),
+							},
+							Info: archive.Info{
+								"filename1": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusUnmodified,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: time.Now().Add(-10 * time.Minute),
+							},
+							Info: archive.Info{
+								"filename2": archive.ItemInfo{
+									ID:     "123454",
+									Status: archive.ItemInfoStatusUnmodified,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: time.Now().Add(-20 * time.Minute),
+							},
+							Info: archive.Info{
+								"filename2": archive.ItemInfo{
+									ID:     "123455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			expectedError: errors.New("could not save the backup"),
+		},
+		{
+			description: "it should detect an error while removing the local backup",
+			ids:         []string{"123456"},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockSave: func(b storage.Backup) error {
+					return nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: time.Now(),
+							},
+							Info: archive.Info{
+								"filename1": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusUnmodified,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: time.Now().Add(-10 * time.Minute),
+							},
+							Info: archive.Info{
+								"filename2": archive.ItemInfo{
+									ID:     "123454",
+									Status: archive.ItemInfoStatusUnmodified,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: time.Now().Add(-20 * time.Minute),
+							},
+							Info: archive.Info{
+								"filename2": archive.ItemInfo{
+									ID:     "123455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					return errors.New("error removing backup")
+				},
+			},
+			expectedError: errors.New("error removing backup"),
+		},
+		{
+			description: "it should remove many backups updating the local storage in a single transaction",
+			ids:         []string{"123456", "123457"},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			storage: mockBatchStorage{
+				mockStorage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						now := time.Now()
+
+						return storage.Backups{
+							{
+								Backup: cloud.Backup{ID: "123455", CreatedAt: now.Add(-30 * time.Minute)},
+								Info: archive.Info{
+									"filename1": archive.ItemInfo{ID: "123455", Status: archive.ItemInfoStatusNew},
+								},
+							},
+							{
+								Backup: cloud.Backup{ID: "123456", CreatedAt: now.Add(-20 * time.Minute)},
+								Info: archive.Info{
+									"filename1": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusModified},
+									"filename2": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusNew},
+								},
+							},
+							{
+								Backup: cloud.Backup{ID: "123457", CreatedAt: now.Add(-10 * time.Minute)},
+								Info: archive.Info{
+									"filename1": archive.ItemInfo{ID: "123457", Status: archive.ItemInfoStatusModified},
+									"filename2": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusUnmodified},
+								},
+							},
+							{
+								Backup: cloud.Backup{ID: "123458", CreatedAt: now},
+								Info: archive.Info{
+									"filename1": archive.ItemInfo{ID: "123457", Status: archive.ItemInfoStatusUnmodified},
+									"filename2": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusUnmodified},
+								},
+							},
+						}, nil
+					},
+				},
+				mockRemoveBatch: func(updated storage.Backups, ids []string) error {
+					if !reflect.DeepEqual([]string{"123456", "123457"}, ids) {
+						return fmt.Errorf("removing unexpected ids %v", ids)
+					}
+
+					if len(updated) != 1 || updated[0].Backup.ID != "123458" {
+						return fmt.Errorf("updating unexpected backups %v", updated)
+					}
+
+					expectedInfo := archive.Info{
+						"filename1": archive.ItemInfo{ID: "123455", Status: archive.ItemInfoStatusUnmodified},
+					}
+
+					if !reflect.DeepEqual(expectedInfo, updated[0].Info) {
+						return fmt.Errorf("unexpected items info %v", updated[0].Info)
+					}
+
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should remove from the local storage the backups already removed from the cloud",
+			ids:         []string{"123456", "123457"},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					if id == "123457" {
+						return errors.New("error removing backup")
+					}
+					return nil
+				},
+			},
+			storage: mockBatchStorage{
+				mockStorage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						now := time.Now()
+
+						return storage.Backups{
+							{
+								Backup: cloud.Backup{ID: "123455", CreatedAt: now.Add(-30 * time.Minute)},
+								Info: archive.Info{
+									"filename1": archive.ItemInfo{ID: "123455", Status: archive.ItemInfoStatusNew},
+								},
+							},
+							{
+								Backup: cloud.Backup{ID: "123456", CreatedAt: now.Add(-20 * time.Minute)},
+								Info: archive.Info{
+									"filename1": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusModified},
+									"filename2": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusNew},
+								},
+							},
+							{
+								Backup: cloud.Backup{ID: "123457", CreatedAt: now.Add(-10 * time.Minute)},
+								Info: archive.Info{
+									"filename1": archive.ItemInfo{ID: "123457", Status: archive.ItemInfoStatusModified},
+									"filename2": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusUnmodified},
+								},
+							},
+							{
+								Backup: cloud.Backup{ID: "123458", CreatedAt: now},
+								Info: archive.Info{
+									"filename1": archive.ItemInfo{ID: "123457", Status: archive.ItemInfoStatusUnmodified},
+									"filename2": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusUnmodified},
+								},
+							},
+						}, nil
+					},
+				},
+				mockRemoveBatch: func(updated storage.Backups, ids []string) error {
+					if !reflect.DeepEqual([]string{"123456"}, ids) {
+						return fmt.Errorf("removing unexpected ids %v", ids)
+					}
+					return nil
+				},
+			},
+			expectedError: errors.New("error removing backup"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud,
+				Replica: scenario.replica,
+				Storage: scenario.storage,
+				Routes:  scenario.routes,
+				Logger:  scenario.logger,
+			}
+
+			if err := toGlacier.RemoveBackups(scenario.ids...); !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_RemoveOldBackups(t *testing.T) {
+	now := time.Now()
+
+	scenarios := []struct {
+		description       string
+		keepBackups       int
+		removeConcurrency int
+		groupByDay        bool
+		cloud             cloud.Cloud
+		routes            []toglacier.Route
+		storage           storage.Storage
+		journal           storage.Journal
+		trash             storage.Trash
+		expectedError     error
+	}{
+		{
+			description: "it should remove all old backups correctly",
+			keepBackups: 2,
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					if id != "123456" {
+						return fmt.Errorf("unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return nil, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: now,
+								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: now.Add(time.Second),
+								Checksum:  "0484ed70359cd1a4337d16a4143a3d247e0a3ecbce01482c318d709ed5161016",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "123459",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "4c6733f2d51c5cde947835279ce9f031bcacaa2265988ef1353078810695fb20",
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123458",
+								CreatedAt: now.Add(time.Minute),
+								Checksum:  "5f9c426fb1e150c1c09dda260bb962c7602b595df7586a1f3899735b839b138f",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123458",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123459",
+								CreatedAt: now.Add(-time.Hour),
+								Checksum:  "9a16f6eaebe1a7a3c9e456c5a37063d712de11d839040e5963cf864feb16e114",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123459",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id != "123456" {
+						return fmt.Errorf("removing unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should keep the old backups of each route",
+			keepBackups: 1,
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					if id != "123455" {
+						return fmt.Errorf("unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+			routes: []toglacier.Route{
+				{
+					Prefix:    "/data/photos",
+					VaultName: "photos",
+					Cloud: mockCloud{
+						mockRemove: func(id string) error {
+							if id != "223455" {
+								return fmt.Errorf("unexpected id %s", id)
+							}
+							return nil
+						},
+					},
+				},
+			},
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return nil, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: now.Add(-time.Hour),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: now,
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "223455",
+								CreatedAt: now.Add(-2 * time.Hour),
+								VaultName: "photos",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "223455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "223456",
+								CreatedAt: now.Add(time.Minute),
+								VaultName: "photos",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "223456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id != "123455" && id != "223455" {
+						return fmt.Errorf("removing unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should preserve the archives referenced by the backups of other routes",
+			keepBackups: 1,
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return fmt.Errorf("unexpected id %s", id)
+				},
+			},
+			routes: []toglacier.Route{
+				{
+					Prefix:    "/data/photos",
+					VaultName: "photos",
+					Cloud: mockCloud{
+						mockRemove: func(id string) error {
+							if id != "223455" {
+								return fmt.Errorf("unexpected id %s", id)
+							}
+							return nil
+						},
+					},
+				},
+			},
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return nil, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: now.Add(-time.Hour),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: now,
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "223455",
+								CreatedAt: now.Add(-2 * time.Hour),
+								VaultName: "photos",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "223455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "223456",
+								CreatedAt: now.Add(time.Minute),
+								VaultName: "photos",
+							},
+							Info: archive.Info{
+								"/data/photos/file1": archive.ItemInfo{
+									ID:       "123455",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "4c6733f2d51c5cde947835279ce9f031bcacaa2265988ef1353078810695fb20",
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id != "223455" {
+						return fmt.Errorf("removing unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should preserve the archives referenced by the backups in the recovery journal",
+			keepBackups: 1,
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return fmt.Errorf("unexpected id %s", id)
+				},
+			},
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return nil, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: now.Add(-time.Hour),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: now,
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					return fmt.Errorf("removing unexpected id %s", id)
+				},
+			},
+			journal: mockJournal{
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: now.Add(time.Minute),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "123455",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "4c6733f2d51c5cde947835279ce9f031bcacaa2265988ef1353078810695fb20",
+								},
+							},
+						},
+					}, nil
+				},
+			},
+		},
+		{
+			description: "it should preserve the archives referenced by the backups in the trash",
+			keepBackups: 1,
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return fmt.Errorf("unexpected id %s", id)
+				},
+			},
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return nil, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: now.Add(-time.Hour),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:     "123455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: now,
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file2": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: now.Add(time.Minute),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:       "123455",
+									Status:   archive.ItemInfoStatusUnmodified,
+									Checksum: "4c6733f2d51c5cde947835279ce9f031bcacaa2265988ef1353078810695fb20",
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					return fmt.Errorf("removing unexpected id %s", id)
+				},
+			},
+			trash: mockTrash{
+				mockList: func() ([]storage.TrashedBackup, error) {
+					return []storage.TrashedBackup{
+						{ID: "123457", TrashedAt: now},
+					}, nil
+				},
+			},
+		},
+		{
+			description: "it should detect when there's an error listing the recovery journal",
+			keepBackups: 1,
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return nil, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: now,
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+			},
+			journal: mockJournal{
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("journal corrupted")
+				},
+			},
+			expectedError: errors.New("journal corrupted"),
+		},
+		{
+			description: "it should detect when there's an error listing the local backups",
+			keepBackups: 2,
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return nil, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("local storage corrupted")
+				},
+			},
+			expectedError: errors.New("local storage corrupted"),
+		},
+		{
+			description: "it should detect when there is an error removing an old backup from the cloud",
+			keepBackups: 2,
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return errors.New("backup not found")
+				},
+			},
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return nil, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: now,
+								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: now.Add(time.Second),
+								Checksum:  "0484ed70359cd1a4337d16a4143a3d247e0a3ecbce01482c318d709ed5161016",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123457",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123458",
+								CreatedAt: now.Add(time.Minute),
+								Checksum:  "5f9c426fb1e150c1c09dda260bb962c7602b595df7586a1f3899735b839b138f",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123458",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id != "123456" {
+						return fmt.Errorf("removing unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeRemovingBackups,
+				Err:  errors.New("1 of 1 backups not removed: backup “123456”: backup not found"),
+			},
+		},
+		{
+			description: "it should detect when there is an error removing an old backup from the local storage",
+			keepBackups: 2,
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					if id != "123456" {
+						return fmt.Errorf("unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return nil, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: now,
+								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: now.Add(time.Second),
+								Checksum:  "0484ed70359cd1a4337d16a4143a3d247e0a3ecbce01482c318d709ed5161016",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123457",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123458",
+								CreatedAt: now.Add(time.Minute),
+								Checksum:  "5f9c426fb1e150c1c09dda260bb962c7602b595df7586a1f3899735b839b138f",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123458",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					return errors.New("backup not found")
+				},
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeRemovingBackups,
+				Err:  errors.New("1 of 1 backups not removed: backup “123456”: backup not found"),
+			},
+		},
+		{
+			description:       "it should keep removing the old backups concurrently after a failure",
+			keepBackups:       1,
+			removeConcurrency: 2,
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					if id == "123457" {
+						return errors.New("backup not found")
+					}
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return nil, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: now,
+								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: now.Add(time.Second),
+								Checksum:  "0484ed70359cd1a4337d16a4143a3d247e0a3ecbce01482c318d709ed5161016",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123457",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123458",
+								CreatedAt: now.Add(time.Minute),
+								Checksum:  "5f9c426fb1e150c1c09dda260bb962c7602b595df7586a1f3899735b839b138f",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123458",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123459",
+								CreatedAt: now.Add(time.Hour),
+								Checksum:  "9a4d6ab0e8b8d0b0fd6f3cd6b3a7c1d6c0e8f6b4b0c8c1d9e1a5b3c6d7e8f9a0",
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123459",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id == "123457" || id == "123459" {
+						t.Errorf("removing unexpected id %s from the local storage", id)
+					}
+					return nil
+				},
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeRemovingBackups,
+				Err:  errors.New("1 of 3 backups not removed: backup “123457”: backup not found"),
+			},
+		},
+		{
+			description: "it should keep the pinned backups without counting them",
+			keepBackups: 1,
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					if id != "123457" {
+						return fmt.Errorf("unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return []storage.Pin{{ID: "123456"}}, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: now.Add(-time.Hour),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file1": archive.ItemInfo{
+									ID:     "123455",
+									Status: archive.ItemInfoStatusUnmodified,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: now.Add(-2 * time.Hour),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: now.Add(-time.Minute),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123457",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123458",
+								CreatedAt: now,
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123458",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id != "123457" {
+						return fmt.Errorf("removing unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should remove a backup with an expired hold",
+			keepBackups: 1,
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					if id != "123456" {
+						return fmt.Errorf("unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return []storage.Pin{{ID: "123456", Until: now.Add(-time.Minute)}}, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123456",
+								CreatedAt: now.Add(-time.Hour),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123456",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123457",
+								CreatedAt: now,
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123457",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id != "123456" {
+						return fmt.Errorf("removing unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should keep all backups of the same day in a single place",
+			keepBackups: 2,
+			groupByDay:  true,
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					if id != "123451" && id != "123452" {
+						return fmt.Errorf("unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+			storage: mockStorage{
+				mockPins: func() ([]storage.Pin, error) {
+					return nil, nil
+				},
+				mockList: func() (storage.Backups, error) {
+					return storage.Backups{
+						{
+							Backup: cloud.Backup{
+								ID:        "123451",
+								CreatedAt: time.Date(2017, 9, 12, 10, 0, 0, 0, time.UTC),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123451",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123452",
+								CreatedAt: time.Date(2017, 9, 12, 15, 0, 0, 0, time.UTC),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123452",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123453",
+								CreatedAt: time.Date(2017, 9, 13, 9, 0, 0, 0, time.UTC),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123453",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123454",
+								CreatedAt: time.Date(2017, 9, 14, 8, 0, 0, 0, time.UTC),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123454",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+						{
+							Backup: cloud.Backup{
+								ID:        "123455",
+								CreatedAt: time.Date(2017, 9, 14, 20, 0, 0, 0, time.UTC),
+								VaultName: "test",
+							},
+							Info: archive.Info{
+								"file": archive.ItemInfo{
+									ID:     "123455",
+									Status: archive.ItemInfoStatusNew,
+								},
+							},
+						},
+					}, nil
+				},
+				mockRemove: func(id string) error {
+					if id != "123451" && id != "123452" {
+						return fmt.Errorf("removing unexpected id %s", id)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should detect an error while listing the pinned backups",
+			keepBackups: 1,
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, nil
+				},
+				mockPins: func() ([]storage.Pin, error) {
+					return nil, errors.New("error listing pins")
+				},
+			},
+			expectedError: errors.New("error listing pins"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context:           context.Background(),
+				Cloud:             scenario.cloud,
+				Storage:           scenario.storage,
+				Journal:           scenario.journal,
+				Trash:             scenario.trash,
+				Routes:            scenario.routes,
+				RemoveConcurrency: scenario.removeConcurrency,
+				GroupByDay:        scenario.groupByDay,
+				Location:          time.UTC,
+			}
+
+			if err := toGlacier.RemoveOldBackups(scenario.keepBackups, ""); !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_RemoveOldBackupsAuditFile(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	backups := storage.Backups{
+		{
+			Backup: cloud.Backup{ID: "123456", CreatedAt: now, VaultName: "test", Location: cloud.LocationAWS},
+			Info: archive.Info{
+				"file1": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusNew},
+			},
+		},
+		{
+			Backup: cloud.Backup{ID: "123457", CreatedAt: now.Add(time.Second), VaultName: "test", Location: cloud.LocationAWS},
+			Info: archive.Info{
+				"file2": archive.ItemInfo{ID: "123457", Status: archive.ItemInfoStatusNew},
+			},
+		},
+		{
+			Backup: cloud.Backup{ID: "123458", CreatedAt: now.Add(time.Minute), VaultName: "test", Location: cloud.LocationAWS, CatalogID: "CATALOG123458"},
+			Info: archive.Info{
+				"file1": archive.ItemInfo{ID: "123456", Status: archive.ItemInfoStatusUnmodified},
+				"file3": archive.ItemInfo{ID: "123458", Status: archive.ItemInfoStatusNew},
+			},
+		},
+	}
+
+	scenarios := []struct {
+		description     string
+		legacy          bool
+		get             func(ids ...string) (map[string]string, error)
+		expectedRemoved []string
+		expectedError   error
+	}{
+		{
+			description:     "it should keep the old backups referenced by the incremental backup",
+			expectedRemoved: []string{"123457"},
+		},
+		{
+			description: "it should retrieve the unknown references from the catalog of the kept backup",
+			legacy:      true,
+			get: func(ids ...string) (map[string]string, error) {
+				if len(ids) != 1 || ids[0] != "CATALOG123458" {
+					return nil, fmt.Errorf("unexpected archives “%v”", ids)
+				}
+
+				return map[string]string{
+					"CATALOG123458": writeCatalogTestFile(t, `{"backup":{"Backup":{"ID":"123458"},"Info":{"file1":{"ID":"123456","Status":"unmodified"},"file3":{"ID":"123458","Status":"new"}}}}`),
+				}, nil
+			},
+			expectedRemoved: []string{"123457"},
+		},
+		{
+			description: "it should refuse to remove old backups when the references can't be retrieved",
+			legacy:      true,
+			get: func(ids ...string) (map[string]string, error) {
+				return nil, errors.New("connection error")
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeUnknownReferences,
+				Err:  errors.New("backups without archive information: 123458"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "toglacier-test")
+			if err != nil {
+				t.Fatalf("error creating temporary directory. details: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			logger := mockLogger{
+				mockDebug:    func(args ...interface{}) {},
+				mockDebugf:   func(format string, args ...interface{}) {},
+				mockInfo:     func(args ...interface{}) {},
+				mockInfof:    func(format string, args ...interface{}) {},
+				mockWarning:  func(args ...interface{}) {},
+				mockWarningf: func(format string, args ...interface{}) {},
+			}
+
+			auditFile := storage.NewAuditFile(logger, path.Join(dir, "audit.log"))
+			for _, backup := range backups {
+				if err := auditFile.Save(context.Background(), backup); err != nil {
+					t.Fatalf("error saving backup. details: %s", err)
+				}
+			}
+
+			if scenario.legacy {
+				// older versions of the audit file didn't store the archive
+				// information
+				if err := os.RemoveAll(path.Join(dir, "audit.log.info")); err != nil {
+					t.Fatalf("error removing the archive information. details: %s", err)
+				}
+			}
+
+			var removed []string
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud: mockCloud{
+					mockGet: scenario.get,
+					mockRemove: func(id string) error {
+						removed = append(removed, id)
+						return nil
+					},
+				},
+				Storage: auditFile,
+				Logger:  logger,
+			}
+
+			if err := toGlacier.RemoveOldBackups(1, ""); !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedRemoved, removed) {
+				t.Errorf("removed backups don't match. expected “%v” and got “%v”", scenario.expectedRemoved, removed)
+			}
+		})
+	}
+}
+
+func TestToGlacier_CompactStorage(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		storage       storage.Storage
+		expected      int
+		expectedError error
+	}{
+		{
+			description: "it should compact the local storage",
+			storage: mockCompactorStorage{
+				mockCompact: func() (int, error) {
+					return 3, nil
+				},
+			},
+			expected: 3,
+		},
+		{
+			description: "it should ignore a local storage that doesn't need compaction",
+			storage:     mockStorage{},
+		},
+		{
+			description: "it should detect an error compacting the local storage",
+			storage: mockCompactorStorage{
+				mockCompact: func() (int, error) {
+					return 0, errors.New("error compacting")
+				},
+			},
+			expectedError: errors.New("error compacting"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Storage: scenario.storage,
+			}
+
+			dropped, err := toGlacier.CompactStorage()
+			if dropped != scenario.expected {
+				t.Errorf("dropped records don't match. expected “%d” and got “%d”", scenario.expected, dropped)
+			}
+
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_SendReport(t *testing.T) {
+	date := time.Date(2017, 3, 10, 14, 10, 46, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		reports       []report.Report
+		machineID     string
+		routes        []toglacier.Route
+		storage       storage.Storage
+		emailSender   toglacier.EmailSender
+		emailServer   string
+		emailPort     int
+		emailUsername string
+		emailPassword string
+		emailFrom     string
+		emailTo       []string
+		format        report.Format
+		expectedError error
+	}{
+		{
+			description: "it should send an e-mail correctly",
+			reports: []report.Report{
+				func() report.Report {
+					r := report.NewTest()
+					r.CreatedAt = date
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					return r
+				}(),
+			},
+			emailSender: toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+				if addr != "127.0.0.1:587" {
+					return fmt.Errorf("unexpected “address” %s", addr)
+				}
+
+				if from != "test@example.com" {
+					return fmt.Errorf("unexpected “from” %s", from)
+				}
+
+				if !reflect.DeepEqual(to, []string{"user@example.com"}) {
+					return fmt.Errorf("unexpected “to” %v", to)
+				}
+
+				expectedMsg := `From: test@example.com
+To: user@example.com
+Subject: toglacier report
+MIME-Version: 1.0
+Content-Type: text/plain; charset=utf-8
+
+
+[2017-03-10 14:10:46] Test report
+
+  Testing the notification mechanisms.
+
+  Errors
+  ------
+
+    * timeout connecting to aws
+
+`
+
+				msgLines := strings.Split(string(msg), "\n")
+				for i := range msgLines {
+					msgLines[i] = strings.TrimSpace(msgLines[i])
+				}
+
+				expectedLines := strings.Split(expectedMsg, "\n")
+				for i := range expectedLines {
+					expectedLines[i] = strings.TrimSpace(expectedLines[i])
+				}
+
+				if !reflect.DeepEqual(expectedLines, msgLines) {
+					return fmt.Errorf("unexpected message\n%v", Diff(expectedLines, msgLines))
+				}
+
+				return nil
+			}),
+			emailServer:   "127.0.0.1",
+			emailPort:     587,
+			emailUsername: "user",
+			emailPassword: "abc123",
+			emailFrom:     "test@example.com",
+			emailTo: []string{
+				"user@example.com",
+			},
+			format: report.FormatPlain,
+		},
+		{
+			description: "it should identify the machine in the e-mail subject",
+			reports: []report.Report{
+				func() report.Report {
+					r := report.NewTest()
+					r.CreatedAt = date
+					return r
+				}(),
+			},
+			machineID: "server1",
+			emailSender: toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+				if !strings.Contains(string(msg), "\nSubject: toglacier report (server1)\n") {
+					return fmt.Errorf("unexpected message\n%s", string(msg))
+				}
+
+				return nil
+			}),
+			emailServer: "127.0.0.1",
+			emailPort:   587,
+			emailFrom:   "test@example.com",
+			emailTo: []string{
+				"user@example.com",
+			},
+			format: report.FormatPlain,
+		},
+		{
+			description: "it should send the reports of a route to its own recipients",
+			reports: []report.Report{
+				func() report.Report {
+					r := report.NewSendBackup()
+					r.CreatedAt = date
+					r.Route = "photos"
+					r.Backup = cloud.Backup{ID: "AWSID123", VaultName: "photos"}
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewTest()
+					r.CreatedAt = date
+					return r
+				}(),
+			},
+			routes: []toglacier.Route{
+				{Prefix: "/data/documents", VaultName: "documents"},
+				{
+					Prefix:    "/data/photos",
+					VaultName: "photos",
+					Notification: &toglacier.Notification{
+						To: []string{"family@example.com"},
+					},
+				},
+			},
+			emailSender: toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+				switch {
+				case reflect.DeepEqual(to, []string{"family@example.com"}):
+					if !strings.Contains(string(msg), "\nSubject: toglacier report [/data/photos]\n") || !strings.Contains(string(msg), "AWSID123") {
+						return fmt.Errorf("unexpected route message\n%s", string(msg))
+					}
+				case reflect.DeepEqual(to, []string{"user@example.com"}):
+					if strings.Contains(string(msg), "AWSID123") {
+						return fmt.Errorf("unexpected route report in message\n%s", string(msg))
+					}
+				default:
+					return fmt.Errorf("unexpected “to” %v", to)
+				}
+
+				return nil
+			}),
+			emailServer: "127.0.0.1",
+			emailPort:   587,
+			emailFrom:   "test@example.com",
+			emailTo: []string{
+				"user@example.com",
+			},
+			format: report.FormatPlain,
+		},
+		{
+			description: "it should discard the successful reports of a route that only notifies errors",
+			reports: []report.Report{
+				func() report.Report {
+					r := report.NewSendBackup()
+					r.CreatedAt = date
+					r.Route = "photos"
+					r.Backup = cloud.Backup{ID: "AWSID123", VaultName: "photos"}
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewTest()
+					r.CreatedAt = date
+					return r
+				}(),
+			},
+			routes: []toglacier.Route{
+				{
+					Prefix:    "/data/photos",
+					VaultName: "photos",
+					Notification: &toglacier.Notification{
+						To:         []string{"family@example.com"},
+						ErrorsOnly: true,
+					},
+				},
+			},
+			emailSender: toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+				if !reflect.DeepEqual(to, []string{"user@example.com"}) {
+					return fmt.Errorf("unexpected “to” %v", to)
+				}
+
+				if strings.Contains(string(msg), "AWSID123") {
+					return fmt.Errorf("unexpected route report in message\n%s", string(msg))
+				}
+
+				return nil
+			}),
+			emailServer: "127.0.0.1",
+			emailPort:   587,
+			emailFrom:   "test@example.com",
+			emailTo: []string{
+				"user@example.com",
+			},
+			format: report.FormatPlain,
+		},
+		{
+			description: "it should send the reports of a route with the other ones when they can't be sent",
+			reports: []report.Report{
+				func() report.Report {
+					r := report.NewSendBackup()
+					r.CreatedAt = date
+					r.Route = "photos"
+					r.Backup = cloud.Backup{ID: "AWSID123", VaultName: "photos"}
+					return r
+				}(),
+				func() report.Report {
+					r := report.NewTest()
+					r.CreatedAt = date
+					return r
+				}(),
+			},
+			routes: []toglacier.Route{
+				{
+					Prefix:    "/data/photos",
+					VaultName: "photos",
+					Notification: &toglacier.Notification{
+						To: []string{"family@example.com"},
+					},
+				},
+			},
+			emailSender: toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+				if reflect.DeepEqual(to, []string{"family@example.com"}) {
+					return errors.New("mailbox unavailable")
+				}
+
+				if !strings.Contains(string(msg), "AWSID123") {
+					return fmt.Errorf("missing route report in message\n%s", string(msg))
+				}
+
+				return nil
+			}),
+			emailServer: "127.0.0.1",
+			emailPort:   587,
+			emailFrom:   "test@example.com",
+			emailTo: []string{
+				"user@example.com",
+			},
+			format: report.FormatPlain,
 		},
 		{
-			description: "it should detect when there is an error removing an old backup from the local storage",
-			keepBackups: 2,
-			cloud: mockCloud{
-				mockRemove: func(id string) error {
-					if id != "123456" {
-						return fmt.Errorf("unexpected id %s", id)
-					}
-					return nil
+			description: "it should fail to build the reports",
+			reports: []report.Report{
+				mockReport{
+					mockBuild: func(report.Format) (string, error) {
+						return "", errors.New("error generating report")
+					},
 				},
 			},
+			emailServer:   "127.0.0.1",
+			emailPort:     587,
+			emailUsername: "user",
+			emailPassword: "abc123",
+			emailFrom:     "test@example.com",
+			emailTo: []string{
+				"user@example.com",
+			},
+			format:        report.FormatPlain,
+			expectedError: errors.New("error generating report"),
+		},
+		{
+			description: "it should chart the backups history in HTML reports",
 			storage: mockStorage{
 				mockList: func() (storage.Backups, error) {
 					return storage.Backups{
-						{
-							Backup: cloud.Backup{
-								ID:        "123456",
-								CreatedAt: now,
-								Checksum:  "ca34f069795292e834af7ea8766e9e68fdddf3f46c7ce92ab94fc2174910adb7",
-								VaultName: "test",
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123457",
-								CreatedAt: now.Add(time.Second),
-								Checksum:  "0484ed70359cd1a4337d16a4143a3d247e0a3ecbce01482c318d709ed5161016",
-								VaultName: "test",
-							},
-						},
-						{
-							Backup: cloud.Backup{
-								ID:        "123458",
-								CreatedAt: now.Add(time.Minute),
-								Checksum:  "5f9c426fb1e150c1c09dda260bb962c7602b595df7586a1f3899735b839b138f",
-								VaultName: "test",
-							},
-						},
+						{Backup: cloud.Backup{ID: "AWSID124", CreatedAt: date, Size: 2097152, Duration: 5 * time.Minute}},
+						{Backup: cloud.Backup{ID: "AWSID123", CreatedAt: date.Add(-24 * time.Hour), Size: 1048576, Duration: 2 * time.Minute}},
 					}, nil
 				},
-				mockRemove: func(id string) error {
-					return errors.New("backup not found")
+			},
+			emailSender: toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+				if !strings.Contains(string(msg), "<h1>Backups History</h1>") {
+					return fmt.Errorf("missing history report in message\n%s", string(msg))
+				}
+
+				older := strings.Index(string(msg), "<title>2017-03-09 14:10:46: 1.0 MB</title>")
+				newer := strings.Index(string(msg), "<title>2017-03-10 14:10:46: 2.0 MB</title>")
+				if older == -1 || newer == -1 || older > newer {
+					return fmt.Errorf("backups history not charted from the oldest to the newest\n%s", string(msg))
+				}
+
+				return nil
+			}),
+			emailServer: "127.0.0.1",
+			emailPort:   587,
+			emailFrom:   "test@example.com",
+			emailTo: []string{
+				"user@example.com",
+			},
+			format: report.FormatHTML,
+		},
+		{
+			description: "it should report an error while listing the backups history",
+			storage: mockStorage{
+				mockList: func() (storage.Backups, error) {
+					return nil, errors.New("file corrupted")
 				},
 			},
-			expectedError: errors.New("backup not found"),
+			emailSender: toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+				if !strings.Contains(string(msg), "<li>file corrupted</li>") {
+					return fmt.Errorf("missing history error in message\n%s", string(msg))
+				}
+
+				return nil
+			}),
+			emailServer: "127.0.0.1",
+			emailPort:   587,
+			emailFrom:   "test@example.com",
+			emailTo: []string{
+				"user@example.com",
+			},
+			format: report.FormatHTML,
+		},
+		{
+			description: "it should detect an error while sending the e-mail",
+			emailSender: toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+				return errors.New("generic error while sending e-mail")
+			}),
+			emailServer:   "127.0.0.1",
+			emailPort:     587,
+			emailUsername: "user",
+			emailPassword: "abc123",
+			emailFrom:     "test@example.com",
+			emailTo: []string{
+				"user@example.com",
+			},
+			format:        report.FormatPlain,
+			expectedError: errors.New("generic error while sending e-mail"),
 		},
 	}
 
 	for _, scenario := range scenarios {
+		report.Clear()
+
 		t.Run(scenario.description, func(t *testing.T) {
 			toGlacier := toglacier.ToGlacier{
-				Context: context.Background(),
-				Cloud:   scenario.cloud,
-				Storage: scenario.storage,
+				MachineID: scenario.machineID,
+				Routes:    scenario.routes,
+				Storage:   scenario.storage,
+				Logger: mockLogger{
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			for _, r := range scenario.reports {
+				report.Add(r)
+			}
+
+			emailInfo := toglacier.EmailInfo{
+				Sender:   scenario.emailSender,
+				Server:   scenario.emailServer,
+				Port:     scenario.emailPort,
+				Username: scenario.emailUsername,
+				Password: scenario.emailPassword,
+				From:     scenario.emailFrom,
+				To:       scenario.emailTo,
+				Format:   scenario.format,
 			}
 
-			if err := toGlacier.RemoveOldBackups(scenario.keepBackups); !ErrorEqual(scenario.expectedError, err) {
+			if err := toGlacier.SendReport(emailInfo); !ErrorEqual(scenario.expectedError, err) {
 				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
 			}
 		})
 	}
 }
 
-func TestToGlacier_SendReport(t *testing.T) {
+func TestToGlacier_SendAlert(t *testing.T) {
 	date := time.Date(2017, 3, 10, 14, 10, 46, 0, time.UTC)
 
 	scenarios := []struct {
@@ -2596,46 +6698,35 @@ func TestToGlacier_SendReport(t *testing.T) {
 		emailSender   toglacier.EmailSender
 		emailServer   string
 		emailPort     int
-		emailUsername string
-		emailPassword string
 		emailFrom     string
 		emailTo       []string
 		format        report.Format
+		messengers    []toglacier.Messenger
 		expectedError error
 	}{
 		{
-			description: "it should send an e-mail correctly",
+			description: "it should send an alert e-mail correctly",
 			reports: []report.Report{
 				func() report.Report {
-					r := report.NewTest()
+					r := report.NewEscalation("backup", 3)
 					r.CreatedAt = date
 					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
 					return r
 				}(),
 			},
 			emailSender: toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
-				if addr != "127.0.0.1:587" {
-					return fmt.Errorf("unexpected “address” %s", addr)
-				}
-
-				if from != "test@example.com" {
-					return fmt.Errorf("unexpected “from” %s", from)
-				}
-
-				if !reflect.DeepEqual(to, []string{"user@example.com"}) {
-					return fmt.Errorf("unexpected “to” %v", to)
-				}
-
 				expectedMsg := `From: test@example.com
 To: user@example.com
-Subject: toglacier report
+Subject: toglacier alert
+X-Priority: 1 (Highest)
+Importance: high
 MIME-Version: 1.0
 Content-Type: text/plain; charset=utf-8
 
 
-[2017-03-10 14:10:46] Test report
+[2017-03-10 14:10:46] Escalation
 
-  Testing the notification mechanisms.
+  Action “backup” failed 3 consecutive times.
 
   Errors
   ------
@@ -2660,18 +6751,78 @@ Content-Type: text/plain; charset=utf-8
 
 				return nil
 			}),
-			emailServer:   "127.0.0.1",
-			emailPort:     587,
-			emailUsername: "user",
-			emailPassword: "abc123",
-			emailFrom:     "test@example.com",
+			emailServer: "127.0.0.1",
+			emailPort:   587,
+			emailFrom:   "test@example.com",
 			emailTo: []string{
 				"user@example.com",
 			},
 			format: report.FormatPlain,
 		},
 		{
-			description: "it should fail to build the reports",
+			description: "it should send an alert to the messengers without e-mail recipients",
+			reports: []report.Report{
+				func() report.Report {
+					r := report.NewEscalation("backup", 3)
+					r.CreatedAt = date
+					r.Errors = append(r.Errors, errors.New("timeout connecting to aws"))
+					return r
+				}(),
+			},
+			emailSender: toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+				return errors.New("e-mail shouldn't be sent")
+			}),
+			format: report.FormatHTML,
+			messengers: []toglacier.Messenger{
+				mockMessenger{
+					mockSendMessage: func(message string) error {
+						expected := `toglacier alert
+
+[2017-03-10 14:10:46] Escalation
+
+  Action “backup” failed 3 consecutive times.
+
+  Errors
+  ------
+
+    * timeout connecting to aws
+`
+
+						if message != expected {
+							return fmt.Errorf("unexpected message\n%v", Diff(expected, message))
+						}
+
+						return nil
+					},
+				},
+			},
+		},
+		{
+			description: "it should send the alert e-mail when a messenger fails",
+			reports: []report.Report{
+				report.NewEscalation("backup", 3),
+			},
+			emailSender: toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+				return errors.New("generic error while sending e-mail")
+			}),
+			emailServer: "127.0.0.1",
+			emailPort:   587,
+			emailFrom:   "test@example.com",
+			emailTo: []string{
+				"user@example.com",
+			},
+			format: report.FormatPlain,
+			messengers: []toglacier.Messenger{
+				mockMessenger{
+					mockSendMessage: func(message string) error {
+						return errors.New("bot blocked by the user")
+					},
+				},
+			},
+			expectedError: errors.New("generic error while sending e-mail"),
+		},
+		{
+			description: "it should fail to build the alert",
 			reports: []report.Report{
 				mockReport{
 					mockBuild: func(report.Format) (string, error) {
@@ -2679,11 +6830,9 @@ Content-Type: text/plain; charset=utf-8
 					},
 				},
 			},
-			emailServer:   "127.0.0.1",
-			emailPort:     587,
-			emailUsername: "user",
-			emailPassword: "abc123",
-			emailFrom:     "test@example.com",
+			emailServer: "127.0.0.1",
+			emailPort:   587,
+			emailFrom:   "test@example.com",
 			emailTo: []string{
 				"user@example.com",
 			},
@@ -2691,15 +6840,13 @@ Content-Type: text/plain; charset=utf-8
 			expectedError: errors.New("error generating report"),
 		},
 		{
-			description: "it should detect an error while sending the e-mail",
+			description: "it should detect an error while sending the alert e-mail",
 			emailSender: toglacier.EmailSenderFunc(func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
 				return errors.New("generic error while sending e-mail")
 			}),
-			emailServer:   "127.0.0.1",
-			emailPort:     587,
-			emailUsername: "user",
-			emailPassword: "abc123",
-			emailFrom:     "test@example.com",
+			emailServer: "127.0.0.1",
+			emailPort:   587,
+			emailFrom:   "test@example.com",
 			emailTo: []string{
 				"user@example.com",
 			},
@@ -2709,44 +6856,50 @@ Content-Type: text/plain; charset=utf-8
 	}
 
 	for _, scenario := range scenarios {
-		report.Clear()
-
 		t.Run(scenario.description, func(t *testing.T) {
-			toGlacier := toglacier.ToGlacier{}
-
-			for _, r := range scenario.reports {
-				report.Add(r)
+			toGlacier := toglacier.ToGlacier{
+				Context:    context.Background(),
+				Messengers: scenario.messengers,
+				Logger: mockLogger{
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
 			}
 
 			emailInfo := toglacier.EmailInfo{
-				Sender:   scenario.emailSender,
-				Server:   scenario.emailServer,
-				Port:     scenario.emailPort,
-				Username: scenario.emailUsername,
-				Password: scenario.emailPassword,
-				From:     scenario.emailFrom,
-				To:       scenario.emailTo,
-				Format:   scenario.format,
+				Sender: scenario.emailSender,
+				Server: scenario.emailServer,
+				Port:   scenario.emailPort,
+				From:   scenario.emailFrom,
+				To:     scenario.emailTo,
+				Format: scenario.format,
 			}
 
-			if err := toGlacier.SendReport(emailInfo); !ErrorEqual(scenario.expectedError, err) {
+			if err := toGlacier.SendAlert(emailInfo, scenario.reports...); !ErrorEqual(scenario.expectedError, err) {
 				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
 			}
 		})
 	}
 }
 
+type mockMessenger struct {
+	mockSendMessage func(message string) error
+}
+
+func (m mockMessenger) SendMessage(ctx context.Context, message string) error {
+	return m.mockSendMessage(message)
+}
+
 type mockArchive struct {
 	mockBuild        func(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error)
 	mockExtract      func(filename string, filter []string) (archive.Info, error)
 	mockFileChecksum func(filename string) (string, error)
 }
 
-func (m mockArchive) Build(lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
+func (m mockArchive) Build(ctx context.Context, lastArchiveInfo archive.Info, ignorePatterns []*regexp.Regexp, backupPaths ...string) (string, archive.Info, error) {
 	return m.mockBuild(lastArchiveInfo, ignorePatterns, backupPaths...)
 }
 
-func (m mockArchive) Extract(filename string, filter []string) (archive.Info, error) {
+func (m mockArchive) Extract(ctx context.Context, filename string, filter []string) (archive.Info, error) {
 	return m.mockExtract(filename, filter)
 }
 
@@ -2767,16 +6920,39 @@ func (m mockEnvelop) Decrypt(encryptedFilename, secret string) (string, error) {
 	return m.mockDecrypt(encryptedFilename, secret)
 }
 
+type mockParity struct {
+	mockGenerate func(filename string, redundancy int) (string, error)
+	mockRepair   func(filename, parityFilename string) error
+}
+
+func (m mockParity) Generate(filename string, redundancy int) (string, error) {
+	return m.mockGenerate(filename, redundancy)
+}
+
+func (m mockParity) Repair(filename, parityFilename string) error {
+	return m.mockRepair(filename, parityFilename)
+}
+
 type mockCloud struct {
-	mockSend   func(filename string) (cloud.Backup, error)
-	mockList   func() ([]cloud.Backup, error)
-	mockGet    func(id ...string) (filenames map[string]string, err error)
-	mockRemove func(id string) error
-	mockClose  func() error
+	mockSend        func(filename, comment string) (cloud.Backup, error)
+	mockSendParity  func(filename, backupID string) (cloud.Backup, error)
+	mockSendCatalog func(filename, backupID string) (cloud.Backup, error)
+	mockList        func() ([]cloud.Backup, error)
+	mockGet         func(id ...string) (filenames map[string]string, err error)
+	mockRemove      func(id string) error
+	mockClose       func() error
+}
+
+func (m mockCloud) Send(ctx context.Context, filename, comment string) (cloud.Backup, error) {
+	return m.mockSend(filename, comment)
+}
+
+func (m mockCloud) SendParity(ctx context.Context, filename, backupID string) (cloud.Backup, error) {
+	return m.mockSendParity(filename, backupID)
 }
 
-func (m mockCloud) Send(ctx context.Context, filename string) (cloud.Backup, error) {
-	return m.mockSend(filename)
+func (m mockCloud) SendCatalog(ctx context.Context, filename, backupID string) (cloud.Backup, error) {
+	return m.mockSendCatalog(filename, backupID)
 }
 
 func (m mockCloud) List(ctx context.Context) ([]cloud.Backup, error) {
@@ -2796,23 +6972,104 @@ func (m mockCloud) Close() error {
 }
 
 type mockStorage struct {
-	mockSave   func(storage.Backup) error
-	mockList   func() (storage.Backups, error)
-	mockRemove func(id string) error
+	mockSave                  func(storage.Backup) error
+	mockList                  func() (storage.Backups, error)
+	mockRemove                func(id string) error
+	mockSaveInventoryDate     func(time.Time) error
+	mockInventoryDate         func() (time.Time, error)
+	mockSaveRestoreProgress   func(id string, progress storage.RestoreProgress) error
+	mockRestoreProgress       func(id string) (storage.RestoreProgress, error)
+	mockRemoveRestoreProgress func(id string) error
+	mockSavePause             func(until time.Time) error
+	mockPause                 func() (bool, time.Time, error)
+	mockRemovePause           func() error
+	mockSavePin               func(pin storage.Pin) error
+	mockPins                  func() ([]storage.Pin, error)
+	mockRemovePin             func(unpin storage.Pin) error
+	mockPinEvents             func() ([]storage.PinEvent, error)
 }
 
-func (m mockStorage) Save(b storage.Backup) error {
+func (m mockStorage) Save(ctx context.Context, b storage.Backup) error {
 	return m.mockSave(b)
 }
 
-func (m mockStorage) List() (storage.Backups, error) {
+func (m mockStorage) List(ctx context.Context) (storage.Backups, error) {
 	return m.mockList()
 }
 
-func (m mockStorage) Remove(id string) error {
+func (m mockStorage) Remove(ctx context.Context, id string) error {
 	return m.mockRemove(id)
 }
 
+func (m mockStorage) SaveInventoryDate(ctx context.Context, date time.Time) error {
+	return m.mockSaveInventoryDate(date)
+}
+
+func (m mockStorage) InventoryDate(ctx context.Context) (time.Time, error) {
+	return m.mockInventoryDate()
+}
+
+func (m mockStorage) SaveRestoreProgress(ctx context.Context, id string, progress storage.RestoreProgress) error {
+	return m.mockSaveRestoreProgress(id, progress)
+}
+
+func (m mockStorage) RestoreProgress(ctx context.Context, id string) (storage.RestoreProgress, error) {
+	return m.mockRestoreProgress(id)
+}
+
+func (m mockStorage) RemoveRestoreProgress(ctx context.Context, id string) error {
+	return m.mockRemoveRestoreProgress(id)
+}
+
+func (m mockStorage) SavePause(ctx context.Context, until time.Time) error {
+	return m.mockSavePause(until)
+}
+
+func (m mockStorage) Pause(ctx context.Context) (bool, time.Time, error) {
+	return m.mockPause()
+}
+
+func (m mockStorage) RemovePause(ctx context.Context) error {
+	return m.mockRemovePause()
+}
+
+func (m mockStorage) SavePin(ctx context.Context, pin storage.Pin) error {
+	return m.mockSavePin(pin)
+}
+
+func (m mockStorage) Pins(ctx context.Context) ([]storage.Pin, error) {
+	return m.mockPins()
+}
+
+func (m mockStorage) RemovePin(ctx context.Context, unpin storage.Pin) error {
+	return m.mockRemovePin(unpin)
+}
+
+func (m mockStorage) PinEvents(ctx context.Context) ([]storage.PinEvent, error) {
+	return m.mockPinEvents()
+}
+
+// mockCompactorStorage is a local storage that accumulates obsolete records.
+type mockCompactorStorage struct {
+	mockStorage
+	mockCompact func() (int, error)
+}
+
+func (m mockCompactorStorage) Compact(ctx context.Context) (int, error) {
+	return m.mockCompact()
+}
+
+// mockBatchStorage is a local storage that removes many backups in a single
+// transaction.
+type mockBatchStorage struct {
+	mockStorage
+	mockRemoveBatch func(updated storage.Backups, ids []string) error
+}
+
+func (m mockBatchStorage) RemoveBatch(ctx context.Context, updated storage.Backups, ids []string) error {
+	return m.mockRemoveBatch(updated, ids)
+}
+
 type mockReport struct {
 	mockBuild func(report.Format) (string, error)
 }
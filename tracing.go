@@ -0,0 +1,19 @@
+package toglacier
+
+import (
+	"context"
+
+	"github.com/rafaeljusto/toglacier/internal/trace"
+)
+
+// startSpan starts the span of an operation stage in the Tracer. The returned
+// context links the spans of the next stages, including the ones created by
+// the cloud, to this span.
+func (t ToGlacier) startSpan(name string) (context.Context, trace.Span) {
+	ctx := t.Context
+	if t.Tracer != nil && ctx != nil {
+		ctx = trace.WithTracer(ctx, t.Tracer)
+	}
+
+	return trace.Start(ctx, name)
+}
@@ -0,0 +1,107 @@
+package toglacier_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+	"github.com/rafaeljusto/toglacier/internal/trace"
+)
+
+func TestToGlacier_BackupTracing(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarning:  func(args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	builder := archive.NewTARBuilder(logger)
+	builder.Clock = fakeClock{now: time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)}
+	builder.FileSystem = archive.NewIOFileSystem(fstest.MapFS{
+		"data/file1.txt": &fstest.MapFile{Data: []byte("content of file1"), Mode: 0600},
+	})
+
+	tracer := &mockTracer{}
+
+	toGlacier := toglacier.ToGlacier{
+		Context: context.Background(),
+		Archive: builder,
+		Envelop: mockEnvelop{
+			mockEncrypt: func(filename, secret string) (string, error) {
+				return filename, nil
+			},
+		},
+		Cloud: mockCloud{
+			mockSend: func(filename, comment string) (cloud.Backup, error) {
+				return cloud.Backup{ID: "AWSID123"}, nil
+			},
+			mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+				return cloud.Backup{ID: "AWSID124"}, nil
+			},
+		},
+		Storage: mockStorage{
+			mockList: func() (storage.Backups, error) {
+				return nil, nil
+			},
+			mockSave: func(b storage.Backup) error {
+				return nil
+			},
+		},
+		Logger: logger,
+		Tracer: tracer,
+	}
+
+	if err := toGlacier.Backup([]string{"data"}, "secret", 0, nil, ""); err != nil {
+		t.Fatalf("unexpected error “%v”", err)
+	}
+
+	expected := []string{
+		"build (backup)",
+		"encrypt (backup)",
+		"send (backup)",
+		"backup",
+	}
+
+	if !reflect.DeepEqual(expected, tracer.ended) {
+		t.Errorf("spans don't match.\n%s", Diff(expected, tracer.ended))
+	}
+}
+
+// mockTracer records the ended spans with the name of their parents.
+type mockTracer struct {
+	lock  sync.Mutex
+	ended []string
+}
+
+type mockSpanKey struct{}
+
+func (m *mockTracer) Start(ctx context.Context, name string) (context.Context, trace.Span) {
+	span := &mockSpan{tracer: m, name: name}
+	if parent, ok := ctx.Value(mockSpanKey{}).(*mockSpan); ok {
+		span.name += " (" + parent.name + ")"
+	}
+	return context.WithValue(ctx, mockSpanKey{}, span), span
+}
+
+type mockSpan struct {
+	tracer *mockTracer
+	name   string
+}
+
+func (m *mockSpan) SetAttribute(key string, value interface{}) {}
+
+func (m *mockSpan) End(err error) {
+	m.tracer.lock.Lock()
+	defer m.tracer.lock.Unlock()
+	m.tracer.ended = append(m.tracer.ended, m.name)
+}
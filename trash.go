@@ -0,0 +1,280 @@
+package toglacier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+// trashBackups moves the backups to the trash, so they are only removed from
+// the cloud by EmptyTrash after the trash period. All backups must exist in
+// the local storage.
+func (t ToGlacier) trashBackups(ids []string) error {
+	trashReport := report.NewTrash()
+	defer func() {
+		t.reports().Add(trashReport)
+	}()
+
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		trashReport.Errors = append(trashReport.Errors, err)
+		t.events().OnError("remove backup", err)
+		return errors.WithStack(err)
+	}
+
+	for _, id := range ids {
+		if _, ok := backups.Search(id); !ok {
+			err = errors.WithStack(newError(nil, ErrorCodeBackupNotFound, fmt.Errorf("backup “%s” isn't in the local storage", id)))
+			trashReport.Errors = append(trashReport.Errors, err)
+			t.events().OnError("remove backup", err)
+			return err
+		}
+
+		trashed := storage.TrashedBackup{
+			ID:        id,
+			TrashedAt: t.now(),
+		}
+
+		if err = t.Trash.Add(t.Context, trashed); err != nil {
+			trashReport.Errors = append(trashReport.Errors, err)
+			t.events().OnError("remove backup", err)
+			return errors.WithStack(err)
+		}
+
+		trashReport.Trashed = append(trashReport.Trashed, report.TrashedBackup{
+			ID:       id,
+			RemoveAt: trashed.TrashedAt.Add(t.TrashPeriod),
+		})
+	}
+
+	return nil
+}
+
+// RestoreTrash takes the backup out of the trash before the trash period
+// expires, undoing its removal. The restoration is recorded in the audit log of
+// the destructive operations. On error it will return an Error or
+// storage.Error type encapsulated in a traceable error. To retrieve the
+// desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) RestoreTrash(id string) (err error) {
+	defer func() {
+		t.recordOperation("restore trash", []string{id}, err)
+	}()
+
+	if t.Trash == nil {
+		return errors.WithStack(newError(nil, ErrorCodeNotTrashed, fmt.Errorf("backup “%s” isn't in the trash", id)))
+	}
+
+	trashedBackups, err := t.Trash.List(t.Context)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, trashed := range trashedBackups {
+		if trashed.ID == id {
+			return errors.WithStack(t.Trash.Remove(t.Context, id))
+		}
+	}
+
+	return errors.WithStack(newError(nil, ErrorCodeNotTrashed, fmt.Errorf("backup “%s” isn't in the trash", id)))
+}
+
+// ListTrash returns the backups in the trash, in the order that they were
+// trashed. On error it will return a storage.Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) ListTrash() ([]storage.TrashedBackup, error) {
+	if t.Trash == nil {
+		return nil, nil
+	}
+
+	trashedBackups, err := t.Trash.List(t.Context)
+	return trashedBackups, errors.WithStack(err)
+}
+
+// EmptyTrash removes from the cloud and from the local storage the backups
+// that are in the trash for longer than the trash period. A backup that
+// couldn't be removed stays in the trash for the next attempt, and the
+// problem is recorded in the reports. The removed backups are recorded in the
+// audit log of the destructive operations. It returns the number of backups
+// removed. On error it will return an Error or storage.Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *storage.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) EmptyTrash() (removed int, err error) {
+	if err := t.writable("empty trash"); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	if t.Trash == nil {
+		return 0, nil
+	}
+
+	trashReport := report.NewTrash()
+	defer func() {
+		if len(trashReport.Removed) > 0 || len(trashReport.Errors) > 0 {
+			t.reports().Add(trashReport)
+
+			ids := make([]string, 0, len(trashReport.Removed))
+			for _, backup := range trashReport.Removed {
+				ids = append(ids, backup.ID)
+			}
+			t.recordOperation("empty trash", ids, err)
+		}
+	}()
+
+	trashedBackups, err := t.Trash.List(t.Context)
+	if err != nil {
+		trashReport.Errors = append(trashReport.Errors, err)
+		return 0, errors.WithStack(err)
+	}
+
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		trashReport.Errors = append(trashReport.Errors, err)
+		return 0, errors.WithStack(err)
+	}
+
+	now := t.now()
+	var expired int
+	var failures []string
+
+	for _, trashed := range trashedBackups {
+		if now.Before(trashed.TrashedAt.Add(t.TrashPeriod)) {
+			continue
+		}
+		expired++
+
+		// the backup could be removed by other means while it was in the trash
+		// (e.g. old backups removal)
+		if backup, ok := backups.Search(trashed.ID); ok {
+			if err = t.removeBackup(trashed.ID); err != nil {
+				t.events().OnError("empty trash", err)
+				trashReport.Errors = append(trashReport.Errors, err)
+				failures = append(failures, err.Error())
+				continue
+			}
+
+			t.events().OnBackupRemoved(trashed.ID)
+			trashReport.Removed = append(trashReport.Removed, backup.Backup)
+			removed++
+		}
+
+		if err = t.Trash.Remove(t.Context, trashed.ID); err != nil {
+			trashReport.Errors = append(trashReport.Errors, err)
+			return removed, errors.WithStack(err)
+		}
+	}
+
+	if len(failures) > 0 {
+		err = fmt.Errorf("%d of %d backups not removed: %s", len(failures), expired, strings.Join(failures, "; "))
+		return removed, errors.WithStack(newError(nil, ErrorCodeEmptyingTrash, err))
+	}
+
+	return removed, nil
+}
+
+// untrashedBackups hides the backups that are in the trash, so they aren't
+// listed or used as a reference by the next backups.
+func (t ToGlacier) untrashedBackups(backups storage.Backups) (storage.Backups, error) {
+	if t.Trash == nil {
+		return backups, nil
+	}
+
+	trashedBackups, err := t.Trash.List(t.Context)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if len(trashedBackups) == 0 {
+		return backups, nil
+	}
+
+	trashed := make(map[string]bool, len(trashedBackups))
+	for _, trashedBackup := range trashedBackups {
+		trashed[trashedBackup.ID] = true
+	}
+
+	untrashed := make(storage.Backups, 0, len(backups))
+	for _, backup := range backups {
+		if !trashed[backup.Backup.ID] {
+			untrashed = append(untrashed, backup)
+		}
+	}
+
+	return untrashed, nil
+}
+
+// trashedBackups returns the backups of the local storage that are in the
+// trash, so the archives that they reference aren't removed while they can
+// still be restored.
+func (t ToGlacier) trashedBackups() (storage.Backups, error) {
+	if t.Trash == nil {
+		return nil, nil
+	}
+
+	trashedBackups, err := t.Trash.List(t.Context)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if len(trashedBackups) == 0 {
+		return nil, nil
+	}
+
+	backups, err := t.Storage.List(t.Context)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var trashed storage.Backups
+	for _, trashedBackup := range trashedBackups {
+		if backup, ok := backups.Search(trashedBackup.ID); ok {
+			trashed = append(trashed, backup)
+		}
+	}
+
+	return trashed, nil
+}
@@ -0,0 +1,335 @@
+package toglacier_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_RemoveBackups_Trash(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		ids           []string
+		trash         func(added *[]storage.TrashedBackup) storage.Trash
+		expected      []storage.TrashedBackup
+		expectedError error
+	}{
+		{
+			description: "it should move the backups to the trash",
+			ids:         []string{"AWSID123", "AWSID124"},
+			trash: func(added *[]storage.TrashedBackup) storage.Trash {
+				return mockTrash{
+					mockAdd: func(trashed storage.TrashedBackup) error {
+						*added = append(*added, trashed)
+						return nil
+					},
+				}
+			},
+			expected: []storage.TrashedBackup{
+				{ID: "AWSID123", TrashedAt: now},
+				{ID: "AWSID124", TrashedAt: now},
+			},
+		},
+		{
+			description: "it should detect when the backup doesn't exist",
+			ids:         []string{"AWSID123", "AWSID999"},
+			trash: func(added *[]storage.TrashedBackup) storage.Trash {
+				return mockTrash{
+					mockAdd: func(trashed storage.TrashedBackup) error {
+						*added = append(*added, trashed)
+						return nil
+					},
+				}
+			},
+			expected: []storage.TrashedBackup{
+				{ID: "AWSID123", TrashedAt: now},
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeBackupNotFound,
+				Err:  errors.New("backup “AWSID999” isn't in the local storage"),
+			},
+		},
+		{
+			description: "it should detect an error while adding the backup to the trash",
+			ids:         []string{"AWSID123"},
+			trash: func(added *[]storage.TrashedBackup) storage.Trash {
+				return mockTrash{
+					mockAdd: func(trashed storage.TrashedBackup) error {
+						return errors.New("disk full")
+					},
+				}
+			},
+			expectedError: errors.New("disk full"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var added []storage.TrashedBackup
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud: mockCloud{
+					mockRemove: func(id string) error {
+						t.Errorf("backup “%s” removed from the cloud", id)
+						return nil
+					},
+				},
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return storage.Backups{
+							{Backup: cloud.Backup{ID: "AWSID123"}},
+							{Backup: cloud.Backup{ID: "AWSID124"}},
+						}, nil
+					},
+				},
+				Trash:       scenario.trash(&added),
+				TrashPeriod: 7 * 24 * time.Hour,
+				Clock:       fakeClock{now: now},
+			}
+
+			err := toGlacier.RemoveBackups(scenario.ids...)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(scenario.expected, added) {
+				t.Errorf("trashed backups don't match.\n%s", Diff(scenario.expected, added))
+			}
+		})
+	}
+}
+
+func TestToGlacier_RestoreTrash(t *testing.T) {
+	scenarios := []struct {
+		description     string
+		id              string
+		trash           storage.Trash
+		expectedRemoved []string
+		expectedError   error
+	}{
+		{
+			description: "it should restore a backup from the trash",
+			id:          "AWSID123",
+			trash: mockTrash{
+				mockList: func() ([]storage.TrashedBackup, error) {
+					return []storage.TrashedBackup{{ID: "AWSID123"}}, nil
+				},
+			},
+			expectedRemoved: []string{"AWSID123"},
+		},
+		{
+			description: "it should detect when the backup isn't in the trash",
+			id:          "AWSID999",
+			trash: mockTrash{
+				mockList: func() ([]storage.TrashedBackup, error) {
+					return []storage.TrashedBackup{{ID: "AWSID123"}}, nil
+				},
+			},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeNotTrashed,
+				Err:  errors.New("backup “AWSID999” isn't in the trash"),
+			},
+		},
+		{
+			description: "it should detect when there's no trash",
+			id:          "AWSID123",
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeNotTrashed,
+				Err:  errors.New("backup “AWSID123” isn't in the trash"),
+			},
+		},
+		{
+			description: "it should detect an error while listing the trash",
+			id:          "AWSID123",
+			trash: mockTrash{
+				mockList: func() ([]storage.TrashedBackup, error) {
+					return nil, errors.New("file corrupted")
+				},
+			},
+			expectedError: errors.New("file corrupted"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var removed []string
+
+			if trash, ok := scenario.trash.(mockTrash); ok {
+				trash.mockRemove = func(id string) error {
+					removed = append(removed, id)
+					return nil
+				}
+				scenario.trash = trash
+			}
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Trash:   scenario.trash,
+			}
+
+			err := toGlacier.RestoreTrash(scenario.id)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedRemoved, removed) {
+				t.Errorf("restored backups don't match.\n%s", Diff(scenario.expectedRemoved, removed))
+			}
+		})
+	}
+}
+
+func TestToGlacier_EmptyTrash(t *testing.T) {
+	now := time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description          string
+		trashed              []storage.TrashedBackup
+		cloud                cloud.Cloud
+		expected             int
+		expectedCloudRemoved []string
+		expectedRemoved      []string
+		expectedError        error
+	}{
+		{
+			description: "it should remove only the expired backups",
+			trashed: []storage.TrashedBackup{
+				{ID: "AWSID123", TrashedAt: now.Add(-8 * 24 * time.Hour)},
+				{ID: "AWSID124", TrashedAt: now.Add(-time.Hour)},
+			},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			expected:             1,
+			expectedCloudRemoved: []string{"AWSID123"},
+			expectedRemoved:      []string{"AWSID123"},
+		},
+		{
+			description: "it should forget a trashed backup that was already removed",
+			trashed: []storage.TrashedBackup{
+				{ID: "AWSID999", TrashedAt: now.Add(-8 * 24 * time.Hour)},
+			},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					return nil
+				},
+			},
+			expectedRemoved: []string{"AWSID999"},
+		},
+		{
+			description: "it should keep in the trash the backups that couldn't be removed",
+			trashed: []storage.TrashedBackup{
+				{ID: "AWSID123", TrashedAt: now.Add(-8 * 24 * time.Hour)},
+				{ID: "AWSID124", TrashedAt: now.Add(-7 * 24 * time.Hour)},
+			},
+			cloud: mockCloud{
+				mockRemove: func(id string) error {
+					if id == "AWSID123" {
+						return errors.New("connection error")
+					}
+					return nil
+				},
+			},
+			expected:             1,
+			expectedCloudRemoved: []string{"AWSID124"},
+			expectedRemoved:      []string{"AWSID124"},
+			expectedError: &toglacier.Error{
+				Code: toglacier.ErrorCodeEmptyingTrash,
+				Err:  fmt.Errorf("1 of 2 backups not removed: connection error"),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var cloudRemoved, removed []string
+
+			c := scenario.cloud.(mockCloud)
+			remove := c.mockRemove
+			c.mockRemove = func(id string) error {
+				if err := remove(id); err != nil {
+					return err
+				}
+				cloudRemoved = append(cloudRemoved, id)
+				return nil
+			}
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   c,
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return storage.Backups{
+							{Backup: cloud.Backup{ID: "AWSID123", CreatedAt: now.Add(-10 * 24 * time.Hour)}},
+							{Backup: cloud.Backup{ID: "AWSID124", CreatedAt: now.Add(-9 * 24 * time.Hour)}},
+						}, nil
+					},
+					mockRemove: func(id string) error {
+						return nil
+					},
+				},
+				Trash: mockTrash{
+					mockList: func() ([]storage.TrashedBackup, error) {
+						return scenario.trashed, nil
+					},
+					mockRemove: func(id string) error {
+						removed = append(removed, id)
+						return nil
+					},
+				},
+				TrashPeriod: 7 * 24 * time.Hour,
+				Clock:       fakeClock{now: now},
+				Logger: mockLogger{
+					mockWarningf: func(format string, args ...interface{}) {},
+				},
+			}
+
+			count, err := toGlacier.EmptyTrash()
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+
+			if count != scenario.expected {
+				t.Errorf("number of removed backups don't match. expected “%d” and got “%d”", scenario.expected, count)
+			}
+
+			if !reflect.DeepEqual(scenario.expectedCloudRemoved, cloudRemoved) {
+				t.Errorf("backups removed from the cloud don't match.\n%s", Diff(scenario.expectedCloudRemoved, cloudRemoved))
+			}
+
+			if !reflect.DeepEqual(scenario.expectedRemoved, removed) {
+				t.Errorf("backups removed from the trash don't match.\n%s", Diff(scenario.expectedRemoved, removed))
+			}
+		})
+	}
+}
+
+type mockTrash struct {
+	mockAdd    func(trashed storage.TrashedBackup) error
+	mockList   func() ([]storage.TrashedBackup, error)
+	mockRemove func(id string) error
+}
+
+func (m mockTrash) Add(ctx context.Context, trashed storage.TrashedBackup) error {
+	return m.mockAdd(trashed)
+}
+
+func (m mockTrash) List(ctx context.Context) ([]storage.TrashedBackup, error) {
+	return m.mockList()
+}
+
+func (m mockTrash) Remove(ctx context.Context, id string) error {
+	return m.mockRemove(id)
+}
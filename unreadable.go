@@ -0,0 +1,78 @@
+package toglacier
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/report"
+)
+
+// unreadableFiles leaves the files that can't be read out of the backup,
+// recording them in the backup report, until the limit is reached.
+type unreadableFiles struct {
+	limit  int
+	report *report.SendBackup
+}
+
+func (u unreadableFiles) OnPathError(path string, err error) bool {
+	if len(u.report.Skipped) >= u.limit {
+		return false
+	}
+
+	if pathErr, ok := errors.Cause(err).(*archive.PathError); ok && pathErr.Err != nil {
+		err = pathErr.Err
+	}
+
+	u.report.Skipped = append(u.report.Skipped, fmt.Sprintf("%s: %s", path, err))
+	return true
+}
+
+// builder returns the archive that leaves up to MaxUnreadable files that can't
+// be read out of the backup and stops after BackupMaxDuration, without changing
+// the archive used by other operations.
+func (t ToGlacier) builder(backupReport *report.SendBackup) archive.Archive {
+	var tolerance archive.PathTolerance
+	if t.MaxUnreadable > 0 {
+		tolerance = unreadableFiles{
+			limit:  t.MaxUnreadable,
+			report: backupReport,
+		}
+	}
+
+	var deadline archive.Deadline
+	if t.BackupMaxDuration > 0 {
+		deadline = &backupDeadline{
+			toGlacier: t,
+			at:        t.now().Add(t.BackupMaxDuration),
+			report:    backupReport,
+		}
+	}
+
+	if tolerance == nil && deadline == nil {
+		return t.Archive
+	}
+
+	switch builder := t.Archive.(type) {
+	case *archive.TARBuilder:
+		limited := *builder
+		if tolerance != nil {
+			limited.Tolerance = tolerance
+		}
+		if deadline != nil {
+			limited.Deadline = deadline
+		}
+		return &limited
+	case *archive.ZIPBuilder:
+		limited := *builder
+		if tolerance != nil {
+			limited.Tolerance = tolerance
+		}
+		if deadline != nil {
+			limited.Deadline = deadline
+		}
+		return &limited
+	}
+
+	return t.Archive
+}
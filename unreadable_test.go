@@ -0,0 +1,140 @@
+package toglacier_test
+
+import (
+	"context"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/archive"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+	"github.com/rafaeljusto/toglacier/internal/report"
+	"github.com/rafaeljusto/toglacier/internal/storage"
+)
+
+func TestToGlacier_BackupUnreadable(t *testing.T) {
+	logger := mockLogger{
+		mockDebug:    func(args ...interface{}) {},
+		mockDebugf:   func(format string, args ...interface{}) {},
+		mockInfo:     func(args ...interface{}) {},
+		mockInfof:    func(format string, args ...interface{}) {},
+		mockWarning:  func(args ...interface{}) {},
+		mockWarningf: func(format string, args ...interface{}) {},
+	}
+
+	scenarios := []struct {
+		description     string
+		maxUnreadable   int
+		expectedSent    bool
+		expectedSkipped []string
+		expectedError   bool
+	}{
+		{
+			description:   "it should skip the unreadable files",
+			maxUnreadable: 2,
+			expectedSent:  true,
+			expectedSkipped: []string{
+				"data/secret1.txt: open data/secret1.txt: permission denied",
+				"data/secret2.txt: open data/secret2.txt: permission denied",
+			},
+		},
+		{
+			description:   "it should fail when there are too many unreadable files",
+			maxUnreadable: 1,
+			expectedSkipped: []string{
+				"data/secret1.txt: open data/secret1.txt: permission denied",
+			},
+			expectedError: true,
+		},
+		{
+			description:   "it should fail on the first unreadable file by default",
+			expectedError: true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			builder := archive.NewTARBuilder(logger)
+			builder.Clock = fakeClock{now: time.Date(2017, 9, 14, 10, 30, 0, 0, time.UTC)}
+			builder.FileSystem = unreadableFileSystem{
+				FileSystem: archive.NewIOFileSystem(fstest.MapFS{
+					"data/file1.txt":   &fstest.MapFile{Data: []byte("content of file1"), Mode: 0600},
+					"data/secret1.txt": &fstest.MapFile{Data: []byte("content of secret1"), Mode: 0600},
+					"data/secret2.txt": &fstest.MapFile{Data: []byte("content of secret2"), Mode: 0600},
+				}),
+				unreadable: map[string]bool{
+					"data/secret1.txt": true,
+					"data/secret2.txt": true,
+				},
+			}
+
+			reports := report.NewCollector()
+			var sent bool
+
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Archive: builder,
+				Cloud: mockCloud{
+					mockSend: func(filename, comment string) (cloud.Backup, error) {
+						sent = true
+						return cloud.Backup{ID: "AWSID123"}, nil
+					},
+					mockSendCatalog: func(filename, backupID string) (cloud.Backup, error) {
+						return cloud.Backup{ID: "AWSID124"}, nil
+					},
+				},
+				Storage: mockStorage{
+					mockList: func() (storage.Backups, error) {
+						return nil, nil
+					},
+					mockSave: func(b storage.Backup) error {
+						return nil
+					},
+				},
+				Logger:        logger,
+				Reports:       reports,
+				MaxUnreadable: scenario.maxUnreadable,
+			}
+
+			err := toGlacier.Backup([]string{"data"}, "", 0, nil, "")
+			if scenario.expectedError != (err != nil) {
+				t.Errorf("unexpected error “%v”", err)
+			}
+
+			if sent != scenario.expectedSent {
+				t.Errorf("unexpected backup upload. expected “%t” and got “%t”", scenario.expectedSent, sent)
+			}
+
+			backupReports := reports.Extract(func(r report.Report) bool {
+				_, ok := r.(report.SendBackup)
+				return ok
+			})
+
+			if len(backupReports) != 1 {
+				t.Fatalf("expected one backup report and got %d", len(backupReports))
+			}
+
+			skipped := backupReports[0].(report.SendBackup).Skipped
+			if !reflect.DeepEqual(scenario.expectedSkipped, skipped) {
+				t.Errorf("skipped files don't match.\n%s", Diff(scenario.expectedSkipped, skipped))
+			}
+		})
+	}
+}
+
+// unreadableFileSystem denies access to the content of some files.
+type unreadableFileSystem struct {
+	archive.FileSystem
+	unreadable map[string]bool
+}
+
+func (u unreadableFileSystem) Open(name string) (fs.File, error) {
+	if u.unreadable[name] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+
+	return u.FileSystem.Open(name)
+}
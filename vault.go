@@ -0,0 +1,172 @@
+package toglacier
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+// LockVault starts the lock process of the backups vault with the given
+// policy, returning the lock identifier. While the lock is in progress the
+// policy can be tested, and the lock must be completed with CompleteVaultLock
+// within 24 hours, otherwise it will expire. On error it will return an Error
+// type encapsulated in a traceable error. To retrieve the desired error you
+// can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) LockVault(policy string) (string, error) {
+	if err := t.writable("lock vault"); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	vaultPolicy, ok := t.Cloud.(cloud.VaultPolicy)
+	if !ok {
+		return "", errors.WithStack(newError(nil, ErrorCodeVaultPolicyNotSupported, nil))
+	}
+
+	lockID, err := vaultPolicy.InitiateLock(t.Context, policy)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return lockID, nil
+}
+
+// CompleteVaultLock makes the policy of the lock identified by lockID
+// immutable. After this the backups can only be removed as allowed by the
+// policy, and there's no way to undo it. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) CompleteVaultLock(lockID string) error {
+	if err := t.writable("complete vault lock"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	vaultPolicy, ok := t.Cloud.(cloud.VaultPolicy)
+	if !ok {
+		return errors.WithStack(newError(nil, ErrorCodeVaultPolicyNotSupported, nil))
+	}
+
+	return errors.WithStack(vaultPolicy.CompleteLock(t.Context, lockID))
+}
+
+// AbortVaultLock stops a lock process that wasn't completed yet, so a new
+// policy can be tried. On error it will return an Error type encapsulated in a
+// traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) AbortVaultLock() error {
+	if err := t.writable("abort vault lock"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	vaultPolicy, ok := t.Cloud.(cloud.VaultPolicy)
+	if !ok {
+		return errors.WithStack(newError(nil, ErrorCodeVaultPolicyNotSupported, nil))
+	}
+
+	return errors.WithStack(vaultPolicy.AbortLock(t.Context))
+}
+
+// SetVaultAccessPolicy replaces the access policy of the backups vault, so
+// it's possible to control who can access or remove the backups. An empty
+// policy removes the current one. On error it will return an Error type
+// encapsulated in a traceable error. To retrieve the desired error you can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) SetVaultAccessPolicy(policy string) error {
+	if err := t.writable("set vault access policy"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	vaultSettings, ok := t.Cloud.(cloud.VaultSettings)
+	if !ok {
+		return errors.WithStack(newError(nil, ErrorCodeVaultSettingsNotSupported, nil))
+	}
+
+	return errors.WithStack(vaultSettings.SetAccessPolicy(t.Context, policy))
+}
+
+// SetVaultTags makes the tags of the backups vault equal to the given ones,
+// removing any tag that isn't in the list. On error it will return an Error
+// type encapsulated in a traceable error. To retrieve the desired error you
+// can do:
+//
+//     type causer interface {
+//       Cause() error
+//     }
+//
+//     if causeErr, ok := err.(causer); ok {
+//       switch specificErr := causeErr.Cause().(type) {
+//       case *toglacier.Error:
+//         // handle specifically
+//       case *cloud.Error:
+//         // handle specifically
+//       default:
+//         // unknown error
+//       }
+//     }
+func (t ToGlacier) SetVaultTags(tags map[string]string) error {
+	if err := t.writable("set vault tags"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	vaultSettings, ok := t.Cloud.(cloud.VaultSettings)
+	if !ok {
+		return errors.WithStack(newError(nil, ErrorCodeVaultSettingsNotSupported, nil))
+	}
+
+	return errors.WithStack(vaultSettings.SetTags(t.Context, tags))
+}
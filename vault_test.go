@@ -0,0 +1,315 @@
+package toglacier_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/rafaeljusto/toglacier"
+	"github.com/rafaeljusto/toglacier/internal/cloud"
+)
+
+func TestToGlacier_LockVault(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		policy        string
+		cloud         cloud.Cloud
+		expected      string
+		expectedError error
+	}{
+		{
+			description: "it should initiate the vault lock correctly",
+			policy:      `{"Version":"2012-10-17","Statement":[]}`,
+			cloud: mockVaultCloud{
+				mockInitiateLock: func(policy string) (string, error) {
+					if policy != `{"Version":"2012-10-17","Statement":[]}` {
+						return "", fmt.Errorf("unexpected policy “%s”", policy)
+					}
+
+					return "LOCKID123", nil
+				},
+			},
+			expected: "LOCKID123",
+		},
+		{
+			description:   "it should detect when the cloud doesn't support vault policies",
+			policy:        `{"Version":"2012-10-17","Statement":[]}`,
+			cloud:         mockCloud{},
+			expectedError: &toglacier.Error{Code: toglacier.ErrorCodeVaultPolicyNotSupported},
+		},
+		{
+			description: "it should detect an error while initiating the vault lock",
+			policy:      "invalid policy",
+			cloud: mockVaultCloud{
+				mockInitiateLock: func(policy string) (string, error) {
+					return "", errors.New("invalid policy")
+				},
+			},
+			expectedError: errors.New("invalid policy"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud,
+			}
+
+			lockID, err := toGlacier.LockVault(scenario.policy)
+			if scenario.expected != lockID {
+				t.Errorf("lock ids don't match. expected “%s” and got “%s”", scenario.expected, lockID)
+			}
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_CompleteVaultLock(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		lockID        string
+		cloud         cloud.Cloud
+		expectedError error
+	}{
+		{
+			description: "it should complete the vault lock correctly",
+			lockID:      "LOCKID123",
+			cloud: mockVaultCloud{
+				mockCompleteLock: func(lockID string) error {
+					if lockID != "LOCKID123" {
+						return fmt.Errorf("unexpected lock id “%s”", lockID)
+					}
+
+					return nil
+				},
+			},
+		},
+		{
+			description:   "it should detect when the cloud doesn't support vault policies",
+			lockID:        "LOCKID123",
+			cloud:         mockCloud{},
+			expectedError: &toglacier.Error{Code: toglacier.ErrorCodeVaultPolicyNotSupported},
+		},
+		{
+			description: "it should detect an error while completing the vault lock",
+			lockID:      "LOCKID123",
+			cloud: mockVaultCloud{
+				mockCompleteLock: func(lockID string) error {
+					return errors.New("lock expired")
+				},
+			},
+			expectedError: errors.New("lock expired"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud,
+			}
+
+			err := toGlacier.CompleteVaultLock(scenario.lockID)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_AbortVaultLock(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		cloud         cloud.Cloud
+		expectedError error
+	}{
+		{
+			description: "it should abort the vault lock correctly",
+			cloud: mockVaultCloud{
+				mockAbortLock: func() error {
+					return nil
+				},
+			},
+		},
+		{
+			description:   "it should detect when the cloud doesn't support vault policies",
+			cloud:         mockCloud{},
+			expectedError: &toglacier.Error{Code: toglacier.ErrorCodeVaultPolicyNotSupported},
+		},
+		{
+			description: "it should detect an error while aborting the vault lock",
+			cloud: mockVaultCloud{
+				mockAbortLock: func() error {
+					return errors.New("vault already locked")
+				},
+			},
+			expectedError: errors.New("vault already locked"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud,
+			}
+
+			err := toGlacier.AbortVaultLock()
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_SetVaultAccessPolicy(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		policy        string
+		cloud         cloud.Cloud
+		expectedError error
+	}{
+		{
+			description: "it should replace the access policy correctly",
+			policy:      `{"Version":"2012-10-17","Statement":[]}`,
+			cloud: mockVaultSettingsCloud{
+				mockSetAccessPolicy: func(policy string) error {
+					if policy != `{"Version":"2012-10-17","Statement":[]}` {
+						return fmt.Errorf("unexpected policy “%s”", policy)
+					}
+
+					return nil
+				},
+			},
+		},
+		{
+			description:   "it should detect when the cloud doesn't support vault settings",
+			policy:        `{"Version":"2012-10-17","Statement":[]}`,
+			cloud:         mockCloud{},
+			expectedError: &toglacier.Error{Code: toglacier.ErrorCodeVaultSettingsNotSupported},
+		},
+		{
+			description: "it should detect an error while replacing the access policy",
+			policy:      "invalid policy",
+			cloud: mockVaultSettingsCloud{
+				mockSetAccessPolicy: func(policy string) error {
+					return errors.New("invalid policy")
+				},
+			},
+			expectedError: errors.New("invalid policy"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud,
+			}
+
+			err := toGlacier.SetVaultAccessPolicy(scenario.policy)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestToGlacier_SetVaultTags(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		tags          map[string]string
+		cloud         cloud.Cloud
+		expectedError error
+	}{
+		{
+			description: "it should change the tags correctly",
+			tags: map[string]string{
+				"environment": "production",
+			},
+			cloud: mockVaultSettingsCloud{
+				mockSetTags: func(tags map[string]string) error {
+					if !reflect.DeepEqual(tags, map[string]string{"environment": "production"}) {
+						return fmt.Errorf("unexpected tags %v", tags)
+					}
+
+					return nil
+				},
+			},
+		},
+		{
+			description: "it should detect when the cloud doesn't support vault settings",
+			tags: map[string]string{
+				"environment": "production",
+			},
+			cloud:         mockCloud{},
+			expectedError: &toglacier.Error{Code: toglacier.ErrorCodeVaultSettingsNotSupported},
+		},
+		{
+			description: "it should detect an error while changing the tags",
+			tags: map[string]string{
+				"environment": "production",
+			},
+			cloud: mockVaultSettingsCloud{
+				mockSetTags: func(tags map[string]string) error {
+					return errors.New("too many tags")
+				},
+			},
+			expectedError: errors.New("too many tags"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			toGlacier := toglacier.ToGlacier{
+				Context: context.Background(),
+				Cloud:   scenario.cloud,
+			}
+
+			err := toGlacier.SetVaultTags(scenario.tags)
+			if !ErrorEqual(scenario.expectedError, err) {
+				t.Errorf("errors don't match. expected “%v” and got “%v”", scenario.expectedError, err)
+			}
+		})
+	}
+}
+
+// mockVaultCloud is a cloud that also supports vault policies.
+type mockVaultCloud struct {
+	mockCloud
+	mockInitiateLock func(policy string) (string, error)
+	mockCompleteLock func(lockID string) error
+	mockAbortLock    func() error
+}
+
+func (m mockVaultCloud) InitiateLock(ctx context.Context, policy string) (string, error) {
+	return m.mockInitiateLock(policy)
+}
+
+func (m mockVaultCloud) CompleteLock(ctx context.Context, lockID string) error {
+	return m.mockCompleteLock(lockID)
+}
+
+func (m mockVaultCloud) AbortLock(ctx context.Context) error {
+	return m.mockAbortLock()
+}
+
+// mockVaultSettingsCloud is a cloud that also supports vault settings.
+type mockVaultSettingsCloud struct {
+	mockCloud
+	mockSetAccessPolicy func(policy string) error
+	mockSetTags         func(tags map[string]string) error
+}
+
+func (m mockVaultSettingsCloud) SetAccessPolicy(ctx context.Context, policy string) error {
+	return m.mockSetAccessPolicy(policy)
+}
+
+func (m mockVaultSettingsCloud) SetTags(ctx context.Context, tags map[string]string) error {
+	return m.mockSetTags(tags)
+}